@@ -10,9 +10,9 @@ import * as path from 'path';
 import * as fs from 'fs/promises';
 import { BoundaryAgent } from './core/agents/boundary-agent.js';
 import { EnhancedBoundaryAgent } from './core/agents/enhanced-boundary-agent.js';
-import { ArchitectAgent } from './core/agents/architect-agent.js';
+import { ArchitectAgent, ArchitecturalPlan, PLAN_SCHEMA_VERSION, checkPlanConstraints } from './core/agents/architect-agent.js';
 import { RefactorAgent } from './core/agents/refactor-agent.js';
-import { TestSynthAgent } from './core/agents/test-synth-agent.js';
+import { TestSynthAgent, TestSynthResult } from './core/agents/test-synth-agent.js';
 import { MigrationRunner } from './core/agents/migration-runner.js';
 import { ReviewAgent } from './core/agents/review-agent.js';
 import { VibeFlowPaths } from './core/utils/file-paths.js';
@@ -25,27 +25,166 @@ import { BusinessLogicMigrationAgent } from './core/agents/business-logic-migrat
 import { TestSynthesisAgent } from './core/agents/test-synthesis-agent.js';
 import { handleResumeFlow } from './core/utils/checkpoint-manager.js';
 import { MetadataDrivenRefactorAgent } from './core/agents/metadata-driven-refactor-agent.js';
+import { PROCESSING_METHODS, PerformanceStore, ProcessingMethod, countLinesOfCode, findFallbackRuns } from './core/utils/performance-store.js';
+import { FILE_PROCESSING_FORMATS, FileProcessingFormat, formatFileProcessing, formatMethodTable, summarizeProcessing } from './core/utils/file-processing.js';
+import { summarizeEscalations } from './core/utils/model-escalation.js';
+import { RunArtifactStore } from './core/utils/run-artifacts.js';
+import { captureRunEnvironment, formatRunEnvironment, redactSecrets } from './core/utils/run-environment.js';
+import {
+  BATCH_POLL_INTERVAL_MS,
+  BatchProvider,
+  BatchRunState,
+  BatchUnsupportedError,
+  LlmBatchSession,
+  advanceBatchRun,
+  pollBatchJobs,
+  resolveBatchProvider,
+} from './core/utils/llm-batch.js';
+import { MutationCheckResult, checkModuleMutations } from './core/utils/mutation-check.js';
+import { AUTONOMY_TIERS, AutonomyTier } from './core/utils/module-risk.js';
+import {
+  QUERY_FORMATS,
+  QueryFormat,
+  QueryResult,
+  formatQueryResult,
+  loadQueryDomainMap,
+  queryDeps,
+  queryFiles,
+  queryRoutes,
+  queryTables,
+} from './core/utils/architecture-query.js';
+import { GRAPH_EXTENSIONS, GRAPH_FORMATS, GraphFormat, buildBoundaryGraph, renderBoundaryGraph } from './core/utils/boundary-graph.js';
+import { C4_EXTENSIONS, C4_FORMATS, C4Format, buildC4Model, renderC4Diagrams } from './core/utils/c4-diagrams.js';
+import { diffDomainMaps, formatDomainMapDiff } from './core/utils/domain-map-diff.js';
+import { checkDiscoveryGates, formatGateViolations, formatUnmeasuredGates, hasDiscoveryGates } from './core/utils/discovery-gates.js';
+import { FailedResponseStore, writeBugReport } from './core/utils/bug-report.js';
+import { ConfigLoader } from './core/utils/config-loader.js';
+import { getErrorMessage } from './core/utils/error-utils.js';
+import { parseDomainMap, parsePlan } from './core/utils/input-parsers.js';
+import { DebtInventory, DebtInventoryScanner, topDebtFiles } from './core/utils/debt-inventory.js';
+import { SamplingOptions, parseSampleRate } from './core/utils/discovery-sampling.js';
+import { GranularityOptions } from './core/utils/module-granularity.js';
+import { ScopeReport, discoveryScopes, findScopeEdges, resolveScopeRoot } from './core/utils/discovery-scopes.js';
+import { resolvePathFilters } from './core/utils/path-filters.js';
+import { ArchitectureStyle, parseArchitectureStyle } from './core/utils/architecture-style.js';
+import { ArchitectureCandidate } from './core/utils/architecture-comparison.js';
+import { MigrationMode, parseMigrationMode } from './core/utils/strangler-fig.js';
+import { GO_MODULE_LAYOUTS, GoModuleLayout, parseGoModuleLayout } from './core/utils/go-module-layout.js';
+import { PlanTarget, parsePlanTarget } from './core/utils/service-extraction.js';
+import { ModuleDeployment, parseDeployments } from './core/utils/service-deployment.js';
+import { enforceOffline, isOffline } from './core/utils/offline-guard.js';
+import { onShutdown } from './core/utils/shutdown.js';
+import { AgentStage, ModuleRef, ModuleStatusTracker, formatModuleStatus } from './core/utils/module-status.js';
+import { GenerationMode } from './core/types/refactor.js';
+import { BusinessLogicMigrationExecuteResult } from './core/types/business-logic.js';
+import { DiscoveryGates, DomainMap } from './core/types/config.js';
 
 // -----------------------------------------------------------------------------
 // Workflow execution functions
 // -----------------------------------------------------------------------------
-async function runAutomaticBoundaryDiscovery(projectRoot: string): Promise<void> {
+async function runAutomaticBoundaryDiscovery(
+  projectRoot: string,
+  options: { debt?: boolean; sampling?: SamplingOptions; scopes?: string[]; paths?: string[]; packages?: string[]; reconsiderEstablished?: string[]; full?: boolean; graph?: GraphFormat; concurrency?: number; granularity?: GranularityOptions; gates?: DiscoveryGates; compare?: string } = {}
+): Promise<void> {
   const absolutePath = path.resolve(projectRoot);
-  
+
+  // Scopes (--scope or scopes: of the config) are discovered one by one
+  const scopes = discoveryScopes(absolutePath, options.scopes);
+  if (scopes.length > 0) {
+    if (options.paths || options.packages) {
+      throw new Error('--path / --package filter one project; the config defines scopes, so run vf discover <scope dir> --path ... instead');
+    }
+    if (options.compare) {
+      throw new Error('--compare takes the domain map of one project; run it with --scope <dir> for a single scope');
+    }
+    await runScopedDiscovery(absolutePath, scopes, options);
+    return;
+  }
+  // Read before discovery overwrites it, so the live domain-map.json can be compared too
+  const previous = options.compare ? await loadComparedDomainMap(options.compare) : undefined;
+  const domainMap = await discoverProject(absolutePath, options);
+  if (previous) reportDomainMapDiff(absolutePath, previous, domainMap, options.compare!);
+}
+
+/**
+ * Domain map given to --compare
+ */
+async function loadComparedDomainMap(file: string): Promise<DomainMap> {
+  let content: string;
+  try {
+    content = await fs.readFile(file, 'utf8');
+  } catch (error) {
+    throw new Error(`Cannot read --compare ${file}: ${getErrorMessage(error)}`);
+  }
+  const { map, invalid } = parseDomainMap(content, file);
+  if (invalid.length > 0) {
+    console.warn(chalk.yellow(`⚠️  ${file} の不正な境界${invalid.length}個を比較から除外しました`));
+  }
+  return map;
+}
+
+/**
+ * Print how the boundaries changed since the compared domain map and write .vibeflow/domain-map-diff.json
+ */
+function reportDomainMapDiff(projectRoot: string, previous: DomainMap, current: DomainMap, previousPath: string): void {
+  const paths = new VibeFlowPaths(projectRoot);
+  const diff = diffDomainMaps(projectRoot, previous, current, previousPath);
+  paths.writeArtifact(paths.domainMapDiffPath, diff);
+
+  console.log(chalk.cyan(`\n🔀 前回のドメインマップとの差分 (${previousPath}):`));
+  formatDomainMapDiff(diff).forEach(line => console.log(chalk.gray(`   ${line}`)));
+  console.log(chalk.gray(`   詳細: ${paths.getRelativePath(paths.domainMapDiffPath)}`));
+}
+
+async function discoverProject(
+  absolutePath: string,
+  options: { debt?: boolean; sampling?: SamplingOptions; scope?: string; paths?: string[]; packages?: string[]; reconsiderEstablished?: string[]; full?: boolean; graph?: GraphFormat; concurrency?: number; granularity?: GranularityOptions; gates?: DiscoveryGates } = {}
+): Promise<DomainMap> {
   // Verify project exists
   try {
     await fs.access(absolutePath);
   } catch {
     throw new Error(`Project directory not found: ${absolutePath}`);
   }
+  // --path / --package: only these directories are discovered and merged into domain-map.json
+  const pathFilters = resolvePathFilters(absolutePath, { paths: options.paths, packages: options.packages });
 
   console.log(chalk.blue(`🤖 AI自動境界発見: ${absolutePath}`));
   console.log(chalk.gray('設定ファイル不要 - AIが完全自動でモジュール境界を発見します'));
   
+  // Record the run so debt counts can be followed across discoveries
+  const performanceStore = new PerformanceStore(absolutePath);
+  let runId: number | undefined;
+  try {
+    runId = performanceStore.startRun('discover', { environment: captureRunEnvironment(absolutePath) });
+  } catch {
+    // Metrics are best-effort and must not block discovery
+  }
+
   try {
     // AI完全自動境界発見（設定ファイルなしで実行）
-    const enhancedBoundaryAgent = new EnhancedBoundaryAgent(absolutePath, undefined, undefined);
+    const enhancedBoundaryAgent = new EnhancedBoundaryAgent(absolutePath, undefined, undefined, {
+      sampling: options.sampling,
+      scope: options.scope,
+      reconsiderEstablished: options.reconsiderEstablished,
+      incremental: !options.full,
+      concurrency: options.concurrency,
+      granularity: options.granularity,
+      pathFilters,
+    });
     const boundaryResult = await enhancedBoundaryAgent.analyzeBoundaries();
+
+    if (runId !== undefined) {
+      try {
+        if (boundaryResult.debtInventory) {
+          new DebtInventoryScanner(absolutePath, boundaryResult.debtInventory.markers).recordMetrics(boundaryResult.debtInventory, runId);
+        }
+        performanceStore.finishRun(runId, { status: 'success', modules_planned: boundaryResult.domainMap.boundaries.length });
+      } catch {
+        // Metrics are best-effort
+      }
+    }
+    recordModuleStage(absolutePath, boundaryResult.domainMap.boundaries, 'discovered', runId);
     
     console.log(chalk.green('✨ AI自動境界発見完了!'));
     console.log(chalk.cyan('\n📊 発見結果サマリ:'));
@@ -54,6 +193,19 @@ async function runAutomaticBoundaryDiscovery(projectRoot: string): Promise<void>
     console.log(chalk.gray(`   🏗️  構造一貫性: ${boundaryResult.discoveryMetrics.confidence_metrics.structural_coherence.toFixed(1)}%`));
     console.log(chalk.gray(`   🗄️  DB整合性: ${boundaryResult.discoveryMetrics.confidence_metrics.database_alignment.toFixed(1)}%`));
     
+    const sampling = boundaryResult.domainMap.sampling;
+    if (sampling) {
+      console.log(chalk.yellow(`\n🎯 サンプリング分析: ${sampling.analyzed_files}/${sampling.total_files}ファイル (${sampling.skipped_files}ファイルをスキップ)`));
+      console.log(chalk.gray(`   推定誤差 (95%): 凝集度 ±${sampling.error_band.cohesion}, 結合度 ±${sampling.error_band.coupling}`));
+      if (sampling.low_coverage_packages.length > 0) {
+        console.log(chalk.gray('   カバレッジの低いパッケージ:'));
+        sampling.low_coverage_packages.forEach(p => {
+          console.log(chalk.gray(`   - ${p.package}: ${p.analyzed}/${p.total}ファイル (${(p.coverage * 100).toFixed(0)}%)`));
+        });
+      }
+      console.log(chalk.gray('   このドメインマップから生成する計画は探索用で、vf refactor には使用できません'));
+    }
+    
     console.log(chalk.cyan('\n🎯 発見された境界:'));
     boundaryResult.autoDiscoveredBoundaries
       .slice(0, 10)
@@ -61,8 +213,75 @@ async function runAutomaticBoundaryDiscovery(projectRoot: string): Promise<void>
         console.log(chalk.gray(`   ${i + 1}. ${boundary.name} (信頼度${(boundary.confidence * 100).toFixed(1)}%)`));
         console.log(chalk.gray(`      └─ ${boundary.description}`));
         console.log(chalk.gray(`      └─ ファイル数: ${boundary.files.length}, キーワード: ${boundary.semantic_keywords.slice(0, 3).join(', ')}`));
+        if (boundary.confidence_breakdown) {
+          console.log(chalk.gray(`      └─ ${boundary.confidence_breakdown.rationale}`));
+        }
       });
     
+    const loadErrors = boundaryResult.discoveryMetrics.load_errors ?? [];
+    if (loadErrors.length > 0) {
+      console.log(chalk.yellow(`\n⚠️  読み込めないパッケージ (構文のみで解析, analysis: degraded): ${loadErrors.length}個`));
+      loadErrors.forEach(loadError => {
+        console.log(chalk.gray(`   - ${loadError.package} (${loadError.files.length}ファイル)`));
+        console.log(chalk.gray(`      └─ ${loadError.errors[0]}`));
+      });
+      console.log(chalk.gray('   これらのファイルを含むモジュールは --allow-degraded なしではリファクタリングされません'));
+    }
+
+    const sharedTables = boundaryResult.discoveryMetrics.data_ownership?.violations ?? [];
+    if (sharedTables.length > 0) {
+      console.log(chalk.yellow(`\n🗄️  複数モジュールからアクセスされるテーブル: ${sharedTables.length}個`));
+      sharedTables.forEach(violation => {
+        console.log(chalk.gray(`   - ${violation.table}: ${violation.modules.join(', ')}`));
+        console.log(chalk.gray(`      └─ 所有: ${violation.owners.length > 0 ? violation.owners.join(', ') : 'なし（読み取りのみ）'}`));
+      });
+    }
+
+    const cycles = boundaryResult.domainMap.cycles ?? [];
+    if (cycles.length > 0) {
+      console.log(chalk.yellow(`\n🔁 境界間の依存サイクル: ${cycles.length}件`));
+      cycles.slice(0, 5).forEach(cycle => {
+        console.log(chalk.gray(`   - ${[...cycle.modules, cycle.modules[0]].join(' → ')}`));
+        console.log(chalk.gray(`      └─ 切断候補: ${cycle.break_at.from} → ${cycle.break_at.to} (${cycle.break_at.calls}箇所、${cycle.break_at.remedy === 'interface' ? 'インターフェース' : 'イベント'})`));
+      });
+    }
+
+    const hiddenCoupling = (boundaryResult.domainMap.global_coupling ?? []).filter(coupling => coupling.exclusive);
+    if (hiddenCoupling.length > 0) {
+      console.log(chalk.yellow(`\n🌐 グローバル変数・init() だけで結合したパッケージ: ${hiddenCoupling.length}件`));
+      hiddenCoupling.slice(0, 5).forEach(coupling => {
+        const modules = coupling.from_module && coupling.to_module && coupling.from_module !== coupling.to_module
+          ? ` [${coupling.from_module} → ${coupling.to_module}]`
+          : '';
+        console.log(chalk.gray(`   - ${coupling.from} → ${coupling.to}${modules}`));
+        console.log(chalk.gray(`      └─ ${coupling.kind === 'global-state' ? '可変グローバル変数' : 'init() の副作用'}: ${coupling.symbols.join(', ')}`));
+      });
+      console.log(chalk.gray('   モジュールを分割すると状態や登録が共有されなくなるため、明示的な依存（引数・コンストラクタ）に置き換えてください'));
+    }
+
+    const sharedDependencies = (boundaryResult.domainMap.third_party ?? []).filter(dependency => dependency.shared);
+    if (sharedDependencies.length > 0) {
+      console.log(chalk.yellow(`\n📦 多くのモジュールが直接使うサードパーティ依存: ${sharedDependencies.length}件`));
+      sharedDependencies.slice(0, 5).forEach(dependency => {
+        console.log(chalk.gray(`   - ${dependency.name}: ${dependency.used_by.map(usage => usage.module).join(', ')}`));
+      });
+      console.log(chalk.gray('   リファクタリングでは各モジュールのアダプターの背後に閉じ込めてください'));
+    }
+
+    const orchestrators = boundaryResult.domainMap.orchestrators ?? [];
+    if (orchestrators.length > 0) {
+      console.log(chalk.yellow(`\n🎼 複数モジュールにまたがる関数（サーガ・オーケストレーター候補）: ${orchestrators.length}件`));
+      orchestrators.slice(0, 5).forEach(candidate => {
+        const kind = candidate.kind === 'saga' ? 'サーガ' : 'オーケストレーター';
+        console.log(chalk.gray(`   - ${candidate.function} (${candidate.file}) [${kind}]: ${candidate.touches.map(touch => touch.module).join(', ')}`));
+      });
+      console.log(chalk.gray('   どれか1つのモジュールに割り当てず、plan.md のサーガ・オーケストレーター候補を確認してください'));
+    }
+
+    if (boundaryResult.debtInventory) {
+      printDebtInventory(boundaryResult.debtInventory, options.debt ?? false);
+    }
+
     if (boundaryResult.discoveryMetrics.recommendations.length > 0) {
       console.log(chalk.yellow('\n💡 AI推奨事項:'));
       boundaryResult.discoveryMetrics.recommendations
@@ -72,175 +291,1924 @@ async function runAutomaticBoundaryDiscovery(projectRoot: string): Promise<void>
           console.log(chalk.gray(`      └─ ${rec.expected_benefit}`));
         });
     }
-    
-    const paths = new VibeFlowPaths(absolutePath);
-    console.log(chalk.green('\n📄 Generated files:'));
-    console.log(chalk.gray(`   - ${paths.getRelativePath(boundaryResult.outputPath)} (ドメインマップ)`));
-    console.log(chalk.gray(`   - ${paths.getRelativePath(paths.autoBoundaryReportPath)} (詳細レポート)`));
-    
-    console.log(chalk.cyan('\n✨ 次のステップ:'));
-    console.log(chalk.gray('   1. 生成されたドメインマップを確認'));
-    console.log(chalk.gray('   2. 必要に応じてvibeflow.config.yamlを作成'));
-    console.log(chalk.gray('   3. vf plan でアーキテクチャ設計を実行'));
-    console.log(chalk.gray('   4. vf refactor で実際のリファクタリングを実行'));
-    
+    
+    const paths = new VibeFlowPaths(absolutePath);
+    let graphPath: string | undefined;
+    if (options.graph) {
+      graphPath = paths.boundaryGraphPath(GRAPH_EXTENSIONS[options.graph]);
+      await fs.writeFile(graphPath, renderBoundaryGraph(buildBoundaryGraph(boundaryResult.domainMap), options.graph));
+    }
+    console.log(chalk.green('\n📄 Generated files:'));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(boundaryResult.outputPath)} (ドメインマップ)`));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(paths.autoBoundaryReportPath)} (詳細レポート)`));
+    if (graphPath) {
+      console.log(chalk.gray(`   - ${paths.getRelativePath(graphPath)} (モジュールグラフ: ${options.graph})`));
+    }
+    if (cycles.length > 0) {
+      console.log(chalk.gray(`   - ${paths.getRelativePath(paths.boundaryCyclesPath)} (依存サイクル)`));
+    }
+    if (boundaryResult.debtInventory) {
+      console.log(chalk.gray(`   - ${paths.getRelativePath(new DebtInventoryScanner(absolutePath, boundaryResult.debtInventory.markers).reportPath)} (技術的負債)`));
+    }
+    
+    console.log(chalk.cyan('\n✨ 次のステップ:'));
+    console.log(chalk.gray('   1. 生成されたドメインマップを確認'));
+    if (sampling) {
+      console.log(chalk.gray('   2. vf plan で探索用のアーキテクチャ設計を確認'));
+      console.log(chalk.gray('   3. --sample を上げて再実行 (解析済みのファイルはキャッシュを再利用)'));
+      console.log(chalk.gray('   4. サンプリングなしの vf discover と vf plan の後に vf refactor を実行'));
+    } else {
+      console.log(chalk.gray('   2. 必要に応じてvibeflow.config.yamlを作成'));
+      console.log(chalk.gray('   3. vf plan でアーキテクチャ設計を実行'));
+      console.log(chalk.gray('   4. vf refactor で実際のリファクタリングを実行'));
+    }
+
+    const gates = { ...ConfigLoader.loadBoundaryConfig(path.join(absolutePath, 'boundary.yaml'))?.gates, ...options.gates };
+    if (hasDiscoveryGates(gates)) {
+      if (sampling) {
+        console.log(chalk.yellow('\n⏭️  品質ゲートをスキップしました: --sample の分析は一部のファイルだけのため評価しません'));
+      } else {
+        const report = checkDiscoveryGates(boundaryResult.domainMap, gates);
+        paths.writeArtifact(paths.discoveryGatesPath, report);
+        formatUnmeasuredGates(report).forEach(line => console.log(chalk.yellow(`\n⚠️  品質ゲート ${line}`)));
+        if (report.passed) {
+          console.log(chalk.green(`\n✅ 品質ゲート: ${report.modules}モジュールすべて合格`));
+        } else {
+          console.log(chalk.red(`\n❌ 品質ゲート: ${report.violations.length}件の違反`));
+          formatGateViolations(report).forEach(line => console.log(chalk.red(`   - ${line}`)));
+          console.log(chalk.gray(`   詳細: ${paths.getRelativePath(paths.discoveryGatesPath)}`));
+          process.exitCode = 1;
+        }
+      }
+    }
+
+    return boundaryResult.domainMap;
+  } catch (error) {
+    if (runId !== undefined) {
+      try {
+        performanceStore.finishRun(runId, { status: 'failed', error: String(error) });
+      } catch {
+        // Ignore metrics failures while reporting the original error
+      }
+    }
+    console.error(chalk.red('❌ Error in automatic boundary discovery:'), error);
+    throw error;
+  }
+}
+
+/**
+ * Discover each scope as a project of its own (domain map under <scope>/.vibeflow/)
+ * and report the references that cross scopes in .vibeflow/scope-report.json
+ */
+async function runScopedDiscovery(
+  projectRoot: string,
+  scopes: string[],
+  options: { debt?: boolean; sampling?: SamplingOptions; reconsiderEstablished?: string[]; full?: boolean; graph?: GraphFormat; concurrency?: number; granularity?: GranularityOptions; gates?: DiscoveryGates }
+): Promise<void> {
+  console.log(chalk.blue(`🧭 スコープ別の境界発見: ${scopes.join(', ')}`));
+
+  const domainMaps: DomainMap[] = [];
+  for (const scope of scopes) {
+    console.log(chalk.cyan(`\n▶ スコープ ${scope}`));
+    domainMaps.push(await discoverProject(path.join(projectRoot, scope), {
+      debt: options.debt,
+      sampling: options.sampling,
+      scope,
+      reconsiderEstablished: options.reconsiderEstablished,
+      full: options.full,
+      graph: options.graph,
+      concurrency: options.concurrency,
+      granularity: options.granularity,
+      gates: options.gates,
+    }));
+  }
+
+  const edges = findScopeEdges(projectRoot, scopes);
+  const paths = new VibeFlowPaths(projectRoot);
+  const report: ScopeReport = {
+    generated_at: new Date().toISOString(),
+    scopes: scopes.map((scope, i) => ({
+      scope,
+      domain_map: paths.toPortablePath(new VibeFlowPaths(path.join(projectRoot, scope)).domainMapPath),
+      boundaries: domainMaps[i].boundaries.length,
+      files: domainMaps[i].total_files,
+      external_edges: edges.filter(edge => edge.from_scope === scope).length,
+    })),
+    edges,
+  };
+  paths.writeArtifact(paths.scopeReportPath, report);
+
+  console.log(chalk.cyan('\n🧭 スコープ別サマリ:'));
+  report.scopes.forEach(summary => {
+    console.log(chalk.gray(`   - ${summary.scope}: 境界${summary.boundaries}個, ${summary.files}ファイル, スコープ外への参照${summary.external_edges}件 (${summary.domain_map})`));
+  });
+  if (edges.length > 0) {
+    console.log(chalk.yellow(`\n⚠️  スコープ外への参照 (external to scope): ${edges.length}件`));
+    edges.slice(0, 10).forEach(edge => {
+      console.log(chalk.gray(`   - ${edge.from_scope} → ${edge.to_scope ?? '(どのスコープにも属さない)'}: ${edge.import_path} (${edge.files.length}ファイル)`));
+    });
+  }
+  console.log(chalk.gray(`\n📄 ${paths.getRelativePath(paths.scopeReportPath)} (スコープをまたぐ参照)`));
+  console.log(chalk.gray('   vf plan / refactor / check / metrics は --scope <dir> でスコープを選択します'));
+}
+
+/**
+ * Root of the project a command works on, honoring --scope and the configured scopes
+ */
+function scopedRoot(pathParam: string, scope?: string): string {
+  try {
+    return resolveScopeRoot(path.resolve(pathParam), scope);
+  } catch (error) {
+    console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+    process.exit(1);
+  }
+}
+
+/**
+ * Names of a comma-separated option such as --reconsider-established auth,billing
+ */
+function parseModuleList(value: string | undefined): string[] | undefined {
+  return value?.split(',').map(name => name.trim()).filter(Boolean);
+}
+
+/**
+ * Styles of --compare clean,hexagonal: at least two distinct ones, and no --style alongside
+ */
+function parseCompareStyles(value: string, style: string | undefined): ArchitectureStyle[] {
+  if (style) throw new Error('--compare designs a plan per style; drop --style');
+  const styles = [...new Set((parseModuleList(value) ?? []).map(parseArchitectureStyle))];
+  if (styles.length < 2) throw new Error(`--compare needs at least two different styles (got '${value}')`);
+  return styles;
+}
+
+/**
+ * Debt summary after discovery; with --debt also the worst modules and files with file:line
+ */
+function printDebtInventory(inventory: DebtInventory, detailed: boolean): void {
+  const { totals } = inventory;
+  console.log(chalk.cyan(`\n🧾 技術的負債: ${totals.source}件 (テスト ${totals.test}件, 生成コード ${totals.generated}件, vendor ${totals.vendor}件は別集計)`));
+  if (!detailed) {
+    if (totals.source > 0) console.log(chalk.gray('   詳細は vf discover --debt'));
+    return;
+  }
+
+  const formatCategories = (byCategory: Record<string, number>) => Object.entries(byCategory)
+    .sort(([, a], [, b]) => b - a)
+    .map(([category, count]) => `${category} ${count}`)
+    .join(', ');
+
+  const modules = inventory.modules.filter(m => m.total > 0).sort((a, b) => b.total - a.total).slice(0, 10);
+  if (modules.length > 0) {
+    console.log(chalk.cyan('\n   モジュール別:'));
+    modules.forEach((module, i) => {
+      console.log(chalk.gray(`   ${i + 1}. ${module.module}: ${module.total}件 (${formatCategories(module.by_category)})`));
+    });
+  }
+  if (totals.unassigned > 0) {
+    console.log(chalk.gray(`   境界外: ${totals.unassigned}件`));
+  }
+
+  const files = topDebtFiles(inventory);
+  if (files.length > 0) {
+    console.log(chalk.cyan('\n   ファイル別:'));
+    for (const file of files) {
+      console.log(chalk.gray(`   ${file.file} (${file.items.length}件${file.module ? `, ${file.module}` : ''})`));
+      for (const item of file.items.slice(0, 5)) {
+        console.log(chalk.gray(`      └─ ${item.file}:${item.line} ${item.marker} [${item.category}] ${item.text}`));
+      }
+      if (file.items.length > 5) {
+        console.log(chalk.gray(`      └─ ...他${file.items.length - 5}件`));
+      }
+    }
+  }
+}
+
+async function planTasks(
+  projectRoot: string,
+  options: { deployments?: Record<string, ModuleDeployment>; style?: ArchitectureStyle; target?: PlanTarget; migration?: MigrationMode; goModules?: GoModuleLayout; reconsiderEstablished?: string[]; c4?: C4Format } = {}
+): Promise<void> {
+  const absolutePath = path.resolve(projectRoot);
+  
+  // Verify project exists
+  try {
+    await fs.access(absolutePath);
+  } catch {
+    throw new Error(`Project directory not found: ${absolutePath}`);
+  }
+
+  console.log(chalk.blue(`📂 Analyzing project: ${absolutePath}`));
+  
+  try {
+    // 1. Enhanced Boundary Analysis (AI + Manual)
+    const enhancedBoundaryAgent = new EnhancedBoundaryAgent(absolutePath, undefined, undefined, {
+      reconsiderEstablished: options.reconsiderEstablished,
+    });
+    const boundaryResult = await enhancedBoundaryAgent.analyzeBoundaries();
+    
+    // 2. Architectural Design
+    const architectAgent = new ArchitectAgent(absolutePath);
+    const architectResult = await architectAgent.generateArchitecturalPlan(boundaryResult.outputPath, {
+      deployments: options.deployments,
+      style: options.style,
+      target: options.target,
+      migration: options.migration,
+      goModules: options.goModules,
+    });
+    
+    const planPaths = new VibeFlowPaths(absolutePath);
+    recordModuleStage(absolutePath, architectResult.plan.modules, 'planned');
+    const diagramPaths: string[] = [];
+    if (options.c4) {
+      const model = buildC4Model(architectResult.plan, path.basename(absolutePath));
+      for (const diagram of renderC4Diagrams(model, options.c4)) {
+        const diagramPath = planPaths.c4DiagramPath(diagram.name, C4_EXTENSIONS[options.c4]);
+        await fs.writeFile(diagramPath, diagram.content);
+        diagramPaths.push(diagramPath);
+      }
+    }
+    console.log(chalk.green('✅ Plan generation complete!'));
+    console.log(chalk.gray('📄 Generated files:'));
+    console.log(chalk.gray(`   - ${planPaths.getRelativePath(boundaryResult.outputPath)}`));
+    console.log(chalk.gray(`   - ${planPaths.getRelativePath(architectResult.outputPath)}`));
+    console.log(chalk.gray(`   - ${planPaths.getRelativePath(architectResult.jsonPath)}`));
+    diagramPaths.forEach(diagramPath => {
+      console.log(chalk.gray(`   - ${planPaths.getRelativePath(diagramPath)} (C4: ${options.c4})`));
+    });
+    
+    const violations = architectResult.plan.constraint_violations;
+    if (violations.length > 0) {
+      console.log(chalk.yellow(`\n⚠️  境界制約違反: ${violations.length}件`));
+      violations.forEach(v => {
+        console.log(chalk.yellow(`   - [${v.constraint}] ${v.message}${v.reason ? ` (${v.reason})` : ''}`));
+      });
+    }
+    
+    // Display AI discovery results
+    if (boundaryResult.autoDiscoveredBoundaries.length > 0) {
+      console.log(chalk.cyan('\n🤖 AI自動境界発見結果:'));
+      console.log(chalk.gray(`   発見された境界: ${boundaryResult.autoDiscoveredBoundaries.length}個`));
+      console.log(chalk.gray(`   全体信頼度: ${boundaryResult.discoveryMetrics.confidence_metrics.overall_confidence.toFixed(1)}%`));
+      
+      if (boundaryResult.hybridRecommendations.length > 0) {
+        console.log(chalk.yellow(`   推奨事項: ${boundaryResult.hybridRecommendations.length}個`));
+        boundaryResult.hybridRecommendations.slice(0, 3).forEach((rec, i) => {
+          console.log(chalk.gray(`     ${i + 1}. ${rec.action}`));
+        });
+      }
+    }
+    
+  } catch (error) {
+    console.error(chalk.red('❌ Error in plan generation:'), error);
+    throw error;
+  }
+}
+
+/**
+ * vf plan --compare: design a plan per candidate style and compare them side by side;
+ * plan.md and plan.json are left as they are
+ */
+async function comparePlanTasks(
+  projectRoot: string,
+  styles: ArchitectureStyle[],
+  options: { deployments?: Record<string, ModuleDeployment>; target?: PlanTarget; migration?: MigrationMode; goModules?: GoModuleLayout; reconsiderEstablished?: string[] } = {}
+): Promise<void> {
+  const absolutePath = path.resolve(projectRoot);
+  try {
+    await fs.access(absolutePath);
+  } catch {
+    throw new Error(`Project directory not found: ${absolutePath}`);
+  }
+  console.log(chalk.blue(`📂 Analyzing project: ${absolutePath}`));
+
+  const boundaryResult = await new EnhancedBoundaryAgent(absolutePath, undefined, undefined, {
+    reconsiderEstablished: options.reconsiderEstablished,
+  }).analyzeBoundaries();
+  const result = new ArchitectAgent(absolutePath).compareArchitectures(boundaryResult.outputPath, styles, {
+    deployments: options.deployments,
+    target: options.target,
+    migration: options.migration,
+    goModules: options.goModules,
+  });
+
+  const paths = new VibeFlowPaths(absolutePath);
+  const candidates = result.comparison.candidates;
+  const row = (label: string, value: (candidate: ArchitectureCandidate) => string) =>
+    console.log(`   ${label.padEnd(24)}${candidates.map(candidate => value(candidate).padStart(16)).join('')}`);
+  console.log(chalk.green(`\n✅ Compared ${styles.join(', ')}:`));
+  row('', c => c.style);
+  row('Files moved', c => `${c.files_moved}`);
+  row('Files created', c => `${c.files_created}`);
+  row('Packages created', c => `${c.packages_created}`);
+  row('Interfaces created', c => `${c.interfaces_created}`);
+  row('Estimated tokens', c => (c.tokens.input + c.tokens.output).toLocaleString('en-US'));
+  row('Estimated cost', c => `$${c.cost_usd.toFixed(2)}`);
+  row('Effort (days)', c => (c.effort_days !== undefined ? `${c.effort_days}` : '-'));
+  row('Average risk', c => (c.risk_score !== undefined ? `${c.risk_score}` : '-'));
+  row('Manual-only modules', c => `${c.manual_only}`);
+  console.log(chalk.gray('📄 Generated files:'));
+  console.log(chalk.gray(`   - ${paths.getRelativePath(result.outputPath)}`));
+  result.candidates.forEach(candidate => {
+    console.log(chalk.gray(`   - ${paths.getRelativePath(candidate.markdownPath)}, ${paths.getRelativePath(candidate.jsonPath)}`));
+  });
+  console.log(chalk.gray(`Adopt a style with: vf plan --style <${styles.join('|')}>`));
+}
+
+/**
+ * vf plan sync: carry the structured edits of plan.md over to plan.json
+ */
+function runPlanSync(projectRoot: string, options: { force?: boolean }): void {
+  const paths = new VibeFlowPaths(projectRoot);
+  const result = new ArchitectAgent(projectRoot).syncPlanMarkdown(options);
+
+  if (result.applied.length === 0 && result.conflicts.length === 0 && result.uncaptured.length === 0) {
+    console.log(chalk.green('✅ plan.md and plan.json are in sync'));
+    return;
+  }
+
+  if (result.applied.length > 0) {
+    console.log(chalk.green(`✅ Applied ${result.applied.length} edit(s) of plan.md to ${paths.getRelativePath(paths.planJsonPath)}:`));
+    result.applied.forEach(change => {
+      console.log(`   ${change.field}: ${change.from.replace(/\n/g, ', ')} → ${change.to.replace(/\n/g, ', ')}`);
+    });
+  }
+  if (result.domainMapUpdates.length > 0) {
+    console.log(chalk.gray(`   Module names and files mirrored to ${paths.getRelativePath(paths.domainMapPath)}: ${result.domainMapUpdates.join(', ')}`));
+  }
+
+  if (result.uncaptured.length > 0) {
+    console.log(chalk.yellow(`⚠️  ${result.uncaptured.length} edit(s) inside generated blocks could not be carried over:`));
+    result.uncaptured.forEach(edit => {
+      console.log(chalk.yellow(`   ${edit.block}: ${edit.reason}`));
+      edit.lines.forEach(line => console.log(chalk.gray(`     ${line}`)));
+    });
+    console.log(chalk.gray('   Move free text outside the <!-- vf:generated --> blocks to keep it'));
+  }
+
+  if (result.conflicts.length > 0) {
+    console.log(chalk.red(`❌ ${result.conflicts.length} field(s) changed in both plan.md and plan.json since the last sync:`));
+    result.conflicts.forEach(conflict => {
+      console.log(chalk.red(`   ${conflict.field}`));
+      console.log(chalk.gray(`     plan.md:   ${conflict.markdown.replace(/\n/g, ', ')}`));
+      console.log(chalk.gray(`     plan.json: ${conflict.json.replace(/\n/g, ', ')}`));
+    });
+    console.log(chalk.gray('   Make both files agree and run vf plan sync again'));
+  }
+
+  if (result.markdown !== null) {
+    console.log(chalk.gray(`   Regenerated ${paths.getRelativePath(paths.planPath)} (text outside generated blocks kept)`));
+  } else {
+    console.log(chalk.gray(`   ${paths.getRelativePath(paths.planPath)} was left as is${result.conflicts.length === 0 ? '; pass --force to regenerate it and drop the edits above' : ''}`));
+  }
+  if (result.conflicts.length > 0) process.exit(1);
+}
+
+/**
+ * vf plan validate: whether a hand-edited plan.json still matches domain-map.json
+ * and is consistent in itself, before vf refactor starts from it
+ */
+async function runPlanValidate(projectRoot: string): Promise<void> {
+  const { validatePlan } = await import('./core/utils/plan-validation.js');
+  const { applyCuratedModules } = await import('./core/utils/boundary-curation.js');
+  const paths = new VibeFlowPaths(projectRoot);
+
+  let content: string;
+  try {
+    content = await fs.readFile(paths.planJsonPath, 'utf8');
+  } catch {
+    throw new Error(`${paths.getRelativePath(paths.planJsonPath)} not found (run "vf plan" first)`);
+  }
+  const plan = parsePlan(content, paths.getRelativePath(paths.planJsonPath)) as unknown as ArchitecturalPlan;
+  let domainMap: string;
+  try {
+    domainMap = await fs.readFile(paths.domainMapPath, 'utf8');
+  } catch {
+    throw new Error(`${paths.getRelativePath(paths.domainMapPath)} not found (run "vf discover" first)`);
+  }
+  const { map } = parseDomainMap(domainMap, paths.getRelativePath(paths.domainMapPath));
+  const boundaryConfig = ConfigLoader.loadBoundaryConfig(path.join(projectRoot, 'boundary.yaml'));
+  const { boundaries } = applyCuratedModules(projectRoot, map.boundaries, boundaryConfig?.modules);
+  const markdown = await fs.readFile(paths.planPath, 'utf8').catch(() => undefined);
+
+  const issues = validatePlan(plan, boundaries, { markdown });
+  if ((plan.schema_version ?? PLAN_SCHEMA_VERSION) > PLAN_SCHEMA_VERSION) {
+    issues.unshift({ severity: 'error', subject: 'plan.json', message: `schema version ${plan.schema_version}; this vibeflow reads up to ${PLAN_SCHEMA_VERSION}` });
+  }
+
+  const errors = issues.filter(issue => issue.severity === 'error');
+  const warnings = issues.filter(issue => issue.severity === 'warning');
+  errors.forEach(issue => console.log(chalk.red(`❌ ${issue.subject}: ${issue.message}`)));
+  warnings.forEach(issue => console.log(chalk.yellow(`⚠️  ${issue.subject}: ${issue.message}`)));
+  if (errors.length > 0) {
+    console.log(chalk.red(`❌ ${errors.length} error(s): fix plan.md and run vf plan sync, or rerun vf plan, before vf refactor`));
+    process.exit(1);
+  }
+  console.log(chalk.green(`✅ Plan is consistent with ${paths.getRelativePath(paths.domainMapPath)} (${plan.modules.length} modules, ${plan.migration_strategy.phases.length} phases${warnings.length > 0 ? `, ${warnings.length} warning(s)` : ''})`));
+}
+
+/**
+ * plan.json for the commands checking it; exits when it is missing, unparsable or missing fields they read
+ */
+async function loadPlanOrExit(planPaths: VibeFlowPaths): Promise<ArchitecturalPlan> {
+  const { planStructureIssues } = await import('./core/utils/plan-validation.js');
+  let plan: ArchitecturalPlan;
+  try {
+    plan = parsePlan(await fs.readFile(planPaths.planJsonPath, 'utf8'), planPaths.getRelativePath(planPaths.planJsonPath)) as unknown as ArchitecturalPlan;
+  } catch (error) {
+    if ((error as NodeJS.ErrnoException).code === 'ENOENT') {
+      console.error(chalk.red(`❌ plan.json not found: ${planPaths.planJsonPath}`));
+      console.error(chalk.gray('   Run "vf plan" first'));
+    } else {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+    }
+    process.exit(1);
+  }
+
+  const issues = planStructureIssues(plan);
+  if (issues.length > 0) {
+    issues.forEach(issue => console.error(chalk.red(`❌ ${issue.subject}: ${issue.message}`)));
+    console.error(chalk.gray(`   Fix ${planPaths.getRelativePath(planPaths.planJsonPath)} or rerun "vf plan"`));
+    process.exit(1);
+  }
+  return plan;
+}
+
+/**
+ * plan.json for the commands that also work without one; undefined only when it does not exist
+ */
+async function loadPlanIfPresent(planPaths: VibeFlowPaths): Promise<ArchitecturalPlan | undefined> {
+  try {
+    await fs.access(planPaths.planJsonPath);
+  } catch (error) {
+    if ((error as NodeJS.ErrnoException).code === 'ENOENT') return undefined;
+  }
+  return loadPlanOrExit(planPaths);
+}
+
+async function checkPlanConstraintsCommand(projectRoot: string): Promise<void> {
+  const absolutePath = path.resolve(projectRoot);
+  const planPaths = new VibeFlowPaths(absolutePath);
+  const { ConfigLoader } = await import('./core/utils/config-loader.js');
+
+  const plan = await loadPlanOrExit(planPaths);
+
+  const boundaryConfig = ConfigLoader.loadBoundaryConfig(path.join(absolutePath, 'boundary.yaml'));
+  if (!boundaryConfig?.constraints) {
+    console.log(chalk.gray('ℹ️  No constraints defined in boundary.yaml'));
+    return;
+  }
+
+  const violations = checkPlanConstraints(plan, boundaryConfig.constraints);
+  if (violations.length === 0) {
+    console.log(chalk.green(`✅ Plan satisfies all boundary constraints (${plan.modules.length} modules)`));
+    return;
+  }
+
+  const { constraintViolationFindings } = await import('./core/utils/findings.js');
+  const { formatLocation } = await import('./core/utils/source-positions.js');
+  const modules = plan.modules.map(m => ({ name: m.name, files: m.current_state.files }));
+
+  console.log(chalk.red(`❌ ${violations.length} constraint violation(s):`));
+  violations.forEach(v => {
+    console.log(chalk.red(`   - [${v.constraint}] ${v.message}`));
+    constraintViolationFindings(absolutePath, [v], modules).forEach(finding => {
+      console.log(chalk.gray(`     at ${formatLocation(finding.location)}`));
+    });
+  });
+  process.exit(1);
+}
+
+/**
+ * vf check: boundary constraints of plan.json plus the stricter rules of
+ * modules marked `deployment: service`, against the current code
+ */
+async function runCheck(projectRoot: string): Promise<void> {
+  const planPaths = new VibeFlowPaths(projectRoot);
+  const { constraintViolationFindings, serviceRuleFindings, formatFinding } = await import('./core/utils/findings.js');
+  const { analyzeServiceRequirements } = await import('./core/utils/service-deployment.js');
+
+  const plan = await loadPlanOrExit(planPaths);
+
+  const modules = plan.modules.map(m => ({
+    name: m.name,
+    files: m.current_state.files,
+    deployment: m.deployment,
+    owned_tables: m.owned_tables,
+  }));
+  const findings: import('./core/utils/findings.js').Finding[] = [];
+
+  const boundaryConfig = ConfigLoader.loadBoundaryConfig(path.join(projectRoot, 'boundary.yaml'));
+  if (boundaryConfig?.constraints) {
+    const violations = checkPlanConstraints(plan, boundaryConfig.constraints);
+    findings.push(...constraintViolationFindings(projectRoot, violations, modules));
+  }
+
+  const planned = plan.modules.flatMap(m => m.service ?? []);
+  findings.push(...serviceRuleFindings(projectRoot, analyzeServiceRequirements(projectRoot, modules), planned));
+
+  const services = modules.filter(m => m.deployment === 'service').map(m => m.name);
+  console.log(chalk.blue(`🔎 ${plan.modules.length} modules${services.length > 0 ? ` (services: ${services.join(', ')})` : ''}`));
+  for (const finding of findings) {
+    const line = formatFinding(finding);
+    console.log(finding.severity === 'error' ? chalk.red(line) : finding.severity === 'warning' ? chalk.yellow(line) : chalk.gray(line));
+  }
+
+  const errors = findings.filter(f => f.severity === 'error').length;
+  if (errors > 0) {
+    console.log(chalk.red(`❌ ${errors} error(s)`));
+    process.exit(1);
+  }
+  console.log(chalk.green(`✅ Check passed${findings.length > 0 ? ` (${findings.length} warning(s))` : ''}`));
+}
+
+/**
+ * vf validate: //vf: annotations in the source, checked for unknown directives,
+ * misplaced ones and boundaries missing from domain-map.json and boundary.yaml
+ */
+async function runValidate(projectRoot: string): Promise<void> {
+  const { collectProjectAnnotations } = await import('./core/utils/source-annotations.js');
+  const planPaths = new VibeFlowPaths(projectRoot);
+  const { annotations, problems } = collectProjectAnnotations(projectRoot);
+
+  const known = new Set<string>();
+  try {
+    const { map } = parseDomainMap(await fs.readFile(planPaths.domainMapPath, 'utf8'), planPaths.domainMapPath);
+    map.boundaries.forEach(b => {
+      known.add(b.name);
+      if (b.id) known.add(b.id);
+    });
+  } catch {
+    // No domain map yet; boundary.yaml alone names the boundaries
+  }
+  const boundaryConfig = ConfigLoader.loadBoundaryConfig(path.join(projectRoot, 'boundary.yaml'));
+  Object.keys(boundaryConfig?.modules ?? {}).forEach(name => known.add(name));
+
+  if (known.size === 0) {
+    console.log(chalk.gray('ℹ️  No domain-map.json or boundary.yaml - //vf:boundary names not checked (run "vf discover" first)'));
+  } else {
+    annotations
+      .filter(a => a.directive === 'boundary' && !known.has(a.argument!))
+      .forEach(a => problems.push({ file: a.file, line: a.line, message: `//vf:boundary ${a.argument}: no such boundary (known: ${[...known].sort().join(', ')})` }));
+  }
+
+  console.log(chalk.blue(`📌 ${annotations.length} annotations`));
+  if (problems.length === 0) {
+    console.log(chalk.green('✅ All annotations are valid'));
+    return;
+  }
+  problems
+    .sort((a, b) => a.file.localeCompare(b.file) || a.line - b.line)
+    .forEach(p => console.log(chalk.red(`${p.file}:${p.line}: ${p.message}`)));
+  console.log(chalk.red(`❌ ${problems.length} invalid annotation(s)`));
+  process.exit(1);
+}
+
+/**
+ * vf drift: how far the workspace has diverged from the accepted plan and the
+ * state captured by the last `vf verify`. The score is stored per run for trending.
+ */
+async function runDrift(projectRoot: string, opts: {
+  format?: string; output?: string; failAbove?: string; rules?: string; updateBaseline?: boolean;
+}): Promise<void> {
+  const { SHARED_KERNEL, analyzeDrift, captureDriftBaseline, formatDriftReport, ruleCatalogFindings } = await import('./core/utils/drift-report.js');
+  const { FindingsReporter, formatFinding, toSarif } = await import('./core/utils/findings.js');
+  const format = opts.format ?? 'text';
+  if (!['text', 'json', 'sarif'].includes(format)) {
+    throw new Error(`Unsupported format: ${format} (expected text, json or sarif)`);
+  }
+  const failAbove = opts.failAbove !== undefined ? Number(opts.failAbove) : undefined;
+  if (failAbove !== undefined && (!Number.isFinite(failAbove) || failAbove < 0 || failAbove > 100)) {
+    throw new Error(`Invalid --fail-above '${opts.failAbove}' (expected a score between 0 and 100)`);
+  }
+
+  const planPaths = new VibeFlowPaths(projectRoot);
+  const plan = await loadPlanOrExit(planPaths);
+
+  let rules: import('./core/utils/findings.js').Finding[];
+  if (opts.rules) {
+    const raw = JSON.parse(await fs.readFile(opts.rules, 'utf8'));
+    rules = ruleCatalogFindings(Array.isArray(raw) ? raw : raw.rules ?? []);
+  } else {
+    rules = (new FindingsReporter(projectRoot).load()?.findings ?? []).filter(f => f.kind === 'business-rule');
+  }
+
+  const store = new PerformanceStore(projectRoot);
+  const accepted = new ModuleStatusTracker(projectRoot, store).list()
+    .filter(view => view.stage === 'accepted')
+    .map(view => view.module);
+  const boundaryConfig = ConfigLoader.loadBoundaryConfig(path.join(projectRoot, 'boundary.yaml'));
+  const modules = driftModules(plan, SHARED_KERNEL);
+  const report = analyzeDrift(projectRoot, modules, { constraints: boundaryConfig?.constraints, rules, accepted });
+
+  const previous = store.getMetrics().filter(m => m.metric === 'drift_score').slice(-4).map(m => m.value);
+  const runId = store.startRun('drift', { modules_planned: plan.modules.length, files_processed: report.files, environment: captureRunEnvironment(projectRoot) });
+  store.recordMetric(runId, 'drift_score', report.score);
+  report.modules.forEach(m => store.recordMetric(runId, 'module_drift_percent', m.percent, { module: m.module }));
+  store.finishRun(runId, { status: 'success' });
+
+  if (format === 'text') {
+    console.log(chalk.blue(`🧭 Drift report (run ${runId})`));
+    console.log(formatDriftReport(report));
+    if (previous.length > 0) {
+      console.log(chalk.gray(`Trend: ${[...previous, report.score].map(score => `${score}%`).join(' → ')}`));
+    }
+    if (report.findings.length > 0) console.log('');
+    for (const finding of report.findings) {
+      const line = formatFinding(finding);
+      console.log(finding.severity === 'error' ? chalk.red(line) : finding.severity === 'warning' ? chalk.yellow(line) : chalk.gray(line));
+    }
+    if (rules.length === 0) {
+      console.log(chalk.gray('\nℹ️  No business rules catalog - run "vf report findings --business-rules" or pass --rules'));
+    }
+  }
+  const content = format === 'json'
+    ? JSON.stringify(report, null, 2)
+    : format === 'sarif' ? JSON.stringify(toSarif(report.findings), null, 2) : undefined;
+  if (content !== undefined && opts.output) {
+    await fs.writeFile(opts.output, content);
+    console.log(chalk.green(`✅ Drift report written: ${opts.output}`));
+  } else if (content !== undefined) {
+    process.stdout.write(`${content}\n`);
+  }
+
+  if (opts.updateBaseline) {
+    captureDriftBaseline(projectRoot, modules);
+    console.error(chalk.gray(`   Baseline updated: ${planPaths.driftBaselinePath}`));
+  }
+  if (failAbove !== undefined && report.score > failAbove) {
+    console.error(chalk.red(`❌ Drift score ${report.score}% is above ${failAbove}%`));
+    process.exit(1);
+  }
+}
+
+/**
+ * The plan's modules, and its shared kernel packages as the kernel pseudo module
+ */
+function driftModules(plan: ArchitecturalPlan, sharedKernel: string): import('./core/utils/drift-report.js').DriftModule[] {
+  return plan.modules.map(m => ({
+    name: m.name,
+    files: m.current_state.files,
+    dependencies: m.dependencies.map(dep => dep.module),
+  })).concat(plan.shared_kernel?.length
+    ? [{ name: sharedKernel, files: plan.shared_kernel.flatMap(pkg => pkg.files), dependencies: [] }]
+    : []);
+}
+
+async function runRefactor(projectRoot: string, apply: boolean, resumeOptions?: any, options: { mutationCheck?: boolean } = {}): Promise<void> {
+  const absolutePath = path.resolve(projectRoot);
+  const paths = new VibeFlowPaths(absolutePath);
+  
+  // Verify required files exist
+  const planPath = paths.planPath;
+  const domainMapPath = paths.domainMapPath;
+  
+  // Check for required files unless in test environment
+  if (process.env.NODE_ENV !== 'test' && process.env.VITEST !== 'true') {
+    try {
+      await fs.access(planPath);
+      await fs.access(paths.planJsonPath);
+      await fs.access(domainMapPath);
+    } catch {
+      throw new Error(
+        `Required files not found. Please run "vf plan" first to generate ${paths.getRelativePath(planPath)}, ${paths.getRelativePath(paths.planJsonPath)} and ${paths.getRelativePath(domainMapPath)}`
+      );
+    }
+  } else {
+    console.log(chalk.yellow('🔧 Test environment - skipping required file validation'));
+  }
+
+  console.log(chalk.blue(`🔧 Refactoring project: ${absolutePath}`));
+
+  // Record the run so that `vf metrics aggregate` can report on it
+  const performanceStore = new PerformanceStore(absolutePath);
+  let runId: number | undefined;
+  try {
+    const { map: domainMap } = parseDomainMap(await fs.readFile(domainMapPath, 'utf8'), domainMapPath);
+    const boundaries = domainMap.boundaries;
+    runId = performanceStore.startRun('refactor', {
+      modules_planned: boundaries.length,
+      environment: captureRunEnvironment(absolutePath),
+      loc: countLinesOfCode(absolutePath, boundaries.flatMap(b => b.files || [])),
+    });
+    captureRunArtifacts(absolutePath, performanceStore, runId);
+  } catch {
+    // Metrics are best-effort and must not block refactoring
+  }
+  
+  try {
+    // 1. Business Logic Migration (AI-powered)
+    console.log(chalk.blue('🧠 Step 1/5: AI-powered business logic migration...'));
+    const businessLogicAgent = new BusinessLogicMigrationAgent(absolutePath);
+    const businessLogicResult = await businessLogicAgent.execute({
+      projectPath: absolutePath,
+      domainMapPath: domainMapPath,
+      planPath: planPath,
+      aiEnabled: true,
+      language: 'go' as const, // TODO: Auto-detect language
+      preserveMode: 'strict',
+      generateTests: true,
+      generateDocumentation: true
+    });
+    
+    console.log(chalk.green(`✅ 業務ロジック移行完了: ${businessLogicResult.migratedBoundaries.length}個の境界を処理`));
+    for (const line of businessLogicMethodLines(businessLogicResult, '   ')) console.log(chalk.gray(line));
+    
+    // 2. Test Synthesis for files without tests
+    console.log(chalk.blue('🧪 Step 2/5: AI-powered test synthesis...'));
+    const testSynthesisAgent = new TestSynthesisAgent(absolutePath);
+    const testSynthesisResult = await testSynthesisAgent.execute({
+      projectPath: absolutePath,
+      language: 'go' as const,
+      outputPath: path.join(absolutePath, '__generated__/tests'),
+      documentationPath: path.join(absolutePath, '__generated__/docs'),
+      aiEnabled: true,
+      generateDocumentation: true,
+      localization: 'ja'
+    });
+    
+    console.log(chalk.green(`✅ テスト生成完了: ${testSynthesisResult.generatedTests.length}個のテスト, ${testSynthesisResult.generatedDocuments.length}個のドキュメント`));
+    
+    // 3. Generate refactoring patches
+    console.log(chalk.blue('🏗️  Step 3/5: Generating refactoring patches...'));
+    const refactorAgent = new RefactorAgent(absolutePath);
+    const refactorResult = await refactorAgent.generateRefactorPlan(paths.planJsonPath);
+    
+    // 4. Synthesize and relocate tests
+    console.log(chalk.blue('🔄 Step 4/5: Test relocation and synthesis...'));
+    const testSynthAgent = new TestSynthAgent(absolutePath);
+    const testSynthResult = await testSynthAgent.synthesizeTests(paths.patchesDir);
+    
+    // 5. Run migration (apply patches)
+    console.log(chalk.blue('🚀 Step 5/5: Applying patches and migration...'));
+    const migrationRunner = new MigrationRunner(absolutePath, undefined, !apply);
+    const migrationResult = await migrationRunner.executeMigration(paths.patchesDir, apply);
+    
+    // 6. Review changes
+    const reviewAgent = new ReviewAgent(absolutePath);
+    const reviewResult = await reviewAgent.reviewChanges(migrationResult.outputPath);
+
+    const mutationResults = options.mutationCheck ? runMutationChecks(absolutePath, testSynthResult, performanceStore, runId) : [];
+    
+    console.log(chalk.green('✅ AI-powered完全なリファクタリングパイプライン完了!'));
+    console.log(chalk.gray('📄 Generated files:'));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(refactorResult.outputPath)}/ (${refactorResult.plan.summary.total_patches} patches)`));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(testSynthResult.outputPath)} (${testSynthResult.generated_tests.length} tests)`));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(migrationResult.outputPath)} (migration results)`));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(reviewResult.outputPath)} (review report)`));
+    console.log(chalk.gray(`   - __generated__/tests/ (${testSynthesisResult.generatedTests.length} AI-generated tests)`));
+    console.log(chalk.gray(`   - __generated__/docs/ (${testSynthesisResult.generatedDocuments.length} user stories & specs)`));
+    
+    // Display key results
+    console.log(chalk.cyan('\n📊 実行結果サマリ:'));
+    console.log(chalk.gray(`   🧠 業務ロジック移行: ${businessLogicResult.migratedBoundaries.length}境界`));
+    for (const line of businessLogicMethodLines(businessLogicResult, '      ')) console.log(chalk.gray(line));
+    console.log(chalk.gray(`   🧪 AI生成テスト: ${testSynthesisResult.generatedTests.length}個 (カバレッジ向上推定: ${testSynthesisResult.coverageImprovement?.improvement || 'N/A'}%)`));
+    console.log(chalk.gray(`   📚 生成ドキュメント: ${testSynthesisResult.generatedDocuments.length}個のユーザーストーリー・仕様書`));
+    console.log(chalk.gray(`   🔄 テスト移行: ${testSynthResult.test_relocations.length}件 (ヘルパー import 書き換え: ${testSynthResult.helper_imports_rewritten}ファイル)`));
+    console.log(chalk.gray(`   🔄 パッチ適用: ${migrationResult.applied_patches.length}成功 / ${migrationResult.failed_patches.length}失敗`));
+    console.log(chalk.gray(`   ✅ ビルド: ${migrationResult.build_result.success ? '✅ 成功' : '❌ 失敗'}`));
+    console.log(chalk.gray(`   🧪 テスト: ${migrationResult.test_result.success ? '✅ 成功' : '❌ 失敗'}`));
+    for (const mutation of mutationResults) {
+      console.log(chalk.gray(`   🧬 ミューテーション (${mutation.module}): ${mutation.skipped ? `未実施 (${mutation.skipped})` : `kill率 ${formatKillRate(mutation.kill_rate)} (${mutation.killed}/${mutation.mutants})`}`));
+      const vacuous = mutation.tests.filter(t => t.vacuous);
+      if (vacuous.length > 0) {
+        console.log(chalk.yellow(`      ⚠️  何も検出しない生成テスト (優先レビュー): ${vacuous.map(t => t.file).join(', ')}`));
+      }
+    }
+    console.log(chalk.gray(`   📋 総合評価: ${reviewResult.overall_assessment.grade}グレード`));
+    console.log(chalk.gray(`   🤖 自動マージ: ${reviewResult.auto_merge_decision.should_auto_merge ? '✅ 可能' : '❌ 手動レビュー必要'}`));
+    
+    if (!apply) {
+      console.log(chalk.yellow('\nℹ️  ドライランモード - 実際の変更は行われていません'));
+      console.log(chalk.yellow('   --applyフラグで実際の変更を適用できます'));
+    }
+
+    if (apply) {
+      recordModuleStage(absolutePath, businessLogicResult.migratedBoundaries, 'refactored', runId);
+      const relocated = [...new Set(testSynthResult.test_relocations.map(r => r.module))];
+      recordModuleStage(absolutePath, relocated.map(name => ({ name })), 'tests-relocated', runId);
+    }
+
+    if (runId !== undefined) {
+      performanceStore.finishRun(runId, {
+        status: migrationResult.failed_patches.length > 0 ? 'partial' : 'success',
+        modules_migrated: businessLogicResult.migratedBoundaries.length,
+        files_processed: businessLogicResult.aiProcessedFiles + businessLogicResult.staticAnalysisFiles,
+      });
+    }
+    
+  } catch (error) {
+    if (runId !== undefined) {
+      try {
+        performanceStore.finishRun(runId, { status: 'failed', error: String(error) });
+      } catch {
+        // Ignore metrics failures while reporting the original error
+      }
+    }
+    console.error(chalk.red('❌ Error in refactor execution:'), error);
+    throw error;
+  }
+}
+
+/**
+ * Mutation smoke check of the modules test synthesis produced or relocated tests
+ * for; kill rates go to performance_metrics and .vibeflow/reports/mutation-<module>.json
+ */
+function runMutationChecks(projectRoot: string, testSynth: TestSynthResult, performanceStore: PerformanceStore, runId: number | undefined): MutationCheckResult[] {
+  const config = ConfigLoader.loadVibeFlowConfig(path.join(projectRoot, 'vibeflow.config.yaml')).tests?.mutation;
+  const generatedTests = [...testSynth.generated_tests.map(t => t.file), ...testSynth.test_relocations.map(r => r.new_location)];
+  const modules = [...new Set([
+    ...testSynth.test_relocations.map(r => r.module),
+    ...testSynth.generated_tests.map(t => t.file.match(/^internal\/([^/]+)\//)?.[1]).filter((m): m is string => m !== undefined),
+  ])].sort();
+  const paths = new VibeFlowPaths(projectRoot);
+
+  const results: MutationCheckResult[] = [];
+  for (const moduleName of modules) {
+    console.log(chalk.blue(`🧬 Mutation check: ${moduleName}`));
+    let result: MutationCheckResult;
+    try {
+      result = checkModuleMutations(projectRoot, moduleName, {
+        generatedTests,
+        maxMutants: config?.maxMutants,
+        timeoutSeconds: config?.timeout,
+      });
+    } catch (error) {
+      console.warn(chalk.yellow(`⚠️  Mutation check of ${moduleName} failed: ${getErrorMessage(error)}`));
+      continue;
+    }
+    results.push(result);
+    paths.writeArtifact(path.join(paths.reportsDir, `mutation-${moduleName}.json`), result);
+
+    if (runId === undefined || result.kill_rate === null) continue;
+    try {
+      performanceStore.recordMetric(runId, 'mutation_kill_rate', result.kill_rate, { module: moduleName });
+      for (const file of result.files.filter(f => f.kill_rate !== null)) {
+        performanceStore.recordMetric(runId, 'mutation_kill_rate', file.kill_rate!, { module: moduleName, file: file.file });
+      }
+      performanceStore.recordMetric(runId, 'vacuous_test_files', result.tests.filter(t => t.vacuous).length, { module: moduleName });
+    } catch {
+      // Metrics are best-effort
+    }
+  }
+  return results;
+}
+
+function formatKillRate(rate: number | null): string {
+  return rate === null ? '-' : `${Math.round(rate * 100)}%`;
+}
+
+/**
+ * Snapshot the inputs of a run and apply the metrics retention policy (metrics.retainRuns)
+ * to snapshots and saved failed responses
+ */
+function captureRunArtifacts(projectRoot: string, performanceStore: PerformanceStore, runId: number): void {
+  try {
+    let retainRuns: number | undefined;
+    try {
+      retainRuns = ConfigLoader.loadVibeFlowConfig(path.join(projectRoot, 'vibeflow.config.yaml')).metrics?.retainRuns;
+    } catch {
+      // Keep every run
+    }
+    if (retainRuns !== undefined) performanceStore.pruneRuns(retainRuns);
+
+    const artifactStore = new RunArtifactStore(projectRoot);
+    artifactStore.capture(runId);
+    const retained = performanceStore.getRuns().map(r => r.run_id);
+    artifactStore.prune(retained);
+    new FailedResponseStore(projectRoot).prune(retained);
+  } catch (error) {
+    console.warn(chalk.yellow(`⚠️  Run artifact snapshot skipped: ${getErrorMessage(error)}`));
+  }
+}
+
+/**
+ * Mark the run failed if the process is stopped (Ctrl+C, Ctrl+Break, console closed) before it finishes
+ */
+function markRunInterrupted(performanceStore: PerformanceStore, runId: number): () => void {
+  return onShutdown(reason => {
+    performanceStore.finishRun(runId, { status: 'failed', error: `Interrupted (${reason})`, current_module: undefined });
+    console.log(chalk.yellow(`\n⏹️  Run ${runId} marked as interrupted`));
+  });
+}
+
+/**
+ * Advance the lifecycle of modules after a successful step (best-effort)
+ */
+function recordModuleStage(projectRoot: string, modules: ModuleRef[], stage: AgentStage, runId?: number): void {
+  try {
+    const changed = new ModuleStatusTracker(projectRoot).advance(modules, stage, runId);
+    if (changed.length > 0) {
+      console.log(chalk.gray(`   Module status: ${changed.join(', ')} → ${stage}`));
+    }
+  } catch (error) {
+    console.warn(chalk.yellow(`⚠️  Module status not updated: ${getErrorMessage(error)}`));
+  }
+}
+
+/**
+ * Modules already accepted by a reviewer that may be overwritten: all with --reopen-accepted,
+ * otherwise those confirmed at the prompt (none without a terminal)
+ */
+async function confirmReopenAccepted(projectRoot: string, boundaries: ModuleRef[], reopenAccepted: boolean): Promise<Set<string>> {
+  let tracker: ModuleStatusTracker;
+  try {
+    tracker = new ModuleStatusTracker(projectRoot);
+  } catch {
+    return new Set();
+  }
+  const accepted = boundaries
+    .map(boundary => ({ name: boundary.name, status: tracker.find(boundary) }))
+    .filter(entry => entry.status?.stage === 'accepted');
+  if (accepted.length === 0 || reopenAccepted) return new Set(accepted.map(entry => entry.name));
+  if (!process.stdin.isTTY) return new Set();
+
+  const readline = await import('readline');
+  const rl = readline.createInterface({ input: process.stdin, output: process.stdout });
+  const ask = (question: string) => new Promise<string>(resolve => rl.question(question, resolve));
+  const approved = new Set<string>();
+  try {
+    for (const { name, status } of accepted) {
+      const by = status?.accepted_by ? ` by ${status.accepted_by}` : '';
+      const answer = await ask(chalk.yellow(`⚠️  ${name} was accepted${by}. Overwrite it and reset its status to refactored? [y/N] `));
+      if (answer.trim().toLowerCase() === 'y') approved.add(name);
+    }
+  } finally {
+    rl.close();
+  }
+  return approved;
+}
+
+async function runModuleRefactor(projectRoot: string, moduleNames: string[], options: {
+  apply: boolean;
+  cleanModule: boolean;
+  commit?: boolean;
+  allowDegraded?: boolean;
+  generationMode?: GenerationMode;
+  reopenAccepted?: boolean;
+  escalation?: boolean;
+  /** Submit the prompts as async batch jobs (--async-batch), or answer them from collected results */
+  batchProvider?: BatchProvider;
+  /** Run whose batch results are being collected (--collect) */
+  collect?: BatchRunState;
+  /** Highest risk tier applied without --force (default: autonomy.maxAutoApplyTier) */
+  maxAutoApplyTier?: AutonomyTier;
+  force?: boolean;
+}): Promise<void> {
+  const paths = new VibeFlowPaths(projectRoot);
+
+  let content: string;
+  try {
+    content = await fs.readFile(paths.domainMapPath, 'utf8');
+  } catch {
+    throw new Error(`Domain map not found. Please run "vf plan" first to generate ${paths.getRelativePath(paths.domainMapPath)}`);
+  }
+  const { map: domainMap, invalid } = parseDomainMap(content, paths.getRelativePath(paths.domainMapPath));
+
+  // A malformed entry fails only that module; the others still run
+  const invalidModules = invalid.filter(entry => entry.name && moduleNames.includes(entry.name));
+  for (const entry of invalidModules) {
+    console.error(chalk.red(`❌ Module "${entry.name}" has an invalid domain map entry: ${entry.error}`));
+  }
+  const boundaries = moduleNames
+    .filter(moduleName => !invalidModules.some(entry => entry.name === moduleName))
+    .map(moduleName => {
+      const boundary = domainMap.boundaries.find(b => b.name === moduleName);
+      if (!boundary) {
+        const available = domainMap.boundaries.map(b => b.name).join(', ');
+        throw new Error(`Module "${moduleName}" not found in domain map. Available: ${available}`);
+      }
+      return boundary;
+    });
+  if (boundaries.length === 0) {
+    throw new Error('No valid modules to refactor');
+  }
+  if (invalidModules.length > 0) {
+    process.exitCode = 1;
+  }
+
+  console.log(chalk.blue(`🔧 Refactoring module: ${moduleNames.join(', ')}${options.cleanModule ? ' (clean)' : ''}`));
+  const reopened = options.apply ? await confirmReopenAccepted(projectRoot, boundaries, options.reopenAccepted ?? false) : new Set<string>();
+
+  // Track the run so the current module can be skipped from another terminal
+  const performanceStore = new PerformanceStore(projectRoot);
+  let runId: number | undefined;
+  try {
+    if (options.collect) {
+      // Collected results continue the run that submitted them
+      runId = options.collect.run_id;
+      performanceStore.finishRun(runId, { status: 'running', finished_at: undefined });
+    } else {
+      runId = performanceStore.startRun('refactor-module', { modules_planned: boundaries.length, environment: captureRunEnvironment(projectRoot) });
+      captureRunArtifacts(projectRoot, performanceStore, runId);
+    }
+  } catch {
+    // Metrics are best-effort and must not block refactoring
+  }
+  if (options.batchProvider && runId === undefined) {
+    throw new Error('Async batch runs need a run record (.vibeflow/performance.json could not be written)');
+  }
+  const batch = options.batchProvider ? new LlmBatchSession(projectRoot, runId!, options.batchProvider.discount) : undefined;
+
+  const refactorAgent = new RefactorAgent(projectRoot, options.generationMode);
+  const stopShutdownWatch = runId !== undefined ? markRunInterrupted(performanceStore, runId) : () => {};
+  const stopKeypress = refactorAgent.skipController.watchKeypress();
+  const stopRunWatch = runId !== undefined
+    ? refactorAgent.skipController.watchRunRecord(performanceStore, runId)
+    : () => {};
+  if (process.stdin.isTTY) {
+    console.log(chalk.gray('   Press "s" to skip the module currently being processed'));
+  }
+  if (runId !== undefined) {
+    console.log(chalk.gray(`   Run ID: ${runId} (vf control skip-module --run-id ${runId})`));
+  }
+
+  let result;
+  try {
+    result = await refactorAgent.executeRefactoring(boundaries, options.apply, {
+      cleanModule: options.cleanModule,
+      allowDegraded: options.allowDegraded,
+      confirmReopen: async moduleName => reopened.has(moduleName),
+      escalation: options.escalation,
+      batch,
+      maxAutoApplyTier: options.maxAutoApplyTier,
+      force: options.force,
+    });
+  } catch (error) {
+    if (runId !== undefined) {
+      try {
+        performanceStore.finishRun(runId, { status: 'failed', error: String(error), current_module: undefined });
+      } catch {
+        // Ignore metrics failures while reporting the original error
+      }
+    }
+    throw error;
+  } finally {
+    stopKeypress();
+    stopRunWatch();
+    stopShutdownWatch();
+  }
+
+  const skipped = result.skipped_modules ?? [];
+  const fallbacks = result.fallback_modules ?? [];
+  const escalations = result.escalations ?? [];
+  const pending = result.pending_modules ?? [];
+  const batchState = batch && options.batchProvider
+    ? await advanceBatchRun(options.batchProvider, batch, options.collect ?? {
+      run_id: runId!,
+      provider: options.batchProvider.name,
+      discount: options.batchProvider.discount,
+      options: {
+        apply: options.apply,
+        cleanModule: options.cleanModule,
+        allowDegraded: options.allowDegraded ?? false,
+        escalation: options.escalation ?? true,
+        commit: options.commit ?? false,
+        reopenAccepted: options.reopenAccepted ?? false,
+        ...(options.maxAutoApplyTier ? { maxAutoApplyTier: options.maxAutoApplyTier } : {}),
+        ...(options.force ? { force: true } : {}),
+      },
+      modules: [],
+      jobs: [],
+    }, boundaries.map(b => b.name), pending)
+    : undefined;
+  if (runId !== undefined) {
+    try {
+      const previous = options.collect ? performanceStore.getRun(runId) : undefined;
+      performanceStore.recordMetric(runId, 'template_fallback_modules', fallbacks.length);
+      if (batch) {
+        // What the batch discount saved over synchronous calls
+        const batched = performanceStore.getLlmCalls(runId).filter(call => call.batch_job !== undefined);
+        const savings = batched.reduce((sum, call) => sum + call.cost / (1 - (call.batch_discount ?? 0)) - call.cost, 0);
+        performanceStore.recordMetric(runId, 'batch_discount_savings', savings);
+      }
+      performanceStore.finishRun(runId, {
+        status: result.failed_patches.length > 0 || skipped.length > 0 || pending.length > 0 || invalidModules.length > 0 ? 'partial' : 'success',
+        modules_migrated: (previous?.modules_migrated ?? 0) + boundaries.length - skipped.length - pending.length,
+        files_processed: (previous?.files_processed ?? 0) + result.applied_patches.length,
+        current_module: undefined,
+        ...(batchState ? { batch: batchState.modules.length > 0 ? 'pending' as const : 'collected' as const } : {}),
+      });
+    } catch {
+      // Metrics are best-effort
+    }
+  }
+
+  if (result.deleted_files.length > 0) {
+    console.log(chalk.gray(`   🗑️  Removed ${result.deleted_files.length} outputs from previous attempts`));
+  }
+  if (fallbacks.length > 0) {
+    console.log(chalk.yellow.bold(`\n⚠️  ${fallbacks.length} module(s) downgraded to templates because the LLM was unavailable:`));
+    for (const fallback of fallbacks) {
+      console.log(chalk.yellow(`   - ${fallback.module}: ${fallback.files} files (${fallback.reason})`));
+    }
+    console.log(chalk.yellow(`   Re-run them with --require-llm (vf metrics --fallbacks lists affected runs)`));
+  }
+  if (escalations.length > 0) {
+    const extraCost = escalations.reduce((sum, e) => sum + e.extra_cost, 0);
+    console.log(chalk.yellow(`\n⬆️  ${escalations.length} file(s) escalated to a stronger model (extra cost ~$${extraCost.toFixed(2)}):`));
+    for (const escalation of escalations) {
+      console.log(chalk.yellow(`   - ${escalation.module}/${escalation.file}: ${escalation.category}, ${escalation.original_model} → ${escalation.escalated_model} (${escalation.outcome})`));
+    }
+  }
+  if (skipped.length > 0) {
+    console.log(chalk.yellow(`\n⏭️  Skipped modules: ${skipped.join(', ')}`));
+    if (runId !== undefined) {
+      console.log(chalk.yellow(`   Resume later with: vf refactor --resume-skipped ${runId}`));
+    }
+  }
+  if (batchState && batchState.modules.length > 0) {
+    const inProgress = batchState.jobs.filter(job => job.status === 'in_progress');
+    console.log(chalk.cyan(`\n📨 Waiting for async batch results: ${batchState.modules.join(', ')} (${inProgress.length} job(s), ${options.batchProvider!.discount * 100}% cheaper)`));
+    console.log(chalk.cyan(`   Collect with: vf refactor --collect ${runId} (--wait to poll until every job has ended)`));
+  }
+  if (!options.apply) {
+    console.log(chalk.yellow('\nℹ️  ドライランモード - 実際の変更は行われていません'));
+  }
+
+  const applied = boundaries.map(b => b.name).filter(name => !skipped.includes(name) && !pending.includes(name));
+  if (options.apply && options.commit && applied.length > 0 && result.applied_patches.length > 0) {
+    await commitAppliedModules(projectRoot, applied, result.deleted_files, runId);
+  }
+}
+
+/**
+ * Commit applied modules with a generated message and record them in .vibeflow/MIGRATION_LOG.md
+ */
+async function commitAppliedModules(projectRoot: string, moduleNames: string[], removed: string[], runId?: number): Promise<void> {
+  const { MigrationCommitWriter, verifyGoProject } = await import('./core/utils/migration-commit.js');
+  const writer = new MigrationCommitWriter(projectRoot);
+
+  console.log(chalk.blue('🔍 Verifying build and tests before committing...'));
+  const verification = verifyGoProject(projectRoot);
+
+  // Offline runs keep the template summary instead of asking the model
+  const modules = await writer.describeModules(moduleNames, removed, isOffline() ? undefined : async change => {
+    const { ClaudeCodeIntegration } = await import('./core/utils/claude-code-integration.js');
+    return new ClaudeCodeIntegration({ projectRoot }).summarizeMigration({
+      module: change.module,
+      facts: change.summary,
+    });
+  });
+  const context = { run_id: runId, modules, verification };
+
+  try {
+    const commit = writer.commit(writer.composeMessage(context));
+    writer.appendMigrationLog(context, commit);
+    console.log(chalk.green(`✅ Committed ${moduleNames.join(', ')} as ${commit.slice(0, 8)}`));
+    console.log(chalk.gray(`   📝 ${new VibeFlowPaths(projectRoot).getRelativePath(writer.migrationLogPath)}`));
+  } catch (error) {
+    writer.appendMigrationLog(context);
+    console.error(chalk.red(`❌ Commit failed: ${getErrorMessage(error)}`));
+    process.exitCode = 1;
+  }
+}
+
+async function domainMapModules(projectRoot: string): Promise<string[]> {
+  const paths = new VibeFlowPaths(projectRoot);
+  let content: string;
+  try {
+    content = await fs.readFile(paths.domainMapPath, 'utf8');
+  } catch {
+    throw new Error(`Domain map not found. Please run "vf plan" first to generate ${paths.getRelativePath(paths.domainMapPath)}`);
+  }
+  return parseDomainMap(content, paths.getRelativePath(paths.domainMapPath)).map.boundaries.map(b => b.name);
+}
+
+/**
+ * Modules skipped in a previous run (latest run with skips when no id is given)
+ */
+function findSkippedModules(projectRoot: string, runIdOption: string | true): { runId: number; modules: string[] } {
+  const store = new PerformanceStore(projectRoot, { readOnly: true });
+  const runs = store.getRuns();
+  const run = runIdOption === true
+    ? [...runs].reverse().find(r => (r.skipped_modules ?? []).length > 0)
+    : runs.find(r => r.run_id === parseInt(runIdOption, 10));
+
+  if (!run) {
+    throw new Error(runIdOption === true ? 'No run with skipped modules found' : `Run ${runIdOption} not found`);
+  }
+  return { runId: run.run_id, modules: run.skipped_modules ?? [] };
+}
+
+/**
+ * Poll the async batch jobs of a run and refactor the modules whose results are
+ * all in; the others keep waiting for a later --collect
+ */
+async function collectBatchRun(projectRoot: string, runIdOption: string, provider: BatchProvider, options: { wait?: boolean }): Promise<void> {
+  const runId = parseInt(runIdOption, 10);
+  const session = new LlmBatchSession(projectRoot, runId, provider.discount);
+  const state = session.loadState();
+  if (!state) {
+    throw new Error(`Run ${runIdOption} has no async batch waiting for results`);
+  }
+
+  let { ready, waiting } = await pollBatchJobs(provider, session, state);
+  session.saveState(state);
+  while (options.wait && waiting.length > 0) {
+    console.log(chalk.gray(`   ⏳ Waiting for ${waiting.join(', ')}; polling again in ${BATCH_POLL_INTERVAL_MS / 1000}s`));
+    await new Promise(resolve => setTimeout(resolve, BATCH_POLL_INTERVAL_MS));
+    ({ ready, waiting } = await pollBatchJobs(provider, session, state));
+    session.saveState(state);
+  }
+
+  if (ready.length === 0) {
+    const jobs = state.jobs.filter(job => job.status === 'in_progress');
+    console.log(chalk.cyan(`⏳ Run ${runId}: no module has all its results yet (${jobs.map(job => `${job.module}: ${job.id}`).join(', ')})`));
+    return;
+  }
+  console.log(chalk.cyan(`📥 Collecting ${ready.length} module(s) of run ${runId}${waiting.length > 0 ? `; still waiting for ${waiting.join(', ')}` : ''}`));
+  await runModuleRefactor(projectRoot, ready, {
+    ...state.options,
+    generationMode: 'auto',
+    batchProvider: provider,
+    collect: state,
+  });
+}
+
+function showRunMetrics(projectRoot: string, runId: number, options: { env?: boolean; json?: boolean } = {}): void {
+  const run = new PerformanceStore(projectRoot, { readOnly: true }).getRun(runId);
+  if (!run) {
+    console.error(chalk.red(`❌ Run ${runId} not found in ${PerformanceStore.storePath(projectRoot)}`));
+    process.exit(1);
+  }
+  if (options.json) {
+    console.log(JSON.stringify(redactSecrets(run), null, 2));
+    return;
+  }
+  if (options.env) {
+    if (!run.environment) {
+      console.log(chalk.gray(`   No environment snapshot recorded for run ${runId}`));
+      return;
+    }
+    console.log(chalk.blue(`🧪 Environment of run ${run.run_id} (captured ${run.environment.captured_at})`));
+    formatRunEnvironment(redactSecrets(run.environment)).forEach(line => console.log(`   ${line}`));
+    return;
+  }
+
+  console.log(chalk.blue(`📊 Run ${run.run_id}: ${run.command} (${run.status})`));
+  console.log(chalk.gray(`   Started: ${run.started_at}${run.finished_at ? `, finished: ${run.finished_at}` : ''}`));
+  console.log(chalk.gray(`   Modules: ${run.modules_migrated}/${run.modules_planned}, files: ${run.files_processed}, LOC: ${run.loc}`));
+  console.log(chalk.gray(`   Tokens: ${run.input_tokens} in / ${run.output_tokens} out, cost: $${run.cost.toFixed(4)}`));
+  for (const escalation of run.escalations ?? []) {
+    console.log(chalk.gray(`   Escalated ${escalation.file}: ${escalation.original_model} → ${escalation.escalated_model} (${escalation.category}, ${escalation.outcome}, +$${escalation.extra_cost.toFixed(4)})`));
+  }
+  if (run.error) console.log(chalk.red(`   Error: ${run.error}`));
+
+  const store = new RunArtifactStore(projectRoot);
+  if (!store.load(runId)) {
+    console.log(chalk.gray('   No input snapshot recorded for this run'));
+    return;
+  }
+  printArtifactComparison(store, runId);
+}
+
+/**
+ * Runs with unintended template fallbacks and the commands to redo those modules with the LLM
+ */
+function showFallbackRuns(projectRoot: string, runId?: number): void {
+  const runs = findFallbackRuns(new PerformanceStore(projectRoot, { readOnly: true }))
+    .filter(run => runId === undefined || run.run_id === runId);
+  if (runs.length === 0) {
+    console.log(chalk.green(`✅ No ${runId !== undefined ? `template fallbacks in run ${runId}` : 'runs with template fallbacks'}`));
+    return;
+  }
+
+  for (const run of runs) {
+    console.log(chalk.yellow(`\n⚠️  Run ${run.run_id}: ${run.command} (${run.started_at})`));
+    for (const module of run.modules) {
+      const name = module.module_name ?? module.module;
+      console.log(`   ${name}: ${module.files} files from templates (${module.reasons.join('; ') || 'unknown'})`);
+      console.log(chalk.gray(`      vf refactor --module ${name} --require-llm --apply`));
+    }
+  }
+}
+
+/**
+ * How often refactor runs needed a stronger model and how often the retry fixed the file
+ */
+function showEscalations(projectRoot: string, runId?: number): void {
+  const runs = new PerformanceStore(projectRoot, { readOnly: true }).getRuns()
+    .filter(run => run.command.startsWith('refactor') && (runId === undefined || run.run_id === runId));
+  const summary = summarizeEscalations(runs);
+  if (summary.escalations === 0) {
+    console.log(chalk.green(`✅ No escalations in ${runId !== undefined ? `run ${runId}` : `${summary.runs} refactor runs`}`));
+    return;
+  }
+
+  const rate = summary.success_rate !== null ? `${Math.round(summary.success_rate * 100)}%` : 'n/a';
+  console.log(chalk.blue(`⬆️  Escalations: ${summary.escalations} in ${summary.runs_with_escalations}/${summary.runs} refactor runs`));
+  console.log(chalk.gray(`   Succeeded: ${summary.succeeded}, failed: ${summary.failed}, over budget: ${summary.over_budget} (success rate ${rate})`));
+  console.log(chalk.gray(`   Extra cost: $${summary.extra_cost.toFixed(4)}`));
+  for (const [models, count] of Object.entries(summary.by_model)) {
+    console.log(chalk.gray(`   ${models}: ${count}`));
+  }
+}
+
+/**
+ * file_processing records of a run, an agent and/or a method, as a table or an export
+ */
+function showFileProcessing(projectRoot: string, opts: { runId?: string; agent?: string; method?: string; export?: string }): void {
+  const format = (opts.export ?? 'table') as FileProcessingFormat;
+  if (!FILE_PROCESSING_FORMATS.includes(format)) {
+    console.error(chalk.red(`❌ Unknown export format: ${opts.export} (expected ${FILE_PROCESSING_FORMATS.join(', ')})`));
+    process.exit(1);
+  }
+  if (opts.method !== undefined && !PROCESSING_METHODS.includes(opts.method as ProcessingMethod)) {
+    console.error(chalk.red(`❌ Unknown processing method: ${opts.method} (expected ${PROCESSING_METHODS.join(', ')})`));
+    process.exit(1);
+  }
+
+  const records = new PerformanceStore(projectRoot, { readOnly: true }).queryFileProcessing({
+    runId: opts.runId !== undefined ? Number(opts.runId) : undefined,
+    agent: opts.agent,
+    method: opts.method as ProcessingMethod | undefined,
+  });
+  process.stdout.write(formatFileProcessing(records, format));
+  if (format === 'table' && records.length > 0) {
+    formatMethodTable(summarizeProcessing(records), '').forEach(line => console.log(chalk.gray(line)));
+  }
+}
+
+/**
+ * Per-method lines of the business logic step; the plain counts for results without them
+ */
+function businessLogicMethodLines(result: BusinessLogicMigrationExecuteResult, indent: string): string[] {
+  if (result.processing && result.processing.length > 0) return formatMethodTable(result.processing, indent);
+  return [`${indent}AI処理: ${result.aiProcessedFiles}ファイル, 静的解析: ${result.staticAnalysisFiles}ファイル`];
+}
+
+/**
+ * Migration stage of every module, as a table or a JSON/CSV export
+ */
+async function showModuleStatus(projectRoot: string, opts: { export?: string; output?: string }): Promise<void> {
+  if (opts.export && !['json', 'csv'].includes(opts.export)) {
+    throw new Error(`Unsupported export format: ${opts.export} (expected json or csv)`);
+  }
+
+  const views = new ModuleStatusTracker(projectRoot, new PerformanceStore(projectRoot, { readOnly: true })).list();
+  if (opts.export) {
+    const content = formatModuleStatus(views, opts.export as 'json' | 'csv');
+    if (opts.output) {
+      await fs.writeFile(opts.output, content);
+      console.log(chalk.green(`✅ Module status exported: ${opts.output}`));
+    } else {
+      process.stdout.write(content);
+    }
+    return;
+  }
+
+  if (views.length === 0) {
+    console.log(chalk.gray('ℹ️  No modules yet - run "vf discover" first'));
+    return;
+  }
+  const width = Math.max(6, ...views.map(v => v.module.length)) + 2;
+  console.log(chalk.blue('📋 Module status'));
+  console.log(chalk.gray(`   ${'Module'.padEnd(width)}${'Stage'.padEnd(17)}${'Run'.padEnd(6)}${'Team'.padEnd(14)}Next`));
+  for (const view of views) {
+    const color = view.stage === 'accepted' ? chalk.green : view.stage === 'verified' ? chalk.cyan : chalk.white;
+    const stage = view.stage === 'accepted' && view.accepted_by ? `${view.stage} (${view.accepted_by})` : view.stage;
+    console.log(`   ${view.module.padEnd(width)}${color(stage.padEnd(17))}${String(view.run_id ?? '-').padEnd(6)}${(view.team ?? '-').padEnd(14)}${chalk.gray(view.next ?? '')}`);
+  }
+  const accepted = views.filter(v => v.stage === 'accepted').length;
+  console.log(chalk.gray(`\n   ${accepted}/${views.length} modules accepted`));
+}
+
+function printArtifactComparison(store: RunArtifactStore, runId: number): void {
+  const comparison = store.compare(runId);
+  const changed = comparison.filter(a => a.status !== 'same').length;
+
+  console.log(chalk.blue(`\n📦 Inputs of run ${runId} (${changed} differ from the workspace)`));
+  for (const artifact of comparison) {
+    const marker = artifact.status === 'same'
+      ? chalk.green('same')
+      : artifact.status === 'changed' ? chalk.yellow('changed') : chalk.red('missing');
+    console.log(`   ${artifact.hash.slice(0, 12)}  ${artifact.name} [${marker}]`);
+  }
+}
+
+async function runFindingsReport(projectRoot: string, options: { businessRules?: boolean }): Promise<void> {
+  const { CodeAnalyzer } = await import('./core/utils/code-analyzer.js');
+  const { ConfigLoader } = await import('./core/utils/config-loader.js');
+  const findingsModule = await import('./core/utils/findings.js');
+  const findings: import('./core/utils/findings.js').Finding[] = [];
+
+  const analyzer = new CodeAnalyzer(projectRoot);
+  const files = await analyzer.analyzeFiles(['**/*.go'], ['**/vendor/**', '**/*_test.go', '.vibeflow/**']);
+  const cycles = analyzer.detectCircularDependencies(analyzer.buildDependencyGraph(files));
+  findings.push(...findingsModule.cycleEdgeFindings(analyzer, cycles, files));
+
+  const planPaths = new VibeFlowPaths(projectRoot);
+  const plan = await loadPlanIfPresent(planPaths);
+  const boundaryConfig = ConfigLoader.loadBoundaryConfig(path.join(projectRoot, 'boundary.yaml'));
+  if (boundaryConfig?.constraints) {
+    if (plan) {
+      const violations = checkPlanConstraints(plan, boundaryConfig.constraints);
+      const modules = plan.modules.map(m => ({ name: m.name, files: m.current_state.files }));
+      findings.push(...findingsModule.constraintViolationFindings(projectRoot, violations, modules));
+    } else {
+      console.log(chalk.gray('ℹ️  plan.json not found - boundary violations skipped (run "vf plan" first)'));
+    }
+  }
+
+  const { loadSharedState } = await import('./core/utils/shared-state.js');
+  findings.push(...findingsModule.sharedStateFindings(projectRoot, loadSharedState(projectRoot)));
+
+  // Without a plan there are no service modules
+  const planned = plan?.modules.flatMap(m => m.service ?? []) ?? [];
+  if (plan && planned.length > 0) {
+    const { analyzeServiceRequirements } = await import('./core/utils/service-deployment.js');
+    const current = analyzeServiceRequirements(projectRoot, plan.modules.map(m => ({
+      name: m.name,
+      files: m.current_state.files,
+      deployment: m.deployment,
+      owned_tables: m.owned_tables,
+    })));
+    findings.push(...findingsModule.serviceRuleFindings(projectRoot, current, planned));
+  }
+
+  if (options.businessRules) {
+    const agent = new BusinessLogicMigrationAgent(projectRoot, {
+      extractionLevel: 'basic',
+      patterns: { validations: true, calculations: true, workflows: true, dataAccess: true, errorHandling: true },
+      complexityThreshold: 'low',
+      language: 'go',
+      claudeCode: { enabled: false },
+    });
+    for (const file of files) {
+      const { rules } = await agent.extractBusinessLogic(file.relativePath);
+      findings.push(...findingsModule.businessRuleFindings(projectRoot, rules));
+    }
+  }
+
+  // Declarations marked //vf:ignore are not reported as business rules or dead code
+  const { collectProjectAnnotations, isIgnored } = await import('./core/utils/source-annotations.js');
+  const { annotations } = collectProjectAnnotations(projectRoot);
+  const reported = findings.filter(f =>
+    !(f.kind === 'business-rule' || f.kind === 'dead-code') || !isIgnored(annotations, f.location.file, f.symbol));
+
+  const reporter = new findingsModule.FindingsReporter(projectRoot);
+  const report = reporter.write(reported);
+
+  console.log(chalk.blue(`📍 ${report.findings.length} findings`));
+  for (const finding of report.findings) {
+    const line = findingsModule.formatFinding(finding);
+    console.log(finding.severity === 'error' ? chalk.red(line) : finding.severity === 'warning' ? chalk.yellow(line) : chalk.gray(line));
+  }
+  console.log(chalk.gray(`   - ${planPaths.getRelativePath(reporter.reportPath)}`));
+  console.log(chalk.gray(`   - ${planPaths.getRelativePath(reporter.sarifPath)}`));
+}
+
+async function runClean(projectRoot: string, options: {
+  cache?: boolean; backups?: boolean; previews?: boolean; reports?: boolean;
+  all?: boolean; metrics?: boolean; plans?: boolean; dryRun?: boolean;
+}): Promise<void> {
+  const { WorkspaceCleaner, DEFAULT_CLEAN_SCOPES, formatBytes } = await import('./core/utils/workspace-cleaner.js');
+  const scopes: typeof DEFAULT_CLEAN_SCOPES = options.all ? [...DEFAULT_CLEAN_SCOPES] : [];
+  (['cache', 'backups', 'previews', 'reports', 'metrics', 'plans'] as const)
+    .filter(scope => options[scope])
+    .forEach(scope => scopes.push(scope));
+  if (scopes.length === 0) {
+    console.error(chalk.red('❌ Specify what to clean: --cache, --backups, --previews, --reports, --all, --metrics or --plans'));
+    process.exit(1);
+  }
+
+  let keepLastBackups: number | undefined;
+  try {
+    const { ConfigLoader } = await import('./core/utils/config-loader.js');
+    keepLastBackups = ConfigLoader.loadVibeFlowConfig(path.join(projectRoot, 'vibeflow.config.yaml')).backups?.keepLast;
+  } catch {
+    // Default retention
+  }
+
+  const cleaner = new WorkspaceCleaner(projectRoot);
+  const lockHolder = cleaner.findActiveLock();
+  if (lockHolder && !options.dryRun) {
+    console.error(chalk.red(`❌ Workspace is locked by ${lockHolder}. Wait for it to finish before cleaning.`));
+    process.exit(1);
+  }
+
+  const plan = cleaner.plan({ scopes, keepLastBackups });
+  console.log(chalk.blue(`🧹 ${options.dryRun ? 'Would remove' : 'Removing'} ${plan.targets.length} paths (${scopes.join(', ')})`));
+  for (const target of plan.targets) {
+    console.log(chalk.gray(`   - [${target.scope}] ${target.path} (${formatBytes(target.bytes)})`));
+  }
+  if (plan.keptBackups.length > 0) {
+    console.log(chalk.gray(`   Keeping ${plan.keptBackups.length} most recent backups`));
+  }
+
+  if (options.dryRun) {
+    console.log(chalk.yellow(`\nℹ️  Dry run - ${formatBytes(plan.totalBytes)} would be reclaimed`));
+    return;
+  }
+
+  const reclaimed = cleaner.execute(plan);
+  console.log(chalk.green(`✅ Reclaimed ${formatBytes(reclaimed)}`));
+}
+
+/**
+ * vf shared-state: list package variables shared across modules, or record
+ * the resolution chosen for one in the plan
+ */
+async function runSharedState(projectRoot: string, options: { accept?: string; resolution?: string; note?: string }): Promise<void> {
+  const { loadSharedState, acceptSharedStateResolution, SHARED_STATE_STRATEGIES } = await import('./core/utils/shared-state.js');
+  const { formatLocation } = await import('./core/utils/source-positions.js');
+
+  if (options.accept) {
+    if (!options.resolution) {
+      console.error(chalk.red(`❌ --resolution is required with --accept (${SHARED_STATE_STRATEGIES.join(', ')})`));
+      process.exit(1);
+    }
+    try {
+      const finding = acceptSharedStateResolution(
+        projectRoot, options.accept, options.resolution as typeof SHARED_STATE_STRATEGIES[number], options.note);
+      console.log(chalk.green(`✅ ${finding.id}: resolution '${finding.resolution?.strategy}' recorded in the plan`));
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+    return;
+  }
+
+  const findings = loadSharedState(projectRoot);
+  if (findings.length === 0) {
+    console.log(chalk.green('✅ No shared mutable state across modules (or no plan yet - run "vf plan")'));
+    return;
+  }
+
+  const unresolved = findings.filter(f => !f.resolution);
+  console.log(chalk.blue(`🔗 ${findings.length} package variables shared across modules (${unresolved.length} unresolved)`));
+  for (const finding of findings) {
+    const color = finding.resolution ? chalk.gray : chalk.red;
+    console.log(color(`\n${finding.id} ${finding.type} [${finding.kind}, ${finding.access}]`));
+    console.log(chalk.gray(`   declared at ${formatLocation(finding.declared_at)}; modules: ${finding.modules.join(', ')}`));
+    for (const access of finding.accesses) {
+      console.log(chalk.gray(`   - ${formatLocation(access.location)} ${access.module}${access.function ? ` ${access.function}()` : ''}${access.write ? ' (write)' : ''}`));
+    }
+    if (finding.resolution) {
+      console.log(chalk.green(`   ✅ ${finding.resolution.strategy}${finding.resolution.note ? `: ${finding.resolution.note}` : ''}`));
+    } else {
+      finding.suggested_resolutions.forEach(r => console.log(chalk.yellow(`   💡 ${r.strategy}: ${r.description}`)));
+    }
+  }
+  if (unresolved.length > 0) {
+    console.log(chalk.yellow(`\n   Record a decision with: vf shared-state --accept <id> --resolution <${SHARED_STATE_STRATEGIES.join('|')}>`));
+    process.exitCode = 1;
+  }
+}
+
+async function runMigrateCallers(projectRoot: string, options: { module: string; package?: string; apply?: boolean; yes?: boolean }): Promise<void> {
+  const { CallerMigrator } = await import('./core/utils/caller-migration.js');
+  const { formatLocation } = await import('./core/utils/source-positions.js');
+  const migrator = new CallerMigrator(projectRoot);
+
+  try {
+    if (options.package) {
+      const sites = migrator.findCallSites(options.module, options.package);
+      const wiring = migrator.planWiring(options.module, options.package);
+      const deterministic = sites.filter(s => s.kind === 'deterministic');
+
+      if (sites.length === 0) {
+        console.log(chalk.green(`✅ ${options.package}: no legacy calls into ${options.module} left`));
+      } else {
+        console.log(chalk.blue(`📞 ${options.package}: ${sites.length} calls into ${options.module} (${deterministic.length} mechanical, ${sites.length - deterministic.length} need a human)`));
+      }
+      for (const site of sites) {
+        console.log(chalk.white(`\n   ${formatLocation(site)}`));
+        console.log(chalk.red(`   - ${site.original}`));
+        if (site.kind === 'deterministic') {
+          console.log(chalk.green(`   + ${site.replacement}`));
+          if (site.context_todo) console.log(chalk.gray('     (no caller context: context.TODO())'));
+        } else {
+          console.log(chalk.yellow(`   ? ${site.reason}`));
+        }
+      }
+      if (deterministic.length > 0) {
+        console.log(chalk.gray(`\n   Wiring: ${wiring.statement} in ${wiring.composition_root ?? '(no composition root found)'} [${wiring.status}]`));
+      }
+
+      if (options.apply && sites.length > 0) {
+        let accepted = deterministic;
+        if (!options.yes) {
+          if (!process.stdin.isTTY || !process.stdout.isTTY) {
+            console.log(chalk.yellow('\n   Confirm each rewrite in a terminal, or accept all mechanical rewrites with --yes'));
+            process.exitCode = 1;
+            return;
+          }
+          accepted = await confirmCallRewrites(deterministic);
+        }
+
+        const { FileSafetyManager } = await import('./core/utils/file-safety.js');
+        const safetyManager = new FileSafetyManager(projectRoot);
+        const result = await migrator.apply(options.module, options.package, accepted, safetyManager);
+        if (result.error) {
+          console.log(chalk.red('\n❌ Rolled back, the rewrite does not compile'));
+          console.log(chalk.gray(result.error.split('\n').map(line => `   ${line}`).join('\n')));
+          process.exitCode = 1;
+        } else {
+          console.log(chalk.green(`\n✅ Migrated ${result.applied.length} calls, marked ${result.todos.length} with TODO(vibeflow) (${result.files.length} files)`));
+          if (result.wiring?.status === 'pending') {
+            console.log(chalk.yellow(`⚠️  Inject the usecase in the composition root: ${result.wiring.statement}`));
+          }
+        }
+      } else if (!options.apply && sites.length > 0) {
+        console.log(chalk.gray(`\n   Apply with: vf migrate-callers --module ${options.module} --package ${options.package} --apply`));
+      }
+    }
+
+    const adoption = migrator.recordAdoption(options.module);
+    console.log(chalk.blue(`\n📊 Adoption of ${options.module} by consumer package`));
+    if (adoption.length === 0) {
+      console.log(chalk.gray('   No calls into the module outside it'));
+    }
+    for (const consumer of adoption) {
+      const color = consumer.remaining === 0 ? chalk.green : chalk.white;
+      console.log(color(`   ${consumer.package}: ${consumer.migrated} migrated, ${consumer.remaining} remaining${consumer.ambiguous > 0 ? ` (${consumer.ambiguous} need a human)` : ''}`));
+    }
+    console.log(chalk.gray(`   - ${new VibeFlowPaths(projectRoot).getRelativePath(migrator.summaryPath)}`));
   } catch (error) {
-    console.error(chalk.red('❌ Error in automatic boundary discovery:'), error);
-    throw error;
+    console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+    process.exit(1);
   }
 }
 
-async function planTasks(projectRoot: string): Promise<void> {
-  const absolutePath = path.resolve(projectRoot);
-  
-  // Verify project exists
+/**
+ * Ask for each mechanical call rewrite: [y]es / [n]o / [a]ll / [q]uit
+ */
+async function confirmCallRewrites<T extends { original: string; replacement?: string }>(sites: T[]): Promise<T[]> {
+  const readline = await import('readline');
+  const rl = readline.createInterface({ input: process.stdin, output: process.stdout });
+  const ask = (question: string) => new Promise<string>(resolve => rl.question(question, resolve));
+  const accepted: T[] = [];
+
   try {
-    await fs.access(absolutePath);
-  } catch {
-    throw new Error(`Project directory not found: ${absolutePath}`);
+    for (const [index, site] of sites.entries()) {
+      const answer = (await ask(`   [${index + 1}/${sites.length}] ${site.original} → ${site.replacement}  [y]es / [n]o / [a]ll / [q]uit > `)).trim().toLowerCase();
+      if (answer === 'y') accepted.push(site);
+      else if (answer === 'a') return [...accepted, ...sites.slice(index)];
+      else if (answer === 'q') break;
+    }
+  } finally {
+    rl.close();
   }
+  return accepted;
+}
 
-  console.log(chalk.blue(`📂 Analyzing project: ${absolutePath}`));
-  
+async function runReviewPacket(projectRoot: string, options: { module?: string; all?: boolean; output?: string; zip?: boolean }): Promise<void> {
+  const { ReviewPacketBuilder, zipDirectory } = await import('./core/utils/review-packet.js');
+  const builder = new ReviewPacketBuilder(projectRoot);
+
+  if (!options.module && !options.all) {
+    console.error(chalk.red('❌ Specify --module <name> or --all'));
+    process.exit(1);
+  }
+
+  let modules: string[];
   try {
-    // 1. Enhanced Boundary Analysis (AI + Manual)
-    const enhancedBoundaryAgent = new EnhancedBoundaryAgent(absolutePath);
-    const boundaryResult = await enhancedBoundaryAgent.analyzeBoundaries();
-    
-    // 2. Architectural Design
-    const architectAgent = new ArchitectAgent(absolutePath);
-    const architectResult = await architectAgent.generateArchitecturalPlan(boundaryResult.outputPath);
-    
-    const planPaths = new VibeFlowPaths(absolutePath);
-    console.log(chalk.green('✅ Plan generation complete!'));
-    console.log(chalk.gray('📄 Generated files:'));
-    console.log(chalk.gray(`   - ${planPaths.getRelativePath(boundaryResult.outputPath)}`));
-    console.log(chalk.gray(`   - ${planPaths.getRelativePath(architectResult.outputPath)}`));
-    
-    // Display AI discovery results
-    if (boundaryResult.autoDiscoveredBoundaries.length > 0) {
-      console.log(chalk.cyan('\n🤖 AI自動境界発見結果:'));
-      console.log(chalk.gray(`   発見された境界: ${boundaryResult.autoDiscoveredBoundaries.length}個`));
-      console.log(chalk.gray(`   全体信頼度: ${boundaryResult.discoveryMetrics.confidence_metrics.overall_confidence.toFixed(1)}%`));
-      
-      if (boundaryResult.hybridRecommendations.length > 0) {
-        console.log(chalk.yellow(`   推奨事項: ${boundaryResult.hybridRecommendations.length}個`));
-        boundaryResult.hybridRecommendations.slice(0, 3).forEach((rec, i) => {
-          console.log(chalk.gray(`     ${i + 1}. ${rec.action}`));
-        });
+    modules = options.all ? builder.listModules() : [options.module!];
+  } catch (error) {
+    console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+    process.exit(1);
+  }
+
+  const outputRoot = path.resolve(options.output ?? (options.all ? 'review-packets' : `${options.module}-review`));
+  for (const moduleName of modules) {
+    const outputDir = options.all ? path.join(outputRoot, moduleName) : outputRoot;
+    try {
+      const packet = await builder.build(moduleName);
+      builder.write(packet, outputDir);
+      const stale = packet.artifacts.filter(a => a.stale).map(a => a.name);
+      console.log(chalk.green(`✅ ${moduleName}${packet.id ? ` (${packet.id})` : ''}: ${path.relative(process.cwd(), outputDir) || '.'}`));
+      if (stale.length > 0 || packet.edited_outputs.length > 0) {
+        console.log(chalk.yellow(`   ⚠️  stale: ${[...stale, ...packet.edited_outputs].join(', ')} (see README.md)`));
+      }
+      if (options.zip) {
+        const zipPath = `${outputDir}.zip`;
+        zipDirectory(outputDir, zipPath);
+        console.log(chalk.gray(`   - ${path.relative(process.cwd(), zipPath)}`));
       }
+    } catch (error) {
+      console.error(chalk.red(`❌ ${moduleName}: ${getErrorMessage(error)}`));
+      process.exitCode = 1;
     }
-    
-  } catch (error) {
-    console.error(chalk.red('❌ Error in plan generation:'), error);
-    throw error;
   }
 }
 
-async function runRefactor(projectRoot: string, apply: boolean, resumeOptions?: any): Promise<void> {
-  const absolutePath = path.resolve(projectRoot);
-  const paths = new VibeFlowPaths(absolutePath);
-  
-  // Verify required files exist
-  const planPath = paths.planPath;
-  const domainMapPath = paths.domainMapPath;
-  
-  // Check for required files unless in test environment
-  if (process.env.NODE_ENV !== 'test' && process.env.VITEST !== 'true') {
-    try {
-      await fs.access(planPath);
-      await fs.access(domainMapPath);
-    } catch {
-      throw new Error(
-        `Required files not found. Please run "vf plan" first to generate ${paths.getRelativePath(planPath)} and ${paths.getRelativePath(domainMapPath)}`
-      );
+/**
+ * Walk through conflicts left by re-runs in manually edited outputs
+ */
+async function runResolve(projectRoot: string, options: { accept?: string }): Promise<void> {
+  const { ConflictStore, parseConflictBlocks, planExcerpt, OURS_LABEL, THEIRS_LABEL } = await import('./core/utils/merge-conflicts.js');
+  const { FileSafetyManager } = await import('./core/utils/file-safety.js');
+  const store = new ConflictStore(projectRoot);
+  const conflicts = store.unresolved();
+
+  if (conflicts.length === 0) {
+    console.log(chalk.green('✅ No unresolved conflicts'));
+    return;
+  }
+  if (options.accept && !['ours', 'theirs', 'both'].includes(options.accept)) {
+    console.error(chalk.red('❌ --accept must be ours, theirs or both'));
+    process.exit(1);
+  }
+
+  const safetyManager = new FileSafetyManager(projectRoot);
+  const runLabel = (runId?: number) => runId !== undefined ? ` (run ${runId})` : '';
+
+  if (options.accept) {
+    const side = options.accept as 'ours' | 'theirs' | 'both';
+    for (const conflict of conflicts) {
+      const content = await fs.readFile(path.join(projectRoot, conflict.path), 'utf8');
+      await store.apply(conflict.path, parseConflictBlocks(content).map(() => side), safetyManager);
+      console.log(chalk.green(`✅ ${conflict.path}: kept ${side === 'ours' ? OURS_LABEL : side === 'theirs' ? THEIRS_LABEL : 'both sides'}`));
     }
-  } else {
-    console.log(chalk.yellow('🔧 Test environment - skipping required file validation'));
+    console.log(chalk.gray(`   💾 Previous versions backed up under ${new VibeFlowPaths(projectRoot).getRelativePath(safetyManager.getBackupSummary().location)}`));
+    return;
   }
 
-  console.log(chalk.blue(`🔧 Refactoring project: ${absolutePath}`));
-  
+  if (!process.stdin.isTTY || !process.stdout.isTTY) {
+    console.log(chalk.yellow(`⚔️  ${conflicts.length} files have unresolved conflicts:`));
+    for (const conflict of conflicts) {
+      console.log(chalk.gray(`   - ${conflict.path} [${conflict.module}] ${conflict.hunks.filter(h => !h.resolution).length} hunks${runLabel(conflict.run_id)}`));
+    }
+    console.log(chalk.gray(`\n   Markers: <<<<<<< ${OURS_LABEL} / ======= / >>>>>>> ${THEIRS_LABEL}`));
+    console.log(chalk.gray('   Resolve interactively in a terminal with: vf resolve'));
+    console.log(chalk.gray('   Or keep one side everywhere with: vf resolve --accept ours|theirs|both'));
+    process.exitCode = 1;
+    return;
+  }
+
+  const readline = await import('readline');
+  const { spawnSync } = await import('child_process');
+  const rl = readline.createInterface({ input: process.stdin, output: process.stdout });
+  const ask = (question: string) => new Promise<string>(resolve => rl.question(question, resolve));
+
   try {
-    // 1. Business Logic Migration (AI-powered)
-    console.log(chalk.blue('🧠 Step 1/5: AI-powered business logic migration...'));
-    const businessLogicAgent = new BusinessLogicMigrationAgent(absolutePath);
-    const businessLogicResult = await businessLogicAgent.execute({
-      projectPath: absolutePath,
-      domainMapPath: domainMapPath,
-      planPath: planPath,
-      aiEnabled: true,
-      language: 'go' as const, // TODO: Auto-detect language
-      preserveMode: 'strict',
-      generateTests: true,
-      generateDocumentation: true
-    });
-    
-    console.log(chalk.green(`✅ 業務ロジック移行完了: ${businessLogicResult.migratedBoundaries.length}個の境界を処理`));
-    console.log(chalk.gray(`   AI処理: ${businessLogicResult.aiProcessedFiles}ファイル, 静的解析: ${businessLogicResult.staticAnalysisFiles}ファイル`));
-    
-    // 2. Test Synthesis for files without tests
-    console.log(chalk.blue('🧪 Step 2/5: AI-powered test synthesis...'));
-    const testSynthesisAgent = new TestSynthesisAgent(absolutePath);
-    const testSynthesisResult = await testSynthesisAgent.execute({
-      projectPath: absolutePath,
-      language: 'go' as const,
-      outputPath: path.join(absolutePath, '__generated__/tests'),
-      documentationPath: path.join(absolutePath, '__generated__/docs'),
-      aiEnabled: true,
-      generateDocumentation: true,
-      localization: 'ja'
-    });
-    
-    console.log(chalk.green(`✅ テスト生成完了: ${testSynthesisResult.generatedTests.length}個のテスト, ${testSynthesisResult.generatedDocuments.length}個のドキュメント`));
-    
-    // 3. Generate refactoring patches
-    console.log(chalk.blue('🏗️  Step 3/5: Generating refactoring patches...'));
-    const refactorAgent = new RefactorAgent(absolutePath);
-    const refactorResult = await refactorAgent.generateRefactorPlan(planPath);
-    
-    // 4. Synthesize and relocate tests
-    console.log(chalk.blue('🔄 Step 4/5: Test relocation and synthesis...'));
-    const testSynthAgent = new TestSynthAgent(absolutePath);
-    const testSynthResult = await testSynthAgent.synthesizeTests(paths.patchesDir);
-    
-    // 5. Run migration (apply patches)
-    console.log(chalk.blue('🚀 Step 5/5: Applying patches and migration...'));
-    const migrationRunner = new MigrationRunner(absolutePath, undefined, !apply);
-    const migrationResult = await migrationRunner.executeMigration(paths.patchesDir, apply);
-    
-    // 6. Review changes
-    const reviewAgent = new ReviewAgent(absolutePath);
-    const reviewResult = await reviewAgent.reviewChanges(migrationResult.outputPath);
-    
-    console.log(chalk.green('✅ AI-powered完全なリファクタリングパイプライン完了!'));
-    console.log(chalk.gray('📄 Generated files:'));
-    console.log(chalk.gray(`   - ${paths.getRelativePath(refactorResult.outputPath)}/ (${refactorResult.plan.summary.total_patches} patches)`));
-    console.log(chalk.gray(`   - ${paths.getRelativePath(testSynthResult.outputPath)} (${testSynthResult.generated_tests.length} tests)`));
-    console.log(chalk.gray(`   - ${paths.getRelativePath(migrationResult.outputPath)} (migration results)`));
-    console.log(chalk.gray(`   - ${paths.getRelativePath(reviewResult.outputPath)} (review report)`));
-    console.log(chalk.gray(`   - __generated__/tests/ (${testSynthesisResult.generatedTests.length} AI-generated tests)`));
-    console.log(chalk.gray(`   - __generated__/docs/ (${testSynthesisResult.generatedDocuments.length} user stories & specs)`));
-    
-    // Display key results
-    console.log(chalk.cyan('\n📊 実行結果サマリ:'));
-    console.log(chalk.gray(`   🧠 業務ロジック移行: ${businessLogicResult.migratedBoundaries.length}境界 (AI: ${businessLogicResult.aiProcessedFiles}, 静的: ${businessLogicResult.staticAnalysisFiles})`));
-    console.log(chalk.gray(`   🧪 AI生成テスト: ${testSynthesisResult.generatedTests.length}個 (カバレッジ向上推定: ${testSynthesisResult.coverageImprovement?.improvement || 'N/A'}%)`));
-    console.log(chalk.gray(`   📚 生成ドキュメント: ${testSynthesisResult.generatedDocuments.length}個のユーザーストーリー・仕様書`));
-    console.log(chalk.gray(`   🔄 パッチ適用: ${migrationResult.applied_patches.length}成功 / ${migrationResult.failed_patches.length}失敗`));
-    console.log(chalk.gray(`   ✅ ビルド: ${migrationResult.build_result.success ? '✅ 成功' : '❌ 失敗'}`));
-    console.log(chalk.gray(`   🧪 テスト: ${migrationResult.test_result.success ? '✅ 成功' : '❌ 失敗'}`));
-    console.log(chalk.gray(`   📋 総合評価: ${reviewResult.overall_assessment.grade}グレード`));
-    console.log(chalk.gray(`   🤖 自動マージ: ${reviewResult.auto_merge_decision.should_auto_merge ? '✅ 可能' : '❌ 手動レビュー必要'}`));
-    
-    if (!apply) {
-      console.log(chalk.yellow('\nℹ️  ドライランモード - 実際の変更は行われていません'));
-      console.log(chalk.yellow('   --applyフラグで実際の変更を適用できます'));
+    for (const [fileIndex, conflict] of conflicts.entries()) {
+      const fullPath = path.join(projectRoot, conflict.path);
+      const blocks = parseConflictBlocks(await fs.readFile(fullPath, 'utf8'));
+
+      console.log(chalk.blue(`\n⚔️  [${fileIndex + 1}/${conflicts.length}] ${conflict.path}${runLabel(conflict.run_id)}`));
+      console.log(chalk.gray(`   Module ${conflict.module}, generated from ${conflict.source}`));
+      const excerpt = planExcerpt(projectRoot, conflict.module);
+      if (excerpt.length > 0) {
+        console.log(chalk.gray('   Plan:'));
+        excerpt.forEach(line => console.log(chalk.gray(`     ${line}`)));
+      }
+
+      const choices: ('ours' | 'theirs' | 'both' | undefined)[] = [];
+      let action: 'next' | 'editor' | 'quit' = 'next';
+      for (const [blockIndex, block] of blocks.entries()) {
+        console.log(chalk.white(`\n   Hunk ${blockIndex + 1}/${blocks.length}`));
+        console.log(chalk.yellow(`   ── ${OURS_LABEL}`));
+        block.ours.forEach(line => console.log(chalk.yellow(`   - ${line}`)));
+        console.log(chalk.cyan(`   ── ${THEIRS_LABEL}`));
+        block.theirs.forEach(line => console.log(chalk.cyan(`   + ${line}`)));
+
+        const answer = (await ask('   [o]urs / [t]heirs / [b]oth / [e]ditor / [s]kip / [q]uit > ')).trim().toLowerCase();
+        if (answer === 'o') choices.push('ours');
+        else if (answer === 't') choices.push('theirs');
+        else if (answer === 'b') choices.push('both');
+        else if (answer === 'e') { action = 'editor'; break; }
+        else if (answer === 'q') { action = 'quit'; break; }
+        else choices.push(undefined);
+      }
+
+      if (choices.some(choice => choice)) {
+        await store.apply(conflict.path, choices, safetyManager);
+      }
+      if (action === 'editor') {
+        if (!choices.some(choice => choice)) {
+          await safetyManager.backupFile(fullPath);
+        }
+        const editor = process.env.VISUAL || process.env.EDITOR || 'vi';
+        rl.pause();
+        spawnSync(editor, [fullPath], { stdio: 'inherit', shell: true });
+        rl.resume();
+        store.markProgress(conflict.path, []);
+      }
+
+      const remaining = parseConflictBlocks(await fs.readFile(fullPath, 'utf8')).length;
+      console.log(remaining === 0
+        ? chalk.green(`   ✅ ${conflict.path} resolved`)
+        : chalk.yellow(`   ⏭️  ${remaining} hunks left in ${conflict.path}`));
+      if (action === 'quit') break;
+    }
+  } finally {
+    rl.close();
+  }
+
+  const left = store.unresolved();
+  if (left.length > 0) {
+    console.log(chalk.yellow(`\n⚠️  ${left.length} files still have conflicts - run 'vf resolve' again`));
+    return;
+  }
+  const runIds = [...new Set(conflicts.map(c => c.run_id).filter(id => id !== undefined))];
+  console.log(chalk.green('\n✅ All conflicts resolved'));
+  runIds.forEach(runId => console.log(chalk.gray(`   Re-run verification with: vf verify --run-id ${runId}`)));
+}
+
+/**
+ * Prompt-driven edits of the discovered boundaries, recorded in boundary.yaml so
+ * planning and later discoveries keep them
+ */
+async function runBoundaryEditor(projectRoot: string): Promise<void> {
+  const { BoundaryEditSession, writeCuration } = await import('./core/utils/boundary-curation.js');
+  const { DomainMapWriter } = await import('./core/utils/domain-map-writer.js');
+  const paths = new VibeFlowPaths(projectRoot);
+  const writer = new DomainMapWriter(projectRoot);
+  const map = writer.load();
+  if (!map) {
+    console.error(chalk.red(`❌ ${paths.getRelativePath(paths.domainMapPath)} not found (run "vf discover" first)`));
+    process.exit(1);
+  }
+
+  const session = new BoundaryEditSession(projectRoot, map.boundaries);
+  console.log(chalk.blue(`✏️  ${map.boundaries.length} boundaries - "help" lists the commands, "save" writes boundary.yaml`));
+  session.execute('list').output.forEach(line => console.log(chalk.gray(`   ${line}`)));
+
+  const readline = await import('readline');
+  const rl = readline.createInterface({ input: process.stdin, output: process.stdout, terminal: Boolean(process.stdin.isTTY) });
+  let saved = false;
+  rl.setPrompt('boundaries> ');
+  rl.prompt();
+  try {
+    for await (const line of rl) {
+      try {
+        const result = session.execute(line);
+        result.output.forEach(output => console.log(chalk.gray(`   ${output}`)));
+        if (result.exit === 'quit') break;
+        if (result.exit === 'save') {
+          const file = writeCuration(projectRoot, map.boundaries, session.boundaries);
+          writer.write({ ...map, boundaries: session.boundaries });
+          console.log(chalk.green(`✅ Saved ${session.boundaries.length} boundaries`));
+          console.log(chalk.gray(`   - ${path.relative(projectRoot, file)}`));
+          console.log(chalk.gray(`   - ${paths.getRelativePath(paths.domainMapPath)}`));
+          console.log(chalk.gray('   Re-run "vf plan" to plan the curated boundaries'));
+          saved = true;
+          break;
+        }
+      } catch (error) {
+        console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      }
+      rl.prompt();
+    }
+  } finally {
+    rl.close();
+  }
+  if (!saved && session.changed) {
+    console.log(chalk.yellow('⚠️  Changes discarded (not saved)'));
+  }
+}
+
+/**
+ * Re-run build and test verification for a run once its conflicts are resolved
+ */
+async function runVerify(projectRoot: string, runId: number): Promise<void> {
+  const { ConflictStore } = await import('./core/utils/merge-conflicts.js');
+  const { verifyGoProject } = await import('./core/utils/migration-commit.js');
+  const store = new PerformanceStore(projectRoot);
+  if (!store.getRun(runId)) {
+    console.error(chalk.red(`❌ Run ${runId} not found in ${PerformanceStore.storePath(projectRoot)}`));
+    process.exit(1);
+  }
+
+  const open = new ConflictStore(projectRoot).unresolved().filter(c => c.run_id === runId);
+  if (open.length > 0) {
+    console.error(chalk.red(`❌ Run ${runId} has ${open.length} files with unresolved conflicts - run 'vf resolve' first`));
+    process.exit(1);
+  }
+
+  console.log(chalk.blue(`🔍 Verifying run ${runId}...`));
+  const verification = verifyGoProject(projectRoot);
+  if (verification.build === undefined) {
+    console.log(chalk.yellow('⚠️  No Go project found - nothing to verify'));
+    return;
+  }
+
+  const tests = verification.tests ?? false;
+  store.finishRun(runId, { verification: { build: verification.build, tests, verified_at: new Date().toISOString() } });
+  console.log(verification.build ? chalk.green('   ✅ go build passed') : chalk.red('   ❌ go build failed'));
+  console.log(tests ? chalk.green('   ✅ go test passed') : chalk.red(`   ❌ go test ${verification.build ? 'failed' : 'skipped'}`));
+  if (!verification.build || !tests) {
+    process.exitCode = 1;
+    return;
+  }
+
+  try {
+    const verified = new ModuleStatusTracker(projectRoot, store).verifyRun(runId);
+    if (verified.length > 0) {
+      console.log(chalk.gray(`   Module status: ${verified.join(', ')} → verified`));
     }
-    
   } catch (error) {
-    console.error(chalk.red('❌ Error in refactor execution:'), error);
-    throw error;
+    console.warn(chalk.yellow(`⚠️  Module status not updated: ${getErrorMessage(error)}`));
+  }
+  try {
+    // The verified state is what `vf drift` measures divergence against
+    const plan: ArchitecturalPlan = JSON.parse(await fs.readFile(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    const { SHARED_KERNEL, captureDriftBaseline } = await import('./core/utils/drift-report.js');
+    captureDriftBaseline(projectRoot, driftModules(plan, SHARED_KERNEL), runId);
+  } catch {
+    // No plan yet; drift is measured against plan.json once there is one
   }
 }
 
@@ -261,10 +2229,11 @@ async function runIncrementalRefactor(projectRoot: string, options: {
   if (process.env.NODE_ENV !== 'test' && process.env.VITEST !== 'true') {
     try {
       await fs.access(planPath);
+      await fs.access(paths.planJsonPath);
       await fs.access(domainMapPath);
     } catch {
       throw new Error(
-        `Required files not found. Please run "vf plan" first to generate ${paths.getRelativePath(planPath)} and ${paths.getRelativePath(domainMapPath)}`
+        `Required files not found. Please run "vf plan" first to generate ${paths.getRelativePath(planPath)}, ${paths.getRelativePath(paths.planJsonPath)} and ${paths.getRelativePath(domainMapPath)}`
       );
     }
   } else {
@@ -297,7 +2266,7 @@ async function runIncrementalRefactor(projectRoot: string, options: {
     // 2. Generate refactoring patches
     console.log(chalk.blue('🏗️  Step 2/3: Generating refactoring patches...'));
     const refactorAgent = new RefactorAgent(absolutePath);
-    const refactorResult = await refactorAgent.generateRefactorPlan(planPath);
+    const refactorResult = await refactorAgent.generateRefactorPlan(paths.planJsonPath);
     
     // 3. Execute incremental migration
     console.log(chalk.blue('🔧 Step 3/3: Incremental patch application...'));
@@ -372,30 +2341,240 @@ async function runIncrementalRefactor(projectRoot: string, options: {
   }
 }
 
-// -----------------------------------------------------------------------------
-// CLI definition
-// -----------------------------------------------------------------------------
-const program = new Command()
-  .name('vf')
-  .description('VibeFlow CLI - modular monolith refactoring assistant')
-  .version('0.1.0');
+// -----------------------------------------------------------------------------
+// CLI definition
+// -----------------------------------------------------------------------------
+const program = new Command()
+  .name('vf')
+  .description('VibeFlow CLI - modular monolith refactoring assistant')
+  .version('0.1.0');
+
+const planCommand = program
+  .command('plan')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--check-constraints', 'validate an existing plan.json against boundary.yaml constraints')
+  .option('--deployment <module=target,...>', 'mark modules as separately deployable, e.g. notifications=service (kept across regenerations)')
+  .option('--target <modular-monolith|microservices>', 'microservices proposes which modules to extract as services, with their API, data and communication (kept across regenerations)')
+  .option('--migration <restructure|strangler-fig>', 'strangler-fig keeps the legacy code in place and routes callers through a facade per module, phase by phase (kept across regenerations)')
+  .option('--go-modules <layout>', `Go module layout after the migration: ${GO_MODULE_LAYOUTS.join(', ')}; vf refactor creates the go.mod/go.work files (kept across regenerations)`)
+  .option('--style <clean|hexagonal>', 'target architecture: four clean-architecture layers or ports & adapters (kept across regenerations)')
+  .option('--compare <styles>', 'design a plan per comma-separated style (e.g. clean,hexagonal) and compare files, interfaces, estimated cost and risk; plan.md and plan.json are not changed')
+  .option('--c4 <format>', `also write C4 container and component diagrams of the target state (${C4_FORMATS.join(', ')})`)
+  .option('--scope <dir>', 'plan a scope discovered with vf discover --scope (default: the only configured scope)')
+  .option('--reconsider-established <modules>', 'comma-separated established modules (own go.mod or boundary.yaml established:) to re-cluster and refactor again')
+  .description('Generate refactor plan')
+  .action(async (pathParam: string, options: any) => {
+    const path = scopedRoot(pathParam, options.scope);
+    if (options.checkConstraints) {
+      console.log(chalk.cyan('▶ checking plan constraints...'));
+      await checkPlanConstraintsCommand(path);
+      return;
+    }
+    let deployments: Record<string, ModuleDeployment> | undefined;
+    let style: ArchitectureStyle | undefined;
+    let target: PlanTarget | undefined;
+    let migration: MigrationMode | undefined;
+    let goModules: GoModuleLayout | undefined;
+    let compare: ArchitectureStyle[] | undefined;
+    try {
+      deployments = options.deployment ? parseDeployments(options.deployment) : undefined;
+      style = options.style ? parseArchitectureStyle(options.style) : undefined;
+      target = options.target ? parsePlanTarget(options.target) : undefined;
+      migration = options.migration ? parseMigrationMode(options.migration) : undefined;
+      goModules = options.goModules ? parseGoModuleLayout(options.goModules) : undefined;
+      compare = options.compare ? parseCompareStyles(options.compare, options.style) : undefined;
+      if (options.c4 !== undefined && !C4_FORMATS.includes(options.c4)) {
+        throw new Error(`Invalid --c4 '${options.c4}' (expected ${C4_FORMATS.join(', ')})`);
+      }
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+    if (compare) {
+      console.log(chalk.cyan('▶ comparing architectures...'));
+      await comparePlanTasks(path, compare, { deployments, target, migration, goModules, reconsiderEstablished: parseModuleList(options.reconsiderEstablished) });
+      return;
+    }
+    console.log(chalk.cyan('▶ generating plan...'));
+    await planTasks(path, { deployments, style, target, migration, goModules, reconsiderEstablished: parseModuleList(options.reconsiderEstablished), c4: options.c4 });
+  });
+
+planCommand
+  .command('sync')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--force', 'regenerate plan.md even if edits inside generated blocks could not be carried over')
+  .option('--scope <dir>', 'sync the plan of a scope discovered with vf discover --scope (default: the only configured scope)')
+  .description('Apply edits of plan.md (module names, descriptions, files, actions, phases and their order) to plan.json')
+  .action((pathParam: string, opts: { force?: boolean; scope?: string }) => {
+    try {
+      runPlanSync(path.resolve(scopedRoot(pathParam, opts.scope)), { force: opts.force });
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+  });
+
+planCommand
+  .command('validate')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--scope <dir>', 'validate the plan of a scope discovered with vf discover --scope (default: the only configured scope)')
+  .description('Check an edited plan.json against domain-map.json: modules, files, phases and their dependency order')
+  .action(async (pathParam: string, opts: { scope?: string }) => {
+    try {
+      await runPlanValidate(path.resolve(scopedRoot(pathParam, opts.scope)));
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+  });
+
+program
+  .command('validate')
+  .argument('[path]', 'target project root', 'workspace')
+  .description('Validate //vf: annotations: unknown directives and references to nonexistent boundaries')
+  .action(async (pathParam: string) => {
+    await runValidate(path.resolve(pathParam));
+  });
+
+program
+  .command('check')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--scope <dir>', 'check a scope discovered with vf discover --scope (default: the only configured scope)')
+  .description('Check the code against plan.json: boundary constraints and the rules of modules marked as services')
+  .action(async (pathParam: string, opts: { scope?: string }) => {
+    await runCheck(scopedRoot(pathParam, opts.scope));
+  });
 
 program
-  .command('plan')
+  .command('drift')
   .argument('[path]', 'target project root', 'workspace')
-  .description('Generate refactor plan')
-  .action(async (path: string) => {
-    console.log(chalk.cyan('▶ generating plan...'));
-    await planTasks(path);
+  .option('--format <format>', 'text, json or sarif', 'text')
+  .option('-o, --output <path>', 'write the json or sarif report to a file instead of stdout')
+  .option('--fail-above <score>', 'exit 1 when the drift score (0-100) is above the threshold')
+  .option('--rules <path>', 'business-rule catalog JSON (default: business rules in findings.json)')
+  .option('--update-baseline', 'record the current workspace as the baseline after reporting')
+  .description('Report how far the code has drifted from the accepted plan and the last verified state')
+  .action(async (pathParam: string, opts: { format?: string; output?: string; failAbove?: string; rules?: string; updateBaseline?: boolean }) => {
+    try {
+      await runDrift(path.resolve(pathParam), { ...opts, rules: opts.rules ? path.resolve(opts.rules) : undefined });
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
   });
 
 program
   .command('discover')
   .argument('[path]', 'target project root', 'workspace')
+  .option('--debt', 'print the modules and files with the most TODO/FIXME/HACK markers')
+  .option('--sample <rate>', 'analyze a representative sample of the files, e.g. 20% (exploratory)')
+  .option('--max-files <n>', 'analyze at most n representative files (exploratory)')
+  .option('--scope <dir>', 'discover a product directory on its own (repeatable; default: scopes of the config)', (value: string, previous: string[] = []) => [...previous, value])
+  .option('--path <pattern>', 'only discover a directory (./internal/billing) or subtree (./internal/billing/...) and merge it into domain-map.json (repeatable)', (value: string, previous: string[] = []) => [...previous, value])
+  .option('--package <pattern>', 'only discover the packages of an import path pattern (example.com/app/internal/billing/...) and merge them into domain-map.json (repeatable)', (value: string, previous: string[] = []) => [...previous, value])
+  .option('--reconsider-established <modules>', 'comma-separated established modules (own go.mod or boundary.yaml established:) to re-cluster and refactor again')
+  .option('--full', 're-cluster every file instead of merging the files changed since the last discovery into domain-map.json')
+  .option('--graph <format>', `also write the module graph with coupling weights (${GRAPH_FORMATS.join(', ')})`)
+  .option('--concurrency <n>', 'analyze packages in n parallel worker threads (default 1)')
+  .option('--target-modules <n>', 'split and merge the discovered modules towards n modules (implies --full; default: boundary.yaml clustering.targetModules)')
+  .option('--min-module-files <n>', 'merge modules with fewer files into the module they are most coupled to (implies --full)')
+  .option('--max-module-files <n>', 'split modules with more files along their package directories (implies --full)')
+  .option('--compare <domain-map>', 'report added, removed and moved files per boundary and cohesion/coupling changes against a previous domain-map.json')
+  .option('--min-cohesion <score>', 'exit 1 when a module\'s cohesion is below the score (0-1; default: boundary.yaml gates.minCohesion)')
+  .option('--max-coupling <score>', 'exit 1 when a module\'s coupling is above the score (0-1; default: boundary.yaml gates.maxCoupling)')
+  .option('--max-files-per-module <n>', 'exit 1 when a module has more files (default: boundary.yaml gates.maxFilesPerModule)')
   .description('AI-powered automatic boundary discovery (no config required)')
-  .action(async (path: string) => {
+  .action(async (path: string, opts: { debt?: boolean; sample?: string; maxFiles?: string; scope?: string[]; path?: string[]; package?: string[]; reconsiderEstablished?: string; full?: boolean; graph?: string; concurrency?: string; targetModules?: string; minModuleFiles?: string; maxModuleFiles?: string; compare?: string; minCohesion?: string; maxCoupling?: string; maxFilesPerModule?: string }) => {
+    let sampling: SamplingOptions | undefined;
+    let concurrency: number | undefined;
+    let granularity: GranularityOptions | undefined;
+    let gates: DiscoveryGates | undefined;
+    try {
+      const positive = (value: string | undefined, option: string) => {
+        if (value === undefined) return undefined;
+        const n = Number(value);
+        if (!Number.isInteger(n) || n < 1) throw new Error(`Invalid ${option} '${value}' (expected a positive integer)`);
+        return n;
+      };
+      const targetModules = positive(opts.targetModules, '--target-modules');
+      const minFiles = positive(opts.minModuleFiles, '--min-module-files');
+      const maxFiles = positive(opts.maxModuleFiles, '--max-module-files');
+      if (minFiles !== undefined && maxFiles !== undefined && minFiles > maxFiles) {
+        throw new Error(`--min-module-files ${minFiles} exceeds --max-module-files ${maxFiles}`);
+      }
+      if (targetModules !== undefined || minFiles !== undefined || maxFiles !== undefined) {
+        granularity = {
+          ...(targetModules !== undefined ? { targetModules } : {}),
+          ...(minFiles !== undefined ? { minFiles } : {}),
+          ...(maxFiles !== undefined ? { maxFiles } : {}),
+        };
+      }
+      const score = (value: string | undefined, option: string) => {
+        if (value === undefined) return undefined;
+        const n = Number(value);
+        if (value.trim() === '' || !Number.isFinite(n) || n < 0 || n > 1) throw new Error(`Invalid ${option} '${value}' (expected a score between 0 and 1)`);
+        return n;
+      };
+      const minCohesion = score(opts.minCohesion, '--min-cohesion');
+      const maxCoupling = score(opts.maxCoupling, '--max-coupling');
+      const maxFilesPerModule = positive(opts.maxFilesPerModule, '--max-files-per-module');
+      if (minCohesion !== undefined || maxCoupling !== undefined || maxFilesPerModule !== undefined) {
+        gates = {
+          ...(minCohesion !== undefined ? { minCohesion } : {}),
+          ...(maxCoupling !== undefined ? { maxCoupling } : {}),
+          ...(maxFilesPerModule !== undefined ? { maxFilesPerModule } : {}),
+        };
+      }
+      if (opts.concurrency !== undefined) {
+        concurrency = Number(opts.concurrency);
+        if (!Number.isInteger(concurrency) || concurrency < 1) {
+          throw new Error(`Invalid --concurrency '${opts.concurrency}' (expected a positive integer)`);
+        }
+      }
+      if (opts.graph !== undefined && !GRAPH_FORMATS.includes(opts.graph as GraphFormat)) {
+        throw new Error(`Invalid --graph '${opts.graph}' (expected ${GRAPH_FORMATS.join(', ')})`);
+      }
+      if (opts.path || opts.package) {
+        if (opts.scope) throw new Error('--path / --package cannot be combined with --scope');
+        if (opts.sample !== undefined || opts.maxFiles !== undefined) {
+          throw new Error('--path / --package merge into domain-map.json and cannot be combined with --sample / --max-files');
+        }
+      }
+      if (opts.sample !== undefined || opts.maxFiles !== undefined) {
+        const maxFiles = opts.maxFiles !== undefined ? Number(opts.maxFiles) : undefined;
+        if (maxFiles !== undefined && (!Number.isInteger(maxFiles) || maxFiles < 1)) {
+          throw new Error(`Invalid --max-files '${opts.maxFiles}' (expected a positive integer)`);
+        }
+        sampling = {
+          ...(opts.sample !== undefined ? { rate: parseSampleRate(opts.sample) } : {}),
+          ...(maxFiles !== undefined ? { max_files: maxFiles } : {}),
+        };
+      }
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
     console.log(chalk.magenta('▶ AI automatic boundary discovery...'));
-    await runAutomaticBoundaryDiscovery(path);
+    try {
+      await runAutomaticBoundaryDiscovery(path, {
+        debt: opts.debt,
+        sampling,
+        scopes: opts.scope,
+        paths: opts.path,
+        packages: opts.package,
+        reconsiderEstablished: parseModuleList(opts.reconsiderEstablished),
+        // The previous boundaries of unchanged files would keep the old granularity
+        full: opts.full || granularity !== undefined,
+        graph: opts.graph as GraphFormat | undefined,
+        concurrency,
+        granularity,
+        gates,
+        compare: opts.compare,
+      });
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
   });
 
 program
@@ -411,8 +2590,24 @@ program
   .option('--clear-checkpoint', 'clear existing checkpoint and start fresh')
   .option('--from-step <step>', 'resume from specific step (boundary, migration, refactor, test, review)')
   .option('--only-files <files...>', 'process only specified files or patterns')
+  .option('-m, --module <name>', 'refactor a single module from the domain map; only --module, --resume-skipped and --async-batch runs can skip modules ("s" or vf control skip-module)')
+  .option('--clean-module', 'remove previously generated outputs of the module before regenerating')
+  .option('--resume-skipped [runId]', 'refactor modules skipped in a previous run (default: latest)')
+  .option('--commit', 'commit the applied modules with a generated Conventional Commits message')
+  .option('--allow-degraded', 'refactor modules containing files whose package failed to load')
+  .option('--offline', 'generate from templates only; fail on any network access')
+  .option('--require-llm', 'fail a module instead of falling back to templates when the LLM is unavailable')
+  .option('--reopen-accepted', 'overwrite modules already accepted by a reviewer without asking')
+  .option('--no-escalation', 'do not retry malformed or unverifiable files with the next model of llm.escalation')
+  .option('--scope <dir>', 'refactor a scope discovered with vf discover --scope (default: the only configured scope)')
+  .option('--async-batch', 'submit the module prompts as async batch jobs (cheaper, results take minutes to hours)')
+  .option('--collect <runId>', 'apply the results of an --async-batch run that are ready')
+  .option('--wait', 'with --collect, poll until every batch job of the run has ended')
+  .option('--with-mutation-check', 'run the tests of modules with synthesized tests against a few mutants and report kill rates')
+  .option('--max-auto-apply-tier <tier>', `apply only modules up to this risk tier of the plan (${AUTONOMY_TIERS.join(', ')}; default: autonomy.maxAutoApplyTier)`)
+  .option('--force', 'apply modules above the allowed risk tier')
   .description('Execute refactor according to plan')
-  .action(async (pathParam: string, opts: { 
+  .action(async (projectParam: string, opts: { 
     apply?: boolean; 
     incremental?: boolean;
     maxStageSize?: string;
@@ -423,8 +2618,68 @@ program
     clearCheckpoint?: boolean;
     fromStep?: string;
     onlyFiles?: string[];
+    module?: string;
+    cleanModule?: boolean;
+    resumeSkipped?: string | true;
+    commit?: boolean;
+    allowDegraded?: boolean;
+    offline?: boolean;
+    requireLlm?: boolean;
+    reopenAccepted?: boolean;
+    escalation?: boolean;
+    scope?: string;
+    asyncBatch?: boolean;
+    collect?: string;
+    wait?: boolean;
+    withMutationCheck?: boolean;
+    maxAutoApplyTier?: string;
+    force?: boolean;
   }) => {
+    const pathParam = scopedRoot(projectParam, opts.scope);
     console.log(chalk.green('▶ running refactor...'));
+
+    if (opts.offline && opts.requireLlm) {
+      throw new Error('--offline and --require-llm cannot be combined');
+    }
+    if (opts.requireLlm && !opts.module && !opts.resumeSkipped) {
+      throw new Error('--require-llm requires --module <name> (or --resume-skipped)');
+    }
+    if (opts.offline) {
+      enforceOffline();
+      console.log(chalk.gray('   Offline: template generation only, network access fails the run'));
+    }
+    const generationMode: GenerationMode = opts.offline ? 'template' : opts.requireLlm ? 'llm' : 'auto';
+
+    if (opts.asyncBatch && (opts.offline || opts.incremental || opts.resume || opts.collect)) {
+      throw new Error('--async-batch cannot be combined with --offline, --incremental, --resume or --collect');
+    }
+    if (opts.withMutationCheck && (!opts.apply || opts.module || opts.resumeSkipped || opts.incremental || opts.asyncBatch)) {
+      throw new Error('--with-mutation-check requires --apply and runs after test synthesis of the full refactor pipeline (not with --module, --resume-skipped, --incremental or --async-batch)');
+    }
+    if (opts.wait && !opts.collect) {
+      throw new Error('--wait requires --collect <run-id>');
+    }
+    if (opts.maxAutoApplyTier !== undefined && !AUTONOMY_TIERS.includes(opts.maxAutoApplyTier as AutonomyTier)) {
+      throw new Error(`--max-auto-apply-tier must be one of: ${AUTONOMY_TIERS.join(', ')}`);
+    }
+    if ((opts.maxAutoApplyTier || opts.force) && (!opts.apply || (!opts.module && !opts.resumeSkipped && !opts.asyncBatch))) {
+      throw new Error('--max-auto-apply-tier and --force require --apply and --module <name> (or --resume-skipped, --async-batch)');
+    }
+    const autonomy = { maxAutoApplyTier: opts.maxAutoApplyTier as AutonomyTier | undefined, force: opts.force ?? false };
+    let batchProvider: BatchProvider | undefined;
+    if (opts.asyncBatch || opts.collect) {
+      try {
+        batchProvider = resolveBatchProvider();
+      } catch (error) {
+        if (!(error instanceof BatchUnsupportedError)) throw error;
+        console.error(chalk.red(`❌ ${error.message}`));
+        process.exit(1);
+      }
+    }
+    if (opts.collect) {
+      await collectBatchRun(path.resolve(pathParam), opts.collect, batchProvider!, { wait: opts.wait });
+      return;
+    }
     
     // Handle resume flow first
     const absolutePath = path.resolve(pathParam);
@@ -440,7 +2695,46 @@ program
       return; // Exit after clearing checkpoint
     }
     
-    if (opts.incremental) {
+    if (opts.cleanModule && !opts.module && !opts.resumeSkipped) {
+      throw new Error('--clean-module requires --module <name>');
+    }
+    if (opts.commit && (!opts.apply || (!opts.module && !opts.resumeSkipped))) {
+      throw new Error('--commit requires --apply and --module <name> (or --resume-skipped)');
+    }
+    
+    if (opts.resumeSkipped) {
+      const { runId, modules } = findSkippedModules(absolutePath, opts.resumeSkipped);
+      if (modules.length === 0) {
+        console.log(chalk.gray(`ℹ️  Run ${runId} has no skipped modules`));
+        return;
+      }
+      console.log(chalk.cyan(`⏯️  Resuming ${modules.length} module(s) skipped in run ${runId}`));
+      await runModuleRefactor(absolutePath, modules, {
+        apply: opts.apply ?? false,
+        cleanModule: opts.cleanModule ?? false,
+        commit: opts.commit ?? false,
+        allowDegraded: opts.allowDegraded ?? false,
+        generationMode,
+        reopenAccepted: opts.reopenAccepted ?? false,
+        escalation: opts.escalation ?? true,
+        batchProvider,
+        ...autonomy,
+      });
+    } else if (opts.module || batchProvider) {
+      // An async batch without --module covers every module of the domain map
+      const modules = opts.module ? [opts.module] : await domainMapModules(absolutePath);
+      await runModuleRefactor(absolutePath, modules, {
+        apply: opts.apply ?? false,
+        cleanModule: opts.cleanModule ?? false,
+        commit: opts.commit ?? false,
+        allowDegraded: opts.allowDegraded ?? false,
+        generationMode,
+        reopenAccepted: opts.reopenAccepted ?? false,
+        escalation: opts.escalation ?? true,
+        batchProvider,
+        ...autonomy,
+      });
+    } else if (opts.incremental) {
       console.log(chalk.cyan('🔄 インクリメンタルモード - 段階的に安全に実行します'));
       await runIncrementalRefactor(pathParam, {
         apply: opts.apply ?? false,
@@ -449,7 +2743,7 @@ program
         skipStages: opts.skipStages ? opts.skipStages.split(',').map(n => parseInt(n.trim())) : [],
       });
     } else {
-      await runRefactor(pathParam, opts.apply ?? false, shouldResume ? resumeOptions : undefined);
+      await runRefactor(pathParam, opts.apply ?? false, shouldResume ? resumeOptions : undefined, { mutationCheck: opts.withMutationCheck });
     }
   });
 
@@ -806,6 +3100,630 @@ program
     }
   });
 
+const metricsCommand = program
+  .command('metrics')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--run-id <id>', 'show a recorded run and the inputs it used')
+  .option('--env', 'with --run-id: the environment the run started with (versions, model, config and prompt hashes)')
+  .option('--json', 'with --run-id: print the run record, environment included, as JSON')
+  .option('--fallbacks', 'list runs in which modules were downgraded to templates because the LLM was unavailable')
+  .option('--escalations', 'how often files needed a stronger model (llm.escalation) and how often it helped')
+  .option('--agent <name>', 'per-file processing records of one agent, e.g. RefactorAgent')
+  .option('--method <method>', `per-file processing records of one method (${PROCESSING_METHODS.join(', ')})`)
+  .option('--export <format>', `per-file processing records as ${FILE_PROCESSING_FORMATS.join(', ')} (filtered by --run-id, --agent, --method)`)
+  .option('--scope <dir>', 'metrics of a scope discovered with vf discover --scope (default: the only configured scope)')
+  .description('Inspect recorded run metrics')
+  .action(async (projectParam: string, opts: {
+    runId?: string;
+    env?: boolean;
+    json?: boolean;
+    fallbacks?: boolean;
+    escalations?: boolean;
+    agent?: string;
+    method?: string;
+    export?: string;
+    scope?: string;
+  }) => {
+    const pathParam = scopedRoot(projectParam, opts.scope);
+    if (opts.agent || opts.method || opts.export) {
+      showFileProcessing(path.resolve(pathParam), opts);
+      return;
+    }
+    if (opts.fallbacks) {
+      showFallbackRuns(path.resolve(pathParam), opts.runId !== undefined ? Number(opts.runId) : undefined);
+      return;
+    }
+    if (opts.escalations) {
+      showEscalations(path.resolve(pathParam), opts.runId !== undefined ? Number(opts.runId) : undefined);
+      return;
+    }
+    if (!opts.runId) {
+      metricsCommand.help();
+    }
+    showRunMetrics(path.resolve(pathParam), Number(opts.runId), { env: opts.env, json: opts.json });
+  });
+
+metricsCommand
+  .command('artifacts')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('--run-id <id>', 'run whose inputs to inspect')
+  .option('--extract <dir>', 'write the domain map, plan, boundary.yaml, config and prompts the run used')
+  .description('Inspect or materialize the inputs a run used')
+  .action(async (pathParam: string, opts: { runId: string; extract?: string }) => {
+    const projectRoot = path.resolve(pathParam);
+    const runId = Number(opts.runId);
+    const store = new RunArtifactStore(projectRoot);
+
+    try {
+      if (opts.extract) {
+        const written = store.extract(runId, path.resolve(opts.extract));
+        console.log(chalk.green(`✅ Extracted ${written.length} artifacts of run ${runId} to ${opts.extract}`));
+        written.forEach(file => console.log(chalk.gray(`   - ${path.relative(path.resolve(opts.extract!), file)}`)));
+        return;
+      }
+      printArtifactComparison(store, runId);
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+  });
+
+metricsCommand
+  .command('aggregate')
+  .requiredOption('-w, --workspaces <list>', 'comma separated workspace directories or glob')
+  .option('-e, --export <format>', 'export format: csv, json or prometheus')
+  .option('-o, --output <path>', 'write export to file instead of stdout')
+  .description('Aggregate run metrics across multiple workspaces')
+  .action(async (opts: { workspaces: string; export?: string; output?: string }) => {
+    const { runMetricsAggregate } = await import('./core/utils/metrics-aggregator.js');
+    await runMetricsAggregate(opts);
+  });
+
+metricsCommand
+  .command('methods')
+  .argument('[path]', 'target project root', 'workspace')
+  .description('Compare static, template and LLM processing across recorded runs and evaluations')
+  .action(async (pathParam: string) => {
+    const { summarizeMethods, QUALITY_METRICS } = await import('./core/utils/method-evaluation.js');
+    const summaries = summarizeMethods(new PerformanceStore(path.resolve(pathParam), { readOnly: true }));
+    const byMethod = Object.fromEntries(summaries.map(s => [s.method, s]));
+    const percent = (value: number | null | undefined) => value === null || value === undefined ? '-' : `${(value * 100).toFixed(0)}%`;
+    const number = (value: number | undefined) => value === undefined ? '-' : Number.isInteger(value) ? String(value) : value.toFixed(2);
+    const row = (label: string, cells: string[]) => `   ${label.padEnd(18)}${cells.map(c => c.padStart(12)).join('')}`;
+
+    console.log(chalk.blue('📊 Processing methods'));
+    console.log(chalk.gray(row('', [...summaries.map(s => s.method), 'Δ llm-tmpl'])));
+    console.log(row('Files', summaries.map(s => String(s.files))));
+    console.log(row('Success rate', summaries.map(s => percent(s.success_rate))));
+    console.log(row('Avg duration', summaries.map(s => s.avg_duration_ms === null ? '-' : `${(s.avg_duration_ms / 1000).toFixed(1)}s`)));
+    console.log(row('Evaluations', summaries.map(s => String(s.evaluations))));
+
+    for (const metric of QUALITY_METRICS) {
+      const values = summaries.map(s => s.quality[metric.key]);
+      if (values.every(v => v === undefined)) continue;
+      const rate = metric.key === 'compiles' || metric.key.endsWith('_rate');
+      const llm = byMethod.llm?.quality[metric.key];
+      const template = byMethod.template?.quality[metric.key];
+      let delta = '-';
+      if (llm !== undefined && template !== undefined) {
+        const diff = llm - template;
+        const text = `${diff >= 0 ? '+' : ''}${rate ? `${(diff * 100).toFixed(0)}pt` : diff.toFixed(2)}`;
+        const better = metric.better === 'higher' ? diff > 0 : diff < 0;
+        delta = diff === 0 ? text : better ? chalk.green(text) : chalk.red(text);
+      }
+      console.log(row(metric.label, [...values.map(v => rate ? percent(v) : number(v)), delta]));
+    }
+
+    if (summaries.every(s => s.evaluations === 0)) {
+      console.log(chalk.gray('   No quality data yet; run "vf evaluate-methods --module <name>"'));
+    }
+  });
+
+metricsCommand
+  .command('prompts')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--task <task>', 'only calls of one task (e.g. refactor)')
+  .option('--compare <hashes...>', 'compare two template versions (hash prefixes): base candidate')
+  .option('--json', 'print the summaries or the comparison as JSON')
+  .description('Token usage, retries, malformed responses, verification failures and cost per prompt template version')
+  .action(async (pathParam: string, opts: { task?: string; compare?: string[]; json?: boolean }) => {
+    const { summarizePromptTemplates, comparePromptTemplates } = await import('./core/utils/prompt-metrics.js');
+    const summaries = summarizePromptTemplates(new PerformanceStore(path.resolve(pathParam), { readOnly: true }).getLlmCalls(), { task: opts.task });
+    const percent = (value: number) => `${(value * 100).toFixed(0)}%`;
+
+    if (opts.compare) {
+      if (opts.compare.length !== 2) {
+        console.error(chalk.red('❌ --compare takes two template hashes: base candidate'));
+        process.exit(1);
+      }
+      let comparison;
+      try {
+        comparison = comparePromptTemplates(summaries, opts.compare[0], opts.compare[1], opts.task);
+      } catch (error) {
+        console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+        process.exit(1);
+      }
+      if (opts.json) {
+        console.log(JSON.stringify(comparison, null, 2));
+        return;
+      }
+
+      const { base, candidate, delta } = comparison;
+      const row = (label: string, from: string, to: string, change: number, better: 'lower' | 'higher', text: string) => {
+        const colored = change === 0 ? text : (better === 'lower' ? change < 0 : change > 0) ? chalk.green(text) : chalk.red(text);
+        console.log(`   ${label.padEnd(22)}${from.padStart(12)}${to.padStart(12)}   ${colored}`);
+      };
+      const signed = (value: number, digits = 0) => `${value >= 0 ? '+' : ''}${value.toFixed(digits)}`;
+      console.log(chalk.blue(`📝 ${comparison.task}: ${base.template_hash.slice(0, 12)} → ${candidate.template_hash.slice(0, 12)}`));
+      console.log(chalk.gray(`   ${''.padEnd(22)}${'base'.padStart(12)}${'candidate'.padStart(12)}   Δ`));
+      console.log(`   ${'Calls'.padEnd(22)}${String(base.calls).padStart(12)}${String(candidate.calls).padStart(12)}`);
+      row('Avg input tokens', base.avg_input_tokens.toFixed(0), candidate.avg_input_tokens.toFixed(0), delta.avg_input_tokens, 'lower', signed(delta.avg_input_tokens));
+      row('Avg output tokens', base.avg_output_tokens.toFixed(0), candidate.avg_output_tokens.toFixed(0), delta.avg_output_tokens, 'lower', signed(delta.avg_output_tokens));
+      row('Retry rate', percent(base.retry_rate), percent(candidate.retry_rate), delta.retry_rate, 'lower', `${signed(delta.retry_rate * 100)}pt`);
+      row('Malformed', percent(base.malformed_rate), percent(candidate.malformed_rate), delta.malformed_rate, 'lower', `${signed(delta.malformed_rate * 100)}pt`);
+      row('Verification failures', percent(base.verification_failure_rate), percent(candidate.verification_failure_rate), delta.verification_failure_rate, 'lower', `${signed(delta.verification_failure_rate * 100)}pt`);
+      row('Cost per module', `$${base.avg_cost_per_module.toFixed(4)}`, `$${candidate.avg_cost_per_module.toFixed(4)}`, delta.avg_cost_per_module, 'lower', signed(delta.avg_cost_per_module, 4));
+      for (const module of comparison.modules) {
+        console.log(chalk.gray(`     ${module.module.padEnd(20)}${`$${module.base_cost.toFixed(4)}`.padStart(12)}${`$${module.candidate_cost.toFixed(4)}`.padStart(12)}`));
+      }
+      return;
+    }
+
+    if (opts.json) {
+      console.log(JSON.stringify(summaries, null, 2));
+      return;
+    }
+    if (summaries.length === 0) {
+      console.log(chalk.gray('ℹ️  No LLM calls recorded yet - they are recorded by "vf refactor" runs that use the LLM'));
+      return;
+    }
+
+    console.log(chalk.blue('📝 Prompt templates'));
+    console.log(chalk.gray(`   ${'Task'.padEnd(10)}${'Hash'.padEnd(14)}${'Calls'.padStart(6)}${'Avg in'.padStart(9)}${'Avg out'.padStart(9)}${'Retry'.padStart(7)}${'Malformed'.padStart(11)}${'Verify'.padStart(8)}${'$/module'.padStart(10)}  Template`));
+    for (const summary of summaries) {
+      console.log(`   ${summary.task.padEnd(10)}${summary.template_hash.slice(0, 12).padEnd(14)}${String(summary.calls).padStart(6)}` +
+        `${summary.avg_input_tokens.toFixed(0).padStart(9)}${summary.avg_output_tokens.toFixed(0).padStart(9)}${percent(summary.retry_rate).padStart(7)}` +
+        `${percent(summary.malformed_rate).padStart(11)}${percent(summary.verification_failure_rate).padStart(8)}${summary.avg_cost_per_module.toFixed(4).padStart(10)}  ${summary.template}`);
+    }
+    console.log(chalk.gray('   Compare two versions with --compare <base> <candidate>'));
+  });
+
+const queryCommand = program
+  .command('query')
+  .description('Answer architecture questions from the persisted domain map (no re-analysis)');
+
+/**
+ * Run a query against the domain map of the project and print it in the requested format
+ */
+async function printQuery(pathParam: string, opts: { format: string; scope?: string }, build: (map: DomainMap) => QueryResult): Promise<void> {
+  try {
+    if (!QUERY_FORMATS.includes(opts.format as QueryFormat)) {
+      throw new Error(`Unknown format '${opts.format}' (use ${QUERY_FORMATS.join(', ')})`);
+    }
+    const result = build(loadQueryDomainMap(path.resolve(scopedRoot(pathParam, opts.scope))));
+    process.stdout.write(formatQueryResult(result, opts.format as QueryFormat));
+  } catch (error) {
+    console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+    process.exit(1);
+  }
+}
+
+queryCommand
+  .command('deps')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('--of <boundary>', 'boundary whose dependencies are listed')
+  .option('--direction <direction>', 'out: what it depends on, in: what depends on it', 'out')
+  .option('--format <format>', 'table, json or csv', 'table')
+  .option('--scope <dir>', 'query a scope discovered with vf discover --scope')
+  .description('Boundaries a boundary depends on, or that depend on it')
+  .action(async (pathParam: string, opts: { of: string; direction: string; format: string; scope?: string }) => {
+    await printQuery(pathParam, opts, map => {
+      if (opts.direction !== 'in' && opts.direction !== 'out') throw new Error(`--direction must be in or out, got '${opts.direction}'`);
+      return queryDeps(map, { of: opts.of, direction: opts.direction });
+    });
+  });
+
+queryCommand
+  .command('files')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('--boundary <name>', 'boundary whose files are listed')
+  .option('--to <boundary>', 'count only references to this boundary')
+  .option('--min-coupling <references>', 'only files with at least this many references to other boundaries')
+  .option('--format <format>', 'table, json or csv', 'table')
+  .option('--scope <dir>', 'query a scope discovered with vf discover --scope')
+  .description('Files of a boundary and their coupling to other boundaries')
+  .action(async (pathParam: string, opts: { boundary: string; to?: string; minCoupling?: string; format: string; scope?: string }) => {
+    await printQuery(pathParam, opts, map => {
+      const minCoupling = opts.minCoupling !== undefined ? Number(opts.minCoupling) : undefined;
+      if (minCoupling !== undefined && !Number.isInteger(minCoupling)) throw new Error(`--min-coupling must be an integer, got '${opts.minCoupling}'`);
+      return queryFiles(map, { boundary: opts.boundary, to: opts.to, minCoupling });
+    });
+  });
+
+queryCommand
+  .command('tables')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--table <name>', 'only the boundaries that access this table')
+  .option('--format <format>', 'table, json or csv', 'table')
+  .option('--scope <dir>', 'query a scope discovered with vf discover --scope')
+  .description('Boundaries that access each table')
+  .action(async (pathParam: string, opts: { table?: string; format: string; scope?: string }) => {
+    await printQuery(pathParam, opts, map => queryTables(map, opts.table));
+  });
+
+queryCommand
+  .command('routes')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--module <name>', 'only the routes of this module')
+  .option('--format <format>', 'table, json or csv', 'table')
+  .option('--scope <dir>', 'query a scope discovered with vf discover --scope')
+  .description('API endpoints of each module')
+  .action(async (pathParam: string, opts: { module?: string; format: string; scope?: string }) => {
+    await printQuery(pathParam, opts, map => queryRoutes(map, opts.module));
+  });
+
+const cacheCommand = program
+  .command('cache')
+  .description('Inspect or clear the per-file analysis cache');
+
+cacheCommand
+  .command('stats')
+  .argument('[path]', 'target project root', 'workspace')
+  .description('Show analysis cache size and hit/miss counts')
+  .action(async (pathParam: string) => {
+    const { AnalysisCache, ANALYZER_VERSION } = await import('./core/utils/analysis-cache.js');
+    const { formatBytes } = await import('./core/utils/workspace-cleaner.js');
+    const stats = new AnalysisCache(path.resolve(pathParam)).stats();
+    const lookups = stats.total_hits + stats.total_misses;
+
+    console.log(chalk.blue(`🗃️  Analysis cache (analyzer v${ANALYZER_VERSION})`));
+    console.log(chalk.gray(`   Entries: ${stats.entries} (${formatBytes(stats.bytes)})`));
+    console.log(chalk.gray(`   Hits: ${stats.total_hits}, misses: ${stats.total_misses}` +
+      (lookups > 0 ? ` (${((stats.total_hits / lookups) * 100).toFixed(1)}% hit rate)` : '')));
+    if (stats.total_corrupted > 0) {
+      console.log(chalk.yellow(`   Corrupted entries rebuilt: ${stats.total_corrupted}`));
+    }
+    if (stats.entries > 0 && stats.analyzer_version !== ANALYZER_VERSION) {
+      console.log(chalk.yellow(`   Entries were written by analyzer v${stats.analyzer_version} and will be rebuilt`));
+    }
+  });
+
+cacheCommand
+  .command('clear')
+  .argument('[path]', 'target project root', 'workspace')
+  .description('Remove every analysis cache entry')
+  .action(async (pathParam: string) => {
+    const { AnalysisCache } = await import('./core/utils/analysis-cache.js');
+    try {
+      new AnalysisCache(path.resolve(pathParam)).clear();
+      console.log(chalk.green('✅ Analysis cache cleared'));
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+  });
+
+const reportCommand = program
+  .command('report')
+  .description('Generate reports from persisted refactoring artifacts');
+
+reportCommand
+  .command('data-mapping')
+  .argument('[path]', 'target project root', 'workspace')
+  .description('Map every table column to the new module entity field (CSV and JSON)')
+  .action(async (pathParam: string) => {
+    const absolutePath = path.resolve(pathParam);
+    const { DataMappingReporter } = await import('./core/utils/data-mapping-report.js');
+    const reporter = new DataMappingReporter(absolutePath);
+    const report = reporter.build();
+    const { jsonPath, csvPath } = reporter.write(report);
+    const paths = new VibeFlowPaths(absolutePath);
+
+    const unmapped = report.mappings.filter(m => m.changes.includes('unmapped')).length;
+    console.log(chalk.green(`✅ Data mapping report: ${report.tables.length} tables, ${report.mappings.length} columns (${unmapped} unmapped)`));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(jsonPath)}`));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(csvPath)}`));
+    if (report.split_ownership_risks.length > 0) {
+      console.log(chalk.yellow(`⚠️  Split ownership risk: ${report.split_ownership_risks.join(', ')}`));
+    }
+  });
+
+reportCommand
+  .command('api-surface')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--apply-unexport', 'unexport symbols without references outside their package (verified with go vet)')
+  .description('List exported symbols of the generated modules that can be unexported or narrowed (JSON and HTML)')
+  .action(async (pathParam: string, opts: { applyUnexport?: boolean }) => {
+    const absolutePath = path.resolve(pathParam);
+    const { ApiSurfaceReporter } = await import('./core/utils/api-surface.js');
+    const reporter = new ApiSurfaceReporter(absolutePath);
+    const paths = new VibeFlowPaths(absolutePath);
+    let report = reporter.build();
+
+    if (opts.applyUnexport) {
+      const { FileSafetyManager } = await import('./core/utils/file-safety.js');
+      const result = await reporter.applyUnexports(report, new FileSafetyManager(absolutePath));
+      for (const applied of result.applied) {
+        console.log(chalk.green(`✅ ${applied.module}: unexported ${applied.symbols.length} symbols in ${applied.files.length} files`));
+      }
+      for (const failed of result.failed) {
+        console.log(chalk.red(`❌ ${failed.module}: rolled back, the rewrite does not compile`));
+        console.log(chalk.gray(failed.error.split('\n').map(line => `   ${line}`).join('\n')));
+      }
+      report = reporter.build();
+    }
+
+    const { jsonPath, htmlPath } = reporter.write(report);
+    try {
+      reporter.recordMetrics(report);
+    } catch (error) {
+      console.warn(chalk.yellow(`⚠️  API surface metrics skipped: ${getErrorMessage(error)}`));
+    }
+
+    const { totals } = report;
+    console.log(chalk.green(`✅ API surface: ${totals.exported} exported symbols in ${report.modules.length} modules`));
+    console.log(`   ${totals.unexport} safe to unexport, ${totals.single_consumer} with a single consumer, ${totals.public_after} public after the suggestions`);
+    console.log(chalk.gray(`   - ${paths.getRelativePath(jsonPath)}`));
+    console.log(chalk.gray(`   - ${paths.getRelativePath(htmlPath)}`));
+  });
+
+reportCommand
+  .command('findings')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--business-rules', 'also locate business rules (static extraction of every Go file)')
+  .description('Locate boundary violations, dependency cycles and business rules (JSON, SARIF and file:line:col)')
+  .action(async (pathParam: string, opts: { businessRules?: boolean }) => {
+    await runFindingsReport(path.resolve(pathParam), opts);
+  });
+
+program
+  .command('clean')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--cache', 'analysis and LLM caches')
+  .option('--backups', 'backup runs beyond the keep-last retention (backups.keepLast)')
+  .option('--previews', 'generated patch previews')
+  .option('--reports', 'generated reports and results')
+  .option('--all', 'cache, backups, previews and reports')
+  .option('--metrics', 'run metrics (performance store); never included by --all')
+  .option('--plans', 'domain map and plan files; never included by --all')
+  .option('--dry-run', 'list paths and sizes without deleting')
+  .description('Safely remove .vibeflow artifacts by scope (boundary.yaml is never touched)')
+  .action(async (pathParam: string, opts: {
+    cache?: boolean; backups?: boolean; previews?: boolean; reports?: boolean;
+    all?: boolean; metrics?: boolean; plans?: boolean; dryRun?: boolean;
+  }) => {
+    await runClean(path.resolve(pathParam), opts);
+  });
+
+program
+  .command('shared-state')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--accept <id>', 'record an accepted resolution for a finding in the plan')
+  .option('--resolution <strategy>', 'owning-module-interface, injected-dependency or cache-service')
+  .option('--note <text>', 'note stored with the resolution')
+  .description('List package-level mutable state shared across module boundaries')
+  .action(async (pathParam: string, opts: { accept?: string; resolution?: string; note?: string }) => {
+    await runSharedState(path.resolve(pathParam), opts);
+  });
+
+program
+  .command('migrate-callers')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('-m, --module <name>', 'extracted module from the domain map')
+  .option('-p, --package <dir>', 'consumer package to migrate (omit to only report adoption)')
+  .option('--apply', 'apply the accepted rewrites (verified with go vet, rolled back on failure)')
+  .option('-y, --yes', 'accept every mechanical rewrite without asking')
+  .description('Move legacy call sites of a consumer package onto the usecase interface of an extracted module')
+  .action(async (pathParam: string, opts: { module: string; package?: string; apply?: boolean; yes?: boolean }) => {
+    await runMigrateCallers(path.resolve(pathParam), opts);
+  });
+
+program
+  .command('review-packet')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('-m, --module <name>', 'module from the domain map')
+  .option('--all', 'one packet per module')
+  .option('-o, --output <dir>', 'output directory (default: <module>-review, or review-packets/ with --all)')
+  .option('--zip', 'also write a .zip of each packet')
+  .description('Bundle plan, files, dependencies, business rules, risks and cost of a module for architecture review')
+  .action(async (pathParam: string, opts: { module?: string; all?: boolean; output?: string; zip?: boolean }) => {
+    await runReviewPacket(path.resolve(pathParam), opts);
+  });
+
+program
+  .command('bugreport')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('--run-id <id>', 'run to report')
+  .option('-o, --output <file>', 'archive path (default: .vibeflow/reports/bugreport-run-<id>.zip)')
+  .description('Bundle the environment, log entries, failed responses and redacted config of a run into one archive for an issue')
+  .action(async (pathParam: string, opts: { runId: string; output?: string }) => {
+    try {
+      const report = writeBugReport(path.resolve(pathParam), Number(opts.runId), opts.output ? path.resolve(opts.output) : undefined);
+      console.log(chalk.green(`✅ Bug report for run ${opts.runId}: ${report.path}`));
+      report.entries.forEach(entry => console.log(chalk.gray(`   - ${entry.name}`)));
+      console.log(chalk.yellow('⚠️  Credentials are redacted, but review the archive before attaching it to an issue'));
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+  });
+
+program
+  .command('prompt-bench')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('--template <file>', 'candidate prompt template ({{name}} placeholders as in the built-in refactor prompt)')
+  .option('-m, --module <name>', 'only the prompt inputs recorded for this module')
+  .option('--replay', 're-render the prompt inputs recorded by llm.cache; no API calls are made')
+  .option('--json', 'print the result as JSON')
+  .description('Measure how many input tokens a prompt template change saves or costs')
+  .action(async (pathParam: string, opts: { template: string; module?: string; replay?: boolean; json?: boolean }) => {
+    const projectRoot = path.resolve(pathParam);
+    const { LlmCache } = await import('./core/utils/llm-cache.js');
+    const { benchPromptTemplate, loadRefactorTemplate, templateHash } = await import('./core/utils/prompt-metrics.js');
+
+    if (!opts.replay) {
+      console.error(chalk.red('❌ prompt-bench only replays recorded inputs; add --replay'));
+      process.exit(1);
+    }
+
+    let result;
+    try {
+      const inputs = new LlmCache(projectRoot).listInputs(opts.module);
+      if (inputs.length === 0) {
+        throw new Error(`No prompt inputs recorded${opts.module ? ` for ${opts.module}` : ''}; enable llm.cache and run "vf refactor" first`);
+      }
+      const text = await fs.readFile(path.resolve(opts.template), 'utf8');
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(projectRoot, 'vibeflow.config.yaml'));
+      const baseline = loadRefactorTemplate(projectRoot, config.prompt?.refactorTemplate);
+      result = benchPromptTemplate(inputs, { name: opts.template, text, hash: templateHash(text) }, baseline);
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+
+    if (opts.json) {
+      console.log(JSON.stringify(result, null, 2));
+      return;
+    }
+    const signed = (value: number) => `${value >= 0 ? '+' : ''}${value}`;
+    const change = `${signed(result.delta)} tokens (${signed(Number((result.delta_rate * 100).toFixed(1)))}%)`;
+    console.log(chalk.blue(`🧪 ${result.candidate.template} (${result.candidate.template_hash.slice(0, 12)}) vs ${result.baseline.template} (${result.baseline.template_hash.slice(0, 12)})`));
+    console.log(chalk.gray(`   Replayed ${result.files.length} recorded prompts, no API calls`));
+    for (const file of result.files) {
+      console.log(chalk.gray(`   ${file.module}/${file.file}: ${file.baseline_tokens} → ${file.candidate_tokens} (${signed(file.delta)})`));
+    }
+    console.log((result.delta <= 0 ? chalk.green : chalk.yellow)(`   Input tokens: ${result.baseline_tokens} → ${result.candidate_tokens}, ${change}`));
+    if (result.unknown_placeholders.length > 0) {
+      console.log(chalk.yellow(`⚠️  Placeholders without a recorded value (left in the prompt): ${result.unknown_placeholders.map(name => `{{${name}}}`).join(', ')}`));
+    }
+    if (result.unused_variables.length > 0) {
+      console.log(chalk.yellow(`⚠️  Inputs the template drops: ${result.unused_variables.join(', ')}`));
+    }
+  });
+
+program
+  .command('evaluate-methods')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('-m, --module <name>', 'module from the domain map to generate with each method')
+  .option('--methods <list>', 'comma separated methods to compare', 'template,llm')
+  .option('--rules <path>', 'business-rule catalog JSON (default: static extraction of the module files)')
+  .option('--keep-workspaces', 'keep the isolated copies the outputs were generated in')
+  .description('Generate a module with the template fallback and the LLM in isolated copies and score both')
+  .action(async (pathParam: string, opts: { module: string; methods: string; rules?: string; keepWorkspaces?: boolean }) => {
+    const projectRoot = path.resolve(pathParam);
+    const { MethodEvaluator, EVALUATED_METHODS, formatScorecard } = await import('./core/utils/method-evaluation.js');
+    const methods = opts.methods.split(',').map(m => m.trim()).filter(Boolean);
+    const unknown = methods.filter(m => !(EVALUATED_METHODS as string[]).includes(m));
+    if (unknown.length > 0) {
+      console.error(chalk.red(`❌ Unknown method: ${unknown.join(', ')} (expected ${EVALUATED_METHODS.join(', ')})`));
+      process.exit(1);
+    }
+
+    const evaluator = new MethodEvaluator(projectRoot);
+    let scorecard;
+    try {
+      scorecard = await evaluator.evaluate(opts.module, {
+        methods: methods as typeof EVALUATED_METHODS,
+        rulesPath: opts.rules ? path.resolve(opts.rules) : undefined,
+        keepWorkspaces: opts.keepWorkspaces,
+      });
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+
+    try {
+      const runId = evaluator.record(scorecard);
+      console.log(chalk.gray(`   Recorded as run ${runId} (vf metrics methods)`));
+    } catch (error) {
+      console.warn(chalk.yellow(`⚠️  Evaluation metrics skipped: ${getErrorMessage(error)}`));
+    }
+    const scorecardPath = evaluator.write(scorecard);
+
+    console.log(chalk.blue('\n🧪 Method evaluation'));
+    console.log(formatScorecard(scorecard));
+    console.log(chalk.gray(`   - ${new VibeFlowPaths(projectRoot).getRelativePath(scorecardPath)}`));
+    for (const [method, workspace] of Object.entries(scorecard.workspaces ?? {})) {
+      console.log(chalk.gray(`   ${method} workspace: ${workspace}`));
+    }
+  });
+
+program
+  .command('resolve')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--accept <side>', 'resolve every hunk without prompting: ours, theirs or both')
+  .description('Resolve merge conflicts left by re-runs in manually edited outputs')
+  .action(async (pathParam: string, opts: { accept?: string }) => {
+    await runResolve(path.resolve(pathParam), opts);
+  });
+
+program
+  .command('verify')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('--run-id <id>', 'run to verify')
+  .description('Re-run build and test verification for a run after its conflicts are resolved')
+  .action(async (pathParam: string, opts: { runId: string }) => {
+    await runVerify(path.resolve(pathParam), parseInt(opts.runId, 10));
+  });
+
+const boundariesCommand = program
+  .command('boundaries')
+  .description('Curate the discovered boundaries');
+
+boundariesCommand
+  .command('edit')
+  .argument('[path]', 'target project root', 'workspace')
+  .description('Move files between boundaries, rename, merge and split modules; the result is kept in boundary.yaml')
+  .action(async (pathParam: string) => {
+    await runBoundaryEditor(path.resolve(pathParam));
+  });
+
+const statusCommand = program
+  .command('status')
+  .argument('[path]', 'target project root', 'workspace')
+  .option('--modules', 'list the migration stage of every module')
+  .option('-e, --export <format>', 'export format: json or csv')
+  .option('-o, --output <path>', 'write export to file instead of stdout')
+  .description('Track migration progress per module (discovered → planned → refactored → tests-relocated → verified → accepted)')
+  .action(async (pathParam: string, opts: { modules?: boolean; export?: string; output?: string }) => {
+    if (!opts.modules && !opts.export) {
+      statusCommand.help();
+    }
+    await showModuleStatus(path.resolve(pathParam), opts);
+  });
+
+statusCommand
+  .command('accept')
+  .argument('<module>', 'module name or boundary ID')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('--by <name>', 'reviewer accepting the module')
+  .option('--force', 'accept a module that has not been verified')
+  .description('Record that a reviewer accepted a migrated module')
+  .action(async (moduleName: string, pathParam: string, opts: { by: string; force?: boolean }) => {
+    try {
+      const record = new ModuleStatusTracker(path.resolve(pathParam)).accept(moduleName, opts.by, { force: opts.force });
+      console.log(chalk.green(`✅ ${record.module_name} accepted by ${opts.by}`));
+    } catch (error) {
+      console.error(chalk.red(`❌ ${getErrorMessage(error)}`));
+      process.exit(1);
+    }
+  });
+
+const controlCommand = program
+  .command('control')
+  .description('Control a running refactor from another terminal');
+
+controlCommand
+  .command('skip-module')
+  .argument('[path]', 'target project root', 'workspace')
+  .requiredOption('--run-id <id>', 'run id shown when the refactor started')
+  .description('Skip the module currently being processed by a vf refactor --module, --resume-skipped or --async-batch run (the full pipeline cannot skip modules)')
+  .action(async (pathParam: string, opts: { runId: string }) => {
+    const store = new PerformanceStore(path.resolve(pathParam));
+    const moduleName = store.requestSkip(parseInt(opts.runId, 10));
+    console.log(chalk.yellow(`⏭️  Requested skip of module ${moduleName} in run ${opts.runId}`));
+  });
+
 // -----------------------------------------------------------------------------
 // Entry
 // -----------------------------------------------------------------------------
@@ -1,17 +1,211 @@
 import * as fs from 'fs';
-import { DomainMap, DomainBoundary, VibeFlowConfig, BoundaryConfig } from '../types/config.js';
+import * as path from 'path';
+import { DomainMap, DomainBoundary, VibeFlowConfig, BoundaryConfig, BoundaryConstraints, BoundaryDebt, DomainMapSampling, SharedKernelPackage, AsyncEdge, GeneratedFile, BoundaryCycle, ExternalConsumer, OrchestratorCandidate } from '../types/config.js';
 import { ConfigLoader } from '../utils/config-loader.js';
 import { VibeFlowPaths } from '../utils/file-paths.js';
-
+import {
+  ConstraintViolation,
+  resolveConstraints,
+  evaluateConstraints,
+  domainBoundaryAdapter,
+} from '../utils/boundary-constraints.js';
+import {
+  SharedStateFinding,
+  findSharedMutableState,
+  mergeResolutions,
+  renderSharedStateSection,
+} from '../utils/shared-state.js';
+import {
+  ConfigAccessInventory,
+  ModuleConfig,
+  findConfigAccess,
+  planModuleConfigs,
+  renderConfigAccessSection,
+} from '../utils/config-access.js';
+import { InputParseError, getErrorMessage } from '../utils/error-utils.js';
+import { DEBT_RISK_THRESHOLDS } from '../utils/debt-inventory.js';
+import { PackageMismatch, findPackageMismatches, renderPackageMismatchSection } from '../utils/go-packages.js';
+import { formatSampling } from '../utils/discovery-sampling.js';
+import { applyCuratedModules } from '../utils/boundary-curation.js';
+import {
+  ModuleDeployment,
+  ServiceRequirements,
+  analyzeServiceRequirements,
+  renderServiceSection,
+  resolveDeployments,
+  writeServiceScaffolds,
+} from '../utils/service-deployment.js';
+import {
+  PlanTarget,
+  ServiceExtractionPlan,
+  loadPlanTarget,
+  proposeServiceExtraction,
+  proposedDeployments,
+  renderServiceExtractionSection,
+} from '../utils/service-extraction.js';
+import { TestSupportPlan, planTestSupport, renderTestSupportSection } from '../utils/test-support.js';
+import { DecisionRecord, collectDecisions, renderDecisionsSection, writeDecisionRecords } from '../utils/architecture-decisions.js';
+import { renderSharedKernelSection } from '../utils/shared-kernel.js';
+import { renderAsyncEdgesSection } from '../utils/message-topics.js';
+import { renderGeneratedCodeSection } from '../utils/generated-code.js';
+import { renderBoundaryCyclesSection } from '../utils/boundary-cycles.js';
+import { DomainEventPlan, planDomainEvents, renderDomainEventsSection } from '../utils/domain-events.js';
+import { renderOrchestratorsSection } from '../utils/orchestrators.js';
+import { renderExternalConsumersSection } from '../utils/frontend-api-calls.js';
+import {
+  PlanSchedule,
+  estimateEffortDays,
+  formatPhaseDuration,
+  mergeScheduleConfig,
+  renderScheduleSection,
+  schedulePhases,
+} from '../utils/phase-schedule.js';
+import { ModuleStatusTracker } from '../utils/module-status.js';
+import { DomainMapWriter } from '../utils/domain-map-writer.js';
+import { parsePlan } from '../utils/input-parsers.js';
+import { LlmCallRecord, PerformanceStore } from '../utils/performance-store.js';
+import { portableArtifact, toPosixPath } from '../utils/workspace-paths.js';
+import { tableOwners } from '../utils/review-packet.js';
+import {
+  ArchitectureStyle,
+  PlannedTargetFiles,
+  loadArchitectureStyle,
+  moduleDirectories,
+  planTargetFiles,
+  renderArchitectureStyleSection,
+} from '../utils/architecture-style.js';
+import { ArchitectureComparison, compareArchitectures, renderArchitectureComparison } from '../utils/architecture-comparison.js';
+import {
+  GeneratedBlock,
+  PLAN_ACTIONS_HEADING,
+  PLAN_FILES_HEADING,
+  PlanSyncResult,
+  PlanSyncState,
+  editedBlocks,
+  formatAction,
+  moduleKey,
+  planSyncState,
+  renderPlanDocument,
+  syncPlan,
+} from '../utils/plan-sync.js';
+import {
+  EstablishedApiChange,
+  EstablishedConsumer,
+  establishedApiUsage,
+  renderEstablishedSection,
+} from '../utils/established-modules.js';
+import {
+  ModuleRisk,
+  RiskThresholds,
+  gatherRiskInputs,
+  renderRiskSection,
+  resolveRiskThresholds,
+  scoreModuleRisk,
+} from '../utils/module-risk.js';
+import { PlanEstimates, estimateModules, renderEstimatesSection } from '../utils/module-estimates.js';
+import { FileChurn, mineFileChurn } from '../utils/co-change.js';
+import { RiskHeatmap, buildRiskHeatmap, renderHeatmapSection } from '../utils/risk-heatmap.js';
+import { DatabaseDecomposition, planDatabaseDecomposition, renderDatabaseSection } from '../utils/database-decomposition.js';
+import { ModulePorts, planModulePorts, renderPortsSection } from '../utils/module-ports.js';
+import { AclRecommendation, DEFAULT_ACL_MIN_REFERENCES, ModuleCoupling, recommendAntiCorruptionLayers, renderAclSection } from '../utils/anti-corruption-layer.js';
+import {
+  MigrationMode,
+  StranglerFacade,
+  StranglerPlan,
+  loadMigrationMode,
+  planFacades,
+  planStranglerSteps,
+  renderStranglerSection,
+  writeFacadeScaffold,
+} from '../utils/strangler-fig.js';
+import {
+  GoModuleLayout,
+  GoModuleLayoutPlan,
+  loadGoModuleLayout,
+  planGoModuleLayout,
+  renderGoModulesSection,
+} from '../utils/go-module-layout.js';
+import {
+  MigrationOrder,
+  dependencyLayers,
+  findMisorderedModules,
+  renderMigrationOrderSection,
+} from '../utils/migration-order.js';
+
+/** Bumped when plan.json changes incompatibly for the tools reading it */
+export const PLAN_SCHEMA_VERSION = 1;
+
+/**
+ * plan.json: the plan vf refactor and external tools read; plan.md is rendered from it
+ */
 export interface ArchitecturalPlan {
+  /** PLAN_SCHEMA_VERSION of the vibeflow that wrote it; missing before versioning */
+  schema_version?: number;
   overview: string;
   modules: ModuleDesign[];
   migration_strategy: MigrationStrategy;
   implementation_guide: ImplementationGuide;
   quality_gates: QualityGate[];
+  constraint_adjustments: string[];
+  constraint_violations: ConstraintViolation[];
+  /** Package-level mutable state used from several modules; unresolved entries block refactor */
+  shared_state?: SharedStateFinding[];
+  /** LOC moved, complexity, call sites to rewrite, churn and effort of each migrated module */
+  estimates?: PlanEstimates;
+  /** Modules and files ranked by churn × complexity; missing without git history */
+  risk_heatmap?: RiskHeatmap;
+  /** Tables each module owns, cross-module queries its API replaces, and the schema migration of each phase */
+  database?: DatabaseDecomposition;
+  /** Events replacing the calls of the cycle edges cut with an event; vf refactor scaffolds them */
+  domain_events?: DomainEventPlan;
+  /** Anti-corruption layers of the strongly coupled module pairs */
+  anti_corruption_layers?: AclRecommendation[];
+  /** Proposals that would change the public API of an established module, with the symbols affected */
+  established_api_changes?: EstablishedApiChange[];
+  /** Every configuration key the modules read (env, viper, flags, global config structs) */
+  config_access?: ConfigAccessInventory;
+  /** Packages whose declared name differs from their directory; cleanup before migrating */
+  package_mismatches?: PackageMismatch[];
+  /** Where shared test helpers move: each module's test-support package, the shared one, manual review */
+  test_support?: TestSupportPlan;
+  /** Utility packages of no module; refactoring leaves them in place and any module may import them */
+  shared_kernel?: SharedKernelPackage[];
+  /** Message topics modules integrate through; kept as event contracts, not turned into dependencies */
+  async_edges?: AsyncEdge[];
+  /** Generated files with the go:generate source each module regenerates them from */
+  generated_code?: GeneratedFile[];
+  /** Call cycles between modules with the edge each is cut at */
+  cycles?: BoundaryCycle[];
+  /** Functions coordinating several modules; extracted as sagas or application services, not assigned to one module */
+  orchestrators?: OrchestratorCandidate[];
+  /** Frontend requests reaching each module's routes (boundary.yaml frontend); those routes must stay compatible */
+  external_consumers?: { module: string; consumers: ExternalConsumer[] }[];
+  /** Dated phases when schedule constraints are configured; imported by PM tooling */
+  schedule?: PlanSchedule;
+  /** Target architecture of refactored modules when not the four clean-architecture layers */
+  architecture_style?: ArchitectureStyle;
+  /** Set when the plan targets microservices rather than a modular monolith */
+  target?: PlanTarget;
+  /** Modules proposed as standalone services (target: microservices) */
+  service_extraction?: ServiceExtractionPlan;
+  /** Set when the legacy code stays in place and callers are routed through facades */
+  migration_mode?: MigrationMode;
+  /** Target go.mod/go.work layout with module paths and the replace directives of the transition */
+  go_modules?: GoModuleLayoutPlan;
+  /** Facades of the strangler-fig migration and the call sites each phase redirects */
+  strangler?: StranglerPlan;
+  /** ADRs of the plan's decisions (interface extraction, event introduction, module merges) */
+  decisions?: DecisionRecord[];
+  /** What plan.md and plan.json agreed on at the last vf plan or vf plan sync */
+  sync?: PlanSyncState;
+  /** Generated from a sampled domain map: for orientation only, refactoring refuses it */
+  exploratory?: boolean;
+  sampling?: DomainMapSampling;
 }
 
 export interface ModuleDesign {
+  /** Stable boundary ID from domain-map.json; survives renames */
+  id?: string;
   name: string;
   description: string;
   current_state: ModuleState;
@@ -19,6 +213,32 @@ export interface ModuleDesign {
   refactoring_actions: RefactoringAction[];
   dependencies: ModuleDependency[];
   interfaces: InterfaceDefinition[];
+  owned_tables?: string[];
+  merged_from?: string[];
+  /** Known debt markers from discovery; many of them make auto-refactoring riskier */
+  debt?: BoundaryDebt;
+  /** Owning team found by discovery (CODEOWNERS or git blame); configured teams take precedence */
+  team?: string;
+  /** monolith unless marked with `vf plan --deployment`; kept across regenerations */
+  deployment?: ModuleDeployment;
+  /** Stricter requirements of a separately deployable service */
+  service?: ServiceRequirements;
+  /** Files each source file is refactored into in the target layout; vf refactor generates its patches from them */
+  target_files?: PlannedTargetFiles[];
+  /** Risk score and tier deciding whether unattended runs may apply the module */
+  risk?: ModuleRisk;
+  /** Typed config holding exactly the keys the module reads, populated by the composition root */
+  config?: ModuleConfig;
+  /** Exported symbols other modules use; every other export is internal to the module */
+  ports?: ModulePorts;
+  /** Extracted before vibeflow: kept as is, out of the migration phases and refactoring */
+  status?: 'established';
+  established?: {
+    root: string;
+    module_path?: string;
+    /** What the other modules use of its public API */
+    consumers: EstablishedConsumer[];
+  };
 }
 
 export interface ModuleState {
@@ -31,15 +251,19 @@ export interface ModuleState {
 }
 
 export interface RefactoringAction {
-  type: 'extract_interface' | 'move_file' | 'create_value_object' | 'split_function' | 'introduce_event';
+  type: 'extract_interface' | 'move_file' | 'create_value_object' | 'split_function' | 'introduce_event' | 'create_entrypoint' | 'extract_orchestrator' | 'introduce_acl';
   description: string;
   files_affected: string[];
   priority: 'high' | 'medium' | 'low';
   effort_estimate: string;
+  /** Stable key of the architectural decision behind the action; recorded as an ADR under docs/adr/ */
+  decision?: string;
 }
 
 export interface ModuleDependency {
   module: string;
+  /** Stable ID of the module depended on, when it is a discovered boundary */
+  module_id?: string;
   type: 'interface' | 'event' | 'shared_data';
   description: string;
 }
@@ -55,6 +279,8 @@ export interface InterfaceDefinition {
 
 export interface MigrationStrategy {
   phases: MigrationPhase[];
+  /** Cycles and misordered configured phases found while ordering the phases by dependency */
+  order?: MigrationOrder;
   rollback_plan: string;
   validation_steps: string[];
 }
@@ -66,6 +292,11 @@ export interface MigrationPhase {
   actions: RefactoringAction[];
   success_criteria: string[];
   risks: Risk[];
+  /** Set when the phases come from the schedule (see PlanSchedule) */
+  start?: string;
+  end?: string;
+  effort_days?: number;
+  fixed?: boolean;
 }
 
 export interface Risk {
@@ -117,54 +348,768 @@ export interface QualityGate {
 export interface ArchitectAnalysisResult {
   plan: ArchitecturalPlan;
   outputPath: string;
+  jsonPath: string;
+}
+
+export interface ArchitectureComparisonResult {
+  comparison: ArchitectureComparison;
+  outputPath: string;
+  /** Candidate plans written next to the comparison */
+  candidates: { style: ArchitectureStyle; markdownPath: string; jsonPath: string }[];
+}
+
+export interface PlanOptions {
+  /** Deployment per module name or ID, overriding the previous plan */
+  deployments?: Record<string, ModuleDeployment>;
+  /** Target architecture, overriding the previous plan (default: clean) */
+  style?: ArchitectureStyle;
+  /** microservices proposes which modules to extract; overrides the previous plan (default: modular-monolith) */
+  target?: PlanTarget;
+  /** strangler-fig keeps the legacy code and routes callers through facades; overrides the previous plan (default: restructure) */
+  migration?: MigrationMode;
+  /** go.mod per module with replace directives (multi-module) or a go.work; overrides the previous plan (default: single) */
+  goModules?: GoModuleLayout;
 }
 
 export class ArchitectAgent {
   private config: VibeFlowConfig;
   private boundaryConfig: BoundaryConfig | null;
   private paths: VibeFlowPaths;
+  private projectRoot: string;
 
   constructor(projectRoot: string, configPath?: string, boundaryConfigPath?: string) {
+    this.projectRoot = projectRoot;
     this.config = ConfigLoader.loadVibeFlowConfig(configPath);
     this.boundaryConfig = ConfigLoader.loadBoundaryConfig(boundaryConfigPath);
     this.paths = new VibeFlowPaths(projectRoot);
   }
 
-  async generateArchitecturalPlan(domainMapPath: string): Promise<ArchitectAnalysisResult> {
+  async generateArchitecturalPlan(domainMapPath: string, options: PlanOptions = {}): Promise<ArchitectAnalysisResult> {
     console.log('🏗️  モジュラーアーキテクチャを設計中...');
+    const plan = this.designArchitecturalPlan(domainMapPath, options);
+
+    // 8. 判断ごとの ADR（既存の ADR は上書きしない。探索用の計画では書かない）
+    const adr = plan.exploratory ? undefined : writeDecisionRecords(this.projectRoot, this.paths.adrDir, collectDecisions(plan.modules, plan.constraint_adjustments));
+    if (adr && adr.records.length > 0) plan.decisions = adr.records;
+
+    // 9. 計画出力（plan.md の生成ブロック外の記述は保持）
+    const outputPath = this.paths.planPath;
+    const jsonPath = this.paths.planJsonPath;
+    const previousMarkdown = this.readPreviousPlanMarkdown();
+    const stored = portableArtifact(this.projectRoot, plan);
+    const blocks = this.renderPlanBlocks(stored);
+    fs.writeFileSync(outputPath, renderPlanDocument(blocks, previousMarkdown));
+    plan.sync = planSyncState(stored, blocks);
+    this.paths.writeArtifact(jsonPath, plan);
+    
+    console.log(`✅ アーキテクチャ計画を生成しました: ${this.paths.getRelativePath(outputPath)}`);
+    if (plan.sampling) {
+      console.log(`🔍 探索用の計画です（サンプリング: ${formatSampling(plan.sampling)}）。vf refactor には使用できません`);
+    }
+    if (plan.strangler) {
+      const redirects = plan.strangler.facades.reduce((sum, facade) => sum + facade.redirects.length, 0);
+      console.log(`🌿 ストラングラーフィグ: ファサード ${plan.strangler.facades.filter(f => f.operations.length > 0).length}個、切り替える呼び出し箇所 ${redirects}件（計画書の「ストラングラーフィグ移行」を参照）`);
+    }
+    if (plan.go_modules && plan.go_modules.layout !== 'single') {
+      console.log(`📦 Goモジュール構成: ${plan.go_modules.layout}（go.mod ${plan.go_modules.modules.length}個${plan.go_modules.go_work ? '、go.work' : '、移行中の replace ディレクティブ'}。計画書の「Goモジュール構成」を参照）`);
+    }
+    const hot = (plan.risk_heatmap?.modules ?? []).filter(module => module.level === 'hot');
+    if (hot.length > 0) {
+      console.log(`🔥 リスクヒートマップ: 高リスク ${hot.map(module => `${module.module}（${module.recommendation === 'phase-late' ? '後半のフェーズへ' : '手動'}）`).join(', ')}（計画書の「リスクヒートマップ」を参照）`);
+    }
+    if (plan.database) {
+      const contested = plan.database.tables.filter(table => table.contested).length;
+      console.log(`🗄️  データベース分割: テーブル ${plan.database.tables.length}個、APIに置き換えるクエリ ${plan.database.cross_module_queries.length}件${contested > 0 ? `、所有モジュール未決定 ${contested}個` : ''}（計画書の「データベース分割」を参照）`);
+    }
+    if (plan.domain_events) {
+      console.log(`📣 ドメインイベント: ${plan.domain_events.events.map(event => event.name).join(', ')}（計画書の「ドメインイベント」を参照）`);
+    }
+    if (plan.anti_corruption_layers) {
+      console.log(`🛡️  腐敗防止層: ${plan.anti_corruption_layers.map(recommendation => recommendation.modules.join(' ⇄ ')).join(', ')}（計画書の「腐敗防止層」を参照）`);
+    }
+    const ported = plan.modules.filter(module => module.ports);
+    if (ported.length > 0) {
+      const exposed = ported.reduce((sum, module) => sum + module.ports!.exposed.length, 0);
+      const internal = ported.reduce((sum, module) => sum + module.ports!.internal.length, 0);
+      console.log(`🔌 公開ポート: ${exposed}個、内部化するエクスポート ${internal}個（計画書の「公開ポート」を参照）`);
+    }
+    if (adr && (adr.added.length > 0 || adr.deprecated.length > 0)) {
+      console.log(`📝 ADR: 新規 ${adr.added.length}件${adr.deprecated.length > 0 ? `、廃止 ${adr.deprecated.length}件` : ''}（${this.paths.getRelativePath(this.paths.adrDir)}/）`);
+    }
+    const order = plan.migration_strategy.order;
+    if (order && order.misordered.length > 0) {
+      console.log(`⚠️  migration.phases が依存順になっていません（${order.misordered.length}件）。依存グラフから計算したフェーズを使用します`);
+    }
+    if (order && order.cycles.length > 0) {
+      console.log(`⚠️  依存サイクルのため順序を決められないモジュール: ${order.cycles.map(cycle => cycle.join(', ')).join(' / ')}（計画書の「移行順序」を参照）`);
+    }
+    if (plan.constraint_violations.length > 0) {
+      console.log(`⚠️  満たせない境界制約: ${plan.constraint_violations.length}件（計画書の「制約違反」を参照）`);
+    }
+    const unresolvedState = (plan.shared_state ?? []).filter(f => !f.resolution);
+    if (unresolvedState.length > 0) {
+      console.log(`⛔ 未解決の共有ミュータブル状態: ${unresolvedState.length}件（計画書の「共有ミュータブル状態」を参照）`);
+    }
+    
+    const established = plan.modules.filter(module => module.status === 'established');
+    if (established.length > 0) {
+      console.log(`🏛️  確立済みモジュール: ${established.map(m => m.name).join(', ')}（公開APIの変更が必要な提案 ${(plan.established_api_changes ?? []).length}件、計画書の「確立済みモジュール」を参照）`);
+    }
+
+    const configAccess = plan.config_access;
+    if (configAccess && (configAccess.keys.length > 0 || configAccess.unresolvable.length > 0)) {
+      const shared = configAccess.keys.filter(key => key.modules.length > 1).length;
+      console.log(`⚙️  設定キー: ${configAccess.keys.length}件（複数モジュール共有 ${shared}件、解決不能 ${configAccess.unresolvable.length}件、計画書の「設定キー」を参照）`);
+    }
+
+    if ((plan.package_mismatches ?? []).length > 0) {
+      console.log(`🧹 パッケージ名の不一致: ${plan.package_mismatches!.length}件（計画書の「パッケージ名の不一致」を参照）`);
+    }
+
+    if (plan.test_support) {
+      const moved = plan.test_support.packages.reduce((sum, pkg) => sum + pkg.helpers.length, 0);
+      console.log(`🧪 テストヘルパーの移動: ${moved}件${plan.test_support.manual_review.length > 0 ? `、要確認 ${plan.test_support.manual_review.length}件` : ''}（計画書の「テスト支援パッケージ」を参照）`);
+    }
+
+    if (plan.schedule) {
+      const unsatisfiable = plan.schedule.unsatisfiable.length;
+      console.log(`📅 移行スケジュール: ${plan.schedule.start} 〜 ${plan.schedule.end}（${plan.schedule.phases.length}フェーズ${unsatisfiable > 0 ? `、満たせない制約 ${unsatisfiable}件` : ''}、計画書の「スケジュール」を参照）`);
+    }
+
+    const tiers = plan.modules.filter(module => module.risk).map(module => module.risk!.tier);
+    if (tiers.length > 0) {
+      const count = (tier: string) => tiers.filter(t => t === tier).length;
+      console.log(`🛡️  自動適用のリスク分類: auto-apply ${count('auto-apply')}件、apply-with-review ${count('apply-with-review')}件、manual-only ${count('manual-only')}件（計画書の「自動適用のリスク分類」を参照）`);
+    }
+
+    const extraction = plan.service_extraction;
+    if (extraction) {
+      const recommended = extraction.candidates.filter(candidate => candidate.recommended).length;
+      console.log(`🧭 マイクロサービス抽出計画: ${extraction.candidates.length}モジュール中 ${recommended}件を独立サービスに推奨（計画書の「マイクロサービス抽出計画」を参照）`);
+    }
+
+    const services = plan.modules.filter(module => module.service);
+    if (services.length > 0) {
+      const calls = services.reduce((sum, module) => sum + module.service!.network_calls.length, 0);
+      console.log(`🚀 独立デプロイするサービス: ${services.map(m => m.name).join(', ')}（ネットワーク呼び出しになる箇所: ${calls}件、計画書の「サービス化要件」を参照）`);
+    }
+    
+    return { plan, outputPath, jsonPath };
+  }
+
+  /**
+   * 候補スタイルごとに計画を設計して比較（plan.md・plan.json・ADR は変更しない）
+   * Each candidate is written to plan.<style>.md/json next to the comparison.
+   */
+  compareArchitectures(domainMapPath: string, styles: ArchitectureStyle[], options: PlanOptions = {}): ArchitectureComparisonResult {
+    console.log(`🏗️  候補アーキテクチャを比較中: ${styles.join(', ')}`);
+    const candidates = styles.map(style => {
+      const plan = this.designArchitecturalPlan(domainMapPath, { ...options, style });
+      const markdownPath = this.paths.candidatePlanPath(style, 'md');
+      const jsonPath = this.paths.candidatePlanPath(style, 'json');
+      fs.writeFileSync(markdownPath, renderPlanDocument(this.renderPlanBlocks(portableArtifact(this.projectRoot, plan))));
+      this.paths.writeArtifact(jsonPath, plan);
+      return { style, plan, markdownPath, jsonPath };
+    });
+
+    const comparison = compareArchitectures(this.projectRoot, candidates);
+    const outputPath = this.paths.architectureComparisonPath;
+    fs.writeFileSync(outputPath, renderArchitectureComparison(
+      comparison,
+      Object.fromEntries(candidates.map(candidate => [candidate.style, this.paths.getRelativePath(candidate.markdownPath)]))
+    ));
+    console.log(`✅ アーキテクチャ比較を生成しました: ${this.paths.getRelativePath(outputPath)}`);
+    return { comparison, outputPath, candidates: candidates.map(({ style, markdownPath, jsonPath }) => ({ style, markdownPath, jsonPath })) };
+  }
+
+  /**
+   * 計画の設計（plan.md・plan.json・ADR は書かない）
+   * vf plan --compare designs one candidate per style with it.
+   */
+  designArchitecturalPlan(domainMapPath: string, options: PlanOptions = {}): ArchitecturalPlan {
+    // 1. ドメインマップ読み込み（boundary.yaml の手動編集を反映）
+    const domainMap = this.applyCuration(this.loadDomainMap(domainMapPath));
     
-    // 1. ドメインマップ読み込み
-    const domainMap = this.loadDomainMap(domainMapPath);
+    // 2. 境界制約の適用とモジュール設計
+    const constraints = this.boundaryConfig?.constraints;
+    const resolution = resolveConstraints(domainMap.boundaries, constraints, domainBoundaryAdapter);
+    resolution.adjustments.forEach(adjustment => console.log(`🔒 ${adjustment}`));
     
-    // 2. モジュール設計
-    const modules = this.designModules(domainMap.boundaries);
+    const designed = this.designModules(resolution.items);
+    this.addConstraintActions(designed, resolution.violations);
+    const domainEvents = this.planDomainEvents(domainMap.cycles ?? []);
+    this.addCycleActions(designed, domainMap.cycles ?? [], domainEvents);
+    this.addOrchestratorActions(designed, domainMap.orchestrators ?? []);
+    const target = options.target ?? loadPlanTarget(this.projectRoot);
+    const extraction = target === 'microservices' ? this.proposeServices(designed, domainMap) : undefined;
+    const modules = this.applyDeployments(designed, options.deployments, extraction);
+    this.planPorts(modules);
+    const acl = this.recommendAntiCorruptionLayers(modules, domainMap);
+    const migrationMode = options.migration ?? loadMigrationMode(this.projectRoot);
+    const facades = migrationMode === 'strangler-fig' ? this.designFacades(modules) : undefined;
     
     // 3. 移行戦略策定
-    const migrationStrategy = this.createMigrationStrategy(modules);
+    const schedule = this.scheduleModules(modules);
+    const migrationStrategy = this.createMigrationStrategy(modules, schedule);
+    const strangler: StranglerPlan | undefined = facades ? { facades, steps: planStranglerSteps(facades, migrationStrategy.phases) } : undefined;
     
-    // 4. 実装ガイド作成
-    const implementationGuide = this.createImplementationGuide(modules);
+    // 4. 実装ガイド作成（ターゲットアーキテクチャは前回の plan.json を引き継ぐ）
+    const style = options.style ?? loadArchitectureStyle(this.projectRoot);
+    const implementationGuide = this.createImplementationGuide(modules, style);
+    modules.filter(module => module.status !== 'established').forEach(module => {
+      module.target_files = planTargetFiles(module.name, module.current_state.files, style);
+    });
     
     // 5. 品質ゲート定義
     const qualityGates = this.defineQualityGates(domainMap);
     
-    // 6. アーキテクチャ計画統合
+    // 6. 自動適用のリスク分類
+    const sharedState = this.analyzeSharedState(modules);
+    this.assessRisk(modules, sharedState, domainMap, resolveRiskThresholds(this.config.autonomy));
+    const configAccess = this.analyzeConfigAccess(modules);
+    const packageMismatches = this.analyzePackageNames(domainMap);
+    const establishedChanges = this.analyzeEstablishedModules(domainMap, modules, resolution.adjustments, sharedState, packageMismatches);
+    const consumers = this.collectExternalConsumers(domainMap, resolution.items);
+    const churn = this.mineChurn();
+    const estimates = this.estimateModules(modules, migrationMode, churn);
+    const heatmap = this.buildRiskHeatmap(modules, migrationStrategy.phases, churn);
+    const database = this.planDatabase(modules, migrationStrategy.phases);
+    const goModules = this.planGoModules(modules, migrationStrategy.phases, options.goModules ?? loadGoModuleLayout(this.projectRoot));
+
+    // 7. アーキテクチャ計画統合
     const plan: ArchitecturalPlan = {
+      schema_version: PLAN_SCHEMA_VERSION,
       overview: this.generateOverview(domainMap, modules),
       modules,
+      ...(style !== 'clean' ? { architecture_style: style } : {}),
+      ...(extraction ? { target, service_extraction: extraction } : {}),
       migration_strategy: migrationStrategy,
+      ...(strangler ? { migration_mode: migrationMode, strangler } : {}),
+      ...(goModules ? { go_modules: goModules } : {}),
       implementation_guide: implementationGuide,
       quality_gates: qualityGates,
+      constraint_adjustments: resolution.adjustments,
+      constraint_violations: resolution.violations,
+      shared_state: sharedState,
+      ...(estimates ? { estimates } : {}),
+      ...(heatmap ? { risk_heatmap: heatmap } : {}),
+      ...(database ? { database } : {}),
+      ...(acl.length > 0 ? { anti_corruption_layers: acl } : {}),
+      ...(establishedChanges.length > 0 ? { established_api_changes: establishedChanges } : {}),
+      config_access: configAccess,
+      package_mismatches: packageMismatches,
+      ...(schedule ? { schedule } : {}),
+      ...(domainMap.test_helpers?.length ? { test_support: planTestSupport(this.projectRoot, domainMap.test_helpers, modules) } : {}),
+      ...(domainMap.shared_kernel?.length ? { shared_kernel: domainMap.shared_kernel } : {}),
+      ...(domainMap.async_edges?.length ? { async_edges: domainMap.async_edges } : {}),
+      ...(domainMap.generated_code?.length ? { generated_code: domainMap.generated_code } : {}),
+      ...(domainMap.cycles?.length ? { cycles: domainMap.cycles } : {}),
+      ...(domainEvents ? { domain_events: domainEvents } : {}),
+      ...(domainMap.orchestrators?.length ? { orchestrators: domainMap.orchestrators } : {}),
+      ...(consumers.length > 0 ? { external_consumers: consumers } : {}),
+      ...(domainMap.sampling ? { exploratory: true, sampling: domainMap.sampling } : {}),
     };
 
-    // 7. 計画出力
-    const outputPath = this.paths.planPath;
-    const planMarkdown = this.generatePlanMarkdown(plan);
-    fs.writeFileSync(outputPath, planMarkdown);
-    
-    console.log(`✅ アーキテクチャ計画を生成しました: ${this.paths.getRelativePath(outputPath)}`);
-    
-    return { plan, outputPath };
+    return plan;
+  }
+
+  /**
+   * ドメインマップに記録されたパッケージ識別子から、ディレクトリ名と一致しないパッケージ名を検出
+   */
+  private analyzePackageNames(domainMap: DomainMap): PackageMismatch[] {
+    const packages = new Map<string, { import_path: string; dir: string; name: string }>();
+    for (const boundary of domainMap.boundaries) {
+      for (const identity of boundary.file_packages ?? []) {
+        const file = path.isAbsolute(identity.file) ? path.relative(this.projectRoot, identity.file) : identity.file;
+        packages.set(identity.import_path, {
+          import_path: identity.import_path,
+          dir: path.posix.dirname(toPosixPath(file)),
+          name: identity.package,
+        });
+      }
+    }
+    return findPackageMismatches([...packages.values()]);
+  }
+
+  /**
+   * マイクロサービスとして切り出すモジュールの提案（--target microservices）
+   */
+  private proposeServices(modules: ModuleDesign[], domainMap: DomainMap): ServiceExtractionPlan {
+    return proposeServiceExtraction(
+      modules.map(module => ({
+        name: module.name,
+        files: module.current_state.files,
+        lines_of_code: module.current_state.lines_of_code,
+        depends_on: module.dependencies.map(dep => dep.module),
+        owned_tables: module.owned_tables,
+        status: module.status,
+      })),
+      {
+        cycles: domainMap.cycles,
+        orchestrators: domainMap.orchestrators,
+        async_edges: domainMap.async_edges,
+        global_coupling: domainMap.global_coupling,
+      }
+    );
+  }
+
+  /**
+   * モジュールごとのデプロイ形態を決定し、サービスには独立デプロイの要件を追加
+   * Deployments of the previous plan.json are kept unless overridden; the extraction
+   * proposal decides for modules without one.
+   */
+  private applyDeployments(
+    designed: ModuleDesign[],
+    overrides: Record<string, ModuleDeployment> = {},
+    extraction?: ServiceExtractionPlan
+  ): ModuleDesign[] {
+    let previous: ModuleDesign[] | undefined;
+    let previousTarget: PlanTarget | undefined;
+    try {
+      const previousPlan = JSON.parse(fs.readFileSync(this.paths.planJsonPath, 'utf8'));
+      previous = previousPlan.modules;
+      previousTarget = previousPlan.target;
+    } catch {
+      previous = undefined;
+    }
+
+    // A modular-monolith plan records monolith for every module by default; only its services are decisions
+    const recorded = !Array.isArray(previous) ? []
+      : extraction && previousTarget !== 'microservices' ? previous.filter(module => module.deployment === 'service')
+      : previous;
+    const { modules, unknown } = resolveDeployments(designed, recorded, overrides, extraction ? proposedDeployments(extraction) : {});
+    unknown.forEach(name => console.warn(`⚠️  --deployment のモジュールが見つかりません: ${name}`));
+
+    let requirements: ServiceRequirements[] = [];
+    try {
+      requirements = analyzeServiceRequirements(this.projectRoot, modules.map(module => ({
+        name: module.name,
+        files: module.current_state.files,
+        deployment: module.deployment,
+        owned_tables: module.owned_tables,
+      })));
+    } catch (error) {
+      console.warn(`⚠️  サービス化要件の解析に失敗しました: ${getErrorMessage(error)}`);
+    }
+
+    for (const service of requirements) {
+      const module = modules.find(m => m.name === service.module)!;
+      try {
+        service.scaffolds = writeServiceScaffolds(this.projectRoot, service);
+      } catch (error) {
+        console.warn(`⚠️  ${service.module} のひな形を出力できませんでした: ${getErrorMessage(error)}`);
+      }
+      module.service = service;
+      module.refactoring_actions.unshift(...serviceActions(module, service));
+    }
+    return modules;
+  }
+
+  /**
+   * ストラングラーフィグ移行のファサード設計
+   * Every migrated module gets a facade over its legacy packages; the call sites
+   * into it are redirected in its phase, and the legacy code stays in place.
+   */
+  private designFacades(modules: ModuleDesign[]): StranglerFacade[] {
+    let facades: StranglerFacade[] = [];
+    try {
+      facades = planFacades(this.projectRoot, modules
+        .filter(module => module.status !== 'established')
+        .map(module => ({ name: module.name, files: module.current_state.files })));
+    } catch (error) {
+      console.warn(`⚠️  ファサードの解析に失敗しました: ${getErrorMessage(error)}`);
+    }
+
+    for (const facade of facades.filter(f => f.operations.length > 0)) {
+      const module = modules.find(m => m.name === facade.module)!;
+      try {
+        facade.scaffold = writeFacadeScaffold(this.projectRoot, facade);
+      } catch (error) {
+        console.warn(`⚠️  ${facade.module} のファサードを出力できませんでした: ${getErrorMessage(error)}`);
+      }
+      module.refactoring_actions.unshift({
+        type: 'extract_interface',
+        description: `ファサード ${facade.path} (${facade.operations.length}個の関数) を追加し、${facade.redirects.length}件の呼び出しをファサード経由に変更（レガシーコードはそのまま）`,
+        files_affected: [...new Set(facade.redirects.map(call => call.location.file))],
+        priority: 'high',
+        effort_estimate: facade.redirects.length > 20 ? '1-2週間' : '2-3日',
+        decision: `strangler-facade:${module.name}`,
+      });
+    }
+    return facades;
+  }
+
+  /**
+   * 複数モジュールから使われるパッケージ変数の検出
+   * Accepted resolutions of the previous plan.json are kept for findings that still exist.
+   */
+  private analyzeSharedState(modules: ModuleDesign[]): SharedStateFinding[] {
+    let previous: SharedStateFinding[] | undefined;
+    try {
+      previous = JSON.parse(fs.readFileSync(this.paths.planJsonPath, 'utf8')).shared_state;
+    } catch {
+      previous = undefined;
+    }
+
+    try {
+      const findings = findSharedMutableState(
+        this.projectRoot,
+        modules.map(module => ({ name: module.name, files: module.current_state.files }))
+      );
+      return mergeResolutions(findings, previous);
+    } catch (error) {
+      console.warn(`⚠️  共有状態の解析に失敗しました: ${getErrorMessage(error)}`);
+      return [];
+    }
+  }
+
+  /**
+   * 設定アクセス（環境変数・viper・flag・グローバル設定構造体）の棚卸しとモジュール別 Config の設計
+   */
+  private analyzeConfigAccess(modules: ModuleDesign[]): ConfigAccessInventory {
+    try {
+      const inventory = findConfigAccess(
+        this.projectRoot,
+        modules.map(module => ({ name: module.name, files: module.current_state.files }))
+      );
+      // Established modules are not refactored, so they get no config of their own
+      const configs = planModuleConfigs(this.projectRoot, inventory, modules.filter(m => m.status !== 'established').map(m => m.name));
+      for (const module of modules) {
+        const config = configs.get(module.name);
+        if (config) module.config = config;
+      }
+      return inventory;
+    } catch (error) {
+      console.warn(`⚠️  設定アクセスの解析に失敗しました: ${getErrorMessage(error)}`);
+      return { keys: [], unresolvable: [] };
+    }
+  }
+
+  /**
+   * モジュールごとの公開ポート（他モジュールが使うインターフェース・DTO・関数）と内部に閉じるエクスポート
+   * Established modules count as consumers but keep their API as it is.
+   */
+  private planPorts(modules: ModuleDesign[]): void {
+    try {
+      const ports = planModulePorts(
+        this.projectRoot,
+        modules.map(module => ({ name: module.name, files: module.current_state.files }))
+      );
+      for (const module of modules.filter(m => m.status !== 'established')) {
+        const planned = ports.get(module.name);
+        if (planned) module.ports = planned;
+      }
+    } catch (error) {
+      console.warn(`⚠️  公開ポートの解析に失敗しました: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * 参照の多いモジュール間に腐敗防止層（ファサードと変換関数）を推奨し、汎用のインターフェース抽出アクションを置き換える
+   * Coupling is counted from the file references recorded in domain-map.json.
+   */
+  private recommendAntiCorruptionLayers(modules: ModuleDesign[], domainMap: DomainMap): AclRecommendation[] {
+    const coupling = new Map<string, ModuleCoupling>();
+    for (const boundary of domainMap.boundaries) {
+      const from = findModule(modules, boundary.name)?.name;
+      for (const reference of boundary.file_coupling ?? []) {
+        const to = findModule(modules, reference.boundary)?.name;
+        if (!from || !to || from === to) continue;
+        const edge = coupling.get(`${from}\n${to}`) ?? { from, to, references: 0, files: [] };
+        edge.references += reference.references;
+        if (!edge.files.includes(reference.file)) edge.files.push(reference.file);
+        coupling.set(`${from}\n${to}`, edge);
+      }
+    }
+
+    const recommendations = recommendAntiCorruptionLayers(modules, [...coupling.values()], this.config.acl?.minReferences);
+    for (const side of recommendations.flatMap(recommendation => recommendation.sides)) {
+      const module = modules.find(m => m.name === side.module)!;
+      module.refactoring_actions = module.refactoring_actions.filter(action => action.decision !== `extract-interface:${module.name}`);
+      module.refactoring_actions.push({
+        type: 'introduce_acl',
+        description: `${side.upstream} への参照${side.references}箇所を腐敗防止層で隔離: ${side.file} に ${side.facade} ファサードと変換関数を作成し、${side.types.length > 0 ? `${side.types.map(type => type.upstream).join(', ')} を ${module.name} 側の型に複製` : `${side.upstream} の型を直接使わない`}`,
+        files_affected: side.files,
+        priority: 'high',
+        effort_estimate: side.references > 30 ? '1-2週間' : '3-5日',
+        decision: `acl:${side.module}->${side.upstream}`,
+      });
+    }
+    return recommendations;
+  }
+
+  /**
+   * 確立済みモジュールの公開APIの利用状況と、その公開APIを変更しないと実施できない提案
+   */
+  private analyzeEstablishedModules(
+    domainMap: DomainMap,
+    modules: ModuleDesign[],
+    adjustments: string[],
+    sharedState: SharedStateFinding[],
+    packageMismatches: PackageMismatch[]
+  ): EstablishedApiChange[] {
+    const boundaries = domainMap.boundaries.filter(boundary => boundary.status === 'established');
+    if (boundaries.length === 0) return [];
+
+    let usage = new Map<string, EstablishedConsumer[]>();
+    try {
+      usage = establishedApiUsage(this.projectRoot, boundaries, modules.map(module => ({ name: module.name, files: module.current_state.files })));
+    } catch (error) {
+      console.warn(`⚠️  確立済みモジュールの利用状況の解析に失敗しました: ${getErrorMessage(error)}`);
+    }
+
+    const changes: EstablishedApiChange[] = [];
+    for (const boundary of boundaries) {
+      const consumers = usage.get(boundary.name) ?? [];
+      const used = [...new Set(consumers.flatMap(consumer => consumer.symbols))].sort();
+      const root = boundary.module_root ?? boundary.directories?.[0] ?? '';
+      const module = modules.find(m => m.name === boundary.name && m.established);
+      if (module) module.established!.consumers = consumers;
+
+      // Merging or splitting the module by constraints moves its packages
+      const files = new Set(boundary.files);
+      if (!module || module.current_state.files.length !== files.size || module.current_state.files.some(file => !files.has(file))) {
+        const related = adjustments.filter(adjustment => adjustment.includes(boundary.name));
+        changes.push({
+          module: boundary.name,
+          proposal: 'constraint',
+          description: related.length > 0 ? related.join('; ') : `${boundary.name} の境界が境界制約で変更されます`,
+          symbols: used,
+        });
+      }
+
+      for (const finding of sharedState.filter(f => f.declared_at.file.startsWith(`${root}/`))) {
+        changes.push({
+          module: boundary.name,
+          proposal: 'shared-state',
+          description: `${finding.id} を ${finding.modules.filter(name => name !== boundary.name).join(', ')} から直接使わない形に置き換える`,
+          symbols: [finding.id],
+        });
+      }
+
+      for (const mismatch of packageMismatches.filter(m => m.dir === root || m.dir.startsWith(`${root}/`))) {
+        changes.push({
+          module: boundary.name,
+          proposal: 'package-rename',
+          description: `パッケージ ${mismatch.package} (${mismatch.dir}) を ${mismatch.proposed_name} に改名`,
+          symbols: used.filter(symbol => symbol.slice(0, symbol.lastIndexOf('.')) === mismatch.dir),
+        });
+      }
+    }
+    return changes;
+  }
+
+  /**
+   * モジュールごとのリスクスコアと自動適用の段階を算出
+   * Verification failures come from the LLM calls of earlier runs in performance.db.
+   */
+  private assessRisk(modules: ModuleDesign[], sharedState: SharedStateFinding[], domainMap: DomainMap, thresholds: RiskThresholds): void {
+    let llmCalls: LlmCallRecord[] = [];
+    try {
+      llmCalls = new PerformanceStore(this.projectRoot, { readOnly: true }).getLlmCalls();
+    } catch (error) {
+      console.warn(`⚠️  LLM呼び出し履歴の読み込みに失敗しました: ${getErrorMessage(error)}`);
+    }
+
+    const owners = tableOwners(domainMap.boundaries, modules);
+    for (const module of modules) {
+      const sources = domainMap.boundaries.filter(b => b.name === module.name || (module.merged_from ?? []).includes(b.name));
+      try {
+        module.risk = scoreModuleRisk(gatherRiskInputs(this.projectRoot, {
+          id: module.id,
+          name: module.name,
+          files: module.current_state.files,
+          debt: module.debt,
+        }, {
+          sharedState,
+          tableOwners: owners,
+          circularDependencies: [...new Set(sources.flatMap(b => b.circular_dependencies ?? []))],
+          llmCalls,
+        }), thresholds);
+      } catch (error) {
+        console.warn(`⚠️  ${module.name} のリスク分類に失敗しました: ${getErrorMessage(error)}`);
+      }
+    }
+  }
+
+  /**
+   * テーブルの所有モジュール、APIに置き換えるモジュール間クエリとフェーズごとのスキーマ移行手順
+   * Tables come from schema.sql and migrations (repository.schema), otherwise from gorm/ent models.
+   */
+  private planDatabase(modules: ModuleDesign[], phases: MigrationPhase[]): DatabaseDecomposition | undefined {
+    try {
+      return planDatabaseDecomposition(
+        this.projectRoot,
+        modules.map(module => ({ name: module.name, files: module.current_state.files, owned_tables: module.owned_tables })),
+        phases,
+        { schema: this.config.repository?.schema }
+      );
+    } catch (error) {
+      console.warn(`⚠️  データベース分割の計画に失敗しました: ${getErrorMessage(error)}`);
+      return undefined;
+    }
+  }
+
+  /**
+   * 移行後の go.mod/go.work 構成（モジュールパス、移行中の replace ディレクティブ、フェーズごとの分離）
+   */
+  private planGoModules(modules: ModuleDesign[], phases: MigrationPhase[], layout: GoModuleLayout): GoModuleLayoutPlan | undefined {
+    try {
+      return planGoModuleLayout(
+        this.projectRoot,
+        layout,
+        modules.map(module => ({
+          name: module.name,
+          files: module.current_state.files,
+          dependencies: module.dependencies.map(dependency => dependency.module),
+          established: module.established,
+        })),
+        phases
+      );
+    } catch (error) {
+      console.warn(`⚠️  Goモジュール構成の計画に失敗しました: ${getErrorMessage(error)}`);
+      return undefined;
+    }
+  }
+
+  /**
+   * ファイルごとの変更回数（Git履歴がなければ null）
+   * Mined as configured for co-changes (boundary.yaml coChange).
+   */
+  private mineChurn(): FileChurn | null {
+    const coChange = this.boundaryConfig?.coChange;
+    return coChange?.enabled === false ? null : mineFileChurn(this.projectRoot, coChange);
+  }
+
+  /**
+   * モジュールごとの工数・リスクの見積もり
+   */
+  private estimateModules(modules: ModuleDesign[], mode: MigrationMode, churn: FileChurn | null): PlanEstimates | undefined {
+    const schedule = mergeScheduleConfig(this.config.schedule, this.boundaryConfig?.schedule);
+    try {
+      return estimateModules(
+        this.projectRoot,
+        modules.filter(module => module.status !== 'established').map(module => ({
+          name: module.name,
+          files: module.current_state.files,
+          effort_days: module.refactoring_actions.reduce((sum, action) => sum + estimateEffortDays(action.effort_estimate, schedule), 0),
+        })),
+        churn,
+        { moved: mode !== 'strangler-fig' }
+      );
+    } catch (error) {
+      console.warn(`⚠️  モジュールの見積もりに失敗しました: ${getErrorMessage(error)}`);
+      return undefined;
+    }
+  }
+
+  /**
+   * 変更回数 × 循環的複雑度によるモジュール・ファイルのリスクヒートマップ
+   */
+  private buildRiskHeatmap(modules: ModuleDesign[], phases: MigrationPhase[], churn: FileChurn | null): RiskHeatmap | undefined {
+    if (!churn) return undefined;
+    try {
+      return buildRiskHeatmap(
+        this.projectRoot,
+        modules.filter(module => module.status !== 'established').map(module => ({ name: module.name, files: module.current_state.files })),
+        churn,
+        phases
+      );
+    } catch (error) {
+      console.warn(`⚠️  リスクヒートマップの作成に失敗しました: ${getErrorMessage(error)}`);
+      return undefined;
+    }
+  }
+
+  /**
+   * plan.json に plan.md の構造化された編集（モジュール名・説明・ファイル・アクション、フェーズ）を反映
+   * Module renames and file moves are mirrored to domain-map.json, which drives refactoring.
+   */
+  syncPlanMarkdown(options: { force?: boolean } = {}): PlanSyncResult & { domainMapUpdates: string[] } {
+    let plan: ArchitecturalPlan;
+    let markdown: string;
+    try {
+      plan = parsePlan(fs.readFileSync(this.paths.planJsonPath, 'utf8'), this.paths.getRelativePath(this.paths.planJsonPath)) as unknown as ArchitecturalPlan;
+      markdown = fs.readFileSync(this.paths.planPath, 'utf8');
+    } catch (error) {
+      if (error instanceof InputParseError) throw error;
+      throw new Error(`No plan found at ${this.paths.getRelativePath(this.paths.planPath)}; run 'vf plan' first`);
+    }
+
+    const result = syncPlan(plan, markdown, synced => this.renderPlanBlocks(synced), options);
+    this.paths.writeArtifact(this.paths.planJsonPath, result.plan);
+    if (result.markdown !== null) fs.writeFileSync(this.paths.planPath, result.markdown);
+    return { ...result, domainMapUpdates: this.mirrorToDomainMap(plan, result) };
+  }
+
+  /**
+   * 名前とファイル一覧の変更を domain-map.json の境界に反映
+   */
+  private mirrorToDomainMap(previous: ArchitecturalPlan, result: PlanSyncResult): string[] {
+    const changed = new Set(result.applied
+      .filter(change => /^module:.+\.(?:name|files)$/.test(change.field))
+      .map(change => change.field.slice('module:'.length, change.field.lastIndexOf('.'))));
+    if (changed.size === 0) return [];
+
+    const writer = new DomainMapWriter(this.projectRoot);
+    const map = writer.load();
+    if (!map) {
+      console.warn('⚠️  domain-map.json が見つからないため、モジュール名・ファイルの変更は plan.json にのみ反映しました');
+      return [];
+    }
+
+    const updates: string[] = [];
+    for (const key of changed) {
+      const before = previous.modules.find(m => moduleKey(m) === key)!;
+      const after = result.plan.modules.find(m => moduleKey(m) === key)!;
+      const boundary = map.boundaries.find(b => (before.id ? b.id === before.id : b.name === before.name));
+      if (!boundary) {
+        console.warn(`⚠️  ${before.name} は domain-map.json の境界ではありません（統合されたモジュール）。plan.json にのみ反映しました`);
+        continue;
+      }
+      boundary.name = after.name;
+      boundary.files = [...after.current_state.files];
+      updates.push(after.name);
+    }
+    for (const rename of result.renames) {
+      for (const boundary of map.boundaries) {
+        if (boundary.dependencies?.internal) {
+          boundary.dependencies.internal = boundary.dependencies.internal.map(name => (name === rename.from ? rename.to : name));
+        }
+      }
+    }
+    if (updates.length > 0) writer.write(map);
+    return updates;
+  }
+
+  /**
+   * 既存の plan.md（生成ブロック外の記述を引き継ぐ）。未同期の編集がある生成ブロックは警告
+   */
+  private readPreviousPlanMarkdown(): string | undefined {
+    let markdown: string;
+    try {
+      markdown = fs.readFileSync(this.paths.planPath, 'utf8');
+    } catch {
+      return undefined;
+    }
+
+    let sync: PlanSyncState | undefined;
+    try {
+      sync = JSON.parse(fs.readFileSync(this.paths.planJsonPath, 'utf8')).sync;
+    } catch {
+      sync = undefined;
+    }
+
+    try {
+      const edited = editedBlocks(markdown, sync);
+      if (edited.length > 0) {
+        console.warn(`⚠️  plan.md の生成ブロックに未同期の編集があり、上書きされます: ${edited.join(', ')}（先に vf plan sync で反映してください）`);
+      }
+      return markdown;
+    } catch (error) {
+      console.warn(`⚠️  plan.md の生成ブロックを解析できないため、手書きの記述を引き継げません: ${getErrorMessage(error)}`);
+      return undefined;
+    }
   }
 
   private loadDomainMap(filePath: string): DomainMap {
@@ -176,11 +1121,21 @@ export class ArchitectAgent {
     return JSON.parse(content) as DomainMap;
   }
 
+  /**
+   * vf boundaries edit で boundary.yaml に記録された名前の変更・ファイルの固定
+   */
+  private applyCuration(domainMap: DomainMap): DomainMap {
+    const result = applyCuratedModules(this.projectRoot, domainMap.boundaries, this.boundaryConfig?.modules);
+    if (result.renamed.length === 0 && result.moved.length === 0) return domainMap;
+    console.log(`✏️  boundary.yaml の手動編集を適用: ${[...result.renamed, ...result.moved].join(', ')}`);
+    return { ...domainMap, boundaries: result.boundaries };
+  }
+
   private designModules(boundaries: DomainBoundary[]): ModuleDesign[] {
-    return boundaries.map(boundary => this.designModule(boundary));
+    return boundaries.map(boundary => this.designModule(boundary, boundaries));
   }
 
-  private designModule(boundary: DomainBoundary): ModuleDesign {
+  private designModule(boundary: DomainBoundary, allBoundaries: DomainBoundary[]): ModuleDesign {
     const currentState: ModuleState = {
       files: boundary.files,
       lines_of_code: boundary.files.length * 100, // Rough estimate
@@ -190,18 +1145,22 @@ export class ArchitectAgent {
       cohesion_score: boundary.cohesion_score ?? boundary.metrics?.cohesion ?? 0,
     };
 
-    const targetState: ModuleState = {
+    // Established modules keep their current shape; the plan only records how others use them
+    const established = boundary.status === 'established';
+    const targetState: ModuleState = established ? { ...currentState } : {
       ...currentState,
       test_coverage: this.config.refactoring.quality_gates.test_coverage.minimum,
       coupling_score: Math.max(0, (boundary.coupling_score ?? boundary.metrics?.coupling ?? 0) - 0.3),
       cohesion_score: Math.min(1, (boundary.cohesion_score ?? boundary.metrics?.cohesion ?? 0) + 0.2),
     };
 
-    const refactoringActions = this.generateRefactoringActions(boundary, currentState, targetState);
-    const dependencies = this.extractModuleDependencies(boundary);
+    const refactoringActions = established ? [] : this.generateRefactoringActions(boundary, currentState, targetState);
+    const dependencies = this.extractModuleDependencies(boundary, allBoundaries);
     const interfaces = this.defineModuleInterfaces(boundary);
+    const ownedTables = boundary.tables ?? this.boundaryConfig?.modules[boundary.name]?.owns_tables;
 
     return {
+      ...(boundary.id ? { id: boundary.id } : {}),
       name: boundary.name,
       description: boundary.description,
       current_state: currentState,
@@ -209,9 +1168,129 @@ export class ArchitectAgent {
       refactoring_actions: refactoringActions,
       dependencies,
       interfaces,
+      ...(ownedTables && ownedTables.length > 0 ? { owned_tables: ownedTables } : {}),
+      ...(boundary.merged_from && boundary.merged_from.length > 0 ? { merged_from: boundary.merged_from } : {}),
+      ...(boundary.debt ? { debt: boundary.debt } : {}),
+      ...(boundary.ownership ? { team: boundary.ownership.team } : {}),
+      ...(established ? {
+        status: 'established' as const,
+        established: {
+          root: boundary.module_root ?? boundary.directories?.[0] ?? '',
+          ...(boundary.module_path ? { module_path: boundary.module_path } : {}),
+          consumers: [],
+        },
+      } : {}),
     };
   }
 
+  /**
+   * 満たせない制約に対する推奨アクションを追加
+   */
+  private addConstraintActions(modules: ModuleDesign[], violations: ConstraintViolation[]): void {
+    for (const violation of violations) {
+      if (violation.constraint === 'forbiddenDependencies') {
+        const [from, to] = violation.modules;
+        const module = findModule(modules, from);
+        if (module?.status === 'established') continue;
+        module?.refactoring_actions.unshift({
+          type: 'introduce_event',
+          description: `禁止された依存 ${from} → ${to} をインターフェースまたはドメインイベントで反転`,
+          files_affected: module.current_state.files,
+          priority: 'high',
+          effort_estimate: '1-2週間',
+          decision: `invert-dependency:${from}->${to}`,
+        });
+      }
+
+      if (violation.constraint === 'forbiddenTableCombinations') {
+        const module = findModule(modules, violation.modules[0]);
+        if (module?.status === 'established') continue;
+        module?.refactoring_actions.unshift({
+          type: 'move_file',
+          description: `${violation.message}: テーブル所有をモジュール間で分割`,
+          files_affected: module.current_state.files,
+          priority: 'high',
+          effort_estimate: '2-3週間',
+        });
+      }
+    }
+  }
+
+  /**
+   * 依存サイクルの切断候補（呼び出し箇所が最も少ない依存）を呼び出し側モジュールのアクションに追加
+   */
+  /**
+   * 境界のフロントエンドからの呼び出しを、統合後のモジュールごとにまとめる
+   */
+  private collectExternalConsumers(domainMap: DomainMap, boundaries: DomainBoundary[]): { module: string; consumers: ExternalConsumer[] }[] {
+    const byModule = new Map<string, ExternalConsumer[]>();
+    for (const boundary of domainMap.boundaries) {
+      if (!boundary.external_consumers?.length) continue;
+      const module = boundaries.find(b => b.name === boundary.name || (b.merged_from ?? []).includes(boundary.name))?.name ?? boundary.name;
+      byModule.set(module, [...(byModule.get(module) ?? []), ...boundary.external_consumers]);
+    }
+    return [...byModule].map(([module, consumers]) => ({ module, consumers })).sort((a, b) => a.module.localeCompare(b.module));
+  }
+
+  /**
+   * 依存サイクルを切るドメインイベント（名前・ペイロード・発行側・購読側）
+   */
+  private planDomainEvents(cycles: BoundaryCycle[]): DomainEventPlan | undefined {
+    try {
+      return planDomainEvents(this.projectRoot, cycles);
+    } catch (error) {
+      console.warn(`⚠️  ドメインイベントの計画に失敗しました: ${getErrorMessage(error)}`);
+      return undefined;
+    }
+  }
+
+  private addCycleActions(modules: ModuleDesign[], cycles: BoundaryCycle[], events?: DomainEventPlan): void {
+    const cuts = new Map<string, { cycle: BoundaryCycle; cycles: number }>();
+    for (const cycle of cycles) {
+      const key = `${cycle.break_at.from}\n${cycle.break_at.to}`;
+      cuts.set(key, { cycle: cuts.get(key)?.cycle ?? cycle, cycles: (cuts.get(key)?.cycles ?? 0) + 1 });
+    }
+
+    for (const { cycle, cycles: count } of cuts.values()) {
+      const { from, to, calls, remedy } = cycle.break_at;
+      const module = findModule(modules, from);
+      if (!module || module.status === 'established') continue;
+      const sites = cycle.edges.find(edge => edge.from === from && edge.to === to)?.sites ?? [];
+      const names = (events?.events ?? []).filter(event => event.producer === from && event.consumers.some(c => c.module === to)).map(event => event.name);
+      module.refactoring_actions.unshift({
+        type: remedy === 'interface' ? 'extract_interface' : 'introduce_event',
+        description: remedy === 'interface'
+          ? `依存サイクル${count > 1 ? ` ${count}件` : ''}を ${from} → ${to} の呼び出し${calls}箇所で切断: ${from} 側にインターフェースを定義し、${to} の実装をコンポジションルートで注入`
+          : `依存サイクル${count > 1 ? ` ${count}件` : ''}を ${from} → ${to} の呼び出し${calls}箇所で切断: ${from} がドメインイベント${names.length > 0 ? `（${names.join(', ')}）` : ''}を発行し、${to} が購読`,
+        files_affected: [...new Set(sites.map(site => site.caller_file))],
+        priority: 'high',
+        effort_estimate: calls > 5 ? '1-2週間' : '3-5日',
+        decision: `cut-cycle:${from}->${to}`,
+      });
+    }
+  }
+
+  /**
+   * 複数モジュールをまとめる関数を、現在割り当てられているモジュールからサーガ・オーケストレーターとして切り出すアクション
+   */
+  private addOrchestratorActions(modules: ModuleDesign[], candidates: OrchestratorCandidate[]): void {
+    for (const candidate of candidates) {
+      const module = candidate.module ? findModule(modules, candidate.module) : undefined;
+      if (!module || module.status === 'established') continue;
+      const others = candidate.touches.map(touch => touch.module).join(', ');
+      module.refactoring_actions.unshift({
+        type: 'extract_orchestrator',
+        description: candidate.kind === 'saga'
+          ? `${candidate.function} は ${module.name} と ${others} のデータを更新: 補償処理を持つサーガとして切り出す`
+          : `${candidate.function} は ${others} を呼び出してまとめる: アプリケーションサービスとして切り出し、各モジュールの公開 API を呼ぶ`,
+        files_affected: [candidate.file],
+        priority: 'high',
+        effort_estimate: candidate.kind === 'saga' ? '1-2週間' : '3-5日',
+        decision: `orchestrator:${candidate.function}`,
+      });
+    }
+  }
+
   private generateRefactoringActions(
     boundary: DomainBoundary,
     currentState: ModuleState,
@@ -227,6 +1306,7 @@ export class ArchitectAgent {
         files_affected: (boundary.dependencies?.internal ?? []).slice(0, 3),
         priority: 'high',
         effort_estimate: '1-2週間',
+        decision: `extract-interface:${boundary.name}`,
       });
     }
 
@@ -261,19 +1341,32 @@ export class ArchitectAgent {
         files_affected: boundary.circular_dependencies,
         priority: 'high',
         effort_estimate: '2-3週間',
+        decision: `introduce-event:${boundary.name}`,
       });
     }
 
     return actions;
   }
 
-  private extractModuleDependencies(boundary: DomainBoundary): ModuleDependency[] {
+  private extractModuleDependencies(boundary: DomainBoundary, allBoundaries: DomainBoundary[]): ModuleDependency[] {
     const dependencies: ModuleDependency[] = [];
     
+    // コード上の依存（ドメインマップ由来）
+    domainBoundaryAdapter.toConstrained(boundary, allBoundaries).dependsOn.forEach(dep => {
+      const id = allBoundaries.find(other => other.name === dep)?.id;
+      dependencies.push({
+        module: dep,
+        ...(id ? { module_id: id } : {}),
+        type: 'interface',
+        description: `${dep}モジュールのコードに直接依存`,
+      });
+    });
+    
     if (this.boundaryConfig) {
       const moduleConfig = this.boundaryConfig.modules[boundary.name];
       if (moduleConfig) {
         moduleConfig.depends_on?.forEach(dep => {
+          if (dependencies.some(d => d.module === dep.split('.')[0])) return;
           dependencies.push({
             module: dep.split('.')[0], // Extract module name from interface path
             type: 'interface',
@@ -316,18 +1409,116 @@ export class ArchitectAgent {
 }`;
   }
 
-  private createMigrationStrategy(modules: ModuleDesign[]): MigrationStrategy {
-    const configPhases = this.config.migration.phases;
+  /**
+   * schedule 設定に沿ってモジュールを日程付きのフェーズに割り当てる
+   * Module effort is the sum of its refactoring action estimates; phases of the
+   * previous plan.json whose modules are all accepted are kept as they were.
+   */
+  private scheduleModules(modules: ModuleDesign[]): PlanSchedule | undefined {
+    const config = mergeScheduleConfig(this.config.schedule, this.boundaryConfig?.schedule);
+    if (!config) return undefined;
+
+    let previous: PlanSchedule | undefined;
+    try {
+      previous = JSON.parse(fs.readFileSync(this.paths.planJsonPath, 'utf8')).schedule;
+    } catch {
+      previous = undefined;
+    }
+
+    let accepted = new Set<string>();
+    try {
+      const statuses = new ModuleStatusTracker(this.projectRoot, new PerformanceStore(this.projectRoot, { readOnly: true })).list();
+      accepted = new Set(statuses.filter(status => status.stage === 'accepted').map(status => status.module));
+    } catch (error) {
+      console.warn(`⚠️  モジュールの受け入れ状況の読み込みに失敗しました: ${getErrorMessage(error)}`);
+    }
+
+    // 制約で統合されたモジュールの期限は統合先の最も早い期限にする
+    const deadlines: Record<string, string> = {};
+    for (const [name, date] of Object.entries(config.deadlines ?? {})) {
+      const module = findModule(modules, name);
+      if (!module) {
+        console.warn(`⚠️  schedule.deadlines のモジュールが見つかりません: ${name}`);
+        continue;
+      }
+      if (!deadlines[module.name] || date < deadlines[module.name]) deadlines[module.name] = date;
+    }
+
+    // Established modules are not migrated; depending on them does not order the phases
+    const migrated = modules.filter(module => module.status !== 'established');
+    return schedulePhases(
+      migrated.map(module => {
+        const team = this.config.boundaries?.target_modules?.[module.name]?.team ?? config.teams?.[module.name] ?? module.team;
+        return {
+          name: module.name,
+          effort_days: module.refactoring_actions.reduce((sum, action) => sum + estimateEffortDays(action.effort_estimate, config), 0),
+          depends_on: migratedDependencies(modules, module),
+          ...(team ? { team } : {}),
+        };
+      }),
+      { ...config, deadlines },
+      { today: new Date().toISOString().slice(0, 10), accepted, previous }
+    );
+  }
+
+  private createMigrationStrategy(modules: ModuleDesign[], schedule?: PlanSchedule): MigrationStrategy {
     const phases: MigrationPhase[] = [];
 
-    for (const [phaseKey, phaseConfig] of Object.entries(configPhases)) {
-      const phaseModules = modules.filter(m => phaseConfig.modules.includes(m.name));
+    // Established modules stay as they are and belong to no phase
+    const scheduled = new Set<string>(modules.filter(module => module.status === 'established').map(module => module.name));
+    const migrated = modules
+      .filter(module => module.status !== 'established')
+      .map(module => ({ name: module.name, depends_on: migratedDependencies(modules, module) }));
+    const { layers, cycles } = dependencyLayers(migrated);
+    const effortConfig = mergeScheduleConfig(this.config.schedule, this.boundaryConfig?.schedule);
+    const layerPhases = (names: string[][]) => names.map(members => ({
+      name: members.join(', '),
+      duration: formatEffortDuration(modules.filter(m => members.includes(m.name)), effortConfig),
+      modules: members,
+      dates: {},
+    }));
+
+    let order: MigrationOrder;
+    let plannedPhases: { name: string; duration: string; modules: string[]; dates: Partial<MigrationPhase> }[];
+    if (schedule) {
+      order = { source: 'schedule', cycles, misordered: [] };
+      plannedPhases = schedule.phases.map(phase => ({
+        name: phase.name,
+        duration: formatPhaseDuration(phase),
+        modules: phase.modules,
+        dates: { start: phase.start, end: phase.end, effort_days: phase.effort_days, fixed: phase.fixed },
+      }));
+    } else {
+      // 設定のフェーズは依存先が先に移行される場合だけ使い、含まれないモジュールは依存順に後ろへ追加する
+      const configured = Object.values(this.config.migration.phases).map(phase => ({
+        ...phase,
+        modules: phase.modules.map(name => findModule(modules, name)?.name ?? name),
+      }));
+      const misordered = findMisorderedModules(configured, migrated, cycles);
+      const unplaced = layers
+        .map(layer => layer.filter(name => !configured.some(phase => phase.modules.includes(name))))
+        .filter(layer => layer.length > 0);
+      if (configured.length > 0 && misordered.length === 0) {
+        order = { source: 'configured', cycles, misordered };
+        plannedPhases = [...configured.map(phase => ({ ...phase, dates: {} })), ...layerPhases(unplaced)];
+      } else {
+        order = { source: 'dependencies', cycles, misordered };
+        plannedPhases = layerPhases(layers);
+      }
+    }
+
+    for (const phaseConfig of plannedPhases) {
+      // 制約で統合・分離されたモジュールは最初に登場するフェーズへ寄せる
+      const phaseModuleNames = [...new Set(phaseConfig.modules.map(name => findModule(modules, name)?.name ?? name))]
+        .filter(name => !scheduled.has(name));
+      phaseModuleNames.forEach(name => scheduled.add(name));
+      const phaseModules = modules.filter(m => phaseModuleNames.includes(m.name));
       const phaseActions = phaseModules.flatMap(m => m.refactoring_actions);
 
       phases.push({
         name: phaseConfig.name,
         duration: phaseConfig.duration,
-        modules: phaseConfig.modules,
+        modules: phaseModuleNames,
         actions: phaseActions,
         success_criteria: [
           'すべてのテストが通る',
@@ -341,12 +1532,15 @@ export class ArchitectAgent {
             impact: 'high',
             mitigation: 'ロールバック計画の準備とバックアップ作成',
           },
+          ...phaseModules.flatMap(module => debtRisk(module)),
         ],
+        ...phaseConfig.dates,
       });
     }
 
     return {
       phases,
+      order,
       rollback_plan: 'Gitを使用した段階的ロールバック。各フェーズ完了後にタグ作成。',
       validation_steps: [
         'テストスイート実行',
@@ -357,22 +1551,32 @@ export class ArchitectAgent {
     };
   }
 
-  private createImplementationGuide(modules: ModuleDesign[]): ImplementationGuide {
+  private createImplementationGuide(modules: ModuleDesign[], style: ArchitectureStyle = 'clean'): ImplementationGuide {
     const directoryStructure: DirectoryStructure = {};
     
-    modules.forEach(module => {
-      directoryStructure[module.name] = [
-        `internal/${module.name}/`,
-        `internal/${module.name}/domain/`,
-        `internal/${module.name}/usecase/`,
-        `internal/${module.name}/repository/`,
-        `internal/${module.name}/handler/`,
-      ];
+    modules.filter(module => module.status !== 'established').forEach(module => {
+      directoryStructure[module.name] = moduleDirectories(module.name, style);
     });
 
     return {
       directory_structure: directoryStructure,
-      naming_conventions: [
+      naming_conventions: style === 'hexagonal' ? [
+        {
+          type: 'Inbound Port',
+          pattern: '{Entity}UseCase',
+          example: 'port.CustomerUseCase',
+        },
+        {
+          type: 'Outbound Port',
+          pattern: '{Entity}Repository / {Service}Gateway',
+          example: 'port.CustomerRepository',
+        },
+        {
+          type: 'Adapter',
+          pattern: '{Technology}{Entity}Repository',
+          example: 'repository.PostgresCustomerRepository',
+        },
+      ] : [
         {
           type: 'Interface',
           pattern: 'I{ServiceName}',
@@ -428,11 +1632,12 @@ export class ArchitectAgent {
   }
 
   private generateOverview(domainMap: DomainMap, modules: ModuleDesign[]): string {
+    const established = modules.filter(module => module.status === 'established').map(module => module.name);
     return `# ${domainMap.project} リファクタリング計画
 
 ## 現状分析
 - 総ファイル数: ${domainMap.total_files}
-- 識別されたモジュール: ${modules.length}個
+- 識別されたモジュール: ${modules.length}個${established.length > 0 ? `（うち確立済み ${established.length}個: ${established.join(', ')}）` : ''}
 - 全体的凝集度: ${domainMap.metrics.overall_cohesion}
 - 全体的結合度: ${domainMap.metrics.overall_coupling}
 - モジュラリティスコア: ${domainMap.metrics.modularity_score}
@@ -447,17 +1652,24 @@ ${this.config.refactoring.target_architecture.pattern}パターンによる${thi
 - イベント駆動による循環依存解消`;
   }
 
-  private generatePlanMarkdown(plan: ArchitecturalPlan): string {
-    let markdown = `# アーキテクチャ計画書
-
+  /**
+   * plan.md の生成ブロック（モジュール・フェーズ・各セクション）
+   * Module and phase blocks carry the fields vf plan sync reads back into plan.json.
+   */
+  renderPlanBlocks(plan: ArchitecturalPlan): GeneratedBlock[] {
+    const blocks: GeneratedBlock[] = [{
+      key: 'overview',
+      content: `# アーキテクチャ計画書
+${plan.sampling ? renderExploratoryNotice(plan.sampling) : ''}
 ${plan.overview}
 
-## モジュール設計
-
-`;
+## モジュール設計`,
+    }];
 
     plan.modules.forEach(module => {
-      markdown += `### ${module.name}
+      blocks.push({
+        key: `module:${moduleKey(module)}`,
+        content: `### ${module.name}
 
 **説明**: ${module.description}
 
@@ -470,35 +1682,191 @@ ${plan.overview}
 - 結合度: ${module.target_state.coupling_score}
 - 凝集度: ${module.target_state.cohesion_score}
 
-**リファクタリングアクション**:
-${module.refactoring_actions.map(action => `- ${action.description} (${action.priority})`).join('\n')}
+${module.established ? `**状態**: established（\`${module.established.root}\`、再クラスタリング・リファクタリングの対象外）\n\n` : ''}${module.deployment === 'service' ? '**デプロイ**: service（独立デプロイ）\n\n' : ''}${module.debt ? `**技術的負債**: ${module.debt.total}件${formatDebtCategories(module.debt)}\n\n` : ''}${PLAN_FILES_HEADING}
+${module.current_state.files.map(file => `- \`${file}\``).join('\n')}
 
-`;
+${PLAN_ACTIONS_HEADING}
+${module.refactoring_actions.map(action => `- ${formatAction(action)}`).join('\n')}`,
+      });
     });
 
-    markdown += `## 移行戦略
-
-`;
-
+    blocks.push({ key: 'section:migration', content: '## 移行戦略' });
     plan.migration_strategy.phases.forEach((phase, index) => {
-      markdown += `### フェーズ${index + 1}: ${phase.name}
+      blocks.push({
+        key: `phase:${index + 1}`,
+        content: `### フェーズ${index + 1}: ${phase.name}
 
-- 期間: ${phase.duration}
+- 期間: ${phase.duration}${phase.start ? `（予定: ${phase.start} 〜 ${phase.end}${phase.fixed ? '、受け入れ済み' : ''}）` : ''}
 - 対象モジュール: ${phase.modules.join(', ')}
-- アクション数: ${phase.actions.length}
+- アクション数: ${phase.actions.length}`,
+      });
+    });
 
-`;
+    blocks.push({
+      key: 'section:quality-gates',
+      content: `## 品質ゲート
+
+${plan.quality_gates.map(gate => `- **${gate.name}**: ${gate.current_value} → ${gate.threshold} (${gate.description})`).join('\n')}`,
     });
 
-    markdown += `## 品質ゲート
+    const sections: [string, string][] = [];
+    if (plan.constraint_adjustments.length > 0) {
+      sections.push(['constraint-adjustments', `## 境界制約による調整
 
-`;
+${plan.constraint_adjustments.map(adjustment => `- ${adjustment}`).join('\n')}`]);
+    }
 
-    plan.quality_gates.forEach(gate => {
-      markdown += `- **${gate.name}**: ${gate.current_value} → ${gate.threshold} (${gate.description})
-`;
-    });
+    if (plan.constraint_violations.length > 0) {
+      sections.push(['constraint-violations', `## 制約違反 (Constraint Violations)
+
+以下の制約は自動的に満たすことができませんでした。
 
-    return markdown;
+${plan.constraint_violations.map(v => `- **${v.constraint}** [${v.modules.join(', ')}]: ${v.message}${v.reason ? `\n  - 理由: ${v.reason}` : ''}`).join('\n')}`]);
+    }
+
+    const debtRisks = plan.modules.flatMap(module => debtRisk(module));
+    if (debtRisks.length > 0) {
+      sections.push(['debt-risks', `## 技術的負債によるリスク
+
+${debtRisks.map(risk => `- ${risk.description} (発生確率: ${risk.probability}, 影響: ${risk.impact})\n  - 対策: ${risk.mitigation}`).join('\n')}`]);
+    }
+
+    sections.push(
+      ['architecture', renderArchitectureStyleSection(plan.architecture_style, plan.modules.filter(m => m.status !== 'established').map(m => m.name))],
+      ['risk', renderRiskSection(plan.modules, resolveRiskThresholds(this.config.autonomy))],
+      ['estimates', renderEstimatesSection(plan.estimates, plan.migration_strategy.phases)],
+      ['risk-heatmap', renderHeatmapSection(plan.risk_heatmap)],
+      ['database', renderDatabaseSection(plan.database)],
+      ['ports', renderPortsSection(plan.modules)],
+      ['anti-corruption-layers', renderAclSection(plan.anti_corruption_layers, this.config.acl?.minReferences ?? DEFAULT_ACL_MIN_REFERENCES)],
+      ['established', renderEstablishedSection(plan.modules, plan.established_api_changes)],
+      ['shared-state', renderSharedStateSection(plan.shared_state ?? [])],
+      ['config-access', renderConfigAccessSection(plan.config_access, plan.modules)],
+      ['package-mismatches', renderPackageMismatchSection(plan.package_mismatches ?? [])],
+      ['test-support', renderTestSupportSection(plan.test_support)],
+      ['shared-kernel', renderSharedKernelSection(plan.shared_kernel)],
+      ['cycles', renderBoundaryCyclesSection(plan.cycles)],
+      ['domain-events', renderDomainEventsSection(plan.domain_events)],
+      ['orchestrators', renderOrchestratorsSection(plan.orchestrators)],
+      ['async-edges', renderAsyncEdgesSection(plan.async_edges)],
+      ['generated-code', renderGeneratedCodeSection(plan.generated_code)],
+      ['external-consumers', renderExternalConsumersSection(plan.external_consumers)],
+      ['migration-order', renderMigrationOrderSection(plan.migration_strategy.order)],
+      ['go-modules', renderGoModulesSection(plan.go_modules)],
+      ['schedule', renderScheduleSection(plan.schedule)],
+      ['service-extraction', renderServiceExtractionSection(plan.service_extraction, plan.modules.flatMap(module => module.service ?? []))],
+      ['services', renderServiceSection(plan.modules.flatMap(module => module.service ?? []))],
+      ['strangler', renderStranglerSection(plan.strangler)],
+      ['decisions', renderDecisionsSection(plan.decisions)],
+    );
+
+    return blocks.concat(sections.filter(([, content]) => content !== '').map(([name, content]) => ({ key: `section:${name}`, content })));
+  }
+}
+
+/**
+ * 既存のplan.jsonが境界制約を満たしているか検証
+ */
+export function checkPlanConstraints(plan: ArchitecturalPlan, constraints: BoundaryConstraints | undefined): ConstraintViolation[] {
+  return evaluateConstraints(
+    plan.modules.map(module => ({
+      name: module.name,
+      files: module.current_state.files,
+      mergedFrom: module.merged_from ?? [],
+      dependsOn: module.dependencies.map(dep => dep.module),
+      tables: module.owned_tables ?? [],
+      cycles: [],
+    })),
+    constraints
+  );
+}
+
+/**
+ * 独立デプロイに必要なアクション: テーブル直接アクセスの置き換え、API定義、エントリポイント
+ */
+function serviceActions(module: ModuleDesign, service: ServiceRequirements): RefactoringAction[] {
+  const actions: RefactoringAction[] = [];
+  const owners = [...new Set(service.foreign_table_access.map(access => access.module === module.name ? access.owner : access.module))];
+  if (owners.length > 0) {
+    actions.push({
+      type: 'introduce_event',
+      description: `他モジュール所有テーブルへの直接アクセス${service.foreign_table_access.length}件を所有モジュールのインターフェースまたはイベントに置き換え (${owners.join(', ')})`,
+      files_affected: [...new Set(service.foreign_table_access.map(access => access.location.file))],
+      priority: 'high',
+      effort_estimate: '1-2週間',
+      decision: `service-data:${module.name}`,
+    });
   }
+  if (service.api.operations.length > 0) {
+    actions.push({
+      type: 'extract_interface',
+      description: `API定義 ${service.api.path} (${service.api.operations.length}個の rpc) を追加し、${service.network_calls.length}件の呼び出しをクライアント経由に変更`,
+      files_affected: [...new Set(service.network_calls.map(call => call.location.file))],
+      priority: 'high',
+      effort_estimate: '1-2週間',
+      decision: `service-api:${module.name}`,
+    });
+  }
+  actions.push({
+    type: 'create_entrypoint',
+    description: `エントリポイント ${service.entrypoint} を作成し、${module.name} 専用のコンポジションルートを構成`,
+    files_affected: service.scaffolds ?? [],
+    priority: 'medium',
+    effort_estimate: '2-3日',
+  });
+  return actions;
+}
+
+/**
+ * 既知の負債が多いモジュールの自動リファクタリングのリスク
+ */
+function debtRisk(module: ModuleDesign): Risk[] {
+  const debt = module.debt;
+  if (!debt || debt.total < DEBT_RISK_THRESHOLDS.medium) return [];
+  const missing = debt.by_category['missing-implementation'] ?? 0;
+  return [{
+    description: `${module.name}モジュールに既知の技術的負債が${debt.total}件 (未実装${missing}件, 既知のバグ${debt.by_category['known-bug'] ?? 0}件)`,
+    probability: debt.total >= DEBT_RISK_THRESHOLDS.high ? 'high' : 'medium',
+    impact: missing > 0 ? 'high' : 'medium',
+    mitigation: 'vf discover --debt で該当箇所を確認し、未実装・既知のバグを先に解消するか手動で移行',
+  }];
+}
+
+function renderExploratoryNotice(sampling: DomainMapSampling): string {
+  const lowCoverage = sampling.low_coverage_packages
+    .map(p => `\`${p.package}\` ${p.analyzed}/${p.total}`)
+    .join(', ');
+  return `
+> **探索用 (exploratory)**: サンプリングしたドメインマップ (${formatSampling(sampling)}) から生成した計画です。
+> 凝集度 ±${sampling.error_band.cohesion} / 結合度 ±${sampling.error_band.coupling} の誤差を含みます。${lowCoverage ? `カバレッジの低いパッケージ: ${lowCoverage}` : ''}
+> この計画では vf refactor を実行できません。サンプリングなしで vf discover と vf plan を再実行してください。
+`;
+}
+
+function formatDebtCategories(debt: BoundaryDebt): string {
+  const categories = Object.entries(debt.by_category).map(([category, count]) => `${category} ${count}`);
+  return categories.length > 0 ? ` (${categories.join(', ')})` : '';
+}
+
+/**
+ * Modules `module` depends on, by their planned names; established modules are not migrated and do not order the phases
+ */
+function migratedDependencies(modules: ModuleDesign[], module: ModuleDesign): string[] {
+  return module.dependencies
+    .map(dependency => findModule(modules, dependency.module)?.name ?? dependency.module)
+    .filter(name => !modules.some(m => m.name === name && m.status === 'established'));
+}
+
+/**
+ * Duration of a phase ordered by dependency, from the effort estimates of its
+ * actions at the velocity and sprint length the schedule uses
+ */
+function formatEffortDuration(modules: ModuleDesign[], config: { velocity?: number; sprintWeeks?: number } = {}): string {
+  const days = modules.flatMap(module => module.refactoring_actions)
+    .reduce((sum, action) => sum + estimateEffortDays(action.effort_estimate, config), 0);
+  return days > 0 ? `${Math.ceil(days)}人日` : '未見積';
+}
+
+function findModule(modules: ModuleDesign[], name: string): ModuleDesign | undefined {
+  return modules.find(m => m.name === name || (m.merged_from ?? []).includes(name));
 }
\ No newline at end of file
@@ -1,6 +1,7 @@
 import * as fs from 'fs';
 import { CodeAnalyzer, FileInfo, DependencyGraph } from '../utils/code-analyzer.js';
 import { ConfigLoader } from '../utils/config-loader.js';
+import { portableArtifact } from '../utils/workspace-paths.js';
 import { VibeFlowConfig, BoundaryConfig, DomainMap, DomainBoundary } from '../types/config.js';
 
 export interface BoundaryAnalysisResult {
@@ -9,10 +10,12 @@ export interface BoundaryAnalysisResult {
 }
 
 export class BoundaryAgent {
+  private projectRoot: string;
   private analyzer: CodeAnalyzer;
   private config: VibeFlowConfig;
 
   constructor(projectRoot: string, configPath?: string, boundaryConfigPath?: string) {
+    this.projectRoot = projectRoot;
     this.analyzer = new CodeAnalyzer(projectRoot);
     this.config = ConfigLoader.loadVibeFlowConfig(configPath);
     // boundaryConfig is loaded but not used in current implementation
@@ -58,7 +61,7 @@ export class BoundaryAgent {
 
     // 7. 出力
     const outputPath = this.config.output.artifacts.domain_map;
-    fs.writeFileSync(outputPath, JSON.stringify(domainMap, null, 2));
+    fs.writeFileSync(outputPath, JSON.stringify(portableArtifact(this.projectRoot, domainMap), null, 2));
     
     console.log(`✅ ドメインマップを生成しました: ${outputPath}`);
     
@@ -5,6 +5,8 @@ import * as fs from 'fs';
 import * as path from 'path';
 import { glob } from 'glob';
 import { detectGoProject, withGoWorkingDirectory } from '../utils/go-project-utils.js';
+import { goImports } from '../utils/go-load-check.js';
+import { goImportSpec, loadGoPackages, packageNameOf, rewriteGoImport } from '../utils/go-packages.js';
 
 const BuildErrorSchema = z.object({
   file: z.string(),
@@ -201,11 +203,9 @@ export class BuildFixerAgent extends BaseAgent<BuildFixerInput, BuildFixerOutput
     
     if (!newImportPath) return null;
 
+    // Keep the name the code uses for the package; alias when the new package's name differs
     const fileContent = fs.readFileSync(error.file, 'utf-8');
-    const updatedContent = fileContent.replace(
-      new RegExp(`"${oldImportPath}"`, 'g'),
-      `"${newImportPath}"`
-    );
+    const updatedContent = rewriteGoImport(fileContent, oldImportPath, newImportPath, loadGoPackages(input.projectPath));
 
     return {
       type: 'import',
@@ -280,15 +280,17 @@ export class BuildFixerAgent extends BaseAgent<BuildFixerInput, BuildFixerOutput
 
     const fileContent = fs.readFileSync(error.file, 'utf-8');
     
-    // Add import for the new location if not present
+    // Add import for the new location if not present, named after the package's
+    // declared name and never after an identifier the file already uses
     const importPath = this.getImportPathForType(newLocation);
-    const importAlias = this.getImportAlias(importPath);
+    const importSpec = goImportSpec(fileContent, importPath, packageNameOf(loadGoPackages(input.projectPath), importPath));
+    const importAlias = importSpec.name;
     
     let updatedContent = fileContent;
     
     // Add import if not present
-    if (!updatedContent.includes(importPath)) {
-      const importStatement = `import ${importAlias} "${importPath}"`;
+    if (!goImports(updatedContent).some(i => i.path === importPath)) {
+      const importStatement = `import ${importSpec.alias ? `${importSpec.alias} ` : ''}"${importPath}"`;
       updatedContent = this.addImportToGoFile(updatedContent, importStatement);
     }
     
@@ -540,11 +542,6 @@ require (
     return location;
   }
 
-  private getImportAlias(importPath: string): string {
-    const parts = importPath.split('/');
-    return parts[parts.length - 1];
-  }
-
   private addImportToGoFile(content: string, importStatement: string): string {
     const importBlockMatch = content.match(/import\s*\([^)]*\)/s);
     
@@ -16,8 +16,13 @@ import { DomainBoundary } from '../types/config.js';
 import { ClaudeCodeBusinessLogicIntegration } from '../utils/claude-code-business-logic-integration.js';
 import { BusinessLogicPreservationValidator } from '../validators/business-logic-preservation-validator.js';
 import { getErrorMessage } from '../utils/error-utils.js';
+import { summarizeProcessing } from '../utils/file-processing.js';
+import { FileProcessingRecord, PerformanceStore, ProcessingMethod } from '../utils/performance-store.js';
 import { CheckpointManager, CheckpointData, ResumeOptions } from '../utils/checkpoint-manager.js';
 import { RateLimitManager } from '../utils/rate-limit-manager.js';
+import { toPosixPath } from '../utils/workspace-paths.js';
+import { SourceFile } from '../utils/source-positions.js';
+import { isIgnored, parseAnnotations } from '../utils/source-annotations.js';
 
 /**
  * 業務ロジック移行エージェント
@@ -109,13 +114,11 @@ export class BusinessLogicMigrationAgent {
       const absolutePath = path.isAbsolute(filePath) ? filePath : path.join(this.projectRoot, filePath);
       const content = await fs.readFile(absolutePath, 'utf8');
       
-      // Claude Codeを使った高度な抽出
-      if (this.useAI && this.claudeCodeIntegration) {
-        return await this.extractWithClaudeCode(content, filePath);
-      }
-      
-      // フォールバック: 基本的な静的解析
-      return await this.extractWithStaticAnalysis(content, filePath);
+      // Claude Codeを使った高度な抽出（フォールバック: 基本的な静的解析）
+      const result = this.useAI && this.claudeCodeIntegration
+        ? await this.extractWithClaudeCode(content, filePath)
+        : await this.extractWithStaticAnalysis(content, filePath);
+      return this.dropIgnoredRules(result, content, filePath);
       
     } catch (error) {
       console.error(`❌ Failed to extract business logic from ${filePath}:`, getErrorMessage(error));
@@ -184,7 +187,8 @@ export class BusinessLogicMigrationAgent {
       console.log(`    🔍 Analyzing function: ${func.name || 'anonymous'}`);
       
       // 業務ルールの抽出
-      const extractedRules = this.extractBusinessRulesFromFunction(func, filePath);
+      const extractedRules = this.extractBusinessRulesFromFunction(func, filePath)
+        .map(rule => ({ ...rule, location: { ...rule.location, function: func.name } }));
       rules.push(...extractedRules);
       console.log(`      📝 Extracted ${extractedRules.length} business rules`);
       
@@ -206,8 +210,9 @@ export class BusinessLogicMigrationAgent {
 
     console.log(`  ✅ Static analysis complete: ${rules.length} rules, ${dataAccess.length} data patterns, ${workflows.length} workflows`);
 
+    const source = new SourceFile(filePath, content);
     return {
-      rules,
+      rules: rules.map(rule => this.locateRule(rule, source)),
       dataAccess,
       workflows,
       complexity: this.calculateComplexity(rules, dataAccess, workflows)
@@ -402,6 +407,20 @@ export class BusinessLogicMigrationAgent {
     }
   }
 
+  /**
+   * 業務ルールのコード位置 (line/column/offset) を特定
+   * Falls back to the enclosing function declaration, then to the recorded line.
+   */
+  private locateRule(rule: BusinessRule, source: SourceFile): BusinessRule {
+    const location = source.findSnippet(rule.code, rule.location.line) ??
+      (rule.location.function ? source.findDeclaration(rule.location.function) : null) ??
+      source.locateLine(rule.location.line);
+    if (!location) return rule;
+
+    const { file: _, ...position } = location;
+    return { ...rule, location: { ...rule.location, ...position } };
+  }
+
   // ヘルパーメソッド群
   private detectLanguage(filePath: string): 'go' | 'typescript' | 'python' {
     if (filePath.endsWith('.go')) return 'go';
@@ -1092,7 +1111,17 @@ export class BusinessLogicMigrationAgent {
     return summary;
   }
 
-  private createEmptyExtractResult(): BusinessLogicExtractResult {
+  /**
+   * //vf:ignore が付いた宣言のルールを除外
+   */
+  private dropIgnoredRules(result: BusinessLogicExtractResult, content: string, filePath: string): BusinessLogicExtractResult {
+    const file = toPosixPath(path.isAbsolute(filePath) ? path.relative(this.projectRoot, filePath) : filePath);
+    const { annotations } = parseAnnotations(content, file);
+    if (!annotations.some(a => a.directive === 'ignore')) return result;
+    return { ...result, rules: result.rules.filter(rule => !isIgnored(annotations, file, rule.location.function)) };
+  }
+
+    private createEmptyExtractResult(): BusinessLogicExtractResult {
     return {
       rules: [],
       dataAccess: [],
@@ -1169,16 +1198,20 @@ export class BusinessLogicMigrationAgent {
       // 3. 各ファイルから業務ロジックを抽出（チェックポイント対応）
       let totalRules = result.totalBusinessRules;
       const saveInterval = 50; // 50ファイルごとにチェックポイント保存
+      const method: ProcessingMethod = this.useAI && this.claudeCodeIntegration ? 'llm' : 'static';
+      const processed: Pick<FileProcessingRecord, 'method' | 'status' | 'duration_ms'>[] = [];
       
       for (let i = 0; i < projectFiles.length; i++) {
         const filePath = projectFiles[i];
-        const relativePath = path.relative(request.projectPath, filePath);
+        const relativePath = toPosixPath(path.relative(request.projectPath, filePath));
         
         // レジューム時のスキップ判定
         if (resumeOptions && !this.checkpointManager.shouldProcessFile(relativePath, checkpoint, resumeOptions)) {
           continue;
         }
 
+        const startedAt = Date.now();
+        const boundaryName = this.determineBoundaryForFile(filePath, domainMap);
         try {
           console.log(`\n📁 Processing: ${relativePath} (${i + 1}/${projectFiles.length})`);
           
@@ -1188,18 +1221,24 @@ export class BusinessLogicMigrationAgent {
           );
           totalRules += extractResult.rules.length;
           
-          if (this.useAI && this.claudeCodeIntegration) {
+          if (method === 'llm') {
             result.aiProcessedFiles++;
           } else {
             result.staticAnalysisFiles++;
           }
+          processed.push({ method, status: 'success', duration_ms: Date.now() - startedAt });
+          await this.recordFileProcessing(relativePath, filePath, boundaryName, {
+            method,
+            status: 'success',
+            duration_ms: Date.now() - startedAt,
+            bytes_out: Buffer.byteLength(JSON.stringify(extractResult), 'utf8'),
+          });
 
           // 業務ロジックがある場合のみ移行処理
           if (extractResult.rules.length > 0 || extractResult.workflows.length > 0) {
             console.log(`  🎯 Found ${extractResult.rules.length} rules and ${extractResult.workflows.length} workflows`);
             
             // 境界情報の更新
-            const boundaryName = this.determineBoundaryForFile(filePath, domainMap);
             let boundary = result.migratedBoundaries.find(b => b.name === boundaryName);
             if (!boundary) {
               boundary = { name: boundaryName, files: 0, extractedRules: 0, migratedRules: 0 };
@@ -1231,11 +1270,19 @@ export class BusinessLogicMigrationAgent {
           const errorMsg = `Failed to process ${filePath}: ${getErrorMessage(error)}`;
           result.errors.push(errorMsg);
           failedFiles.push(relativePath);
+          processed.push({ method, status: 'failed', duration_ms: Date.now() - startedAt });
+          await this.recordFileProcessing(relativePath, filePath, boundaryName, {
+            method,
+            status: 'failed',
+            duration_ms: Date.now() - startedAt,
+            error: getErrorMessage(error),
+          });
           console.error(`❌ ${errorMsg}`);
         }
       }
 
       result.totalBusinessRules = totalRules;
+      result.processing = summarizeProcessing(processed);
 
       // 最終チェックポイント保存
       await this.saveProgressCheckpoint(
@@ -1349,10 +1396,41 @@ export class BusinessLogicMigrationAgent {
     return files;
   }
 
+  /**
+   * file_processing record of an extracted file for the active run. Extraction
+   * writes no code, so there are no transformations and nothing is verified
+   */
+  private async recordFileProcessing(
+    relativePath: string,
+    filePath: string,
+    boundaryName: string,
+    details: Pick<FileProcessingRecord, 'method' | 'status' | 'duration_ms' | 'bytes_out' | 'error'>
+  ): Promise<void> {
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      const runId = store.getActiveRunId();
+      if (runId === undefined) return;
+
+      store.recordFileProcessing({
+        run_id: runId,
+        file: relativePath,
+        module: boundaryName,
+        module_name: boundaryName,
+        agent: 'BusinessLogicMigrationAgent',
+        bytes_in: (await fs.stat(filePath)).size,
+        transformations: [],
+        verification: 'not-run',
+        ...details,
+      });
+    } catch {
+      // Metrics are best-effort
+    }
+  }
+
   private determineBoundaryForFile(filePath: string, domainMap: any): string {
     if (domainMap?.boundaries) {
       for (const boundary of domainMap.boundaries) {
-        if (boundary.files?.some((f: string) => filePath.includes(f))) {
+        if (boundary.files?.some((f: string) => toPosixPath(filePath).includes(toPosixPath(f)))) {
           return boundary.name;
         }
       }
@@ -1,15 +1,51 @@
 import * as fs from 'fs';
+import * as path from 'path';
 import { CodeAnalyzer, FileInfo, DependencyGraph } from '../utils/code-analyzer.js';
 import { ConfigLoader } from '../utils/config-loader.js';
 import { AutoBoundaryDiscovery, AutoDiscoveredBoundary, BoundaryDiscoveryResult } from '../utils/auto-boundary-discovery.js';
 import { VibeFlowPaths } from '../utils/file-paths.js';
-import { VibeFlowConfig, BoundaryConfig, DomainMap, DomainBoundary } from '../types/config.js';
+import { resolveConstraints, domainBoundaryAdapter, ConstraintViolation } from '../utils/boundary-constraints.js';
+import { PackageLoadError } from '../utils/go-load-check.js';
+import { GoPackage, filePackages, goImportNames, loadGoPackages } from '../utils/go-packages.js';
+import { attachGoModules, loadGoWorkspace } from '../utils/go-workspace.js';
+import { findSharedKernel, separateSharedKernel } from '../utils/shared-kernel.js';
+import { annotateManualOverlap, fileOverlap } from '../utils/boundary-confidence.js';
+import { DomainMapWriter, assignBoundaryIds } from '../utils/domain-map-writer.js';
+import { DebtInventory, DebtInventoryScanner, attachDebt } from '../utils/debt-inventory.js';
+import { SamplingOptions, describeSample } from '../utils/discovery-sampling.js';
+import { SourceAnnotation, applyAnnotations } from '../utils/source-annotations.js';
+import { applyCuratedModules } from '../utils/boundary-curation.js';
+import { findEstablishedModules, importEstablishedBoundaries } from '../utils/established-modules.js';
+import { diffFiles, loadDiscoveryState, mergeIncrementalBoundaries, mergeScopedBoundaries, saveDiscoveryState } from '../utils/incremental-discovery.js';
+import { PathFilter, describePathFilters, matchesPathFilters } from '../utils/path-filters.js';
+import { analyzeTestHelpers } from '../utils/test-support.js';
+import { assignTestOwnership } from '../utils/test-ownership.js';
+import { HttpRoute, assignRouteModules } from '../utils/http-routes.js';
+import { buildAsyncEdges } from '../utils/message-topics.js';
+import { assignGeneratedModules } from '../utils/generated-code.js';
+import { findBoundaryCycles } from '../utils/boundary-cycles.js';
+import { assignGlobalCouplingModules, findGlobalCoupling } from '../utils/global-coupling.js';
+import { attachThirdPartyDependencies, findThirdPartyDependencies } from '../utils/third-party-deps.js';
+import { findOrchestrators } from '../utils/orchestrators.js';
+import { loadGlossary, nameBoundaries } from '../utils/boundary-naming.js';
+import { GranularityOptions } from '../utils/module-granularity.js';
+import { attachDomainModels } from '../utils/domain-model-style.js';
+import { attributeTeamOwnership } from '../utils/team-ownership.js';
+import { attachExternalConsumers, findFrontendApiCalls, matchApiCalls } from '../utils/frontend-api-calls.js';
+import { isOffline } from '../utils/offline-guard.js';
+import { CallGraph, callCoupling } from '../utils/call-graph.js';
+import { writeAnalysisDatabase } from '../utils/analysis-db.js';
+import { getErrorMessage } from '../utils/error-utils.js';
+import { VibeFlowConfig, BoundaryConfig, DomainMap, DomainBoundary, SharedKernelPackage, TestHelperUsage, TestOwnership, BoundaryCycle, GlobalCoupling, ThirdPartyDependency, OrchestratorCandidate } from '../types/config.js';
 
 export interface EnhancedBoundaryAnalysisResult {
   domainMap: DomainMap;
   autoDiscoveredBoundaries: AutoDiscoveredBoundary[];
   discoveryMetrics: BoundaryDiscoveryResult;
   hybridRecommendations: HybridRecommendation[];
+  constraintViolations: ConstraintViolation[];
+  /** TODO/FIXME inventory; absent when the scan failed */
+  debtInventory?: DebtInventory;
   outputPath: string;
 }
 
@@ -29,13 +65,57 @@ export class EnhancedBoundaryAgent {
   private paths: VibeFlowPaths;
   private config: VibeFlowConfig | null = null;
   private boundaryConfig: BoundaryConfig | null = null;
+  private sampling?: SamplingOptions;
+  private scope?: string;
+  private reconsiderEstablished: string[];
+  private incremental: boolean;
+  private pathFilters: PathFilter[];
+  /** Discovery state hashes of the files outside pathFilters, kept for the next incremental discovery */
+  private carriedState?: Record<string, string>;
 
-  constructor(projectRoot: string, config?: any, userBoundaries?: any[]) {
+  /**
+   * @param options.scope - Repository-relative directory `projectRoot` is a scope of, recorded in the domain map
+   * @param options.reconsiderEstablished - Established modules (name or root) left to clustering
+   * @param options.incremental - Merge changed files into the previous domain map (default); false re-clusters everything
+   * @param options.granularity - Target module count and sizes, overriding boundary.yaml clustering
+   * @param options.pathFilters - Only discover these directories and merge them into the previous domain map (vf discover --path / --package)
+   */
+  constructor(
+    projectRoot: string,
+    config?: any,
+    userBoundaries?: any[],
+    options: { sampling?: SamplingOptions; scope?: string; reconsiderEstablished?: string[]; incremental?: boolean; concurrency?: number; granularity?: GranularityOptions; pathFilters?: PathFilter[] } = {}
+  ) {
     this.projectRoot = projectRoot;
     this.analyzer = new CodeAnalyzer(projectRoot);
-    this.autoDiscovery = new AutoBoundaryDiscovery(projectRoot);
     this.paths = new VibeFlowPaths(projectRoot);
     
+    // boundary.yaml（モジュール定義と境界制約）はオプショナル
+    try {
+      this.boundaryConfig = ConfigLoader.loadBoundaryConfig(path.join(projectRoot, 'boundary.yaml'));
+    } catch (error) {
+      console.log('⚠️  boundary.yamlの読み込みに失敗しました。境界制約なしで実行します');
+    }
+    this.sampling = options.sampling;
+    this.scope = options.scope;
+    this.reconsiderEstablished = options.reconsiderEstablished ?? [];
+    this.incremental = options.incremental ?? true;
+    this.pathFilters = options.pathFilters ?? [];
+    this.autoDiscovery = new AutoBoundaryDiscovery(projectRoot, this.boundaryConfig?.constraints, {
+      sampling: options.sampling,
+      cache: true,
+      concurrency: options.concurrency,
+      pathFilters: this.pathFilters,
+      coChange: this.boundaryConfig?.coChange,
+      callGraph: this.boundaryConfig?.callGraph,
+      embeddings: this.boundaryConfig?.embeddings,
+      clustering: options.granularity ? { ...this.boundaryConfig?.clustering, ...options.granularity } : this.boundaryConfig?.clustering,
+      seeds: Object.fromEntries(
+        Object.entries(this.boundaryConfig?.modules ?? {}).flatMap(([name, module]) => (module.seeds ? [[name, module.seeds] as const] : []))
+      ),
+      schema: (config as VibeFlowConfig | undefined)?.repository?.schema ?? this.loadSchemaPaths(),
+    });
+    
     // 設定とユーザー境界はオプショナル（自動発見のため）
     if (config) {
       this.config = config;
@@ -67,14 +147,23 @@ export class EnhancedBoundaryAgent {
     // 1. 従来の手動境界分析
     const manualResult = await this.runManualBoundaryAnalysis();
     
-    // 2. AI自動境界発見
-    const autoResult = await this.autoDiscovery.discoverBoundaries();
+    // 2. AI自動境界発見（各境界に手動分析とのファイル重複を記録）
+    const discovered = await this.autoDiscovery.discoverBoundaries();
+    const autoResult = {
+      ...discovered,
+      discovered_boundaries: annotateManualOverlap(discovered.discovered_boundaries, manualResult.boundaries),
+    };
     
-    // 3. 手動と自動の結果を比較・統合
-    const hybridBoundaries = await this.mergeManulaAndAutoBoundaries(
+    // 3. 手動と自動の結果を比較・統合（境界制約を適用）
+    const mergedBoundaries = await this.mergeManulaAndAutoBoundaries(
       manualResult.boundaries,
       autoResult.discovered_boundaries
     );
+    const constrained = resolveConstraints(mergedBoundaries, this.boundaryConfig?.constraints, domainBoundaryAdapter);
+    const hybridBoundaries = this.scoreByCalls(
+      this.pathFilters.length > 0 ? this.mergeWithScopedDiscovery(constrained.items) : constrained.items,
+      autoResult.call_graph
+    );
     
     // 4. ハイブリッド推奨事項生成
     const hybridRecommendations = await this.generateHybridRecommendations(
@@ -83,17 +172,45 @@ export class EnhancedBoundaryAgent {
     );
     
     // 5. 最終ドメインマップ作成
-    const domainMap: DomainMap = {
+    const loadErrors = autoResult.load_errors ?? [];
+    // 6. 結果保存（安定IDを付与し、決定的な順序で書き出す）
+    const named = await this.nameBoundaries(hybridBoundaries);
+    const annotated = this.importEstablishedModules(this.applySourceAnnotations(this.applyCuratedBoundaries(named), autoResult.annotations));
+    const kernel = this.separateSharedKernel(annotated);
+    const debt = this.inventoryDebt(this.attributeTeamOwnership(this.classifyDomainModels(this.attachPackages(markDegradedFiles(kernel.boundaries, loadErrors)))));
+    const outputPath = this.paths.domainMapPath;
+    const sampling = autoResult.sample && describeSample(autoResult.sample, this.sampling ?? {}, manualResult.metrics);
+    const testHelpers = this.analyzeTestHelpers(debt.boundaries);
+    const testOwnership = this.assignTestOwnership(debt.boundaries);
+    const routes = assignRouteModules(autoResult.http_routes ?? [], debt.boundaries);
+    const asyncEdges = buildAsyncEdges(autoResult.message_endpoints ?? [], debt.boundaries);
+    const generatedCode = assignGeneratedModules(autoResult.generated_code ?? [], debt.boundaries);
+    const cycles = this.findCycles(debt.boundaries, autoResult.call_graph);
+    const globalCoupling = this.findGlobalCoupling(debt.boundaries);
+    const thirdParty = this.findThirdPartyDependencies(debt.boundaries);
+    const orchestrators = this.findOrchestrators(debt.boundaries, autoResult);
+    const domainMap = new DomainMapWriter(this.projectRoot).write({
       ...manualResult,
-      boundaries: hybridBoundaries,
+      boundaries: attachThirdPartyDependencies(this.attachFrontendConsumers(debt.boundaries, routes), thirdParty),
       metrics: {
         ...manualResult.metrics,
+        ...(autoResult.call_graph ? this.calculateBasicMetrics(hybridBoundaries, manualResult.total_files) : {}),
       },
-    };
-    
-    // 6. 結果保存
-    const outputPath = this.paths.domainMapPath;
-    fs.writeFileSync(outputPath, JSON.stringify(domainMap, null, 2));
+      ...(loadErrors.length > 0 ? { load_errors: loadErrors } : {}),
+      ...(sampling ? { sampling } : {}),
+      ...(testHelpers.length > 0 ? { test_helpers: testHelpers } : {}),
+      ...(testOwnership.length > 0 ? { test_ownership: testOwnership } : {}),
+      ...(kernel.packages.length > 0 ? { shared_kernel: kernel.packages } : {}),
+      ...(routes.length > 0 ? { routes } : {}),
+      ...(asyncEdges.length > 0 ? { async_edges: asyncEdges } : {}),
+      ...(generatedCode.length > 0 ? { generated_code: generatedCode } : {}),
+      ...(cycles.length > 0 ? { cycles } : {}),
+      ...(globalCoupling.length > 0 ? { global_coupling: globalCoupling } : {}),
+      ...(thirdParty.length > 0 ? { third_party: thirdParty } : {}),
+      ...(orchestrators.length > 0 ? { orchestrators } : {}),
+      ...(this.scope ? { scope: this.scope } : {}),
+    });
+    this.persistAnalysisGraph(domainMap, autoResult);
     
     console.log(`✅ ハイブリッド境界分析完了: ${hybridBoundaries.length}個の境界`);
     
@@ -102,6 +219,8 @@ export class EnhancedBoundaryAgent {
       autoDiscoveredBoundaries: autoResult.discovered_boundaries,
       discoveryMetrics: autoResult,
       hybridRecommendations,
+      constraintViolations: constrained.violations,
+      debtInventory: debt.inventory,
       outputPath,
     };
   }
@@ -110,33 +229,67 @@ export class EnhancedBoundaryAgent {
     // 1. AI自動境界発見
     const autoResult = await this.autoDiscovery.discoverBoundaries();
     
-    // 2. 自動発見された境界を従来形式に変換
-    const domainBoundaries = this.convertAutoToDomainBoundaries(autoResult.discovered_boundaries);
+    // 2. 自動発見された境界を従来形式に変換（前回の結果があれば変更ファイルだけを反映）
+    const discovered = this.convertAutoToDomainBoundaries(autoResult.discovered_boundaries);
+    const domainBoundaries = this.scoreByCalls(
+      autoResult.sample ? discovered : this.pathFilters.length > 0 ? this.mergeWithScopedDiscovery(discovered) : this.mergeWithPreviousDiscovery(discovered),
+      autoResult.call_graph
+    );
     
-    // 3. 基本的なコード分析も実行（メトリクス取得のため）
-    const files = await this.analyzer.analyzeFiles(['**/*.go'], ['**/*_test.go', '**/vendor/**']);
+    // 3. 基本的なコード分析も実行（メトリクス取得のため、サンプリング時は選択されたファイルのみ）
+    const files = autoResult.sample
+      ? await this.analyzer.analyzePaths(autoResult.sample.files)
+      : await this.analyzer.analyzeFiles(['**/*.go'], ['**/*_test.go', '**/vendor/**']);
     const dependencyGraph = this.analyzer.buildDependencyGraph(files);
     const metrics = this.calculateBasicMetrics(domainBoundaries, files.length);
+    const sampling = autoResult.sample && describeSample(autoResult.sample, this.sampling ?? {}, metrics);
     
     // 4. ドメインマップ作成
-    const domainMap: DomainMap = {
+    const loadErrors = autoResult.load_errors ?? [];
+    // 5. 結果保存（安定IDを付与し、決定的な順序で書き出す）
+    const named = await this.nameBoundaries(domainBoundaries);
+    const annotated = this.importEstablishedModules(this.applySourceAnnotations(this.applyCuratedBoundaries(named), autoResult.annotations));
+    const kernel = this.separateSharedKernel(annotated);
+    const debt = this.inventoryDebt(this.attributeTeamOwnership(this.classifyDomainModels(this.attachPackages(markDegradedFiles(kernel.boundaries, loadErrors)))));
+    const outputPath = this.paths.domainMapPath;
+    const testHelpers = this.analyzeTestHelpers(debt.boundaries);
+    const testOwnership = this.assignTestOwnership(debt.boundaries);
+    const routes = assignRouteModules(autoResult.http_routes ?? [], debt.boundaries);
+    const asyncEdges = buildAsyncEdges(autoResult.message_endpoints ?? [], debt.boundaries);
+    const generatedCode = assignGeneratedModules(autoResult.generated_code ?? [], debt.boundaries);
+    const cycles = this.findCycles(debt.boundaries, autoResult.call_graph);
+    const globalCoupling = this.findGlobalCoupling(debt.boundaries);
+    const thirdParty = this.findThirdPartyDependencies(debt.boundaries);
+    const orchestrators = this.findOrchestrators(debt.boundaries, autoResult);
+    const domainMap = new DomainMapWriter(this.projectRoot).write({
       project: 'auto-discovered-project',
       language: 'go',
       analyzed_at: new Date().toISOString(),
       total_files: files.length,
-      boundaries: domainBoundaries,
+      boundaries: attachThirdPartyDependencies(this.attachFrontendConsumers(debt.boundaries, routes), thirdParty),
       metrics: {
         ...metrics,
       },
-    };
-    
-    // 5. 結果保存
-    const outputPath = this.paths.domainMapPath;
-    fs.writeFileSync(outputPath, JSON.stringify(domainMap, null, 2));
+      ...(loadErrors.length > 0 ? { load_errors: loadErrors } : {}),
+      ...(sampling ? { sampling } : {}),
+      ...(testHelpers.length > 0 ? { test_helpers: testHelpers } : {}),
+      ...(testOwnership.length > 0 ? { test_ownership: testOwnership } : {}),
+      ...(kernel.packages.length > 0 ? { shared_kernel: kernel.packages } : {}),
+      ...(routes.length > 0 ? { routes } : {}),
+      ...(asyncEdges.length > 0 ? { async_edges: asyncEdges } : {}),
+      ...(generatedCode.length > 0 ? { generated_code: generatedCode } : {}),
+      ...(cycles.length > 0 ? { cycles } : {}),
+      ...(globalCoupling.length > 0 ? { global_coupling: globalCoupling } : {}),
+      ...(thirdParty.length > 0 ? { third_party: thirdParty } : {}),
+      ...(orchestrators.length > 0 ? { orchestrators } : {}),
+      ...(this.scope ? { scope: this.scope } : {}),
+    });
+    if (!autoResult.sample && (this.pathFilters.length === 0 || this.carriedState)) this.saveDiscoveryState(domainMap);
+    this.persistAnalysisGraph(domainMap, autoResult);
     
     // 6. 詳細レポート保存
     const detailedReportPath = this.paths.autoBoundaryReportPath;
-    fs.writeFileSync(detailedReportPath, JSON.stringify(autoResult, null, 2));
+    this.paths.writeArtifact(detailedReportPath, autoResult);
     
     // 7. .gitignore更新
     this.paths.updateGitignore();
@@ -149,10 +302,341 @@ export class EnhancedBoundaryAgent {
       autoDiscoveredBoundaries: autoResult.discovered_boundaries,
       discoveryMetrics: autoResult,
       hybridRecommendations: [],
+      constraintViolations: autoResult.constraint_violations ?? [],
+      debtInventory: debt.inventory,
       outputPath,
     };
   }
 
+  /**
+   * 前回の境界発見から変更・追加・削除されたファイルだけを新しいクラスタリングで配置し、
+   * 未変更ファイルの境界は domain-map.json のまま維持する
+   */
+  private mergeWithPreviousDiscovery(discovered: DomainBoundary[]): DomainBoundary[] {
+    if (!this.incremental) return discovered;
+    const previous = new DomainMapWriter(this.projectRoot).load();
+    const state = previous && !previous.sampling ? loadDiscoveryState(this.projectRoot, this.paths.domainMapPath) : null;
+    if (!previous || !state) return discovered;
+
+    try {
+      const changes = diffFiles(this.projectRoot, state.files, discovered.flatMap(boundary => boundary.files));
+      // Established modules are imported again afterwards
+      const merged = mergeIncrementalBoundaries(previous.boundaries.filter(b => b.status !== 'established'), discovered, changes);
+      console.log(`♻️  差分境界発見: 変更${changes.changed.length}・追加${changes.added.length}・削除${changes.removed.length}ファイル（未変更${changes.unchanged.length}ファイルは前回の境界を維持）`);
+      if (merged.dropped.length > 0) {
+        console.log(`   ファイルがなくなった境界を削除: ${merged.dropped.join(', ')}`);
+      }
+      return merged.boundaries;
+    } catch (error) {
+      console.warn(`⚠️  差分境界発見に失敗しました。全体を再クラスタリングします: ${getErrorMessage(error)}`);
+      return discovered;
+    }
+  }
+
+  /**
+   * --path / --package の範囲のファイルだけを新しいクラスタリングで前回の domain-map.json に反映し、
+   * 範囲外のファイルの境界はそのまま維持する
+   */
+  private mergeWithScopedDiscovery(discovered: DomainBoundary[]): DomainBoundary[] {
+    const previous = new DomainMapWriter(this.projectRoot).load();
+    if (!previous || previous.sampling) {
+      console.log(`⚠️  統合先の domain-map.json がないため、${describePathFilters(this.pathFilters)} の境界だけを書き出します`);
+      return discovered;
+    }
+
+    const inScope = (file: string) => matchesPathFilters(file, this.pathFilters);
+    const state = loadDiscoveryState(this.projectRoot, this.paths.domainMapPath);
+    if (state) {
+      this.carriedState = Object.fromEntries(Object.entries(state.files).filter(([file]) => !inScope(file)));
+    }
+    // Established modules are imported again afterwards
+    const merged = mergeScopedBoundaries(previous.boundaries.filter(b => b.status !== 'established'), discovered, inScope);
+    console.log(`🔎 範囲指定の境界発見 (${describePathFilters(this.pathFilters)}): ${merged.placed.length}ファイルを再配置（範囲外の境界は前回のまま維持）`);
+    if (merged.dropped.length > 0) {
+      console.log(`   ファイルがなくなった境界を削除: ${merged.dropped.join(', ')}`);
+    }
+    return merged.boundaries;
+  }
+
+  /**
+   * 次回の差分境界発見のためにファイルのハッシュを記録（失敗しても境界発見は続行）
+   */
+  private saveDiscoveryState(domainMap: DomainMap): void {
+    try {
+      saveDiscoveryState(this.projectRoot, this.paths.domainMapPath, [
+        ...domainMap.boundaries.flatMap(boundary => boundary.files),
+        ...(domainMap.shared_kernel ?? []).flatMap(pkg => pkg.files),
+      ], this.carriedState);
+    } catch (error) {
+      console.warn(`⚠️  差分境界発見の状態を保存できませんでした: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * ユーティリティ・ロギング・設定・エラーなどの共有カーネルを境界から切り離す（失敗しても境界発見は続行）
+   * boundary.yaml の files・seeds と //vf:boundary で割り当てたパッケージは対象外
+   */
+  private separateSharedKernel(boundaries: DomainBoundary[]): { boundaries: DomainBoundary[]; packages: SharedKernelPackage[] } {
+    try {
+      const modules = Object.values(this.boundaryConfig?.modules ?? {});
+      const packages = loadGoPackages(this.projectRoot);
+      const byImportPath = new Map(packages.map(pkg => [pkg.import_path, pkg.dir]));
+      const pinned = [
+        ...modules.flatMap(module => module.files ?? []),
+        ...modules.flatMap(module => module.seeds ?? []).map(seed => byImportPath.get(seed) ?? seed),
+        ...boundaries.flatMap(boundary => (boundary.annotations ?? []).filter(a => a.directive === 'boundary').map(a => a.file)),
+      ];
+      const kernel = findSharedKernel(this.projectRoot, boundaries, packages, { pinned });
+      if (kernel.length === 0) return { boundaries, packages: [] };
+
+      const result = separateSharedKernel(this.projectRoot, boundaries, kernel);
+      console.log(`🧰 共有カーネル: ${kernel.map(pkg => pkg.dir).join(', ')}（どの境界にも含めず、リファクタリングで移動しない）`);
+      if (result.dropped.length > 0) {
+        console.log(`   共有カーネルだけだった境界を削除: ${result.dropped.join(', ')}`);
+      }
+      return { boundaries: result.boundaries, packages: kernel };
+    } catch (error) {
+      console.warn(`⚠️  共有カーネルの検出に失敗しました: ${getErrorMessage(error)}`);
+      return { boundaries, packages: [] };
+    }
+  }
+
+  /**
+   * ファイルごとのパッケージ識別子（import path とパッケージ名）と、go.work のどの go.mod に属するかを記録
+   */
+  private attachPackages(boundaries: DomainBoundary[]): DomainBoundary[] {
+    const workspace = loadGoWorkspace(this.projectRoot);
+    const packages = loadGoPackages(this.projectRoot, undefined, workspace);
+    const attached = attachFileCoupling(this.projectRoot, attachFilePackages(this.projectRoot, boundaries, packages), packages);
+    return workspace ? attachGoModules(this.projectRoot, attached, workspace) : attached;
+  }
+
+  /**
+   * フロントエンド（boundary.yaml frontend）の fetch・axios 呼び出しを Go のルートに対応付け、
+   * 境界の外部コンシューマーとして記録（失敗しても境界発見は続行）
+   */
+  private attachFrontendConsumers(boundaries: DomainBoundary[], routes: HttpRoute[]): DomainBoundary[] {
+    const frontend = this.boundaryConfig?.frontend;
+    if (!frontend) return boundaries;
+    try {
+      const calls = findFrontendApiCalls(this.projectRoot, frontend.paths);
+      const { matched, unmatched } = matchApiCalls(calls, routes, { stripPrefix: frontend.stripPrefix });
+      const sample = unmatched.slice(0, 5).map(call => `${call.method} ${call.path}`).join(', ') + (unmatched.length > 5 ? ', ...' : '');
+      console.log(`🖥️  フロントエンドのAPI呼び出し: ${calls.length}件中${matched.length}件をルートに対応付け${unmatched.length > 0 ? `（未対応: ${sample}）` : ''}`);
+      return attachExternalConsumers(boundaries, matched);
+    } catch (error) {
+      console.warn(`⚠️  フロントエンドのAPI呼び出しの解析に失敗しました: ${getErrorMessage(error)}`);
+      return boundaries;
+    }
+  }
+
+  /**
+   * ビジネスロジックがエンティティのメソッド（リッチ）とサービス関数（ドメインモデル貧血症）のどちらにあるかを分類（失敗しても境界発見は続行）
+   */
+  private classifyDomainModels(boundaries: DomainBoundary[]): DomainBoundary[] {
+    try {
+      const classified = attachDomainModels(this.projectRoot, boundaries);
+      const styles = classified.filter(b => b.domain_model).map(b => `${b.name}: ${b.domain_model!.style}`);
+      if (styles.length > 0) console.log(`🧱 ドメインモデル: ${styles.join(', ')}`);
+      return classified;
+    } catch (error) {
+      console.warn(`⚠️  ドメインモデルの分類に失敗しました: ${getErrorMessage(error)}`);
+      return boundaries;
+    }
+  }
+
+  /**
+   * CODEOWNERS（なければ git blame の行数）から境界ごとの担当チームを推定（失敗しても境界発見は続行）
+   */
+  private attributeTeamOwnership(boundaries: DomainBoundary[]): DomainBoundary[] {
+    const config = this.boundaryConfig?.ownership;
+    if (config?.enabled === false) return boundaries;
+    try {
+      const owned = attributeTeamOwnership(this.projectRoot, boundaries, config);
+      const teams = owned.filter(b => b.ownership).map(b => `${b.name}: ${b.ownership!.team} (${b.ownership!.source} ${Math.round(b.ownership!.share * 100)}%)`);
+      if (teams.length > 0) console.log(`👥 担当チーム: ${teams.join(', ')}`);
+      return owned;
+    } catch (error) {
+      console.warn(`⚠️  担当チームの推定に失敗しました: ${getErrorMessage(error)}`);
+      return boundaries;
+    }
+  }
+
+  /**
+   * 技術的負債の棚卸し（失敗しても境界発見は続行）
+   */
+  private inventoryDebt(boundaries: DomainBoundary[]): { boundaries: DomainBoundary[]; inventory?: DebtInventory } {
+    try {
+      const scanner = new DebtInventoryScanner(this.projectRoot);
+      const inventory = scanner.scan(boundaries);
+      scanner.write(inventory);
+      return { boundaries: attachDebt(boundaries, inventory), inventory };
+    } catch (error) {
+      console.warn(`⚠️  技術的負債の棚卸しに失敗しました: ${getErrorMessage(error)}`);
+      return { boundaries };
+    }
+  }
+
+  /**
+   * module_3 のような汎用的な名前の境界を、識別子・コメントと用語集（boundary.yaml naming）から命名
+   * （naming.llm で LLM に提案させる、失敗しても境界発見は続行）
+   */
+  private async nameBoundaries(boundaries: DomainBoundary[]): Promise<DomainBoundary[]> {
+    const naming = this.boundaryConfig?.naming;
+    let integration: import('../utils/claude-code-integration.js').ClaudeCodeIntegration | undefined;
+    try {
+      const glossary = naming?.glossary ? loadGlossary(this.projectRoot, naming.glossary) : [];
+      const named = await nameBoundaries(boundaries, {
+        projectRoot: this.projectRoot,
+        glossary,
+        // Offline runs name boundaries from the glossary and identifiers only
+        namer: naming?.llm && !isOffline() ? async prompt => {
+          const { ClaudeCodeIntegration } = await import('../utils/claude-code-integration.js');
+          integration ??= new ClaudeCodeIntegration({ projectRoot: this.projectRoot });
+          return integration.proposeBoundaryName(prompt);
+        } : undefined,
+      });
+      const renamed = named.filter(boundary => boundary.naming);
+      if (renamed.length > 0) {
+        console.log(`🏷️  境界の命名: ${renamed.map(b => `${b.naming!.previous} → ${b.name} (${b.naming!.source})`).join(', ')}`);
+      }
+      return named;
+    } catch (error) {
+      console.warn(`⚠️  境界の命名に失敗しました。発見時の名前のまま続行します: ${getErrorMessage(error)}`);
+      return boundaries;
+    }
+  }
+
+  /**
+   * 共有テストヘルパーを使用する境界に帰属（失敗しても境界発見は続行）
+   */
+  private analyzeTestHelpers(boundaries: DomainBoundary[]): TestHelperUsage[] {
+    try {
+      const helpers = analyzeTestHelpers(this.projectRoot, boundaries);
+      const review = helpers.filter(helper => helper.placement === 'manual-review');
+      if (helpers.length > 0) {
+        console.log(`🧪 共有テストヘルパー: ${helpers.length}件${review.length > 0 ? `（要確認: ${review.map(h => h.id).join(', ')}）` : ''}`);
+      }
+      return helpers;
+    } catch (error) {
+      console.warn(`⚠️  テストヘルパーの解析に失敗しました: ${getErrorMessage(error)}`);
+      return [];
+    }
+  }
+
+  /**
+   * 既存の _test.go を、参照している宣言（import とパッケージ内の識別子）から境界に割り当てる（失敗しても境界発見は続行）
+   */
+  private assignTestOwnership(boundaries: DomainBoundary[]): TestOwnership[] {
+    try {
+      const ownership = assignTestOwnership(this.projectRoot, boundaries);
+      const fallback = ownership.filter(entry => entry.basis !== 'symbols');
+      if (ownership.length > 0) {
+        console.log(`🧪 既存テストの所属: ${ownership.length}件${fallback.length > 0 ? `（うち${fallback.length}件はファイル名・ディレクトリから推定）` : ''}`);
+      }
+      return ownership;
+    } catch (error) {
+      console.warn(`⚠️  既存テストの所属の解析に失敗しました: ${getErrorMessage(error)}`);
+      return [];
+    }
+  }
+
+  /**
+   * パッケージレベルの可変グローバル変数・init() の副作用による結合を検出（import 解析では見えず、分割すると壊れる）
+   */
+  private findGlobalCoupling(boundaries: DomainBoundary[]): GlobalCoupling[] {
+    try {
+      const couplings = assignGlobalCouplingModules(findGlobalCoupling(this.projectRoot), boundaries);
+      const exclusive = couplings.filter(coupling => coupling.exclusive);
+      if (couplings.length > 0) {
+        console.log(`🌐 グローバル変数・init() による結合: ${couplings.length}件${exclusive.length > 0 ? `（それだけで結合: ${exclusive.map(c => `${c.from} → ${c.to}`).join(', ')}）` : ''}`);
+      }
+      return couplings;
+    } catch (error) {
+      console.warn(`⚠️  グローバル変数・init() による結合の検出に失敗しました: ${getErrorMessage(error)}`);
+      return [];
+    }
+  }
+
+  /**
+   * 境界ごとのサードパーティ依存（AWS SDK・Stripe・Redis クライアントなど）を集計し、多くの境界が直接使う依存を検出（失敗しても境界発見は続行）
+   */
+  private findThirdPartyDependencies(boundaries: DomainBoundary[]): ThirdPartyDependency[] {
+    try {
+      const dependencies = findThirdPartyDependencies(this.projectRoot, boundaries, this.boundaryConfig?.thirdParty);
+      const shared = dependencies.filter(dependency => dependency.shared);
+      if (dependencies.length > 0) {
+        console.log(`📦 サードパーティ依存: ${dependencies.length}件${shared.length > 0 ? `（複数の境界で共有: ${shared.map(d => `${d.name} (${d.used_by.length})`).join(', ')}）` : ''}`);
+      }
+      return dependencies;
+    } catch (error) {
+      console.warn(`⚠️  サードパーティ依存の集計に失敗しました: ${getErrorMessage(error)}`);
+      return [];
+    }
+  }
+
+  /**
+   * 複数の境界を呼び出し・テーブルアクセスでまとめる関数（トランザクションスクリプト）をサーガ・オーケストレーター候補として検出
+   * （1つの境界に黙って割り当てない、失敗しても境界発見は続行）
+   */
+  private findOrchestrators(boundaries: DomainBoundary[], autoResult: BoundaryDiscoveryResult): OrchestratorCandidate[] {
+    try {
+      const candidates = findOrchestrators(autoResult.call_graph?.edges ?? [], autoResult.table_ownership?.access ?? [], boundaries);
+      if (candidates.length > 0) {
+        const sagas = candidates.filter(candidate => candidate.kind === 'saga');
+        console.log(`🎼 サーガ・オーケストレーター候補: ${candidates.length}件${sagas.length > 0 ? `（サーガ: ${sagas.map(c => c.function).join(', ')}）` : ''}`);
+      }
+      return candidates;
+    } catch (error) {
+      console.warn(`⚠️  サーガ・オーケストレーター候補の検出に失敗しました: ${getErrorMessage(error)}`);
+      return [];
+    }
+  }
+
+  /**
+   * 境界間の呼び出しの循環と切断候補を検出し、.vibeflow/boundary-cycles.json に書き出す（失敗しても境界発見は続行）
+   */
+  private findCycles(boundaries: DomainBoundary[], callGraph: CallGraph | undefined): BoundaryCycle[] {
+    if (!callGraph) return [];
+    try {
+      const cycles = findBoundaryCycles(callGraph.edges, boundaries);
+      this.paths.writeArtifact(this.paths.boundaryCyclesPath, { call_graph: callGraph.algorithm, cycles });
+      if (cycles.length > 0) {
+        console.log(`🔁 境界間の依存サイクル: ${cycles.length}件（切断候補: ${[...new Set(cycles.map(c => `${c.break_at.from} → ${c.break_at.to}`))].join(', ')}）`);
+      }
+      return cycles;
+    } catch (error) {
+      console.warn(`⚠️  依存サイクルの検出に失敗しました: ${getErrorMessage(error)}`);
+      return [];
+    }
+  }
+
+  /**
+   * ファイル・シンボル・呼び出し・型の関係・テーブルアクセスのグラフを .vibeflow/analysis.db に書き出す
+   * （sqlite3 がなければ analysis.sql、失敗しても境界発見は続行）
+   */
+  private persistAnalysisGraph(domainMap: DomainMap, autoResult: BoundaryDiscoveryResult): void {
+    try {
+      const graph = this.autoDiscovery.analysisGraph([
+        ...domainMap.boundaries,
+        ...(domainMap.shared_kernel ?? []).map(pkg => ({ name: 'shared-kernel', files: pkg.files })),
+      ]);
+      if (!graph) return;
+      const written = writeAnalysisDatabase(this.paths.analysisDbPath, graph, {
+        analyzed_at: domainMap.analyzed_at,
+        ...(autoResult.call_graph ? { call_graph: autoResult.call_graph.algorithm } : {}),
+        ...(autoResult.type_relations ? { type_relations: autoResult.type_relations.source } : {}),
+        ...(autoResult.sample ? { sampled: 'true' } : {}),
+      });
+      console.log(`🗃️  解析グラフ: ${graph.nodes.length}ノード・${graph.edges.length}エッジ → ${this.paths.getRelativePath(written)}`);
+      if (written !== this.paths.analysisDbPath) {
+        console.warn(`⚠️  sqlite3 が見つからないため SQL を書き出しました（sqlite3 .vibeflow/analysis.db < ${this.paths.getRelativePath(written)} で読み込めます）`);
+      }
+    } catch (error) {
+      console.warn(`⚠️  解析グラフを書き出せませんでした: ${getErrorMessage(error)}`);
+    }
+  }
+
   private async runManualBoundaryAnalysis(): Promise<DomainMap> {
     // 従来のBoundaryAgentのロジックを使用
     const files = await this.analyzer.analyzeFiles(
@@ -200,6 +684,7 @@ export class EnhancedBoundaryAgent {
           external: []
         },
         circular_dependencies: circularDeps,
+        tables: this.boundaryConfig?.modules[moduleName]?.owns_tables,
         cohesion_score: cohesionScore,
         coupling_score: couplingScore,
       };
@@ -288,6 +773,20 @@ export class EnhancedBoundaryAgent {
     return externalDependencies.length / moduleFiles.length;
   }
 
+  /**
+   * 凝集度・結合度をコールグラフの呼び出し（境界内・境界間）から算出（呼び出しのない境界は従来の値のまま）
+   */
+  private scoreByCalls(boundaries: DomainBoundary[], graph?: CallGraph): DomainBoundary[] {
+    if (!graph) return boundaries;
+    const scores = callCoupling(graph, boundaries);
+    return boundaries.map(boundary => {
+      const score = scores.get(boundary.name);
+      if (!score) return boundary;
+      const { scores_estimated: _, ...measured } = boundary;
+      return { ...measured, cohesion_score: score.cohesion, coupling_score: score.coupling };
+    });
+  }
+
   private calculateBasicMetrics(boundaries: DomainBoundary[], totalFiles: number) {
     const totalCohesion = boundaries.reduce((sum, b) => sum + (b.cohesion_score ?? 0), 0);
     const totalCoupling = boundaries.reduce((sum, b) => sum + (b.coupling_score ?? 0), 0);
@@ -303,6 +802,75 @@ export class EnhancedBoundaryAgent {
     };
   }
 
+  /**
+   * boundary.yaml の手動編集（vf boundaries edit）を適用: ID による名前の変更とファイルの固定
+   */
+  private applyCuratedBoundaries(boundaries: DomainBoundary[]): DomainBoundary[] {
+    const modules = this.boundaryConfig?.modules ?? {};
+    if (!Object.values(modules).some(module => module.id || module.files?.length)) return boundaries;
+
+    // 名前の変更は安定IDで指定されるため、書き出し時と同じく前回のドメインマップと照合してIDを付与
+    const previous = new DomainMapWriter(this.projectRoot).load();
+    const result = applyCuratedModules(this.projectRoot, assignBoundaryIds(boundaries, previous?.boundaries), modules);
+    if (result.renamed.length > 0) {
+      console.log(`✏️  boundary.yaml による名前の変更: ${result.renamed.join(', ')}`);
+    }
+    if (result.moved.length > 0) {
+      console.log(`✏️  boundary.yaml により${result.moved.length}ファイルの境界を固定: ${result.moved.join(', ')}`);
+    }
+    if (result.missing.length > 0) {
+      console.warn(`⚠️  boundary.yaml で固定されたファイルが見つかりません: ${result.missing.join(', ')}`);
+    }
+    return result.boundaries;
+  }
+
+  /**
+   * ソースコードの //vf: 注釈でクラスタリング結果を上書き（境界への固定、keep-together）
+   */
+  private applySourceAnnotations(boundaries: DomainBoundary[], annotations: SourceAnnotation[] = []): DomainBoundary[] {
+    const result = applyAnnotations(this.projectRoot, boundaries, annotations);
+    if (result.moved.length > 0) {
+      console.log(`📌 注釈により${result.moved.length}ファイルの境界を固定: ${result.moved.join(', ')}`);
+    }
+    result.unknown.forEach(a => {
+      console.warn(`⚠️  ${a.file}:${a.line} //vf:boundary ${a.argument}: 境界が見つかりません（vf validate で確認）`);
+    });
+    return result.boundaries;
+  }
+
+  /**
+   * 既存のモジュール構造（独自の go.mod、boundary.yaml の established）を固定境界として取り込む
+   */
+  private importEstablishedModules(boundaries: DomainBoundary[]): DomainBoundary[] {
+    try {
+      const established = findEstablishedModules(this.projectRoot, this.boundaryConfig?.established);
+      const result = importEstablishedBoundaries(this.projectRoot, boundaries, established, this.reconsiderEstablished);
+      if (result.imported.length > 0) {
+        console.log(`🏛️  確立済みモジュールを固定境界として取り込み: ${result.imported.join(', ')}（再クラスタリング・リファクタリングの対象外）`);
+      }
+      if (result.reconsidered.length > 0) {
+        console.log(`🔁 再分析する確立済みモジュール: ${result.reconsidered.join(', ')}`);
+      }
+      result.unknown.forEach(name => console.warn(`⚠️  --reconsider-established のモジュールが見つかりません: ${name}`));
+      return result.boundaries;
+    } catch (error) {
+      console.warn(`⚠️  既存のモジュール構造の検出に失敗しました: ${getErrorMessage(error)}`);
+      return boundaries;
+    }
+  }
+
+  /**
+   * vibeflow.config.yaml の repository.schema（なければ schema.sql・マイグレーションを自動検出）
+   */
+  private loadSchemaPaths(): string[] | undefined {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return config.repository?.schema;
+    } catch {
+      return undefined;
+    }
+  }
+
   private convertAutoToDomainBoundaries(autoBoundaries: AutoDiscoveredBoundary[]): DomainBoundary[] {
     return autoBoundaries.map(auto => ({
       name: auto.name,
@@ -313,8 +881,12 @@ export class EnhancedBoundaryAgent {
         external: []
       },
       circular_dependencies: [], // Would need additional analysis
+      tables: auto.database_tables,
+      merged_from: auto.merged_from,
+      ...(auto.confidence_breakdown ? { confidence_breakdown: auto.confidence_breakdown } : {}),
       cohesion_score: auto.confidence, // Use confidence as proxy for cohesion
       coupling_score: Math.max(0, 1 - auto.confidence), // Inverse of confidence
+      scores_estimated: true as const,
     }));
   }
 
@@ -333,8 +905,7 @@ export class EnhancedBoundaryAgent {
       );
       
       if (!hasOverlap) {
-        const domainBoundary = this.convertAutoToDomainBoundary(autoBoundary);
-        merged.push(domainBoundary);
+        merged.push(this.convertAutoToDomainBoundary(autoBoundary));
       }
     }
     
@@ -342,12 +913,7 @@ export class EnhancedBoundaryAgent {
   }
 
   private calculateFileOverlap(files1: string[], files2: string[]): number {
-    const set1 = new Set(files1);
-    const set2 = new Set(files2);
-    const intersection = new Set([...set1].filter(x => set2.has(x)));
-    const union = new Set([...set1, ...set2]);
-    
-    return union.size > 0 ? intersection.size / union.size : 0;
+    return fileOverlap(files1, files2);
   }
 
   private convertAutoToDomainBoundary(auto: AutoDiscoveredBoundary): DomainBoundary {
@@ -360,8 +926,12 @@ export class EnhancedBoundaryAgent {
         external: []
       },
       circular_dependencies: [],
+      tables: auto.database_tables,
+      merged_from: auto.merged_from,
+      ...(auto.confidence_breakdown ? { confidence_breakdown: auto.confidence_breakdown } : {}),
       cohesion_score: auto.confidence,
       coupling_score: Math.max(0, 1 - auto.confidence),
+      scores_estimated: true,
     };
   }
 
@@ -420,4 +990,64 @@ export class EnhancedBoundaryAgent {
     
     return recommendations;
   }
-}
\ No newline at end of file
+}
+
+/**
+ * Mark files of packages that failed to load with `analysis: degraded` and the package's first error
+ */
+export function markDegradedFiles(boundaries: DomainBoundary[], loadErrors: PackageLoadError[]): DomainBoundary[] {
+  if (loadErrors.length === 0) return boundaries;
+
+  const errorsByFile = new Map(loadErrors.flatMap(e => e.files.map(file => [file, e.errors[0]] as const)));
+  return boundaries.map(boundary => {
+    const degraded = boundary.files
+      .filter(file => errorsByFile.has(file))
+      .map(file => ({ file, analysis: 'degraded' as const, error: errorsByFile.get(file) }));
+    return degraded.length > 0 ? { ...boundary, degraded_files: degraded } : boundary;
+  });
+}
+
+/**
+ * Record the import path and declared package name of every boundary file
+ */
+export function attachFilePackages(projectRoot: string, boundaries: DomainBoundary[], packages: GoPackage[]): DomainBoundary[] {
+  if (packages.length === 0) return boundaries;
+
+  return boundaries.map(boundary => {
+    const identities = filePackages(projectRoot, packages, boundary.files);
+    return identities.length > 0 ? { ...boundary, file_packages: identities } : boundary;
+  });
+}
+
+/**
+ * Count each file's qualified references to the packages of other boundaries,
+ * so that `vf query files --min-coupling` reads them from the domain map
+ */
+export function attachFileCoupling(projectRoot: string, boundaries: DomainBoundary[], packages: GoPackage[]): DomainBoundary[] {
+  const owners = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const identity of boundary.file_packages ?? []) owners.set(identity.import_path, boundary.name);
+  }
+  if (owners.size === 0) return boundaries;
+
+  return boundaries.map(boundary => {
+    const coupling: { file: string; boundary: string; references: number }[] = [];
+    for (const file of boundary.files) {
+      let content: string;
+      try {
+        content = fs.readFileSync(path.resolve(projectRoot, file), 'utf8');
+      } catch {
+        continue;
+      }
+      const references = new Map<string, number>();
+      for (const [name, importPath] of goImportNames(content, packages)) {
+        const owner = owners.get(importPath);
+        if (owner === undefined || owner === boundary.name) continue;
+        const count = content.match(new RegExp(`(?<![\\w.])${name}\\.[A-Za-z_]`, 'g'))?.length ?? 0;
+        if (count > 0) references.set(owner, (references.get(owner) ?? 0) + count);
+      }
+      coupling.push(...[...references].map(([owner, count]) => ({ file, boundary: owner, references: count })));
+    }
+    return coupling.length > 0 ? { ...boundary, file_coupling: coupling } : boundary;
+  });
+}
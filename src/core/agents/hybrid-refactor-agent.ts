@@ -1,8 +1,11 @@
-import { RefactorAgent } from './refactor-agent.js';
+import { RefactorAgent, RefactorExecutionOptions, ModuleOutput, degradedModuleRefusal } from './refactor-agent.js';
 import { ClaudeCodeIntegration } from '../utils/claude-code-integration.js';
 import { DomainBoundary } from '../types/config.js';
 import { RefactoredFile, RefactorResult } from '../types/refactor.js';
 import { getErrorMessage } from '../utils/error-utils.js';
+import { FileSafetyManager } from '../utils/file-safety.js';
+import { loadSharedState, sharedStateRefusal } from '../utils/shared-state.js';
+import { autonomyTierRefusal } from '../utils/module-risk.js';
 import * as fs from 'fs/promises';
 
 /**
@@ -36,10 +39,17 @@ export class HybridRefactorAgent extends RefactorAgent {
   /**
    * Override the base refactoring method to add AI enhancement
    */
-  async executeRefactoring(boundaries: DomainBoundary[], applyChanges: boolean): Promise<RefactorResult> {
+  async executeRefactoring(
+    boundaries: DomainBoundary[],
+    applyChanges: boolean,
+    options: RefactorExecutionOptions = {}
+  ): Promise<RefactorResult> {
     console.log('🔧 Hybrid refactoring starting...');
     console.log(`Mode: ${this.useAI ? 'Template + AI' : 'Template Only'}`);
-    
+    this.assertPlanNotExploratory();
+
+    // Originals overwritten by module outputs are backed up so --clean-module can restore them
+    const safetyManager = applyChanges ? new FileSafetyManager(this.projectRoot) : null;
     const results: RefactorResult = {
       applied_patches: [],
       failed_patches: [],
@@ -50,10 +60,26 @@ export class HybridRefactorAgent extends RefactorAgent {
       aiEnhanced: this.useAI,
       tokenUsage: undefined
     };
+    const sharedState = applyChanges ? loadSharedState(this.projectRoot) : [];
+    const autonomy = this.loadAutonomyGate(applyChanges, options);
 
     for (const boundary of boundaries) {
       console.log(`\n📁 Processing boundary: ${boundary.name}`);
+
+      const refusal = degradedModuleRefusal(boundary, options.allowDegraded ?? false)
+        ?? sharedStateRefusal(boundary.name, sharedState)
+        ?? (autonomy ? autonomyTierRefusal(boundary.name, autonomy.risks.get(boundary.name), autonomy.maxTier, options.force ?? false) : null);
+      if (refusal) {
+        console.error(`  ❌ ${refusal}`);
+        results.failed_patches.push(...boundary.files.map(file => ({ file, error: refusal })));
+        continue;
+      }
+
+      if (applyChanges && options.cleanModule) {
+        results.deleted_files.push(...await this.cleanModuleOutputs(boundary.name));
+      }
       
+      const moduleOutputs: ModuleOutput[] = [];
       for (const file of boundary.files) {
         try {
           console.log(`  📄 Transforming: ${file}`);
@@ -69,9 +95,7 @@ export class HybridRefactorAgent extends RefactorAgent {
           
           // Step 3: Apply changes if requested
           if (applyChanges) {
-            await this.applyRefactoredFiles(finalResult);
-            results.applied_patches.push(file);
-            results.created_files.push(...finalResult.refactored_files.map(f => f.path));
+            moduleOutputs.push({ source: file, result: finalResult });
           }
           
           console.log(`    ✅ Success: ${finalResult.refactored_files.length} files generated`);
@@ -82,6 +106,20 @@ export class HybridRefactorAgent extends RefactorAgent {
           results.failed_patches.push({ file, error: errorMessage });
         }
       }
+
+      // Step 3: Apply changes if requested (idempotent across re-runs)
+      if (applyChanges && moduleOutputs.length > 0) {
+        try {
+          const applied = await this.applyModuleOutputs(boundary.name, moduleOutputs, safetyManager || undefined);
+          results.applied_patches.push(...moduleOutputs.map(o => o.source));
+          results.created_files.push(...applied.written);
+          results.deleted_files.push(...applied.deleted);
+        } catch (error) {
+          const errorMessage = getErrorMessage(error);
+          console.error(`    ❌ Failed to apply ${boundary.name} outputs: ${errorMessage}`);
+          results.failed_patches.push(...moduleOutputs.map(o => ({ file: o.source, error: errorMessage })));
+        }
+      }
     }
 
     // Add usage report if AI was used
@@ -102,6 +140,13 @@ export class HybridRefactorAgent extends RefactorAgent {
       }
     }
 
+    if (safetyManager && applyChanges) {
+      const backupInfo = safetyManager.getBackupSummary();
+      console.log(`📦 Safety Backup:`);
+      console.log(`   Backed up ${backupInfo.count} files`);
+      console.log(`   Location: ${backupInfo.location}`);
+    }
+
     return results;
   }
 
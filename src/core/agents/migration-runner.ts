@@ -8,6 +8,8 @@ import { ConfigLoader } from '../utils/config-loader.js';
 import { VibeFlowPaths } from '../utils/file-paths.js';
 import { BuildFixerAgent, BuildError } from './build-fixer-agent.js';
 import { detectGoProject, withGoWorkingDirectory } from '../utils/go-project-utils.js';
+import { parseCoverageProfile, readTextInput } from '../utils/input-parsers.js';
+import { getErrorMessage } from '../utils/error-utils.js';
 
 const execAsync = promisify(exec);
 
@@ -649,22 +651,12 @@ export class MigrationRunner {
     }
 
     try {
-      const { stdout } = await execAsync('go tool cover -func=coverage.out', {
-        cwd: workingDir,
-      });
-      
-      const totalLine = stdout.split('\n').find(line => line.includes('total:'));
-      if (totalLine) {
-        const match = totalLine.match(/(\d+\.\d+)%/);
-        if (match) {
-          return parseFloat(match[1]);
-        }
-      }
-    } catch {
-      // Coverage parsing failed, not critical
+      return parseCoverageProfile(readTextInput('coverage-profile', coverageFile), coverageFile).percentage ?? undefined;
+    } catch (error) {
+      // A truncated or corrupt profile only costs the coverage figure
+      console.warn(`⚠️  ${getErrorMessage(error)}`);
+      return undefined;
     }
-    
-    return undefined;
   }
 
   private async promptForPatchApplication(patch: RefactorPatch, patchId: number): Promise<boolean> {
@@ -3,10 +3,80 @@ import * as fsSync from 'fs';
 import * as path from 'path';
 import { VibeFlowPaths } from '../utils/file-paths.js';
 import { ClaudeCodeClient } from '../utils/claude-code-client.js';
-import { RefactoredFile, RefactorResult } from '../types/refactor.js';
+import { RefactoredFile, RefactorResult, MethodNameMapping, ContextTodo, GenerationInfo, GenerationMode } from '../types/refactor.js';
 import { DomainBoundary } from '../types/config.js';
-import { RefactorError, getErrorMessage } from '../utils/error-utils.js';
+import { FailureCategory, InputParseError, RefactorError, VerificationError, failureCategory, getErrorMessage } from '../utils/error-utils.js';
 import { FileSafetyManager } from '../utils/file-safety.js';
+import { LineEndingMode, writeTextFile } from '../utils/file-io.js';
+import { ConfigLoader } from '../utils/config-loader.js';
+import { PromptConfig, RepositoryConfig, LlmConfig, RefactorConfig, FilesConfig, AutonomyConfig } from '../types/config.js';
+import { ContextSelector, SelectedContext, renderContext, estimateTokens, splitSourceIntoChunks } from '../utils/context-selector.js';
+import { LlmTimeoutError, LlmUnavailableError, ModuleSkippedError, ModuleSkipController } from '../utils/llm-call-guard.js';
+import { EscalationRecord, FileProcessingRecord, LlmCallOutcome, ModuleStatusRecord, PerformanceStore, ProcessingMethod } from '../utils/performance-store.js';
+import { byteSize, describeTransformations, formatMethodTable, refactoredOutputs, summarizeProcessing } from '../utils/file-processing.js';
+import { AgentStage, ModuleStatusTracker } from '../utils/module-status.js';
+import { extractSqlQueries, ExtractedQuery, NonExtractableQuery } from '../utils/sql-extractor.js';
+import { SqlcGenerator, detectSchemaPaths } from '../utils/sqlc-generator.js';
+import { ModuleManifestStore, PendingOutput, stripDeclarations, hashContent } from '../utils/module-manifest.js';
+import { ConflictStore, FileConflict, hasConflictMarkers, mergeWithMarkers } from '../utils/merge-conflicts.js';
+import { contextMethodNames, legacyContextFunctions, renderContextSection, threadContext } from '../utils/context-threading.js';
+import { SharedStateFinding, loadSharedState, sharedStateRefusal } from '../utils/shared-state.js';
+import { establishedModuleRefusal } from '../utils/established-modules.js';
+import {
+  ConfigAccessInventory,
+  ConfigAccessSite,
+  ModuleConfig,
+  loadConfigPlan,
+  remainingConfigReads,
+  renderConfigPromptSection,
+  renderModuleConfigFile,
+  rewriteEnvReads,
+} from '../utils/config-access.js';
+import { ModulePorts, loadModulePorts, renderPortsPromptSection } from '../utils/module-ports.js';
+import { DomainEventPlan, loadDomainEvents, renderEventBusFile, renderEventsFile, renderEventsPromptSection } from '../utils/domain-events.js';
+import { GoModuleLayoutPlan, addRootDirectives, loadGoModulePlan, renderGoModFile, renderGoWorkFile } from '../utils/go-module-layout.js';
+import { parseGoWork } from '../utils/go-workspace.js';
+import { AutonomyTier, ModuleRisk, autonomyTierRefusal, loadModuleRisks } from '../utils/module-risk.js';
+import { ModuleLayout, MODULE_LAYOUTS, loadArchitectureStyle, planTargetFiles, renderArchitectureSection } from '../utils/architecture-style.js';
+import { ArchitecturalPlan, PLAN_SCHEMA_VERSION } from './architect-agent.js';
+import { DataMappingReporter } from '../utils/data-mapping-report.js';
+import { ApiSurfaceReporter } from '../utils/api-surface.js';
+import { checkGoSource, parseDomainMap, parsePlan } from '../utils/input-parsers.js';
+import { planStructureIssues } from '../utils/plan-validation.js';
+import { DebtInventoryScanner } from '../utils/debt-inventory.js';
+import { FindingsReporter } from '../utils/findings.js';
+import { checkGoSyntax, goPackageName } from '../utils/go-load-check.js';
+import { goPackageImportPath } from '../utils/go-project-utils.js';
+import { CostManager } from '../utils/cost-manager.js';
+import { exploratoryPlanReason } from '../utils/discovery-sampling.js';
+import { parseAnnotations, preserveAnnotations, renderAnnotationSection } from '../utils/source-annotations.js';
+import { FailedResponseStore } from '../utils/bug-report.js';
+import {
+  HandlerRoute,
+  HttpConventions,
+  ModuleRoutes,
+  extractHandlerRoutes,
+  loadHttpConventions,
+  renderHttpConventionSection,
+  renderRoutesFile,
+  routeMountCall,
+  verifyRouteMount,
+} from '../utils/http-conventions.js';
+import { FunctionValueUse, ValueCompatibilityCheck, checkValueCompatibility, declaredFunctions, formatIncompatibleValues, renderFunctionValueSection, scanFunctionValueUses } from '../utils/function-values.js';
+import { ESCALATION_CATEGORIES, PreviousAttempt, estimateModelCost, nextModel, renderPreviousAttemptSection } from '../utils/model-escalation.js';
+import { LlmCache } from '../utils/llm-cache.js';
+import { BatchItemError, BatchPendingError, LlmBatchSession } from '../utils/llm-batch.js';
+import { renderDomainModelSection } from '../utils/domain-model-style.js';
+import { PromptTemplate, REFACTOR_TASK, loadRefactorTemplate, renderPromptTemplate } from '../utils/prompt-metrics.js';
+import {
+  MethodNameStore,
+  buildMethodNameMapping,
+  extractLegacyFunctions,
+  reconcileMethodNames,
+  renameMethods,
+  renderMethodNamingSection,
+  formatMethodNameTable,
+} from '../utils/method-naming.js';
 
 export interface RefactorPlan {
   summary: {
@@ -33,6 +103,71 @@ export interface PatchChange {
   description: string;
 }
 
+export interface RefactorExecutionOptions {
+  /** Remove all previously generated outputs of each module before regenerating */
+  cleanModule?: boolean;
+  /** Process modules containing files whose package failed to load (analysis: degraded) */
+  allowDegraded?: boolean;
+  /** Asked before overwriting a module a reviewer accepted; without it accepted modules are refused */
+  confirmReopen?: (moduleName: string, acceptedBy?: string) => Promise<boolean>;
+  /** Retry malformed or unverifiable files with the next model of llm.escalation (default: true, --no-escalation) */
+  escalation?: boolean;
+  /** Answer prompts from async batch results and queue the others; their modules are left pending (--async-batch) */
+  batch?: LlmBatchSession;
+  /** Highest risk tier of plan.json applied without force (default: autonomy.maxAutoApplyTier; unset: no gate) */
+  maxAutoApplyTier?: AutonomyTier;
+  /** Apply modules above maxAutoApplyTier anyway (--force) */
+  force?: boolean;
+}
+
+/**
+ * Model for one generation attempt, with the failure of the previous one when escalating
+ */
+export interface ModelAttempt {
+  model?: string;
+  previous?: PreviousAttempt;
+}
+
+/**
+ * What a file_processing record says about the file beyond its run and module
+ */
+export type FileProcessingDetails = Omit<FileProcessingRecord, 'run_id' | 'file' | 'module' | 'module_name' | 'agent' | 'recorded_at'>;
+
+/**
+ * What one generateRefactoredCode attempt sent and received, over all its chunks
+ */
+interface AttemptUsage {
+  input_tokens: number;
+  output_tokens: number;
+  method?: ProcessingMethod;
+  cached: boolean;
+  /** Batch job that answered the attempt, and its price reduction; replayed when an earlier collect already recorded it */
+  batch?: { job_id: string; discount: number; replayed: boolean };
+  /** Responses to cache once the attempt passed verification */
+  responses: { prompt: string; model?: string; response: string }[];
+}
+
+/**
+ * Module risks of plan.json and the highest tier applied without --force
+ */
+export interface AutonomyGate {
+  maxTier: AutonomyTier;
+  risks: Map<string, ModuleRisk>;
+}
+
+interface BoundaryRunContext {
+  results: RefactorResult;
+  sharedState: SharedStateFinding[];
+  autonomy: AutonomyGate | null;
+  safetyManager: FileSafetyManager | null;
+  repositoryConfig: RepositoryConfig;
+}
+
+export interface ModuleOutput {
+  source: string;
+  result: RefactoredFile;
+}
+
 export interface RefactorAgentResult {
   plan: RefactorPlan;
   outputPath: string;
@@ -42,98 +177,1010 @@ export interface RefactorAgentResult {
  * RefactorAgent - Soul Implementation
  * Actually uses Claude Code SDK for real code transformation
  */
+/**
+ * How many times a timed-out call is split in half before the file fails
+ */
+const MAX_CHUNK_SPLIT_DEPTH = 2;
+
 export class RefactorAgent {
   private paths: VibeFlowPaths;
   private claudeClient: ClaudeCodeClient;
+  private generationMode: GenerationMode;
+  /** llm.escalation: the first model is used for every file, the next ones for retries */
+  private escalationChain: string[];
   protected projectRoot: string;
+  /** Skips the module being processed ("s" in TTY or `vf control skip-module`) */
+  readonly skipController = new ModuleSkipController();
+  /** Value uses of each Go file's functions, shared by the prompt and verification */
+  private valueUses = new Map<string, FunctionValueUse[]>();
+  /** Raw text of the last model response, saved when it fails to parse or verify */
+  private lastResponse?: string;
+  /** Router framework and response helpers of the project, detected on first use */
+  private conventions?: HttpConventions;
+  /** prompt.refactorTemplate or the built-in prompt, loaded on first use */
+  private template?: PromptTemplate;
+  /** Config inventory and per-module configs of plan.json, loaded on first use */
+  private configPlan?: { inventory: ConfigAccessInventory | null; modules: Map<string, ModuleConfig> };
+  /** Per-module ports of plan.json, loaded on first use */
+  private modulePorts?: Map<string, ModulePorts>;
+  /** Domain events of plan.json (null without any), loaded on first use */
+  private domainEvents?: DomainEventPlan | null;
+  /** Go module layout of plan.json (null without one), loaded on first use */
+  private goModulePlan?: GoModuleLayoutPlan | null;
+  /** Set with llm.cache */
+  private llmCache?: LlmCache;
+  /** Async batch of the run in progress (--async-batch / --collect) */
+  private batch?: LlmBatchSession;
+  /** Packages of the target architecture (plan.json architecture_style) */
+  private layout: ModuleLayout;
+  /** Usage of the attempt in progress, recorded as one LLM call */
+  private attemptUsage: AttemptUsage = { input_tokens: 0, output_tokens: 0, cached: false, responses: [] };
+  /** Agent of the file_processing records */
+  protected readonly agentName: string = 'RefactorAgent';
+  /** Files processed by the run in progress, for the per-method summary */
+  private processed: FileProcessingDetails[] = [];
 
-  constructor(projectRoot: string) {
+  /**
+   * @param generationMode 'template' (--offline) or 'llm' (--require-llm, method evaluation) pins the generation method
+   */
+  constructor(projectRoot: string, generationMode: GenerationMode = 'auto') {
     this.projectRoot = projectRoot;
+    this.generationMode = generationMode;
     this.paths = new VibeFlowPaths(projectRoot);
+    const llmConfig = this.loadLlmConfig();
+    this.escalationChain = llmConfig.escalation ?? [];
+    if (llmConfig.cache) this.llmCache = new LlmCache(projectRoot);
+    this.layout = MODULE_LAYOUTS[loadArchitectureStyle(projectRoot)];
     this.claudeClient = new ClaudeCodeClient({
       cwd: projectRoot,
       maxTurns: 5,
-      systemPrompt: 'You are the world\'s best refactoring engineer. Transform legacy code into clean, maintainable architecture.'
+      generationMode,
+      architectureStyle: this.layout.style,
+      systemPrompt: 'You are the world\'s best refactoring engineer. Transform legacy code into clean, maintainable architecture.',
+      requestTimeoutMs: llmConfig.requestTimeout !== undefined ? llmConfig.requestTimeout * 1000 : undefined,
+      idleTimeoutMs: llmConfig.idleTimeout !== undefined ? llmConfig.idleTimeout * 1000 : undefined,
     });
   }
 
   /**
    * Generate actual refactored code using Claude Code SDK
    * Not template generation, actual code transformation
+   *
+   * A call that times out is retried on smaller chunks of the file.
+   * Usecase methods are named after the legacy functions (see method-naming.ts).
+   * With refactor.addContext set, ctx parameters are made consistent (see context-threading.ts).
+   * Functions used as values keep their signature or get an adapter (see function-values.ts).
+   * Handlers follow the project's router conventions (see http-conventions.ts).
+   * LLM output is verified before it is returned (VerificationError); responses that
+   * fail to parse or verify are kept for `vf bugreport`. Each attempt is recorded in
+   * llm_calls with the hash of the prompt template (`vf metrics prompts`).
    */
-  async generateRefactoredCode(file: string, boundary: DomainBoundary): Promise<RefactoredFile> {
+  async generateRefactoredCode(file: string, boundary: DomainBoundary, signal?: AbortSignal, attempt: ModelAttempt = {}): Promise<RefactoredFile> {
+    this.lastResponse = undefined;
+    this.attemptUsage = { input_tokens: 0, output_tokens: 0, cached: false, responses: [] };
+    try {
+      const result = await this.transformFile(file, boundary, signal, attempt);
+      this.recordLlmCall(file, boundary, attempt, 'success');
+      this.cacheResponses();
+      return result;
+    } catch (error) {
+      const category = failureCategory(error);
+      if (ESCALATION_CATEGORIES.includes(category)) this.saveFailedResponse(file, error, attempt.model);
+      if (!(error instanceof ModuleSkippedError) && !(error instanceof BatchPendingError)) {
+        this.recordLlmCall(file, boundary, attempt, llmCallOutcome(category));
+      }
+      throw error;
+    }
+  }
+
+  private async transformFile(file: string, boundary: DomainBoundary, signal: AbortSignal | undefined, attempt: ModelAttempt): Promise<RefactoredFile> {
     console.log(`🤖 Transforming ${file} for ${boundary.name} module...`);
     
     const originalCode = await fs.readFile(file, 'utf8');
-    
-    const prompt = `
-Transform this ${this.detectLanguage(file)} code to Domain-Driven Design architecture suitable for the "${boundary.name}" bounded context:
-
-## Current Situation
-- File: ${file}
-- Target bounded context: ${boundary.name}
-- Business capability: ${boundary.description}
-- Ubiquitous language terms: ${boundary.ubiquitousLanguage?.join(', ') || 'Not specified'}
-- Context dependencies: ${boundary.dependencies?.internal?.join(', ') || 'None'}
-
-## Required Transformations
-1. **Preserve Business Language**: Use exact business terminology from the bounded context
-2. **Domain Layer Separation**: Extract pure business logic that captures domain rules and invariants
-3. **Application Services**: Create use cases that orchestrate domain operations
-4. **Infrastructure Independence**: Separate infrastructure concerns from business logic
-5. **Aggregate Boundaries**: Respect business transaction and consistency boundaries
-6. **Value Objects**: Model business concepts that don't have identity but have business rules
-
-IMPORTANT: Maintain the business meaning and terminology identified in this bounded context. Do not introduce technical abstractions that obscure business concepts.
-
-## Output Format
-Return in JSON format:
-{
-  "refactored_files": [
-    {
-      "path": "internal/${boundary.name}/domain/${boundary.name}.go",
-      "content": "package domain\\n\\n// Domain logic...",
-      "description": "${boundary.name} domain entity"
-    },
-    {
-      "path": "internal/${boundary.name}/usecase/${boundary.name}_service.go", 
-      "content": "package usecase\\n\\n// Use case...",
-      "description": "${boundary.name} service use case"
-    }
-  ],
-  "interfaces": [
-    {
-      "name": "${boundary.name}Repository",
-      "path": "internal/${boundary.name}/domain/repository.go",
-      "content": "type ${boundary.name}Repository interface { ... }"
-    }
-  ],
-  "tests": [
-    {
-      "path": "internal/${boundary.name}/domain/${boundary.name}_test.go",
-      "content": "package domain\\n\\nfunc Test${boundary.name}..."
-    }
-  ]
-}
+    if (file.endsWith('.go')) checkGoSource(originalCode, file);
+    const methodNames = this.planMethodNames(file, boundary, originalCode);
+    let result: RefactoredFile;
+    try {
+      result = await this.transformSource(file, boundary, originalCode, signal, methodNames, attempt);
+    } catch (error) {
+      if (!(error instanceof LlmTimeoutError)) throw error;
+      console.warn(`    ⏱️  ${error.message}; retrying ${file} in smaller chunks`);
+      result = await this.transformInChunks(file, boundary, originalCode, signal, 1, error, methodNames, attempt);
+    }
+
+    const threaded = this.applyContextThreading(file, originalCode, this.applyMethodNames(boundary, result, methodNames));
+    const generated = this.applyValueCompatibility(file, originalCode, this.applySourceAnnotations(file, originalCode, threaded));
+    const method = generated.generation?.method ?? (this.generationMode === 'template' ? 'template' : 'llm');
+    if (method === 'llm') verifyGeneratedOutput(generated);
+    return generated;
+  }
+
+  private async transformInChunks(
+    file: string,
+    boundary: DomainBoundary,
+    code: string,
+    signal: AbortSignal | undefined,
+    depth: number,
+    cause: LlmTimeoutError,
+    methodNames: MethodNameMapping[] = [],
+    attempt: ModelAttempt = {}
+  ): Promise<RefactoredFile> {
+    const chunks = splitSourceIntoChunks(code, file, 2);
+    if (chunks.length < 2) throw cause;
+
+    const results: RefactoredFile[] = [];
+    for (const [index, chunk] of chunks.entries()) {
+      console.log(`    🧩 Chunk ${index + 1}/${chunks.length} (depth ${depth})`);
+      try {
+        results.push(await this.transformSource(file, boundary, chunk, signal, methodNames, attempt));
+      } catch (error) {
+        if (!(error instanceof LlmTimeoutError) || depth >= MAX_CHUNK_SPLIT_DEPTH) throw error;
+        results.push(await this.transformInChunks(file, boundary, chunk, signal, depth + 1, error, methodNames, attempt));
+      }
+    }
+
+    return mergeRefactoredFiles(results);
+  }
 
-Original code:
-\`\`\`${this.detectLanguage(file)}
-${originalCode}
-\`\`\`
-    `;
+  private async transformSource(
+    file: string,
+    boundary: DomainBoundary,
+    originalCode: string,
+    signal?: AbortSignal,
+    methodNames: MethodNameMapping[] = [],
+    attempt: ModelAttempt = {}
+  ): Promise<RefactoredFile> {
+    const context = this.selectPromptContext(file, boundary);
+    const contextSection = context ? renderContext(context) : '';
+    const repositorySection = this.buildRepositoryInstructions(file, originalCode);
+    if (context) {
+      console.log(`    📏 Context: ${context.tokens}/${context.budgetTokens} tokens (${context.items.length} items, ${context.dropped.length} dropped)`);
+    }
     
-    const result = await this.claudeClient.queryForResult(prompt);
-    return this.claudeClient.extractJsonFromResult(result);
+    const template = this.refactorTemplate;
+    const variables: Record<string, string> = {
+      language: this.detectLanguage(file),
+      file,
+      boundary: boundary.name,
+      description: boundary.description,
+      ubiquitous_language: boundary.ubiquitousLanguage?.join(', ') || 'Not specified',
+      dependencies: boundary.dependencies?.internal?.join(', ') || 'None',
+      previous_attempt: attempt.previous ? `\n${renderPreviousAttemptSection(attempt.previous)}` : '',
+      architecture: renderArchitectureSection(this.layout.style, boundary.name),
+      domain_model: renderDomainModelSection(boundary.domain_model),
+      context: contextSection,
+      repository: repositorySection,
+      http_conventions: renderHttpConventionSection(this.httpConventions),
+      method_naming: renderMethodNamingSection(methodNames),
+      context_threading: this.buildContextInstructions(file, originalCode),
+      annotations: renderAnnotationSection(parseAnnotations(originalCode, this.paths.toPortablePath(file)).annotations),
+      function_values: renderFunctionValueSection(this.functionValueUses(file)),
+      configuration: this.buildConfigInstructions(file, boundary),
+      ports: this.buildPortInstructions(file, boundary),
+      domain_events: renderEventsPromptSection(boundary.name, this.loadDomainEvents(), this.paths.toPortablePath(file)),
+      code: originalCode,
+    };
+    const prompt = renderPromptTemplate(template.text, variables);
+
+    this.recordPromptMetrics(file, boundary, prompt, context);
+    this.attemptUsage.input_tokens += estimateTokens(prompt);
+
+    if (this.llmCache) {
+      this.savePromptInputs(file, boundary, template, variables, prompt);
+      const cached = this.llmCache.get(prompt, attempt.model);
+      if (cached !== undefined) {
+        console.log('    🗃️  Response served from the LLM cache');
+        this.lastResponse = cached;
+        this.attemptUsage.cached = true;
+        this.attemptUsage.method = 'llm';
+        this.attemptUsage.output_tokens += estimateTokens(cached);
+        return { ...this.claudeClient.extractJsonFromResult(cached), generation: { method: 'llm' } };
+      }
+    }
+    if (this.batch) return this.batchTransformation(file, boundary, prompt, attempt.model);
+
+    this.lastResponse = undefined;
+    try {
+      const result = await this.requestTransformation(prompt, signal, attempt.model);
+      this.attemptUsage.method = result.generation?.method;
+      if (this.lastResponse !== undefined && result.generation?.method === 'llm') {
+        this.attemptUsage.responses.push({ prompt, model: attempt.model, response: this.lastResponse });
+      }
+      return result;
+    } finally {
+      this.attemptUsage.output_tokens += estimateTokens(this.lastResponse ?? '');
+    }
+  }
+
+  /**
+   * Answer a prompt from the results of the run's batch jobs, or queue it for the
+   * next job (BatchPendingError). Failed batch items fail the file as LLM errors.
+   */
+  private batchTransformation(file: string, boundary: DomainBoundary, prompt: string, model?: string): RefactoredFile {
+    const stored = this.batch!.response(prompt, model);
+    if (!stored) {
+      this.batch!.enqueue(boundary.name, this.paths.toPortablePath(file), prompt, model);
+      throw new BatchPendingError(file);
+    }
+    if (stored.response === undefined) throw new BatchItemError(stored.failure ?? 'errored', stored.error ?? 'no response');
+
+    this.lastResponse = stored.response;
+    this.attemptUsage.method = 'llm';
+    this.attemptUsage.batch = { job_id: stored.job_id, discount: this.batch!.discount, replayed: stored.used ?? false };
+    if (!stored.used) this.batch!.markUsed(prompt, model);
+    this.attemptUsage.output_tokens += estimateTokens(stored.response);
+    this.attemptUsage.responses.push({ prompt, model, response: stored.response });
+    return { ...this.claudeClient.extractJsonFromResult(stored.response), generation: { method: 'llm' } };
+  }
+
+  /**
+   * prompt.refactorTemplate or the built-in transformation prompt
+   */
+  private get refactorTemplate(): PromptTemplate {
+    if (!this.template) this.template = loadRefactorTemplate(this.projectRoot, this.loadPromptConfig().refactorTemplate);
+    return this.template;
+  }
+
+  /**
+   * Keep the placeholder values of a prompt for `vf prompt-bench --replay`
+   */
+  private savePromptInputs(file: string, boundary: DomainBoundary, template: PromptTemplate, variables: Record<string, string>, prompt: string): void {
+    try {
+      this.llmCache!.saveInputs({
+        task: REFACTOR_TASK,
+        template: template.name,
+        template_hash: template.hash,
+        module: boundary.name,
+        file: this.paths.toPortablePath(file),
+        variables,
+        input_tokens: estimateTokens(prompt),
+      });
+    } catch (error) {
+      console.warn(`    ⚠️  Prompt inputs not cached for ${file}: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * Store the responses of an attempt that passed verification (llm.cache)
+   */
+  private cacheResponses(): void {
+    if (!this.llmCache) return;
+    try {
+      for (const { prompt, model, response } of this.attemptUsage.responses) {
+        this.llmCache.put(prompt, model, response);
+      }
+    } catch (error) {
+      console.warn(`    ⚠️  LLM response not cached: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * Legacy function → usecase method table for a file, reusing names already recorded for the module
+   */
+  private planMethodNames(file: string, boundary: DomainBoundary, originalCode: string): MethodNameMapping[] {
+    try {
+      const store = new MethodNameStore(this.projectRoot);
+      const portableFile = this.paths.toPortablePath(file);
+      return buildMethodNameMapping(boundary.name, portableFile, extractLegacyFunctions(originalCode, file), store.load(boundary.name));
+    } catch (error) {
+      console.warn(`    ⚠️  Method naming skipped for ${file}: ${getErrorMessage(error)}`);
+      return [];
+    }
+  }
+
+  /**
+   * Make interface, implementation, handler and tests agree on the mapped names and persist the table
+   */
+  private applyMethodNames(boundary: DomainBoundary, result: RefactoredFile, methodNames: MethodNameMapping[]): RefactoredFile {
+    if (methodNames.length === 0) return result;
+
+    const { mappings, renames } = reconcileMethodNames(
+      methodNames,
+      [...result.refactored_files, ...result.interfaces, ...result.tests],
+      result.method_names
+    );
+    const rename = <T extends { content: string }>(items: T[]): T[] =>
+      items.map(item => ({ ...item, content: renameMethods(item.content, renames) }));
+
+    try {
+      new MethodNameStore(this.projectRoot).save(boundary.name, methodNames[0].file, mappings);
+    } catch (error) {
+      console.warn(`    ⚠️  Could not persist method names: ${getErrorMessage(error)}`);
+    }
+
+    return {
+      ...result,
+      refactored_files: rename(result.refactored_files),
+      interfaces: rename(result.interfaces),
+      tests: rename(result.tests),
+      method_names: mappings,
+    };
+  }
+
+  /**
+   * Thread or drop ctx parameters across the outputs of one file and collect context.TODO() sites
+   */
+  private applyContextThreading(file: string, originalCode: string, result: RefactoredFile): RefactoredFile {
+    const addContext = this.loadRefactorConfig().addContext;
+    if (addContext === undefined) return result;
+
+    const legacyWithContext = contextMethodNames(legacyContextFunctions(originalCode, file), result.method_names);
+    const threaded = threadContext([...result.refactored_files, ...result.interfaces, ...result.tests], { addContext, legacyWithContext });
+    const byPath = new Map(threaded.files.map(f => [f.path, f.content]));
+    const rethread = <T extends { path: string; content: string }>(items: T[]): T[] =>
+      items.map(item => ({ ...item, content: byPath.get(item.path) ?? item.content }));
+
+    if (threaded.todos.length > 0) {
+      console.log(`    🧵 ${threaded.todos.length} context.TODO() sites left for callers without a context`);
+    }
+
+    return {
+      ...result,
+      refactored_files: rethread(result.refactored_files),
+      interfaces: rethread(result.interfaces),
+      tests: rethread(result.tests),
+      context_todos: threaded.todos,
+    };
+  }
+
+  /**
+   * Keep the //vf: directives of the original file on the declarations they moved
+   * to; outputs that rewrite a //vf:freeze declaration fail verification
+   */
+  private applySourceAnnotations(file: string, originalCode: string, result: RefactoredFile): RefactoredFile {
+    const { annotations } = parseAnnotations(originalCode, this.paths.toPortablePath(file));
+    if (annotations.length === 0) return result;
+
+    return {
+      ...result,
+      refactored_files: preserveAnnotations(annotations, originalCode, result.refactored_files),
+      interfaces: preserveAnnotations(annotations, originalCode, result.interfaces),
+      tests: preserveAnnotations(annotations, originalCode, result.tests),
+    };
+  }
+
+  /**
+   * Functions of the file that are passed, stored or assigned as values must keep
+   * their signature: a change that only adds a context gets an adapter under the
+   * old name, any other change fails verification listing the value-usage sites
+   */
+  private applyValueCompatibility(file: string, originalCode: string, result: RefactoredFile): RefactoredFile {
+    const uses = this.functionValueUses(file);
+    if (uses.length === 0) return result;
+
+    const outputs = [...result.refactored_files, ...result.interfaces, ...result.tests];
+    const checked = checkValueCompatibility(declaredFunctions(originalCode), uses, outputs);
+    const incompatible = checked.checks.filter(check => check.status === 'incompatible');
+    if (incompatible.length > 0) throw new VerificationError(formatIncompatibleValues(incompatible), file);
+
+    for (const check of checked.checks.filter(c => c.status === 'adapter')) {
+      console.log(`    🔌 ${check.symbol} is used as a value (${check.uses.length} sites); kept ${check.original_signature} as an adapter for ${check.renamed_to}`);
+    }
+    const byPath = new Map(checked.outputs.map(o => [o.path, o.content]));
+    const recheck = <T extends { path: string; content: string }>(items: T[]): T[] =>
+      items.map(item => ({ ...item, content: byPath.get(item.path) ?? item.content }));
+
+    return {
+      ...result,
+      refactored_files: recheck(result.refactored_files),
+      interfaces: recheck(result.interfaces),
+      tests: recheck(result.tests),
+      value_checks: checked.checks,
+    };
+  }
+
+  /**
+   * Config field replacing each configuration read of the file (plan.json config_access)
+   */
+  private buildConfigInstructions(file: string, boundary: DomainBoundary): string {
+    const plan = this.loadConfigPlan();
+    const portable = this.paths.toPortablePath(file);
+    const sites = [...(plan.inventory?.keys.flatMap(key => key.accesses) ?? []), ...(plan.inventory?.unresolvable ?? [])]
+      .filter(site => site.location.file === portable);
+    return renderConfigPromptSection(boundary.name, plan.modules.get(boundary.name), sites);
+  }
+
+  /**
+   * Exported symbols of the file that stay public (plan.json ports) and the ones to unexport
+   */
+  private buildPortInstructions(file: string, boundary: DomainBoundary): string {
+    if (!this.modulePorts) this.modulePorts = loadModulePorts(this.projectRoot);
+    return renderPortsPromptSection(boundary.name, this.modulePorts.get(boundary.name), this.paths.toPortablePath(file));
+  }
+
+  private loadDomainEvents(): DomainEventPlan | undefined {
+    if (this.domainEvents === undefined) this.domainEvents = loadDomainEvents(this.projectRoot) ?? null;
+    return this.domainEvents ?? undefined;
+  }
+
+  private loadConfigPlan(): { inventory: ConfigAccessInventory | null; modules: Map<string, ModuleConfig> } {
+    if (!this.configPlan) this.configPlan = loadConfigPlan(this.projectRoot);
+    return this.configPlan;
+  }
+
+  /**
+   * Replace the env reads left in generated code with the module config; the
+   * other reads of the module's keys are reported for review
+   */
+  private rewriteConfigReads(boundary: DomainBoundary, result: RefactoredFile, reads: { rewritten: number; remaining: ConfigAccessSite[] }): void {
+    const config = this.loadConfigPlan().modules.get(boundary.name);
+    if (!config) return;
+
+    for (const output of refactoredOutputs(result)) {
+      if (!output.path.endsWith('.go')) continue;
+      const rewrite = rewriteEnvReads(output.content, config, boundary.name);
+      output.content = rewrite.content;
+      reads.rewritten += rewrite.rewritten;
+      reads.remaining.push(...remainingConfigReads(output.path, output.content, config, boundary.name));
+    }
+  }
+
+  /**
+   * Generate the events package of a module publishing domain events, and the
+   * in-process event bus with the first of them
+   */
+  private async generateDomainEvents(
+    boundary: DomainBoundary,
+    applyChanges: boolean,
+    results: RefactorResult,
+    safetyManager?: FileSafetyManager
+  ): Promise<void> {
+    const plan = this.loadDomainEvents();
+    const events = plan?.events.filter(event => event.producer === boundary.name) ?? [];
+    if (!plan || events.length === 0) return;
+
+    const files = [{ path: `${events[0].package}/events.go`, content: renderEventsFile(boundary.name, events), description: `${boundary.name} domain events` }];
+    const busFile = `${plan.bus.package}/bus.go`;
+    if (!(results.domain_events ?? []).some(generated => generated.bus)) {
+      files.push({ path: busFile, content: renderEventBusFile(), description: 'in-process event bus' });
+    }
+    if (!applyChanges) {
+      files.forEach(file => console.log(`    └─ events: ${file.path}`));
+    } else {
+      await this.applyRefactoredFiles({ refactored_files: files, interfaces: [], tests: [] }, safetyManager);
+      results.created_files.push(...files.map(file => file.path));
+    }
+    results.domain_events = [...(results.domain_events ?? []), {
+      module: boundary.name,
+      package: events[0].package,
+      events: events.map(event => event.name),
+      ...(files.some(file => file.path === busFile) ? { bus: busFile } : {}),
+    }];
+  }
+
+  /**
+   * Create the module's go.mod when the plan splits it into its own Go module,
+   * and wire it in: require and replace directives in the root go.mod
+   * (multi-module) or a go.work using it (go-work). An existing go.mod of the
+   * module is left alone.
+   */
+  private async generateGoModule(
+    boundary: DomainBoundary,
+    applyChanges: boolean,
+    results: RefactorResult,
+    safetyManager?: FileSafetyManager
+  ): Promise<void> {
+    if (this.goModulePlan === undefined) this.goModulePlan = loadGoModulePlan(this.projectRoot) ?? null;
+    const plan = this.goModulePlan;
+    const module = plan?.modules.find(m => m.module === boundary.name);
+    if (!plan || plan.layout === 'single' || !module) return;
+
+    const exists = (file: string) => fsSync.existsSync(path.join(this.projectRoot, file));
+    const created: { path: string; content: string; description: string }[] = [];
+    const modified: { path: string; content: string; description: string }[] = [];
+    if (!exists(module.go_mod)) {
+      created.push({ path: module.go_mod, content: renderGoModFile(module, plan.root.go_version), description: `${boundary.name} go.mod` });
+    }
+    if (plan.layout === 'multi-module') {
+      const root = fsSync.readFileSync(path.join(this.projectRoot, plan.root.go_mod), 'utf8');
+      const updated = addRootDirectives(root, plan, boundary.name);
+      if (updated !== root) modified.push({ path: plan.root.go_mod, content: updated, description: `require and replace ${module.module_path}` });
+    } else if (plan.go_work) {
+      const split = plan.modules.filter(m => m.module === boundary.name || exists(m.go_mod)).map(m => m.dir);
+      const existing = exists(plan.go_work.file) ? parseGoWork(fsSync.readFileSync(path.join(this.projectRoot, plan.go_work.file), 'utf8')) : [];
+      const content = renderGoWorkFile(plan, split, existing);
+      (exists(plan.go_work.file) ? modified : created).push({ path: plan.go_work.file, content, description: 'go.work' });
+    }
+    if (!applyChanges) {
+      [...created, ...modified].forEach(file => console.log(`    └─ go module: ${file.path}`));
+    } else {
+      await this.applyRefactoredFiles({ refactored_files: [...created, ...modified], interfaces: [], tests: [] }, safetyManager);
+      results.created_files.push(...created.map(file => file.path));
+      results.modified_files.push(...modified.map(file => file.path));
+    }
+    results.go_modules = [...(results.go_modules ?? []), {
+      module: boundary.name,
+      layout: plan.layout,
+      module_path: module.module_path,
+      files: [...created, ...modified].map(file => file.path),
+    }];
+  }
+
+  /**
+   * Generate internal/<module>/config/config.go with the keys the plan assigned to the module
+   */
+  private async generateModuleConfig(
+    boundary: DomainBoundary,
+    applyChanges: boolean,
+    results: RefactorResult,
+    reads: { rewritten: number; remaining: ConfigAccessSite[] },
+    safetyManager?: FileSafetyManager
+  ): Promise<void> {
+    const config = this.loadConfigPlan().modules.get(boundary.name);
+    if (!config) return;
+
+    const file = { path: `${config.package}/config.go`, content: renderModuleConfigFile(boundary.name, config), description: `${boundary.name} module config` };
+    if (!applyChanges) {
+      console.log(`    └─ config: ${config.fields.length} keys → ${file.path}`);
+    } else {
+      await this.applyRefactoredFiles({ refactored_files: [file], interfaces: [], tests: [] }, safetyManager);
+      results.created_files.push(file.path);
+    }
+    results.module_configs = [...(results.module_configs ?? []), {
+      module: boundary.name,
+      package: config.package,
+      keys: config.fields.length,
+      rewritten: reads.rewritten,
+      remaining: reads.remaining,
+      wiring: config.wiring,
+    }];
+  }
+
+  /**
+   * Value uses of the functions declared in a Go file, scanned once per file
+   */
+  private functionValueUses(file: string): FunctionValueUse[] {
+    if (!file.endsWith('.go')) return [];
+    const cached = this.valueUses.get(file);
+    if (cached) return cached;
+
+    let uses: FunctionValueUse[] = [];
+    try {
+      const portable = this.paths.toPortablePath(file);
+      uses = scanFunctionValueUses(this.projectRoot, portable, fsSync.readFileSync(path.resolve(this.projectRoot, file), 'utf8'));
+    } catch (error) {
+      console.warn(`    ⚠️  Function value scan skipped for ${file}: ${getErrorMessage(error)}`);
+    }
+    this.valueUses.set(file, uses);
+    return uses;
+  }
+
+  /**
+   * Context threading rules for the model (refactor.addContext)
+   */
+  private buildContextInstructions(file: string, originalCode: string): string {
+    const addContext = this.loadRefactorConfig().addContext;
+    if (addContext === undefined) return '';
+    return renderContextSection(addContext, legacyContextFunctions(originalCode, file));
+  }
+
+  /**
+   * Send one transformation prompt to the model (the SDK default when none is given)
+   */
+  protected async requestTransformation(prompt: string, signal?: AbortSignal, model?: string): Promise<RefactoredFile> {
+    const { text, generation } = await this.claudeClient.queryWithGeneration(prompt, { signal, model });
+    this.lastResponse = text;
+    return { ...this.claudeClient.extractJsonFromResult(text), generation };
+  }
+
+  /**
+   * Keep the response that could not be parsed or verified for `vf bugreport`
+   */
+  private saveFailedResponse(file: string, error: unknown, model?: string): void {
+    if (this.lastResponse === undefined) return;
+    try {
+      const runId = new PerformanceStore(this.projectRoot, { readOnly: true }).getActiveRunId();
+      new FailedResponseStore(this.projectRoot).save({
+        run_id: runId,
+        file: this.paths.toPortablePath(file),
+        model,
+        category: failureCategory(error),
+        error: getErrorMessage(error),
+        response: this.lastResponse,
+      });
+    } catch {
+      // Diagnostics are best-effort
+    }
+  }
+
+  /**
+   * Select minimal dependency-aware context for a file within prompt.contextBudgetTokens
+   */
+  protected selectPromptContext(file: string, boundary: DomainBoundary): SelectedContext | null {
+    try {
+      const promptConfig = this.loadPromptConfig();
+      const selector = new ContextSelector(this.projectRoot, {
+        budgetTokens: promptConfig.contextBudgetTokens,
+        inlineBodyMaxLines: promptConfig.inlineBodyMaxLines,
+      });
+
+      let planExcerpt = '';
+      if (fsSync.existsSync(this.paths.planPath)) {
+        planExcerpt = ContextSelector.extractPlanExcerpt(fsSync.readFileSync(this.paths.planPath, 'utf8'), boundary.name);
+      }
+
+      return selector.selectContext(file, boundary.files, planExcerpt);
+    } catch (error) {
+      console.warn(`    ⚠️  Context selection skipped for ${file}: ${getErrorMessage(error)}`);
+      return null;
+    }
+  }
+
+  private loadPromptConfig(): PromptConfig {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return config.prompt ?? {};
+    } catch {
+      return {};
+    }
+  }
+
+  private loadLlmConfig(): LlmConfig {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return config.llm ?? {};
+    } catch {
+      return {};
+    }
+  }
+
+  private loadRefactorConfig(): RefactorConfig {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return config.refactor ?? {};
+    } catch {
+      return {};
+    }
+  }
+
+  /**
+   * Unattended runs only apply modules up to the allowed risk tier of the plan;
+   * null when not applying or when no tier is configured
+   */
+  protected loadAutonomyGate(applyChanges: boolean, options: RefactorExecutionOptions): AutonomyGate | null {
+    const maxTier = options.maxAutoApplyTier ?? this.loadAutonomyConfig().maxAutoApplyTier;
+    return applyChanges && maxTier ? { maxTier, risks: loadModuleRisks(this.projectRoot) } : null;
+  }
+
+  private loadAutonomyConfig(): AutonomyConfig {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return config.autonomy ?? {};
+    } catch {
+      return {};
+    }
+  }
+
+  private loadFilesConfig(): FilesConfig {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return config.files ?? {};
+    } catch {
+      return {};
+    }
+  }
+
+  private get lineEndings(): LineEndingMode {
+    return this.loadFilesConfig().lineEndings ?? 'preserve';
+  }
+
+  private loadRepositoryConfig(): RepositoryConfig {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return config.repository ?? {};
+    } catch {
+      return {};
+    }
+  }
+
+  private get httpConventions(): HttpConventions {
+    if (!this.conventions) this.conventions = loadHttpConventions(this.projectRoot);
+    return this.conventions;
+  }
+
+  /**
+   * With repository.style: sqlc, instruct the model to call sqlc-generated queries
+   * instead of copying raw SQL strings into the repository implementation
+   */
+  private buildRepositoryInstructions(file: string, originalCode: string): string {
+    if (this.loadRepositoryConfig().style !== 'sqlc') return '';
+
+    const { queries, nonExtractable } = extractSqlQueries(originalCode, file);
+    if (queries.length === 0 && nonExtractable.length === 0) return '';
+
+    const lines = ['## Repository Style: sqlc'];
+    if (queries.length > 0) {
+      lines.push('The following SQL has been extracted into sqlc query files. Repository implementations must call the generated `db.Queries` methods instead of embedding SQL strings:');
+      queries.forEach(q => lines.push(`- ${q.name} (${q.command}) from ${q.function}`));
+    }
+    if (nonExtractable.length > 0) {
+      lines.push('Keep these dynamically built queries as-is:');
+      nonExtractable.forEach(q => lines.push(`- ${q.function}: ${q.reason}`));
+    }
+    return lines.join('\n');
+  }
+
+  /**
+   * Extract SQL from a module's files into sqlc queries and generate the repository
+   */
+  private async generateSqlcRepository(
+    boundary: DomainBoundary,
+    repositoryConfig: RepositoryConfig,
+    applyChanges: boolean,
+    results: RefactorResult,
+    safetyManager?: FileSafetyManager
+  ): Promise<void> {
+    const queries: ExtractedQuery[] = [];
+    const nonExtractable: NonExtractableQuery[] = [];
+
+    for (const file of boundary.files) {
+      const fullPath = path.isAbsolute(file) ? file : path.join(this.projectRoot, file);
+      try {
+        const extraction = extractSqlQueries(await fs.readFile(fullPath, 'utf8'), file);
+        queries.push(...extraction.queries);
+        nonExtractable.push(...extraction.nonExtractable);
+      } catch (error) {
+        console.warn(`    ⚠️  SQL extraction skipped for ${file}: ${getErrorMessage(error)}`);
+      }
+    }
+
+    results.non_extractable_queries = [
+      ...(results.non_extractable_queries || []),
+      ...nonExtractable.map(q => ({ file: q.file, function: q.function, reason: q.reason })),
+    ];
+
+    if (queries.length === 0) return;
+
+    const generator = new SqlcGenerator(this.projectRoot, detectSchemaPaths(this.projectRoot, repositoryConfig.schema));
+    const artifacts = generator.generateModuleArtifacts(boundary.name, queries);
+
+    if (!applyChanges) {
+      console.log(`    └─ sqlc: ${queries.length} queries → ${artifacts.moduleDir}/queries/${boundary.name}.sql`);
+      return;
+    }
+
+    await this.applyRefactoredFiles({ refactored_files: artifacts.files, interfaces: [], tests: [] }, safetyManager);
+    results.created_files.push(...artifacts.files.map(f => f.path));
+
+    try {
+      generator.runSqlcGenerate(artifacts);
+    } catch (error) {
+      console.warn(`    ⚠️  sqlc generate failed for ${boundary.name}: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * Record per-request prompt and context token counts for the active run
+   */
+  private recordPromptMetrics(file: string, boundary: DomainBoundary, prompt: string, context: SelectedContext | null): void {
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      const runId = store.getActiveRunId();
+      if (runId === undefined) return;
+
+      // Keyed by the stable boundary ID so renaming a boundary keeps its history
+      const labels = { file: this.paths.toPortablePath(file), module: boundary.id ?? boundary.name, module_name: boundary.name };
+      store.recordMetric(runId, 'prompt_context_tokens', context?.tokens ?? 0, labels);
+      store.recordMetric(runId, 'prompt_input_tokens', estimateTokens(prompt), labels);
+    } catch {
+      // Metrics are best-effort
+    }
+  }
+
+  /**
+   * llm_calls record of an attempt for the active run; attempts that produced template
+   * output or never sent a prompt are not LLM calls
+   */
+  private recordLlmCall(file: string, boundary: DomainBoundary, attempt: ModelAttempt, outcome: LlmCallOutcome): void {
+    const usage = this.attemptUsage;
+    const method = usage.method ?? (this.generationMode === 'template' ? 'template' : 'llm');
+    if (method !== 'llm' || usage.input_tokens === 0 || !this.template || usage.batch?.replayed) return;
+
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      const runId = store.getActiveRunId();
+      if (runId === undefined) return;
+
+      store.recordLlmCall({
+        run_id: runId,
+        task: REFACTOR_TASK,
+        template: this.template.name,
+        template_hash: this.template.hash,
+        module: boundary.id ?? boundary.name,
+        module_name: boundary.name,
+        file: this.paths.toPortablePath(file),
+        ...(attempt.model ? { model: attempt.model } : {}),
+        attempt: attempt.previous ? 2 : 1,
+        input_tokens: usage.input_tokens,
+        output_tokens: usage.output_tokens,
+        // Unknown models are priced as sonnet
+        cost: usage.cached ? 0 : estimateModelCost(attempt.model ?? '', usage.input_tokens, usage.output_tokens) * (1 - (usage.batch?.discount ?? 0)),
+        outcome,
+        ...(usage.cached ? { cached: true } : {}),
+        ...(usage.batch ? { batch_job: usage.batch.job_id, batch_discount: usage.batch.discount } : {}),
+      });
+    } catch {
+      // Metrics are best-effort
+    }
+  }
+
+  /**
+   * file_processing record for the active run; template-fallback marks files meant for the LLM
+   */
+  protected recordFileProcessing(file: string, boundary: DomainBoundary, details: FileProcessingDetails): void {
+    this.processed.push(details);
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      const runId = store.getActiveRunId();
+      if (runId === undefined) return;
+
+      store.recordFileProcessing({
+        run_id: runId,
+        file: this.paths.toPortablePath(file),
+        module: boundary.id ?? boundary.name,
+        module_name: boundary.name,
+        agent: this.agentName,
+        ...details,
+      });
+    } catch {
+      // Metrics are best-effort
+    }
+  }
+
+  /**
+   * Sizes, transformations, verification and template of a generated file
+   */
+  private generationDetails(file: string, result: RefactoredFile, generation: GenerationInfo): Partial<FileProcessingDetails> {
+    let source = '';
+    try {
+      source = fsSync.readFileSync(file, 'utf8');
+    } catch {
+      // Removed while processing: nothing to compare against
+    }
+    const outputs = refactoredOutputs(result);
+    return {
+      bytes_in: Buffer.byteLength(source, 'utf8'),
+      bytes_out: byteSize(outputs),
+      transformations: describeTransformations({ path: this.paths.toPortablePath(file), content: source }, outputs, generation.method),
+      // LLM output is verified before it is returned (see transformFile); templates are not
+      verification: generation.method === 'llm' ? 'passed' : 'not-run',
+      ...(generation.template ? { template: generation.template.name, template_hash: generation.template.hash } : {}),
+    };
+  }
+
+  /**
+   * Regenerate .vibeflow/reports/data-mapping.* from the updated manifests
+   */
+  private writeDataMappingReport(): void {
+    try {
+      const reporter = new DataMappingReporter(this.projectRoot);
+      const report = reporter.build();
+      const { csvPath } = reporter.write(report);
+      console.log(`🗂️  Data mapping report: ${this.paths.getRelativePath(csvPath)}`);
+      if (report.split_ownership_risks.length > 0) {
+        console.log(`⚠️  Split ownership risk: ${report.split_ownership_risks.join(', ')}`);
+      }
+    } catch (error) {
+      console.warn(`⚠️  Data mapping report skipped: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * Exported symbols of the generated modules nobody outside them uses; counts go to performance_metrics
+   */
+  private writeApiSurfaceReport(): void {
+    try {
+      const reporter = new ApiSurfaceReporter(this.projectRoot);
+      const report = reporter.build();
+      const { htmlPath } = reporter.write(report);
+      reporter.recordMetrics(report);
+      console.log(`🔐 API surface report: ${this.paths.getRelativePath(htmlPath)} (${report.totals.unexport} of ${report.totals.exported} exported symbols can be unexported)`);
+    } catch (error) {
+      console.warn(`⚠️  API surface report skipped: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * Rescan debt markers after an apply so the metrics show debt shrinking as modules migrate
+   */
+  private recordDebtInventory(): void {
+    try {
+      const { map } = parseDomainMap(fsSync.readFileSync(this.paths.domainMapPath, 'utf8'), this.paths.domainMapPath);
+      const scanner = new DebtInventoryScanner(this.projectRoot);
+      const inventory = scanner.scan(map.boundaries);
+      scanner.write(inventory);
+      scanner.recordMetrics(inventory);
+    } catch (error) {
+      console.warn(`⚠️  Debt inventory skipped: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * Point .vibeflow/reports/findings.* at the files written by this apply
+   */
+  private relocateFindings(): void {
+    try {
+      const result = new FindingsReporter(this.projectRoot).relocate();
+      if (result && result.relocated > 0) {
+        console.log(`📍 Relocated ${result.relocated} findings to the generated files`);
+      }
+      if (result && result.stale > 0) {
+        console.warn(`⚠️  ${result.stale} findings could not be located after apply`);
+      }
+    } catch (error) {
+      console.warn(`⚠️  Findings relocation skipped: ${getErrorMessage(error)}`);
+    }
+  }
+
+  private updateRunModule(moduleName: string | undefined): void {
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      const runId = store.getActiveRunId();
+      if (runId !== undefined) store.setCurrentModule(runId, moduleName);
+    } catch {
+      // Run tracking is best-effort
+    }
+  }
+
+  /**
+   * Advance the module lifecycle for the active run (best-effort)
+   */
+  private recordModuleStage(boundary: DomainBoundary, stage: AgentStage): void {
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      new ModuleStatusTracker(this.projectRoot, store).advance([boundary], stage, store.getActiveRunId());
+    } catch (error) {
+      console.warn(`    ⚠️  Module status not updated: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * Refusal for a module a reviewer already accepted, unless the caller confirms overwriting it
+   */
+  private async acceptedModuleRefusal(boundary: DomainBoundary, options: RefactorExecutionOptions): Promise<string | null> {
+    let status: ModuleStatusRecord | undefined;
+    try {
+      status = new ModuleStatusTracker(this.projectRoot).find(boundary);
+    } catch {
+      return null;
+    }
+    if (status?.stage !== 'accepted') return null;
+    if (options.confirmReopen && await options.confirmReopen(boundary.name, status.accepted_by)) {
+      console.warn(`  ⚠️  ${boundary.name} was accepted${status.accepted_by ? ` by ${status.accepted_by}` : ''}; overwriting resets it to refactored`);
+      return null;
+    }
+    return `Module ${boundary.name} was accepted${status.accepted_by ? ` by ${status.accepted_by}` : ''} and is not overwritten without confirmation (--reopen-accepted)`;
+  }
+
+  private recordSkippedModule(moduleName: string): void {
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      const runId = store.getActiveRunId();
+      if (runId !== undefined) store.markModuleSkipped(runId, moduleName);
+    } catch {
+      // Run tracking is best-effort
+    }
   }
 
   /**
    * Execute actual refactoring - not plan generation, actual file operations
    */
-  async executeRefactoring(boundaries: DomainBoundary[], applyChanges: boolean): Promise<RefactorResult> {
+  async executeRefactoring(
+    boundaries: DomainBoundary[],
+    applyChanges: boolean,
+    options: RefactorExecutionOptions = {}
+  ): Promise<RefactorResult> {
     console.log('🔧 AI automatic code transformation starting...');
     console.log(`Mode: ${applyChanges ? 'Apply Changes' : 'Dry Run'}`);
+    this.assertPlanNotExploratory();
+    this.batch = options.batch;
+    this.processed = [];
     
     const safetyManager = applyChanges ? new FileSafetyManager(this.projectRoot) : null;
+    const repositoryConfig = this.loadRepositoryConfig();
+    const http = this.httpConventions;
+    console.log(`HTTP: ${http.framework} (${http.source}: ${http.evidence})${http.mount ? `, router in ${http.mount.file ?? http.mount.dir}` : ''}`);
+    http.warnings.forEach(warning => console.warn(`⚠️  http: ${warning}`));
     
     const results: RefactorResult = {
       applied_patches: [],
@@ -143,46 +1190,34 @@ ${originalCode}
       deleted_files: [],
       outputPath: ''
     };
+    // Shared mutable state must have an accepted resolution in the plan before it is split
+    const sharedState = applyChanges ? loadSharedState(this.projectRoot) : [];
+    const autonomy = this.loadAutonomyGate(applyChanges, options);
+    const context: BoundaryRunContext = { results, sharedState, autonomy, safetyManager, repositoryConfig };
 
     for (const boundary of boundaries) {
-      console.log(`\n📁 Refactoring ${boundary.name} module (${boundary.files.length} files)...`);
-      
-      // 1. Create module structure
-      if (applyChanges) {
-        await this.createModuleStructure(boundary);
-      }
-      
-      // 2. Actually transform each file
-      for (const file of boundary.files) {
-        try {
-          console.log(`  🔄 Processing ${file}...`);
-          const refactoredFiles = await this.generateRefactoredCode(file, boundary);
-          
-          if (applyChanges) {
-            await this.applyRefactoredFiles(refactoredFiles, safetyManager || undefined);
-            results.applied_patches.push(file);
-            results.created_files.push(...refactoredFiles.refactored_files.map(f => f.path));
-            results.created_files.push(...refactoredFiles.interfaces.map(i => i.path));
-            results.created_files.push(...refactoredFiles.tests.map(t => t.path));
-          } else {
-            console.log(`    └─ Will split into ${refactoredFiles.refactored_files.length} files + ${refactoredFiles.interfaces.length} interfaces + ${refactoredFiles.tests.length} tests`);
-          }
-        } catch (error) {
-          const errorMessage = getErrorMessage(error);
-          console.error(`    ❌ Failed to transform ${file}: ${errorMessage}`);
-          
-          if (error instanceof RefactorError) {
-            console.error(`       Boundary: ${error.boundary}`);
-            if (error.details) {
-              console.error(`       Details: ${JSON.stringify(error.details)}`);
-            }
-          }
-          
-          results.failed_patches.push({ file, error: errorMessage });
-        }
+      try {
+        await this.refactorBoundary(boundary, applyChanges, options, context);
+      } catch (error) {
+        // A malformed boundary or input must only fail its own module
+        const errorMessage = getErrorMessage(error);
+        console.error(`  ❌ ${boundary?.name ?? 'unnamed'} module failed: ${errorMessage}`);
+        this.skipController.endModule();
+        this.updateRunModule(undefined);
+        const handled = new Set(results.applied_patches.concat(results.failed_patches.map(f => f.file)));
+        const files = Array.isArray(boundary?.files) ? boundary.files.filter(file => !handled.has(file)) : [];
+        results.failed_patches.push(...(files.length > 0 ? files : [`<module ${boundary?.name ?? 'unnamed'}>`])
+          .map(file => ({ file, error: errorMessage, category: failureCategory(error) })));
       }
     }
 
+    if (applyChanges && results.created_files.length > 0) {
+      this.writeDataMappingReport();
+      this.writeApiSurfaceReport();
+      this.recordDebtInventory();
+      this.relocateFindings();
+    }
+
     const summary = this.generateRefactorSummary(results, boundaries);
     console.log(summary);
     
@@ -198,16 +1233,352 @@ ${originalCode}
   }
 
   /**
-   * Create clean architecture module structure
+   * Transform and write one module, accumulating into context.results
+   */
+  private async refactorBoundary(
+    boundary: DomainBoundary,
+    applyChanges: boolean,
+    options: RefactorExecutionOptions,
+    { results, sharedState, autonomy, safetyManager, repositoryConfig }: BoundaryRunContext
+  ): Promise<void> {
+    if (!boundary || typeof boundary.name !== 'string' || !Array.isArray(boundary.files) || boundary.files.some(f => typeof f !== 'string')) {
+      throw new InputParseError('domain-map', `boundary ${boundary?.name ?? '(unnamed)'} needs a name and a list of file paths`);
+    }
+    console.log(`\n📁 Refactoring ${boundary.name} module (${boundary.files.length} files)...`);
+
+    const refusal = establishedModuleRefusal(boundary)
+      ?? degradedModuleRefusal(boundary, options.allowDegraded ?? false)
+      ?? sharedStateRefusal(boundary.name, sharedState)
+      ?? (autonomy ? autonomyTierRefusal(boundary.name, autonomy.risks.get(boundary.name), autonomy.maxTier, options.force ?? false) : null);
+    if (refusal) {
+      console.error(`  ❌ ${refusal}`);
+      results.failed_patches.push(...boundary.files.map(file => ({ file, error: refusal, category: 'refused' as const })));
+      return;
+    }
+    
+    if (applyChanges) {
+      const acceptedRefusal = await this.acceptedModuleRefusal(boundary, options);
+      if (acceptedRefusal) {
+        console.error(`  ❌ ${acceptedRefusal}`);
+        results.failed_patches.push(...boundary.files.map(file => ({ file, error: acceptedRefusal, category: 'refused' as const })));
+        return;
+      }
+    }
+
+    // 1. Create module structure
+    if (applyChanges) {
+      if (options.cleanModule) {
+        results.deleted_files.push(...await this.cleanModuleOutputs(boundary.name));
+      }
+      await this.createModuleStructure(boundary);
+    }
+    
+    // 2. Actually transform each file
+    const moduleOutputs: ModuleOutput[] = [];
+    const contextTodos: ContextTodo[] = [];
+    const valueAdapters: ValueCompatibilityCheck[] = [];
+    const fallbacks: { file: string; reason: string }[] = [];
+    const configReads = { rewritten: 0, remaining: [] as ConfigAccessSite[] };
+    const skipSignal = this.skipController.beginModule(boundary.name);
+    this.updateRunModule(boundary.name);
+    const failedBefore = results.failed_patches.length;
+    let skipped = false;
+    let pending = false;
+
+    for (const file of boundary.files) {
+      if (skipSignal.aborted) {
+        skipped = true;
+        break;
+      }
+
+      const startedAt = Date.now();
+      try {
+        console.log(`  🔄 Processing ${file}...`);
+        const refactoredFiles = await this.generateWithEscalation(file, boundary, skipSignal, options, results);
+        const generation: GenerationInfo = refactoredFiles.generation ?? { method: this.generationMode === 'template' ? 'template' : 'llm' };
+        if (generation.method === 'template-fallback') {
+          fallbacks.push({ file, reason: generation.fallback_reason ?? 'unknown' });
+        }
+        this.recordFileProcessing(file, boundary, {
+          method: generation.method,
+          status: 'success',
+          duration_ms: Date.now() - startedAt,
+          ...(generation.fallback_reason ? { fallback_reason: generation.fallback_reason } : {}),
+          ...this.generationDetails(file, refactoredFiles, generation),
+        });
+        this.rewriteConfigReads(boundary, refactoredFiles, configReads);
+        results.method_names = [...(results.method_names ?? []), ...(refactoredFiles.method_names ?? [])];
+        contextTodos.push(...(refactoredFiles.context_todos ?? []));
+        valueAdapters.push(...(refactoredFiles.value_checks ?? []).filter(check => check.status === 'adapter'));
+        
+        if (applyChanges) {
+          moduleOutputs.push({ source: file, result: refactoredFiles });
+        } else {
+          console.log(`    └─ Will split into ${refactoredFiles.refactored_files.length} files + ${refactoredFiles.interfaces.length} interfaces + ${refactoredFiles.tests.length} tests`);
+          for (const line of formatMethodNameTable(refactoredFiles.method_names ?? [])) {
+            console.log(`       🏷️  ${line}`);
+          }
+        }
+      } catch (error) {
+        if (error instanceof ModuleSkippedError) {
+          skipped = true;
+          break;
+        }
+        // Queued for the async batch: keep going so every prompt of the module is submitted
+        if (error instanceof BatchPendingError) {
+          pending = true;
+          continue;
+        }
+
+        const errorMessage = getErrorMessage(error);
+        console.error(`    ❌ Failed to transform ${file}: ${errorMessage}`);
+        this.recordFileProcessing(file, boundary, {
+          method: this.generationMode === 'template' ? 'template' : 'llm',
+          status: 'failed',
+          duration_ms: Date.now() - startedAt,
+          ...(fsSync.existsSync(file) ? { bytes_in: fsSync.statSync(file).size } : {}),
+          verification: failureCategory(error) === 'verification-failure' ? 'failed' : 'not-run',
+          error: errorMessage,
+        });
+        // --require-llm: the whole module fails rather than mixing in template output
+        if (error instanceof LlmUnavailableError) throw error;
+        
+        if (error instanceof RefactorError) {
+          console.error(`       Boundary: ${error.boundary}`);
+          if (error.details) {
+            console.error(`       Details: ${JSON.stringify(error.details)}`);
+          }
+        }
+        
+        results.failed_patches.push({ file, error: errorMessage, category: failureCategory(error) });
+      }
+    }
+
+    this.skipController.endModule();
+    this.updateRunModule(undefined);
+
+    // Skipped modules are not written; they can be resumed later with --resume-skipped
+    if (skipped) {
+      console.log(`  ⏭️  Skipped ${boundary.name} (${moduleOutputs.length}/${boundary.files.length} files transformed, discarded)`);
+      results.skipped_modules = [...(results.skipped_modules ?? []), boundary.name];
+      results.method_names = results.method_names?.filter(m => m.module !== boundary.name);
+      this.recordSkippedModule(boundary.name);
+      return;
+    }
+    // Pending modules are written once every file has its batch result
+    if (pending) {
+      console.log(`  ⏳ ${boundary.name} waits for async batch results (${moduleOutputs.length}/${boundary.files.length} files ready)`);
+      results.pending_modules = [...(results.pending_modules ?? []), boundary.name];
+      results.method_names = results.method_names?.filter(m => m.module !== boundary.name);
+      return;
+    }
+    if (contextTodos.length > 0) {
+      results.context_todos = [...(results.context_todos ?? []), ...contextTodos];
+    }
+    if (valueAdapters.length > 0) {
+      results.value_adapters = [...(results.value_adapters ?? []), ...valueAdapters];
+    }
+    if (fallbacks.length > 0) {
+      const reason = [...new Set(fallbacks.map(f => f.reason))].join('; ');
+      console.warn(`  ⚠️  ${boundary.name}: ${fallbacks.length}/${boundary.files.length} files generated from templates because the LLM was unavailable (${reason})`);
+      console.warn(`     Re-run with: vf refactor --module ${boundary.name} --require-llm`);
+      results.fallback_modules = [...(results.fallback_modules ?? []), { module: boundary.name, files: fallbacks.length, reason }];
+    }
+
+    // 3. Write all module outputs at once so re-runs regenerate in place
+    if (applyChanges && moduleOutputs.length > 0) {
+      try {
+        const routes = this.attachModuleRoutes(boundary, moduleOutputs);
+        const applied = await this.applyModuleOutputs(boundary.name, moduleOutputs, safetyManager || undefined);
+        results.applied_patches.push(...moduleOutputs.map(o => o.source));
+        results.created_files.push(...applied.written);
+        results.deleted_files.push(...applied.deleted);
+        if (routes.length > 0) {
+          results.route_mounts = [...(results.route_mounts ?? []), ...routes];
+          this.verifyModuleRoutes(routes, results);
+        }
+        if (results.failed_patches.length === failedBefore) {
+          this.recordModuleStage(boundary, 'refactored');
+        }
+      } catch (error) {
+        const errorMessage = getErrorMessage(error);
+        console.error(`    ❌ Failed to apply ${boundary.name} outputs: ${errorMessage}`);
+        results.failed_patches.push(...moduleOutputs.map(o => ({ file: o.source, error: errorMessage, category: failureCategory(error) })));
+      }
+    }
+
+    if (repositoryConfig.style === 'sqlc') {
+      await this.generateSqlcRepository(boundary, repositoryConfig, applyChanges, results, safetyManager || undefined);
+    }
+    await this.generateModuleConfig(boundary, applyChanges, results, configReads, safetyManager || undefined);
+    await this.generateDomainEvents(boundary, applyChanges, results, safetyManager || undefined);
+    await this.generateGoModule(boundary, applyChanges, results, safetyManager || undefined);
+  }
+
+  /**
+   * Generate a file with the first model of llm.escalation, retrying once with the next
+   * model (and the failure in the prompt) when the response was malformed or failed
+   * verification. Retries must fit the cost limits and are recorded in the run record.
+   */
+  private async generateWithEscalation(
+    file: string,
+    boundary: DomainBoundary,
+    signal: AbortSignal,
+    options: RefactorExecutionOptions,
+    results: RefactorResult
+  ): Promise<RefactoredFile> {
+    const model = this.escalationChain[0];
+    try {
+      return await this.generateRefactoredCode(file, boundary, signal, model ? { model } : {});
+    } catch (error) {
+      const category = failureCategory(error);
+      const escalated = model ? nextModel(this.escalationChain, model) : undefined;
+      if (!model || !escalated || options.escalation === false || !ESCALATION_CATEGORIES.includes(category)) throw error;
+
+      const escalation = {
+        module: boundary.name,
+        file: this.paths.toPortablePath(file),
+        category,
+        original_model: model,
+        escalated_model: escalated,
+      };
+      const estimate = await this.estimateEscalation(file, escalated);
+      const spent = (results.escalations ?? []).reduce((sum, e) => sum + e.extra_cost, 0);
+      const refusal = await this.escalationBudgetRefusal(estimate.cost, spent);
+      if (refusal) {
+        console.warn(`    💸 Not retrying ${file} with ${escalated}: ${refusal}`);
+        await this.recordEscalation(results, { ...escalation, outcome: 'over-budget', extra_cost: 0, error: refusal });
+        throw error;
+      }
+
+      console.warn(`    ⬆️  ${category} from ${model}; retrying ${file} with ${escalated}`);
+      const previous: PreviousAttempt = { model, category, error: getErrorMessage(error) };
+      try {
+        const result = await this.generateRefactoredCode(file, boundary, signal, { model: escalated, previous });
+        await this.recordEscalation(results, { ...escalation, outcome: 'success', extra_cost: estimate.cost }, estimate.tokens);
+        return result;
+      } catch (retryError) {
+        if (retryError instanceof ModuleSkippedError || retryError instanceof BatchPendingError) throw retryError;
+        await this.recordEscalation(results, { ...escalation, outcome: 'failed', extra_cost: estimate.cost, error: getErrorMessage(retryError) }, estimate.tokens);
+        throw retryError;
+      }
+    }
+  }
+
+  /**
+   * Rough cost of regenerating a file: prompt with context in, several output files out
+   */
+  private async estimateEscalation(file: string, model: string): Promise<{ tokens: number; cost: number }> {
+    const sourceTokens = estimateTokens(await fs.readFile(file, 'utf8'));
+    const inputTokens = sourceTokens + (this.loadPromptConfig().contextBudgetTokens ?? 2000);
+    const outputTokens = sourceTokens * 3;
+    return { tokens: inputTokens + outputTokens, cost: estimateModelCost(model, inputTokens, outputTokens) };
+  }
+
+  /**
+   * Why a retry costing `estimate` is not allowed, counting escalations already spent in this run
+   */
+  private async escalationBudgetRefusal(estimate: number, spent: number): Promise<string | undefined> {
+    try {
+      const costManager = new CostManager(this.projectRoot);
+      await costManager.initialize();
+      const { perRun } = costManager.getUsageReport().limits;
+      if (spent + estimate > perRun) {
+        return `escalations would cost $${(spent + estimate).toFixed(2)} in this run, over the per-run limit ($${perRun.toFixed(2)})`;
+      }
+      const check = await costManager.checkLimits(estimate, 'escalation');
+      return check.allowed ? undefined : check.reason;
+    } catch (error) {
+      // Unknown budget: do not spend on a stronger model
+      return `cost limits unavailable: ${getErrorMessage(error)}`;
+    }
+  }
+
+  private async recordEscalation(results: RefactorResult, escalation: Omit<EscalationRecord, 'recorded_at'>, tokens = 0): Promise<void> {
+    results.escalations = [...(results.escalations ?? []), { ...escalation, recorded_at: new Date().toISOString() }];
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      const runId = store.getActiveRunId();
+      if (runId !== undefined) store.recordEscalation(runId, escalation);
+    } catch {
+      // Run tracking is best-effort
+    }
+    if (escalation.extra_cost === 0) return;
+    try {
+      // Counted against the daily and monthly limits of later escalations
+      const costManager = new CostManager(this.projectRoot);
+      await costManager.initialize();
+      await costManager.recordUsage(tokens, escalation.extra_cost, `escalation:${escalation.escalated_model}`);
+    } catch {
+      // Usage history is best-effort
+    }
+  }
+
+  /**
+   * Add a routes.go to each package of the module with handlers, registering them
+   * from their "handles" comments in the conventions of the project's router
+   */
+  private attachModuleRoutes(boundary: DomainBoundary, outputs: ModuleOutput[]): ModuleRoutes[] {
+    const conventions = this.httpConventions;
+    const packages = new Map<string, { output: ModuleOutput; name: string; routes: Map<string, HandlerRoute> }>();
+    for (const output of outputs) {
+      for (const file of output.result.refactored_files) {
+        const filePath = this.paths.toPortablePath(file.path);
+        if (!filePath.endsWith('.go') || filePath.endsWith('_test.go') || path.posix.basename(filePath) === 'routes.go') continue;
+        const routes = extractHandlerRoutes(file.content);
+        if (routes.length === 0) continue;
+
+        const dir = path.posix.dirname(filePath);
+        const entry = packages.get(dir) ?? { output, name: goPackageName(file.content) ?? path.posix.basename(dir), routes: new Map() };
+        // A route declared twice (regenerated handler): the later one wins
+        routes.forEach(route => entry.routes.set(`${route.method} ${route.path}`, route));
+        packages.set(dir, entry);
+      }
+    }
+
+    return [...packages].map(([dir, entry]) => {
+      const routes = [...entry.routes.values()];
+      const routesPath = `${dir}/routes.go`;
+      entry.output.result.refactored_files.push({
+        path: routesPath,
+        content: renderRoutesFile(conventions, entry.name, routes, boundary.name),
+        description: `${boundary.name} route registration (${conventions.framework})`,
+      });
+      const alias = `${boundary.name.replace(/\W/g, '').toLowerCase()}${entry.name}`;
+      return {
+        module: boundary.name,
+        path: routesPath,
+        framework: conventions.framework,
+        routes: routes.length,
+        import_path: goPackageImportPath(this.projectRoot, dir) ?? dir,
+        call: routeMountCall(conventions, alias, routes),
+        ...(conventions.mount ? { mount: conventions.mount.file ?? conventions.mount.dir } : {}),
+      };
+    });
+  }
+
+  /**
+   * Compile the generated handler packages with the package that mounts the router
+   */
+  private verifyModuleRoutes(routes: ModuleRoutes[], results: RefactorResult): void {
+    const mount = this.httpConventions.mount;
+    const failure = verifyRouteMount(this.projectRoot, [...routes.map(r => path.posix.dirname(r.path)), ...(mount ? [mount.dir] : [])]);
+    if (!failure) {
+      routes.forEach(r => console.log(`    🔌 ${r.path}: ${r.routes} routes; mount with ${r.call}${r.mount ? ` in ${r.mount}` : ''}`));
+      return;
+    }
+
+    const error = new VerificationError(`handler packages${mount ? ` and ${mount.dir}` : ''} do not compile:\n${failure}`);
+    console.error(`    ❌ ${getErrorMessage(error)}`);
+    results.failed_patches.push(...routes.map(r => ({ file: r.path, error: getErrorMessage(error), category: failureCategory(error) })));
+  }
+
+  /**
+   * Create the module structure of the target architecture
    */
   private async createModuleStructure(boundary: DomainBoundary): Promise<void> {
-    const dirs = [
-      `${this.projectRoot}/internal/${boundary.name}/domain`,
-      `${this.projectRoot}/internal/${boundary.name}/usecase`, 
-      `${this.projectRoot}/internal/${boundary.name}/infrastructure`,
-      `${this.projectRoot}/internal/${boundary.name}/handler`,
-      `${this.projectRoot}/internal/${boundary.name}/test`
-    ];
+    const dirs = this.layout.dirs
+      .map(layer => path.join(this.projectRoot, 'internal', boundary.name, layer));
     
     for (const dir of dirs) {
       await fs.mkdir(dir, { recursive: true });
@@ -216,18 +1587,160 @@ ${originalCode}
     console.log(`  📂 Created module structure for ${boundary.name}`);
   }
 
+  /**
+   * Apply a module's outputs idempotently using the per-module output manifest.
+   * Outputs from earlier attempts are regenerated in place, renamed or stale
+   * outputs are removed, and duplicated declarations keep the newest definition.
+   * Outputs edited by hand since the last run are merged with conflict markers
+   * and recorded for `vf resolve` instead of being overwritten.
+   */
+  protected async applyModuleOutputs(
+    moduleName: string,
+    outputs: ModuleOutput[],
+    safetyManager?: FileSafetyManager
+  ): Promise<{ written: string[]; deleted: string[] }> {
+    const manifestStore = new ModuleManifestStore(this.projectRoot);
+    const previous = manifestStore.load(moduleName);
+    const previousEntries = new Map((previous?.files ?? []).map(e => [path.normalize(e.path), e]));
+
+    const pending: PendingOutput[] = outputs.flatMap(({ source, result }) => [
+      ...result.refactored_files.map(f => ({ path: f.path, content: f.content, source })),
+      ...result.interfaces.map(i => ({ path: i.path, content: i.content, source })),
+      ...result.tests.map(t => ({ path: t.path, content: t.content, source })),
+    ]);
+
+    const plan = manifestStore.planWrite(moduleName, pending, outputs.map(o => o.source));
+    for (const conflict of plan.conflicts) {
+      console.log(`    ⚠️  ${conflict.symbol} declared in ${conflict.files.join(' and ')} - keeping newest`);
+    }
+
+    const lineEndings = this.lineEndings;
+    const backups = new Map<string, string>();
+    const conflicts: Omit<FileConflict, 'run_id' | 'detected_at'>[] = [];
+    for (const output of plan.write) {
+      const fullPath = path.join(this.projectRoot, output.path);
+      const previousEntry = previousEntries.get(path.normalize(output.path));
+      const isOriginal = fsSync.existsSync(fullPath) && !previousEntry;
+      if (isOriginal && safetyManager) {
+        const backup = await safetyManager.backupFile(fullPath);
+        backups.set(path.normalize(output.path), this.paths.toPortablePath(backup.backupPath));
+      }
+
+      // 前回の生成物が手動編集されている場合は上書きせずにマージ
+      const onDisk = previousEntry && fsSync.existsSync(fullPath) ? await fs.readFile(fullPath, 'utf8') : null;
+      if (previousEntry && onDisk !== null && hashContent(onDisk) !== previousEntry.hash) {
+        if (hasConflictMarkers(onDisk)) {
+          console.log(`    ⚠️  ${output.path} has unresolved conflicts - run 'vf resolve' first`);
+          continue;
+        }
+        if (previousEntry.hash === hashContent(output.content)) {
+          console.log(`    ✋ Kept manual edit of ${output.path}`);
+          continue;
+        }
+        if (safetyManager) {
+          await safetyManager.backupFile(fullPath);
+        }
+        const merged = mergeWithMarkers(onDisk, output.content);
+        await writeTextFile(fullPath, merged.content, lineEndings);
+        conflicts.push({ path: output.path, module: moduleName, source: output.source, hunks: merged.hunks });
+        console.log(`    ⚔️  ${output.path} was edited manually - ${merged.hunks.length} conflicts to resolve`);
+        continue;
+      }
+
+      await writeTextFile(fullPath, output.content, lineEndings);
+      console.log(`    ✅ ${previousEntry ? 'Regenerated' : 'Created'} ${output.path}`);
+    }
+    if (conflicts.length > 0) {
+      this.recordConflicts(conflicts);
+    }
+    if (plan.unchanged.length > 0) {
+      console.log(`    ⏭️  ${plan.unchanged.length} outputs unchanged`);
+    }
+
+    const deleted: string[] = [];
+    const removals = [
+      ...plan.renamed.map(r => ({ path: r.from, reason: `renamed to ${r.to}` })),
+      ...plan.stale.map(p => ({ path: p, reason: 'no longer produced' })),
+    ];
+    for (const removal of removals) {
+      await this.removeGeneratedOutput(removal.path, previousEntries.get(removal.path)?.backup);
+      deleted.push(removal.path);
+      console.log(`    🗑️  Removed ${removal.path} (${removal.reason})`);
+    }
+
+    for (const strip of plan.strip) {
+      const fullPath = path.join(this.projectRoot, strip.path);
+      const content = await fs.readFile(fullPath, 'utf8');
+      await writeTextFile(fullPath, stripDeclarations(strip.path, content, strip.symbols), lineEndings);
+      console.log(`    ✂️  Removed duplicate ${strip.symbols.join(', ')} from ${strip.path}`);
+    }
+
+    manifestStore.save(manifestStore.buildManifest(moduleName, plan, backups));
+
+    return {
+      written: [...plan.write, ...plan.unchanged].map(o => o.path),
+      deleted,
+    };
+  }
+
+  /**
+   * Record merge conflicts of a re-run against the active run (best-effort)
+   */
+  private recordConflicts(conflicts: Omit<FileConflict, 'run_id' | 'detected_at'>[]): void {
+    try {
+      const runId = new PerformanceStore(this.projectRoot, { readOnly: true }).getActiveRunId();
+      new ConflictStore(this.projectRoot).record(runId, conflicts);
+      console.log(`    👉 Resolve ${conflicts.length} conflicted files with 'vf resolve'`);
+    } catch (error) {
+      console.warn(`    ⚠️  Could not record conflicts: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * Wipe all previously generated outputs for a module, restoring overwritten originals
+   */
+  async cleanModuleOutputs(moduleName: string): Promise<string[]> {
+    const manifestStore = new ModuleManifestStore(this.projectRoot);
+    const manifest = manifestStore.load(moduleName);
+    if (!manifest) {
+      console.log(`  ℹ️  No previous outputs recorded for ${moduleName}`);
+      return [];
+    }
+
+    console.log(`  🧹 Cleaning ${manifest.files.length} previous outputs of ${moduleName}...`);
+    const removed: string[] = [];
+    for (const entry of manifest.files) {
+      await this.removeGeneratedOutput(entry.path, entry.backup);
+      removed.push(entry.path);
+    }
+
+    manifestStore.remove(moduleName);
+    return removed;
+  }
+
+  private async removeGeneratedOutput(relativePath: string, backupPath?: string): Promise<void> {
+    const fullPath = path.join(this.projectRoot, relativePath);
+    const backupFile = backupPath ? this.paths.resolvePortablePath(backupPath) : undefined;
+    if (backupFile && fsSync.existsSync(backupFile)) {
+      await fs.copyFile(backupFile, fullPath);
+      return;
+    }
+    await fs.rm(fullPath, { force: true });
+  }
+
   /**
    * Apply refactored files to the filesystem
    */
   protected async applyRefactoredFiles(refactoredFiles: RefactoredFile, safetyManager?: FileSafetyManager): Promise<void> {
+    const lineEndings = this.lineEndings;
+
     // Create actual files
     for (const file of refactoredFiles.refactored_files) {
       const fullPath = path.join(this.projectRoot, file.path);
       if (safetyManager) {
-        await safetyManager.safeWrite(fullPath, file.content);
+        await safetyManager.safeWrite(fullPath, file.content, lineEndings);
       } else {
-        await fs.mkdir(path.dirname(fullPath), { recursive: true });
-        await fs.writeFile(fullPath, file.content);
+        await writeTextFile(fullPath, file.content, lineEndings);
       }
       console.log(`    ✅ Created ${file.path}`);
     }
@@ -235,16 +1748,14 @@ ${originalCode}
     // Create interfaces
     for (const iface of refactoredFiles.interfaces) {
       const fullPath = path.join(this.projectRoot, iface.path);
-      await fs.mkdir(path.dirname(fullPath), { recursive: true });
-      await fs.writeFile(fullPath, iface.content);
+      await writeTextFile(fullPath, iface.content, lineEndings);
       console.log(`    🔌 Created ${iface.name} interface`);
     }
     
     // Create tests
     for (const test of refactoredFiles.tests) {
       const fullPath = path.join(this.projectRoot, test.path);
-      await fs.mkdir(path.dirname(fullPath), { recursive: true });
-      await fs.writeFile(fullPath, test.content);
+      await writeTextFile(fullPath, test.content, lineEndings);
       console.log(`    🧪 Created test ${test.path}`);
     }
   }
@@ -269,7 +1780,7 @@ ${originalCode}
    * Generate refactor summary
    */
   private generateRefactorSummary(results: RefactorResult, boundaries: DomainBoundary[]): string {
-    const totalFiles = boundaries.reduce((sum, b) => sum + b.files.length, 0);
+    const totalFiles = boundaries.reduce((sum, b) => sum + (Array.isArray(b?.files) ? b.files.length : 0), 0);
     const successRate = totalFiles > 0 ? (results.applied_patches.length / totalFiles * 100).toFixed(1) : '0';
     
     return `
@@ -279,67 +1790,176 @@ ${originalCode}
    📁 Created files: ${results.created_files.length}
    🏗️  Generated modules: ${boundaries.length}
    ⏱️  Average time per file: ${totalFiles > 0 ? '~2-3 seconds' : 'N/A'}
-`;
+${this.formatMethodSummary()}${this.formatFallbackSummary(results)}${this.formatMethodNameSummary(results)}${this.formatContextDebt(results)}${this.formatValueAdapters(results)}${this.formatRouteMounts(results)}${this.formatModuleConfigs(results)}${this.formatDomainEvents(results)}${this.formatGoModules(results)}${this.formatNonExtractableWarnings(results)}`;
+  }
+
+  private formatMethodSummary(): string {
+    const lines = formatMethodTable(summarizeProcessing(this.processed));
+    return lines.length > 0 ? `${lines.join('\n')}\n` : '';
+  }
+
+  private formatFallbackSummary(results: RefactorResult): string {
+    const fallbacks = results.fallback_modules || [];
+    if (fallbacks.length === 0) return '';
+
+    return [
+      `   ⚠️  Downgraded to templates (LLM unavailable): ${fallbacks.length} modules`,
+      ...fallbacks.map(f => `      - ${f.module}: ${f.files} files (${f.reason})`),
+      '',
+    ].join('\n');
+  }
+
+  private formatMethodNameSummary(results: RefactorResult): string {
+    const names = results.method_names || [];
+    if (names.length === 0) return '';
+
+    const disambiguated = names.filter(m => m.disambiguated).length;
+    return `   🏷️  Method names: ${names.length} mapped${disambiguated > 0 ? ` (${disambiguated} disambiguated)` : ''} → ${this.paths.getRelativePath(path.join(this.paths.outputRootPath, 'method-names.json'))}\n`;
+  }
+
+  private formatContextDebt(results: RefactorResult): string {
+    const todos = results.context_todos || [];
+    if (todos.length === 0) return '';
+
+    return [
+      `   🧵 Context TODOs (migration debt): ${todos.length}`,
+      ...todos.map(t => `      - ${t.file}:${t.line} ${t.function}`),
+      '',
+    ].join('\n');
+  }
+
+  private formatValueAdapters(results: RefactorResult): string {
+    const adapters = results.value_adapters || [];
+    if (adapters.length === 0) return '';
+
+    return [
+      `   🔌 Adapters for functions used as values: ${adapters.length}`,
+      ...adapters.map(a => `      - ${a.symbol} ${a.original_signature} → ${a.renamed_to} ${a.new_signature} (${a.adapter_path})`),
+      '',
+    ].join('\n');
+  }
+
+  private formatRouteMounts(results: RefactorResult): string {
+    const mounts = results.route_mounts || [];
+    if (mounts.length === 0) return '';
+
+    return [
+      `   🔌 Generated routes (${mounts[0].framework}): ${mounts.length} modules - add one call where the router is built`,
+      ...mounts.map(m => `      - ${m.module}: import "${m.import_path}", ${m.call}${m.mount ? ` (${m.mount})` : ''}`),
+      '',
+    ].join('\n');
+  }
+
+  private formatModuleConfigs(results: RefactorResult): string {
+    const configs = results.module_configs || [];
+    if (configs.length === 0) return '';
+
+    return [
+      `   ⚙️  Module configs: ${configs.length} modules - populate each in the composition root`,
+      ...configs.flatMap(c => [
+        `      - ${c.module}: ${c.keys} keys in ${c.package}, ${c.rewritten} env reads rewritten${c.remaining.length > 0 ? `, ${c.remaining.length} reads left for review` : ''}`,
+        ...c.remaining.map(site => `        ${site.expression} (${site.location.file}:${site.location.line})`),
+        ...c.wiring.split('\n').map(line => `        ${line}`),
+      ]),
+      '',
+    ].join('\n');
+  }
+
+  private formatDomainEvents(results: RefactorResult): string {
+    const generated = results.domain_events || [];
+    if (generated.length === 0) return '';
+
+    return [
+      `   📣 Domain events: ${generated.reduce((sum, g) => sum + g.events.length, 0)} - subscribe the handlers in the composition root`,
+      ...generated.map(g => `      - ${g.module}: ${g.events.join(', ')} in ${g.package}${g.bus ? ` (event bus: ${g.bus})` : ''}`),
+      '',
+    ].join('\n');
+  }
+
+  private formatGoModules(results: RefactorResult): string {
+    const generated = results.go_modules || [];
+    if (generated.length === 0) return '';
+
+    return [
+      `   📦 Go modules (${generated[0].layout}): ${generated.length} - run go mod tidy in each once the code moved in`,
+      ...generated.map(g => `      - ${g.module}: ${g.module_path}${g.files.length > 0 ? ` (${g.files.join(', ')})` : ' (go.mod already present)'}`),
+      '',
+    ].join('\n');
+  }
+
+  private formatNonExtractableWarnings(results: RefactorResult): string {
+    const warnings = results.non_extractable_queries || [];
+    if (warnings.length === 0) return '';
+
+    return [
+      `   ⚠️  Non-extractable SQL (kept as-is): ${warnings.length}`,
+      ...warnings.map(w => `      - ${w.file} ${w.function}: ${w.reason}`),
+      '',
+    ].join('\n');
   }
 
   /**
-   * Generate actual refactor plan based on discovered boundaries
+   * サンプリングしたドメインマップから生成された探索用の計画ではリファクタリングしない
    */
-  async generateRefactorPlan(planPath: string): Promise<RefactorAgentResult> {
+  protected assertPlanNotExploratory(): void {
+    const reason = exploratoryPlanReason(this.projectRoot);
+    if (reason) throw new Error(reason);
+  }
+
+  /**
+   * Generate the refactor patches from plan.json: modules in the order of the
+   * migration phases, each source file with the target files the plan assigned it
+   */
+  async generateRefactorPlan(planPath: string = this.paths.planJsonPath): Promise<RefactorAgentResult> {
     console.log('🔧 Generating refactor plan from architectural analysis...');
+    this.assertPlanNotExploratory();
     
-    // Load the architectural plan
-    const planContent = fsSync.readFileSync(planPath, 'utf8');
-    
-    // Load domain map to get discovered boundaries
-    const domainMapPath = path.join(this.projectRoot, '.vibeflow', 'domain-map.json');
-    if (!fsSync.existsSync(domainMapPath)) {
-      throw new Error('Domain map not found. Run boundary discovery first.');
+    // Load the architectural plan (plan.md is rendered from it)
+    let content: string;
+    try {
+      content = fsSync.readFileSync(planPath, 'utf8');
+    } catch (error) {
+      throw new Error(`Plan not found or unreadable: ${planPath} (${getErrorMessage(error)}). Run "vf plan" first.`);
     }
+    const architecturalPlan = parsePlan(content, planPath) as unknown as ArchitecturalPlan;
+    if ((architecturalPlan.schema_version ?? PLAN_SCHEMA_VERSION) > PLAN_SCHEMA_VERSION) {
+      throw new Error(`plan.json has schema version ${architecturalPlan.schema_version}; this vibeflow reads up to ${PLAN_SCHEMA_VERSION}`);
+    }
+    const malformed = planStructureIssues(architecturalPlan);
+    if (malformed.length > 0) {
+      throw new InputParseError('plan', `${malformed.map(issue => `${issue.subject}: ${issue.message}`).join('; ')} (fix plan.json or rerun "vf plan")`, planPath);
+    }
+
+    // Established modules are kept as they are
+    const established = architecturalPlan.modules.filter(m => m.status === 'established');
+    if (established.length > 0) {
+      console.log(`   Skipping established modules: ${established.map(m => m.name).join(', ')}`);
+    }
+    const phaseOrder = architecturalPlan.migration_strategy.phases.flatMap(phase => phase.modules);
+    const rank = (name: string) => (phaseOrder.includes(name) ? phaseOrder.indexOf(name) : phaseOrder.length);
+    const modules = architecturalPlan.modules
+      .filter(m => m.status !== 'established')
+      .sort((a, b) => rank(a.name) - rank(b.name));
     
-    const domainMap = JSON.parse(fsSync.readFileSync(domainMapPath, 'utf8'));
-    const boundaries = domainMap.boundaries;
-    
-    // Generate actual refactor patches based on boundaries
+    // Generate actual refactor patches based on the planned modules
     const patches: RefactorPatch[] = [];
     
-    for (let i = 0; i < boundaries.length; i++) {
-      const boundary = boundaries[i];
+    for (let i = 0; i < modules.length; i++) {
+      const module = modules[i];
       const patchId = (i + 1).toString().padStart(3, '0');
+      // Plans written before target_files: the same layout, derived here
+      const targetFiles = module.target_files ?? planTargetFiles(module.name, module.current_state.files, this.layout.style);
       
-      // Create patches for each file in the boundary
-      for (const file of boundary.files) {
-        const changes: PatchChange[] = [
-          {
-            type: 'create',
-            target_path: `internal/${boundary.name}/domain/${path.basename(file, '.go')}_entity.go`,
-            description: `Extract ${boundary.name} domain entity from ${file}`,
-          },
-          {
-            type: 'create',
-            target_path: `internal/${boundary.name}/usecase/${boundary.name}_service.go`,
-            description: `Create ${boundary.name} service with business logic`,
-          },
-          {
-            type: 'create',
-            target_path: `internal/${boundary.name}/infrastructure/${boundary.name}_repository.go`,
-            description: `Create ${boundary.name} repository implementation`,
-          },
-          {
-            type: 'create',
-            target_path: `internal/${boundary.name}/handler/${boundary.name}_handler.go`,
-            description: `Create ${boundary.name} HTTP handler`,
-          },
-        ];
-        
+      // Create patches for each file in the module
+      for (const { source, targets } of targetFiles) {
         patches.push({
-          id: `${patchId}_${path.basename(file, '.go')}`,
-          target_file: file,
-          changes,
-          dependencies: boundary.dependencies || [],
+          id: `${patchId}_${path.basename(source, '.go')}`,
+          target_file: source,
+          changes: targets.map(target => ({ type: 'create' as const, target_path: target.path, description: target.description })),
+          dependencies: module.dependencies.map(dep => dep.module),
           test_requirements: [
-            `internal/${boundary.name}/test/${boundary.name}_test.go`,
-            `internal/${boundary.name}/test/${boundary.name}_integration_test.go`,
+            `internal/${module.name}/test/${module.name}_test.go`,
+            `internal/${module.name}/test/${module.name}_integration_test.go`,
           ],
         });
       }
@@ -348,14 +1968,14 @@ ${originalCode}
     const plan: RefactorPlan = {
       summary: {
         total_patches: patches.length,
-        target_modules: boundaries.map((b: any) => b.name),
+        target_modules: modules.map(m => m.name),
         estimated_time: `${Math.ceil(patches.length * 2)}~${Math.ceil(patches.length * 3)} minutes`,
       },
       patches,
     };
 
     const outputPath = this.paths.patchesDir;
-    const manifestPath = `${outputPath}/manifest.json`;
+    const manifestPath = path.join(outputPath, 'manifest.json');
 
     // Create patches directory
     if (!fsSync.existsSync(outputPath)) {
@@ -364,12 +1984,94 @@ ${originalCode}
 
     fsSync.writeFileSync(manifestPath, JSON.stringify(plan, null, 2));
 
-    console.log(`📝 Generated ${patches.length} refactor patches for ${boundaries.length} modules`);
-    console.log(`   Target modules: ${boundaries.map((b: any) => b.name).join(', ')}`);
+    console.log(`📝 Generated ${patches.length} refactor patches for ${modules.length} modules`);
+    console.log(`   Target modules: ${modules.map(m => m.name).join(', ')}`);
 
     return {
       plan,
       outputPath,
     };
   }
-}
\ No newline at end of file
+}
+
+function llmCallOutcome(category: FailureCategory): LlmCallOutcome {
+  if (category === 'llm-malformed-response') return 'malformed';
+  if (category === 'verification-failure') return 'verification-failed';
+  return 'failed';
+}
+
+/**
+ * Combine results of chunked transformations; files generated for the same
+ * path are joined with their imports merged
+ */
+/**
+ * Reason to refuse a module whose files were only analyzed syntax-only, or null to process it
+ */
+export function degradedModuleRefusal(boundary: DomainBoundary, allowDegraded: boolean): string | null {
+  const degraded = boundary.degraded_files ?? [];
+  if (degraded.length === 0) return null;
+
+  const files = degraded.map(d => d.file).join(', ');
+  if (allowDegraded) {
+    console.warn(`  ⚠️  ${boundary.name} contains degraded files (${files}); continuing with --allow-degraded`);
+    return null;
+  }
+  return `Module ${boundary.name} contains files whose package failed to load (${files}: ${degraded[0].error ?? 'analysis: degraded'}). Fix the package or pass --allow-degraded`;
+}
+
+export function mergeRefactoredFiles(results: RefactoredFile[]): RefactoredFile {
+  const mergeByPath = <T extends { path: string; content: string }>(items: T[]): T[] => {
+    const byPath = new Map<string, T>();
+    for (const item of items) {
+      const existing = byPath.get(item.path);
+      byPath.set(item.path, existing ? { ...existing, content: joinGoSources(existing.content, item.content) } : item);
+    }
+    return [...byPath.values()];
+  };
+
+  return {
+    refactored_files: mergeByPath(results.flatMap(r => r.refactored_files)),
+    interfaces: mergeByPath(results.flatMap(r => r.interfaces)),
+    tests: mergeByPath(results.flatMap(r => r.tests)),
+    ...(results.some(r => r.method_names) ? { method_names: results.flatMap(r => r.method_names ?? []) } : {}),
+    // One chunk generated from templates downgrades the whole file
+    ...(results.some(r => r.generation)
+      ? { generation: results.find(r => r.generation?.method === 'template-fallback')?.generation ?? results.find(r => r.generation)!.generation }
+      : {}),
+  };
+}
+
+function joinGoSources(first: string, second: string): string {
+  const importPattern = /^import\s*(?:\(([\s\S]*?)\)|("[^"]+"))\s*$/m;
+  const importsOf = (source: string): string[] => {
+    const match = source.match(importPattern);
+    if (!match) return [];
+    return (match[1] ?? match[2]).split('\n').map(l => l.trim()).filter(Boolean);
+  };
+
+  const imports = [...new Set([...importsOf(first), ...importsOf(second)])];
+  const secondBody = second.replace(/^package\s+\w+\s*$/m, '').replace(importPattern, '').trim();
+  const firstWithImports = imports.length === 0
+    ? first
+    : importPattern.test(first)
+      ? first.replace(importPattern, `import (\n\t${imports.join('\n\t')}\n)`)
+      : first.replace(/^(package\s+\w+)\s*$/m, `$1\n\nimport (\n\t${imports.join('\n\t')}\n)`);
+
+  return `${firstWithImports.trimEnd()}\n\n${secondBody}\n`;
+}
+
+/**
+ * Checks LLM output must pass before it is written: every generated Go file parses
+ * (see checkGoSyntax). Interface snippets are exempt since the prompt asks for bare declarations.
+ *
+ * @throws VerificationError naming the first offending file
+ */
+export function verifyGeneratedOutput(result: RefactoredFile): void {
+  for (const output of [...result.refactored_files, ...result.tests]) {
+    if (!output.path.endsWith('.go')) continue;
+    const error = checkGoSyntax(output.content);
+    if (error) {
+      throw new VerificationError(`${error.line}:${error.column}: ${error.message} (truncated or malformed output)`, output.path);
+    }
+  }
+}
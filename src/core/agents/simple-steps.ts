@@ -1,3 +1,4 @@
+import * as path from 'path';
 import { z } from 'zod';
 
 // シンプルなステップ定義（ビルドを通すための暫定実装）
@@ -23,7 +24,7 @@ export const boundaryExtractionStep = {
     console.log('  (BoundaryAgent implementation pending)');
     
     return {
-      domainMapPath: `${projectPath}/domain-map.json`,
+      domainMapPath: path.join(projectPath, 'domain-map.json'),
       domainMap: { boundaries: [] },
     };
   },
@@ -80,9 +81,9 @@ export const refactoringStep = {
     console.log('  (RefactorAgent implementation pending)');
     
     return {
-      patchesDirectory: `${projectPath}/.refactor`,
+      patchesDirectory: path.join(projectPath, '.refactor'),
       patchCount: 0,
-      metricsPath: `${projectPath}/metrics.json`,
+      metricsPath: path.join(projectPath, 'metrics.json'),
     };
   },
 };
@@ -107,10 +108,10 @@ export const testSynthesisStep = {
     console.log('  (TestSynthAgent implementation pending)');
     
     return {
-      testDirectory: `${projectPath}/__generated__`,
+      testDirectory: path.join(projectPath, '__generated__'),
       relocatedTests: 0,
       generatedTests: 0,
-      coverageDiffPath: `${projectPath}/coverage-diff.json`,
+      coverageDiffPath: path.join(projectPath, 'coverage-diff.json'),
     };
   },
 };
@@ -143,7 +144,7 @@ export const migrationRunnerStep = {
     console.log('  (MigrationRunner implementation pending)');
     
     return {
-      resultPath: `${projectPath}/result.json`,
+      resultPath: path.join(projectPath, 'result.json'),
       success: true,
       appliedPatches: 0,
       testsPassed: true,
@@ -1,14 +1,20 @@
 import * as fs from 'fs';
 import * as path from 'path';
 import { RefactorPlan, RefactorPatch } from './refactor-agent.js';
-import { VibeFlowConfig } from '../types/config.js';
+import { TestOwnership, VibeFlowConfig } from '../types/config.js';
 import { ConfigLoader } from '../utils/config-loader.js';
 import { CodeAnalyzer, FileInfo } from '../utils/code-analyzer.js';
+import { HelperImportRewrite, loadTestSupport, rewriteHelperImports } from '../utils/test-support.js';
+import { testsOwnedBy } from '../utils/test-ownership.js';
+import { DomainMapWriter } from '../utils/domain-map-writer.js';
+import { toPosixPath } from '../utils/workspace-paths.js';
 
 export interface TestSynthResult {
   test_relocations: TestRelocation[];
   generated_tests: GeneratedTest[];
   coverage_improvement: CoverageImprovement;
+  /** Relocated test files whose shared-helper imports were pointed at the planned test-support packages */
+  helper_imports_rewritten: number;
   outputPath: string;
 }
 
@@ -17,6 +23,10 @@ export interface TestRelocation {
   new_location: string;
   module: string;
   dependencies_updated: string[];
+  /** Helper calls moved to the module's or the shared test-support package */
+  helper_imports?: HelperImportRewrite[];
+  /** Test source with the helper imports rewritten */
+  content?: string;
 }
 
 export interface GeneratedTest {
@@ -34,10 +44,12 @@ export interface CoverageImprovement {
 }
 
 export class TestSynthAgent {
+  private projectRoot: string;
   private config: VibeFlowConfig;
   private analyzer: CodeAnalyzer;
 
   constructor(projectRoot: string, configPath?: string) {
+    this.projectRoot = projectRoot;
     this.config = ConfigLoader.loadVibeFlowConfig(configPath);
     this.analyzer = new CodeAnalyzer(projectRoot);
   }
@@ -53,6 +65,7 @@ export class TestSynthAgent {
     
     // 3. テストファイルの移行計画
     const testRelocations = this.planTestRelocations(refactorPlan, existingTests);
+    const helperImportsRewritten = this.rewriteHelperImports(testRelocations);
     
     // 4. 不足しているテストの生成
     const generatedTests = this.generateMissingTests(refactorPlan, existingTests);
@@ -66,15 +79,20 @@ export class TestSynthAgent {
       test_relocations: testRelocations,
       generated_tests: generatedTests,
       coverage_improvement: coverageImprovement,
+      helper_imports_rewritten: helperImportsRewritten,
       outputPath,
     });
     
     console.log(`✅ テスト合成完了: ${generatedTests.length}個の新規テスト、${testRelocations.length}個のテスト移行`);
+    if (helperImportsRewritten > 0) {
+      console.log(`🧪 テストヘルパーの import を書き換えたテスト: ${helperImportsRewritten}個`);
+    }
     
     return {
       test_relocations: testRelocations,
       generated_tests: generatedTests,
       coverage_improvement: coverageImprovement,
+      helper_imports_rewritten: helperImportsRewritten,
       outputPath,
     };
   }
@@ -142,14 +160,21 @@ export class TestSynthAgent {
 
   private planTestRelocations(refactorPlan: RefactorPlan, existingTests: FileInfo[]): TestRelocation[] {
     const relocations: TestRelocation[] = [];
+    // Test ownership recorded by discovery; without it, tests are matched to patches by file name
+    const ownership = new DomainMapWriter(this.projectRoot).load()?.test_ownership;
+    const relocated = new Set<string>();
     
     // Find tests that need to be moved based on refactor patches
     for (const patch of refactorPlan.patches) {
       if (patch.changes.some(c => c.type === 'create') && patch.target_file.startsWith('internal/')) {
         const module = this.extractModuleName(patch.target_file);
-        const relatedTests = this.findRelatedTests(patch.target_file, existingTests);
+        const relatedTests = ownership
+          ? this.findOwnedTests(module, ownership, existingTests)
+          : this.findRelatedTests(patch.target_file, existingTests);
         
         for (const test of relatedTests) {
+          if (relocated.has(test.relativePath)) continue;
+          relocated.add(test.relativePath);
           const newLocation = this.generateTestLocation(module, test.relativePath);
           relocations.push({
             original_test: test.relativePath,
@@ -164,6 +189,31 @@ export class TestSynthAgent {
     return relocations;
   }
 
+  /**
+   * Point the shared-helper calls of relocated tests at the test-support packages
+   * of plan.json; returns the number of test files rewritten
+   */
+  private rewriteHelperImports(relocations: TestRelocation[]): number {
+    const testSupport = loadTestSupport(this.projectRoot);
+    if (!testSupport) return 0;
+
+    const rewritten = new Set<string>();
+    for (const relocation of relocations) {
+      let content: string;
+      try {
+        content = fs.readFileSync(path.join(this.projectRoot, relocation.original_test), 'utf8');
+      } catch {
+        continue;
+      }
+      const result = rewriteHelperImports(content, testSupport);
+      if (result.rewrites.length === 0) continue;
+      relocation.helper_imports = result.rewrites;
+      relocation.content = result.content;
+      rewritten.add(relocation.original_test);
+    }
+    return rewritten.size;
+  }
+
   private generateMissingTests(refactorPlan: RefactorPlan, existingTests: FileInfo[]): GeneratedTest[] {
     const generatedTests: GeneratedTest[] = [];
     const existingTestPaths = new Set(existingTests.map(t => t.relativePath));
@@ -220,6 +270,14 @@ export class TestSynthAgent {
     );
   }
 
+  /**
+   * Existing tests discovery assigned to the module (domain-map.json test_ownership)
+   */
+  private findOwnedTests(module: string, ownership: TestOwnership[], existingTests: FileInfo[]): FileInfo[] {
+    const owned = new Set(testsOwnedBy(ownership, module));
+    return existingTests.filter(test => owned.has(toPosixPath(test.relativePath)));
+  }
+
   private generateTestLocation(module: string, originalPath: string): string {
     const fileName = path.basename(originalPath);
     return `internal/${module}/test/${fileName}`;
@@ -553,9 +611,16 @@ func TestE2ETestSuite(t *testing.T) {
       fs.writeFileSync(test.file, test.content);
     }
 
+    // Save relocated tests whose helper imports were rewritten
+    for (const relocation of result.test_relocations.filter(r => r.content !== undefined)) {
+      const relocatedPath = path.join(result.outputPath, 'relocated', relocation.new_location);
+      fs.mkdirSync(path.dirname(relocatedPath), { recursive: true });
+      fs.writeFileSync(relocatedPath, relocation.content!);
+    }
+
     // Save test relocation plan
     const relocationPath = path.join(result.outputPath, 'test_relocations.json');
-    fs.writeFileSync(relocationPath, JSON.stringify(result.test_relocations, null, 2));
+    fs.writeFileSync(relocationPath, JSON.stringify(result.test_relocations.map(({ content, ...relocation }) => relocation), null, 2));
 
     // Save coverage improvement analysis
     const coveragePath = path.join(result.outputPath, 'coverage_improvement.json');
@@ -566,6 +631,7 @@ func TestE2ETestSuite(t *testing.T) {
     const summary = {
       generated_tests: result.generated_tests.length,
       test_relocations: result.test_relocations.length,
+      helper_imports_rewritten: result.helper_imports_rewritten,
       coverage_improvement: result.coverage_improvement,
       files_created: result.generated_tests.map(t => t.file),
     };
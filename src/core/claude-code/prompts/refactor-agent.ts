@@ -0,0 +1,72 @@
+/**
+ * File transformation prompt of RefactorAgent. {{name}} placeholders are filled
+ * by renderPromptTemplate; prompt.refactorTemplate can replace the whole text.
+ */
+export const REFACTOR_TRANSFORMATION_PROMPT = `
+Transform this {{language}} code to Domain-Driven Design architecture suitable for the "{{boundary}}" bounded context:
+
+## Current Situation
+- File: {{file}}
+- Target bounded context: {{boundary}}
+- Business capability: {{description}}
+- Ubiquitous language terms: {{ubiquitous_language}}
+- Context dependencies: {{dependencies}}
+{{previous_attempt}}
+## Required Transformations
+1. **Preserve Business Language**: Use exact business terminology from the bounded context
+2. **Domain Layer Separation**: Extract pure business logic that captures domain rules and invariants
+3. **Application Services**: Create use cases that orchestrate domain operations
+4. **Infrastructure Independence**: Separate infrastructure concerns from business logic
+5. **Aggregate Boundaries**: Respect business transaction and consistency boundaries
+6. **Value Objects**: Model business concepts that don't have identity but have business rules
+
+IMPORTANT: Maintain the business meaning and terminology identified in this bounded context. Do not introduce technical abstractions that obscure business concepts.
+
+## Output Format
+Return in JSON format:
+{
+  "refactored_files": [
+    {
+      "path": "internal/{{boundary}}/domain/{{boundary}}.go",
+      "content": "package domain\\n\\n// Domain logic...",
+      "description": "{{boundary}} domain entity"
+    },
+    {
+      "path": "internal/{{boundary}}/usecase/{{boundary}}_service.go",
+      "content": "package usecase\\n\\n// Use case...",
+      "description": "{{boundary}} service use case"
+    }
+  ],
+  "interfaces": [
+    {
+      "name": "{{boundary}}Repository",
+      "path": "internal/{{boundary}}/domain/repository.go",
+      "content": "type {{boundary}}Repository interface { ... }"
+    }
+  ],
+  "tests": [
+    {
+      "path": "internal/{{boundary}}/domain/{{boundary}}_test.go",
+      "content": "package domain\\n\\nfunc Test{{boundary}}..."
+    }
+  ]
+}
+
+{{architecture}}
+{{domain_model}}
+{{context}}
+{{repository}}
+{{http_conventions}}
+{{method_naming}}
+{{context_threading}}
+{{annotations}}
+{{function_values}}
+{{configuration}}
+{{ports}}
+{{domain_events}}
+
+Original code:
+\`\`\`{{language}}
+{{code}}
+\`\`\`
+`;
@@ -2,6 +2,8 @@
  * 業務ロジック抽出・移行のための型定義
  */
 
+import { MethodProcessingSummary } from '../utils/file-processing.js';
+
 export interface BusinessRule {
   type: 'validation' | 'calculation' | 'constraint' | 'workflow' | 'transformation';
   description: string;
@@ -10,6 +12,12 @@ export interface BusinessRule {
     file: string;
     line: number;
     function?: string;
+    /** Exact range (UTF-8 byte columns/offsets) when the rule's code was located */
+    column?: number;
+    offset?: number;
+    end_line?: number;
+    end_column?: number;
+    end_offset?: number;
   };
   dependencies: string[];
   complexity: 'low' | 'medium' | 'high';
@@ -188,6 +196,8 @@ export interface BusinessLogicMigrationExecuteResult {
   }>;
   aiProcessedFiles: number;
   staticAnalysisFiles: number;
+  /** 処理方式ごとのファイル数・失敗数・平均処理時間（この実行で処理したファイルのみ） */
+  processing?: MethodProcessingSummary[];
   totalBusinessRules: number;
   warnings: string[];
   errors: string[];
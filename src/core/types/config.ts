@@ -3,6 +3,8 @@ import { z } from 'zod';
 export const ModuleConfigSchema = z.object({
   description: z.string(),
   paths: z.array(z.string()),
+  // Owning team, shown by `vf status --modules`
+  team: z.string().optional(),
 });
 
 export const ProjectConfigSchema = z.object({
@@ -61,6 +63,112 @@ export const MigrationConfigSchema = z.object({
   phases: z.record(MigrationPhaseSchema),
 });
 
+export const PromptConfigSchema = z.object({
+  contextBudgetTokens: z.number().int().positive().optional(),
+  inlineBodyMaxLines: z.number().int().nonnegative().optional(),
+  // File (relative to the project root) replacing the built-in refactor prompt; {{name}} placeholders as in prompts/refactor-agent.ts
+  refactorTemplate: z.string().min(1).optional(),
+});
+
+export const RepositoryConfigSchema = z.object({
+  style: z.enum(['plain', 'sqlc']).optional(),
+  schema: z.array(z.string()).optional(),
+});
+
+// Per-call limits for LLM requests (seconds)
+export const LlmConfigSchema = z.object({
+  requestTimeout: z.number().positive().optional(),
+  idleTimeout: z.number().positive().optional(),
+  // Models tried in order: a file whose response is malformed or fails verification is retried once with the next one
+  escalation: z.array(z.string().min(1)).min(1).optional(),
+  // Keep responses and prompt inputs in .vibeflow/llm-cache: identical prompts are not sent again, `vf prompt-bench --replay` re-renders the inputs
+  cache: z.boolean().optional(),
+});
+
+export const BackupConfigSchema = z.object({
+  // Backup runs kept by `vf clean --backups`
+  keepLast: z.number().int().nonnegative().optional(),
+});
+
+export const MetricsConfigSchema = z.object({
+  // Most recent runs kept in performance.json (and their artifact snapshots)
+  retainRuns: z.number().int().positive().optional(),
+});
+
+export const RefactorConfigSchema = z.object({
+  // Thread ctx context.Context through extracted call chains (true) or keep legacy signatures (false)
+  addContext: z.boolean().optional(),
+});
+
+export const DebtConfigSchema = z.object({
+  // Comment markers collected by the debt inventory (default: TODO, FIXME, HACK, XXX)
+  markers: z.array(z.string().min(1)).optional(),
+});
+
+export const FilesConfigSchema = z.object({
+  // Line endings of generated files: keep those of the file being replaced (default), or force lf / crlf
+  lineEndings: z.enum(['preserve', 'lf', 'crlf']).optional(),
+});
+
+export const HttpConfigSchema = z.object({
+  // Router framework of generated handlers (default: detected from the project's imports)
+  framework: z.enum(['echo', 'gin', 'chi', 'stdlib']).optional(),
+  // Response helpers generated handlers call, as <package dir>.<Func> (e.g. pkg/httpx.RespondJSON)
+  respondJSON: z.string().regex(/^[\w./-]+\.\w+$/).optional(),
+  respondError: z.string().regex(/^[\w./-]+\.\w+$/).optional(),
+  // Package directory that mounts the router (default: the package that creates it)
+  mount: z.string().optional(),
+});
+
+export const TestsConfigSchema = z.object({
+  // Mutation smoke check of `vf refactor --with-mutation-check`
+  mutation: z.object({
+    // Mutants tried per module (default 20)
+    maxMutants: z.number().int().positive().optional(),
+    // Seconds the module's tests may run against one mutant (default 60)
+    timeout: z.number().positive().optional(),
+  }).optional(),
+});
+
+export const AutonomyConfigSchema = z.object({
+  // Highest risk tier vf refactor --apply applies without --force (unset: no gate)
+  maxAutoApplyTier: z.enum(['auto-apply', 'apply-with-review', 'manual-only']).optional(),
+  // Upper bounds (0-100) of the risk score of each tier (defaults 25 and 60)
+  thresholds: z.object({
+    autoApply: z.number().min(0).max(100).optional(),
+    applyWithReview: z.number().min(0).max(100).optional(),
+  }).optional(),
+});
+
+// Anti-corruption layers recommended by vf plan (see anti-corruption-layer.ts)
+export const AclConfigSchema = z.object({
+  // References between two modules from which a layer is recommended instead of a plain interface (default 10)
+  minReferences: z.number().int().positive().optional(),
+});
+
+const ISO_DATE = /^\d{4}-\d{2}-\d{2}$/;
+
+// Calendar constraints of the migration phases (see phase-schedule.ts); boundary.yaml overrides vibeflow.config.yaml
+export const ScheduleConfigSchema = z.object({
+  // First day of the plan (default: the day it is generated)
+  start: z.string().regex(ISO_DATE).optional(),
+  // Estimated effort days completed per calendar week (default 5)
+  velocity: z.number().positive().optional(),
+  // Weeks per sprint (default 2)
+  sprintWeeks: z.number().positive().optional(),
+  // Upper bound of a phase's estimated effort, e.g. 2-sprints, 3-weeks, 10-days
+  maxPhaseEffort: z.string().regex(/^\d+(?:\.\d+)?-(?:sprints?|weeks?|days?)$/).optional(),
+  // Module → date by which it must be done
+  deadlines: z.record(z.string().regex(ISO_DATE)).optional(),
+  // Team → when it can work on the migration: a quarter (2025-Q3) or a date range
+  teamAvailability: z.record(z.union([
+    z.string().regex(/^\d{4}-Q[1-4]$/),
+    z.object({ from: z.string().regex(ISO_DATE), to: z.string().regex(ISO_DATE) }),
+  ])).optional(),
+  // Module → team, for modules without a team in boundaries.target_modules
+  teams: z.record(z.string()).optional(),
+});
+
 export const VibeFlowConfigSchema = z.object({
   project: ProjectConfigSchema,
   analysis: AnalysisConfigSchema,
@@ -68,6 +176,21 @@ export const VibeFlowConfigSchema = z.object({
   refactoring: RefactoringConfigSchema,
   output: OutputConfigSchema,
   migration: MigrationConfigSchema,
+  prompt: PromptConfigSchema.optional(),
+  repository: RepositoryConfigSchema.optional(),
+  llm: LlmConfigSchema.optional(),
+  backups: BackupConfigSchema.optional(),
+  metrics: MetricsConfigSchema.optional(),
+  refactor: RefactorConfigSchema.optional(),
+  debt: DebtConfigSchema.optional(),
+  files: FilesConfigSchema.optional(),
+  http: HttpConfigSchema.optional(),
+  schedule: ScheduleConfigSchema.optional(),
+  tests: TestsConfigSchema.optional(),
+  autonomy: AutonomyConfigSchema.optional(),
+  acl: AclConfigSchema.optional(),
+  // Directories of unrelated products discovered independently (vf discover --scope)
+  scopes: z.array(z.string().min(1)).optional(),
 });
 
 export type ModuleConfig = z.infer<typeof ModuleConfigSchema>;
@@ -78,6 +201,19 @@ export type RefactoringConfig = z.infer<typeof RefactoringConfigSchema>;
 export type OutputConfig = z.infer<typeof OutputConfigSchema>;
 export type MigrationPhase = z.infer<typeof MigrationPhaseSchema>;
 export type MigrationConfig = z.infer<typeof MigrationConfigSchema>;
+export type PromptConfig = z.infer<typeof PromptConfigSchema>;
+export type RepositoryConfig = z.infer<typeof RepositoryConfigSchema>;
+export type LlmConfig = z.infer<typeof LlmConfigSchema>;
+export type BackupConfig = z.infer<typeof BackupConfigSchema>;
+export type MetricsConfig = z.infer<typeof MetricsConfigSchema>;
+export type RefactorConfig = z.infer<typeof RefactorConfigSchema>;
+export type DebtConfig = z.infer<typeof DebtConfigSchema>;
+export type FilesConfig = z.infer<typeof FilesConfigSchema>;
+export type HttpConfig = z.infer<typeof HttpConfigSchema>;
+export type ScheduleConfig = z.infer<typeof ScheduleConfigSchema>;
+export type TestsConfig = z.infer<typeof TestsConfigSchema>;
+export type AutonomyConfig = z.infer<typeof AutonomyConfigSchema>;
+export type AclConfig = z.infer<typeof AclConfigSchema>;
 export type VibeFlowConfig = z.infer<typeof VibeFlowConfigSchema>;
 
 // Boundary YAML types
@@ -87,17 +223,217 @@ export const BoundaryModuleSchema = z.object({
   publishes_events: z.array(z.string()).optional(),
   subscribes_to: z.array(z.string()).optional(),
   depends_on: z.array(z.string()).optional(),
+  // Stable ID of the discovered boundary named after this module (renamed with vf boundaries edit)
+  id: z.string().min(1).optional(),
+  // Files pinned to this module whatever the clustering decides (vf boundaries edit)
+  files: z.array(z.string().min(1)).optional(),
+  // Files, package directories or import paths known to belong to this module; clustering groups the rest around them
+  seeds: z.array(z.string().min(1)).optional(),
+});
+
+// Negative constraints the clustering and planner must respect
+export const BoundaryConstraintsSchema = z.object({
+  mustSeparate: z.array(z.array(z.string()).min(2)).optional(),
+  mustMerge: z.array(z.array(z.string()).min(2)).optional(),
+  maxModules: z.number().int().positive().optional(),
+  // [from, to]: module `from` must not depend on module `to`
+  forbiddenDependencies: z.array(z.tuple([z.string(), z.string()])).optional(),
+  // Tables that must never be owned by the same module
+  forbiddenTableCombinations: z.array(z.array(z.string()).min(2)).optional(),
+});
+
+// Git history mined for files that change together (an extra clustering signal)
+export const CoChangeConfigSchema = z.object({
+  enabled: z.boolean().optional(),
+  // Passed to git log --since, e.g. "2 years ago"
+  since: z.string().optional(),
+  maxCommits: z.number().int().positive().optional(),
+  maxFilesPerCommit: z.number().int().positive().optional(),
+  minCoChanges: z.number().int().positive().optional(),
+  weight: z.number().min(0).max(1).optional(),
+});
+
+// Call graph whose call edges drive clustering and per-boundary cohesion/coupling
+export const CallGraphConfigSchema = z.object({
+  enabled: z.boolean().optional(),
+  // cha: every method an interface call may reach; rta: only types instantiated from main packages
+  algorithm: z.enum(['cha', 'rta']).optional(),
+  // golang.org/x/tools/cmd/callgraph binary
+  command: z.string().min(1).optional(),
+});
+
+// Embeddings of each file's path, identifiers and comments; their cosine similarity adds to the
+// clustering edge weight, so a domain spread over utility-named directories still groups (see semantic-embeddings.ts)
+export const EmbeddingsConfigSchema = z.object({
+  enabled: z.boolean().optional(),
+  // ollama: local model server; openai: OpenAI-compatible embeddings API
+  provider: z.enum(['ollama', 'openai']),
+  model: z.string().min(1).optional(),
+  // Base URL (default http://localhost:11434 or https://api.openai.com)
+  endpoint: z.string().url().optional(),
+  // Environment variable holding the API key of the openai provider (default OPENAI_API_KEY)
+  apiKeyEnv: z.string().min(1).optional(),
+  weight: z.number().min(0).max(1).optional(),
+  // Cosine similarity below which two files count as unrelated
+  minSimilarity: z.number().min(0).max(0.99).optional(),
+});
+
+// How types and functions are grouped by the strength of their dependencies
+export const ClusteringConfigSchema = z.object({
+  // distance: greedy threshold grouping; louvain/leiden: modularity-based community detection
+  algorithm: z.enum(['distance', 'louvain', 'leiden']).optional(),
+  // louvain/leiden: above 1 gives more, smaller modules; below 1 fewer, larger ones
+  resolution: z.number().positive().optional(),
+  // Granularity after clustering: split along package directories and merge the most coupled modules
+  targetModules: z.number().int().positive().optional(),
+  minFiles: z.number().int().positive().optional(),
+  maxFiles: z.number().int().positive().optional(),
+});
+
+// How generic boundary names (module_3) are replaced (see boundary-naming.ts)
+export const NamingConfigSchema = z.object({
+  // YAML file of canonical domain terms; when set, names are only taken from it
+  glossary: z.string().min(1).optional(),
+  // Ask the LLM for names (not with --offline); otherwise the glossary and identifiers name boundaries
+  llm: z.boolean().optional(),
+});
+
+// Frontend code calling the Go API (see frontend-api-calls.ts); directories may lie outside the project root
+export const FrontendConfigSchema = z.object({
+  paths: z.array(z.string().min(1)).min(1),
+  // Path prefix a proxy mounts the API under, removed from request paths (e.g. /api)
+  stripPrefix: z.string().optional(),
+});
+
+// Thresholds that make `vf discover` fail (exit code 1) with .vibeflow/discovery-gates.json, for CI
+export const DiscoveryGatesSchema = z.object({
+  minCohesion: z.number().min(0).max(1).optional(),
+  maxCoupling: z.number().min(0).max(1).optional(),
+  maxFilesPerModule: z.number().int().positive().optional(),
+});
+
+// Owning team of each boundary: CODEOWNERS, else git blame statistics (see team-ownership.ts)
+export const OwnershipConfigSchema = z.object({
+  enabled: z.boolean().optional(),
+  // Team → git author emails or names, so blame statistics name a team instead of a person
+  teams: z.record(z.array(z.string().min(1))).optional(),
+  // Files blamed per boundary not covered by CODEOWNERS
+  maxBlameFiles: z.number().int().positive().optional(),
+});
+
+// Third-party imports per boundary (see third-party-deps.ts)
+export const ThirdPartyConfigSchema = z.object({
+  // Boundaries importing a dependency directly before it is flagged as shared (default 3)
+  sharedModules: z.number().int().min(2).optional(),
 });
 
 export const BoundaryConfigSchema = z.object({
-  modules: z.record(BoundaryModuleSchema),
+  modules: z.record(BoundaryModuleSchema).default({}),
+  constraints: BoundaryConstraintsSchema.optional(),
+  schedule: ScheduleConfigSchema.optional(),
+  // Directories of modules extracted by hand; directories with their own go.mod are found without it
+  established: z.array(z.string().min(1)).optional(),
+  coChange: CoChangeConfigSchema.optional(),
+  callGraph: CallGraphConfigSchema.optional(),
+  embeddings: EmbeddingsConfigSchema.optional(),
+  clustering: ClusteringConfigSchema.optional(),
+  naming: NamingConfigSchema.optional(),
+  frontend: FrontendConfigSchema.optional(),
+  gates: DiscoveryGatesSchema.optional(),
+  ownership: OwnershipConfigSchema.optional(),
+  thirdParty: ThirdPartyConfigSchema.optional(),
 });
 
 export type BoundaryModule = z.infer<typeof BoundaryModuleSchema>;
+export type BoundaryConstraints = z.infer<typeof BoundaryConstraintsSchema>;
+export type CoChangeConfig = z.infer<typeof CoChangeConfigSchema>;
+export type CallGraphConfig = z.infer<typeof CallGraphConfigSchema>;
+export type EmbeddingsConfig = z.infer<typeof EmbeddingsConfigSchema>;
+export type ClusteringConfig = z.infer<typeof ClusteringConfigSchema>;
+export type NamingConfig = z.infer<typeof NamingConfigSchema>;
+export type FrontendConfig = z.infer<typeof FrontendConfigSchema>;
+export type DiscoveryGates = z.infer<typeof DiscoveryGatesSchema>;
+export type OwnershipConfig = z.infer<typeof OwnershipConfigSchema>;
 export type BoundaryConfig = z.infer<typeof BoundaryConfigSchema>;
 
 // Domain map output types
+// Debt markers of a boundary; test and generated files are counted apart from `total`
+export const BoundaryDebtSchema = z.object({
+  total: z.number(),
+  by_category: z.record(z.number()),
+  test: z.number(),
+  generated: z.number(),
+});
+
+// What a discovered boundary's confidence is made of, each in [0, 1] (see boundary-confidence.ts)
+export const ConfidenceBreakdownSchema = z.object({
+  score: z.number(),
+  structural_cohesion: z.number(),
+  naming_similarity: z.number(),
+  data_ownership: z.number(),
+  // File overlap (Jaccard) with the closest boundary of the manual analysis; hybrid mode (vibeflow.config.yaml) only
+  manual_overlap: z.number().optional(),
+  // Share of the files only analyzed syntax-only; lowers the score
+  degraded_share: z.number().optional(),
+  rationale: z.string(),
+});
+
+// `//vf:` directive from the source (see source-annotations.ts)
+export const SourceAnnotationSchema = z.object({
+  directive: z.enum(['boundary', 'keep-together', 'ignore', 'freeze']),
+  argument: z.string().optional(),
+  symbol: z.string(),
+  declaration: z.enum(['func', 'method', 'type', 'var', 'const']),
+  file: z.string(),
+  line: z.number(),
+  members: z.array(z.object({ symbol: z.string(), file: z.string() })).optional(),
+  source: z.literal('annotation'),
+});
+
+// Where a boundary's name came from when discovery gave it a generic one
+// Frontend request reaching a handler of the boundary; its route must stay compatible
+export const ExternalConsumerSchema = z.object({
+  file: z.string(),
+  line: z.number().int(),
+  client: z.enum(['fetch', 'axios']),
+  method: z.string(),
+  // Requested path, {param} for template expressions
+  path: z.string(),
+  // Matched Go route: "GET /orders/{id}"
+  route: z.string(),
+  handler: z.string(),
+});
+
+// Where a boundary's business logic lives (see domain-model-style.ts); the refactoring strategy follows it
+export const DomainModelSchema = z.object({
+  style: z.enum(['rich', 'anemic', 'mixed']),
+  entities: z.array(z.string()),
+  // Behaviour methods of the entities (Type.Method)
+  entity_methods: z.array(z.string()),
+  // Service functions setting entity fields (transaction scripts)
+  script_functions: z.array(z.string()),
+  rationale: z.string(),
+});
+
+export const BoundaryNamingSchema = z.object({
+  source: z.enum(['llm', 'glossary', 'identifiers']),
+  previous: z.string(),
+  rationale: z.string(),
+});
+
+// Team owning most of a boundary: by CODEOWNERS rules over its files, else by blamed lines
+export const BoundaryOwnershipSchema = z.object({
+  team: z.string(),
+  source: z.enum(['codeowners', 'blame']),
+  // Share of the files (codeowners) or blamed lines (blame) the team owns
+  share: z.number(),
+  // Every owner with its share, largest first
+  owners: z.array(z.object({ owner: z.string(), share: z.number() })),
+});
+
 export const DomainBoundarySchema = z.object({
+  // Stable across runs and renames; artifacts that refer to a boundary use this instead of the name
+  id: z.string().optional(),
   name: z.string(),
   description: z.string(),
   ubiquitousLanguage: z.array(z.string()).optional(),
@@ -111,17 +447,251 @@ export const DomainBoundarySchema = z.object({
     external: z.array(z.string()).optional(),
   }).optional(),
   circular_dependencies: z.array(z.string()).optional(),
+  tables: z.array(z.string()).optional(),
+  merged_from: z.array(z.string()).optional(),
   metrics: z.object({
     cohesion: z.number(),
     coupling: z.number(),
     complexity: z.string(),
   }).optional(),
+  // TODO/FIXME/HACK markers and not-implemented stubs (see debt-inventory.ts)
+  debt: BoundaryDebtSchema.optional(),
+  // Files of packages that failed to load; only imports and declarations were analyzed
+  degraded_files: z.array(z.object({
+    file: z.string(),
+    analysis: z.literal('degraded'),
+    error: z.string().optional(),
+  })).optional(),
+  // Import path and declared package name of each file; the name need not match the directory
+  file_packages: z.array(z.object({
+    file: z.string(),
+    import_path: z.string(),
+    package: z.string(),
+  })).optional(),
+  // Qualified references (pkg.Name) of each file to the packages of another boundary
+  file_coupling: z.array(z.object({
+    file: z.string(),
+    boundary: z.string(),
+    references: z.number().int(),
+  })).optional(),
+  // Directives of the boundary's files and symbols pinned to it with //vf:boundary; they override clustering
+  annotations: z.array(SourceAnnotationSchema).optional(),
+  // Module extracted before vibeflow (own go.mod or `established:` of boundary.yaml): fixed, never
+  // re-clustered or refactored, but part of the dependency graph (see established-modules.ts)
+  status: z.literal('established').optional(),
+  module_root: z.string().optional(),
+  module_path: z.string().optional(),
+  // go.mod of the go.work member holding most of the boundary's files; go_mods lists every member it spans
+  go_mod: z.string().optional(),
+  go_mods: z.array(z.string()).optional(),
+  confidence_breakdown: ConfidenceBreakdownSchema.optional(),
+  // How a generic clustering name (module_3) was replaced (see boundary-naming.ts)
+  naming: BoundaryNamingSchema.optional(),
+  domain_model: DomainModelSchema.optional(),
+  external_consumers: z.array(ExternalConsumerSchema).optional(),
+  ownership: BoundaryOwnershipSchema.optional(),
   // Backward compatibility
   cohesion_score: z.number().optional(),
   coupling_score: z.number().optional(),
+  // cohesion_score/coupling_score stand in from the discovery confidence: no call graph measured them
+  scores_estimated: z.literal(true).optional(),
+});
+
+export const PackageLoadErrorSchema = z.object({
+  package: z.string(),
+  files: z.array(z.string()),
+  errors: z.array(z.string()),
+});
+
+export const PackageCoverageSchema = z.object({
+  package: z.string(),
+  analyzed: z.number(),
+  total: z.number(),
+  coverage: z.number(),
+});
+
+export const DomainMapSamplingSchema = z.object({
+  // Requested fraction of files (0-1) and/or file cap
+  rate: z.number().optional(),
+  max_files: z.number().optional(),
+  analyzed_files: z.number(),
+  total_files: z.number(),
+  skipped_files: z.number(),
+  // Half-width of the 95% interval around overall_cohesion / overall_coupling
+  error_band: z.object({
+    cohesion: z.number(),
+    coupling: z.number(),
+  }),
+  // Packages with the smallest share of analyzed files, lowest first
+  low_coverage_packages: z.array(PackageCoverageSchema),
+});
+
+// Exported declaration of a shared test-helper package and the boundaries that use it (see test-support.ts)
+export const TestHelperUsageSchema = z.object({
+  // `<package dir>.<symbol>`
+  id: z.string(),
+  symbol: z.string(),
+  kind: z.enum(['func', 'type', 'value']),
+  // Package directory, import path and declared name
+  package: z.string(),
+  import_path: z.string(),
+  package_name: z.string(),
+  file: z.string(),
+  line: z.number(),
+  // Boundaries whose tests call it, and the test files that do
+  used_by: z.array(z.string()),
+  test_files: z.array(z.string()),
+  // Boundaries whose entities it builds or references (directly or through other helpers)
+  references: z.array(z.string()),
+  placement: z.enum(['module', 'shared', 'manual-review']),
+  owner: z.string().optional(),
+  reason: z.string(),
+});
+
+// Existing _test.go file and the boundary of the code it exercises (see test-ownership.ts)
+export const TestOwnershipSchema = z.object({
+  file: z.string(),
+  module: z.string(),
+  // symbols: most references to the boundary's declarations (imports and same-package identifiers);
+  // source: no references, the boundary of <name>.go; directory: the boundary with the most files next to it
+  basis: z.enum(['symbols', 'source', 'directory']),
+  // References to the declarations of each boundary
+  references: z.record(z.number()),
+});
+
+// Utility package kept out of the domain boundaries: logging, config and error helpers, pkg/utils,
+// internal/common, or a leaf package most boundaries import (see shared-kernel.ts)
+export const SharedKernelPackageSchema = z.object({
+  // Package directory, import path and declared name
+  dir: z.string(),
+  import_path: z.string(),
+  package: z.string(),
+  files: z.array(z.string()),
+  kind: z.enum(['utility', 'logging', 'config', 'errors', 'common']),
+  // name: the directory says what it is; fan-in: imported by most boundaries without importing any of them
+  reason: z.enum(['name', 'fan-in']),
+  // Boundaries whose files import it
+  used_by: z.array(z.string()),
+});
+
+// HTTP route found in the router registrations and the boundary of its handler (see http-routes.ts)
+export const HttpRouteSchema = z.object({
+  method: z.string(),
+  // Full path with group prefixes, {name} parameters
+  path: z.string(),
+  handler: z.string(),
+  // File declaring the handler, file registering the route
+  file: z.string().optional(),
+  registered_in: z.string(),
+  framework: z.enum(['echo', 'gin', 'chi', 'stdlib']),
+  module: z.string().optional(),
+});
+
+export const GenerateDirectiveSchema = z.object({
+  file: z.string(),
+  line: z.number(),
+  command: z.string(),
+});
+
+// Generated Go file (see generated-code.ts), left out of clustering; the module owning its
+// go:generate source regenerates it after refactoring
+export const GeneratedFileSchema = z.object({
+  file: z.string(),
+  // File name (*.pb.go, *_gen.go, mock_*.go) or "Code generated ... DO NOT EDIT." header
+  reason: z.enum(['protobuf', 'gen-suffix', 'mock', 'header']),
+  // Tool of the header, lowercased (mockgen, protoc-gen-go, stringer)
+  generator: z.string().optional(),
+  directive: GenerateDirectiveSchema.optional(),
+  module: z.string().optional(),
+});
+
+export const CycleEdgeSchema = z.object({
+  from: z.string(),
+  to: z.string(),
+  // Call sites from the files of `from` into those of `to`
+  calls: z.number(),
+  sites: z.array(z.object({
+    caller_file: z.string(),
+    caller: z.string(),
+    callee_file: z.string(),
+    callee: z.string(),
+    count: z.number(),
+  })),
+});
+
+// Dependency cycle between boundaries (see boundary-cycles.ts); modules in call order, the last calling the first
+export const BoundaryCycleSchema = z.object({
+  modules: z.array(z.string()),
+  edges: z.array(CycleEdgeSchema),
+  // Cheapest edge to cut, inverted with an interface (queries only) or replaced by an event
+  break_at: z.object({
+    from: z.string(),
+    to: z.string(),
+    calls: z.number(),
+    remedy: z.enum(['interface', 'event']),
+  }),
+});
+
+// Packages coupled through package-level mutable variables or init() side effects (see global-coupling.ts):
+// invisible beyond the import, but splitting the packages changes behaviour
+export const GlobalCouplingSchema = z.object({
+  // Package directory relying on the state, and the package declaring it (or whose init() it relies on)
+  from: z.string(),
+  to: z.string(),
+  // global-state: reads or writes variables someone assigns; init-side-effect: blank import of a package with init(), or calls from init()
+  kind: z.enum(['global-state', 'init-side-effect']),
+  // Variables or functions of `to` (init() for a blank import), and the files of `from` using them
+  symbols: z.array(z.string()),
+  files: z.array(z.string()),
+  // The packages share nothing else: no function, type or constant of `to` is used outside init()
+  exclusive: z.boolean(),
+  from_module: z.string().optional(),
+  to_module: z.string().optional(),
+});
+
+// Function reaching into several boundaries through calls and table access (see orchestrators.ts)
+export const OrchestratorCandidateSchema = z.object({
+  // Function or Type.Method
+  function: z.string(),
+  file: z.string(),
+  // Boundary the clustering assigned it to
+  module: z.string().optional(),
+  // saga: writes the data of two or more modules, so a failure halfway needs compensation
+  kind: z.enum(['saga', 'orchestrator']),
+  touches: z.array(z.object({
+    module: z.string(),
+    calls: z.number().int(),
+    tables: z.array(z.string()),
+    writes: z.boolean(),
+  })),
+});
+
+// Third-party dependency family (an SDK or client library) and the boundaries importing it
+export const ThirdPartyDependencySchema = z.object({
+  // Host, owner and repository without major version: github.com/aws/aws-sdk-go-v2
+  name: z.string(),
+  // go.mod modules of the family and the packages imported from them
+  modules: z.array(z.string()),
+  packages: z.array(z.string()),
+  used_by: z.array(z.object({ module: z.string(), files: z.array(z.string()) })),
+  // Imported directly by many boundaries: a candidate to hide behind an adapter
+  shared: z.boolean(),
+});
+
+// Topic a module publishes and another subscribes to (see message-topics.ts): an integration point, not coupling
+export const AsyncEdgeSchema = z.object({
+  topic: z.string(),
+  broker: z.enum(['kafka', 'nats', 'sqs']),
+  // Publishing and subscribing module
+  from: z.string(),
+  to: z.string(),
+  // Files with the publish and subscribe call sites
+  files: z.array(z.string()),
 });
 
 export const DomainMapSchema = z.object({
+  // Format of the file (see DOMAIN_MAP_SCHEMA_VERSION); absent in maps written before versioning
+  schema_version: z.number().int().optional(),
   project: z.string(),
   language: z.string(),
   analyzed_at: z.string(),
@@ -132,7 +702,50 @@ export const DomainMapSchema = z.object({
     overall_coupling: z.number(),
     modularity_score: z.number(),
   }),
+  // Packages analyzed syntax-only instead of aborting discovery
+  load_errors: z.array(PackageLoadErrorSchema).optional(),
+  // Present when only a sample of the files was analyzed (vf discover --sample / --max-files);
+  // plans from such a map are exploratory and cannot drive refactoring
+  sampling: DomainMapSamplingSchema.optional(),
+  // Helpers of shared test packages (testutil/, fixtures only imported by tests) attributed to boundaries
+  test_helpers: z.array(TestHelperUsageSchema).optional(),
+  // Boundary each existing _test.go file belongs to, for relocating the tests with their code
+  test_ownership: z.array(TestOwnershipSchema).optional(),
+  // Shared kernel: utility packages that belong to no boundary and stay where they are
+  shared_kernel: z.array(SharedKernelPackageSchema).optional(),
+  // Route → handler → boundary of the project's HTTP router, for handler generation
+  routes: z.array(HttpRouteSchema).optional(),
+  // Message topics between boundaries, for the architect to keep as event contracts
+  async_edges: z.array(AsyncEdgeSchema).optional(),
+  // Generated files excluded from the boundaries, with the go:generate source that produces them
+  generated_code: z.array(GeneratedFileSchema).optional(),
+  // Call cycles between boundaries with the edge to cut (also in .vibeflow/boundary-cycles.json)
+  cycles: z.array(BoundaryCycleSchema).optional(),
+  // Package pairs coupled through mutable globals or init() side effects
+  global_coupling: z.array(GlobalCouplingSchema).optional(),
+  third_party: z.array(ThirdPartyDependencySchema).optional(),
+  orchestrators: z.array(OrchestratorCandidateSchema).optional(),
+  // Directory the map was discovered in, relative to the repository root (vf discover --scope)
+  scope: z.string().optional(),
 });
 
+export type AsyncEdge = z.infer<typeof AsyncEdgeSchema>;
+export type ThirdPartyDependency = z.infer<typeof ThirdPartyDependencySchema>;
+export type OrchestratorCandidate = z.infer<typeof OrchestratorCandidateSchema>;
+export type CycleEdge = z.infer<typeof CycleEdgeSchema>;
+export type BoundaryCycle = z.infer<typeof BoundaryCycleSchema>;
+export type GlobalCoupling = z.infer<typeof GlobalCouplingSchema>;
+export type GenerateDirective = z.infer<typeof GenerateDirectiveSchema>;
+export type GeneratedFile = z.infer<typeof GeneratedFileSchema>;
+export type BoundaryDebt = z.infer<typeof BoundaryDebtSchema>;
+export type BoundaryNaming = z.infer<typeof BoundaryNamingSchema>;
+export type DomainModel = z.infer<typeof DomainModelSchema>;
+export type ExternalConsumer = z.infer<typeof ExternalConsumerSchema>;
+export type ConfidenceBreakdown = z.infer<typeof ConfidenceBreakdownSchema>;
 export type DomainBoundary = z.infer<typeof DomainBoundarySchema>;
-export type DomainMap = z.infer<typeof DomainMapSchema>;
\ No newline at end of file
+export type BoundaryOwnership = z.infer<typeof BoundaryOwnershipSchema>;
+export type DomainMap = z.infer<typeof DomainMapSchema>;
+export type DomainMapSampling = z.infer<typeof DomainMapSamplingSchema>;
+export type TestHelperUsage = z.infer<typeof TestHelperUsageSchema>;
+export type TestOwnership = z.infer<typeof TestOwnershipSchema>;
+export type SharedKernelPackage = z.infer<typeof SharedKernelPackageSchema>;
\ No newline at end of file
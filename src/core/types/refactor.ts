@@ -1,3 +1,35 @@
+import { FailureCategory } from '../utils/error-utils.js';
+import { EscalationRecord } from '../utils/performance-store.js';
+import { ValueCompatibilityCheck } from '../utils/function-values.js';
+import { ModuleRoutes } from '../utils/http-conventions.js';
+import { ConfigAccessSite } from '../utils/config-access.js';
+import { ArchitectureStyle } from '../utils/architecture-style.js';
+
+/**
+ * Legacy function → generated usecase method (see method-naming.ts)
+ */
+export interface MethodNameMapping {
+  module: string;
+  file: string;
+  legacy: string;
+  method: string;
+  /** heuristic: derived from the legacy name/comments, llm: suggested by the model */
+  source: 'heuristic' | 'llm';
+  /** Set when the derived name collided and was made unique */
+  disambiguated?: boolean;
+}
+
+/**
+ * A `context.TODO()` left in generated code where the caller cannot supply a
+ * context yet (see context-threading.ts). Reported as migration debt.
+ */
+export interface ContextTodo {
+  file: string;
+  line: number;
+  /** Enclosing function (Receiver.Method for methods) */
+  function: string;
+}
+
 export interface RefactoredFile {
   refactored_files: {
     path: string;
@@ -13,16 +45,81 @@ export interface RefactoredFile {
     path: string;
     content: string;
   }[];
+  /** Usecase method names used across interface, implementation, handler and tests */
+  method_names?: MethodNameMapping[];
+  /** context.TODO() sites left where callers cannot supply a context yet */
+  context_todos?: ContextTodo[];
+  /** Signature checks of functions the project uses as values (see function-values.ts) */
+  value_checks?: ValueCompatibilityCheck[];
+  /** How the result was produced (see ClaudeCodeClient.queryWithGeneration) */
+  generation?: GenerationInfo;
+}
+
+/**
+ * template-fallback: the LLM was wanted but unavailable, unlike intentional template mode
+ */
+export interface GenerationInfo {
+  method: 'llm' | 'template' | 'template-fallback';
+  /** Why the LLM could not be used (template-fallback only) */
+  fallback_reason?: string;
+  /** Template the result was generated from (template and template-fallback) */
+  template?: { name: string; hash: string };
 }
 
 export interface RefactorResult {
   applied_patches: string[];
-  failed_patches: { file: string; error: string }[];
+  /** category: why the unit failed (invalid-input, llm, llm-malformed-response, verification-failure, io, refused, internal) */
+  failed_patches: { file: string; error: string; category?: FailureCategory }[];
   created_files: string[];
   modified_files: string[];
   deleted_files: string[];
   outputPath: string;
   aiEnhanced?: boolean;
+  /** SQL queries left in Go code because they are built dynamically (repository.style: sqlc) */
+  non_extractable_queries?: { file: string; function: string; reason: string }[];
+  /** Modules skipped by the user while processing; resumable with --resume-skipped */
+  skipped_modules?: string[];
+  /** Modules waiting for async batch results (vf refactor --async-batch / --collect) */
+  pending_modules?: string[];
+  /** Modules with files generated from templates because the LLM was unavailable */
+  fallback_modules?: { module: string; files: number; reason: string }[];
+  /** Files retried with the next model of llm.escalation after a malformed response or failed verification */
+  escalations?: EscalationRecord[];
+  /** Legacy symbol → new method name table, also persisted in .vibeflow/method-names.json */
+  method_names?: MethodNameMapping[];
+  /** context.TODO() sites in generated code (migration debt, refactor.addContext) */
+  context_todos?: ContextTodo[];
+  /** Functions used as values whose changed signature is kept by an adapter */
+  value_adapters?: ValueCompatibilityCheck[];
+  /** Generated routes.go files and how to mount them on the project's router */
+  route_mounts?: ModuleRoutes[];
+  /** Generated per-module config packages and the composition root statement populating each */
+  module_configs?: {
+    module: string;
+    package: string;
+    keys: number;
+    /** os.Getenv reads replaced deterministically */
+    rewritten: number;
+    /** Reads of the module's keys still in generated code */
+    remaining: ConfigAccessSite[];
+    wiring: string;
+  }[];
+  /** Generated events packages of the modules publishing domain events (plan.json domain_events) */
+  domain_events?: {
+    module: string;
+    package: string;
+    events: string[];
+    /** Event bus file, generated with the first events package of the run */
+    bus?: string;
+  }[];
+  /** go.mod of the modules split into their own Go module (plan.json go_modules), and the root go.mod/go.work updates */
+  go_modules?: {
+    module: string;
+    layout: 'multi-module' | 'go-work';
+    module_path: string;
+    /** Files created or updated; empty when the module already had its go.mod */
+    files: string[];
+  }[];
   tokenUsage?: {
     inputTokens: number;
     outputTokens: number;
@@ -31,10 +128,24 @@ export interface RefactorResult {
   };
 }
 
+/**
+ * How code is generated: the LLM with template fallback (auto), or one of them only
+ * (llm: --require-llm, template: --offline)
+ */
+export type GenerationMode = 'auto' | 'llm' | 'template';
+
 export interface ClaudeCodeConfig {
   cwd: string;
   maxTurns: number;
   systemPrompt: string;
+  /** Default: auto */
+  generationMode?: GenerationMode;
+  /** Hard limit for a single call (ms) */
+  requestTimeoutMs?: number;
+  /** Maximum silence between streamed messages (ms) */
+  idleTimeoutMs?: number;
+  /** Package layout of the template outputs; default clean */
+  architectureStyle?: ArchitectureStyle;
 }
 
 export interface CompileResult {
@@ -0,0 +1,350 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { WorkspaceLock } from './workspace-lock.js';
+import { PerformanceStore } from './performance-store.js';
+import { getErrorMessage } from './error-utils.js';
+import { renameWithRetry } from './file-io.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * Version of the analyzers whose results are cached.
+ * Bump whenever CodeAnalyzer or ASTAnalyzer output changes so a new release never serves stale entries.
+ */
+export const ANALYZER_VERSION = 4;
+
+/** On-disk layout of the cache directory */
+const CACHE_FORMAT = 1;
+
+export interface FileSummary {
+  language: string;
+  lines: number;
+  imports: string[];
+  exports: string[];
+  structs?: string[];
+  interfaces?: string[];
+  functions?: string[];
+  classes?: string[];
+}
+
+export interface SymbolEntry {
+  name: string;
+  kind: 'function' | 'struct' | 'interface' | 'class';
+}
+
+export interface DependencyEdge {
+  /** Workspace-relative file */
+  from: string;
+  /** Resolved dependency (file path or package import path) */
+  to: string;
+  /** Import as written in the source */
+  import: string;
+}
+
+export interface FileAnalysis {
+  summary: FileSummary;
+  symbols: SymbolEntry[];
+  edges: DependencyEdge[];
+}
+
+interface CacheEntry<T> {
+  path: string;
+  content_hash: string;
+  analyzer_version: number;
+  /** sha256 over path, content hash, analyzer version and data */
+  checksum: string;
+  data: T;
+}
+
+interface CacheMeta {
+  format: number;
+  analyzer_version: number;
+  hits: number;
+  misses: number;
+  corrupted: number;
+}
+
+export interface AnalysisCacheStats {
+  analyzer_version: number;
+  entries: number;
+  bytes: number;
+  /** Lifetime counters persisted by writers */
+  total_hits: number;
+  total_misses: number;
+  total_corrupted: number;
+  /** Counters of this instance */
+  hits: number;
+  misses: number;
+  corrupted: number;
+}
+
+/**
+ * AnalysisCache - ファイル単位の解析結果キャッシュ
+ *
+ * Entries are keyed by workspace path + content hash + ANALYZER_VERSION and stored
+ * one file per path under .vibeflow/analysis-cache/entries. Writes go through a
+ * temp file + rename so concurrent readers never see partial entries, and only
+ * the process holding analysis-cache.lock writes; others just read.
+ * Corrupted entries (unparseable or checksum mismatch) count as misses and are rebuilt.
+ * Other analyzers keep their own data type under a `namespace` subdirectory, so
+ * `vf cache clear` drops them together.
+ */
+export class AnalysisCache<T = FileAnalysis> {
+  private projectRoot: string;
+  private cacheDir: string;
+  private lock: WorkspaceLock;
+  private pending = new Map<string, CacheEntry<T>>();
+  private invalid = new Set<string>();
+  private counters = { hits: 0, misses: 0, corrupted: 0 };
+  private analyzerVersion: number;
+  private namespace?: string;
+
+  constructor(projectRoot: string, options: { analyzerVersion?: number; namespace?: string } = {}) {
+    this.projectRoot = projectRoot;
+    this.analyzerVersion = options.analyzerVersion ?? ANALYZER_VERSION;
+    this.namespace = options.namespace;
+    this.cacheDir = options.namespace
+      ? path.join(AnalysisCache.cacheDir(projectRoot), options.namespace)
+      : AnalysisCache.cacheDir(projectRoot);
+    this.lock = new WorkspaceLock(projectRoot, 'analysis-cache.lock');
+  }
+
+  static cacheDir(projectRoot: string): string {
+    return path.join(projectRoot, '.vibeflow', 'analysis-cache');
+  }
+
+  static hashContent(content: string): string {
+    return createHash('sha256').update(content).digest('hex');
+  }
+
+  /**
+   * Cached analysis for the file content, or null on a miss
+   */
+  get(filePath: string, contentHash: string): T | null {
+    const key = toPosixPath(filePath);
+    const pending = this.pending.get(key);
+    if (pending && pending.content_hash === contentHash) {
+      this.counters.hits++;
+      return pending.data;
+    }
+
+    const entry = this.readEntry(key);
+    if (entry && entry.content_hash === contentHash && entry.analyzer_version === this.analyzerVersion) {
+      this.counters.hits++;
+      return entry.data;
+    }
+
+    this.counters.misses++;
+    return null;
+  }
+
+  getFileSummary(this: AnalysisCache<FileAnalysis>, filePath: string, contentHash: string): FileSummary | null {
+    return this.get(filePath, contentHash)?.summary ?? null;
+  }
+
+  getSymbolTable(this: AnalysisCache<FileAnalysis>, filePath: string, contentHash: string): SymbolEntry[] | null {
+    return this.get(filePath, contentHash)?.symbols ?? null;
+  }
+
+  getDependencyEdges(this: AnalysisCache<FileAnalysis>, filePath: string, contentHash: string): DependencyEdge[] | null {
+    return this.get(filePath, contentHash)?.edges ?? null;
+  }
+
+  /**
+   * Queue an entry; written by flush()
+   */
+  put(filePath: string, contentHash: string, data: T): void {
+    const key = toPosixPath(filePath);
+    this.pending.set(key, {
+      path: key,
+      content_hash: contentHash,
+      analyzer_version: this.analyzerVersion,
+      checksum: checksum(key, contentHash, this.analyzerVersion, data),
+      data,
+    });
+  }
+
+  /**
+   * Cached analysis or the result of `compute`, which is queued for writing
+   */
+  getOrCompute(filePath: string, content: string, compute: () => T): T {
+    const contentHash = AnalysisCache.hashContent(content);
+    const cached = this.get(filePath, contentHash);
+    if (cached) return cached;
+
+    const data = compute();
+    this.put(filePath, contentHash, data);
+    return data;
+  }
+
+  /**
+   * Write queued entries and counters if this process can become the writer,
+   * and report hit/miss counts to the active run's performance_metrics.
+   *
+   * @returns whether the queued entries were written
+   */
+  flush(): boolean {
+    const counters = this.counters;
+    this.counters = { hits: 0, misses: 0, corrupted: 0 };
+    this.recordRunMetrics(counters);
+    if (this.pending.size === 0 && this.invalid.size === 0 && counters.hits + counters.misses === 0) {
+      return true;
+    }
+
+    try {
+      if (!this.lock.acquire('analysis-cache')) return false;
+    } catch {
+      return false;
+    }
+
+    try {
+      const meta = this.prepareDirectory();
+      for (const key of this.invalid) {
+        if (!this.pending.has(key)) fs.rmSync(this.entryPath(key), { force: true });
+      }
+      for (const [key, entry] of this.pending) {
+        writeAtomic(this.entryPath(key), JSON.stringify(entry));
+      }
+
+      meta.hits += counters.hits;
+      meta.misses += counters.misses;
+      meta.corrupted += counters.corrupted;
+      writeAtomic(this.metaPath, JSON.stringify(meta, null, 2));
+      return true;
+    } catch (error) {
+      console.warn(`⚠️  Analysis cache not written: ${getErrorMessage(error)}`);
+      return false;
+    } finally {
+      this.pending.clear();
+      this.invalid.clear();
+      this.lock.release();
+    }
+  }
+
+  stats(): AnalysisCacheStats {
+    const meta = this.readMeta();
+    const entriesDir = path.join(this.cacheDir, 'entries');
+    let entries = 0;
+    let bytes = 0;
+
+    if (fs.existsSync(entriesDir)) {
+      for (const name of fs.readdirSync(entriesDir)) {
+        if (!name.endsWith('.json')) continue;
+        entries++;
+        bytes += fs.statSync(path.join(entriesDir, name)).size;
+      }
+    }
+
+    return {
+      analyzer_version: meta?.analyzer_version ?? this.analyzerVersion,
+      entries,
+      bytes,
+      total_hits: meta?.hits ?? 0,
+      total_misses: meta?.misses ?? 0,
+      total_corrupted: meta?.corrupted ?? 0,
+      ...this.counters,
+    };
+  }
+
+  /**
+   * Remove every entry
+   *
+   * @throws when another process is writing the cache
+   */
+  clear(): void {
+    if (!this.lock.acquire('analysis-cache clear')) {
+      throw new Error(`Analysis cache is being written by another process (${this.lock.path})`);
+    }
+    try {
+      fs.rmSync(this.cacheDir, { recursive: true, force: true });
+      this.pending.clear();
+      this.invalid.clear();
+    } finally {
+      this.lock.release();
+    }
+  }
+
+  private get metaPath(): string {
+    return path.join(this.cacheDir, 'meta.json');
+  }
+
+  private entryPath(key: string): string {
+    return path.join(this.cacheDir, 'entries', `${createHash('sha256').update(key).digest('hex')}.json`);
+  }
+
+  private readMeta(): CacheMeta | null {
+    try {
+      return JSON.parse(fs.readFileSync(this.metaPath, 'utf8'));
+    } catch {
+      return null;
+    }
+  }
+
+  /**
+   * Drop all entries when the analyzer version or layout changed (writer only)
+   */
+  private prepareDirectory(): CacheMeta {
+    const meta = this.readMeta();
+    if (meta && meta.format === CACHE_FORMAT && meta.analyzer_version === this.analyzerVersion) {
+      return meta;
+    }
+
+    fs.rmSync(path.join(this.cacheDir, 'entries'), { recursive: true, force: true });
+    fs.mkdirSync(path.join(this.cacheDir, 'entries'), { recursive: true });
+    return { format: CACHE_FORMAT, analyzer_version: this.analyzerVersion, hits: 0, misses: 0, corrupted: 0 };
+  }
+
+  private readEntry(key: string): CacheEntry<T> | null {
+    const entryPath = this.entryPath(key);
+    let raw: string;
+    try {
+      if (!fs.existsSync(entryPath)) return null;
+      raw = fs.readFileSync(entryPath, 'utf8');
+    } catch {
+      return null;
+    }
+
+    try {
+      const entry = JSON.parse(raw) as CacheEntry<T>;
+      if (entry.path === key && entry.checksum === checksum(entry.path, entry.content_hash, entry.analyzer_version, entry.data)) {
+        return entry;
+      }
+    } catch {
+      // Fall through to corruption handling
+    }
+
+    this.counters.corrupted++;
+    this.invalid.add(key);
+    return null;
+  }
+
+  private recordRunMetrics(counters: { hits: number; misses: number; corrupted: number }): void {
+    if (counters.hits + counters.misses === 0) return;
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      const runId = store.getActiveRunId();
+      if (runId === undefined) return;
+      const labels = this.namespace ? { cache: this.namespace } : undefined;
+      store.recordMetric(runId, 'analysis_cache_hits', counters.hits, labels);
+      store.recordMetric(runId, 'analysis_cache_misses', counters.misses, labels);
+      if (counters.corrupted > 0) {
+        store.recordMetric(runId, 'analysis_cache_corrupted', counters.corrupted, labels);
+      }
+    } catch {
+      // Metrics are best-effort
+    }
+  }
+}
+
+function checksum(key: string, contentHash: string, analyzerVersion: number, data: unknown): string {
+  return createHash('sha256')
+    .update(`${key}\0${contentHash}\0${analyzerVersion}\0${JSON.stringify(data)}`)
+    .digest('hex');
+}
+
+function writeAtomic(filePath: string, content: string): void {
+  fs.mkdirSync(path.dirname(filePath), { recursive: true });
+  const tmpPath = `${filePath}.${process.pid}.tmp`;
+  fs.writeFileSync(tmpPath, content);
+  renameWithRetry(tmpPath, filePath);
+}
@@ -0,0 +1,237 @@
+import { execFileSync } from 'child_process';
+import * as fs from 'fs';
+import * as path from 'path';
+import { DatabaseAccess, GoFunction, GoInterface, GoStruct } from './ast-analyzer.js';
+import { CallGraph, receiverType } from './call-graph.js';
+import { GoPackage } from './go-packages.js';
+import { TableOwnership } from './table-ownership.js';
+import { TypeRelations } from './type-relations.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** sqlite3 command-line shell, looked up on PATH */
+const SQLITE_COMMAND = 'sqlite3';
+
+export type AnalysisNodeKind = 'module' | 'package' | 'file' | 'struct' | 'interface' | 'function' | 'method' | 'table';
+
+/**
+ * contains: module or package → file; declares: file → symbol; imports: file → package;
+ * calls: function → function; embeds / implements: type → type;
+ * maps: struct → table; accesses: function → table (detail is the statement)
+ */
+export type AnalysisEdgeKind = 'contains' | 'declares' | 'imports' | 'calls' | 'embeds' | 'implements' | 'maps' | 'accesses';
+
+export interface AnalysisNode {
+  id: number;
+  kind: AnalysisNodeKind;
+  /** Methods are `Type.Method`, packages their import path */
+  name: string;
+  file?: string;
+  line?: number;
+  /** Import path of the package declaring the file or symbol */
+  package?: string;
+  /** Boundary owning the file or symbol */
+  module?: string;
+}
+
+export interface AnalysisEdge {
+  source: number;
+  target: number;
+  kind: AnalysisEdgeKind;
+  /** Call sites for calls, 1 otherwise */
+  weight: number;
+  detail?: string;
+}
+
+export interface AnalysisGraph {
+  nodes: AnalysisNode[];
+  edges: AnalysisEdge[];
+}
+
+export interface AnalysisGraphInput {
+  packages: GoPackage[];
+  structs: GoStruct[];
+  interfaces: GoInterface[];
+  functions: GoFunction[];
+  database_access: DatabaseAccess[];
+  call_graph?: CallGraph;
+  type_relations?: TypeRelations;
+  table_ownership?: TableOwnership;
+  boundaries: { name: string; files: string[] }[];
+}
+
+/**
+ * Everything discovery knows about the code as one graph: modules, packages,
+ * files and their symbols as nodes; imports, calls, type relations and table
+ * access as edges. domain-map.json only keeps the per-boundary summary.
+ */
+export function buildAnalysisGraph(projectRoot: string, input: AnalysisGraphInput): AnalysisGraph {
+  const relative = (file: string) => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+  const nodes: AnalysisNode[] = [];
+  const ids = new Map<string, number>();
+  const edges = new Map<string, AnalysisEdge>();
+
+  const packageOf = new Map<string, string>();
+  for (const pkg of input.packages) {
+    for (const file of pkg.files) packageOf.set(file, pkg.import_path);
+  }
+  const moduleOf = new Map<string, string>();
+  for (const boundary of input.boundaries) {
+    for (const file of boundary.files.map(relative)) {
+      if (!moduleOf.has(file)) moduleOf.set(file, boundary.name);
+    }
+  }
+
+  const node = (kind: AnalysisNodeKind, name: string, file?: string, line?: number): number => {
+    const key = `${kind}\n${name}\n${file ?? ''}`;
+    const existing = ids.get(key);
+    if (existing !== undefined) {
+      if (line !== undefined && nodes[existing - 1].line === undefined) nodes[existing - 1].line = line;
+      return existing;
+    }
+    const id = nodes.length + 1;
+    const pkg = file ? packageOf.get(file) : undefined;
+    const module = file ? moduleOf.get(file) : undefined;
+    nodes.push({
+      id,
+      kind,
+      name,
+      ...(file ? { file } : {}),
+      ...(line !== undefined ? { line } : {}),
+      ...(pkg ? { package: pkg } : {}),
+      ...(module ? { module } : {}),
+    });
+    ids.set(key, id);
+    if (file && kind !== 'file') edge(node('file', file, file), id, 'declares');
+    return id;
+  };
+  const edge = (source: number, target: number, kind: AnalysisEdgeKind, weight = 1, detail?: string) => {
+    const key = `${source}\n${target}\n${kind}\n${detail ?? ''}`;
+    const existing = edges.get(key);
+    if (existing) existing.weight += kind === 'calls' ? weight : 0;
+    else edges.set(key, { source, target, kind, weight, ...(detail ? { detail } : {}) });
+  };
+  // Functions and methods by file and name without the receiver, for table access
+  const holders = new Map<string, number>();
+  const callable = (file: string, name: string, type?: string, line?: number) => {
+    const id = node(type ? 'method' : 'function', type ? `${type}.${name}` : name, file, line);
+    if (!holders.has(`${file}\n${name}`)) holders.set(`${file}\n${name}`, id);
+    return id;
+  };
+  const holder = (file: string, name: string) => holders.get(`${file}\n${name}`) ?? callable(file, name);
+  const typeNode = (file: string, name: string) =>
+    ids.get(`interface\n${name}\n${file}`) ?? node('struct', name, file);
+
+  for (const boundary of input.boundaries) {
+    const module = node('module', boundary.name);
+    for (const file of boundary.files.map(relative)) edge(module, node('file', file, file), 'contains');
+  }
+  for (const pkg of input.packages) {
+    const id = node('package', pkg.import_path);
+    for (const file of pkg.files) edge(id, node('file', file, file), 'contains');
+  }
+
+  const projectPackages = new Set(input.packages.map(pkg => pkg.import_path));
+  const declarations = [...input.structs, ...input.interfaces, ...input.functions];
+  for (const declaration of declarations) {
+    const file = relative(declaration.file);
+    if (declaration.type === 'function') {
+      callable(file, declaration.name, receiverType(declaration.receiver), declaration.line);
+    } else {
+      node(declaration.type, declaration.name, file, declaration.line);
+    }
+    for (const dependency of declaration.dependencies.filter(dependency => projectPackages.has(dependency))) {
+      edge(node('file', file, file), node('package', dependency), 'imports');
+    }
+  }
+
+  for (const call of input.call_graph?.edges ?? []) {
+    edge(
+      callable(relative(call.caller_file), call.caller, call.caller_type),
+      callable(relative(call.callee_file), call.callee, call.callee_type),
+      'calls',
+      call.count
+    );
+  }
+  for (const relation of input.type_relations?.relations ?? []) {
+    const to = relation.kind === 'implements'
+      ? node('interface', relation.to_type, relative(relation.to_file))
+      : typeNode(relative(relation.to_file), relation.to_type);
+    edge(typeNode(relative(relation.from_file), relation.from_type), to, relation.kind);
+  }
+  for (const access of input.table_ownership?.access ?? []) {
+    const file = relative(access.file);
+    const table = node('table', access.table);
+    if (access.via === 'struct') {
+      edge(node('struct', access.symbol, file), table, 'maps');
+    } else {
+      edge(holder(file, access.symbol), table, 'accesses', 1, access.operation);
+    }
+  }
+  for (const access of input.database_access) {
+    edge(holder(relative(access.file), access.function), node('table', access.table), 'accesses', 1, access.operation);
+  }
+
+  return { nodes, edges: [...edges.values()] };
+}
+
+/**
+ * SQL script creating the nodes and edges tables (plus the named_edges view
+ * joining both) and inserting the graph in one transaction
+ */
+export function renderAnalysisSql(graph: AnalysisGraph, meta: Record<string, string> = {}): string {
+  const lines = [
+    'BEGIN TRANSACTION;',
+    'CREATE TABLE meta (key TEXT PRIMARY KEY, value TEXT);',
+    'CREATE TABLE nodes (id INTEGER PRIMARY KEY, kind TEXT NOT NULL, name TEXT NOT NULL, file TEXT, line INTEGER, package TEXT, module TEXT);',
+    'CREATE TABLE edges (source INTEGER NOT NULL REFERENCES nodes(id), target INTEGER NOT NULL REFERENCES nodes(id), kind TEXT NOT NULL, weight INTEGER NOT NULL, detail TEXT);',
+    'CREATE INDEX nodes_kind_name ON nodes (kind, name);',
+    'CREATE INDEX nodes_file ON nodes (file);',
+    'CREATE INDEX edges_source ON edges (source, kind);',
+    'CREATE INDEX edges_target ON edges (target, kind);',
+    'CREATE VIEW named_edges AS SELECT e.kind, ' +
+      's.kind AS source_kind, s.name AS source, s.file AS source_file, s.module AS source_module, ' +
+      't.kind AS target_kind, t.name AS target, t.file AS target_file, t.module AS target_module, ' +
+      'e.weight, e.detail FROM edges e JOIN nodes s ON s.id = e.source JOIN nodes t ON t.id = e.target;',
+  ];
+  for (const [key, value] of Object.entries(meta)) {
+    lines.push(`INSERT INTO meta VALUES (${sqlValue(key)}, ${sqlValue(value)});`);
+  }
+  for (const n of graph.nodes) {
+    lines.push(`INSERT INTO nodes VALUES (${[n.id, n.kind, n.name, n.file, n.line, n.package, n.module].map(sqlValue).join(', ')});`);
+  }
+  for (const e of graph.edges) {
+    lines.push(`INSERT INTO edges VALUES (${[e.source, e.target, e.kind, e.weight, e.detail].map(sqlValue).join(', ')});`);
+  }
+  lines.push('COMMIT;');
+  return `${lines.join('\n')}\n`;
+}
+
+/**
+ * Replace the database at dbPath with the graph through the sqlite3 shell.
+ * Without sqlite3 the script is written next to it (`analysis.sql`) so it can
+ * be loaded later with `sqlite3 analysis.db < analysis.sql`; returns the path written.
+ */
+export function writeAnalysisDatabase(dbPath: string, graph: AnalysisGraph, meta: Record<string, string> = {}): string {
+  const sql = renderAnalysisSql(graph, meta);
+  const scriptPath = dbPath.replace(/\.db$/, '') + '.sql';
+  fs.mkdirSync(path.dirname(dbPath), { recursive: true });
+
+  const tmpPath = `${dbPath}.${process.pid}.tmp`;
+  try {
+    fs.rmSync(tmpPath, { force: true });
+    execFileSync(SQLITE_COMMAND, [tmpPath], { input: sql, stdio: ['pipe', 'ignore', 'pipe'], timeout: 600000 });
+    fs.renameSync(tmpPath, dbPath);
+    fs.rmSync(scriptPath, { force: true });
+    return dbPath;
+  } catch {
+    fs.rmSync(tmpPath, { force: true });
+    fs.writeFileSync(scriptPath, sql);
+    return scriptPath;
+  }
+}
+
+function sqlValue(value: string | number | undefined): string {
+  if (value === undefined) return 'NULL';
+  if (typeof value === 'number') return String(value);
+  return `'${value.replace(/'/g, "''")}'`;
+}
@@ -0,0 +1,172 @@
+import * as path from 'path';
+import { ModulePort, ModulePorts } from './module-ports.js';
+
+/** Qualified references between two modules from which a layer is recommended */
+export const DEFAULT_ACL_MIN_REFERENCES = 10;
+
+/**
+ * Qualified references (pkg.Name) of one module's files to another module
+ */
+export interface ModuleCoupling {
+  from: string;
+  to: string;
+  references: number;
+  files: string[];
+}
+
+/**
+ * Upstream type the downstream module keeps its own copy of
+ */
+export interface TranslatedType {
+  /** package.Name of the upstream type */
+  upstream: string;
+  /** Name of the copy in the layer */
+  local: string;
+  /** dto: a struct with the fields the module uses; type: a named type converted directly */
+  kind: 'dto' | 'type';
+  /** Function of the layer converting the upstream value into the copy */
+  translator: string;
+}
+
+/**
+ * Anti-corruption layer one module builds in front of another: a facade with
+ * the operations it needs and translators into its own copies of the types
+ */
+export interface AclSide {
+  /** Module the layer lives in */
+  module: string;
+  upstream: string;
+  references: number;
+  /** Files of the module referencing the upstream; they switch to the facade */
+  files: string[];
+  /** File of the layer, relative to the project root */
+  file: string;
+  facade: string;
+  /** Upstream functions the facade wraps */
+  operations: string[];
+  /** Upstream interfaces the facade replaces */
+  replaces: string[];
+  types: TranslatedType[];
+}
+
+export interface AclRecommendation {
+  modules: [string, string];
+  /** References in both directions */
+  references: number;
+  sides: AclSide[];
+}
+
+/**
+ * Module pairs coupled by at least `minReferences` references, with the layer
+ * each side builds to use the other: which upstream functions its facade
+ * wraps and which upstream types it duplicates. The used symbols come from
+ * the ports of the upstream module. No layer is planned inside established
+ * modules, which are not refactored.
+ */
+export function recommendAntiCorruptionLayers(
+  modules: { name: string; ports?: ModulePorts; status?: 'established' }[],
+  coupling: ModuleCoupling[],
+  minReferences: number = DEFAULT_ACL_MIN_REFERENCES
+): AclRecommendation[] {
+  const pairs = new Map<string, ModuleCoupling[]>();
+  for (const edge of coupling.filter(c => c.from !== c.to && c.references > 0)) {
+    const key = [edge.from, edge.to].sort().join('\n');
+    pairs.set(key, [...(pairs.get(key) ?? []), edge]);
+  }
+
+  const recommendations: AclRecommendation[] = [];
+  for (const [key, edges] of pairs) {
+    const references = edges.reduce((sum, edge) => sum + edge.references, 0);
+    if (references < minReferences) continue;
+
+    const sides = edges
+      .filter(edge => modules.some(m => m.name === edge.from && m.status !== 'established'))
+      .map(edge => planSide(edge, modules.find(m => m.name === edge.to)?.ports))
+      .sort((a, b) => b.references - a.references || a.module.localeCompare(b.module));
+    if (sides.length === 0) continue;
+    recommendations.push({ modules: key.split('\n') as [string, string], references, sides });
+  }
+  return recommendations.sort((a, b) => b.references - a.references || a.modules.join().localeCompare(b.modules.join()));
+}
+
+function planSide(edge: ModuleCoupling, ports: ModulePorts | undefined): AclSide {
+  const used = (ports?.exposed ?? []).filter(port => port.consumers.includes(edge.from));
+  const qualified = (port: ModulePort) => `${path.posix.basename(port.package)}.${port.name}`;
+  return {
+    module: edge.from,
+    upstream: edge.to,
+    references: edge.references,
+    files: [...edge.files].sort(),
+    file: `internal/${edge.from}/acl/${aclFileName(edge.to)}.go`,
+    facade: `${pascalCase(edge.to)}Gateway`,
+    operations: used.filter(port => port.kind === 'func').map(qualified),
+    replaces: used.filter(port => port.kind === 'interface').map(qualified),
+    types: used.filter(port => port.kind === 'dto' || port.kind === 'type').map(port => ({
+      upstream: qualified(port),
+      local: port.name,
+      kind: port.kind === 'dto' ? 'dto' as const : 'type' as const,
+      translator: `to${port.name}`,
+    })),
+  };
+}
+
+/**
+ * Go sketch of a layer: the facade interface, the duplicated types and their translators
+ */
+export function renderAclSketch(side: AclSide): string {
+  const operations = side.operations.map(operation => `\t${operation.split('.').pop()}(/* ${side.module} の型 */)`);
+  const types = side.types.map(type => `// ${type.local} は ${type.upstream} の ${side.module} 側のコピー
+${type.kind === 'dto' ? `type ${type.local} struct {
+\t// ${side.module} が使うフィールドだけを持つ
+}` : `type ${type.local} /* ${type.upstream} の基底型 */`}
+
+func ${type.translator}(v ${type.upstream}) ${type.local} {
+\treturn ${type.kind === 'dto' ? `${type.local}{ /* フィールドを対応付け */ }` : `${type.local}(v)`}
+}`);
+  return `// ${side.file}
+package acl
+
+// ${side.facade} は ${side.module} が ${side.upstream} に求める操作だけを ${side.module} の型で表す${side.replaces.length > 0 ? `（${side.replaces.join(', ')} を置き換え）` : ''}
+type ${side.facade} interface {
+${operations.length > 0 ? operations.join('\n') : `\t// ${side.upstream} の呼び出しを ${side.module} の言葉で定義`}
+}
+${types.length > 0 ? `\n${types.join('\n\n')}\n` : ''}`;
+}
+
+/**
+ * plan.md section: the recommended layers of each strongly coupled pair with a sketch per side
+ */
+export function renderAclSection(recommendations: AclRecommendation[] | undefined, minReferences: number = DEFAULT_ACL_MIN_REFERENCES): string {
+  if (!recommendations || recommendations.length === 0) return '';
+
+  const pairs = recommendations.map(recommendation => {
+    const sides = recommendation.sides.map(side => [
+      `- ${side.module} 側: \`${side.file}\` に \`${side.facade}\` ファサードと変換関数を作成（${side.upstream} への参照 ${side.references}箇所、${side.files.length}ファイル）`,
+      ...(side.operations.length > 0 ? [`  - ファサードで包む操作: ${side.operations.map(name => `\`${name}\``).join(', ')}`] : []),
+      ...(side.replaces.length > 0 ? [`  - 置き換えるインターフェース: ${side.replaces.map(name => `\`${name}\``).join(', ')}`] : []),
+      `  - ${side.module} 側に複製する型: ${side.types.length > 0 ? side.types.map(type => `\`${type.upstream}\` → \`${type.local}\`（変換: \`${type.translator}\`）`).join(', ') : 'なし'}`,
+    ].join('\n'));
+    const sketches = recommendation.sides.map(side => `\`\`\`go\n${renderAclSketch(side)}\`\`\``);
+    return `### ${recommendation.modules.join(' ⇄ ')}（参照 ${recommendation.references}箇所）
+
+${sides.join('\n')}
+
+${sketches.join('\n\n')}`;
+  });
+
+  return `
+## 腐敗防止層 (Anti-Corruption Layers)
+
+参照が${minReferences}箇所以上あるモジュール間は、インターフェースの抽出だけでなく腐敗防止層（ファサード＋変換関数）で相手のモデルを隔離します。各モジュールは相手の型を直接使わず、自分の側のコピーに変換して扱います。
+
+${pairs.join('\n\n')}
+`;
+}
+
+function aclFileName(module: string): string {
+  return module.toLowerCase().replace(/[^a-z0-9]+/g, '_').replace(/^_|_$/g, '') || 'upstream';
+}
+
+function pascalCase(name: string): string {
+  return name.split(/[^A-Za-z0-9]+/).filter(Boolean).map(part => part[0].toUpperCase() + part.slice(1)).join('');
+}
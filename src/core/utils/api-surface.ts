@@ -0,0 +1,560 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { execSync } from 'child_process';
+import fastGlob from 'fast-glob';
+import { VibeFlowPaths } from './file-paths.js';
+import { ModuleManifestStore, ModuleOutputManifest } from './module-manifest.js';
+import { FileSafetyManager } from './file-safety.js';
+import { PerformanceStore } from './performance-store.js';
+import { detectGoProject, goImportAlias, goPackageImportPath } from './go-project-utils.js';
+import { goPackageName } from './go-load-check.js';
+import { toPosixPath } from './workspace-paths.js';
+import { escapeRegExp } from './text-utils.js';
+
+export type ApiSymbolKind = 'func' | 'type' | 'var' | 'const';
+
+/** Why an exported symbol without external references stays exported */
+export type KeepReason = 'reflection' | 'struct-tag' | 'name-collision';
+
+export interface ApiSymbol {
+  name: string;
+  kind: ApiSymbolKind;
+  /** Package directory relative to the project root */
+  package: string;
+  file: string;
+  line: number;
+  /** Modules (or package directories of code outside any module) referencing the symbol */
+  external_consumers: string[];
+  external_references: number;
+  /** References from other packages of the same module */
+  module_references: number;
+  /** Unexported name suggested for symbols without references outside the package */
+  rename_to?: string;
+  kept?: KeepReason;
+}
+
+export interface ModuleApiSurface {
+  module: string;
+  packages: string[];
+  exported: number;
+  /** No references outside their package: safe to unexport */
+  unexport: ApiSymbol[];
+  /** Exactly one external consumer: candidates for a narrower interface */
+  single_consumer: ApiSymbol[];
+  /** Unreferenced outside the package but must stay exported */
+  kept: ApiSymbol[];
+  /** `package.Symbol` left exported once the suggestions are applied */
+  public_api: string[];
+}
+
+export interface ApiSurfaceReport {
+  generated_at: string;
+  modules: ModuleApiSurface[];
+  totals: {
+    exported: number;
+    unexport: number;
+    single_consumer: number;
+    public_after: number;
+  };
+}
+
+export interface UnexportResult {
+  applied: { module: string; symbols: string[]; files: string[] }[];
+  failed: { module: string; error: string }[];
+}
+
+interface GoFile {
+  file: string;
+  dir: string;
+  content: string;
+  /** Code with string literals and comments blanked out (same length) */
+  code: string;
+  package: string | null;
+}
+
+const GO_KEYWORDS = new Set([
+  'break', 'case', 'chan', 'const', 'continue', 'default', 'defer', 'else', 'fallthrough', 'for', 'func',
+  'go', 'goto', 'if', 'import', 'interface', 'map', 'package', 'range', 'return', 'select', 'struct',
+  'switch', 'type', 'var',
+]);
+
+const GO_PREDECLARED = new Set([
+  'any', 'bool', 'byte', 'comparable', 'complex64', 'complex128', 'error', 'float32', 'float64', 'int',
+  'int8', 'int16', 'int32', 'int64', 'rune', 'string', 'uint', 'uint8', 'uint16', 'uint32', 'uint64',
+  'uintptr', 'true', 'false', 'iota', 'nil', 'append', 'cap', 'clear', 'close', 'complex', 'copy',
+  'delete', 'imag', 'len', 'make', 'max', 'min', 'new', 'panic', 'print', 'println', 'real', 'recover',
+]);
+
+/**
+ * ApiSurfaceReporter - 生成モジュールの公開APIの最小化レポート
+ *
+ * For every module recorded in the output manifests, counts references to
+ * its exported top-level symbols from outside the module. Symbols nobody
+ * outside their package uses can be unexported; symbols with exactly one
+ * consumer are candidates for interface narrowing. Methods are not
+ * considered since interface satisfaction cannot be checked from source text.
+ */
+export class ApiSurfaceReporter {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  get reportPath(): string {
+    return path.join(this.paths.reportsDir, 'api-surface.json');
+  }
+
+  get htmlPath(): string {
+    return path.join(this.paths.reportsDir, 'api-surface.html');
+  }
+
+  build(): ApiSurfaceReport {
+    const store = new ModuleManifestStore(this.projectRoot);
+    const manifests = store.listModules()
+      .map(name => store.load(name))
+      .filter((m): m is ModuleOutputManifest => m !== null);
+
+    const moduleDirs = new Map<string, string>();
+    for (const manifest of manifests) {
+      for (const entry of manifest.files.filter(e => e.path.endsWith('.go') && !e.path.endsWith('_test.go'))) {
+        const file = path.isAbsolute(entry.path) ? path.relative(this.projectRoot, entry.path) : entry.path;
+        const dir = path.posix.dirname(toPosixPath(file));
+        if (!moduleDirs.has(dir)) moduleDirs.set(dir, manifest.module);
+      }
+    }
+
+    const files = loadGoFiles(this.projectRoot);
+    const modules = manifests.map(manifest => analyzeModule(
+      this.projectRoot,
+      manifest.module,
+      [...moduleDirs].filter(([, module]) => module === manifest.module).map(([dir]) => dir).sort(),
+      files,
+      moduleDirs
+    ));
+
+    return {
+      generated_at: new Date().toISOString(),
+      modules,
+      totals: {
+        exported: sum(modules.map(m => m.exported)),
+        unexport: sum(modules.map(m => m.unexport.length)),
+        single_consumer: sum(modules.map(m => m.single_consumer.length)),
+        public_after: sum(modules.map(m => m.public_api.length)),
+      },
+    };
+  }
+
+  /**
+   * Write api-surface.json and api-surface.html under .vibeflow/reports
+   */
+  write(report: ApiSurfaceReport): { jsonPath: string; htmlPath: string } {
+    this.paths.writeArtifact(this.reportPath, report);
+    fs.writeFileSync(this.htmlPath, formatApiSurfaceHtml(report));
+    return { jsonPath: this.reportPath, htmlPath: this.htmlPath };
+  }
+
+  /**
+   * Record the per-module counts in performance_metrics of the active (or latest) run
+   */
+  recordMetrics(report: ApiSurfaceReport, runId?: number): number | undefined {
+    const store = new PerformanceStore(this.projectRoot);
+    const target = runId ?? store.getActiveRunId() ?? store.getRuns().at(-1)?.run_id;
+    if (target === undefined) return undefined;
+
+    for (const module of report.modules) {
+      const labels = { module: module.module };
+      store.recordMetric(target, 'api_exported_symbols', module.exported, labels);
+      store.recordMetric(target, 'api_unexport_candidates', module.unexport.length, labels);
+      store.recordMetric(target, 'api_single_consumer_symbols', module.single_consumer.length, labels);
+      store.recordMetric(target, 'api_public_symbols', module.public_api.length, labels);
+    }
+    return target;
+  }
+
+  /**
+   * Rename unexport candidates inside their packages, one module at a time.
+   * A module whose rewrite does not compile is restored from the original contents.
+   *
+   * @param verify compile check run after each module; returns an error message or null
+   */
+  async applyUnexports(
+    report: ApiSurfaceReport,
+    safetyManager: FileSafetyManager,
+    verify: () => string | null = () => compileCheck(this.projectRoot)
+  ): Promise<UnexportResult> {
+    const result: UnexportResult = { applied: [], failed: [] };
+    const files = loadGoFiles(this.projectRoot);
+
+    for (const module of report.modules) {
+      const renamesByDir = new Map<string, Map<string, string>>();
+      for (const symbol of module.unexport) {
+        if (!symbol.rename_to) continue;
+        const renames = renamesByDir.get(symbol.package) ?? new Map<string, string>();
+        renames.set(symbol.name, symbol.rename_to);
+        renamesByDir.set(symbol.package, renames);
+      }
+      if (renamesByDir.size === 0) continue;
+
+      const originals = new Map<string, string>();
+      for (const file of files) {
+        const renames = renamesByDir.get(file.dir);
+        if (!renames || file.package?.endsWith('_test')) continue;
+        const rewritten = renameIdentifiers(file.content, renames);
+        if (rewritten === file.content) continue;
+        originals.set(file.file, file.content);
+        await safetyManager.safeWrite(path.join(this.projectRoot, file.file), rewritten);
+      }
+
+      const error = originals.size > 0 ? verify() : null;
+      if (error) {
+        for (const [file, content] of originals) {
+          fs.writeFileSync(path.join(this.projectRoot, file), content);
+        }
+        result.failed.push({ module: module.module, error });
+        continue;
+      }
+
+      for (const file of files) {
+        if (originals.has(file.file)) {
+          file.content = fs.readFileSync(path.join(this.projectRoot, file.file), 'utf8');
+        }
+      }
+      result.applied.push({
+        module: module.module,
+        symbols: module.unexport.filter(s => s.rename_to).map(s => `${s.package}.${s.name}`),
+        files: [...originals.keys()].sort(),
+      });
+    }
+
+    return result;
+  }
+}
+
+/**
+ * Exported top-level symbols of each module with their references from
+ * other modules, for modules given by their files rather than an output
+ * manifest (the plan before refactoring). Packages belong to the module of
+ * their first file; references from the same package are not counted.
+ */
+export function analyzeModuleSymbols(projectRoot: string, modules: { name: string; files: string[] }[]): Map<string, ApiSymbol[]> {
+  const moduleDirs = new Map<string, string>();
+  for (const module of modules) {
+    for (const file of module.files.map(toPosixPath).filter(f => f.endsWith('.go') && !f.endsWith('_test.go'))) {
+      const dir = path.posix.dirname(file);
+      if (!moduleDirs.has(dir)) moduleDirs.set(dir, module.name);
+    }
+  }
+
+  const files = loadGoFiles(projectRoot);
+  return new Map(modules.map(module => [module.name, collectSymbols(
+    projectRoot,
+    module.name,
+    [...moduleDirs].filter(([, name]) => name === module.name).map(([dir]) => dir).sort(),
+    files,
+    moduleDirs
+  )]));
+}
+
+function loadGoFiles(projectRoot: string): GoFile[] {
+  return fastGlob.sync('**/*.go', {
+    cwd: projectRoot,
+    ignore: ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**'],
+  }).sort().map(file => {
+    const content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+    return { file, dir: path.posix.dirname(file), content, code: maskLiterals(content), package: goPackageName(content) };
+  });
+}
+
+function analyzeModule(
+  projectRoot: string,
+  moduleName: string,
+  dirs: string[],
+  files: GoFile[],
+  moduleDirs: Map<string, string>
+): ModuleApiSurface {
+  const symbols = collectSymbols(projectRoot, moduleName, dirs, files, moduleDirs);
+  const candidates = symbols.filter(s => s.rename_to);
+  const removed = new Set(candidates);
+
+  return {
+    module: moduleName,
+    packages: dirs,
+    exported: symbols.length,
+    unexport: candidates,
+    single_consumer: symbols.filter(s => s.external_consumers.length === 1),
+    kept: symbols.filter(s => s.kept),
+    public_api: symbols.filter(s => !removed.has(s)).map(s => `${s.package}.${s.name}`),
+  };
+}
+
+function collectSymbols(
+  projectRoot: string,
+  moduleName: string,
+  dirs: string[],
+  files: GoFile[],
+  moduleDirs: Map<string, string>
+): ApiSymbol[] {
+  const goProject = detectGoProject(projectRoot);
+  const symbols: ApiSymbol[] = [];
+
+  for (const dir of dirs) {
+    const packageFiles = files.filter(f => f.dir === dir && !f.file.endsWith('_test.go'));
+    // Identifiers already used anywhere in the package (tests included): renaming onto one could shadow or clash
+    const taken = new Set(files
+      .filter(f => f.dir === dir && !f.package?.endsWith('_test'))
+      .flatMap(f => f.code.match(/(?<![\w.])[a-z_]\w*/g) ?? []));
+    const importPath = goPackageImportPath(projectRoot, dir, goProject);
+    const packageName = packageFiles.find(f => f.package)?.package ?? undefined;
+
+    for (const source of packageFiles) {
+      for (const decl of topLevelDeclarations(source.code).filter(d => /^[A-Z]/.test(d.name))) {
+        const symbol: ApiSymbol = {
+          name: decl.name,
+          kind: decl.kind,
+          package: dir,
+          file: source.file,
+          line: decl.line,
+          external_consumers: [],
+          external_references: 0,
+          module_references: 0,
+        };
+
+        const consumers = new Set<string>();
+        for (const other of files) {
+          // Files of the package itself, except external test packages (package foo_test)
+          if (other.dir === dir && !other.package?.endsWith('_test')) continue;
+          const alias = importPath ? goImportAlias(other.content, importPath, packageName) : null;
+          if (!alias || alias === '_') continue;
+
+          const pattern = alias === '.'
+            ? new RegExp(`(?<![\\w.])${decl.name}\\b`, 'g')
+            : new RegExp(`(?<![\\w.])${escapeRegExp(alias)}\\.${decl.name}\\b`, 'g');
+          const count = (other.code.match(pattern) ?? []).length;
+          if (count === 0) continue;
+
+          const consumer = moduleDirs.get(other.dir) ?? other.dir;
+          if (consumer === moduleName) {
+            symbol.module_references += count;
+          } else {
+            symbol.external_references += count;
+            consumers.add(consumer);
+          }
+        }
+        symbol.external_consumers = [...consumers].sort();
+        symbols.push(symbol);
+        if (symbol.external_references > 0 || symbol.module_references > 0) continue;
+
+        const kept = keepReason(decl, source, files);
+        const renameTo = unexportedName(decl.name);
+        if (kept) {
+          symbol.kept = kept;
+        } else if (taken.has(renameTo) || GO_KEYWORDS.has(renameTo) || GO_PREDECLARED.has(renameTo)) {
+          symbol.kept = 'name-collision';
+        } else {
+          symbol.rename_to = renameTo;
+        }
+      }
+    }
+  }
+
+  return symbols;
+}
+
+/**
+ * Symbols used through reflection or struct tags, and types whose fields carry
+ * tags (encoders reach them by reflection), must stay exported
+ */
+function keepReason(decl: { name: string; kind: ApiSymbolKind; body: string }, source: GoFile, files: GoFile[]): KeepReason | null {
+  if (decl.kind === 'type' && /`[^`]*\w+:"[^"]*"[^`]*`/.test(decl.body)) return 'struct-tag';
+
+  const name = escapeRegExp(decl.name);
+  const inTag = new RegExp(`\`[^\`\\n]*\\w+:"[^"]*\\b${name}\\b[^"]*"[^\`\\n]*\``);
+  const reflective = [
+    new RegExp(`\\breflect\\.[\\w.]+\\([^\\n]*\\b${name}\\b`),
+    new RegExp(`\\bgob\\.Register(?:Name)?\\([^\\n]*\\b${name}\\b`),
+    new RegExp(`ByName\\(\\s*"${name}"\\s*\\)`),
+  ];
+
+  for (const file of files) {
+    if (file.dir === source.dir && inTag.test(file.content)) return 'struct-tag';
+    if (reflective.some(pattern => pattern.test(file.content))) return 'reflection';
+  }
+  return null;
+}
+
+/**
+ * Top-level func, type, var and const declarations (methods excluded)
+ */
+function topLevelDeclarations(code: string): { name: string; kind: ApiSymbolKind; line: number; body: string }[] {
+  const lines = code.split('\n');
+  const declarations: { name: string; kind: ApiSymbolKind; line: number; body: string }[] = [];
+  let group: 'type' | 'var' | 'const' | null = null;
+
+  lines.forEach((line, index) => {
+    if (group) {
+      if (/^\)/.test(line)) {
+        group = null;
+        return;
+      }
+      const spec = line.match(/^\t(\w+(?:\s*,\s*\w+)*)(?:\s|$)/);
+      if (spec) {
+        spec[1].split(',').map(n => n.trim()).forEach(name =>
+          declarations.push({ name, kind: group!, line: index + 1, body: group === 'type' ? declarationBody(lines, index) : line }));
+      }
+      return;
+    }
+
+    const grouped = line.match(/^(type|var|const)\s*\(\s*$/);
+    if (grouped) {
+      group = grouped[1] as 'type' | 'var' | 'const';
+      return;
+    }
+
+    const func = line.match(/^func\s+(\w+)\s*[([]/);
+    if (func) {
+      declarations.push({ name: func[1], kind: 'func', line: index + 1, body: line });
+      return;
+    }
+
+    const single = line.match(/^(type|var|const)\s+(\w+(?:\s*,\s*\w+)*)\b/);
+    if (single) {
+      const kind = single[1] as ApiSymbolKind;
+      single[2].split(',').map(n => n.trim()).forEach(name =>
+        declarations.push({ name, kind, line: index + 1, body: kind === 'type' ? declarationBody(lines, index) : line }));
+    }
+  });
+
+  return declarations.filter(d => d.name !== '_');
+}
+
+function declarationBody(lines: string[], start: number): string {
+  let depth = 0;
+  for (let i = start; i < lines.length; i++) {
+    depth += (lines[i].match(/\{/g) ?? []).length - (lines[i].match(/\}/g) ?? []).length;
+    if (depth <= 0) return lines.slice(start, i + 1).join('\n');
+  }
+  return lines.slice(start).join('\n');
+}
+
+/**
+ * Go naming for an unexported identifier: User → user, HTTPClient → httpClient, ID → id
+ */
+export function unexportedName(name: string): string {
+  const upper = name.match(/^[A-Z0-9]+/)?.[0] ?? '';
+  if (upper.length === name.length) return name.toLowerCase();
+  if (upper.length > 1) return upper.slice(0, -1).toLowerCase() + name.slice(upper.length - 1);
+  return name[0].toLowerCase() + name.slice(1);
+}
+
+/**
+ * Rename package-level identifiers within a file of the same package.
+ * Selectors (x.Name), struct field names and composite literal keys are left alone;
+ * strings are never touched, comments are updated so doc comments keep their subject.
+ */
+export function renameIdentifiers(content: string, renames: Map<string, string>): string {
+  if (renames.size === 0) return content;
+
+  const masked = maskLiterals(content, { keepComments: true });
+  const pattern = new RegExp(`(?<![\\w.])(${[...renames.keys()].map(escapeRegExp).join('|')})\\b`, 'g');
+  const lines = content.split('\n');
+  const maskedLines = masked.split('\n');
+  let structDepth = 0;
+
+  return lines.map((line, index) => {
+    const code = maskedLines[index];
+    const fieldLine = structDepth > 0;
+    if (/\bstruct\s*\{\s*$/.test(code)) structDepth++;
+    else if (structDepth > 0) structDepth += (code.match(/\{/g) ?? []).length - (code.match(/\}/g) ?? []).length;
+    const isCase = /^\s*case\b/.test(code);
+
+    let result = '';
+    let cursor = 0;
+    let match: RegExpExecArray | null;
+    pattern.lastIndex = 0;
+    while ((match = pattern.exec(code)) !== null) {
+      const before = code.slice(0, match.index);
+      const after = code.slice(match.index + match[0].length);
+      const fieldName = fieldLine && /^\s*$/.test(before) && /^\s+[\w*[\]]/.test(after);
+      const literalKey = !isCase && /^\s*:(?!=)/.test(after) && /(?:^|[{,])\s*$/.test(before);
+      if (fieldName || literalKey) continue;
+
+      result += line.slice(cursor, match.index) + renames.get(match[1]);
+      cursor = match.index + match[0].length;
+    }
+    return result + line.slice(cursor);
+  }).join('\n');
+}
+
+/**
+ * Replace string/rune literal contents (and comments unless kept) with spaces, preserving offsets
+ */
+export function maskLiterals(content: string, options: { keepComments?: boolean } = {}): string {
+  return content.replace(/\/\/[^\n]*|\/\*[\s\S]*?\*\/|"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*'|`[^`]*`/g, token => {
+    if (options.keepComments && token.startsWith('/')) return token;
+    return token.replace(/[^\n]/g, ' ');
+  });
+}
+
+/**
+ * Type-check the whole module including tests; returns the compiler output on failure
+ */
+export function compileCheck(projectRoot: string): string | null {
+  const goProject = detectGoProject(projectRoot);
+  if (!goProject.hasGoProject) return null;
+
+  try {
+    execSync('go vet ./...', { cwd: goProject.workingDirectory!, stdio: 'pipe', timeout: 600000 });
+    return null;
+  } catch (error) {
+    const output = (error as { stderr?: Buffer }).stderr?.toString().trim();
+    return output || 'go vet ./... failed';
+  }
+}
+
+export function formatApiSurfaceHtml(report: ApiSurfaceReport): string {
+  const rows = (symbols: ApiSymbol[], extra: (s: ApiSymbol) => string) => symbols.map(s =>
+    `<tr><td><code>${escapeHtml(`${s.package}.${s.name}`)}</code></td><td>${s.kind}</td><td>${escapeHtml(`${s.file}:${s.line}`)}</td><td>${escapeHtml(extra(s))}</td></tr>`
+  ).join('\n');
+  const table = (title: string, header: string, body: string) => body
+    ? `<h3>${title}</h3>\n<table>\n<tr><th>Symbol</th><th>Kind</th><th>Declared at</th><th>${header}</th></tr>\n${body}\n</table>`
+    : '';
+
+  const sections = report.modules.map(m => [
+    `<h2>${escapeHtml(m.module)}</h2>`,
+    `<p>${m.exported} exported → ${m.public_api.length} after applying the suggestions (${m.packages.map(escapeHtml).join(', ')})</p>`,
+    table('Safe to unexport', 'Rename to', rows(m.unexport, s => s.rename_to ?? '')),
+    table('Single external consumer', 'Consumer', rows(m.single_consumer, s => `${s.external_consumers[0]} (${s.external_references} references)`)),
+    table('Kept exported', 'Reason', rows(m.kept, s => s.kept ?? '')),
+    m.public_api.length > 0 ? `<h3>Resulting public API</h3>\n<ul>\n${m.public_api.map(s => `<li><code>${escapeHtml(s)}</code></li>`).join('\n')}\n</ul>` : '',
+  ].filter(Boolean).join('\n')).join('\n');
+
+  return `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API surface report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+</style>
+</head>
+<body>
+<h1>API surface report</h1>
+<p>Generated ${escapeHtml(report.generated_at)}: ${report.totals.exported} exported symbols, ${report.totals.unexport} safe to unexport, ${report.totals.single_consumer} with a single consumer, ${report.totals.public_after} public after applying the suggestions.</p>
+${sections}
+</body>
+</html>
+`;
+}
+
+function sum(values: number[]): number {
+  return values.reduce((total, value) => total + value, 0);
+}
+
+function escapeHtml(text: string): string {
+  return text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+}
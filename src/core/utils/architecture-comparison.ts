@@ -0,0 +1,166 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import type { ArchitecturalPlan } from '../agents/architect-agent.js';
+import { ArchitectureStyle, MODULE_LAYOUTS } from './architecture-style.js';
+import { estimateModelCost } from './model-escalation.js';
+import { round } from './number-utils.js';
+
+/**
+ * Interfaces every refactored module declares in each layout: the repository
+ * interface of clean architecture, or the inbound and outbound ports of
+ * hexagonal architecture
+ */
+export const LAYOUT_INTERFACES: Record<ArchitectureStyle, string[]> = {
+  clean: ['repository'],
+  hexagonal: ['inbound port', 'outbound port'],
+};
+
+/** Prompt template, plan context and instructions sent with every source file */
+const PROMPT_OVERHEAD_TOKENS = 1500;
+/** Package clause, imports and wiring the model writes for every target file */
+const TARGET_FILE_TOKENS = 300;
+const DEFAULT_MODEL = 'sonnet';
+
+/**
+ * What refactoring into one candidate architecture takes, measured on its plan
+ */
+export interface ArchitectureCandidate {
+  style: ArchitectureStyle;
+  /** Modules refactored (established ones are left as they are) */
+  modules: number;
+  /** Source files moved into the target layout */
+  files_moved: number;
+  /** Files the refactoring creates */
+  files_created: number;
+  packages_created: number;
+  interfaces_created: number;
+  /** Rough LLM tokens of refactoring every source file: its code plus the prompt in, its code split over the target files out */
+  tokens: { input: number; output: number };
+  cost_usd: number;
+  /** From the action estimates; missing when the plan has none */
+  effort_days?: number;
+  /** Average risk score (0-100) of the refactored modules; missing without risk classification */
+  risk_score?: number;
+  manual_only: number;
+  /** Hot modules of the risk heat map */
+  hot_modules: number;
+}
+
+export interface ArchitectureComparison {
+  model: string;
+  candidates: ArchitectureCandidate[];
+}
+
+/**
+ * Measure each candidate plan: files and packages the refactoring moves and
+ * creates, interfaces it introduces, estimated tokens and cost, effort and risk
+ */
+export function compareArchitectures(
+  projectRoot: string,
+  candidates: { style: ArchitectureStyle; plan: ArchitecturalPlan }[],
+  model: string = DEFAULT_MODEL
+): ArchitectureComparison {
+  const sourceTokens = new Map<string, number>();
+  const tokensOf = (file: string) => {
+    if (!sourceTokens.has(file)) sourceTokens.set(file, readTokens(projectRoot, file));
+    return sourceTokens.get(file)!;
+  };
+
+  return {
+    model,
+    candidates: candidates.map(({ style, plan }) => {
+      const migrated = plan.modules.filter(module => module.status !== 'established');
+      const targetFiles = migrated.flatMap(module => module.target_files ?? []);
+      const input = targetFiles.reduce((sum, file) => sum + tokensOf(file.source) + PROMPT_OVERHEAD_TOKENS, 0);
+      const output = targetFiles.reduce((sum, file) => sum + tokensOf(file.source) + file.targets.length * TARGET_FILE_TOKENS, 0);
+      const risks = migrated.flatMap(module => (module.risk ? [module.risk] : []));
+      const estimates = (plan.estimates?.modules ?? []).filter(estimate => migrated.some(module => module.name === estimate.module));
+
+      return {
+        style,
+        modules: migrated.length,
+        files_moved: new Set(targetFiles.map(file => file.source)).size,
+        files_created: new Set(targetFiles.flatMap(file => file.targets.map(target => target.path))).size,
+        packages_created: migrated.length * MODULE_LAYOUTS[style].dirs.length,
+        interfaces_created: migrated.reduce((sum, module) => sum + module.interfaces.length + LAYOUT_INTERFACES[style].length, 0),
+        tokens: { input, output },
+        cost_usd: estimateModelCost(model, input, output),
+        ...(estimates.length > 0 ? { effort_days: round(estimates.reduce((sum, estimate) => sum + estimate.effort_days, 0), 1) } : {}),
+        ...(risks.length > 0 ? { risk_score: Math.round(risks.reduce((sum, risk) => sum + risk.score, 0) / risks.length) } : {}),
+        manual_only: risks.filter(risk => risk.tier === 'manual-only').length,
+        hot_modules: (plan.risk_heatmap?.modules ?? []).filter(module => module.level === 'hot').length,
+      };
+    }),
+  };
+}
+
+/**
+ * Side-by-side comparison of the candidates, with each one's difference from the first
+ *
+ * @param plans candidate plan.md of each style, relative to the project root
+ */
+export function renderArchitectureComparison(comparison: ArchitectureComparison, plans: Partial<Record<ArchitectureStyle, string>> = {}): string {
+  const candidates = comparison.candidates;
+  const row = (label: string, value: (candidate: ArchitectureCandidate) => string) =>
+    `| ${label} | ${candidates.map(value).join(' | ')} |`;
+  const optional = (value: number | undefined) => (value !== undefined ? `${value}` : '-');
+
+  const [base, ...others] = candidates;
+  const differences = others.map(candidate => {
+    const deltas = [
+      delta('作成するファイル', candidate.files_created - base.files_created),
+      delta('パッケージ', candidate.packages_created - base.packages_created),
+      delta('インターフェース', candidate.interfaces_created - base.interfaces_created),
+      delta('出力トークン', candidate.tokens.output - base.tokens.output),
+      Math.abs(candidate.cost_usd - base.cost_usd) >= 0.005 ? `推定コスト ${candidate.cost_usd > base.cost_usd ? '+' : '-'}$${Math.abs(candidate.cost_usd - base.cost_usd).toFixed(2)}` : '',
+    ].filter(Boolean);
+    return `- ${candidate.style} は ${base.style} に比べて: ${deltas.join('、') || '差なし'}`;
+  });
+
+  return `# アーキテクチャ比較 (Architecture Comparison)
+
+候補ごとに計画を設計し、リファクタリングの規模・推定コスト・リスクを比較しました。採用するスタイルは \`vf plan --style <スタイル>\` で計画に反映します。
+
+| 指標 | ${candidates.map(candidate => candidate.style).join(' | ')} |
+|------|${candidates.map(() => '------').join('|')}|
+${[
+    row('対象モジュール', c => `${c.modules}`),
+    row('移動するファイル', c => `${c.files_moved}`),
+    row('作成するファイル', c => `${c.files_created}`),
+    row('作成するパッケージ', c => `${c.packages_created}`),
+    row('作成するインターフェース', c => `${c.interfaces_created}`),
+    row('推定トークン (入力 / 出力)', c => `${c.tokens.input.toLocaleString('en-US')} / ${c.tokens.output.toLocaleString('en-US')}`),
+    row(`推定コスト (${comparison.model})`, c => `$${c.cost_usd.toFixed(2)}`),
+    row('工数 (人日)', c => optional(c.effort_days)),
+    row('リスクスコア (平均)', c => optional(c.risk_score)),
+    row('manual-only モジュール', c => `${c.manual_only}`),
+    row('高リスク（ヒートマップ）', c => `${c.hot_modules}`),
+  ].join('\n')}
+${differences.length > 0 ? `
+## 差分
+
+${differences.join('\n')}
+` : ''}
+## 算出方法
+
+- トークン: ソースファイルごとに、コード（4文字 = 1トークン）とプロンプト ${PROMPT_OVERHEAD_TOKENS}トークンを入力、コードと作成ファイルごとに ${TARGET_FILE_TOKENS}トークンを出力として概算
+- インターフェース: 計画のインターフェースに、各モジュールがレイアウト上宣言するもの（${Object.entries(LAYOUT_INTERFACES).map(([style, names]) => `${style}: ${names.join('・')}`).join('、')}）を加算
+- リスクスコア・manual-only: 自動適用のリスク分類、高リスク: リスクヒートマップ（Git履歴がある場合）
+${Object.keys(plans).length > 0 ? `
+## 候補の計画
+
+${Object.entries(plans).map(([style, file]) => `- ${style}: \`${file}\``).join('\n')}
+` : ''}`;
+}
+
+function delta(label: string, value: number): string {
+  return value === 0 ? '' : `${label} ${value > 0 ? '+' : ''}${value.toLocaleString('en-US')}`;
+}
+
+function readTokens(projectRoot: string, file: string): number {
+  try {
+    return Math.ceil(fs.readFileSync(path.isAbsolute(file) ? file : path.join(projectRoot, file), 'utf8').length / 4);
+  } catch {
+    return 0;
+  }
+}
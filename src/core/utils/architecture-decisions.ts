@@ -0,0 +1,246 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import type { ModuleDesign, RefactoringAction } from '../agents/architect-agent.js';
+import { round } from './number-utils.js';
+
+/**
+ * Decisions of the plan that get an ADR: the ones a reviewer could not infer
+ * from the module layout alone
+ */
+export type DecisionKind = 'extract_interface' | 'introduce_event' | 'extract_orchestrator' | 'introduce_acl' | 'merge_modules';
+
+const RECORDED_ACTIONS: RefactoringAction['type'][] = ['extract_interface', 'introduce_event', 'extract_orchestrator', 'introduce_acl'];
+
+export interface ArchitectureDecision {
+  /** Stable across regenerations, e.g. cut-cycle:billing->order; the ADR is found again by it */
+  key: string;
+  kind: DecisionKind;
+  module: string;
+  title: string;
+  context: string[];
+  decision: string;
+  consequences: string[];
+}
+
+export interface DecisionRecord {
+  key: string;
+  title: string;
+  /** ADR file relative to the project root */
+  path: string;
+  /** Status line of the ADR; edited by the team once decided (Accepted, Rejected, ...) */
+  status: string;
+}
+
+export interface DecisionRecordResult {
+  /** Every ADR vf plan has written, current and deprecated */
+  records: DecisionRecord[];
+  /** Keys of the ADRs written now */
+  added: string[];
+  /** Keys of proposed ADRs the plan no longer makes, now marked deprecated */
+  deprecated: string[];
+}
+
+const MARKER = /<!-- vf:adr (\S+) -->/;
+
+const CONSEQUENCES: Record<DecisionKind, string[]> = {
+  extract_interface: [
+    '呼び出し側は具象実装ではなくインターフェースに依存し、実装はコンポジションルートで注入する',
+    'モジュール間の依存方向が反転し、依存サイクルや禁止された依存が解消される',
+    '間接参照が一段増え、実装を追うにはコンポジションルートを読む必要がある',
+  ],
+  introduce_event: [
+    '発行側は購読側を知らずに済み、コード上の依存がなくなる',
+    '処理は結果整合になる：購読側の失敗・再試行・順序をイベント契約として設計する必要がある',
+    'イベントのスキーマが公開契約になり、変更には後方互換性の配慮が必要になる',
+  ],
+  extract_orchestrator: [
+    '複数モジュールにまたがる処理の所有者が明確になり、各モジュールは公開 API だけを提供する',
+    'サーガとして切り出す場合は単一トランザクションがなくなり、補償処理が必要になる',
+  ],
+  introduce_acl: [
+    '相手モジュールの型や命名の変更はファサードと変換関数で吸収され、モジュール内部のモデルに波及しない',
+    '複製した型と変換関数を保守する必要があり、相手の変更に合わせて変換を更新する',
+  ],
+  merge_modules: [
+    '統合したモジュール間の呼び出しはモジュール内部の呼び出しになり、公開 API が不要になる',
+    'モジュールが大きくなり凝集度が下がる可能性がある。後で分割する場合は新しい ADR で記録する',
+  ],
+};
+
+/**
+ * Decisions behind the planned modules: the interface extractions, event
+ * introductions, orchestrator extractions and anti-corruption layers carrying
+ * a decision key, and the
+ * modules merged from several discovered boundaries. `adjustments` are the
+ * constraint adjustments of the plan, quoted as context of the merges.
+ */
+export function collectDecisions(modules: ModuleDesign[], adjustments: string[] = []): ArchitectureDecision[] {
+  const decisions = new Map<string, ArchitectureDecision>();
+
+  for (const module of modules) {
+    if (module.status === 'established') continue;
+    const context = moduleContext(module);
+
+    for (const action of module.refactoring_actions) {
+      if (!action.decision || !RECORDED_ACTIONS.includes(action.type) || decisions.has(action.decision)) continue;
+      const kind = action.type as DecisionKind;
+      decisions.set(action.decision, {
+        key: action.decision,
+        kind,
+        module: module.name,
+        title: `${module.name}: ${action.description.split(/[:：]/)[0].trim()}`,
+        context: [...context, ...(action.files_affected.length > 0 ? [`対象: ${formatList(action.files_affected)}`] : [])],
+        decision: action.description,
+        consequences: CONSEQUENCES[kind],
+      });
+    }
+
+    if (module.merged_from && module.merged_from.length > 0) {
+      const names = [module.name, ...module.merged_from];
+      const key = `merge:${module.name}`;
+      decisions.set(key, {
+        key,
+        kind: 'merge_modules',
+        module: module.name,
+        title: `${module.name}: ${module.merged_from.join(', ')} を統合`,
+        context: [
+          ...context,
+          `発見された境界 ${names.join(', ')} は単独のモジュールとしては小さすぎるか、互いに強く結合している`,
+          ...adjustments.filter(adjustment => names.some(name => adjustment.includes(name))).map(adjustment => `境界制約による調整: ${adjustment}`),
+        ],
+        decision: `${module.merged_from.join(', ')} を ${module.name} モジュールに統合し、1つのモジュールとして移行する`,
+        consequences: CONSEQUENCES.merge_modules,
+      });
+    }
+  }
+
+  return [...decisions.values()];
+}
+
+/**
+ * Write an ADR (Nygard format) for every decision without one in `adrDir` yet.
+ * Existing ADRs keep their number and are never rewritten: the team records
+ * the outcome in them. Proposed ADRs whose decision the plan no longer makes
+ * are marked deprecated.
+ */
+export function writeDecisionRecords(
+  projectRoot: string,
+  adrDir: string,
+  decisions: ArchitectureDecision[],
+  now: Date = new Date()
+): DecisionRecordResult {
+  const date = now.toISOString().slice(0, 10);
+  const files = readDecisionRecords(adrDir);
+  const existing = files.flatMap(record => (record.key ? [{ ...record, key: record.key }] : []));
+  const relative = (file: string) => path.relative(projectRoot, file).split(path.sep).join('/');
+  const added: string[] = [];
+  const deprecated: string[] = [];
+
+  // ADRs written by hand take up their number too
+  let next = Math.max(0, ...files.map(record => record.number)) + 1;
+  for (const decision of decisions) {
+    if (existing.some(record => record.key === decision.key)) continue;
+    fs.mkdirSync(adrDir, { recursive: true });
+    const file = path.join(adrDir, `${String(next).padStart(4, '0')}-${slug(decision.key)}.md`);
+    fs.writeFileSync(file, renderDecisionRecord(next, decision, date));
+    existing.push({ key: decision.key, number: next, file, title: decision.title, status: 'Proposed' });
+    added.push(decision.key);
+    next++;
+  }
+
+  const current = new Set(decisions.map(decision => decision.key));
+  for (const record of existing) {
+    if (current.has(record.key) || record.status !== 'Proposed') continue;
+    const content = fs.readFileSync(record.file, 'utf8');
+    fs.writeFileSync(record.file, content.replace(
+      /## Status\n\nProposed\n/,
+      `## Status\n\nDeprecated\n\n${date}: vf plan no longer proposes this decision.\n`
+    ));
+    record.status = 'Deprecated';
+    deprecated.push(record.key);
+  }
+
+  return {
+    records: existing
+      .sort((a, b) => a.number - b.number)
+      .map(record => ({ key: record.key, title: record.title, path: relative(record.file), status: record.status })),
+    added,
+    deprecated,
+  };
+}
+
+export function renderDecisionRecord(number: number, decision: ArchitectureDecision, date: string): string {
+  return `# ${number}. ${decision.title}
+
+Date: ${date}
+
+## Status
+
+Proposed
+
+## Context
+
+${decision.context.map(line => `- ${line}`).join('\n')}
+
+## Decision
+
+${decision.decision}
+
+## Consequences
+
+${decision.consequences.map(line => `- ${line}`).join('\n')}
+
+<!-- vf:adr ${decision.key} -->
+`;
+}
+
+/**
+ * plan.md section listing the ADRs of the plan's decisions
+ */
+export function renderDecisionsSection(records: DecisionRecord[] | undefined): string {
+  if (!records || records.length === 0) return '';
+  return `
+## アーキテクチャ決定記録 (ADR)
+
+計画の判断（インターフェース抽出・イベント導入・モジュール統合など）ごとの ADR です。ステータスは各ファイルで更新してください（再生成しても上書きされません）。
+
+${records.map(record => `- \`${record.path}\` ${record.title} — ${record.status}`).join('\n')}
+`;
+}
+
+/**
+ * Numbered ADRs of the directory; `key` is set on the ones vf plan wrote
+ */
+function readDecisionRecords(adrDir: string): { key?: string; number: number; file: string; title: string; status: string }[] {
+  if (!fs.existsSync(adrDir)) return [];
+  return fs.readdirSync(adrDir)
+    .filter(name => /^\d+-.*\.md$/.test(name))
+    .map(name => {
+      const file = path.join(adrDir, name);
+      const content = fs.readFileSync(file, 'utf8');
+      return {
+        key: content.match(MARKER)?.[1],
+        number: parseInt(name, 10),
+        file,
+        title: content.match(/^# \d+\. (.*)$/m)?.[1] ?? name,
+        status: content.match(/^## Status\n\n(.*)$/m)?.[1].trim() ?? '',
+      };
+    });
+}
+
+function moduleContext(module: ModuleDesign): string[] {
+  const dependencies = module.dependencies.map(dep => dep.module);
+  return [
+    `モジュール: ${module.name} — ${module.description}`,
+    `現状: ${module.current_state.files.length}ファイル、結合度 ${round(module.current_state.coupling_score, 2)}、凝集度 ${round(module.current_state.cohesion_score, 2)}`,
+    ...(dependencies.length > 0 ? [`依存先: ${dependencies.join(', ')}`] : []),
+  ];
+}
+
+function formatList(items: string[], limit = 10): string {
+  return items.slice(0, limit).join(', ') + (items.length > limit ? ` ほか${items.length - limit}件` : '');
+}
+
+function slug(key: string): string {
+  return key.toLowerCase().replace(/[^a-z0-9]+/g, '-').replace(/^-+|-+$/g, '');
+}
@@ -0,0 +1,218 @@
+import * as fs from 'fs';
+import { DomainBoundary, DomainMap } from '../types/config.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { parseDomainMap } from './input-parsers.js';
+import { csvCell } from './metrics-aggregator.js';
+
+/**
+ * Version of the row schemas of `vf query`. Scripts of other teams read this
+ * output: renaming or removing a column needs a new version, adding one does not.
+ */
+export const QUERY_SCHEMA_VERSION = 1;
+
+export type QueryName = 'deps' | 'files' | 'tables' | 'routes';
+
+export type QueryFormat = 'table' | 'json' | 'csv';
+
+export const QUERY_FORMATS: QueryFormat[] = ['table', 'json', 'csv'];
+
+export type QueryValue = string | number | string[];
+
+export interface QueryResult {
+  query: QueryName;
+  schema_version: number;
+  /** Options the query was run with */
+  params: Record<string, string | number>;
+  /** Column order of table and csv output */
+  columns: string[];
+  rows: Record<string, QueryValue>[];
+}
+
+export interface DepsQuery {
+  of: string;
+  /** out: boundaries `of` depends on; in: boundaries that depend on `of` */
+  direction: 'in' | 'out';
+}
+
+export interface FilesQuery {
+  boundary: string;
+  /** Count only references to this boundary's packages */
+  to?: string;
+  minCoupling?: number;
+}
+
+/**
+ * A boundary or table name that is not in the domain map; lists close matches
+ */
+export class UnknownNameError extends Error {
+  constructor(public readonly kind: string, public readonly value: string, public readonly suggestions: string[]) {
+    super(`Unknown ${kind} '${value}'${suggestions.length > 0 ? `. Did you mean: ${suggestions.join(', ')}?` : ''}`);
+    this.name = 'UnknownNameError';
+  }
+}
+
+/**
+ * Domain map of the project as persisted by `vf discover`; nothing is re-analyzed
+ */
+export function loadQueryDomainMap(projectRoot: string): DomainMap {
+  const paths = new VibeFlowPaths(projectRoot);
+  if (!fs.existsSync(paths.domainMapPath)) {
+    throw new Error(`Domain map not found. Please run "vf discover" first to generate ${paths.getRelativePath(paths.domainMapPath)}`);
+  }
+  return parseDomainMap(fs.readFileSync(paths.domainMapPath, 'utf8'), paths.getRelativePath(paths.domainMapPath)).map;
+}
+
+/**
+ * Boundaries `of` depends on (out) or that depend on it (in), with the file
+ * references behind each dependency when the domain map records them
+ */
+export function queryDeps(map: DomainMap, query: DepsQuery): QueryResult {
+  const target = findBoundary(map, query.of);
+  const edges = query.direction === 'out'
+    ? (target.dependencies?.internal ?? []).map(to => [target, to] as const)
+    : map.boundaries.filter(b => b.name !== target.name && (b.dependencies?.internal ?? []).includes(target.name)).map(b => [b, target.name] as const);
+
+  return {
+    query: 'deps',
+    schema_version: QUERY_SCHEMA_VERSION,
+    params: { of: target.name, direction: query.direction },
+    columns: ['from', 'to', 'files', 'references'],
+    rows: edges
+      .map(([from, to]) => {
+        const coupling = (from.file_coupling ?? []).filter(c => c.boundary === to);
+        return {
+          from: from.name,
+          to,
+          files: new Set(coupling.map(c => c.file)).size,
+          references: coupling.reduce((sum, c) => sum + c.references, 0),
+        };
+      })
+      .sort((a, b) => compareText(a.from, b.from) || compareText(a.to, b.to)),
+  };
+}
+
+/**
+ * Files of a boundary with their references to other boundaries, most coupled first
+ */
+export function queryFiles(map: DomainMap, query: FilesQuery): QueryResult {
+  const boundary = findBoundary(map, query.boundary);
+  const to = query.to !== undefined ? findBoundary(map, query.to).name : undefined;
+  const packages = new Map((boundary.file_packages ?? []).map(p => [p.file, p.import_path]));
+
+  const rows = boundary.files.map(file => {
+    const coupling = (boundary.file_coupling ?? []).filter(c => c.file === file && (to === undefined || c.boundary === to));
+    return {
+      file,
+      package: packages.get(file) ?? '',
+      coupling: coupling.reduce((sum, c) => sum + c.references, 0),
+      coupled_to: coupling.map(c => c.boundary).sort(compareText),
+    };
+  });
+
+  return {
+    query: 'files',
+    schema_version: QUERY_SCHEMA_VERSION,
+    params: {
+      boundary: boundary.name,
+      ...(to !== undefined ? { to } : {}),
+      ...(query.minCoupling !== undefined ? { min_coupling: query.minCoupling } : {}),
+    },
+    columns: ['file', 'package', 'coupling', 'coupled_to'],
+    rows: rows
+      .filter(row => query.minCoupling === undefined || row.coupling >= query.minCoupling)
+      .sort((a, b) => b.coupling - a.coupling || compareText(a.file, b.file)),
+  };
+}
+
+/**
+ * Boundaries that access each table, or only `table`
+ */
+export function queryTables(map: DomainMap, table?: string): QueryResult {
+  const tables = [...new Set(map.boundaries.flatMap(b => b.tables ?? []))].sort(compareText);
+  if (table !== undefined && !tables.includes(table)) {
+    throw new UnknownNameError('table', table, suggestNames(table, tables));
+  }
+
+  return {
+    query: 'tables',
+    schema_version: QUERY_SCHEMA_VERSION,
+    params: table !== undefined ? { table } : {},
+    columns: ['table', 'boundaries'],
+    rows: tables
+      .filter(name => table === undefined || name === table)
+      .map(name => ({ table: name, boundaries: map.boundaries.filter(b => (b.tables ?? []).includes(name)).map(b => b.name).sort(compareText) })),
+  };
+}
+
+/**
+ * API endpoints of each boundary, or only those of `module`
+ */
+export function queryRoutes(map: DomainMap, module?: string): QueryResult {
+  const boundaries = module !== undefined ? [findBoundary(map, module)] : map.boundaries;
+
+  return {
+    query: 'routes',
+    schema_version: QUERY_SCHEMA_VERSION,
+    params: module !== undefined ? { module: boundaries[0].name } : {},
+    columns: ['module', 'method', 'path'],
+    rows: boundaries
+      .flatMap(boundary => (boundary.apiEndpoints ?? []).map(endpoint => {
+        const match = endpoint.trim().match(/^([A-Z]+)\s+(\S+)/);
+        return { module: boundary.name, method: match ? match[1] : '', path: match ? match[2] : endpoint.trim() };
+      }))
+      .sort((a, b) => compareText(a.module, b.module) || compareText(a.path, b.path) || compareText(a.method, b.method)),
+  };
+}
+
+export function formatQueryResult(result: QueryResult, format: QueryFormat): string {
+  if (format === 'json') return JSON.stringify(result, null, 2) + '\n';
+
+  const cell = (value: QueryValue) => (Array.isArray(value) ? value.join(';') : String(value));
+  if (format === 'csv') {
+    return [result.columns.join(','), ...result.rows.map(row => result.columns.map(column => csvCell(cell(row[column]))).join(','))].join('\n') + '\n';
+  }
+
+  if (result.rows.length === 0) return '(no rows)\n';
+  const cells = result.rows.map(row => result.columns.map(column => cell(row[column])));
+  const widths = result.columns.map((column, index) => Math.max(column.length, ...cells.map(row => row[index].length)));
+  const line = (values: string[]) => values.map((value, index) => value.padEnd(widths[index])).join('  ').trimEnd();
+  return [line(result.columns), line(widths.map(width => '-'.repeat(width))), ...cells.map(line)].join('\n') + '\n';
+}
+
+/**
+ * Candidates within a small edit distance of `value`, or that contain it, closest first
+ */
+export function suggestNames(value: string, candidates: string[]): string[] {
+  const lower = value.toLowerCase();
+  return candidates
+    .map(candidate => ({ candidate, distance: editDistance(lower, candidate.toLowerCase()) }))
+    .filter(({ candidate, distance }) => distance <= Math.max(2, Math.floor(value.length / 3)) || candidate.toLowerCase().includes(lower))
+    .sort((a, b) => a.distance - b.distance || compareText(a.candidate, b.candidate))
+    .slice(0, 3)
+    .map(({ candidate }) => candidate);
+}
+
+function findBoundary(map: DomainMap, name: string): DomainBoundary {
+  const boundary = map.boundaries.find(b => b.name === name || b.id === name);
+  if (!boundary) throw new UnknownNameError('boundary', name, suggestNames(name, map.boundaries.map(b => b.name)));
+  return boundary;
+}
+
+function editDistance(a: string, b: string): number {
+  let previous = Array.from({ length: b.length + 1 }, (_, j) => j);
+  for (let i = 1; i <= a.length; i++) {
+    const current = [i];
+    for (let j = 1; j <= b.length; j++) {
+      current[j] = Math.min(previous[j] + 1, current[j - 1] + 1, previous[j - 1] + (a[i - 1] === b[j - 1] ? 0 : 1));
+    }
+    previous = current;
+  }
+  return previous[b.length];
+}
+
+/**
+ * Code unit order; unlike localeCompare it does not depend on the machine's locale
+ */
+function compareText(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
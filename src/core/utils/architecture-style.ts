@@ -0,0 +1,173 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+
+/**
+ * Target architecture of the refactored modules: the four clean-architecture
+ * layers, or ports & adapters (hexagonal)
+ */
+export type ArchitectureStyle = 'clean' | 'hexagonal';
+
+export const ARCHITECTURE_STYLES: ArchitectureStyle[] = ['clean', 'hexagonal'];
+
+/**
+ * Package directories of a module under internal/<module>/
+ */
+export interface ModuleLayout {
+  style: ArchitectureStyle;
+  /** Directories created for a module, in creation order */
+  dirs: string[];
+  /** Entities and value objects */
+  domain: string;
+  /** Use case and repository interfaces */
+  ports: string;
+  /** Use case implementations */
+  service: string;
+  /** Repository implementations (driven adapter) */
+  persistence: string;
+  /** HTTP handlers (driving adapter) */
+  handler: string;
+}
+
+export const MODULE_LAYOUTS: Record<ArchitectureStyle, ModuleLayout> = {
+  clean: {
+    style: 'clean',
+    dirs: ['domain', 'usecase', 'infrastructure', 'handler', 'test'],
+    domain: 'domain',
+    ports: 'domain',
+    service: 'usecase',
+    persistence: 'infrastructure',
+    handler: 'handler',
+  },
+  hexagonal: {
+    style: 'hexagonal',
+    dirs: ['domain', 'port', 'service', 'adapter/handler', 'adapter/repository', 'test'],
+    domain: 'domain',
+    ports: 'port',
+    service: 'service',
+    persistence: 'adapter/repository',
+    handler: 'adapter/handler',
+  },
+};
+
+/**
+ * Style of a --style value
+ */
+export function parseArchitectureStyle(value: string): ArchitectureStyle {
+  const style = value.trim().toLowerCase();
+  if (!(ARCHITECTURE_STYLES as string[]).includes(style)) {
+    throw new Error(`Unknown architecture style "${value}" (expected ${ARCHITECTURE_STYLES.join(' or ')})`);
+  }
+  return style as ArchitectureStyle;
+}
+
+/**
+ * Style of the accepted plan.json; clean when there is no plan or it names none
+ */
+export function loadArchitectureStyle(projectRoot: string): ArchitectureStyle {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    return (ARCHITECTURE_STYLES as unknown[]).includes(plan.architecture_style) ? plan.architecture_style : 'clean';
+  } catch {
+    return 'clean';
+  }
+}
+
+/**
+ * Directories of a module in the given style, relative to the project
+ */
+export function moduleDirectories(moduleName: string, style: ArchitectureStyle): string[] {
+  return [`internal/${moduleName}/`, ...MODULE_LAYOUTS[style].dirs.map(dir => `internal/${moduleName}/${dir}/`)];
+}
+
+/**
+ * File the refactoring creates in the target layout
+ */
+export interface TargetFile {
+  path: string;
+  description: string;
+}
+
+/**
+ * Target files of one source file of a module (plan.json target_files)
+ */
+export interface PlannedTargetFiles {
+  source: string;
+  targets: TargetFile[];
+}
+
+/**
+ * Target files of every source file of a module
+ */
+export function planTargetFiles(moduleName: string, sourceFiles: string[], style: ArchitectureStyle): PlannedTargetFiles[] {
+  return sourceFiles.map(source => ({ source, targets: moduleTargetFiles(moduleName, source, style) }));
+}
+
+/**
+ * Files created from one source file of a module: its entity, and the
+ * module's ports, service, persistence and handler it contributes to
+ */
+export function moduleTargetFiles(moduleName: string, sourceFile: string, style: ArchitectureStyle): TargetFile[] {
+  const root = `internal/${moduleName}`;
+  const layout = MODULE_LAYOUTS[style];
+  const hexagonal = style === 'hexagonal';
+  return [
+    {
+      path: `${root}/${layout.domain}/${path.basename(sourceFile, '.go')}_entity.go`,
+      description: `Extract ${moduleName} domain entity from ${sourceFile}`,
+    },
+    ...(hexagonal ? [{
+      path: `${root}/${layout.ports}/${moduleName}_ports.go`,
+      description: `Declare ${moduleName} inbound (use case) and outbound (repository) port interfaces`,
+    }] : []),
+    {
+      path: `${root}/${layout.service}/${moduleName}_service.go`,
+      description: hexagonal ? `Implement the ${moduleName} inbound port on the outbound ports` : `Create ${moduleName} service with business logic`,
+    },
+    {
+      path: `${root}/${layout.persistence}/${moduleName}_repository.go`,
+      description: hexagonal ? `Create ${moduleName} persistence adapter implementing the repository port` : `Create ${moduleName} repository implementation`,
+    },
+    {
+      path: `${root}/${layout.handler}/${moduleName}_handler.go`,
+      description: hexagonal ? `Create ${moduleName} HTTP adapter calling the inbound port` : `Create ${moduleName} HTTP handler`,
+    },
+  ];
+}
+
+/**
+ * Prompt section of the target layout; empty for clean architecture, which the
+ * transformation prompt already describes
+ */
+export function renderArchitectureSection(style: ArchitectureStyle, boundaryName: string): string {
+  if (style === 'clean') return '';
+  const root = `internal/${boundaryName}`;
+  return `## Target Architecture: Ports & Adapters (hexagonal)
+Place the outputs in these packages instead of domain/usecase/infrastructure/handler:
+- \`${root}/domain/\`: entities and value objects; imports no other package of the module
+- \`${root}/port/\`: the inbound port (the use case interface handlers call) and the outbound ports (repository and gateway interfaces the service needs); imports domain only
+- \`${root}/service/\`: implements the inbound port, depends on outbound ports only
+- \`${root}/adapter/handler/\`: driving adapter calling the inbound port
+- \`${root}/adapter/repository/\`: driven adapters implementing the outbound ports (database, external APIs)
+Report every port interface in "interfaces" with its path under \`${root}/port/\`.
+`;
+}
+
+/**
+ * plan.md section describing the target layout of the refactored modules
+ */
+export function renderArchitectureStyleSection(style: ArchitectureStyle | undefined, modules: string[]): string {
+  if (!style || style === 'clean' || modules.length === 0) return '';
+  const layout = MODULE_LAYOUTS[style];
+  return `## ターゲットアーキテクチャ: ヘキサゴナル（ポート&アダプター）
+
+各モジュールは4層ではなく、ポートとアダプターのパッケージに分割します（\`vf plan --style clean\` で4層に戻せます）。
+
+- \`${layout.domain}/\`: エンティティ・値オブジェクト
+- \`${layout.ports}/\`: 入力ポート（ユースケースのインターフェース）と出力ポート（リポジトリ・外部サービスのインターフェース）
+- \`${layout.service}/\`: 入力ポートの実装。出力ポートにのみ依存
+- \`${layout.handler}/\`: 入力ポートを呼び出す駆動アダプター（HTTPハンドラー）
+- \`${layout.persistence}/\`: 出力ポートを実装する被駆動アダプター（DB・外部API）
+
+対象モジュール: ${modules.join(', ')}`;
+}
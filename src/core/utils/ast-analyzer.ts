@@ -1,5 +1,17 @@
 import * as fs from 'fs';
 import * as path from 'path';
+import { fileURLToPath } from 'url';
+import { PackageLoadError, findPackageLoadErrors, goImports } from './go-load-check.js';
+import { GoPackage, goImportNames, loadGoPackages } from './go-packages.js';
+import { listGoPackages } from './go-list.js';
+import { AnalysisCache } from './analysis-cache.js';
+import { SampleSelection, SamplingOptions, selectSample } from './discovery-sampling.js';
+import { PathFilter, describePathFilters, matchesPathFilters } from './path-filters.js';
+import { SourceAnnotation, parseAnnotations, resolveKeepTogether } from './source-annotations.js';
+import { functionValueCandidates } from './function-values.js';
+import { WorkerPool } from './worker-pool.js';
+import { getErrorMessage } from './error-utils.js';
+import { isGeneratedGoFile } from './generated-code.js';
 
 export interface ASTNode {
   type: string;
@@ -49,6 +61,8 @@ export interface GoFunction extends ASTNode {
   parameters: ASTParameter[];
   returnType: string;
   calls: string[];
+  /** Functions and methods the body passes, stores or assigns as values (`Register(order.Process)`) */
+  function_values: string[];
   tables_accessed: string[];
 }
 
@@ -71,52 +85,318 @@ export interface ModuleCandidateNode {
   external_dependencies: string[];
 }
 
+export interface GoFileAnalysis {
+  structs: GoStruct[];
+  interfaces: GoInterface[];
+  functions: GoFunction[];
+  database_access: DatabaseAccess[];
+  /** `//vf:` directives of the file */
+  annotations: SourceAnnotation[];
+}
+
+export interface ASTAnalyzerOptions {
+  /** Analyze a representative sample instead of the default file cap */
+  sampling?: SamplingOptions;
+  /** Keep file results in the analysis cache so re-runs only re-analyze changed files */
+  cache?: boolean;
+  /** Packages analyzed in parallel worker threads (default 1: in process) */
+  concurrency?: number;
+  /** Only analyze the files under these directories (vf discover --path / --package) */
+  pathFilters?: PathFilter[];
+}
+
+/**
+ * Files of one package to analyze, as sent to the worker threads (ast-worker.ts)
+ */
+export interface PackageAnalysisTask {
+  package: string;
+  files: { path: string; content: string }[];
+}
+
+/**
+ * Analysis of one file of a task, or why it failed
+ */
+export interface FileAnalysisResult {
+  path: string;
+  analysis?: GoFileAnalysis;
+  error?: string;
+}
+
+/** Entry of the worker threads; only present in the built package */
+const WORKER_SCRIPT = new URL('./ast-worker.js', import.meta.url);
+
 export class ASTAnalyzer {
   private projectRoot: string;
+  private packages: GoPackage[] = [];
+  private sampling?: SamplingOptions;
+  private cache: AnalysisCache<GoFileAnalysis> | null = null;
+  private concurrency: number;
+  private pathFilters?: PathFilter[];
 
-  constructor(projectRoot: string) {
+  constructor(projectRoot: string, options: ASTAnalyzerOptions = {}) {
     this.projectRoot = projectRoot;
+    this.sampling = options.sampling;
+    this.pathFilters = options.pathFilters?.length ? options.pathFilters : undefined;
+    this.concurrency = Math.max(1, Math.floor(options.concurrency ?? 1));
+    if (options.sampling || options.cache) {
+      // Sampled runs are ramped up (20% → 100%) and discovery re-runs, so file results are kept across runs
+      this.cache = new AnalysisCache<GoFileAnalysis>(projectRoot, { namespace: 'go-structure' });
+    }
   }
 
+  /**
+   * Packages that fail to load are not fatal: their files get syntax-only
+   * analysis (imports and top-level declarations) and are listed in
+   * `degraded_files` / `load_errors`.
+   * Dependencies are import paths, resolved through the declared package names
+   * in `packages` rather than directory names.
+   * With `sampling`, only the selected files are analyzed and `sample` describes
+   * what was skipped.
+   * With `pathFilters`, files outside the directories are not analyzed at all.
+   */
   async analyzeGoProject(): Promise<{
     structs: GoStruct[];
     interfaces: GoInterface[];
     functions: GoFunction[];
     database_access: DatabaseAccess[];
+    load_errors: PackageLoadError[];
+    degraded_files: string[];
+    /** Generated files (*.pb.go, mocks, "DO NOT EDIT" headers), read but not analyzed */
+    generated_files: string[];
+    packages: GoPackage[];
+    annotations: SourceAnnotation[];
+    sample?: SampleSelection;
   }> {
     console.log('🔍 Goプロジェクトを詳細分析中...');
     
-    const goFiles = await this.findGoFiles();
-    
-    // 大規模プロジェクトの場合は重要なファイルのみをサンプリング
-    const maxFiles = 150;
-    const filesToAnalyze = goFiles.length > maxFiles ? 
-      this.selectImportantFiles(goFiles, maxFiles) : goFiles;
+    let goFiles = await this.findGoFiles();
+    if (this.pathFilters) {
+      const filters = this.pathFilters;
+      goFiles = goFiles.filter(file => matchesPathFilters(path.relative(this.projectRoot, file), filters));
+      if (goFiles.length === 0) {
+        throw new Error(`No Go files under ${describePathFilters(filters)}`);
+      }
+      console.log(`🔎 範囲指定の分析: ${goFiles.length}ファイル (${describePathFilters(filters)})`);
+    }
+    this.packages = loadGoPackages(this.projectRoot);
     
-    if (filesToAnalyze.length < goFiles.length) {
-      console.log(`⚡ パフォーマンス向上のため${filesToAnalyze.length}/${goFiles.length}ファイルをサンプリング分析`);
+    let filesToAnalyze: string[];
+    let sample: SampleSelection | undefined;
+    if (this.sampling) {
+      // 指定された割合で代表的なファイルを層化サンプリング
+      sample = selectSample(this.projectRoot, goFiles.map(file => path.relative(this.projectRoot, file)), this.sampling, this.packages);
+      filesToAnalyze = sample.files.map(file => path.join(this.projectRoot, file));
+      console.log(`🎯 サンプリング分析: ${sample.files.length}/${sample.total}ファイル (${sample.skipped}ファイルをスキップ)`);
+    } else {
+      // 大規模プロジェクトの場合は重要なファイルのみをサンプリング
+      const maxFiles = 150;
+      filesToAnalyze = goFiles.length > maxFiles ? 
+        this.selectImportantFiles(goFiles, maxFiles) : goFiles;
+      
+      if (filesToAnalyze.length < goFiles.length) {
+        console.log(`⚡ パフォーマンス向上のため${filesToAnalyze.length}/${goFiles.length}ファイルをサンプリング分析`);
+      }
     }
     
     const structs: GoStruct[] = [];
     const interfaces: GoInterface[] = [];
     const functions: GoFunction[] = [];
     const databaseAccess: DatabaseAccess[] = [];
+    const annotations: SourceAnnotation[] = [];
 
-    for (const file of filesToAnalyze) {
-      const content = fs.readFileSync(file, 'utf8');
-      const relativePath = path.relative(this.projectRoot, file);
-      
-      const fileAnalysis = this.analyzeGoFile(content, relativePath);
-      
+    const relativePaths = filesToAnalyze.map(file => path.relative(this.projectRoot, file));
+    const loadErrors = findPackageLoadErrors(this.projectRoot, relativePaths);
+    const degraded = new Set(loadErrors.flatMap(e => e.files));
+
+    // Degraded and cached files are settled here; the rest is analyzed package by package
+    const analyses = new Map<string, GoFileAnalysis>();
+    const contents = new Map<string, { content: string; key?: string }>();
+    const pending = new Map<string, PackageAnalysisTask>();
+    const generated: string[] = [];
+    for (const relativePath of relativePaths) {
+      let content: string;
+      try {
+        content = fs.readFileSync(path.join(this.projectRoot, relativePath), 'utf8');
+      } catch {
+        continue; // Reported by findPackageLoadErrors
+      }
+      // Generated code follows its source and is not clustered on its own
+      if (isGeneratedGoFile(relativePath, content)) {
+        generated.push(relativePath);
+        continue;
+      }
+
+      if (degraded.has(relativePath)) {
+        analyses.set(relativePath, this.analyzeGoFileSyntaxOnly(content, relativePath));
+        continue;
+      }
+      const key = this.cacheKey(content);
+      const cached = key ? this.cache!.get(relativePath, key) : null;
+      if (cached) {
+        analyses.set(relativePath, cached);
+        continue;
+      }
+      contents.set(relativePath, { content, key });
+      const dir = path.dirname(relativePath) || '.';
+      if (!pending.has(dir)) pending.set(dir, { package: dir, files: [] });
+      pending.get(dir)!.files.push({ path: relativePath, content });
+    }
+
+    for (const result of await this.analyzePackages([...pending.values()])) {
+      const { content, key } = contents.get(result.path)!;
+      if (result.analysis) {
+        analyses.set(result.path, result.analysis);
+        if (key) this.cache!.put(result.path, key, result.analysis);
+        continue;
+      }
+      // Keep going with what the declarations tell us
+      const dir = path.dirname(result.path) || '.';
+      const message = `${result.path}: analysis failed: ${result.error}`;
+      const existing = loadErrors.find(e => e.package === dir);
+      if (existing) existing.errors.push(message);
+      else loadErrors.push({ package: dir, files: relativePaths.filter(f => (path.dirname(f) || '.') === dir), errors: [message] });
+      degraded.add(result.path);
+      analyses.set(result.path, this.analyzeGoFileSyntaxOnly(content, result.path));
+    }
+
+    for (const relativePath of relativePaths) {
+      const fileAnalysis = analyses.get(relativePath);
+      if (!fileAnalysis) continue;
       structs.push(...fileAnalysis.structs);
       interfaces.push(...fileAnalysis.interfaces);
       functions.push(...fileAnalysis.functions);
       databaseAccess.push(...fileAnalysis.database_access);
+      annotations.push(...fileAnalysis.annotations);
     }
+    this.cache?.flush();
+
+    // Only names declared in the project are function values; the rest are ordinary variables
+    const declared = new Set(functions.map(fn => fn.name));
+    const resolved = functions.map(fn => ({ ...fn, function_values: fn.function_values.filter(value => declared.has(value.split('.').pop()!)) }));
 
     console.log(`📊 分析完了: ${structs.length}構造体, ${interfaces.length}インターフェース, ${functions.length}関数`);
+    if (loadErrors.length > 0) {
+      console.log(`⚠️  ${loadErrors.length}パッケージを構文のみで解析 (degraded): ${loadErrors.map(e => e.package).join(', ')}`);
+    }
     
-    return { structs, interfaces, functions, database_access: databaseAccess };
+    return {
+      structs,
+      interfaces,
+      functions: resolved,
+      database_access: databaseAccess,
+      load_errors: loadErrors,
+      degraded_files: relativePaths.filter(f => degraded.has(f)),
+      generated_files: generated,
+      packages: this.packages,
+      annotations: resolveKeepTogether(annotations, resolved),
+      ...(sample ? { sample } : {}),
+    };
+  }
+
+  /**
+   * Full analysis of the files of one package; a file that fails is reported
+   * instead of failing the package. This is what the worker threads run.
+   */
+  analyzePackage(task: PackageAnalysisTask): FileAnalysisResult[] {
+    return task.files.map(file => {
+      try {
+        return { path: file.path, analysis: this.analyzeGoFile(file.content, file.path) };
+      } catch (error) {
+        return { path: file.path, error: getErrorMessage(error) };
+      }
+    });
+  }
+
+  /**
+   * Packages import names resolve through (set in worker threads, which do not load them)
+   */
+  usePackages(packages: GoPackage[]): void {
+    this.packages = packages;
+  }
+
+  /**
+   * キャッシュのキー（キャッシュはサンプリング時と境界発見で有効）
+   * 依存先はimport名から解決するため、importしているパッケージ名もキーに含める
+   */
+  private cacheKey(content: string): string | undefined {
+    if (!this.cache) return undefined;
+    const imports = JSON.stringify([...goImportNames(content, this.packages)]);
+    return AnalysisCache.hashContent(`${content}\0${imports}`);
+  }
+
+  /**
+   * パッケージ単位で解析し、パッケージごとに進捗を表示。concurrency が2以上なら
+   * ワーカースレッドで並列に解析する（結果はファイルの順序に依存しない）
+   */
+  private async analyzePackages(tasks: PackageAnalysisTask[]): Promise<FileAnalysisResult[]> {
+    if (tasks.length === 0) return [];
+    let done = 0;
+    const progress = (task: PackageAnalysisTask, results: FileAnalysisResult[]) => {
+      done++;
+      console.log(`   📦 [${done}/${tasks.length}] ${task.package} (${task.files.length}ファイル)`);
+      return results;
+    };
+
+    const workers = Math.min(this.concurrency, tasks.length);
+    if (workers > 1 && !fs.existsSync(fileURLToPath(WORKER_SCRIPT))) {
+      console.warn('⚠️  ワーカースクリプトが見つかりません（ビルド前のソースから実行中）。並列解析せずに続行します');
+    } else if (workers > 1) {
+      console.log(`🧵 ${workers}スレッドで${tasks.length}パッケージを並列解析`);
+      const pool = new WorkerPool<PackageAnalysisTask, FileAnalysisResult[]>(WORKER_SCRIPT, workers, {
+        projectRoot: this.projectRoot,
+        packages: this.packages,
+      });
+      try {
+        const results = await Promise.all(tasks.map(task =>
+          pool.run(task)
+            // A crashed worker does not lose the package: it is analyzed here instead
+            .catch(() => this.analyzePackage(task))
+            .then(results => progress(task, results))
+        ));
+        return results.flat();
+      } finally {
+        await pool.close();
+      }
+    }
+
+    return tasks.flatMap(task => progress(task, this.analyzePackage(task)));
+  }
+
+  /**
+   * Syntax-only fallback for files of packages that fail to load:
+   * top-level declarations, with imports as dependencies
+   */
+  private analyzeGoFileSyntaxOnly(content: string, filePath: string): GoFileAnalysis {
+    const dependencies = [...new Set(goImports(content).map(i => i.path))];
+    const structs: GoStruct[] = [];
+    const interfaces: GoInterface[] = [];
+    const functions: GoFunction[] = [];
+
+    content.split('\n').forEach((line, index) => {
+      const typeMatch = line.match(/^type\s+(\w+)\s+(struct|interface)\b/);
+      const funcMatch = line.match(/^func\s*(?:\(\s*(\w+)\s+\*?(\w+)\s*\))?\s*(\w+)\s*\(/);
+      const base = { name: '', file: filePath, line: index + 1, dependencies };
+
+      if (typeMatch?.[2] === 'struct') {
+        structs.push({ ...base, type: 'struct', name: typeMatch[1], properties: [], methods: [], implementsInterfaces: [], embeds: [] });
+      } else if (typeMatch?.[2] === 'interface') {
+        interfaces.push({ ...base, type: 'interface', name: typeMatch[1], methods: [], extends: [] });
+      } else if (funcMatch) {
+        functions.push({
+          ...base,
+          type: 'function',
+          name: funcMatch[3],
+          receiver: funcMatch[1] ? `${funcMatch[1]} ${funcMatch[2]}` : undefined,
+          parameters: [],
+          returnType: 'void',
+          calls: [],
+          function_values: [],
+          tables_accessed: [],
+        });
+      }
+    });
+
+    return { structs, interfaces, functions, database_access: [], annotations: parseAnnotations(content, filePath).annotations };
   }
 
   private selectImportantFiles(files: string[], maxCount: number): string[] {
@@ -150,7 +430,19 @@ export class ASTAnalyzer {
     return scored.slice(0, maxCount).map(item => item.file);
   }
 
+  /**
+   * Non-test files the toolchain builds (go list); files excluded by build constraints
+   * would otherwise form boundaries of code that is never compiled
+   */
   private async findGoFiles(): Promise<string[]> {
+    const listed = listGoPackages(this.projectRoot);
+    if (listed) {
+      if (listed.ignored_files.length > 0) {
+        console.log(`🏷️  ビルド制約で除外されたファイル: ${listed.ignored_files.length}件（分析対象外）`);
+      }
+      return listed.packages.flatMap(pkg => pkg.files).map(file => path.resolve(this.projectRoot, file));
+    }
+
     const { execSync } = await import('child_process');
     
     try {
@@ -173,13 +465,9 @@ export class ASTAnalyzer {
     }
   }
 
-  private analyzeGoFile(content: string, filePath: string): {
-    structs: GoStruct[];
-    interfaces: GoInterface[];
-    functions: GoFunction[];
-    database_access: DatabaseAccess[];
-  } {
+  private analyzeGoFile(content: string, filePath: string): GoFileAnalysis {
     const lines = content.split('\n');
+    const imports = goImportNames(content, this.packages);
     const structs: GoStruct[] = [];
     const interfaces: GoInterface[] = [];
     const functions: GoFunction[] = [];
@@ -192,7 +480,7 @@ export class ASTAnalyzer {
       // Struct definition
       const structMatch = line.match(/type\s+(\w+)\s+struct\s*{?$/);
       if (structMatch) {
-        const struct = this.parseStruct(lines, i, structMatch[1], filePath);
+        const struct = this.parseStruct(lines, i, structMatch[1], filePath, imports);
         if (struct) structs.push(struct);
       }
       
@@ -217,10 +505,16 @@ export class ASTAnalyzer {
       }
     }
 
-    return { structs, interfaces, functions, database_access: databaseAccess };
+    return {
+      structs,
+      interfaces,
+      functions,
+      database_access: databaseAccess,
+      annotations: parseAnnotations(content, filePath).annotations,
+    };
   }
 
-  private parseStruct(lines: string[], startLine: number, name: string, filePath: string): GoStruct | null {
+  private parseStruct(lines: string[], startLine: number, name: string, filePath: string, imports: Map<string, string>): GoStruct | null {
     const properties: ASTProperty[] = [];
     const dependencies: string[] = [];
     const implementsInterfaces: string[] = [];
@@ -235,10 +529,9 @@ export class ASTAnalyzer {
         i++;
       }
       if (i >= lines.length) return null;
+      i++; // Move past opening brace
     }
 
-    i++; // Move past opening brace
-    
     while (i < lines.length && braceCount > 0) {
       const line = lines[i].trim();
       
@@ -257,17 +550,16 @@ export class ASTAnalyzer {
             tags: tags ? [tags] : [],
           });
           
-          // Extract dependencies from field types
-          if (fieldType.includes('.')) {
-            const typeParts = fieldType.split('.');
-            if (typeParts.length > 1) {
-              dependencies.push(typeParts[0]);
-            }
+          // Extract dependencies from field types (the package a qualifier refers to)
+          const qualifier = fieldType.match(/(\w+)\.\w+/)?.[1];
+          const importPath = qualifier ? imports.get(qualifier) : undefined;
+          if (importPath && !dependencies.includes(importPath)) {
+            dependencies.push(importPath);
           }
         }
         
-        // Check for embedded types
-        const embedMatch = line.match(/^([A-Z]\w+)$/);
+        // Check for embedded types (Base, *Base, notify.Base)
+        const embedMatch = line.match(/^\*?(?:\w+\.)?([A-Z]\w*)$/);
         if (embedMatch) {
           embeds.push(embedMatch[1]);
         }
@@ -303,10 +595,9 @@ export class ASTAnalyzer {
         i++;
       }
       if (i >= lines.length) return null;
+      i++; // Move past opening brace
     }
 
-    i++; // Move past opening brace
-    
     while (i < lines.length && braceCount > 0) {
       const line = lines[i].trim();
       
@@ -365,12 +656,14 @@ export class ASTAnalyzer {
     // Parse function body for function calls and database access
     const calls: string[] = [];
     const tablesAccessed: string[] = [];
+    const body: string[] = [];
     
     let i = startLine + 1;
     let braceCount = 1;
     
     while (i < lines.length && braceCount > 0) {
       const bodyLine = lines[i].trim();
+      body.push(lines[i]);
       
       if (bodyLine.includes('{')) braceCount++;
       if (bodyLine.includes('}')) braceCount--;
@@ -419,6 +712,7 @@ export class ASTAnalyzer {
       parameters,
       returnType: returnType.trim(),
       calls: [...new Set(calls)], // Remove duplicates
+      function_values: functionValueCandidates(body.join('\n')),
       tables_accessed: [...new Set(tablesAccessed)],
     };
   }
@@ -0,0 +1,14 @@
+import { workerData } from 'worker_threads';
+import { ASTAnalyzer, FileAnalysisResult, PackageAnalysisTask } from './ast-analyzer.js';
+import { GoPackage } from './go-packages.js';
+import { serveWorker } from './worker-pool.js';
+
+/**
+ * Worker thread of ASTAnalyzer's `concurrency`: analyzes one package per task
+ * with the packages the main thread loaded
+ */
+const { projectRoot, packages } = workerData as { projectRoot: string; packages: GoPackage[] };
+const analyzer = new ASTAnalyzer(projectRoot);
+analyzer.usePackages(packages);
+
+serveWorker<PackageAnalysisTask, FileAnalysisResult[]>(task => analyzer.analyzePackage(task));
@@ -1,6 +1,51 @@
 import * as fs from 'fs';
 import * as path from 'path';
-import { ASTAnalyzer, ModuleCandidateNode, GoStruct, GoInterface, GoFunction, DatabaseAccess } from './ast-analyzer.js';
+import { ASTAnalyzer, ASTAnalyzerOptions, ModuleCandidateNode, GoStruct, GoInterface, GoFunction, DatabaseAccess, GoFileAnalysis } from './ast-analyzer.js';
+import { BoundaryConstraints, CallGraphConfig, ClusteringConfig, CoChangeConfig, ConfidenceBreakdown, EmbeddingsConfig, GeneratedFile } from '../types/config.js';
+import { ModuleAdapter, ConstraintViolation, resolveConstraints, fileBelongsTo } from './boundary-constraints.js';
+import { PackageLoadError } from './go-load-check.js';
+import { GoPackage } from './go-packages.js';
+import { SampleSelection } from './discovery-sampling.js';
+import { SourceAnnotation } from './source-annotations.js';
+import { impliedPackageName } from './go-project-utils.js';
+import { CoChangeAnalysis, CoChangeIndex, DEFAULT_CO_CHANGE_WEIGHT, mineCoChanges } from './co-change.js';
+import { CallGraph, CallGraphIndex, buildCallGraph, syntacticCallGraph } from './call-graph.js';
+import { DEFAULT_EMBEDDING_WEIGHT, EmbeddingIndex, embedFiles } from './semantic-embeddings.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { getErrorMessage } from './error-utils.js';
+import { TYPE_RELATION_WEIGHT, TypeRelationIndex, TypeRelations, buildTypeRelations, syntacticTypeRelations } from './type-relations.js';
+import { CommunityAlgorithm, WeightedEdge, detectCommunities, modularity } from './community-detection.js';
+import {
+  DataOwnershipMap,
+  TABLE_OWNERSHIP_WEIGHT,
+  TableOwnership,
+  TableOwnershipIndex,
+  buildDataOwnership,
+  findOrmTables,
+  findTableAccess,
+  loadSchemaTables,
+  ownsTable,
+} from './table-ownership.js';
+import { loadGoWorkspace } from './go-workspace.js';
+import { HttpRoute, ROUTE_PREFIX_WEIGHT, RoutePrefixIndex, findHttpRoutes } from './http-routes.js';
+import { GrpcService, findGrpcServices, grpcSeeds } from './grpc-services.js';
+import { MessageEndpoint, MessageTopicIndex, findMessageEndpoints } from './message-topics.js';
+import { findGeneratedCode } from './generated-code.js';
+import { GranularityAdapter, adjustGranularity } from './module-granularity.js';
+import { explainConfidence } from './boundary-confidence.js';
+import { AnalysisGraph, buildAnalysisGraph } from './analysis-db.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Nodes the pairwise distance clustering compares at most (sampled beyond) */
+const DISTANCE_MAX_NODES = 100;
+/** Nodes the community detection graph holds at most (sampled beyond) */
+const COMMUNITY_MAX_NODES = 1000;
+/**
+ * Dependency strength an edge of the community graph needs: sharing a directory
+ * alone (0.2) does not link two nodes, or a large package becomes one community
+ */
+const MIN_COMMUNITY_EDGE_WEIGHT = 0.2;
+
 export interface AutoDiscoveredBoundary {
   name: string;
   description: string;
@@ -13,6 +58,11 @@ export interface AutoDiscoveredBoundary {
   reasoning: string[];
   semantic_keywords: string[];
   dependency_clusters: string[];
+  merged_from?: string[];
+  /** Files of packages that failed to load (syntax-only analysis) */
+  degraded_files?: string[];
+  /** What the confidence is made of, with a rationale for reviewers */
+  confidence_breakdown?: ConfidenceBreakdown;
 }
 
 export interface BoundaryDiscoveryResult {
@@ -20,6 +70,32 @@ export interface BoundaryDiscoveryResult {
   confidence_metrics: ConfidenceMetrics;
   clustering_analysis: ClusteringAnalysis;
   recommendations: BoundaryRecommendation[];
+  constraint_adjustments?: string[];
+  constraint_violations?: ConstraintViolation[];
+  /** Packages analyzed syntax-only because they failed to load */
+  load_errors?: PackageLoadError[];
+  /** Files analyzed in sampling mode; absent when the whole project was analyzed */
+  sample?: SampleSelection;
+  /** `//vf:` directives of the analyzed files; they override the clustering */
+  annotations?: SourceAnnotation[];
+  /** Files that changed together in the git history; absent without history or when disabled */
+  co_change?: CoChangeAnalysis;
+  /** Schema tables with the structs and queries that touch them; absent without schema.sql or migrations */
+  table_ownership?: TableOwnership;
+  /** Call edges between the project's functions (callgraph cha/rta, else matched by name) */
+  call_graph?: CallGraph;
+  /** Embedded types and interface satisfaction between the project's types (go/types, else matched by name) */
+  type_relations?: TypeRelations;
+  /** Services of the .proto files; their generated and implementing packages seed one module each */
+  grpc_services?: GrpcService[];
+  /** Router registrations with the file declaring each handler; absent when none were found */
+  http_routes?: HttpRoute[];
+  /** Kafka/NATS/SQS publish and subscribe call sites with their topics */
+  message_endpoints?: MessageEndpoint[];
+  /** Generated files left out of clustering, with the go:generate directive producing each */
+  generated_code?: GeneratedFile[];
+  /** Table → struct → package → module map of the discovered boundaries, with tables several modules access */
+  data_ownership?: DataOwnershipMap;
 }
 
 export interface ConfidenceMetrics {
@@ -35,6 +111,13 @@ export interface ClusteringAnalysis {
   cluster_quality_score: number;
   boundary_overlaps: BoundaryOverlap[];
   orphaned_files: string[];
+  /** Clustering of the dependency graph (boundary.yaml clustering) */
+  algorithm?: 'distance' | CommunityAlgorithm;
+  resolution?: number;
+  /** Modularity of the detected communities (louvain/leiden) */
+  modularity?: number;
+  /** Splits and merges towards boundary.yaml clustering targetModules/minFiles/maxFiles */
+  granularity_adjustments?: string[];
 }
 
 export interface BoundaryOverlap {
@@ -56,10 +139,73 @@ export interface BoundaryRecommendation {
 export class AutoBoundaryDiscovery {
   private astAnalyzer: ASTAnalyzer;
   private projectRoot: string;
+  private constraints?: BoundaryConstraints;
+  private constraintAdjustments: string[] = [];
+  private constraintViolations: ConstraintViolation[] = [];
+  private degradedFiles = new Set<string>();
+  private generatedCode?: GeneratedFile[];
+  private packages: GoPackage[] = [];
+  private coChangeConfig?: CoChangeConfig;
+  private coChange?: CoChangeAnalysis;
+  private coChangeIndex?: CoChangeIndex;
+  private schemaPaths?: string[];
+  private tableOwnership?: TableOwnership;
+  private tableIndex?: TableOwnershipIndex;
+  private callGraphConfig?: CallGraphConfig;
+  private callGraph?: CallGraph;
+  private callIndex?: CallGraphIndex;
+  private embeddingsConfig?: EmbeddingsConfig;
+  private embeddingIndex?: EmbeddingIndex;
+  private typeRelations?: TypeRelations;
+  private typeIndex?: TypeRelationIndex;
+  private httpRoutes?: HttpRoute[];
+  private routeIndex?: RoutePrefixIndex;
+  private messageEndpoints?: MessageEndpoint[];
+  private topicIndex?: MessageTopicIndex;
+  private clusteringConfig?: ClusteringConfig;
+  private communityModularity?: number;
+  private granularityAdjustments: string[] = [];
+  /** Files declaring each type and function name, to split boundaries by file */
+  private declarationFiles = new Map<string, Set<string>>();
+  /** boundary.yaml seeds */
+  private configuredSeeds: Record<string, string[]>;
+  /** boundary.yaml seeds with those of the project's gRPC services */
+  private seeds: Record<string, string[]> = {};
+  private grpcServices?: GrpcService[];
+  /** Seed paths (file or package directory) with their module, most specific first */
+  private seedPaths: [string, string][] = [];
+  /** Symbols of the last discovery, kept for analysisGraph() */
+  private declarations?: GoFileAnalysis;
 
-  constructor(projectRoot: string) {
+  /**
+   * @param options.coChange - How the git history is mined for files that change together (boundary.yaml coChange)
+   * @param options.schema - schema.sql / migration paths (vibeflow.config.yaml repository.schema); detected when absent
+   * @param options.callGraph - How the call graph is built (boundary.yaml callGraph)
+   * @param options.embeddings - Embedding provider whose file similarity adds to clustering (boundary.yaml embeddings)
+   * @param options.clustering - Algorithm grouping the dependency graph and target granularity (boundary.yaml clustering)
+   * @param options.seeds - Files, package directories or import paths known to belong to each module (boundary.yaml modules.<name>.seeds)
+   */
+  constructor(
+    projectRoot: string,
+    constraints?: BoundaryConstraints,
+    options: ASTAnalyzerOptions & {
+      coChange?: CoChangeConfig;
+      schema?: string[];
+      callGraph?: CallGraphConfig;
+      embeddings?: EmbeddingsConfig;
+      clustering?: ClusteringConfig;
+      seeds?: Record<string, string[]>;
+    } = {}
+  ) {
     this.projectRoot = projectRoot;
-    this.astAnalyzer = new ASTAnalyzer(projectRoot);
+    this.astAnalyzer = new ASTAnalyzer(projectRoot, options);
+    this.constraints = constraints;
+    this.coChangeConfig = options.coChange;
+    this.schemaPaths = options.schema;
+    this.callGraphConfig = options.callGraph;
+    this.embeddingsConfig = options.embeddings;
+    this.clusteringConfig = options.clustering;
+    this.configuredSeeds = options.seeds ?? {};
   }
 
   async discoverBoundaries(): Promise<BoundaryDiscoveryResult> {
@@ -67,6 +213,24 @@ export class AutoBoundaryDiscovery {
     
     // 1. AST解析でコード構造を抽出
     const astAnalysis = await this.astAnalyzer.analyzeGoProject();
+    this.degradedFiles = new Set(astAnalysis.degraded_files);
+    this.packages = astAnalysis.packages;
+    this.declarations = astAnalysis;
+    this.declarationFiles = new Map();
+    for (const node of [...astAnalysis.structs, ...astAnalysis.interfaces, ...astAnalysis.functions]) {
+      this.declarationFiles.set(node.name, new Set([...(this.declarationFiles.get(node.name) ?? []), toPosixPath(node.file)]));
+    }
+    this.logWorkspace();
+    this.findGeneratedCode();
+    this.seedGrpcServices();
+    this.resolveSeeds([...astAnalysis.structs, ...astAnalysis.interfaces, ...astAnalysis.functions].map(n => n.file));
+    this.mineCoChanges();
+    await this.embedFiles([...astAnalysis.structs, ...astAnalysis.interfaces, ...astAnalysis.functions].map(n => n.file));
+    this.buildCallGraph(astAnalysis.functions, astAnalysis.sample !== undefined);
+    this.buildTypeRelations(astAnalysis.structs, astAnalysis.interfaces, astAnalysis.functions, astAnalysis.sample !== undefined);
+    this.mapSchemaTables([...astAnalysis.structs, ...astAnalysis.interfaces, ...astAnalysis.functions].map(n => n.file));
+    this.findHttpRoutes([...astAnalysis.structs, ...astAnalysis.interfaces, ...astAnalysis.functions].map(n => n.file));
+    this.findMessageTopics(astAnalysis.functions.map(n => n.file));
     
     // 2. セマンティッククラスタリング
     const semanticClusters = await this.astAnalyzer.findSemanticClusters(
@@ -88,18 +252,24 @@ export class AutoBoundaryDiscovery {
       astAnalysis.functions
     );
     
+    // 4b. スキーマのテーブル所有（構造体マッピング・書き込みクエリ）
+    const tableOwnershipClusters = this.analyzeTableOwnership(
+      [...astAnalysis.structs, ...astAnalysis.functions]
+    );
+    
     // 5. ファイルパス・ディレクトリ構造分析
     const structuralClusters = await this.analyzeStructuralPatterns(
       [...astAnalysis.structs, ...astAnalysis.interfaces, ...astAnalysis.functions]
     );
     
-    // 6. 複数手法の結果をマージ
-    const mergedBoundaries = await this.mergeClusteringResults([
+    // 6. 複数手法の結果をマージ（シードのファイルは各モジュールへ）
+    const mergedBoundaries = this.applySeeds(await this.mergeClusteringResults([
       semanticClusters,
       dependencyClusters,
       databaseClusters,
+      tableOwnershipClusters,
       structuralClusters,
-    ]);
+    ]), [...astAnalysis.structs, ...astAnalysis.interfaces, ...astAnalysis.functions]);
     
     // 7. 境界の信頼度評価
     const boundariesWithConfidence = await this.evaluateBoundaryConfidence(
@@ -116,15 +286,51 @@ export class AutoBoundaryDiscovery {
     const clusteringAnalysis = this.analyzeClusteringQuality(optimizedBoundaries);
     
     console.log(`✨ ${optimizedBoundaries.length}個の境界を自動発見（信頼度${confidenceMetrics.overall_confidence.toFixed(1)}%）`);
+    const dataOwnership = this.buildDataOwnership(optimizedBoundaries);
     
     return {
       discovered_boundaries: optimizedBoundaries,
       confidence_metrics: confidenceMetrics,
       clustering_analysis: clusteringAnalysis,
       recommendations,
+      ...(astAnalysis.load_errors.length > 0 ? { load_errors: astAnalysis.load_errors } : {}),
+      ...(astAnalysis.sample ? { sample: astAnalysis.sample } : {}),
+      ...(astAnalysis.annotations.length > 0 ? { annotations: astAnalysis.annotations } : {}),
+      ...(this.coChange ? { co_change: this.coChange } : {}),
+      ...(this.tableOwnership ? { table_ownership: this.tableOwnership } : {}),
+      ...(this.callGraph ? { call_graph: this.callGraph } : {}),
+      ...(this.typeRelations ? { type_relations: this.typeRelations } : {}),
+      ...(dataOwnership ? { data_ownership: dataOwnership } : {}),
+      ...(this.httpRoutes ? { http_routes: this.httpRoutes } : {}),
+      ...(this.grpcServices ? { grpc_services: this.grpcServices } : {}),
+      ...(this.messageEndpoints ? { message_endpoints: this.messageEndpoints } : {}),
+      ...(this.generatedCode ? { generated_code: this.generatedCode } : {}),
+      ...(this.constraints ? {
+        constraint_adjustments: this.constraintAdjustments,
+        constraint_violations: this.constraintViolations,
+      } : {}),
     };
   }
 
+  /**
+   * The full graph of the last discovery (files, symbols, calls, type relations,
+   * table access) with the files of the final boundaries; undefined before discovery
+   */
+  analysisGraph(boundaries: { name: string; files: string[] }[]): AnalysisGraph | undefined {
+    if (!this.declarations) return undefined;
+    return buildAnalysisGraph(this.projectRoot, {
+      packages: this.packages,
+      structs: this.declarations.structs,
+      interfaces: this.declarations.interfaces,
+      functions: this.declarations.functions,
+      database_access: this.declarations.database_access,
+      call_graph: this.callGraph,
+      type_relations: this.typeRelations,
+      table_ownership: this.tableOwnership,
+      boundaries,
+    });
+  }
+
   private async performDependencyBasedClustering(
     structs: GoStruct[],
     interfaces: GoInterface[],
@@ -133,13 +339,18 @@ export class AutoBoundaryDiscovery {
     console.log('🔗 依存関係ベースクラスタリング実行中...');
     
     const allNodes = [...structs, ...interfaces, ...functions];
+    const algorithm = this.clusteringConfig?.algorithm ?? 'distance';
+    this.communityModularity = undefined;
     
     // 大規模データセットの場合はサンプリングして処理速度を向上
-    const maxNodes = 100;
+    const maxNodes = algorithm === 'distance' ? DISTANCE_MAX_NODES : COMMUNITY_MAX_NODES;
     const sampledNodes = allNodes.length > maxNodes ? 
       this.sampleNodes(allNodes, maxNodes) : allNodes;
     
     try {
+      if (algorithm !== 'distance') {
+        return this.performCommunityClustering(sampledNodes, algorithm);
+      }
       // 簡単な距離ベースクラスタリングを使用（K-meansより高速）
       const clusters = this.performSimpleDistanceClustering(sampledNodes);
       return clusters;
@@ -150,7 +361,304 @@ export class AutoBoundaryDiscovery {
   }
 
 
+  /**
+   * Git履歴から同時に変更されるファイルの組を抽出（履歴がなければ構造のみでクラスタリング）
+   */
+  private mineCoChanges(): void {
+    this.coChange = undefined;
+    this.coChangeIndex = undefined;
+    if (this.coChangeConfig?.enabled === false) return;
+
+    const analysis = mineCoChanges(this.projectRoot, this.coChangeConfig);
+    if (!analysis) return;
+    this.coChange = analysis;
+    this.coChangeIndex = new CoChangeIndex(analysis);
+    console.log(`🕰️  Git履歴の同時変更: ${analysis.commits}コミットから${analysis.pairs.length}組のファイル`);
+  }
+
+  /**
+   * ファイルのパス・識別子・コメントを埋め込み、コサイン類似度をクラスタリングの重みに加える（boundary.yaml embeddings、
+   * 失敗・--offline では構造のみでクラスタリング）。ユーティリティ的な名前のディレクトリに散らばった同じドメインをまとめる
+   */
+  private async embedFiles(files: string[]): Promise<void> {
+    this.embeddingIndex = undefined;
+    const config = this.embeddingsConfig;
+    if (!config || config.enabled === false) return;
+
+    try {
+      const relative = [...new Set(files.map(file => toPosixPath(path.isAbsolute(file) ? path.relative(this.projectRoot, file) : file)))];
+      const vectors = await embedFiles(this.projectRoot, relative, config, new VibeFlowPaths(this.projectRoot).embeddingsCachePath);
+      this.embeddingIndex = new EmbeddingIndex(vectors, config.minSimilarity);
+      console.log(`🧠 埋め込みによる意味的類似度 (${config.provider}): ${vectors.size}ファイル`);
+    } catch (error) {
+      console.warn(`⚠️  ファイルの埋め込みに失敗しました。埋め込みなしでクラスタリングします: ${getErrorMessage(error)}`);
+    }
+  }
+
+  /**
+   * コールグラフを構築（callgraph の cha/rta、利用できない・サンプリング時は関数名の照合による構文上の呼び出し）
+   */
+  private buildCallGraph(functions: GoFunction[], sampled: boolean): void {
+    this.callGraph = undefined;
+    this.callIndex = undefined;
+    if (this.callGraphConfig?.enabled === false) return;
+
+    const graph = (!sampled && buildCallGraph(this.projectRoot, this.callGraphConfig)) || syntacticCallGraph(functions);
+    if (graph.algorithm === 'syntactic' && !sampled) {
+      console.warn(`⚠️  callgraph (golang.org/x/tools/cmd/callgraph) を実行できません。関数名の照合による呼び出しで代用します`);
+    }
+    this.callGraph = graph;
+    this.callIndex = new CallGraphIndex(graph);
+    console.log(`📞 コールグラフ (${graph.algorithm}): ${graph.edges.length}本の呼び出し`);
+  }
+
+  /**
+   * 構造体の埋め込みとインターフェースの実装を依存関係に追加（go/types、Go ツールチェーンがない・サンプリング時は名前の照合）
+   * Go の実装は暗黙的で import や呼び出しに現れないため、インターフェース中心のコードでは結合の手がかりになる
+   */
+  private buildTypeRelations(structs: GoStruct[], interfaces: GoInterface[], functions: GoFunction[], sampled: boolean): void {
+    this.typeRelations = undefined;
+    this.typeIndex = undefined;
+
+    const relations = (!sampled && buildTypeRelations(this.projectRoot, this.packages)) || syntacticTypeRelations(structs, interfaces, functions);
+    if (relations.relations.length === 0) return;
+    this.typeRelations = relations;
+    this.typeIndex = new TypeRelationIndex(relations);
+    const count = (kind: string) => relations.relations.filter(relation => relation.kind === kind).length;
+    console.log(`🧬 型の関係 (${relations.source}): 埋め込み${count('embeds')}件・インターフェース実装${count('implements')}件`);
+  }
+
+  /**
+   * schema.sql・マイグレーションのテーブル（なければ gorm・ent のモデルが宣言するテーブル）を、
+   * それに対応する構造体とクエリ（gorm・sqlx・ent の呼び出しを含む）に対応付け
+   */
+  private mapSchemaTables(files: string[]): void {
+    this.tableOwnership = undefined;
+    this.tableIndex = undefined;
+
+    try {
+      const schema = loadSchemaTables(this.projectRoot, this.schemaPaths);
+      const tables = schema.tables.length > 0 ? schema.tables : findOrmTables(this.projectRoot, files);
+      if (tables.length === 0) return;
+      const sources = schema.tables.length > 0 ? schema.sources : [...new Set(tables.map(t => t.source))].sort();
+      const access = findTableAccess(this.projectRoot, files, tables.map(t => t.name));
+      this.tableOwnership = { sources, tables, access };
+      this.tableIndex = new TableOwnershipIndex(access);
+      const mapped = new Set(access.map(a => a.table)).size;
+      if (schema.tables.length > 0) {
+        console.log(`🗄️  スキーマのテーブル: ${tables.length}個中${mapped}個を構造体・クエリに対応付け（${sources.join(', ')}）`);
+      } else {
+        console.log(`🗄️  ORMモデル（gorm・ent）のテーブル: ${tables.length}個中${mapped}個を構造体・クエリに対応付け`);
+      }
+    } catch (error) {
+      console.warn('⚠️  スキーマの読み込みに失敗しました。テーブル所有なしで続行します:', error);
+    }
+  }
+
+  /**
+   * gin・echo・chi・net/http のルート登録を解析し、ハンドラーをURLプレフィックスでまとめる手がかりにする
+   */
+  private findHttpRoutes(files: string[]): void {
+    this.httpRoutes = undefined;
+    this.routeIndex = undefined;
+
+    try {
+      const routes = findHttpRoutes(this.projectRoot, files);
+      if (routes.length === 0) return;
+      this.httpRoutes = routes;
+      this.routeIndex = new RoutePrefixIndex(routes);
+      const prefixes = this.routeIndex.prefixesOf(routes.flatMap(route => route.file ? [route.file] : []));
+      const unresolved = routes.filter(route => !route.file).length;
+      console.log(`🌐 HTTPルート: ${routes.length}本、${prefixes.length}個のURLプレフィックス${unresolved > 0 ? `（ハンドラー未解決${unresolved}本）` : ''}`);
+    } catch (error) {
+      console.warn('⚠️  HTTPルートの解析に失敗しました。ルートなしで続行します:', error);
+    }
+  }
+
+  /**
+   * Kafka・NATS・SQS の publish/subscribe 呼び出しとトピックを検出（トピックを介した連携は結合として数えない）
+   */
+  private findMessageTopics(files: string[]): void {
+    this.messageEndpoints = undefined;
+    this.topicIndex = undefined;
+
+    try {
+      const endpoints = findMessageEndpoints(this.projectRoot, files);
+      if (endpoints.length === 0) return;
+      this.messageEndpoints = endpoints;
+      this.topicIndex = new MessageTopicIndex(endpoints);
+      const topics = new Set(endpoints.map(endpoint => `${endpoint.broker}:${endpoint.topic}`));
+      console.log(`📨 メッセージトピック: ${topics.size}個（publish ${endpoints.filter(e => e.role === 'publish').length}箇所、subscribe ${endpoints.filter(e => e.role === 'subscribe').length}箇所）`);
+    } catch (error) {
+      console.warn('⚠️  メッセージトピックの検出に失敗しました。トピックなしで続行します:', error);
+    }
+  }
+
+  /**
+   * 発見した境界ごとのテーブル所有（テーブル → 構造体 → パッケージ → モジュール）、複数モジュールからアクセスされるテーブルを警告
+   */
+  private buildDataOwnership(boundaries: AutoDiscoveredBoundary[]): DataOwnershipMap | undefined {
+    if (!this.tableOwnership) return undefined;
+    const importPaths = new Map(this.packages.map(pkg => [pkg.dir, pkg.import_path]));
+    const ownership = buildDataOwnership(this.tableOwnership, boundaries, file => {
+      const dir = path.posix.dirname(toPosixPath(file));
+      return importPaths.get(dir) ?? dir;
+    });
+    for (const violation of ownership.violations) {
+      console.warn(`⚠️  複数モジュールからアクセスされるテーブル: ${violation.table} (${violation.modules.join(', ')}${violation.owners.length > 0 ? `、所有: ${violation.owners.join(', ')}` : ''})`);
+    }
+    return ownership;
+  }
+
+  /**
+   * go.work のメンバーモジュール（モジュールをまたいで1つのグラフとして解析）
+   */
+  private logWorkspace(): void {
+    const workspace = loadGoWorkspace(this.projectRoot);
+    if (!workspace) return;
+    console.log(`🧩 Go ワークスペース（go.work）: ${workspace.modules.length}個のモジュールをまたいで解析 (${workspace.modules.map(m => m.module_path ?? m.dir).join(', ')})`);
+    workspace.missing.forEach(dir => console.warn(`⚠️  go.work の use ${dir} に go.mod がありません`));
+  }
+  
+  /**
+   * 生成コード（*.pb.go・*_gen.go・mock_*.go・"DO NOT EDIT" ヘッダー）と、それを生成する go:generate ディレクティブを記録
+   * （生成コードはクラスタリングの対象外、ディレクティブを持つモジュールが再生成を担う）
+   */
+  private findGeneratedCode(): void {
+    this.generatedCode = undefined;
+
+    try {
+      const generated = findGeneratedCode(this.projectRoot, this.packages.flatMap(pkg => pkg.files));
+      if (generated.length === 0) return;
+      this.generatedCode = generated;
+      const directives = new Set(generated.filter(entry => entry.directive).map(entry => `${entry.directive!.file}:${entry.directive!.line}`));
+      console.log(`🧬 生成コード: ${generated.length}ファイルをクラスタリングから除外（go:generate ディレクティブ ${directives.size}件）`);
+    } catch (error) {
+      console.warn('⚠️  生成コードの検出に失敗しました:', error);
+    }
+  }
+
+  /**
+   * .proto のサービスごとに、生成コードと実装（Unimplemented<Service>Server を埋め込む構造体）の
+   * パッケージを1つのモジュールのシードにする（boundary.yaml のシードが優先）
+   */
+  private seedGrpcServices(): void {
+    this.grpcServices = undefined;
+    this.seeds = this.configuredSeeds;
+
+    try {
+      const services = findGrpcServices(this.projectRoot, this.packages);
+      if (services.length === 0) return;
+      this.grpcServices = services;
+
+      const byImportPath = new Map(this.packages.map(pkg => [pkg.import_path, pkg.dir]));
+      const configured = Object.values(this.configuredSeeds).flat()
+        .map(seed => toPosixPath(byImportPath.get(seed) ?? seed).replace(/^\.\//, '').replace(/\/+$/, ''));
+      const seeds = grpcSeeds(services, configured);
+      this.seeds = { ...this.configuredSeeds };
+      for (const [module, paths] of Object.entries(seeds)) {
+        this.seeds[module] = [...(this.seeds[module] ?? []), ...paths];
+      }
+      console.log(`🛰️  gRPC サービス: ${services.map(service => `${service.name} → ${service.module}`).join(', ')}（.proto の契約に沿ってシード）`);
+    } catch (error) {
+      console.warn('⚠️  .proto の解析に失敗しました。gRPC サービスのシードなしで続行します:', error);
+    }
+  }
+
+  /**
+   * boundary.yaml・gRPC サービスのシード（ファイル、パッケージのディレクトリまたはインポートパス）を解析対象のパスに対応付け
+   */
+  private resolveSeeds(files: string[]): void {
+    const byImportPath = new Map(this.packages.map(pkg => [pkg.import_path, pkg.dir]));
+    const normalize = (seed: string) => toPosixPath(byImportPath.get(seed) ?? seed).replace(/^\.\//, '').replace(/\/+$/, '');
+    this.seedPaths = Object.entries(this.seeds)
+      .flatMap(([module, seeds]) => seeds.map(seed => [normalize(seed), module] as [string, string]))
+      .sort((a, b) => b[0].length - a[0].length);
+    if (this.seedPaths.length === 0) return;
+    
+    const seeded = new Set(files.filter(file => this.seedOf(file) !== undefined).map(file => this.relativePath(file)));
+    console.log(`🌱 シード: ${Object.keys(this.seeds).length}個のモジュールに${seeded.size}ファイル`);
+    for (const [seedPath, module] of this.seedPaths) {
+      const covers = (file: string) => file === seedPath || file.startsWith(`${seedPath}/`);
+      // A package of generated code only (e.g. the *.pb.go of a gRPC service) is not clustered
+      if (![...seeded].some(covers) && !this.generatedCode?.some(entry => covers(entry.file))) {
+        console.warn(`⚠️  ${module} のシード ${seedPath} に一致するファイルがありません`);
+      }
+    }
+  }
+  
+  /**
+   * Module a file is seeded to: the most specific seed containing it
+   */
+  private seedOf(file: string): string | undefined {
+    if (this.seedPaths.length === 0) return undefined;
+    const relative = this.relativePath(file);
+    return this.seedPaths.find(([seed]) => relative === seed || relative.startsWith(`${seed}/`))?.[1];
+  }
+  
+  private relativePath(file: string): string {
+    return toPosixPath(path.isAbsolute(file) ? path.relative(this.projectRoot, file) : file);
+  }
+  
+  /**
+   * Module most of the seeded files of a cluster are seeded to
+   */
+  private clusterSeed(cluster: ModuleCandidateNode): string | undefined {
+    const counts = new Map<string, number>();
+    for (const file of cluster.files) {
+      const seed = this.seedOf(file);
+      if (seed !== undefined) counts.set(seed, (counts.get(seed) ?? 0) + 1);
+    }
+    return [...counts].sort((a, b) => b[1] - a[1] || (a[0] < b[0] ? -1 : 1))[0]?.[0];
+  }
+  
+  /**
+   * Seeded files end up in the cluster of their module, named after it: a cluster
+   * takes the module most of its seeded files belong to, the nodes of files seeded
+   * elsewhere move out, and a module no cluster formed around gets one of its own
+   */
+  private applySeeds(clusters: ModuleCandidateNode[], nodes: (GoStruct | GoInterface | GoFunction)[]): ModuleCandidateNode[] {
+    if (this.seedPaths.length === 0) return clusters;
+    
+    const bySeed = new Map<string, ModuleCandidateNode[]>();
+    const unseeded: ModuleCandidateNode[] = [];
+    for (const cluster of clusters) {
+      const seed = this.clusterSeed(cluster);
+      const keep = (file: string) => {
+        const other = this.seedOf(file);
+        return other === undefined || other === seed;
+      };
+      const trimmed: ModuleCandidateNode = {
+        ...cluster,
+        name: seed ?? cluster.name,
+        files: cluster.files.filter(keep),
+        structs: cluster.structs.filter(n => keep(n.file)),
+        interfaces: cluster.interfaces.filter(n => keep(n.file)),
+        functions: cluster.functions.filter(n => keep(n.file)),
+        database_access: cluster.database_access.filter(da => keep(da.file)),
+      };
+      if (trimmed.files.length === 0) continue;
+      if (seed === undefined) unseeded.push(trimmed);
+      else bySeed.set(seed, [...(bySeed.get(seed) ?? []), trimmed]);
+    }
+    
+    const seeded: ModuleCandidateNode[] = [];
+    for (const module of Object.keys(this.seeds)) {
+      const members = nodes.filter(n => this.seedOf(n.file) === module);
+      const candidates = bySeed.get(module) ?? [];
+      if (members.length > 0) candidates.push(this.buildCluster(members));
+      if (candidates.length === 0) continue;
+      seeded.push({ ...this.mergeClusterCandidates(candidates), name: module });
+    }
+    return [...seeded, ...unseeded];
+  }
+  
   private calculateDependencyStrength(node1: any, node2: any): number {
+    // Seeds of boundary.yaml: nodes seeded to one module belong together, nodes seeded to different modules never do
+    const seed1 = this.seedOf(node1.file);
+    const seed2 = this.seedOf(node2.file);
+    if (seed1 !== undefined && seed2 !== undefined) return seed1 === seed2 ? 1.0 : 0;
+    
     let strength = 0;
     
     // Direct dependencies
@@ -158,11 +666,18 @@ export class AutoBoundaryDiscovery {
       strength += 0.8;
     }
     
-    // Function calls
-    if (node1.calls?.includes(node2.name)) {
+    // Call edges of the call graph (either direction), and functions passed or stored as values (callbacks, handler registries)
+    const calls = this.callIndex ? this.callIndex.calls(node1, node2) : node1.calls?.includes(node2.name);
+    if (calls ||
+        node1.function_values?.some((value: string) => value === node2.name || value.endsWith(`.${node2.name}`))) {
       strength += 0.6;
     }
     
+    // Embedded types and satisfied interfaces: implicit in Go, so neither an import nor a call shows them
+    if (this.typeIndex?.relates(node1, node2)) {
+      strength += TYPE_RELATION_WEIGHT;
+    }
+    
     // Same file
     if (node1.file === node2.file) {
       strength += 0.4;
@@ -173,9 +688,32 @@ export class AutoBoundaryDiscovery {
       strength += 0.2;
     }
     
-    // Semantic similarity
-    const semanticSim = this.calculateSemanticSimilarity(node1.name, node2.name);
-    strength += semanticSim * 0.3;
+    // Semantic similarity, except between a topic's publisher and subscriber
+    // (PublishOrderCreated / HandleOrderCreated integrate through the broker)
+    if (!this.topicIndex?.integrates(node1.file, node2.file)) {
+      const semanticSim = this.calculateSemanticSimilarity(node1.name, node2.name);
+      strength += semanticSim * 0.3;
+    }
+    
+    // Files that change together in the git history, however weak their static coupling
+    if (this.coChangeIndex && node1.file !== node2.file) {
+      strength += this.coChangeIndex.strength(node1.file, node2.file) * (this.coChangeConfig?.weight ?? DEFAULT_CO_CHANGE_WEIGHT);
+    }
+    
+    // Files about the same thing by their embedded identifiers and comments, wherever they live
+    if (this.embeddingIndex && node1.file !== node2.file) {
+      strength += this.embeddingIndex.strength(node1.file, node2.file) * (this.embeddingsConfig?.weight ?? DEFAULT_EMBEDDING_WEIGHT);
+    }
+    
+    // Files owning the same schema tables (mapped structs, writing queries)
+    if (this.tableIndex && node1.file !== node2.file) {
+      strength += this.tableIndex.strength(node1.file, node2.file) * TABLE_OWNERSHIP_WEIGHT;
+    }
+    
+    // Handlers serving the same URL prefix (/api/v1/orders/...)
+    if (this.routeIndex && node1.file !== node2.file) {
+      strength += this.routeIndex.strength(node1.file, node2.file) * ROUTE_PREFIX_WEIGHT;
+    }
     
     return Math.min(strength, 1.0);
   }
@@ -220,6 +758,48 @@ export class AutoBoundaryDiscovery {
     return sampled;
   }
 
+  /**
+   * 依存関係の強さを重みとするグラフのコミュニティ検出（Louvain/Leiden、resolution で粒度を調整）
+   */
+  private performCommunityClustering(nodes: any[], algorithm: CommunityAlgorithm): ModuleCandidateNode[] {
+    const resolution = this.clusteringConfig?.resolution ?? 1;
+    const edges: WeightedEdge[] = [];
+    for (let i = 0; i < nodes.length; i++) {
+      for (let j = i + 1; j < nodes.length; j++) {
+        const weight = this.calculateDependencyStrength(nodes[i], nodes[j]);
+        if (weight > MIN_COMMUNITY_EDGE_WEIGHT) edges.push({ source: i, target: j, weight });
+      }
+    }
+    
+    const seeds = nodes.map(node => this.seedOf(node.file));
+    const communities = detectCommunities(nodes.length, edges, { algorithm, resolution, seeds });
+    this.communityModularity = Math.round(modularity(nodes.length, edges, communities, resolution) * 1000) / 1000;
+    
+    const members = new Map<number, any[]>();
+    communities.forEach((community, node) => {
+      if (!members.has(community)) members.set(community, []);
+      members.get(community)!.push(nodes[node]);
+    });
+    const clusters = [...members.values()].filter(cluster => cluster.length >= 2).map(cluster => this.buildCluster(cluster));
+    
+    console.log(`🧩 コミュニティ検出 (${algorithm}, resolution ${resolution}): ${clusters.length}個のコミュニティ（モジュラリティ ${this.communityModularity}）`);
+    return clusters;
+  }
+
+  private buildCluster(nodes: any[]): ModuleCandidateNode {
+    return {
+      name: this.generateClusterName(nodes),
+      files: [...new Set(nodes.map(n => n.file))],
+      structs: nodes.filter(n => n.type === 'struct'),
+      interfaces: nodes.filter(n => n.type === 'interface'),
+      functions: nodes.filter(n => n.type === 'function'),
+      database_access: [],
+      semantic_keywords: this.extractClusterKeywords(nodes),
+      cohesion_score: this.calculateClusterCohesion(nodes),
+      external_dependencies: [],
+    };
+  }
+
   private performSimpleDistanceClustering(nodes: any[]): ModuleCandidateNode[] {
     const clusters: ModuleCandidateNode[] = [];
     const processed = new Set<number>();
@@ -229,33 +809,25 @@ export class AutoBoundaryDiscovery {
       
       const cluster = [nodes[i]];
       processed.add(i);
+      // シードのモジュール: 同じモジュールのシードは必ず加え、別のモジュールのシードは加えない
+      let seed = this.seedOf(nodes[i].file);
       
       // 類似ノードを同じクラスターに追加
       for (let j = i + 1; j < nodes.length; j++) {
         if (processed.has(j)) continue;
         
+        const other = this.seedOf(nodes[j].file);
+        if (seed !== undefined && other !== undefined && other !== seed) continue;
         const similarity = this.calculateDependencyStrength(nodes[i], nodes[j]);
-        if (similarity > 0.3) { // 閾値を下げて高速化
+        if (similarity > 0.3 || (seed !== undefined && other === seed)) { // 閾値を下げて高速化
           cluster.push(nodes[j]);
           processed.add(j);
+          seed ??= other;
         }
       }
       
       if (cluster.length >= 2) {
-        const clusterName = this.generateClusterName(cluster);
-        const files = [...new Set(cluster.map(n => n.file))];
-        
-        clusters.push({
-          name: clusterName,
-          files,
-          structs: cluster.filter(n => n.type === 'struct'),
-          interfaces: cluster.filter(n => n.type === 'interface'),
-          functions: cluster.filter(n => n.type === 'function'),
-          database_access: [],
-          semantic_keywords: this.extractClusterKeywords(cluster),
-          cohesion_score: this.calculateClusterCohesion(cluster),
-          external_dependencies: [],
-        });
+        clusters.push(this.buildCluster(cluster));
       }
     }
     
@@ -355,6 +927,42 @@ export class AutoBoundaryDiscovery {
     return clusters;
   }
 
+  /**
+   * One cluster per schema table with the files owning it: structs mapped to
+   * the table and functions writing it. Reading a table does not make a file its owner.
+   */
+  private analyzeTableOwnership(nodes: (GoStruct | GoFunction)[]): ModuleCandidateNode[] {
+    if (!this.tableOwnership) return [];
+    console.log('🗄️  テーブル所有パターン分析中...');
+    
+    const clusters: ModuleCandidateNode[] = [];
+    for (const table of this.tableOwnership.tables) {
+      const owning = this.tableOwnership.access.filter(a => a.table === table.name && ownsTable(a));
+      if (owning.length === 0) continue;
+      
+      const files = [...new Set(owning.map(a => a.file))];
+      const symbols = new Set(owning.map(a => `${a.file}\n${a.symbol}`));
+      const owned = nodes.filter(n => symbols.has(`${toPosixPath(n.file)}\n${n.name}`));
+      const keywords = this.extractClusterKeywords(owned);
+      
+      clusters.push({
+        name: this.generateTableBasedModuleName(table.name, keywords),
+        files,
+        structs: owned.filter((n): n is GoStruct => n.type === 'struct'),
+        interfaces: [],
+        functions: owned.filter((n): n is GoFunction => n.type === 'function'),
+        database_access: this.tableOwnership.access
+          .filter(a => a.table === table.name && a.via === 'query' && files.includes(a.file))
+          .map(a => ({ table: a.table, operation: a.operation!, file: a.file, function: a.symbol })),
+        semantic_keywords: keywords,
+        cohesion_score: owned.length > 0 ? this.calculateClusterCohesion(owned) : 0.5,
+        external_dependencies: [],
+      });
+    }
+    
+    return clusters;
+  }
+
   private generateTableBasedModuleName(table: string, keywords: string[]): string {
     // Try to extract meaningful name from table name
     const tableTokens = this.extractSemanticTokens(table);
@@ -378,30 +986,38 @@ export class AutoBoundaryDiscovery {
   private async analyzeStructuralPatterns(nodes: any[]): Promise<ModuleCandidateNode[]> {
     console.log('📁 ファイル・ディレクトリ構造分析中...');
     
-    const directoryClusters = new Map<string, any[]>();
+    // Group by package identity (import path), not by directory name: a
+    // directory may declare a different package name than its path suggests
+    const byDir = new Map(this.packages.map(pkg => [pkg.dir, pkg]));
+    const packageClusters = new Map<string, { name: string; nodes: any[] }>();
     
-    // Group by directory
     for (const node of nodes) {
-      const dir = path.dirname(node.file);
-      const dirName = path.basename(dir);
+      const dir = toPosixPath(path.dirname(node.file));
+      const pkg = byDir.get(dir);
+      const key = pkg?.import_path ?? dir;
       
-      if (!directoryClusters.has(dirName)) {
-        directoryClusters.set(dirName, []);
+      if (!packageClusters.has(key)) {
+        packageClusters.set(key, { name: dir === '.' ? 'root' : pkg?.name ?? path.posix.basename(dir), nodes: [] });
       }
       
-      directoryClusters.get(dirName)!.push(node);
+      packageClusters.get(key)!.nodes.push(node);
     }
     
+    // Packages sharing a name keep their own cluster, named after their directory
+    const nameCounts = new Map<string, number>();
+    for (const { name } of packageClusters.values()) nameCounts.set(name, (nameCounts.get(name) ?? 0) + 1);
+    
     const clusters: ModuleCandidateNode[] = [];
     
-    for (const [dirName, dirNodes] of directoryClusters) {
-      if (dirNodes.length < 3) continue; // Skip small directories
+    for (const [key, { name, nodes: dirNodes }] of packageClusters) {
+      if (dirNodes.length < 3) continue; // Skip small packages
       
       const files = [...new Set(dirNodes.map(n => n.file))];
       const keywords = this.extractClusterKeywords(dirNodes);
+      const clusterName = (nameCounts.get(name) ?? 0) > 1 ? impliedPackageName(key) : name;
       
       clusters.push({
-        name: dirName === '.' ? 'root' : dirName,
+        name: clusterName,
         files,
         structs: dirNodes.filter(n => n.type === 'struct'),
         interfaces: dirNodes.filter(n => n.type === 'interface'),
@@ -439,8 +1055,9 @@ export class AutoBoundaryDiscovery {
         
         const other = allClusters[j];
         const overlap = this.calculateFileOverlap(cluster.files, other.files);
+        const seeds = [this.clusterSeed(cluster), this.clusterSeed(other)];
         
-        if (overlap > 0.5) { // 50% file overlap threshold
+        if (overlap > 0.5 && (seeds.includes(undefined) || seeds[0] === seeds[1])) { // 50% file overlap threshold
           similar.push(other);
           processed.add(j);
         }
@@ -520,51 +1137,57 @@ export class AutoBoundaryDiscovery {
     const result: AutoDiscoveredBoundary[] = [];
     
     for (const boundary of boundaries) {
-      const confidence = this.calculateBoundaryConfidence(boundary, boundaries);
+      const breakdown = this.calculateBoundaryConfidence(boundary, boundaries);
       const reasoning = this.generateBoundaryReasoning(boundary);
       const description = this.generateBoundaryDescription(boundary);
+      const degradedFiles = boundary.files.filter(f => this.degradedFiles.has(f));
       
       result.push({
         name: boundary.name,
         description,
-        confidence,
+        confidence: breakdown.score,
         files: boundary.files,
         structs: boundary.structs.map(s => s.name),
         interfaces: boundary.interfaces.map(i => i.name),
         functions: boundary.functions.map(f => f.name),
-        database_tables: [...new Set(boundary.database_access.map(da => da.table))],
+        database_tables: [...new Set([
+          ...boundary.database_access.map(da => da.table),
+          ...(this.tableIndex?.tablesOwnedBy(boundary.files) ?? []),
+        ])],
         reasoning,
         semantic_keywords: boundary.semantic_keywords,
         dependency_clusters: boundary.external_dependencies,
+        ...(degradedFiles.length > 0 ? { degraded_files: degradedFiles } : {}),
+        confidence_breakdown: breakdown,
       });
     }
     
     return result;
   }
 
+  /**
+   * Structural cohesion weighs the boundary's size (20 of 45) and how little it
+   * overlaps the others (25 of 45); naming similarity is the semantic cohesion
+   * of its declarations and data ownership its database alignment
+   */
   private calculateBoundaryConfidence(
     boundary: ModuleCandidateNode,
     allBoundaries: ModuleCandidateNode[]
-  ): number {
-    let confidence = 0;
-    
-    // Semantic coherence (30%)
-    const semanticScore = boundary.cohesion_score;
-    confidence += semanticScore * 0.3;
-    
-    // Size appropriateness (20%)
+  ): ConfidenceBreakdown {
     const sizeScore = this.evaluateBoundarySize(boundary);
-    confidence += sizeScore * 0.2;
-    
-    // Database alignment (25%)
-    const dbScore = this.evaluateDatabaseAlignment(boundary);
-    confidence += dbScore * 0.25;
-    
-    // Isolation score (25%)
     const isolationScore = this.evaluateBoundaryIsolation(boundary, allBoundaries);
-    confidence += isolationScore * 0.25;
     
-    return Math.min(confidence, 1.0);
+    // Syntax-only files contribute less evidence
+    const degradedShare = boundary.files.length > 0
+      ? boundary.files.filter(f => this.degradedFiles.has(f)).length / boundary.files.length
+      : 0;
+    
+    return explainConfidence({
+      structural_cohesion: (sizeScore * 0.2 + isolationScore * 0.25) / 0.45,
+      naming_similarity: boundary.cohesion_score,
+      data_ownership: this.evaluateDatabaseAlignment(boundary),
+      degraded_share: degradedShare,
+    });
   }
 
   private evaluateBoundarySize(boundary: ModuleCandidateNode): number {
@@ -630,6 +1253,46 @@ export class AutoBoundaryDiscovery {
       reasons.push(`高い内部凝集度: ${(boundary.cohesion_score * 100).toFixed(1)}%`);
     }
     
+    const seeded = boundary.files.filter(f => this.seedOf(f) === boundary.name).length;
+    if (seeded > 0) {
+      reasons.push(`${this.configuredSeeds[boundary.name] ? 'boundary.yaml のシード' : 'gRPC サービスのシード'}: ${seeded}ファイル`);
+    }
+    
+    const services = (this.grpcServices ?? []).filter(service => service.module === boundary.name);
+    if (services.length > 0) {
+      reasons.push(`gRPC サービス: ${services.map(service => service.name).join(', ')}`);
+    }
+    
+    const ownedTables = this.tableIndex?.tablesOwnedBy(boundary.files) ?? [];
+    if (ownedTables.length > 0) {
+      reasons.push(`テーブル所有: ${ownedTables.slice(0, 3).join(', ')}`);
+    }
+    
+    const prefixes = this.routeIndex?.prefixesOf(boundary.files) ?? [];
+    if (prefixes.length > 0) {
+      reasons.push(`HTTPルート: ${prefixes.slice(0, 3).join(', ')}`);
+    }
+    
+    const published = [...new Set((this.messageEndpoints ?? [])
+      .filter(endpoint => endpoint.role === 'publish' && boundary.files.includes(endpoint.file))
+      .map(endpoint => endpoint.topic))];
+    if (published.length > 0) {
+      reasons.push(`publish するトピック: ${published.slice(0, 3).join(', ')}`);
+    }
+    
+    const files = new Set(boundary.files);
+    const internalCalls = this.callGraph?.edges
+      .filter(edge => edge.caller_file !== edge.callee_file && files.has(edge.caller_file) && files.has(edge.callee_file))
+      .reduce((sum, edge) => sum + edge.count, 0) ?? 0;
+    if (internalCalls > 0) {
+      reasons.push(`ファイル間の呼び出し: ${internalCalls}件`);
+    }
+    
+    const coChanged = this.coChangeIndex?.pairsWithin(boundary.files) ?? 0;
+    if (coChanged > 0) {
+      reasons.push(`Git履歴で同時に変更: ${coChanged}組のファイル`);
+    }
+    
     if (boundary.files.length > 0) {
       const dirs = [...new Set(boundary.files.map(f => path.dirname(f)))];
       if (dirs.length === 1) {
@@ -648,11 +1311,26 @@ export class AutoBoundaryDiscovery {
   }
 
   private async optimizeBoundaries(boundaries: AutoDiscoveredBoundary[]): Promise<AutoDiscoveredBoundary[]> {
-    // Filter out low-confidence boundaries
-    const highConfidenceBoundaries = boundaries.filter(b => b.confidence > 0.5);
+    // Filter out low-confidence boundaries; modules seeded in boundary.yaml are kept
+    const highConfidenceBoundaries = boundaries.filter(b => b.confidence > 0.5 || this.seeds[b.name] !== undefined);
+    
+    // Apply negative constraints from boundary.yaml
+    const resolution = resolveConstraints(highConfidenceBoundaries, this.constraints, autoBoundaryAdapter);
+    this.constraintAdjustments = resolution.adjustments;
+    this.constraintViolations = resolution.violations;
+    resolution.adjustments.forEach(adjustment => console.log(`🔒 ${adjustment}`));
+    if (resolution.violations.length > 0) {
+      console.log(`⚠️  ${resolution.violations.length}個の境界制約を満たせませんでした`);
+    }
+    
+    // Bring the modules to the requested granularity (boundary.yaml clustering, vf discover --target-modules)
+    const granularity = adjustGranularity(resolution.items, this.clusteringConfig, this.granularityAdapter(), this.constraints);
+    this.granularityAdjustments = granularity.adjustments;
+    granularity.adjustments.forEach(adjustment => console.log(`📏 ${adjustment}`));
+    granularity.unmet.forEach(reason => console.warn(`⚠️  粒度の目標を満たせませんでした: ${reason}`));
     
     // Sort by confidence
-    return highConfidenceBoundaries.sort((a, b) => b.confidence - a.confidence);
+    return granularity.items.sort((a, b) => b.confidence - a.confidence);
   }
 
   private async generateRecommendations(boundaries: AutoDiscoveredBoundary[]): Promise<BoundaryRecommendation[]> {
@@ -719,6 +1397,170 @@ export class AutoBoundaryDiscovery {
       cluster_quality_score: 85, // Placeholder
       boundary_overlaps: [],
       orphaned_files: [],
+      algorithm: this.clusteringConfig?.algorithm ?? 'distance',
+      ...(this.communityModularity !== undefined ? {
+        resolution: this.clusteringConfig?.resolution ?? 1,
+        modularity: this.communityModularity,
+      } : {}),
+      ...(this.granularityAdjustments.length > 0 ? { granularity_adjustments: this.granularityAdjustments } : {}),
+    };
+  }
+
+  /**
+   * 粒度の調整用アダプター: パッケージディレクトリ単位で分割し、結合の強い境界に統合する（シードのモジュールは分割しない）
+   */
+  private granularityAdapter(): GranularityAdapter<AutoDiscoveredBoundary> {
+    const declaredIn = (symbol: string, files: Set<string>) => [...(this.declarationFiles.get(symbol) ?? [])].some(file => files.has(file));
+    return {
+      ...autoBoundaryAdapter,
+      split: (boundary, name, files) => {
+        const moved = new Set(files.map(toPosixPath));
+        const kept = new Set(boundary.files.map(toPosixPath).filter(file => !moved.has(file)));
+        // A name declared on both sides (e.g. a method name) stays with both
+        const keep = (symbols: string[]) => symbols.filter(symbol => !declaredIn(symbol, moved) || declaredIn(symbol, kept));
+        const move = (symbols: string[]) => symbols.filter(symbol => declaredIn(symbol, moved));
+        return [
+          {
+            ...boundary,
+            files: boundary.files.filter(file => kept.has(toPosixPath(file))),
+            degraded_files: boundary.degraded_files?.filter(file => kept.has(toPosixPath(file))),
+            structs: keep(boundary.structs),
+            interfaces: keep(boundary.interfaces),
+            functions: keep(boundary.functions),
+            database_tables: this.tableIndex?.tablesOwnedBy([...kept]) ?? boundary.database_tables,
+          },
+          {
+            ...boundary,
+            name,
+            description: `${name}パッケージのモジュール（粒度の調整により${boundary.name}から分離）`,
+            files: boundary.files.filter(file => moved.has(toPosixPath(file))),
+            degraded_files: boundary.degraded_files?.filter(file => moved.has(toPosixPath(file))),
+            structs: move(boundary.structs),
+            interfaces: move(boundary.interfaces),
+            functions: move(boundary.functions),
+            database_tables: this.tableIndex?.tablesOwnedBy([...moved]) ?? [],
+            reasoning: [`粒度の調整により${boundary.name}から分離`],
+            semantic_keywords: [name],
+            dependency_clusters: [boundary.name],
+            merged_from: [],
+          },
+        ];
+      },
+      affinity: (a, b) => this.boundaryAffinity(a, b),
+      pinned: boundary => this.seeds[boundary.name] !== undefined,
     };
   }
-}
\ No newline at end of file
+
+  /**
+   * 2つの境界の結合の強さ: ファイル間の呼び出し回数、型の埋め込み・実装、同時変更、互いへの依存、パッケージディレクトリの近さ（同点の順位付け）
+   */
+  private boundaryAffinity(a: AutoDiscoveredBoundary, b: AutoDiscoveredBoundary): number {
+    const filesA = new Set(a.files.map(toPosixPath));
+    const filesB = new Set(b.files.map(toPosixPath));
+    let score = 0;
+    for (const edge of this.callGraph?.edges ?? []) {
+      const caller = toPosixPath(edge.caller_file);
+      const callee = toPosixPath(edge.callee_file);
+      if ((filesA.has(caller) && filesB.has(callee)) || (filesB.has(caller) && filesA.has(callee))) score += edge.count;
+    }
+    for (const relation of this.typeRelations?.relations ?? []) {
+      if ((filesA.has(relation.from_file) && filesB.has(relation.to_file)) || (filesB.has(relation.from_file) && filesA.has(relation.to_file))) score++;
+    }
+    if (this.coChangeIndex) {
+      for (const fileA of filesA) {
+        for (const fileB of filesB) score += this.coChangeIndex.strength(fileA, fileB);
+      }
+    }
+    if (this.embeddingIndex) {
+      for (const fileA of filesA) {
+        for (const fileB of filesB) score += this.embeddingIndex.strength(fileA, fileB);
+      }
+    }
+    if (a.dependency_clusters.includes(b.name)) score += 2;
+    if (b.dependency_clusters.includes(a.name)) score += 2;
+
+    const dirs = (files: Set<string>) => [...new Set([...files].map(file => path.posix.dirname(file)))].map(dir => dir.split('/'));
+    let shared = 0;
+    for (const dirA of dirs(filesA)) {
+      for (const dirB of dirs(filesB)) {
+        let depth = 0;
+        while (depth < dirA.length && depth < dirB.length && dirA[depth] === dirB[depth]) depth++;
+        shared = Math.max(shared, depth);
+      }
+    }
+    return score + shared / 10;
+  }
+}
+
+/**
+ * Constraint adapter for clustering results
+ */
+const autoBoundaryAdapter: ModuleAdapter<AutoDiscoveredBoundary> = {
+  toConstrained(boundary, all) {
+    return {
+      name: boundary.name,
+      files: boundary.files,
+      mergedFrom: boundary.merged_from ?? [],
+      dependsOn: all
+        .filter(other => other !== boundary && boundary.dependency_clusters.some(c => c === other.name || (other.merged_from ?? []).includes(c)))
+        .map(other => other.name),
+      tables: boundary.database_tables,
+      cycles: [],
+    };
+  },
+
+  merge(target, source) {
+    const union = (a: string[], b: string[]) => [...new Set([...a, ...b])];
+    return {
+      ...target,
+      confidence: Math.min(target.confidence, source.confidence),
+      ...(source.confidence < target.confidence && source.confidence_breakdown
+        ? { confidence_breakdown: source.confidence_breakdown }
+        : {}),
+      files: union(target.files, source.files),
+      structs: union(target.structs, source.structs),
+      interfaces: union(target.interfaces, source.interfaces),
+      functions: union(target.functions, source.functions),
+      database_tables: union(target.database_tables, source.database_tables),
+      reasoning: [...target.reasoning, `制約により${source.name}を統合`],
+      semantic_keywords: union(target.semantic_keywords, source.semantic_keywords),
+      dependency_clusters: union(target.dependency_clusters, source.dependency_clusters)
+        .filter(c => c !== target.name && c !== source.name),
+      merged_from: union(target.merged_from ?? [], [source.name, ...(source.merged_from ?? [])]),
+      ...(target.degraded_files || source.degraded_files
+        ? { degraded_files: union(target.degraded_files ?? [], source.degraded_files ?? []) }
+        : {}),
+    };
+  },
+
+  split(boundary, name, files) {
+    const moved = new Set(files);
+    const belongs = (symbol: string) => fileBelongsTo(symbol, name) || symbol.toLowerCase().includes(name.toLowerCase());
+    return [
+      {
+        ...boundary,
+        files: boundary.files.filter(f => !moved.has(f)),
+        degraded_files: boundary.degraded_files?.filter(f => !moved.has(f)),
+        structs: boundary.structs.filter(s => !belongs(s)),
+        interfaces: boundary.interfaces.filter(i => !belongs(i)),
+        functions: boundary.functions.filter(f => !belongs(f)),
+        semantic_keywords: boundary.semantic_keywords.filter(k => k !== name),
+      },
+      {
+        ...boundary,
+        name,
+        description: `${name}に関連する機能を含むモジュール（制約により${boundary.name}から分離）`,
+        files,
+        degraded_files: boundary.degraded_files?.filter(f => moved.has(f)),
+        structs: boundary.structs.filter(belongs),
+        interfaces: boundary.interfaces.filter(belongs),
+        functions: boundary.functions.filter(belongs),
+        database_tables: [],
+        reasoning: [`mustSeparate制約により${boundary.name}から分離`],
+        semantic_keywords: [name],
+        dependency_clusters: [boundary.name],
+        merged_from: [],
+      },
+    ];
+  },
+};
@@ -0,0 +1,116 @@
+import { ConfidenceBreakdown } from '../types/config.js';
+import { round } from './number-utils.js';
+
+/**
+ * Weight of each component in a discovered boundary's confidence; the file
+ * overlap with the manual analysis (manual_overlap) is reported next to the
+ * score but does not change it. There is no LLM agreement component: discovery
+ * does not ask an LLM to judge boundaries, and every rationale says so.
+ */
+export const CONFIDENCE_WEIGHTS = {
+  structural_cohesion: 0.45,
+  naming_similarity: 0.3,
+  data_ownership: 0.25,
+} as const;
+
+/**
+ * Share of a boundary's confidence lost when all of its files only had syntax-only analysis
+ */
+export const DEGRADED_CONFIDENCE_PENALTY = 0.3;
+
+/** Closes every rationale: reviewers should not read the score as confirmed by an LLM */
+const NO_LLM_AGREEMENT = 'LLM による境界の妥当性判定は含まない。';
+
+/** A component at least this high is named as a reason to trust the boundary */
+const STRONG = 0.7;
+/** A component below this is named as a reason to doubt it (0.5 is neutral: no evidence either way) */
+const WEAK = 0.5;
+
+export type ConfidenceComponents = Omit<ConfidenceBreakdown, 'score' | 'rationale'>;
+
+const LABELS: Record<keyof typeof CONFIDENCE_WEIGHTS, string> = {
+  structural_cohesion: '構造的凝集度',
+  naming_similarity: '命名の類似度',
+  data_ownership: 'データ所有',
+};
+
+/**
+ * Confidence of a boundary from its components, with the rationale reviewers read
+ */
+export function explainConfidence(components: ConfidenceComponents): ConfidenceBreakdown {
+  const weighted = (Object.keys(CONFIDENCE_WEIGHTS) as (keyof typeof CONFIDENCE_WEIGHTS)[])
+    .reduce((sum, key) => sum + components[key] * CONFIDENCE_WEIGHTS[key], 0);
+  const score = Math.min(weighted * (1 - (components.degraded_share ?? 0) * DEGRADED_CONFIDENCE_PENALTY), 1.0);
+
+  const breakdown: ConfidenceComponents = {
+    structural_cohesion: round(components.structural_cohesion, 3),
+    naming_similarity: round(components.naming_similarity, 3),
+    data_ownership: round(components.data_ownership, 3),
+    ...(components.manual_overlap !== undefined ? { manual_overlap: round(components.manual_overlap, 3) } : {}),
+    ...(components.degraded_share ? { degraded_share: round(components.degraded_share, 3) } : {}),
+  };
+  return { score: round(score, 3), ...breakdown, rationale: confidenceRationale(round(score, 3), breakdown) };
+}
+
+/**
+ * The breakdown with the file overlap with the manual analysis recorded (the score stays the same)
+ */
+export function withManualOverlap(breakdown: ConfidenceBreakdown, overlap: number): ConfidenceBreakdown {
+  const { score, rationale: _, ...components } = breakdown;
+  const updated = { ...components, manual_overlap: round(overlap, 3) };
+  return { score, ...updated, rationale: confidenceRationale(score, updated) };
+}
+
+/**
+ * Record on every discovered boundary its overlap with the closest boundary of the manual analysis
+ */
+export function annotateManualOverlap<T extends { files: string[]; confidence_breakdown?: ConfidenceBreakdown }>(
+  discovered: T[],
+  manual: { files: string[] }[]
+): T[] {
+  return discovered.map(boundary => boundary.confidence_breakdown
+    ? {
+      ...boundary,
+      confidence_breakdown: withManualOverlap(
+        boundary.confidence_breakdown,
+        Math.max(0, ...manual.map(m => fileOverlap(m.files, boundary.files)))
+      ),
+    }
+    : boundary);
+}
+
+/**
+ * Jaccard similarity of two file lists
+ */
+export function fileOverlap(files1: string[], files2: string[]): number {
+  const set1 = new Set(files1);
+  const set2 = new Set(files2);
+  const intersection = [...set1].filter(file => set2.has(file)).length;
+  const union = new Set([...set1, ...set2]).size;
+  return union > 0 ? intersection / union : 0;
+}
+
+function confidenceRationale(score: number, components: ConfidenceComponents): string {
+  const level = score >= 0.8 ? '高' : score >= 0.5 ? '中' : '低';
+  const keys = Object.keys(CONFIDENCE_WEIGHTS) as (keyof typeof CONFIDENCE_WEIGHTS)[];
+  const reasons = [
+    ...keys.filter(key => components[key] >= STRONG).map(key => `${LABELS[key]}が高い (${percent(components[key])})`),
+    ...keys.filter(key => components[key] < WEAK).map(key => `${LABELS[key]}が低い (${percent(components[key])})`),
+  ];
+  if (components.manual_overlap !== undefined) {
+    reasons.push(components.manual_overlap >= WEAK
+      ? `手動分析の境界とファイルが重なる (${percent(components.manual_overlap)})`
+      : `手動分析の境界とファイルがほぼ重ならない (${percent(components.manual_overlap)})`);
+  }
+
+  const sentences = [`信頼度${percent(score)}（${level}）: ${reasons.length > 0 ? reasons.join('、') : '突出した根拠・懸念なし'}。`];
+  if (components.degraded_share) {
+    sentences.push(`構文のみで解析したファイルが${percent(components.degraded_share)}あり減点。`);
+  }
+  sentences.push(NO_LLM_AGREEMENT);
+  return sentences.join('');
+}
+
+function percent(value: number): string {
+  return `${Math.round(value * 100)}%`;
+}
@@ -0,0 +1,360 @@
+import { BoundaryConstraints, DomainBoundary } from '../types/config.js';
+
+export type ConstraintType =
+  | 'mustSeparate'
+  | 'mustMerge'
+  | 'maxModules'
+  | 'forbiddenDependencies'
+  | 'forbiddenTableCombinations';
+
+export interface ConstraintViolation {
+  constraint: ConstraintType;
+  modules: string[];
+  message: string;
+  /** Why the planner could not satisfy the constraint automatically */
+  reason?: string;
+}
+
+/**
+ * Minimal module view the constraint engine works on
+ */
+export interface ConstrainedModule {
+  name: string;
+  files: string[];
+  /** Names of modules merged into this one */
+  mergedFrom: string[];
+  /** Names of modules this module depends on */
+  dependsOn: string[];
+  tables: string[];
+  /** Circular dependency chains, e.g. "a.go → b.go → a.go" */
+  cycles: string[];
+}
+
+/**
+ * Adapter so clustering (AutoDiscoveredBoundary) and planning (DomainBoundary)
+ * can share the same constraint resolution
+ */
+export interface ModuleAdapter<T> {
+  toConstrained(item: T, all: T[]): ConstrainedModule;
+  merge(target: T, source: T): T;
+  split(item: T, name: string, files: string[]): [T, T];
+}
+
+export interface ConstraintResolution<T> {
+  items: T[];
+  adjustments: string[];
+  violations: ConstraintViolation[];
+}
+
+/**
+ * Apply mustMerge, mustSeparate and maxModules by merging/splitting modules,
+ * then report every constraint that still does not hold
+ */
+export function resolveConstraints<T>(
+  items: T[],
+  constraints: BoundaryConstraints | undefined,
+  adapter: ModuleAdapter<T>
+): ConstraintResolution<T> {
+  if (!constraints) {
+    return { items, adjustments: [], violations: [] };
+  }
+
+  let current = [...items];
+  const adjustments: string[] = [];
+  const unsatisfiable: ConstraintViolation[] = [];
+  const view = () => current.map(item => adapter.toConstrained(item, current));
+
+  // 1. mustMerge
+  for (const group of constraints.mustMerge ?? []) {
+    const conflict = (constraints.mustSeparate ?? []).find(pair => pair.every(name => group.includes(name)));
+    if (conflict) {
+      unsatisfiable.push({
+        constraint: 'mustMerge',
+        modules: group,
+        message: `${group.join(', ')} must be merged`,
+        reason: `conflicts with mustSeparate [${conflict.join(', ')}]`,
+      });
+      continue;
+    }
+
+    const indexes = group
+      .map(name => view().findIndex(m => aliases(m).includes(name)))
+      .filter((index, i, all) => index >= 0 && all.indexOf(index) === i);
+    if (indexes.length < 2) continue;
+
+    const [targetIndex, ...sourceIndexes] = indexes;
+    let target = current[targetIndex];
+    for (const sourceIndex of sourceIndexes) {
+      target = adapter.merge(target, current[sourceIndex]);
+    }
+    current = current
+      .map((item, i) => (i === targetIndex ? target : item))
+      .filter((_, i) => !sourceIndexes.includes(i));
+    adjustments.push(`Merged ${group.join(', ')} (mustMerge)`);
+  }
+
+  // 2. mustSeparate: split a module that contains both sides when possible
+  for (const pair of constraints.mustSeparate ?? []) {
+    const modules = view();
+    const index = modules.findIndex(m => coversAll(m, pair));
+    if (index < 0) continue;
+
+    const module = modules[index];
+    const other = pair.find(name => name !== module.name) ?? pair[1];
+    const extracted = module.files.filter(file => attributeFile(file, pair) === other);
+    const remaining = module.files.filter(file => !extracted.includes(file));
+
+    if (extracted.length === 0 || remaining.length === 0) {
+      unsatisfiable.push({
+        constraint: 'mustSeparate',
+        modules: pair,
+        message: `${pair.join(' and ')} must not be in the same module`,
+        reason: `no files of "${module.name}" can be attributed to "${other}" separately`,
+      });
+      continue;
+    }
+
+    const cycle = module.cycles.find(chain => {
+      const files = chain.split(/\s*→\s*/);
+      return files.some(f => extracted.includes(f)) && files.some(f => remaining.includes(f));
+    });
+    if (cycle) {
+      unsatisfiable.push({
+        constraint: 'mustSeparate',
+        modules: pair,
+        message: `${pair.join(' and ')} must not be in the same module`,
+        reason: `inseparable circular dependency: ${cycle}`,
+      });
+      continue;
+    }
+
+    const [rest, split] = adapter.split(current[index], other, extracted);
+    current.splice(index, 1, rest, split);
+    adjustments.push(`Split ${other} out of ${module.name} (mustSeparate)`);
+  }
+
+  // 3. maxModules: merge the smallest module into its closest allowed neighbour
+  if (constraints.maxModules !== undefined) {
+    while (current.length > constraints.maxModules) {
+      const modules = view();
+      const candidates = modules
+        .map((m, i) => ({ m, i }))
+        .sort((a, b) => a.m.files.length - b.m.files.length);
+
+      let merged = false;
+      for (const { m: source, i: sourceIndex } of candidates) {
+        const targets = modules
+          .map((m, i) => ({ m, i }))
+          .filter(({ i }) => i !== sourceIndex)
+          .filter(({ m }) => !violatesSeparation([...aliases(m), ...aliases(source)], constraints))
+          .sort((a, b) => affinity(source, b.m) - affinity(source, a.m));
+
+        if (targets.length === 0) continue;
+
+        const target = targets[0];
+        current[target.i] = adapter.merge(current[target.i], current[sourceIndex]);
+        current.splice(sourceIndex, 1);
+        adjustments.push(`Merged ${source.name} into ${target.m.name} (maxModules ${constraints.maxModules})`);
+        merged = true;
+        break;
+      }
+
+      if (!merged) {
+        unsatisfiable.push({
+          constraint: 'maxModules',
+          modules: modules.map(m => m.name),
+          message: `${modules.length} modules exceed maxModules ${constraints.maxModules}`,
+          reason: 'every remaining merge would violate a mustSeparate constraint',
+        });
+        break;
+      }
+    }
+  }
+
+  const remaining = evaluateConstraints(view(), constraints);
+  const violations = [
+    ...unsatisfiable,
+    ...remaining.filter(v => !unsatisfiable.some(u => u.constraint === v.constraint && sameModules(u.modules, v.modules))),
+  ];
+
+  return { items: current, adjustments, violations };
+}
+
+/**
+ * Adapter for domain-map boundaries (planner and hybrid boundary analysis)
+ */
+export const domainBoundaryAdapter: ModuleAdapter<DomainBoundary> = {
+  toConstrained(boundary, all) {
+    const dependsOn = new Set<string>();
+    for (const dep of boundary.dependencies?.internal ?? []) {
+      const owner = all.find(other =>
+        other !== boundary &&
+        (other.name === dep || (other.merged_from ?? []).includes(dep) || other.files.includes(dep))
+      );
+      if (owner) dependsOn.add(owner.name);
+    }
+
+    return {
+      name: boundary.name,
+      files: boundary.files,
+      mergedFrom: boundary.merged_from ?? [],
+      dependsOn: [...dependsOn],
+      tables: boundary.tables ?? [],
+      cycles: boundary.circular_dependencies ?? [],
+    };
+  },
+
+  merge(target, source) {
+    const ownFiles = new Set([...target.files, ...source.files]);
+    return {
+      ...target,
+      description: `${target.description} / ${source.description}`,
+      files: [...ownFiles],
+      dependencies: {
+        internal: unique([...(target.dependencies?.internal ?? []), ...(source.dependencies?.internal ?? [])])
+          .filter(dep => !ownFiles.has(dep) && dep !== target.name && dep !== source.name),
+        external: unique([...(target.dependencies?.external ?? []), ...(source.dependencies?.external ?? [])]),
+      },
+      circular_dependencies: unique([...(target.circular_dependencies ?? []), ...(source.circular_dependencies ?? [])]),
+      tables: unique([...(target.tables ?? []), ...(source.tables ?? [])]),
+      merged_from: unique([...(target.merged_from ?? []), source.name, ...(source.merged_from ?? [])]),
+    };
+  },
+
+  split(boundary, name, files) {
+    const moved = new Set(files);
+    return [
+      { ...boundary, files: boundary.files.filter(f => !moved.has(f)) },
+      {
+        name,
+        description: `${boundary.description}（${name}を分離）`,
+        files,
+        dependencies: { internal: [boundary.name], external: [] },
+        circular_dependencies: [],
+        cohesion_score: boundary.cohesion_score,
+        coupling_score: boundary.coupling_score,
+      },
+    ];
+  },
+};
+
+/**
+ * Check constraints without modifying modules (used by `vf plan --check-constraints`)
+ */
+export function evaluateConstraints(modules: ConstrainedModule[], constraints: BoundaryConstraints | undefined): ConstraintViolation[] {
+  if (!constraints) return [];
+  const violations: ConstraintViolation[] = [];
+
+  for (const pair of constraints.mustSeparate ?? []) {
+    const module = modules.find(m => coversAll(m, pair));
+    if (module) {
+      violations.push({
+        constraint: 'mustSeparate',
+        modules: pair,
+        message: `${pair.join(' and ')} must not be in the same module (found together in "${module.name}")`,
+      });
+    }
+  }
+
+  for (const group of constraints.mustMerge ?? []) {
+    const owners = new Set(
+      group
+        .map(name => modules.find(m => aliases(m).includes(name))?.name)
+        .filter((name): name is string => Boolean(name))
+    );
+    if (owners.size > 1) {
+      violations.push({
+        constraint: 'mustMerge',
+        modules: group,
+        message: `${group.join(', ')} must be one module (currently ${[...owners].join(', ')})`,
+      });
+    }
+  }
+
+  if (constraints.maxModules !== undefined && modules.length > constraints.maxModules) {
+    violations.push({
+      constraint: 'maxModules',
+      modules: modules.map(m => m.name),
+      message: `${modules.length} modules exceed maxModules ${constraints.maxModules}`,
+    });
+  }
+
+  for (const [from, to] of constraints.forbiddenDependencies ?? []) {
+    const source = modules.find(m => aliases(m).includes(from));
+    const target = modules.find(m => aliases(m).includes(to));
+    if (!source || !target || source === target) continue;
+
+    if (source.dependsOn.some(dep => aliases(target).includes(dep))) {
+      violations.push({
+        constraint: 'forbiddenDependencies',
+        modules: [from, to],
+        message: `${from} must not depend on ${to}`,
+        reason: 'dependency exists in the analysed code; invert it with an interface or domain event',
+      });
+    }
+  }
+
+  for (const tables of constraints.forbiddenTableCombinations ?? []) {
+    const module = modules.find(m => tables.every(t => m.tables.includes(t)));
+    if (module) {
+      violations.push({
+        constraint: 'forbiddenTableCombinations',
+        modules: [module.name],
+        message: `${module.name} must not own all of ${tables.join(', ')}`,
+      });
+    }
+  }
+
+  return violations;
+}
+
+/**
+ * Whether a file path refers to the given module name (e.g. user.go, users/, user_service.go)
+ */
+export function fileBelongsTo(file: string, moduleName: string): boolean {
+  const escaped = moduleName.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+  return new RegExp(`(^|[/_.-])${escaped}s?([/_.-]|$)`, 'i').test(file);
+}
+
+/**
+ * Which of the given module names a file belongs to; the file name wins over its directory
+ */
+export function attributeFile(file: string, names: string[]): string | undefined {
+  const basename = file.split('/').pop() ?? file;
+  return names.find(name => fileBelongsTo(basename, name)) ?? names.find(name => fileBelongsTo(file, name));
+}
+
+function aliases(module: ConstrainedModule): string[] {
+  return [module.name, ...module.mergedFrom];
+}
+
+function coversAll(module: ConstrainedModule, names: string[]): boolean {
+  const moduleAliases = aliases(module);
+  if (!moduleAliases.some(alias => names.includes(alias))) return false;
+
+  const attributed = new Set(module.files.map(file => attributeFile(file, names)));
+  return names.every(name => moduleAliases.includes(name) || attributed.has(name));
+}
+
+/**
+ * Whether modules with these names (and merged names) together would break a mustSeparate pair
+ */
+export function violatesSeparation(names: string[], constraints: BoundaryConstraints): boolean {
+  return (constraints.mustSeparate ?? []).some(pair => pair.every(name => names.includes(name)));
+}
+
+function affinity(a: ConstrainedModule, b: ConstrainedModule): number {
+  let score = 0;
+  if (a.dependsOn.some(dep => aliases(b).includes(dep))) score += 2;
+  if (b.dependsOn.some(dep => aliases(a).includes(dep))) score += 2;
+  score += a.tables.filter(t => b.tables.includes(t)).length;
+  return score;
+}
+
+function unique(values: string[]): string[] {
+  return [...new Set(values)];
+}
+
+function sameModules(a: string[], b: string[]): boolean {
+  return a.length === b.length && a.every(name => b.includes(name));
+}
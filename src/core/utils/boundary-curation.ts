@@ -0,0 +1,399 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import * as yaml from 'js-yaml';
+import { BoundaryConstraints, BoundaryModule, DomainBoundary } from '../types/config.js';
+import { ConfigLoader } from './config-loader.js';
+import { parseBoundaryConfig, readTextInput } from './input-parsers.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/*
+ * Boundaries curated by hand (`vf boundaries edit`) are kept in boundary.yaml:
+ * the `id` of a module renames the discovered boundary with that stable ID to
+ * the module's name, its `files` are pinned to it whatever the clustering
+ * decides. Discovery and planning apply them on top of the domain map.
+ */
+
+const CURATED_DESCRIPTION = 'Curated in boundary.yaml (vf boundaries edit)';
+
+export const BOUNDARY_EDIT_HELP = [
+  'list                                  boundaries and their file counts',
+  'show <module>                         files of a module',
+  'move <module> <file|dir>...           move files (a directory moves every file under it) to a module, new or existing',
+  'rename <module> <new-name>            rename a module',
+  'merge <module> <other>...             merge the other modules into the first',
+  'split <module> <new-module> <file|dir>...  move files of a module to a new one',
+  'undo                                  revert the last change',
+  'save                                  write boundary.yaml and domain-map.json, then exit',
+  'quit                                  exit without saving',
+];
+
+export interface CurationResult {
+  boundaries: DomainBoundary[];
+  /** `old → new` for every boundary renamed by its ID */
+  renamed: string[];
+  /** Pinned files that moved to another boundary */
+  moved: string[];
+  /** Pinned files no boundary has (deleted or renamed since they were pinned) */
+  missing: string[];
+}
+
+/**
+ * Apply the renames and file pins of boundary.yaml modules to discovered boundaries.
+ * Boundaries emptied by the pins are dropped; modules that name no boundary yet are created.
+ */
+export function applyCuratedModules(
+  projectRoot: string,
+  boundaries: DomainBoundary[],
+  modules: Record<string, BoundaryModule> = {}
+): CurationResult {
+  const relative = relativeTo(projectRoot);
+  const renames = new Map<string, string>();
+  for (const [name, module] of Object.entries(modules)) {
+    const boundary = module.id ? boundaries.find(b => b.id === module.id) : undefined;
+    if (boundary && boundary.name !== name) renames.set(boundary.name, name);
+  }
+
+  let result = renameBoundaries(boundaries, renames);
+  const moved: string[] = [];
+  const missing: string[] = [];
+  for (const [name, module] of Object.entries(modules)) {
+    const pinned = new Set<string>();
+    for (const file of (module.files ?? []).map(toPosixPath)) {
+      const owner = result.find(b => b.files.some(f => relative(f) === file));
+      if (!owner) missing.push(file);
+      else if (owner.name !== name) pinned.add(file);
+    }
+    if (pinned.size === 0) continue;
+    result = transferFiles(result, pinned, name, relative);
+    moved.push(...pinned);
+  }
+
+  return {
+    boundaries: result,
+    renamed: [...renames].map(([from, to]) => `${from} → ${to}`),
+    moved,
+    missing,
+  };
+}
+
+/**
+ * boundary.yaml modules recording the edits from `original` to `edited`: renamed
+ * boundaries keep their ID under the new name, files that changed boundary are pinned.
+ * Other fields of the modules are kept.
+ *
+ * @returns the modules and the renames, for constraints naming the old modules
+ */
+export function curateModules(
+  modules: Record<string, BoundaryModule>,
+  original: DomainBoundary[],
+  edited: DomainBoundary[]
+): { modules: Record<string, BoundaryModule>; renames: Map<string, string> } {
+  const nameBefore = (boundary: DomainBoundary) => boundary.id ? original.find(b => b.id === boundary.id)?.name : undefined;
+  const renamed = edited.filter(boundary => nameBefore(boundary) !== undefined && nameBefore(boundary) !== boundary.name);
+  const renames = new Map(renamed.map(boundary => [nameBefore(boundary)!, boundary.name] as const));
+
+  const result: Record<string, BoundaryModule> = {};
+  for (const [name, module] of Object.entries(modules)) {
+    const target = renames.get(name) ?? name;
+    result[target] = { ...result[target], ...module };
+  }
+
+  const touched = new Set<string>();
+  const entry = (name: string) => {
+    touched.add(name);
+    return (result[name] ??= {});
+  };
+
+  // IDs of boundaries that no longer exist (merged away) rename nothing
+  const ids = new Set(edited.map(b => b.id).filter(Boolean));
+  for (const [name, module] of Object.entries(result)) {
+    if (module.id && !ids.has(module.id)) delete entry(name).id;
+  }
+  renamed.forEach(boundary => { entry(boundary.name).id = boundary.id; });
+
+  const key = (boundary: DomainBoundary) => boundary.id ?? boundary.name;
+  const ownerBefore = new Map(original.flatMap(b => b.files.map(file => [file, key(b)] as const)));
+  for (const boundary of edited) {
+    const pinned = boundary.files.filter(file => ownerBefore.get(file) !== key(boundary));
+    if (pinned.length === 0) continue;
+    for (const [name, module] of Object.entries(result)) {
+      if (name !== boundary.name && module.files?.some(file => pinned.includes(file))) {
+        entry(name).files = module.files.filter(file => !pinned.includes(file));
+      }
+    }
+    const module = entry(boundary.name);
+    module.files = [...new Set([...(module.files ?? []), ...pinned])].sort();
+  }
+
+  for (const name of touched) {
+    const module = result[name];
+    if (module.files?.length === 0) delete module.files;
+    if (Object.keys(module).length === 0) delete result[name];
+  }
+  return { modules: result, renames };
+}
+
+/**
+ * Record an edit session in boundary.yaml (created when missing). Other keys of
+ * the file are kept and constraints follow renamed modules; comments are not preserved.
+ *
+ * @throws InputParseError when the existing boundary.yaml is invalid
+ */
+export function writeCuration(projectRoot: string, original: DomainBoundary[], edited: DomainBoundary[]): string {
+  const file = path.join(projectRoot, 'boundary.yaml');
+  const content = fs.existsSync(file) ? readTextInput('boundary-config', file) : '';
+  parseBoundaryConfig(content, file);
+
+  const raw = (yaml.load(content) ?? {}) as Record<string, unknown>;
+  const { modules, renames } = curateModules((raw.modules ?? {}) as Record<string, BoundaryModule>, original, edited);
+  raw.modules = modules;
+  if (raw.constraints) raw.constraints = renameConstraints(raw.constraints as BoundaryConstraints, renames);
+
+  ConfigLoader.saveConfig(raw, file);
+  return file;
+}
+
+/**
+ * Edits of `vf boundaries edit`, one command line at a time
+ */
+export class BoundaryEditSession {
+  boundaries: DomainBoundary[];
+  private history: DomainBoundary[][] = [];
+  private relative: (file: string) => string;
+  private warnedUnsaved = false;
+
+  constructor(projectRoot: string, boundaries: DomainBoundary[]) {
+    this.boundaries = boundaries;
+    this.relative = relativeTo(projectRoot);
+  }
+
+  get changed(): boolean {
+    return this.history.length > 0;
+  }
+
+  /**
+   * @throws Error for unknown commands, modules and files; the boundaries are left unchanged
+   */
+  execute(line: string): { output: string[]; exit?: 'save' | 'quit' } {
+    const [command, ...args] = line.trim().split(/\s+/).filter(Boolean);
+    if (!command) return { output: [] };
+
+    switch (command) {
+      case 'help':
+        return { output: BOUNDARY_EDIT_HELP };
+      case 'list':
+        return {
+          output: [...this.boundaries]
+            .sort((a, b) => compare(a.name, b.name))
+            .map(b => `${b.name}${b.id ? ` (${b.id})` : ''}: ${b.files.length} files${b.status === 'established' ? ', established' : ''}`),
+        };
+      case 'show':
+        this.expectArgs(args, 1, 'show <module>');
+        return { output: this.boundary(args[0]).files.map(this.relative).sort() };
+      case 'move': {
+        this.expectArgs(args, 2, 'move <module> <file|dir>...');
+        const to = this.boundaries.find(b => b.name === args[0] || b.id === args[0])?.name ?? args[0];
+        const files = this.matchFiles(this.boundaries.filter(b => b.name !== to), args.slice(1));
+        return this.apply(transferFiles(this.boundaries, files, to, this.relative), `moved ${files.size} files to ${to}`);
+      }
+      case 'rename': {
+        this.expectArgs(args, 2, 'rename <module> <new-name>');
+        const from = this.boundary(args[0]).name;
+        this.expectNew(args[1]);
+        return this.apply(renameBoundaries(this.boundaries, new Map([[from, args[1]]])), `renamed ${from} to ${args[1]}`);
+      }
+      case 'merge': {
+        this.expectArgs(args, 2, 'merge <module> <other>...');
+        const into = this.boundary(args[0]).name;
+        const others = [...new Set(args.slice(1).map(name => this.boundary(name).name))].filter(name => name !== into);
+        if (others.length === 0) throw new Error('merge needs another module');
+        return this.apply(mergeBoundaries(this.boundaries, into, others, this.relative), `merged ${others.join(', ')} into ${into}`);
+      }
+      case 'split': {
+        this.expectArgs(args, 3, 'split <module> <new-module> <file|dir>...');
+        const from = this.boundary(args[0]);
+        this.expectNew(args[1]);
+        const files = this.matchFiles([from], args.slice(2));
+        if (files.size === from.files.length) throw new Error(`split would move every file of ${from.name} (use rename)`);
+        return this.apply(transferFiles(this.boundaries, files, args[1], this.relative), `moved ${files.size} files of ${from.name} to ${args[1]}`);
+      }
+      case 'undo': {
+        const previous = this.history.pop();
+        if (!previous) throw new Error('nothing to undo');
+        this.boundaries = previous;
+        return { output: ['reverted the last change'] };
+      }
+      case 'save':
+        return { output: [], exit: 'save' };
+      case 'quit':
+        if (this.changed && !this.warnedUnsaved) {
+          this.warnedUnsaved = true;
+          return { output: ['unsaved changes: "save" to keep them, "quit" again to discard them'] };
+        }
+        return { output: [], exit: 'quit' };
+      default:
+        throw new Error(`unknown command '${command}' (type "help")`);
+    }
+  }
+
+  private apply(boundaries: DomainBoundary[], message: string): { output: string[] } {
+    this.history.push(this.boundaries);
+    this.boundaries = boundaries;
+    this.warnedUnsaved = false;
+    return { output: [message] };
+  }
+
+  private boundary(nameOrId: string): DomainBoundary {
+    const boundary = this.boundaries.find(b => b.name === nameOrId || b.id === nameOrId);
+    if (!boundary) throw new Error(`no module ${nameOrId} (type "list")`);
+    if (boundary.status === 'established') {
+      throw new Error(`${boundary.name} is an established module (vf discover --reconsider-established ${boundary.name} to change it)`);
+    }
+    return boundary;
+  }
+
+  private expectNew(name: string): void {
+    if (this.boundaries.some(b => b.name === name)) throw new Error(`module ${name} already exists`);
+  }
+
+  private expectArgs(args: string[], count: number, usage: string): void {
+    if (args.length < count) throw new Error(`usage: ${usage}`);
+  }
+
+  /**
+   * Files of the boundaries matching each argument: a file, or a directory and everything under it
+   */
+  private matchFiles(boundaries: DomainBoundary[], patterns: string[]): Set<string> {
+    const files = new Set<string>();
+    for (const pattern of patterns.map(p => toPosixPath(p).replace(/^\.\//, '').replace(/\/+$/, ''))) {
+      const matched = boundaries
+        .filter(b => b.status !== 'established')
+        .flatMap(b => b.files.map(this.relative))
+        .filter(file => file === pattern || file.startsWith(`${pattern}/`));
+      if (matched.length === 0) throw new Error(`no file matches ${pattern}`);
+      matched.forEach(file => files.add(file));
+    }
+    return files;
+  }
+}
+
+/**
+ * Move files (relative paths) with their per-file entries to boundary `to`, creating it when needed
+ */
+function transferFiles(
+  boundaries: DomainBoundary[],
+  files: Set<string>,
+  to: string,
+  relative: (file: string) => string
+): DomainBoundary[] {
+  const moves = (file: string) => files.has(relative(file));
+  const existing = boundaries.find(b => b.name === to);
+  const target: DomainBoundary = existing
+    ? { ...existing, files: [...existing.files] }
+    : { name: to, description: CURATED_DESCRIPTION, files: [] };
+
+  const result: DomainBoundary[] = [];
+  for (const boundary of boundaries) {
+    if (boundary === existing) {
+      result.push(target);
+      continue;
+    }
+    if (!boundary.files.some(moves)) {
+      result.push(boundary);
+      continue;
+    }
+
+    target.files.push(...boundary.files.filter(moves));
+    const [packages, keptPackages] = partition(boundary.file_packages, e => moves(e.file));
+    const [coupling, keptCoupling] = partition(boundary.file_coupling, e => moves(e.file));
+    const [degraded, keptDegraded] = partition(boundary.degraded_files, e => moves(e.file));
+    if (packages.length > 0) target.file_packages = [...(target.file_packages ?? []), ...packages];
+    if (coupling.length > 0) target.file_coupling = [...(target.file_coupling ?? []), ...coupling];
+    if (degraded.length > 0) target.degraded_files = [...(target.degraded_files ?? []), ...degraded];
+
+    const remaining = boundary.files.filter(file => !moves(file));
+    // Boundaries emptied by the move are dropped
+    if (remaining.length === 0) continue;
+    result.push({
+      ...boundary,
+      files: remaining,
+      ...(boundary.file_packages ? { file_packages: keptPackages } : {}),
+      ...(boundary.file_coupling ? { file_coupling: keptCoupling } : {}),
+      ...(boundary.degraded_files ? { degraded_files: keptDegraded } : {}),
+    });
+  }
+  if (!existing) result.push(target);
+
+  // References to the target's own files are no longer coupling
+  if (target.file_coupling) target.file_coupling = target.file_coupling.filter(c => c.boundary !== to);
+  return result;
+}
+
+function mergeBoundaries(
+  boundaries: DomainBoundary[],
+  into: string,
+  others: string[],
+  relative: (file: string) => string
+): DomainBoundary[] {
+  const sources = boundaries.filter(b => others.includes(b.name));
+  const files = new Set(sources.flatMap(b => b.files.map(relative)));
+  const renamed = renameBoundaries(transferFiles(boundaries, files, into, relative), new Map(others.map(name => [name, into] as const)));
+  return renamed.map(boundary => {
+    if (boundary.name !== into) return boundary;
+    const internal = [...new Set([
+      ...(boundary.dependencies?.internal ?? []),
+      ...sources.flatMap(b => b.dependencies?.internal ?? []).map(name => others.includes(name) ? into : name),
+    ])].filter(name => name !== into);
+    return {
+      ...boundary,
+      dependencies: { ...boundary.dependencies, internal },
+      merged_from: [...new Set([...(boundary.merged_from ?? []), ...others])],
+    };
+  });
+}
+
+/**
+ * Rename boundaries (old name → new name, all at once) and the dependencies and coupling naming them
+ */
+function renameBoundaries(boundaries: DomainBoundary[], renames: Map<string, string>): DomainBoundary[] {
+  if (renames.size === 0) return boundaries;
+  const rename = (name: string) => renames.get(name) ?? name;
+  return boundaries.map(boundary => ({
+    ...boundary,
+    name: rename(boundary.name),
+    ...(boundary.dependencies?.internal ? {
+      dependencies: { ...boundary.dependencies, internal: [...new Set(boundary.dependencies.internal.map(rename))] },
+    } : {}),
+    ...(boundary.file_coupling ? {
+      file_coupling: boundary.file_coupling.map(c => ({ ...c, boundary: rename(c.boundary) })),
+    } : {}),
+  }));
+}
+
+function renameConstraints(constraints: BoundaryConstraints, renames: Map<string, string>): BoundaryConstraints {
+  if (renames.size === 0) return constraints;
+  const rename = (name: string) => renames.get(name) ?? name;
+  return {
+    ...constraints,
+    ...(constraints.mustSeparate ? { mustSeparate: constraints.mustSeparate.map(group => group.map(rename)) } : {}),
+    ...(constraints.mustMerge ? { mustMerge: constraints.mustMerge.map(group => group.map(rename)) } : {}),
+    ...(constraints.forbiddenDependencies ? {
+      forbiddenDependencies: constraints.forbiddenDependencies.map(([from, to]) => [rename(from), rename(to)] as [string, string]),
+    } : {}),
+  };
+}
+
+function relativeTo(projectRoot: string): (file: string) => string {
+  return file => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+}
+
+function partition<T>(items: T[] | undefined, predicate: (item: T) => boolean): [T[], T[]] {
+  const matched: T[] = [];
+  const rest: T[] = [];
+  for (const item of items ?? []) (predicate(item) ? matched : rest).push(item);
+  return [matched, rest];
+}
+
+function compare(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
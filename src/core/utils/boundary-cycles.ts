@@ -0,0 +1,110 @@
+import { BoundaryCycle, CycleEdge } from '../types/config.js';
+import { CallEdge } from './call-graph.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Cycles reported at most; enumeration stops there on densely coupled maps */
+const MAX_CYCLES = 50;
+/** Longest cycle enumerated, in modules */
+const MAX_CYCLE_LENGTH = 6;
+
+/** Callees answering a question: the caller needs the result, so the edge is inverted with an interface */
+const QUERY_NAME = /^(?:get|find|list|load|fetch|lookup|search|query|count|is|has|can)(?:[A-Z_]|$)/i;
+
+/**
+ * Dependency cycles between boundaries in the call graph, each with the calls
+ * behind its edges and the cheapest edge to cut (fewest call sites). A cut edge
+ * calling only queries is inverted with an interface, any other with an event.
+ */
+export function findBoundaryCycles(edges: CallEdge[], boundaries: { name: string; files: string[] }[]): BoundaryCycle[] {
+  const moduleOf = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) {
+      if (!moduleOf.has(toPosixPath(file))) moduleOf.set(toPosixPath(file), boundary.name);
+    }
+  }
+
+  const moduleEdges = new Map<string, CycleEdge>();
+  for (const edge of edges) {
+    const from = moduleOf.get(toPosixPath(edge.caller_file));
+    const to = moduleOf.get(toPosixPath(edge.callee_file));
+    if (!from || !to || from === to) continue;
+    const key = `${from}\n${to}`;
+    const moduleEdge = moduleEdges.get(key) ?? { from, to, calls: 0, sites: [] };
+    moduleEdge.calls += edge.count;
+    moduleEdge.sites.push({
+      caller_file: toPosixPath(edge.caller_file),
+      caller: qualified(edge.caller, edge.caller_type),
+      callee_file: toPosixPath(edge.callee_file),
+      callee: qualified(edge.callee, edge.callee_type),
+      count: edge.count,
+    });
+    moduleEdges.set(key, moduleEdge);
+  }
+
+  const next = new Map<string, string[]>();
+  for (const { from, to } of moduleEdges.values()) next.set(from, [...(next.get(from) ?? []), to].sort(compare));
+
+  // Elementary cycles, each found once from its first module in name order
+  const cycles: string[][] = [];
+  for (const start of [...next.keys()].sort(compare)) {
+    const visit = (modules: string[]) => {
+      for (const to of next.get(modules[modules.length - 1]) ?? []) {
+        if (cycles.length >= MAX_CYCLES) return;
+        if (to === start) cycles.push(modules);
+        else if (compare(to, start) > 0 && !modules.includes(to) && modules.length < MAX_CYCLE_LENGTH) visit([...modules, to]);
+      }
+    };
+    visit([start]);
+  }
+
+  return cycles
+    .map(modules => {
+      const cycleEdges = modules.map((from, i) => moduleEdges.get(`${from}\n${modules[(i + 1) % modules.length]}`)!)
+        .map(edge => ({ ...edge, sites: [...edge.sites].sort((a, b) => b.count - a.count || compare(a.caller_file, b.caller_file) || compare(a.caller, b.caller)) }));
+      const cut = [...cycleEdges].sort((a, b) => a.calls - b.calls || a.sites.length - b.sites.length || compare(a.from, b.from))[0];
+      return {
+        modules,
+        edges: cycleEdges,
+        break_at: {
+          from: cut.from,
+          to: cut.to,
+          calls: cut.calls,
+          remedy: cut.sites.every(site => QUERY_NAME.test(site.callee.split('.').pop()!)) ? 'interface' as const : 'event' as const,
+        },
+      };
+    })
+    .sort((a, b) => a.modules.length - b.modules.length || compare(a.modules.join('\n'), b.modules.join('\n')));
+}
+
+/**
+ * plan.md section listing each cycle with the edge to cut and its call sites
+ */
+export function renderBoundaryCyclesSection(cycles: BoundaryCycle[] = []): string {
+  if (cycles.length === 0) return '';
+
+  const entries = cycles.map(cycle => {
+    const { from, to, calls, remedy } = cycle.break_at;
+    const cut = cycle.edges.find(edge => edge.from === from && edge.to === to);
+    const sites = (cut?.sites ?? []).map(site => `  - ${site.caller_file} ${site.caller} → ${site.callee_file} ${site.callee}${site.count > 1 ? ` (${site.count})` : ''}`);
+    return [
+      `- ${[...cycle.modules, cycle.modules[0]].join(' → ')}: **${from} → ${to}** を切る（${calls}箇所、${remedy === 'interface' ? `${from} 側にインターフェースを定義して依存を反転` : `${to} が購読するドメインイベントに置き換え`}）`,
+      ...sites,
+    ].join('\n');
+  });
+
+  return `
+## 依存サイクル
+
+モジュール間の呼び出しが循環しています。各サイクルで呼び出し箇所が最も少ない依存を切ってください。
+
+${entries.join('\n')}
+`;
+}
+
+function qualified(name: string, type?: string): string {
+  return type ? `${type}.${name}` : name;
+}
+
+function compare(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
@@ -0,0 +1,177 @@
+import { DomainMap } from '../types/config.js';
+import { round } from './number-utils.js';
+
+export type GraphFormat = 'dot' | 'mermaid' | 'graphml';
+
+export const GRAPH_FORMATS: GraphFormat[] = ['dot', 'mermaid', 'graphml'];
+
+export const GRAPH_EXTENSIONS: Record<GraphFormat, string> = {
+  dot: 'dot',
+  mermaid: 'mmd',
+  graphml: 'graphml',
+};
+
+export interface BoundaryGraphNode {
+  name: string;
+  files: number;
+  cohesion?: number;
+  coupling?: number;
+  established: boolean;
+}
+
+export interface BoundaryGraphEdge {
+  from: string;
+  to: string;
+  /** References from files of `from` to packages of `to` (0 when only the dependency is recorded) */
+  references: number;
+  /** Files of `from` making them */
+  files: number;
+}
+
+export interface BoundaryGraph {
+  nodes: BoundaryGraphNode[];
+  edges: BoundaryGraphEdge[];
+}
+
+/**
+ * Module graph of a domain map: boundaries, and their dependencies weighted by
+ * the file references behind them
+ */
+export function buildBoundaryGraph(map: DomainMap): BoundaryGraph {
+  const names = new Set(map.boundaries.map(b => b.name));
+  const edges = new Map<string, BoundaryGraphEdge & { fileSet: Set<string> }>();
+  const edge = (from: string, to: string) => {
+    const key = `${from}\n${to}`;
+    if (!edges.has(key)) edges.set(key, { from, to, references: 0, files: 0, fileSet: new Set() });
+    return edges.get(key)!;
+  };
+
+  for (const boundary of map.boundaries) {
+    for (const to of boundary.dependencies?.internal ?? []) {
+      if (to !== boundary.name && names.has(to)) edge(boundary.name, to);
+    }
+    for (const coupling of boundary.file_coupling ?? []) {
+      if (coupling.boundary === boundary.name || !names.has(coupling.boundary)) continue;
+      const e = edge(boundary.name, coupling.boundary);
+      e.references += coupling.references;
+      e.fileSet.add(coupling.file);
+    }
+  }
+
+  return {
+    nodes: map.boundaries
+      .map(boundary => ({
+        name: boundary.name,
+        files: boundary.files.length,
+        ...(boundary.cohesion_score !== undefined ? { cohesion: round(boundary.cohesion_score, 2) } : {}),
+        ...(boundary.coupling_score !== undefined ? { coupling: round(boundary.coupling_score, 2) } : {}),
+        established: boundary.status === 'established',
+      }))
+      .sort((a, b) => compare(a.name, b.name)),
+    edges: [...edges.values()]
+      .map(({ fileSet, ...e }) => ({ ...e, files: fileSet.size }))
+      .sort((a, b) => compare(a.from, b.from) || compare(a.to, b.to)),
+  };
+}
+
+export function renderBoundaryGraph(graph: BoundaryGraph, format: GraphFormat): string {
+  switch (format) {
+    case 'dot':
+      return renderDot(graph);
+    case 'mermaid':
+      return renderMermaid(graph);
+    case 'graphml':
+      return renderGraphMl(graph);
+  }
+}
+
+/**
+ * Graphviz: edge width grows with the references; established modules are dashed
+ */
+function renderDot(graph: BoundaryGraph): string {
+  const escape = (text: string) => text.replace(/\\/g, '\\\\').replace(/"/g, '\\"');
+  const quote = (text: string) => `"${escape(text)}"`;
+  const max = Math.max(1, ...graph.edges.map(e => e.references));
+  const lines = ['digraph boundaries {', '  rankdir=LR;', '  node [shape=box, style=rounded];'];
+
+  for (const node of graph.nodes) {
+    const label = [node.name, ...nodeDetails(node)].map(escape).join('\\n');
+    lines.push(`  ${quote(node.name)} [label="${label}"${node.established ? ', style="rounded,dashed"' : ''}];`);
+  }
+  for (const e of graph.edges) {
+    const width = (1 + (4 * e.references) / max).toFixed(1);
+    lines.push(`  ${quote(e.from)} -> ${quote(e.to)} [label="${e.references}", weight=${Math.max(1, e.references)}, penwidth=${width}];`);
+  }
+
+  lines.push('}');
+  return lines.join('\n') + '\n';
+}
+
+function renderMermaid(graph: BoundaryGraph): string {
+  const ids = new Map(graph.nodes.map((node, i) => [node.name, `m${i}_${node.name.replace(/\W/g, '_')}`]));
+  const label = (text: string) => text.replace(/"/g, '#quot;');
+  const lines = ['graph LR'];
+
+  for (const node of graph.nodes) {
+    const details = nodeDetails(node);
+    lines.push(`  ${ids.get(node.name)}["${label([node.name, ...details].join('<br/>'))}"]${node.established ? ':::established' : ''}`);
+  }
+  for (const e of graph.edges) {
+    lines.push(`  ${ids.get(e.from)} -->|${e.references}| ${ids.get(e.to)}`);
+  }
+  if (graph.nodes.some(node => node.established)) {
+    lines.push('  classDef established stroke-dasharray: 5 5');
+  }
+  return lines.join('\n') + '\n';
+}
+
+function renderGraphMl(graph: BoundaryGraph): string {
+  const xml = (text: string) => text.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;').replace(/"/g, '&quot;');
+  const data = (key: string, value: string | number | boolean | undefined) =>
+    value === undefined ? [] : [`      <data key="${key}">${xml(String(value))}</data>`];
+  const lines = [
+    '<?xml version="1.0" encoding="UTF-8"?>',
+    '<graphml xmlns="http://graphml.graphdrawing.org/xmlns">',
+    '  <key id="files" for="node" attr.name="files" attr.type="int"/>',
+    '  <key id="cohesion" for="node" attr.name="cohesion" attr.type="double"/>',
+    '  <key id="coupling" for="node" attr.name="coupling" attr.type="double"/>',
+    '  <key id="established" for="node" attr.name="established" attr.type="boolean"/>',
+    '  <key id="references" for="edge" attr.name="references" attr.type="int"/>',
+    '  <key id="source_files" for="edge" attr.name="source_files" attr.type="int"/>',
+    '  <graph id="boundaries" edgedefault="directed">',
+  ];
+
+  for (const node of graph.nodes) {
+    lines.push(
+      `    <node id="${xml(node.name)}">`,
+      ...data('files', node.files),
+      ...data('cohesion', node.cohesion),
+      ...data('coupling', node.coupling),
+      ...data('established', node.established),
+      '    </node>'
+    );
+  }
+  for (const e of graph.edges) {
+    lines.push(
+      `    <edge source="${xml(e.from)}" target="${xml(e.to)}">`,
+      ...data('references', e.references),
+      ...data('source_files', e.files),
+      '    </edge>'
+    );
+  }
+
+  lines.push('  </graph>', '</graphml>');
+  return lines.join('\n') + '\n';
+}
+
+function nodeDetails(node: BoundaryGraphNode): string[] {
+  return [
+    `${node.files} files`,
+    ...(node.cohesion !== undefined ? [`cohesion ${node.cohesion}`] : []),
+    ...(node.established ? ['established'] : []),
+  ];
+}
+
+function compare(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
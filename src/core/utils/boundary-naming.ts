@@ -0,0 +1,259 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import * as yaml from 'js-yaml';
+import { BoundaryNaming, DomainBoundary } from '../types/config.js';
+import { getErrorMessage } from './error-utils.js';
+
+/**
+ * Canonical domain term of the glossary (boundary.yaml naming.glossary)
+ */
+export interface GlossaryTerm {
+  /** Name boundaries take, snake_cased */
+  term: string;
+  /** Words the code uses for the same concept */
+  aliases: string[];
+  description?: string;
+}
+
+/**
+ * Identifiers and comments of a boundary's files, the material names are taken from
+ */
+export interface NamingEvidence {
+  /** Exported types and functions, most frequent words first */
+  identifiers: string[];
+  /** Words of the identifiers with their occurrences, domain words only */
+  words: [string, number][];
+  /** Package and declaration doc comments */
+  comments: string[];
+}
+
+/** Asks the LLM for a name; the reply is parsed with parseProposedName */
+export type BoundaryNamer = (prompt: string) => Promise<string | null>;
+
+/** Names clustering falls back to (module, module_3, cluster-2) or that only describe a layer */
+const GENERIC_NAME = /^(?:module|cluster|boundary|group|component|package|service|services|handler|handlers|manager|util|utils|helper|helpers|common|core|internal|impl|api|server|misc)(?:[_-]?\d+)?$/;
+
+/** Identifier words that say nothing about the domain */
+const GENERIC_WORDS = new Set([
+  'get', 'set', 'new', 'create', 'update', 'delete', 'find', 'list', 'save', 'load', 'handle', 'handler', 'service',
+  'repository', 'repo', 'impl', 'model', 'data', 'type', 'error', 'err', 'context', 'request', 'response', 'config',
+  'manager', 'util', 'helper', 'client', 'server', 'test', 'with', 'from', 'into', 'string', 'int', 'by', 'id', 'ids',
+  'all', 'to', 'is', 'has', 'make', 'build', 'run', 'init', 'default', 'info', 'input', 'output', 'result', 'params',
+]);
+
+/** Identifiers and terms matching a glossary term before it names a boundary without the LLM */
+const MIN_GLOSSARY_MATCHES = 2;
+/** Identifiers and comments put into a prompt */
+const MAX_EVIDENCE = 20;
+
+export function isGenericBoundaryName(name: string): boolean {
+  return GENERIC_NAME.test(name.toLowerCase());
+}
+
+/**
+ * Glossary file: a YAML (or JSON) list of terms, or `terms:` with one; each a string
+ * or `{ term, aliases, description }`
+ */
+export function loadGlossary(projectRoot: string, file: string): GlossaryTerm[] {
+  const fullPath = path.resolve(projectRoot, file);
+  let parsed: unknown;
+  try {
+    parsed = yaml.load(fs.readFileSync(fullPath, 'utf8'));
+  } catch (error) {
+    throw new Error(`Cannot read glossary ${file}: ${getErrorMessage(error)}`);
+  }
+
+  const entries = Array.isArray(parsed) ? parsed : (parsed as { terms?: unknown } | null)?.terms;
+  if (!Array.isArray(entries)) {
+    throw new Error(`Invalid glossary ${file}: expected a list of terms`);
+  }
+  return entries.flatMap((entry): GlossaryTerm[] => {
+    if (typeof entry === 'string' && entry.trim()) return [{ term: snakeCase(entry), aliases: [] }];
+    if (!entry || typeof entry !== 'object' || typeof (entry as any).term !== 'string') return [];
+    const { term, aliases, description } = entry as { term: string; aliases?: unknown; description?: unknown };
+    return [{
+      term: snakeCase(term),
+      aliases: Array.isArray(aliases) ? aliases.filter((alias): alias is string => typeof alias === 'string') : [],
+      ...(typeof description === 'string' ? { description } : {}),
+    }];
+  });
+}
+
+/**
+ * Exported identifiers and doc comments of the files
+ */
+export function namingEvidence(projectRoot: string, files: string[]): NamingEvidence {
+  const identifiers = new Set<string>();
+  const comments = new Set<string>();
+  for (const file of files) {
+    let content: string;
+    try {
+      content = fs.readFileSync(path.resolve(projectRoot, file), 'utf8');
+    } catch {
+      continue;
+    }
+    for (const match of content.matchAll(/^(?:type|func(?:\s*\([^)]*\))?)\s+([A-Z]\w*)/gm)) identifiers.add(match[1]);
+    for (const match of content.matchAll(/^\/\/\s*((?:Package\s+)?[A-Z]\w*\s+.+)$/gm)) comments.add(match[1].trim());
+  }
+
+  const counts = new Map<string, number>();
+  for (const identifier of identifiers) {
+    for (const word of identifierWords(identifier)) {
+      if (word.length > 2 && !GENERIC_WORDS.has(word)) counts.set(word, (counts.get(word) ?? 0) + 1);
+    }
+  }
+  const words = [...counts].sort((a, b) => b[1] - a[1] || (a[0] < b[0] ? -1 : 1));
+  const rank = new Map(words.map(([word], i) => [word, i]));
+  const bestRank = (identifier: string) => Math.min(...identifierWords(identifier).map(word => rank.get(word) ?? Infinity));
+
+  return {
+    identifiers: [...identifiers].sort((a, b) => bestRank(a) - bestRank(b) || (a < b ? -1 : 1)),
+    words,
+    comments: [...comments].sort(),
+  };
+}
+
+/**
+ * Glossary term the evidence names most often, counting identifiers and comments
+ * containing all words of the term or one of its aliases
+ */
+export function glossaryMatch(evidence: NamingEvidence, glossary: GlossaryTerm[]): { term: string; matches: number } | undefined {
+  const scored = glossary.map(entry => {
+    const forms = [entry.term, ...entry.aliases].map(form => identifierWords(form).map(singular));
+    const contains = (words: string[]) => forms.some(form => form.length > 0 && form.every(word => words.includes(word)));
+    const matches = evidence.identifiers.filter(identifier => contains(identifierWords(identifier).map(singular))).length
+      + evidence.comments.filter(comment => contains(comment.toLowerCase().split(/[^a-z0-9]+/).map(singular))).length;
+    return { term: entry.term, matches };
+  });
+  return scored
+    .filter(candidate => candidate.matches >= MIN_GLOSSARY_MATCHES)
+    .sort((a, b) => b.matches - a.matches || (a.term < b.term ? -1 : 1))[0];
+}
+
+export function buildNamingPrompt(
+  boundary: { name: string; files: string[] },
+  evidence: NamingEvidence,
+  glossary: GlossaryTerm[],
+  taken: string[]
+): string {
+  const glossaryLines = glossary.map(entry =>
+    `- ${entry.term}${entry.aliases.length > 0 ? ` (also: ${entry.aliases.join(', ')})` : ''}${entry.description ? `: ${entry.description}` : ''}`);
+
+  return `
+Propose a name for a module boundary discovered in a Go codebase. It is currently called "${boundary.name}".
+
+Files (${boundary.files.length}): ${boundary.files.slice(0, MAX_EVIDENCE).join(', ')}
+Exported identifiers: ${evidence.identifiers.slice(0, MAX_EVIDENCE).join(', ') || '(none)'}
+Doc comments:
+${evidence.comments.slice(0, MAX_EVIDENCE).map(comment => `- ${comment}`).join('\n') || '(none)'}
+
+${glossary.length > 0
+    ? `The name must be one of these canonical domain terms:\n${glossaryLines.join('\n')}`
+    : 'Use a domain concept (e.g. order, billing, inventory), not a technical layer (service, handler, util).'}
+Names already taken: ${taken.join(', ') || '(none)'}
+
+Reply with JSON only: {"name": "<snake_case name>", "rationale": "<one sentence>"}
+`;
+}
+
+/**
+ * Name and rationale of an LLM reply; with a glossary, the name (or the alias it
+ * is) must resolve to a glossary term
+ */
+export function parseProposedName(text: string, glossary: GlossaryTerm[]): { name: string; rationale: string } | null {
+  const json = text.match(/\{[\s\S]*\}/)?.[0];
+  if (!json) return null;
+  let parsed: { name?: unknown; rationale?: unknown };
+  try {
+    parsed = JSON.parse(json);
+  } catch {
+    return null;
+  }
+  if (typeof parsed.name !== 'string') return null;
+
+  const proposed = snakeCase(parsed.name);
+  const name = glossary.length > 0
+    ? glossary.find(entry => [entry.term, ...entry.aliases].some(form => snakeCase(form) === proposed))?.term
+    : proposed;
+  if (!name || !/^[a-z][a-z0-9_]{1,39}$/.test(name) || isGenericBoundaryName(name)) return null;
+  return { name, rationale: typeof parsed.rationale === 'string' ? parsed.rationale.trim() : '' };
+}
+
+/**
+ * Rename boundaries with generic names: the LLM's proposal when a namer is given,
+ * else the glossary term the identifiers and comments use most, else their most
+ * frequent domain word. Names of other boundaries are not reused, and dependencies
+ * on a renamed boundary follow it. Established boundaries keep their names.
+ */
+export async function nameBoundaries(
+  boundaries: DomainBoundary[],
+  options: { projectRoot: string; glossary?: GlossaryTerm[]; namer?: BoundaryNamer }
+): Promise<DomainBoundary[]> {
+  const glossary = options.glossary ?? [];
+  const taken = new Set(boundaries.map(boundary => boundary.name));
+  const renamed = new Map<number, BoundaryNaming & { name: string }>();
+
+  for (const [index, boundary] of boundaries.entries()) {
+    if (boundary.status === 'established' || !isGenericBoundaryName(boundary.name)) continue;
+    const evidence = namingEvidence(options.projectRoot, boundary.files);
+    const available = (name: string | undefined) => name !== undefined && name !== boundary.name && !taken.has(name);
+
+    let proposal: { name: string; source: BoundaryNaming['source']; rationale: string } | undefined;
+    if (options.namer) {
+      try {
+        const reply = await options.namer(buildNamingPrompt(boundary, evidence, glossary, [...taken]));
+        const parsed = reply ? parseProposedName(reply, glossary) : null;
+        if (parsed && available(parsed.name)) proposal = { ...parsed, source: 'llm' };
+      } catch (error) {
+        console.warn(`⚠️  ${boundary.name} の名前を LLM に提案させられませんでした: ${getErrorMessage(error)}`);
+      }
+    }
+    const match = glossaryMatch(evidence, glossary);
+    if (!proposal && match && available(match.term)) {
+      proposal = { name: match.term, source: 'glossary', rationale: `用語集の「${match.term}」に一致する識別子・コメント ${match.matches}件` };
+    }
+    const [word, count] = evidence.words[0] ?? [];
+    if (!proposal && glossary.length === 0 && count !== undefined && count >= 2 && available(word)) {
+      proposal = { name: word, source: 'identifiers', rationale: `識別子の ${count}件に含まれる「${word}」` };
+    }
+    if (!proposal) continue;
+
+    taken.add(proposal.name);
+    renamed.set(index, { name: proposal.name, source: proposal.source, previous: boundary.name, rationale: proposal.rationale });
+  }
+
+  // Dependencies follow a renamed boundary when its old name was unique
+  const byOldName = new Map<string, string>();
+  for (const [index, { name }] of renamed) {
+    const previous = boundaries[index].name;
+    if (boundaries.filter(boundary => boundary.name === previous).length === 1) byOldName.set(previous, name);
+  }
+
+  return boundaries.map((boundary, index) => {
+    const naming = renamed.get(index);
+    const internal = boundary.dependencies?.internal?.map(dep => byOldName.get(dep) ?? dep);
+    return {
+      ...boundary,
+      ...(naming ? { name: naming.name, naming: { source: naming.source, previous: naming.previous, rationale: naming.rationale } } : {}),
+      ...(internal ? { dependencies: { ...boundary.dependencies, internal } } : {}),
+    };
+  });
+}
+
+function identifierWords(identifier: string): string[] {
+  return identifier
+    .replace(/([a-z0-9])([A-Z])/g, '$1 $2')
+    .replace(/([A-Z]+)([A-Z][a-z])/g, '$1 $2')
+    .toLowerCase()
+    .split(/[^a-z0-9]+/)
+    .filter(Boolean);
+}
+
+function singular(word: string): string {
+  if (word.length > 4 && word.endsWith('ies')) return `${word.slice(0, -3)}y`;
+  return word.length > 3 && word.endsWith('s') && !word.endsWith('ss') ? word.slice(0, -1) : word;
+}
+
+function snakeCase(text: string): string {
+  return identifierWords(text.trim()).join('_');
+}
@@ -0,0 +1,176 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { PerformanceStore, RunRecord } from './performance-store.js';
+import { FailureCategory } from './error-utils.js';
+import { formatRunEnvironment, redactSecrets, redactText } from './run-environment.js';
+import { createZip } from './review-packet.js';
+
+export interface FailedResponse {
+  run_id?: number;
+  /** Source file the response was generated for */
+  file: string;
+  model?: string;
+  category: FailureCategory;
+  error: string;
+  /** Raw model output (credentials redacted) */
+  response: string;
+  recorded_at: string;
+}
+
+export interface BugReportEntry {
+  /** Path inside the archive */
+  name: string;
+  content: string;
+}
+
+/**
+ * FailedResponseStore - 失敗したLLM応答の保存
+ *
+ * Malformed responses and responses that failed verification are kept under
+ * .vibeflow/failed-responses/run-<id>/ so `vf bugreport` can attach them.
+ */
+export class FailedResponseStore {
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  /**
+   * @returns the written file
+   */
+  save(record: Omit<FailedResponse, 'recorded_at'>): string {
+    const entry: FailedResponse = {
+      ...record,
+      error: redactText(record.error),
+      response: redactText(record.response),
+      recorded_at: new Date().toISOString(),
+    };
+    const dir = this.runDir(record.run_id);
+    fs.mkdirSync(dir, { recursive: true });
+    const name = `${entry.recorded_at.replace(/[:.]/g, '-')}-${path.basename(record.file).replace(/[^\w.-]/g, '_')}.json`;
+    const target = path.join(dir, name);
+    fs.writeFileSync(target, JSON.stringify(entry, null, 2));
+    return target;
+  }
+
+  list(runId: number): FailedResponse[] {
+    const dir = this.runDir(runId);
+    if (!fs.existsSync(dir)) return [];
+    return fs.readdirSync(dir)
+      .filter(name => name.endsWith('.json'))
+      .sort()
+      .flatMap(name => {
+        try {
+          return [JSON.parse(fs.readFileSync(path.join(dir, name), 'utf8')) as FailedResponse];
+        } catch {
+          return [];
+        }
+      });
+  }
+
+  /**
+   * Drop the responses of runs no longer kept by the metrics store
+   */
+  prune(retainedRunIds: number[]): number {
+    const root = this.paths.failedResponsesDir;
+    if (!fs.existsSync(root)) return 0;
+
+    const retained = new Set(retainedRunIds.map(id => `run-${id}`));
+    const removed = fs.readdirSync(root).filter(name => /^run-\d+$/.test(name) && !retained.has(name));
+    removed.forEach(name => fs.rmSync(path.join(root, name), { recursive: true, force: true }));
+    return removed.length;
+  }
+
+  private runDir(runId?: number): string {
+    return path.join(this.paths.failedResponsesDir, runId === undefined ? 'no-run' : `run-${runId}`);
+  }
+}
+
+/**
+ * Everything needed to reproduce a run, with credentials redacted: the environment
+ * snapshot, the run record with its file and metric entries, log entries written
+ * while it ran, its failed responses and the configuration
+ */
+export function collectBugReport(projectRoot: string, runId: number): BugReportEntry[] {
+  const paths = new VibeFlowPaths(projectRoot);
+  const store = new PerformanceStore(projectRoot, { readOnly: true });
+  const run = store.getRun(runId);
+  if (!run) throw new Error(`Run ${runId} not found in ${PerformanceStore.storePath(projectRoot)}`);
+
+  const { environment, ...record } = run;
+  const failed = new FailedResponseStore(projectRoot).list(runId);
+  const entries: BugReportEntry[] = [
+    { name: 'README.txt', content: readme(run, failed.length) },
+    { name: 'environment.json', content: JSON.stringify(redactSecrets(environment ?? null), null, 2) },
+    {
+      name: 'run.json',
+      content: JSON.stringify(redactSecrets({
+        run: record,
+        file_processing: store.getFileProcessing(runId),
+        metrics: store.getMetrics(runId),
+        llm_calls: store.getLlmCalls(runId),
+      }), null, 2),
+    },
+    { name: 'log.jsonl', content: runLogEntries(paths.logPath, run).map(redactText).join('\n') },
+    ...failed.map((response, index) => ({
+      name: `failed-responses/${String(index + 1).padStart(3, '0')}-${path.basename(response.file).replace(/[^\w.-]/g, '_')}.json`,
+      content: JSON.stringify(redactSecrets(response), null, 2),
+    })),
+  ];
+
+  for (const name of ['vibeflow.config.yaml', 'boundary.yaml']) {
+    const file = path.join(projectRoot, name);
+    if (fs.existsSync(file)) entries.push({ name: `config/${name}`, content: redactText(fs.readFileSync(file, 'utf8')) });
+  }
+  return entries;
+}
+
+/**
+ * Write the bug report of a run as a single zip archive
+ *
+ * @param output - archive path (default: .vibeflow/reports/bugreport-run-<id>.zip)
+ */
+export function writeBugReport(projectRoot: string, runId: number, output?: string): { path: string; entries: BugReportEntry[] } {
+  const entries = collectBugReport(projectRoot, runId);
+  const target = output ?? path.join(new VibeFlowPaths(projectRoot).reportsDir, `bugreport-run-${runId}.zip`);
+  fs.mkdirSync(path.dirname(target), { recursive: true });
+  fs.writeFileSync(target, createZip(entries.map(entry => ({ name: `bugreport-run-${runId}/${entry.name}`, data: Buffer.from(entry.content, 'utf8') }))));
+  return { path: target, entries };
+}
+
+/**
+ * vibeflow.log lines written between the start and the end of the run
+ */
+function runLogEntries(logPath: string, run: RunRecord): string[] {
+  if (!fs.existsSync(logPath)) return [];
+  const start = Date.parse(run.started_at);
+  const end = run.finished_at ? Date.parse(run.finished_at) : Date.now();
+
+  return fs.readFileSync(logPath, 'utf8').split('\n').filter(line => {
+    if (!line.trim()) return false;
+    try {
+      const entry = JSON.parse(line);
+      if (entry.run_id !== undefined) return entry.run_id === run.run_id;
+      const at = Date.parse(entry.timestamp);
+      return at >= start && at <= end;
+    } catch {
+      return false;
+    }
+  });
+}
+
+function readme(run: RunRecord, failedResponses: number): string {
+  return [
+    `VibeFlow bug report for run ${run.run_id} (${run.command}, ${run.status})`,
+    `Started: ${run.started_at}${run.finished_at ? `, finished: ${run.finished_at}` : ''}`,
+    ...(run.error ? [`Error: ${redactText(run.error)}`] : []),
+    '',
+    ...(run.environment ? formatRunEnvironment(redactSecrets(run.environment)) : ['No environment snapshot was recorded for this run']),
+    '',
+    `Failed responses: ${failedResponses}`,
+    'Credentials are redacted; review the files before attaching the archive to an issue.',
+    '',
+  ].join('\n');
+}
@@ -0,0 +1,343 @@
+import type { ArchitecturalPlan, ModuleDesign } from '../agents/architect-agent.js';
+import { MODULE_LAYOUTS } from './architecture-style.js';
+
+export type C4Format = 'plantuml' | 'structurizr';
+
+export const C4_FORMATS: C4Format[] = ['plantuml', 'structurizr'];
+
+export const C4_EXTENSIONS: Record<C4Format, string> = {
+  plantuml: 'puml',
+  structurizr: 'dsl',
+};
+
+export interface C4Element {
+  id: string;
+  name: string;
+  kind: 'container' | 'database' | 'queue' | 'component';
+  technology: string;
+  description: string;
+  /** Container of a component */
+  parent?: string;
+  /** Established module: kept as it is, drawn as external */
+  established?: boolean;
+}
+
+export interface C4Relation {
+  from: string;
+  to: string;
+  label: string;
+  technology?: string;
+}
+
+/**
+ * Target state of a plan in C4 terms: the monolith, every module deployed as a
+ * service, their databases and message brokers as containers; the modules of
+ * the monolith and the layers of each service as components
+ */
+export interface C4Model {
+  system: string;
+  containers: C4Element[];
+  components: C4Element[];
+  /** Between the innermost elements; container diagrams lift them to the containers */
+  relations: C4Relation[];
+}
+
+export interface C4Diagram {
+  /** File name without extension, e.g. containers or components-monolith */
+  name: string;
+  content: string;
+}
+
+const MONOLITH = 'monolith';
+
+/**
+ * C4 model of a plan's target state; `system` names the software system
+ * (the project directory)
+ */
+export function buildC4Model(plan: ArchitecturalPlan, system: string): C4Model {
+  const layout = MODULE_LAYOUTS[plan.architecture_style ?? 'clean'];
+  const modules = [...plan.modules].sort((a, b) => compare(a.name, b.name));
+  const isService = (module: ModuleDesign) => module.deployment === 'service';
+  const monolith = modules.filter(module => !isService(module));
+  const services = modules.filter(isService);
+  const byName = new Map(modules.map(module => [module.name, module]));
+
+  const containers: C4Element[] = [];
+  const components: C4Element[] = [];
+  const relations = new Map<string, C4Relation>();
+  const relate = (from: string, to: string, label: string, technology?: string) => {
+    if (from === to) return;
+    const key = `${from}\n${to}\n${label}`;
+    if (!relations.has(key)) relations.set(key, { from, to, label, ...(technology ? { technology } : {}) });
+  };
+
+  // Where a module is entered, calls out from and keeps its data
+  const element = (module: ModuleDesign, role: 'inbound' | 'outbound' | 'data') => {
+    if (!isService(module)) return id('mod', module.name);
+    const layer = role === 'inbound' ? 'handler' : role === 'outbound' ? 'service' : 'persistence';
+    return id(id('svc', module.name), layer);
+  };
+
+  if (monolith.length > 0) {
+    containers.push({
+      id: MONOLITH,
+      name: system,
+      kind: 'container',
+      technology: 'Go, modular monolith',
+      description: `Modules: ${monolith.map(module => module.name).join(', ')}`,
+    });
+    monolith.forEach(module => components.push({
+      id: id('mod', module.name),
+      name: module.name,
+      kind: 'component',
+      technology: 'Go module',
+      description: module.description,
+      parent: MONOLITH,
+      ...(module.status === 'established' ? { established: true } : {}),
+    }));
+  }
+
+  const layers: { key: 'handler' | 'service' | 'persistence'; description: string }[] = layout.style === 'hexagonal'
+    ? [
+        { key: 'handler', description: 'Driving adapter: gRPC server calling the inbound port' },
+        { key: 'service', description: 'Implements the inbound port over the outbound ports' },
+        { key: 'persistence', description: 'Driven adapter implementing the repository port' },
+      ]
+    : [
+        { key: 'handler', description: 'gRPC server' },
+        { key: 'service', description: 'Use cases' },
+        { key: 'persistence', description: 'Repository implementations' },
+      ];
+  for (const module of services) {
+    const container = id('svc', module.name);
+    containers.push({
+      id: container,
+      name: module.name,
+      kind: 'container',
+      technology: 'Go, gRPC',
+      description: module.description,
+    });
+    layers.forEach(layer => components.push({
+      id: id(container, layer.key),
+      name: layout[layer.key],
+      kind: 'component',
+      technology: `Go package internal/${module.name}/${layout[layer.key]}`,
+      description: layer.description,
+      parent: container,
+    }));
+    relate(id(container, 'handler'), id(container, 'service'), 'Calls', 'in-process');
+    relate(id(container, 'service'), id(container, 'persistence'), 'Uses', `${layout.ports} interfaces`);
+  }
+
+  const monolithTables = monolith.flatMap(module => module.owned_tables ?? []);
+  if (monolithTables.length > 0) {
+    containers.push({
+      id: id(MONOLITH, 'db'),
+      name: 'Monolith database',
+      kind: 'database',
+      technology: 'SQL',
+      description: `Tables: ${[...monolithTables].sort().join(', ')}`,
+    });
+  }
+  for (const module of modules) {
+    const tables = module.owned_tables ?? [];
+    if (tables.length === 0) continue;
+    let database = id(MONOLITH, 'db');
+    if (isService(module)) {
+      database = id(id('svc', module.name), 'db');
+      containers.push({
+        id: database,
+        name: `${module.name} database`,
+        kind: 'database',
+        technology: 'SQL',
+        description: `Tables: ${[...tables].sort().join(', ')}`,
+      });
+    }
+    relate(element(module, 'data'), database, 'Reads from and writes to', 'SQL');
+  }
+
+  for (const module of modules) {
+    for (const dependency of module.dependencies) {
+      const target = byName.get(dependency.module);
+      if (!target || target === module || dependency.type === 'event') continue;
+      const remote = isService(module) || isService(target);
+      relate(
+        element(module, 'outbound'),
+        element(target, 'inbound'),
+        dependency.type === 'shared_data' ? 'Shares data with' : remote ? 'Calls' : 'Uses',
+        remote ? 'gRPC' : 'in-process'
+      );
+    }
+  }
+
+  const edges = (plan.async_edges ?? []).filter(edge => byName.has(edge.from) && byName.has(edge.to));
+  for (const broker of [...new Set(edges.map(edge => edge.broker))].sort()) {
+    const brokerEdges = edges.filter(edge => edge.broker === broker);
+    const queue = id('broker', broker);
+    containers.push({
+      id: queue,
+      name: `Message broker (${broker})`,
+      kind: 'queue',
+      technology: broker,
+      description: `Topics: ${[...new Set(brokerEdges.map(edge => edge.topic))].sort().join(', ')}`,
+    });
+    const topics = (key: (edge: typeof brokerEdges[number]) => string) => {
+      const grouped = new Map<string, Set<string>>();
+      brokerEdges.forEach(edge => grouped.set(key(edge), (grouped.get(key(edge)) ?? new Set()).add(edge.topic)));
+      return [...grouped.entries()].sort(([a], [b]) => compare(a, b));
+    };
+    topics(edge => edge.from).forEach(([from, set]) =>
+      relate(element(byName.get(from)!, 'outbound'), queue, `Publishes ${[...set].sort().join(', ')}`, broker));
+    topics(edge => edge.to).forEach(([to, set]) =>
+      relate(element(byName.get(to)!, 'inbound'), queue, `Subscribes to ${[...set].sort().join(', ')}`, broker));
+  }
+
+  return {
+    system,
+    containers,
+    components,
+    relations: [...relations.values()].sort((a, b) => compare(a.from, b.from) || compare(a.to, b.to) || compare(a.label, b.label)),
+  };
+}
+
+/**
+ * PlantUML: one container diagram and a component diagram per container with
+ * components; Structurizr: one workspace holding all of these views
+ */
+export function renderC4Diagrams(model: C4Model, format: C4Format): C4Diagram[] {
+  switch (format) {
+    case 'plantuml':
+      return renderPlantUml(model);
+    case 'structurizr':
+      return [{ name: 'workspace', content: renderStructurizr(model) }];
+  }
+}
+
+/**
+ * C4-PlantUML from the PlantUML standard library
+ */
+function renderPlantUml(model: C4Model): C4Diagram[] {
+  const text = (value: string) => value.replace(/\s+/g, ' ').replace(/"/g, "'").trim();
+  const macro = (element: C4Element) => {
+    const name = element.kind === 'component' ? 'Component' : element.kind === 'database' ? 'ContainerDb' : element.kind === 'queue' ? 'ContainerQueue' : 'Container';
+    return `${name}${element.established ? '_Ext' : ''}(${element.id}, "${text(element.name)}", "${text(element.technology)}", "${text(element.description)}")`;
+  };
+  const rel = (from: string, to: string, label: string, technology?: string) =>
+    `Rel(${from}, ${to}, "${text(label)}"${technology ? `, "${text(technology)}"` : ''})`;
+  const diagram = (name: string, library: string, title: string, body: string[]) => ({
+    name,
+    content: [`@startuml ${name}`, `!include <C4/${library}>`, '', `title ${title}`, '', ...body, '', 'SHOW_LEGEND()', '@enduml', ''].join('\n'),
+  });
+  const containerOf = liftToContainers(model);
+
+  const containerRelations = new Map<string, string>();
+  for (const relation of model.relations) {
+    const from = containerOf(relation.from);
+    const to = containerOf(relation.to);
+    if (from === to) continue;
+    const key = `${from}\n${to}\n${relation.label}`;
+    if (!containerRelations.has(key)) containerRelations.set(key, rel(from, to, relation.label, relation.technology));
+  }
+  const diagrams = [diagram('containers', 'C4_Container', `Containers: ${text(model.system)} (target state)`, [
+    `System_Boundary(system, "${text(model.system)}") {`,
+    ...model.containers.map(container => `  ${macro(container)}`),
+    '}',
+    '',
+    ...containerRelations.values(),
+  ])];
+
+  for (const container of model.containers) {
+    const components = model.components.filter(component => component.parent === container.id);
+    if (components.length === 0) continue;
+    const inside = new Set(components.map(component => component.id));
+    const relations = model.relations.filter(relation => inside.has(relation.from) || inside.has(relation.to));
+    const outside = new Set<string>();
+    const lines = new Map<string, string>();
+    for (const relation of relations) {
+      const from = inside.has(relation.from) ? relation.from : containerOf(relation.from);
+      const to = inside.has(relation.to) ? relation.to : containerOf(relation.to);
+      [from, to].filter(ref => !inside.has(ref)).forEach(ref => outside.add(ref));
+      const key = `${from}\n${to}\n${relation.label}`;
+      if (!lines.has(key)) lines.set(key, rel(from, to, relation.label, relation.technology));
+    }
+    diagrams.push(diagram(`components-${container.id.replace(/^svc_/, '')}`, 'C4_Component', `Components: ${text(container.name)}`, [
+      `Container_Boundary(${container.id}, "${text(container.name)}") {`,
+      ...components.map(component => `  ${macro(component)}`),
+      '}',
+      ...model.containers.filter(other => outside.has(other.id)).map(macro),
+      '',
+      ...lines.values(),
+    ]));
+  }
+  return diagrams;
+}
+
+/**
+ * Structurizr DSL workspace; component-level relationships imply the container ones
+ */
+function renderStructurizr(model: C4Model): string {
+  const quote = (value: string) => `"${value.replace(/\s+/g, ' ').replace(/\\/g, '\\\\').replace(/"/g, '\\"').trim()}"`;
+  const tags = (element: C4Element) => [
+    ...(element.kind === 'database' ? ['Database'] : element.kind === 'queue' ? ['Queue'] : []),
+    ...(element.established ? ['Established'] : []),
+  ];
+  const declare = (element: C4Element, type: 'container' | 'component') => {
+    const elementTags = tags(element);
+    return `${element.id} = ${type} ${quote(element.name)} ${quote(element.description)} ${quote(element.technology)}${elementTags.length > 0 ? ` ${quote(elementTags.join(','))}` : ''}`;
+  };
+
+  const lines = [`workspace ${quote(model.system)} ${quote('Target state of vf plan')} {`, '    model {', `        system = softwareSystem ${quote(model.system)} {`];
+  for (const container of model.containers) {
+    const components = model.components.filter(component => component.parent === container.id);
+    if (components.length === 0) {
+      lines.push(`            ${declare(container, 'container')}`);
+      continue;
+    }
+    lines.push(`            ${declare(container, 'container')} {`);
+    components.forEach(component => lines.push(`                ${declare(component, 'component')}`));
+    lines.push('            }');
+  }
+  lines.push('        }', '');
+  model.relations.forEach(relation => lines.push(
+    `        ${relation.from} -> ${relation.to} ${quote(relation.label)}${relation.technology ? ` ${quote(relation.technology)}` : ''}`
+  ));
+  lines.push('    }', '', '    views {', '        container system "Containers" {', '            include *', '            autolayout lr', '        }');
+  for (const container of model.containers) {
+    if (!model.components.some(component => component.parent === container.id)) continue;
+    lines.push(
+      `        component ${container.id} ${quote(`Components-${container.id}`)} {`,
+      '            include *',
+      '            autolayout lr',
+      '        }'
+    );
+  }
+  lines.push(
+    '        styles {',
+    '            element "Database" {',
+    '                shape Cylinder',
+    '            }',
+    '            element "Queue" {',
+    '                shape Pipe',
+    '            }',
+    '            element "Established" {',
+    '                border dashed',
+    '            }',
+    '        }',
+    '    }',
+    '}'
+  );
+  return lines.join('\n') + '\n';
+}
+
+function liftToContainers(model: C4Model): (ref: string) => string {
+  const parents = new Map(model.components.map(component => [component.id, component.parent!]));
+  return ref => parents.get(ref) ?? ref;
+}
+
+function id(prefix: string, name: string): string {
+  return `${prefix}_${name.replace(/\W/g, '_')}`;
+}
+
+function compare(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
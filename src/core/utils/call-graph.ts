@@ -0,0 +1,249 @@
+import { execFileSync } from 'child_process';
+import * as path from 'path';
+import { CallGraphConfig } from '../types/config.js';
+import { GoFunction } from './ast-analyzer.js';
+import { detectGoProject } from './go-project-utils.js';
+import { loadGoWorkspace } from './go-workspace.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** golang.org/x/tools/cmd/callgraph, looked up on PATH when not configured */
+const DEFAULT_CALLGRAPH_COMMAND = 'callgraph';
+
+// One edge per line: caller file, caller, callee file, callee (posn gives where a function is declared)
+const EDGE_FORMAT = '{{(posn .Caller).Filename}}\t{{.Caller}}\t{{(posn .Callee).Filename}}\t{{.Callee}}';
+
+export interface CallEdge {
+  caller_file: string;
+  /** Function or method name without package and receiver; closures count as their enclosing function */
+  caller: string;
+  /** Receiver type name of a method caller */
+  caller_type?: string;
+  callee_file: string;
+  callee: string;
+  callee_type?: string;
+  /** Call sites */
+  count: number;
+}
+
+export interface CallGraph {
+  /** syntactic: calls matched by name from the parsed sources (callgraph unavailable or sampling) */
+  algorithm: 'cha' | 'rta' | 'syntactic';
+  edges: CallEdge[];
+}
+
+export interface CallCoupling {
+  /** Calls between files of the boundary */
+  internal: number;
+  /** Calls from or into files of other boundaries */
+  external: number;
+  /** internal / (internal + external) */
+  cohesion: number;
+  /** external / (internal + external) */
+  coupling: number;
+}
+
+/**
+ * Build the call graph of the project's own packages with the callgraph tool (cha or rta).
+ * null when the tool or the Go toolchain is not available or the packages do not load.
+ */
+export function buildCallGraph(projectRoot: string, config: CallGraphConfig = {}): CallGraph | null {
+  const goProject = detectGoProject(projectRoot);
+  if (!goProject.hasGoProject) return null;
+
+  const algorithm = config.algorithm ?? 'cha';
+  // In a Go workspace the packages of every member module, so calls across modules are edges too
+  const workspace = loadGoWorkspace(projectRoot);
+  const patterns = workspace && workspace.modules.length > 0
+    ? workspace.modules.map(m => (m.dir === '.' ? './...' : `./${m.dir}/...`))
+    : ['./...'];
+  let output: string;
+  try {
+    output = execFileSync(config.command ?? DEFAULT_CALLGRAPH_COMMAND, [`-algo=${algorithm}`, `-format=${EDGE_FORMAT}`, ...patterns], {
+      cwd: workspace ? projectRoot : goProject.workingDirectory!,
+      encoding: 'utf8',
+      stdio: ['ignore', 'pipe', 'ignore'],
+      maxBuffer: 256 * 1024 * 1024,
+      timeout: 600000,
+    });
+  } catch {
+    return null;
+  }
+  return { algorithm, edges: parseCallEdges(output, projectRoot) };
+}
+
+/**
+ * Edges of callgraph output in EDGE_FORMAT, limited to functions declared in
+ * non-test files of the project (standard library and dependencies left out)
+ */
+export function parseCallEdges(output: string, projectRoot: string): CallEdge[] {
+  const edges = new Map<string, CallEdge>();
+
+  for (const line of output.split('\n')) {
+    const [callerPath, caller, calleePath, callee] = line.split('\t');
+    if (!callee) continue;
+    const callerFile = projectFile(projectRoot, callerPath);
+    const calleeFile = projectFile(projectRoot, calleePath);
+    if (!callerFile || !calleeFile) continue;
+
+    const from = ssaFunctionName(caller);
+    const to = ssaFunctionName(callee);
+    addEdge(edges, {
+      caller_file: callerFile,
+      caller: from.name,
+      ...(from.type ? { caller_type: from.type } : {}),
+      callee_file: calleeFile,
+      callee: to.name,
+      ...(to.type ? { callee_type: to.type } : {}),
+      count: 1,
+    });
+  }
+
+  return sortEdges(edges);
+}
+
+/**
+ * Call edges from the calls of the parsed functions, matched by name: a call
+ * resolves to a function of the same file, else of the same directory, else to
+ * the only function of that name in the project. Used when callgraph is not available.
+ */
+export function syntacticCallGraph(functions: GoFunction[]): CallGraph {
+  const byName = new Map<string, GoFunction[]>();
+  for (const fn of functions) {
+    if (!byName.has(fn.name)) byName.set(fn.name, []);
+    byName.get(fn.name)!.push(fn);
+  }
+
+  const edges = new Map<string, CallEdge>();
+  for (const fn of functions) {
+    for (const call of fn.calls) {
+      const candidates = byName.get(call.split('.').pop()!) ?? [];
+      const target = candidates.find(c => c.file === fn.file)
+        ?? candidates.find(c => path.dirname(c.file) === path.dirname(fn.file))
+        ?? (candidates.length === 1 ? candidates[0] : undefined);
+      if (!target || target === fn) continue;
+
+      const callerType = receiverType(fn.receiver);
+      const calleeType = receiverType(target.receiver);
+      addEdge(edges, {
+        caller_file: toPosixPath(fn.file),
+        caller: fn.name,
+        ...(callerType ? { caller_type: callerType } : {}),
+        callee_file: toPosixPath(target.file),
+        callee: target.name,
+        ...(calleeType ? { callee_type: calleeType } : {}),
+        count: 1,
+      });
+    }
+  }
+
+  return { algorithm: 'syntactic', edges: sortEdges(edges) };
+}
+
+/**
+ * Call edges between clustering nodes: functions by name, structs by the
+ * methods declared on them
+ */
+export class CallGraphIndex {
+  private connected = new Set<string>();
+
+  constructor(graph: CallGraph) {
+    for (const edge of graph.edges) {
+      for (const from of endpoints(edge.caller_file, edge.caller, edge.caller_type)) {
+        for (const to of endpoints(edge.callee_file, edge.callee, edge.callee_type)) {
+          if (from === to) continue;
+          this.connected.add(`${from}\t${to}`);
+          this.connected.add(`${to}\t${from}`);
+        }
+      }
+    }
+  }
+
+  /** Whether either node calls the other */
+  calls(node1: { file: string; name: string }, node2: { file: string; name: string }): boolean {
+    return this.connected.has(`${toPosixPath(node1.file)}\n${node1.name}\t${toPosixPath(node2.file)}\n${node2.name}`);
+  }
+}
+
+/**
+ * Cohesion and coupling of each boundary from the calls between its files and
+ * the files of other boundaries; boundaries without any call are left out
+ */
+export function callCoupling(graph: CallGraph, boundaries: { name: string; files: string[] }[]): Map<string, CallCoupling> {
+  const owner = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) owner.set(toPosixPath(file), boundary.name);
+  }
+
+  const counts = new Map<string, { internal: number; external: number }>();
+  const count = (name: string) => {
+    if (!counts.has(name)) counts.set(name, { internal: 0, external: 0 });
+    return counts.get(name)!;
+  };
+  for (const edge of graph.edges) {
+    const from = owner.get(edge.caller_file);
+    const to = owner.get(edge.callee_file);
+    if (from === undefined || to === undefined) continue;
+    if (from === to) {
+      count(from).internal += edge.count;
+    } else {
+      count(from).external += edge.count;
+      count(to).external += edge.count;
+    }
+  }
+
+  const result = new Map<string, CallCoupling>();
+  for (const [name, { internal, external }] of counts) {
+    const total = internal + external;
+    result.set(name, {
+      internal,
+      external,
+      cohesion: Math.round((internal / total) * 100) / 100,
+      coupling: Math.round((external / total) * 100) / 100,
+    });
+  }
+  return result;
+}
+
+/**
+ * Function and receiver type names of an ssa function:
+ * `(*example.com/shop/order.Repository).Save$1` is Save on Repository
+ */
+export function ssaFunctionName(ssaName: string): { name: string; type?: string } {
+  const method = ssaName.match(/^\(\*?([^)]*)\)\.(\w+)/);
+  if (method) {
+    return { name: method[2], type: method[1].split('.').pop()!.replace(/\[.*$/, '') };
+  }
+  const name = ssaName.replace(/\$.*$/, '').replace(/\[.*$/, '').split('.').pop()!;
+  return { name };
+}
+
+function projectFile(projectRoot: string, file: string | undefined): string | null {
+  if (!file || !file.endsWith('.go') || file.endsWith('_test.go')) return null;
+  const relative = toPosixPath(path.relative(projectRoot, path.resolve(projectRoot, file)));
+  if (relative.startsWith('../') || path.isAbsolute(relative) || relative.split('/').includes('vendor')) return null;
+  return relative;
+}
+
+/**
+ * Type name of a method receiver: `s *Repository[T]` is Repository
+ */
+export function receiverType(receiver: string | undefined): string | undefined {
+  return receiver?.split(/\s+/).pop()?.replace(/^\*/, '').replace(/\[.*$/, '');
+}
+
+function endpoints(file: string, name: string, type?: string): string[] {
+  return type ? [`${file}\n${name}`, `${file}\n${type}`] : [`${file}\n${name}`];
+}
+
+function addEdge(edges: Map<string, CallEdge>, edge: CallEdge): void {
+  const key = [edge.caller_file, edge.caller_type ?? '', edge.caller, edge.callee_file, edge.callee_type ?? '', edge.callee].join('\n');
+  const existing = edges.get(key);
+  if (existing) existing.count += edge.count;
+  else edges.set(key, edge);
+}
+
+function sortEdges(edges: Map<string, CallEdge>): CallEdge[] {
+  return [...edges.values()].sort((a, b) =>
+    a.caller_file.localeCompare(b.caller_file) || a.caller.localeCompare(b.caller) ||
+    a.callee_file.localeCompare(b.callee_file) || a.callee.localeCompare(b.callee));
+}
@@ -0,0 +1,723 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { VibeFlowPaths } from './file-paths.js';
+import { FileSafetyManager } from './file-safety.js';
+import { MethodNameStore } from './method-naming.js';
+import { ModuleManifestStore } from './module-manifest.js';
+import { parseGoDeclarations } from './context-selector.js';
+import { compileCheck, maskLiterals } from './api-surface.js';
+import { GoProjectInfo, detectGoProject, goImportAlias, goPackageImportPath, impliedPackageName } from './go-project-utils.js';
+import { goPackageName } from './go-load-check.js';
+import { goImportSpec } from './go-packages.js';
+import { toPosixPath } from './workspace-paths.js';
+import { escapeRegExp, splitTopLevel } from './text-utils.js';
+
+/**
+ * deterministic: the symbol mapping fully determines the rewrite.
+ * ambiguous: the usecase method takes different arguments; left with a TODO comment.
+ */
+export type CallSiteKind = 'deterministic' | 'ambiguous';
+
+export interface CallSite {
+  /** File relative to the project root */
+  file: string;
+  line: number;
+  column: number;
+  legacy: string;
+  method: string;
+  kind: CallSiteKind;
+  /** Call expression as written, e.g. `legacy.PlaceOrder(userID, items)` */
+  original: string;
+  /** Usecase call replacing it (deterministic sites only) */
+  replacement?: string;
+  /** The caller has no context to pass; `context.TODO()` is used */
+  context_todo?: boolean;
+  /** Why the site needs a human (ambiguous sites only) */
+  reason?: string;
+}
+
+/**
+ * How the consumer package receives the usecase from the composition root
+ */
+export interface CallerWiring {
+  /** Variable the rewritten calls go through, e.g. orderUseCase */
+  variable: string;
+  /** Setter generated in the consumer package, e.g. SetOrderUseCase */
+  setter: string;
+  /** Generated file declaring the variable and setter */
+  file: string;
+  /** Composition root (package main) the setter call goes into */
+  composition_root?: string;
+  /** Statement injecting the usecase, e.g. `legacyapi.SetOrderUseCase(orderService)` */
+  statement: string;
+  /** existing: already wired; inserted: placed after the usecase is constructed; pending: left as a TODO */
+  status: 'existing' | 'inserted' | 'pending';
+}
+
+export interface CallerMigrationResult {
+  applied: CallSite[];
+  todos: CallSite[];
+  files: string[];
+  wiring?: CallerWiring;
+  /** Compiler output when the rewrite was rolled back */
+  error?: string;
+}
+
+export interface ConsumerAdoption {
+  package: string;
+  /** Calls already going through the usecase */
+  migrated: number;
+  /** Legacy calls left (ambiguous ones included) */
+  remaining: number;
+  /** Remaining calls that need a human because the signature changed */
+  ambiguous: number;
+}
+
+export interface CallerAdoptionSummary {
+  updated_at: string;
+  modules: Record<string, {
+    usecase: string;
+    packages: ConsumerAdoption[];
+  }>;
+}
+
+interface GoFile {
+  file: string;
+  dir: string;
+  content: string;
+  /** Code with string literals and comments blanked out (same length) */
+  code: string;
+  package: string | null;
+}
+
+interface LegacyFunction {
+  name: string;
+  method: string;
+  importPath: string;
+  /** Declared package name, which callers use unless they alias the import */
+  packageName: string;
+  params: string[];
+}
+
+interface UsecaseTarget {
+  module: string;
+  /** Package directory of the interface */
+  package: string;
+  importPath: string;
+  packageName: string;
+  interface: string;
+  /** Method → parameter list of the interface */
+  methods: Map<string, string[]>;
+  functions: LegacyFunction[];
+  /** Package directories of the module */
+  moduleDirs: Set<string>;
+  /** Import path → package name and constructors returning the usecase (New...Service, New...UseCase) */
+  constructors: Map<string, { packageName: string; names: string[] }>;
+}
+
+const TODO_MARKER = 'TODO(vibeflow):';
+
+/**
+ * CallerMigrator - 未移行コードの呼び出し箇所をユースケースへ移行
+ *
+ * Rewrites calls from a consumer package into the legacy functions of an
+ * extracted module so they go through the module's usecase interface.
+ * The symbol table recorded by the refactor (method-names.json) drives the
+ * rewrites: when the usecase method takes the same arguments (plus a leading
+ * ctx) the call is rewritten mechanically; changed signatures are marked with
+ * a TODO comment for a human. The usecase is injected into the consumer
+ * package through a setter called from the composition root.
+ * Only package-level legacy functions are handled: calls to legacy methods
+ * cannot be attributed to their receiver type from source text.
+ */
+export class CallerMigrator {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  get summaryPath(): string {
+    return path.join(this.paths.outputRootPath, 'caller-migration.json');
+  }
+
+  /**
+   * Legacy call sites of the module in one consumer package
+   */
+  findCallSites(moduleName: string, consumerPackage: string): CallSite[] {
+    const target = this.loadTarget(moduleName);
+    const dir = this.consumerDir(target, consumerPackage);
+    return this.callSites(target, this.loadGoFiles().filter(f => f.dir === dir));
+  }
+
+  /**
+   * Composition root change injecting the usecase into the consumer package
+   */
+  planWiring(moduleName: string, consumerPackage: string): CallerWiring {
+    const target = this.loadTarget(moduleName);
+    const dir = this.consumerDir(target, consumerPackage);
+    return this.wiring(target, dir, this.loadGoFiles()).wiring;
+  }
+
+  /**
+   * Rewrite the accepted call sites, mark ambiguous ones with a TODO and wire the
+   * usecase into the consumer package. Everything is rolled back when the result
+   * does not compile.
+   *
+   * @param verify compile check run after the rewrite; returns an error message or null
+   */
+  async apply(
+    moduleName: string,
+    consumerPackage: string,
+    accepted: CallSite[],
+    safetyManager: FileSafetyManager,
+    verify: () => string | null = () => compileCheck(this.projectRoot)
+  ): Promise<CallerMigrationResult> {
+    const target = this.loadTarget(moduleName);
+    const dir = this.consumerDir(target, consumerPackage);
+    const files = this.loadGoFiles();
+    const sites = this.callSites(target, files.filter(f => f.dir === dir));
+    const key = (site: CallSite) => `${site.file}:${site.line}:${site.column}`;
+    const acceptedKeys = new Set(accepted.map(key));
+    const applied = sites.filter(s => s.kind === 'deterministic' && acceptedKeys.has(key(s)));
+    const todos = sites.filter(s => s.kind === 'ambiguous');
+    const variable = wiringNames(target).variable;
+
+    const outputs = new Map<string, string>();
+    for (const file of files.filter(f => f.dir === dir)) {
+      const rewritten = rewriteFile(file, applied.filter(s => s.file === file.file), todos.filter(s => s.file === file.file), target);
+      if (rewritten !== file.content) outputs.set(file.file, rewritten);
+    }
+
+    let wiring: CallerWiring | undefined;
+    if (applied.length > 0 || files.some(f => f.dir === dir && new RegExp(`(?<![\\w.])${variable}\\.`).test(f.code))) {
+      const planned = this.wiring(target, dir, files);
+      wiring = planned.wiring;
+      for (const [file, content] of planned.outputs) outputs.set(file, content);
+    }
+    if (outputs.size === 0) return { applied: [], todos, files: [], wiring };
+
+    const originals = new Map<string, string | null>();
+    for (const [file, content] of outputs) {
+      const fullPath = path.join(this.projectRoot, file);
+      originals.set(file, fs.existsSync(fullPath) ? fs.readFileSync(fullPath, 'utf8') : null);
+      await safetyManager.safeWrite(fullPath, content);
+    }
+
+    const error = verify();
+    if (error) {
+      for (const [file, content] of originals) {
+        const fullPath = path.join(this.projectRoot, file);
+        if (content === null) fs.rmSync(fullPath, { force: true });
+        else fs.writeFileSync(fullPath, content);
+      }
+      return { applied: [], todos, files: [], wiring, error };
+    }
+
+    return { applied, todos, files: [...outputs.keys()].sort(), wiring };
+  }
+
+  /**
+   * Migrated and remaining call sites of the module in every package outside it
+   */
+  adoption(moduleName: string): ConsumerAdoption[] {
+    const target = this.loadTarget(moduleName);
+    const files = this.loadGoFiles().filter(f => !target.moduleDirs.has(f.dir));
+    const { variable } = wiringNames(target);
+    const methods = [...target.methods.keys()].map(escapeRegExp).join('|');
+    const migratedPattern = new RegExp(`(?<![\\w.])${variable}\\.(?:${methods})\\s*\\(`, 'g');
+
+    const packages = new Map<string, ConsumerAdoption>();
+    for (const file of files) {
+      const sites = this.callSites(target, [file]);
+      const migrated = methods ? (file.code.match(migratedPattern) ?? []).length : 0;
+      if (sites.length === 0 && migrated === 0) continue;
+
+      const adoption = packages.get(file.dir) ?? { package: file.dir, migrated: 0, remaining: 0, ambiguous: 0 };
+      adoption.migrated += migrated;
+      adoption.remaining += sites.length;
+      adoption.ambiguous += sites.filter(s => s.kind === 'ambiguous').length;
+      packages.set(file.dir, adoption);
+    }
+    return [...packages.values()].sort((a, b) => a.package.localeCompare(b.package));
+  }
+
+  /**
+   * Recount the adoption of the module and store it in .vibeflow/caller-migration.json
+   */
+  recordAdoption(moduleName: string): ConsumerAdoption[] {
+    const target = this.loadTarget(moduleName);
+    const packages = this.adoption(moduleName);
+    const summary = this.loadSummary();
+    summary.updated_at = new Date().toISOString();
+    summary.modules[moduleName] = { usecase: `${target.package}.${target.interface}`, packages };
+    this.paths.writeArtifact(this.summaryPath, summary);
+    return packages;
+  }
+
+  loadSummary(): CallerAdoptionSummary {
+    if (!fs.existsSync(this.summaryPath)) return { updated_at: '', modules: {} };
+    try {
+      const raw = JSON.parse(fs.readFileSync(this.summaryPath, 'utf8'));
+      return { updated_at: raw.updated_at ?? '', modules: raw.modules ?? {} };
+    } catch {
+      return { updated_at: '', modules: {} };
+    }
+  }
+
+  private callSites(target: UsecaseTarget, files: GoFile[]): CallSite[] {
+    const sites: CallSite[] = [];
+    // External test packages (package foo_test) cannot reach the injected variable
+    for (const file of files.filter(f => !f.package?.endsWith('_test'))) {
+      for (const fn of target.functions) {
+        const alias = goImportAlias(file.content, fn.importPath, fn.packageName);
+        if (!alias || alias === '_') continue;
+
+        const prefix = alias === '.' ? '' : `${escapeRegExp(alias)}\\.`;
+        const pattern = new RegExp(`(?<![\\w.])${prefix}${fn.name}\\s*\\(`, 'g');
+        let match: RegExpExecArray | null;
+        while ((match = pattern.exec(file.code)) !== null) {
+          const open = match.index + match[0].length - 1;
+          const close = closingParen(file.code, open);
+          if (close < 0) continue;
+          sites.push(this.classify(target, fn, file, match.index, open, close));
+        }
+      }
+    }
+    return sites.sort((a, b) => a.file.localeCompare(b.file) || a.line - b.line || a.column - b.column);
+  }
+
+  private classify(target: UsecaseTarget, fn: LegacyFunction, file: GoFile, start: number, open: number, close: number): CallSite {
+    const before = file.content.slice(0, start);
+    const line = before.split('\n').length;
+    const column = start - before.lastIndexOf('\n');
+    const site = {
+      file: file.file,
+      line,
+      column,
+      legacy: fn.name,
+      method: fn.method,
+      original: file.content.slice(start, close + 1),
+    };
+
+    const params = target.methods.get(fn.method)!;
+    const { variable } = wiringNames(target);
+    const legacyCtx = isContextParam(fn.params[0]);
+    const newCtx = isContextParam(params[0]);
+    const legacyArity = fn.params.length - Number(legacyCtx);
+    const newArity = params.length - Number(newCtx);
+    if (legacyArity !== newArity || fn.params.some(isVariadic) !== params.some(isVariadic)) {
+      return {
+        ...site,
+        kind: 'ambiguous',
+        reason: `use ${variable}.${fn.method}(${params.join(', ')}): arguments changed from ${fn.name}(${fn.params.join(', ')})`,
+      };
+    }
+
+    // Arguments are kept as written; only the ctx argument is added or dropped
+    let args = file.content.slice(open + 1, close);
+    let contextTodo = false;
+    if (newCtx && !legacyCtx) {
+      const ctx = callerContext(file.code, start);
+      contextTodo = ctx === 'context.TODO()';
+      args = args.trim() ? `${ctx}, ${args.trimStart()}` : ctx;
+    } else if (!newCtx && legacyCtx) {
+      const comma = topLevelComma(file.code.slice(open + 1, close));
+      args = comma < 0 ? '' : args.slice(comma + 1).trimStart();
+    }
+
+    return {
+      ...site,
+      kind: 'deterministic',
+      replacement: `${variable}.${fn.method}(${args})`,
+      ...(contextTodo ? { context_todo: true } : {}),
+    };
+  }
+
+  /**
+   * Generated dependency file of the consumer package and the composition root change
+   */
+  private wiring(target: UsecaseTarget, dir: string, files: GoFile[]): { wiring: CallerWiring; outputs: Map<string, string> } {
+    const { variable, setter } = wiringNames(target);
+    const consumerFiles = files.filter(f => f.dir === dir && !f.file.endsWith('_test.go'));
+    const consumerName = consumerFiles.find(f => f.package)?.package ?? path.posix.basename(dir);
+    const declared = consumerFiles.find(f => new RegExp(`^func ${setter}\\(`, 'm').test(f.code));
+    const dependencyFile = declared?.file ?? path.posix.join(dir, `${target.module.toLowerCase()}_usecase.go`);
+    const outputs = new Map<string, string>();
+    if (!declared) {
+      outputs.set(dependencyFile, renderDependencyFile(target, consumerName, variable, setter));
+    }
+
+    const roots = files.filter(f => f.package === 'main' && /^func main\(\)/m.test(f.code));
+    const root = roots.find(f => constructedUsecase(f, target)) ?? roots[0];
+    const consumerImport = goPackageImportPath(this.projectRoot, dir);
+    const consumerSpec = root && consumerImport && root.dir !== dir
+      ? goImportSpec(root.content, consumerImport, consumerName)
+      : null;
+    const consumerAlias = consumerSpec?.name ?? null;
+    const call = (argument: string) => `${consumerAlias ? `${consumerAlias}.` : ''}${setter}(${argument})`;
+
+    if (!root) {
+      return { wiring: { variable, setter, file: dependencyFile, statement: call(`<${target.interface}>`), status: 'pending' }, outputs };
+    }
+    const existing = root.content.split('\n').find(line => new RegExp(`(?<![\\w])${setter}\\(`).test(line));
+    if (existing) {
+      const status = existing.includes(TODO_MARKER) ? 'pending' : 'existing';
+      const statement = existing.replace(/^\s*(?:\/\/\s*)?/, '').replace(TODO_MARKER, '').trim();
+      return { wiring: { variable, setter, file: dependencyFile, composition_root: root.file, statement, status }, outputs };
+    }
+
+    const constructed = constructedUsecase(root, target);
+    let content = root.content;
+    let status: CallerWiring['status'];
+    let statement: string;
+    if (constructed) {
+      statement = call(constructed.variable);
+      content = insertAfterLine(content, constructed.endLine, `${constructed.indent}${statement}`);
+      status = 'inserted';
+    } else {
+      statement = call(`<${target.interface}>`);
+      const mainLine = content.split('\n').findIndex(line => /^func main\(\)/.test(line)) + 1;
+      content = insertAfterLine(content, mainLine, `\t// ${TODO_MARKER} ${statement}`);
+      status = 'pending';
+    }
+    if (consumerSpec && consumerImport && status === 'inserted') {
+      content = ensureImport(content, consumerImport, consumerSpec.alias);
+    }
+    outputs.set(root.file, content);
+
+    return { wiring: { variable, setter, file: dependencyFile, composition_root: root.file, statement, status }, outputs };
+  }
+
+  private consumerDir(target: UsecaseTarget, consumerPackage: string): string {
+    const dir = path.posix.normalize(toPosixPath(path.isAbsolute(consumerPackage)
+      ? path.relative(this.projectRoot, consumerPackage)
+      : consumerPackage)).replace(/\/$/, '');
+    if (target.moduleDirs.has(dir)) {
+      throw new Error(`${dir} belongs to module ${target.module}; choose a package that calls into it`);
+    }
+    if (target.functions.some(fn => fn.importPath === goPackageImportPath(this.projectRoot, dir))) {
+      throw new Error(`${dir} is the legacy package of module ${target.module}`);
+    }
+    if (!fs.existsSync(path.join(this.projectRoot, dir)) || !fs.readdirSync(path.join(this.projectRoot, dir)).some(f => f.endsWith('.go'))) {
+      throw new Error(`No Go package at ${dir}`);
+    }
+    return dir;
+  }
+
+  /**
+   * Legacy functions from the symbol table and the usecase interface declaring their methods
+   */
+  private loadTarget(moduleName: string): UsecaseTarget {
+    const mappings = new MethodNameStore(this.projectRoot).load(moduleName);
+    if (mappings.length === 0) {
+      throw new Error(`No symbol mapping recorded for module "${moduleName}". Run 'vf refactor --module ${moduleName} --apply' first`);
+    }
+
+    const manifest = new ModuleManifestStore(this.projectRoot).load(moduleName);
+    const moduleFiles = (manifest?.files ?? [])
+      .map(entry => toPosixPath(path.isAbsolute(entry.path) ? path.relative(this.projectRoot, entry.path) : entry.path))
+      .filter(file => file.endsWith('.go') && !file.endsWith('_test.go') && fs.existsSync(path.join(this.projectRoot, file)));
+    const moduleDirs = new Set(moduleFiles.map(file => path.posix.dirname(file)));
+    const mapped = new Set(mappings.map(m => m.method));
+
+    let best: { file: string; name: string; methods: Map<string, string[]> } | null = null;
+    const contents = new Map(moduleFiles.map(file => [file, fs.readFileSync(path.join(this.projectRoot, file), 'utf8')]));
+    for (const [file, content] of contents) {
+      for (const iface of interfaceDeclarations(content)) {
+        const score = [...iface.methods.keys()].filter(m => mapped.has(m)).length;
+        const bestScore = best ? [...best.methods.keys()].filter(m => mapped.has(m)).length : 0;
+        if (score > bestScore) best = { file, ...iface };
+      }
+    }
+    if (!best) {
+      throw new Error(`No usecase interface declaring the mapped methods of module "${moduleName}" (${[...mapped].join(', ')})`);
+    }
+
+    const goProject = detectGoProject(this.projectRoot);
+    const packageDir = path.posix.dirname(best.file);
+    const importPath = goPackageImportPath(this.projectRoot, packageDir, goProject);
+    if (!importPath) throw new Error(`${packageDir} is outside the Go module`);
+
+    const functions: LegacyFunction[] = [];
+    for (const mapping of mappings.filter(m => best!.methods.has(m.method))) {
+      const legacyPath = this.paths.resolvePortablePath(mapping.file);
+      if (!fs.existsSync(legacyPath)) continue;
+      const legacyDir = toPosixPath(path.relative(this.projectRoot, path.dirname(legacyPath)));
+      const legacyImport = goPackageImportPath(this.projectRoot, legacyDir, goProject);
+      const legacyContent = fs.readFileSync(legacyPath, 'utf8');
+      const decl = parseGoDeclarations(legacyContent, mapping.file)
+        .find(d => d.kind === 'func' && d.name === mapping.legacy);
+      if (!legacyImport || !decl) continue;
+
+      const masked = maskLiterals(decl.body);
+      const open = masked.indexOf('(');
+      const close = closingParen(masked, open);
+      if (close < 0) continue;
+      functions.push({
+        name: decl.name,
+        method: mapping.method,
+        importPath: legacyImport,
+        packageName: goPackageName(legacyContent) ?? impliedPackageName(legacyImport),
+        params: splitTopLevel(decl.body.slice(open + 1, close), masked.slice(open + 1, close)).map(param => param.trim()).filter(Boolean),
+      });
+    }
+
+    return {
+      module: moduleName,
+      package: packageDir,
+      importPath,
+      packageName: goPackageName(contents.get(best.file)!) ?? impliedPackageName(importPath),
+      interface: best.name,
+      methods: best.methods,
+      functions,
+      moduleDirs,
+      constructors: usecaseConstructors(this.projectRoot, contents, best.name, goProject),
+    };
+  }
+
+  private loadGoFiles(): GoFile[] {
+    return fastGlob.sync('**/*.go', {
+      cwd: this.projectRoot,
+      ignore: ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**'],
+    }).sort().map(file => {
+      const content = fs.readFileSync(path.join(this.projectRoot, file), 'utf8');
+      return { file, dir: path.posix.dirname(file), content, code: maskLiterals(content), package: goPackageName(content) };
+    });
+  }
+}
+
+/**
+ * order → orderUseCase / SetOrderUseCase
+ */
+function wiringNames(target: UsecaseTarget): { variable: string; setter: string } {
+  const base = target.module.split(/[^A-Za-z0-9]+/).filter(Boolean)
+    .map((part, i) => (i === 0 ? part.charAt(0).toLowerCase() : part.charAt(0).toUpperCase()) + part.slice(1))
+    .join('');
+  return { variable: `${base}UseCase`, setter: `Set${base.charAt(0).toUpperCase()}${base.slice(1)}UseCase` };
+}
+
+function renderDependencyFile(target: UsecaseTarget, packageName: string, variable: string, setter: string): string {
+  const alias = `${target.module.toLowerCase().replace(/[^a-z0-9]/g, '')}${target.packageName}`;
+  return `package ${packageName}
+
+import ${alias} "${target.importPath}"
+
+// ${variable} replaces the legacy ${target.module} functions in this package.
+// The composition root injects it with ${setter}.
+var ${variable} ${alias}.${target.interface}
+
+// ${setter} injects the ${target.module} usecase
+func ${setter}(useCase ${alias}.${target.interface}) {
+\t${variable} = useCase
+}
+`;
+}
+
+/**
+ * Module constructors the composition root may build the usecase with
+ */
+function usecaseConstructors(
+  projectRoot: string,
+  contents: Map<string, string>,
+  iface: string,
+  goProject: GoProjectInfo
+): Map<string, { packageName: string; names: string[] }> {
+  const constructors = new Map<string, { packageName: string; names: string[] }>();
+  for (const [file, content] of contents) {
+    const importPath = goPackageImportPath(projectRoot, path.posix.dirname(file), goProject);
+    if (!importPath) continue;
+    const names = [...maskLiterals(content).matchAll(/^func\s+(New\w*)\s*\([^)]*\)\s*([^{\n]+)\{/gm)]
+      .filter(match => new RegExp(`\\b${iface}\\b|(?:Service|UseCase|Usecase|Interactor)\\b`).test(match[2]))
+      .map(match => match[1]);
+    const existing = constructors.get(importPath);
+    constructors.set(importPath, {
+      packageName: existing?.packageName ?? goPackageName(content) ?? impliedPackageName(importPath),
+      names: [...(existing?.names ?? []), ...names],
+    });
+  }
+  return constructors;
+}
+
+/**
+ * Variable the composition root assigns the module's usecase to: `svc := usecase.NewOrderService(repo)`
+ */
+function constructedUsecase(root: GoFile, target: UsecaseTarget): { variable: string; endLine: number; indent: string } | null {
+  for (const [importPath, { packageName, names: constructors }] of target.constructors) {
+    const alias = goImportAlias(root.content, importPath, packageName);
+    if (!alias || alias === '_' || alias === '.' || constructors.length === 0) continue;
+
+    const pattern = new RegExp(`^([ \\t]*)(\\w+)(?:\\s*,\\s*\\w+)?\\s*:?=\\s*${escapeRegExp(alias)}\\.(?:${constructors.join('|')})\\s*\\(`, 'gm');
+    const match = pattern.exec(root.code);
+    if (!match) continue;
+    const close = closingParen(root.code, match.index + match[0].length - 1);
+    if (close < 0) continue;
+    return { variable: match[2], endLine: root.code.slice(0, close).split('\n').length, indent: match[1] };
+  }
+  return null;
+}
+
+function rewriteFile(file: GoFile, applied: CallSite[], todos: CallSite[], target: UsecaseTarget): string {
+  if (applied.length === 0 && todos.length === 0) return file.content;
+
+  const lines = file.content.split('\n');
+  const lineStarts = lineOffsets(file.content);
+  const edits: { offset: number; length: number; text: string }[] = applied.map(site => ({
+    offset: lineStarts[site.line - 1] + site.column - 1,
+    length: site.original.length,
+    text: site.replacement!,
+  }));
+  for (const line of new Set(todos.map(s => s.line))) {
+    const site = todos.find(s => s.line === line)!;
+    const previous = lines[line - 2] ?? '';
+    if (previous.includes(TODO_MARKER) && previous.includes(site.legacy)) continue;
+    edits.push({ offset: lineStarts[line - 1], length: 0, text: `${lines[line - 1].match(/^\s*/)![0]}// ${TODO_MARKER} ${site.reason}\n` });
+  }
+
+  // Bottom-up so earlier offsets stay valid
+  let content = file.content;
+  for (const edit of edits.sort((a, b) => b.offset - a.offset)) {
+    content = content.slice(0, edit.offset) + edit.text + content.slice(edit.offset + edit.length);
+  }
+
+  if (applied.some(s => s.context_todo)) content = ensureImport(content, 'context');
+  const legacyPackages = new Map(applied.map(s => target.functions.find(f => f.name === s.legacy)!).map(f => [f.importPath, f.packageName]));
+  for (const [importPath, packageName] of legacyPackages) {
+    content = dropUnusedImport(content, importPath, packageName);
+  }
+  return content;
+}
+
+/**
+ * Context of the function enclosing a call: its ctx parameter, the request context
+ * of an HTTP handler, or context.TODO() when there is none
+ */
+function callerContext(code: string, offset: number): string {
+  const funcStart = code.lastIndexOf('\nfunc ', offset);
+  if (funcStart < 0) return 'context.TODO()';
+  const signature = code.slice(funcStart + 1, code.indexOf('{', funcStart));
+
+  const ctx = signature.match(/(\w+)\s+context\.Context\b/);
+  if (ctx) return ctx[1];
+  const request = signature.match(/(\w+)\s+\*http\.Request\b/);
+  if (request) return `${request[1]}.Context()`;
+  const gin = signature.match(/(\w+)\s+\*gin\.Context\b/);
+  if (gin) return `${gin[1]}.Request.Context()`;
+  const echo = signature.match(/(\w+)\s+echo\.Context\b/);
+  if (echo) return `${echo[1]}.Request().Context()`;
+  return 'context.TODO()';
+}
+
+/**
+ * `type X interface { ... }` declarations with their method parameter lists
+ */
+function interfaceDeclarations(content: string): { name: string; methods: Map<string, string[]> }[] {
+  const code = maskLiterals(content);
+  const declarations: { name: string; methods: Map<string, string[]> }[] = [];
+  const pattern = /^type\s+(\w+)\s+interface\s*\{/gm;
+  let match: RegExpExecArray | null;
+  while ((match = pattern.exec(code)) !== null) {
+    const open = match.index + match[0].length - 1;
+    const close = closingBrace(code, open);
+    if (close < 0) continue;
+
+    const methods = new Map<string, string[]>();
+    const body = code.slice(open + 1, close);
+    const methodPattern = /^\s*([A-Z]\w*)\s*\(/gm;
+    let method: RegExpExecArray | null;
+    while ((method = methodPattern.exec(body)) !== null) {
+      const paramsOpen = open + 1 + method.index + method[0].length - 1;
+      const paramsClose = closingParen(code, paramsOpen);
+      if (paramsClose < 0) continue;
+      methods.set(method[1], splitTopLevel(content.slice(paramsOpen + 1, paramsClose), code.slice(paramsOpen + 1, paramsClose)).map(param => param.trim()).filter(Boolean));
+    }
+    declarations.push({ name: match[1], methods });
+  }
+  return declarations;
+}
+
+/**
+ * Add an import unless the file already has it
+ */
+export function ensureImport(content: string, importPath: string, alias?: string): string {
+  if (goImportAlias(content, importPath)) return content;
+  const spec = `${alias ? `${alias} ` : ''}"${importPath}"`;
+  if (/^import\s*\(/m.test(content)) {
+    return content.replace(/^import\s*\(\n/m, match => `${match}\t${spec}\n`);
+  }
+  if (/^import\s+(?:[\w.]+\s+)?"/m.test(content)) {
+    return content.replace(/^import\s+((?:[\w.]+\s+)?"[^"]+")[ \t]*$/m, (_, existing: string) => `import (\n\t${existing}\n\t${spec}\n)`);
+  }
+  return content.replace(/^(package\s+\w+[ \t]*\n)/m, `$1\nimport ${spec}\n`);
+}
+
+/**
+ * Remove an import whose package is no longer referenced
+ */
+export function dropUnusedImport(content: string, importPath: string, packageName?: string): string {
+  const alias = goImportAlias(content, importPath, packageName);
+  if (!alias || alias === '_' || alias === '.') return content;
+  const code = maskLiterals(content).replace(/^import\s*\([\s\S]*?^\)|^import\s+[^\n]*/gm, '');
+  if (new RegExp(`(?<![\\w.])${escapeRegExp(alias)}\\.`).test(code)) return content;
+
+  const escaped = escapeRegExp(importPath);
+  return content
+    .replace(new RegExp(`^import\\s+(?:[\\w.]+\\s+)?"${escaped}"[ \\t]*\\n`, 'm'), '')
+    .replace(new RegExp(`^[ \\t]+(?:[\\w.]+\\s+)?"${escaped}"[ \\t]*\\n`, 'm'), '');
+}
+
+function insertAfterLine(content: string, line: number, text: string): string {
+  const lines = content.split('\n');
+  lines.splice(line, 0, text);
+  return lines.join('\n');
+}
+
+function lineOffsets(content: string): number[] {
+  const offsets = [0];
+  for (let i = 0; i < content.length; i++) {
+    if (content[i] === '\n') offsets.push(i + 1);
+  }
+  return offsets;
+}
+
+/**
+ * First top-level comma of a parameter or argument list; `code` has its literals masked
+ */
+function topLevelComma(code: string): number {
+  let depth = 0;
+  for (let i = 0; i < code.length; i++) {
+    const ch = code[i];
+    if (ch === '(' || ch === '[' || ch === '{') depth++;
+    else if (ch === ')' || ch === ']' || ch === '}') depth--;
+    else if (ch === ',' && depth === 0) return i;
+  }
+  return -1;
+}
+
+function closingParen(code: string, open: number): number {
+  return closing(code, open, '(', ')');
+}
+
+function closingBrace(code: string, open: number): number {
+  return closing(code, open, '{', '}');
+}
+
+function closing(code: string, open: number, opener: string, closer: string): number {
+  let depth = 0;
+  for (let i = open; i < code.length; i++) {
+    if (code[i] === opener) depth++;
+    else if (code[i] === closer && --depth === 0) return i;
+  }
+  return -1;
+}
+
+function isContextParam(param: string | undefined): boolean {
+  return !!param && /(?:^|\s)context\.Context$/.test(param);
+}
+
+function isVariadic(param: string): boolean {
+  return /\.\.\./.test(param);
+}
@@ -1,6 +1,7 @@
 import { promises as fs } from 'fs';
 import path from 'path';
 import chalk from 'chalk';
+import { portableArtifact, toPosixPath } from './workspace-paths.js';
 
 export interface CheckpointData {
   version: string;
@@ -47,7 +48,7 @@ export class CheckpointManager {
       await fs.mkdir(vibeflowDir, { recursive: true });
 
       // Save checkpoint data
-      await fs.writeFile(this.checkpointPath, JSON.stringify(data, null, 2));
+      await fs.writeFile(this.checkpointPath, JSON.stringify(portableArtifact(this.projectPath, data), null, 2));
       
       console.log(chalk.gray(`💾 チェックポイント保存: ${data.currentStep} (${data.stepProgress.processedFiles.length}/${data.stepProgress.totalFiles})`));
     } catch (error) {
@@ -137,7 +138,8 @@ export class CheckpointManager {
     return {
       version: '1.0.0',
       timestamp: new Date().toISOString(),
-      projectPath: this.projectPath,
+      // Workspace-relative so the checkpoint can be resumed from another checkout
+      projectPath: '.',
       currentStep,
       stepProgress: {
         totalFiles,
@@ -153,8 +155,9 @@ export class CheckpointManager {
   shouldProcessFile(filePath: string, checkpoint: CheckpointData | null, options: ResumeOptions): boolean {
     if (!checkpoint) return true;
 
-    const isCompleted = checkpoint.stepProgress.processedFiles.includes(filePath);
-    const isFailed = checkpoint.stepProgress.failedFiles.includes(filePath);
+    const portablePath = toPosixPath(filePath);
+    const isCompleted = checkpoint.stepProgress.processedFiles.includes(portablePath);
+    const isFailed = checkpoint.stepProgress.failedFiles.includes(portablePath);
 
     // Skip completed files unless explicitly retrying
     if (isCompleted && !options.retryFailed) {
@@ -196,7 +199,7 @@ export class CheckpointManager {
     
     // Basic info
     output.push(`最終実行: ${chalk.cyan(new Date(checkpoint.timestamp).toLocaleString())}`);
-    output.push(`プロジェクト: ${chalk.gray(checkpoint.projectPath)}`);
+    output.push(`プロジェクト: ${chalk.gray(this.projectPath)}`);
     output.push(`中断ステップ: ${chalk.yellow(checkpoint.currentStep)}\n`);
     
     // Progress info
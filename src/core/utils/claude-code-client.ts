@@ -1,5 +1,19 @@
-import { ClaudeCodeConfig, RefactoredFile } from '../types/refactor.js';
+import { createHash } from 'crypto';
+import * as path from 'path';
+import { ClaudeCodeConfig, GenerationInfo, RefactoredFile } from '../types/refactor.js';
 import { getErrorMessage } from './error-utils.js';
+import { guardLlmCall, LlmCallControl, LlmUnavailableError } from './llm-call-guard.js';
+import { assertOnline } from './offline-guard.js';
+import { parseLlmResponse } from './input-parsers.js';
+import { HttpConventions, handlerDialect, handlerImports, loadHttpConventions } from './http-conventions.js';
+import {
+  MethodKind,
+  methodKind,
+  buildMethodNameMapping,
+  extractLegacyFunctions,
+  parseMethodNamingSection,
+} from './method-naming.js';
+import { ModuleLayout, MODULE_LAYOUTS } from './architecture-style.js';
 
 interface CodeAnalysis {
   lineCount: number;
@@ -10,6 +24,28 @@ interface CodeAnalysis {
   hasHTTP: boolean;
 }
 
+interface UseCaseMethod {
+  name: string;
+  kind: MethodKind;
+}
+
+/**
+ * Methods generated when the legacy file has no functions to name them after
+ */
+const DEFAULT_USECASE_METHODS: UseCaseMethod[] = [
+  { name: 'Create', kind: 'create' },
+  { name: 'GetByID', kind: 'read' },
+  { name: 'Update', kind: 'update' },
+  { name: 'Delete', kind: 'delete' },
+];
+
+/** Name of the built-in clean-architecture templates in file_processing */
+export const BUILTIN_TEMPLATE_NAME = 'builtin/clean-architecture';
+/** Name of the built-in templates with the hexagonal layout (plan.json architecture_style) */
+export const BUILTIN_HEXAGONAL_TEMPLATE_NAME = 'builtin/hexagonal';
+
+let builtinTemplateHash: string | undefined;
+
 /**
  * ClaudeCodeClient - AI Integration Layer
  * 
@@ -26,44 +62,105 @@ interface CodeAnalysis {
  */
 export class ClaudeCodeClient {
   private config: ClaudeCodeConfig;
+  private conventions?: HttpConventions;
   
   constructor(config: ClaudeCodeConfig) {
     this.config = config;
   }
 
+  /**
+   * Packages the templates generate, from the architecture style of the plan
+   */
+  private get layout(): ModuleLayout {
+    return MODULE_LAYOUTS[this.config.architectureStyle ?? 'clean'];
+  }
+
+  /**
+   * Router conventions of the project, detected on first use by the handler template
+   */
+  private get httpConventions(): HttpConventions {
+    if (!this.conventions) this.conventions = loadHttpConventions(this.config.cwd);
+    return this.conventions;
+  }
+
   /**
    * Execute code transformation query
+   *
+   * The call is bounded by requestTimeoutMs / idleTimeoutMs and stops early
+   * when `signal` is aborted (module skipped by the user).
    */
-  async queryForResult(prompt: string): Promise<string> {
+  async queryForResult(prompt: string, options: { signal?: AbortSignal } = {}): Promise<string> {
+    return (await this.queryWithGeneration(prompt, options)).text;
+  }
+
+  /**
+   * Like queryForResult, also telling whether the LLM or a template produced the
+   * result. In auto mode an unavailable LLM is a template-fallback, never silent;
+   * in llm mode it throws LlmUnavailableError instead.
+   */
+  async queryWithGeneration(
+    prompt: string,
+    options: { signal?: AbortSignal; model?: string } = {}
+  ): Promise<{ text: string; generation: GenerationInfo }> {
+    return guardLlmCall(control => this.runQuery(prompt, control, options.model), {
+      requestTimeoutMs: this.config.requestTimeoutMs,
+      idleTimeoutMs: this.config.idleTimeoutMs,
+      signal: options.signal,
+    });
+  }
+
+  private async runQuery(prompt: string, control: LlmCallControl, model?: string): Promise<{ text: string; generation: GenerationInfo }> {
+    const mode = this.config.generationMode ?? 'auto';
+    let generation: GenerationInfo = { method: 'template' };
+
     // Try Claude Code SDK first (uses OAuth login, no API key needed)
-    try {
-      console.log('🤖 AI transformation with Claude Code SDK');
-      
-      const { ClaudeCodeIntegration } = await import('./claude-code-integration.js');
-      const integration = new ClaudeCodeIntegration({
-        projectRoot: this.config.cwd
-      });
-      
-      // Extract file and boundary from prompt
-      const fileMatch = prompt.match(/File: ([^\n]+)/);
-      const boundaryMatch = prompt.match(/Boundary: ([^\n]+)/);
-      
-      if (fileMatch && boundaryMatch) {
-        const result = await integration.transformCode({
-          file: fileMatch[1],
-          boundary: boundaryMatch[1],
-          pattern: 'clean-architecture'
+    if (mode !== 'template') {
+      try {
+        assertOnline('Claude Code SDK');
+        console.log('🤖 AI transformation with Claude Code SDK');
+        
+        const { ClaudeCodeIntegration } = await import('./claude-code-integration.js');
+        const integration = new ClaudeCodeIntegration({
+          projectRoot: this.config.cwd,
+          ...(model ? { model } : {}),
         });
         
-        return JSON.stringify(result, null, 2);
+        // Extract file and boundary from prompt
+        const fileMatch = prompt.match(/File: ([^\n]+)/);
+        // LLM-only mode also accepts the "Target bounded context" line of RefactorAgent prompts
+        const boundaryMatch = prompt.match(/Boundary: ([^\n]+)/)
+          ?? (mode === 'llm' ? prompt.match(/Target bounded context: ([^\n]+)/) : null);
+        // Failure of an earlier model, carried forward when escalating (llm.escalation)
+        const previousAttempt = prompt.match(/## Previous Attempt\n([\s\S]*?)(?:\n## |$)/);
+        
+        if (fileMatch && boundaryMatch) {
+          const result = await integration.transformCode({
+            file: fileMatch[1],
+            boundary: boundaryMatch[1],
+            pattern: 'clean-architecture',
+            ...(previousAttempt ? { previousAttempt: previousAttempt[1].trim() } : {}),
+          }, control);
+          
+          return { text: JSON.stringify(result, null, 2), generation: { method: 'llm' } };
+        }
+        if (mode === 'llm') {
+          throw new Error('Prompt names no File/Boundary for the SDK transformation');
+        }
+      } catch (error) {
+        // Timed out or skipped: do not fall back to templates
+        if (control.abortController.signal.aborted) throw error;
+        // LLM-only mode (--require-llm, method evaluation): a template result would be mislabeled
+        if (mode === 'llm') throw new LlmUnavailableError(getErrorMessage(error));
+        generation = { method: 'template-fallback', fallback_reason: getErrorMessage(error).split('\n')[0] };
+        console.warn(`⚠️  LLM unavailable, falling back to templates (template-fallback): ${generation.fallback_reason}`);
+        console.warn('   Use --require-llm to fail instead, or --offline to choose templates explicitly');
       }
-    } catch (error) {
-      console.warn('⚠️  Claude Code SDK not available, using template mode');
-      console.warn(getErrorMessage(error));
     }
     
     // Template Mode - high-quality template generation
     console.log('📋 Template-based transformation');
+    const templateName = this.layout.style === 'hexagonal' ? BUILTIN_HEXAGONAL_TEMPLATE_NAME : BUILTIN_TEMPLATE_NAME;
+    generation = { ...generation, template: { name: templateName, hash: templateVersion() } };
     
     // Extract code from prompt for basic analysis
     const codeMatch = prompt.match(/```[\w]*\n([\s\S]*?)```/);
@@ -74,45 +171,22 @@ export class ClaudeCodeClient {
     console.log(`   📊 Analyzed: ${analysis.lineCount} lines, ${analysis.imports.length} imports`);
     
     // Generate slightly more realistic mock based on analysis
-    const mockResult = this.generateMockRefactorResult(prompt, analysis);
+    const mockResult = this.generateMockRefactorResult(prompt, analysis, originalCode);
     
     // Realistic delay based on code size
     const delay = Math.min(500 + analysis.lineCount * 10, 3000);
     await new Promise(resolve => setTimeout(resolve, delay));
     
-    return JSON.stringify(mockResult, null, 2);
+    return { text: JSON.stringify(mockResult, null, 2), generation };
   }
 
   /**
    * Extract and validate JSON result from Claude response
+   *
+   * @throws InputParseError for empty, non-JSON or malformed responses
    */
   extractJsonFromResult(result: string): RefactoredFile {
-    try {
-      // Clean up the result to extract JSON
-      const jsonMatch = result.match(/\{[\s\S]*\}/);
-      if (!jsonMatch) {
-        throw new Error('No JSON found in Claude response');
-      }
-      
-      const parsed = JSON.parse(jsonMatch[0]);
-      
-      // Validate structure
-      if (!parsed.refactored_files || !Array.isArray(parsed.refactored_files)) {
-        throw new Error('Invalid refactored_files structure');
-      }
-      
-      if (!parsed.interfaces || !Array.isArray(parsed.interfaces)) {
-        parsed.interfaces = [];
-      }
-      
-      if (!parsed.tests || !Array.isArray(parsed.tests)) {
-        parsed.tests = [];
-      }
-      
-      return parsed as RefactoredFile;
-    } catch (error) {
-      throw new Error(`Failed to parse Claude response: ${(error as any).message}`);
-    }
+    return parseLlmResponse(result);
   }
 
   /**
@@ -151,7 +225,7 @@ export class ClaudeCodeClient {
    * Generate mock refactor result for demonstration
    * This will be replaced with actual Claude Code SDK integration
    */
-  private generateMockRefactorResult(prompt: string, analysis: CodeAnalysis): RefactoredFile {
+  private generateMockRefactorResult(prompt: string, analysis: CodeAnalysis, originalCode: string): RefactoredFile {
     // Extract file info from prompt
     const fileMatch = prompt.match(/File: ([^\n]+)/);
     const boundaryMatch = prompt.match(/internal\/([^\/]+)\//);
@@ -162,50 +236,55 @@ export class ClaudeCodeClient {
     
     // Use analysis to generate more realistic content
     console.log(`   🔍 Found ${analysis.structs.length} structs, ${analysis.functions.length} functions`);
+    const methods = this.resolveUseCaseMethods(prompt, originalCode, boundaryName, fileName);
+    
+    const layout = this.layout;
+    const root = `internal/${boundaryName}`;
+    const hexagonal = layout.style === 'hexagonal';
     
     return {
       refactored_files: [
         {
-          path: `internal/${boundaryName}/domain/${baseName}.go`,
+          path: `${root}/${layout.domain}/${baseName}.go`,
           content: this.generateDomainCode(baseName, boundaryName),
           description: `${baseName} domain entity`
         },
         {
-          path: `internal/${boundaryName}/usecase/${baseName}_service.go`,
-          content: this.generateUseCaseCode(baseName, boundaryName),
-          description: `${baseName} service use case`
+          path: `${root}/${layout.service}/${baseName}_service.go`,
+          content: this.generateUseCaseCode(baseName, boundaryName, methods, layout),
+          description: hexagonal ? `${baseName} service implementing the use case port` : `${baseName} service use case`
         },
         {
-          path: `internal/${boundaryName}/infrastructure/${baseName}_repository.go`,
-          content: this.generateRepositoryCode(baseName, boundaryName),
-          description: `${baseName} repository implementation`
+          path: `${root}/${layout.persistence}/${baseName}_repository.go`,
+          content: this.generateRepositoryCode(baseName, boundaryName, layout),
+          description: hexagonal ? `${baseName} persistence adapter` : `${baseName} repository implementation`
         },
         {
-          path: `internal/${boundaryName}/handler/${baseName}_handler.go`,
-          content: this.generateHandlerCode(baseName, boundaryName),
-          description: `${baseName} HTTP handler`
+          path: `${root}/${layout.handler}/${baseName}_handler.go`,
+          content: this.generateHandlerCode(baseName, boundaryName, methods, layout),
+          description: hexagonal ? `${baseName} HTTP adapter` : `${baseName} HTTP handler`
         }
       ],
       interfaces: [
         {
           name: `${baseName}Repository`,
-          path: `internal/${boundaryName}/domain/repository.go`,
-          content: this.generateRepositoryInterface(baseName, boundaryName)
+          path: `${root}/${layout.ports}/repository.go`,
+          content: this.generateRepositoryInterface(baseName, boundaryName, layout)
         },
         {
           name: `${baseName}UseCase`,
-          path: `internal/${boundaryName}/domain/usecase.go`,
-          content: this.generateUseCaseInterface(baseName, boundaryName)
+          path: `${root}/${layout.ports}/usecase.go`,
+          content: this.generateUseCaseInterface(baseName, boundaryName, methods, layout)
         }
       ],
       tests: [
         {
-          path: `internal/${boundaryName}/domain/${baseName}_test.go`,
+          path: `${root}/${layout.domain}/${baseName}_test.go`,
           content: this.generateDomainTest(baseName, boundaryName)
         },
         {
-          path: `internal/${boundaryName}/usecase/${baseName}_service_test.go`,
-          content: this.generateUseCaseTest(baseName, boundaryName)
+          path: `${root}/${layout.service}/${baseName}_service_test.go`,
+          content: this.generateUseCaseTest(baseName, boundaryName, methods, layout)
         }
       ]
     };
@@ -254,62 +333,96 @@ func generateID() string {
 `;
   }
 
-  private generateUseCaseCode(baseName: string, boundaryName: string): string {
+  /**
+   * Usecase method names: the table from the prompt's "Method Naming" section,
+   * else derived from the legacy functions, else plain CRUD
+   */
+  private resolveUseCaseMethods(prompt: string, originalCode: string, boundaryName: string, fileName: string): UseCaseMethod[] {
+    const named = parseMethodNamingSection(prompt);
+    const names = named.length > 0
+      ? named.map(m => m.method)
+      : buildMethodNameMapping(boundaryName, fileName, extractLegacyFunctions(originalCode, fileName)).map(m => m.method);
+
+    if (names.length === 0) return DEFAULT_USECASE_METHODS;
+    return [...new Set(names)].map(name => ({ name, kind: methodKind(name) }));
+  }
+
+  private useCaseSignature(method: UseCaseMethod, entityType: string): string {
+    switch (method.kind) {
+      case 'create':
+        return `${method.name}(ctx context.Context) (*${entityType}, error)`;
+      case 'read':
+        return `${method.name}(ctx context.Context, id string) (*${entityType}, error)`;
+      case 'delete':
+        return `${method.name}(ctx context.Context, id string) error`;
+      default:
+        return `${method.name}(ctx context.Context, entity *${entityType}) (*${entityType}, error)`;
+    }
+  }
+
+  private generateUseCaseCode(baseName: string, boundaryName: string, methods: UseCaseMethod[], layout: ModuleLayout): string {
     const entityName = this.capitalize(baseName);
-    return `package usecase
+    const ports = path.posix.basename(layout.ports);
+    const imports = [...new Set(['context', ...[layout.domain, layout.ports].map(dir => `${boundaryName}/internal/${boundaryName}/${dir}`)])];
+    const bodies: Record<MethodKind, string> = {
+      create: `    entity := domain.New${entityName}()
+    
+    if err := entity.Validate(); err != nil {
+        return nil, err
+    }
+    
+    return s.repo.Save(ctx, entity)`,
+      read: `    return s.repo.GetByID(ctx, id)`,
+      update: `    if err := entity.Validate(); err != nil {
+        return nil, err
+    }
+    
+    return s.repo.Update(ctx, entity)`,
+      delete: `    return s.repo.Delete(ctx, id)`,
+    };
+
+    const implementations = methods.map(method => `// ${method.name} ${this.describeMethod(method, baseName)}
+func (s *${entityName}Service) ${this.useCaseSignature(method, `domain.${entityName}`)} {
+${bodies[method.kind]}
+}`).join('\n\n');
+
+    return `package ${path.posix.basename(layout.service)}
 
 import (
-    "context"
-    "${boundaryName}/internal/${boundaryName}/domain"
+${imports.map(i => `    "${i}"`).join('\n')}
 )
 
 // ${entityName}Service implements ${baseName} business logic
 type ${entityName}Service struct {
-    repo domain.${entityName}Repository
+    repo ${ports}.${entityName}Repository
 }
 
 // New${entityName}Service creates a new ${baseName} service
-func New${entityName}Service(repo domain.${entityName}Repository) *${entityName}Service {
+func New${entityName}Service(repo ${ports}.${entityName}Repository) *${entityName}Service {
     return &${entityName}Service{
         repo: repo,
     }
 }
 
-// Create creates a new ${baseName}
-func (s *${entityName}Service) Create(ctx context.Context) (*domain.${entityName}, error) {
-    entity := domain.New${entityName}()
-    
-    if err := entity.Validate(); err != nil {
-        return nil, err
-    }
-    
-    return s.repo.Save(ctx, entity)
-}
-
-// GetByID retrieves a ${baseName} by ID
-func (s *${entityName}Service) GetByID(ctx context.Context, id string) (*domain.${entityName}, error) {
-    return s.repo.GetByID(ctx, id)
-}
-
-// Update updates a ${baseName}
-func (s *${entityName}Service) Update(ctx context.Context, entity *domain.${entityName}) (*domain.${entityName}, error) {
-    if err := entity.Validate(); err != nil {
-        return nil, err
-    }
-    
-    return s.repo.Update(ctx, entity)
-}
-
-// Delete deletes a ${baseName}
-func (s *${entityName}Service) Delete(ctx context.Context, id string) error {
-    return s.repo.Delete(ctx, id)
-}
+${implementations}
 `;
   }
 
-  private generateRepositoryCode(baseName: string, boundaryName: string): string {
+  private describeMethod(method: UseCaseMethod, baseName: string): string {
+    const defaults: Record<MethodKind, string> = {
+      create: `creates a new ${baseName}`,
+      read: `retrieves a ${baseName} by ID`,
+      update: `updates a ${baseName}`,
+      delete: `deletes a ${baseName}`,
+    };
+    return DEFAULT_USECASE_METHODS.some(m => m.name === method.name)
+      ? defaults[method.kind]
+      : `${defaults[method.kind]} (migrated from the legacy ${method.name})`;
+  }
+
+  private generateRepositoryCode(baseName: string, boundaryName: string, layout: ModuleLayout): string {
     const entityName = this.capitalize(baseName);
-    return `package infrastructure
+    return `package ${path.posix.basename(layout.persistence)}
 
 import (
     "context"
@@ -372,126 +485,158 @@ func (r *${entityName}RepositoryImpl) Delete(ctx context.Context, id string) err
 `;
   }
 
-  private generateHandlerCode(baseName: string, boundaryName: string): string {
+  /**
+   * Handler in the conventions of the project's router (see http-conventions.ts);
+   * the "handles" comments become the module's routes.go
+   */
+  private generateHandlerCode(baseName: string, boundaryName: string, methods: UseCaseMethod[], layout: ModuleLayout): string {
     const entityName = this.capitalize(baseName);
-    return `package handler
-
-import (
-    "encoding/json"
-    "net/http"
-    "${boundaryName}/internal/${boundaryName}/domain"
-)
+    const ports = path.posix.basename(layout.ports);
+    const receiver = `${entityName}Handler`;
+    const conventions = this.httpConventions;
+    const dialect = handlerDialect(conventions);
+    const isDefault = methods === DEFAULT_USECASE_METHODS;
+    const route = (method: UseCaseMethod, withId: boolean) => {
+      const action = isDefault ? '' : `/${method.name.replace(/([a-z0-9])([A-Z])/g, '$1-$2').toLowerCase()}`;
+      return `/${baseName}s${withId ? '/{id}' : ''}${action}`;
+    };
+    const indent = (lines: string[]) => lines.map(line => `\t${line}`);
+    const ctx = dialect.requestContext;
+    const readId = [
+      `id := ${dialect.pathParam('id')}`,
+      'if id == "" {',
+      ...indent(dialect.fail('http.StatusBadRequest', 'errMissingID')),
+      '}',
+    ];
+
+    const handlers = methods.map(method => {
+      let verb: string;
+      let body: string[];
+      switch (method.kind) {
+        case 'create':
+          verb = `POST ${route(method, false)}`;
+          body = [
+            `entity, err := h.useCase.${method.name}(${ctx})`,
+            'if err != nil {',
+            ...indent(dialect.fail('http.StatusBadRequest', 'err')),
+            '}',
+            ...dialect.respond('http.StatusCreated', 'entity'),
+          ];
+          break;
+        case 'read':
+          verb = `GET ${route(method, true)}`;
+          body = [
+            ...readId,
+            `entity, err := h.useCase.${method.name}(${ctx}, id)`,
+            'if err != nil {',
+            ...indent(dialect.fail('http.StatusNotFound', 'err')),
+            '}',
+            ...dialect.respond('http.StatusOK', 'entity'),
+          ];
+          break;
+        case 'delete':
+          verb = `DELETE ${route(method, true)}`;
+          body = [
+            ...readId,
+            `if err := h.useCase.${method.name}(${ctx}, id); err != nil {`,
+            ...indent(dialect.fail('http.StatusInternalServerError', 'err')),
+            '}',
+            ...dialect.noContent(),
+          ];
+          break;
+        default:
+          verb = `PUT ${route(method, true)}`;
+          body = [
+            `var entity domain.${entityName}`,
+            ...dialect.bind('&entity'),
+            `updated, err := h.useCase.${method.name}(${ctx}, &entity)`,
+            'if err != nil {',
+            ...indent(dialect.fail('http.StatusBadRequest', 'err')),
+            '}',
+            ...dialect.respond('http.StatusOK', 'updated'),
+          ];
+      }
+      return `// ${method.name} handles ${verb}
+${dialect.signature(receiver, method.name)} {
+${indent(body).join('\n')}
+}`;
+    }).join('\n\n');
 
-// ${entityName}Handler handles ${baseName} HTTP requests
-type ${entityName}Handler struct {
-    useCase domain.${entityName}UseCase
+    const usesId = methods.some(m => m.kind === 'read' || m.kind === 'delete');
+    const declarations = `${usesId ? 'var errMissingID = errors.New("ID is required")\n\n' : ''}// ${receiver} handles ${baseName} HTTP requests
+type ${receiver} struct {
+\tuseCase ${ports}.${entityName}UseCase
 }
 
-// New${entityName}Handler creates a new ${baseName} handler
-func New${entityName}Handler(useCase domain.${entityName}UseCase) *${entityName}Handler {
-    return &${entityName}Handler{
-        useCase: useCase,
-    }
+// New${receiver} creates a new ${baseName} handler
+func New${receiver}(useCase ${ports}.${entityName}UseCase) *${receiver} {
+\treturn &${receiver}{
+\t\tuseCase: useCase,
+\t}
 }
 
-// Create handles POST /${baseName}s
-func (h *${entityName}Handler) Create(w http.ResponseWriter, r *http.Request) {
-    entity, err := h.useCase.Create(r.Context())
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
-        return
-    }
-    
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(entity)
-}
+${handlers}
+`;
+    // The port package, and the domain when an update handler binds the entity
+    const layerImports = [layout.ports, layout.domain]
+      .filter(dir => new RegExp(`\\b${path.posix.basename(dir)}\\.`).test(declarations))
+      .map(dir => `${conventions.module ?? boundaryName}/internal/${boundaryName}/${dir}`);
+    const imports = handlerImports(conventions, declarations, [...layerImports, ...(usesId ? ['errors'] : [])]);
 
-// GetByID handles GET /${baseName}s/{id}
-func (h *${entityName}Handler) GetByID(w http.ResponseWriter, r *http.Request) {
-    id := r.URL.Query().Get("id")
-    if id == "" {
-        http.Error(w, "ID is required", http.StatusBadRequest)
-        return
-    }
-    
-    entity, err := h.useCase.GetByID(r.Context(), id)
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusNotFound)
-        return
-    }
-    
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(entity)
-}
+    return `package ${path.posix.basename(layout.handler)}
 
-// Update handles PUT /${baseName}s/{id}
-func (h *${entityName}Handler) Update(w http.ResponseWriter, r *http.Request) {
-    var entity domain.${entityName}
-    if err := json.NewDecoder(r.Body).Decode(&entity); err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
-        return
-    }
-    
-    updated, err := h.useCase.Update(r.Context(), &entity)
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusBadRequest)
-        return
-    }
-    
-    w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(updated)
-}
+import (
+${imports.map(i => `\t"${i}"`).join('\n')}
+)
 
-// Delete handles DELETE /${baseName}s/{id}
-func (h *${entityName}Handler) Delete(w http.ResponseWriter, r *http.Request) {
-    id := r.URL.Query().Get("id")
-    if id == "" {
-        http.Error(w, "ID is required", http.StatusBadRequest)
-        return
-    }
-    
-    if err := h.useCase.Delete(r.Context(), id); err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
-    }
-    
-    w.WriteHeader(http.StatusNoContent)
-}
-`;
+${declarations}`;
   }
 
-  private generateRepositoryInterface(baseName: string, boundaryName: string): string {
+  private generateRepositoryInterface(baseName: string, boundaryName: string, layout: ModuleLayout): string {
     const entityName = this.capitalize(baseName);
-    return `package domain
-
-import "context"
+    const entityType = this.portEntityType(entityName, layout);
+    return `${this.portHeader(boundaryName, layout)}
 
 // ${entityName}Repository defines the interface for ${baseName} data access
 type ${entityName}Repository interface {
-    Save(ctx context.Context, entity *${entityName}) (*${entityName}, error)
-    GetByID(ctx context.Context, id string) (*${entityName}, error)
-    Update(ctx context.Context, entity *${entityName}) (*${entityName}, error)
+    Save(ctx context.Context, entity *${entityType}) (*${entityType}, error)
+    GetByID(ctx context.Context, id string) (*${entityType}, error)
+    Update(ctx context.Context, entity *${entityType}) (*${entityType}, error)
     Delete(ctx context.Context, id string) error
 }
 `;
   }
 
-  private generateUseCaseInterface(baseName: string, boundaryName: string): string {
+  private generateUseCaseInterface(baseName: string, boundaryName: string, methods: UseCaseMethod[], layout: ModuleLayout): string {
     const entityName = this.capitalize(baseName);
-    return `package domain
-
-import "context"
+    const entityType = this.portEntityType(entityName, layout);
+    return `${this.portHeader(boundaryName, layout)}
 
 // ${entityName}UseCase defines the interface for ${baseName} business logic
 type ${entityName}UseCase interface {
-    Create(ctx context.Context) (*${entityName}, error)
-    GetByID(ctx context.Context, id string) (*${entityName}, error)
-    Update(ctx context.Context, entity *${entityName}) (*${entityName}, error)
-    Delete(ctx context.Context, id string) error
+${methods.map(method => `    ${this.useCaseSignature(method, entityType)}`).join('\n')}
 }
 `;
   }
 
+  /**
+   * Package clause and imports of an interface file: the domain package, or a
+   * port package importing the domain
+   */
+  private portHeader(boundaryName: string, layout: ModuleLayout): string {
+    if (layout.ports === layout.domain) return `package ${layout.domain}\n\nimport "context"`;
+    return `package ${path.posix.basename(layout.ports)}
+
+import (
+    "context"
+    "${boundaryName}/internal/${boundaryName}/${layout.domain}"
+)`;
+  }
+
+  private portEntityType(entityName: string, layout: ModuleLayout): string {
+    return layout.ports === layout.domain ? entityName : `${path.posix.basename(layout.domain)}.${entityName}`;
+  }
+
   private generateDomainTest(baseName: string, boundaryName: string): string {
     const entityName = this.capitalize(baseName);
     return `package domain
@@ -554,12 +699,70 @@ func Test${entityName}_Validate(t *testing.T) {
 `;
   }
 
-  private generateUseCaseTest(baseName: string, boundaryName: string): string {
+  private generateUseCaseTest(baseName: string, boundaryName: string, methods: UseCaseMethod[], layout: ModuleLayout): string {
     const entityName = this.capitalize(baseName);
-    return `package usecase
+    const seeded = `    repo := NewMock${entityName}Repository()
+    service := New${entityName}Service(repo)
+    ctx := context.Background()
+    
+    existing := domain.New${entityName}()
+    if _, err := repo.Save(ctx, existing); err != nil {
+        t.Fatalf("Save failed: %v", err)
+    }`;
+
+    const tests = methods.map(method => {
+      switch (method.kind) {
+        case 'create':
+          return `func Test${entityName}Service_${method.name}(t *testing.T) {
+    repo := NewMock${entityName}Repository()
+    service := New${entityName}Service(repo)
+    
+    entity, err := service.${method.name}(context.Background())
+    if err != nil {
+        t.Fatalf("${method.name} failed: %v", err)
+    }
+    
+    if entity.ID == "" {
+        t.Error("Created entity should have an ID")
+    }
+}`;
+        case 'read':
+          return `func Test${entityName}Service_${method.name}(t *testing.T) {
+${seeded}
+    
+    retrieved, err := service.${method.name}(ctx, existing.ID)
+    if err != nil {
+        t.Fatalf("${method.name} failed: %v", err)
+    }
+    
+    if retrieved.ID != existing.ID {
+        t.Errorf("Expected ID %s, got %s", existing.ID, retrieved.ID)
+    }
+}`;
+        case 'delete':
+          return `func Test${entityName}Service_${method.name}(t *testing.T) {
+${seeded}
+    
+    if err := service.${method.name}(ctx, existing.ID); err != nil {
+        t.Fatalf("${method.name} failed: %v", err)
+    }
+}`;
+        default:
+          return `func Test${entityName}Service_${method.name}(t *testing.T) {
+${seeded}
+    
+    if _, err := service.${method.name}(ctx, existing); err != nil {
+        t.Fatalf("${method.name} failed: %v", err)
+    }
+}`;
+      }
+    }).join('\n\n');
+
+    return `package ${path.posix.basename(layout.service)}
 
 import (
     "context"
+    "errors"
     "testing"
     "${boundaryName}/internal/${boundaryName}/domain"
 )
@@ -598,44 +801,19 @@ func (m *Mock${entityName}Repository) Delete(ctx context.Context, id string) err
     return nil
 }
 
-func Test${entityName}Service_Create(t *testing.T) {
-    repo := NewMock${entityName}Repository()
-    service := New${entityName}Service(repo)
-    
-    entity, err := service.Create(context.Background())
-    if err != nil {
-        t.Fatalf("Create failed: %v", err)
-    }
-    
-    if entity.ID == "" {
-        t.Error("Created entity should have an ID")
-    }
-}
-
-func Test${entityName}Service_GetByID(t *testing.T) {
-    repo := NewMock${entityName}Repository()
-    service := New${entityName}Service(repo)
-    
-    // Create an entity first
-    created, err := service.Create(context.Background())
-    if err != nil {
-        t.Fatalf("Create failed: %v", err)
-    }
-    
-    // Get the entity by ID
-    retrieved, err := service.GetByID(context.Background(), created.ID)
-    if err != nil {
-        t.Fatalf("GetByID failed: %v", err)
-    }
-    
-    if retrieved.ID != created.ID {
-        t.Errorf("Expected ID %s, got %s", created.ID, retrieved.ID)
-    }
-}
+${tests}
 `;
   }
 
   private capitalize(str: string): string {
     return str.charAt(0).toUpperCase() + str.slice(1);
   }
-}
\ No newline at end of file
+}
+
+/**
+ * Version of the built-in templates: the hash of the code generating them
+ */
+function templateVersion(): string {
+  if (!builtinTemplateHash) builtinTemplateHash = createHash('sha256').update(ClaudeCodeClient.toString()).digest('hex');
+  return builtinTemplateHash;
+}
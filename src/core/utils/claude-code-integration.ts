@@ -1,6 +1,8 @@
 import { query as claudeCodeQuery, type Options } from '@anthropic-ai/claude-code';
 import { RefactoredFile } from '../types/refactor.js';
 import { getErrorMessage } from './error-utils.js';
+import { LlmCallControl } from './llm-call-guard.js';
+import { assertOnline } from './offline-guard.js';
 import * as path from 'path';
 
 export interface ClaudeCodeIntegrationConfig {
@@ -17,6 +19,8 @@ export class ClaudeCodeIntegration {
   private config: ClaudeCodeIntegrationConfig;
 
   constructor(config: ClaudeCodeIntegrationConfig) {
+    // --offline: fail here rather than in the middle of a module
+    assertOnline('Claude Code SDK');
     this.config = {
       maxTurns: 3,
       model: 'claude-3-5-sonnet-20241022',
@@ -32,7 +36,9 @@ export class ClaudeCodeIntegration {
     boundary: string;
     pattern: string;
     instructions?: string;
-  }): Promise<RefactoredFile> {
+    /** Why the previous model's attempt failed (model escalation) */
+    previousAttempt?: string;
+  }, control?: LlmCallControl): Promise<RefactoredFile> {
     const prompt = this.buildTransformationPrompt(params);
 
     try {
@@ -43,13 +49,15 @@ export class ClaudeCodeIntegration {
         options: {
           cwd: this.config.projectRoot,
           maxTurns: this.config.maxTurns!,
-          model: this.config.model
+          model: this.config.model,
+          abortController: control?.abortController,
         }
       });
 
-      // Collect all messages
+      // Collect all messages (each streamed message counts as progress)
       for await (const message of response) {
         messages.push(message);
+        control?.onProgress();
       }
 
       // Parse the result to extract refactored files
@@ -118,6 +126,65 @@ Return only the improved code.
     }
   }
 
+  /**
+   * One-paragraph summary of an applied module for commit messages and the migration log
+   */
+  async summarizeMigration(params: {
+    module: string;
+    facts: string;
+  }): Promise<string | null> {
+    const prompt = `
+Write a one-paragraph summary (at most 4 sentences, plain text, no markdown) of this
+completed migration step for a commit message and a migration changelog.
+
+Module: ${params.module}
+Facts: ${params.facts}
+
+Describe what was extracted and why it matters to reviewers. Do not invent changes beyond the facts.
+`;
+
+    const messages: any[] = [];
+    const response = claudeCodeQuery({
+      prompt,
+      options: {
+        cwd: this.config.projectRoot,
+        maxTurns: 1,
+        model: this.config.model
+      }
+    });
+
+    for await (const message of response) {
+      messages.push(message);
+    }
+
+    const lastMessage = messages[messages.length - 1];
+    const content: string = lastMessage?.result || lastMessage?.content || '';
+    return typeof content === 'string' && content.trim() ? content.trim().replace(/\s*\n\s*/g, ' ') : null;
+  }
+
+  /**
+   * Reply to a boundary naming prompt (see boundary-naming.ts), parsed by the caller
+   */
+  async proposeBoundaryName(prompt: string): Promise<string | null> {
+    const messages: any[] = [];
+    const response = claudeCodeQuery({
+      prompt,
+      options: {
+        cwd: this.config.projectRoot,
+        maxTurns: 1,
+        model: this.config.model
+      }
+    });
+
+    for await (const message of response) {
+      messages.push(message);
+    }
+
+    const lastMessage = messages[messages.length - 1];
+    const content: string = lastMessage?.result || lastMessage?.content || '';
+    return typeof content === 'string' && content.trim() ? content : null;
+  }
+
   /**
    * Analyze code for refactoring opportunities
    */
@@ -187,8 +254,12 @@ Return as JSON with keys: businessLogic, patterns, dependencies, suggestions
     boundary: string;
     pattern: string;
     instructions?: string;
+    previousAttempt?: string;
   }): string {
-    return params.instructions || `
+    const previous = params.previousAttempt
+      ? `\nA previous attempt failed:\n${params.previousAttempt}\n`
+      : '';
+    return (params.instructions || `
 Transform the code in ${params.file} following these requirements:
 
 1. Refactor into clean architecture pattern
@@ -208,7 +279,7 @@ Structure:
 - internal/${params.boundary}/handler/
 
 Return the transformed code as separate files with clear boundaries.
-`;
+`) + previous;
   }
 
   private parseClaudeCodeResult(result: string, params: any): RefactoredFile {
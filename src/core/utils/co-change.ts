@@ -0,0 +1,187 @@
+import { execFileSync } from 'child_process';
+import { CoChangeConfig } from '../types/config.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Commits mined when no limit is configured */
+const DEFAULT_MAX_COMMITS = 2000;
+/** Commits touching more files are bulk changes (formatting, renames, vendoring) and say nothing about coupling */
+const DEFAULT_MAX_FILES_PER_COMMIT = 30;
+/** Pairs changed together fewer times are coincidence */
+const DEFAULT_MIN_CO_CHANGES = 3;
+/** Share of the clustering edge weight a pair that always changes together adds */
+export const DEFAULT_CO_CHANGE_WEIGHT = 0.5;
+
+// git log prints it before the files of every commit (--format=%x1e)
+const RECORD_SEPARATOR = '\x1e';
+
+export interface CoChangePair {
+  files: [string, string];
+  /** Commits that changed both files */
+  count: number;
+  /** count / commits that changed either file (1 = always changed together) */
+  strength: number;
+}
+
+export interface FileChurn {
+  /** Commits counted */
+  commits: number;
+  /** Commits that changed each file, by its path relative to the project root */
+  changes: Record<string, number>;
+}
+
+export interface CoChangeAnalysis {
+  /** Commits counted */
+  commits: number;
+  /** Bulk commits left out (more than maxFilesPerCommit Go files) */
+  skipped_commits: number;
+  pairs: CoChangePair[];
+}
+
+/**
+ * Mine how often Go files changed together in the git history of the project.
+ * null when the project is no git checkout or git is not available.
+ */
+export function mineCoChanges(projectRoot: string, config: CoChangeConfig = {}): CoChangeAnalysis | null {
+  const commits = logChangedFiles(projectRoot, config);
+  return commits ? analyzeCoChanges(commits, config) : null;
+}
+
+/**
+ * How often each non-test Go file changed in the git history of the project,
+ * bulk commits left out as for co-changes. null without git history.
+ */
+export function mineFileChurn(projectRoot: string, config: CoChangeConfig = {}): FileChurn | null {
+  const commits = logChangedFiles(projectRoot, config);
+  return commits ? countFileChanges(commits, config) : null;
+}
+
+/**
+ * Files of each commit in `git log --name-only --format=%x1e` output
+ */
+export function parseChangedFiles(output: string): string[][] {
+  return output
+    .split(RECORD_SEPARATOR)
+    .map(record => record.split('\n').map(line => toPosixPath(line.trim())).filter(line => line.length > 0))
+    .filter(files => files.length > 0);
+}
+
+/**
+ * Count the pairs of non-test Go files changed in the same commits
+ */
+export function analyzeCoChanges(commits: string[][], config: CoChangeConfig = {}): CoChangeAnalysis {
+  const maxFiles = config.maxFilesPerCommit ?? DEFAULT_MAX_FILES_PER_COMMIT;
+  const minCoChanges = config.minCoChanges ?? DEFAULT_MIN_CO_CHANGES;
+  const changes = new Map<string, number>();
+  const together = new Map<string, number>();
+  let counted = 0;
+  let skipped = 0;
+
+  for (const commit of commits) {
+    const files = [...new Set(commit.filter(file => file.endsWith('.go') && !file.endsWith('_test.go')))].sort();
+    if (files.length === 0) continue;
+    if (files.length > maxFiles) {
+      skipped++;
+      continue;
+    }
+    counted++;
+    for (const file of files) changes.set(file, (changes.get(file) ?? 0) + 1);
+    for (let i = 0; i < files.length; i++) {
+      for (let j = i + 1; j < files.length; j++) {
+        const key = pairKey(files[i], files[j]);
+        together.set(key, (together.get(key) ?? 0) + 1);
+      }
+    }
+  }
+
+  const pairs: CoChangePair[] = [];
+  for (const [key, count] of together) {
+    if (count < minCoChanges) continue;
+    const [a, b] = key.split('\n') as [string, string];
+    const strength = count / ((changes.get(a) ?? 0) + (changes.get(b) ?? 0) - count);
+    pairs.push({ files: [a, b], count, strength: Math.round(strength * 100) / 100 });
+  }
+  pairs.sort((x, y) => y.strength - x.strength || y.count - x.count || x.files[0].localeCompare(y.files[0]) || x.files[1].localeCompare(y.files[1]));
+
+  return { commits: counted, skipped_commits: skipped, pairs };
+}
+
+/**
+ * Commits that changed each non-test Go file
+ */
+export function countFileChanges(commits: string[][], config: CoChangeConfig = {}): FileChurn {
+  const maxFiles = config.maxFilesPerCommit ?? DEFAULT_MAX_FILES_PER_COMMIT;
+  const changes: Record<string, number> = {};
+  let counted = 0;
+
+  for (const commit of commits) {
+    const files = [...new Set(commit.filter(file => file.endsWith('.go') && !file.endsWith('_test.go')))];
+    if (files.length === 0 || files.length > maxFiles) continue;
+    counted++;
+    for (const file of files) changes[file] = (changes[file] ?? 0) + 1;
+  }
+  return { commits: counted, changes };
+}
+
+/**
+ * Co-change strength lookup by file pair, in either order
+ */
+export class CoChangeIndex {
+  private strengths = new Map<string, number>();
+
+  constructor(analysis: CoChangeAnalysis) {
+    for (const pair of analysis.pairs) {
+      this.strengths.set(pairKey(...pair.files), pair.strength);
+    }
+  }
+
+  strength(file1: string, file2: string): number {
+    const a = toPosixPath(file1);
+    const b = toPosixPath(file2);
+    if (a === b) return 0;
+    return this.strengths.get(a < b ? pairKey(a, b) : pairKey(b, a)) ?? 0;
+  }
+
+  /** Pairs of the files that changed together often enough to count */
+  pairsWithin(files: string[]): number {
+    const sorted = [...new Set(files.map(toPosixPath))].sort();
+    let count = 0;
+    for (let i = 0; i < sorted.length; i++) {
+      for (let j = i + 1; j < sorted.length; j++) {
+        if (this.strengths.has(pairKey(sorted[i], sorted[j]))) count++;
+      }
+    }
+    return count;
+  }
+}
+
+function pairKey(a: string, b: string): string {
+  return `${a}\n${b}`;
+}
+
+/**
+ * Go files of each commit in the history mined by the config; null without git
+ */
+function logChangedFiles(projectRoot: string, config: CoChangeConfig): string[][] | null {
+  const args = [
+    'log',
+    '--no-merges',
+    '--relative',
+    '--name-only',
+    '--format=%x1e',
+    `--max-count=${config.maxCommits ?? DEFAULT_MAX_COMMITS}`,
+    ...(config.since ? [`--since=${config.since}`] : []),
+    '--',
+    '*.go',
+  ];
+  try {
+    return parseChangedFiles(execFileSync('git', args, {
+      cwd: projectRoot,
+      encoding: 'utf8',
+      stdio: ['ignore', 'pipe', 'ignore'],
+      maxBuffer: 64 * 1024 * 1024,
+      timeout: 60000,
+    }));
+  } catch {
+    return null;
+  }
+}
@@ -1,6 +1,9 @@
 import * as fs from 'fs';
 import * as path from 'path';
 import fastGlob from 'fast-glob';
+import { SourceFile, SourceLocation } from './source-positions.js';
+import { AnalysisCache, FileAnalysis } from './analysis-cache.js';
+import { goImports } from './go-load-check.js';
 
 export interface FileInfo {
   path: string;
@@ -20,7 +23,10 @@ export interface DependencyGraph {
 }
 
 export class CodeAnalyzer {
-  constructor(private rootPath: string) {}
+  /**
+   * @param cache per-file analysis cache; pass null to always re-analyze
+   */
+  constructor(private rootPath: string, private cache: AnalysisCache | null = new AnalysisCache(rootPath)) {}
 
   async analyzeFiles(patterns: string[], excludePatterns: string[] = []): Promise<FileInfo[]> {
     const files = await fastGlob(patterns, {
@@ -28,7 +34,13 @@ export class CodeAnalyzer {
       ignore: excludePatterns,
       absolute: false,
     });
+    return this.analyzePaths(files);
+  }
 
+  /**
+   * Analyze the given files (relative to the root), e.g. the sample selected by `vf discover --sample`
+   */
+  async analyzePaths(files: string[]): Promise<FileInfo[]> {
     const fileInfos: FileInfo[] = [];
     
     for (const file of files) {
@@ -36,13 +48,40 @@ export class CodeAnalyzer {
       if (!fs.existsSync(fullPath)) continue;
       
       const content = fs.readFileSync(fullPath, 'utf8');
-      const info = this.analyzeFile(fullPath, file, content);
+      const info = this.cache
+        ? this.analyzeFileCached(this.cache, fullPath, file, content)
+        : this.analyzeFile(fullPath, file, content);
       fileInfos.push(info);
     }
 
+    this.cache?.flush();
     return fileInfos;
   }
 
+  private analyzeFileCached(cache: AnalysisCache, fullPath: string, relativePath: string, content: string): FileInfo {
+    const analysis = cache.getOrCompute(relativePath, content, () =>
+      this.toFileAnalysis(this.analyzeFile(fullPath, relativePath, content)));
+    const { language: _, ...summary } = analysis.summary;
+    return { path: fullPath, relativePath, content, ...summary };
+  }
+
+  private toFileAnalysis(info: FileInfo): FileAnalysis {
+    const { path: fullPath, relativePath, content: _, ...summary } = info;
+    return {
+      summary: { language: path.extname(fullPath).slice(1), ...summary },
+      symbols: [
+        ...(info.functions ?? []).map(name => ({ name, kind: 'function' as const })),
+        ...(info.structs ?? []).map(name => ({ name, kind: 'struct' as const })),
+        ...(info.interfaces ?? []).map(name => ({ name, kind: 'interface' as const })),
+        ...(info.classes ?? []).map(name => ({ name, kind: 'class' as const })),
+      ],
+      edges: info.imports.flatMap(importPath => {
+        const resolved = this.resolveImportPath(importPath, relativePath);
+        return resolved ? [{ from: relativePath, to: resolved, import: importPath }] : [];
+      }),
+    };
+  }
+
   private analyzeFile(fullPath: string, relativePath: string, content: string): FileInfo {
     const lines = content.split('\n').length;
     const ext = path.extname(fullPath);
@@ -78,22 +117,13 @@ export class CodeAnalyzer {
     info.structs = [];
     info.interfaces = [];
 
+    // Import analysis (single and grouped, aliased or not): edges are import
+    // paths, i.e. package identities, whatever name the package declares
+    info.imports.push(...new Set(goImports(content).map(i => i.path)));
+
     for (const line of lines) {
       const trimmed = line.trim();
       
-      // Import analysis
-      if (trimmed.startsWith('import ')) {
-        const importMatch = trimmed.match(/import\s+"([^"]+)"/);
-        if (importMatch) {
-          info.imports.push(importMatch[1]);
-        }
-      }
-      
-      // Multi-line imports
-      if (trimmed.includes('import (')) {
-        // TODO: Handle multi-line imports
-      }
-      
       // Struct analysis
       const structMatch = trimmed.match(/type\s+(\w+)\s+struct/);
       if (structMatch) {
@@ -192,6 +222,18 @@ export class CodeAnalyzer {
     return graph;
   }
 
+  /**
+   * Position of the import in `file` that resolves to `dependency` (a dependency graph edge)
+   */
+  locateDependency(file: FileInfo, dependency: string): SourceLocation | null {
+    const importPath = file.imports.find(i => this.resolveImportPath(i, file.relativePath) === dependency);
+    if (!importPath) return null;
+
+    const source = new SourceFile(file.relativePath, file.content);
+    if (file.relativePath.endsWith('.go')) return source.findImport(importPath);
+    return source.findSnippet(`'${importPath}'`) ?? source.findSnippet(`"${importPath}"`);
+  }
+
   private resolveImportPath(importPath: string, fromFile: string): string | null {
     // Simplified import resolution - in a real implementation,
     // this would need to handle language-specific module resolution
@@ -0,0 +1,284 @@
+/**
+ * Modularity-based community detection (Louvain, Leiden) on a weighted
+ * undirected graph. Deterministic: nodes are visited in index order and ties
+ * go to the lower community, so the same graph always gives the same modules.
+ */
+
+export type CommunityAlgorithm = 'louvain' | 'leiden';
+
+export interface WeightedEdge {
+  source: number;
+  target: number;
+  weight: number;
+}
+
+export interface CommunityOptions {
+  algorithm: CommunityAlgorithm;
+  /** Higher values favor more, smaller communities (default 1) */
+  resolution?: number;
+  /**
+   * Module each node is seeded to, if any: nodes seeded to the same module
+   * start as one node, nodes seeded to different modules never share a community
+   */
+  seeds?: (string | undefined)[];
+}
+
+/** Aggregation levels at most; each level shrinks the graph, so this only guards degenerate input */
+const MAX_LEVELS = 50;
+
+/** Symmetric adjacency; a self-loop holds the weight of the edges aggregated into the node, counted twice */
+type Graph = Map<number, number>[];
+
+/**
+ * Community of every node, numbered in order of the first node of each community
+ */
+export function detectCommunities(nodeCount: number, edges: WeightedEdge[], options: CommunityOptions): number[] {
+  const resolution = options.resolution ?? 1;
+  // Community of each original node, as a node of the current (aggregated) graph
+  let membership = seedGroups(nodeCount, options.seeds);
+  let labels = relabel(options.seeds ?? [], membership);
+  let graph = buildGraph(nodeCount, edges);
+  if (totalWeight(graph) === 0) return renumber(membership);
+
+  if (labels.length < nodeCount) graph = aggregate(graph, membership, labels.length);
+  let partition = [...Array(graph.length).keys()];
+
+  for (let level = 0; level < MAX_LEVELS; level++) {
+    partition = moveNodes(graph, partition, resolution, labels);
+    const communities = new Set(partition).size;
+    if (communities === graph.length) break;
+
+    // Leiden aggregates the refined partition (well-connected subcommunities) and starts from the unrefined one
+    const aggregateBy = options.algorithm === 'leiden' ? refine(graph, partition, resolution) : partition;
+    const ids = renumber(aggregateBy);
+    const size = new Set(ids).size;
+    if (size === graph.length) break;
+
+    const next: number[] = Array(size);
+    ids.forEach((id, node) => { next[id] = partition[node]; });
+    membership = membership.map(node => ids[node]);
+    labels = relabel(labels, ids);
+    graph = aggregate(graph, ids, size);
+    partition = renumber(next);
+  }
+
+  return renumber(membership.map(node => partition[node]));
+}
+
+/**
+ * Modularity of a partition with the given resolution (Newman-Girvan for 1)
+ */
+export function modularity(nodeCount: number, edges: WeightedEdge[], communities: number[], resolution = 1): number {
+  const graph = buildGraph(nodeCount, edges);
+  const twoM = totalWeight(graph);
+  if (twoM === 0) return 0;
+
+  const internal = new Map<number, number>();
+  const totals = new Map<number, number>();
+  graph.forEach((neighbors, node) => {
+    const community = communities[node];
+    totals.set(community, (totals.get(community) ?? 0) + degree(graph, node));
+    for (const [neighbor, weight] of neighbors) {
+      if (communities[neighbor] === community) internal.set(community, (internal.get(community) ?? 0) + weight);
+    }
+  });
+
+  let q = 0;
+  for (const [community, total] of totals) {
+    q += (internal.get(community) ?? 0) / twoM - resolution * (total / twoM) ** 2;
+  }
+  return q;
+}
+
+function buildGraph(nodeCount: number, edges: WeightedEdge[]): Graph {
+  const graph: Graph = Array.from({ length: nodeCount }, () => new Map());
+  for (const { source, target, weight } of edges) {
+    if (weight <= 0) continue;
+    if (source === target) {
+      graph[source].set(source, (graph[source].get(source) ?? 0) + 2 * weight);
+    } else {
+      graph[source].set(target, (graph[source].get(target) ?? 0) + weight);
+      graph[target].set(source, (graph[target].get(source) ?? 0) + weight);
+    }
+  }
+  return graph;
+}
+
+function degree(graph: Graph, node: number): number {
+  let sum = 0;
+  for (const weight of graph[node].values()) sum += weight;
+  return sum;
+}
+
+function totalWeight(graph: Graph): number {
+  return graph.reduce((sum, _, node) => sum + degree(graph, node), 0);
+}
+
+/**
+ * Local moving: move single nodes to the neighboring community with the
+ * largest modularity gain until no move improves it. A seeded node does not
+ * move into a community holding nodes seeded to another module.
+ */
+function moveNodes(graph: Graph, initial: number[], resolution: number, labels: (string | undefined)[]): number[] {
+  const partition = [...initial];
+  const twoM = totalWeight(graph);
+  const degrees = graph.map((_, node) => degree(graph, node));
+  const totals = new Map<number, number>();
+  partition.forEach((community, node) => totals.set(community, (totals.get(community) ?? 0) + degrees[node]));
+  // Seeded nodes per community; a community holds the seeds of one module at most
+  const seeded = new Map<number, { label: string; count: number }>();
+  const addSeed = (community: number, label: string | undefined, delta: number) => {
+    if (label === undefined) return;
+    const count = (seeded.get(community)?.count ?? 0) + delta;
+    if (count > 0) seeded.set(community, { label, count });
+    else seeded.delete(community);
+  };
+  partition.forEach((community, node) => addSeed(community, labels[node], 1));
+
+  let moved = true;
+  while (moved) {
+    moved = false;
+    for (let node = 0; node < graph.length; node++) {
+      const current = partition[node];
+      const links = communityLinks(graph, partition, node);
+      totals.set(current, totals.get(current)! - degrees[node]);
+      addSeed(current, labels[node], -1);
+
+      const gain = (community: number) =>
+        (links.get(community) ?? 0) - resolution * (totals.get(community) ?? 0) * degrees[node] / twoM;
+      const allowed = (community: number) =>
+        labels[node] === undefined || !seeded.has(community) || seeded.get(community)!.label === labels[node];
+      let best = current;
+      let bestGain = gain(current);
+      for (const community of [...links.keys()].sort((a, b) => a - b)) {
+        const candidate = gain(community);
+        if (candidate > bestGain + 1e-12 && allowed(community)) {
+          best = community;
+          bestGain = candidate;
+        }
+      }
+
+      totals.set(best, (totals.get(best) ?? 0) + degrees[node]);
+      addSeed(best, labels[node], 1);
+      if (best !== current) {
+        partition[node] = best;
+        moved = true;
+      }
+    }
+  }
+  return partition;
+}
+
+/**
+ * Leiden refinement: within each community, merge singletons only into
+ * subcommunities they and the subcommunity are well connected to, so every
+ * resulting subcommunity is connected
+ */
+function refine(graph: Graph, partition: number[], resolution: number): number[] {
+  const twoM = totalWeight(graph);
+  const degrees = graph.map((_, node) => degree(graph, node));
+  const refined = [...Array(graph.length).keys()];
+  const refinedTotals = new Map(refined.map(node => [node, degrees[node]]));
+  const singleton = new Set(refined);
+
+  const communityTotals = new Map<number, number>();
+  partition.forEach((community, node) => communityTotals.set(community, (communityTotals.get(community) ?? 0) + degrees[node]));
+  // Weight from each subcommunity to the rest of its community
+  const external = new Map(refined.map(node => [node, linksWithin(graph, partition, node, partition[node], node)]));
+
+  for (let node = 0; node < graph.length; node++) {
+    if (!singleton.has(node)) continue;
+    const community = partition[node];
+    const communityTotal = communityTotals.get(community)!;
+    if (external.get(node)! < resolution * degrees[node] * (communityTotal - degrees[node]) / twoM) continue;
+
+    const links = new Map<number, number>();
+    for (const [neighbor, weight] of graph[node]) {
+      if (neighbor === node || partition[neighbor] !== community) continue;
+      links.set(refined[neighbor], (links.get(refined[neighbor]) ?? 0) + weight);
+    }
+
+    let best = node;
+    let bestGain = 0;
+    for (const target of [...links.keys()].sort((a, b) => a - b)) {
+      const total = refinedTotals.get(target)!;
+      const wellConnected = external.get(target)! >= resolution * total * (communityTotal - total) / twoM;
+      const gain = links.get(target)! - resolution * total * degrees[node] / twoM;
+      if (wellConnected && gain >= 0 && (best === node || gain > bestGain)) {
+        best = target;
+        bestGain = gain;
+      }
+    }
+    if (best === node) continue;
+
+    refined[node] = best;
+    singleton.delete(node);
+    singleton.delete(best);
+    refinedTotals.set(best, refinedTotals.get(best)! + degrees[node]);
+    refinedTotals.delete(node);
+    // Edges between the node and the subcommunity become internal
+    external.set(best, external.get(best)! + external.get(node)! - 2 * links.get(best)!);
+    external.delete(node);
+  }
+
+  return refined;
+}
+
+function communityLinks(graph: Graph, partition: number[], node: number): Map<number, number> {
+  const links = new Map<number, number>();
+  for (const [neighbor, weight] of graph[node]) {
+    if (neighbor === node) continue;
+    links.set(partition[neighbor], (links.get(partition[neighbor]) ?? 0) + weight);
+  }
+  return links;
+}
+
+function linksWithin(graph: Graph, partition: number[], node: number, community: number, exclude: number): number {
+  let sum = 0;
+  for (const [neighbor, weight] of graph[node]) {
+    if (neighbor !== exclude && partition[neighbor] === community) sum += weight;
+  }
+  return sum;
+}
+
+function aggregate(graph: Graph, ids: number[], size: number): Graph {
+  const next: Graph = Array.from({ length: size }, () => new Map());
+  graph.forEach((neighbors, node) => {
+    for (const [neighbor, weight] of neighbors) {
+      const from = ids[node];
+      const to = ids[neighbor];
+      next[from].set(to, (next[from].get(to) ?? 0) + weight);
+    }
+  });
+  return next;
+}
+
+/**
+ * Nodes seeded to the same module share a group, every other node is a group of its own
+ */
+function seedGroups(nodeCount: number, seeds: (string | undefined)[] = []): number[] {
+  const first = new Map<string, number>();
+  return renumber([...Array(nodeCount).keys()].map(node => {
+    const label = seeds[node];
+    if (label === undefined) return node;
+    if (!first.has(label)) first.set(label, node);
+    return first.get(label)!;
+  }));
+}
+
+/**
+ * Seed label of each group of `ids` (numbered from 0)
+ */
+function relabel(labels: (string | undefined)[], ids: number[]): (string | undefined)[] {
+  const result: (string | undefined)[] = Array(new Set(ids).size).fill(undefined);
+  ids.forEach((id, node) => { result[id] ??= labels[node]; });
+  return result;
+}
+
+function renumber(communities: number[]): number[] {
+  const ids = new Map<number, number>();
+  return communities.map(community => {
+    if (!ids.has(community)) ids.set(community, ids.size);
+    return ids.get(community)!;
+  });
+}
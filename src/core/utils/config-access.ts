@@ -0,0 +1,683 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { VibeFlowPaths } from './file-paths.js';
+import { SourceFile, SourceLocation, formatLocation } from './source-positions.js';
+import { detectGoProject, goImportAlias, goPackageImportPath } from './go-project-utils.js';
+import { goPackageName } from './go-load-check.js';
+import { maskLiterals } from './api-surface.js';
+import { dropUnusedImport, ensureImport } from './caller-migration.js';
+import { toPosixPath } from './workspace-paths.js';
+import { escapeRegExp } from './text-utils.js';
+
+/**
+ * env: os.Getenv/os.LookupEnv, viper: viper.Get*, flag: flag.String/StringVar/...,
+ * global: fields of a package-level config struct (e.g. config.Cfg.Database.Host)
+ */
+export type ConfigSource = 'env' | 'viper' | 'flag' | 'global';
+
+export interface ConfigAccessSite {
+  module: string;
+  /** Enclosing function ('' at package level) */
+  function: string;
+  source: ConfigSource;
+  /** null when the key is built at runtime */
+  key: string | null;
+  /** Access as written, e.g. os.Getenv("DB_HOST") */
+  expression: string;
+  location: SourceLocation;
+}
+
+/**
+ * One configuration key and every module that reads it
+ */
+export interface ConfigKey {
+  /** `<source>:<key>`, stable across plan regenerations */
+  id: string;
+  key: string;
+  source: ConfigSource;
+  /** Go type of the module config field */
+  type: string;
+  /** Expression the composition root reads the value with */
+  read: string;
+  modules: string[];
+  accesses: ConfigAccessSite[];
+}
+
+/**
+ * Every configuration read of the target modules, for ops to check nothing was dropped
+ */
+export interface ConfigAccessInventory {
+  keys: ConfigKey[];
+  /** Reads whose key is computed at runtime (or the whole config struct); they need a human */
+  unresolvable: ConfigAccessSite[];
+}
+
+export interface ModuleConfigField {
+  /** Exported field of the module's Config struct */
+  name: string;
+  key: string;
+  source: ConfigSource;
+  type: string;
+}
+
+/**
+ * Typed Config struct planned for one module, holding exactly the keys it reads
+ */
+export interface ModuleConfig {
+  /** Package directory of the generated config, e.g. internal/order/config */
+  package: string;
+  /** null without a go.mod */
+  import_path: string | null;
+  fields: ModuleConfigField[];
+  /** Composition root statement populating the config before the module is used */
+  wiring: string;
+}
+
+/** A read with what the module config field needs to know about its key */
+type ConfigRead = ConfigAccessSite & { type: string; read: string };
+
+interface ConfigGlobal {
+  variable: string;
+  type: string;
+  file: string;
+  dir: string;
+  packageName: string;
+  importPath: string | null;
+}
+
+interface GoSource {
+  file: string;
+  dir: string;
+  content: string;
+  /** content with literals and comments blanked out (same length) */
+  code: string;
+}
+
+/** Field tree of a struct type: field name → type, or the fields of an inline struct */
+type StructFields = Map<string, string | StructFields>;
+
+const ENV_FUNCTIONS = new Set(['Getenv', 'LookupEnv']);
+
+/** viper getters and the Go type of their result */
+const VIPER_GETTERS: Record<string, string> = {
+  Get: 'any',
+  GetString: 'string',
+  GetBool: 'bool',
+  GetInt: 'int',
+  GetInt32: 'int32',
+  GetInt64: 'int64',
+  GetUint: 'uint',
+  GetUint32: 'uint32',
+  GetUint64: 'uint64',
+  GetFloat64: 'float64',
+  GetDuration: 'time.Duration',
+  GetTime: 'time.Time',
+  GetStringSlice: '[]string',
+  GetIntSlice: '[]int',
+  GetStringMap: 'map[string]any',
+  GetStringMapString: 'map[string]string',
+  GetStringMapStringSlice: 'map[string][]string',
+};
+
+/** flag definitions and the Go type of the flag value */
+const FLAG_DEFINITIONS: Record<string, string> = {
+  String: 'string',
+  Bool: 'bool',
+  Int: 'int',
+  Int64: 'int64',
+  Uint: 'uint',
+  Uint64: 'uint64',
+  Float64: 'float64',
+  Duration: 'time.Duration',
+};
+
+const CONFIG_TYPE = /^(?:\w*Config|\w*Configuration|\w*Settings|Conf)$/;
+
+/** Name parts written in upper case in Go identifiers */
+const INITIALISMS = new Set(['API', 'DB', 'DNS', 'HTTP', 'HTTPS', 'ID', 'IP', 'JSON', 'JWT', 'SQL', 'SSL', 'TLS', 'TTL', 'UI', 'URI', 'URL', 'UUID', 'XML']);
+
+/**
+ * Find every configuration read in the target modules: os.Getenv/LookupEnv,
+ * viper getters, flag definitions and fields of package-level config structs.
+ * Keys are string literals or constants of the same file; anything else is
+ * reported as unresolvable with its location.
+ *
+ * @param modules target modules with their files relative to the project root
+ */
+export function findConfigAccess(projectRoot: string, modules: { name: string; files: string[] }[]): ConfigAccessInventory {
+  const moduleOf = new Map<string, string>();
+  for (const module of modules) {
+    for (const file of module.files) {
+      const key = toPosixPath(file);
+      if (key.endsWith('.go') && !key.endsWith('_test.go') && !moduleOf.has(key)) moduleOf.set(key, module.name);
+    }
+  }
+
+  const sources = [...moduleOf.keys()].sort()
+    .map(file => SourceFile.read(projectRoot, file))
+    .filter((source): source is SourceFile => source !== null);
+  const globals = findConfigGlobals(projectRoot);
+
+  const sites: ConfigRead[] = [];
+  for (const source of sources) {
+    const module = moduleOf.get(source.file) as string;
+    sites.push(...envReads(source, module), ...viperReads(source, module), ...flagReads(source, module));
+    for (const global of globals) sites.push(...globalReads(projectRoot, source, module, global));
+  }
+
+  const keys = new Map<string, ConfigKey>();
+  const unresolvable: ConfigAccessSite[] = [];
+  for (const read of sites) {
+    const site = accessSite(read);
+    if (site.key === null) {
+      unresolvable.push(site);
+      continue;
+    }
+    const id = `${site.source}:${site.key}`;
+    const entry = keys.get(id) ?? { id, key: site.key, source: site.source, type: read.type, read: read.read, modules: [], accesses: [] };
+    if (!entry.modules.includes(site.module)) entry.modules.push(site.module);
+    entry.accesses.push(site);
+    keys.set(id, entry);
+  }
+
+  return {
+    keys: [...keys.values()].map(key => ({ ...key, modules: key.modules.sort() })).sort((a, b) => a.id.localeCompare(b.id)),
+    unresolvable,
+  };
+}
+
+/**
+ * Per-module Config structs: one field for every key the module reads
+ */
+export function planModuleConfigs(projectRoot: string, inventory: ConfigAccessInventory, modules: string[]): Map<string, ModuleConfig> {
+  const goProject = detectGoProject(projectRoot);
+  const configs = new Map<string, ModuleConfig>();
+
+  for (const module of modules) {
+    const keys = inventory.keys.filter(key => key.modules.includes(module));
+    if (keys.length === 0) continue;
+
+    const taken = new Set<string>();
+    const fields = keys.map(key => {
+      let name = goFieldName(key.source === 'global' ? key.key.split('.').slice(1).join('.') : key.key);
+      if (taken.has(name)) name = `${name}${capitalize(key.source)}`;
+      for (let i = 2; taken.has(name); i++) name = `${name.replace(/\d+$/, '')}${i}`;
+      taken.add(name);
+      return { name, key: key.key, source: key.source, type: key.type };
+    });
+
+    const dir = `internal/${module}/config`;
+    const alias = moduleConfigAlias(module);
+    const width = Math.max(...fields.map(field => field.name.length)) + 1;
+    const values = fields.map(field => `\t${`${field.name}:`.padEnd(width)} ${keys.find(k => k.key === field.key && k.source === field.source)!.read},`);
+    configs.set(module, {
+      package: dir,
+      import_path: goPackageImportPath(projectRoot, dir, goProject),
+      fields,
+      wiring: `${alias}.Set(${alias}.Config{\n${values.join('\n')}\n})`,
+    });
+  }
+
+  return configs;
+}
+
+/**
+ * Name the module's code and the composition root import its config package as
+ */
+export function moduleConfigAlias(module: string): string {
+  return `${module.toLowerCase().replace(/[^a-z0-9]/g, '')}config`;
+}
+
+/**
+ * Config inventory and per-module configs recorded in .vibeflow/plan.json (empty without a plan)
+ */
+export function loadConfigPlan(projectRoot: string): { inventory: ConfigAccessInventory | null; modules: Map<string, ModuleConfig> } {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    const modules: { name?: unknown; config?: ModuleConfig }[] = Array.isArray(plan.modules) ? plan.modules : [];
+    return {
+      inventory: plan.config_access ?? null,
+      modules: new Map(modules.filter(m => typeof m.name === 'string' && m.config).map(m => [m.name as string, m.config!])),
+    };
+  } catch {
+    return { inventory: null, modules: new Map() };
+  }
+}
+
+/**
+ * config.go of a module: the Config struct, and Set/Get for the composition root and the module
+ */
+export function renderModuleConfigFile(module: string, config: ModuleConfig): string {
+  const width = Math.max(...config.fields.map(field => field.name.length));
+  const typeWidth = Math.max(...config.fields.map(field => field.type.length));
+  const imports = config.fields.some(field => field.type.includes('time.')) ? '\nimport "time"\n' : '';
+  return `// Code generated by vibeflow from the configuration keys in plan.json. DO NOT EDIT.
+
+// Package config holds every configuration key the ${module} module reads.
+// The composition root populates it with Set; the module does not read the
+// environment, flags or global configuration itself.
+package config
+${imports}
+// Config of the ${module} module
+type Config struct {
+${config.fields.map(field => `\t${field.name.padEnd(width)} ${field.type.padEnd(typeWidth)} // ${field.key} (${field.source})`).join('\n')}
+}
+
+var current Config
+
+// Set is called once by the composition root before the module is used
+func Set(c Config) {
+\tcurrent = c
+}
+
+// Get returns the configuration set by the composition root
+func Get() Config {
+\treturn current
+}
+`;
+}
+
+/**
+ * Replace os.Getenv("KEY") reads of the module's env keys with the module config.
+ * Other reads are left to the LLM; returns the rewritten content and the count.
+ */
+export function rewriteEnvReads(content: string, config: ModuleConfig, module: string): { content: string; rewritten: number } {
+  const osAlias = goImportAlias(content, 'os');
+  const fields = new Map(config.fields.filter(field => field.source === 'env').map(field => [field.key, field.name]));
+  if (!osAlias || osAlias === '_' || osAlias === '.' || fields.size === 0 || !config.import_path) return { content, rewritten: 0 };
+
+  const alias = moduleConfigAlias(module);
+  let rewritten = 0;
+  const pattern = new RegExp(`(?<![\\w.])${escapeRegExp(osAlias)}\\.Getenv\\(\\s*(?:"([^"\\\\]+)"|\`([^\`]+)\`)\\s*\\)`, 'g');
+  const code = maskLiterals(content);
+  const result = content.replace(pattern, (match, quoted: string | undefined, raw: string | undefined, offset: number) => {
+    const field = fields.get(quoted ?? raw ?? '');
+    // Matches inside comments or string literals are not reads
+    if (!field || code.slice(offset, offset + osAlias.length) !== osAlias) return match;
+    rewritten++;
+    return `${alias}.Get().${field}`;
+  });
+  if (rewritten === 0) return { content, rewritten: 0 };
+  return { content: dropUnusedImport(ensureImport(result, config.import_path, alias), 'os'), rewritten };
+}
+
+/**
+ * Reads of the module's keys still present in generated code
+ */
+export function remainingConfigReads(file: string, content: string, config: ModuleConfig, module: string): ConfigAccessSite[] {
+  const keys = new Set(config.fields.map(field => `${field.source}:${field.key}`));
+  const source = new SourceFile(file, content);
+  return [...envReads(source, module), ...viperReads(source, module), ...flagReads(source, module)]
+    .filter(site => site.key !== null && keys.has(`${site.source}:${site.key}`))
+    .map(accessSite);
+}
+
+/**
+ * Prompt section telling the model which config field replaces each read of the file
+ */
+export function renderConfigPromptSection(module: string, config: ModuleConfig | undefined, sites: ConfigAccessSite[]): string {
+  if (!config || !config.import_path || sites.length === 0) return '';
+
+  const alias = moduleConfigAlias(module);
+  const fieldOf = (site: ConfigAccessSite) => config.fields.find(field => field.source === site.source && field.key === site.key);
+  const lines = sites.map(site => {
+    const field = site.key !== null ? fieldOf(site) : undefined;
+    if (field) return `- ${site.expression} (${formatLocation(site.location)}) → ${alias}.Get().${field.name}`;
+    const reason = site.source === 'global' ? 'the whole config struct is used' : 'the key is computed at runtime';
+    return `- ${site.expression} (${formatLocation(site.location)}): ${reason}; keep it and add a TODO(vibeflow) comment`;
+  });
+  return `## Configuration
+The module reads its configuration from a generated package instead of the environment, flags or global config.
+Import it as \`${alias} "${config.import_path}"\` and replace these reads (do not declare the Config struct yourself):
+${lines.join('\n')}
+`;
+}
+
+export const CONFIG_ACCESS_HEADING = '## 設定キー (Configuration Access)';
+
+/**
+ * plan.md section: the full key inventory, keys shared by modules, unresolvable reads and the per-module structs
+ */
+export function renderConfigAccessSection(inventory: ConfigAccessInventory | undefined, modules: { name: string; config?: ModuleConfig }[]): string {
+  if (!inventory || (inventory.keys.length === 0 && inventory.unresolvable.length === 0)) return '';
+
+  const shared = inventory.keys.filter(key => key.modules.length > 1);
+  const configs = modules.filter(module => module.config);
+  return `
+${CONFIG_ACCESS_HEADING}
+
+移行後の各モジュールは、コンポジションルートが設定する専用の Config 構造体からのみ設定を読みます。
+以下は全ての設定キーの一覧です。デプロイ環境で漏れがないことを確認してください。
+
+| キー | 種類 | 型 | モジュール | 読み取り箇所 |
+|------|------|----|------------|--------------|
+${inventory.keys.map(key => `| \`${key.key}\` | ${key.source} | \`${key.type}\` | ${key.modules.join(', ')} | ${key.accesses.map(a => formatLocation(a.location)).join(', ')} |`).join('\n')}
+${shared.length > 0 ? `
+複数モジュールが読むキー: ${shared.map(key => `\`${key.key}\` (${key.modules.join(', ')})`).join(', ')}
+` : ''}${inventory.unresolvable.length > 0 ? `
+### 解決できない設定アクセス
+
+キーが実行時に組み立てられているか、設定構造体全体が使われているため、自動では移行できません。
+
+${inventory.unresolvable.map(site => `- \`${site.expression}\` (${site.source}, ${site.module}) — ${formatLocation(site.location)}`).join('\n')}
+` : ''}${configs.map(module => `
+### ${module.name} の Config (\`${module.config!.package}\`)
+
+${module.config!.fields.map(field => `- \`${field.name} ${field.type}\` ← \`${field.key}\` (${field.source})`).join('\n')}
+
+コンポジションルート:
+
+\`\`\`go
+${module.config!.wiring}
+\`\`\`
+`).join('')}`;
+}
+
+function accessSite({ module, function: func, source, key, expression, location }: ConfigRead): ConfigAccessSite {
+  return { module, function: func, source, key, expression, location };
+}
+
+function envReads(source: SourceFile, module: string): ConfigRead[] {
+  const alias = goImportAlias(source.content, 'os');
+  if (!alias || alias === '_' || alias === '.') return [];
+  return calls(source, alias, [...ENV_FUNCTIONS]).map(call => {
+    const key = resolveKey(source, call.args[0]);
+    return site(source, module, call, 'env', key, 'string', key !== null ? `os.Getenv(${JSON.stringify(key)})` : '');
+  });
+}
+
+function viperReads(source: SourceFile, module: string): ConfigRead[] {
+  const alias = goImportAlias(source.content, 'github.com/spf13/viper');
+  if (!alias || alias === '_' || alias === '.') return [];
+  return calls(source, alias, Object.keys(VIPER_GETTERS)).map(call => {
+    const key = resolveKey(source, call.args[0]);
+    return site(source, module, call, 'viper', key, VIPER_GETTERS[call.name], key !== null ? `viper.${call.name}(${JSON.stringify(key)})` : '');
+  });
+}
+
+function flagReads(source: SourceFile, module: string): ConfigRead[] {
+  const alias = goImportAlias(source.content, 'flag');
+  if (!alias || alias === '_' || alias === '.') return [];
+  const names = Object.keys(FLAG_DEFINITIONS).flatMap(name => [name, `${name}Var`]);
+  return calls(source, alias, names).map(call => {
+    const definition = call.name.replace(/Var$/, '');
+    const key = resolveKey(source, call.args[call.name.endsWith('Var') ? 1 : 0]);
+    const type = FLAG_DEFINITIONS[definition];
+    return site(source, module, call, 'flag', key, type, key !== null ? `flag.Lookup(${JSON.stringify(key)}).Value.(flag.Getter).Get().(${type})` : '');
+  });
+}
+
+/**
+ * Field reads of a package-level config struct from a module file
+ */
+function globalReads(projectRoot: string, source: SourceFile, module: string, global: ConfigGlobal): ConfigRead[] {
+  if (source.file === global.file) return [];
+  const sameDir = path.posix.dirname(source.file) === global.dir;
+  const alias = sameDir ? null : global.importPath ? goImportAlias(source.content, global.importPath, global.packageName) : null;
+  if (!sameDir && (!alias || alias === '_' || alias === '.')) return [];
+
+  const reference = sameDir ? global.variable : `${alias}.${global.variable}`;
+  const code = maskLiterals(source.content);
+  const pattern = new RegExp(`(?<![\\w.])${escapeRegExp(reference)}((?:\\.\\w+)*)`, 'g');
+  const reads: ConfigRead[] = [];
+  let fields: StructFields | undefined;
+
+  let match: RegExpExecArray | null;
+  while ((match = pattern.exec(code)) !== null) {
+    const rest = code.slice(match.index + match[0].length);
+    // Writes (the loader filling the struct) are not reads
+    if (/^\s*(?:[-+*/%|&^]|<<|>>)?=(?!=)/.test(rest)) continue;
+
+    let selectors = match[1].split('.').filter(Boolean);
+    // A trailing method call is not a field
+    if (/^\s*\(/.test(rest) && selectors.length > 0) selectors = selectors.slice(0, -1);
+    const expression = `${reference}${selectors.map(s => `.${s}`).join('')}`;
+    const key = selectors.length > 0 ? [global.variable, ...selectors].join('.') : null;
+    reads.push({
+      module,
+      function: enclosingFunction(code, match.index),
+      source: 'global',
+      key,
+      expression,
+      location: source.locate(match.index, match.index + expression.length),
+      type: key !== null ? fieldType(projectRoot, global.dir, fields ??= structFields(projectRoot, global.dir, global.type), selectors) : 'any',
+      read: key !== null ? `${global.packageName}.${key}` : '',
+    });
+  }
+  return reads;
+}
+
+function site(
+  source: SourceFile,
+  module: string,
+  call: { name: string; start: number; end: number },
+  configSource: ConfigSource,
+  key: string | null,
+  type: string,
+  read: string
+): ConfigRead {
+  return {
+    module,
+    function: enclosingFunction(maskLiterals(source.content), call.start),
+    source: configSource,
+    key,
+    expression: source.content.slice(call.start, call.end),
+    location: source.locate(call.start, call.end),
+    type,
+    read,
+  };
+}
+
+/**
+ * Calls of `qualifier.Name(...)` with their arguments as written
+ */
+function calls(source: SourceFile, qualifier: string, names: string[]): { name: string; args: string[]; start: number; end: number }[] {
+  const code = maskLiterals(source.content);
+  const pattern = new RegExp(`(?<![\\w.])${escapeRegExp(qualifier)}\\.(${names.join('|')})\\s*\\(`, 'g');
+  const found: { name: string; args: string[]; start: number; end: number }[] = [];
+
+  let match: RegExpExecArray | null;
+  while ((match = pattern.exec(code)) !== null) {
+    const open = match.index + match[0].length - 1;
+    const close = closingParen(code, open);
+    if (close < 0) continue;
+    found.push({ name: match[1], args: splitArguments(source.content, code, open + 1, close), start: match.index, end: close + 1 });
+  }
+  return found;
+}
+
+/**
+ * A string literal key, or a string constant declared in the same file
+ */
+function resolveKey(source: SourceFile, arg: string | undefined): string | null {
+  if (arg === undefined) return null;
+  const literal = arg.match(/^"((?:[^"\\]|\\.)*)"$|^`([^`]*)`$/);
+  if (literal) return literal[1] !== undefined ? JSON.parse(`"${literal[1]}"`) : literal[2];
+  if (!/^\w+$/.test(arg)) return null;
+
+  const spec = new RegExp(`^${arg}(?:\\s+string)?\\s*=\\s*"((?:[^"\\\\]|\\\\.)*)"`);
+  let inGroup = false;
+  for (const line of source.content.split('\n')) {
+    if (/^const\s*\(\s*$/.test(line)) {
+      inGroup = true;
+      continue;
+    }
+    if (inGroup && /^\)/.test(line)) {
+      inGroup = false;
+      continue;
+    }
+    const declared = inGroup ? line.trim().match(spec) : line.match(/^const\s+(.*)$/)?.[1].match(spec);
+    if (declared) return JSON.parse(`"${declared[1]}"`);
+  }
+  return null;
+}
+
+/**
+ * Package-level variables of a config struct type (var Cfg *Config, var AppConfig = &Config{})
+ */
+function findConfigGlobals(projectRoot: string): ConfigGlobal[] {
+  const goProject = detectGoProject(projectRoot);
+  const globals: ConfigGlobal[] = [];
+
+  for (const source of loadGoSources(projectRoot)) {
+    const packageName = goPackageName(source.content);
+    if (!packageName || packageName === 'main') continue;
+
+    let inGroup = false;
+    for (const line of source.code.split('\n')) {
+      if (/^var\s*\(\s*$/.test(line)) {
+        inGroup = true;
+        continue;
+      }
+      if (inGroup && /^\)/.test(line)) {
+        inGroup = false;
+        continue;
+      }
+      const spec = inGroup ? line.match(/^\s+(\w+)\s+(.*)$/) : line.match(/^var\s+(\w+)\s+(.*)$/);
+      if (!spec) continue;
+
+      const type = spec[2].trim().match(/^\*?(\w+)\s*$|^=\s*(?:&|new\()?(\w+)[{)]/);
+      const typeName = type?.[1] ?? type?.[2];
+      if (!typeName || !CONFIG_TYPE.test(typeName) || !/^[A-Z]/.test(spec[1])) continue;
+      if (!declaresStruct(projectRoot, source.dir, typeName)) continue;
+
+      globals.push({
+        variable: spec[1],
+        type: typeName,
+        file: source.file,
+        dir: source.dir,
+        packageName,
+        importPath: goPackageImportPath(projectRoot, source.dir, goProject),
+      });
+    }
+  }
+  return globals;
+}
+
+function loadGoSources(projectRoot: string): GoSource[] {
+  return fastGlob.sync('**/*.go', {
+    cwd: projectRoot,
+    ignore: ['**/vendor/**', '**/node_modules/**', '**/*_test.go', '.vibeflow/**', '.git/**'],
+  }).sort().map(file => {
+    const content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+    return { file, dir: path.posix.dirname(file), content, code: maskLiterals(content) };
+  });
+}
+
+function packageCode(projectRoot: string, dir: string): string {
+  try {
+    return fs.readdirSync(path.join(projectRoot, dir))
+      .filter(name => name.endsWith('.go') && !name.endsWith('_test.go'))
+      .map(name => maskLiterals(fs.readFileSync(path.join(projectRoot, dir, name), 'utf8')))
+      .join('\n');
+  } catch {
+    return '';
+  }
+}
+
+function declaresStruct(projectRoot: string, dir: string, typeName: string): boolean {
+  return new RegExp(`^type\\s+${typeName}\\s+struct\\s*\\{`, 'm').test(packageCode(projectRoot, dir));
+}
+
+/**
+ * Fields of a struct type declared in a package directory, inline structs nested
+ */
+function structFields(projectRoot: string, dir: string, typeName: string): StructFields {
+  const code = packageCode(projectRoot, dir);
+  const start = code.search(new RegExp(`^type\\s+${typeName}\\s+struct\\s*\\{`, 'm'));
+  if (start < 0) return new Map();
+
+  const lines = code.slice(start).split('\n').slice(1);
+  const stack: StructFields[] = [new Map()];
+  const pending: string[] = [];
+  for (const line of lines) {
+    const trimmed = line.trim();
+    if (trimmed.startsWith('}')) {
+      const closed = stack.pop()!;
+      if (stack.length === 0) return closed;
+      stack[stack.length - 1].set(pending.pop()!, closed);
+      continue;
+    }
+    const inline = trimmed.match(/^(\w+)\s+struct\s*\{$/);
+    if (inline) {
+      pending.push(inline[1]);
+      stack.push(new Map());
+      continue;
+    }
+    const field = trimmed.match(/^(\w+(?:\s*,\s*\w+)*)\s+([^\s]+)/);
+    if (field) field[1].split(',').forEach(name => stack[stack.length - 1].set(name.trim(), field[2]));
+  }
+  return stack[0];
+}
+
+/**
+ * Type of a field path, following named struct types of the same package; `any` when unknown
+ */
+function fieldType(projectRoot: string, dir: string, fields: StructFields, selectors: string[]): string {
+  let current: string | StructFields | undefined = fields;
+  for (const selector of selectors) {
+    if (typeof current === 'string') {
+      const named = current.replace(/^\*/, '');
+      current = /^\w+$/.test(named) ? structFields(projectRoot, dir, named).get(selector) : undefined;
+    } else {
+      current = current?.get(selector);
+    }
+    if (current === undefined) return 'any';
+  }
+  return typeof current === 'string' ? current : 'any';
+}
+
+function enclosingFunction(code: string, index: number): string {
+  let current = '';
+  for (const line of code.slice(0, index).split('\n')) {
+    const func = line.match(/^func\s*(?:\([^)]*\)\s*)?(\w+)/);
+    if (func) current = func[1];
+    else if (/^\}/.test(line)) current = '';
+  }
+  return current;
+}
+
+function closingParen(code: string, open: number): number {
+  let depth = 0;
+  for (let i = open; i < code.length; i++) {
+    if (code[i] === '(') depth++;
+    else if (code[i] === ')' && --depth === 0) return i;
+  }
+  return -1;
+}
+
+function splitArguments(content: string, code: string, start: number, end: number): string[] {
+  const args: string[] = [];
+  let depth = 0;
+  let from = start;
+  for (let i = start; i < end; i++) {
+    const ch = code[i];
+    if (ch === '(' || ch === '[' || ch === '{') depth++;
+    else if (ch === ')' || ch === ']' || ch === '}') depth--;
+    else if (ch === ',' && depth === 0) {
+      args.push(content.slice(from, i).trim());
+      from = i + 1;
+    }
+  }
+  const last = content.slice(from, end).trim();
+  if (last) args.push(last);
+  return args;
+}
+
+/**
+ * Exported Go field name of a key: DB_HOST → DBHost, server.port → ServerPort
+ */
+export function goFieldName(key: string): string {
+  const parts = key
+    .replace(/([a-z0-9])([A-Z])/g, '$1 $2')
+    .split(/[^A-Za-z0-9]+/)
+    .filter(Boolean);
+  const name = parts.map(part => INITIALISMS.has(part.toUpperCase()) ? part.toUpperCase() : capitalize(part.toLowerCase())).join('');
+  if (!name) return 'Value';
+  return /^\d/.test(name) ? `K${name}` : name;
+}
+
+function capitalize(text: string): string {
+  return text.charAt(0).toUpperCase() + text.slice(1);
+}
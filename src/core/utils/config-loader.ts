@@ -1,6 +1,7 @@
 import * as fs from 'fs';
 import * as yaml from 'js-yaml';
-import { VibeFlowConfig, VibeFlowConfigSchema, BoundaryConfig, BoundaryConfigSchema } from '../types/config.js';
+import { VibeFlowConfig, VibeFlowConfigSchema, BoundaryConfig } from '../types/config.js';
+import { parseBoundaryConfig, readTextInput } from './input-parsers.js';
 
 export class ConfigLoader {
   static loadVibeFlowConfig(configPath?: string): VibeFlowConfig {
@@ -76,15 +77,7 @@ export class ConfigLoader {
       return null; // boundary.yaml is optional
     }
 
-    const content = fs.readFileSync(filePath, 'utf8');
-    const rawConfig = yaml.load(content);
-    
-    const result = BoundaryConfigSchema.safeParse(rawConfig);
-    if (!result.success) {
-      throw new Error(`Invalid boundary config: ${result.error.message}`);
-    }
-    
-    return result.data;
+    return parseBoundaryConfig(readTextInput('boundary-config', filePath), filePath);
   }
 
   static saveConfig(config: any, filePath: string): void {
@@ -0,0 +1,371 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { escapeRegExp } from './text-utils.js';
+
+export type ContextItemKind = 'plan' | 'type' | 'signature' | 'body';
+
+export interface ContextItem {
+  kind: ContextItemKind;
+  symbol: string;
+  file: string;
+  content: string;
+  tokens: number;
+  /** Number of references from the target file (plan excerpt uses Infinity) */
+  relevance: number;
+}
+
+export interface SelectedContext {
+  items: ContextItem[];
+  dropped: ContextItem[];
+  tokens: number;
+  budgetTokens: number;
+}
+
+export interface ContextSelectorOptions {
+  /** Maximum tokens for the selected context (prompt.contextBudgetTokens) */
+  budgetTokens?: number;
+  /** Directly called functions up to this many lines are included with their body */
+  inlineBodyMaxLines?: number;
+}
+
+export interface GoDeclaration {
+  name: string;
+  kind: 'func' | 'method' | 'type';
+  file: string;
+  signature: string;
+  body: string;
+  lines: number;
+}
+
+export const DEFAULT_CONTEXT_BUDGET_TOKENS = 4000;
+export const DEFAULT_INLINE_BODY_MAX_LINES = 15;
+
+/**
+ * Rough token estimate (same heuristic as HybridRefactorAgent.estimateCost)
+ */
+export function estimateTokens(text: string): number {
+  return Math.ceil(text.length / 4);
+}
+
+/**
+ * ContextSelector - 依存グラフに基づくプロンプトコンテキスト選択
+ *
+ * Instead of sending whole dependency files, only the declarations the target
+ * file actually references are included: type definitions, function signatures,
+ * and bodies of small directly called functions. Items are ranked by reference
+ * count and the least referenced ones are dropped first to fit the budget.
+ */
+export class ContextSelector {
+  private projectRoot: string;
+  private budgetTokens: number;
+  private inlineBodyMaxLines: number;
+  private modulePath: string | null;
+
+  constructor(projectRoot: string, options: ContextSelectorOptions = {}) {
+    this.projectRoot = projectRoot;
+    this.budgetTokens = options.budgetTokens ?? DEFAULT_CONTEXT_BUDGET_TOKENS;
+    this.inlineBodyMaxLines = options.inlineBodyMaxLines ?? DEFAULT_INLINE_BODY_MAX_LINES;
+    this.modulePath = this.readModulePath();
+  }
+
+  /**
+   * Select the minimal context for transforming a single file
+   */
+  selectContext(file: string, candidateFiles: string[] = [], planExcerpt?: string): SelectedContext {
+    const targetPath = this.resolve(file);
+    const source = fs.readFileSync(targetPath, 'utf8');
+    const ownDeclarations = new Set(parseGoDeclarations(source, targetPath).map(d => d.name));
+
+    const candidates: ContextItem[] = [];
+
+    if (planExcerpt && planExcerpt.trim()) {
+      candidates.push({
+        kind: 'plan',
+        symbol: 'plan',
+        file: 'plan.md',
+        content: planExcerpt.trim(),
+        tokens: estimateTokens(planExcerpt.trim()),
+        relevance: Number.POSITIVE_INFINITY,
+      });
+    }
+
+    // The package clause names the package, not a declaration (package main vs func main)
+    const strippedSource = stripCommentsAndStrings(source).replace(/^\s*package\s+\w+/m, '');
+    const seen = new Set<string>();
+
+    for (const decl of this.collectDeclarations(targetPath, source, candidateFiles)) {
+      if (ownDeclarations.has(decl.name) || seen.has(`${decl.file}:${decl.name}`)) continue;
+
+      const references = countReferences(strippedSource, decl.name);
+      if (references === 0) continue;
+      seen.add(`${decl.file}:${decl.name}`);
+
+      let kind: ContextItemKind;
+      let content: string;
+      if (decl.kind === 'type') {
+        kind = 'type';
+        content = decl.body;
+      } else if (isDirectlyCalled(strippedSource, decl.name) && decl.lines <= this.inlineBodyMaxLines) {
+        kind = 'body';
+        content = decl.body;
+      } else {
+        kind = 'signature';
+        content = decl.signature;
+      }
+
+      candidates.push({
+        kind,
+        symbol: decl.name,
+        file: path.relative(this.projectRoot, decl.file),
+        content,
+        tokens: estimateTokens(content),
+        relevance: references,
+      });
+    }
+
+    return applyBudget(candidates, this.budgetTokens);
+  }
+
+  /**
+   * Extract the section of plan.md describing the given module
+   */
+  static extractPlanExcerpt(planContent: string, moduleName: string, maxLines = 40): string {
+    // Generated-block markers of plan.md (see plan-sync.ts) are not plan content
+    const lines = planContent.split('\n').filter(line => !/^<!-- \/?vf:generated\b.*-->$/.test(line));
+    const pattern = new RegExp(`^(#+)\\s.*\\b${escapeRegExp(moduleName)}\\b`, 'i');
+
+    for (let i = 0; i < lines.length; i++) {
+      const match = lines[i].match(pattern);
+      if (!match) continue;
+
+      const level = match[1].length;
+      const section = [lines[i]];
+      for (let j = i + 1; j < lines.length && section.length < maxLines; j++) {
+        const heading = lines[j].match(/^(#+)\s/);
+        if (heading && heading[1].length <= level) break;
+        section.push(lines[j]);
+      }
+      return section.join('\n').trim();
+    }
+
+    return '';
+  }
+
+  private collectDeclarations(targetPath: string, source: string, candidateFiles: string[]): GoDeclaration[] {
+    const files = new Set<string>();
+
+    // Same package
+    for (const sibling of listGoFiles(path.dirname(targetPath))) {
+      files.add(sibling);
+    }
+
+    // Imported in-module packages
+    for (const importPath of parseImports(source)) {
+      if (this.modulePath && importPath.startsWith(this.modulePath + '/')) {
+        const dir = path.join(this.projectRoot, importPath.slice(this.modulePath.length + 1));
+        listGoFiles(dir).forEach(f => files.add(f));
+      }
+    }
+
+    candidateFiles.forEach(f => files.add(this.resolve(f)));
+    files.delete(targetPath);
+
+    const declarations: GoDeclaration[] = [];
+    for (const file of files) {
+      try {
+        declarations.push(...parseGoDeclarations(fs.readFileSync(file, 'utf8'), file));
+      } catch {
+        // Unreadable files contribute no context
+      }
+    }
+    return declarations;
+  }
+
+  private readModulePath(): string | null {
+    try {
+      const goMod = fs.readFileSync(path.join(this.projectRoot, 'go.mod'), 'utf8');
+      const match = goMod.match(/^module\s+(\S+)/m);
+      return match ? match[1] : null;
+    } catch {
+      return null;
+    }
+  }
+
+  private resolve(file: string): string {
+    return path.isAbsolute(file) ? file : path.join(this.projectRoot, file);
+  }
+}
+
+/**
+ * Keep the most relevant items within the token budget
+ */
+export function applyBudget(candidates: ContextItem[], budgetTokens: number): SelectedContext {
+  const ranked = [...candidates].sort((a, b) => b.relevance - a.relevance || a.tokens - b.tokens);
+  const items: ContextItem[] = [];
+  const dropped: ContextItem[] = [];
+  let tokens = 0;
+
+  for (const item of ranked) {
+    if (tokens + item.tokens <= budgetTokens) {
+      items.push(item);
+      tokens += item.tokens;
+    } else {
+      dropped.push(item);
+    }
+  }
+
+  return { items, dropped, tokens, budgetTokens };
+}
+
+/**
+ * Render selected context as a prompt section
+ */
+export function renderContext(context: SelectedContext): string {
+  if (context.items.length === 0) return '';
+
+  const sections: string[] = ['## Related Context'];
+  const plan = context.items.find(i => i.kind === 'plan');
+  if (plan) {
+    sections.push('### Module Plan', plan.content);
+  }
+
+  const code = context.items.filter(i => i.kind !== 'plan');
+  if (code.length > 0) {
+    sections.push('### Referenced Declarations', '```go');
+    for (const item of code) {
+      sections.push(`// ${item.file} (${item.kind})`, item.content, '');
+    }
+    sections.push('```');
+  }
+
+  return sections.join('\n');
+}
+
+// -----------------------------------------------------------------------------
+// Go source helpers (regex based, consistent with ast-analyzer)
+// -----------------------------------------------------------------------------
+
+export function parseGoDeclarations(source: string, file: string): GoDeclaration[] {
+  const lines = source.split('\n');
+  const declarations: GoDeclaration[] = [];
+
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i];
+    const funcMatch = line.match(/^func\s+(?:\(\s*\w*\s*\*?\s*(\w+)[^)]*\)\s*)?(\w+)\s*[([]/);
+    const typeMatch = line.match(/^type\s+(\w+)\b/);
+    if (!funcMatch && !typeMatch) continue;
+
+    const end = findDeclarationEnd(lines, i);
+    const body = lines.slice(i, end + 1).join('\n');
+
+    if (funcMatch) {
+      declarations.push({
+        name: funcMatch[2],
+        kind: funcMatch[1] ? 'method' : 'func',
+        file,
+        signature: line.replace(/\s*\{\s*$/, ''),
+        body,
+        lines: end - i + 1,
+      });
+    } else if (typeMatch) {
+      declarations.push({
+        name: typeMatch[1],
+        kind: 'type',
+        file,
+        signature: line.replace(/\s*\{\s*$/, ''),
+        body,
+        lines: end - i + 1,
+      });
+    }
+
+    i = end;
+  }
+
+  return declarations;
+}
+
+/**
+ * Split Go source into roughly equal chunks of whole top-level declarations.
+ * Each chunk keeps the package clause and imports so it can be sent on its own.
+ */
+export function splitSourceIntoChunks(source: string, file: string, parts: number): string[] {
+  const declarations = parseGoDeclarations(source, file);
+  if (declarations.length < 2 || parts < 2) return [source];
+
+  const firstIndex = source.indexOf(declarations[0].body);
+  const header = firstIndex > 0 ? source.slice(0, firstIndex).trimEnd() : '';
+  const totalLines = declarations.reduce((sum, d) => sum + d.lines, 0);
+  const target = Math.ceil(totalLines / Math.min(parts, declarations.length));
+
+  const chunks: GoDeclaration[][] = [[]];
+  let size = 0;
+  for (const decl of declarations) {
+    if (size >= target && chunks.length < parts) {
+      chunks.push([]);
+      size = 0;
+    }
+    chunks[chunks.length - 1].push(decl);
+    size += decl.lines;
+  }
+
+  return chunks
+    .filter(chunk => chunk.length > 0)
+    .map(chunk => [header, ...chunk.map(d => d.body)].filter(Boolean).join('\n\n') + '\n');
+}
+
+function findDeclarationEnd(lines: string[], start: number): number {
+  let depth = 0;
+  let opened = false;
+
+  for (let i = start; i < lines.length; i++) {
+    const code = stripCommentsAndStrings(lines[i]);
+    for (const ch of code) {
+      if (ch === '{') { depth++; opened = true; }
+      else if (ch === '}') depth--;
+    }
+    if (opened && depth <= 0) return i;
+    // Declarations without a body (e.g. `type ID string`) end on their first line,
+    // unless the signature continues onto following lines
+    if (!opened && i === start && !/[(,]\s*$/.test(code)) return i;
+    if (!opened && i > start && /^\)/.test(lines[i])) return i;
+  }
+
+  return lines.length - 1;
+}
+
+function parseImports(source: string): string[] {
+  const imports: string[] = [];
+  const block = source.match(/import\s*\(([\s\S]*?)\)/);
+  if (block) {
+    for (const m of block[1].matchAll(/"([^"]+)"/g)) imports.push(m[1]);
+  }
+  for (const m of source.matchAll(/^import\s+(?:\w+\s+)?"([^"]+)"/gm)) imports.push(m[1]);
+  return imports;
+}
+
+function listGoFiles(dir: string): string[] {
+  try {
+    return fs.readdirSync(dir)
+      .filter(f => f.endsWith('.go') && !f.endsWith('_test.go'))
+      .map(f => path.join(dir, f));
+  } catch {
+    return [];
+  }
+}
+
+function stripCommentsAndStrings(source: string): string {
+  return source
+    .replace(/\/\*[\s\S]*?\*\//g, '')
+    .replace(/\/\/.*$/gm, '')
+    .replace(/`[^`]*`/g, '``')
+    .replace(/"(?:[^"\\\n]|\\.)*"/g, '""');
+}
+
+function countReferences(source: string, name: string): number {
+  const matches = source.match(new RegExp(`\\b${escapeRegExp(name)}\\b`, 'g'));
+  return matches ? matches.length : 0;
+}
+
+function isDirectlyCalled(source: string, name: string): boolean {
+  return new RegExp(`\\b${escapeRegExp(name)}\\s*\\(`).test(source);
+}
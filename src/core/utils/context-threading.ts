@@ -0,0 +1,305 @@
+import { parseGoDeclarations } from './context-selector.js';
+import { splitTopLevel } from './text-utils.js';
+import { ContextTodo, MethodNameMapping } from '../types/refactor.js';
+
+export interface ContextThreadingOptions {
+  /** refactor.addContext: thread ctx through the extracted call chain (true) or keep legacy signatures (false) */
+  addContext: boolean;
+  /** Method names (legacy or mapped) whose legacy function already takes a context.Context */
+  legacyWithContext?: Set<string>;
+}
+
+const CONTEXT_TYPE = /^\s*(?:(\w+)\s+)?context\.Context\s*$/;
+const CONTEXT_EXPRESSION = /^\s*(?:ctx|\w+\.Context\(\)|\w+\.Request\.Context\(\)|context\.(?:TODO|Background)\(\))\s*$/;
+const ENTRYPOINT_PARAM = /http\.ResponseWriter|\*gin\.Context|echo\.Context|\*fiber\.Ctx/;
+
+/**
+ * Legacy functions whose signature already takes a context.Context
+ */
+export function legacyContextFunctions(source: string, file = ''): string[] {
+  return parseGoDeclarations(source, file)
+    .filter(decl => (decl.kind === 'func' || decl.kind === 'method') && /context\.Context/.test(decl.signature))
+    .map(decl => decl.name);
+}
+
+/**
+ * Names a legacy ctx-taking function may have in generated code (its own and the mapped usecase name)
+ */
+export function contextMethodNames(legacy: string[], methodNames: MethodNameMapping[] = []): Set<string> {
+  const names = new Set(legacy);
+  for (const mapping of methodNames) {
+    if (names.has(mapping.legacy)) names.add(mapping.method);
+  }
+  return names;
+}
+
+/**
+ * Markdown section telling the model how to handle context.Context
+ */
+export function renderContextSection(addContext: boolean, legacyWithContext: string[]): string {
+  const lines = ['## Context Threading'];
+  if (addContext) {
+    lines.push(
+      'Thread `ctx context.Context` as the first parameter through the whole extracted call chain:',
+      'interfaces, usecases, repositories and handlers (handlers pass `r.Context()`).',
+      'Never add a second context parameter to a function that already takes one.',
+      'Where a legacy caller cannot supply a context yet, pass `context.TODO()`.'
+    );
+  } else {
+    lines.push(
+      'Do not add context.Context parameters: generated interfaces and implementations must keep',
+      'the legacy function signatures.'
+    );
+  }
+  if (legacyWithContext.length > 0) {
+    lines.push(`These legacy functions already take a context and keep it: ${legacyWithContext.map(n => `\`${n}\``).join(', ')}`);
+  }
+  return lines.join('\n') + '\n';
+}
+
+/**
+ * Make ctx parameters consistent across the outputs generated for one legacy file.
+ *
+ * - addContext: interface methods and their implementations take ctx first,
+ *   calls pass the caller's ctx (`r.Context()` in HTTP handlers) or
+ *   `context.TODO()` when the caller has none.
+ * - !addContext: ctx parameters and arguments are removed except for methods
+ *   whose legacy function already took a context.
+ * - Double context parameters and arguments are collapsed in both modes.
+ *
+ * Every `context.TODO()` left in the result is reported.
+ */
+export function threadContext<T extends { path: string; content: string }>(
+  files: T[],
+  options: ContextThreadingOptions
+): { files: T[]; todos: ContextTodo[] } {
+  const keep = options.legacyWithContext ?? new Set<string>();
+  const methods = new Set<string>();
+  for (const file of files) {
+    for (const method of interfaceMethods(file.content)) {
+      if (options.addContext || !keep.has(method)) methods.add(method);
+    }
+  }
+
+  const todos: ContextTodo[] = [];
+  const threaded = files.map(file => {
+    if (!file.path.endsWith('.go')) return file;
+
+    let content = rewriteInterfaces(file.content, methods, options.addContext);
+    content = rewriteFunctions(content, methods, options.addContext);
+    content = fixContextImport(content);
+    todos.push(...findContextTodos(file.path, content));
+    return { ...file, content };
+  });
+
+  return { files: threaded, todos };
+}
+
+/**
+ * `context.TODO()` call sites with their enclosing function
+ */
+export function findContextTodos(file: string, content: string): ContextTodo[] {
+  const todos: ContextTodo[] = [];
+  let current = '';
+  content.split('\n').forEach((line, index) => {
+    const decl = line.match(/^func\s*(?:\(\s*(?:\w+\s+)?\*?\s*(\w+)[^)]*\)\s*)?(\w+)/);
+    if (decl) current = decl[1] ? `${decl[1]}.${decl[2]}` : decl[2];
+    if (line.includes('context.TODO()') && !/^\s*\/\//.test(line)) {
+      todos.push({ file, line: index + 1, function: current || '(package level)' });
+    }
+  });
+  return todos;
+}
+
+function interfaceMethods(content: string): string[] {
+  const methods: string[] = [];
+  for (const body of content.matchAll(/^type\s+\w+\s+interface\s*\{\n([\s\S]*?)^\}/gm)) {
+    for (const method of body[1].matchAll(/^\s+([A-Za-z_]\w*)\s*\(/gm)) {
+      methods.push(method[1]);
+    }
+  }
+  return methods;
+}
+
+function rewriteInterfaces(content: string, methods: Set<string>, addContext: boolean): string {
+  return content.replace(/^(type\s+\w+\s+interface\s*\{\n)([\s\S]*?)(^\})/gm, (_, head: string, body: string, tail: string) => {
+    const rewritten = body.split('\n').map(line => {
+      const match = line.match(/^(\s+)([A-Za-z_]\w*)\s*\(/);
+      if (!match) return line;
+      const open = line.indexOf('(', match[0].length - 1);
+      const close = matchingParen(line, open);
+      if (close < 0) return line;
+
+      const params = rewriteParams(line.slice(open + 1, close), methods.has(match[2]), addContext).params;
+      return line.slice(0, open + 1) + params + line.slice(close);
+    });
+    return head + rewritten.join('\n') + tail;
+  });
+}
+
+/**
+ * Rewrite signatures of interface method implementations and calls in every top-level func
+ */
+function rewriteFunctions(content: string, methods: Set<string>, addContext: boolean): string {
+  const lines = content.split('\n');
+  const output: string[] = [];
+  let i = 0;
+
+  while (i < lines.length) {
+    if (!/^func\b/.test(lines[i])) {
+      output.push(lines[i++]);
+      continue;
+    }
+
+    // One-line functions end on their own line, others at the first closing brace in column 0
+    let end = i;
+    const oneLine = lines[i].includes('{') && count(lines[i], '{') === count(lines[i], '}');
+    while (!oneLine && end < lines.length - 1 && !/^\}/.test(lines[end])) {
+      end++;
+    }
+    const chunk = lines.slice(i, end + 1).join('\n');
+    output.push(rewriteFunction(chunk, methods, addContext));
+    i = end + 1;
+  }
+
+  return output.join('\n');
+}
+
+function rewriteFunction(chunk: string, methods: Set<string>, addContext: boolean): string {
+  const decl = chunk.match(/^func\s*(\([^)]*\)\s*)?(\w+)\s*(?:\[[^\]]*\]\s*)?\(/);
+  if (!decl) return chunk;
+
+  const open = decl[0].length - 1;
+  const close = matchingParen(chunk, open);
+  if (close < 0) return chunk;
+
+  const isMethod = Boolean(decl[1]);
+  const rawParams = chunk.slice(open + 1, close);
+  const isEntrypoint = ENTRYPOINT_PARAM.test(rawParams);
+  const threadable = isMethod && methods.has(decl[2]) && !isEntrypoint;
+  const { params, removed, replacement } = rewriteParams(rawParams, threadable, addContext);
+
+  const header = chunk.slice(0, open + 1) + params;
+  let body = chunk.slice(close);
+
+  // Context available to calls inside this function
+  const ctxName = params.split(',').map(p => p.match(CONTEXT_TYPE)?.[1]).find(Boolean);
+  const request = rawParams.match(/(\w+)\s+\*http\.Request/)?.[1];
+  const gin = rawParams.match(/(\w+)\s+\*gin\.Context/)?.[1];
+  const available = ctxName ?? (request ? `${request}.Context()` : gin ? `${gin}.Request.Context()` : 'context.TODO()');
+
+  // Uses of dropped parameters: context.TODO() (legacy signatures) or the kept ctx (duplicates)
+  for (const name of removed) {
+    body = body.replace(new RegExp(`(?<![.\\w])${name}\\b(?!\\s*:?=)`, 'g'), replacement);
+  }
+
+  body = rewriteCalls(body, methods, addContext, available, ctxName);
+
+  // Local contexts no call needs any more would not compile (declared and not used)
+  body = body.replace(/^[ \t]*(\w+) := context\.(?:Background|TODO)\(\)[ \t]*\n/gm, (line, name: string) =>
+    new RegExp(`(?<![.\\w])${name}\\b`).test(body.replace(line, '')) ? line : '');
+
+  return header + body;
+}
+
+function rewriteCalls(body: string, methods: Set<string>, addContext: boolean, available: string, ctxName?: string): string {
+  const isContext = (arg: string) => CONTEXT_EXPRESSION.test(arg) || (ctxName !== undefined && arg.trim() === ctxName);
+  // Selector calls on variables and fields only (not on call results such as r.URL.Query().Get)
+  const calls = [...body.matchAll(/(?<=\w)\.([A-Za-z_]\w*)\(/g)].filter(m => methods.has(m[1]));
+
+  // Rewrite from the end so earlier offsets stay valid
+  for (const call of calls.reverse()) {
+    const open = call.index! + call[0].length - 1;
+    const close = matchingParen(body, open);
+    if (close < 0) continue;
+
+    const args = splitTopLevel(body.slice(open + 1, close));
+    const contextArgs = args.filter(isContext);
+    let rewritten = args.filter(arg => !isContext(arg));
+    if (addContext) {
+      const ctx = contextArgs.length > 0 ? contextArgs[0].trim() : available;
+      rewritten = [ctx, ...rewritten];
+    }
+
+    const joined = rewritten.map(a => a.trim()).filter(a => a !== '').join(', ');
+    body = body.slice(0, open + 1) + joined + body.slice(close);
+  }
+
+  return body;
+}
+
+/**
+ * Add ctx first (or collapse duplicates) when threading; drop ctx when keeping legacy signatures.
+ * `removed` lists dropped parameter names, `replacement` what their uses become.
+ */
+function rewriteParams(
+  params: string,
+  threadable: boolean,
+  addContext: boolean
+): { params: string; removed: string[]; replacement: string } {
+  const parts = splitTopLevel(params);
+  const contextParts = parts.filter(p => CONTEXT_TYPE.test(p));
+  const others = parts.filter(p => !CONTEXT_TYPE.test(p)).map(p => p.trim()).filter(p => p !== '');
+  const names = contextParts.map(p => p.match(CONTEXT_TYPE)?.[1]).filter((n): n is string => Boolean(n) && n !== '_');
+  const first = contextParts[0]?.trim();
+
+  if (threadable && !addContext) {
+    return { params: others.join(', '), removed: names, replacement: 'context.TODO()' };
+  }
+  if (threadable && contextParts.length <= 1) {
+    return { params: [first ?? 'ctx context.Context', ...others].join(', '), removed: [], replacement: '' };
+  }
+  if (contextParts.length <= 1) {
+    return { params, removed: [], replacement: '' };
+  }
+
+  // Double ctx: keep the first one and point the others at it
+  const kept = first.match(CONTEXT_TYPE)?.[1] ?? 'context.TODO()';
+  return { params: [first, ...others].join(', '), removed: names.filter(n => n !== kept), replacement: kept };
+}
+
+/**
+ * Add the "context" import when used and drop it when no longer used
+ */
+function fixContextImport(content: string): string {
+  const uses = /(?<![\w"])context\.\w/.test(content.replace(/^import[\s\S]*?(?:\)\n|"\n)/m, ''));
+  const imported = /^import\s+"context"\s*$/m.test(content) || /^import\s*\([^)]*^\s*"context"\s*$/m.test(content);
+
+  if (uses && !imported) {
+    if (/^import\s*\(/m.test(content)) {
+      return content.replace(/^import\s*\(\n/m, match => `${match}\t"context"\n`);
+    }
+    if (/^import\s+"/m.test(content)) {
+      return content.replace(/^import\s+("[^"]+")\s*$/m, (_, existing: string) => `import (\n\t"context"\n\t${existing}\n)`);
+    }
+    return content.replace(/^(package\s+\w+\s*\n)/m, '$1\nimport "context"\n');
+  }
+
+  if (!uses && imported) {
+    return content
+      .replace(/^import\s+"context"\s*\n/m, '')
+      .replace(/^(import\s*\([^)]*?)^\s*"context"\s*\n/m, '$1');
+  }
+
+  return content;
+}
+
+function count(text: string, ch: string): number {
+  return text.split(ch).length - 1;
+}
+
+function matchingParen(text: string, open: number): number {
+  let depth = 0;
+  for (let i = open; i < text.length; i++) {
+    const ch = text[i];
+    if (ch === '"' || ch === '`' || ch === "'") {
+      const end = text.indexOf(ch, i + 1);
+      if (end < 0) return -1;
+      i = end;
+      continue;
+    }
+    if (ch === '(') depth++;
+    else if (ch === ')' && --depth === 0) return i;
+  }
+  return -1;
+}
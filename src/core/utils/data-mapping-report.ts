@@ -0,0 +1,360 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { parseGoDeclarations } from './context-selector.js';
+import { ModuleManifestStore, ModuleOutputManifest } from './module-manifest.js';
+import { detectSchemaPaths } from './sqlc-generator.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { csvCell } from './metrics-aggregator.js';
+import { splitTopLevel } from './text-utils.js';
+
+export type MappingChange = 'unchanged' | 'renamed' | 'moved' | 'dropped' | 'added' | 'unmapped';
+
+/**
+ * A Go struct field that represents a database column
+ */
+export interface OrmField {
+  table: string;
+  column: string;
+  struct: string;
+  field: string;
+  file: string;
+}
+
+export interface ColumnMapping {
+  table: string;
+  column: string;
+  old_module?: string;
+  old_struct?: string;
+  old_field?: string;
+  old_file?: string;
+  new_module?: string;
+  new_entity?: string;
+  new_field?: string;
+  new_file?: string;
+  changes: MappingChange[];
+}
+
+export interface TableOwnership {
+  table: string;
+  /** Modules whose new entities map columns of this table */
+  modules: string[];
+  split_ownership: boolean;
+}
+
+export interface DataMappingReport {
+  generated_at: string;
+  sources: {
+    schema: string[];
+    original_files: number;
+    generated_files: number;
+  };
+  tables: TableOwnership[];
+  mappings: ColumnMapping[];
+  split_ownership_risks: string[];
+}
+
+interface ModuleField extends OrmField {
+  module?: string;
+}
+
+/**
+ * DataMappingReporter - テーブル列と新エンティティの対応表
+ *
+ * Builds the column → entity mapping purely from persisted artifacts
+ * (domain map, module manifests, schema/migrations and the sources on disk),
+ * so the report can be regenerated without another LLM run.
+ */
+export class DataMappingReporter {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  build(): DataMappingReport {
+    const manifests = new ModuleManifestStore(this.projectRoot);
+    const modules = manifests.listModules()
+      .map(name => manifests.load(name))
+      .filter((m): m is ModuleOutputManifest => m !== null);
+    const backups = new Map(modules.flatMap(m => m.files.filter(f => f.backup).map(f => [path.normalize(f.path), f.backup!] as const)));
+
+    // Original sources (from a backup when the refactoring overwrote them)
+    const originals = this.loadOriginalFiles().map(({ file, module }) => ({
+      file,
+      module,
+      source: this.readSource(backups.get(path.normalize(file)) ?? file),
+    })).filter(o => o.source !== null);
+
+    const generated = modules.flatMap(manifest => manifest.files
+      .filter(entry => entry.path.endsWith('.go') && !entry.path.endsWith('_test.go'))
+      .map(entry => ({ file: entry.path, module: manifest.module, source: this.readSource(entry.path) }))
+      .filter(g => g.source !== null));
+
+    const schemaPaths = detectSchemaPaths(this.projectRoot);
+    const schemaColumns = this.loadSchemaColumns(schemaPaths);
+    const rawSqlColumns = new Map<string, Set<string>>();
+    originals.forEach(o => mergeColumns(rawSqlColumns, extractRawSqlColumns(o.source!)));
+
+    const knownTables = new Set([...schemaColumns.keys(), ...rawSqlColumns.keys()]);
+    const oldFields: ModuleField[] = originals.flatMap(o =>
+      extractOrmFields(o.source!, o.file, knownTables).map(f => ({ ...f, module: o.module })));
+    oldFields.forEach(f => knownTables.add(f.table));
+    const newFields: ModuleField[] = generated.flatMap(g =>
+      extractOrmFields(g.source!, g.file, knownTables).map(f => ({ ...f, module: g.module })));
+
+    // Column universe: schema, raw SQL and every ORM-mapped field
+    const columns = new Map<string, Set<string>>();
+    mergeColumns(columns, schemaColumns);
+    mergeColumns(columns, rawSqlColumns);
+    [...oldFields, ...newFields].forEach(f => mergeColumns(columns, new Map([[f.table, new Set([f.column])]])));
+
+    const mappings: ColumnMapping[] = [];
+    for (const table of [...columns.keys()].sort()) {
+      for (const column of [...columns.get(table)!].sort()) {
+        const oldField = oldFields.find(f => f.table === table && f.column === column);
+        const candidates = newFields.filter(f => f.table === table && f.column === column);
+        const newField = candidates.find(f => f.struct === oldField?.struct) ?? candidates[0];
+        mappings.push(buildMapping(table, column, oldField, newField));
+      }
+    }
+
+    const tables = [...columns.keys()].sort().map(table => {
+      const owners = [...new Set(newFields.filter(f => f.table === table && f.module).map(f => f.module!))].sort();
+      return { table, modules: owners, split_ownership: owners.length > 1 };
+    });
+
+    return {
+      generated_at: new Date().toISOString(),
+      sources: {
+        schema: schemaPaths,
+        original_files: originals.length,
+        generated_files: generated.length,
+      },
+      tables,
+      mappings,
+      split_ownership_risks: tables.filter(t => t.split_ownership).map(t => t.table),
+    };
+  }
+
+  /**
+   * Write data-mapping.json and data-mapping.csv under .vibeflow/reports
+   */
+  write(report: DataMappingReport): { jsonPath: string; csvPath: string } {
+    fs.mkdirSync(this.paths.reportsDir, { recursive: true });
+    const jsonPath = path.join(this.paths.reportsDir, 'data-mapping.json');
+    const csvPath = path.join(this.paths.reportsDir, 'data-mapping.csv');
+
+    this.paths.writeArtifact(jsonPath, report);
+    fs.writeFileSync(csvPath, formatDataMappingCsv(report));
+
+    return { jsonPath, csvPath };
+  }
+
+  private loadOriginalFiles(): { file: string; module?: string }[] {
+    if (!fs.existsSync(this.paths.domainMapPath)) return [];
+
+    const domainMap = JSON.parse(fs.readFileSync(this.paths.domainMapPath, 'utf8'));
+    return (domainMap.boundaries ?? []).flatMap((b: { name: string; files?: string[] }) =>
+      (b.files ?? []).filter(f => f.endsWith('.go')).map(file => ({ file, module: b.name })));
+  }
+
+  private loadSchemaColumns(schemaPaths: string[]): Map<string, Set<string>> {
+    const columns = new Map<string, Set<string>>();
+
+    for (const schemaPath of schemaPaths) {
+      const fullPath = path.join(this.projectRoot, schemaPath);
+      const files = fs.statSync(fullPath).isDirectory()
+        ? fastGlob.sync('**/*.sql', { cwd: fullPath, absolute: true }).sort()
+        : [fullPath];
+
+      files.forEach(file => mergeColumns(columns, parseSchemaColumns(fs.readFileSync(file, 'utf8'))));
+    }
+
+    return columns;
+  }
+
+  private readSource(file: string): string | null {
+    const fullPath = this.paths.resolvePortablePath(file);
+    return fs.existsSync(fullPath) ? fs.readFileSync(fullPath, 'utf8') : null;
+  }
+}
+
+/**
+ * Struct fields mapped to columns via `db`/`gorm` tags or GORM naming conventions.
+ * Untagged structs count only when their conventional table name is known.
+ */
+export function extractOrmFields(source: string, file: string, knownTables: Set<string> = new Set()): OrmField[] {
+  const fields: OrmField[] = [];
+  const tableNames = new Map<string, string>();
+
+  const tableNamePattern = /func\s*\(\s*(?:\w+\s+)?\*?\s*(\w+)\s*\)\s*TableName\s*\(\s*\)\s*string\s*\{\s*return\s+"([^"]+)"/g;
+  let match: RegExpExecArray | null;
+  while ((match = tableNamePattern.exec(source)) !== null) {
+    tableNames.set(match[1], match[2]);
+  }
+
+  for (const decl of parseGoDeclarations(source, file)) {
+    if (decl.kind !== 'type' || !/\bstruct\s*\{/.test(decl.signature + decl.body.split('\n')[0])) continue;
+
+    const structFields = decl.body.split('\n').slice(1, -1)
+      .map(line => line.match(/^\s*([A-Z]\w*)\s+[\w.*[\]]+\s*(?:`([^`]*)`)?/))
+      .filter((m): m is RegExpMatchArray => m !== null)
+      .map(m => ({ field: m[1], tags: parseTags(m[2] ?? '') }));
+
+    const tagged = structFields.some(f => f.tags.db !== undefined || f.tags.gorm !== undefined);
+    const table = tableNames.get(decl.name) ?? defaultTableName(decl.name);
+    if (!tagged && !tableNames.has(decl.name) && !knownTables.has(table)) continue;
+
+    for (const { field, tags } of structFields) {
+      const column = columnName(field, tags);
+      if (column) {
+        fields.push({ table, column, struct: decl.name, field, file });
+      }
+    }
+  }
+
+  return fields;
+}
+
+/**
+ * Columns declared by CREATE TABLE statements
+ */
+export function parseSchemaColumns(sql: string): Map<string, Set<string>> {
+  const columns = new Map<string, Set<string>>();
+  const createPattern = /CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?[`"]?(\w+)[`"]?\s*\(/gi;
+  let match: RegExpExecArray | null;
+
+  while ((match = createPattern.exec(sql)) !== null) {
+    const body = takeParenthesized(sql, match.index + match[0].length - 1);
+    const names = splitTopLevel(body)
+      .map(def => def.trim().match(/^[`"]?(\w+)[`"]?/)?.[1])
+      .filter((name): name is string => !!name && !/^(PRIMARY|KEY|CONSTRAINT|UNIQUE|INDEX|FOREIGN|CHECK)$/i.test(name));
+    mergeColumns(columns, new Map([[match[1], new Set(names)]]));
+  }
+
+  return columns;
+}
+
+/**
+ * Columns written through raw SQL (INSERT INTO t (a, b))
+ */
+export function extractRawSqlColumns(source: string): Map<string, Set<string>> {
+  const columns = new Map<string, Set<string>>();
+  const insertPattern = /INSERT\s+INTO\s+[`"]?(\w+)[`"]?\s*\(([^)]*)\)/gi;
+  let match: RegExpExecArray | null;
+
+  while ((match = insertPattern.exec(source)) !== null) {
+    const names = match[2].split(',').map(c => c.trim().replace(/[`"]/g, '')).filter(c => /^\w+$/.test(c));
+    mergeColumns(columns, new Map([[match[1], new Set(names)]]));
+  }
+
+  return columns;
+}
+
+export function formatDataMappingCsv(report: DataMappingReport): string {
+  const risky = new Set(report.split_ownership_risks);
+  const header = 'table,column,old_module,old_struct,old_field,new_module,new_entity,new_field,change,split_ownership';
+  const rows = report.mappings.map(m => [
+    m.table,
+    m.column,
+    m.old_module ?? '',
+    m.old_struct ?? '',
+    m.old_field ?? '',
+    m.new_module ?? '',
+    m.new_entity ?? '',
+    m.new_field ?? '',
+    m.changes.join('+'),
+    risky.has(m.table) ? 'yes' : 'no',
+  ].map(csvCell).join(','));
+
+  return [header, ...rows].join('\n') + '\n';
+}
+
+function buildMapping(table: string, column: string, oldField?: ModuleField, newField?: ModuleField): ColumnMapping {
+  const changes: MappingChange[] = [];
+  if (!oldField && !newField) {
+    changes.push('unmapped');
+  } else if (!newField) {
+    changes.push('dropped');
+  } else if (!oldField) {
+    changes.push('added');
+  } else {
+    if (oldField.field !== newField.field || oldField.struct !== newField.struct) changes.push('renamed');
+    if (oldField.module && newField.module && oldField.module !== newField.module) changes.push('moved');
+    if (changes.length === 0) changes.push('unchanged');
+  }
+
+  return {
+    table,
+    column,
+    old_module: oldField?.module,
+    old_struct: oldField?.struct,
+    old_field: oldField?.field,
+    old_file: oldField?.file,
+    new_module: newField?.module,
+    new_entity: newField?.struct,
+    new_field: newField?.field,
+    new_file: newField?.file,
+    changes,
+  };
+}
+
+function parseTags(raw: string): Record<string, string> {
+  const tags: Record<string, string> = {};
+  const pattern = /(\w+):"([^"]*)"/g;
+  let match: RegExpExecArray | null;
+  while ((match = pattern.exec(raw)) !== null) {
+    tags[match[1]] = match[2];
+  }
+  return tags;
+}
+
+function columnName(field: string, tags: Record<string, string>): string | null {
+  if (tags.db !== undefined) {
+    const name = tags.db.split(',')[0];
+    return name === '-' ? null : name || toSnakeCase(field);
+  }
+  if (tags.gorm !== undefined) {
+    if (tags.gorm === '-') return null;
+    const column = tags.gorm.split(';').find(part => part.startsWith('column:'));
+    return column ? column.slice('column:'.length) : toSnakeCase(field);
+  }
+  return toSnakeCase(field);
+}
+
+/**
+ * GORM (and ent) default table name: snake_case plural of the struct name
+ */
+export function defaultTableName(structName: string): string {
+  const snake = toSnakeCase(structName);
+  if (/[^aeiou]y$/.test(snake)) return snake.slice(0, -1) + 'ies';
+  if (/(s|x|ch|sh)$/.test(snake)) return snake + 'es';
+  return snake + 's';
+}
+
+function toSnakeCase(name: string): string {
+  return name
+    .replace(/([A-Z]+)([A-Z][a-z])/g, '$1_$2')
+    .replace(/([a-z\d])([A-Z])/g, '$1_$2')
+    .toLowerCase();
+}
+
+function takeParenthesized(text: string, openIndex: number): string {
+  let depth = 0;
+  for (let i = openIndex; i < text.length; i++) {
+    if (text[i] === '(') depth++;
+    else if (text[i] === ')' && --depth === 0) return text.slice(openIndex + 1, i);
+  }
+  return text.slice(openIndex + 1);
+}
+
+function mergeColumns(target: Map<string, Set<string>>, source: Map<string, Set<string>>): void {
+  for (const [table, names] of source) {
+    const set = target.get(table) ?? new Set<string>();
+    names.forEach(name => set.add(name));
+    target.set(table, set);
+  }
+}
@@ -0,0 +1,236 @@
+import { SchemaTable, TableAccess, findOrmTables, findTableAccess, loadSchemaTables, ownsTable } from './table-ownership.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * Why a table was given to its module: boundary.yaml owns_tables (or the
+ * discovered tables of the boundary), the only module writing it or mapping a
+ * struct to it, or the only module reading it
+ */
+export type TableOwnerBasis = 'configured' | 'writes' | 'reads';
+
+export interface PlannedTable {
+  table: string;
+  /** Module whose schema the table moves to; missing when no single module can own it */
+  owner?: string;
+  basis?: TableOwnerBasis;
+  /** Modules writing the table when none is configured as its owner; one has to be picked */
+  contested?: string[];
+  /** Other modules accessing it; their queries are replaced by the owner's API */
+  accessed_by: string[];
+}
+
+/**
+ * A query (or struct mapping) on a table owned by another module. Once the
+ * owner's tables move to their own schema it has to go through the owner's API.
+ */
+export interface CrossModuleQuery {
+  /** Module whose code accesses the table */
+  module: string;
+  table: string;
+  owner: string;
+  file: string;
+  /** Function holding the query, or the struct mapped to the table */
+  symbol: string;
+  via: TableAccess['via'];
+  operation?: TableAccess['operation'];
+  /** Operation the owner exposes instead: a query for reads, a command for writes and mappings */
+  replacement: 'query' | 'command';
+}
+
+/**
+ * Foreign key between tables of two modules; dropped when the tables are
+ * split, the column stays as a plain ID reference
+ */
+export interface CrossModuleForeignKey {
+  table: string;
+  owner: string;
+  references: string;
+  referenced_owner: string;
+}
+
+/**
+ * Schema migration of one module in the phase it is migrated in
+ */
+export interface SchemaMigrationStep {
+  phase: string;
+  module: string;
+  /** Tables moved to the module's own schema */
+  tables: string[];
+  /** Queries of the module on other modules' tables, switched to their APIs */
+  queries_to_replace: number;
+  /** Queries of other modules on its tables, served by its API from now on */
+  queries_to_serve: number;
+  /** Foreign keys of its tables to other modules' tables, dropped */
+  foreign_keys: string[];
+}
+
+export interface DatabaseDecomposition {
+  /** Schema and migration files (or ORM model files) the tables were read from */
+  sources: string[];
+  tables: PlannedTable[];
+  cross_module_queries: CrossModuleQuery[];
+  foreign_keys: CrossModuleForeignKey[];
+  steps: SchemaMigrationStep[];
+}
+
+export interface DatabaseModule {
+  name: string;
+  /** Files relative to the project root */
+  files: string[];
+  owned_tables?: string[];
+}
+
+/**
+ * Split the database along the modules: the tables each module owns, the
+ * queries crossing module lines that its API has to replace, the foreign keys
+ * to drop, and per phase the schema migration of each module
+ *
+ * @returns undefined when neither schema files nor ORM models declare tables
+ */
+export function planDatabaseDecomposition(
+  projectRoot: string,
+  modules: DatabaseModule[],
+  phases: { name: string; modules: string[] }[],
+  options: { schema?: string[] } = {}
+): DatabaseDecomposition | undefined {
+  const files = modules.flatMap(module => module.files.map(toPosixPath));
+  const schema = loadSchemaTables(projectRoot, options.schema);
+  const tables = schema.tables.length > 0 ? schema.tables : findOrmTables(projectRoot, files);
+  if (tables.length === 0) return undefined;
+  const sources = schema.tables.length > 0 ? schema.sources : [...new Set(tables.map(table => table.source))].sort();
+  return decomposeDatabase(sources, tables, findTableAccess(projectRoot, files, tables.map(table => table.name)), modules, phases);
+}
+
+/**
+ * Ownership, cross-module queries and migration steps from tables and their accesses
+ */
+export function decomposeDatabase(
+  sources: string[],
+  tables: SchemaTable[],
+  access: TableAccess[],
+  modules: DatabaseModule[],
+  phases: { name: string; modules: string[] }[]
+): DatabaseDecomposition {
+  const moduleOf = new Map<string, string>();
+  modules.forEach(module => module.files.forEach(file => {
+    if (!moduleOf.has(toPosixPath(file))) moduleOf.set(toPosixPath(file), module.name);
+  }));
+  const configured = new Map<string, string>();
+  modules.forEach(module => (module.owned_tables ?? []).forEach(table => {
+    if (!configured.has(table.toLowerCase())) configured.set(table.toLowerCase(), module.name);
+  }));
+
+  const planned: PlannedTable[] = [];
+  for (const table of tables) {
+    const accesses = access.filter(a => a.table === table.name && moduleOf.has(a.file));
+    const accessing = unique(accesses.map(a => moduleOf.get(a.file)!));
+    const writers = unique(accesses.filter(ownsTable).map(a => moduleOf.get(a.file)!));
+    const owner = configured.get(table.name)
+      ?? (writers.length === 1 ? writers[0] : undefined)
+      ?? (writers.length === 0 && accessing.length === 1 ? accessing[0] : undefined);
+    if (!owner && accessing.length === 0) continue;
+
+    const basis: TableOwnerBasis | undefined = !owner ? undefined
+      : configured.has(table.name) ? 'configured'
+      : writers.length === 1 ? 'writes'
+      : 'reads';
+    planned.push({
+      table: table.name,
+      ...(owner ? { owner, basis } : {}),
+      ...(!owner && writers.length > 1 ? { contested: writers } : {}),
+      accessed_by: accessing.filter(module => module !== owner),
+    });
+  }
+
+  const ownerOf = new Map(planned.flatMap(table => (table.owner ? [[table.table, table.owner] as const] : [])));
+  const queries: CrossModuleQuery[] = access.flatMap(a => {
+    const module = moduleOf.get(a.file);
+    const owner = ownerOf.get(a.table);
+    if (!module || !owner || module === owner) return [];
+    return [{
+      module,
+      table: a.table,
+      owner,
+      file: a.file,
+      symbol: a.symbol,
+      via: a.via,
+      ...(a.operation ? { operation: a.operation } : {}),
+      replacement: ownsTable(a) ? 'command' as const : 'query' as const,
+    }];
+  });
+
+  const foreignKeys: CrossModuleForeignKey[] = tables.flatMap(table => table.references.flatMap(references => {
+    const owner = ownerOf.get(table.name);
+    const referencedOwner = ownerOf.get(references);
+    return owner && referencedOwner && owner !== referencedOwner
+      ? [{ table: table.name, owner, references, referenced_owner: referencedOwner }]
+      : [];
+  }));
+
+  const steps: SchemaMigrationStep[] = phases.flatMap(phase => phase.modules.flatMap(module => {
+    const step = {
+      phase: phase.name,
+      module,
+      tables: planned.filter(table => table.owner === module).map(table => table.table),
+      queries_to_replace: queries.filter(query => query.module === module).length,
+      queries_to_serve: queries.filter(query => query.owner === module).length,
+      foreign_keys: foreignKeys.filter(fk => fk.owner === module).map(fk => `${fk.table} → ${fk.references}`),
+    };
+    return step.tables.length > 0 || step.queries_to_replace > 0 ? [step] : [];
+  }));
+
+  return { sources, tables: planned, cross_module_queries: queries, foreign_keys: foreignKeys, steps };
+}
+
+/**
+ * plan.md section: table ownership, the cross-module queries to replace and
+ * the schema migration steps of each phase
+ */
+export function renderDatabaseSection(database: DatabaseDecomposition | undefined): string {
+  if (!database) return '';
+
+  const basis: Record<TableOwnerBasis, string> = { configured: '設定', writes: '書き込み', reads: '参照のみ' };
+  const tableRows = database.tables.map(table =>
+    `| \`${table.table}\` | ${table.owner ?? (table.contested ? `未決定（書き込み: ${table.contested.join(', ')}）` : '未決定')} | ${table.basis ? basis[table.basis] : '-'} | ${table.accessed_by.length > 0 ? table.accessed_by.join(', ') : '-'} |`);
+
+  const queries = database.cross_module_queries.map(query =>
+    `- ${query.module} → \`${query.table}\` (所有: ${query.owner}) — ${query.file} \`${query.symbol}\` (${query.via === 'struct' && !query.operation ? '構造体マッピング' : query.operation})、${query.owner} の${query.replacement === 'query' ? '参照' : '更新'}APIに置き換え`);
+
+  const steps = database.steps.map(step => {
+    const lines = [
+      ...(step.queries_to_serve > 0 ? [`他モジュールから ${step.module} のテーブルへのクエリ ${step.queries_to_serve}件を ${step.module} のAPI経由に置き換え`] : []),
+      ...(step.queries_to_replace > 0 ? [`${step.module} から他モジュール所有テーブルへのクエリ ${step.queries_to_replace}件を所有モジュールのAPI経由に変更`] : []),
+      ...(step.foreign_keys.length > 0 ? [`モジュールをまたぐ外部キーを削除し、ID参照に変更: ${step.foreign_keys.join(', ')}`] : []),
+      ...(step.tables.length > 0 ? [
+        `テーブルを ${step.module} 専用スキーマへ移動するマイグレーションを追加: ${step.tables.map(table => `\`${table}\``).join(', ')}`,
+        `他モジュールのDBユーザーから ${step.module} のテーブルへの権限を外す`,
+      ] : []),
+    ];
+    return `- ${step.phase} / ${step.module}\n${lines.map((line, index) => `  ${index + 1}. ${line}`).join('\n')}`;
+  });
+
+  const contested = database.tables.filter(table => table.contested);
+  return `
+## データベース分割 (Database Decomposition)
+
+テーブルの所有モジュール、モジュールをまたぐクエリ、フェーズごとのスキーマ移行手順です（テーブル定義: ${database.sources.join(', ')}）。
+
+| テーブル | 所有モジュール | 根拠 | 他にアクセスするモジュール |
+|----------|----------------|------|----------------------------|
+${tableRows.join('\n')}
+${contested.length > 0 ? `
+⚠️ 複数モジュールが書き込むテーブルは所有モジュールを決めてください（boundary.yaml の owns_tables）: ${contested.map(table => `\`${table.table}\``).join(', ')}
+` : ''}
+### APIに置き換えるクエリ (${database.cross_module_queries.length}件)
+
+${queries.length > 0 ? queries.join('\n') : 'なし（各テーブルは所有モジュールからのみアクセスされています）'}
+
+### スキーマ移行手順
+
+${steps.length > 0 ? steps.join('\n') : 'なし'}
+`;
+}
+
+function unique(items: string[]): string[] {
+  return [...new Set(items)].sort();
+}
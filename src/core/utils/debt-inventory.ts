@@ -0,0 +1,274 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { VibeFlowPaths } from './file-paths.js';
+import { ConfigLoader } from './config-loader.js';
+import { PerformanceStore } from './performance-store.js';
+import { toPosixPath } from './workspace-paths.js';
+import { escapeRegExp } from './text-utils.js';
+import { BoundaryDebt, DomainBoundary } from '../types/config.js';
+
+export type DebtCategory = 'missing-implementation' | 'known-bug' | 'performance' | 'deprecation' | 'other';
+
+/** Where a marker was found; only `source` counts towards a module's total */
+export type DebtScope = 'source' | 'test' | 'generated' | 'vendor';
+
+export const DEFAULT_DEBT_MARKERS = ['TODO', 'FIXME', 'HACK', 'XXX'];
+
+/** Modules with at least this many source markers get a plan risk (medium / high) */
+export const DEBT_RISK_THRESHOLDS = { medium: 50, high: 200 };
+
+export interface DebtItem {
+  /** Project-relative path */
+  file: string;
+  line: number;
+  /** Comment marker, or `panic` for not-implemented stubs */
+  marker: string;
+  category: DebtCategory;
+  text: string;
+  scope: DebtScope;
+  module?: string;
+}
+
+export interface ModuleDebt extends BoundaryDebt {
+  module: string;
+}
+
+export interface DebtInventory {
+  generated_at: string;
+  markers: string[];
+  items: DebtItem[];
+  modules: ModuleDebt[];
+  totals: {
+    source: number;
+    /** Source markers outside every boundary */
+    unassigned: number;
+    test: number;
+    generated: number;
+    vendor: number;
+    by_category: Record<string, number>;
+  };
+}
+
+const CATEGORY_KEYWORDS: [DebtCategory, RegExp][] = [
+  ['missing-implementation', /not (?:yet )?implemented|unimplemented|\bimplement|\bstub\b|\bmissing\b/i],
+  ['known-bug', /\bbugs?\b|\bbroken\b|\bwrong\b|\brace\b|\bleaks?\b|\bcrash|\bincorrect|\bworkaround/i],
+  ['performance', /\bperf|\bslow|optimi[sz]|\bcach(?:e|ing)\b|n\+1|\balloc|\bfaster\b|\blatency\b/i],
+  ['deprecation', /deprecat|\bremove\b|\bobsolete\b|\blegacy\b|\bmigrate\b/i],
+];
+
+const GO_TOKEN = /\/\/[^\n]*|\/\*[\s\S]*?\*\/|"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*'|`[^`]*`/g;
+const GENERATED_HEADER = /^\/\/ Code generated .* DO NOT EDIT\.$/m;
+const STUB_MESSAGE = /not.?implemented|unimplemented|\bTODO\b/i;
+
+/**
+ * Category from the marker text, falling back to the marker itself (FIXME → known bug)
+ */
+export function classifyDebt(marker: string, text: string): DebtCategory {
+  if (marker === 'panic') return 'missing-implementation';
+  const match = CATEGORY_KEYWORDS.find(([, pattern]) => pattern.test(text));
+  if (match) return match[0];
+  return marker === 'FIXME' ? 'known-bug' : 'other';
+}
+
+export function debtScope(file: string, content: string): DebtScope {
+  if (toPosixPath(file).split('/').includes('vendor')) return 'vendor';
+  if (GENERATED_HEADER.test(content)) return 'generated';
+  return file.endsWith('_test.go') ? 'test' : 'source';
+}
+
+/**
+ * Markers in the comments of one Go file plus `panic("not implemented")` stubs.
+ * Markers are matched case-sensitively as whole words, one per comment line.
+ */
+export function findDebtMarkers(content: string, file: string, markers: string[] = DEFAULT_DEBT_MARKERS): DebtItem[] {
+  const scope = debtScope(file, content);
+  const markerPattern = new RegExp(`\\b(${markers.map(escapeRegExp).join('|')})\\b(?:\\([^)]*\\))?:?\\s*(.*)`);
+  const items: DebtItem[] = [];
+  const lineAt = lineIndex(content);
+
+  for (const token of content.matchAll(GO_TOKEN)) {
+    if (!token[0].startsWith('/')) continue;
+    token[0].split('\n').forEach((commentLine, offset) => {
+      const match = commentLine.replace(/^\s*(?:\/\/|\/\*|\*)/, '').match(markerPattern);
+      if (!match) return;
+      const text = match[2].replace(/\*\/\s*$/, '').trim();
+      items.push({ file, line: lineAt(token.index!) + offset, marker: match[1], category: classifyDebt(match[1], text), text, scope });
+    });
+  }
+
+  const code = content.replace(GO_TOKEN, token => token.startsWith('/') ? token.replace(/[^\n]/g, ' ') : token);
+  for (const stub of code.matchAll(/\bpanic\(\s*("(?:[^"\\\n]|\\.)*"|`[^`]*`)\s*\)/g)) {
+    if (!STUB_MESSAGE.test(stub[1])) continue;
+    items.push({ file, line: lineAt(stub.index!), marker: 'panic', category: 'missing-implementation', text: stub[0], scope });
+  }
+
+  return items.sort((a, b) => a.line - b.line);
+}
+
+/**
+ * Set each boundary's `debt` from the inventory (zero counts included)
+ */
+export function attachDebt(boundaries: DomainBoundary[], inventory: DebtInventory): DomainBoundary[] {
+  const byModule = new Map(inventory.modules.map(({ module, ...debt }) => [module, debt]));
+  return boundaries.map(boundary => ({ ...boundary, debt: byModule.get(boundary.name) ?? emptyDebt() }));
+}
+
+/**
+ * Source files with the most markers, for `vf discover --debt`
+ */
+export function topDebtFiles(inventory: DebtInventory, limit = 10): { file: string; module?: string; items: DebtItem[] }[] {
+  const byFile = new Map<string, DebtItem[]>();
+  for (const item of inventory.items.filter(i => i.scope === 'source')) {
+    byFile.set(item.file, [...(byFile.get(item.file) ?? []), item]);
+  }
+  return [...byFile]
+    .map(([file, items]) => ({ file, ...(items[0].module ? { module: items[0].module } : {}), items }))
+    .sort((a, b) => b.items.length - a.items.length || compare(a.file, b.file))
+    .slice(0, limit);
+}
+
+/**
+ * DebtInventoryScanner - 既知の技術的負債の棚卸し
+ *
+ * Collects TODO/FIXME/HACK comments (configurable via `debt.markers` in
+ * vibeflow.config.yaml) and not-implemented panics from every Go file,
+ * attributes them to boundaries by file or, for tests and generated code
+ * discovery leaves out, by package directory, and classifies them with
+ * keyword heuristics. Vendored, generated and test files are counted apart.
+ */
+export class DebtInventoryScanner {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+  private markers: string[];
+
+  constructor(projectRoot: string, markers?: string[]) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+    this.markers = markers ?? this.loadMarkers();
+  }
+
+  get reportPath(): string {
+    return path.join(this.paths.reportsDir, 'debt-inventory.json');
+  }
+
+  scan(boundaries: DomainBoundary[]): DebtInventory {
+    const fileOwners = new Map<string, string>();
+    const dirOwners = new Map<string, string>();
+    for (const boundary of boundaries) {
+      for (const file of boundary.files) {
+        const relative = this.relative(file);
+        if (!fileOwners.has(relative)) fileOwners.set(relative, boundary.name);
+        if (!dirOwners.has(path.posix.dirname(relative))) dirOwners.set(path.posix.dirname(relative), boundary.name);
+      }
+    }
+
+    const files = fastGlob.sync('**/*.go', {
+      cwd: this.projectRoot,
+      ignore: ['**/node_modules/**', '.vibeflow/**', '.git/**'],
+    }).sort();
+
+    const items = files.flatMap(file => {
+      const content = fs.readFileSync(path.join(this.projectRoot, file), 'utf8');
+      return findDebtMarkers(content, file, this.markers).map(item => {
+        const module = item.scope === 'vendor' ? undefined : fileOwners.get(file) ?? dirOwners.get(path.posix.dirname(file));
+        return module ? { ...item, module } : item;
+      });
+    });
+
+    const modules = boundaries.map(boundary => {
+      const debt = emptyDebt();
+      for (const item of items.filter(i => i.module === boundary.name)) {
+        if (item.scope === 'source') {
+          debt.total++;
+          debt.by_category[item.category] = (debt.by_category[item.category] ?? 0) + 1;
+        } else if (item.scope === 'test' || item.scope === 'generated') {
+          debt[item.scope]++;
+        }
+      }
+      return { module: boundary.name, ...debt };
+    });
+
+    const source = items.filter(i => i.scope === 'source');
+    const count = (scope: DebtScope) => items.filter(i => i.scope === scope).length;
+    return {
+      generated_at: new Date().toISOString(),
+      markers: this.markers,
+      items,
+      modules,
+      totals: {
+        source: source.length,
+        unassigned: source.filter(i => !i.module).length,
+        test: count('test'),
+        generated: count('generated'),
+        vendor: count('vendor'),
+        by_category: source.reduce<Record<string, number>>((acc, i) => ({ ...acc, [i.category]: (acc[i.category] ?? 0) + 1 }), {}),
+      },
+    };
+  }
+
+  write(inventory: DebtInventory): string {
+    this.paths.writeArtifact(this.reportPath, inventory);
+    return this.reportPath;
+  }
+
+  /**
+   * Record per-module marker counts in performance_metrics of the given (or active, or latest) run
+   */
+  recordMetrics(inventory: DebtInventory, runId?: number): number | undefined {
+    const store = new PerformanceStore(this.projectRoot);
+    const target = runId ?? store.getActiveRunId() ?? store.getRuns().at(-1)?.run_id;
+    if (target === undefined) return undefined;
+
+    for (const module of inventory.modules) {
+      store.recordMetric(target, 'debt_markers', module.total, { module: module.module });
+      for (const [category, value] of Object.entries(module.by_category)) {
+        store.recordMetric(target, 'debt_markers_by_category', value, { module: module.module, category });
+      }
+    }
+    for (const scope of ['test', 'generated', 'vendor'] as const) {
+      store.recordMetric(target, 'debt_markers_excluded', inventory.totals[scope], { scope });
+    }
+    store.recordMetric(target, 'debt_markers_total', inventory.totals.source);
+    return target;
+  }
+
+  private loadMarkers(): string[] {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return config.debt?.markers ?? DEFAULT_DEBT_MARKERS;
+    } catch {
+      return DEFAULT_DEBT_MARKERS;
+    }
+  }
+
+  private relative(file: string): string {
+    return toPosixPath(path.isAbsolute(file) ? path.relative(this.projectRoot, file) : path.normalize(file));
+  }
+}
+
+function emptyDebt(): BoundaryDebt {
+  return { total: 0, by_category: {}, test: 0, generated: 0 };
+}
+
+/**
+ * 1-based line number of a character offset
+ */
+function lineIndex(content: string): (offset: number) => number {
+  const starts = [0];
+  for (let i = content.indexOf('\n'); i >= 0; i = content.indexOf('\n', i + 1)) starts.push(i + 1);
+  return offset => {
+    let low = 0;
+    let high = starts.length - 1;
+    while (low < high) {
+      const mid = (low + high + 1) >> 1;
+      if (starts[mid] <= offset) low = mid;
+      else high = mid - 1;
+    }
+    return low + 1;
+  };
+}
+
+function compare(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
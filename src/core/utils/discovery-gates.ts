@@ -0,0 +1,95 @@
+import { DiscoveryGates, DomainMap } from '../types/config.js';
+import { compareCodeUnits } from './domain-map-writer.js';
+
+export type DiscoveryGate = 'min-cohesion' | 'max-coupling' | 'max-files-per-module';
+
+export interface GateViolation {
+  gate: DiscoveryGate;
+  module: string;
+  /** Stable boundary ID from domain-map.json */
+  module_id?: string;
+  actual: number;
+  threshold: number;
+}
+
+/**
+ * `vf discover` as an architecture health check: the thresholds and the modules breaking them
+ */
+export interface DiscoveryGateReport {
+  generated_at: string;
+  gates: DiscoveryGates;
+  /** Modules checked (established modules are not) */
+  modules: number;
+  passed: boolean;
+  violations: GateViolation[];
+  /** Cohesion/coupling gates not evaluated for a module: it has no measured score */
+  unmeasured: { gate: DiscoveryGate; module: string }[];
+}
+
+/**
+ * Modules of the domain map breaking the configured thresholds. Established
+ * modules are left out (they are never re-clustered). Cohesion/coupling gates
+ * skip modules without measured scores, including those whose scores only
+ * stand in from the discovery confidence, and list them as unmeasured.
+ */
+export function checkDiscoveryGates(map: DomainMap, gates: DiscoveryGates): DiscoveryGateReport {
+  const modules = map.boundaries.filter(boundary => boundary.status !== 'established');
+  const violations: GateViolation[] = [];
+  const unmeasured: DiscoveryGateReport['unmeasured'] = [];
+
+  for (const boundary of modules) {
+    const violation = (gate: DiscoveryGate, actual: number, threshold: number) =>
+      violations.push({ gate, module: boundary.name, ...(boundary.id ? { module_id: boundary.id } : {}), actual, threshold });
+    const cohesion = boundary.metrics?.cohesion ?? (boundary.scores_estimated ? undefined : boundary.cohesion_score);
+    const coupling = boundary.metrics?.coupling ?? (boundary.scores_estimated ? undefined : boundary.coupling_score);
+
+    if (gates.minCohesion !== undefined) {
+      if (cohesion === undefined) unmeasured.push({ gate: 'min-cohesion', module: boundary.name });
+      else if (cohesion < gates.minCohesion) violation('min-cohesion', cohesion, gates.minCohesion);
+    }
+    if (gates.maxCoupling !== undefined) {
+      if (coupling === undefined) unmeasured.push({ gate: 'max-coupling', module: boundary.name });
+      else if (coupling > gates.maxCoupling) violation('max-coupling', coupling, gates.maxCoupling);
+    }
+    if (gates.maxFilesPerModule !== undefined && boundary.files.length > gates.maxFilesPerModule) {
+      violation('max-files-per-module', boundary.files.length, gates.maxFilesPerModule);
+    }
+  }
+
+  const order = (a: { module: string; gate: string }, b: { module: string; gate: string }) =>
+    compareCodeUnits(a.module, b.module) || compareCodeUnits(a.gate, b.gate);
+  return {
+    generated_at: new Date().toISOString(),
+    gates,
+    modules: modules.length,
+    passed: violations.length === 0,
+    violations: violations.sort(order),
+    unmeasured: unmeasured.sort(order),
+  };
+}
+
+/**
+ * One line per violation, for the discover summary
+ */
+export function formatGateViolations(report: DiscoveryGateReport): string[] {
+  const labels: Record<DiscoveryGate, (v: GateViolation) => string> = {
+    'min-cohesion': v => `凝集度 ${v.actual.toFixed(2)} < ${v.threshold}`,
+    'max-coupling': v => `結合度 ${v.actual.toFixed(2)} > ${v.threshold}`,
+    'max-files-per-module': v => `ファイル数 ${v.actual} > ${v.threshold}`,
+  };
+  return report.violations.map(v => `[${v.gate}] ${v.module}: ${labels[v.gate](v)}`);
+}
+
+/**
+ * Note for the discover summary on the modules a score gate could not check
+ */
+export function formatUnmeasuredGates(report: DiscoveryGateReport): string[] {
+  return (['min-cohesion', 'max-coupling'] as const).flatMap(gate => {
+    const modules = report.unmeasured.filter(entry => entry.gate === gate).map(entry => entry.module);
+    return modules.length > 0 ? [`[${gate}] 計測値がないため未評価: ${modules.join(', ')}`] : [];
+  });
+}
+
+export function hasDiscoveryGates(gates: DiscoveryGates | undefined): gates is DiscoveryGates {
+  return gates !== undefined && Object.values(gates).some(value => value !== undefined);
+}
@@ -0,0 +1,226 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { GoPackage, goImportNames } from './go-packages.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { toPosixPath } from './workspace-paths.js';
+import { round } from './number-utils.js';
+import { DomainMapSampling } from '../types/config.js';
+
+/** z-score of the reported error band (95% interval) */
+const CONFIDENCE_Z = 1.96;
+
+/** Packages listed as the fuzziest part of the picture */
+const LOW_COVERAGE_LIMIT = 5;
+
+export interface SamplingOptions {
+  /** Fraction of files to analyze (0 < rate <= 1) */
+  rate?: number;
+  /** Upper bound on the number of analyzed files */
+  max_files?: number;
+}
+
+export interface PackageCoverage {
+  /** Package directory relative to the project root */
+  package: string;
+  analyzed: number;
+  total: number;
+  coverage: number;
+}
+
+export interface SampleSelection {
+  /** Selected files relative to the project root */
+  files: string[];
+  total: number;
+  skipped: number;
+  /** Coverage of every package, lowest first */
+  coverage: PackageCoverage[];
+}
+
+interface Candidate {
+  file: string;
+  size: number;
+  /** References from other packages to the exported declarations of the file */
+  references: number;
+}
+
+/**
+ * Parse `--sample`: `20%`, `20` or `0.2`
+ */
+export function parseSampleRate(value: string): number {
+  const trimmed = value.trim();
+  const percent = trimmed.endsWith('%');
+  const number = Number(percent ? trimmed.slice(0, -1) : trimmed);
+  const rate = percent || number > 1 ? number / 100 : number;
+  if (trimmed === '' || !Number.isFinite(rate) || rate <= 0 || rate > 1) {
+    throw new Error(`Invalid sample rate '${value}' (expected a percentage such as 20% or a fraction such as 0.2)`);
+  }
+  return rate;
+}
+
+export function sampleSize(total: number, options: SamplingOptions): number {
+  let size = total;
+  if (options.rate !== undefined) size = Math.ceil(total * options.rate);
+  if (options.max_files !== undefined) size = Math.min(size, options.max_files);
+  return Math.min(total, Math.max(size, Math.min(1, total)));
+}
+
+/**
+ * Representative subset of `files` (relative to the project root).
+ * Every directory first contributes its largest file and the file whose exported
+ * declarations other packages reference most; the remaining budget is spread over
+ * the directories in proportion to their size. The order behind the selection
+ * only depends on the files, so a higher rate selects a superset of a lower one
+ * and ramping up reuses the analysis cache.
+ */
+export function selectSample(
+  projectRoot: string,
+  files: string[],
+  options: SamplingOptions,
+  packages: GoPackage[] = []
+): SampleSelection {
+  const sources = new Map<string, string>();
+  for (const file of files.map(toPosixPath)) {
+    try {
+      sources.set(file, fs.readFileSync(path.join(projectRoot, file), 'utf8'));
+    } catch {
+      sources.set(file, '');
+    }
+  }
+
+  const references = exportReferences(sources, packages);
+  const byDir = new Map<string, Candidate[]>();
+  for (const [file, content] of sources) {
+    const dir = path.posix.dirname(file);
+    byDir.set(dir, [...(byDir.get(dir) ?? []), { file, size: content.length, references: references.get(file) ?? 0 }]);
+  }
+
+  const anchors: Candidate[] = [];
+  const rest: { file: string; position: number }[] = [];
+  for (const candidates of byDir.values()) {
+    const largest = [...candidates].sort((a, b) => b.size - a.size || compareHash(a.file, b.file))[0];
+    const mostImported = [...candidates].sort((a, b) => b.references - a.references || compareHash(a.file, b.file))[0];
+    const chosen = new Set([largest, ...(mostImported.references > 0 ? [mostImported] : [])]);
+    anchors.push(...chosen);
+
+    // Systematic stratified order: the k-th file of every directory comes before the (k+1)-th
+    const remaining = candidates.filter(c => !chosen.has(c)).sort((a, b) => compareHash(a.file, b.file));
+    remaining.forEach((c, i) => rest.push({ file: c.file, position: (i + 0.5) / remaining.length }));
+  }
+  anchors.sort((a, b) => b.references - a.references || b.size - a.size || compareHash(a.file, b.file));
+  rest.sort((a, b) => a.position - b.position || compareHash(a.file, b.file));
+
+  const order = [...anchors.map(a => a.file), ...rest.map(r => r.file)];
+  const selected = order.slice(0, sampleSize(order.length, options)).sort();
+  const picked = new Set(selected);
+
+  const coverage = [...byDir].map(([dir, candidates]) => {
+    const analyzed = candidates.filter(c => picked.has(c.file)).length;
+    return { package: dir, analyzed, total: candidates.length, coverage: round(analyzed / candidates.length, 4) };
+  }).sort((a, b) => a.coverage - b.coverage || b.total - a.total || a.package.localeCompare(b.package));
+
+  return { files: selected, total: order.length, skipped: order.length - selected.length, coverage };
+}
+
+/**
+ * Half-width of the 95% interval of a 0-1 score estimated from `analyzed` of
+ * `total` files (normal approximation with finite population correction).
+ * The score is clamped to [0.05, 0.95] so a degenerate sample does not claim certainty.
+ */
+export function errorBand(score: number, analyzed: number, total: number): number {
+  if (analyzed >= total) return 0;
+  if (analyzed === 0) return 1;
+  const p = Math.min(0.95, Math.max(0.05, score));
+  const correction = (total - analyzed) / Math.max(1, total - 1);
+  return round(CONFIDENCE_Z * Math.sqrt((p * (1 - p) / analyzed) * correction), 4);
+}
+
+/**
+ * domain-map.json `sampling` entry, or undefined when every file was analyzed
+ */
+export function describeSample(
+  selection: SampleSelection,
+  options: SamplingOptions,
+  metrics: { overall_cohesion: number; overall_coupling: number }
+): DomainMapSampling | undefined {
+  if (selection.skipped === 0) return undefined;
+
+  const analyzed = selection.files.length;
+  return {
+    ...(options.rate !== undefined ? { rate: options.rate } : {}),
+    ...(options.max_files !== undefined ? { max_files: options.max_files } : {}),
+    analyzed_files: analyzed,
+    total_files: selection.total,
+    skipped_files: selection.skipped,
+    error_band: {
+      cohesion: errorBand(metrics.overall_cohesion, analyzed, selection.total),
+      coupling: errorBand(metrics.overall_coupling, analyzed, selection.total),
+    },
+    low_coverage_packages: selection.coverage.filter(c => c.coverage < 1).slice(0, LOW_COVERAGE_LIMIT),
+  };
+}
+
+export function formatSampling(sampling: DomainMapSampling): string {
+  const parameters = [
+    ...(sampling.rate !== undefined ? [`--sample ${Math.round(sampling.rate * 100)}%`] : []),
+    ...(sampling.max_files !== undefined ? [`--max-files ${sampling.max_files}`] : []),
+  ];
+  return `${sampling.analyzed_files}/${sampling.total_files} files (${parameters.join(', ')})`;
+}
+
+/**
+ * Why the current plan must not drive refactoring, or null: plans generated from
+ * a sampled domain map are exploratory
+ */
+export function exploratoryPlanReason(projectRoot: string): string | null {
+  const paths = new VibeFlowPaths(projectRoot);
+  const sampling = readSampling(paths.planJsonPath) ?? readSampling(paths.domainMapPath);
+  if (!sampling) return null;
+
+  return `The plan is exploratory: it was generated from a sampled domain map (${formatSampling(sampling)}). ` +
+    'Run "vf discover" without --sample/--max-files (or with --sample 100%) and "vf plan" again before refactoring.';
+}
+
+function readSampling(filePath: string): DomainMapSampling | null {
+  try {
+    const artifact = JSON.parse(fs.readFileSync(filePath, 'utf8'));
+    return artifact?.sampling && typeof artifact.sampling === 'object' ? artifact.sampling : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * References per file from other packages to its exported top-level
+ * declarations (`order.Place` counts for the file declaring `func Place`)
+ */
+function exportReferences(sources: Map<string, string>, packages: GoPackage[]): Map<string, number> {
+  const byDir = new Map(packages.map(pkg => [pkg.dir, pkg]));
+  const declaredIn = new Map<string, string>();
+  for (const [file, content] of sources) {
+    const pkg = byDir.get(path.posix.dirname(file));
+    if (!pkg) continue;
+    for (const match of content.matchAll(/^(?:func|type|var|const)\s+([A-Z]\w*)/gm)) {
+      declaredIn.set(`${pkg.import_path}.${match[1]}`, file);
+    }
+  }
+
+  const counts = new Map<string, number>();
+  for (const content of sources.values()) {
+    for (const [name, importPath] of goImportNames(content, packages)) {
+      for (const match of content.matchAll(new RegExp(`(?<![\\w.])${name}\\.([A-Z]\\w*)`, 'g'))) {
+        const file = declaredIn.get(`${importPath}.${match[1]}`);
+        if (file) counts.set(file, (counts.get(file) ?? 0) + 1);
+      }
+    }
+  }
+  return counts;
+}
+
+function compareHash(a: string, b: string): number {
+  return hash(a).localeCompare(hash(b)) || a.localeCompare(b);
+}
+
+function hash(file: string): string {
+  return createHash('sha256').update(file).digest('hex');
+}
@@ -0,0 +1,150 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { ConfigLoader } from './config-loader.js';
+import { goImports } from './go-load-check.js';
+import { loadGoPackages } from './go-packages.js';
+import { detectGoProject } from './go-project-utils.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * A reference from a scope to a package of the repository that it does not own:
+ * another scope's package, or one outside every scope
+ */
+export interface ScopeEdge {
+  from_scope: string;
+  /** Scope of the imported package; null when it lies outside every scope */
+  to_scope: string | null;
+  import_path: string;
+  /** Importing files relative to the project root */
+  files: string[];
+}
+
+export interface ScopeSummary {
+  scope: string;
+  /** Domain map of the scope relative to the project root */
+  domain_map: string;
+  boundaries: number;
+  files: number;
+  external_edges: number;
+}
+
+/** .vibeflow/scope-report.json: scopes discovered together and what crosses them */
+export interface ScopeReport {
+  generated_at: string;
+  scopes: ScopeSummary[];
+  edges: ScopeEdge[];
+}
+
+/**
+ * Workspace-relative, slash-separated form of a scope directory
+ *
+ * @throws Error when the directory does not exist or lies outside the project
+ */
+export function normalizeScope(projectRoot: string, scope: string): string {
+  const relative = toPosixPath(path.relative(projectRoot, path.resolve(projectRoot, scope)));
+  if (relative === '' || relative === '.' || relative.startsWith('..') || path.isAbsolute(relative)) {
+    throw new Error(`Scope '${scope}' must be a directory inside ${projectRoot}`);
+  }
+  if (!fs.existsSync(path.join(projectRoot, relative)) || !fs.statSync(path.join(projectRoot, relative)).isDirectory()) {
+    throw new Error(`Scope directory not found: ${relative}`);
+  }
+  return relative;
+}
+
+/**
+ * `scopes:` of vibeflow.config.yaml
+ */
+export function configuredScopes(projectRoot: string): string[] {
+  const configPath = path.join(projectRoot, 'vibeflow.config.yaml');
+  if (!fs.existsSync(configPath)) return [];
+  return ConfigLoader.loadVibeFlowConfig(configPath).scopes ?? [];
+}
+
+/**
+ * Scopes `vf discover` analyzes: those given with --scope, else the configured ones.
+ * Nested scopes are rejected since their files would be analyzed twice.
+ */
+export function discoveryScopes(projectRoot: string, requested: string[] = []): string[] {
+  const scopes = [...new Set((requested.length > 0 ? requested : configuredScopes(projectRoot)).map(s => normalizeScope(projectRoot, s)))];
+  for (const scope of scopes) {
+    const parent = scopes.find(other => other !== scope && scope.startsWith(`${other}/`));
+    if (parent) throw new Error(`Scope ${scope} is nested in scope ${parent}`);
+  }
+  return scopes;
+}
+
+/**
+ * Root a scoped command works on: the directory of `scope`, the only configured
+ * scope, or the project itself when no scopes are configured
+ *
+ * @throws Error when several scopes are configured and none is selected
+ */
+export function resolveScopeRoot(projectRoot: string, scope?: string): string {
+  if (scope !== undefined) return path.join(projectRoot, normalizeScope(projectRoot, scope));
+
+  const scopes = configuredScopes(projectRoot);
+  if (scopes.length === 0) return projectRoot;
+  if (scopes.length === 1) return path.join(projectRoot, normalizeScope(projectRoot, scopes[0]));
+  throw new Error(`Several scopes are configured (${scopes.join(', ')}); choose one with --scope`);
+}
+
+/**
+ * Scope containing `file` (relative to the project root), or null
+ */
+export function scopeOf(file: string, scopes: string[]): string | null {
+  const posix = toPosixPath(file);
+  return scopes.find(scope => posix === scope || posix.startsWith(`${scope}/`)) ?? null;
+}
+
+/**
+ * Imports of each scope's Go files that resolve to packages of the repository
+ * outside the scope. A scope with its own go.mod is resolved against it,
+ * otherwise against the project's module. Standard library and third-party
+ * imports are not edges.
+ */
+export function findScopeEdges(projectRoot: string, scopes: string[]): ScopeEdge[] {
+  const rootProject = detectGoProject(projectRoot);
+  const owners = new Map<string, string | null>();
+  for (const pkg of loadGoPackages(projectRoot, rootProject)) {
+    if (scopeOf(pkg.dir, scopes) === null) owners.set(pkg.import_path, null);
+  }
+
+  const scopeFiles: { scope: string; file: string }[] = [];
+  for (const scope of scopes) {
+    const scopeRoot = path.join(projectRoot, scope);
+    const own = detectGoProject(scopeRoot);
+    for (const pkg of loadGoPackages(scopeRoot, own.hasGoProject ? own : rootProject)) {
+      owners.set(pkg.import_path, scope);
+      scopeFiles.push(...pkg.files.map(file => ({ scope, file: path.posix.join(scope, file) })));
+    }
+  }
+
+  const edges = new Map<string, ScopeEdge>();
+  for (const { scope, file } of scopeFiles) {
+    let content: string;
+    try {
+      content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+    } catch {
+      continue;
+    }
+    for (const imported of goImports(content)) {
+      const owner = owners.get(imported.path);
+      if (owner === undefined || owner === scope) continue;
+
+      const key = `${scope}\0${owner ?? ''}\0${imported.path}`;
+      const edge = edges.get(key) ?? { from_scope: scope, to_scope: owner, import_path: imported.path, files: [] };
+      if (!edge.files.includes(file)) edge.files.push(file);
+      edges.set(key, edge);
+    }
+  }
+
+  // Edges to packages outside every scope come after those to other scopes
+  const target = (edge: ScopeEdge) => (edge.to_scope === null ? 1 : 0);
+  return [...edges.values()]
+    .map(edge => ({ ...edge, files: edge.files.sort() }))
+    .sort((a, b) =>
+      a.from_scope.localeCompare(b.from_scope) ||
+      target(a) - target(b) ||
+      (a.to_scope ?? '').localeCompare(b.to_scope ?? '') ||
+      a.import_path.localeCompare(b.import_path));
+}
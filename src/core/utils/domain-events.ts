@@ -0,0 +1,334 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { BoundaryCycle } from '../types/config.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { maskLiterals } from './api-surface.js';
+import { detectGoProject, goPackageImportPath } from './go-project-utils.js';
+import { splitTopLevel } from './text-utils.js';
+
+/** Package of the in-process event bus shared by every module */
+export const EVENT_BUS_PACKAGE = 'internal/eventbus';
+
+export interface EventField {
+  name: string;
+  /** Go type in the events package; any when the parameter's type lives in the consumer */
+  type: string;
+  /** Parameter type of the consumer when it was replaced by any */
+  original?: string;
+}
+
+export interface EventConsumer {
+  module: string;
+  /** Function called today, which becomes the event handler */
+  handler: string;
+  file: string;
+}
+
+/**
+ * Event replacing the calls of a cut cycle edge: the producer publishes it
+ * where it called the consumer, and the consumer runs the former callee when
+ * it receives it
+ */
+export interface DomainEvent {
+  name: string;
+  producer: string;
+  /** Function of the producer publishing the event instead of calling */
+  publisher: { function: string; file: string };
+  /** Parameters of the handlers, context.Context left out */
+  payload: EventField[];
+  consumers: EventConsumer[];
+  /** Events package of the producer, relative to the project root */
+  package: string;
+  import_path: string | null;
+}
+
+export interface DomainEventPlan {
+  bus: { package: string; import_path: string | null };
+  events: DomainEvent[];
+}
+
+const IRREGULAR_PAST: Record<string, string> = {
+  Build: 'Built', Buy: 'Bought', Make: 'Made', Pay: 'Paid', Put: 'Put', Send: 'Sent', Sell: 'Sold', Set: 'Set', Write: 'Written',
+};
+
+const INITIALISMS = new Set(['id', 'ip', 'url', 'uuid', 'sku', 'api']);
+
+/**
+ * Domain events breaking the cycles whose cut edge calls commands (remedy
+ * event): one event per calling function of the producer, named after what it
+ * did (PlaceOrder → OrderPlaced), with the callees' parameters as payload and
+ * their modules as consumers. Cycles sharing a cut edge get the same events.
+ */
+export function planDomainEvents(projectRoot: string, cycles: BoundaryCycle[]): DomainEventPlan | undefined {
+  const goProject = detectGoProject(projectRoot);
+  const sources = new Map<string, string>();
+  const read = (file: string) => {
+    if (!sources.has(file)) {
+      const full = path.join(projectRoot, file);
+      sources.set(file, fs.existsSync(full) ? maskLiterals(fs.readFileSync(full, 'utf8')) : '');
+    }
+    return sources.get(file)!;
+  };
+
+  const events = new Map<string, DomainEvent>();
+  const cut = new Set<string>();
+  for (const cycle of cycles.filter(c => c.break_at.remedy === 'event')) {
+    const { from, to } = cycle.break_at;
+    if (cut.has(`${from}\n${to}`)) continue;
+    cut.add(`${from}\n${to}`);
+
+    for (const site of cycle.edges.find(edge => edge.from === from && edge.to === to)?.sites ?? []) {
+      const key = `${from}\n${site.caller_file}\n${site.caller}`;
+      const dir = `internal/${packageName(from)}/events`;
+      const event = events.get(key) ?? {
+        name: '',
+        producer: from,
+        publisher: { function: site.caller, file: site.caller_file },
+        payload: [],
+        consumers: [],
+        package: dir,
+        import_path: goPackageImportPath(projectRoot, dir, goProject),
+      };
+      if (!event.consumers.some(c => c.handler === site.callee && c.file === site.callee_file)) {
+        event.consumers.push({ module: to, handler: site.callee, file: site.callee_file });
+        for (const field of handlerParameters(read(site.callee_file), site.callee.split('.').pop()!)) {
+          if (!event.payload.some(existing => existing.name === field.name)) event.payload.push(field);
+        }
+      }
+      events.set(key, event);
+    }
+  }
+  if (events.size === 0) return undefined;
+
+  const taken = new Set<string>();
+  const planned = [...events.values()].map(event => {
+    let name = eventName(event.publisher.function.split('.').pop()!, event.producer);
+    for (let i = 2; taken.has(`${event.producer}.${name}`); i++) name = `${name.replace(/\d+$/, '')}${i}`;
+    taken.add(`${event.producer}.${name}`);
+    return { ...event, name };
+  });
+  return {
+    bus: { package: EVENT_BUS_PACKAGE, import_path: goPackageImportPath(projectRoot, EVENT_BUS_PACKAGE, goProject) },
+    events: planned,
+  };
+}
+
+/**
+ * Past-tense event name of what a function did: PlaceOrder → OrderPlaced,
+ * Cancel (of module order) → OrderCancelled
+ */
+export function eventName(functionName: string, module: string): string {
+  const words = capitalize(functionName).match(/[A-Z]+(?![a-z])|[A-Z][a-z0-9]*/g) ?? [capitalize(functionName)];
+  const [verb, ...object] = words;
+  return `${object.length > 0 ? object.join('') : pascalCase(module)}${pastTense(verb)}`;
+}
+
+function pastTense(verb: string): string {
+  if (IRREGULAR_PAST[verb]) return IRREGULAR_PAST[verb];
+  if (/e$/.test(verb)) return `${verb}d`;
+  if (/[^aeiou]y$/.test(verb)) return `${verb.slice(0, -1)}ied`;
+  if (/^[A-Z][a-z]*[^aeiouwxy][aeiou][lp]$/.test(verb) && verb.length <= 6) return `${verb}${verb.slice(-1)}ed`;
+  return `${verb}ed`;
+}
+
+/**
+ * Parameters of a function or method declared in a Go file, as event fields
+ */
+function handlerParameters(code: string, name: string): EventField[] {
+  const declaration = code.match(new RegExp(`^func\\s+(?:\\([^)]*\\)\\s*)?${name}\\s*(?:\\[[^\\]]*\\])?\\(([^)]*)\\)`, 'm'));
+  if (!declaration) return [];
+
+  const fields = splitTopLevel(declaration[1]).map(part => part.trim()).filter(Boolean).map(part => {
+    const named = part.match(/^(\w+)\s+(.+)$/);
+    return named ? { name: named[1], type: named[2].trim() } : { name: part };
+  });
+  // Only types when no parameter is named, func(string, int); otherwise a, b string share the type after them
+  const unnamed = fields.every(field => field.type === undefined);
+  const params: { name: string; type: string }[] = [];
+  let type = '';
+  for (let i = fields.length - 1; i >= 0; i--) {
+    type = fields[i].type ?? type;
+    params.unshift(unnamed ? { name: `Arg${i + 1}`, type: fields[i].name } : { name: fields[i].name, type });
+  }
+
+  return params
+    .filter(param => param.type !== 'context.Context')
+    .map(param => {
+      const fieldType = param.type.replace(/^\.\.\./, '[]');
+      return {
+        name: fieldName(param.name),
+        ...(portableType(fieldType) ? { type: fieldType } : { type: 'any', original: param.type }),
+      };
+    });
+}
+
+/**
+ * Types the events package can declare without importing the consumer: builtins and time
+ */
+function portableType(type: string): boolean {
+  return type.split(/[^\w.]+/).filter(Boolean).every(ident =>
+    /^(?:bool|byte|rune|string|error|any|u?int(?:8|16|32|64)?|float(?:32|64)|map|interface|struct|time\.(?:Time|Duration))$/.test(ident));
+}
+
+function fieldName(param: string): string {
+  if (INITIALISMS.has(param.toLowerCase())) return param.toUpperCase();
+  return capitalize(param.replace(/Id$/, 'ID'));
+}
+
+/**
+ * events.go of a producer: one struct per event with its name constant
+ */
+export function renderEventsFile(producer: string, events: DomainEvent[]): string {
+  const imports = events.some(event => event.payload.some(field => field.type.includes('time.'))) ? '\nimport "time"\n' : '';
+  const declarations = events.map(event => {
+    const width = Math.max(0, ...event.payload.map(field => field.name.length));
+    const fields = event.payload.map(field =>
+      `\t${field.name.padEnd(width)} ${field.type}${field.original ? ` // TODO(vibeflow): was ${field.original}; use a type of ${producer} or an ID` : ''}`);
+    return `// ${event.name}Name identifies ${event.name} on the event bus.
+const ${event.name}Name = "${producer}.${event.name}"
+
+// ${event.name} is published by ${event.publisher.function} (${event.publisher.file}).
+// Subscribers: ${event.consumers.map(consumer => `${consumer.module} (${consumer.handler})`).join(', ')}.
+type ${event.name} struct {
+${fields.join('\n')}
+}
+
+// EventName implements eventbus.Event.
+func (${event.name}) EventName() string { return ${event.name}Name }`;
+  });
+
+  return `// Code generated by vibeflow from the domain events in plan.json. DO NOT EDIT.
+
+// Package events holds the domain events the ${producer} module publishes.
+// Subscribers depend on this package only, never on the ${producer} module itself.
+package events
+${imports}
+${declarations.join('\n\n')}
+`;
+}
+
+/**
+ * The in-process event bus: synchronous delivery to the handlers subscribed to an event name
+ */
+export function renderEventBusFile(): string {
+  return `// Code generated by vibeflow. DO NOT EDIT.
+
+// Package eventbus delivers domain events in-process, so publishers do not
+// depend on their subscribers. The composition root creates one Bus, passes it
+// to the publishers and subscribes the handlers of each module.
+package eventbus
+
+import (
+\t"context"
+\t"sync"
+)
+
+// Event is a domain event identified by its name.
+type Event interface {
+\tEventName() string
+}
+
+// Handler reacts to an event; an error stops the delivery and is returned to the publisher.
+type Handler func(ctx context.Context, event Event) error
+
+// Bus dispatches each event synchronously to the handlers subscribed to its name.
+type Bus struct {
+\tmu       sync.RWMutex
+\thandlers map[string][]Handler
+}
+
+// New returns a bus without subscribers.
+func New() *Bus {
+\treturn &Bus{handlers: map[string][]Handler{}}
+}
+
+// Subscribe registers a handler for the events named name.
+func (b *Bus) Subscribe(name string, handler Handler) {
+\tb.mu.Lock()
+\tdefer b.mu.Unlock()
+\tb.handlers[name] = append(b.handlers[name], handler)
+}
+
+// Publish delivers the event to its handlers in subscription order.
+func (b *Bus) Publish(ctx context.Context, event Event) error {
+\tb.mu.RLock()
+\thandlers := append([]Handler(nil), b.handlers[event.EventName()]...)
+\tb.mu.RUnlock()
+\tfor _, handler := range handlers {
+\t\tif err := handler(ctx, event); err != nil {
+\t\t\treturn err
+\t\t}
+\t}
+\treturn nil
+}
+`;
+}
+
+/**
+ * Domain events recorded in .vibeflow/plan.json (undefined without a plan or events)
+ */
+export function loadDomainEvents(projectRoot: string): DomainEventPlan | undefined {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    return plan.domain_events ?? undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+/**
+ * Refactor prompt section: calls of the file to replace with publishing, and handlers to subscribe
+ *
+ * @param file path relative to the project root
+ */
+export function renderEventsPromptSection(module: string, plan: DomainEventPlan | undefined, file: string): string {
+  if (!plan) return '';
+  const published = plan.events.filter(event => event.producer === module && event.publisher.file === file);
+  const handled = plan.events.flatMap(event => event.consumers
+    .filter(consumer => consumer.module === module && consumer.file === file)
+    .map(consumer => ({ event, consumer })));
+  if (published.length === 0 && handled.length === 0) return '';
+
+  const bus = plan.bus.import_path ? `\`eventbus "${plan.bus.import_path}"\`` : `the ${plan.bus.package} package`;
+  const eventsImport = (event: DomainEvent) => (event.import_path ? `\`"${event.import_path}"\`` : event.package);
+  const lines = [
+    ...published.map(event => `- In ${event.publisher.function}, publish events.${event.name}{${event.payload.map(field => field.name).join(', ')}} (${eventsImport(event)}) on an injected *eventbus.Bus instead of calling ${event.consumers.map(consumer => `${consumer.module} ${consumer.handler}`).join(', ')}`),
+    ...handled.map(({ event, consumer }) => `- Keep ${consumer.handler} and add a handler of events.${event.name} (${eventsImport(event)}) calling it; the composition root subscribes it to events.${event.name}Name`),
+  ];
+  return `## Domain events
+These calls close a dependency cycle between modules; replace them with an in-process event (${bus}, generated; do not declare it yourself).
+${lines.join('\n')}
+`;
+}
+
+/**
+ * plan.md section: each event with its producer, consumers and payload
+ */
+export function renderDomainEventsSection(plan: DomainEventPlan | undefined): string {
+  if (!plan || plan.events.length === 0) return '';
+
+  const entries = plan.events.map(event => [
+    `- **${event.name}**（\`${event.package}\`）: ${event.producer} の ${event.publisher.function} が発行 → ${event.consumers.map(consumer => `${consumer.module} の ${consumer.handler}`).join('、')} が購読`,
+    `  - ペイロード: ${event.payload.length > 0 ? event.payload.map(field => `\`${field.name} ${field.type}\`${field.original ? `（元の型: ${field.original}）` : ''}`).join(', ') : 'なし'}`,
+  ].join('\n'));
+
+  return `
+## ドメインイベント (Domain Events)
+
+依存サイクルを切るため、次の呼び出しをドメインイベントに置き換えます。vf refactor が発行側モジュールのイベント型とインプロセスのイベントバス（\`${plan.bus.package}\`）を生成します。
+
+${entries.join('\n')}
+`;
+}
+
+function packageName(module: string): string {
+  return module.toLowerCase().replace(/[^a-z0-9]/g, '') || 'module';
+}
+
+function capitalize(name: string): string {
+  return name.charAt(0).toUpperCase() + name.slice(1);
+}
+
+function pascalCase(name: string): string {
+  return name.split(/[^A-Za-z0-9]+/).filter(Boolean).map(part => capitalize(part)).join('');
+}
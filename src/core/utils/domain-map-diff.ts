@@ -0,0 +1,214 @@
+import { DomainBoundary, DomainMap } from '../types/config.js';
+import { portableArtifact, relocateLegacyArtifact } from './workspace-paths.js';
+import { round } from './number-utils.js';
+
+export interface ScoreDelta {
+  previous: number;
+  current: number;
+  delta: number;
+}
+
+/**
+ * How one boundary changed between two discovery runs
+ */
+export interface BoundaryDiff {
+  /** Current name (the previous one for a removed boundary) */
+  boundary: string;
+  id?: string;
+  status: 'added' | 'removed' | 'changed' | 'unchanged';
+  /** Previous name of a boundary matched by its ID */
+  renamed_from?: string;
+  /** Files that were in no boundary of the previous run */
+  added_files: string[];
+  /** Files that are in no boundary any more */
+  removed_files: string[];
+  /** Files another boundary had in the previous run */
+  moved_in: { file: string; from: string }[];
+  /** Files another boundary has now */
+  moved_out: { file: string; to: string }[];
+  cohesion?: ScoreDelta;
+  coupling?: ScoreDelta;
+}
+
+/**
+ * `vf discover --compare`: the current domain map against a previous one
+ */
+export interface DomainMapDiff {
+  generated_at: string;
+  /** Domain map compared against, as given on the command line */
+  previous: string;
+  boundaries: BoundaryDiff[];
+  summary: {
+    added: number;
+    removed: number;
+    changed: number;
+    unchanged: number;
+    added_files: number;
+    removed_files: number;
+    moved_files: number;
+  };
+  metrics: { cohesion?: ScoreDelta; coupling?: ScoreDelta; modularity?: ScoreDelta };
+}
+
+/**
+ * Compare two domain maps of the same project. Boundaries are matched by their
+ * stable ID (so renames are followed), then by name; file paths of the previous
+ * map are made workspace-relative first, even when it comes from another checkout.
+ */
+export function diffDomainMaps(projectRoot: string, previousMap: DomainMap, currentMap: DomainMap, previousPath: string): DomainMapDiff {
+  const previous = relocateLegacyArtifact(projectRoot, previousMap).value.boundaries;
+  const current = portableArtifact(projectRoot, currentMap).boundaries;
+
+  const matched = new Map<DomainBoundary, DomainBoundary>();
+  const used = new Set<DomainBoundary>();
+  for (const match of [(a: DomainBoundary, b: DomainBoundary) => Boolean(a.id) && a.id === b.id, (a: DomainBoundary, b: DomainBoundary) => a.name === b.name]) {
+    for (const boundary of current) {
+      if (matched.has(boundary)) continue;
+      const old = previous.find(candidate => !used.has(candidate) && match(boundary, candidate));
+      if (!old) continue;
+      matched.set(boundary, old);
+      used.add(old);
+    }
+  }
+
+  const ownerBefore = ownership(previous);
+  const ownerNow = ownership(current);
+  const diffs: BoundaryDiff[] = [];
+
+  for (const boundary of current) {
+    const old = matched.get(boundary);
+    const before = new Set(old?.files ?? []);
+    const now = new Set(boundary.files);
+    const entering = boundary.files.filter(file => !before.has(file)).sort();
+    const leaving = [...before].filter(file => !now.has(file)).sort();
+    const diff: BoundaryDiff = {
+      boundary: boundary.name,
+      ...(boundary.id ? { id: boundary.id } : {}),
+      status: 'unchanged',
+      ...(old && old.name !== boundary.name ? { renamed_from: old.name } : {}),
+      added_files: entering.filter(file => !ownerBefore.has(file)),
+      removed_files: leaving.filter(file => !ownerNow.has(file)),
+      moved_in: entering.filter(file => ownerBefore.has(file)).map(file => ({ file, from: ownerBefore.get(file)! })),
+      moved_out: leaving.filter(file => ownerNow.has(file)).map(file => ({ file, to: ownerNow.get(file)! })),
+      ...scoreDeltas(old, boundary),
+    };
+    diff.status = !old ? 'added' : isChanged(diff) ? 'changed' : 'unchanged';
+    diffs.push(diff);
+  }
+
+  for (const old of previous.filter(boundary => !used.has(boundary))) {
+    const leaving = [...new Set(old.files)].sort();
+    diffs.push({
+      boundary: old.name,
+      ...(old.id ? { id: old.id } : {}),
+      status: 'removed',
+      added_files: [],
+      removed_files: leaving.filter(file => !ownerNow.has(file)),
+      moved_in: [],
+      moved_out: leaving.filter(file => ownerNow.has(file)).map(file => ({ file, to: ownerNow.get(file)! })),
+    });
+  }
+
+  diffs.sort((a, b) => STATUS_ORDER.indexOf(a.status) - STATUS_ORDER.indexOf(b.status) || (a.boundary < b.boundary ? -1 : a.boundary > b.boundary ? 1 : 0));
+  const count = (status: BoundaryDiff['status']) => diffs.filter(diff => diff.status === status).length;
+  const allFiles = (key: 'added_files' | 'removed_files') => new Set(diffs.flatMap(diff => diff[key])).size;
+
+  return {
+    generated_at: new Date().toISOString(),
+    previous: previousPath,
+    boundaries: diffs,
+    summary: {
+      added: count('added'),
+      removed: count('removed'),
+      changed: count('changed'),
+      unchanged: count('unchanged'),
+      added_files: allFiles('added_files'),
+      removed_files: allFiles('removed_files'),
+      moved_files: new Set(diffs.flatMap(diff => diff.moved_in.map(m => m.file))).size,
+    },
+    metrics: {
+      ...optionalDelta('cohesion', previousMap.metrics?.overall_cohesion, currentMap.metrics?.overall_cohesion),
+      ...optionalDelta('coupling', previousMap.metrics?.overall_coupling, currentMap.metrics?.overall_coupling),
+      ...optionalDelta('modularity', previousMap.metrics?.modularity_score, currentMap.metrics?.modularity_score),
+    },
+  };
+}
+
+/**
+ * Console lines of a diff: the summary, then each boundary that changed
+ */
+export function formatDomainMapDiff(diff: DomainMapDiff, limit = 10): string[] {
+  const { summary } = diff;
+  const lines = [
+    `境界: 追加${summary.added} / 削除${summary.removed} / 変更${summary.changed} / 変更なし${summary.unchanged}`,
+    `ファイル: 追加${summary.added_files} / 削除${summary.removed_files} / 境界間の移動${summary.moved_files}`,
+  ];
+  const metrics = [
+    diff.metrics.cohesion && `凝集度 ${formatDelta(diff.metrics.cohesion)}`,
+    diff.metrics.coupling && `結合度 ${formatDelta(diff.metrics.coupling)}`,
+    diff.metrics.modularity && `モジュール性 ${formatDelta(diff.metrics.modularity)}`,
+  ].filter(Boolean);
+  if (metrics.length > 0) lines.push(`全体: ${metrics.join(', ')}`);
+
+  const marks: Record<BoundaryDiff['status'], string> = { added: '+', removed: '-', changed: '~', unchanged: ' ' };
+  for (const entry of diff.boundaries.filter(d => d.status !== 'unchanged').slice(0, limit)) {
+    const details = [
+      entry.renamed_from && `旧名 ${entry.renamed_from}`,
+      entry.added_files.length > 0 && `+${entry.added_files.length}ファイル`,
+      entry.removed_files.length > 0 && `-${entry.removed_files.length}ファイル`,
+      entry.moved_in.length > 0 && `移入${entry.moved_in.length} (${[...new Set(entry.moved_in.map(m => m.from))].join(', ')}から)`,
+      entry.moved_out.length > 0 && `移出${entry.moved_out.length} (${[...new Set(entry.moved_out.map(m => m.to))].join(', ')}へ)`,
+      entry.cohesion && entry.cohesion.delta !== 0 && `凝集度 ${formatDelta(entry.cohesion)}`,
+      entry.coupling && entry.coupling.delta !== 0 && `結合度 ${formatDelta(entry.coupling)}`,
+    ].filter(Boolean);
+    lines.push(`${marks[entry.status]} ${entry.boundary}${details.length > 0 ? `: ${details.join(', ')}` : ''}`);
+  }
+  const rest = diff.boundaries.filter(d => d.status !== 'unchanged').length - limit;
+  if (rest > 0) lines.push(`… ほか${rest}個の境界`);
+  return lines;
+}
+
+const STATUS_ORDER: BoundaryDiff['status'][] = ['added', 'removed', 'changed', 'unchanged'];
+
+/** Decimal places kept for score deltas, as in domain-map.json */
+const SCORE_PRECISION = 4;
+
+function ownership(boundaries: DomainBoundary[]): Map<string, string> {
+  const owners = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) {
+      if (!owners.has(file)) owners.set(file, boundary.name);
+    }
+  }
+  return owners;
+}
+
+function isChanged(diff: BoundaryDiff): boolean {
+  return Boolean(diff.renamed_from)
+    || diff.added_files.length + diff.removed_files.length + diff.moved_in.length + diff.moved_out.length > 0
+    || (diff.cohesion?.delta ?? 0) !== 0
+    || (diff.coupling?.delta ?? 0) !== 0;
+}
+
+function scoreDeltas(previous: DomainBoundary | undefined, current: DomainBoundary): Pick<BoundaryDiff, 'cohesion' | 'coupling'> {
+  if (!previous) return {};
+  return {
+    ...optionalDelta('cohesion', previous.metrics?.cohesion ?? previous.cohesion_score, current.metrics?.cohesion ?? current.cohesion_score),
+    ...optionalDelta('coupling', previous.metrics?.coupling ?? previous.coupling_score, current.metrics?.coupling ?? current.coupling_score),
+  };
+}
+
+function optionalDelta<K extends string>(key: K, previous: number | undefined, current: number | undefined): Partial<Record<K, ScoreDelta>> {
+  if (previous === undefined || current === undefined) return {};
+  return {
+    [key]: {
+      previous: round(previous, SCORE_PRECISION),
+      current: round(current, SCORE_PRECISION),
+      delta: round(current - previous, SCORE_PRECISION),
+    },
+  } as Partial<Record<K, ScoreDelta>>;
+}
+
+function formatDelta(score: ScoreDelta): string {
+  return `${score.previous.toFixed(2)} → ${score.current.toFixed(2)} (${score.delta >= 0 ? '+' : ''}${score.delta.toFixed(2)})`;
+}
@@ -0,0 +1,238 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { VibeFlowPaths } from './file-paths.js';
+import { fileOverlap } from './boundary-confidence.js';
+import { round } from './number-utils.js';
+import { portableArtifact } from './workspace-paths.js';
+import { DomainBoundary, DomainMap } from '../types/config.js';
+
+/**
+ * Current schema version of .vibeflow/domain-map.json.
+ * Version 0 is every map written before the field existed.
+ * Version 1 adds schema_version.
+ */
+export const DOMAIN_MAP_SCHEMA_VERSION = 1;
+
+/** Upgrade of a map from each version to the next, applied in order */
+const MIGRATIONS: Record<number, (map: Record<string, unknown>) => Record<string, unknown>> = {
+  // v0 → v1: unversioned maps already have the v1 shape
+  0: map => map,
+};
+
+/** Decimal places kept for cohesion/coupling scores */
+const SCORE_PRECISION = 4;
+
+/** Minimum file overlap (Jaccard) for a boundary to keep the ID of a previous one */
+const MIN_ID_OVERLAP = 0.5;
+
+/**
+ * Stable boundary ID: slug of the name plus a short hash of the seed file set
+ */
+export function boundaryId(name: string, files: string[]): string {
+  const slug = name.toLowerCase().replace(/[^a-z0-9]+/g, '-').replace(/^-+|-+$/g, '') || 'boundary';
+  const hash = createHash('sha256').update([...files].sort().join('\n')).digest('hex').slice(0, 8);
+  return `${slug}-${hash}`;
+}
+
+/**
+ * Give every boundary an ID, reusing the ID of the previous boundary it matches
+ * (same name or mostly the same files) so renames and small file moves keep history.
+ */
+export function assignBoundaryIds(boundaries: DomainBoundary[], previous: DomainBoundary[] = []): DomainBoundary[] {
+  const candidates = previous.filter((b): b is DomainBoundary & { id: string } => Boolean(b.id));
+  const pairs = boundaries.flatMap((boundary, index) => candidates.map(old => {
+    const overlap = fileOverlap(boundary.files, old.files);
+    const sameName = boundary.name === old.name;
+    return { index, id: old.id, overlap, sameName, score: overlap + (sameName ? 1 : 0) };
+  })).filter(p => p.overlap >= MIN_ID_OVERLAP || (p.sameName && p.overlap > 0));
+
+  // Best matches first; ties are broken by ID so the assignment does not depend on input order
+  pairs.sort((a, b) => b.score - a.score || compareCodeUnits(a.id, b.id) || a.index - b.index);
+
+  const assigned = new Map<number, string>();
+  const used = new Set<string>();
+  for (const pair of pairs) {
+    if (assigned.has(pair.index) || used.has(pair.id)) continue;
+    assigned.set(pair.index, pair.id);
+    used.add(pair.id);
+  }
+
+  return boundaries.map((boundary, index) => {
+    let id = assigned.get(index) ?? boundary.id ?? boundaryId(boundary.name, boundary.files);
+    for (let n = 2; used.has(id) && !assigned.has(index); n++) {
+      id = `${boundaryId(boundary.name, boundary.files)}-${n}`;
+    }
+    used.add(id);
+    return { ...boundary, id };
+  });
+}
+
+/**
+ * Sorted boundaries, file lists and edges with rounded scores, so that the
+ * same analysis always serializes to the same bytes
+ */
+export function canonicalizeDomainMap(map: DomainMap): DomainMap {
+  return {
+    ...map,
+    boundaries: map.boundaries
+      .map(canonicalizeBoundary)
+      .sort((a, b) => compareCodeUnits(a.id ?? a.name, b.id ?? b.name) || compareCodeUnits(a.name, b.name)),
+    metrics: {
+      overall_cohesion: round(map.metrics.overall_cohesion, SCORE_PRECISION),
+      overall_coupling: round(map.metrics.overall_coupling, SCORE_PRECISION),
+      modularity_score: round(map.metrics.modularity_score, SCORE_PRECISION),
+    },
+    ...(map.load_errors ? {
+      load_errors: map.load_errors
+        .map(e => ({ ...e, files: sorted(e.files) }))
+        .sort((a, b) => compareCodeUnits(a.package, b.package)),
+    } : {}),
+  };
+}
+
+/**
+ * JSON with object keys in sorted order (arrays keep their order)
+ */
+export function canonicalJson(value: unknown): string {
+  return JSON.stringify(sortKeys(value), null, 2) + '\n';
+}
+
+/**
+ * Upgrade a domain map read from disk to the current schema. Maps written by a
+ * newer vibeflow are rejected instead of being read without the fields this
+ * version does not know.
+ */
+export function migrateDomainMap(raw: Record<string, unknown>): Record<string, unknown> {
+  const version = raw.schema_version ?? 0;
+  if (typeof version !== 'number' || !Number.isInteger(version) || version < 0) {
+    throw new Error(`schema_version must be a non-negative integer, got ${JSON.stringify(version)}`);
+  }
+  if (version > DOMAIN_MAP_SCHEMA_VERSION) {
+    throw new Error(
+      `domain-map.json schema v${version} is newer than supported v${DOMAIN_MAP_SCHEMA_VERSION}. Please upgrade vibeflow or re-run vf discover.`
+    );
+  }
+
+  let map = raw;
+  for (let from = version; from < DOMAIN_MAP_SCHEMA_VERSION; from++) {
+    map = MIGRATIONS[from](map);
+  }
+  return { ...map, schema_version: DOMAIN_MAP_SCHEMA_VERSION };
+}
+
+/**
+ * DomainMapWriter - domain-map.json の決定的な書き出し
+ *
+ * Assigns stable boundary IDs (matched against the map already on disk),
+ * canonicalizes ordering and writes sorted-key JSON. `analyzed_at` is kept
+ * when nothing else changed so re-running discovery leaves the file untouched.
+ */
+export class DomainMapWriter {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  /**
+   * The map on disk upgraded to the current schema; null when there is none,
+   * it is unreadable or it was written by a newer vibeflow
+   */
+  load(): DomainMap | null {
+    const raw = this.readRaw();
+    if (!raw) return null;
+    try {
+      return migrateDomainMap(raw) as DomainMap;
+    } catch {
+      return null;
+    }
+  }
+
+  write(map: DomainMap): DomainMap {
+    const raw = this.readRaw();
+    const previous = this.load();
+    let result = canonicalizeDomainMap({
+      ...map,
+      schema_version: DOMAIN_MAP_SCHEMA_VERSION,
+      boundaries: assignBoundaryIds(map.boundaries, previous?.boundaries),
+    });
+
+    // Compared with the file as written, so older maps are rewritten with the current version
+    const unchanged = { ...result, analyzed_at: previous?.analyzed_at ?? result.analyzed_at };
+    if (raw && canonicalJson(portableArtifact(this.projectRoot, unchanged)) === canonicalJson(raw)) {
+      result = unchanged;
+    }
+
+    fs.mkdirSync(path.dirname(this.paths.domainMapPath), { recursive: true });
+    fs.writeFileSync(this.paths.domainMapPath, canonicalJson(portableArtifact(this.projectRoot, result)));
+    return result;
+  }
+
+  private readRaw(): Record<string, unknown> | null {
+    try {
+      const map = JSON.parse(fs.readFileSync(this.paths.domainMapPath, 'utf8'));
+      return map && Array.isArray(map.boundaries) ? map : null;
+    } catch {
+      return null;
+    }
+  }
+}
+
+function canonicalizeBoundary(boundary: DomainBoundary): DomainBoundary {
+  const result: DomainBoundary = { ...boundary, files: sorted(boundary.files) };
+
+  for (const key of ['ubiquitousLanguage', 'businessRules', 'directories', 'entities', 'apiEndpoints',
+    'circular_dependencies', 'tables', 'merged_from'] as const) {
+    if (boundary[key]) result[key] = sorted(boundary[key] as string[]);
+  }
+  if (boundary.dependencies) {
+    result.dependencies = {
+      ...(boundary.dependencies.internal ? { internal: sorted(boundary.dependencies.internal) } : {}),
+      ...(boundary.dependencies.external ? { external: sorted(boundary.dependencies.external) } : {}),
+    };
+  }
+  if (boundary.degraded_files) {
+    result.degraded_files = [...boundary.degraded_files].sort((a, b) => compareCodeUnits(a.file, b.file));
+  }
+  if (boundary.file_packages) {
+    result.file_packages = [...boundary.file_packages].sort((a, b) => compareCodeUnits(a.file, b.file));
+  }
+  if (boundary.file_coupling) {
+    result.file_coupling = [...boundary.file_coupling].sort((a, b) => compareCodeUnits(a.file, b.file) || compareCodeUnits(a.boundary, b.boundary));
+  }
+  if (boundary.metrics) {
+    result.metrics = {
+      ...boundary.metrics,
+      cohesion: round(boundary.metrics.cohesion, SCORE_PRECISION),
+      coupling: round(boundary.metrics.coupling, SCORE_PRECISION),
+    };
+  }
+  if (boundary.cohesion_score !== undefined) result.cohesion_score = round(boundary.cohesion_score, SCORE_PRECISION);
+  if (boundary.coupling_score !== undefined) result.coupling_score = round(boundary.coupling_score, SCORE_PRECISION);
+
+  return result;
+}
+
+/**
+ * Code unit order; unlike localeCompare it does not depend on the machine's locale
+ */
+export function compareCodeUnits(a: string, b: string): number {
+  return a < b ? -1 : a > b ? 1 : 0;
+}
+
+function sorted(values: string[]): string[] {
+  return [...new Set(values)].sort();
+}
+
+function sortKeys(value: unknown): unknown {
+  if (Array.isArray(value)) return value.map(sortKeys);
+  if (value && typeof value === 'object') {
+    return Object.fromEntries(Object.keys(value as object).sort()
+      .filter(key => (value as Record<string, unknown>)[key] !== undefined)
+      .map(key => [key, sortKeys((value as Record<string, unknown>)[key])]));
+  }
+  return value;
+}
@@ -0,0 +1,131 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { DomainBoundary, DomainModel } from '../types/config.js';
+import { GoDeclaration, parseGoDeclarations } from './context-selector.js';
+import { isGeneratedGoFile } from './generated-code.js';
+
+/** Types holding operations rather than domain state */
+const SERVICE_TYPE = /(?:Service|Usecase|UseCase|Interactor|Handler|Controller|Manager|Repository|Repo|Store|Client|Server|Gateway|Adapter|Worker|Processor|Provider|Factory)$/;
+/** Share of behaviour on the entities above which a model is rich, below which it is anemic */
+const RICH_SHARE = 0.6;
+const ANEMIC_SHARE = 0.3;
+
+/**
+ * Where the business logic of a boundary lives: in methods of its entities (rich)
+ * or in service functions setting the fields of passive structs (anemic,
+ * transaction scripts). Boundaries without entities or logic are not classified.
+ */
+export function classifyDomainModel(projectRoot: string, files: string[]): DomainModel | undefined {
+  const declarations: GoDeclaration[] = [];
+  for (const file of files) {
+    if (!file.endsWith('.go') || file.endsWith('_test.go')) continue;
+    try {
+      const content = fs.readFileSync(path.resolve(projectRoot, file), 'utf8');
+      if (!isGeneratedGoFile(file, content)) declarations.push(...parseGoDeclarations(content, file));
+    } catch {
+      continue;
+    }
+  }
+
+  const entities = declarations
+    .filter(d => d.kind === 'type' && /^type\s+\w+\s+struct\b/.test(d.signature) && !SERVICE_TYPE.test(d.name))
+    .map(d => d.name);
+  if (entities.length === 0) return undefined;
+  const isEntity = new Set(entities);
+  const entityName = new RegExp(`\\b(?:${entities.join('|')})\\b`);
+  const mentionsEntity = (body: string) => entityName.test(body);
+
+  const behavior = new Set<string>();
+  const scripts = new Set<string>();
+  for (const declaration of declarations.filter(d => d.kind !== 'type')) {
+    const receiver = receiverOf(declaration.signature);
+    if (receiver && isEntity.has(receiver.type)) {
+      if (!isAccessor(declaration)) behavior.add(`${receiver.type}.${declaration.name}`);
+    } else if (setsForeignFields(declaration.body, receiver?.name) && mentionsEntity(declaration.body)) {
+      scripts.add(receiver ? `${receiver.type}.${declaration.name}` : declaration.name);
+    }
+  }
+  const total = behavior.size + scripts.size;
+  if (total === 0) return undefined;
+
+  const share = behavior.size / total;
+  const style = share >= RICH_SHARE ? 'rich' : share <= ANEMIC_SHARE ? 'anemic' : 'mixed';
+  return {
+    style,
+    entities: entities.sort(),
+    entity_methods: [...behavior].sort(),
+    script_functions: [...scripts].sort(),
+    rationale: `${behavior.size} behaviour methods on entities, ${scripts.size} service functions setting entity fields`,
+  };
+}
+
+/**
+ * Domain model style of each boundary; established modules are not refactored
+ */
+export function attachDomainModels(projectRoot: string, boundaries: DomainBoundary[]): DomainBoundary[] {
+  return boundaries.map(boundary => {
+    // A previous classification (incremental discovery) is replaced, or dropped when nothing is left to classify
+    const { domain_model: _, ...rest } = boundary;
+    if (boundary.status === 'established') return rest;
+    const domainModel = classifyDomainModel(projectRoot, boundary.files);
+    return domainModel ? { ...rest, domain_model: domainModel } : rest;
+  });
+}
+
+/**
+ * Transformation strategy of the refactoring prompt for a boundary's domain model
+ */
+export function renderDomainModelSection(domainModel: DomainModel | undefined): string {
+  if (!domainModel) return '';
+  const list = (names: string[]) => names.slice(0, 10).join(', ') + (names.length > 10 ? ', ...' : '');
+
+  switch (domainModel.style) {
+    case 'anemic':
+      return [
+        '## Domain Model: anemic (transaction scripts)',
+        `The business rules live in service functions (${list(domainModel.script_functions)}) that set fields of passive structs (${list(domainModel.entities)}).`,
+        '- Move each rule that reads and changes one entity into a method of that entity, guarding its invariants; unexport the fields it sets.',
+        '- Keep the use case a thin sequence: load the aggregate, call its methods, save it.',
+        '- Rules spanning several aggregates stay in the use case or become a domain service.',
+      ].join('\n');
+    case 'rich':
+      return [
+        '## Domain Model: rich',
+        `The entities already carry the behaviour (${list(domainModel.entity_methods)}).`,
+        '- Move the entities with their methods into the domain package unchanged; do not pull their logic out into services.',
+        '- Use cases only orchestrate these methods and the repositories.',
+      ].join('\n');
+    case 'mixed':
+      return [
+        '## Domain Model: mixed',
+        `Some behaviour is on the entities (${list(domainModel.entity_methods)}), some in service functions setting their fields (${list(domainModel.script_functions)}).`,
+        '- Keep the existing entity methods as they are.',
+        '- Move the service rules that change a single entity into methods of it; leave orchestration across aggregates in the use cases.',
+      ].join('\n');
+  }
+}
+
+function receiverOf(signature: string): { name?: string; type: string } | undefined {
+  const match = signature.match(/^func\s*\(\s*(?:(\w+)\s+)?\*?\s*(\w+)/);
+  return match ? { ...(match[1] ? { name: match[1] } : {}), type: match[2] } : undefined;
+}
+
+/**
+ * Short methods returning or setting a single field, or formatting the value
+ */
+function isAccessor(declaration: GoDeclaration): boolean {
+  if (declaration.name === 'String' || declaration.name === 'Error') return true;
+  const statements = declaration.body.split('\n').slice(1, -1).map(line => line.trim()).filter(Boolean);
+  return statements.length === 1 && /^(?:return\s+\w+\.\w+|\w+\.\w+\s*=\s*\w+)$/.test(statements[0]);
+}
+
+/**
+ * Whether a function assigns exported fields of a value other than its receiver
+ * (order.Status = ..., order.Total += ...); callers also require an entity type in it
+ */
+function setsForeignFields(body: string, receiver: string | undefined): boolean {
+  for (const match of body.matchAll(/\b(\w+)\.[A-Z]\w*\s*(?:[-+*/]?=(?!=)|\+\+|--)/g)) {
+    if (match[1] !== receiver) return true;
+  }
+  return false;
+}
@@ -0,0 +1,515 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { ModuleManifestStore } from './module-manifest.js';
+import { Finding, FindingSeverity } from './findings.js';
+import { SourceFile, SourceLocation, formatLocation } from './source-positions.js';
+import { loadGoPackages } from './go-packages.js';
+import { goImports } from './go-load-check.js';
+import { toPosixPath } from './workspace-paths.js';
+import { BusinessRule } from '../types/business-logic.js';
+import { BoundaryConstraints } from '../types/config.js';
+
+/**
+ * unassigned-file: a new file outside every module and the shared kernel.
+ * undeclared-dependency / forbidden-dependency: a new cross-module import the
+ * plan does not sanction (forbidden: boundary.yaml forbids it outright).
+ * layer-violation: an import against the layering inside a module.
+ * shared-kernel-growth: a file added to the shared kernel.
+ * rule-moved / rule-missing: a business rule of the catalog found elsewhere, or nowhere.
+ */
+export type DriftRule =
+  | 'unassigned-file'
+  | 'undeclared-dependency'
+  | 'forbidden-dependency'
+  | 'layer-violation'
+  | 'shared-kernel-growth'
+  | 'rule-moved'
+  | 'rule-missing';
+
+/**
+ * Pseudo module of the shared kernel directories (shared/, common/, kernel/);
+ * a DriftModule of this name lists the plan's shared kernel packages
+ */
+export const SHARED_KERNEL = '(shared kernel)';
+
+/** Pseudo module of new files outside every module */
+export const UNASSIGNED = '(unassigned)';
+
+export interface DriftModule {
+  name: string;
+  /** Files of the module in the accepted plan, relative to the project root */
+  files: string[];
+  /** Modules the plan lets this module depend on */
+  dependencies: string[];
+}
+
+export interface BoundarySuggestion {
+  module: string;
+  /** 0-1, from the same signals as the dependency-based clustering */
+  score: number;
+  reasons: string[];
+}
+
+export interface DriftFinding extends Finding {
+  kind: 'drift';
+  rule: DriftRule;
+  /** Module the drift counts against */
+  module: string;
+  /** Best boundary for an unassigned file */
+  suggested_boundary?: BoundarySuggestion;
+}
+
+export interface ModuleDrift {
+  module: string;
+  accepted: boolean;
+  files: number;
+  drifted_files: number;
+  /** Share of the module's files with at least one drift finding */
+  percent: number;
+}
+
+/**
+ * Workspace state drift is measured against, captured when `vf verify` passes
+ */
+export interface DriftBaseline {
+  captured_at: string;
+  /** Run whose verification captured the baseline */
+  run_id?: number;
+  /** Non-test Go files relative to the project root */
+  files: string[];
+  shared_kernel: { files: string[]; lines: number };
+  /** Cross-module imports present at the baseline, [from, to] */
+  dependencies: [string, string][];
+}
+
+export interface DriftReport {
+  generated_at: string;
+  /** verified: the state captured by the last `vf verify`; plan: files of plan.json (no baseline yet) */
+  baseline: { source: 'verified' | 'plan'; captured_at?: string; run_id?: number };
+  /** Share of the project's files that drifted (0-100) */
+  score: number;
+  files: number;
+  modules: ModuleDrift[];
+  shared_kernel: { files: number; lines: number; baseline_files: number; baseline_lines: number };
+  findings: DriftFinding[];
+}
+
+export interface DriftOptions {
+  /** Default: .vibeflow/drift-baseline.json, else the files of the plan */
+  baseline?: DriftBaseline | null;
+  constraints?: BoundaryConstraints;
+  /** Business rules catalog (business-rule findings) */
+  rules?: Finding[];
+  /** Modules a reviewer accepted */
+  accepted?: string[];
+}
+
+type Layer = 'domain' | 'usecase' | 'infrastructure' | 'handler';
+
+/**
+ * Layers of the module structure created by refactor; dependencies point inwards
+ */
+const ALLOWED_LAYER_IMPORTS: Record<Layer, Layer[]> = {
+  domain: [],
+  usecase: ['domain'],
+  infrastructure: ['domain', 'usecase'],
+  handler: ['usecase', 'domain'],
+};
+
+const SHARED_KERNEL_DIR = /(?:^|\/)(?:shared|sharedkernel|kernel|common)(?:\/|$)/;
+
+const SEVERITIES: Record<DriftRule, FindingSeverity> = {
+  'unassigned-file': 'warning',
+  'undeclared-dependency': 'warning',
+  'forbidden-dependency': 'error',
+  'layer-violation': 'error',
+  'shared-kernel-growth': 'warning',
+  'rule-moved': 'note',
+  'rule-missing': 'error',
+};
+
+interface ImportEdge {
+  file: string;
+  from: string;
+  to: string;
+  import_path: string;
+  /** Directory of the imported package */
+  target_dir: string;
+  location: SourceLocation;
+}
+
+interface WorkspaceScan {
+  files: string[];
+  /** Module (or SHARED_KERNEL) of each file; unassigned files are missing */
+  membership: Map<string, string>;
+  sources: Map<string, SourceFile>;
+  /** Import path of each package directory */
+  packages: Map<string, string>;
+  imports: ImportEdge[];
+}
+
+/**
+ * Compare the workspace against the accepted plan and the last verified state
+ */
+export function analyzeDrift(projectRoot: string, modules: DriftModule[], options: DriftOptions = {}): DriftReport {
+  const planned = withManifestOutputs(projectRoot, modules);
+  const scan = scanWorkspace(projectRoot, planned);
+  const stored = options.baseline === undefined ? loadDriftBaseline(projectRoot) : options.baseline;
+  const baseline = stored ?? planBaseline(scan, planned);
+  const baselineFiles = new Set(baseline.files);
+  const findings: DriftFinding[] = [];
+
+  const unassigned = scan.files.filter(file => !scan.membership.has(file) && !baselineFiles.has(file));
+  for (const file of unassigned) {
+    const suggestion = suggestBoundary(file, scan, planned);
+    findings.push(driftFinding('unassigned-file', UNASSIGNED, scan.sources.get(file)!.locateLine(1)!,
+      `${file} is not assigned to any boundary${suggestion ? `; suggested: ${suggestion.module} (${suggestion.reasons.join(', ')})` : ''}`,
+      suggestion ? { suggested_boundary: suggestion } : {}));
+  }
+
+  const known = new Set(baseline.dependencies.map(([from, to]) => `${from}→${to}`));
+  const allowed = new Map(planned.map(m => [m.name, new Set(m.dependencies)]));
+  const forbidden = new Set((options.constraints?.forbiddenDependencies ?? []).map(([from, to]) => `${from}→${to}`));
+  for (const edge of scan.imports) {
+    if (edge.from === edge.to) {
+      const from = layerOf(path.posix.dirname(edge.file));
+      const to = layerOf(edge.target_dir);
+      if (from && to && from !== to && !ALLOWED_LAYER_IMPORTS[from].includes(to)) {
+        findings.push(driftFinding('layer-violation', edge.from, edge.location,
+          `${from} layer of ${edge.from} imports its ${to} layer (${edge.import_path}); ${from} may only import ${ALLOWED_LAYER_IMPORTS[from].join(', ') || 'no other layer'}`));
+      }
+      continue;
+    }
+    if (edge.from === SHARED_KERNEL || edge.to === SHARED_KERNEL || known.has(`${edge.from}→${edge.to}`)) continue;
+
+    if (forbidden.has(`${edge.from}→${edge.to}`)) {
+      findings.push(driftFinding('forbidden-dependency', edge.from, edge.location,
+        `${edge.from} imports ${edge.to} (${edge.import_path}), which boundary.yaml forbids`));
+    } else if (!allowed.get(edge.from)?.has(edge.to)) {
+      findings.push(driftFinding('undeclared-dependency', edge.from, edge.location,
+        `${edge.from} imports ${edge.to} (${edge.import_path}), a dependency the plan does not declare`));
+    }
+  }
+
+  const kernelFiles = scan.files.filter(file => scan.membership.get(file) === SHARED_KERNEL);
+  const baselineKernel = new Set(baseline.shared_kernel.files);
+  for (const file of kernelFiles.filter(f => !baselineKernel.has(f))) {
+    const source = scan.sources.get(file)!;
+    findings.push(driftFinding('shared-kernel-growth', SHARED_KERNEL, source.locateLine(1)!,
+      `${file} was added to the shared kernel (${source.lineCount} lines); prefer the owning module`));
+  }
+
+  findings.push(...ruleDrift(projectRoot, options.rules ?? [], scan));
+
+  const accepted = new Set(options.accepted ?? []);
+  const drifted = new Map<string, Set<string>>();
+  for (const finding of findings) {
+    const files = drifted.get(finding.module) ?? new Set<string>();
+    files.add(finding.location.file);
+    drifted.set(finding.module, files);
+  }
+  const moduleFiles = (name: string) => scan.files.filter(file => scan.membership.get(file) === name).length;
+  const moduleDrift = [...new Set([
+    ...planned.map(m => m.name),
+    ...(kernelFiles.length > 0 ? [SHARED_KERNEL] : []),
+    ...(unassigned.length > 0 ? [UNASSIGNED] : []),
+  ])].map(module => {
+    const files = module === UNASSIGNED ? unassigned.length : moduleFiles(module);
+    const count = drifted.get(module)?.size ?? 0;
+    return { module, accepted: accepted.has(module), files, drifted_files: count, percent: percent(count, Math.max(files, count)) };
+  });
+
+  const driftedFiles = new Set(findings.map(f => f.location.file)).size;
+  return {
+    generated_at: new Date().toISOString(),
+    baseline: stored ? { source: 'verified', captured_at: stored.captured_at, ...(stored.run_id !== undefined ? { run_id: stored.run_id } : {}) } : { source: 'plan' },
+    score: percent(driftedFiles, Math.max(scan.files.length, driftedFiles)),
+    files: scan.files.length,
+    modules: moduleDrift,
+    shared_kernel: {
+      files: kernelFiles.length,
+      lines: kernelFiles.reduce((sum, file) => sum + scan.sources.get(file)!.lineCount, 0),
+      baseline_files: baseline.shared_kernel.files.length,
+      baseline_lines: baseline.shared_kernel.lines,
+    },
+    findings: findings.sort((a, b) =>
+      a.location.file.localeCompare(b.location.file) || a.location.line - b.location.line || a.location.column - b.location.column),
+  };
+}
+
+/**
+ * Record the current workspace as the state drift is measured against
+ */
+export function captureDriftBaseline(projectRoot: string, modules: DriftModule[], runId?: number): DriftBaseline {
+  const scan = scanWorkspace(projectRoot, withManifestOutputs(projectRoot, modules));
+  const baseline: DriftBaseline = {
+    ...snapshot(scan),
+    captured_at: new Date().toISOString(),
+    ...(runId !== undefined ? { run_id: runId } : {}),
+  };
+  new VibeFlowPaths(projectRoot).writeArtifact(driftBaselinePath(projectRoot), baseline);
+  return baseline;
+}
+
+export function loadDriftBaseline(projectRoot: string): DriftBaseline | null {
+  try {
+    const baseline = JSON.parse(fs.readFileSync(driftBaselinePath(projectRoot), 'utf8'));
+    return Array.isArray(baseline?.files) ? baseline : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Business rules of a catalog written by `--rules` (BusinessRule JSON) as findings
+ */
+export function ruleCatalogFindings(rules: BusinessRule[]): Finding[] {
+  return rules.map(rule => {
+    const { function: symbol, file, line, column, offset, end_line, end_column, end_offset } = rule.location;
+    return {
+      kind: 'business-rule' as const,
+      rule: rule.type,
+      severity: 'note' as const,
+      message: rule.description,
+      location: {
+        file: toPosixPath(file),
+        line,
+        column: column ?? 1,
+        offset: offset ?? 0,
+        end_line: end_line ?? line,
+        end_column: end_column ?? 1,
+        end_offset: end_offset ?? 0,
+      },
+      snippet: rule.code,
+      ...(symbol ? { symbol } : {}),
+    };
+  });
+}
+
+export function formatDriftReport(report: DriftReport): string {
+  const width = Math.max(6, ...report.modules.map(m => m.module.length)) + 2;
+  const baseline = report.baseline.source === 'verified'
+    ? `verified state of ${report.baseline.captured_at}${report.baseline.run_id !== undefined ? ` (run ${report.baseline.run_id})` : ''}`
+    : 'plan.json (no verified baseline yet)';
+  const lines = [
+    `Drift score: ${report.score}% of ${report.files} files`,
+    `Baseline: ${baseline}`,
+    '',
+    `${'Module'.padEnd(width)}${'Files'.padStart(7)}${'Drifted'.padStart(9)}${'Drift'.padStart(8)}`,
+    ...report.modules.map(m =>
+      `${(m.module + (m.accepted ? ' *' : '')).padEnd(width)}${String(m.files).padStart(7)}${String(m.drifted_files).padStart(9)}${`${m.percent}%`.padStart(8)}`),
+  ];
+  if (report.modules.some(m => m.accepted)) lines.push('* accepted');
+  if (report.shared_kernel.files > 0 || report.shared_kernel.baseline_files > 0) {
+    const { files, lines: kernelLines, baseline_files, baseline_lines } = report.shared_kernel;
+    lines.push('', `Shared kernel: ${baseline_files} → ${files} files, ${baseline_lines} → ${kernelLines} lines`);
+  }
+  return lines.join('\n');
+}
+
+function driftBaselinePath(projectRoot: string): string {
+  return new VibeFlowPaths(projectRoot).driftBaselinePath;
+}
+
+function driftFinding(
+  rule: DriftRule,
+  module: string,
+  location: SourceLocation,
+  message: string,
+  extra: Partial<DriftFinding> = {}
+): DriftFinding {
+  return { kind: 'drift', rule, severity: SEVERITIES[rule], module, message, location, ...extra };
+}
+
+/**
+ * Outputs written for a module by refactor belong to it like its planned files
+ */
+function withManifestOutputs(projectRoot: string, modules: DriftModule[]): DriftModule[] {
+  const store = new ModuleManifestStore(projectRoot);
+  return modules.map(module => ({
+    ...module,
+    files: [...new Set([...module.files.map(toPosixPath), ...(store.load(module.name)?.files ?? []).map(entry => toPosixPath(entry.path))])],
+  }));
+}
+
+function scanWorkspace(projectRoot: string, modules: DriftModule[]): WorkspaceScan {
+  const packages = loadGoPackages(projectRoot);
+  const sources = new Map<string, SourceFile>();
+  for (const file of packages.flatMap(pkg => pkg.files).sort()) {
+    const source = SourceFile.read(projectRoot, file);
+    if (source) sources.set(file, source);
+  }
+  const files = [...sources.keys()];
+  const membership = assignFiles(modules, files);
+
+  const byImportPath = new Map(packages.map(pkg => [pkg.import_path, pkg]));
+  const imports: ImportEdge[] = [];
+  for (const [file, source] of sources) {
+    const from = membership.get(file);
+    if (!from) continue;
+    for (const imported of goImports(source.content)) {
+      const pkg = byImportPath.get(imported.path);
+      const to = pkg?.files.map(f => membership.get(f)).find(Boolean);
+      const location = pkg && to ? source.findImport(imported.path) : null;
+      if (!pkg || !to || !location) continue;
+      imports.push({ file, from, to, import_path: imported.path, target_dir: pkg.dir, location });
+    }
+  }
+
+  return { files, membership, sources, packages: new Map(packages.map(pkg => [pkg.dir, pkg.import_path])), imports };
+}
+
+/**
+ * Files listed by a module belong to it; otherwise files in the shared kernel
+ * directories to the kernel, and new files to the module owning their directory
+ */
+function assignFiles(modules: DriftModule[], files: string[]): Map<string, string> {
+  const listed = new Map<string, string>();
+  const dirOwners = new Map<string, Set<string>>();
+  for (const module of modules) {
+    for (const file of module.files) {
+      listed.set(file, module.name);
+      const owners = dirOwners.get(path.posix.dirname(file)) ?? new Set<string>();
+      owners.add(module.name);
+      dirOwners.set(path.posix.dirname(file), owners);
+    }
+  }
+
+  const membership = new Map<string, string>();
+  for (const file of files) {
+    const owners = dirOwners.get(path.posix.dirname(file));
+    const module = listed.get(file)
+      ?? (SHARED_KERNEL_DIR.test(path.posix.dirname(file)) ? SHARED_KERNEL : undefined)
+      ?? (owners?.size === 1 ? [...owners][0] : undefined);
+    if (module) membership.set(file, module);
+  }
+  return membership;
+}
+
+function snapshot(scan: WorkspaceScan): Omit<DriftBaseline, 'captured_at' | 'run_id'> {
+  const kernel = scan.files.filter(file => scan.membership.get(file) === SHARED_KERNEL);
+  const dependencies = new Map(scan.imports
+    .filter(edge => edge.from !== edge.to)
+    .map(edge => [`${edge.from}→${edge.to}`, [edge.from, edge.to] as [string, string]]));
+  return {
+    files: scan.files,
+    shared_kernel: { files: kernel, lines: kernel.reduce((sum, file) => sum + scan.sources.get(file)!.lineCount, 0) },
+    dependencies: [...dependencies.values()],
+  };
+}
+
+/**
+ * Without a verified baseline the plan's files are the baseline; cross-module
+ * imports then all have to be declared by the plan
+ */
+function planBaseline(scan: WorkspaceScan, modules: DriftModule[]): DriftBaseline {
+  const files = new Set(modules.flatMap(m => m.files));
+  const kernel = scan.files.filter(file => files.has(file)
+    && (scan.membership.get(file) === SHARED_KERNEL || SHARED_KERNEL_DIR.test(path.posix.dirname(file))));
+  return {
+    captured_at: '',
+    files: [...files].sort(),
+    shared_kernel: { files: kernel, lines: kernel.reduce((sum, file) => sum + scan.sources.get(file)!.lineCount, 0) },
+    dependencies: [],
+  };
+}
+
+function layerOf(dir: string): Layer | undefined {
+  return dir.split('/').reverse().find((segment): segment is Layer => segment in ALLOWED_LAYER_IMPORTS);
+}
+
+/**
+ * Catalog rules are looked for at their recorded file, then in the outputs
+ * generated from it, then anywhere in the workspace
+ */
+function ruleDrift(projectRoot: string, rules: Finding[], scan: WorkspaceScan): DriftFinding[] {
+  const store = new ModuleManifestStore(projectRoot);
+  const outputs = store.listModules().flatMap(name => store.load(name)?.files ?? []);
+  const findings: DriftFinding[] = [];
+
+  for (const rule of rules.filter(r => r.kind === 'business-rule')) {
+    const file = toPosixPath(rule.location.file);
+    const candidates = [...new Set([
+      file,
+      ...outputs.filter(entry => toPosixPath(entry.source) === file).map(entry => toPosixPath(entry.path)),
+      ...scan.files,
+    ])];
+    let found: SourceLocation | null = null;
+    for (const candidate of candidates) {
+      const source = scan.sources.get(candidate) ?? (candidate === file ? SourceFile.read(projectRoot, candidate) : null);
+      found = source && (rule.snippet?.trim()
+        ? source.findSnippet(rule.snippet, candidate === file ? Math.max(1, rule.location.line) : 1)
+          ?? (candidate === file ? source.findSnippet(rule.snippet) : null)
+        : rule.symbol ? source.findDeclaration(rule.symbol) : null);
+      if (found) break;
+    }
+
+    const original = formatLocation({ file, line: rule.location.line });
+    if (!found) {
+      findings.push(driftFinding('rule-missing', scan.membership.get(file) ?? UNASSIGNED, { ...rule.location, file },
+        `business rule "${rule.message}" (${original}) was not found in the workspace`, rule.symbol ? { symbol: rule.symbol } : {}));
+    } else if (found.file !== file || found.line !== rule.location.line) {
+      findings.push(driftFinding('rule-moved', scan.membership.get(found.file) ?? UNASSIGNED, found,
+        `business rule "${rule.message}" moved from ${original}`, { related: [{ ...rule.location, file }], ...(rule.symbol ? { symbol: rule.symbol } : {}) }));
+    }
+  }
+  return findings;
+}
+
+/**
+ * Score each module for a new file with the weights of the dependency-based
+ * clustering: imports (0.8), calls into the file's package (0.6), same
+ * directory tree (0.2) and name similarity (0.3)
+ */
+function suggestBoundary(file: string, scan: WorkspaceScan, modules: DriftModule[]): BoundarySuggestion | undefined {
+  const source = scan.sources.get(file)!;
+  const imported = new Set(goImports(source.content).map(i => i.path));
+  const dir = path.posix.dirname(file);
+  const tokens = nameTokens(path.posix.basename(file, '.go'));
+  const importPath = scan.packages.get(dir);
+
+  const suggestions = modules.filter(module => module.name !== SHARED_KERNEL).map(module => {
+    const reasons: string[] = [];
+    let score = 0;
+    const dirs = new Set(module.files.map(f => path.posix.dirname(f)));
+    if ([...dirs].some(d => imported.has(scan.packages.get(d) ?? ''))) {
+      score += 0.8;
+      reasons.push(`imports ${module.name}`);
+    }
+    if (importPath && module.files.some(f => goImports(scan.sources.get(f)?.content ?? '').some(i => i.path === importPath))) {
+      score += 0.6;
+      reasons.push(`imported by ${module.name}`);
+    }
+    if ([...dirs].some(d => path.posix.dirname(d) === path.posix.dirname(dir))) {
+      score += 0.2;
+      reasons.push('same directory tree');
+    }
+    const moduleTokens = nameTokens(module.name);
+    const common = tokens.filter(token => moduleTokens.includes(token)).length;
+    const similarity = common / new Set([...tokens, ...moduleTokens]).size || 0;
+    if (similarity > 0) {
+      score += similarity * 0.3;
+      reasons.push(`name matches ${module.name}`);
+    }
+    return { module: module.name, score: Math.round(Math.min(score, 1) * 100) / 100, reasons };
+  });
+
+  return suggestions
+    .filter(s => s.score > 0)
+    .sort((a, b) => b.score - a.score || a.module.localeCompare(b.module))[0];
+}
+
+function nameTokens(name: string): string[] {
+  return name
+    .replace(/([a-z])([A-Z])/g, '$1 $2')
+    .replace(/[_\-.]/g, ' ')
+    .toLowerCase()
+    .split(/\s+/)
+    .map(word => word.replace(/s$/, ''))
+    .filter(word => word.length > 2);
+}
+
+function percent(count: number, total: number): number {
+  return total === 0 ? 0 : Math.round((count / total) * 1000) / 10;
+}
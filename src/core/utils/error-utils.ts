@@ -32,6 +32,61 @@ export class ValidationError extends VibeFlowError {
   }
 }
 
+export type InputKind =
+  | 'boundary-config'
+  | 'domain-map'
+  | 'plan'
+  | 'llm-response'
+  | 'coverage-profile'
+  | 'go-source';
+
+/**
+ * A malformed input artifact; only the unit (file or module) that read it fails
+ */
+export class InputParseError extends VibeFlowError {
+  constructor(
+    public readonly input: InputKind,
+    message: string,
+    public readonly file?: string,
+    details?: any
+  ) {
+    super(`${file ? `${file}: ` : ''}invalid ${input}: ${message}`, 'INPUT_PARSE_ERROR', details);
+    this.name = 'InputParseError';
+  }
+}
+
+/**
+ * Generated output that does not pass the checks run before it is written
+ */
+export class VerificationError extends VibeFlowError {
+  constructor(message: string, public readonly file?: string, details?: any) {
+    super(`${file ? `${file}: ` : ''}${message}`, 'VERIFICATION_ERROR', details);
+    this.name = 'VerificationError';
+  }
+}
+
+/**
+ * Why a unit of work failed, as reported in failed_patches.
+ * llm-malformed-response and verification-failure are retried with a stronger model (llm.escalation).
+ */
+export type FailureCategory =
+  | 'invalid-input'
+  | 'llm'
+  | 'llm-malformed-response'
+  | 'verification-failure'
+  | 'io'
+  | 'refused'
+  | 'internal';
+
+export function failureCategory(error: unknown): FailureCategory {
+  if (error instanceof InputParseError) return error.input === 'llm-response' ? 'llm-malformed-response' : 'invalid-input';
+  if (error instanceof VerificationError) return 'verification-failure';
+  if (error instanceof Error && ['LlmTimeoutError', 'LlmUnavailableError', 'OfflineNetworkError', 'BatchItemError'].includes(error.name)) return 'llm';
+  if (error instanceof Error && !(error instanceof VibeFlowError) && typeof (error as NodeJS.ErrnoException).code === 'string'
+    && /^E[A-Z]+$/.test((error as NodeJS.ErrnoException).code!)) return 'io';
+  return 'internal';
+}
+
 export function getErrorMessage(error: unknown): string {
   if (error instanceof Error) {
     return error.message;
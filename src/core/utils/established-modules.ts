@@ -0,0 +1,305 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { DomainBoundary } from '../types/config.js';
+import { detectGoProject, findAllGoModules, goImportAlias, goPackageImportPath } from './go-project-utils.js';
+import { goImports, goPackageName } from './go-load-check.js';
+import { maskLiterals } from './api-surface.js';
+import { loadGoWorkspace } from './go-workspace.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * A module extracted before vibeflow: a directory with its own go.mod, or one
+ * listed under `established:` in boundary.yaml
+ */
+export interface EstablishedModule {
+  name: string;
+  /** Module root relative to the project root */
+  root: string;
+  /** Module path of its own go.mod; absent for directories of the main module */
+  module_path?: string;
+  source: 'go.mod' | 'config';
+}
+
+export interface EstablishedImport {
+  boundaries: DomainBoundary[];
+  /** Established modules imported as fixed boundaries */
+  imported: string[];
+  /** Established modules left to clustering (--reconsider-established) */
+  reconsidered: string[];
+  /** --reconsider-established names that are no established module */
+  unknown: string[];
+}
+
+/** Exported symbols (`<package dir>.<Name>`) of an established module one other module uses */
+export interface EstablishedConsumer {
+  module: string;
+  symbols: string[];
+}
+
+export type EstablishedApiProposal = 'constraint' | 'shared-state' | 'package-rename';
+
+/**
+ * A plan proposal that cannot be carried out without changing the public API
+ * of an established module
+ */
+export interface EstablishedApiChange {
+  module: string;
+  proposal: EstablishedApiProposal;
+  description: string;
+  /** Exported symbols (`<package dir>.<Name>`) whose users would have to change */
+  symbols: string[];
+}
+
+const IGNORED = ['**/vendor/**', '**/node_modules/**', '**/testdata/**', '**/*_test.go', '.vibeflow/**', '.git/**'];
+
+/**
+ * Nested Go modules (every go.mod but the main one and the members of go.work,
+ * which are analyzed together) and the configured directories
+ */
+export function findEstablishedModules(projectRoot: string, configured: string[] = []): EstablishedModule[] {
+  const main = detectGoProject(projectRoot).workingDirectory;
+  const members = new Set(loadGoWorkspace(projectRoot)?.modules.map(m => m.dir) ?? []);
+  const modules: EstablishedModule[] = [];
+
+  for (const info of findAllGoModules(projectRoot)) {
+    const dir = info.workingDirectory!;
+    if (path.resolve(dir) === path.resolve(main ?? projectRoot)) continue;
+    const root = toPosixPath(path.relative(projectRoot, dir));
+    if (!root || root.split('/').includes('testdata') || members.has(root)) continue;
+    modules.push({ name: '', root, ...(info.moduleName ? { module_path: info.moduleName } : {}), source: 'go.mod' });
+  }
+
+  for (const entry of configured) {
+    const root = toPosixPath(path.normalize(entry)).replace(/^\.\/|\/+$/g, '');
+    if (!root || root === '.' || modules.some(m => m.root === root)) continue;
+    modules.push({ name: '', root, source: 'config' });
+  }
+
+  modules.sort((a, b) => (a.root < b.root ? -1 : a.root > b.root ? 1 : 0));
+  const names = new Set<string>();
+  for (const module of modules) {
+    const base = path.posix.basename(module.root);
+    module.name = names.has(base) ? module.root.replace(/\//g, '-') : base;
+    names.add(module.name);
+  }
+  return modules;
+}
+
+/**
+ * Replace whatever clustering made of the established modules with one fixed
+ * boundary each (`status: established`). Their files leave the clustered
+ * boundaries; import edges to and from them are added to `dependencies.internal`
+ * so they stay in the dependency graph.
+ *
+ * @param reconsider - names or roots of established modules to leave to clustering
+ */
+export function importEstablishedBoundaries(
+  projectRoot: string,
+  boundaries: DomainBoundary[],
+  established: EstablishedModule[],
+  reconsider: string[] = []
+): EstablishedImport {
+  const relative = (file: string) => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+  const unknown = reconsider.filter(name => !established.some(m => m.name === name || m.root === name));
+  const reconsidered = established.filter(m => reconsider.includes(m.name) || reconsider.includes(m.root));
+  const fixed = established
+    .filter(m => !reconsidered.includes(m))
+    .map(module => ({ module, files: moduleFiles(projectRoot, module.root) }))
+    .filter(entry => entry.files.length > 0);
+  if (fixed.length === 0) {
+    return { boundaries, imported: [], reconsidered: reconsidered.map(m => m.name), unknown };
+  }
+
+  const taken = new Set(fixed.flatMap(entry => entry.files));
+  const remaining = boundaries
+    .map(boundary => ({ ...boundary, files: boundary.files.filter(file => !taken.has(relative(file))) }))
+    .filter((boundary, i) => boundary.files.length > 0 || boundaries[i].files.length === 0)
+    .map(boundary => (fixed.some(entry => entry.module.name === boundary.name) ? { ...boundary, name: `${boundary.name}-legacy` } : boundary));
+
+  const imported: DomainBoundary[] = fixed.map(({ module, files }) => {
+    const own = new Set(files);
+    // A boundary clustering already drew exactly around the module keeps its ID and description
+    const match = boundaries.find(b => b.files.length === files.length && b.files.every(file => own.has(relative(file))));
+    const cycles = [...new Set(boundaries.flatMap(b => (b.circular_dependencies ?? [])
+      .filter(chain => chain.split(/\s*→\s*/).some(file => own.has(relative(file))))))];
+    return {
+      ...(match?.id ? { id: match.id } : {}),
+      name: module.name,
+      description: match?.description ?? `既存モジュール ${module.root}`,
+      directories: [module.root],
+      files,
+      dependencies: { internal: [], external: [] },
+      circular_dependencies: cycles,
+      ...(match?.cohesion_score !== undefined ? { cohesion_score: match.cohesion_score } : {}),
+      ...(match?.coupling_score !== undefined ? { coupling_score: match.coupling_score } : {}),
+      status: 'established' as const,
+      module_root: module.root,
+      ...(module.module_path ? { module_path: module.module_path } : {}),
+    };
+  });
+
+  return {
+    boundaries: linkEstablished(projectRoot, [...remaining, ...imported], fixed.map(entry => entry.module)),
+    imported: imported.map(b => b.name),
+    reconsidered: reconsidered.map(m => m.name),
+    unknown,
+  };
+}
+
+/**
+ * Why RefactorAgent leaves a module alone, or null when it may refactor it
+ */
+export function establishedModuleRefusal(boundary: Pick<DomainBoundary, 'name' | 'status' | 'module_root'>): string | null {
+  if (boundary.status !== 'established') return null;
+  return `Module ${boundary.name} is an established module (${boundary.module_root}) and is not refactored; rediscover with --reconsider-established ${boundary.name} to include it`;
+}
+
+/**
+ * Exported symbols of each established module used by the other modules
+ */
+export function establishedApiUsage(
+  projectRoot: string,
+  established: { name: string; files: string[]; module_root?: string; module_path?: string }[],
+  modules: { name: string; files: string[] }[]
+): Map<string, EstablishedConsumer[]> {
+  const usage = new Map<string, EstablishedConsumer[]>();
+  for (const module of established) {
+    const packages = establishedPackages(projectRoot, module);
+    const consumers: EstablishedConsumer[] = [];
+    for (const consumer of modules.filter(m => m.name !== module.name).sort((a, b) => a.name.localeCompare(b.name))) {
+      const symbols = new Set<string>();
+      for (const file of consumer.files.filter(f => f.endsWith('.go') && !f.endsWith('_test.go'))) {
+        const content = readSource(projectRoot, file);
+        if (content === null) continue;
+        const code = maskLiterals(content);
+        for (const pkg of packages) {
+          const alias = goImportAlias(content, pkg.import_path, pkg.name ?? undefined);
+          if (!alias || alias === '_' || alias === '.') continue;
+          for (const match of code.matchAll(new RegExp(`(?<![\\w.])${alias}\\.([A-Z]\\w*)`, 'g'))) {
+            symbols.add(`${pkg.dir}.${match[1]}`);
+          }
+        }
+      }
+      if (symbols.size > 0) consumers.push({ module: consumer.name, symbols: [...symbols].sort() });
+    }
+    usage.set(module.name, consumers);
+  }
+  return usage;
+}
+
+export const ESTABLISHED_HEADING = '## 確立済みモジュール (Established Modules)';
+
+const PROPOSAL_LABELS: Record<EstablishedApiProposal, string> = {
+  'constraint': '境界制約',
+  'shared-state': '共有ミュータブル状態',
+  'package-rename': 'パッケージ名の変更',
+};
+
+/**
+ * plan.md section: the established modules, what the new modules use of them,
+ * and the proposals that would change their public API
+ */
+export function renderEstablishedSection(
+  modules: { name: string; status?: 'established'; established?: { root: string; module_path?: string; consumers: EstablishedConsumer[] } }[],
+  changes: EstablishedApiChange[] = []
+): string {
+  const established = modules.filter(module => module.status === 'established' && module.established);
+  if (established.length === 0) return '';
+
+  const entries = established.map(module => {
+    const info = module.established!;
+    const consumers = info.consumers.length > 0
+      ? info.consumers.map(c => `  - ${c.module}: ${c.symbols.map(s => `\`${s}\``).join(', ')}`).join('\n')
+      : '  - (他のモジュールからの利用なし)';
+    return `- **${module.name}** (\`${info.root}\`${info.module_path ? `, \`${info.module_path}\`` : ''})\n${consumers}`;
+  });
+
+  return `
+${ESTABLISHED_HEADING}
+
+以下のモジュールは既存のモジュール構造として固定されています。再クラスタリングとリファクタリングの対象外ですが、
+依存グラフ・循環依存の検出・vf check には含まれます。新しいモジュールは各モジュールの公開APIを現状のまま利用します。
+見直す場合は \`vf discover --reconsider-established <module>\` で再分析してください。
+
+${entries.join('\n')}
+${changes.length > 0 ? `
+### 公開APIの変更が必要な提案
+
+以下の提案は確立済みモジュールの公開APIを変更しないと実施できません。モジュールの担当者と合意してから進めてください。
+
+${changes.map(change => `- ⚠️ **${change.module}** (${PROPOSAL_LABELS[change.proposal]}): ${change.description}${change.symbols.length > 0 ? `\n  - 影響するシンボル: ${change.symbols.map(s => `\`${s}\``).join(', ')}` : ''}`).join('\n')}
+` : ''}`;
+}
+
+function moduleFiles(projectRoot: string, root: string): string[] {
+  if (!fs.existsSync(path.join(projectRoot, root))) return [];
+  return fastGlob.sync(`${root}/**/*.go`, { cwd: projectRoot, ignore: IGNORED }).map(toPosixPath).sort();
+}
+
+/**
+ * Add the import edges between established modules and the rest, in both directions
+ */
+function linkEstablished(projectRoot: string, boundaries: DomainBoundary[], established: EstablishedModule[]): DomainBoundary[] {
+  const goProject = detectGoProject(projectRoot);
+  const owners = new Map<string, string>();
+  for (const boundary of boundaries) {
+    const module = established.find(m => m.name === boundary.name && boundary.status === 'established');
+    for (const pkg of module
+      ? establishedPackages(projectRoot, { files: boundary.files, module_root: module.root, module_path: module.module_path })
+      : packageDirs(projectRoot, boundary.files).map(dir => ({ dir, import_path: goPackageImportPath(projectRoot, dir, goProject) }))) {
+      if (pkg.import_path && !owners.has(pkg.import_path)) owners.set(pkg.import_path, boundary.name);
+    }
+  }
+
+  const isEstablished = (name: string) => boundaries.some(b => b.name === name && b.status === 'established');
+  return boundaries.map(boundary => {
+    const edges = new Set<string>();
+    for (const file of boundary.files.filter(f => f.endsWith('.go'))) {
+      const content = readSource(projectRoot, file);
+      if (content === null) continue;
+      for (const { path: importPath } of goImports(content)) {
+        const owner = owners.get(importPath);
+        if (owner && owner !== boundary.name && (boundary.status === 'established' || isEstablished(owner))) edges.add(owner);
+      }
+    }
+    if (edges.size === 0) return boundary;
+    const internal = [...new Set([...(boundary.dependencies?.internal ?? []), ...edges])];
+    return { ...boundary, dependencies: { ...boundary.dependencies, internal } };
+  });
+}
+
+/**
+ * Import path, directory and declared name of each package of an established module
+ */
+function establishedPackages(
+  projectRoot: string,
+  module: { files: string[]; module_root?: string; module_path?: string }
+): { dir: string; import_path: string | null; name: string | null }[] {
+  return packageDirs(projectRoot, module.files).map(dir => {
+    let importPath: string | null;
+    if (module.module_path && module.module_root) {
+      const sub = path.posix.relative(module.module_root, dir);
+      importPath = sub ? `${module.module_path}/${sub}` : module.module_path;
+    } else {
+      importPath = goPackageImportPath(projectRoot, dir);
+    }
+    const first = module.files.find(file => path.posix.dirname(toPosixPath(file)) === dir);
+    const content = first ? readSource(projectRoot, first) : null;
+    return { dir, import_path: importPath, name: content !== null ? goPackageName(content) : null };
+  }).filter((pkg): pkg is { dir: string; import_path: string; name: string | null } => pkg.import_path !== null);
+}
+
+function packageDirs(projectRoot: string, files: string[]): string[] {
+  return [...new Set(files
+    .filter(file => file.endsWith('.go'))
+    .map(file => path.posix.dirname(toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file))))].sort();
+}
+
+function readSource(projectRoot: string, file: string): string | null {
+  try {
+    return fs.readFileSync(path.resolve(projectRoot, file), 'utf8');
+  } catch {
+    return null;
+  }
+}
@@ -0,0 +1,107 @@
+import * as fs from 'fs';
+import * as fsp from 'fs/promises';
+import * as path from 'path';
+
+/**
+ * preserve: keep the endings of the file being overwritten (new files are written as generated)
+ */
+export type LineEndingMode = 'preserve' | 'lf' | 'crlf';
+
+export interface SharingRetryOptions {
+  attempts?: number;
+  delayMs?: number;
+  platform?: NodeJS.Platform;
+}
+
+const DEFAULT_RETRY_ATTEMPTS = 5;
+const DEFAULT_RETRY_DELAY_MS = 100;
+
+/**
+ * Dominant line ending of a text, or null when it has no line breaks
+ */
+export function detectLineEnding(content: string): '\r\n' | '\n' | null {
+  const crlf = content.match(/\r\n/g)?.length ?? 0;
+  const lf = (content.match(/\n/g)?.length ?? 0) - crlf;
+  if (crlf === 0 && lf === 0) return null;
+  return crlf > lf ? '\r\n' : '\n';
+}
+
+/**
+ * Convert content to the endings selected by mode; `existing` is the content being replaced
+ */
+export function applyLineEndings(content: string, mode: LineEndingMode, existing?: string | null): string {
+  const target = mode === 'crlf' ? '\r\n' : mode === 'lf' ? '\n' : existing ? detectLineEnding(existing) : null;
+  if (!target) return content;
+
+  const normalized = content.replace(/\r\n/g, '\n');
+  return target === '\n' ? normalized : normalized.replace(/\n/g, '\r\n');
+}
+
+/**
+ * Whether an fs error means another process (editor, antivirus, indexer) holds the file open.
+ * Windows reports those sharing violations as EBUSY, EPERM or EACCES.
+ */
+export function isSharingViolation(error: unknown, platform: NodeJS.Platform = process.platform): boolean {
+  const code = (error as NodeJS.ErrnoException | undefined)?.code;
+  if (code === 'EBUSY') return true;
+  return platform === 'win32' && (code === 'EPERM' || code === 'EACCES');
+}
+
+/**
+ * Run a synchronous fs operation, retrying with backoff while the file is locked by another process
+ */
+export function withSharingRetry<T>(operation: () => T, options: SharingRetryOptions = {}): T {
+  const attempts = options.attempts ?? DEFAULT_RETRY_ATTEMPTS;
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return operation();
+    } catch (error) {
+      if (attempt >= attempts || !isSharingViolation(error, options.platform)) throw error;
+      sleepSync((options.delayMs ?? DEFAULT_RETRY_DELAY_MS) * attempt);
+    }
+  }
+}
+
+/**
+ * Async variant of withSharingRetry
+ */
+export async function withSharingRetryAsync<T>(operation: () => Promise<T>, options: SharingRetryOptions = {}): Promise<T> {
+  const attempts = options.attempts ?? DEFAULT_RETRY_ATTEMPTS;
+  for (let attempt = 1; ; attempt++) {
+    try {
+      return await operation();
+    } catch (error) {
+      if (attempt >= attempts || !isSharingViolation(error, options.platform)) throw error;
+      await new Promise(resolve => setTimeout(resolve, (options.delayMs ?? DEFAULT_RETRY_DELAY_MS) * attempt));
+    }
+  }
+}
+
+export function writeFileWithRetry(filePath: string, content: string | Buffer): void {
+  withSharingRetry(() => fs.writeFileSync(filePath, content));
+}
+
+export function renameWithRetry(from: string, to: string): void {
+  withSharingRetry(() => fs.renameSync(from, to));
+}
+
+/**
+ * Write a text file, creating its directory, applying the line-ending mode and
+ * retrying while another process holds the file open
+ */
+export async function writeTextFile(filePath: string, content: string, mode: LineEndingMode = 'preserve'): Promise<void> {
+  const existing = mode === 'preserve' ? await fsp.readFile(filePath, 'utf8').catch(() => null) : null;
+  await fsp.mkdir(path.dirname(filePath), { recursive: true });
+  await withSharingRetryAsync(() => fsp.writeFile(filePath, applyLineEndings(content, mode, existing)));
+}
+
+/**
+ * ISO timestamp usable as a file or directory name on every platform (no ':' for NTFS)
+ */
+export function fileSafeTimestamp(date: Date = new Date()): string {
+  return date.toISOString().replace(/[:.]/g, '-');
+}
+
+function sleepSync(ms: number): void {
+  Atomics.wait(new Int32Array(new SharedArrayBuffer(4)), 0, 0, ms);
+}
@@ -1,5 +1,6 @@
 import * as fs from 'fs';
 import * as path from 'path';
+import { migrateWorkspaceArtifacts, portableArtifact, resolveWorkspacePath, toWorkspacePath } from './workspace-paths.js';
 
 /**
  * VibeFlow出力ファイルパス管理ユーティリティ
@@ -14,6 +15,18 @@ export class VibeFlowPaths {
     this.projectRoot = projectRoot;
     this.outputRoot = path.join(projectRoot, VibeFlowPaths.OUTPUT_DIR);
     this.ensureOutputDirectories();
+    this.migrateArtifacts();
+  }
+
+  /**
+   * 既存成果物の絶対パスをワークスペース相対に移行（初回のみ）
+   */
+  private migrateArtifacts(): void {
+    try {
+      migrateWorkspaceArtifacts(this.projectRoot);
+    } catch (error) {
+      console.warn('⚠️  成果物パスの移行に失敗:', error);
+    }
   }
 
   /**
@@ -49,6 +62,83 @@ export class VibeFlowPaths {
     return path.join(this.outputRoot, 'auto-boundary-discovery-report.json');
   }
 
+  /**
+   * モジュールグラフ（vf discover --graph）ファイルパス
+   */
+  boundaryGraphPath(extension: string): string {
+    return path.join(this.outputRoot, `boundary-graph.${extension}`);
+  }
+
+  /**
+   * 計画の判断ごとの ADR（Architecture Decision Record）を置くディレクトリ（プロジェクトの docs/adr）
+   */
+  get adrDir(): string {
+    return path.join(this.projectRoot, 'docs', 'adr');
+  }
+
+  /**
+   * 計画のC4図（vf plan --c4）ファイルパス
+   */
+  c4DiagramPath(name: string, extension: string): string {
+    return path.join(this.outputRoot, `c4-${name}.${extension}`);
+  }
+
+  /**
+   * 比較用の候補計画（vf plan --compare）ファイルパス
+   */
+  candidatePlanPath(style: string, extension: string): string {
+    return path.join(this.outputRoot, `plan.${style}.${extension}`);
+  }
+
+  /**
+   * 候補アーキテクチャの比較（vf plan --compare）ファイルパス
+   */
+  get architectureComparisonPath(): string {
+    return path.join(this.outputRoot, 'architecture-comparison.md');
+  }
+
+  /**
+   * 境界間の依存サイクルと切断候補のレポートファイルパス
+   */
+  get boundaryCyclesPath(): string {
+    return path.join(this.outputRoot, 'boundary-cycles.json');
+  }
+
+  /**
+   * 解析グラフ（ファイル・シンボル・呼び出し・テーブルアクセス）の SQLite データベースパス
+   */
+  get analysisDbPath(): string {
+    return path.join(this.outputRoot, 'analysis.db');
+  }
+
+  /**
+   * ファイルの埋め込みベクトル（boundary.yaml embeddings）のキャッシュファイルパス
+   */
+  get embeddingsCachePath(): string {
+    return path.join(this.outputRoot, 'embeddings-cache.json');
+  }
+
+  /**
+   * 前回のドメインマップとの差分（vf discover --compare）ファイルパス
+   */
+  get domainMapDiffPath(): string {
+    return path.join(this.outputRoot, 'domain-map-diff.json');
+  }
+
+  /**
+   * 境界発見の品質ゲート（凝集度・結合度・モジュールのファイル数）の判定結果ファイルパス
+   */
+  get discoveryGatesPath(): string {
+    return path.join(this.outputRoot, 'discovery-gates.json');
+  }
+
+  /**
+   * スコープ別境界発見レポート（スコープをまたぐ参照）ファイルパス
+   */
+  get scopeReportPath(): string {
+    return path.join(this.outputRoot, 'scope-report.json');
+  }
+
   /**
    * アーキテクチャプランファイルパス
    */
@@ -56,6 +146,13 @@ export class VibeFlowPaths {
     return path.join(this.outputRoot, 'plan.md');
   }
 
+  /**
+   * 機械可読なアーキテクチャプランファイルパス
+   */
+  get planJsonPath(): string {
+    return path.join(this.outputRoot, 'plan.json');
+  }
+
   /**
    * パッチディレクトリパス
    */
@@ -91,6 +188,62 @@ export class VibeFlowPaths {
     return path.join(this.outputRoot, 'logs', 'vibeflow.log');
   }
 
+  /**
+   * 実行メトリクスストアファイルパス
+   */
+  get performanceStorePath(): string {
+    return path.join(this.outputRoot, 'performance.json');
+  }
+
+  /**
+   * レポート出力ディレクトリパス
+   */
+  get reportsDir(): string {
+    return path.join(this.outputRoot, 'reports');
+  }
+
+  /**
+   * サービス化のひな形（API定義・エントリポイント）出力ディレクトリパス
+   */
+  get servicesDir(): string {
+    return path.join(this.outputRoot, 'services');
+  }
+
+  /**
+   * ストラングラーフィグ移行のファサードのひな形出力ディレクトリパス
+   */
+  get stranglerDir(): string {
+    return path.join(this.outputRoot, 'strangler');
+  }
+
+  /**
+   * ランタイムプロファイル（リクエストパスごとの関数呼び出し回数）ファイルパス
+   */
+  get runtimeProfilePath(): string {
+    return path.join(this.outputRoot, 'runtime-profile.json');
+  }
+
+  /**
+   * ドリフト計測の基準（最後に検証が通った時点のワークスペース）ファイルパス
+   */
+  get driftBaselinePath(): string {
+    return path.join(this.outputRoot, 'drift-baseline.json');
+  }
+
+  /**
+   * 失敗したLLM応答の保存ディレクトリパス（vf bugreport に同梱）
+   */
+  get failedResponsesDir(): string {
+    return path.join(this.outputRoot, 'failed-responses');
+  }
+
+  /**
+   * 非同期バッチ（vf refactor --async-batch）のジョブと応答の保存ディレクトリパス
+   */
+  get batchesDir(): string {
+    return path.join(this.outputRoot, 'batches');
+  }
+
   /**
    * 出力ルートディレクトリパス
    */
@@ -105,6 +258,28 @@ export class VibeFlowPaths {
     return path.relative(this.projectRoot, filePath);
   }
 
+  /**
+   * 成果物に保存する形式（ワークスペース相対・スラッシュ区切り）に変換
+   */
+  toPortablePath(filePath: string): string {
+    return toWorkspacePath(this.projectRoot, filePath);
+  }
+
+  /**
+   * 成果物に保存されたパスを現在のワークスペースで解決
+   */
+  resolvePortablePath(storedPath: string): string {
+    return resolveWorkspacePath(this.projectRoot, storedPath);
+  }
+
+  /**
+   * 成果物JSONをポータブルなパスで書き出す
+   */
+  writeArtifact(filePath: string, data: unknown): void {
+    fs.mkdirSync(path.dirname(filePath), { recursive: true });
+    fs.writeFileSync(filePath, JSON.stringify(portableArtifact(this.projectRoot, data), null, 2));
+  }
+
   /**
    * .gitignoreに.vibeflow/を追加
    */
@@ -0,0 +1,155 @@
+import * as path from 'path';
+import { RefactoredFile } from '../types/refactor.js';
+import { goImports, goPackageName } from './go-load-check.js';
+import { csvCell } from './metrics-aggregator.js';
+import {
+  FILE_PROCESSING_V4_FIELDS,
+  FileProcessingRecord,
+  FileTransformation,
+  PROCESSING_METHODS,
+  ProcessingMethod,
+} from './performance-store.js';
+import { toPosixPath } from './workspace-paths.js';
+
+export type FileProcessingFormat = 'table' | 'csv' | 'json' | 'jsonl';
+
+export const FILE_PROCESSING_FORMATS: FileProcessingFormat[] = ['table', 'csv', 'json', 'jsonl'];
+
+/** Column order of table and csv output */
+export const FILE_PROCESSING_COLUMNS = [
+  'run_id',
+  'file',
+  'module',
+  'method',
+  'status',
+  'duration_ms',
+  ...FILE_PROCESSING_V4_FIELDS,
+  'fallback_reason',
+  'error',
+  'recorded_at',
+] as const;
+
+export interface MethodProcessingSummary {
+  method: ProcessingMethod;
+  files: number;
+  failed: number;
+  /** null when no record of the method has a duration */
+  avg_duration_ms: number | null;
+}
+
+export interface ProcessedOutput {
+  path: string;
+  content: string;
+}
+
+/**
+ * What processing did to a legacy file, judged from the outputs generated from it
+ */
+export function describeTransformations(source: ProcessedOutput, outputs: ProcessedOutput[], method: ProcessingMethod): FileTransformation[] {
+  const sourcePath = toPosixPath(source.path);
+  const code = outputs.filter(output => !output.path.endsWith('_test.go'));
+  const transformations: FileTransformation[] = [];
+
+  if (code.some(output => path.posix.dirname(toPosixPath(output.path)) !== path.posix.dirname(sourcePath))) {
+    transformations.push('moved');
+  }
+
+  if (sourcePath.endsWith('.go')) {
+    const sourcePackage = goPackageName(source.content);
+    if (sourcePackage !== null && code.some(output => (goPackageName(output.content) ?? sourcePackage) !== sourcePackage)) {
+      transformations.push('package-renamed');
+    }
+    const sourceImports = new Set(goImports(source.content).map(i => i.path));
+    const outputImports = new Set(code.flatMap(output => goImports(output.content).map(i => i.path)));
+    if ([...outputImports].some(i => !sourceImports.has(i)) || [...sourceImports].some(i => !outputImports.has(i))) {
+      transformations.push('imports-rewritten');
+    }
+  }
+
+  if (code.length > 1) transformations.push('split');
+
+  if (method === 'template' || method === 'template-fallback') {
+    const generated = new Set(code.map(output => templateOutputKind(output.path)).filter((kind): kind is FileTransformation => kind !== null));
+    transformations.push(...(['template-entity', 'template-repository', 'template-handler'] as const).filter(kind => generated.has(kind)));
+  }
+
+  return transformations;
+}
+
+/**
+ * Every file generated from a legacy file: implementations, interfaces and tests
+ */
+export function refactoredOutputs(result: RefactoredFile): ProcessedOutput[] {
+  return [...result.refactored_files, ...result.interfaces, ...result.tests];
+}
+
+export function byteSize(outputs: { content: string }[]): number {
+  return outputs.reduce((sum, output) => sum + Buffer.byteLength(output.content, 'utf8'), 0);
+}
+
+/**
+ * Files, failures and mean duration per processing method, in a fixed method order
+ */
+export function summarizeProcessing(records: Pick<FileProcessingRecord, 'method' | 'status' | 'duration_ms'>[]): MethodProcessingSummary[] {
+  return PROCESSING_METHODS
+    .map(method => {
+      const own = records.filter(r => r.method === method);
+      const timed = own.filter(r => r.duration_ms !== undefined && r.duration_ms !== null);
+      return {
+        method,
+        files: own.length,
+        failed: own.filter(r => r.status === 'failed').length,
+        avg_duration_ms: timed.length > 0 ? Math.round(timed.reduce((sum, r) => sum + r.duration_ms!, 0) / timed.length) : null,
+      };
+    })
+    .filter(summary => summary.files > 0);
+}
+
+/**
+ * Per-method table of a run summary (no lines when nothing was processed)
+ */
+export function formatMethodTable(summaries: MethodProcessingSummary[], indent = '   '): string[] {
+  if (summaries.length === 0) return [];
+  const row = (cells: string[]) => `${indent}${cells[0].padEnd(18)}${cells.slice(1).map(c => c.padStart(8)).join('')}`;
+  return [
+    row(['Method', 'Files', 'Failed', 'Avg']),
+    ...summaries.map(s => row([s.method, String(s.files), String(s.failed), s.avg_duration_ms === null ? '-' : formatDuration(s.avg_duration_ms)])),
+  ];
+}
+
+export function formatFileProcessing(records: FileProcessingRecord[], format: FileProcessingFormat): string {
+  if (format === 'json') return JSON.stringify(records, null, 2) + '\n';
+  if (format === 'jsonl') return records.map(record => JSON.stringify(record) + '\n').join('');
+
+  const cell = (record: FileProcessingRecord, column: typeof FILE_PROCESSING_COLUMNS[number]): string => {
+    const value = record[column];
+    if (value === undefined || value === null) return '';
+    return Array.isArray(value) ? value.join(';') : String(value);
+  };
+  if (format === 'csv') {
+    return [
+      FILE_PROCESSING_COLUMNS.join(','),
+      ...records.map(record => FILE_PROCESSING_COLUMNS.map(column => csvCell(cell(record, column))).join(',')),
+    ].join('\n') + '\n';
+  }
+
+  if (records.length === 0) return '(no records)\n';
+  const columns = ['run_id', 'file', 'method', 'status', 'duration_ms', 'bytes_in', 'bytes_out', 'transformations', 'verification', 'template'] as const;
+  const cells = records.map(record => columns.map(column => cell(record, column) || '-'));
+  const widths = columns.map((column, index) => Math.max(column.length, ...cells.map(row => row[index].length)));
+  const line = (values: string[]) => values.map((value, index) => value.padEnd(widths[index])).join('  ').trimEnd();
+  return [line([...columns]), line(widths.map(width => '-'.repeat(width))), ...cells.map(line)].join('\n') + '\n';
+}
+
+function templateOutputKind(file: string): FileTransformation | null {
+  const name = path.posix.basename(toPosixPath(file));
+  const dirs = path.posix.dirname(toPosixPath(file)).split('/');
+  if (name.includes('repository') || dirs.includes('infrastructure')) return 'template-repository';
+  if (name.includes('handler') || dirs.includes('handler')) return 'template-handler';
+  if (dirs.includes('domain') && name !== 'usecase.go') return 'template-entity';
+  return null;
+}
+
+function formatDuration(ms: number): string {
+  return ms < 1000 ? `${ms}ms` : `${(ms / 1000).toFixed(1)}s`;
+}
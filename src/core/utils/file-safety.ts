@@ -1,6 +1,7 @@
 import * as fs from 'fs/promises';
 import * as path from 'path';
 import { createHash } from 'crypto';
+import { LineEndingMode, fileSafeTimestamp, withSharingRetryAsync, writeTextFile } from './file-io.js';
 
 export interface BackupInfo {
   originalPath: string;
@@ -11,10 +12,12 @@ export interface BackupInfo {
 
 export class FileSafetyManager {
   private backups: Map<string, BackupInfo> = new Map();
+  private projectRoot: string;
   private backupDir: string;
 
   constructor(projectRoot: string) {
-    this.backupDir = path.join(projectRoot, '.vibeflow', 'backups', new Date().toISOString().replace(/:/g, '-'));
+    this.projectRoot = projectRoot;
+    this.backupDir = path.join(projectRoot, '.vibeflow', 'backups', fileSafeTimestamp());
   }
 
   /**
@@ -30,7 +33,7 @@ export class FileSafetyManager {
       const checksum = this.calculateChecksum(content);
 
       // Create backup path
-      const relativePath = path.relative(process.cwd(), filePath);
+      const relativePath = path.relative(this.projectRoot, path.resolve(this.projectRoot, filePath));
       const backupPath = path.join(this.backupDir, relativePath);
       const backupFileDir = path.dirname(backupPath);
 
@@ -38,7 +41,7 @@ export class FileSafetyManager {
       await fs.mkdir(backupFileDir, { recursive: true });
 
       // Write backup
-      await fs.writeFile(backupPath, content);
+      await withSharingRetryAsync(() => fs.writeFile(backupPath, content));
 
       const backupInfo: BackupInfo = {
         originalPath: filePath,
@@ -67,7 +70,7 @@ export class FileSafetyManager {
     }
 
     const backupContent = await fs.readFile(backup.backupPath, 'utf8');
-    await fs.writeFile(filePath, backupContent);
+    await withSharingRetryAsync(() => fs.writeFile(filePath, backupContent));
     console.log(`   ✅ Restored: ${filePath}`);
   }
 
@@ -85,7 +88,7 @@ export class FileSafetyManager {
   /**
    * Create a safe write operation with automatic backup
    */
-  async safeWrite(filePath: string, content: string): Promise<void> {
+  async safeWrite(filePath: string, content: string, lineEndings: LineEndingMode = 'preserve'): Promise<void> {
     // Check if file exists
     const exists = await fs.access(filePath).then(() => true).catch(() => false);
     
@@ -94,12 +97,8 @@ export class FileSafetyManager {
       await this.backupFile(filePath);
     }
 
-    // Ensure directory exists
-    const dir = path.dirname(filePath);
-    await fs.mkdir(dir, { recursive: true });
-
-    // Write new content
-    await fs.writeFile(filePath, content);
+    // Write new content (creates the directory)
+    await writeTextFile(filePath, content, lineEndings);
   }
 
   /**
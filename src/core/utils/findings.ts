@@ -0,0 +1,385 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { ModuleManifestStore, ModuleOutputManifest } from './module-manifest.js';
+import { SourceFile, SourceLocation, formatLocation } from './source-positions.js';
+import { ConstraintViolation } from './boundary-constraints.js';
+import { BusinessRule } from '../types/business-logic.js';
+import { CodeAnalyzer, FileInfo } from './code-analyzer.js';
+import { SharedStateFinding } from './shared-state.js';
+import { ServiceRequirements } from './service-deployment.js';
+
+export type FindingKind =
+  | 'boundary-violation'
+  | 'cycle-edge'
+  | 'business-rule'
+  | 'duplicate-code'
+  | 'dead-code'
+  | 'contract-break'
+  | 'shared-state'
+  | 'service-rule'
+  | 'drift';
+
+export type FindingSeverity = 'error' | 'warning' | 'note';
+
+/**
+ * A located analysis result, shared by the JSON report, SARIF and console output
+ */
+export interface Finding {
+  kind: FindingKind;
+  /** Rule within the kind, e.g. forbiddenDependencies or validation */
+  rule: string;
+  severity: FindingSeverity;
+  message: string;
+  location: SourceLocation;
+  /** Source text at the location; used to find it again after files move */
+  snippet?: string;
+  /** Enclosing declaration, used to pick the output a moved finding went to */
+  symbol?: string;
+  /** Other ends of the finding (remaining edges of a cycle, other copies of duplicated code) */
+  related?: SourceLocation[];
+  /** Original `file:line:col` when the location was recomputed after apply */
+  relocated_from?: string;
+  /** The snippet could not be found again after apply; the location may be out of date */
+  stale?: boolean;
+}
+
+export interface FindingsReport {
+  generated_at: string;
+  findings: Finding[];
+}
+
+/**
+ * Boundary violations located at the import (or use) of the forbidden module
+ */
+export function constraintViolationFindings(
+  projectRoot: string,
+  violations: ConstraintViolation[],
+  modules: { name: string; files: string[] }[]
+): Finding[] {
+  const findings: Finding[] = [];
+
+  for (const violation of violations) {
+    const [from, to] = violation.modules;
+    const sourceFiles = modules.find(m => m.name === from)?.files ?? [];
+    const locations: { location: SourceLocation; snippet: string }[] = [];
+
+    if (violation.constraint === 'forbiddenDependencies') {
+      for (const file of sourceFiles) {
+        const source = SourceFile.read(projectRoot, file);
+        const location = source?.findImportOf(to);
+        if (source && location) locations.push({ location, snippet: source.textAt(location) });
+      }
+    }
+
+    if (locations.length === 0) {
+      const source = sourceFiles.map(file => SourceFile.read(projectRoot, file)).find(Boolean);
+      const location = source && (source.findSnippet('package') ?? source.locateLine(1));
+      if (!source || !location) continue;
+      locations.push({ location, snippet: source.textAt(location) });
+    }
+
+    for (const { location, snippet } of locations) {
+      findings.push({
+        kind: 'boundary-violation',
+        rule: violation.constraint,
+        severity: 'error',
+        message: violation.reason ? `${violation.message} (${violation.reason})` : violation.message,
+        location,
+        snippet,
+      });
+    }
+  }
+
+  return findings;
+}
+
+/**
+ * One finding per import edge of each dependency cycle; the other edges are related locations
+ */
+export function cycleEdgeFindings(analyzer: CodeAnalyzer, cycles: string[][], files: FileInfo[]): Finding[] {
+  const findings: Finding[] = [];
+
+  for (const cycle of cycles) {
+    const chain = [...cycle, cycle[0]].join(' → ');
+    const edges = cycle.map((from, i) => {
+      const file = files.find(f => f.relativePath === from);
+      const to = cycle[(i + 1) % cycle.length];
+      const location = file ? analyzer.locateDependency(file, to) : null;
+      return location && file ? { location, snippet: new SourceFile(file.relativePath, file.content).textAt(location), to } : null;
+    }).filter((edge): edge is { location: SourceLocation; snippet: string; to: string } => edge !== null);
+
+    for (const edge of edges) {
+      findings.push({
+        kind: 'cycle-edge',
+        rule: 'circular-dependency',
+        severity: 'warning',
+        message: `imports ${edge.to}, closing the cycle ${chain}`,
+        location: edge.location,
+        snippet: edge.snippet,
+        related: edges.filter(other => other !== edge).map(other => other.location),
+      });
+    }
+  }
+
+  return findings;
+}
+
+/**
+ * Business rules located at their code; rules with only a line number cover that line
+ */
+export function businessRuleFindings(projectRoot: string, rules: BusinessRule[]): Finding[] {
+  return rules.flatMap(rule => {
+    const source = SourceFile.read(projectRoot, rule.location.file);
+    const { function: _, ...position } = rule.location;
+    const location = isExact(position) ? position : source?.locateLine(rule.location.line);
+    if (!location) return [];
+
+    return [{
+      kind: 'business-rule' as const,
+      rule: rule.type,
+      severity: 'note' as const,
+      message: rule.description,
+      location,
+      snippet: source?.textAt(location) ?? rule.code,
+      ...(rule.location.function ? { symbol: rule.location.function } : {}),
+    }];
+  });
+}
+
+/**
+ * Shared mutable state located at the variable declaration; every access is a related location.
+ * Unresolved findings are errors since they block refactor, accepted ones are kept as notes.
+ */
+export function sharedStateFindings(projectRoot: string, state: SharedStateFinding[]): Finding[] {
+  return state.map(item => {
+    const source = SourceFile.read(projectRoot, item.declared_at.file);
+    const resolution = item.resolution
+      ? `resolved: ${item.resolution.strategy}`
+      : `unresolved; suggested: ${item.suggested_resolutions.map(r => r.strategy).join(', ')}`;
+
+    return {
+      kind: 'shared-state' as const,
+      rule: item.access,
+      severity: item.resolution ? 'note' as const : 'error' as const,
+      message: `package ${item.kind} ${item.variable} is shared by modules ${item.modules.join(', ')} (${resolution})`,
+      location: item.declared_at,
+      snippet: source?.textAt(item.declared_at) ?? item.variable,
+      symbol: item.variable,
+      related: item.accesses.map(access => access.location),
+    };
+  });
+}
+
+/**
+ * Rules that only apply to modules marked `deployment: service`: no queries on
+ * tables owned across the service boundary, and every call from another module
+ * must target an operation of the planned API definition. A missing entry point
+ * is a warning until the scaffold is moved into place.
+ *
+ * @param current - requirements recomputed from the current code
+ * @param planned - requirements recorded in plan.json
+ */
+export function serviceRuleFindings(projectRoot: string, current: ServiceRequirements[], planned: ServiceRequirements[]): Finding[] {
+  return current.flatMap(service => {
+    const plan = planned.find(p => p.module === service.module);
+    const operations = new Set(plan?.api.operations.map(op => op.name) ?? []);
+    const snippet = (location: SourceLocation) => SourceFile.read(projectRoot, location.file)?.textAt(location);
+
+    const tables = service.foreign_table_access.map(access => ({
+      kind: 'service-rule' as const,
+      rule: 'foreign-table-access',
+      severity: 'error' as const,
+      message: access.module === service.module
+        ? `service ${service.module} queries table ${access.table} owned by ${access.owner}; use ${access.owner}'s interface or events`
+        : `${access.module} queries table ${access.table} owned by service ${service.module}; call the service API instead`,
+      location: access.location,
+      snippet: snippet(access.location) ?? access.table,
+      ...(access.function ? { symbol: access.function } : {}),
+    }));
+    const calls = service.network_calls.filter(call => !operations.has(call.operation)).map(call => ({
+      kind: 'service-rule' as const,
+      rule: 'undeclared-operation',
+      severity: 'error' as const,
+      message: `${call.caller} calls ${call.operation} of service ${service.module}, which is not in its API definition (${plan?.api.path ?? 'no plan'}); run "vf plan" again`,
+      location: call.location,
+      snippet: snippet(call.location) ?? call.operation,
+      ...(call.function ? { symbol: call.function } : {}),
+    }));
+    const entrypoint = fs.existsSync(path.join(projectRoot, service.entrypoint)) ? [] : [{
+      kind: 'service-rule' as const,
+      rule: 'missing-entrypoint',
+      severity: 'warning' as const,
+      message: `service ${service.module} has no entry point ${service.entrypoint} yet${plan?.scaffolds?.length ? ` (scaffold: ${plan.scaffolds.join(', ')})` : ''}`,
+      location: { file: service.entrypoint, line: 1, column: 1, offset: 0, end_line: 1, end_column: 1, end_offset: 0 },
+    }];
+
+    return [...tables, ...calls, ...entrypoint];
+  });
+}
+
+/**
+ * Recompute locations after apply: findings in files that moved or were
+ * regenerated are searched for in the outputs recorded by the module manifests.
+ */
+export function relocateFindings(projectRoot: string, findings: Finding[], manifests: ModuleOutputManifest[]): Finding[] {
+  const entries = manifests.flatMap(m => m.files);
+
+  return findings.map(finding => {
+    const { stale: _, ...current } = finding;
+    const file = finding.location.file;
+    const original = SourceFile.read(projectRoot, file);
+    const snippet = finding.snippet;
+
+    // Unchanged in place
+    if (original && snippet && original.textAt(finding.location) === snippet) return current;
+
+    const candidates = [
+      ...(original ? [file] : []),
+      ...entries
+        .filter(e => e.source === file || e.path === file)
+        .sort((a, b) => Number(b.symbols.includes(finding.symbol ?? '')) - Number(a.symbols.includes(finding.symbol ?? '')))
+        .map(e => e.path),
+    ];
+
+    for (const candidate of [...new Set(candidates)]) {
+      const source = candidate === file ? original : SourceFile.read(projectRoot, candidate);
+      if (!source) continue;
+      const location = snippet
+        ? source.findSnippet(snippet)
+        : finding.symbol ? source.findDeclaration(finding.symbol) : null;
+      if (location) {
+        return {
+          ...current,
+          location,
+          relocated_from: finding.relocated_from ?? formatLocation(finding.location),
+        };
+      }
+    }
+
+    return { ...current, stale: true };
+  });
+}
+
+export function formatFinding(finding: Finding): string {
+  const relocated = finding.relocated_from ? ` (was ${finding.relocated_from})` : '';
+  return `${formatLocation(finding.location)}: ${finding.severity}: ${finding.message} [${finding.kind}/${finding.rule}]${relocated}`;
+}
+
+/**
+ * SARIF 2.1.0 log for code scanning / PR annotations.
+ * Columns are UTF-8 byte columns like the JSON report; byteOffset/byteLength are exact.
+ */
+export function toSarif(findings: Finding[]): object {
+  const ruleIds = [...new Set(findings.map(ruleId))].sort();
+
+  return {
+    $schema: 'https://json.schemastore.org/sarif-2.1.0.json',
+    version: '2.1.0',
+    runs: [{
+      tool: {
+        driver: {
+          name: 'vibeflow',
+          informationUri: 'https://github.com/t3ta/vibeflow',
+          rules: ruleIds.map(id => ({ id })),
+        },
+      },
+      results: findings.map(finding => ({
+        ruleId: ruleId(finding),
+        level: finding.severity,
+        message: { text: finding.message },
+        locations: [sarifLocation(finding.location)],
+        ...(finding.related?.length ? { relatedLocations: finding.related.map(sarifLocation) } : {}),
+      })),
+    }],
+  };
+}
+
+/**
+ * FindingsReporter - 位置情報付き解析結果の出力
+ *
+ * Writes .vibeflow/reports/findings.json and findings.sarif from the same
+ * findings so every output points at the same file:line:col.
+ */
+export class FindingsReporter {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  get reportPath(): string {
+    return path.join(this.paths.reportsDir, 'findings.json');
+  }
+
+  get sarifPath(): string {
+    return path.join(this.paths.reportsDir, 'findings.sarif');
+  }
+
+  load(): FindingsReport | null {
+    try {
+      return JSON.parse(fs.readFileSync(this.reportPath, 'utf8'));
+    } catch {
+      return null;
+    }
+  }
+
+  write(findings: Finding[]): FindingsReport {
+    const sorted = [...findings].sort((a, b) =>
+      a.location.file.localeCompare(b.location.file) || a.location.offset - b.location.offset);
+    const report: FindingsReport = { generated_at: new Date().toISOString(), findings: sorted };
+
+    this.paths.writeArtifact(this.reportPath, report);
+    fs.writeFileSync(this.sarifPath, JSON.stringify(toSarif(sorted), null, 2));
+    return report;
+  }
+
+  /**
+   * Recompute the stored findings against the files written by the last apply
+   *
+   * @returns number of findings whose location changed, or null without a report
+   */
+  relocate(): { relocated: number; stale: number } | null {
+    const report = this.load();
+    if (!report) return null;
+
+    const store = new ModuleManifestStore(this.projectRoot);
+    const manifests = store.listModules()
+      .map(name => store.load(name))
+      .filter((m): m is ModuleOutputManifest => m !== null);
+    const findings = relocateFindings(this.projectRoot, report.findings, manifests);
+    this.write(findings);
+
+    return {
+      relocated: findings.filter((f, i) => formatLocation(f.location) !== formatLocation(report.findings[i].location)).length,
+      stale: findings.filter(f => f.stale).length,
+    };
+  }
+}
+
+function ruleId(finding: Finding): string {
+  return `${finding.kind}/${finding.rule}`;
+}
+
+function sarifLocation(location: SourceLocation): object {
+  return {
+    physicalLocation: {
+      artifactLocation: { uri: location.file },
+      region: {
+        startLine: location.line,
+        startColumn: location.column,
+        endLine: location.end_line,
+        endColumn: location.end_column,
+        byteOffset: location.offset,
+        byteLength: location.end_offset - location.offset,
+      },
+    },
+  };
+}
+
+function isExact(location: Partial<SourceLocation>): location is SourceLocation {
+  return location.column !== undefined && location.end_line !== undefined &&
+    location.end_column !== undefined && location.offset !== undefined && location.end_offset !== undefined;
+}
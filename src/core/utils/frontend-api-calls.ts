@@ -0,0 +1,207 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { ExternalConsumer } from '../types/config.js';
+import { HttpRoute } from './http-routes.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * An HTTP request of the frontend code, with its path normalized to {param} segments
+ */
+export interface FrontendApiCall {
+  /** Project-relative (portable) path of the calling file */
+  file: string;
+  line: number;
+  client: 'fetch' | 'axios';
+  /** GET, POST, ...; fetch without a method option is GET */
+  method: string;
+  /** Path without origin, base URL expression or query: /api/orders/{param} */
+  path: string;
+}
+
+const FRONTEND_PATTERNS = ['**/*.ts', '**/*.tsx', '**/*.js', '**/*.jsx', '**/*.mjs'];
+const FRONTEND_IGNORE = ['**/node_modules/**', '**/dist/**', '**/build/**', '**/.next/**', '**/coverage/**', '**/*.d.ts', '**/*.test.*', '**/*.spec.*'];
+const AXIOS_METHODS = 'get|post|put|patch|delete|head|options';
+/** A string, or a template literal without nested backticks */
+const URL_LITERAL = '(\'[^\'\\n]*\'|"[^"\\n]*"|`[^`]*`)';
+
+/**
+ * fetch and axios requests (axios.get(...), axios({ url, method }), instances
+ * from axios.create) of the frontend files under the given directories, which
+ * may lie outside the project root (monorepos). Requests whose URL is not a
+ * literal are skipped.
+ */
+export function findFrontendApiCalls(projectRoot: string, dirs: string[]): FrontendApiCall[] {
+  const calls: FrontendApiCall[] = [];
+  for (const dir of dirs) {
+    const root = path.resolve(projectRoot, dir);
+    if (!fs.existsSync(root)) continue;
+    for (const relative of fastGlob.sync(FRONTEND_PATTERNS, { cwd: root, ignore: FRONTEND_IGNORE }).sort()) {
+      const file = toPosixPath(path.relative(projectRoot, path.join(root, relative)));
+      try {
+        calls.push(...parseApiCalls(fs.readFileSync(path.join(root, relative), 'utf8'), file));
+      } catch {
+        continue;
+      }
+    }
+  }
+  return calls;
+}
+
+/**
+ * Requests of one frontend source file
+ */
+export function parseApiCalls(source: string, file: string): FrontendApiCall[] {
+  const lineAt = (index: number) => source.slice(0, index).split('\n').length;
+  const calls: FrontendApiCall[] = [];
+  const add = (index: number, client: FrontendApiCall['client'], method: string, url: string) => {
+    const normalized = normalizeUrl(url);
+    if (normalized) calls.push({ file, line: lineAt(index), client, method: method.toUpperCase(), path: normalized });
+  };
+
+  for (const match of source.matchAll(new RegExp(`\\bfetch\\(\\s*${URL_LITERAL}`, 'g'))) {
+    add(match.index!, 'fetch', optionMethod(argumentsAfter(source, match.index! + match[0].length)) ?? 'GET', match[1]);
+  }
+
+  const clients = ['axios', ...[...source.matchAll(/\b(\w+)\s*=\s*axios\.create\(/g)].map(match => match[1])];
+  const client = `(?:${[...new Set(clients)].join('|')})`;
+  for (const match of source.matchAll(new RegExp(`\\b${client}\\.(${AXIOS_METHODS})\\(\\s*${URL_LITERAL}`, 'g'))) {
+    add(match.index!, 'axios', match[1], match[2]);
+  }
+  for (const match of source.matchAll(new RegExp(`\\b${client}(?:\\.request)?\\(\\s*\\{`, 'g'))) {
+    const config = argumentsAfter(source, match.index! + match[0].length);
+    const url = config.match(new RegExp(`\\burl:\\s*${URL_LITERAL}`))?.[1];
+    if (url) add(match.index!, 'axios', optionMethod(config) ?? 'GET', url);
+  }
+  for (const match of source.matchAll(new RegExp(`\\b${client}\\(\\s*${URL_LITERAL}`, 'g'))) {
+    add(match.index!, 'axios', optionMethod(argumentsAfter(source, match.index! + match[0].length)) ?? 'GET', match[1]);
+  }
+
+  return calls.sort((a, b) => a.line - b.line);
+}
+
+/**
+ * The Go route each request reaches: same method (or a route for any method), same
+ * segments with route parameters matching anything; literal segments beat parameters.
+ * `stripPrefix` is removed from request paths first (a proxy mounting the API).
+ */
+export function matchApiCalls(
+  calls: FrontendApiCall[],
+  routes: HttpRoute[],
+  options: { stripPrefix?: string } = {}
+): { matched: { call: FrontendApiCall; route: HttpRoute }[]; unmatched: FrontendApiCall[] } {
+  const matched: { call: FrontendApiCall; route: HttpRoute }[] = [];
+  const unmatched: FrontendApiCall[] = [];
+  const prefix = options.stripPrefix?.replace(/\/+$/, '');
+
+  for (const call of calls) {
+    const callPath = prefix && (call.path === prefix || call.path.startsWith(`${prefix}/`)) ? call.path.slice(prefix.length) || '/' : call.path;
+    const best = routes
+      .filter(route => route.method === 'ANY' || route.method === call.method)
+      .map(route => ({ route, score: matchScore(segments(callPath), segments(route.path)) }))
+      .filter(candidate => candidate.score >= 0)
+      .sort((a, b) => b.score - a.score || (a.route.method === 'ANY' ? 1 : 0) - (b.route.method === 'ANY' ? 1 : 0))[0];
+    if (best) matched.push({ call, route: best.route });
+    else unmatched.push(call);
+  }
+  return { matched, unmatched };
+}
+
+/**
+ * Frontend requests reaching each boundary's handlers, as external consumers of its API
+ */
+export function attachExternalConsumers<T extends { name: string; external_consumers?: ExternalConsumer[] }>(
+  boundaries: T[],
+  matched: { call: FrontendApiCall; route: HttpRoute }[]
+): T[] {
+  return boundaries.map(boundary => {
+    const { external_consumers: _, ...rest } = boundary;
+    const consumers = matched
+      .filter(({ route }) => route.module === boundary.name)
+      .map(({ call, route }) => ({
+        file: call.file,
+        line: call.line,
+        client: call.client,
+        method: call.method,
+        path: call.path,
+        route: `${route.method} ${route.path}`,
+        handler: route.handler,
+      }));
+    return (consumers.length > 0 ? { ...rest, external_consumers: consumers } : rest) as T;
+  });
+}
+
+/**
+ * plan.md section listing the routes each module must keep stable for the frontend
+ */
+export function renderExternalConsumersSection(modules: { module: string; consumers: ExternalConsumer[] }[] = []): string {
+  const consumed = modules.filter(entry => entry.consumers.length > 0);
+  if (consumed.length === 0) return '';
+
+  const entries = consumed.map(({ module, consumers }) => {
+    const byRoute = new Map<string, ExternalConsumer[]>();
+    for (const consumer of consumers) byRoute.set(consumer.route, [...(byRoute.get(consumer.route) ?? []), consumer]);
+    const routes = [...byRoute].sort(([a], [b]) => (a < b ? -1 : 1)).map(([route, callers]) =>
+      `  - \`${route}\` (${callers[0].handler}): ${callers.map(c => `${c.file}:${c.line}`).join(', ')}`);
+    return [`- **${module}**`, ...routes].join('\n');
+  });
+
+  return `
+## 外部コンシューマー (フロントエンド)
+
+以下のルートはフロントエンドから呼び出されています。パス・メソッド・レスポンス形式は互換性を保ってください。
+
+${entries.join('\n')}
+`;
+}
+
+/**
+ * Path of a URL literal with template expressions as {param}, or undefined when
+ * it is not a path (relative URL, bare expression)
+ */
+function normalizeUrl(literal: string): string | undefined {
+  let url = literal.slice(1, -1).replace(/\$\{[^}]*\}/g, '{param}');
+  url = url.replace(/^[a-z][a-z0-9+.-]*:\/\/[^/]*/i, '');
+  // A base URL expression in front of the path: `${API_URL}/orders`
+  url = url.replace(/^\{param\}(?=\/)/, '');
+  url = url.split(/[?#]/)[0];
+  if (!url.startsWith('/')) return undefined;
+  return `/${segments(url).join('/')}`;
+}
+
+function segments(urlPath: string): string[] {
+  return urlPath.split('/').filter(Boolean);
+}
+
+/**
+ * Literal segments matched, or -1 when the request does not reach the route
+ */
+function matchScore(call: string[], route: string[]): number {
+  let score = 0;
+  for (let i = 0; i < route.length; i++) {
+    const segment = route[i];
+    // Trailing wildcards: /static/*, {path...}
+    if (segment === '*' || /^\{\w+\.\.\.\}$/.test(segment)) return i < call.length ? score : -1;
+    if (i >= call.length) return -1;
+    if (/^\{\w+\}$/.test(segment)) continue;
+    if (segment !== call[i]) return -1;
+    score++;
+  }
+  return route.length === call.length ? score : -1;
+}
+
+/**
+ * Source of the call arguments following a position, up to the closing parenthesis
+ */
+function argumentsAfter(source: string, start: number): string {
+  let depth = 1;
+  for (let i = start; i < source.length; i++) {
+    if (source[i] === '(') depth++;
+    else if (source[i] === ')' && --depth === 0) return source.slice(start, i);
+  }
+  return source.slice(start);
+}
+
+function optionMethod(options: string): string | undefined {
+  return options.match(/\bmethod:\s*['"`](\w+)['"`]/)?.[1];
+}
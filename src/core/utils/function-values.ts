@@ -0,0 +1,470 @@
+import * as path from 'path';
+import { SourceFile, SourceLocation, formatLocation } from './source-positions.js';
+import { maskLiterals } from './api-surface.js';
+import { loadGoPackages } from './go-packages.js';
+import { goImportAlias } from './go-project-utils.js';
+import { goImports } from './go-load-check.js';
+import { toPosixPath } from './workspace-paths.js';
+import { escapeRegExp, splitTopLevel } from './text-utils.js';
+
+/**
+ * func-value: `order.Process` or `cleanup` without a call.
+ * method-value: `svc.Process`, bound to its receiver.
+ * method-expression: `Order.Process` or `(*Order).Process`, the receiver becomes the first argument.
+ */
+export type FunctionValueKind = 'func-value' | 'method-value' | 'method-expression';
+
+/**
+ * Where the value goes: a call argument (RegisterHandler(order.Process)), a map
+ * or slice of functions, a function-typed struct field, a variable
+ * (`cleanup := unlock` for a later `defer cleanup()`) or a return value
+ */
+export type FunctionValueContext = 'argument' | 'registry' | 'field' | 'variable' | 'return';
+
+export interface GoParam {
+  name?: string;
+  type: string;
+}
+
+export interface FunctionSymbol {
+  /** Name, or Type.Method for methods */
+  symbol: string;
+  name: string;
+  /** Receiver type without pointer and type parameters */
+  receiver?: string;
+  params: GoParam[];
+  results: string[];
+  /** Parameters and results as written, e.g. (id string) error */
+  signature: string;
+}
+
+export interface FunctionValueUse {
+  symbol: string;
+  kind: FunctionValueKind;
+  context: FunctionValueContext;
+  /** Enclosing function ('' at package level) */
+  function: string;
+  location: SourceLocation;
+}
+
+/**
+ * compatible: the declaration in the outputs has the same parameter and result types.
+ * adapter: the changed declaration was renamed and an adapter keeps the old signature under the old name.
+ * incompatible: the value uses need manual changes.
+ */
+export type ValueCompatibilityStatus = 'compatible' | 'adapter' | 'incompatible';
+
+export interface ValueCompatibilityCheck {
+  symbol: string;
+  original_signature: string;
+  new_signature: string;
+  status: ValueCompatibilityStatus;
+  /** New name of the changed declaration (adapter only) */
+  renamed_to?: string;
+  /** Output holding the adapter */
+  adapter_path?: string;
+  uses: FunctionValueUse[];
+}
+
+const CONTEXT_TYPE = /^context\.Context$/;
+
+/**
+ * Top-level functions and methods of a Go file with their parsed signatures
+ */
+export function declaredFunctions(content: string): FunctionSymbol[] {
+  const code = maskLiterals(content);
+  const symbols: FunctionSymbol[] = [];
+
+  for (const match of code.matchAll(/^func\b\s*/gm)) {
+    let index = match.index! + match[0].length;
+    let receiver: string | undefined;
+    if (code[index] === '(') {
+      const close = closing(code, index, '(', ')');
+      receiver = code.slice(index + 1, close).trim().split(/\s+/).pop()!.replace(/^\*/, '').replace(/\[.*$/, '');
+      index = skipSpaces(code, close + 1);
+    }
+    const name = code.slice(index).match(/^\w+/)?.[0];
+    if (!name) continue;
+    index += name.length;
+    if (code[index] === '[') index = closing(code, index, '[', ']') + 1;
+    index = skipSpaces(code, index);
+    if (code[index] !== '(') continue;
+
+    const close = closing(code, index, '(', ')');
+    const params = code.slice(index + 1, close);
+    const results = code.slice(close + 1, bodyStart(code, close + 1)).trim();
+    symbols.push({
+      symbol: receiver ? `${receiver}.${name}` : name,
+      name,
+      ...(receiver ? { receiver } : {}),
+      params: parseParams(params),
+      results: parseResults(results),
+      signature: `(${params.replace(/\s+/g, ' ').trim()})${results ? ` ${results.replace(/\s+/g, ' ')}` : ''}`,
+    });
+  }
+  return symbols;
+}
+
+/**
+ * Identifiers and selectors a function body uses as values: arguments,
+ * composite literal elements, assignments and returns that are not calls
+ */
+export function functionValueCandidates(body: string): string[] {
+  const code = maskLiterals(body);
+  const pattern = /(?:[(,{:=]|\breturn)\s*(\(\*[\w.]+\)\.\w+|[A-Za-z_]\w*(?:\.[A-Za-z_]\w*)*)\s*(?=[,)}\];\n]|$)/g;
+  const candidates = new Set<string>();
+  for (const match of code.matchAll(pattern)) {
+    const name = match[1].replace(/^\(\*([\w.]+)\)/, '$1');
+    if (!/^(?:nil|true|false|iota)$/.test(name)) candidates.add(name);
+  }
+  return [...candidates];
+}
+
+/**
+ * Places a file uses the given functions as values instead of calling them
+ *
+ * @param qualifier - name the file imports the declaring package by; omitted in the same package
+ */
+export function findFunctionValueUses(source: SourceFile, symbols: FunctionSymbol[], qualifier?: string): FunctionValueUse[] {
+  const code = maskLiterals(source.content);
+  const uses: FunctionValueUse[] = [];
+  const funcs = symbols.filter(s => !s.receiver);
+  const methods = symbols.filter(s => s.receiver);
+  const otherPackages = new Set(goImports(source.content).map(i => i.alias ?? i.path.split('/').pop()!));
+  if (qualifier) otherPackages.delete(qualifier);
+
+  const push = (symbol: string, kind: FunctionValueKind, expression: number, start: number, end: number) => {
+    uses.push({ symbol, kind, context: valueContext(code, expression), function: enclosingFunction(code, start), location: source.locate(start, end) });
+  };
+
+  if (funcs.length > 0) {
+    const names = funcs.map(f => escapeRegExp(f.name)).join('|');
+    const pattern = qualifier
+      ? new RegExp(`(?<![\\w.])${escapeRegExp(qualifier)}\\.(${names})\\b`, 'g')
+      : new RegExp(`(?<![\\w.])(${names})\\b`, 'g');
+    for (const match of code.matchAll(pattern)) {
+      const start = match.index! + match[0].length - match[1].length;
+      const end = start + match[1].length;
+      if (!isValuePosition(code, end) || /\bfunc\s*$/.test(code.slice(0, start))) continue;
+      push(match[1], 'func-value', match.index!, start, end);
+    }
+  }
+
+  if (methods.length > 0) {
+    const names = [...new Set(methods.map(m => escapeRegExp(m.name)))].join('|');
+    for (const match of code.matchAll(new RegExp(`(\\(\\*?[\\w.]+\\)|[\\w.]+)\\.(${names})\\b`, 'g'))) {
+      const start = match.index! + match[0].length - match[2].length;
+      const end = start + match[2].length;
+      if (!isValuePosition(code, end) || /[\w.]$/.test(code.slice(0, match.index!))) continue;
+
+      const prefix = match[1].replace(/^\(\*?|\)$/g, '');
+      const type = prefix.split('.').pop()!;
+      const expression = methods.find(m => m.name === match[2] && m.receiver === type &&
+        (prefix === type || prefix === `${qualifier}.${type}`));
+      if (expression) {
+        push(expression.symbol, 'method-expression', match.index!, start, end);
+      } else if (!otherPackages.has(prefix) && !(qualifier && prefix === qualifier) && !/^[A-Z]/.test(type)) {
+        push(methods.find(m => m.name === match[2])!.symbol, 'method-value', match.index!, start, end);
+      }
+    }
+  }
+
+  return uses.sort((a, b) => a.location.offset - b.location.offset);
+}
+
+/**
+ * Value uses of the functions declared in `file` across the project: the file's own
+ * package refers to them by name, other packages through their import of it
+ */
+export function scanFunctionValueUses(
+  projectRoot: string,
+  file: string,
+  content: string,
+  symbols: FunctionSymbol[] = declaredFunctions(content)
+): FunctionValueUse[] {
+  if (symbols.length === 0) return [];
+  const relative = toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+  const dir = path.posix.dirname(relative);
+  const packages = loadGoPackages(projectRoot);
+  const own = packages.find(pkg => pkg.dir === dir);
+  const uses: FunctionValueUse[] = [];
+
+  for (const pkg of packages) {
+    for (const other of pkg.files) {
+      const source = other === relative ? new SourceFile(relative, content) : SourceFile.read(projectRoot, other);
+      if (!source) continue;
+      if (pkg.dir === dir) {
+        uses.push(...findFunctionValueUses(source, symbols));
+        continue;
+      }
+      const alias = own ? goImportAlias(source.content, own.import_path, own.name) : null;
+      if (alias && alias !== '_' && alias !== '.') uses.push(...findFunctionValueUses(source, symbols, alias));
+    }
+  }
+  return uses;
+}
+
+/**
+ * Compare the signature of every function used as a value with its declaration
+ * in the outputs. When the change only adds context.Context parameters, the new
+ * declaration is renamed to <Name>Context (calls in the outputs follow it) and
+ * an adapter keeps the old signature under the old name, so the value uses still
+ * compile; any other change is incompatible.
+ */
+export function checkValueCompatibility<T extends { path: string; content: string }>(
+  symbols: FunctionSymbol[],
+  uses: FunctionValueUse[],
+  outputs: T[]
+): { outputs: T[]; checks: ValueCompatibilityCheck[] } {
+  let current = outputs;
+  const checks: ValueCompatibilityCheck[] = [];
+
+  for (const symbol of symbols) {
+    const sites = uses.filter(use => use.symbol === symbol.symbol);
+    if (sites.length === 0) continue;
+    const index = current.findIndex(o => o.path.endsWith('.go') && declaredFunctions(o.content).some(d => d.symbol === symbol.symbol));
+    if (index === -1) continue;
+    const declared = declaredFunctions(current[index].content).find(d => d.symbol === symbol.symbol)!;
+    const base = { symbol: symbol.symbol, original_signature: symbol.signature, new_signature: declared.signature, uses: sites };
+
+    if (sameTypes(symbol.params.map(p => p.type), declared.params.map(p => p.type)) && sameTypes(symbol.results, declared.results)) {
+      checks.push({ ...base, status: 'compatible' });
+      continue;
+    }
+    const adapter = adapterCall(symbol, declared);
+    if (!adapter) {
+      checks.push({ ...base, status: 'incompatible' });
+      continue;
+    }
+
+    const taken = new Set(current.flatMap(o => o.path.endsWith('.go') ? declaredFunctions(o.content).map(d => d.name) : []));
+    const renamed = [`${symbol.name}Context`, `${symbol.name}WithContext`].find(name => !taken.has(name)) ?? `${symbol.name}Context2`;
+    current = current.map(o => o.path.endsWith('.go') ? { ...o, content: renameFunction(o.content, symbol, renamed) } : o);
+    current = current.map((o, i) => i === index ? { ...o, content: `${o.content.replace(/\s*$/, '\n')}\n${renderAdapter(o.content, symbol, declared, renamed, adapter)}` } : o);
+    checks.push({ ...base, status: 'adapter', renamed_to: renamed, adapter_path: current[index].path });
+  }
+
+  return { outputs: current, checks };
+}
+
+/**
+ * Value-usage sites of incompatible checks, for the failure message
+ */
+export function formatIncompatibleValues(checks: ValueCompatibilityCheck[]): string {
+  return [
+    'functions used as values changed their signature; update these sites by hand or keep the signature:',
+    ...checks.flatMap(check => [
+      `  ${check.symbol}: ${check.original_signature} → ${check.new_signature}`,
+      ...check.uses.map(use => `    ${formatLocation(use.location)} (${use.kind}, ${use.context}${use.function ? ` in ${use.function}` : ''})`),
+    ]),
+  ].join('\n');
+}
+
+/**
+ * Prompt section listing the functions of the file used as values elsewhere
+ */
+export function renderFunctionValueSection(uses: FunctionValueUse[]): string {
+  if (uses.length === 0) return '';
+
+  const symbols = [...new Set(uses.map(use => use.symbol))];
+  return `## Functions used as values
+These functions are passed, stored or assigned as values, not only called. Keep their parameter and result types unchanged:
+${symbols.map(symbol => `- ${symbol} (${uses.filter(u => u.symbol === symbol).map(u => formatLocation(u.location)).join(', ')})`).join('\n')}
+`;
+}
+
+function parseParams(list: string): GoParam[] {
+  const parts = splitTopLevel(list).map(part => part.replace(/\s+/g, ' ').trim()).filter(Boolean);
+  const named = parts.some(part => /^\w+\s+\S/.test(part) && !/^chan\s/.test(part));
+  if (!named) return parts.map(type => ({ type }));
+
+  const params: GoParam[] = [];
+  let pending: string[] = [];
+  for (const part of parts) {
+    const match = part.match(/^(\w+)\s+(\S[\s\S]*)$/);
+    if (!match) {
+      pending.push(part);
+      continue;
+    }
+    params.push(...pending.map(name => ({ name, type: match[2].trim() })), { name: match[1], type: match[2].trim() });
+    pending = [];
+  }
+  return params;
+}
+
+function parseResults(text: string): string[] {
+  if (!text) return [];
+  if (text.startsWith('(') && closing(text, 0, '(', ')') === text.length - 1) {
+    return parseParams(text.slice(1, -1)).map(p => p.type);
+  }
+  return [text];
+}
+
+function sameTypes(a: string[], b: string[]): boolean {
+  return a.length === b.length && a.every((type, i) => normalizeType(type) === normalizeType(b[i]));
+}
+
+/**
+ * Package qualifiers are ignored: a moved declaration may refer to the same type through another import
+ */
+function normalizeType(type: string): string {
+  return type.replace(/\s+/g, ' ').replace(/\b[a-z]\w*\./g, '').trim();
+}
+
+/**
+ * Parameters of the adapter (old names, new types) and the arguments it passes on,
+ * or null when the new declaration needs more than additional contexts
+ */
+function adapterCall(previous: FunctionSymbol, next: FunctionSymbol): { params: GoParam[]; args: string[] } | null {
+  if (!sameTypes(previous.results, next.results)) return null;
+
+  const params: GoParam[] = [];
+  const args: string[] = [];
+  let i = 0;
+  for (const param of next.params) {
+    const old = previous.params[i];
+    if (old && normalizeType(old.type) === normalizeType(param.type)) {
+      const name = old.name && old.name !== '_' ? old.name : `p${i}`;
+      params.push({ name, type: param.type });
+      args.push(param.type.startsWith('...') ? `${name}...` : name);
+      i++;
+    } else if (CONTEXT_TYPE.test(param.type)) {
+      args.push('context.TODO()');
+    } else {
+      return null;
+    }
+  }
+  return i === previous.params.length ? { params, args } : null;
+}
+
+function renderAdapter(content: string, symbol: FunctionSymbol, declared: FunctionSymbol, renamed: string, call: { params: GoParam[]; args: string[] }): string {
+  let receiver = '';
+  let target = renamed;
+  if (symbol.receiver) {
+    const spec = maskLiterals(content).match(new RegExp(`^func\\s*\\(([^)]*)\\)\\s*${escapeRegExp(renamed)}\\b`, 'm'))?.[1].trim() ?? `r *${symbol.receiver}`;
+    const named = /\s/.test(spec) ? spec : `r ${spec}`;
+    receiver = `(${named}) `;
+    target = `${named.split(/\s+/)[0]}.${renamed}`;
+  }
+  const results = declared.signature.slice(closing(declared.signature, 0, '(', ')') + 1).trim();
+  const invocation = `${target}(${call.args.join(', ')})`;
+
+  return `// ${symbol.name} adapts ${renamed} to the signature ${symbol.symbol} is used with as a function value.
+func ${receiver}${symbol.name}(${call.params.map(p => `${p.name} ${p.type}`).join(', ')})${results ? ` ${results}` : ''} {
+\t${results ? 'return ' : ''}${invocation}
+}
+`;
+}
+
+/**
+ * Rename a declaration and its direct calls; value uses keep the old name and reach the adapter
+ */
+function renameFunction(content: string, symbol: FunctionSymbol, renamed: string): string {
+  const code = maskLiterals(content);
+  const name = escapeRegExp(symbol.name);
+  const edits: { start: number; end: number }[] = [];
+  const collect = (pattern: RegExp, text = code, offset = 0) => {
+    for (const match of text.matchAll(pattern)) {
+      const start = offset + match.index! + match[0].lastIndexOf(symbol.name);
+      edits.push({ start, end: start + symbol.name.length });
+    }
+  };
+
+  if (symbol.receiver) {
+    const receiver = escapeRegExp(symbol.receiver);
+    collect(new RegExp(`^func\\s*\\([^)]*\\b${receiver}(?:\\[[^\\]]*\\])?\\)\\s*${name}(?=\\s*[[(])`, 'gm'));
+    collect(new RegExp(`\\.${name}(?=\\s*\\()`, 'g'));
+    for (const block of code.matchAll(/\binterface\s*\{/g)) {
+      const open = block.index! + block[0].length - 1;
+      collect(new RegExp(`^\\s*${name}(?=\\s*\\()`, 'gm'), code.slice(open, closing(code, open, '{', '}')), open);
+    }
+  } else {
+    collect(new RegExp(`^func\\s+${name}(?=\\s*[[(])`, 'gm'));
+    collect(new RegExp(`(?<![\\w.])${name}(?=\\s*\\()`, 'g'));
+  }
+
+  const unique = [...new Map(edits.map(e => [e.start, e])).values()].sort((a, b) => b.start - a.start);
+  let result = content;
+  for (const edit of unique) {
+    result = result.slice(0, edit.start) + renamed + result.slice(edit.end);
+  }
+  return result;
+}
+
+/**
+ * A name is used as a value unless it is called, declared or assigned to
+ */
+function isValuePosition(code: string, end: number): boolean {
+  const next = code.slice(end).match(/^\s*(\S)(\S)?/);
+  if (!next) return true;
+  if (next[1] === '(' || next[1] === '.' || next[1] === '[') return false;
+  if (next[1] === ':' || (next[1] === '=' && next[2] !== '=')) return false;
+  return true;
+}
+
+function valueContext(code: string, start: number): FunctionValueContext {
+  const before = code.slice(0, start).replace(/\s+$/, '');
+  if (/\breturn$/.test(before)) return 'return';
+
+  if (/(?<![=!<>:])=$/.test(before) || before.endsWith(':=')) {
+    const line = before.slice(before.lastIndexOf('\n') + 1).replace(/:?=$/, '').trim();
+    if (/\]$/.test(line)) return 'registry';
+    if (/\.\w+$/.test(line)) return 'field';
+    return 'variable';
+  }
+
+  let depth = 0;
+  for (let i = before.length - 1; i >= 0; i--) {
+    const ch = before[i];
+    if (ch === ')' || ch === '}' || ch === ']') depth++;
+    else if (ch === '(' || ch === '{' || ch === '[') {
+      if (depth > 0) {
+        depth--;
+        continue;
+      }
+      if (ch !== '{') return 'argument';
+      const literal = before.slice(before.lastIndexOf('\n', i - 1) + 1, i);
+      if (/map\[|\[\]/.test(literal)) return 'registry';
+      return before.endsWith(':') ? 'field' : 'registry';
+    }
+  }
+  return 'variable';
+}
+
+function enclosingFunction(code: string, index: number): string {
+  const before = code.slice(0, index);
+  const declarations = [...before.matchAll(/^func\s*(?:\([^)]*\)\s*)?(\w+)/gm)];
+  const last = declarations[declarations.length - 1];
+  if (!last) return '';
+  return /^\}/m.test(before.slice(last.index!)) ? '' : last[1];
+}
+
+function bodyStart(code: string, index: number): number {
+  let depth = 0;
+  for (let i = index; i < code.length; i++) {
+    const ch = code[i];
+    if (ch === '(' || ch === '[') depth++;
+    else if (ch === ')' || ch === ']') depth--;
+    else if (ch === '{' && depth === 0) {
+      if (!/\b(?:interface|struct)\s*$/.test(code.slice(index, i))) return i;
+      i = closing(code, i, '{', '}');
+    } else if (ch === '\n' && depth === 0) {
+      return i;
+    }
+  }
+  return code.length;
+}
+
+function closing(code: string, open: number, opener: string, closer: string): number {
+  let depth = 0;
+  for (let i = open; i < code.length; i++) {
+    if (code[i] === opener) depth++;
+    else if (code[i] === closer && --depth === 0) return i;
+  }
+  return code.length;
+}
+
+function skipSpaces(code: string, index: number): number {
+  while (index < code.length && /[ \t]/.test(code[index])) index++;
+  return index;
+}
@@ -0,0 +1,140 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { GeneratedFile, GenerateDirective } from '../types/config.js';
+import { toPosixPath } from './workspace-paths.js';
+
+const GENERATED_HEADER = /^\/\/ Code generated .* DO NOT EDIT\.$/m;
+const GENERATE_DIRECTIVE = /^\/\/go:generate\s+(.+?)\s*$/gm;
+
+/**
+ * Why a Go file counts as generated, or undefined for hand-written code.
+ * The "Code generated ... DO NOT EDIT." header only counts before the package clause.
+ */
+export function generatedReason(file: string, content: string): GeneratedFile['reason'] | undefined {
+  const name = path.posix.basename(toPosixPath(file));
+  if (name.endsWith('.pb.go') || name.endsWith('.pb.gw.go')) return 'protobuf';
+  if (name.endsWith('_gen.go')) return 'gen-suffix';
+  if (name.startsWith('mock_')) return 'mock';
+  const packageClause = content.search(/^package\s/m);
+  return GENERATED_HEADER.test(packageClause >= 0 ? content.slice(0, packageClause) : content) ? 'header' : undefined;
+}
+
+export function isGeneratedGoFile(file: string, content: string): boolean {
+  return generatedReason(file, content) !== undefined;
+}
+
+/**
+ * //go:generate directives of one file
+ */
+export function findGenerateDirectives(content: string, file: string): GenerateDirective[] {
+  const lineAt = (index: number) => content.slice(0, index).split('\n').length;
+  return [...content.matchAll(GENERATE_DIRECTIVE)].map(match => ({ file, line: lineAt(match.index!), command: match[1] }));
+}
+
+/**
+ * Generated files among the given ones, each with the go:generate directive of the
+ * hand-written code producing it when one is found: a directive naming the file as
+ * output, or else one of the same package running the tool named in the header
+ * (or the package's only directive).
+ */
+export function findGeneratedCode(projectRoot: string, files: string[]): GeneratedFile[] {
+  const generated: Omit<GeneratedFile, 'directive'>[] = [];
+  const directives: GenerateDirective[] = [];
+  for (const file of [...new Set(files.map(toPosixPath))].sort()) {
+    if (!file.endsWith('.go') || file.endsWith('_test.go')) continue;
+    let content: string;
+    try {
+      content = fs.readFileSync(path.resolve(projectRoot, file), 'utf8');
+    } catch {
+      continue;
+    }
+    const reason = generatedReason(file, content);
+    if (reason) {
+      const generator = headerGenerator(content);
+      generated.push({ file, reason, ...(generator ? { generator } : {}) });
+    } else {
+      directives.push(...findGenerateDirectives(content, file));
+    }
+  }
+
+  return generated.map(entry => {
+    const directive = producingDirective(entry.file, entry.generator, directives);
+    return directive ? { ...entry, directive } : entry;
+  });
+}
+
+/**
+ * Module of each generated file: the module owning its go:generate source
+ */
+export function assignGeneratedModules(generated: GeneratedFile[], boundaries: { name: string; files: string[] }[]): GeneratedFile[] {
+  const moduleOf = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) {
+      if (!moduleOf.has(toPosixPath(file))) moduleOf.set(toPosixPath(file), boundary.name);
+    }
+  }
+  return generated.map(entry => {
+    const module = entry.directive && moduleOf.get(entry.directive.file);
+    return module ? { ...entry, module } : entry;
+  });
+}
+
+/**
+ * plan.md section listing the go:generate sources to move with their module
+ */
+export function renderGeneratedCodeSection(generated: GeneratedFile[] = []): string {
+  const sourced = generated.filter(entry => entry.directive);
+  if (sourced.length === 0) return '';
+
+  const byDirective = new Map<string, GeneratedFile[]>();
+  for (const entry of sourced) {
+    const key = `${entry.directive!.file}:${entry.directive!.line}`;
+    byDirective.set(key, [...(byDirective.get(key) ?? []), entry]);
+  }
+  const entries = [...byDirective].map(([location, outputs]) => {
+    const { command } = outputs[0].directive!;
+    const module = outputs[0].module ? ` (${outputs[0].module})` : '';
+    return `- ${location}${module}: \`${command}\` → ${outputs.map(entry => entry.file).join(', ')}`;
+  });
+  const unsourced = generated.length - sourced.length;
+
+  return `
+## 生成コード (go:generate)
+
+生成コードは境界の分析から除外しています。以下の go:generate ディレクティブは生成元のコードと一緒に移動し、
+移動後に \`go generate\` で再生成してください（出力パスは移動先に合わせて更新）。
+${entries.join('\n')}
+${unsourced > 0 ? `\nディレクティブが見つからない生成コードが${unsourced}ファイルあります（buf・protoc などプロジェクト外の手順で生成）。\n` : ''}`;
+}
+
+/**
+ * Tool of a "Code generated by <tool>" header, lowercased: MockGen → mockgen
+ */
+function headerGenerator(content: string): string | undefined {
+  const tool = content.match(/^\/\/ Code generated by "?([\w./@-]+?)[",.]?\s/m)?.[1];
+  return tool ? path.posix.basename(tool.replace(/@.*$/, '')).toLowerCase() : undefined;
+}
+
+/**
+ * Tool a directive runs: the command, or the package of `go run`
+ */
+function directiveTool(command: string): string {
+  const words = command.split(/\s+/);
+  const tool = words[0] === 'go' && words[1] === 'run' ? words.slice(2).find(word => !word.startsWith('-')) ?? 'go' : words[0];
+  return path.posix.basename(tool.replace(/@.*$/, '')).toLowerCase();
+}
+
+function producingDirective(file: string, generator: string | undefined, directives: GenerateDirective[]): GenerateDirective | undefined {
+  // Output paths are relative to the directive's package
+  const named = directives.find(directive => directive.command.split(/\s+/)
+    .map(word => word.replace(/^-{1,2}[\w-]+=/, '').replace(/^["']|["']$/g, ''))
+    .some(word => word.endsWith('.go') && path.posix.join(path.posix.dirname(directive.file), word) === file));
+  if (named) return named;
+
+  const local = directives.filter(directive => path.posix.dirname(directive.file) === path.posix.dirname(file));
+  const tool = generator && local.find(directive => {
+    const name = directiveTool(directive.command);
+    return generator === name || generator.startsWith(`${name}-`);
+  });
+  return tool || (local.length === 1 ? local[0] : undefined);
+}
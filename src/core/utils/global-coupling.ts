@@ -0,0 +1,213 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { GlobalCoupling } from '../types/config.js';
+import { maskLiterals } from './api-surface.js';
+import { goImports } from './go-load-check.js';
+import { GoPackage, goImportNames, loadGoPackages } from './go-packages.js';
+import { dominantOwners } from './test-support.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** What follows a variable (or an index, field or pointer into it) being assigned */
+const ASSIGNMENT = String.raw`\s*(?:\[[^\]\n]*\]\s*)?(?:\.\w+\s*)*(?:[-+*/%|&^]?=(?!=)|<<=|>>=|\+\+|--)`;
+
+interface PackageState {
+  pkg: GoPackage;
+  /** Package-level variables */
+  globals: Set<string>;
+  /** Variables assigned after their declaration, by the package itself or an importer */
+  mutated: Set<string>;
+  hasInit: boolean;
+  files: { file: string; content: string; code: string; inits: [number, number][] }[];
+}
+
+interface PairUsage {
+  globals: Map<string, Set<string>>;
+  init: Map<string, Set<string>>;
+  /** References to functions, types and constants outside init() */
+  other: number;
+}
+
+/**
+ * Package pairs coupled through package-level mutable variables (read or written
+ * across the package boundary) or init() side effects (a blank import of a package
+ * with init(), or calls into another package from init()). Import analysis sees
+ * these imports but not that splitting the packages changes behaviour; `exclusive`
+ * marks pairs that share nothing else.
+ */
+export function findGlobalCoupling(projectRoot: string, packages: GoPackage[] = loadGoPackages(projectRoot)): GlobalCoupling[] {
+  const states = new Map<string, PackageState>();
+  for (const pkg of packages) {
+    const state: PackageState = { pkg, globals: new Set(), mutated: new Set(), hasInit: false, files: [] };
+    for (const file of pkg.files) {
+      let content: string;
+      try {
+        content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+      } catch {
+        continue;
+      }
+      const masked = maskLiterals(content);
+      const declarations = variableDeclarations(masked);
+      declarations.names.forEach(name => state.globals.add(name));
+      const inits = initBodies(masked);
+      if (inits.length > 0) state.hasInit = true;
+      state.files.push({ file, content, code: declarations.code, inits });
+    }
+    states.set(pkg.import_path, state);
+  }
+
+  // Writes of each package to its own variables
+  for (const state of states.values()) {
+    for (const name of state.globals) {
+      const write = new RegExp(`(?<![\\w.])${name}${ASSIGNMENT}|\\bdelete\\(\\s*${name}\\b`);
+      if (state.files.some(file => write.test(file.code))) state.mutated.add(name);
+    }
+  }
+
+  const usages = new Map<string, PairUsage>();
+  const usage = (from: string, to: string) => {
+    const key = `${from}\n${to}`;
+    if (!usages.has(key)) usages.set(key, { globals: new Map(), init: new Map(), other: 0 });
+    return usages.get(key)!;
+  };
+  const record = (map: Map<string, Set<string>>, symbol: string, file: string) =>
+    map.set(symbol, new Set([...(map.get(symbol) ?? []), file]));
+
+  for (const state of states.values()) {
+    for (const file of state.files) {
+      for (const imported of goImports(file.content).filter(i => i.alias === '_')) {
+        const target = states.get(imported.path);
+        if (target?.hasInit && target !== state) record(usage(state.pkg.import_path, imported.path).init, 'init()', file.file);
+      }
+
+      // Masked code still holds the declarations' initializers: they are references too
+      const code = maskLiterals(file.content);
+      for (const [alias, importPath] of goImportNames(file.content, packages)) {
+        const target = states.get(importPath);
+        if (!target || target === state) continue;
+        const pair = usage(state.pkg.import_path, importPath);
+        for (const match of code.matchAll(new RegExp(`(?<![\\w.])${alias}\\.(\\w+)`, 'g'))) {
+          const symbol = match[1];
+          if (target.globals.has(symbol) && new RegExp(`^${ASSIGNMENT}`).test(code.slice(match.index! + match[0].length))) {
+            target.mutated.add(symbol);
+          }
+          if (file.inits.some(([start, end]) => match.index! >= start && match.index! < end)) record(pair.init, symbol, file.file);
+          else if (target.globals.has(symbol)) record(pair.globals, symbol, file.file);
+          else pair.other++;
+        }
+      }
+    }
+  }
+
+  const couplings: GlobalCoupling[] = [];
+  for (const [key, pair] of usages) {
+    const [from, to] = key.split('\n');
+    const target = states.get(to)!;
+    // Variables nobody assigns (sentinel errors, lookup tables) are constants in all but name
+    for (const [symbol] of pair.globals) {
+      if (target.mutated.has(symbol)) continue;
+      pair.globals.delete(symbol);
+      pair.other++;
+    }
+    const exclusive = pair.other === 0;
+    const entry = (kind: GlobalCoupling['kind'], symbols: Map<string, Set<string>>): GlobalCoupling => ({
+      from: states.get(from)!.pkg.dir,
+      to: target.pkg.dir,
+      kind,
+      symbols: [...symbols.keys()].sort(),
+      files: [...new Set([...symbols.values()].flatMap(files => [...files]))].sort(),
+      exclusive,
+    });
+    if (pair.globals.size > 0) couplings.push(entry('global-state', pair.globals));
+    if (pair.init.size > 0) couplings.push(entry('init-side-effect', pair.init));
+  }
+
+  return couplings.sort((a, b) => a.from.localeCompare(b.from) || a.to.localeCompare(b.to) || a.kind.localeCompare(b.kind));
+}
+
+/**
+ * The boundaries of both packages (the one owning most of each directory's files)
+ */
+export function assignGlobalCouplingModules(
+  couplings: GlobalCoupling[],
+  boundaries: { name: string; files: string[] }[]
+): GlobalCoupling[] {
+  const ownerOf = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) {
+      if (!ownerOf.has(toPosixPath(file))) ownerOf.set(toPosixPath(file), boundary.name);
+    }
+  }
+  const dirOwner = dominantOwners(ownerOf);
+  return couplings.map(({ from_module: _from, to_module: _to, ...coupling }) => {
+    const fromModule = dirOwner.get(coupling.from);
+    const toModule = dirOwner.get(coupling.to);
+    return {
+      ...coupling,
+      ...(fromModule ? { from_module: fromModule } : {}),
+      ...(toModule ? { to_module: toModule } : {}),
+    };
+  });
+}
+
+/**
+ * Names of the top-level `var` declarations (single and grouped), and the code
+ * with those declarations blanked so their initializers do not count as writes
+ */
+function variableDeclarations(masked: string): { names: string[]; code: string } {
+  const names: string[] = [];
+  let inGroup = false;
+  // Open braces and parentheses of a multi-line initializer
+  let depth = 0;
+  const code = masked.split('\n').map(line => {
+    if (depth > 0) {
+      depth += nesting(line);
+      return '';
+    }
+    if (inGroup) {
+      if (/^\)/.test(line)) {
+        inGroup = false;
+      } else {
+        names.push(...declaredNames(line.match(/^\s+([\w\s,]+?)(?:\s*=|\s+[^\s,=]|\s*$)/)?.[1]));
+        depth = nesting(line);
+      }
+      return '';
+    }
+    if (/^var\s*\(\s*$/.test(line)) {
+      inGroup = true;
+      return '';
+    }
+    const single = line.match(/^var\s+([\w\s,]+?)(?:\s*=|\s+[^\s,=])/);
+    if (!single) return line;
+    names.push(...declaredNames(single[1]));
+    depth = nesting(line);
+    return '';
+  }).join('\n');
+  return { names, code };
+}
+
+function nesting(line: string): number {
+  return (line.match(/[{(]/g) ?? []).length - (line.match(/[})]/g) ?? []).length;
+}
+
+function declaredNames(list: string | undefined): string[] {
+  return (list ?? '').split(',').map(name => name.trim()).filter(name => /^\w+$/.test(name) && name !== '_');
+}
+
+/**
+ * Offsets of the bodies of the file's init() functions
+ */
+function initBodies(masked: string): [number, number][] {
+  const bodies: [number, number][] = [];
+  for (const match of masked.matchAll(/^func\s+init\s*\(\s*\)\s*\{/gm)) {
+    const start = match.index! + match[0].length;
+    let depth = 1;
+    let end = start;
+    while (end < masked.length && depth > 0) {
+      if (masked[end] === '{') depth++;
+      else if (masked[end] === '}') depth--;
+      end++;
+    }
+    bodies.push([start, end]);
+  }
+  return bodies;
+}
@@ -0,0 +1,122 @@
+import { execFileSync } from 'child_process';
+import { createHash } from 'crypto';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { GoPackage } from './go-packages.js';
+import { GoProjectInfo, detectGoProject, findAllGoModules } from './go-project-utils.js';
+import { GoWorkspace, loadGoWorkspace } from './go-workspace.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Fields read from `go list -json`; naming them lets go skip computing the rest */
+const GO_LIST_FIELDS = ['Dir', 'ImportPath', 'Name', 'GoFiles', 'CgoFiles', 'InvalidGoFiles', 'IgnoredGoFiles'];
+
+/**
+ * Packages of the project as the Go toolchain builds them
+ */
+export interface GoListResult {
+  packages: GoPackage[];
+  /** Non-test files the build constraints exclude (//go:build ignore, another GOOS, tags not set) */
+  ignored_files: string[];
+}
+
+interface GoListPackage {
+  Dir?: string;
+  ImportPath: string;
+  Name?: string;
+  GoFiles?: string[];
+  CgoFiles?: string[];
+  InvalidGoFiles?: string[];
+  IgnoredGoFiles?: string[];
+}
+
+/** Listings by project root, reused while no Go file, go.mod or go.work changes */
+const listings = new Map<string, { fingerprint: string; result: GoListResult | null }>();
+
+/**
+ * Packages from `go list -e -find -json ./...` of every module (the go.work members in a
+ * workspace, else each go.mod of the project): only the files the current build constraints
+ * select, vendor/ and testdata/ left out the way the toolchain leaves them out.
+ * Build tags and the target platform come from the environment (GOFLAGS=-tags=..., GOOS).
+ * null when the toolchain is unavailable, the module cannot be loaded or it lists no package;
+ * callers fall back to reading package clauses.
+ */
+export function listGoPackages(
+  projectRoot: string,
+  goProject: GoProjectInfo = detectGoProject(projectRoot),
+  workspace: GoWorkspace | null = loadGoWorkspace(projectRoot)
+): GoListResult | null {
+  if (!goProject.hasGoProject) return null;
+  const root = path.resolve(projectRoot);
+  try {
+    const fingerprint = projectFingerprint(root);
+    const cached = listings.get(root);
+    if (cached?.fingerprint === fingerprint) return cached.result;
+
+    const runs = workspace
+      ? [{ cwd: root, patterns: workspace.modules.map(module => (module.dir === '.' ? './...' : `./${module.dir}/...`)) }]
+      : findAllGoModules(root)
+        .map(module => module.workingDirectory ?? root)
+        .filter(dir => !/(?:^|\/)(?:testdata|[._][^/]*)(?:\/|$)/.test(toPosixPath(path.relative(root, dir))))
+        .map(cwd => ({ cwd, patterns: ['./...'] }));
+    const output = runs.map(({ cwd, patterns }) => execFileSync('go', ['list', '-e', '-find', `-json=${GO_LIST_FIELDS.join(',')}`, ...patterns], {
+      cwd,
+      // A newer go directive must not download a toolchain
+      env: { ...process.env, GOTOOLCHAIN: 'local' },
+      encoding: 'utf8',
+      stdio: ['ignore', 'pipe', 'ignore'],
+      maxBuffer: 256 * 1024 * 1024,
+      timeout: 300000,
+    })).join('\n');
+    const result = parseGoListOutput(root, output);
+    const listed = result.packages.length > 0 ? result : null;
+    listings.set(root, { fingerprint, result: listed });
+    return listed;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Packages of `go list -json` output (a stream of JSON objects) inside the project.
+ * Files that fail to parse stay in their package so they can be analyzed syntax-only.
+ */
+export function parseGoListOutput(projectRoot: string, output: string): GoListResult {
+  const packages: GoPackage[] = [];
+  const ignored: string[] = [];
+  for (const entry of output.split(/\n(?=\{)/).map(chunk => chunk.trim()).filter(Boolean)) {
+    const pkg = JSON.parse(entry) as GoListPackage;
+    if (!pkg.Dir) continue;
+    const dir = toPosixPath(path.relative(projectRoot, pkg.Dir)) || '.';
+    if (dir === '..' || dir.startsWith('../') || path.isAbsolute(dir)) continue;
+
+    const relative = (file: string) => (dir === '.' ? file : `${dir}/${file}`);
+    const files = [...new Set([...(pkg.GoFiles ?? []), ...(pkg.CgoFiles ?? []), ...(pkg.InvalidGoFiles ?? [])])]
+      .filter(file => !file.endsWith('_test.go'))
+      .sort()
+      .map(relative);
+    ignored.push(...(pkg.IgnoredGoFiles ?? []).filter(file => !file.endsWith('_test.go')).map(relative));
+    if (files.length === 0 || !pkg.Name) continue;
+    packages.push({ import_path: pkg.ImportPath, dir, name: pkg.Name, files });
+  }
+  return {
+    // In path order of their first file, as the package clause fallback returns them
+    packages: packages.sort((a, b) => (a.files[0] < b.files[0] ? -1 : a.files[0] > b.files[0] ? 1 : 0)),
+    ignored_files: ignored.sort(),
+  };
+}
+
+/**
+ * Paths, sizes and modification times of the Go sources and module files
+ */
+function projectFingerprint(projectRoot: string): string {
+  const entries = fastGlob.sync(['**/*.go', '**/go.mod', 'go.work'], {
+    cwd: projectRoot,
+    ignore: ['**/node_modules/**', '.vibeflow/**', '.git/**'],
+    stats: true,
+  });
+  const hash = createHash('sha256').update(process.env.GOFLAGS ?? '').update(process.env.GOOS ?? '');
+  for (const entry of entries.sort((a, b) => a.path.localeCompare(b.path))) {
+    hash.update(`${entry.path}\0${entry.stats?.size}\0${entry.stats?.mtimeMs}\n`);
+  }
+  return hash.digest('hex');
+}
@@ -0,0 +1,231 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { detectGoProject } from './go-project-utils.js';
+import { loadGoWorkspace } from './go-workspace.js';
+
+export interface GoSyntaxError {
+  line: number;
+  column: number;
+  message: string;
+}
+
+/**
+ * A package that cannot be fully analyzed; all its files fall back to syntax-only analysis
+ */
+export interface PackageLoadError {
+  /** Package directory relative to the project root ('.' for the root) */
+  package: string;
+  files: string[];
+  /** `file:line:col: message`, first error first */
+  errors: string[];
+}
+
+const CLOSERS: Record<string, string> = { ')': '(', ']': '[', '}': '{' };
+const OPENERS: Record<string, string> = { '(': ')', '[': ']', '{': '}' };
+
+/**
+ * Lightweight Go syntax check: package clause, terminated literals and comments,
+ * balanced brackets. Reports the first error like the go toolchain (line:col).
+ */
+export function checkGoSyntax(content: string): GoSyntaxError | null {
+  const stack: { char: string; line: number; column: number }[] = [];
+  let line = 1;
+  let column = 1;
+  let i = 0;
+  let sawToken = false;
+
+  const error = (message: string, at: { line: number; column: number } = { line, column }): GoSyntaxError =>
+    ({ line: at.line, column: at.column, message });
+  const advance = () => {
+    if (content[i] === '\n') {
+      line++;
+      column = 1;
+    } else {
+      column++;
+    }
+    i++;
+  };
+
+  while (i < content.length) {
+    const ch = content[i];
+    const next = content[i + 1];
+
+    if (ch === '/' && next === '/') {
+      while (i < content.length && content[i] !== '\n') advance();
+      continue;
+    }
+    if (ch === '/' && next === '*') {
+      const start = { line, column };
+      advance();
+      advance();
+      while (i < content.length && !(content[i] === '*' && content[i + 1] === '/')) advance();
+      if (i >= content.length) return error('comment not terminated', start);
+      advance();
+      advance();
+      continue;
+    }
+    if (/\s/.test(ch)) {
+      advance();
+      continue;
+    }
+
+    if (!sawToken) {
+      sawToken = true;
+      const clause = content.slice(i).match(/^package\s+([A-Za-z_]\w*)/);
+      if (!clause) {
+        const found = content.slice(i).match(/^\S+/)?.[0] ?? 'EOF';
+        return error(`expected 'package', found '${found}'`);
+      }
+    }
+
+    if (ch === '"' || ch === "'" || ch === '`') {
+      const start = { line, column };
+      advance();
+      while (i < content.length && content[i] !== ch) {
+        if (ch !== '`' && content[i] === '\n') {
+          return error(ch === '"' ? 'newline in string' : 'rune literal not terminated', start);
+        }
+        if (ch !== '`' && content[i] === '\\') advance();
+        advance();
+      }
+      if (i >= content.length) {
+        return error(ch === '`' ? 'raw string literal not terminated' : 'string literal not terminated', start);
+      }
+      advance();
+      continue;
+    }
+
+    if (OPENERS[ch]) {
+      stack.push({ char: ch, line, column });
+    } else if (CLOSERS[ch]) {
+      const open = stack.pop();
+      if (!open) return error(`unexpected ${ch}`);
+      if (open.char !== CLOSERS[ch]) return error(`expected '${OPENERS[open.char]}', found '${ch}'`);
+    }
+    advance();
+  }
+
+  if (!sawToken) return error("expected 'package', found 'EOF'");
+  const unclosed = stack.pop();
+  if (unclosed) return error(`expected '${OPENERS[unclosed.char]}', found 'EOF'`);
+  return null;
+}
+
+/**
+ * Package clause of a Go file (null when missing)
+ */
+export function goPackageName(content: string): string | null {
+  return content.match(/^package\s+(\w+)/m)?.[1] ?? null;
+}
+
+/**
+ * Import paths with their 1-based line and column (single and grouped imports)
+ * and the explicit alias, if any
+ */
+export function goImports(content: string): { path: string; line: number; column: number; alias?: string }[] {
+  const imports: { path: string; line: number; column: number; alias?: string }[] = [];
+  const lines = content.split('\n');
+  let inGroup = false;
+
+  lines.forEach((text, index) => {
+    const trimmed = text.trim();
+    if (/^import\s*\($/.test(trimmed)) {
+      inGroup = true;
+      return;
+    }
+    if (inGroup && trimmed.startsWith(')')) {
+      inGroup = false;
+      return;
+    }
+    const match = inGroup
+      ? text.match(/^\s*(?:([\w.]+)\s+)?"([^"]+)"/)
+      : text.match(/^\s*import\s+(?:([\w.]+)\s+)?"([^"]+)"/);
+    if (match) {
+      imports.push({
+        path: match[2],
+        line: index + 1,
+        column: text.indexOf(`"${match[2]}"`) + 1,
+        ...(match[1] ? { alias: match[1] } : {}),
+      });
+    }
+  });
+
+  return imports;
+}
+
+/**
+ * Find packages that would fail to load: syntax errors, conflicting package
+ * clauses in one directory, and imports of module packages that have no Go
+ * files (e.g. generated code that was never generated).
+ *
+ * @param files Go files relative to the project root
+ */
+export function findPackageLoadErrors(projectRoot: string, files: string[]): PackageLoadError[] {
+  const goProject = detectGoProject(projectRoot);
+  // Module path and root of every module whose imports resolve inside the project (each go.work member)
+  const workspace = loadGoWorkspace(projectRoot);
+  const modules = workspace
+    ? workspace.modules
+      .flatMap(m => (m.module_path ? [{ name: m.module_path, dir: path.join(projectRoot, m.dir) }] : []))
+      .sort((a, b) => b.name.length - a.name.length)
+    : goProject.moduleName ? [{ name: goProject.moduleName, dir: goProject.workingDirectory ?? projectRoot }] : [];
+
+  const packages = new Map<string, string[]>();
+  for (const file of files) {
+    const dir = path.dirname(file) || '.';
+    packages.set(dir, [...(packages.get(dir) ?? []), file]);
+  }
+
+  const results: PackageLoadError[] = [];
+  for (const [dir, packageFiles] of [...packages].sort(([a], [b]) => a.localeCompare(b))) {
+    const errors: string[] = [];
+    const names = new Map<string, string>();
+
+    for (const file of [...packageFiles].sort()) {
+      let content: string;
+      try {
+        content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+      } catch {
+        errors.push(`${file}: cannot read file`);
+        continue;
+      }
+
+      const syntaxError = checkGoSyntax(content);
+      if (syntaxError) {
+        errors.push(`${file}:${syntaxError.line}:${syntaxError.column}: ${syntaxError.message}`);
+        continue;
+      }
+
+      const name = goPackageName(content);
+      if (name && !names.has(name)) names.set(name, file);
+
+      for (const imported of goImports(content)) {
+        const module = modules.find(m => imported.path === m.name || imported.path.startsWith(`${m.name}/`));
+        if (!module) continue;
+        const importDir = path.join(module.dir, imported.path.slice(module.name.length));
+        if (!hasGoFiles(importDir)) {
+          errors.push(`${file}:${imported.line}:${imported.column}: could not import ${imported.path} (no Go files in ${path.relative(projectRoot, importDir) || '.'})`);
+        }
+      }
+    }
+
+    if (names.size > 1) {
+      const [[first, firstFile], [second, secondFile]] = [...names];
+      errors.push(`${dir}: found packages ${first} (${path.basename(firstFile)}) and ${second} (${path.basename(secondFile)})`);
+    }
+
+    if (errors.length > 0) {
+      results.push({ package: dir, files: packageFiles, errors });
+    }
+  }
+
+  return results;
+}
+
+function hasGoFiles(dir: string): boolean {
+  try {
+    return fs.readdirSync(dir).some(name => String(name).endsWith('.go') && !String(name).endsWith('_test.go'));
+  } catch {
+    return false;
+  }
+}
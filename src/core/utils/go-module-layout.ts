@@ -0,0 +1,333 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { goImports } from './go-load-check.js';
+import { detectGoProject } from './go-project-utils.js';
+import { toPosixPath } from './workspace-paths.js';
+import { escapeRegExp } from './text-utils.js';
+
+/**
+ * Go module layout of the target: one go.mod for the whole monolith, a go.mod
+ * per module wired with replace directives, or a go.mod per module tied
+ * together by a go.work
+ */
+export type GoModuleLayout = 'single' | 'multi-module' | 'go-work';
+
+export const GO_MODULE_LAYOUTS: GoModuleLayout[] = ['single', 'multi-module', 'go-work'];
+
+export interface GoModuleRequirement {
+  module_path: string;
+  version: string;
+}
+
+/**
+ * replace directive resolving a module from the working tree during the
+ * transition, until it is tagged and required by version
+ */
+export interface GoModuleReplace {
+  module_path: string;
+  /** Directory relative to the module declaring the directive (./ or ../) */
+  path: string;
+}
+
+export interface PlannedGoModule {
+  module: string;
+  /** Module root relative to the project root */
+  dir: string;
+  go_mod: string;
+  module_path: string;
+  /** Sibling modules it depends on, the root module when it uses packages left there, and its third-party modules */
+  requires: GoModuleRequirement[];
+  replaces: GoModuleReplace[];
+}
+
+export interface GoModuleLayoutPlan {
+  layout: GoModuleLayout;
+  root: {
+    dir: string;
+    go_mod: string;
+    module_path: string;
+    go_version?: string;
+    /** Directives the root go.mod gets for the split modules */
+    requires: GoModuleRequirement[];
+    replaces: GoModuleReplace[];
+  };
+  /** Empty for the single layout */
+  modules: PlannedGoModule[];
+  go_work?: { file: string; use: string[] };
+  /** Modules split into their own go.mod in each phase */
+  steps: { phase: string; modules: string[] }[];
+}
+
+/** Version required for modules of the working tree; the replace directive or go.work resolves it */
+const LOCAL_VERSION = 'v0.0.0';
+
+/**
+ * Layout of a --go-modules value
+ */
+export function parseGoModuleLayout(value: string): GoModuleLayout {
+  const layout = value.trim().toLowerCase();
+  if (!(GO_MODULE_LAYOUTS as string[]).includes(layout)) {
+    throw new Error(`Unknown Go module layout "${value}" (expected ${GO_MODULE_LAYOUTS.join(', ')})`);
+  }
+  return layout as GoModuleLayout;
+}
+
+/**
+ * Layout of the previous plan.json; without one, go-work for projects already
+ * using a go.work and single otherwise
+ */
+export function loadGoModuleLayout(projectRoot: string): GoModuleLayout {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    if ((GO_MODULE_LAYOUTS as unknown[]).includes(plan.go_modules?.layout)) return plan.go_modules.layout;
+  } catch {
+    // no previous plan
+  }
+  return fs.existsSync(path.join(projectRoot, 'go.work')) ? 'go-work' : 'single';
+}
+
+/**
+ * Go module layout plan recorded in .vibeflow/plan.json
+ */
+export function loadGoModulePlan(projectRoot: string): GoModuleLayoutPlan | undefined {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    return plan.go_modules ?? undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+/**
+ * go.mod of every module in internal/<module> for the multi-module and go-work
+ * layouts: the module path below the root module's, the sibling and
+ * established modules it depends on, the root module when it still imports
+ * packages no module takes, and the third-party modules of its imports with
+ * the root go.mod's versions. Established modules keep their go.mod.
+ *
+ * @returns undefined outside a Go module
+ */
+export function planGoModuleLayout(
+  projectRoot: string,
+  layout: GoModuleLayout,
+  modules: { name: string; files: string[]; dependencies: string[]; established?: { root: string; module_path?: string } }[],
+  phases: { name: string; modules: string[] }[]
+): GoModuleLayoutPlan | undefined {
+  const goProject = detectGoProject(projectRoot);
+  if (!goProject.goModulePath || !goProject.moduleName) return undefined;
+
+  const rootDir = toPosixPath(path.relative(projectRoot, goProject.workingDirectory!)) || '.';
+  const rootContent = fs.readFileSync(goProject.goModulePath, 'utf8');
+  const rootPath = goProject.moduleName;
+  const goVersion = rootContent.match(/^go\s+(\S+)/m)?.[1];
+  const root = {
+    dir: rootDir,
+    go_mod: joinDir(rootDir, 'go.mod'),
+    module_path: rootPath,
+    ...(goVersion ? { go_version: goVersion } : {}),
+    requires: [] as GoModuleRequirement[],
+    replaces: [] as GoModuleReplace[],
+  };
+  if (layout === 'single') return { layout, root, modules: [], steps: [] };
+
+  const split = modules.filter(module => !module.established);
+  const dirOf = (name: string) => joinDir(rootDir, `internal/${packageDir(name)}`);
+  const pathOf = (name: string) => `${rootPath}/internal/${packageDir(name)}`;
+  const requirements = goModRequirements(rootContent);
+  const movedDirs = new Set(split.flatMap(module => module.files.map(file => path.posix.dirname(toPosixPath(file)))));
+
+  const planned: PlannedGoModule[] = split.map(module => {
+    const dir = dirOf(module.name);
+    const local = new Map<string, string>();
+    for (const dependency of module.dependencies) {
+      const sibling = split.find(m => m.name === dependency && m.name !== module.name);
+      const established = modules.find(m => m.name === dependency && m.established?.module_path && m.established.root)?.established;
+      if (sibling) local.set(pathOf(sibling.name), dirOf(sibling.name));
+      else if (established) local.set(established.module_path!, established.root);
+    }
+
+    const imports = new Set(module.files.filter(file => file.endsWith('.go')).flatMap(file => {
+      try {
+        return goImports(fs.readFileSync(path.join(projectRoot, file), 'utf8')).map(i => i.path);
+      } catch {
+        return [];
+      }
+    }));
+    const leftInRoot = [...imports].some(importPath => importPath.startsWith(`${rootPath}/`)
+      && !movedDirs.has(joinDir(rootDir, importPath.slice(rootPath.length + 1))));
+    if (leftInRoot) local.set(rootPath, rootDir);
+
+    const thirdParty = requirements.filter(requirement =>
+      [...imports].some(importPath => importPath === requirement.module_path || importPath.startsWith(`${requirement.module_path}/`)));
+    return {
+      module: module.name,
+      dir,
+      go_mod: `${dir}/go.mod`,
+      module_path: pathOf(module.name),
+      requires: [...[...local.keys()].map(modulePath => ({ module_path: modulePath, version: LOCAL_VERSION })), ...thirdParty],
+      replaces: layout === 'multi-module'
+        ? [...local].map(([modulePath, target]) => ({ module_path: modulePath, path: relativeDir(dir, target) }))
+        : [],
+    };
+  });
+
+  root.requires = planned.map(module => ({ module_path: module.module_path, version: LOCAL_VERSION }));
+  root.replaces = layout === 'multi-module'
+    ? planned.map(module => ({ module_path: module.module_path, path: relativeDir(rootDir, module.dir) }))
+    : [];
+
+  const established = modules.flatMap(module => (module.established?.root ? [module.established.root] : []));
+  return {
+    layout,
+    root,
+    modules: planned,
+    ...(layout === 'go-work' ? { go_work: { file: 'go.work', use: [rootDir, ...established, ...planned.map(module => module.dir)].map(workUse) } } : {}),
+    steps: phases
+      .map(phase => ({ phase: phase.name, modules: phase.modules.filter(name => planned.some(module => module.module === name)) }))
+      .filter(step => step.modules.length > 0),
+  };
+}
+
+/**
+ * go.mod of a split module
+ */
+export function renderGoModFile(module: PlannedGoModule, goVersion?: string): string {
+  return `// Generated by vibeflow for the ${module.module} module; run go mod tidy after the code moved in.
+module ${module.module_path}
+${goVersion ? `\ngo ${goVersion}\n` : ''}${renderDirectives(module.requires, module.replaces)}`;
+}
+
+/**
+ * go.work using the root module and the modules already split out
+ *
+ * @param split directories (relative to the project root) of the modules with a go.mod
+ * @param existing use directories of the current go.work, which are kept
+ */
+export function renderGoWorkFile(plan: GoModuleLayoutPlan, split: string[], existing: string[] = []): string {
+  const pending = new Set(plan.modules.filter(module => !split.includes(module.dir)).map(module => workUse(module.dir)));
+  const uses = [...new Set([
+    ...existing.map(use => workUse(path.posix.normalize(toPosixPath(use)).replace(/\/$/, ''))),
+    ...(plan.go_work?.use ?? []).filter(use => !pending.has(use)),
+  ])];
+  return `// Generated by vibeflow from the Go module layout in plan.json.
+${plan.root.go_version ? `go ${plan.root.go_version}\n\n` : ''}use (
+${uses.map(use => `\t${use}`).join('\n')}
+)
+`;
+}
+
+/**
+ * Root go.mod with the require and replace directives of a split module added
+ * (unchanged when they are there already)
+ */
+export function addRootDirectives(content: string, plan: GoModuleLayoutPlan, module: string): string {
+  const planned = plan.modules.find(m => m.module === module);
+  if (!planned) return content;
+  const has = (directive: string) => new RegExp(`^\\s*(?:${directive}\\s+)?${escapeRegExp(planned.module_path)}\\s`, 'm').test(content);
+
+  const requires = has('require') ? [] : plan.root.requires.filter(r => r.module_path === planned.module_path);
+  const replaces = plan.root.replaces.filter(r => r.module_path === planned.module_path && !new RegExp(`^\\s*(?:replace\\s+)?${escapeRegExp(r.module_path)}\\s+=>`, 'm').test(content));
+  if (requires.length === 0 && replaces.length === 0) return content;
+  return `${content.replace(/\n*$/, '\n')}${renderDirectives(requires, replaces)}`;
+}
+
+function renderDirectives(requires: GoModuleRequirement[], replaces: GoModuleReplace[]): string {
+  return [
+    ...(requires.length > 0 ? [`\nrequire (\n${requires.map(r => `\t${r.module_path} ${r.version}`).join('\n')}\n)\n`] : []),
+    ...(replaces.length > 0 ? [`\n// Transition: resolved from the working tree until the modules are tagged\nreplace (\n${replaces.map(r => `\t${r.module_path} => ${r.path}`).join('\n')}\n)\n`] : []),
+  ].join('');
+}
+
+/**
+ * plan.md section: the target layout, the module paths, the transition directives and the split per phase
+ */
+export function renderGoModulesSection(plan: GoModuleLayoutPlan | undefined): string {
+  if (!plan) return '';
+
+  const names: Record<GoModuleLayout, string> = {
+    single: '単一モジュール',
+    'multi-module': 'マルチモジュール（replace ディレクティブ）',
+    'go-work': 'マルチモジュール（go.work）',
+  };
+  if (plan.layout === 'single') {
+    return `
+## Goモジュール構成 (Go Module Layout)
+
+- 構成: ${names.single}
+- \`${plan.root.go_mod}\`: \`${plan.root.module_path}\`。各モジュールは \`internal/<モジュール>\` のパッケージとしてこの go.mod に残ります
+`;
+  }
+
+  const rows = plan.modules.map(module =>
+    `| ${module.module} | \`${module.go_mod}\` | \`${module.module_path}\` | ${module.requires.filter(r => r.version === LOCAL_VERSION).map(r => `\`${r.module_path}\``).join(', ') || '-'} |`);
+  const replaces = [
+    ...plan.root.replaces.map(r => `- \`${plan.root.go_mod}\`: \`${r.module_path} => ${r.path}\``),
+    ...plan.modules.flatMap(module => module.replaces.map(r => `- \`${module.go_mod}\`: \`${r.module_path} => ${r.path}\``)),
+  ];
+  return `
+## Goモジュール構成 (Go Module Layout)
+
+- 構成: ${names[plan.layout]}
+- ルート: \`${plan.root.go_mod}\`（\`${plan.root.module_path}\`${plan.root.go_version ? `、go ${plan.root.go_version}` : ''}）
+
+| モジュール | go.mod | モジュールパス | 依存するローカルモジュール |
+|------------|--------|----------------|----------------------------|
+${rows.join('\n')}
+${plan.go_work ? `
+### go.work
+
+\`\`\`
+use (
+${plan.go_work.use.map(use => `\t${use}`).join('\n')}
+)
+\`\`\`
+` : `
+### 移行中の replace ディレクティブ
+
+タグを打って版で require できるまで、作業ツリーのモジュールを参照します。
+
+${replaces.join('\n')}
+`}
+### フェーズごとの分離
+
+${plan.steps.map(step => `- ${step.phase}: ${step.modules.join(', ')} を独立した go.mod に分離（vf refactor が go.mod${plan.go_work ? ' と go.work' : ' とルートの require/replace'}を生成）`).join('\n') || 'なし'}
+`;
+}
+
+/**
+ * Module paths and versions of the require directives of a go.mod
+ */
+function goModRequirements(content: string): GoModuleRequirement[] {
+  const requirements: GoModuleRequirement[] = [];
+  let inBlock = false;
+  for (const raw of content.split('\n')) {
+    const line = raw.replace(/\/\/.*$/, '').trim();
+    if (/^require\s*\($/.test(line)) {
+      inBlock = true;
+    } else if (inBlock && line.startsWith(')')) {
+      inBlock = false;
+    } else {
+      const match = inBlock ? line.match(/^"?([^"\s]+)"?\s+(\S+)/) : line.match(/^require\s+"?([^"\s]+)"?\s+(\S+)/);
+      if (match) requirements.push({ module_path: match[1], version: match[2] });
+    }
+  }
+  return requirements;
+}
+
+function packageDir(module: string): string {
+  return module.toLowerCase().replace(/[^a-z0-9_-]/g, '') || 'module';
+}
+
+function joinDir(dir: string, child: string): string {
+  return dir === '.' ? child : `${dir}/${child}`;
+}
+
+function relativeDir(from: string, to: string): string {
+  const relative = path.posix.relative(from === '.' ? '' : from, to === '.' ? '' : to) || '.';
+  return relative.startsWith('.') ? relative : `./${relative}`;
+}
+
+function workUse(dir: string): string {
+  return dir === '.' ? '.' : `./${dir}`;
+}
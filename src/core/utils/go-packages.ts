@@ -0,0 +1,264 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { maskLiterals } from './api-surface.js';
+import { GoProjectInfo, detectGoProject, goPackageImportPath, impliedPackageName } from './go-project-utils.js';
+import { goImports, goPackageName } from './go-load-check.js';
+import { listGoPackages } from './go-list.js';
+import { GoWorkspace, loadGoWorkspace, workspaceImportPath } from './go-workspace.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * A Go package as the toolchain sees it: one directory, one import path and
+ * the name from its package clause, which need not match the directory
+ */
+export interface GoPackage {
+  import_path: string;
+  /** Directory relative to the project root ('.' for the root) */
+  dir: string;
+  name: string;
+  /** Non-test Go files relative to the project root */
+  files: string[];
+}
+
+/**
+ * Package identity of one file, as recorded per boundary in domain-map.json
+ */
+export interface FilePackage {
+  file: string;
+  import_path: string;
+  package: string;
+}
+
+/**
+ * name-mismatch: the package name differs from the name importers assume from the path.
+ * duplicate-name: additionally, another directory provides a package of the same name.
+ */
+export type PackageMismatchKind = 'name-mismatch' | 'duplicate-name';
+
+export interface PackageMismatch {
+  kind: PackageMismatchKind;
+  dir: string;
+  import_path: string;
+  /** Declared package name */
+  package: string;
+  /** Name implied by the import path (what an unaliased import reads as) */
+  implied_name: string;
+  /** Other import paths declaring the same package name */
+  conflicts_with?: string[];
+  /** Canonical name to rename the package (or its directory) to */
+  proposed_name: string;
+}
+
+/**
+ * Directories of shared test doubles; their packages get the `<name>test` convention
+ */
+export const TEST_HELPER_DIR = /(?:^|\/)(?:testutils?|testhelpers?|testing|mocks?|fakes?|stubs?)(?:\/|$)/;
+
+const PACKAGE_MISMATCH_HEADING = '## パッケージ名の不一致 (Cleanup)';
+
+/**
+ * Load every package of the project with `go list` (see go-list.ts), so files
+ * excluded by build constraints and nested modules are left out as the toolchain
+ * leaves them out. Without the toolchain, packages come from the package clauses
+ * of the files: external test packages (`package foo_test`) do not define the
+ * package name, and in a Go workspace import paths come from the go.work member
+ * containing the package.
+ */
+export function loadGoPackages(
+  projectRoot: string,
+  goProject: GoProjectInfo = detectGoProject(projectRoot),
+  workspace: GoWorkspace | null = loadGoWorkspace(projectRoot)
+): GoPackage[] {
+  const listed = listGoPackages(projectRoot, goProject, workspace);
+  if (listed) return listed.packages;
+
+  const files = fastGlob.sync('**/*.go', {
+    cwd: projectRoot,
+    ignore: ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**', '**/testdata/**'],
+  }).sort();
+
+  const packages = new Map<string, GoPackage>();
+  for (const file of files.filter(f => !f.endsWith('_test.go'))) {
+    let name: string | null;
+    try {
+      name = goPackageName(fs.readFileSync(path.join(projectRoot, file), 'utf8'));
+    } catch {
+      continue;
+    }
+    if (!name) continue;
+
+    const dir = path.posix.dirname(file);
+    const existing = packages.get(dir);
+    if (existing) {
+      existing.files.push(file);
+      continue;
+    }
+    packages.set(dir, {
+      import_path: (workspace && workspaceImportPath(workspace, dir)) ?? goPackageImportPath(projectRoot, dir, goProject) ?? dir,
+      dir,
+      name,
+      files: [file],
+    });
+  }
+  return [...packages.values()];
+}
+
+/**
+ * Package identity of each file; files outside any loaded package are skipped
+ */
+export function filePackages(projectRoot: string, packages: GoPackage[], files: string[]): FilePackage[] {
+  const byDir = new Map(packages.map(pkg => [pkg.dir, pkg]));
+  return files.flatMap(file => {
+    const relative = toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+    const pkg = byDir.get(path.posix.dirname(relative));
+    return pkg ? [{ file, import_path: pkg.import_path, package: pkg.name }] : [];
+  });
+}
+
+/**
+ * Declared name of the package at `importPath`, or the implied name when it is not loaded
+ * (standard library, third party, not generated yet)
+ */
+export function packageNameOf(packages: GoPackage[], importPath: string): string {
+  return packages.find(pkg => pkg.import_path === importPath)?.name ?? impliedPackageName(importPath);
+}
+
+/**
+ * Names a file refers to its imports by, mapped to the import path: the explicit
+ * alias, else the declared name of the loaded package, else the implied name
+ */
+export function goImportNames(content: string, packages: GoPackage[] = []): Map<string, string> {
+  const names = new Map<string, string>();
+  for (const imported of goImports(content)) {
+    if (imported.alias === '_' || imported.alias === '.') continue;
+    names.set(imported.alias ?? packageNameOf(packages, imported.path), imported.path);
+  }
+  return names;
+}
+
+/**
+ * How a file should import `importPath` whose package is `packageName`.
+ * An alias is emitted when the package name differs from the name implied by
+ * the path, or when that name would collide with an identifier already used in
+ * the file (declarations, locals or other imports); `name` is the qualifier to
+ * write in code.
+ */
+export function goImportSpec(content: string, importPath: string, packageName: string): { name: string; alias?: string } {
+  const existing = goImports(content).find(i => i.path === importPath);
+  if (existing && existing.alias !== '_' && existing.alias !== '.') {
+    return { name: existing.alias ?? packageName, ...(existing.alias ? { alias: existing.alias } : {}) };
+  }
+
+  const taken = fileIdentifiers(content);
+  const parent = impliedPackageName(path.posix.dirname(importPath));
+  const candidates = [packageName, `${parent}${packageName}`];
+  for (let i = 2; candidates.length < 100; i++) candidates.push(`${packageName}${i}`);
+
+  const name = candidates.find(candidate => /^[A-Za-z_]\w*$/.test(candidate) && !taken.has(candidate))!;
+  return name === impliedPackageName(importPath) ? { name } : { name, alias: name };
+}
+
+/**
+ * Point an import at a new path while keeping the name the file's code uses for
+ * it; an alias is added unless the new package declares that same name and it
+ * matches the new path
+ */
+export function rewriteGoImport(content: string, oldPath: string, newPath: string, packages: GoPackage[] = []): string {
+  const existing = goImports(content).find(i => i.path === oldPath);
+  if (!existing) return content;
+
+  const escaped = oldPath.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+  if (existing.alias) {
+    return content.replace(new RegExp(`^(\\s*(?:import\\s+)?[\\w.]+\\s+)"${escaped}"`, 'm'), `$1"${newPath}"`);
+  }
+  const used = packageNameOf(packages, oldPath);
+  const declared = packageNameOf(packages, newPath);
+  const alias = used !== declared || declared !== impliedPackageName(newPath) ? `${used} ` : '';
+  return content.replace(new RegExp(`^(\\s*(?:import\\s+)?)"${escaped}"`, 'm'), `$1${alias}"${newPath}"`);
+}
+
+/**
+ * Identifiers a new import name must not shadow or be shadowed by: every
+ * identifier of the file that is not a field or method selector, plus the names
+ * of its imports
+ */
+function fileIdentifiers(content: string): Set<string> {
+  const code = maskLiterals(content)
+    .replace(/^package\s+\w+/m, '')
+    .replace(/^import\s*\([\s\S]*?^\)|^import\s+[^\n]*/gm, '');
+  const identifiers = new Set(goImportNames(content).keys());
+  for (const match of code.matchAll(/(?<![\w.])[A-Za-z_]\w*/g)) identifiers.add(match[0]);
+  return identifiers;
+}
+
+/**
+ * Packages whose name does not match their import path, with a proposed canonical
+ * name. `package main` is exempt; test helper directories (testutil/, mocks/, ...)
+ * get `<name>test`, other packages take the directory's name, prefixed with the
+ * parent directory while that is still taken.
+ */
+export function findPackageMismatches(packages: Pick<GoPackage, 'import_path' | 'dir' | 'name'>[]): PackageMismatch[] {
+  const byName = new Map<string, string[]>();
+  for (const pkg of packages) byName.set(pkg.name, [...(byName.get(pkg.name) ?? []), pkg.import_path]);
+  const taken = new Set(packages.filter(pkg => pkg.name === impliedPackageName(pkg.import_path)).map(pkg => pkg.name));
+
+  const mismatches: PackageMismatch[] = [];
+  for (const pkg of [...packages].sort((a, b) => a.import_path.localeCompare(b.import_path))) {
+    const implied = impliedPackageName(pkg.import_path);
+    if (pkg.name === 'main' || pkg.name === implied) continue;
+
+    const conflicts = (byName.get(pkg.name) ?? []).filter(other => other !== pkg.import_path);
+    const proposed = proposeName(pkg, implied, taken);
+    taken.add(proposed);
+    mismatches.push({
+      kind: conflicts.length > 0 ? 'duplicate-name' : 'name-mismatch',
+      dir: pkg.dir,
+      import_path: pkg.import_path,
+      package: pkg.name,
+      implied_name: implied,
+      ...(conflicts.length > 0 ? { conflicts_with: conflicts } : {}),
+      proposed_name: proposed,
+    });
+  }
+  return mismatches;
+}
+
+function proposeName(pkg: Pick<GoPackage, 'dir' | 'name'>, implied: string, taken: Set<string>): string {
+  const base = TEST_HELPER_DIR.test(pkg.dir) && !pkg.name.endsWith('test')
+    ? `${pkg.name}test`
+    : implied.toLowerCase().replace(/_/g, '');
+  const parents = pkg.dir.split('/').slice(0, -1).reverse()
+    .map(part => part.toLowerCase().replace(/[^a-z0-9]/g, ''))
+    .filter(Boolean);
+
+  let candidate = /^[a-z]\w*$/.test(base) ? base : pkg.name;
+  for (const parent of parents) {
+    if (!taken.has(candidate)) break;
+    candidate = `${parent}${candidate}`;
+  }
+  for (let i = 2; taken.has(candidate); i++) candidate = `${base}${i}`;
+  return candidate;
+}
+
+/**
+ * plan.md section listing the package name cleanups
+ */
+export function renderPackageMismatchSection(mismatches: PackageMismatch[]): string {
+  if (mismatches.length === 0) return '';
+
+  const entries = mismatches.map(m => [
+    `- \`${m.import_path}\` は \`package ${m.package}\` を宣言 (パスから想定される名前: \`${m.implied_name}\`)`,
+    ...(m.conflicts_with ? [`  - 同名のパッケージ: ${m.conflicts_with.map(p => `\`${p}\``).join(', ')}`] : []),
+    `  - 提案: パッケージ名を \`${m.proposed_name}\` に統一${m.proposed_name !== m.implied_name ? ` (ディレクトリも \`${m.proposed_name}\` に変更)` : ''}`,
+  ].join('\n'));
+
+  return `
+${PACKAGE_MISMATCH_HEADING}
+
+以下のパッケージはディレクトリ名とパッケージ名が一致していません。分析と import の書き換えはパッケージ名を基準に行いますが、
+移行前に名前を揃えると生成コードのエイリアスが不要になります。
+
+${entries.join('\n')}
+`;
+}
@@ -1,5 +1,6 @@
 import * as fs from 'fs';
 import * as path from 'path';
+import { toPosixPath } from './workspace-paths.js';
 
 export interface GoProjectInfo {
   /** Whether a Go project was found */
@@ -10,6 +11,8 @@ export interface GoProjectInfo {
   workingDirectory?: string;
   /** Module name from go.mod */
   moduleName?: string;
+  /** go.work of a Go workspace at the project root; Go commands run there for every member module */
+  goWorkPath?: string;
 }
 
 /**
@@ -32,6 +35,16 @@ export function detectGoProject(projectRoot: string): GoProjectInfo {
     'golang',
   ];
 
+  // A workspace root needs no go.mod of its own: go.work lists the member modules
+  const goWorkPath = path.join(projectRoot, 'go.work');
+  if (fs.existsSync(goWorkPath) && !fs.existsSync(path.join(projectRoot, 'go.mod'))) {
+    return {
+      hasGoProject: true,
+      workingDirectory: projectRoot,
+      goWorkPath,
+    };
+  }
+
   for (const subDir of commonGoDirs) {
     const searchPath = path.join(projectRoot, subDir);
     const goModPath = path.join(searchPath, 'go.mod');
@@ -47,6 +60,7 @@ export function detectGoProject(projectRoot: string): GoProjectInfo {
           goModulePath: goModPath,
           workingDirectory: searchPath,
           moduleName,
+          ...(subDir === '' && fs.existsSync(goWorkPath) ? { goWorkPath } : {}),
         };
       } catch (error) {
         // Continue searching if we can't read the go.mod file
@@ -136,4 +150,40 @@ export function withGoWorkingDirectory<T>(
     return null;
   }
   return callback(workingDir);
-}
\ No newline at end of file
+}
+/**
+ * Import path of a package directory (relative to the project root), or null
+ * when there is no go.mod or the directory is outside the Go module
+ */
+export function goPackageImportPath(projectRoot: string, dir: string, goProject: GoProjectInfo = detectGoProject(projectRoot)): string | null {
+  if (!goProject.moduleName) return null;
+  const relative = toPosixPath(path.relative(goProject.workingDirectory ?? projectRoot, path.join(projectRoot, dir)));
+  if (relative.startsWith('..')) return null;
+  return relative && relative !== '.' ? `${goProject.moduleName}/${relative}` : goProject.moduleName;
+}
+
+/**
+ * Package name goimports assumes for an import path: the last element
+ * (skipping a /vN major version suffix), without a "go-" prefix and cut at the
+ * first character that cannot appear in an identifier
+ */
+export function impliedPackageName(importPath: string): string {
+  const elements = importPath.split('/');
+  let base = elements[elements.length - 1];
+  if (/^v\d+$/.test(base) && elements.length > 1) base = elements[elements.length - 2];
+  base = base.replace(/^go-/, '');
+  const cut = base.search(/[^A-Za-z0-9_]/);
+  return cut >= 0 ? base.slice(0, cut) : base;
+}
+
+/**
+ * Name under which a Go file refers to an imported package: the explicit alias
+ * ('.' and '_' included), else the package's declared name when known, else the
+ * name implied by the import path; null when it is not imported
+ */
+export function goImportAlias(content: string, importPath: string, packageName?: string): string | null {
+  const escaped = importPath.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+  const match = content.match(new RegExp(`^\\s*(?:import\\s+)?(?:([\\w.]+)\\s+)?"${escaped}"`, 'm'));
+  if (!match) return null;
+  return match[1] ?? packageName ?? impliedPackageName(importPath);
+}
@@ -0,0 +1,129 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { DomainBoundary } from '../types/config.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * A member module of a Go workspace (a `use` directive of go.work)
+ */
+export interface GoWorkspaceModule {
+  /** Module root relative to the project root ('.' for the root) */
+  dir: string;
+  /** Its go.mod relative to the project root */
+  go_mod: string;
+  /** Module path declared in the go.mod; absent when it has none */
+  module_path?: string;
+}
+
+export interface GoWorkspace {
+  /** go.work relative to the project root */
+  file: string;
+  /** Members that have a go.mod, most specific (deepest) first */
+  modules: GoWorkspaceModule[];
+  /** `use` directories without a go.mod */
+  missing: string[];
+}
+
+/**
+ * Directories of the `use` directives of a go.work file, in order (single-line
+ * and block form, quoted or not, comments stripped)
+ */
+export function parseGoWork(content: string): string[] {
+  const dirs: string[] = [];
+  const unquote = (entry: string) => entry.replace(/^"(.*)"$|^`(.*)`$/, (_, a, b) => a ?? b);
+  let inBlock = false;
+
+  for (const raw of content.split('\n')) {
+    const line = raw.replace(/\/\/.*$/, '').trim();
+    if (!line) continue;
+    if (inBlock) {
+      if (line === ')') inBlock = false;
+      else dirs.push(unquote(line));
+      continue;
+    }
+    const match = line.match(/^use\s*(\(|.+)$/);
+    if (!match) continue;
+    if (match[1] === '(') inBlock = true;
+    else dirs.push(unquote(match[1].trim()));
+  }
+  return dirs;
+}
+
+/**
+ * The go.work at the project root with its member modules, or null when the
+ * project is not a Go workspace (no go.work, or one without `use` directives)
+ */
+export function loadGoWorkspace(projectRoot: string): GoWorkspace | null {
+  const workPath = path.join(projectRoot, 'go.work');
+  let uses: string[];
+  try {
+    if (!fs.existsSync(workPath)) return null;
+    uses = parseGoWork(fs.readFileSync(workPath, 'utf8'));
+  } catch {
+    return null;
+  }
+  if (uses.length === 0) return null;
+
+  const modules: GoWorkspaceModule[] = [];
+  const missing: string[] = [];
+  for (const use of uses) {
+    const dir = path.posix.normalize(toPosixPath(use)).replace(/\/+$/, '') || '.';
+    if (dir.startsWith('..') || path.isAbsolute(dir) || modules.some(m => m.dir === dir)) continue;
+    const goMod = dir === '.' ? 'go.mod' : `${dir}/go.mod`;
+    let modulePath: string | undefined;
+    try {
+      modulePath = fs.readFileSync(path.join(projectRoot, goMod), 'utf8').match(/^module\s+"?([^"\s]+)"?/m)?.[1];
+    } catch {
+      missing.push(dir);
+      continue;
+    }
+    modules.push({ dir, go_mod: goMod, ...(modulePath ? { module_path: modulePath } : {}) });
+  }
+
+  modules.sort((a, b) => depth(b.dir) - depth(a.dir) || (a.dir < b.dir ? -1 : a.dir > b.dir ? 1 : 0));
+  return { file: 'go.work', modules, missing };
+}
+
+/**
+ * Member module containing a directory or file (relative to the project root)
+ */
+export function workspaceModuleOf(workspace: GoWorkspace, relativePath: string): GoWorkspaceModule | undefined {
+  const target = toPosixPath(relativePath);
+  return workspace.modules.find(m => m.dir === '.' || target === m.dir || target.startsWith(`${m.dir}/`));
+}
+
+/**
+ * Import path of a package directory (relative to the project root) in the
+ * member module containing it, or null outside every member
+ */
+export function workspaceImportPath(workspace: GoWorkspace, dir: string): string | null {
+  const module = workspaceModuleOf(workspace, dir);
+  if (!module?.module_path) return null;
+  const relative = module.dir === '.' ? toPosixPath(dir) : toPosixPath(dir).slice(module.dir.length + 1);
+  return relative && relative !== '.' ? `${module.module_path}/${relative}` : module.module_path;
+}
+
+/**
+ * Record the go.mod each boundary maps to: `go_mod` of the member module
+ * holding most of its files, and `go_mods` listing every member when the
+ * boundary spans several
+ */
+export function attachGoModules(projectRoot: string, boundaries: DomainBoundary[], workspace: GoWorkspace): DomainBoundary[] {
+  if (workspace.modules.length === 0) return boundaries;
+
+  return boundaries.map(boundary => {
+    const counts = new Map<string, number>();
+    for (const file of boundary.files) {
+      const relative = toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+      const module = workspaceModuleOf(workspace, relative);
+      if (module) counts.set(module.go_mod, (counts.get(module.go_mod) ?? 0) + 1);
+    }
+    const goMods = [...counts].sort((a, b) => b[1] - a[1] || (a[0] < b[0] ? -1 : 1)).map(([goMod]) => goMod);
+    if (goMods.length === 0) return boundary;
+    return { ...boundary, go_mod: goMods[0], ...(goMods.length > 1 ? { go_mods: goMods } : {}) };
+  });
+}
+
+function depth(dir: string): number {
+  return dir === '.' ? 0 : dir.split('/').length;
+}
@@ -0,0 +1,193 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { parseGoDeclarations } from './context-selector.js';
+import { GoPackage } from './go-packages.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * A service of the project's .proto files, with the Go code generated for it and
+ * the servers implementing it
+ */
+export interface GrpcService {
+  /** Service name as declared in the .proto file */
+  name: string;
+  /** Qualified with the proto package, e.g. shop.order.v1.OrderService */
+  full_name: string;
+  proto: string;
+  rpcs: string[];
+  /** Package directory of the generated Go code, when it is in the project */
+  generated_dir?: string;
+  /**
+   * Structs embedding Unimplemented<Service>Server, with the files declaring them and
+   * their methods; shared when their package implements other services as well
+   */
+  implementations: { struct: string; files: string[]; shared: boolean }[];
+  /** Module the service seeds */
+  module: string;
+}
+
+export interface ProtoService {
+  name: string;
+  package?: string;
+  /** Import path of option go_package, without the ;name suffix */
+  go_package?: string;
+  rpcs: string[];
+}
+
+const PROTO_IGNORE = ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**', '**/testdata/**', '**/third_party/**'];
+
+/**
+ * Services of the .proto files under the project root, resolved to their generated
+ * Go package and implementing servers
+ */
+export function findGrpcServices(projectRoot: string, packages: GoPackage[]): GrpcService[] {
+  const protos = fastGlob.sync('**/*.proto', { cwd: projectRoot, ignore: PROTO_IGNORE }).sort();
+  if (protos.length === 0) return [];
+
+  const declared: (ProtoService & { proto: string })[] = [];
+  for (const proto of protos) {
+    try {
+      declared.push(...parseProtoServices(fs.readFileSync(path.join(projectRoot, proto), 'utf8')).map(service => ({ ...service, proto })));
+    } catch {
+      continue;
+    }
+  }
+  if (declared.length === 0) return [];
+
+  const implementations = findImplementations(projectRoot, packages);
+  const byImportPath = new Map(packages.map(pkg => [pkg.import_path, pkg.dir]));
+
+  return declared.map(service => {
+    const generatedDir = (service.go_package && byImportPath.get(service.go_package)) ?? generatedDirOf(projectRoot, packages, service.name);
+    return {
+      name: service.name,
+      full_name: service.package ? `${service.package}.${service.name}` : service.name,
+      proto: service.proto,
+      rpcs: service.rpcs,
+      ...(generatedDir ? { generated_dir: generatedDir } : {}),
+      implementations: implementations
+        .filter(impl => impl.service === service.name)
+        .map(({ struct, files, dir }) => ({
+          struct,
+          files,
+          shared: implementations.some(other => other.dir === dir && other.service !== service.name),
+        })),
+      module: serviceModuleName(service.name),
+    };
+  });
+}
+
+/**
+ * Services of one .proto file with their RPCs, the proto package and go_package
+ */
+export function parseProtoServices(content: string): ProtoService[] {
+  const source = content.replace(/\/\/[^\n]*|\/\*[\s\S]*?\*\//g, '');
+  const protoPackage = source.match(/^\s*package\s+([\w.]+)\s*;/m)?.[1];
+  const goPackage = source.match(/^\s*option\s+go_package\s*=\s*"([^";]+)(?:;\w+)?"\s*;/m)?.[1];
+
+  const services: ProtoService[] = [];
+  for (const match of source.matchAll(/\bservice\s+(\w+)\s*\{/g)) {
+    const open = match.index! + match[0].length - 1;
+    let depth = 0;
+    let close = open;
+    for (; close < source.length; close++) {
+      if (source[close] === '{') depth++;
+      else if (source[close] === '}' && --depth === 0) break;
+    }
+    const body = source.slice(open + 1, close);
+    services.push({
+      name: match[1],
+      ...(protoPackage ? { package: protoPackage } : {}),
+      ...(goPackage ? { go_package: goPackage } : {}),
+      rpcs: [...body.matchAll(/\brpc\s+(\w+)\s*\(/g)].map(rpc => rpc[1]),
+    });
+  }
+  return services;
+}
+
+/**
+ * Module a service seeds: its name without the Service/API suffix, in snake_case
+ * (OrderService → order, UserAccountService → user_account)
+ */
+export function serviceModuleName(service: string): string {
+  const base = service.replace(/(?:Service|Svc|API|Api)$/, '') || service;
+  return base
+    .replace(/([a-z0-9])([A-Z])/g, '$1_$2')
+    .replace(/([A-Z]+)([A-Z][a-z])/g, '$1_$2')
+    .toLowerCase();
+}
+
+/**
+ * Seeds of each service's module: the generated package and the implementing
+ * package (its files when the package implements other services too). Paths a
+ * configured seed already covers are left to it, and so is a generated package
+ * shared by services of different modules.
+ */
+export function grpcSeeds(services: GrpcService[], configured: string[] = []): Record<string, string[]> {
+  const covered = (seed: string) => configured.some(taken => seed === taken || seed.startsWith(`${taken}/`) || taken.startsWith(`${seed}/`));
+  const modulesOf = (dir: string) => new Set(services
+    .filter(service => service.generated_dir === dir)
+    .map(service => service.module));
+
+  const seeds: Record<string, string[]> = {};
+  for (const service of services) {
+    const paths: string[] = [];
+    if (service.generated_dir && modulesOf(service.generated_dir).size === 1) paths.push(service.generated_dir);
+    for (const impl of service.implementations) {
+      paths.push(...(impl.shared ? impl.files : impl.files.map(file => path.posix.dirname(file))));
+    }
+    const free = [...new Set(paths)].filter(seed => !covered(seed));
+    if (free.length > 0) seeds[service.module] = [...new Set([...(seeds[service.module] ?? []), ...free])].sort();
+  }
+  return seeds;
+}
+
+/**
+ * Go structs embedding an Unimplemented<Service>Server (any service, so packages
+ * serving several are recognized), with the files of the struct and its methods
+ */
+function findImplementations(projectRoot: string, packages: GoPackage[]): { service: string; struct: string; files: string[]; dir: string }[] {
+  const found: { service: string; struct: string; files: string[]; dir: string }[] = [];
+  for (const pkg of packages) {
+    const declarations = pkg.files
+      .filter(file => !file.endsWith('.pb.go'))
+      .flatMap(file => {
+        try {
+          return parseGoDeclarations(fs.readFileSync(path.join(projectRoot, file), 'utf8'), toPosixPath(file));
+        } catch {
+          return [];
+        }
+      });
+
+    for (const declaration of declarations) {
+      if (declaration.kind !== 'type') continue;
+      const embedded = declaration.body.match(/^\s*\*?(?:\w+\.)?Unimplemented(\w+)Server\s*$/m);
+      if (!embedded) continue;
+      const methods = declarations.filter(d =>
+        d.kind === 'method' && d.signature.match(/^func\s*\((?:\s*\w+\s+)?\s*\*?\s*(\w+)/)?.[1] === declaration.name);
+      found.push({
+        service: embedded[1],
+        struct: declaration.name,
+        files: [...new Set([declaration.file, ...methods.map(m => m.file)])].sort(),
+        dir: pkg.dir,
+      });
+    }
+  }
+  return found;
+}
+
+/**
+ * Package declaring the <Service>Server interface, for protos without go_package
+ */
+function generatedDirOf(projectRoot: string, packages: GoPackage[], service: string): string | undefined {
+  const pattern = new RegExp(`^type\\s+${service}Server\\s+interface\\b`, 'm');
+  return packages.find(pkg => pkg.files.some(file => {
+    if (!file.endsWith('.pb.go')) return false;
+    try {
+      return pattern.test(fs.readFileSync(path.join(projectRoot, file), 'utf8'));
+    } catch {
+      return false;
+    }
+  }))?.dir;
+}
@@ -0,0 +1,622 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { execFileSync } from 'child_process';
+import fastGlob from 'fast-glob';
+import { HttpConfig } from '../types/config.js';
+import { maskLiterals } from './api-surface.js';
+import { ConfigLoader } from './config-loader.js';
+import { goImports, goPackageName } from './go-load-check.js';
+import { detectGoProject, goImportAlias, goPackageImportPath, impliedPackageName } from './go-project-utils.js';
+import { toPosixPath } from './workspace-paths.js';
+
+export type HttpFramework = 'echo' | 'gin' | 'chi' | 'stdlib';
+
+/**
+ * What a response helper parameter receives: the framework context or
+ * ResponseWriter (target), the request, the status code, the payload, the error
+ * or its message
+ */
+export type HelperParam = 'target' | 'request' | 'status' | 'payload' | 'error' | 'message';
+
+export interface ResponseHelper {
+  /** As configured or found, e.g. pkg/httpx.RespondJSON */
+  ref: string;
+  import_path: string;
+  package: string;
+  name: string;
+  params: HelperParam[];
+  returns_error: boolean;
+}
+
+/**
+ * The package that creates the project's router; generated routes are plugged in there
+ */
+export interface RouterMount {
+  /** Package directory relative to the project root */
+  dir: string;
+  file?: string;
+  /** Router variable, e.g. e for `e := echo.New()` */
+  router?: string;
+}
+
+export interface HttpConventions {
+  framework: HttpFramework;
+  /** config: http.framework, detected: from the project's imports, default: nothing found */
+  source: 'config' | 'detected' | 'default';
+  /** Why this framework was chosen */
+  evidence: string;
+  /** Import path of the framework package (net/http for stdlib) */
+  framework_import: string;
+  /** Go module path, to import the module's own packages */
+  module?: string;
+  respond_json?: ResponseHelper;
+  respond_error?: ResponseHelper;
+  /** echo: request bodies are validated with c.Validate after binding */
+  validates: boolean;
+  mount?: RouterMount;
+  /** Configured parts that could not be resolved, conventions the module cannot support */
+  warnings: string[];
+}
+
+/**
+ * `// <Method> handles <VERB> <path>` above a handler method; routes.go is generated from these
+ */
+export interface HandlerRoute {
+  method: string;
+  /** Path with {name} parameters, whatever the framework */
+  path: string;
+  receiver: string;
+  handler: string;
+}
+
+/**
+ * A generated routes.go and the call that mounts it
+ */
+export interface ModuleRoutes {
+  module: string;
+  path: string;
+  framework: HttpFramework;
+  routes: number;
+  import_path: string;
+  /** e.g. orderhandler.RegisterRoutes(e.Group(""), orderHandler) */
+  call: string;
+  /** File (or package directory) creating the router */
+  mount?: string;
+}
+
+/**
+ * Statements of a handler body in the conventions of one framework
+ */
+export interface HandlerDialect {
+  signature(receiver: string, name: string): string;
+  pathParam(name: string): string;
+  /** Expression of the request's context.Context */
+  requestContext: string;
+  /** Decode (and validate) the request body into `target` */
+  bind(target: string): string[];
+  /** Last statements of a successful handler */
+  respond(status: string, value: string): string[];
+  /** Statements ending the handler with an error response */
+  fail(status: string, err: string): string[];
+  noContent(): string[];
+}
+
+const FRAMEWORKS: { framework: Exclude<HttpFramework, 'stdlib'>; pattern: RegExp; import: string; constructors: string[] }[] = [
+  { framework: 'echo', pattern: /^github\.com\/labstack\/echo(?:\/v\d+)?$/, import: 'github.com/labstack/echo/v4', constructors: ['New'] },
+  { framework: 'gin', pattern: /^github\.com\/gin-gonic\/gin$/, import: 'github.com/gin-gonic/gin', constructors: ['New', 'Default'] },
+  { framework: 'chi', pattern: /^github\.com\/go-chi\/chi(?:\/v\d+)?$/, import: 'github.com/go-chi/chi/v5', constructors: ['NewRouter', 'NewMux'] },
+];
+
+const DEFAULT_ROUTER: Record<HttpFramework, string> = { echo: 'e', gin: 'r', chi: 'r', stdlib: 'mux' };
+
+/** Response helper parameters assumed when a configured helper's source cannot be read */
+const DEFAULT_HELPER_PARAMS: Record<'json' | 'error', HelperParam[]> = {
+  json: ['target', 'status', 'payload'],
+  error: ['target', 'status', 'error'],
+};
+
+const HTTP_METHODS = 'GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS';
+
+/**
+ * Router framework, response helpers and router mount of a project: http.* config
+ * first, then what the existing code uses. Plain net/http when nothing is found.
+ */
+export function detectHttpConventions(projectRoot: string, config: HttpConfig = {}): HttpConventions {
+  const goProject = detectGoProject(projectRoot);
+  const files = goSourceFiles(projectRoot);
+  const warnings: string[] = [];
+
+  const usage = new Map<string, { import: string; files: string[] }>();
+  for (const file of files) {
+    for (const imported of goImports(file.content)) {
+      const framework = FRAMEWORKS.find(f => f.pattern.test(imported.path));
+      if (!framework) continue;
+      const entry = usage.get(framework.framework) ?? { import: imported.path, files: [] };
+      entry.files.push(file.path);
+      usage.set(framework.framework, entry);
+    }
+  }
+  const ranked = FRAMEWORKS
+    .filter(f => usage.has(f.framework))
+    .sort((a, b) => usage.get(b.framework)!.files.length - usage.get(a.framework)!.files.length);
+
+  let framework: HttpFramework;
+  let source: HttpConventions['source'];
+  let evidence: string;
+  if (config.framework) {
+    framework = config.framework;
+    source = 'config';
+    evidence = `http.framework: ${framework} in vibeflow.config.yaml`;
+  } else if (ranked.length > 0) {
+    framework = ranked[0].framework;
+    source = 'detected';
+    const used = usage.get(framework)!;
+    evidence = `${used.import} imported by ${used.files.length} files`;
+  } else {
+    framework = 'stdlib';
+    source = 'default';
+    evidence = 'no router framework imported';
+  }
+
+  const frameworkImport = framework === 'stdlib'
+    ? 'net/http'
+    : usage.get(framework)?.import ?? FRAMEWORKS.find(f => f.framework === framework)!.import;
+  const frameworkFiles = files.filter(file => goImports(file.content).some(i => i.path === frameworkImport));
+
+  const conventions: HttpConventions = {
+    framework,
+    source,
+    evidence,
+    framework_import: frameworkImport,
+    ...(goProject.moduleName ? { module: goProject.moduleName } : {}),
+    validates: framework === 'echo' && frameworkFiles.some(file => /\.Validator\s*=|\bc\.Validate\(/.test(maskLiterals(file.content))),
+    warnings,
+  };
+
+  // Method and {name} patterns of http.ServeMux are disabled below go 1.22
+  const goDirective = goProject.goModulePath ? fs.readFileSync(goProject.goModulePath, 'utf8').match(/^go\s+1\.(\d+)/m) : null;
+  if (framework === 'stdlib' && goDirective && Number(goDirective[1]) < 22) {
+    warnings.push(`generated net/http routes need go 1.22 or later in go.mod (found go 1.${goDirective[1]})`);
+  }
+
+  const moduleRoot = toPosixPath(path.relative(projectRoot, goProject.workingDirectory ?? projectRoot)) || '.';
+  for (const kind of ['json', 'error'] as const) {
+    const ref = kind === 'json' ? config.respondJSON : config.respondError;
+    const helper = ref
+      ? configuredHelper(projectRoot, moduleRoot, conventions, ref, kind)
+      : detectHelper(projectRoot, files, conventions, kind);
+    if (helper) conventions[kind === 'json' ? 'respond_json' : 'respond_error'] = helper;
+  }
+
+  const mount = detectMount(projectRoot, frameworkFiles, conventions, config.mount);
+  if (mount) conventions.mount = mount;
+  return conventions;
+}
+
+/**
+ * Router framework a Go file imports (stdlib when it imports none)
+ */
+export function importedFramework(content: string): HttpFramework {
+  const imports = goImports(content).map(imported => imported.path);
+  return FRAMEWORKS.find(f => imports.some(imported => f.pattern.test(imported)))?.framework ?? 'stdlib';
+}
+
+/**
+ * detectHttpConventions with the http section of the project's vibeflow.config.yaml
+ */
+export function loadHttpConventions(projectRoot: string): HttpConventions {
+  let config: HttpConfig = {};
+  try {
+    config = ConfigLoader.loadVibeFlowConfig(path.join(projectRoot, 'vibeflow.config.yaml')).http ?? {};
+  } catch {
+    // Invalid config: conventions come from the code alone
+  }
+  return detectHttpConventions(projectRoot, config);
+}
+
+/**
+ * Body statements for generated handlers: the framework's signature, binding and
+ * error idioms, going through the project's response helpers when there are any
+ */
+export function handlerDialect(conventions: HttpConventions): HandlerDialect {
+  const pkg = frameworkAlias(conventions);
+  const target = conventions.framework === 'echo' || conventions.framework === 'gin' ? 'c' : 'w';
+  const request = conventions.framework === 'echo' ? 'c.Request()' : conventions.framework === 'gin' ? 'c.Request' : 'r';
+  const returnsError = conventions.framework === 'echo';
+
+  const helperCall = (helper: ResponseHelper, status: string, value: string, err: string): string => {
+    const args: Record<HelperParam, string> = { target, request, status, payload: value, error: err, message: `${err}.Error()` };
+    return `${helper.package}.${helper.name}(${helper.params.map(p => args[p]).join(', ')})`;
+  };
+  // echo handlers return the helper's error, or nil after a helper that returns none
+  const end = (helper: ResponseHelper, call: string, last: boolean): string[] => {
+    if (returnsError) return helper.returns_error ? [`return ${call}`] : [call, 'return nil'];
+    return last ? [call] : [call, 'return'];
+  };
+
+  const respond = (status: string, value: string): string[] => {
+    if (conventions.respond_json) return end(conventions.respond_json, helperCall(conventions.respond_json, status, value, 'nil'), true);
+    switch (conventions.framework) {
+      case 'echo': return [`return c.JSON(${status}, ${value})`];
+      case 'gin': return [`c.JSON(${status}, ${value})`];
+      default: return ['w.Header().Set("Content-Type", "application/json")', `w.WriteHeader(${status})`, `json.NewEncoder(w).Encode(${value})`];
+    }
+  };
+  const fail = (status: string, err: string): string[] => {
+    if (conventions.respond_error) return end(conventions.respond_error, helperCall(conventions.respond_error, status, 'nil', err), false);
+    switch (conventions.framework) {
+      case 'echo': return [`return ${pkg}.NewHTTPError(${status}, ${err}.Error())`];
+      case 'gin': return [`c.AbortWithStatusJSON(${status}, ${pkg}.H{"error": ${err}.Error()})`, 'return'];
+      default: return [`http.Error(w, ${err}.Error(), ${status})`, 'return'];
+    }
+  };
+  const guard = (statement: string): string[] =>
+    [`if err := ${statement}; err != nil {`, ...fail('http.StatusBadRequest', 'err').map(line => `\t${line}`), '}'];
+
+  return {
+    signature: (receiver, name) => {
+      switch (conventions.framework) {
+        case 'echo': return `func (h *${receiver}) ${name}(c ${pkg}.Context) error`;
+        case 'gin': return `func (h *${receiver}) ${name}(c *${pkg}.Context)`;
+        default: return `func (h *${receiver}) ${name}(w http.ResponseWriter, r *http.Request)`;
+      }
+    },
+    pathParam: name => {
+      switch (conventions.framework) {
+        case 'echo':
+        case 'gin': return `c.Param("${name}")`;
+        case 'chi': return `${pkg}.URLParam(r, "${name}")`;
+        default: return `r.PathValue("${name}")`;
+      }
+    },
+    requestContext: `${request}.Context()`,
+    bind: target => {
+      switch (conventions.framework) {
+        case 'echo': return [...guard(`c.Bind(${target})`), ...(conventions.validates ? guard(`c.Validate(${target})`) : [])];
+        case 'gin': return guard(`c.ShouldBindJSON(${target})`);
+        default: return guard(`json.NewDecoder(r.Body).Decode(${target})`);
+      }
+    },
+    respond,
+    fail,
+    noContent: () => {
+      switch (conventions.framework) {
+        case 'echo': return ['return c.NoContent(http.StatusNoContent)'];
+        case 'gin': return ['c.Status(http.StatusNoContent)'];
+        default: return ['w.WriteHeader(http.StatusNoContent)'];
+      }
+    },
+  };
+}
+
+/**
+ * Imports a generated handler file needs, from the package names its body uses
+ */
+export function handlerImports(conventions: HttpConventions, body: string, extra: string[] = []): string[] {
+  const candidates = new Map<string, string>([
+    ['json', 'encoding/json'],
+    ['http', 'net/http'],
+    [frameworkAlias(conventions), conventions.framework_import],
+  ]);
+  for (const helper of [conventions.respond_json, conventions.respond_error]) {
+    if (helper) candidates.set(helper.package, helper.import_path);
+  }
+  const masked = maskLiterals(body);
+  const used = [...candidates].filter(([alias]) => new RegExp(`\\b${alias}\\.`).test(masked)).map(([, importPath]) => importPath);
+  return [...new Set([...used, ...extra])].sort();
+}
+
+/**
+ * Routes declared by `// <Method> handles <VERB> <path>` comments of handler methods
+ */
+export function extractHandlerRoutes(content: string): HandlerRoute[] {
+  const pattern = new RegExp(
+    `^//\\s*(\\w+) handles (${HTTP_METHODS})\\s+(/\\S*)[^\\n]*\\n(?://[^\\n]*\\n)*func\\s*\\(\\s*\\w+\\s+\\*?(\\w+)\\s*\\)\\s*(\\w+)\\s*\\(`,
+    'gm'
+  );
+  const routes: HandlerRoute[] = [];
+  for (const match of content.replace(/\r\n/g, '\n').matchAll(pattern)) {
+    if (match[1] !== match[5]) continue;
+    routes.push({ method: match[2], path: match[3], receiver: match[4], handler: match[5] });
+  }
+  return routes;
+}
+
+/**
+ * routes.go of a handler package: one RegisterRoutes function taking the router
+ * and the package's handlers, so the mounting code needs a single call
+ */
+export function renderRoutesFile(conventions: HttpConventions, packageName: string, routes: HandlerRoute[], moduleName: string): string {
+  const receivers = [...new Set(routes.map(r => r.receiver))];
+  const variable = (receiver: string) => receiver.charAt(0).toLowerCase() + receiver.slice(1);
+  const { param, type } = routerParameter(conventions);
+
+  const registrations = routes.map(route => {
+    const handler = `${variable(route.receiver)}.${route.handler}`;
+    const routePath = conventions.framework === 'echo' || conventions.framework === 'gin'
+      ? route.path.replace(/\{(\w+)\}/g, ':$1')
+      : route.path;
+    switch (conventions.framework) {
+      case 'echo':
+      case 'gin': return `\t${param}.${route.method}("${routePath}", ${handler})`;
+      case 'chi': return `\t${param}.${route.method.charAt(0)}${route.method.slice(1).toLowerCase()}("${routePath}", ${handler})`;
+      default: return `\t${param}.HandleFunc("${route.method} ${routePath}", ${handler})`;
+    }
+  });
+
+  return `package ${packageName}
+
+import "${conventions.framework_import}"
+
+// RegisterRoutes mounts the ${moduleName} routes on the project's router
+//
+// Generated by VibeFlow from the "handles" comments of this package's handlers.
+func RegisterRoutes(${param} ${type}, ${receivers.map(r => `${variable(r)} *${r}`).join(', ')}) {
+${registrations.join('\n')}
+}
+`;
+}
+
+/**
+ * The one call that plugs a generated routes.go into the router, e.g.
+ * `orderhandler.RegisterRoutes(e.Group(""), orderHandler)`
+ */
+export function routeMountCall(conventions: HttpConventions, packageAlias: string, routes: HandlerRoute[]): string {
+  const router = conventions.mount?.router ?? DEFAULT_ROUTER[conventions.framework];
+  const argument = conventions.framework === 'echo' ? `${router}.Group("")` : router;
+  const receivers = [...new Set(routes.map(r => r.receiver))].map(r => r.charAt(0).toLowerCase() + r.slice(1));
+  return `${packageAlias}.RegisterRoutes(${[argument, ...receivers].join(', ')})`;
+}
+
+/**
+ * Prompt section describing the project's HTTP conventions for the handler layer
+ */
+export function renderHttpConventionSection(conventions: HttpConventions): string {
+  const dialect = handlerDialect(conventions);
+  const helpers = [conventions.respond_json, conventions.respond_error]
+    .filter((h): h is ResponseHelper => h !== undefined)
+    .map(h => `${h.package}.${h.name} (${h.import_path})`);
+
+  return [
+    `## HTTP Handlers: ${conventions.framework}`,
+    `Handlers must plug into the project's existing router (${conventions.evidence}):`,
+    `- Signature: ${dialect.signature('XHandler', 'Name')}`,
+    `- Path parameters: ${dialect.pathParam('id')}; request context: ${dialect.requestContext}`,
+    `- Request bodies: ${dialect.bind('&req').join(' ').replace(/\s+/g, ' ')}`,
+    `- Success responses: ${dialect.respond('http.StatusOK', 'result').join('; ')}`,
+    `- Errors: ${dialect.fail('http.StatusBadRequest', 'err').join('; ')}`,
+    ...(helpers.length > 0 ? [`- Use the project's response helpers, never a custom envelope: ${helpers.join(', ')}`] : []),
+    '- Put `// <Method> handles <VERB> <path>` (path parameters as {id}) directly above every handler method. Route registration is generated from these comments into routes.go; do not register routes or create a router yourself.',
+  ].join('\n');
+}
+
+/**
+ * Compile the generated handler packages together with the package that mounts
+ * the router; returns the compiler output on failure
+ */
+export function verifyRouteMount(projectRoot: string, dirs: string[]): string | null {
+  const goProject = detectGoProject(projectRoot);
+  if (!goProject.hasGoProject || dirs.length === 0) return null;
+
+  const packages = [...new Set(dirs.map(dir => {
+    const relative = toPosixPath(path.relative(goProject.workingDirectory!, path.join(projectRoot, dir)));
+    return relative ? `./${relative}` : '.';
+  }))];
+  try {
+    execFileSync('go', ['build', ...packages], { cwd: goProject.workingDirectory!, stdio: 'pipe', timeout: 600000 });
+    return null;
+  } catch (error) {
+    // No Go toolchain: nothing to verify with
+    if ((error as NodeJS.ErrnoException).code === 'ENOENT') return null;
+    const output = (error as { stderr?: Buffer }).stderr?.toString().trim();
+    return output || `go build ${packages.join(' ')} failed`;
+  }
+}
+
+function frameworkAlias(conventions: HttpConventions): string {
+  return conventions.framework === 'stdlib' ? 'http' : impliedPackageName(conventions.framework_import);
+}
+
+function routerParameter(conventions: HttpConventions): { param: string; type: string } {
+  const pkg = frameworkAlias(conventions);
+  switch (conventions.framework) {
+    case 'echo': return { param: 'g', type: `*${pkg}.Group` };
+    case 'gin': return { param: 'r', type: `${pkg}.IRouter` };
+    case 'chi': return { param: 'r', type: `${pkg}.Router` };
+    default: return { param: 'mux', type: '*http.ServeMux' };
+  }
+}
+
+interface SourceFile {
+  path: string;
+  content: string;
+}
+
+function goSourceFiles(projectRoot: string): SourceFile[] {
+  return fastGlob.sync('**/*.go', {
+    cwd: projectRoot,
+    ignore: ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**', '**/testdata/**', '**/*_test.go'],
+  }).sort().flatMap(file => {
+    try {
+      return [{ path: file, content: fs.readFileSync(path.join(projectRoot, file), 'utf8') }];
+    } catch {
+      return [];
+    }
+  });
+}
+
+interface FunctionSignature {
+  params: { type: string; kind: HelperParam | null }[];
+  returns_error: boolean;
+}
+
+/**
+ * Signature of a top-level function, parameters classified by what they receive
+ */
+function functionSignature(content: string, name: string): FunctionSignature | null {
+  const masked = maskLiterals(content);
+  const match = masked.match(new RegExp(`^func\\s+${name}\\s*\\(([^)]*)\\)\\s*([^{\\n]*)\\{`, 'm'));
+  if (!match) return null;
+
+  const parts = match[1].split(',').map(p => p.trim()).filter(Boolean);
+  const named = parts.some(p => /^\w+\s+\S/.test(p));
+  const types: string[] = [];
+  let pending = 0;
+  for (const part of parts) {
+    const typed = part.match(/^\w+\s+(.+)$/);
+    if (named && !typed) {
+      pending++;
+      continue;
+    }
+    const type = named ? typed![1] : part;
+    types.push(...Array(pending + 1).fill(type));
+    pending = 0;
+  }
+
+  return {
+    params: types.map(type => ({ type: type.replace(/\s+/g, ''), kind: classifyParam(type) })),
+    returns_error: /^\(?\s*error\s*\)?$/.test(match[2].trim()),
+  };
+}
+
+function classifyParam(type: string): HelperParam | null {
+  const t = type.replace(/\s+/g, '');
+  if (t === 'context.Context') return null;
+  if (/^\*?\w+\.Context$/.test(t) || /^\w+\.ResponseWriter$/.test(t)) return 'target';
+  if (/^\*\w+\.Request$/.test(t)) return 'request';
+  if (t === 'int') return 'status';
+  if (t === 'any' || t === 'interface{}') return 'payload';
+  if (t === 'error') return 'error';
+  if (t === 'string') return 'message';
+  return null;
+}
+
+/**
+ * Whether a helper's first parameter is the response target of the framework
+ */
+function targetsFramework(type: string, framework: HttpFramework): boolean {
+  switch (framework) {
+    case 'echo': return /^\w+\.Context$/.test(type);
+    case 'gin': return /^\*\w+\.Context$/.test(type);
+    default: return /^\w+\.ResponseWriter$/.test(type);
+  }
+}
+
+function usableAs(signature: FunctionSignature, kind: 'json' | 'error', framework: HttpFramework): boolean {
+  const kinds = signature.params.map(p => p.kind);
+  if (kinds.includes(null) || !signature.params.some(p => p.kind === 'target' && targetsFramework(p.type, framework))) return false;
+  return kind === 'json'
+    ? kinds.includes('payload') && !kinds.includes('error')
+    : kinds.includes('error') || kinds.includes('message');
+}
+
+function configuredHelper(
+  projectRoot: string,
+  moduleRoot: string,
+  conventions: HttpConventions,
+  ref: string,
+  kind: 'json' | 'error'
+): ResponseHelper | undefined {
+  const separator = ref.lastIndexOf('.');
+  let dir = ref.slice(0, separator);
+  const name = ref.slice(separator + 1);
+  if (conventions.module && (dir === conventions.module || dir.startsWith(`${conventions.module}/`))) {
+    dir = dir.slice(conventions.module.length + 1) || '.';
+  }
+  const packageDir = path.posix.join(moduleRoot, dir);
+  const importPath = goPackageImportPath(projectRoot, packageDir) ?? dir;
+
+  let packageName = impliedPackageName(importPath);
+  let signature: FunctionSignature | null = null;
+  const absoluteDir = path.join(projectRoot, packageDir);
+  if (fs.existsSync(absoluteDir)) {
+    for (const file of fs.readdirSync(absoluteDir).filter(f => f.endsWith('.go') && !f.endsWith('_test.go')).sort()) {
+      const content = fs.readFileSync(path.join(absoluteDir, file), 'utf8');
+      packageName = goPackageName(content) ?? packageName;
+      signature = signature ?? functionSignature(content, name);
+    }
+  }
+
+  if (signature && signature.params.every(p => p.kind !== null)) {
+    return { ref, import_path: importPath, package: packageName, name, params: signature.params.map(p => p.kind!), returns_error: signature.returns_error };
+  }
+  conventions.warnings.push(signature
+    ? `${ref}: parameters not understood, assuming (${DEFAULT_HELPER_PARAMS[kind].join(', ')})`
+    : `${ref}: declaration not found, assuming (${DEFAULT_HELPER_PARAMS[kind].join(', ')})`);
+  return { ref, import_path: importPath, package: packageName, name, params: DEFAULT_HELPER_PARAMS[kind], returns_error: conventions.framework === 'echo' };
+}
+
+/**
+ * The most called exported function outside package main that writes responses
+ * for the framework (payload helpers for json, error helpers for error)
+ */
+function detectHelper(projectRoot: string, files: SourceFile[], conventions: HttpConventions, kind: 'json' | 'error'): ResponseHelper | undefined {
+  const namePattern = kind === 'json' ? /^(?:Respond|Write|Render|Send|Reply)?(?:JSON|OK|Success)?$|^(?:Respond|Write|Render|Send|Reply)/ : /Err|Fail|Problem/;
+  const candidates: (ResponseHelper & { calls: number })[] = [];
+
+  const byDir = new Map<string, SourceFile[]>();
+  files.forEach(file => byDir.set(path.posix.dirname(file.path), [...(byDir.get(path.posix.dirname(file.path)) ?? []), file]));
+
+  for (const [dir, dirFiles] of byDir) {
+    const packageName = goPackageName(dirFiles[0].content);
+    if (!packageName || packageName === 'main') continue;
+    const importPath = goPackageImportPath(projectRoot, dir);
+    if (!importPath) continue;
+
+    for (const file of dirFiles) {
+      for (const match of maskLiterals(file.content).matchAll(/^func\s+([A-Z]\w*)\s*\(/gm)) {
+        const name = match[1];
+        if (!namePattern.test(name)) continue;
+        const signature = functionSignature(file.content, name);
+        if (!signature || !usableAs(signature, kind, conventions.framework)) continue;
+
+        const calls = files.reduce((count, other) => {
+          const alias = goImportAlias(other.content, importPath, packageName);
+          if (!alias) return count;
+          return count + (maskLiterals(other.content).match(new RegExp(`\\b${alias}\\.${name}\\(`, 'g'))?.length ?? 0);
+        }, 0);
+        if (calls === 0) continue;
+        candidates.push({
+          ref: `${dir}.${name}`,
+          import_path: importPath,
+          package: packageName,
+          name,
+          params: signature.params.map(p => p.kind!),
+          returns_error: signature.returns_error,
+          calls,
+        });
+      }
+    }
+  }
+
+  const best = candidates.sort((a, b) => b.calls - a.calls || a.ref.localeCompare(b.ref))[0];
+  if (!best) return undefined;
+  const { calls: _, ...helper } = best;
+  return helper;
+}
+
+/**
+ * The package creating the router (`echo.New()`, `gin.Default()`, `chi.NewRouter()`,
+ * `http.NewServeMux()`), preferring package main, or the configured directory
+ */
+function detectMount(projectRoot: string, frameworkFiles: SourceFile[], conventions: HttpConventions, configured?: string): RouterMount | undefined {
+  const constructors = conventions.framework === 'stdlib'
+    ? ['NewServeMux']
+    : FRAMEWORKS.find(f => f.framework === conventions.framework)!.constructors;
+  const dir = configured ? toPosixPath(path.normalize(configured)).replace(/\/$/, '') : undefined;
+
+  const found = frameworkFiles.flatMap(file => {
+    if (dir && path.posix.dirname(file.path) !== dir) return [];
+    const alias = goImportAlias(file.content, conventions.framework_import);
+    if (!alias) return [];
+    const match = maskLiterals(file.content).match(new RegExp(`\\b(\\w+)\\s*:?=\\s*${alias}\\.(?:${constructors.join('|')})\\(\\)`));
+    return match ? [{ file: file.path, router: match[1], main: goPackageName(file.content) === 'main' }] : [];
+  }).sort((a, b) => Number(b.main) - Number(a.main));
+
+  if (found.length > 0) return { dir: path.posix.dirname(found[0].file), file: found[0].file, router: found[0].router };
+  if (dir) {
+    if (!fs.existsSync(path.join(projectRoot, dir))) conventions.warnings.push(`http.mount: ${dir} does not exist`);
+    return { dir };
+  }
+  return undefined;
+}
@@ -0,0 +1,281 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { parseGoDeclarations } from './context-selector.js';
+import { HttpFramework, importedFramework } from './http-conventions.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Share of the clustering edge weight two handler files under the same URL prefix add */
+export const ROUTE_PREFIX_WEIGHT = 0.4;
+
+/**
+ * A route registered on the project's router, with the handler serving it
+ */
+export interface HttpRoute {
+  /** GET, POST, ... or ANY for a route registered without a method */
+  method: string;
+  /** Full path including group prefixes, with {name} parameters whatever the framework */
+  path: string;
+  /** Type.Method or function name, as registered */
+  handler: string;
+  /** File declaring the handler; absent when it could not be resolved */
+  file?: string;
+  /** File registering the route */
+  registered_in: string;
+  framework: HttpFramework;
+  /** Boundary of the handler file, once the boundaries are known */
+  module?: string;
+}
+
+const ROUTE_METHODS = 'GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|Any|Get|Post|Put|Patch|Delete|Head|Options|Handle|HandleFunc';
+
+interface HandlerDeclaration {
+  file: string;
+  receiver?: string;
+}
+
+/**
+ * Route registrations of gin, echo, chi and net/http (ServeMux patterns with a
+ * method included), following Group variables and chi Route closures for prefixes.
+ * Handlers are resolved to their declaring file by name, narrowed by the receiver's type.
+ */
+export function findHttpRoutes(projectRoot: string, files: string[]): HttpRoute[] {
+  const sources = new Map<string, string>();
+  for (const file of [...new Set(files.map(toPosixPath))].sort()) {
+    if (!file.endsWith('.go') || file.endsWith('_test.go')) continue;
+    try {
+      sources.set(file, fs.readFileSync(path.resolve(projectRoot, file), 'utf8'));
+    } catch {
+      continue;
+    }
+  }
+
+  const handlers = new Map<string, HandlerDeclaration[]>();
+  for (const [file, source] of sources) {
+    for (const declaration of parseGoDeclarations(source, file)) {
+      if (declaration.kind === 'type') continue;
+      const receiver = declaration.kind === 'method'
+        ? declaration.signature.match(/^func\s*\((?:\s*\w+\s+)?\s*\*?\s*(\w+)/)?.[1]
+        : undefined;
+      handlers.set(declaration.name, [...(handlers.get(declaration.name) ?? []), { file, ...(receiver ? { receiver } : {}) }]);
+    }
+  }
+
+  const routes = new Map<string, HttpRoute>();
+  for (const [file, source] of sources) {
+    if (!new RegExp(`\\.(?:${ROUTE_METHODS})\\(\\s*"`).test(source)) continue;
+    const framework = importedFramework(source);
+    for (const declaration of parseGoDeclarations(source, file)) {
+      if (declaration.kind === 'type') continue;
+      for (const raw of registrations(declaration.body, maskContents(declaration.body), new Map(), framework)) {
+        const target = raw.target.match(/^(?:(\w+)\.)?(\w+)$/);
+        const candidates = target ? handlers.get(target[2]) ?? [] : [];
+        const resolved = target ? resolveHandler(candidates, target[1], declaration.body) : { file, receiver: undefined };
+        const name = target
+          ? resolved?.receiver ? `${resolved.receiver}.${target[2]}` : target[0]
+          : 'func literal';
+        const route: HttpRoute = {
+          method: raw.method,
+          path: raw.path,
+          handler: name,
+          ...(resolved ? { file: resolved.file } : {}),
+          registered_in: file,
+          framework,
+        };
+        routes.set(`${route.method} ${route.path}`, route);
+      }
+    }
+  }
+
+  return [...routes.values()].sort((a, b) => a.path.localeCompare(b.path) || a.method.localeCompare(b.method));
+}
+
+/**
+ * URL prefix a route is grouped by: the path up to its first resource segment
+ * (/api/v1/orders/{id}/items → /api/v1/orders)
+ */
+export function routePrefix(routePath: string): string {
+  const segments = routePath.split('/').filter(Boolean);
+  const resource = segments.findIndex(segment => !/^(?:api|v\d+)$/i.test(segment) && !/^[{*]/.test(segment));
+  return resource < 0 ? '/' : `/${segments.slice(0, resource + 1).join('/')}`;
+}
+
+/**
+ * The routes with the boundary of their handler file
+ */
+export function assignRouteModules(routes: HttpRoute[], boundaries: { name: string; files: string[] }[]): HttpRoute[] {
+  const moduleOf = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) {
+      if (!moduleOf.has(toPosixPath(file))) moduleOf.set(toPosixPath(file), boundary.name);
+    }
+  }
+  return routes.map(({ module: _, ...route }) => {
+    const module = route.file ? moduleOf.get(route.file) : undefined;
+    return module ? { ...route, module } : route;
+  });
+}
+
+/**
+ * URL prefixes of each handler file, for clustering
+ */
+export class RoutePrefixIndex {
+  private readonly prefixes = new Map<string, Set<string>>();
+
+  constructor(routes: HttpRoute[]) {
+    for (const route of routes) {
+      if (!route.file) continue;
+      const prefixes = this.prefixes.get(route.file) ?? new Set<string>();
+      prefixes.add(routePrefix(route.path));
+      this.prefixes.set(route.file, prefixes);
+    }
+  }
+
+  /** URL prefixes served by the files */
+  prefixesOf(files: string[]): string[] {
+    return [...new Set(files.flatMap(file => [...(this.prefixes.get(toPosixPath(file)) ?? [])]))].sort();
+  }
+
+  /** Shared share of the prefixes the two files serve (0 when either serves none) */
+  strength(file1: string, file2: string): number {
+    const a = this.prefixes.get(toPosixPath(file1));
+    const b = this.prefixes.get(toPosixPath(file2));
+    if (!a || !b || file1 === file2) return 0;
+    const shared = [...a].filter(prefix => b.has(prefix)).length;
+    return shared / new Set([...a, ...b]).size;
+  }
+}
+
+/**
+ * Registrations of a function body; `masked` is the body with comments and literal
+ * contents blanked, so braces and calls inside strings are not mistaken for code
+ */
+function registrations(
+  body: string,
+  masked: string,
+  prefixes: Map<string, string>,
+  framework: HttpFramework
+): { method: string; path: string; target: string }[] {
+  const found: { method: string; path: string; target: string }[] = [];
+
+  // chi: r.Route("/orders", func(r chi.Router) { ... }) — the closure's router is prefixed
+  let outer = masked;
+  let nestedUntil = -1;
+  for (const match of masked.matchAll(/(\w+)\.Route\(\s*"\s*"\s*,\s*func\s*\(\s*(\w+)\s[^)]*\)\s*\{/g)) {
+    if (match.index! < nestedUntil) continue;
+    const open = match.index! + match[0].length - 1;
+    const close = matchingBrace(masked, open);
+    if (close < 0) continue;
+    const prefix = joinPath(prefixes.get(match[1]) ?? '', literalAt(body, masked, match.index!));
+    const inner = new Map([...prefixes, [match[2], prefix]]);
+    found.push(...registrations(body.slice(open + 1, close), masked.slice(open + 1, close), inner, framework));
+    outer = outer.slice(0, open + 1) + ' '.repeat(close - open - 1) + outer.slice(close);
+    nestedUntil = close;
+  }
+
+  // gin/echo/chi groups: g := r.Group("/orders")
+  const scoped = new Map(prefixes);
+  for (const match of outer.matchAll(/(\w+)\s*:?=\s*(\w+)\.Group\(\s*"/g)) {
+    scoped.set(match[1], joinPath(scoped.get(match[2]) ?? '', literalAt(body, outer, match.index!)));
+  }
+
+  for (const match of outer.matchAll(new RegExp(`(\\w+)\\.(${ROUTE_METHODS})\\(\\s*"`, 'g'))) {
+    const pattern = literalAt(body, outer, match.index!);
+    const methodPattern = pattern.match(/^([A-Z]+)\s+(\/.*)$/);
+    const routePath = methodPattern ? methodPattern[2] : pattern;
+    // gin/echo groups register their root as ""
+    if (routePath !== '' && !routePath.startsWith('/')) continue;
+
+    // echo takes route middleware after the handler, the others before it
+    const args = callArguments(body, outer, match.index! + match[0].lastIndexOf('('));
+    const handler = (framework === 'echo' ? args[1] : args[args.length - 1])
+      ?.replace(/^http\.HandlerFunc\((.*)\)$/s, '$1')
+      .replace(/\(.*\)$/s, '')
+      .trim();
+    if (!handler || args.length < 2) continue;
+
+    const method = methodPattern ? methodPattern[1]
+      : /^(?:Handle|HandleFunc|Any)$/.test(match[2]) ? 'ANY'
+      : match[2].toUpperCase();
+    found.push({
+      method,
+      path: joinPath(scoped.get(match[1]) ?? '', routePath),
+      target: /^func\b/.test(handler) ? '' : handler.replace(/^&/, ''),
+    });
+  }
+  return found;
+}
+
+/**
+ * Handler declaration a registered name refers to: the only one with that name,
+ * else the one whose receiver type (and package) the registering code gives the variable
+ * (h := order.NewHandler(...), h := &order.Handler{}, h *order.Handler) or that the variable is named after
+ */
+function resolveHandler(candidates: HandlerDeclaration[], qualifier: string | undefined, body: string): HandlerDeclaration | undefined {
+  if (candidates.length <= 1) return candidates[0];
+  if (!qualifier) return candidates.find(candidate => !candidate.receiver);
+
+  const assigned = body.match(new RegExp(`\\b${qualifier}\\s*:?=\\s*(?:&?(?:(\\w+)\\.)?(\\w+)\\s*\\{|(?:(\\w+)\\.)?New(\\w+)\\s*\\()`));
+  const declared = body.match(new RegExp(`\\b${qualifier}\\s+\\*?(?:(\\w+)\\.)?([A-Z]\\w*)\\b`));
+  const [pkg, type] = assigned
+    ? assigned[2] ? [assigned[1], assigned[2]] : [assigned[3], assigned[4]]
+    : [declared?.[1], declared?.[2]];
+  const inPackage = (candidate: HandlerDeclaration) => !pkg || path.posix.basename(path.posix.dirname(candidate.file)) === pkg;
+  const matching = candidates.filter(candidate => candidate.receiver !== undefined && inPackage(candidate) &&
+    (candidate.receiver === type || candidate.receiver.toLowerCase() === qualifier.toLowerCase()));
+  if (matching.length === 1) return matching[0];
+
+  // A package-qualified function: handlers.ListOrders
+  const packaged = candidates.filter(candidate => !candidate.receiver && path.posix.basename(path.posix.dirname(candidate.file)) === qualifier);
+  return packaged.length === 1 ? packaged[0] : undefined;
+}
+
+/**
+ * Comments blanked and string literals emptied to spaces between their quotes, preserving offsets
+ */
+function maskContents(content: string): string {
+  return content.replace(/\/\/[^\n]*|\/\*[\s\S]*?\*\/|"(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*'|`[^`]*`/g, token => token.startsWith('/')
+    ? token.replace(/[^\n]/g, ' ')
+    : token[0] + token.slice(1, -1).replace(/[^\n]/g, ' ') + token[token.length - 1]);
+}
+
+/** Contents of the first string literal at or after `index` */
+function literalAt(body: string, masked: string, index: number): string {
+  const start = masked.indexOf('"', index);
+  const end = masked.indexOf('"', start + 1);
+  return start < 0 || end < 0 ? '' : body.slice(start + 1, end);
+}
+
+/** Arguments of the call whose opening parenthesis is at `open` */
+function callArguments(body: string, masked: string, open: number): string[] {
+  const args: string[] = [];
+  let depth = 0;
+  let start = open + 1;
+  for (let i = open; i < masked.length; i++) {
+    const char = masked[i];
+    if ('([{'.includes(char)) depth++;
+    else if (')]}'.includes(char)) depth--;
+    if ((char === ',' && depth === 1) || depth === 0) {
+      args.push(body.slice(start, i).trim());
+      start = i + 1;
+      if (depth === 0) break;
+    }
+  }
+  return args.filter(Boolean);
+}
+
+function matchingBrace(masked: string, open: number): number {
+  let depth = 0;
+  for (let i = open; i < masked.length; i++) {
+    if (masked[i] === '{') depth++;
+    else if (masked[i] === '}' && --depth === 0) return i;
+  }
+  return -1;
+}
+
+/** Group prefix and route path joined, :name parameters as {name} */
+function joinPath(prefix: string, routePath: string): string {
+  const joined = `/${[prefix, routePath].join('/')}`
+    .replace(/\/{2,}/g, '/')
+    .replace(/\/:(\w+)/g, '/{$1}');
+  return joined.length > 1 ? joined.replace(/\/$/, '') : joined;
+}
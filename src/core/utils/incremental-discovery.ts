@@ -0,0 +1,217 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { AnalysisCache, ANALYZER_VERSION } from './analysis-cache.js';
+import { renameWithRetry } from './file-io.js';
+import { toPosixPath } from './workspace-paths.js';
+import { DomainBoundary } from '../types/config.js';
+
+/**
+ * What the last full or incremental discovery wrote: the content hash of every
+ * mapped file and of domain-map.json itself, so a map edited or regenerated
+ * by other means is never merged into
+ */
+export interface DiscoveryState {
+  analyzer_version: number;
+  domain_map_hash: string;
+  files: Record<string, string>;
+}
+
+export interface FileChanges {
+  added: string[];
+  changed: string[];
+  removed: string[];
+  unchanged: string[];
+}
+
+export interface IncrementalMerge {
+  boundaries: DomainBoundary[];
+  /** Changed and added files with the boundary they were put in */
+  placed: { file: string; boundary: string }[];
+  /** Boundaries of the previous map left without files */
+  dropped: string[];
+}
+
+/** Recomputed on every discovery, so not carried over from the previous map */
+const DERIVED_FIELDS = ['debt', 'degraded_files', 'file_packages', 'file_coupling', 'annotations'] as const;
+
+export function discoveryStatePath(projectRoot: string): string {
+  return path.join(AnalysisCache.cacheDir(projectRoot), 'discovery-state.json');
+}
+
+/**
+ * State of the last discovery, or null when it does not describe the domain map on disk
+ * (no state, other analyzer version, map written since)
+ */
+export function loadDiscoveryState(projectRoot: string, domainMapPath: string): DiscoveryState | null {
+  try {
+    const state = JSON.parse(fs.readFileSync(discoveryStatePath(projectRoot), 'utf8')) as DiscoveryState;
+    if (state.analyzer_version !== ANALYZER_VERSION || !state.files) return null;
+    return state.domain_map_hash === AnalysisCache.hashContent(fs.readFileSync(domainMapPath, 'utf8')) ? state : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * @param carried - Hashes kept from the previous state for files this run did not analyze (scoped discovery),
+ *   so their changes since are still merged by the next incremental discovery
+ */
+export function saveDiscoveryState(projectRoot: string, domainMapPath: string, files: string[], carried: Record<string, string> = {}): void {
+  const hashes = hashFiles(projectRoot, files.filter(file => carried[toPosixPath(file)] === undefined));
+  for (const file of files.map(toPosixPath)) {
+    if (carried[file] !== undefined) hashes[file] = carried[file];
+  }
+  const state: DiscoveryState = {
+    analyzer_version: ANALYZER_VERSION,
+    domain_map_hash: AnalysisCache.hashContent(fs.readFileSync(domainMapPath, 'utf8')),
+    files: Object.fromEntries(Object.entries(hashes).sort(([a], [b]) => a.localeCompare(b))),
+  };
+  const statePath = discoveryStatePath(projectRoot);
+  fs.mkdirSync(path.dirname(statePath), { recursive: true });
+  const tmpPath = `${statePath}.${process.pid}.tmp`;
+  fs.writeFileSync(tmpPath, JSON.stringify(state, null, 2));
+  renameWithRetry(tmpPath, statePath);
+}
+
+/**
+ * Content hash of each readable file, keyed by its workspace path
+ */
+export function hashFiles(projectRoot: string, files: string[]): Record<string, string> {
+  const hashes: Record<string, string> = {};
+  for (const file of [...new Set(files.map(toPosixPath))].sort()) {
+    try {
+      hashes[file] = AnalysisCache.hashContent(fs.readFileSync(path.resolve(projectRoot, file), 'utf8'));
+    } catch {
+      // Deleted since; reported as removed
+    }
+  }
+  return hashes;
+}
+
+/**
+ * Compare the mapped files of the last discovery with the disk and the files of the new clustering
+ */
+export function diffFiles(projectRoot: string, previous: Record<string, string>, discovered: string[]): FileChanges {
+  const known = Object.keys(previous);
+  const current = hashFiles(projectRoot, known);
+  const changes: FileChanges = { added: [], changed: [], removed: [], unchanged: [] };
+  for (const file of known.sort()) {
+    if (current[file] === undefined) changes.removed.push(file);
+    else if (current[file] !== previous[file]) changes.changed.push(file);
+    else changes.unchanged.push(file);
+  }
+  changes.added = [...new Set(discovered.map(toPosixPath))].filter(file => previous[file] === undefined).sort();
+  return changes;
+}
+
+/**
+ * Keep the boundaries of the previous map for unchanged files and place only
+ * changed and added files by the new clustering: into the previous boundary
+ * that holds most of the unchanged files of their new cluster, or into a new
+ * boundary when their cluster only has changed or added files.
+ * Changed files outside every new cluster stay where they were.
+ */
+export function mergeIncrementalBoundaries(
+  previous: DomainBoundary[],
+  fresh: DomainBoundary[],
+  changes: FileChanges
+): IncrementalMerge {
+  const removed = new Set(changes.removed);
+  const unchanged = new Set(changes.unchanged);
+  const moving = new Set([...changes.changed, ...changes.added]);
+
+  const boundaries = previous.map(boundary => {
+    const result: DomainBoundary = { ...boundary, files: boundary.files.filter(file => !removed.has(file)) };
+    for (const field of DERIVED_FIELDS) delete result[field];
+    return result;
+  });
+  const ownerOf = new Map(boundaries.flatMap(boundary => boundary.files.map(file => [file, boundary] as const)));
+  // Files of boundaries not carried over (established modules reconsidered) are placed again
+  changes.unchanged.filter(file => !ownerOf.has(file)).forEach(file => moving.add(file));
+
+  const placed: { file: string; boundary: string }[] = [];
+  for (const cluster of fresh) {
+    const files = cluster.files.map(toPosixPath).filter(file => moving.has(file));
+    if (files.length === 0) continue;
+
+    const votes = new Map<DomainBoundary, number>();
+    for (const file of cluster.files.map(toPosixPath).filter(f => unchanged.has(f))) {
+      const owner = ownerOf.get(file);
+      if (owner) votes.set(owner, (votes.get(owner) ?? 0) + 1);
+    }
+    let target = [...votes].sort((a, b) => b[1] - a[1] || a[0].name.localeCompare(b[0].name))[0]?.[0];
+    if (!target) {
+      const name = uniqueName(cluster.name, boundaries);
+      target = { ...cluster, name, files: [] };
+      for (const field of DERIVED_FIELDS) delete target[field];
+      boundaries.push(target);
+    }
+
+    for (const file of files) {
+      const owner = ownerOf.get(file);
+      if (owner && owner !== target) owner.files = owner.files.filter(f => f !== file);
+      if (!target.files.includes(file)) target.files.push(file);
+      ownerOf.set(file, target);
+      moving.delete(file);
+      placed.push({ file, boundary: target.name });
+    }
+  }
+
+  const dropped = boundaries.filter(boundary => boundary.files.length === 0).map(boundary => boundary.name);
+  return { boundaries: boundaries.filter(boundary => boundary.files.length > 0), placed, dropped };
+}
+
+/**
+ * Put the files of a scoped discovery (vf discover --path / --package) into the previous map:
+ * files outside the scope keep their boundaries, files inside it come from the new clustering.
+ * Each new cluster continues the previous boundary most of its files were in (one cluster per
+ * boundary, largest first), otherwise it becomes a new boundary; previous boundaries left
+ * without files are dropped.
+ */
+export function mergeScopedBoundaries(
+  previous: DomainBoundary[],
+  fresh: DomainBoundary[],
+  inScope: (file: string) => boolean
+): IncrementalMerge {
+  const boundaries = previous.map(boundary => {
+    const result: DomainBoundary = { ...boundary, files: boundary.files.filter(file => !inScope(file)) };
+    for (const field of DERIVED_FIELDS) delete result[field];
+    return result;
+  });
+  const byName = new Map(boundaries.map(boundary => [boundary.name, boundary]));
+  const previousOwner = new Map(previous.flatMap(boundary => boundary.files.map(file => [toPosixPath(file), byName.get(boundary.name)!] as const)));
+
+  const clusters = fresh
+    .map(cluster => ({ cluster, files: [...new Set(cluster.files.map(toPosixPath))].filter(inScope) }))
+    .filter(({ files }) => files.length > 0)
+    .sort((a, b) => b.files.length - a.files.length || a.cluster.name.localeCompare(b.cluster.name));
+  const claimed = new Set<DomainBoundary>();
+  const placed: { file: string; boundary: string }[] = [];
+  for (const { cluster, files } of clusters) {
+    const votes = new Map<DomainBoundary, number>();
+    for (const file of files) {
+      const owner = previousOwner.get(file);
+      if (owner && !claimed.has(owner)) votes.set(owner, (votes.get(owner) ?? 0) + 1);
+    }
+    let target = [...votes].sort((a, b) => b[1] - a[1] || a[0].name.localeCompare(b[0].name))[0]?.[0];
+    if (!target) {
+      target = { ...cluster, name: uniqueName(cluster.name, boundaries), files: [] };
+      for (const field of DERIVED_FIELDS) delete target[field];
+      boundaries.push(target);
+    }
+    claimed.add(target);
+    for (const file of files) {
+      if (!target.files.includes(file)) target.files.push(file);
+      placed.push({ file, boundary: target.name });
+    }
+  }
+
+  const dropped = boundaries.filter(boundary => boundary.files.length === 0).map(boundary => boundary.name);
+  return { boundaries: boundaries.filter(boundary => boundary.files.length > 0), placed, dropped };
+}
+
+function uniqueName(name: string, boundaries: DomainBoundary[]): string {
+  let candidate = name;
+  for (let n = 2; boundaries.some(boundary => boundary.name === candidate); n++) candidate = `${name}-${n}`;
+  return candidate;
+}
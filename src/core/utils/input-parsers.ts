@@ -0,0 +1,243 @@
+import * as fs from 'fs';
+import * as yaml from 'js-yaml';
+import { ZodError } from 'zod';
+import { BoundaryConfig, BoundaryConfigSchema, DomainBoundary, DomainBoundarySchema } from '../types/config.js';
+import { RefactoredFile } from '../types/refactor.js';
+import { InputKind, InputParseError, getErrorMessage } from './error-utils.js';
+import { migrateDomainMap } from './domain-map-writer.js';
+
+/*
+ * Parsers for every artifact vibeflow reads from disk or from the LLM.
+ * Each one either returns a typed value or throws InputParseError; any other
+ * exception is a bug (see tests/unit/input-parsers.test.ts, which fuzzes them).
+ */
+
+export interface ParsedDomainMap {
+  /** Remaining top-level fields as found (project, metrics, ...) */
+  map: Record<string, unknown> & { boundaries: DomainBoundary[] };
+  /** Boundaries that failed validation; only these modules fail */
+  invalid: { index: number; name?: string; error: string }[];
+}
+
+export interface CoverageBlock {
+  file: string;
+  start_line: number;
+  end_line: number;
+  statements: number;
+  count: number;
+}
+
+export interface CoverageProfile {
+  mode: 'set' | 'count' | 'atomic';
+  blocks: CoverageBlock[];
+  statements: number;
+  covered: number;
+  /** Statement coverage in percent, null for a profile without statements */
+  percentage: number | null;
+}
+
+/**
+ * Read a text artifact, rejecting bytes that are not valid UTF-8
+ */
+export function readTextInput(input: InputKind, file: string): string {
+  const bytes = fs.readFileSync(file);
+  try {
+    return new TextDecoder('utf-8', { fatal: true }).decode(bytes);
+  } catch {
+    throw new InputParseError(input, 'not valid UTF-8', file);
+  }
+}
+
+export function parseBoundaryConfig(content: string, file?: string): BoundaryConfig {
+  const raw = loadYaml('boundary-config', content, file);
+  // An empty file declares no modules
+  if (raw === null || raw === undefined) return BoundaryConfigSchema.parse({});
+  if (typeof raw !== 'object' || Array.isArray(raw)) {
+    throw new InputParseError('boundary-config', 'expected a mapping at the top level', file);
+  }
+
+  const result = BoundaryConfigSchema.safeParse(raw);
+  if (!result.success) {
+    throw new InputParseError('boundary-config', formatIssues(result.error), file, result.error.issues);
+  }
+  return result.data;
+}
+
+/**
+ * Validate domain-map.json boundary by boundary, so one bad entry does not
+ * take the other modules down with it. Older maps are upgraded to the current
+ * schema first; maps from a newer vibeflow are rejected.
+ */
+export function parseDomainMap(content: string, file?: string): ParsedDomainMap {
+  let raw = parseJsonObject('domain-map', content, file, 'empty file (interrupted discovery?)');
+  try {
+    raw = migrateDomainMap(raw);
+  } catch (error) {
+    throw new InputParseError('domain-map', getErrorMessage(error), file);
+  }
+  if (!Array.isArray(raw.boundaries)) {
+    throw new InputParseError('domain-map', 'boundaries must be an array', file);
+  }
+
+  const boundaries: DomainBoundary[] = [];
+  const invalid: ParsedDomainMap['invalid'] = [];
+  raw.boundaries.forEach((entry: unknown, index: number) => {
+    const result = DomainBoundarySchema.safeParse(entry);
+    if (result.success) {
+      boundaries.push(result.data);
+      return;
+    }
+    const name = entry && typeof entry === 'object' && typeof (entry as { name?: unknown }).name === 'string'
+      ? (entry as { name: string }).name
+      : undefined;
+    invalid.push({ index, ...(name ? { name } : {}), error: formatIssues(result.error) });
+  });
+
+  return { map: { ...raw, boundaries }, invalid };
+}
+
+/**
+ * plan.json as written by ArchitectAgent; list fields default to empty
+ */
+export function parsePlan(content: string, file?: string): Record<string, unknown> & { modules: unknown[] } {
+  const raw = parseJsonObject('plan', content, file, 'empty file (interrupted plan?)');
+  for (const key of ['modules', 'shared_state', 'package_mismatches']) {
+    if (raw[key] !== undefined && raw[key] !== null && !Array.isArray(raw[key])) {
+      throw new InputParseError('plan', `${key} must be an array`, file);
+    }
+  }
+  return { ...raw, modules: Array.isArray(raw.modules) ? raw.modules : [] };
+}
+
+/**
+ * Extract the RefactoredFile JSON from an LLM response
+ */
+export function parseLlmResponse(text: string): RefactoredFile {
+  if (typeof text !== 'string' || text.trim() === '') {
+    throw new InputParseError('llm-response', 'empty response');
+  }
+  if (hasInvalidText(text)) {
+    throw new InputParseError('llm-response', 'response contains invalid UTF-8');
+  }
+
+  const start = text.indexOf('{');
+  const end = text.lastIndexOf('}');
+  if (start < 0 || end < start) {
+    throw new InputParseError('llm-response', 'no JSON found in response');
+  }
+
+  let parsed: any;
+  try {
+    parsed = JSON.parse(text.slice(start, end + 1));
+  } catch (error) {
+    throw new InputParseError('llm-response', getErrorMessage(error));
+  }
+  if (!parsed || typeof parsed !== 'object' || !Array.isArray(parsed.refactored_files)) {
+    throw new InputParseError('llm-response', 'Invalid refactored_files structure');
+  }
+
+  for (const key of ['refactored_files', 'interfaces', 'tests'] as const) {
+    if (!Array.isArray(parsed[key])) {
+      parsed[key] = [];
+      continue;
+    }
+    const bad = parsed[key].findIndex((f: any) => !f || typeof f !== 'object' || typeof f.path !== 'string' || typeof f.content !== 'string');
+    if (bad >= 0) {
+      throw new InputParseError('llm-response', `${key}[${bad}] needs string path and content`);
+    }
+  }
+  return parsed as RefactoredFile;
+}
+
+/**
+ * Parse a `go test -coverprofile` file; blocks repeated across packages count once
+ */
+export function parseCoverageProfile(content: string, file?: string): CoverageProfile {
+  const lines = content.split('\n').map(line => line.trim()).filter(Boolean);
+  const mode = lines[0]?.match(/^mode:\s*(set|count|atomic)$/)?.[1] as CoverageProfile['mode'] | undefined;
+  if (!mode) {
+    throw new InputParseError('coverage-profile', 'missing "mode:" header', file);
+  }
+
+  const blocks = new Map<string, CoverageBlock>();
+  lines.slice(1).forEach((line, index) => {
+    const match = line.match(/^(.+):(\d+)\.(\d+),(\d+)\.(\d+) (\d+) (\d+)$/);
+    if (!match) {
+      throw new InputParseError('coverage-profile', `line ${index + 2}: malformed block "${line.slice(0, 80)}"`, file);
+    }
+    const key = `${match[1]}:${match[2]}.${match[3]},${match[4]}.${match[5]}`;
+    const block = {
+      file: match[1],
+      start_line: Number(match[2]),
+      end_line: Number(match[4]),
+      statements: Number(match[6]),
+      count: Number(match[7]),
+    };
+    const existing = blocks.get(key);
+    blocks.set(key, existing ? { ...block, count: Math.max(existing.count, block.count) } : block);
+  });
+
+  const all = [...blocks.values()];
+  const statements = all.reduce((sum, b) => sum + b.statements, 0);
+  const covered = all.filter(b => b.count > 0).reduce((sum, b) => sum + b.statements, 0);
+  return {
+    mode,
+    blocks: all,
+    statements,
+    covered,
+    percentage: statements > 0 ? Math.round((covered / statements) * 1000) / 10 : null,
+  };
+}
+
+/**
+ * A Go file must at least have a package clause (a file holding only a build tag has none)
+ */
+export function checkGoSource(content: string, file?: string): void {
+  if (hasInvalidText(content)) {
+    throw new InputParseError('go-source', 'not valid UTF-8', file);
+  }
+  const code = content.replace(/\/\*[\s\S]*?\*\//g, '').replace(/\/\/[^\n]*/g, '');
+  if (!/^\s*package\s+[A-Za-z_]\w*/m.test(code)) {
+    throw new InputParseError('go-source', 'no package clause', file);
+  }
+}
+
+function loadYaml(input: InputKind, content: string, file?: string): unknown {
+  if (hasInvalidText(content)) {
+    throw new InputParseError(input, 'not valid UTF-8', file);
+  }
+  try {
+    return yaml.load(content);
+  } catch (error) {
+    throw new InputParseError(input, getErrorMessage(error).split('\n')[0], file);
+  }
+}
+
+function parseJsonObject(input: InputKind, content: string, file: string | undefined, emptyMessage: string): Record<string, any> {
+  if (content.trim() === '') {
+    throw new InputParseError(input, emptyMessage, file);
+  }
+  let raw: unknown;
+  try {
+    raw = JSON.parse(content);
+  } catch (error) {
+    throw new InputParseError(input, getErrorMessage(error), file);
+  }
+  if (!raw || typeof raw !== 'object' || Array.isArray(raw)) {
+    throw new InputParseError(input, 'expected a JSON object', file);
+  }
+  return raw as Record<string, any>;
+}
+
+/**
+ * Replacement characters (bytes that failed to decode) or unpaired surrogates
+ */
+function hasInvalidText(text: string): boolean {
+  return /\uFFFD|[\uD800-\uDBFF](?![\uDC00-\uDFFF])|(?<![\uD800-\uDBFF])[\uDC00-\uDFFF]/.test(text);
+}
+
+function formatIssues(error: ZodError): string {
+  return error.issues.slice(0, 3)
+    .map(issue => `${issue.path.join('.') || '(root)'}: ${issue.message}`)
+    .join('; ') + (error.issues.length > 3 ? ` (+${error.issues.length - 3} more)` : '');
+}
@@ -0,0 +1,390 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { VibeFlowPaths } from './file-paths.js';
+import { LlmUnavailableError } from './llm-call-guard.js';
+import { assertOnline } from './offline-guard.js';
+import { llmProvider } from './run-environment.js';
+import { AutonomyTier } from './module-risk.js';
+
+/** Price reduction of the Anthropic Message Batches API (half price) */
+export const ANTHROPIC_BATCH_DISCOUNT = 0.5;
+
+/** Model of batch requests when llm.escalation names none */
+export const DEFAULT_BATCH_MODEL = 'claude-sonnet-4-0';
+
+/** How often `vf refactor --collect --wait` polls the provider */
+export const BATCH_POLL_INTERVAL_MS = 60 * 1000;
+
+const BATCH_MAX_TOKENS = 16000;
+const ANTHROPIC_API_VERSION = '2023-06-01';
+
+/**
+ * --async-batch was requested but the configured provider cannot run batch jobs
+ */
+export class BatchUnsupportedError extends Error {
+  constructor(public readonly provider: string, reason: string) {
+    super(`${provider} does not support async batches: ${reason}`);
+    this.name = 'BatchUnsupportedError';
+  }
+}
+
+/**
+ * The prompt of an attempt was queued for the batch; its module waits for
+ * `vf refactor --collect` instead of failing
+ */
+export class BatchPendingError extends Error {
+  constructor(public readonly file: string) {
+    super(`${file} queued for the async batch`);
+    this.name = 'BatchPendingError';
+  }
+}
+
+export type BatchItemFailure = 'errored' | 'expired' | 'canceled';
+
+/**
+ * A batch item came back without a response; the file fails like any other
+ * unavailable LLM call (category llm)
+ */
+export class BatchItemError extends Error {
+  constructor(public readonly failure: BatchItemFailure, reason: string) {
+    super(`Batch request ${failure}: ${reason}`);
+    this.name = 'BatchItemError';
+  }
+}
+
+export interface BatchRequest {
+  /** sha256 of model and prompt, so a re-rendered prompt finds its response */
+  custom_id: string;
+  prompt: string;
+  model?: string;
+}
+
+export interface BatchItemResult {
+  custom_id: string;
+  response?: string;
+  failure?: BatchItemFailure;
+  error?: string;
+}
+
+export interface BatchProvider {
+  readonly name: string;
+  /** Price reduction of batch calls compared to synchronous ones */
+  readonly discount: number;
+  submit(requests: BatchRequest[]): Promise<{ id: string; expires_at?: string }>;
+  /** ended: every item has a result (succeeded or not) */
+  status(id: string): Promise<{ ended: boolean; expires_at?: string }>;
+  results(id: string): Promise<BatchItemResult[]>;
+}
+
+export interface BatchJob {
+  id: string;
+  module: string;
+  submitted_at: string;
+  expires_at?: string;
+  /** collected: results are in the response store */
+  status: 'in_progress' | 'collected';
+  requests: { custom_id: string; file: string; model?: string }[];
+}
+
+/** <run-id>/checkpoint.json: what a run submitted and how it continues */
+export interface BatchRunState {
+  run_id: number;
+  provider: string;
+  discount: number;
+  /** Options of the submitting run, reused when the results are collected */
+  options: {
+    apply: boolean;
+    cleanModule: boolean;
+    allowDegraded: boolean;
+    escalation: boolean;
+    commit: boolean;
+    reopenAccepted: boolean;
+    maxAutoApplyTier?: AutonomyTier;
+    force?: boolean;
+  };
+  /** Modules waiting for results */
+  modules: string[];
+  jobs: BatchJob[];
+}
+
+export interface StoredBatchResponse {
+  job_id: string;
+  model?: string;
+  response?: string;
+  failure?: BatchItemFailure;
+  error?: string;
+  /** Already answered an attempt; later collects replay it without recording another LLM call */
+  used?: boolean;
+  recorded_at: string;
+}
+
+/**
+ * Provider for --async-batch: the Message Batches API of Anthropic. Bedrock and
+ * Vertex runs, and Claude Code logins without an API key, are refused.
+ *
+ * @throws BatchUnsupportedError
+ */
+export function resolveBatchProvider(env: NodeJS.ProcessEnv = process.env): BatchProvider {
+  const provider = llmProvider();
+  if (provider !== 'anthropic (claude-code sdk)') {
+    throw new BatchUnsupportedError(provider, 'only the Anthropic Message Batches API is supported');
+  }
+  if (!env.ANTHROPIC_API_KEY) {
+    throw new BatchUnsupportedError(provider, 'the Message Batches API needs ANTHROPIC_API_KEY (a Claude Code login cannot submit batches)');
+  }
+  return new AnthropicBatchProvider(env.ANTHROPIC_API_KEY, env.ANTHROPIC_BASE_URL);
+}
+
+export class AnthropicBatchProvider implements BatchProvider {
+  readonly name = 'anthropic';
+  readonly discount = ANTHROPIC_BATCH_DISCOUNT;
+
+  constructor(private apiKey: string, private baseUrl = 'https://api.anthropic.com') {}
+
+  async submit(requests: BatchRequest[]): Promise<{ id: string; expires_at?: string }> {
+    const batch = await this.request('POST', '/v1/messages/batches', {
+      requests: requests.map(request => ({
+        custom_id: request.custom_id,
+        params: {
+          model: request.model ?? DEFAULT_BATCH_MODEL,
+          max_tokens: BATCH_MAX_TOKENS,
+          messages: [{ role: 'user', content: request.prompt }],
+        },
+      })),
+    });
+    return { id: batch.id, expires_at: batch.expires_at };
+  }
+
+  async status(id: string): Promise<{ ended: boolean; expires_at?: string }> {
+    const batch = await this.request('GET', `/v1/messages/batches/${id}`);
+    return { ended: batch.processing_status === 'ended', expires_at: batch.expires_at };
+  }
+
+  async results(id: string): Promise<BatchItemResult[]> {
+    const batch = await this.request('GET', `/v1/messages/batches/${id}`);
+    if (!batch.results_url) return [];
+    const text = await this.send('GET', batch.results_url).then(response => response.text());
+    return text.split('\n').filter(line => line.trim() !== '').map(line => parseBatchResult(JSON.parse(line)));
+  }
+
+  private async request(method: string, route: string, body?: unknown): Promise<any> {
+    return (await this.send(method, `${this.baseUrl}${route}`, body)).json();
+  }
+
+  private async send(method: string, url: string, body?: unknown): Promise<Response> {
+    assertOnline('Anthropic Message Batches API');
+    const response = await fetch(url, {
+      method,
+      headers: { 'x-api-key': this.apiKey, 'anthropic-version': ANTHROPIC_API_VERSION, 'content-type': 'application/json' },
+      ...(body !== undefined ? { body: JSON.stringify(body) } : {}),
+    });
+    if (!response.ok) {
+      throw new LlmUnavailableError(`batch API ${method} ${url.replace(this.baseUrl, '')}: HTTP ${response.status} ${(await response.text()).slice(0, 200)}`);
+    }
+    return response;
+  }
+}
+
+/**
+ * One line of a Message Batches results file
+ */
+export function parseBatchResult(line: any): BatchItemResult {
+  const result = line?.result ?? {};
+  if (result.type === 'succeeded') {
+    const text = (result.message?.content ?? [])
+      .filter((block: any) => block.type === 'text')
+      .map((block: any) => block.text)
+      .join('');
+    return { custom_id: line.custom_id, response: text };
+  }
+  const failure: BatchItemFailure = result.type === 'expired' || result.type === 'canceled' ? result.type : 'errored';
+  return {
+    custom_id: line.custom_id,
+    failure,
+    error: result.error?.error?.message ?? result.error?.message ?? (failure === 'errored' ? 'unknown error' : `request ${failure} before it was processed`),
+  };
+}
+
+/**
+ * LlmBatchSession - 非同期バッチの実行状態と応答
+ *
+ * Stored under .vibeflow/batches/<run-id>/: checkpoint.json (BatchRunState) and
+ * responses/<custom_id>.json. RefactorAgent answers a prompt from the stored
+ * responses, or queues it and marks its module pending.
+ */
+export class LlmBatchSession {
+  private dir: string;
+  /** Prompts without a response, submitted after the run */
+  readonly queued: (BatchRequest & { module: string; file: string })[] = [];
+
+  constructor(projectRoot: string, readonly runId: number, readonly discount: number) {
+    this.dir = path.join(new VibeFlowPaths(projectRoot).batchesDir, String(runId));
+  }
+
+  static requestId(prompt: string, model?: string): string {
+    return createHash('sha256').update(`${model ?? ''}\n${prompt}`).digest('hex');
+  }
+
+  response(prompt: string, model?: string): StoredBatchResponse | undefined {
+    try {
+      return JSON.parse(fs.readFileSync(this.responsePath(LlmBatchSession.requestId(prompt, model)), 'utf8'));
+    } catch {
+      return undefined;
+    }
+  }
+
+  markUsed(prompt: string, model?: string): void {
+    const customId = LlmBatchSession.requestId(prompt, model);
+    const stored = this.response(prompt, model);
+    if (stored) this.writeResponse(customId, { ...stored, used: true });
+  }
+
+  enqueue(module: string, file: string, prompt: string, model?: string): void {
+    const customId = LlmBatchSession.requestId(prompt, model);
+    if (this.queued.some(request => request.custom_id === customId)) return;
+    this.queued.push({ custom_id: customId, prompt, ...(model ? { model } : {}), module, file });
+  }
+
+  storeResults(job: BatchJob, results: BatchItemResult[]): void {
+    const models = new Map(job.requests.map(request => [request.custom_id, request.model]));
+    for (const result of results) {
+      const model = models.get(result.custom_id);
+      this.writeResponse(result.custom_id, {
+        job_id: job.id,
+        ...(model ? { model } : {}),
+        ...(result.response !== undefined ? { response: result.response } : { failure: result.failure, error: result.error }),
+        recorded_at: new Date().toISOString(),
+      });
+    }
+    // Requests the provider returned nothing for fail rather than wait forever
+    const returned = new Set(results.map(result => result.custom_id));
+    for (const request of job.requests.filter(r => !returned.has(r.custom_id))) {
+      this.writeResponse(request.custom_id, {
+        job_id: job.id,
+        ...(request.model ? { model: request.model } : {}),
+        failure: 'errored',
+        error: 'no result returned for the request',
+        recorded_at: new Date().toISOString(),
+      });
+    }
+  }
+
+  loadState(): BatchRunState | null {
+    try {
+      return JSON.parse(fs.readFileSync(this.statePath, 'utf8'));
+    } catch {
+      return null;
+    }
+  }
+
+  saveState(state: BatchRunState): void {
+    fs.mkdirSync(this.dir, { recursive: true });
+    fs.writeFileSync(this.statePath, JSON.stringify(state, null, 2));
+  }
+
+  /**
+   * Remove the checkpoint once every module was collected; responses are kept
+   */
+  clearState(): void {
+    fs.rmSync(this.statePath, { force: true });
+  }
+
+  private get statePath(): string {
+    return path.join(this.dir, 'checkpoint.json');
+  }
+
+  private responsePath(customId: string): string {
+    return path.join(this.dir, 'responses', `${customId}.json`);
+  }
+
+  private writeResponse(customId: string, response: StoredBatchResponse): void {
+    const target = this.responsePath(customId);
+    fs.mkdirSync(path.dirname(target), { recursive: true });
+    fs.writeFileSync(target, JSON.stringify(response, null, 2));
+  }
+}
+
+/**
+ * Submit the queued prompts, one job per module so that finished modules can be
+ * applied while the others are still processed
+ */
+export async function submitBatchJobs(provider: BatchProvider, session: LlmBatchSession, state: BatchRunState): Promise<BatchJob[]> {
+  const byModule = new Map<string, LlmBatchSession['queued']>();
+  for (const request of session.queued) {
+    byModule.set(request.module, [...(byModule.get(request.module) ?? []), request]);
+  }
+
+  const jobs: BatchJob[] = [];
+  for (const [module, requests] of byModule) {
+    const submitted = await provider.submit(requests.map(({ custom_id, prompt, model }) => ({ custom_id, prompt, ...(model ? { model } : {}) })));
+    const job: BatchJob = {
+      id: submitted.id,
+      module,
+      submitted_at: new Date().toISOString(),
+      ...(submitted.expires_at ? { expires_at: submitted.expires_at } : {}),
+      status: 'in_progress',
+      requests: requests.map(({ custom_id, file, model }) => ({ custom_id, file, ...(model ? { model } : {}) })),
+    };
+    state.jobs.push(job);
+    jobs.push(job);
+  }
+  session.queued.length = 0;
+  return jobs;
+}
+
+/**
+ * Poll the jobs in progress and store the results of those that ended. A job
+ * past its expiry fails its remaining requests as expired.
+ *
+ * @returns ready: waiting modules whose jobs have all ended; waiting: the others
+ */
+export async function pollBatchJobs(
+  provider: BatchProvider,
+  session: LlmBatchSession,
+  state: BatchRunState,
+  now: Date = new Date()
+): Promise<{ ready: string[]; waiting: string[] }> {
+  for (const job of state.jobs.filter(j => j.status === 'in_progress')) {
+    const status = await provider.status(job.id);
+    if (status.ended) {
+      session.storeResults(job, await provider.results(job.id));
+      job.status = 'collected';
+    } else if (job.expires_at && new Date(job.expires_at) <= now) {
+      session.storeResults(job, job.requests.map(request => ({
+        custom_id: request.custom_id,
+        failure: 'expired' as const,
+        error: `job ${job.id} expired at ${job.expires_at}`,
+      })));
+      job.status = 'collected';
+    }
+  }
+
+  const inProgress = new Set(state.jobs.filter(j => j.status === 'in_progress').map(j => j.module));
+  return {
+    ready: state.modules.filter(module => !inProgress.has(module)),
+    waiting: state.modules.filter(module => inProgress.has(module)),
+  };
+}
+
+/**
+ * Bring the run's batch state up to date after the modules in `processed` ran:
+ * those still pending keep waiting and their new prompts are submitted. The
+ * checkpoint is removed once no module waits any more.
+ */
+export async function advanceBatchRun(
+  provider: BatchProvider,
+  session: LlmBatchSession,
+  state: BatchRunState,
+  processed: string[],
+  pending: string[]
+): Promise<BatchRunState> {
+  state.modules = [...state.modules.filter(module => !processed.includes(module)), ...pending];
+  await submitBatchJobs(provider, session, state);
+  if (state.modules.length > 0) {
+    session.saveState(state);
+  } else {
+    session.clearState();
+  }
+  return state;
+}
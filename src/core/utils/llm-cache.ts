@@ -0,0 +1,97 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { VibeFlowPaths } from './file-paths.js';
+
+/**
+ * Placeholder values a prompt was rendered from, kept so another template can be
+ * rendered against the same inputs (`vf prompt-bench --replay`)
+ */
+export interface PromptInputRecord {
+  task: string;
+  template: string;
+  template_hash: string;
+  module: string;
+  file: string;
+  variables: Record<string, string>;
+  /** Estimated tokens of the prompt as it was sent */
+  input_tokens: number;
+  recorded_at: string;
+}
+
+export interface CachedResponse {
+  model?: string;
+  response: string;
+  recorded_at: string;
+}
+
+/**
+ * LlmCache - LLM応答とプロンプト入力のキャッシュ
+ *
+ * Enabled with llm.cache. Responses that were parsed and verified are stored
+ * under .vibeflow/llm-cache/responses/<sha256 of model and prompt>.json, so an
+ * identical prompt is answered without an API call. The inputs of the latest
+ * prompt per task and file are stored under inputs/<module>/.
+ */
+export class LlmCache {
+  private rootDir: string;
+
+  constructor(projectRoot: string) {
+    this.rootDir = path.join(new VibeFlowPaths(projectRoot).outputRootPath, 'llm-cache');
+  }
+
+  get(prompt: string, model?: string): string | undefined {
+    try {
+      const entry: CachedResponse = JSON.parse(fs.readFileSync(this.responsePath(prompt, model), 'utf8'));
+      return entry.response;
+    } catch {
+      return undefined;
+    }
+  }
+
+  put(prompt: string, model: string | undefined, response: string): void {
+    const entry: CachedResponse = { ...(model ? { model } : {}), response, recorded_at: new Date().toISOString() };
+    const target = this.responsePath(prompt, model);
+    fs.mkdirSync(path.dirname(target), { recursive: true });
+    fs.writeFileSync(target, JSON.stringify(entry, null, 2));
+  }
+
+  saveInputs(record: Omit<PromptInputRecord, 'recorded_at'>): void {
+    const target = path.join(this.rootDir, 'inputs', safeName(record.module), `${record.task}-${safeName(record.file)}.json`);
+    fs.mkdirSync(path.dirname(target), { recursive: true });
+    fs.writeFileSync(target, JSON.stringify({ ...record, recorded_at: new Date().toISOString() }, null, 2));
+  }
+
+  /**
+   * Recorded prompt inputs, of one module when given, ordered by module and file
+   */
+  listInputs(module?: string): PromptInputRecord[] {
+    const inputsDir = path.join(this.rootDir, 'inputs');
+    if (!fs.existsSync(inputsDir)) return [];
+
+    const modules = module !== undefined ? [safeName(module)] : fs.readdirSync(inputsDir).sort();
+    return modules.flatMap(dir => {
+      const moduleDir = path.join(inputsDir, dir);
+      if (!fs.existsSync(moduleDir)) return [];
+      return fs.readdirSync(moduleDir)
+        .filter(name => name.endsWith('.json'))
+        .sort()
+        .flatMap(name => {
+          try {
+            return [JSON.parse(fs.readFileSync(path.join(moduleDir, name), 'utf8')) as PromptInputRecord];
+          } catch {
+            return [];
+          }
+        });
+    });
+  }
+
+  private responsePath(prompt: string, model?: string): string {
+    const key = createHash('sha256').update(`${model ?? ''}\n${prompt}`).digest('hex');
+    return path.join(this.rootDir, 'responses', key.slice(0, 2), `${key}.json`);
+  }
+}
+
+function safeName(value: string): string {
+  return value.replace(/[^\w.-]/g, '_');
+}
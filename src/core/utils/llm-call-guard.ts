@@ -0,0 +1,181 @@
+import { PerformanceStore } from './performance-store.js';
+import { requestShutdown } from './shutdown.js';
+
+export const DEFAULT_REQUEST_TIMEOUT_MS = 15 * 60 * 1000;
+export const DEFAULT_IDLE_TIMEOUT_MS = 2 * 60 * 1000;
+
+/**
+ * A single LLM call exceeded its time budget. Retryable: callers should
+ * fall back to smaller chunks instead of failing the file.
+ */
+export class LlmTimeoutError extends Error {
+  readonly retryable = true;
+
+  constructor(public readonly kind: 'request' | 'idle', public readonly timeoutMs: number) {
+    super(kind === 'idle'
+      ? `LLM call stalled: no response for ${Math.round(timeoutMs / 1000)}s`
+      : `LLM call timed out after ${Math.round(timeoutMs / 1000)}s`);
+    this.name = 'LlmTimeoutError';
+  }
+}
+
+/**
+ * The LLM could not be reached (SDK missing, network or API error). With
+ * --require-llm the module fails instead of falling back to templates.
+ */
+export class LlmUnavailableError extends Error {
+  constructor(public readonly reason: string) {
+    super(`LLM unavailable: ${reason}`);
+    this.name = 'LlmUnavailableError';
+  }
+}
+
+/**
+ * The user asked to skip the module currently being processed
+ */
+export class ModuleSkippedError extends Error {
+  constructor(public readonly module: string) {
+    super(`Module ${module} skipped by user`);
+    this.name = 'ModuleSkippedError';
+  }
+}
+
+export interface LlmCallControl {
+  /** Aborted on timeout or skip so the underlying request can stop */
+  abortController: AbortController;
+  /** Call for every streamed message to reset the idle timer */
+  onProgress(): void;
+}
+
+export interface LlmGuardOptions {
+  requestTimeoutMs?: number;
+  idleTimeoutMs?: number;
+  /** Aborted when the current module is skipped */
+  signal?: AbortSignal;
+}
+
+/**
+ * Run one LLM call with a hard request timeout, an idle timeout that is reset
+ * by streamed progress, and cancellation through the module skip signal
+ */
+export function guardLlmCall<T>(call: (control: LlmCallControl) => Promise<T>, options: LlmGuardOptions = {}): Promise<T> {
+  const requestTimeoutMs = options.requestTimeoutMs ?? DEFAULT_REQUEST_TIMEOUT_MS;
+  const idleTimeoutMs = options.idleTimeoutMs ?? DEFAULT_IDLE_TIMEOUT_MS;
+  const abortController = new AbortController();
+
+  return new Promise<T>((resolve, reject) => {
+    let settled = false;
+    let idleTimer: NodeJS.Timeout | undefined;
+
+    const finish = (error: Error | null, value?: T) => {
+      if (settled) return;
+      settled = true;
+      clearTimeout(requestTimer);
+      clearTimeout(idleTimer);
+      options.signal?.removeEventListener('abort', onSkip);
+      if (error) {
+        abortController.abort();
+        reject(error);
+      } else {
+        resolve(value as T);
+      }
+    };
+
+    const resetIdle = () => {
+      clearTimeout(idleTimer);
+      idleTimer = setTimeout(() => finish(new LlmTimeoutError('idle', idleTimeoutMs)), idleTimeoutMs);
+    };
+
+    const onSkip = () => finish(new ModuleSkippedError(String(options.signal?.reason ?? 'current')));
+
+    const requestTimer = setTimeout(() => finish(new LlmTimeoutError('request', requestTimeoutMs)), requestTimeoutMs);
+    resetIdle();
+
+    if (options.signal?.aborted) {
+      onSkip();
+      return;
+    }
+    options.signal?.addEventListener('abort', onSkip);
+
+    call({ abortController, onProgress: resetIdle }).then(
+      value => finish(null, value),
+      error => finish(error instanceof Error ? error : new Error(String(error)))
+    );
+  });
+}
+
+/**
+ * ModuleSkipController - 処理中モジュールのスキップ制御
+ *
+ * Skips can be requested from the TTY ("s") or from another terminal through
+ * `vf control skip-module`, which flags the module in the run record.
+ */
+export class ModuleSkipController {
+  private current: { module: string; controller: AbortController } | null = null;
+
+  beginModule(moduleName: string): AbortSignal {
+    this.current = { module: moduleName, controller: new AbortController() };
+    return this.current.controller.signal;
+  }
+
+  endModule(): void {
+    this.current = null;
+  }
+
+  get currentModule(): string | undefined {
+    return this.current?.module;
+  }
+
+  /**
+   * Abort the module being processed; returns its name
+   */
+  skipCurrent(): string | undefined {
+    if (!this.current || this.current.controller.signal.aborted) return undefined;
+    this.current.controller.abort(this.current.module);
+    return this.current.module;
+  }
+
+  /**
+   * Listen for "s" on an interactive terminal. Returns a function that stops listening.
+   */
+  watchKeypress(input: NodeJS.ReadStream = process.stdin): () => void {
+    if (!input.isTTY) return () => {};
+
+    const onData = (data: Buffer) => {
+      const key = data.toString();
+      if (key === '\u0003') {
+        // Raw mode swallows Ctrl+C; process.kill(pid, 'SIGINT') would skip cleanup on Windows
+        requestShutdown('SIGINT');
+      } else if (key.toLowerCase() === 's') {
+        const skipped = this.skipCurrent();
+        if (skipped) console.log(`\n⏭️  Skipping module ${skipped}...`);
+      }
+    };
+
+    input.setRawMode(true);
+    input.resume();
+    input.on('data', onData);
+
+    return () => {
+      input.off('data', onData);
+      input.setRawMode(false);
+      input.pause();
+    };
+  }
+
+  /**
+   * Poll the run record for skip requests written by `vf control skip-module`
+   */
+  watchRunRecord(store: PerformanceStore, runId: number, intervalMs = 1000): () => void {
+    const timer = setInterval(() => {
+      const moduleName = this.current?.module;
+      if (moduleName && store.isSkipRequested(runId, moduleName)) {
+        this.skipCurrent();
+        console.log(`\n⏭️  Skip requested for module ${moduleName}`);
+      }
+    }, intervalMs);
+    timer.unref();
+
+    return () => clearInterval(timer);
+  }
+}
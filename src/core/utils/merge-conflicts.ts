@@ -0,0 +1,322 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { FileSafetyManager } from './file-safety.js';
+import { PerformanceStore } from './performance-store.js';
+
+export const OURS_LABEL = 'your manual edit';
+export const THEIRS_LABEL = 'vibeflow regeneration';
+
+const OURS_MARKER = '<<<<<<<';
+const SEPARATOR = '=======';
+const THEIRS_MARKER = '>>>>>>>';
+
+/**
+ * Above this many line pairs the whole file becomes a single hunk instead of a full LCS diff
+ */
+const MAX_DIFF_CELLS = 4_000_000;
+
+export type HunkResolution = 'ours' | 'theirs' | 'both' | 'manual';
+
+export interface ConflictHunk {
+  /** Lines of the manual edit */
+  ours: string[];
+  /** Lines of the regenerated output */
+  theirs: string[];
+  resolution?: HunkResolution;
+}
+
+export interface FileConflict {
+  /** Workspace-relative path of the file holding conflict markers */
+  path: string;
+  module: string;
+  /** Legacy file the output was generated from */
+  source: string;
+  run_id?: number;
+  detected_at: string;
+  hunks: ConflictHunk[];
+  resolved_at?: string;
+}
+
+export interface ConflictRecord {
+  /** Run that produced the most recent conflicts */
+  run_id?: number;
+  updated_at: string;
+  files: FileConflict[];
+}
+
+export interface ConflictBlock {
+  /** 0-based line of the <<<<<<< marker */
+  start: number;
+  /** 0-based line of the >>>>>>> marker */
+  end: number;
+  ours: string[];
+  theirs: string[];
+}
+
+/**
+ * Merge a manually edited file with its regeneration, marking every differing region
+ * (there is no recorded base version, so both sides of a change are kept for the user)
+ */
+export function mergeWithMarkers(ours: string, theirs: string): { content: string; hunks: ConflictHunk[] } {
+  const a = ours.split('\n');
+  const b = theirs.split('\n');
+  const hunks: ConflictHunk[] = [];
+  const output: string[] = [];
+
+  const flush = (hunk: ConflictHunk | null) => {
+    if (!hunk) return;
+    hunks.push(hunk);
+    output.push(`${OURS_MARKER} ${OURS_LABEL}`, ...hunk.ours, SEPARATOR, ...hunk.theirs, `${THEIRS_MARKER} ${THEIRS_LABEL}`);
+  };
+
+  if (a.length * b.length > MAX_DIFF_CELLS) {
+    flush({ ours: a, theirs: b });
+    return { content: output.join('\n'), hunks };
+  }
+
+  // Longest common subsequence table, filled from the end
+  const lcs: Uint32Array[] = Array.from({ length: a.length + 1 }, () => new Uint32Array(b.length + 1));
+  for (let i = a.length - 1; i >= 0; i--) {
+    for (let j = b.length - 1; j >= 0; j--) {
+      lcs[i][j] = a[i] === b[j] ? lcs[i + 1][j + 1] + 1 : Math.max(lcs[i + 1][j], lcs[i][j + 1]);
+    }
+  }
+
+  let i = 0;
+  let j = 0;
+  let hunk: ConflictHunk | null = null;
+  while (i < a.length || j < b.length) {
+    if (i < a.length && j < b.length && a[i] === b[j]) {
+      flush(hunk);
+      hunk = null;
+      output.push(a[i]);
+      i++;
+      j++;
+    } else {
+      hunk = hunk ?? { ours: [], theirs: [] };
+      if (j >= b.length || (i < a.length && lcs[i + 1][j] >= lcs[i][j + 1])) {
+        hunk.ours.push(a[i++]);
+      } else {
+        hunk.theirs.push(b[j++]);
+      }
+    }
+  }
+  flush(hunk);
+
+  return { content: output.join('\n'), hunks };
+}
+
+export function hasConflictMarkers(content: string): boolean {
+  return parseConflictBlocks(content).length > 0;
+}
+
+export function parseConflictBlocks(content: string): ConflictBlock[] {
+  const lines = content.split('\n');
+  const blocks: ConflictBlock[] = [];
+  let current: { start: number; ours: string[]; theirs: string[]; inTheirs: boolean } | null = null;
+
+  lines.forEach((line, index) => {
+    if (line.startsWith(OURS_MARKER)) {
+      current = { start: index, ours: [], theirs: [], inTheirs: false };
+    } else if (current && line === SEPARATOR && !current.inTheirs) {
+      current.inTheirs = true;
+    } else if (current && current.inTheirs && line.startsWith(THEIRS_MARKER)) {
+      blocks.push({ start: current.start, end: index, ours: current.ours, theirs: current.theirs });
+      current = null;
+    } else if (current) {
+      (current.inTheirs ? current.theirs : current.ours).push(line);
+    }
+  });
+
+  return blocks;
+}
+
+/**
+ * Replace conflict blocks by the chosen side; blocks without a choice are kept
+ */
+export function applyResolutions(content: string, choices: (HunkResolution | undefined)[]): string {
+  const lines = content.split('\n');
+  const blocks = parseConflictBlocks(content);
+  const output: string[] = [];
+  let cursor = 0;
+
+  blocks.forEach((block, index) => {
+    output.push(...lines.slice(cursor, block.start));
+    const choice = choices[index];
+    if (choice === 'ours') output.push(...block.ours);
+    else if (choice === 'theirs') output.push(...block.theirs);
+    else if (choice === 'both') output.push(...block.ours, ...block.theirs);
+    else output.push(...lines.slice(block.start, block.end + 1));
+    cursor = block.end + 1;
+  });
+  output.push(...lines.slice(cursor));
+
+  return output.join('\n');
+}
+
+/**
+ * ConflictStore - 再実行で発生したマージコンフリクトの記録
+ *
+ * Keeps .vibeflow/conflicts.json: the files a re-run left with conflict
+ * markers because they had been edited by hand. `vf resolve` works through
+ * the unresolved entries and marks the run conflicts-resolved once none remain.
+ */
+export class ConflictStore {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  get storePath(): string {
+    return path.join(this.paths.outputRootPath, 'conflicts.json');
+  }
+
+  load(): ConflictRecord | null {
+    try {
+      return JSON.parse(fs.readFileSync(this.storePath, 'utf8'));
+    } catch {
+      return null;
+    }
+  }
+
+  /**
+   * Record conflicts of a run; unresolved conflicts of earlier runs are carried over
+   */
+  record(runId: number | undefined, conflicts: Omit<FileConflict, 'run_id' | 'detected_at'>[]): void {
+    if (conflicts.length === 0) return;
+
+    const now = new Date().toISOString();
+    const paths = new Set(conflicts.map(c => c.path));
+    const carried = (this.load()?.files ?? []).filter(f => !f.resolved_at && !paths.has(f.path));
+    this.save({
+      run_id: runId,
+      updated_at: now,
+      files: [...carried, ...conflicts.map(c => ({ ...c, run_id: runId, detected_at: now }))],
+    });
+
+    if (runId !== undefined) this.updateRun(runId, 'unresolved');
+  }
+
+  /**
+   * Conflicts still present on disk; files whose markers were removed by hand count as resolved
+   */
+  unresolved(): FileConflict[] {
+    const record = this.load();
+    if (!record) return [];
+
+    let changed = false;
+    for (const conflict of record.files) {
+      if (conflict.resolved_at) continue;
+      const content = this.readFile(conflict.path);
+      if (content === null || !hasConflictMarkers(content)) {
+        conflict.resolved_at = new Date().toISOString();
+        conflict.hunks = conflict.hunks.map(h => ({ ...h, resolution: h.resolution ?? 'manual' }));
+        changed = true;
+      }
+    }
+    if (changed) this.saveAndUpdateRun(record);
+
+    return record.files.filter(f => !f.resolved_at);
+  }
+
+  /**
+   * Apply per-hunk choices to a conflicted file (backed up first) and update the record
+   *
+   * @param choices one entry per remaining conflict block; undefined leaves the block
+   * @returns whether the file has no conflict markers left
+   */
+  async apply(conflictPath: string, choices: (HunkResolution | undefined)[], safetyManager: FileSafetyManager): Promise<boolean> {
+    const fullPath = path.join(this.projectRoot, conflictPath);
+    const content = this.readFile(conflictPath);
+    if (content === null) throw new Error(`Conflicted file not found: ${conflictPath}`);
+
+    const resolved = applyResolutions(content, choices);
+    await safetyManager.safeWrite(fullPath, resolved);
+    return this.markProgress(conflictPath, choices);
+  }
+
+  /**
+   * Record choices made for the remaining blocks (e.g. after editing the file in $EDITOR)
+   */
+  markProgress(conflictPath: string, choices: (HunkResolution | undefined)[]): boolean {
+    const record = this.load();
+    const conflict = record?.files.find(f => f.path === conflictPath && !f.resolved_at);
+    if (!record || !conflict) return true;
+
+    const pending = conflict.hunks.filter(h => !h.resolution);
+    pending.forEach((hunk, index) => {
+      if (choices[index]) hunk.resolution = choices[index];
+    });
+
+    const done = !hasConflictMarkers(this.readFile(conflictPath) ?? '');
+    if (done) {
+      conflict.resolved_at = new Date().toISOString();
+      conflict.hunks.forEach(h => { h.resolution = h.resolution ?? 'manual'; });
+    }
+    this.saveAndUpdateRun(record);
+    return done;
+  }
+
+  private saveAndUpdateRun(record: ConflictRecord): void {
+    record.updated_at = new Date().toISOString();
+    this.save(record);
+
+    const runIds = new Set(record.files.map(f => f.run_id).filter((id): id is number => id !== undefined));
+    for (const runId of runIds) {
+      const open = record.files.some(f => f.run_id === runId && !f.resolved_at);
+      this.updateRun(runId, open ? 'unresolved' : 'conflicts-resolved');
+    }
+  }
+
+  private updateRun(runId: number, state: 'unresolved' | 'conflicts-resolved'): void {
+    try {
+      const store = new PerformanceStore(this.projectRoot);
+      if (store.getRun(runId)) store.finishRun(runId, { conflicts: state });
+    } catch {
+      // Run tracking is best-effort
+    }
+  }
+
+  private save(record: ConflictRecord): void {
+    this.paths.writeArtifact(this.storePath, record);
+  }
+
+  private readFile(relativePath: string): string | null {
+    try {
+      return fs.readFileSync(path.join(this.projectRoot, relativePath), 'utf8');
+    } catch {
+      return null;
+    }
+  }
+}
+
+/**
+ * Lines of plan.md describing a module, shown next to its conflicts
+ */
+export function planExcerpt(projectRoot: string, moduleName: string, maxLines: number = 8): string[] {
+  const paths = new VibeFlowPaths(projectRoot);
+  let plan: string;
+  try {
+    plan = fs.readFileSync(paths.planPath, 'utf8');
+  } catch {
+    return [];
+  }
+
+  const lines = plan.split('\n');
+  const pattern = new RegExp(`\\b${moduleName.replace(/[.*+?^${}()|[\]\\]/g, '\\$&')}\\b`, 'i');
+  const heading = lines.findIndex(line => line.startsWith('#') && pattern.test(line));
+  const start = heading >= 0 ? heading : lines.findIndex(line => pattern.test(line));
+  if (start < 0) return [];
+
+  const excerpt: string[] = [lines[start]];
+  for (const line of lines.slice(start + 1)) {
+    if (excerpt.length >= maxLines || (heading >= 0 && /^#{1,3}\s/.test(line))) break;
+    excerpt.push(line);
+  }
+  while (excerpt.length > 1 && excerpt[excerpt.length - 1].trim() === '') excerpt.pop();
+  return excerpt;
+}
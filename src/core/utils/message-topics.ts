@@ -0,0 +1,200 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { AsyncEdge } from '../types/config.js';
+import { parseGoDeclarations } from './context-selector.js';
+import { goImports } from './go-load-check.js';
+import { toPosixPath } from './workspace-paths.js';
+
+export type MessageBroker = 'kafka' | 'nats' | 'sqs';
+
+/**
+ * A publish or subscribe call site with the topic (NATS subject, SQS queue name) it names
+ */
+export interface MessageEndpoint {
+  topic: string;
+  broker: MessageBroker;
+  role: 'publish' | 'subscribe';
+  file: string;
+  /** Function or method with the call site */
+  symbol: string;
+}
+
+export const ASYNC_EDGES_HEADING = '## 非同期連携 (Async Edges)';
+
+/** A topic literal or constant reference */
+const TOPIC = '("(?:[^"\\\\\\n]|\\\\.)*"|[\\w.]+)';
+/** A literal list of topics: []string{"a", b} */
+const TOPICS = '\\[\\]string\\s*\\{([^}]*)\\}';
+
+const BROKERS: { broker: MessageBroker; imports: RegExp; calls: { role: MessageEndpoint['role']; pattern: RegExp }[] }[] = [
+  {
+    broker: 'kafka',
+    imports: /^github\.com\/(?:segmentio\/kafka-go|confluentinc\/confluent-kafka-go(?:\/v\d+)?\/kafka|(?:IBM|Shopify)\/sarama)$/,
+    calls: [
+      { role: 'publish', pattern: new RegExp(`\\b(?:Writer|Message|ProducerMessage)\\s*\\{[^}]*?\\bTopic:\\s*${TOPIC}`, 'g') },
+      { role: 'publish', pattern: new RegExp(`\\bTopicPartition\\s*\\{[^}]*?\\bTopic:\\s*&?${TOPIC}`, 'g') },
+      { role: 'subscribe', pattern: new RegExp(`\\bReaderConfig\\s*\\{[^}]*?\\bTopic:\\s*${TOPIC}`, 'g') },
+      { role: 'subscribe', pattern: new RegExp(`\\.(?:Subscribe|ConsumePartition)\\(\\s*${TOPIC}`, 'g') },
+      { role: 'subscribe', pattern: new RegExp(`\\.(?:SubscribeTopics|Consume)\\(\\s*(?:\\w+\\s*,\\s*)?${TOPICS}`, 'g') },
+    ],
+  },
+  {
+    broker: 'nats',
+    imports: /^github\.com\/nats-io\/nats\.go(?:\/jetstream)?$/,
+    calls: [
+      { role: 'publish', pattern: new RegExp(`\\.(?:Publish|PublishAsync|Request)\\(\\s*(?:ctx\\s*,\\s*)?${TOPIC}\\s*,`, 'g') },
+      { role: 'subscribe', pattern: new RegExp(`\\.(?:Subscribe|SubscribeSync|ChanSubscribe|QueueSubscribe|QueueSubscribeSync|PullSubscribe)\\(\\s*${TOPIC}`, 'g') },
+    ],
+  },
+  {
+    broker: 'sqs',
+    imports: /^github\.com\/aws\/aws-sdk-go(?:-v2)?\/service\/sqs$/,
+    calls: [
+      { role: 'publish', pattern: new RegExp(`\\bSendMessage(?:Batch)?Input\\s*\\{[^}]*?\\bQueueUrl:\\s*(?:aws\\.String\\(\\s*)?${TOPIC}`, 'g') },
+      { role: 'subscribe', pattern: new RegExp(`\\bReceiveMessageInput\\s*\\{[^}]*?\\bQueueUrl:\\s*(?:aws\\.String\\(\\s*)?${TOPIC}`, 'g') },
+    ],
+  },
+];
+
+/**
+ * Publish/subscribe call sites of Kafka (kafka-go, confluent-kafka-go, sarama), NATS
+ * and SQS in the files importing them. Topics are string literals or string constants
+ * of the project; an SQS queue URL is reduced to the queue name.
+ */
+export function findMessageEndpoints(projectRoot: string, files: string[]): MessageEndpoint[] {
+  const sources = new Map<string, string>();
+  for (const file of [...new Set(files.map(toPosixPath))].sort()) {
+    if (!file.endsWith('.go') || file.endsWith('_test.go')) continue;
+    try {
+      sources.set(file, fs.readFileSync(path.resolve(projectRoot, file), 'utf8'));
+    } catch {
+      continue;
+    }
+  }
+  const constants = stringConstants([...sources.values()]);
+
+  const endpoints = new Map<string, MessageEndpoint>();
+  for (const [file, source] of sources) {
+    const imports = goImports(source).map(imported => imported.path);
+    for (const { broker, calls } of BROKERS.filter(b => imports.some(imported => b.imports.test(imported)))) {
+      for (const declaration of parseGoDeclarations(source, file)) {
+        if (declaration.kind === 'type') continue;
+        for (const { role, pattern } of calls) {
+          for (const match of declaration.body.matchAll(pattern)) {
+            const names = match[0].includes('[]string') ? match[1].split(',').map(name => name.trim()).filter(Boolean) : [match[1]];
+            for (const name of names) {
+              const topic = resolveTopic(name, constants, broker);
+              if (!topic) continue;
+              const endpoint: MessageEndpoint = { topic, broker, role, file, symbol: declaration.name };
+              endpoints.set(`${broker}\n${topic}\n${role}\n${file}\n${declaration.name}`, endpoint);
+            }
+          }
+        }
+      }
+    }
+  }
+
+  return [...endpoints.values()].sort((a, b) =>
+    a.topic.localeCompare(b.topic) || a.role.localeCompare(b.role) || a.file.localeCompare(b.file) || a.symbol.localeCompare(b.symbol));
+}
+
+/**
+ * Edges from each module publishing a topic to each other module subscribing to it
+ */
+export function buildAsyncEdges(endpoints: MessageEndpoint[], boundaries: { name: string; files: string[] }[]): AsyncEdge[] {
+  const moduleOf = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) {
+      if (!moduleOf.has(toPosixPath(file))) moduleOf.set(toPosixPath(file), boundary.name);
+    }
+  }
+
+  const edges = new Map<string, AsyncEdge>();
+  for (const publisher of endpoints.filter(e => e.role === 'publish')) {
+    const from = moduleOf.get(publisher.file);
+    if (!from) continue;
+    for (const subscriber of endpoints.filter(e => e.role === 'subscribe' && e.topic === publisher.topic && e.broker === publisher.broker)) {
+      const to = moduleOf.get(subscriber.file);
+      if (!to || to === from) continue;
+      const key = `${publisher.broker}\n${publisher.topic}\n${from}\n${to}`;
+      const edge = edges.get(key) ?? { topic: publisher.topic, broker: publisher.broker, from, to, files: [] };
+      edge.files = [...new Set([...edge.files, publisher.file, subscriber.file])].sort();
+      edges.set(key, edge);
+    }
+  }
+  return [...edges.values()].sort((a, b) => a.topic.localeCompare(b.topic) || a.from.localeCompare(b.from) || a.to.localeCompare(b.to));
+}
+
+/**
+ * Files on the two ends of a topic, for clustering: a publisher and a subscriber
+ * integrate through the broker, so their shared vocabulary is not coupling
+ */
+export class MessageTopicIndex {
+  private readonly published = new Map<string, Set<string>>();
+  private readonly subscribed = new Map<string, Set<string>>();
+
+  constructor(endpoints: MessageEndpoint[]) {
+    for (const endpoint of endpoints) {
+      const side = endpoint.role === 'publish' ? this.published : this.subscribed;
+      const topics = side.get(endpoint.file) ?? new Set<string>();
+      topics.add(`${endpoint.broker}:${endpoint.topic}`);
+      side.set(endpoint.file, topics);
+    }
+  }
+
+  /** Whether one file publishes a topic the other subscribes to */
+  integrates(file1: string, file2: string): boolean {
+    const a = toPosixPath(file1);
+    const b = toPosixPath(file2);
+    return a !== b && (this.feeds(a, b) || this.feeds(b, a));
+  }
+
+  private feeds(publisher: string, subscriber: string): boolean {
+    const subscribed = this.subscribed.get(subscriber);
+    return [...(this.published.get(publisher) ?? [])].some(topic => subscribed?.has(topic));
+  }
+}
+
+/**
+ * plan.md section listing the topics modules integrate through
+ */
+export function renderAsyncEdgesSection(edges: AsyncEdge[] = []): string {
+  if (edges.length === 0) return '';
+
+  const topics = new Map<string, AsyncEdge[]>();
+  for (const edge of edges) {
+    const key = `${edge.topic} (${edge.broker})`;
+    topics.set(key, [...(topics.get(key) ?? []), edge]);
+  }
+  const entries = [...topics].map(([topic, topicEdges]) => `- \`${topic}\`: ${topicEdges.map(edge => `${edge.from} → ${edge.to}`).join(', ')}`);
+
+  return `
+${ASYNC_EDGES_HEADING}
+
+以下のモジュールはメッセージのトピックを介して非同期に連携しています。トピックとメッセージの形式はモジュール間の契約として維持し、
+リファクタリングで同期的な呼び出しや直接の依存に置き換えないでください。
+
+${entries.join('\n')}
+`;
+}
+
+/**
+ * String constants and variables of the sources by name; names bound to different values are dropped
+ */
+function stringConstants(sources: string[]): Map<string, string> {
+  const values = new Map<string, string | null>();
+  for (const source of sources) {
+    for (const match of source.matchAll(/^\s*(?:const\s+|var\s+)?(\w+)\s*(?:string\s*)?=\s*"((?:[^"\\\n]|\\.)*)"\s*$/gm)) {
+      const existing = values.get(match[1]);
+      values.set(match[1], existing === undefined || existing === match[2] ? match[2] : null);
+    }
+  }
+  return new Map([...values].filter((entry): entry is [string, string] => entry[1] !== null));
+}
+
+function resolveTopic(name: string, constants: Map<string, string>, broker: MessageBroker): string | undefined {
+  const value = name.startsWith('"') ? name.slice(1, -1) : constants.get(name.split('.').pop()!);
+  if (!value) return undefined;
+  // SQS: https://sqs.<region>.amazonaws.com/<account>/<queue>
+  return broker === 'sqs' ? value.replace(/\/+$/, '').split('/').pop() : value;
+}
@@ -0,0 +1,486 @@
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { execSync } from 'child_process';
+import { VibeFlowPaths } from './file-paths.js';
+import { ModuleManifestStore } from './module-manifest.js';
+import { PerformanceStore, ProcessingMethod } from './performance-store.js';
+import { captureRunEnvironment } from './run-environment.js';
+import { detectGoProject } from './go-project-utils.js';
+import { parseGoDeclarations } from './context-selector.js';
+import { InputParseError, getErrorMessage } from './error-utils.js';
+import { parseDomainMap } from './input-parsers.js';
+import { toPosixPath } from './workspace-paths.js';
+import { BusinessRule } from '../types/business-logic.js';
+import { DomainBoundary } from '../types/config.js';
+
+/** Methods that can be forced for a whole module (static analysis only applies per file) */
+export type EvaluatedMethod = Exclude<ProcessingMethod, 'static'>;
+
+export const EVALUATED_METHODS: EvaluatedMethod[] = ['template', 'llm'];
+
+/** Artifacts copied into an evaluation workspace; everything else under .vibeflow starts empty */
+const ISOLATED_ARTIFACTS = ['domain-map.json', 'plan.json', 'plan.md', 'method-names.json'];
+
+export interface QualityScore {
+  method: EvaluatedMethod;
+  /** Set when the generation itself failed; the remaining fields are then zero */
+  error?: string;
+  duration_ms: number;
+  generated_files: number;
+  failed_files: number;
+  compiles: boolean;
+  /** Compiler errors reported in the generated files */
+  build_errors: number;
+  vet_warnings: number;
+  /** Generated files gofmt would rewrite */
+  lint_warnings: number;
+  tests_passed: number;
+  tests_failed: number;
+  /** null when the generated tests did not run any test */
+  test_pass_rate: number | null;
+  cyclomatic_avg: number;
+  cyclomatic_max: number;
+  rules_total: number;
+  /** Legacy business rules whose literals (or code) survive verbatim in the generated code */
+  rules_preserved: number;
+  todo_count: number;
+  not_implemented_count: number;
+}
+
+export interface MethodScorecard {
+  module: string;
+  evaluated_at: string;
+  run_id?: number;
+  scores: QualityScore[];
+  /** Evaluation workspaces, when kept for inspection */
+  workspaces?: Record<string, string>;
+}
+
+/**
+ * Rubric entries stored in performance_metrics as quality_<key>, labelled by module and method
+ */
+export const QUALITY_METRICS: { key: string; label: string; better: 'higher' | 'lower' }[] = [
+  { key: 'compiles', label: 'Compiles', better: 'higher' },
+  { key: 'build_errors', label: 'Build errors', better: 'lower' },
+  { key: 'vet_warnings', label: 'Vet warnings', better: 'lower' },
+  { key: 'lint_warnings', label: 'gofmt issues', better: 'lower' },
+  { key: 'test_pass_rate', label: 'Test pass rate', better: 'higher' },
+  { key: 'cyclomatic_avg', label: 'Cyclomatic avg', better: 'lower' },
+  { key: 'cyclomatic_max', label: 'Cyclomatic max', better: 'lower' },
+  { key: 'rules_preserved_rate', label: 'Rules preserved', better: 'higher' },
+  { key: 'todo_count', label: 'TODOs', better: 'lower' },
+  { key: 'not_implemented_count', label: 'Not implemented', better: 'lower' },
+];
+
+export type CommandRunner = (command: string, cwd: string) => { ok: boolean; output: string };
+
+export interface MethodEvaluationOptions {
+  methods?: EvaluatedMethod[];
+  /** Business-rule catalog (JSON array or { rules: [...] }); default: static extraction of the module files */
+  rulesPath?: string;
+  keepWorkspaces?: boolean;
+  run?: CommandRunner;
+  /** Generates the module into the workspace; default: RefactorAgent pinned to the method */
+  generate?: (workspace: string, boundary: DomainBoundary, method: EvaluatedMethod) => Promise<{ failed: number }>;
+}
+
+/**
+ * MethodEvaluator - テンプレート生成とLLM生成の品質比較
+ *
+ * Generates one module with each method in its own copy of the project
+ * and scores both outputs with the same rubric, so the cost of the LLM can
+ * be weighed against how much better its output actually is.
+ */
+export class MethodEvaluator {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  scorecardPath(moduleName: string): string {
+    return path.join(this.paths.reportsDir, `method-evaluation-${moduleName}.json`);
+  }
+
+  async evaluate(moduleName: string, options: MethodEvaluationOptions = {}): Promise<MethodScorecard> {
+    const boundary = this.loadBoundary(moduleName);
+    const rules = await this.loadRules(boundary, options.rulesPath);
+    const run = options.run ?? runCommand;
+    const generate = options.generate ?? generateWithRefactorAgent;
+    const scorecard: MethodScorecard = { module: moduleName, evaluated_at: new Date().toISOString(), scores: [] };
+
+    for (const method of options.methods ?? EVALUATED_METHODS) {
+      const workspace = fs.mkdtempSync(path.join(os.tmpdir(), `vibeflow-eval-${method}-`));
+      console.log(`🧪 ${moduleName}: generating with ${method} in ${workspace}`);
+      const started = Date.now();
+
+      try {
+        isolateWorkspace(this.projectRoot, workspace);
+        const isolated = { ...boundary, files: boundary.files.map(file => path.resolve(workspace, file)) };
+        const { failed } = await generate(workspace, isolated, method);
+        scorecard.scores.push({
+          ...scoreWorkspace(workspace, moduleName, rules, run),
+          method,
+          duration_ms: Date.now() - started,
+          failed_files: failed,
+        });
+      } catch (error) {
+        scorecard.scores.push({ ...emptyScore(method, rules.length), error: getErrorMessage(error), duration_ms: Date.now() - started });
+      } finally {
+        if (options.keepWorkspaces) {
+          scorecard.workspaces = { ...scorecard.workspaces, [method]: workspace };
+        } else {
+          fs.rmSync(workspace, { recursive: true, force: true });
+        }
+      }
+    }
+
+    return scorecard;
+  }
+
+  write(scorecard: MethodScorecard): string {
+    const target = this.scorecardPath(scorecard.module);
+    this.paths.writeArtifact(target, scorecard);
+    return target;
+  }
+
+  /**
+   * Store the scorecard as an evaluate-methods run: quality_* metrics and one
+   * file_processing record per legacy file, both tagged with the method
+   */
+  record(scorecard: MethodScorecard): number {
+    const store = new PerformanceStore(this.projectRoot);
+    const boundary = this.loadBoundary(scorecard.module);
+    const moduleId = boundary.id ?? boundary.name;
+    const runId = store.startRun('evaluate-methods', { modules_planned: 1, current_module: scorecard.module, environment: captureRunEnvironment(this.projectRoot) });
+
+    for (const score of scorecard.scores) {
+      const labels = { module: moduleId, module_name: scorecard.module, method: score.method };
+      for (const [key, value] of Object.entries(qualityValues(score))) {
+        store.recordMetric(runId, `quality_${key}`, value, labels);
+      }
+      const failed = score.error !== undefined;
+      for (const file of boundary.files) {
+        store.recordFileProcessing({
+          run_id: runId,
+          file,
+          module: moduleId,
+          agent: 'MethodEvaluator',
+          method: score.method,
+          status: failed ? 'failed' : 'success',
+          duration_ms: Math.round(score.duration_ms / Math.max(boundary.files.length, 1)),
+          // The module is built as a whole; every file shares its result
+          verification: failed ? 'not-run' : score.compiles ? 'passed' : 'failed',
+          ...(failed ? { error: score.error } : {}),
+        });
+      }
+    }
+
+    store.finishRun(runId, {
+      status: scorecard.scores.some(s => s.error) ? 'partial' : 'success',
+      modules_migrated: 1,
+      files_processed: boundary.files.length * scorecard.scores.length,
+      current_module: undefined,
+    });
+    scorecard.run_id = runId;
+    return runId;
+  }
+
+  private loadBoundary(moduleName: string): DomainBoundary {
+    let content: string;
+    try {
+      content = fs.readFileSync(this.paths.domainMapPath, 'utf8');
+    } catch {
+      throw new Error(`Domain map not found. Please run "vf plan" first to generate ${this.paths.getRelativePath(this.paths.domainMapPath)}`);
+    }
+    const { map: domainMap, invalid } = parseDomainMap(content, this.paths.getRelativePath(this.paths.domainMapPath));
+
+    const entry = invalid.find(i => i.name === moduleName);
+    if (entry) {
+      throw new InputParseError('domain-map', `module "${moduleName}": ${entry.error}`, this.paths.getRelativePath(this.paths.domainMapPath));
+    }
+    const boundary = domainMap.boundaries.find(b => b.name === moduleName);
+    if (!boundary) {
+      const available = domainMap.boundaries.map(b => b.name).join(', ');
+      throw new Error(`Module "${moduleName}" not found in domain map. Available: ${available}`);
+    }
+    const files = boundary.files.map(file => path.isAbsolute(file) ? path.relative(this.projectRoot, file) : file);
+    return { ...boundary, files };
+  }
+
+  private async loadRules(boundary: DomainBoundary, rulesPath?: string): Promise<BusinessRule[]> {
+    if (rulesPath) {
+      const raw = JSON.parse(fs.readFileSync(rulesPath, 'utf8'));
+      const rules: BusinessRule[] = Array.isArray(raw) ? raw : raw.rules ?? [];
+      const files = new Set(boundary.files.map(file => path.normalize(file)));
+      return rules.filter(rule => files.has(path.normalize(rule.location.file)));
+    }
+
+    const { BusinessLogicMigrationAgent } = await import('../agents/business-logic-migration-agent.js');
+    const agent = new BusinessLogicMigrationAgent(this.projectRoot, {
+      extractionLevel: 'basic',
+      patterns: { validations: true, calculations: true, workflows: true, dataAccess: true, errorHandling: true },
+      complexityThreshold: 'low',
+      language: 'go',
+      claudeCode: { enabled: false },
+    });
+    const rules: BusinessRule[] = [];
+    for (const file of boundary.files) {
+      rules.push(...(await agent.extractBusinessLogic(file)).rules);
+    }
+    return rules;
+  }
+}
+
+/**
+ * Copy the project into `target` without VCS data, dependencies or previous
+ * run state, keeping the analysis artifacts the refactoring reads
+ */
+export function isolateWorkspace(projectRoot: string, target: string): void {
+  fs.cpSync(projectRoot, target, {
+    recursive: true,
+    filter: source => {
+      const relative = path.relative(projectRoot, source);
+      if (!relative) return true;
+      const [top, ...rest] = relative.split(path.sep);
+      if (top === '.git' || top === 'node_modules') return false;
+      if (top === '.vibeflow') return rest.length === 0 || (rest.length === 1 && ISOLATED_ARTIFACTS.includes(rest[0]));
+      return true;
+    },
+  });
+}
+
+/**
+ * Apply the module with a RefactorAgent pinned to one generation method
+ */
+async function generateWithRefactorAgent(
+  workspace: string,
+  boundary: DomainBoundary,
+  method: EvaluatedMethod
+): Promise<{ failed: number }> {
+  const { RefactorAgent } = await import('../agents/refactor-agent.js');
+  // No escalation: each method is scored on what a single model produced
+  const result = await new RefactorAgent(workspace, method).executeRefactoring([boundary], true, { escalation: false });
+  if (result.applied_patches.length === 0 && result.failed_patches.length > 0) {
+    throw new Error(result.failed_patches[0].error);
+  }
+  return { failed: result.failed_patches.length };
+}
+
+/**
+ * Score the outputs recorded in the workspace's manifest for the module
+ */
+export function scoreWorkspace(
+  workspace: string,
+  moduleName: string,
+  rules: BusinessRule[],
+  run: CommandRunner = runCommand
+): Omit<QualityScore, 'method' | 'duration_ms' | 'failed_files'> {
+  const files = (new ModuleManifestStore(workspace).load(moduleName)?.files ?? [])
+    .map(entry => entry.path)
+    .filter(file => file.endsWith('.go'));
+  const sources = files.filter(file => !file.endsWith('_test.go'));
+  const tests = files.filter(file => file.endsWith('_test.go'));
+  const contents = sources.map(file => ({ file, content: readFile(path.join(workspace, file)) }));
+
+  const goProject = detectGoProject(workspace);
+  const cwd = goProject.workingDirectory ?? workspace;
+  const relative = files.map(file => toPosixPath(path.relative(cwd, path.join(workspace, file))));
+
+  const build = goProject.hasGoProject ? run('go build ./...', cwd) : { ok: false, output: 'go.mod not found' };
+  const vet = build.ok ? run('go vet ./...', cwd) : { ok: false, output: '' };
+  const gofmt = relative.length > 0 ? run(`gofmt -l ${relative.join(' ')}`, cwd) : { ok: true, output: '' };
+  const testPackages = [...new Set(tests.map(file => `./${path.posix.dirname(toPosixPath(path.relative(cwd, path.join(workspace, file))))}`))];
+  const testRun = build.ok && testPackages.length > 0 ? run(`go test -json ${testPackages.join(' ')}`, cwd) : { ok: true, output: '' };
+  const { passed, failed } = countTestResults(testRun.output);
+
+  const complexities = contents.flatMap(({ file, content }) => parseGoDeclarations(content, file)
+    .filter(decl => decl.kind !== 'type')
+    .map(decl => cyclomaticComplexity(decl.body)));
+  const generated = contents.map(c => c.content).join('\n');
+
+  return {
+    generated_files: files.length,
+    compiles: build.ok,
+    build_errors: diagnostics(build.output, relative).length,
+    vet_warnings: diagnostics(vet.output, relative).length,
+    lint_warnings: gofmt.output.split('\n').filter(line => line.trim()).length,
+    tests_passed: passed,
+    tests_failed: failed,
+    test_pass_rate: passed + failed > 0 ? passed / (passed + failed) : null,
+    cyclomatic_avg: complexities.length > 0 ? complexities.reduce((a, b) => a + b, 0) / complexities.length : 0,
+    cyclomatic_max: Math.max(0, ...complexities),
+    rules_total: rules.length,
+    rules_preserved: rules.filter(rule => ruleSurvives(rule, generated)).length,
+    todo_count: (generated.match(/\bTODO\b/g) ?? []).length,
+    not_implemented_count: (generated.match(/panic\(\s*"[^"]*not implemented[^"]*"\s*\)/gi) ?? []).length,
+  };
+}
+
+/**
+ * McCabe complexity approximated from branch keywords and boolean operators
+ */
+export function cyclomaticComplexity(body: string): number {
+  const code = body
+    .replace(/\/\/[^\n]*|\/\*[\s\S]*?\*\//g, '')
+    .replace(/"(?:[^"\\\n]|\\.)*"|`[^`]*`|'(?:[^'\\\n]|\\.)*'/g, '""');
+  const branches = (code.match(/\b(?:if|for|case)\b|&&|\|\|/g) ?? []).length;
+  return 1 + branches;
+}
+
+/**
+ * A rule survives when every string/number literal of its legacy code appears in
+ * the generated code; rules without literals must keep their code verbatim
+ */
+export function ruleSurvives(rule: BusinessRule, generated: string): boolean {
+  const literals = [...new Set(rule.code.match(/"(?:[^"\\\n]|\\.)*"|`[^`]*`|\b\d+(?:\.\d+)?\b/g) ?? [])]
+    .filter(literal => literal !== '0' && literal !== '1');
+  if (literals.length > 0) {
+    return literals.every(literal => /^\d/.test(literal)
+      ? new RegExp(`(?<![\\w.])${literal.replace('.', '\\.')}(?![\\w.])`).test(generated)
+      : generated.includes(literal));
+  }
+
+  const normalize = (text: string) => text.replace(/\s+/g, ' ').trim();
+  return normalize(rule.code).length > 0 && normalize(generated).includes(normalize(rule.code));
+}
+
+export function formatScorecard(scorecard: MethodScorecard): string {
+  const scores = scorecard.scores;
+  const width = Math.max(...QUALITY_METRICS.map(m => m.label.length), 'Generated files'.length) + 2;
+  const cell = (text: string) => text.padStart(12);
+  const format = (score: QualityScore, key: string): string => {
+    if (score.error) return 'error';
+    const value = qualityValues(score)[key];
+    if (value === undefined) return '-';
+    if (key === 'compiles') return value ? 'yes' : 'no';
+    if (key === 'test_pass_rate' || key === 'rules_preserved_rate') return `${(value * 100).toFixed(0)}%`;
+    return Number.isInteger(value) ? String(value) : value.toFixed(2);
+  };
+
+  const lines = [
+    `Module: ${scorecard.module}`.padEnd(width) + scores.map(s => cell(s.method)).join(''),
+    'Generated files'.padEnd(width) + scores.map(s => cell(s.error ? 'error' : String(s.generated_files))).join(''),
+    ...QUALITY_METRICS.map(metric => metric.label.padEnd(width) + scores.map(s => cell(format(s, metric.key))).join('')),
+    'Rules'.padEnd(width) + scores.map(s => cell(s.error ? 'error' : `${s.rules_preserved}/${s.rules_total}`)).join(''),
+    'Duration'.padEnd(width) + scores.map(s => cell(`${(s.duration_ms / 1000).toFixed(1)}s`)).join(''),
+  ];
+  for (const score of scores.filter(s => s.error)) {
+    lines.push(`${score.method}: ${score.error}`);
+  }
+  return lines.join('\n');
+}
+
+export interface MethodSummary {
+  method: ProcessingMethod;
+  files: number;
+  success_rate: number | null;
+  avg_duration_ms: number | null;
+  /** Number of module evaluations contributing to `quality` */
+  evaluations: number;
+  /** Mean of each quality_* metric (without the prefix) */
+  quality: Record<string, number>;
+}
+
+/**
+ * Per-method aggregates of file_processing records and evaluation quality metrics
+ */
+export function summarizeMethods(store: PerformanceStore): MethodSummary[] {
+  const records = store.getFileProcessing();
+  const metrics = store.getMetrics().filter(m => m.metric.startsWith('quality_') && m.labels?.method);
+  const methods: ProcessingMethod[] = ['static', 'template', 'llm'];
+
+  return methods.map(method => {
+    const files = records.filter(r => r.method === method);
+    const timed = files.filter(r => r.duration_ms !== undefined);
+    const own = metrics.filter(m => m.labels!.method === method);
+    const quality: Record<string, number> = {};
+    for (const key of new Set(own.map(m => m.metric))) {
+      const values = own.filter(m => m.metric === key).map(m => m.value);
+      quality[key.replace(/^quality_/, '')] = values.reduce((a, b) => a + b, 0) / values.length;
+    }
+
+    return {
+      method,
+      files: files.length,
+      success_rate: files.length > 0 ? files.filter(r => r.status === 'success').length / files.length : null,
+      avg_duration_ms: timed.length > 0 ? timed.reduce((sum, r) => sum + r.duration_ms!, 0) / timed.length : null,
+      evaluations: new Set(own.map(m => `${m.run_id}:${m.labels!.module}`)).size,
+      quality,
+    };
+  });
+}
+
+/**
+ * Numeric rubric values as stored in performance_metrics (null entries omitted)
+ */
+function qualityValues(score: QualityScore): Record<string, number> {
+  const values: Record<string, number | null> = {
+    compiles: score.compiles ? 1 : 0,
+    build_errors: score.build_errors,
+    vet_warnings: score.vet_warnings,
+    lint_warnings: score.lint_warnings,
+    test_pass_rate: score.test_pass_rate,
+    cyclomatic_avg: score.cyclomatic_avg,
+    cyclomatic_max: score.cyclomatic_max,
+    rules_preserved_rate: score.rules_total > 0 ? score.rules_preserved / score.rules_total : null,
+    todo_count: score.todo_count,
+    not_implemented_count: score.not_implemented_count,
+  };
+  return Object.fromEntries(Object.entries(values).filter((entry): entry is [string, number] => entry[1] !== null));
+}
+
+function emptyScore(method: EvaluatedMethod, rules: number): QualityScore {
+  return {
+    method, duration_ms: 0, generated_files: 0, failed_files: 0, compiles: false, build_errors: 0,
+    vet_warnings: 0, lint_warnings: 0, tests_passed: 0, tests_failed: 0, test_pass_rate: null,
+    cyclomatic_avg: 0, cyclomatic_max: 0, rules_total: rules, rules_preserved: 0, todo_count: 0, not_implemented_count: 0,
+  };
+}
+
+/**
+ * Compiler/vet diagnostics (file:line:col) that point at one of the given files
+ */
+function diagnostics(output: string, files: string[]): string[] {
+  return output.split('\n').filter(line => {
+    const match = line.match(/^(?:\.\/)?([^\s:]+\.go):\d+(?::\d+)?:/);
+    return match !== null && files.some(file => file.endsWith(match[1]) || match[1].endsWith(file));
+  });
+}
+
+function countTestResults(output: string): { passed: number; failed: number } {
+  let passed = 0;
+  let failed = 0;
+  for (const line of output.split('\n')) {
+    try {
+      const event = JSON.parse(line);
+      if (!event.Test) continue;
+      if (event.Action === 'pass') passed++;
+      if (event.Action === 'fail') failed++;
+    } catch {
+      // Build output interleaved with the JSON stream
+    }
+  }
+  return { passed, failed };
+}
+
+function runCommand(command: string, cwd: string): { ok: boolean; output: string } {
+  try {
+    const output = execSync(command, { cwd, stdio: 'pipe', timeout: 600000 }).toString();
+    return { ok: true, output };
+  } catch (error) {
+    const { stdout, stderr } = error as { stdout?: Buffer; stderr?: Buffer };
+    return { ok: false, output: `${stdout?.toString() ?? ''}${stderr?.toString() ?? ''}` };
+  }
+}
+
+function readFile(file: string): string {
+  try {
+    return fs.readFileSync(file, 'utf8');
+  } catch {
+    return '';
+  }
+}
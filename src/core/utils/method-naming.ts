@@ -0,0 +1,313 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { parseGoDeclarations } from './context-selector.js';
+import { portableArtifact } from './workspace-paths.js';
+import { MethodNameMapping } from '../types/refactor.js';
+
+/**
+ * A legacy Go function that becomes a usecase method
+ */
+export interface LegacyFunction {
+  name: string;
+  receiver?: string;
+  /** Doc comment lines directly above the declaration */
+  comment: string;
+  /** Messages passed to errors.New / fmt.Errorf / http.Error */
+  errorMessages: string[];
+}
+
+export type MethodKind = 'create' | 'read' | 'update' | 'delete';
+
+const GENERIC_NAMES = new Set(['Handle', 'Handler', 'Do', 'Run', 'Exec', 'Execute', 'Process', 'Serve', 'Call', 'Apply']);
+const STRIPPED_PREFIXES = /^(?:handle|do|api|http)(?=[A-Z])/i;
+const STRIPPED_SUFFIXES = /(?:Handler|Handle|Func|Endpoint|Impl|API)$/;
+const SKIPPED_NAMES = new Set(['main', 'init', 'String', 'Error', 'Validate', 'TableName', 'ServeHTTP']);
+
+const CREATE_VERBS = ['Create', 'Register', 'Add', 'Place', 'Open', 'Insert', 'Save', 'Submit', 'Issue', 'Sign'];
+const READ_VERBS = ['Get', 'Find', 'List', 'Fetch', 'Load', 'Search', 'Lookup', 'Query', 'Read', 'Show', 'Count', 'Check', 'Authenticate', 'Verify'];
+const DELETE_VERBS = ['Delete', 'Remove', 'Purge', 'Destroy', 'Archive'];
+
+/**
+ * Exported functions/methods and HTTP handlers that should become usecase methods
+ */
+export function extractLegacyFunctions(source: string, file = ''): LegacyFunction[] {
+  const lines = source.split('\n');
+
+  return parseGoDeclarations(source, file)
+    .filter(decl => decl.kind === 'func' || decl.kind === 'method')
+    .filter(decl => {
+      if (SKIPPED_NAMES.has(decl.name) || /^(New|Test|Benchmark)([A-Z]|$)/.test(decl.name)) return false;
+      return /^[A-Z]/.test(decl.name) || /http\.ResponseWriter|\*gin\.Context/.test(decl.signature);
+    })
+    .map(decl => {
+      const index = lines.findIndex(line => line.startsWith(decl.signature));
+      const comment: string[] = [];
+      for (let i = index - 1; i >= 0 && /^\s*\/\//.test(lines[i]); i--) {
+        comment.unshift(lines[i].replace(/^\s*\/\/\s?/, ''));
+      }
+
+      const errorMessages: string[] = [];
+      const errorPattern = /(?:errors\.New|fmt\.Errorf|http\.Error\([^,]+,)\s*\(?\s*"([^"]+)"/g;
+      let match: RegExpExecArray | null;
+      while ((match = errorPattern.exec(decl.body)) !== null) {
+        errorMessages.push(match[1]);
+      }
+
+      return {
+        name: decl.name,
+        receiver: decl.signature.match(/^func\s*\(\s*(?:\w+\s+)?\*?\s*(\w+)/)?.[1],
+        comment: comment.join(' '),
+        errorMessages,
+      };
+    });
+}
+
+/**
+ * Usecase method name for a legacy function, preserving its domain verb.
+ * Generic names (Handle, Do, Process...) fall back to verbs in error messages and comments.
+ */
+export function deriveMethodName(fn: LegacyFunction): string {
+  let base = pascalCase(fn.name).replace(STRIPPED_SUFFIXES, '');
+  base = base.replace(STRIPPED_PREFIXES, '');
+  base = pascalCase(base);
+
+  if (!base || GENERIC_NAMES.has(base)) {
+    const fromText = verbPhraseFrom(fn.errorMessages) ?? verbPhraseFrom([fn.comment]);
+    if (fromText) return fromText;
+    if (!base) base = pascalCase(fn.name);
+  }
+
+  // (o *Order) Cancel → CancelOrder
+  if (fn.receiver && words(base).length === 1 && !/(Handler|Service|Server|Controller|Repository|Store|App)$/.test(fn.receiver)) {
+    return base + pascalCase(fn.receiver);
+  }
+  return base;
+}
+
+/**
+ * Build the legacy → new symbol table for one file.
+ * Names already recorded for the module are reused so every run (and every
+ * generated file) agrees; colliding names are made unique deterministically.
+ */
+export function buildMethodNameMapping(
+  moduleName: string,
+  file: string,
+  functions: LegacyFunction[],
+  existing: MethodNameMapping[] = []
+): MethodNameMapping[] {
+  const reusable = new Map(existing.filter(m => m.file === file).map(m => [m.legacy, m]));
+  const taken = new Set(existing.filter(m => m.file !== file).map(m => m.method));
+  const mappings: MethodNameMapping[] = [];
+
+  // Reused names first, then new ones in legacy-name order for stable results
+  const ordered = [...functions].sort((a, b) =>
+    Number(reusable.has(b.name)) - Number(reusable.has(a.name)) || a.name.localeCompare(b.name));
+
+  for (const fn of ordered) {
+    const previous = reusable.get(fn.name);
+    if (previous && !taken.has(previous.method)) {
+      mappings.push(previous);
+      taken.add(previous.method);
+      continue;
+    }
+
+    const candidate = deriveMethodName(fn);
+    const method = disambiguate(candidate, fn, taken);
+    taken.add(method);
+    mappings.push({
+      module: moduleName,
+      file,
+      legacy: fn.name,
+      method,
+      source: 'heuristic',
+      ...(method !== candidate ? { disambiguated: true } : {}),
+    });
+  }
+
+  return mappings.sort((a, b) => a.legacy.localeCompare(b.legacy));
+}
+
+/**
+ * Apply the mapping to LLM or template output so interface, implementation,
+ * handler and tests use the same names.
+ *
+ * - Legacy identifiers left in the output are renamed to the mapped name.
+ * - Names the model reported in `method_names` replace the heuristic name when
+ *   the code uses them and they do not collide with another mapping.
+ */
+export function reconcileMethodNames(
+  mappings: MethodNameMapping[],
+  outputs: { content: string }[],
+  suggestions: { legacy: string; method: string }[] = []
+): { mappings: MethodNameMapping[]; renames: Map<string, string> } {
+  const code = outputs.map(o => o.content).join('\n');
+  const taken = new Set(mappings.map(m => m.method));
+  const renames = new Map<string, string>();
+
+  const reconciled = mappings.map(mapping => {
+    const suggestion = suggestions.find(s => s.legacy === mapping.legacy);
+    if (
+      suggestion &&
+      suggestion.method !== mapping.method &&
+      isGoIdentifier(suggestion.method) &&
+      !taken.has(suggestion.method) &&
+      usesIdentifier(code, suggestion.method) &&
+      !usesIdentifier(code, mapping.method)
+    ) {
+      taken.delete(mapping.method);
+      taken.add(suggestion.method);
+      const { disambiguated: _, ...rest } = mapping;
+      return { ...rest, method: suggestion.method, source: 'llm' as const };
+    }
+
+    if (mapping.legacy !== mapping.method && !usesIdentifier(code, mapping.method) && usesIdentifier(code, mapping.legacy)) {
+      renames.set(mapping.legacy, mapping.method);
+    }
+    return mapping;
+  });
+
+  return { mappings: reconciled, renames };
+}
+
+/**
+ * Rename method identifiers (declarations and calls) in Go source
+ */
+export function renameMethods(content: string, renames: Map<string, string>): string {
+  let result = content;
+  for (const [from, to] of renames) {
+    result = result.replace(new RegExp(`\\b${from}\\b(?=\\s*\\()`, 'g'), to);
+  }
+  return result;
+}
+
+/**
+ * Repository operation a usecase method delegates to, by its leading verb
+ */
+export function methodKind(method: string): MethodKind {
+  const verb = words(method)[0] ?? '';
+  if (CREATE_VERBS.includes(verb)) return 'create';
+  if (READ_VERBS.includes(verb)) return 'read';
+  if (DELETE_VERBS.includes(verb)) return 'delete';
+  return 'update';
+}
+
+/**
+ * Markdown section listing the names the model must use
+ */
+export function renderMethodNamingSection(mappings: MethodNameMapping[]): string {
+  if (mappings.length === 0) return '';
+
+  return [
+    '## Method Naming',
+    'Use exactly these usecase method names in the interface, implementation, handler and tests.',
+    'If a name does not reflect the business operation, you may propose a better one in a top-level',
+    '"method_names": [{"legacy": "...", "method": "..."}] array and use it consistently instead.',
+    '',
+    ...mappings.map(m => `- \`${m.legacy}\` → \`${m.method}\``),
+    '',
+  ].join('\n');
+}
+
+/**
+ * Parse the table rendered by renderMethodNamingSection (template mode)
+ */
+export function parseMethodNamingSection(prompt: string): { legacy: string; method: string }[] {
+  const section = prompt.split('## Method Naming')[1]?.split(/\n## /)[0] ?? '';
+  return [...section.matchAll(/^- `(\w+)` → `(\w+)`/gm)].map(m => ({ legacy: m[1], method: m[2] }));
+}
+
+export function formatMethodNameTable(mappings: MethodNameMapping[]): string[] {
+  return mappings.map(m =>
+    `${m.legacy} → ${m.method}${m.source === 'llm' ? ' (llm)' : ''}${m.disambiguated ? ' (disambiguated)' : ''}`);
+}
+
+/**
+ * MethodNameStore - legacy → new symbol table per module
+ *
+ * Persisted at .vibeflow/method-names.json so call-site rewrites and
+ * documentation use the same names as the generated code.
+ */
+export class MethodNameStore {
+  private projectRoot: string;
+  private storePath: string;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.storePath = path.join(projectRoot, '.vibeflow', 'method-names.json');
+  }
+
+  load(moduleName: string): MethodNameMapping[] {
+    return this.loadAll()[moduleName] ?? [];
+  }
+
+  loadAll(): Record<string, MethodNameMapping[]> {
+    if (!fs.existsSync(this.storePath)) return {};
+    try {
+      return JSON.parse(fs.readFileSync(this.storePath, 'utf8')).modules ?? {};
+    } catch {
+      return {};
+    }
+  }
+
+  /**
+   * Replace the mappings recorded for the given file of a module
+   */
+  save(moduleName: string, file: string, mappings: MethodNameMapping[]): void {
+    const all = this.loadAll();
+    all[moduleName] = [...(all[moduleName] ?? []).filter(m => m.file !== file), ...mappings];
+
+    fs.mkdirSync(path.dirname(this.storePath), { recursive: true });
+    fs.writeFileSync(this.storePath, JSON.stringify(portableArtifact(this.projectRoot, { modules: all }), null, 2));
+  }
+}
+
+function disambiguate(candidate: string, fn: LegacyFunction, taken: Set<string>): string {
+  if (!taken.has(candidate)) return candidate;
+
+  const alternatives = [
+    pascalCase(fn.name).replace(STRIPPED_SUFFIXES, ''),
+    fn.receiver ? candidate + pascalCase(fn.receiver) : '',
+  ].filter(name => name && name !== candidate);
+  for (const name of alternatives) {
+    if (!taken.has(name)) return name;
+  }
+
+  let index = 2;
+  while (taken.has(`${candidate}${index}`)) index++;
+  return `${candidate}${index}`;
+}
+
+/**
+ * "failed to authenticate user" → AuthenticateUser
+ */
+function verbPhraseFrom(texts: string[]): string | null {
+  for (const text of texts) {
+    const failure = text.match(/(?:failed to|unable to|cannot|can't|could not|couldn't)\s+(\w+)\s+(?:the\s+|a\s+|an\s+)?(\w+)/i);
+    if (failure) return pascalCase(failure[1]) + pascalCase(failure[2]);
+
+    // Go doc comment: "Handle authenticates the user"
+    const doc = text.match(/^\w+\s+([a-z]+s)\s+(?:the\s+|a\s+|an\s+)?(\w+)/);
+    if (doc) return pascalCase(/(ss|sh|ch|x)es$/.test(doc[1]) ? doc[1].slice(0, -2) : doc[1].slice(0, -1)) + pascalCase(doc[2]);
+  }
+  return null;
+}
+
+function words(name: string): string[] {
+  return name.match(/[A-Z][a-z0-9]*|[A-Z]+(?![a-z])/g) ?? [];
+}
+
+function pascalCase(name: string): string {
+  return name
+    .split(/[^A-Za-z0-9]+/)
+    .filter(Boolean)
+    .map(part => part.charAt(0).toUpperCase() + part.slice(1))
+    .join('');
+}
+
+function isGoIdentifier(name: string): boolean {
+  return /^[A-Z]\w*$/.test(name);
+}
+
+function usesIdentifier(code: string, name: string): boolean {
+  return new RegExp(`\\b${name}\\s*\\(`).test(code);
+}
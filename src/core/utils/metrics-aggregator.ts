@@ -0,0 +1,340 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import chalk from 'chalk';
+import fastGlob from 'fast-glob';
+import { PerformanceStore, RunRecord } from './performance-store.js';
+import { getErrorMessage } from './error-utils.js';
+import { RunEnvironment, redactSecrets } from './run-environment.js';
+
+export type MetricsExportFormat = 'csv' | 'json' | 'prometheus';
+
+export interface WorkspaceRun extends RunRecord {
+  workspace: string;
+}
+
+export interface WorkspaceSummary {
+  workspace: string;
+  path: string;
+  schemaVersion: number;
+  migratedFromVersion?: number;
+  totalRuns: number;
+  successfulRuns: number;
+  successRate: number;
+  modulesPlanned: number;
+  modulesMigrated: number;
+  totalCost: number;
+  totalTokens: number;
+  loc: number;
+  tokensPerKloc: number;
+  /** Environment of the most recent run that recorded one (JSON export only) */
+  environment?: RunEnvironment;
+}
+
+export interface MonthlySpend {
+  workspace: string;
+  month: string; // YYYY-MM
+  cost: number;
+  runs: number;
+}
+
+export interface SkippedWorkspace {
+  workspace: string;
+  path: string;
+  reason: string;
+}
+
+export interface AggregatedMetrics {
+  generatedAt: string;
+  workspaces: WorkspaceSummary[];
+  monthlySpend: MonthlySpend[];
+  skipped: SkippedWorkspace[];
+  totals: {
+    workspaces: number;
+    runs: number;
+    successRate: number;
+    modulesPlanned: number;
+    modulesMigrated: number;
+    cost: number;
+    tokensPerKloc: number;
+  };
+}
+
+/**
+ * Resolve a --workspaces value (comma separated list and/or glob patterns)
+ */
+export async function resolveWorkspaces(spec: string, cwd: string = process.cwd()): Promise<string[]> {
+  const resolved = new Set<string>();
+
+  for (const entry of spec.split(',').map(s => s.trim()).filter(Boolean)) {
+    if (fastGlob.isDynamicPattern(entry)) {
+      const matches = await fastGlob(entry, { cwd, onlyDirectories: true, absolute: true });
+      matches.sort().forEach(m => resolved.add(path.resolve(m)));
+    } else {
+      resolved.add(path.resolve(cwd, entry));
+    }
+  }
+
+  return [...resolved];
+}
+
+/**
+ * MetricsAggregator - 複数ワークスペースの実行メトリクスを集計
+ */
+export class MetricsAggregator {
+  aggregate(workspacePaths: string[]): AggregatedMetrics {
+    const summaries: WorkspaceSummary[] = [];
+    const monthlySpend: MonthlySpend[] = [];
+    const skipped: SkippedWorkspace[] = [];
+    const labels = buildWorkspaceLabels(workspacePaths);
+
+    for (const workspacePath of workspacePaths) {
+      const workspace = labels.get(workspacePath)!;
+      const store = new PerformanceStore(workspacePath, { readOnly: true });
+
+      if (!fs.existsSync(PerformanceStore.storePath(workspacePath)) &&
+          !fs.existsSync(PerformanceStore.legacyUsagePath(workspacePath))) {
+        skipped.push({ workspace, path: workspacePath, reason: 'performance store not found' });
+        continue;
+      }
+
+      if (store.isLocked()) {
+        skipped.push({ workspace, path: workspacePath, reason: 'performance store is locked by another process' });
+        continue;
+      }
+
+      try {
+        const loaded = store.loadWithInfo();
+        const runs: WorkspaceRun[] = loaded.data.runs.map(run => ({ ...run, workspace }));
+        const summary = summarizeWorkspace(workspace, workspacePath, runs);
+        summary.schemaVersion = loaded.data.schema_version;
+        if (loaded.migrated) {
+          summary.migratedFromVersion = loaded.sourceVersion;
+        }
+        summaries.push(summary);
+        monthlySpend.push(...computeMonthlySpend(workspace, runs));
+      } catch (error) {
+        skipped.push({ workspace, path: workspacePath, reason: getErrorMessage(error) });
+      }
+    }
+
+    return {
+      generatedAt: new Date().toISOString(),
+      workspaces: summaries,
+      monthlySpend,
+      skipped,
+      totals: computeTotals(summaries),
+    };
+  }
+}
+
+function buildWorkspaceLabels(workspacePaths: string[]): Map<string, string> {
+  const labels = new Map<string, string>();
+  const used = new Map<string, number>();
+
+  for (const workspacePath of workspacePaths) {
+    const base = path.basename(workspacePath) || workspacePath;
+    const count = used.get(base) ?? 0;
+    used.set(base, count + 1);
+    labels.set(workspacePath, count === 0 ? base : `${base}-${count + 1}`);
+  }
+
+  return labels;
+}
+
+function summarizeWorkspace(workspace: string, workspacePath: string, runs: WorkspaceRun[]): WorkspaceSummary {
+  const finished = runs.filter(r => r.status !== 'running');
+  const successful = finished.filter(r => r.status === 'success');
+
+  // Planned vs migrated reflects the latest finished run that planned modules
+  const latest = [...finished]
+    .filter(r => r.modules_planned > 0)
+    .sort((a, b) => a.started_at.localeCompare(b.started_at))
+    .pop();
+
+  const totalTokens = runs.reduce((sum, r) => sum + r.input_tokens + r.output_tokens, 0);
+  const loc = runs.reduce((max, r) => Math.max(max, r.loc), 0);
+  const environment = runs.filter(r => r.environment).sort((a, b) => a.started_at.localeCompare(b.started_at)).pop()?.environment;
+
+  return {
+    workspace,
+    path: workspacePath,
+    schemaVersion: 0,
+    totalRuns: finished.length,
+    successfulRuns: successful.length,
+    successRate: finished.length > 0 ? successful.length / finished.length : 0,
+    modulesPlanned: latest?.modules_planned ?? 0,
+    modulesMigrated: latest?.modules_migrated ?? 0,
+    totalCost: runs.reduce((sum, r) => sum + r.cost, 0),
+    totalTokens,
+    loc,
+    tokensPerKloc: loc > 0 ? totalTokens / (loc / 1000) : 0,
+    ...(environment ? { environment: redactSecrets(environment) } : {}),
+  };
+}
+
+function computeMonthlySpend(workspace: string, runs: WorkspaceRun[]): MonthlySpend[] {
+  const byMonth = new Map<string, MonthlySpend>();
+
+  for (const run of runs) {
+    const month = run.started_at.slice(0, 7);
+    const entry = byMonth.get(month) ?? { workspace, month, cost: 0, runs: 0 };
+    entry.cost += run.cost;
+    entry.runs += 1;
+    byMonth.set(month, entry);
+  }
+
+  return [...byMonth.values()].sort((a, b) => a.month.localeCompare(b.month));
+}
+
+function computeTotals(summaries: WorkspaceSummary[]): AggregatedMetrics['totals'] {
+  const runs = summaries.reduce((sum, s) => sum + s.totalRuns, 0);
+  const successful = summaries.reduce((sum, s) => sum + s.successfulRuns, 0);
+  const withLoc = summaries.filter(s => s.loc > 0);
+
+  return {
+    workspaces: summaries.length,
+    runs,
+    successRate: runs > 0 ? successful / runs : 0,
+    modulesPlanned: summaries.reduce((sum, s) => sum + s.modulesPlanned, 0),
+    modulesMigrated: summaries.reduce((sum, s) => sum + s.modulesMigrated, 0),
+    cost: summaries.reduce((sum, s) => sum + s.totalCost, 0),
+    tokensPerKloc: withLoc.length > 0
+      ? withLoc.reduce((sum, s) => sum + s.tokensPerKloc, 0) / withLoc.length
+      : 0,
+  };
+}
+
+// -----------------------------------------------------------------------------
+// Formatters
+// -----------------------------------------------------------------------------
+
+export function formatMetrics(metrics: AggregatedMetrics, format: MetricsExportFormat): string {
+  switch (format) {
+    case 'json':
+      return JSON.stringify(metrics, null, 2);
+    case 'csv':
+      return formatCsv(metrics);
+    case 'prometheus':
+      return formatPrometheus(metrics);
+    default:
+      throw new Error(`Unsupported export format: ${format}`);
+  }
+}
+
+export function csvCell(value: string | number): string {
+  const text = String(value);
+  return /[",\n]/.test(text) ? `"${text.replace(/"/g, '""')}"` : text;
+}
+
+export function formatCsv(metrics: AggregatedMetrics): string {
+  const lines = ['workspace,month,cost,runs,success_rate,modules_planned,modules_migrated,tokens_per_kloc'];
+  const summaries = new Map(metrics.workspaces.map(s => [s.workspace, s]));
+
+  for (const spend of metrics.monthlySpend) {
+    const s = summaries.get(spend.workspace)!;
+    lines.push([
+      spend.workspace,
+      spend.month,
+      spend.cost.toFixed(4),
+      spend.runs,
+      s.successRate.toFixed(4),
+      s.modulesPlanned,
+      s.modulesMigrated,
+      s.tokensPerKloc.toFixed(1),
+    ].map(csvCell).join(','));
+  }
+
+  return lines.join('\n') + '\n';
+}
+
+function escapeLabel(value: string): string {
+  return value.replace(/\\/g, '\\\\').replace(/\n/g, '\\n').replace(/"/g, '\\"');
+}
+
+export function formatPrometheus(metrics: AggregatedMetrics): string {
+  const lines: string[] = [];
+  const gauge = (name: string, help: string, samples: Array<[Record<string, string>, number]>) => {
+    lines.push(`# HELP ${name} ${help}`);
+    lines.push(`# TYPE ${name} gauge`);
+    for (const [labels, value] of samples) {
+      const labelText = Object.entries(labels)
+        .map(([k, v]) => `${k}="${escapeLabel(v)}"`)
+        .join(',');
+      lines.push(`${name}{${labelText}} ${value}`);
+    }
+  };
+
+  gauge('vibeflow_spend_usd', 'Total LLM spend per month',
+    metrics.monthlySpend.map(m => [{ workspace: m.workspace, month: m.month }, m.cost]));
+  gauge('vibeflow_run_success_ratio', 'Ratio of successful runs',
+    metrics.workspaces.map(s => [{ workspace: s.workspace }, s.successRate]));
+  gauge('vibeflow_modules_planned', 'Modules planned in the latest run',
+    metrics.workspaces.map(s => [{ workspace: s.workspace }, s.modulesPlanned]));
+  gauge('vibeflow_modules_migrated', 'Modules migrated in the latest run',
+    metrics.workspaces.map(s => [{ workspace: s.workspace }, s.modulesMigrated]));
+  gauge('vibeflow_tokens_per_kloc', 'Average tokens consumed per thousand lines of code',
+    metrics.workspaces.map(s => [{ workspace: s.workspace }, s.tokensPerKloc]));
+  gauge('vibeflow_workspace_skipped', 'Workspaces skipped during aggregation',
+    metrics.skipped.map(s => [{ workspace: s.workspace, reason: s.reason }, 1]));
+
+  return lines.join('\n') + '\n';
+}
+
+// -----------------------------------------------------------------------------
+// CLI entry
+// -----------------------------------------------------------------------------
+
+export async function runMetricsAggregate(options: {
+  workspaces: string;
+  export?: string;
+  output?: string;
+}): Promise<AggregatedMetrics> {
+  const format = options.export as MetricsExportFormat | undefined;
+  if (format && !['csv', 'json', 'prometheus'].includes(format)) {
+    throw new Error(`Unsupported export format: ${format} (expected csv, json or prometheus)`);
+  }
+
+  const workspacePaths = await resolveWorkspaces(options.workspaces);
+  if (workspacePaths.length === 0) {
+    throw new Error(`No workspaces matched: ${options.workspaces}`);
+  }
+
+  const metrics = new MetricsAggregator().aggregate(workspacePaths);
+
+  if (format) {
+    const content = formatMetrics(metrics, format);
+    if (options.output) {
+      fs.writeFileSync(options.output, content);
+      console.log(chalk.green(`✅ Metrics exported: ${options.output}`));
+    } else {
+      process.stdout.write(content);
+    }
+  } else {
+    printSummary(metrics);
+  }
+
+  return metrics;
+}
+
+function printSummary(metrics: AggregatedMetrics): void {
+  console.log(chalk.cyan('\n📊 Workspace Metrics'));
+  for (const s of metrics.workspaces) {
+    const migratedNote = s.migratedFromVersion !== undefined ? chalk.gray(` (schema v${s.migratedFromVersion} → v${s.schemaVersion})`) : '';
+    console.log(chalk.yellow(`\n📁 ${s.workspace}${migratedNote}`));
+    console.log(chalk.gray(`   Runs: ${s.totalRuns} (success ${(s.successRate * 100).toFixed(1)}%)`));
+    console.log(chalk.gray(`   Modules: ${s.modulesMigrated}/${s.modulesPlanned} migrated`));
+    console.log(chalk.gray(`   Spend: $${s.totalCost.toFixed(2)}`));
+    console.log(chalk.gray(`   Tokens/KLOC: ${s.tokensPerKloc.toFixed(1)}`));
+  }
+
+  if (metrics.skipped.length > 0) {
+    console.log(chalk.yellow('\n⚠️  Skipped workspaces:'));
+    metrics.skipped.forEach(s => console.log(chalk.yellow(`   - ${s.workspace}: ${s.reason}`)));
+  }
+
+  const t = metrics.totals;
+  console.log(chalk.cyan('\n📈 Totals'));
+  console.log(chalk.gray(`   Workspaces: ${t.workspaces}, Runs: ${t.runs}, Success: ${(t.successRate * 100).toFixed(1)}%`));
+  console.log(chalk.gray(`   Modules: ${t.modulesMigrated}/${t.modulesPlanned} migrated`));
+  console.log(chalk.gray(`   Spend: $${t.cost.toFixed(2)}, Avg Tokens/KLOC: ${t.tokensPerKloc.toFixed(1)}`));
+}
@@ -0,0 +1,254 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { execSync } from 'child_process';
+import { VibeFlowPaths } from './file-paths.js';
+import { ModuleManifestStore } from './module-manifest.js';
+import { detectGoProject } from './go-project-utils.js';
+import { getErrorMessage } from './error-utils.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * What applying a module changed, used for commit messages and the migration log
+ */
+export interface ModuleChangeSummary {
+  module: string;
+  /** Outputs written for the module */
+  files_created: number;
+  /** Legacy files moved into the module */
+  files_moved: number;
+  /** Outputs of earlier attempts removed */
+  files_removed: number;
+  /** Interfaces declared by the outputs (ports extracted from the legacy code) */
+  interfaces: string[];
+  /** Domain event types declared by the outputs */
+  events: string[];
+  /** One-paragraph summary (LLM-generated when available) */
+  summary: string;
+}
+
+export interface VerificationSummary {
+  build?: boolean;
+  tests?: boolean;
+}
+
+export interface MigrationCommitContext {
+  run_id?: number;
+  modules: ModuleChangeSummary[];
+  verification?: VerificationSummary;
+}
+
+/**
+ * Writes the one-paragraph summary of a module; returning null falls back to the template
+ */
+export type ModuleSummarizer = (change: ModuleChangeSummary) => Promise<string | null>;
+
+/**
+ * Default commit template; .vibeflow/templates/commit-message.tmpl overrides it.
+ * Placeholders: {{type}} {{scope}} {{subject}} {{run}} {{run_id}} {{body}} {{modules}} {{verification}}
+ */
+export const DEFAULT_COMMIT_TEMPLATE = '{{type}}({{scope}}): {{subject}}{{run}}\n\n{{body}}\n';
+
+const MIGRATION_LOG_HEADER = '# Migration Log\n\nModules applied by vibeflow, oldest first.\n';
+
+/**
+ * Template summary used when no LLM summary is available
+ */
+export function templateSummary(change: Omit<ModuleChangeSummary, 'summary'>): string {
+  const sentences = [
+    `Extracted the ${change.module} module: ${change.files_created} files created from ${change.files_moved} legacy files` +
+      (change.files_removed > 0 ? `, ${change.files_removed} outdated outputs removed.` : '.'),
+  ];
+  if (change.interfaces.length > 0) sentences.push(`Extracted interfaces ${change.interfaces.join(', ')}.`);
+  if (change.events.length > 0) sentences.push(`Introduced events ${change.events.join(', ')}.`);
+  return sentences.join(' ');
+}
+
+export function formatVerification(verification?: VerificationSummary): string {
+  if (!verification || (verification.build === undefined && verification.tests === undefined)) {
+    return 'not run';
+  }
+  const status = (passed?: boolean) => passed === undefined ? 'not run' : passed ? 'passed' : 'failed';
+  return `build ${status(verification.build)}, tests ${status(verification.tests)}`;
+}
+
+/**
+ * Conventional Commits message for the applied modules
+ * (`refactor(order): extract order module (vibeflow run 23)`)
+ */
+export function renderCommitMessage(context: MigrationCommitContext, template: string = DEFAULT_COMMIT_TEMPLATE): string {
+  const names = context.modules.map(m => m.module);
+  const single = context.modules.length === 1;
+  const decisions = context.modules.flatMap(m => [
+    ...(m.interfaces.length > 0 ? [`- ${m.module}: extracted interfaces ${m.interfaces.join(', ')}`] : []),
+    ...(m.events.length > 0 ? [`- ${m.module}: introduced events ${m.events.join(', ')}`] : []),
+  ]);
+  const modules = context.modules.map(m => `- ${m.module}: ${m.summary}`).join('\n');
+  const verification = formatVerification(context.verification);
+
+  const body = [
+    single ? context.modules[0].summary : `Modules:\n${modules}`,
+    ...(decisions.length > 0 ? [`Decisions:\n${decisions.join('\n')}`] : []),
+    `Verification: ${verification}`,
+    ...(context.run_id !== undefined ? [`Vibeflow-Run: ${context.run_id}`] : []),
+  ].join('\n\n');
+
+  const values: Record<string, string> = {
+    type: 'refactor',
+    scope: names.join(','),
+    subject: single ? `extract ${names[0]} module` : `extract ${names.length} modules`,
+    run: context.run_id !== undefined ? ` (vibeflow run ${context.run_id})` : '',
+    run_id: context.run_id !== undefined ? String(context.run_id) : '',
+    body,
+    modules,
+    verification,
+  };
+
+  return template.replace(/\{\{\s*(\w+)\s*\}\}/g, (match, key: string) => values[key] ?? match);
+}
+
+/**
+ * MigrationCommitWriter - 適用結果からのコミットメッセージ・移行ログ生成
+ *
+ * Builds commit messages and .vibeflow/MIGRATION_LOG.md entries from the
+ * module output manifests of an apply, so phase commits no longer have to be
+ * written by hand from plan.md.
+ */
+export class MigrationCommitWriter {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  get templatePath(): string {
+    return path.join(this.paths.outputRootPath, 'templates', 'commit-message.tmpl');
+  }
+
+  get migrationLogPath(): string {
+    return path.join(this.paths.outputRootPath, 'MIGRATION_LOG.md');
+  }
+
+  /**
+   * Summarize what applying each module changed.
+   * `removed` are outputs deleted by the apply (RefactorResult.deleted_files).
+   */
+  async describeModules(
+    moduleNames: string[],
+    removed: string[] = [],
+    summarizer?: ModuleSummarizer
+  ): Promise<ModuleChangeSummary[]> {
+    const store = new ModuleManifestStore(this.projectRoot);
+    const changes: ModuleChangeSummary[] = [];
+
+    for (const moduleName of moduleNames) {
+      const files = store.load(moduleName)?.files ?? [];
+      const contents = files.map(entry => {
+        try {
+          return fs.readFileSync(path.join(this.projectRoot, entry.path), 'utf8');
+        } catch {
+          return '';
+        }
+      });
+      const declared = (pattern: RegExp) =>
+        [...new Set(contents.flatMap(content => [...content.matchAll(pattern)].map(m => m[1])))].sort();
+
+      const change = {
+        module: moduleName,
+        files_created: files.length,
+        files_moved: new Set(files.map(entry => entry.source)).size,
+        files_removed: removed.filter(file => toPosixPath(file).includes(`/${moduleName}/`)).length,
+        interfaces: declared(/^type\s+(\w+)\s+interface\b/gm),
+        events: declared(/^type\s+(\w+(?:Event|Created|Updated|Deleted|Changed))\s+struct\b/gm),
+      };
+
+      let summary: string | null = null;
+      if (summarizer) {
+        try {
+          summary = (await summarizer({ ...change, summary: templateSummary(change) }))?.trim() || null;
+        } catch (error) {
+          console.warn(`⚠️  Summary of ${moduleName} falls back to the template: ${getErrorMessage(error)}`);
+        }
+      }
+      changes.push({ ...change, summary: summary ?? templateSummary(change) });
+    }
+
+    return changes;
+  }
+
+  /**
+   * Commit message using .vibeflow/templates/commit-message.tmpl when present
+   */
+  composeMessage(context: MigrationCommitContext): string {
+    const template = fs.existsSync(this.templatePath)
+      ? fs.readFileSync(this.templatePath, 'utf8')
+      : DEFAULT_COMMIT_TEMPLATE;
+    return renderCommitMessage(context, template);
+  }
+
+  /**
+   * Append one entry per applied module to .vibeflow/MIGRATION_LOG.md
+   */
+  appendMigrationLog(context: MigrationCommitContext, commit?: string): void {
+    const date = new Date().toISOString().slice(0, 10);
+    const run = context.run_id !== undefined ? ` (vibeflow run ${context.run_id})` : '';
+    const entries = context.modules.map(change => [
+      `## ${date} — ${change.module}${run}`,
+      '',
+      change.summary,
+      '',
+      `- Files: ${change.files_created} created, ${change.files_moved} moved, ${change.files_removed} removed`,
+      ...(change.interfaces.length > 0 ? [`- Interfaces: ${change.interfaces.join(', ')}`] : []),
+      ...(change.events.length > 0 ? [`- Events: ${change.events.join(', ')}`] : []),
+      `- Verification: ${formatVerification(context.verification)}`,
+      ...(commit ? [`- Commit: ${commit}`] : []),
+      '',
+    ].join('\n'));
+
+    const existing = fs.existsSync(this.migrationLogPath) ? fs.readFileSync(this.migrationLogPath, 'utf8') : MIGRATION_LOG_HEADER;
+    fs.mkdirSync(path.dirname(this.migrationLogPath), { recursive: true });
+    fs.writeFileSync(this.migrationLogPath, `${existing.trimEnd()}\n\n${entries.join('\n')}`);
+  }
+
+  /**
+   * Stage everything and commit with the message
+   *
+   * @returns the new commit hash
+   */
+  commit(message: string): string {
+    execSync('git add -A', { cwd: this.projectRoot });
+
+    // Use temporary file for commit message to avoid shell escaping issues
+    const tmpMessageFile = path.join(this.paths.outputRootPath, 'COMMIT_MSG.tmp');
+    fs.mkdirSync(path.dirname(tmpMessageFile), { recursive: true });
+    fs.writeFileSync(tmpMessageFile, message);
+    try {
+      execSync(`git commit -F "${tmpMessageFile}"`, { cwd: this.projectRoot, stdio: 'pipe' });
+    } finally {
+      fs.rmSync(tmpMessageFile, { force: true });
+    }
+
+    return execSync('git rev-parse HEAD', { cwd: this.projectRoot, encoding: 'utf8' }).trim();
+  }
+}
+
+/**
+ * Build and test the Go project the modules were written to
+ */
+export function verifyGoProject(projectRoot: string): VerificationSummary {
+  const goProject = detectGoProject(projectRoot);
+  if (!goProject.hasGoProject) return {};
+
+  const run = (command: string) => {
+    try {
+      execSync(command, { cwd: goProject.workingDirectory!, stdio: 'pipe', timeout: 600000 });
+      return true;
+    } catch {
+      return false;
+    }
+  };
+
+  const build = run('go build ./...');
+  return { build, tests: build ? run('go test ./...') : undefined };
+}
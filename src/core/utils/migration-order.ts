@@ -0,0 +1,178 @@
+/**
+ * A module to order and the modules it depends on; dependencies outside the
+ * list (established modules, unknown names) do not constrain the order
+ */
+export interface MigrationOrderInput {
+  name: string;
+  depends_on: string[];
+}
+
+export interface DependencyLayers {
+  /** Leaves first: every module comes after the modules it depends on, members of a cycle share a layer */
+  layers: string[][];
+  /** Modules depending on each other in a cycle; they cannot be ordered, only migrated together */
+  cycles: string[][];
+}
+
+export interface MisorderedModule {
+  module: string;
+  dependency: string;
+  /** Phase migration.phases put the module in */
+  phase: string;
+  /** Phase of the dependency; missing when migration.phases left it out */
+  dependency_phase?: string;
+}
+
+/**
+ * How the migration phases of plan.json were ordered
+ */
+export interface MigrationOrder {
+  /**
+   * dependencies: the layers of the dependency graph; configured: migration.phases,
+   * which respected every dependency; schedule: the schedule (see phase-schedule.ts)
+   */
+  source: 'dependencies' | 'configured' | 'schedule';
+  cycles: string[][];
+  /** Why migration.phases was not used: modules it placed before one of their dependencies */
+  misordered: MisorderedModule[];
+}
+
+/**
+ * Layer the modules by dependency: a module with no dependencies is in the
+ * first layer, any other one layer after its latest dependency. Modules calling
+ * each other in a cycle (strongly connected components) are layered as one.
+ * Within a layer, modules keep their order in the input.
+ */
+export function dependencyLayers(modules: MigrationOrderInput[]): DependencyLayers {
+  const components = stronglyConnected(modules);
+  const componentOf = new Map<string, number>();
+  components.forEach((members, index) => members.forEach(name => componentOf.set(name, index)));
+
+  // Tarjan emits a component after every component it depends on
+  const depth: number[] = [];
+  components.forEach((members, index) => {
+    const dependencies = members
+      .flatMap(name => modules.find(module => module.name === name)!.depends_on)
+      .map(name => componentOf.get(name))
+      .filter((component): component is number => component !== undefined && component !== index);
+    depth[index] = dependencies.length > 0 ? Math.max(...dependencies.map(component => depth[component])) + 1 : 0;
+  });
+
+  const layers: string[][] = [];
+  for (const module of modules) {
+    const layer = depth[componentOf.get(module.name)!];
+    (layers[layer] ??= []).push(module.name);
+  }
+
+  return {
+    layers,
+    cycles: components
+      .filter(members => members.length > 1)
+      .map(members => modules.map(module => module.name).filter(name => members.includes(name))),
+  };
+}
+
+/**
+ * Modules the phases put before one of their dependencies, or after a
+ * dependency left out of every phase. Dependencies inside a cycle are skipped:
+ * no order satisfies them.
+ */
+export function findMisorderedModules(
+  phases: { name: string; modules: string[] }[],
+  modules: MigrationOrderInput[],
+  cycles: string[][] = []
+): MisorderedModule[] {
+  const phaseOf = new Map<string, number>();
+  phases.forEach((phase, index) => phase.modules.forEach(name => {
+    if (!phaseOf.has(name)) phaseOf.set(name, index);
+  }));
+  const names = new Set(modules.map(module => module.name));
+  const cyclic = (a: string, b: string) => cycles.some(cycle => cycle.includes(a) && cycle.includes(b));
+
+  const misordered: MisorderedModule[] = [];
+  for (const module of modules) {
+    const index = phaseOf.get(module.name);
+    if (index === undefined) continue;
+    for (const dependency of new Set(module.depends_on)) {
+      if (dependency === module.name || !names.has(dependency) || cyclic(module.name, dependency)) continue;
+      const dependencyIndex = phaseOf.get(dependency);
+      if (dependencyIndex !== undefined && dependencyIndex <= index) continue;
+      misordered.push({
+        module: module.name,
+        dependency,
+        phase: phases[index].name,
+        ...(dependencyIndex !== undefined ? { dependency_phase: phases[dependencyIndex].name } : {}),
+      });
+    }
+  }
+  return misordered;
+}
+
+/**
+ * plan.md section with the dependency cycles and the misordered modules of
+ * migration.phases; empty when the phases simply follow the dependencies
+ */
+export function renderMigrationOrderSection(order: MigrationOrder | undefined): string {
+  if (!order || (order.cycles.length === 0 && order.misordered.length === 0)) return '';
+
+  const sources: Record<MigrationOrder['source'], string> = {
+    dependencies: 'フェーズはモジュール間の依存グラフから、依存先のモジュールが先になるよう並べています。',
+    configured: 'フェーズは設定 (migration.phases) の順序です。依存先のモジュールがすべて先に移行されることを確認済みです。',
+    schedule: 'フェーズはスケジュール (schedule) に従い、依存先のモジュールが先になるよう並べています。',
+  };
+  return `
+## 移行順序
+
+${sources[order.source]}
+
+${[
+    ...order.cycles.map(cycle => `- ⚠️ 依存サイクル: ${cycle.join(', ')} — 互いに依存しているため順序を決められません。同時に移行するか、先にサイクルを切断してください`),
+    ...order.misordered.map(entry => `- ⚠️ migration.phases では ${entry.module}（${entry.phase}）が依存先 ${entry.dependency}（${entry.dependency_phase ?? 'どのフェーズにも含まれない'}）より前になるため、依存グラフから計算した順序を使用しています`),
+  ].join('\n')}
+`;
+}
+
+/**
+ * Tarjan's algorithm: strongly connected components, each after the components it depends on
+ */
+function stronglyConnected(modules: MigrationOrderInput[]): string[][] {
+  const byName = new Map(modules.map(module => [module.name, module]));
+  const index = new Map<string, number>();
+  const lowlink = new Map<string, number>();
+  const stack: string[] = [];
+  const onStack = new Set<string>();
+  const components: string[][] = [];
+
+  const visit = (name: string) => {
+    index.set(name, index.size);
+    lowlink.set(name, index.get(name)!);
+    stack.push(name);
+    onStack.add(name);
+
+    for (const dependency of byName.get(name)!.depends_on) {
+      if (!byName.has(dependency)) continue;
+      if (!index.has(dependency)) {
+        visit(dependency);
+        lowlink.set(name, Math.min(lowlink.get(name)!, lowlink.get(dependency)!));
+      } else if (onStack.has(dependency)) {
+        lowlink.set(name, Math.min(lowlink.get(name)!, index.get(dependency)!));
+      }
+    }
+
+    if (lowlink.get(name) === index.get(name)) {
+      const component: string[] = [];
+      let member: string;
+      do {
+        member = stack.pop()!;
+        onStack.delete(member);
+        component.push(member);
+      } while (member !== name);
+      components.push(component);
+    }
+  };
+
+  modules.forEach(module => {
+    if (!index.has(module.name)) visit(module.name);
+  });
+  return components;
+}
@@ -0,0 +1,88 @@
+import { FailureCategory } from './error-utils.js';
+import { EscalationRecord, RunRecord } from './performance-store.js';
+
+/**
+ * Failures a stronger model can plausibly fix; timeouts, I/O and refusals are not retried
+ */
+export const ESCALATION_CATEGORIES: FailureCategory[] = ['llm-malformed-response', 'verification-failure'];
+
+/**
+ * USD per million tokens, matched against the model name (unknown models are priced as sonnet)
+ */
+const MODEL_PRICING: { match: string; input: number; output: number }[] = [
+  { match: 'haiku', input: 0.80, output: 4.00 },
+  { match: 'sonnet', input: 3.00, output: 15.00 },
+  { match: 'opus', input: 15.00, output: 75.00 },
+];
+
+export interface PreviousAttempt {
+  model: string;
+  category: FailureCategory;
+  error: string;
+}
+
+export interface EscalationSummary {
+  runs: number;
+  /** Runs that needed at least one escalation */
+  runs_with_escalations: number;
+  escalations: number;
+  succeeded: number;
+  failed: number;
+  over_budget: number;
+  /** succeeded / attempted retries, null when none was attempted */
+  success_rate: number | null;
+  extra_cost: number;
+  /** original → escalated model counts */
+  by_model: Record<string, number>;
+}
+
+/**
+ * Model to retry with after `current` failed, or undefined at the end of the chain
+ */
+export function nextModel(chain: string[], current: string): string | undefined {
+  const index = chain.indexOf(current);
+  return index >= 0 ? chain[index + 1] : undefined;
+}
+
+export function estimateModelCost(model: string, inputTokens: number, outputTokens: number): number {
+  const name = model.toLowerCase();
+  const pricing = MODEL_PRICING.find(p => name.includes(p.match)) ?? MODEL_PRICING[1];
+  return (inputTokens / 1_000_000) * pricing.input + (outputTokens / 1_000_000) * pricing.output;
+}
+
+/**
+ * Prompt section telling the escalated model what went wrong with the previous attempt
+ */
+export function renderPreviousAttemptSection(attempt: PreviousAttempt): string {
+  return `## Previous Attempt
+The previous attempt (${attempt.model}) failed with ${attempt.category}:
+${attempt.error}
+Avoid repeating this failure: return exactly one JSON object in the format above, and every Go file must be complete and syntactically valid.
+`;
+}
+
+/**
+ * How often runs needed escalation and how often the stronger model fixed the file
+ */
+export function summarizeEscalations(runs: RunRecord[]): EscalationSummary {
+  const escalations: EscalationRecord[] = runs.flatMap(run => run.escalations ?? []);
+  const succeeded = escalations.filter(e => e.outcome === 'success').length;
+  const failed = escalations.filter(e => e.outcome === 'failed').length;
+  const byModel: Record<string, number> = {};
+  for (const escalation of escalations) {
+    const key = `${escalation.original_model} → ${escalation.escalated_model}`;
+    byModel[key] = (byModel[key] ?? 0) + 1;
+  }
+
+  return {
+    runs: runs.length,
+    runs_with_escalations: runs.filter(run => (run.escalations ?? []).length > 0).length,
+    escalations: escalations.length,
+    succeeded,
+    failed,
+    over_budget: escalations.filter(e => e.outcome === 'over-budget').length,
+    success_rate: succeeded + failed > 0 ? succeeded / (succeeded + failed) : null,
+    extra_cost: escalations.reduce((sum, e) => sum + e.extra_cost, 0),
+    by_model: byModel,
+  };
+}
@@ -0,0 +1,145 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { parseGoDeclarations } from './context-selector.js';
+import { cyclomaticComplexity } from './method-evaluation.js';
+import { FileChurn } from './co-change.js';
+import { ServiceModule, findModuleOperations, readModuleSources } from './service-deployment.js';
+import { toPosixPath } from './workspace-paths.js';
+import { round } from './number-utils.js';
+
+/**
+ * Numbers an engineering manager budgets a module's migration with
+ */
+export interface ModuleEstimate {
+  module: string;
+  /** Lines of the files the migration moves; 0 when the legacy code stays in place */
+  loc_moved: number;
+  /** Sum of the McCabe complexity of the module's functions and methods */
+  cyclomatic_complexity: number;
+  /** Call sites in other modules rewritten to reach the module through its new packages */
+  call_sites: number;
+  /** Changes to the module's files in the git history; missing without history */
+  churn?: number;
+  /** churn × complexity, scaled so the riskiest module of the plan scores 100 */
+  churn_risk?: number;
+  /** From the effort estimates of the module's actions */
+  effort_days: number;
+}
+
+export interface PlanEstimates {
+  modules: ModuleEstimate[];
+  /** Commits the churn was counted from; missing without git history */
+  history_commits?: number;
+}
+
+export interface EstimateInput extends ServiceModule {
+  effort_days: number;
+}
+
+/**
+ * Measure every module: its size and complexity from the sources, the call
+ * sites into it from the other modules, and how often its files changed
+ */
+export function estimateModules(
+  projectRoot: string,
+  modules: EstimateInput[],
+  churn: FileChurn | null,
+  options: { moved?: boolean } = {}
+): PlanEstimates {
+  const moduleSources = readModuleSources(projectRoot, modules);
+  const measured = modules.map(module => {
+    const files = module.files.map(file => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file));
+    const sources = files
+      .filter(file => file.endsWith('.go'))
+      .map(file => ({ file, content: readSource(projectRoot, file) }))
+      .filter((source): source is { file: string; content: string } => source.content !== null);
+    const code = sources.filter(source => !source.file.endsWith('_test.go'));
+
+    return {
+      module: module.name,
+      loc_moved: options.moved === false ? 0 : sources.reduce((sum, source) => sum + countLines(source.content), 0),
+      cyclomatic_complexity: code.reduce((sum, source) => sum + parseGoDeclarations(source.content, source.file)
+        .filter(decl => decl.kind !== 'type')
+        .reduce((total, decl) => total + cyclomaticComplexity(decl.body), 0), 0),
+      call_sites: findModuleOperations(projectRoot, modules, module.name, null, moduleSources).calls.length,
+      ...(churn ? { churn: code.reduce((sum, source) => sum + (churn.changes[source.file] ?? 0), 0) } : {}),
+      effort_days: module.effort_days,
+    };
+  });
+
+  const hotspot = (estimate: { churn?: number; cyclomatic_complexity: number }) => (estimate.churn ?? 0) * estimate.cyclomatic_complexity;
+  const hottest = Math.max(0, ...measured.map(hotspot));
+  return {
+    modules: measured.map(estimate => (churn
+      ? { ...estimate, churn_risk: hottest > 0 ? Math.round((hotspot(estimate) / hottest) * 100) : 0 }
+      : estimate)),
+    ...(churn ? { history_commits: churn.commits } : {}),
+  };
+}
+
+/**
+ * Totals of the modules of each phase; the churn risk of a phase is its riskiest module's
+ */
+export function estimatePhases(estimates: PlanEstimates, phases: { name: string; modules: string[] }[]): {
+  phase: string;
+  modules: string[];
+  loc_moved: number;
+  call_sites: number;
+  effort_days: number;
+  churn_risk?: number;
+}[] {
+  return phases.map(phase => {
+    const members = estimates.modules.filter(estimate => phase.modules.includes(estimate.module));
+    const risks = members.flatMap(estimate => (estimate.churn_risk !== undefined ? [estimate.churn_risk] : []));
+    return {
+      phase: phase.name,
+      modules: members.map(estimate => estimate.module),
+      loc_moved: members.reduce((sum, estimate) => sum + estimate.loc_moved, 0),
+      call_sites: members.reduce((sum, estimate) => sum + estimate.call_sites, 0),
+      effort_days: members.reduce((sum, estimate) => sum + estimate.effort_days, 0),
+      ...(risks.length > 0 ? { churn_risk: Math.max(...risks) } : {}),
+    };
+  });
+}
+
+/**
+ * plan.md section: the estimates of each module and the totals of each phase
+ */
+export function renderEstimatesSection(estimates: PlanEstimates | undefined, phases: { name: string; modules: string[] }[]): string {
+  if (!estimates || estimates.modules.length === 0) return '';
+
+  const risk = (value: number | undefined) => (value !== undefined ? `${value}` : '-');
+  const moduleRows = estimates.modules.map(e =>
+    `| ${e.module} | ${e.loc_moved} | ${e.cyclomatic_complexity} | ${e.call_sites} | ${e.churn ?? '-'} | ${risk(e.churn_risk)} | ${round(e.effort_days, 1)} |`);
+  const phaseRows = estimatePhases(estimates, phases).map((e, index) =>
+    `| フェーズ${index + 1}: ${e.phase} | ${e.loc_moved} | ${e.call_sites} | ${round(e.effort_days, 1)} | ${risk(e.churn_risk)} |`);
+
+  return `
+## 見積もり (Effort & Risk)
+
+移動するコード量（LOC）、循環的複雑度の合計、書き換える呼び出し箇所（他モジュールからの呼び出し）、変更回数（churn）と工数（アクションの見積もりの合計、人日）です。
+${estimates.history_commits !== undefined
+    ? `churn リスクは変更回数 × 複雑度を、最もリスクの高いモジュールを 100 として換算した値です（Git履歴 ${estimates.history_commits}コミット）。`
+    : 'Git履歴がないため、変更回数と churn リスクは算出していません。'}
+
+| モジュール | 移動LOC | 循環的複雑度 | 呼び出し箇所 | 変更回数 | churnリスク | 工数 (人日) |
+|------------|---------|--------------|--------------|----------|-------------|-------------|
+${moduleRows.join('\n')}
+${phaseRows.length > 0 ? `
+| フェーズ | 移動LOC | 呼び出し箇所 | 工数 (人日) | churnリスク (最大) |
+|----------|---------|--------------|-------------|--------------------|
+${phaseRows.join('\n')}
+` : ''}`;
+}
+
+function readSource(projectRoot: string, file: string): string | null {
+  try {
+    return fs.readFileSync(path.join(projectRoot, file), 'utf8');
+  } catch {
+    return null;
+  }
+}
+
+function countLines(content: string): number {
+  return content === '' ? 0 : content.split('\n').length - (content.endsWith('\n') ? 1 : 0);
+}
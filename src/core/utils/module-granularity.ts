@@ -0,0 +1,165 @@
+import * as path from 'path';
+import { BoundaryConstraints, ClusteringConfig } from '../types/config.js';
+import { ModuleAdapter, violatesSeparation } from './boundary-constraints.js';
+import { toPosixPath } from './workspace-paths.js';
+
+export type GranularityOptions = Pick<ClusteringConfig, 'targetModules' | 'minFiles' | 'maxFiles'>;
+
+/**
+ * Granularity adapter: the constraint adapter plus how strongly two modules are coupled
+ */
+export interface GranularityAdapter<T> extends ModuleAdapter<T> {
+  affinity(a: T, b: T): number;
+  /** Modules fixed by the user (seeds): never split, and two of them are never merged */
+  pinned?(item: T): boolean;
+}
+
+export interface GranularityResult<T> {
+  items: T[];
+  adjustments: string[];
+  /** Targets the modules could not be brought to */
+  unmet: string[];
+}
+
+export function hasGranularityTargets(options: GranularityOptions | undefined): boolean {
+  return options?.targetModules !== undefined || options?.minFiles !== undefined || options?.maxFiles !== undefined;
+}
+
+/**
+ * Bring the clustering to the requested granularity: split modules above maxFiles
+ * (and the largest ones while there are fewer than targetModules) along their
+ * package directories, then merge modules below minFiles (and the smallest ones
+ * while there are more than targetModules) into the module they are most coupled
+ * to. mustSeparate constraints are never merged together.
+ */
+export function adjustGranularity<T>(
+  items: T[],
+  options: GranularityOptions | undefined,
+  adapter: GranularityAdapter<T>,
+  constraints?: BoundaryConstraints
+): GranularityResult<T> {
+  if (!hasGranularityTargets(options)) return { items, adjustments: [], unmet: [] };
+  const { targetModules, minFiles, maxFiles } = options!;
+
+  let current = [...items];
+  const adjustments: string[] = [];
+  const unmet: string[] = [];
+  const names = () => current.map(item => adapter.toConstrained(item, current).name);
+
+  // 1. Split: modules above maxFiles, then the largest while below targetModules
+  const splittable = (item: T) => !adapter.pinned?.(item) && packageDirs(adapter.toConstrained(item, current).files).length > 1;
+  const split = (index: number, reason: string) => {
+    const module = adapter.toConstrained(current[index], current);
+    const extracted = bisect(module.files);
+    const name = uniqueName(path.posix.basename(path.posix.dirname(extracted[0])), names());
+    const [rest, part] = adapter.split(current[index], name, extracted);
+    current.splice(index, 1, rest, part);
+    adjustments.push(`Split ${name} (${extracted.length} files) out of ${module.name} (${reason})`);
+  };
+
+  if (maxFiles !== undefined) {
+    for (let index = 0; index < current.length; index++) {
+      const files = adapter.toConstrained(current[index], current).files.length;
+      if (files <= maxFiles) continue;
+      if (splittable(current[index])) {
+        split(index, `maxFiles ${maxFiles}`);
+        index--;
+      } else {
+        unmet.push(`${adapter.toConstrained(current[index], current).name} has ${files} files in one package (maxFiles ${maxFiles})`);
+      }
+    }
+  }
+  while (targetModules !== undefined && current.length < targetModules) {
+    const candidates = current
+      .map((item, index) => ({ index, files: adapter.toConstrained(item, current).files.length }))
+      .filter(({ index, files }) => splittable(current[index]) && (minFiles === undefined || files >= 2 * minFiles))
+      .sort((a, b) => b.files - a.files);
+    if (candidates.length === 0) {
+      unmet.push(`${current.length} modules are below targetModules ${targetModules}: no module spans several packages`);
+      break;
+    }
+    split(candidates[0].index, `targetModules ${targetModules}`);
+  }
+
+  // 2. Merge: modules below minFiles, then the smallest while above targetModules
+  const mergeable = (sourceIndex: number, targetIndex: number) => {
+    const source = adapter.toConstrained(current[sourceIndex], current);
+    const target = adapter.toConstrained(current[targetIndex], current);
+    if (adapter.pinned?.(current[sourceIndex]) && adapter.pinned?.(current[targetIndex])) return false;
+    return !constraints || !violatesSeparation([source.name, ...source.mergedFrom, target.name, ...target.mergedFrom], constraints);
+  };
+  const merge = (sources: number[], reason: string): boolean => {
+    for (const sourceIndex of sources) {
+      const targets = current
+        .map((item, index) => ({ index, affinity: adapter.affinity(current[sourceIndex], item), files: adapter.toConstrained(item, current).files.length }))
+        .filter(({ index }) => index !== sourceIndex && mergeable(sourceIndex, index))
+        .sort((a, b) => b.affinity - a.affinity || a.files - b.files);
+      if (targets.length === 0) continue;
+
+      // A seeded module keeps its name and absorbs the other one
+      const [keep, absorbed] = adapter.pinned?.(current[sourceIndex]) && !adapter.pinned?.(current[targets[0].index])
+        ? [sourceIndex, targets[0].index]
+        : [targets[0].index, sourceIndex];
+      const absorbedName = adapter.toConstrained(current[absorbed], current).name;
+      const keptName = adapter.toConstrained(current[keep], current).name;
+      current[keep] = adapter.merge(current[keep], current[absorbed]);
+      current.splice(absorbed, 1);
+      adjustments.push(`Merged ${absorbedName} into ${keptName} (${reason})`);
+      return true;
+    }
+    return false;
+  };
+  const bySize = () => current
+    .map((item, index) => ({ index, files: adapter.toConstrained(item, current).files.length }))
+    .sort((a, b) => a.files - b.files);
+
+  if (minFiles !== undefined) {
+    while (current.length > 1) {
+      const small = bySize().filter(({ files }) => files < minFiles).map(({ index }) => index);
+      if (small.length === 0) break;
+      if (!merge(small, `minFiles ${minFiles}`)) {
+        unmet.push(`${small.length} modules stay below minFiles ${minFiles}: every merge would violate a mustSeparate constraint or join two seeded modules`);
+        break;
+      }
+    }
+  }
+  while (targetModules !== undefined && current.length > targetModules) {
+    if (!merge(bySize().map(({ index }) => index), `targetModules ${targetModules}`)) {
+      unmet.push(`${current.length} modules exceed targetModules ${targetModules}: every merge would violate a mustSeparate constraint or join two seeded modules`);
+      break;
+    }
+  }
+
+  return { items: current, adjustments, unmet };
+}
+
+/**
+ * Package directories of the files, sorted
+ */
+function packageDirs(files: string[]): string[] {
+  return [...new Set(files.map(file => path.posix.dirname(toPosixPath(file))))].sort();
+}
+
+/**
+ * Files of the leading package directories holding at most half of the module
+ * (at least one directory, never all of them), so related packages stay together
+ */
+function bisect(files: string[]): string[] {
+  const dirs = packageDirs(files);
+  const filesOf = (dir: string) => files.filter(file => path.posix.dirname(toPosixPath(file)) === dir);
+  const extracted = [...filesOf(dirs[0])];
+  for (const dir of dirs.slice(1, -1)) {
+    const next = filesOf(dir);
+    if (extracted.length + next.length > files.length / 2) break;
+    extracted.push(...next);
+  }
+  return extracted;
+}
+
+function uniqueName(base: string, taken: string[]): string {
+  const name = base && base !== '.' ? base : 'module';
+  if (!taken.includes(name)) return name;
+  let suffix = 2;
+  while (taken.includes(`${name}_${suffix}`)) suffix++;
+  return `${name}_${suffix}`;
+}
@@ -0,0 +1,264 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { parseGoDeclarations } from './context-selector.js';
+import { portableArtifact } from './workspace-paths.js';
+
+export interface ManifestEntry {
+  /** Output path relative to project root */
+  path: string;
+  /** Source file whose transformation produced this output */
+  source: string;
+  hash: string;
+  symbols: string[];
+  /** Backup of an original (non-generated) file this output overwrote */
+  backup?: string;
+  generated_at: string;
+}
+
+export interface ModuleOutputManifest {
+  module: string;
+  attempt: number;
+  updated_at: string;
+  files: ManifestEntry[];
+}
+
+export interface PendingOutput {
+  path: string;
+  content: string;
+  source: string;
+}
+
+export interface IdempotentWritePlan {
+  /** Outputs to write (content changed or new) */
+  write: PendingOutput[];
+  /** Outputs whose content is identical to what is on disk */
+  unchanged: PendingOutput[];
+  /** Previously generated outputs replaced by a renamed output (old path → new path) */
+  renamed: { from: string; to: string }[];
+  /** Previously generated outputs no longer produced */
+  stale: string[];
+  /** Previously generated files that keep some declarations but lose duplicated ones */
+  strip: { path: string; symbols: string[] }[];
+  /** Symbols declared in several new outputs of the same package */
+  conflicts: { symbol: string; files: string[] }[];
+}
+
+/**
+ * Share of defined symbols two outputs must exceed to count as the same file renamed
+ */
+const RENAME_OVERLAP_THRESHOLD = 0.5;
+
+/**
+ * ModuleManifestStore - モジュール単位の生成物マニフェスト
+ *
+ * Records every file written for a module so that re-running a module
+ * regenerates in place instead of accumulating duplicate outputs.
+ */
+export class ModuleManifestStore {
+  private projectRoot: string;
+  private manifestDir: string;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.manifestDir = path.join(projectRoot, '.vibeflow', 'manifests');
+  }
+
+  manifestPath(moduleName: string): string {
+    return path.join(this.manifestDir, `${moduleName}.json`);
+  }
+
+  /**
+   * Modules that have a recorded manifest
+   */
+  listModules(): string[] {
+    if (!fs.existsSync(this.manifestDir)) return [];
+    return fs.readdirSync(this.manifestDir)
+      .filter(name => name.endsWith('.json'))
+      .map(name => name.replace(/\.json$/, ''))
+      .sort();
+  }
+
+  load(moduleName: string): ModuleOutputManifest | null {
+    try {
+      return JSON.parse(fs.readFileSync(this.manifestPath(moduleName), 'utf8'));
+    } catch {
+      return null;
+    }
+  }
+
+  save(manifest: ModuleOutputManifest): void {
+    fs.mkdirSync(this.manifestDir, { recursive: true });
+    fs.writeFileSync(this.manifestPath(manifest.module), JSON.stringify(portableArtifact(this.projectRoot, manifest), null, 2));
+  }
+
+  remove(moduleName: string): void {
+    if (fs.existsSync(this.manifestPath(moduleName))) {
+      fs.unlinkSync(this.manifestPath(moduleName));
+    }
+  }
+
+  /**
+   * Decide what to write, rename, delete and strip for a module re-run
+   *
+   * @param processedSources Source files transformed successfully in this run;
+   *   outputs from sources that failed this time are left untouched
+   */
+  planWrite(moduleName: string, outputs: PendingOutput[], processedSources: string[]): IdempotentWritePlan {
+    const previous = this.load(moduleName)?.files ?? [];
+    const plan: IdempotentWritePlan = { write: [], unchanged: [], renamed: [], stale: [], strip: [], conflicts: [] };
+
+    // Symbols declared twice within the new outputs of one package: the later output wins
+    const resolved = outputs.map(o => ({ ...o }));
+    const owners = new Map<string, number>();
+    resolved.forEach((output, index) => {
+      for (const symbol of defineSymbols(output.path, output.content)) {
+        const key = `${path.dirname(normalize(output.path))}:${symbol}`;
+        const previousOwner = owners.get(key);
+        if (previousOwner !== undefined) {
+          const loser = resolved[previousOwner];
+          plan.conflicts.push({ symbol, files: [normalize(loser.path), normalize(output.path)] });
+          loser.content = stripDeclarations(loser.path, loser.content, [symbol]);
+        }
+        owners.set(key, index);
+      }
+    });
+
+    const newPaths = new Set(resolved.map(o => normalize(o.path)));
+    const newSymbols = new Map(resolved.map(o => [normalize(o.path), defineSymbols(o.path, o.content)]));
+    const packageSymbols = new Map<string, Set<string>>();
+    for (const [outputPath, symbols] of newSymbols) {
+      const dir = path.dirname(outputPath);
+      const set = packageSymbols.get(dir) ?? new Set<string>();
+      symbols.forEach(symbol => set.add(symbol));
+      packageSymbols.set(dir, set);
+    }
+
+    for (const output of resolved) {
+      const fullPath = path.join(this.projectRoot, output.path);
+      if (fs.existsSync(fullPath) && hashContent(fs.readFileSync(fullPath, 'utf8')) === hashContent(output.content)) {
+        plan.unchanged.push(output);
+      } else {
+        plan.write.push(output);
+      }
+    }
+
+    const processed = new Set(processedSources.map(normalize));
+
+    for (const entry of previous) {
+      const oldPath = normalize(entry.path);
+      if (newPaths.has(oldPath) || !fs.existsSync(path.join(this.projectRoot, oldPath))) continue;
+
+      // Renamed output: most symbols reappear in a new file of the same package
+      const renamedTo = [...newSymbols.entries()].find(([newPath, symbols]) =>
+        path.dirname(newPath) === path.dirname(oldPath) &&
+        symbolOverlap(entry.symbols, symbols) > RENAME_OVERLAP_THRESHOLD
+      );
+      if (renamedTo) {
+        plan.renamed.push({ from: oldPath, to: renamedTo[0] });
+        continue;
+      }
+
+      if (processed.has(normalize(entry.source))) {
+        plan.stale.push(oldPath);
+        continue;
+      }
+
+      // Kept from an earlier attempt: drop declarations the new outputs redefine (newest wins)
+      const redefined = packageSymbols.get(path.dirname(oldPath));
+      const duplicated = entry.symbols.filter(symbol => redefined?.has(symbol));
+      if (duplicated.length === entry.symbols.length && duplicated.length > 0) {
+        plan.stale.push(oldPath);
+      } else if (duplicated.length > 0) {
+        plan.strip.push({ path: oldPath, symbols: duplicated });
+      }
+    }
+
+    return plan;
+  }
+
+  /**
+   * Build the manifest recorded after a successful write
+   */
+  buildManifest(moduleName: string, plan: IdempotentWritePlan, backups: Map<string, string>): ModuleOutputManifest {
+    const previous = this.load(moduleName);
+    const outputs = [...plan.write, ...plan.unchanged];
+    const removed = new Set([...plan.stale, ...plan.renamed.map(r => r.from)]);
+    const written = new Set(outputs.map(o => normalize(o.path)));
+    const now = new Date().toISOString();
+
+    const kept = (previous?.files ?? []).filter(entry =>
+      !removed.has(normalize(entry.path)) && !written.has(normalize(entry.path))
+    ).map(entry => {
+      const fullPath = path.join(this.projectRoot, entry.path);
+      if (!fs.existsSync(fullPath)) return entry;
+      const content = fs.readFileSync(fullPath, 'utf8');
+      return { ...entry, hash: hashContent(content), symbols: defineSymbols(entry.path, content) };
+    });
+
+    const previousByPath = new Map((previous?.files ?? []).map(e => [normalize(e.path), e]));
+    const current = outputs.map(output => ({
+      path: normalize(output.path),
+      source: output.source,
+      hash: hashContent(output.content),
+      symbols: defineSymbols(output.path, output.content),
+      backup: backups.get(normalize(output.path)) ?? previousByPath.get(normalize(output.path))?.backup,
+      generated_at: now,
+    }));
+
+    return {
+      module: moduleName,
+      attempt: (previous?.attempt ?? 0) + 1,
+      updated_at: now,
+      files: [...kept, ...current],
+    };
+  }
+}
+
+/**
+ * Top-level symbols defined by a generated file (methods as Receiver.Method)
+ */
+export function defineSymbols(filePath: string, content: string): string[] {
+  if (!filePath.endsWith('.go')) return [];
+
+  return parseGoDeclarations(content, filePath).map(decl => {
+    if (decl.kind !== 'method') return decl.name;
+    const receiver = decl.signature.match(/^func\s*\(\s*\w*\s*\*?\s*(\w+)/);
+    return receiver ? `${receiver[1]}.${decl.name}` : decl.name;
+  });
+}
+
+/**
+ * Remove the given top-level declarations from Go source
+ */
+export function stripDeclarations(filePath: string, content: string, symbols: string[]): string {
+  const targets = new Set(symbols);
+  const declarations = parseGoDeclarations(content, filePath);
+  const symbolNames = defineSymbols(filePath, content);
+  let result = content;
+
+  declarations.forEach((decl, index) => {
+    if (targets.has(symbolNames[index])) {
+      result = result.replace(decl.body, '');
+    }
+  });
+
+  return result.replace(/\n{3,}/g, '\n\n');
+}
+
+/**
+ * Content hash ignoring line endings, so outputs written with CRLF are not mistaken for manual edits
+ */
+export function hashContent(content: string): string {
+  return createHash('sha256').update(content.replace(/\r\n/g, '\n')).digest('hex');
+}
+
+function symbolOverlap(oldSymbols: string[], newSymbols: string[]): number {
+  if (oldSymbols.length === 0) return 0;
+  const candidates = new Set(newSymbols);
+  return oldSymbols.filter(s => candidates.has(s)).length / oldSymbols.length;
+}
+
+function normalize(filePath: string): string {
+  return path.normalize(filePath);
+}
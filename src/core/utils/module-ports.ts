@@ -0,0 +1,166 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { ApiSymbol, KeepReason, analyzeModuleSymbols } from './api-surface.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** interface and dto (struct) types are the ports proper; the rest are exposed functions, named types and values */
+export type PortKind = 'interface' | 'dto' | 'type' | 'func' | 'var' | 'const';
+
+export interface PortSymbol {
+  name: string;
+  /** Package directory relative to the project root */
+  package: string;
+  file: string;
+}
+
+/**
+ * Exported symbol other modules use: part of the module's public API
+ */
+export interface ModulePort extends PortSymbol {
+  kind: PortKind;
+  /** Modules (or package directories outside any module) using it */
+  consumers: string[];
+  references: number;
+}
+
+/**
+ * Exported symbol no other module uses; unexported by refactoring
+ */
+export interface InternalSymbol extends PortSymbol {
+  /** Used by other packages of the module: stays exported, but only inside the module */
+  shared?: true;
+}
+
+export interface ModulePorts {
+  exposed: ModulePort[];
+  internal: InternalSymbol[];
+  /** Unused by other modules but reached through reflection or struct tags, so it stays exported */
+  kept: (PortSymbol & { reason: Exclude<KeepReason, 'name-collision'> })[];
+}
+
+const KIND_ORDER: PortKind[] = ['interface', 'dto', 'type', 'func', 'var', 'const'];
+
+/**
+ * Ports of each module: the exported interfaces, DTOs and functions other
+ * modules reference. Every other exported symbol of the module's files is
+ * internal. Methods are left out, as in the API surface report.
+ *
+ * @returns modules without exported symbols are missing
+ */
+export function planModulePorts(projectRoot: string, modules: { name: string; files: string[] }[]): Map<string, ModulePorts> {
+  const symbols = analyzeModuleSymbols(projectRoot, modules);
+  const ports = new Map<string, ModulePorts>();
+  const sources = new Map<string, string[]>();
+  const lineOf = (symbol: ApiSymbol): string => {
+    if (!sources.has(symbol.file)) sources.set(symbol.file, fs.readFileSync(path.join(projectRoot, symbol.file), 'utf8').split('\n'));
+    return sources.get(symbol.file)![symbol.line - 1] ?? '';
+  };
+
+  for (const module of modules) {
+    const files = new Set(module.files.map(toPosixPath));
+    const declared = (symbols.get(module.name) ?? []).filter(symbol => files.has(symbol.file));
+    if (declared.length === 0) continue;
+
+    const exposed = declared.filter(symbol => symbol.external_references > 0);
+    const hidden = declared.filter(symbol => symbol.external_references === 0);
+    const keptReason = (symbol: ApiSymbol) => (symbol.kept && symbol.kept !== 'name-collision' ? symbol.kept : undefined);
+    ports.set(module.name, {
+      exposed: exposed
+        .map(symbol => ({
+          name: symbol.name,
+          kind: portKind(symbol, lineOf(symbol)),
+          package: symbol.package,
+          file: symbol.file,
+          consumers: symbol.external_consumers,
+          references: symbol.external_references,
+        }))
+        .sort((a, b) => KIND_ORDER.indexOf(a.kind) - KIND_ORDER.indexOf(b.kind)),
+      internal: hidden.filter(symbol => !keptReason(symbol)).map(symbol => ({
+        name: symbol.name,
+        package: symbol.package,
+        file: symbol.file,
+        ...(symbol.module_references > 0 ? { shared: true as const } : {}),
+      })),
+      kept: hidden.flatMap(symbol => {
+        const reason = keptReason(symbol);
+        return reason ? [{ name: symbol.name, package: symbol.package, file: symbol.file, reason }] : [];
+      }),
+    });
+  }
+  return ports;
+}
+
+function portKind(symbol: ApiSymbol, declaration: string): PortKind {
+  if (symbol.kind !== 'type') return symbol.kind;
+  const type = declaration.match(new RegExp(`\\b${symbol.name}(?:\\[[^\\]]*\\])?\\s+(interface|struct)\\b`))?.[1];
+  return type === 'interface' ? 'interface' : type === 'struct' ? 'dto' : 'type';
+}
+
+/**
+ * Ports recorded per module in plan.json (modules[].ports)
+ */
+export function loadModulePorts(projectRoot: string): Map<string, ModulePorts> {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    const modules: { name?: unknown; ports?: ModulePorts }[] = Array.isArray(plan.modules) ? plan.modules : [];
+    return new Map(modules.filter(m => typeof m.name === 'string' && m.ports).map(m => [m.name as string, m.ports!]));
+  } catch {
+    return new Map();
+  }
+}
+
+/**
+ * Refactor prompt section: which exported symbols of the file stay public and which become unexported
+ *
+ * @param file path relative to the project root
+ */
+export function renderPortsPromptSection(module: string, ports: ModulePorts | undefined, file: string): string {
+  if (!ports) return '';
+  const inFile = <T extends PortSymbol>(symbols: T[]) => symbols.filter(symbol => symbol.file === toPosixPath(file));
+  const exposed = inFile(ports.exposed);
+  const internal = inFile(ports.internal);
+  const kept = inFile(ports.kept);
+  if (exposed.length === 0 && internal.length === 0) return '';
+
+  const unexport = internal.filter(symbol => !symbol.shared);
+  const shared = internal.filter(symbol => symbol.shared);
+  return `## Public API
+${exposed.length > 0
+    ? `Other modules use only these exported symbols of the ${module} module declared here; keep their names and signatures:
+${exposed.map(port => `- ${port.name} (${port.kind}, used by ${port.consumers.join(', ')})`).join('\n')}`
+    : `No other module uses the exported symbols declared here.`}
+${unexport.length > 0 ? `Unexport these symbols, which are used only inside their package: ${unexport.map(symbol => symbol.name).join(', ')}\n` : ''}${shared.length > 0 ? `Keep these exported for the other packages of the module, but only under its internal/ directory: ${shared.map(symbol => symbol.name).join(', ')}\n` : ''}${kept.length > 0 ? `Keep these exported (reached through reflection or struct tags): ${kept.map(symbol => symbol.name).join(', ')}\n` : ''}Do not export new identifiers unless another package of the module needs them.
+`;
+}
+
+/**
+ * plan.md section: the ports each module exposes and how many exports become internal
+ */
+export function renderPortsSection(modules: { name: string; ports?: ModulePorts }[]): string {
+  const planned = modules.filter(module => module.ports);
+  if (planned.length === 0) return '';
+
+  const kinds: Record<PortKind, string> = { interface: 'インターフェース', dto: 'DTO', type: '型', func: '関数', var: '変数', const: '定数' };
+  const blocks = planned.map(module => {
+    const ports = module.ports!;
+    const rows = ports.exposed.map(port => `| \`${port.package}.${port.name}\` | ${kinds[port.kind]} | ${port.consumers.join(', ')} | ${port.references} |`);
+    const shared = ports.internal.filter(symbol => symbol.shared).length;
+    return `### ${module.name}
+
+${rows.length > 0 ? `| ポート | 種別 | 利用モジュール | 参照数 |
+|--------|------|----------------|--------|
+${rows.join('\n')}` : '他モジュールから使われるエクスポートはありません。'}
+
+- 内部化: ${ports.internal.length}個${shared > 0 ? `（うちモジュール内の他パッケージが使う ${shared}個は internal/ 配下で公開）` : ''}${ports.internal.length > 0 ? `: ${ports.internal.map(symbol => `\`${symbol.name}\``).join(', ')}` : ''}${ports.kept.length > 0 ? `
+- 公開を維持（リフレクション・構造体タグ）: ${ports.kept.map(symbol => `\`${symbol.name}\``).join(', ')}` : ''}`;
+  });
+
+  return `
+## 公開ポート (Public Ports)
+
+各モジュールが他モジュールに公開するインターフェース・DTO・関数です。それ以外のエクスポートはモジュール内部に閉じ、vf refactor が非公開にします。
+
+${blocks.join('\n\n')}
+`;
+}
@@ -0,0 +1,271 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { AutonomyConfig, BoundaryDebt } from '../types/config.js';
+import { LlmCallRecord, countLinesOfCode } from './performance-store.js';
+import { SharedStateFinding } from './shared-state.js';
+import { findCrossBoundaryTransactions } from './review-packet.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { toPosixPath } from './workspace-paths.js';
+import { round } from './number-utils.js';
+
+/**
+ * How much human attention a module needs before its refactoring is applied:
+ * unattended runs may apply auto-apply modules, apply-with-review needs a
+ * reviewer after the fact, manual-only is not applied without --force
+ */
+export type AutonomyTier = 'auto-apply' | 'apply-with-review' | 'manual-only';
+
+/** Least to most risky */
+export const AUTONOMY_TIERS: AutonomyTier[] = ['auto-apply', 'apply-with-review', 'manual-only'];
+
+export interface RiskThresholds {
+  /** Highest score of an auto-apply module */
+  autoApply: number;
+  /** Highest score of an apply-with-review module; anything above is manual-only */
+  applyWithReview: number;
+}
+
+export const DEFAULT_RISK_THRESHOLDS: RiskThresholds = { autoApply: 25, applyWithReview: 60 };
+
+export type RiskFactor =
+  | 'test_coverage'
+  | 'shared_mutable_state'
+  | 'cross_module_transactions'
+  | 'contract_surface'
+  | 'cyclic_dependencies'
+  | 'size'
+  | 'verification_failure_rate'
+  | 'debt_density';
+
+/**
+ * Points each factor adds to the score at its maximum risk; they sum to 100
+ */
+export const RISK_WEIGHTS: Record<RiskFactor, number> = {
+  test_coverage: 25,
+  shared_mutable_state: 10,
+  cross_module_transactions: 10,
+  contract_surface: 10,
+  cyclic_dependencies: 10,
+  size: 10,
+  verification_failure_rate: 15,
+  debt_density: 10,
+};
+
+export interface RiskComponent {
+  factor: RiskFactor;
+  /** The measured signal, e.g. the share of tested files or the LOC */
+  value: number;
+  /** value mapped to 0 (safe) .. 1 (as risky as the factor gets) */
+  risk: number;
+  weight: number;
+  /** risk × weight, the factor's share of the score */
+  points: number;
+  detail: string;
+}
+
+export interface ModuleRisk {
+  /** 0 (safe) .. 100 */
+  score: number;
+  tier: AutonomyTier;
+  components: RiskComponent[];
+}
+
+/**
+ * Objective signals of one module the score is computed from
+ */
+export interface RiskInputs {
+  /** Go source files, without tests */
+  source_files: number;
+  /** Source files with a _test.go file in their directory */
+  tested_files: number;
+  shared_state: number;
+  transactions: number;
+  /** reflect uses and serialization struct tags: contracts the compiler does not check */
+  contract_surface: number;
+  cycles: number;
+  lines_of_code: number;
+  debt_markers: number;
+  /** LLM calls of earlier runs and how many of them failed verification */
+  verification: { calls: number; failed: number; scope: 'module' | 'project' | 'none' };
+}
+
+export interface RiskContext {
+  sharedState: SharedStateFinding[];
+  /** Owning module of each table (see tableOwners) */
+  tableOwners: Map<string, string>;
+  circularDependencies: string[];
+  /** LLM calls of all earlier runs (performance.db) */
+  llmCalls: LlmCallRecord[];
+}
+
+export interface RiskModule {
+  id?: string;
+  name: string;
+  files: string[];
+  debt?: BoundaryDebt;
+}
+
+// Values at which a factor reaches its full weight
+const FULL_RISK = {
+  shared_state: 2,
+  transactions: 2,
+  contract_surface: 20,
+  cycles: 2,
+  lines_of_code: 5000,
+  debt_per_kloc: 10,
+};
+
+const CONTRACT_PATTERN = /\breflect\.|`[^`\n]*\b(?:json|xml|yaml|protobuf|bson|db):"/g;
+
+export function resolveRiskThresholds(config?: AutonomyConfig): RiskThresholds {
+  const thresholds = { ...DEFAULT_RISK_THRESHOLDS, ...config?.thresholds };
+  if (thresholds.autoApply > thresholds.applyWithReview) {
+    throw new Error(`autonomy.thresholds.autoApply (${thresholds.autoApply}) must not exceed applyWithReview (${thresholds.applyWithReview})`);
+  }
+  return thresholds;
+}
+
+export function tierFor(score: number, thresholds: RiskThresholds = DEFAULT_RISK_THRESHOLDS): AutonomyTier {
+  if (score <= thresholds.autoApply) return 'auto-apply';
+  if (score <= thresholds.applyWithReview) return 'apply-with-review';
+  return 'manual-only';
+}
+
+/**
+ * Whether a module of `tier` may be applied when at most `maxTier` is allowed
+ */
+export function tierAllows(tier: AutonomyTier, maxTier: AutonomyTier): boolean {
+  return AUTONOMY_TIERS.indexOf(tier) <= AUTONOMY_TIERS.indexOf(maxTier);
+}
+
+export function scoreModuleRisk(inputs: RiskInputs, thresholds: RiskThresholds = DEFAULT_RISK_THRESHOLDS): ModuleRisk {
+  const coverage = inputs.source_files > 0 ? inputs.tested_files / inputs.source_files : 0;
+  const debtDensity = inputs.lines_of_code > 0 ? (inputs.debt_markers / inputs.lines_of_code) * 1000 : 0;
+  const failureRate = inputs.verification.calls > 0 ? inputs.verification.failed / inputs.verification.calls : 0;
+
+  const components = [
+    component('test_coverage', round(coverage, 2), 1 - coverage,
+      `${inputs.tested_files}/${inputs.source_files} source files have tests in their package`),
+    component('shared_mutable_state', inputs.shared_state, inputs.shared_state / FULL_RISK.shared_state,
+      `${inputs.shared_state} package variables shared with other modules`),
+    component('cross_module_transactions', inputs.transactions, inputs.transactions / FULL_RISK.transactions,
+      `${inputs.transactions} functions open a transaction on tables of other modules`),
+    component('contract_surface', inputs.contract_surface, inputs.contract_surface / FULL_RISK.contract_surface,
+      `${inputs.contract_surface} reflect uses and serialization tags`),
+    component('cyclic_dependencies', inputs.cycles, inputs.cycles / FULL_RISK.cycles,
+      `${inputs.cycles} dependency cycles`),
+    component('size', inputs.lines_of_code, inputs.lines_of_code / FULL_RISK.lines_of_code,
+      `${inputs.lines_of_code} lines of code`),
+    component('verification_failure_rate', round(failureRate, 2), failureRate,
+      inputs.verification.scope === 'none'
+        ? 'no LLM calls recorded yet'
+        : `${inputs.verification.failed}/${inputs.verification.calls} LLM calls failed verification (${inputs.verification.scope === 'module' ? 'this module' : 'all modules'})`),
+    component('debt_density', round(debtDensity, 1), debtDensity / FULL_RISK.debt_per_kloc,
+      `${inputs.debt_markers} debt markers (${round(debtDensity, 1)} per kLOC)`),
+  ];
+
+  const score = round(components.reduce((sum, c) => sum + c.points, 0), 1);
+  return { score, tier: tierFor(score, thresholds), components };
+}
+
+/**
+ * Collects the signals of a module from its source files, the plan's shared
+ * state, the table owners and the LLM calls of earlier runs
+ */
+export function gatherRiskInputs(projectRoot: string, module: RiskModule, context: RiskContext): RiskInputs {
+  const files = module.files.map(file => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file));
+  const sources = files.filter(file => file.endsWith('.go') && !file.endsWith('_test.go'));
+  const testedDirs = new Set<string>();
+  for (const dir of new Set(sources.map(file => path.posix.dirname(file)))) {
+    try {
+      if (fs.readdirSync(path.join(projectRoot, dir)).some(name => name.endsWith('_test.go'))) testedDirs.add(dir);
+    } catch {
+      // Missing directories have no tests
+    }
+  }
+
+  let contractSurface = 0;
+  for (const file of sources) {
+    try {
+      contractSurface += (fs.readFileSync(path.join(projectRoot, file), 'utf8').match(CONTRACT_PATTERN) ?? []).length;
+    } catch {
+      // Missing files do not contribute
+    }
+  }
+
+  const key = module.id ?? module.name;
+  const own = context.llmCalls.filter(call => call.module === key || call.module_name === module.name);
+  const calls = own.length > 0 ? own : context.llmCalls;
+
+  return {
+    source_files: sources.length,
+    tested_files: sources.filter(file => testedDirs.has(path.posix.dirname(file))).length,
+    shared_state: context.sharedState.filter(f => f.modules.includes(module.name)).length,
+    transactions: findCrossBoundaryTransactions(projectRoot, module.name, sources, context.tableOwners).length,
+    contract_surface: contractSurface,
+    cycles: context.circularDependencies.length,
+    lines_of_code: countLinesOfCode(projectRoot, sources),
+    debt_markers: module.debt?.total ?? 0,
+    verification: {
+      calls: calls.length,
+      failed: calls.filter(call => call.outcome === 'verification-failed').length,
+      scope: own.length > 0 ? 'module' : calls.length > 0 ? 'project' : 'none',
+    },
+  };
+}
+
+/**
+ * Risk of each module of plan.json by name; empty when there is no plan
+ */
+export function loadModuleRisks(projectRoot: string): Map<string, ModuleRisk> {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    const modules: { name?: unknown; risk?: ModuleRisk }[] = Array.isArray(plan.modules) ? plan.modules : [];
+    return new Map(modules.filter(m => typeof m.name === 'string' && m.risk).map(m => [m.name as string, m.risk!]));
+  } catch {
+    return new Map();
+  }
+}
+
+/**
+ * Reason to refuse applying a module above the allowed tier, or null to apply it
+ */
+export function autonomyTierRefusal(moduleName: string, risk: ModuleRisk | undefined, maxTier: AutonomyTier, force: boolean): string | null {
+  if (risk && tierAllows(risk.tier, maxTier)) return null;
+
+  const reason = risk
+    ? `Module ${moduleName} is ${risk.tier} (risk ${risk.score}), above the allowed tier ${maxTier}`
+    : `Module ${moduleName} has no risk classification in plan.json (re-run vf plan); the allowed tier is ${maxTier}`;
+  if (force) {
+    console.warn(`  ⚠️  ${reason}; applying with --force`);
+    return null;
+  }
+  return `${reason}. Review it and pass --force to apply`;
+}
+
+export function renderRiskSection(modules: { name: string; risk?: ModuleRisk }[], thresholds: RiskThresholds): string {
+  const scored = modules.filter(module => module.risk);
+  if (scored.length === 0) return '';
+
+  const entries = scored.map(({ name, risk }) => [
+    `### ${name}: ${risk!.score} (${risk!.tier})`,
+    '',
+    '| 要因 | 値 | 点数 | 詳細 |',
+    '|------|----|------|------|',
+    ...risk!.components.map(c => `| ${c.factor} | ${c.value} | ${c.points}/${c.weight} | ${c.detail} |`),
+  ].join('\n'));
+
+  return `
+## 自動適用のリスク分類
+
+リスクスコア（0〜100）は以下の要因の合計です。auto-apply: ${thresholds.autoApply}以下、apply-with-review: ${thresholds.applyWithReview}以下、それ以上は manual-only。
+\`autonomy.maxAutoApplyTier\` を超えるモジュールは \`vf refactor --apply\` が \`--force\` なしでは適用しません。
+
+${entries.join('\n\n')}
+`;
+}
+
+function component(factor: RiskFactor, value: number, risk: number, detail: string): RiskComponent {
+  const clamped = Math.min(1, Math.max(0, risk));
+  return { factor, value, risk: round(clamped, 2), weight: RISK_WEIGHTS[factor], points: round(clamped * RISK_WEIGHTS[factor], 1), detail };
+}
@@ -0,0 +1,239 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { ModuleStage, ModuleStatusChange, ModuleStatusRecord, PerformanceStore } from './performance-store.js';
+import { ConfigLoader } from './config-loader.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { parseDomainMap } from './input-parsers.js';
+import { csvCell } from './metrics-aggregator.js';
+
+export const MODULE_STAGES: ModuleStage[] = ['discovered', 'planned', 'refactored', 'tests-relocated', 'verified', 'accepted'];
+
+/** Stages agents record on success; accepted is only set by `vf status accept` */
+export type AgentStage = Exclude<ModuleStage, 'accepted'>;
+
+export type ModuleStatusExportFormat = 'json' | 'csv';
+
+export interface ModuleRef {
+  id?: string;
+  name: string;
+}
+
+export interface ModuleStatusView {
+  module: string;
+  id?: string;
+  stage: ModuleStage;
+  /** Run that last changed the stage (none for modules only known from domain-map.json) */
+  run_id?: number;
+  updated_at?: string;
+  accepted_by?: string;
+  team?: string;
+  /** Stages still ahead of the module */
+  remaining: ModuleStage[];
+  /** Command that moves the module to its next stage */
+  next?: string;
+}
+
+/**
+ * Accepting a module that has not been verified yet
+ */
+export class ModuleNotVerifiedError extends Error {
+  constructor(public readonly module: string, public readonly stage: ModuleStage) {
+    super(`Module "${module}" is ${stage}, not verified. Run 'vf verify' first or pass --force to accept anyway`);
+    this.name = 'ModuleNotVerifiedError';
+  }
+}
+
+/**
+ * Stage a module moves to when an agent reports `reached`, or null to leave it unchanged.
+ * Refactoring regenerates code, so it resets later stages (including acceptance, which
+ * callers must confirm first); every other stage only moves a module forward.
+ */
+export function nextStage(current: ModuleStage | undefined, reached: AgentStage): ModuleStage | null {
+  if (reached === 'refactored') return reached;
+  if (current && MODULE_STAGES.indexOf(current) >= MODULE_STAGES.indexOf(reached)) return null;
+  return reached;
+}
+
+/**
+ * ModuleStatusTracker - 境界ごとの移行ライフサイクル管理
+ *
+ * Records how far each boundary has come (discovered → planned → refactored →
+ * tests-relocated → verified → accepted) in the module_status section of
+ * performance.json. Agents advance modules when they succeed; acceptance is a
+ * human decision recorded with `vf status accept`.
+ */
+export class ModuleStatusTracker {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+  private store: PerformanceStore;
+
+  constructor(projectRoot: string, store?: PerformanceStore) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+    this.store = store ?? new PerformanceStore(projectRoot);
+  }
+
+  /**
+   * Record that an agent run took the modules to a stage. Modules given by name
+   * are keyed by their boundary ID from domain-map.json when they have one.
+   *
+   * @returns modules whose stage changed
+   */
+  advance(modules: ModuleRef[], stage: AgentStage, runId?: number): string[] {
+    const ids = new Map(this.loadBoundaries().map(b => [b.name, b.id]));
+    const changed: string[] = [];
+    for (const named of modules) {
+      const ref = { id: named.id ?? ids.get(named.name), name: named.name };
+      this.store.updateModuleStatus(ref.id ?? ref.name, current => {
+        const target = nextStage(current?.stage, stage);
+        if (!target) return undefined;
+        changed.push(ref.name);
+        return this.transition(ref, current, { stage: target, run_id: runId, at: new Date().toISOString() });
+      });
+    }
+    return changed;
+  }
+
+  /**
+   * Mark modules refactored or tests-relocated by a run as verified after `vf verify` passed
+   *
+   * @returns verified module names
+   */
+  verifyRun(runId: number): string[] {
+    const refs = this.store.getModuleStatuses()
+      .filter(r => r.run_id === runId && (r.stage === 'refactored' || r.stage === 'tests-relocated'))
+      .map(r => ({ id: r.module, name: r.module_name }));
+    return this.advance(refs, 'verified', runId);
+  }
+
+  /**
+   * Record the human sign-off of a module
+   *
+   * @throws ModuleNotVerifiedError unless the module is verified (or force is set)
+   */
+  accept(moduleName: string, by: string, options: { force?: boolean } = {}): ModuleStatusRecord {
+    const ref = this.resolve(moduleName);
+    const current = this.find(ref);
+    const stage = current?.stage ?? 'discovered';
+    if (stage !== 'verified' && stage !== 'accepted' && !options.force) {
+      throw new ModuleNotVerifiedError(ref.name, stage);
+    }
+
+    return this.store.updateModuleStatus(ref.id ?? ref.name, latest =>
+      this.transition(ref, latest, { stage: 'accepted', by, at: new Date().toISOString() })
+    )!;
+  }
+
+  /**
+   * Status record of a module (by boundary ID or name)
+   */
+  find(ref: ModuleRef): ModuleStatusRecord | undefined {
+    return this.store.getModuleStatuses().find(r => r.module === (ref.id ?? ref.name) || r.module_name === ref.name);
+  }
+
+  /**
+   * Every boundary of the domain map plus tracked modules no longer in it
+   */
+  list(): ModuleStatusView[] {
+    const records = this.store.getModuleStatuses();
+    const boundaries = this.loadBoundaries();
+    // Configured teams take precedence over the ones discovery attributed
+    const teams = {
+      ...Object.fromEntries(boundaries.filter(b => b.team).map(b => [b.name, b.team!])),
+      ...this.loadTeams(),
+    };
+    const views: ModuleStatusView[] = boundaries.map(boundary => {
+      const record = records.find(r => r.module === (boundary.id ?? boundary.name)) ?? records.find(r => r.module_name === boundary.name);
+      return this.view({ id: boundary.id, name: boundary.name }, record, teams);
+    });
+
+    const listed = new Set(views.map(v => v.id ?? v.module));
+    for (const record of records) {
+      if (listed.has(record.module) || views.some(v => v.module === record.module_name)) continue;
+      views.push(this.view({ id: record.module === record.module_name ? undefined : record.module, name: record.module_name }, record, teams));
+    }
+    return views;
+  }
+
+  private view(ref: ModuleRef, record: ModuleStatusRecord | undefined, teams: Record<string, string>): ModuleStatusView {
+    const stage = record?.stage ?? 'discovered';
+    const next = nextCommand(ref.name, stage, record?.run_id);
+    return {
+      module: ref.name,
+      ...(ref.id ? { id: ref.id } : {}),
+      stage,
+      ...(record?.run_id !== undefined ? { run_id: record.run_id } : {}),
+      ...(record ? { updated_at: record.updated_at } : {}),
+      ...(record?.accepted_by ? { accepted_by: record.accepted_by } : {}),
+      ...(teams[ref.name] ? { team: teams[ref.name] } : {}),
+      remaining: MODULE_STAGES.slice(MODULE_STAGES.indexOf(stage) + 1),
+      ...(next ? { next } : {}),
+    };
+  }
+
+  private transition(ref: ModuleRef, current: ModuleStatusRecord | undefined, change: ModuleStatusChange): ModuleStatusRecord {
+    const runId = change.run_id ?? current?.run_id;
+    return {
+      module: ref.id ?? ref.name,
+      module_name: ref.name,
+      stage: change.stage,
+      ...(runId !== undefined ? { run_id: runId } : {}),
+      updated_at: change.at,
+      ...(change.stage === 'accepted' && change.by ? { accepted_by: change.by } : {}),
+      history: [...(current?.history ?? []), change],
+    };
+  }
+
+  private resolve(moduleName: string): ModuleRef {
+    const boundary = this.loadBoundaries().find(b => b.name === moduleName || b.id === moduleName);
+    if (boundary) return { id: boundary.id, name: boundary.name };
+
+    const record = this.store.getModuleStatuses().find(r => r.module_name === moduleName || r.module === moduleName);
+    if (record) return { id: record.module === record.module_name ? undefined : record.module, name: record.module_name };
+    throw new Error(`Module "${moduleName}" not found in domain map or module status`);
+  }
+
+  private loadBoundaries(): (ModuleRef & { team?: string })[] {
+    if (!fs.existsSync(this.paths.domainMapPath)) return [];
+    const content = fs.readFileSync(this.paths.domainMapPath, 'utf8');
+    return parseDomainMap(content, this.paths.getRelativePath(this.paths.domainMapPath)).map.boundaries
+      .map(b => ({ id: b.id, name: b.name, team: b.ownership?.team }));
+  }
+
+  /**
+   * boundaries.target_modules.<name>.team from vibeflow.config.yaml
+   */
+  private loadTeams(): Record<string, string> {
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      return Object.fromEntries(Object.entries(config.boundaries?.target_modules ?? {})
+        .filter(([, module]) => module.team)
+        .map(([name, module]) => [name, module.team!]));
+    } catch {
+      return {};
+    }
+  }
+}
+
+function nextCommand(moduleName: string, stage: ModuleStage, runId?: number): string | undefined {
+  switch (stage) {
+    case 'discovered': return 'vf plan';
+    case 'planned': return `vf refactor --module ${moduleName} --apply`;
+    case 'refactored':
+    case 'tests-relocated': return runId !== undefined ? `vf verify --run-id ${runId}` : 'vf verify';
+    case 'verified': return `vf status accept ${moduleName} --by <name>`;
+    default: return undefined;
+  }
+}
+
+export function formatModuleStatus(views: ModuleStatusView[], format: ModuleStatusExportFormat): string {
+  if (format === 'json') {
+    return JSON.stringify({ generated_at: new Date().toISOString(), modules: views }, null, 2) + '\n';
+  }
+
+  const header = ['module', 'id', 'stage', 'run_id', 'updated_at', 'accepted_by', 'team', 'remaining', 'next'];
+  const rows = views.map(v => [
+    v.module, v.id ?? '', v.stage, v.run_id ?? '', v.updated_at ?? '', v.accepted_by ?? '', v.team ?? '', v.remaining.join(' '), v.next ?? '',
+  ].map(csvCell).join(','));
+  return [header.join(','), ...rows].join('\n') + '\n';
+}
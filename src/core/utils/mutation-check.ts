@@ -0,0 +1,335 @@
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { execSync } from 'child_process';
+import { detectGoProject } from './go-project-utils.js';
+import { isolateWorkspace } from './method-evaluation.js';
+import { toPosixPath } from './workspace-paths.js';
+import { topLevelParts } from './text-utils.js';
+
+/** Mutants tried per module when tests.mutation.maxMutants is not set */
+export const DEFAULT_MAX_MUTANTS = 20;
+
+/** Seconds a module's tests may run against one mutant (tests.mutation.timeout) */
+export const DEFAULT_MUTANT_TIMEOUT_SECONDS = 60;
+
+export type MutationOperator = 'negate-comparison' | 'error-to-nil' | 'off-by-one';
+
+export interface Mutant {
+  /** Source file relative to the project root */
+  file: string;
+  /** 1-based */
+  line: number;
+  operator: MutationOperator;
+  original: string;
+  mutated: string;
+}
+
+export type MutantOutcome = 'killed' | 'survived' | 'timed-out' | 'invalid';
+
+export interface MutantResult extends Mutant {
+  /** timed-out counts as killed; invalid (does not compile) is left out of the kill rate */
+  outcome: MutantOutcome;
+  /** Test files with a test that failed against the mutant */
+  killed_by: string[];
+}
+
+export interface MutationFileResult {
+  file: string;
+  mutants: number;
+  killed: number;
+  /** null when no valid mutant was generated for the file */
+  kill_rate: number | null;
+}
+
+export interface MutationTestFileResult {
+  file: string;
+  /** Generated or relocated by test synthesis */
+  generated: boolean;
+  killed: number;
+  kill_rate: number | null;
+  /** Generated and killed no mutant: review first */
+  vacuous: boolean;
+}
+
+export interface MutationCheckResult {
+  module: string;
+  checked_at: string;
+  /** Valid mutants (they compile) */
+  mutants: number;
+  killed: number;
+  timed_out: number;
+  invalid: number;
+  kill_rate: number | null;
+  files: MutationFileResult[];
+  tests: MutationTestFileResult[];
+  results: MutantResult[];
+  /** Set when the check could not run (no tests, tests failing without mutations) */
+  skipped?: string;
+}
+
+export type MutationRunner = (command: string, cwd: string, timeoutMs: number) => { ok: boolean; output: string; timed_out?: boolean };
+
+export interface MutationCheckOptions {
+  /** Module directory relative to the project root (default: internal/<module>) */
+  moduleDir?: string;
+  /** Test files (relative to the project root) produced or relocated by test synthesis */
+  generatedTests?: string[];
+  maxMutants?: number;
+  timeoutSeconds?: number;
+  run?: MutationRunner;
+}
+
+/**
+ * Every mutation the operators can apply to a Go source file, in line order.
+ * Strings and comments are never mutated; import blocks are skipped.
+ */
+export function generateMutants(file: string, content: string): Mutant[] {
+  const mutants: Mutant[] = [];
+  const state = { block: false, raw: false, imports: false };
+
+  content.split('\n').forEach((original, index) => {
+    const masked = maskLine(original, state);
+    const trimmed = masked.trim();
+    if (state.imports) {
+      if (trimmed.startsWith(')')) state.imports = false;
+      return;
+    }
+    if (/^import\s*\($/.test(trimmed)) {
+      state.imports = true;
+      return;
+    }
+    if (/^(package|import)\b/.test(trimmed)) return;
+
+    const add = (operator: MutationOperator, start: number, end: number, replacement: string) => {
+      mutants.push({ file, line: index + 1, operator, original, mutated: original.slice(0, start) + replacement + original.slice(end) });
+    };
+
+    for (const match of masked.matchAll(/(?<![<>=!:])(<=|>=|<|>)(?![<>=-])/g)) {
+      const before = masked.slice(0, match.index).trimEnd();
+      if (!/[\w)\]]$/.test(before) || /\bfunc\b|\bchan\b/.test(masked.slice(0, match.index))) continue;
+      add('negate-comparison', match.index!, match.index! + match[1].length, NEGATED[match[1]]);
+    }
+
+    const returned = masked.match(/^(\s*return\s+)(.+?)\s*$/);
+    if (returned) {
+      const values = topLevelParts(returned[2]).map(({ text, start }) => ({ text: text.trim(), start: start + text.length - text.trimStart().length }));
+      const last = values[values.length - 1];
+      if (last.text !== 'nil' && /^(err\w*|\w*Err\w*|errors\.New\(.*\)|fmt\.Errorf\(.*\)|&?\w+Error\{.*\})$/.test(last.text)) {
+        const start = returned[1].length + last.start;
+        add('error-to-nil', start, start + last.text.length, 'nil');
+      }
+    }
+
+    for (const match of masked.matchAll(/(?<![\w.])(\d+)(?![\w.])/g)) {
+      add('off-by-one', match.index!, match.index! + match[1].length, String(Number(match[1]) + 1));
+    }
+  });
+  return mutants;
+}
+
+/**
+ * At most `max` mutants, taken from the files in turn so that every file is mutated
+ */
+export function selectMutants(mutants: Mutant[], max: number): Mutant[] {
+  const byFile = new Map<string, Mutant[]>();
+  for (const mutant of mutants) byFile.set(mutant.file, [...(byFile.get(mutant.file) ?? []), mutant]);
+  const queues = [...byFile.keys()].sort().map(file => byFile.get(file)!);
+
+  const selected: Mutant[] = [];
+  for (let round = 0; selected.length < max && queues.some(queue => queue.length > round); round++) {
+    for (const queue of queues) {
+      if (round < queue.length && selected.length < max) selected.push(queue[round]);
+    }
+  }
+  return selected;
+}
+
+/**
+ * Test strength of a module: apply a bounded set of mutants to its non-test
+ * sources in a copy of the project and run the module's tests against each one
+ */
+export function checkModuleMutations(projectRoot: string, moduleName: string, options: MutationCheckOptions = {}): MutationCheckResult {
+  const moduleDir = toPosixPath(options.moduleDir ?? path.posix.join('internal', moduleName));
+  const generated = new Set((options.generatedTests ?? []).map(toPosixPath));
+  const goFiles = listGoFiles(projectRoot, moduleDir);
+  const sources = goFiles.filter(file => !file.endsWith('_test.go'));
+  const tests = goFiles.filter(file => file.endsWith('_test.go'));
+  const result: MutationCheckResult = {
+    module: moduleName,
+    checked_at: new Date().toISOString(),
+    mutants: 0,
+    killed: 0,
+    timed_out: 0,
+    invalid: 0,
+    kill_rate: null,
+    files: [],
+    tests: [],
+    results: [],
+  };
+  if (tests.length === 0) return { ...result, skipped: `no test files in ${moduleDir}` };
+
+  const mutants = selectMutants(
+    sources.flatMap(file => generateMutants(file, fs.readFileSync(path.join(projectRoot, file), 'utf8'))),
+    options.maxMutants ?? DEFAULT_MAX_MUTANTS
+  );
+  const testFunctions = new Map<string, string[]>();
+  for (const file of tests) {
+    for (const match of fs.readFileSync(path.join(projectRoot, file), 'utf8').matchAll(/^func\s+(Test\w*)\s*\(/gm)) {
+      testFunctions.set(match[1], [...(testFunctions.get(match[1]) ?? []), file]);
+    }
+  }
+
+  const workspace = fs.mkdtempSync(path.join(os.tmpdir(), `vibeflow-mutation-${moduleName}-`));
+  try {
+    isolateWorkspace(projectRoot, workspace);
+    const goProject = detectGoProject(workspace);
+    const cwd = goProject.workingDirectory ?? workspace;
+    const command = `go test -json -count=1 ./${toPosixPath(path.relative(cwd, path.join(workspace, moduleDir)))}/...`;
+    const run = options.run ?? runTests;
+    const timeoutMs = (options.timeoutSeconds ?? DEFAULT_MUTANT_TIMEOUT_SECONDS) * 1000;
+
+    const baseline = run(command, cwd, timeoutMs);
+    if (!baseline.ok) return { ...result, skipped: 'the module\'s tests fail without mutations' };
+
+    for (const mutant of mutants) {
+      const target = path.join(workspace, mutant.file);
+      const original = fs.readFileSync(target, 'utf8');
+      const lines = original.split('\n');
+      lines[mutant.line - 1] = mutant.mutated;
+      fs.writeFileSync(target, lines.join('\n'));
+      try {
+        result.results.push({ ...mutant, ...classifyMutant(run(command, cwd, timeoutMs), testFunctions) });
+      } finally {
+        fs.writeFileSync(target, original);
+      }
+    }
+  } finally {
+    fs.rmSync(workspace, { recursive: true, force: true });
+  }
+
+  const valid = result.results.filter(r => r.outcome !== 'invalid');
+  const killed = valid.filter(r => r.outcome !== 'survived');
+  const rate = (count: number, total: number) => (total > 0 ? count / total : null);
+  result.mutants = valid.length;
+  result.killed = killed.length;
+  result.timed_out = valid.filter(r => r.outcome === 'timed-out').length;
+  result.invalid = result.results.length - valid.length;
+  result.kill_rate = rate(killed.length, valid.length);
+  result.files = sources.map(file => {
+    const fileMutants = valid.filter(r => r.file === file);
+    const fileKilled = fileMutants.filter(r => r.outcome !== 'survived').length;
+    return { file, mutants: fileMutants.length, killed: fileKilled, kill_rate: rate(fileKilled, fileMutants.length) };
+  });
+  result.tests = tests.map(file => {
+    const testKilled = valid.filter(r => r.killed_by.includes(file)).length;
+    const killRate = rate(testKilled, valid.length);
+    return { file, generated: generated.has(file), killed: testKilled, kill_rate: killRate, vacuous: generated.has(file) && killRate === 0 };
+  });
+  return result;
+}
+
+/**
+ * A mutant is killed when a test fails or the run times out. A run that fails
+ * without a failing test because the package does not build is invalid.
+ */
+function classifyMutant(
+  run: { ok: boolean; output: string; timed_out?: boolean },
+  testFunctions: Map<string, string[]>
+): { outcome: MutantOutcome; killed_by: string[] } {
+  if (run.timed_out) return { outcome: 'timed-out', killed_by: [] };
+  if (run.ok) return { outcome: 'survived', killed_by: [] };
+
+  const failed = new Set<string>();
+  for (const line of run.output.split('\n')) {
+    try {
+      const event = JSON.parse(line);
+      // Subtests report as Parent/Child
+      if (event.Test && event.Action === 'fail') failed.add(String(event.Test).split('/')[0]);
+    } catch {
+      // Build output interleaved with the JSON stream
+    }
+  }
+  if (failed.size === 0 && /\[build failed\]|\[setup failed\]|build failed/.test(run.output)) {
+    return { outcome: 'invalid', killed_by: [] };
+  }
+  return { outcome: 'killed', killed_by: [...new Set([...failed].flatMap(name => testFunctions.get(name) ?? []))].sort() };
+}
+
+const NEGATED: Record<string, string> = { '<': '>=', '<=': '>', '>': '<=', '>=': '<' };
+
+/**
+ * The line with string, rune and comment contents blanked out, so positions
+ * stay those of the original
+ */
+function maskLine(line: string, state: { block: boolean; raw: boolean }): string {
+  let masked = '';
+  let quote: string | null = null;
+  for (let i = 0; i < line.length; i++) {
+    const char = line[i];
+    if (state.block) {
+      masked += ' ';
+      if (char === '*' && line[i + 1] === '/') {
+        masked += ' ';
+        i++;
+        state.block = false;
+      }
+    } else if (state.raw) {
+      masked += char === '`' ? '`' : ' ';
+      if (char === '`') state.raw = false;
+    } else if (quote) {
+      if (char === '\\') {
+        masked += '  ';
+        i++;
+      } else {
+        masked += char === quote ? char : ' ';
+        if (char === quote) quote = null;
+      }
+    } else if (char === '/' && line[i + 1] === '/') {
+      return masked + ' '.repeat(line.length - i);
+    } else if (char === '/' && line[i + 1] === '*') {
+      masked += '  ';
+      i++;
+      state.block = true;
+    } else if (char === '`') {
+      masked += char;
+      state.raw = true;
+    } else {
+      masked += char;
+      if (char === '"' || char === '\'') quote = char;
+    }
+  }
+  return masked;
+}
+
+function listGoFiles(projectRoot: string, dir: string): string[] {
+  const files: string[] = [];
+  const walk = (relative: string) => {
+    let entries: fs.Dirent[];
+    try {
+      entries = fs.readdirSync(path.join(projectRoot, relative), { withFileTypes: true });
+    } catch {
+      return;
+    }
+    for (const entry of entries) {
+      const child = path.posix.join(relative, entry.name);
+      if (entry.isDirectory() && entry.name !== 'vendor' && entry.name !== 'testdata' && !entry.name.startsWith('.')) walk(child);
+      else if (entry.isFile() && entry.name.endsWith('.go')) files.push(child);
+    }
+  };
+  walk(dir);
+  return files.sort();
+}
+
+function runTests(command: string, cwd: string, timeoutMs: number): { ok: boolean; output: string; timed_out?: boolean } {
+  try {
+    return { ok: true, output: execSync(command, { cwd, stdio: 'pipe', timeout: timeoutMs }).toString() };
+  } catch (error) {
+    const { stdout, stderr, code, signal } = error as { stdout?: Buffer; stderr?: Buffer; code?: string; signal?: string };
+    return {
+      ok: false,
+      output: `${stdout?.toString() ?? ''}${stderr?.toString() ?? ''}`,
+      ...(code === 'ETIMEDOUT' || signal === 'SIGTERM' ? { timed_out: true } : {}),
+    };
+  }
+}
@@ -0,0 +1,12 @@
+/**
+ * Number helpers shared by the reports and scores
+ */
+
+/**
+ * Round to the given number of decimal places, without a negative zero
+ */
+export function round(value: number, digits: number): number {
+  const factor = 10 ** digits;
+  const rounded = Math.round(value * factor) / factor;
+  return Object.is(rounded, -0) ? 0 : rounded;
+}
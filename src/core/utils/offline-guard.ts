@@ -0,0 +1,75 @@
+import * as net from 'net';
+import { VibeFlowError } from './error-utils.js';
+
+/**
+ * A code path tried to reach the network while --offline was set
+ */
+export class OfflineNetworkError extends VibeFlowError {
+  constructor(public readonly target: string) {
+    super(`Network access attempted in offline mode: ${target}`, 'OFFLINE_NETWORK', { target });
+    this.name = 'OfflineNetworkError';
+  }
+}
+
+let restoreNetwork: (() => void) | null = null;
+
+export function isOffline(): boolean {
+  return restoreNetwork !== null;
+}
+
+/**
+ * Throw OfflineNetworkError when offline; call before anything that needs the network
+ */
+export function assertOnline(target: string): void {
+  if (isOffline()) throw new OfflineNetworkError(target);
+}
+
+/**
+ * Make every TCP connection and fetch() throw OfflineNetworkError instead of
+ * silently reaching the network. Local IPC sockets keep working, and child
+ * `go` commands get GOPROXY=off so they fail rather than download modules.
+ *
+ * @returns function restoring the previous behavior
+ */
+export function enforceOffline(): () => void {
+  if (restoreNetwork) return restoreNetwork;
+
+  const originalConnect = net.Socket.prototype.connect;
+  const originalFetch = globalThis.fetch;
+  const originalGoProxy = process.env.GOPROXY;
+
+  net.Socket.prototype.connect = function (this: net.Socket, ...args: unknown[]) {
+    const target = networkTarget(args);
+    if (target !== null) throw new OfflineNetworkError(target);
+    return (originalConnect as (...a: unknown[]) => net.Socket).apply(this, args);
+  } as typeof originalConnect;
+  if (originalFetch) {
+    globalThis.fetch = (async (input: Parameters<typeof fetch>[0]) => {
+      throw new OfflineNetworkError(input instanceof Request ? input.url : String(input));
+    }) as typeof fetch;
+  }
+  process.env.GOPROXY = 'off';
+
+  restoreNetwork = () => {
+    net.Socket.prototype.connect = originalConnect;
+    if (originalFetch) globalThis.fetch = originalFetch;
+    if (originalGoProxy === undefined) delete process.env.GOPROXY;
+    else process.env.GOPROXY = originalGoProxy;
+    restoreNetwork = null;
+  };
+  return restoreNetwork;
+}
+
+/**
+ * host:port of a Socket#connect call, or null for a local IPC path
+ */
+function networkTarget(args: unknown[]): string | null {
+  const [first, second] = Array.isArray(args[0]) ? args[0] as unknown[] : args;
+  if (first && typeof first === 'object') {
+    const options = first as { path?: string | null; host?: string; port?: number | string };
+    if (options.path) return null;
+    return `${options.host ?? 'localhost'}:${options.port ?? ''}`;
+  }
+  if (typeof first === 'string' && !/^\d+$/.test(first)) return null;
+  return `${typeof second === 'string' ? second : 'localhost'}:${first}`;
+}
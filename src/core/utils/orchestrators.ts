@@ -0,0 +1,136 @@
+import { OrchestratorCandidate } from '../types/config.js';
+import { CallEdge } from './call-graph.js';
+import { TableAccess, ownsTable } from './table-ownership.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** A function reaching into this many other boundaries is an orchestrator candidate */
+export const DEFAULT_MIN_TOUCHED_MODULES = 2;
+
+interface Touch {
+  calls: number;
+  tables: Set<string>;
+  writes: boolean;
+}
+
+/**
+ * Functions that orchestrate several boundaries (transaction scripts such as a
+ * ProcessOrder checking the user, reserving inventory and charging the payment):
+ * they call into, or access tables owned by, minModules or more boundaries besides
+ * their own. Assigning them to one module hides the coordination, so they are listed
+ * instead. A function writing the data of two or more boundaries (itself or through
+ * the functions it calls) is a saga: splitting the modules loses its transaction.
+ */
+export function findOrchestrators(
+  edges: CallEdge[],
+  access: TableAccess[],
+  boundaries: { name: string; files: string[] }[],
+  options: { minModules?: number } = {}
+): OrchestratorCandidate[] {
+  const minModules = options.minModules ?? DEFAULT_MIN_TOUCHED_MODULES;
+  const ownerOf = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files.map(toPosixPath)) {
+      if (!ownerOf.has(file)) ownerOf.set(file, boundary.name);
+    }
+  }
+
+  // Modules mapping or writing each table, and the tables each function writes
+  const tableOwners = new Map<string, Set<string>>();
+  const writes = new Map<string, Set<string>>();
+  const queries = new Map<string, TableAccess[]>();
+  for (const entry of access) {
+    const file = toPosixPath(entry.file);
+    const module = ownerOf.get(file);
+    if (module && ownsTable(entry)) tableOwners.set(entry.table, (tableOwners.get(entry.table) ?? new Set()).add(module));
+    if (entry.via !== 'query') continue;
+    const key = functionKey(file, entry.symbol);
+    queries.set(key, [...(queries.get(key) ?? []), entry]);
+    if (ownsTable(entry)) writes.set(key, (writes.get(key) ?? new Set()).add(entry.table));
+  }
+
+  const functions = new Map<string, { file: string; name: string; type?: string; calls: CallEdge[] }>();
+  const fn = (file: string, name: string, type?: string) => {
+    const key = functionKey(file, name);
+    const existing = functions.get(key) ?? { file, name, calls: [] };
+    if (type && !existing.type) existing.type = type;
+    functions.set(key, existing);
+    return existing;
+  };
+  for (const edge of edges) fn(toPosixPath(edge.caller_file), edge.caller, edge.caller_type).calls.push(edge);
+  for (const entry of access.filter(entry => entry.via === 'query')) fn(toPosixPath(entry.file), entry.symbol);
+
+  const candidates: OrchestratorCandidate[] = [];
+  for (const [key, { file, name, type, calls }] of functions) {
+    const module = ownerOf.get(file);
+    const touches = new Map<string, Touch>();
+    const touch = (other: string) => {
+      const existing = touches.get(other) ?? { calls: 0, tables: new Set<string>(), writes: false };
+      touches.set(other, existing);
+      return existing;
+    };
+
+    for (const call of calls) {
+      const callee = ownerOf.get(toPosixPath(call.callee_file));
+      if (!callee || callee === module) continue;
+      const target = touch(callee);
+      target.calls += call.count;
+      if (writes.has(functionKey(toPosixPath(call.callee_file), call.callee))) target.writes = true;
+    }
+    for (const entry of queries.get(key) ?? []) {
+      for (const owner of tableOwners.get(entry.table) ?? []) {
+        if (owner === module) continue;
+        const target = touch(owner);
+        target.tables.add(entry.table);
+        if (ownsTable(entry)) target.writes = true;
+      }
+    }
+    if (touches.size < minModules) continue;
+
+    // Its own writes count for its own module
+    const written = [...touches.values()].filter(t => t.writes).length + (writes.has(key) ? 1 : 0);
+    candidates.push({
+      function: type ? `${type}.${name}` : name,
+      file,
+      ...(module ? { module } : {}),
+      kind: written >= 2 ? 'saga' : 'orchestrator',
+      touches: [...touches.entries()]
+        .map(([other, t]) => ({ module: other, calls: t.calls, tables: [...t.tables].sort(), writes: t.writes }))
+        .sort((a, b) => a.module.localeCompare(b.module)),
+    });
+  }
+
+  return candidates.sort((a, b) => b.touches.length - a.touches.length || a.file.localeCompare(b.file) || a.function.localeCompare(b.function));
+}
+
+/**
+ * plan.md section listing the candidates with what each reaches into
+ */
+export function renderOrchestratorsSection(candidates: OrchestratorCandidate[] = []): string {
+  if (candidates.length === 0) return '';
+
+  const entries = candidates.map(candidate => {
+    const touches = candidate.touches.map(t => {
+      const parts = [
+        ...(t.calls > 0 ? [`呼び出し${t.calls}箇所`] : []),
+        ...(t.tables.length > 0 ? [`テーブル ${t.tables.join(', ')}`] : []),
+      ];
+      return `  - ${t.module}: ${parts.join('、')}${t.writes ? '（書き込みあり）' : ''}`;
+    });
+    const remedy = candidate.kind === 'saga'
+      ? '複数モジュールのデータを更新するため、補償処理を持つサーガとして設計'
+      : 'アプリケーションサービス（オーケストレーター）として切り出し、各モジュールの公開 API だけを呼ぶ';
+    return [`- **${candidate.function}** (${candidate.file}${candidate.module ? `、現在は ${candidate.module}` : ''}): ${remedy}`, ...touches].join('\n');
+  });
+
+  return `
+## サーガ・オーケストレーター候補
+
+以下の関数は複数のモジュールにまたがる処理をまとめています。どれか1つのモジュールに割り当てず、明示的に扱ってください。
+
+${entries.join('\n')}
+`;
+}
+
+function functionKey(file: string, name: string): string {
+  return `${file}\n${name}`;
+}
@@ -0,0 +1,71 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { GoPackage, loadGoPackages } from './go-packages.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/**
+ * Directory a scoped discovery analyzes (vf discover --path / --package)
+ */
+export interface PathFilter {
+  /** Directory relative to the project root ('.' for the root) */
+  dir: string;
+  /** Go `/...` pattern: the directory and every directory below it */
+  recursive: boolean;
+}
+
+/**
+ * Filter of a directory pattern as go list takes it: ./internal/billing/... for the
+ * subtree, ./internal/billing for that package directory only
+ */
+export function parsePathPattern(projectRoot: string, pattern: string): PathFilter {
+  const recursive = pattern === '...' || pattern.endsWith('/...');
+  const dir = toPosixPath(path.relative(projectRoot, path.resolve(projectRoot, recursive ? pattern.slice(0, -3) || '.' : pattern))) || '.';
+  if (dir === '..' || dir.startsWith('../') || path.isAbsolute(dir)) {
+    throw new Error(`--path ${pattern} is outside the project`);
+  }
+  if (!fs.statSync(path.join(projectRoot, dir), { throwIfNoEntry: false })?.isDirectory()) {
+    throw new Error(`--path ${pattern}: no such directory`);
+  }
+  return { dir, recursive };
+}
+
+/**
+ * Package directories an import path pattern (example.com/app/internal/billing/...) matches
+ */
+export function parsePackagePattern(pattern: string, packages: GoPackage[]): PathFilter[] {
+  const recursive = pattern.endsWith('/...');
+  const prefix = recursive ? pattern.slice(0, -4) : pattern;
+  const matched = packages.filter(pkg => pkg.import_path === prefix || (recursive && pkg.import_path.startsWith(`${prefix}/`)));
+  if (matched.length === 0) {
+    throw new Error(`--package ${pattern} matches no package of the project`);
+  }
+  return matched.map(pkg => ({ dir: pkg.dir, recursive: false }));
+}
+
+/**
+ * Filters of the --path and --package patterns; empty when neither is given
+ */
+export function resolvePathFilters(projectRoot: string, patterns: { paths?: string[]; packages?: string[] }): PathFilter[] {
+  const filters = (patterns.paths ?? []).map(pattern => parsePathPattern(projectRoot, pattern));
+  if (patterns.packages?.length) {
+    const packages = loadGoPackages(projectRoot);
+    filters.push(...patterns.packages.flatMap(pattern => parsePackagePattern(pattern, packages)));
+  }
+  return filters;
+}
+
+export function matchesPathFilters(file: string, filters: PathFilter[]): boolean {
+  const dir = path.posix.dirname(toPosixPath(file));
+  return filters.some(filter => {
+    if (dir === filter.dir) return true;
+    if (!filter.recursive) return false;
+    return filter.dir === '.' || dir.startsWith(`${filter.dir}/`);
+  });
+}
+
+export function describePathFilters(filters: PathFilter[]): string {
+  return filters.map(filter => {
+    const dir = filter.dir === '.' ? '.' : `./${filter.dir}`;
+    return filter.recursive ? `${dir}/...` : dir;
+  }).join(', ');
+}
@@ -0,0 +1,700 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { WorkspaceLock } from './workspace-lock.js';
+import { renameWithRetry } from './file-io.js';
+import { UsageRecord } from './cost-manager.js';
+import { RunEnvironment } from './run-environment.js';
+
+/**
+ * Current schema version of .vibeflow/performance.json.
+ * Version 0 is the legacy usage-history.json written by CostManager.
+ * Version 2 adds module_status (per-boundary migration lifecycle).
+ * Version 3 adds llm_calls (per-attempt LLM usage by prompt template).
+ * Version 4 adds the agent, bytes, transformations, verification and template of file_processing.
+ */
+export const PERFORMANCE_SCHEMA_VERSION = 4;
+
+export type RunStatus = 'running' | 'success' | 'failed' | 'partial';
+/** template-fallback: generated from templates because the LLM was unavailable (not chosen) */
+export type ProcessingMethod = 'llm' | 'template' | 'template-fallback' | 'static';
+
+export const PROCESSING_METHODS: ProcessingMethod[] = ['llm', 'template', 'template-fallback', 'static'];
+
+export interface RunRecord {
+  run_id: number;
+  command: string;
+  status: RunStatus;
+  started_at: string;
+  finished_at?: string;
+  modules_planned: number;
+  modules_migrated: number;
+  files_processed: number;
+  loc: number;
+  input_tokens: number;
+  output_tokens: number;
+  cost: number;
+  model?: string;
+  error?: string;
+  /** Module being processed right now (running runs only) */
+  current_module?: string;
+  /** Modules another process asked to skip via `vf control skip-module` */
+  skip_requested?: string[];
+  /** Modules skipped during the run; resumable with `vf refactor --resume-skipped` */
+  skipped_modules?: string[];
+  /** Merge conflicts left by the run in manually edited outputs (see `vf resolve`) */
+  conflicts?: 'unresolved' | 'conflicts-resolved';
+  /** Result of the last `vf verify --run-id` */
+  verification?: { build: boolean; tests: boolean; verified_at: string };
+  /** Files retried with a stronger model (llm.escalation) */
+  escalations?: EscalationRecord[];
+  /** Toolchain, model, config and workspace the run started with (see run-environment.ts) */
+  environment?: RunEnvironment;
+  /** Async batch jobs of the run: pending until `vf refactor --collect` has applied every module */
+  batch?: 'pending' | 'collected';
+}
+
+/** over-budget: the retry was not attempted because it would exceed the cost limits */
+export type EscalationOutcome = 'success' | 'failed' | 'over-budget';
+
+export interface EscalationRecord {
+  module: string;
+  file: string;
+  /** Failure that triggered the retry (llm-malformed-response or verification-failure) */
+  category: string;
+  original_model: string;
+  escalated_model: string;
+  outcome: EscalationOutcome;
+  /** Estimated cost of the retry in USD (0 when it was not attempted) */
+  extra_cost: number;
+  error?: string;
+  recorded_at: string;
+}
+
+/**
+ * What processing did to a file: template-* are the outputs a template generated
+ */
+export type FileTransformation =
+  | 'moved'
+  | 'package-renamed'
+  | 'imports-rewritten'
+  | 'split'
+  | 'template-entity'
+  | 'template-repository'
+  | 'template-handler';
+
+/** not-run: the method has no verification (templates, static) or the file failed before it */
+export type FileVerification = 'passed' | 'failed' | 'not-run';
+
+/**
+ * Fields added in schema v4 are null on records written before it
+ */
+export interface FileProcessingRecord {
+  run_id: number;
+  file: string;
+  /** Stable boundary ID from domain-map.json (the name for boundaries without one) */
+  module: string;
+  /** Module name at the time of the run, for re-running it */
+  module_name?: string;
+  /** Class that processed the file, e.g. RefactorAgent */
+  agent?: string | null;
+  method: ProcessingMethod;
+  /** Why the LLM could not be used (template-fallback only) */
+  fallback_reason?: string;
+  status: 'success' | 'failed' | 'skipped';
+  duration_ms?: number;
+  /** Size of the legacy file and of everything generated from it */
+  bytes_in?: number | null;
+  bytes_out?: number | null;
+  transformations?: FileTransformation[] | null;
+  verification?: FileVerification | null;
+  /** Template the outputs were generated from, and the hash of its version */
+  template?: string | null;
+  template_hash?: string | null;
+  input_tokens?: number;
+  output_tokens?: number;
+  error?: string;
+  recorded_at: string;
+}
+
+export interface FileProcessingFilter {
+  runId?: number;
+  agent?: string;
+  method?: ProcessingMethod;
+}
+
+/** Columns added in schema v4, in export order */
+export const FILE_PROCESSING_V4_FIELDS = ['agent', 'bytes_in', 'bytes_out', 'transformations', 'verification', 'template', 'template_hash'] as const;
+
+/** Migration lifecycle of a boundary, in order (see module-status.ts) */
+export type ModuleStage = 'discovered' | 'planned' | 'refactored' | 'tests-relocated' | 'verified' | 'accepted';
+
+export interface ModuleStatusChange {
+  stage: ModuleStage;
+  run_id?: number;
+  /** Who made the change (accepted only) */
+  by?: string;
+  at: string;
+}
+
+export interface ModuleStatusRecord {
+  /** Stable boundary ID from domain-map.json (the name for boundaries without one) */
+  module: string;
+  module_name: string;
+  stage: ModuleStage;
+  /** Run that last changed the stage */
+  run_id?: number;
+  updated_at: string;
+  accepted_by?: string;
+  history: ModuleStatusChange[];
+}
+
+/** How an LLM attempt ended: parsed and verified, unparseable, rejected by verification, or any other error */
+export type LlmCallOutcome = 'success' | 'malformed' | 'verification-failed' | 'failed';
+
+export interface LlmCallRecord {
+  run_id: number;
+  /** What the prompt was for, e.g. refactor (file transformation) */
+  task: string;
+  /** Template name as in the run artifacts, e.g. prompts/refactor-transformation.txt */
+  template: string;
+  /** sha256 of the template text the prompt was rendered from */
+  template_hash: string;
+  /** Stable boundary ID (the name for boundaries without one) */
+  module: string;
+  module_name?: string;
+  file: string;
+  model?: string;
+  /** 1 for the first attempt at a file, 2 for the retry with the next model of llm.escalation */
+  attempt: number;
+  /** Estimated from the prompt and response text (chars / 4), summed over chunks */
+  input_tokens: number;
+  output_tokens: number;
+  cost: number;
+  outcome: LlmCallOutcome;
+  /** Served from .vibeflow/llm-cache without an API call */
+  cached?: boolean;
+  /** Answered by an async batch job (vf refactor --async-batch); cost has the discount applied */
+  batch_job?: string;
+  batch_discount?: number;
+  recorded_at: string;
+}
+
+export interface PerformanceMetricRecord {
+  run_id: number;
+  metric: string;
+  value: number;
+  labels?: Record<string, string>;
+  recorded_at: string;
+}
+
+export interface PerformanceData {
+  schema_version: number;
+  runs: RunRecord[];
+  file_processing: FileProcessingRecord[];
+  performance_metrics: PerformanceMetricRecord[];
+  module_status: ModuleStatusRecord[];
+  llm_calls: LlmCallRecord[];
+}
+
+export interface LoadedPerformanceData {
+  data: PerformanceData;
+  /** Schema version found on disk before migration */
+  sourceVersion: number;
+  migrated: boolean;
+}
+
+/**
+ * PerformanceStore - 実行メトリクスの永続化ストア
+ *
+ * Persists run records, per-file processing records and named metrics in
+ * .vibeflow/performance.json. Readers always go through migratePerformanceData()
+ * so artifacts written by older releases keep working.
+ */
+export class PerformanceStore {
+  private projectRoot: string;
+  private storePath: string;
+  private readOnly: boolean;
+  private data: PerformanceData | null = null;
+  /** file_processing by agent and method, built on the first query of the loaded data */
+  private fileIndex: Map<string, FileProcessingRecord[]> | null = null;
+  private lock: WorkspaceLock;
+
+  constructor(projectRoot: string, options: { readOnly?: boolean } = {}) {
+    this.projectRoot = projectRoot;
+    this.storePath = PerformanceStore.storePath(projectRoot);
+    this.readOnly = options.readOnly ?? false;
+    this.lock = new WorkspaceLock(projectRoot, 'performance.lock');
+  }
+
+  static storePath(projectRoot: string): string {
+    return path.join(projectRoot, '.vibeflow', 'performance.json');
+  }
+
+  static legacyUsagePath(projectRoot: string): string {
+    return path.join(projectRoot, '.vibeflow', 'usage-history.json');
+  }
+
+  /**
+   * Whether another process is currently writing the store
+   */
+  isLocked(): boolean {
+    return this.lock.isHeldByOtherProcess();
+  }
+
+  /**
+   * Load (and migrate in memory) the store contents
+   */
+  loadWithInfo(): LoadedPerformanceData {
+    this.fileIndex = null;
+    if (fs.existsSync(this.storePath)) {
+      const raw = JSON.parse(fs.readFileSync(this.storePath, 'utf8'));
+      const sourceVersion = typeof raw?.schema_version === 'number' ? raw.schema_version : 0;
+      const data = migratePerformanceData(raw);
+      this.data = data;
+      return { data, sourceVersion, migrated: sourceVersion !== PERFORMANCE_SCHEMA_VERSION };
+    }
+
+    const legacyPath = PerformanceStore.legacyUsagePath(this.projectRoot);
+    if (fs.existsSync(legacyPath)) {
+      const raw = JSON.parse(fs.readFileSync(legacyPath, 'utf8'));
+      const data = migratePerformanceData(raw);
+      this.data = data;
+      return { data, sourceVersion: 0, migrated: true };
+    }
+
+    this.data = createEmptyPerformanceData();
+    return { data: this.data, sourceVersion: PERFORMANCE_SCHEMA_VERSION, migrated: false };
+  }
+
+  load(): PerformanceData {
+    return this.loadWithInfo().data;
+  }
+
+  /**
+   * Start a new run and return its run id
+   */
+  startRun(command: string, init: Partial<Omit<RunRecord, 'run_id' | 'command'>> = {}): number {
+    let runId = 0;
+
+    this.mutate(data => {
+      runId = data.runs.reduce((max, r) => Math.max(max, r.run_id), 0) + 1;
+      data.runs.push({
+        run_id: runId,
+        command,
+        status: 'running',
+        started_at: new Date().toISOString(),
+        modules_planned: 0,
+        modules_migrated: 0,
+        files_processed: 0,
+        loc: 0,
+        input_tokens: 0,
+        output_tokens: 0,
+        cost: 0,
+        ...init,
+      });
+    });
+
+    return runId;
+  }
+
+  /**
+   * Update a run record, finishing it when a terminal status is given
+   */
+  finishRun(runId: number, update: Partial<Omit<RunRecord, 'run_id'>>): void {
+    this.mutate(data => {
+      const run = data.runs.find(r => r.run_id === runId);
+      if (!run) {
+        throw new Error(`Run ${runId} not found in ${this.storePath}`);
+      }
+
+      Object.assign(run, update);
+      if (run.status !== 'running' && !run.finished_at) {
+        run.finished_at = new Date().toISOString();
+      }
+    });
+  }
+
+  /**
+   * Add token/cost usage to a running run
+   */
+  addUsage(runId: number, usage: { inputTokens?: number; outputTokens?: number; cost?: number }): void {
+    this.mutate(data => {
+      const run = data.runs.find(r => r.run_id === runId);
+      if (!run) return;
+
+      run.input_tokens += usage.inputTokens ?? 0;
+      run.output_tokens += usage.outputTokens ?? 0;
+      run.cost += usage.cost ?? 0;
+    });
+  }
+
+  recordFileProcessing(record: Omit<FileProcessingRecord, 'recorded_at'>): void {
+    this.mutate(data => {
+      data.file_processing.push({ ...record, recorded_at: new Date().toISOString() });
+    });
+  }
+
+  recordMetric(runId: number, metric: string, value: number, labels?: Record<string, string>): void {
+    this.mutate(data => {
+      data.performance_metrics.push({
+        run_id: runId,
+        metric,
+        value,
+        labels,
+        recorded_at: new Date().toISOString(),
+      });
+    });
+  }
+
+  recordLlmCall(record: Omit<LlmCallRecord, 'recorded_at'>): void {
+    this.mutate(data => {
+      data.llm_calls.push({ ...record, recorded_at: new Date().toISOString() });
+    });
+  }
+
+  /**
+   * Replace the status of a module with the result of update (undefined keeps it unchanged)
+   *
+   * @returns the stored record
+   */
+  updateModuleStatus(
+    module: string,
+    update: (current: ModuleStatusRecord | undefined) => ModuleStatusRecord | undefined
+  ): ModuleStatusRecord | undefined {
+    let result: ModuleStatusRecord | undefined;
+
+    this.mutate(data => {
+      const index = data.module_status.findIndex(r => r.module === module);
+      const current = index >= 0 ? data.module_status[index] : undefined;
+      result = update(current) ?? current;
+      if (!result || result === current) return;
+      if (index >= 0) data.module_status[index] = result;
+      else data.module_status.push(result);
+    });
+
+    return result;
+  }
+
+  /**
+   * Append an escalation to a run and add its extra cost to the run total
+   */
+  recordEscalation(runId: number, record: Omit<EscalationRecord, 'recorded_at'>): void {
+    this.mutate(data => {
+      const run = data.runs.find(r => r.run_id === runId);
+      if (!run) return;
+
+      run.escalations = [...(run.escalations ?? []), { ...record, recorded_at: new Date().toISOString() }];
+      run.cost += record.extra_cost;
+    });
+  }
+
+  setCurrentModule(runId: number, moduleName: string | undefined): void {
+    this.mutate(data => {
+      const run = data.runs.find(r => r.run_id === runId);
+      if (run) run.current_module = moduleName;
+    });
+  }
+
+  /**
+   * Ask a running run to skip the module it is currently processing
+   *
+   * @returns the module that will be skipped
+   */
+  requestSkip(runId: number): string {
+    let moduleName = '';
+
+    this.mutate(data => {
+      const run = data.runs.find(r => r.run_id === runId);
+      if (!run) {
+        throw new Error(`Run ${runId} not found in ${this.storePath}`);
+      }
+      if (run.status !== 'running' || !run.current_module) {
+        throw new Error(`Run ${runId} is not processing a module`);
+      }
+
+      moduleName = run.current_module;
+      run.skip_requested = [...new Set([...(run.skip_requested ?? []), moduleName])];
+    });
+
+    return moduleName;
+  }
+
+  isSkipRequested(runId: number, moduleName: string): boolean {
+    const run = this.load().runs.find(r => r.run_id === runId);
+    return run?.skip_requested?.includes(moduleName) ?? false;
+  }
+
+  markModuleSkipped(runId: number, moduleName: string): void {
+    this.mutate(data => {
+      const run = data.runs.find(r => r.run_id === runId);
+      if (!run) return;
+
+      run.skipped_modules = [...new Set([...(run.skipped_modules ?? []), moduleName])];
+      run.skip_requested = (run.skip_requested ?? []).filter(m => m !== moduleName);
+    });
+  }
+
+  /**
+   * Keep only the most recent finished runs (running runs are never dropped)
+   *
+   * @returns ids of the removed runs
+   */
+  pruneRuns(retainRuns: number): number[] {
+    let removed: number[] = [];
+
+    this.mutate(data => {
+      const finished = data.runs
+        .filter(r => r.status !== 'running')
+        .sort((a, b) => b.run_id - a.run_id);
+      removed = finished.slice(retainRuns).map(r => r.run_id);
+      if (removed.length === 0) return;
+
+      data.runs = data.runs.filter(r => !removed.includes(r.run_id));
+      data.file_processing = data.file_processing.filter(r => !removed.includes(r.run_id));
+      data.performance_metrics = data.performance_metrics.filter(r => !removed.includes(r.run_id));
+      data.llm_calls = data.llm_calls.filter(r => !removed.includes(r.run_id));
+    });
+
+    return removed;
+  }
+
+  /**
+   * Id of the most recent run that has not finished yet
+   */
+  getActiveRunId(): number | undefined {
+    const active = this.load().runs.filter(r => r.status === 'running');
+    return active.length > 0 ? active[active.length - 1].run_id : undefined;
+  }
+
+  getRun(runId: number): RunRecord | undefined {
+    return this.ensureLoaded().runs.find(r => r.run_id === runId);
+  }
+
+  getRuns(): RunRecord[] {
+    return [...this.ensureLoaded().runs];
+  }
+
+  getFileProcessing(runId?: number): FileProcessingRecord[] {
+    const records = this.ensureLoaded().file_processing;
+    return runId === undefined ? [...records] : records.filter(r => r.run_id === runId);
+  }
+
+  /**
+   * file_processing records of one agent and/or method (and run); answered
+   * from an index so large stores are not scanned per query
+   */
+  queryFileProcessing(filter: FileProcessingFilter = {}): FileProcessingRecord[] {
+    const data = this.ensureLoaded();
+    if (!this.fileIndex) {
+      this.fileIndex = new Map();
+      for (const record of data.file_processing) {
+        for (const key of fileIndexKeys(record.agent ?? undefined, record.method)) {
+          const records = this.fileIndex.get(key) ?? [];
+          records.push(record);
+          this.fileIndex.set(key, records);
+        }
+      }
+    }
+
+    const records = this.fileIndex.get(fileIndexKey(filter.agent, filter.method)) ?? [];
+    return filter.runId === undefined ? [...records] : records.filter(r => r.run_id === filter.runId);
+  }
+
+  getMetrics(runId?: number): PerformanceMetricRecord[] {
+    const records = this.ensureLoaded().performance_metrics;
+    return runId === undefined ? [...records] : records.filter(r => r.run_id === runId);
+  }
+
+  getLlmCalls(runId?: number): LlmCallRecord[] {
+    const records = this.ensureLoaded().llm_calls;
+    return runId === undefined ? [...records] : records.filter(r => r.run_id === runId);
+  }
+
+  getModuleStatuses(): ModuleStatusRecord[] {
+    return [...this.ensureLoaded().module_status];
+  }
+
+  private ensureLoaded(): PerformanceData {
+    return this.data ?? this.load();
+  }
+
+  /**
+   * Apply a change against the latest on-disk contents under the store lock,
+   * so concurrent writers (CLI and agents) do not overwrite each other
+   */
+  private mutate(change: (data: PerformanceData) => void): void {
+    if (this.readOnly) {
+      throw new Error('PerformanceStore was opened read-only');
+    }
+
+    if (!this.lock.acquire('performance-store')) {
+      throw new Error(`Performance store is locked by another process: ${this.lock.path}`);
+    }
+
+    try {
+      const data = this.load();
+      change(data);
+      fs.mkdirSync(path.dirname(this.storePath), { recursive: true });
+      const tmpPath = `${this.storePath}.${process.pid}.tmp`;
+      fs.writeFileSync(tmpPath, JSON.stringify(data, null, 2));
+      renameWithRetry(tmpPath, this.storePath);
+    } finally {
+      this.lock.release();
+    }
+  }
+}
+
+export function createEmptyPerformanceData(): PerformanceData {
+  return {
+    schema_version: PERFORMANCE_SCHEMA_VERSION,
+    runs: [],
+    file_processing: [],
+    performance_metrics: [],
+    module_status: [],
+    llm_calls: [],
+  };
+}
+
+/**
+ * Upgrade any known on-disk representation to the current schema
+ */
+export function migratePerformanceData(raw: any): PerformanceData {
+  // v0: CostManager usage-history.json (bare array of usage records)
+  if (Array.isArray(raw)) {
+    return migrateLegacyUsage(raw as UsageRecord[]);
+  }
+
+  if (!raw || typeof raw !== 'object') {
+    return createEmptyPerformanceData();
+  }
+
+  const version = typeof raw.schema_version === 'number' ? raw.schema_version : 0;
+  if (version > PERFORMANCE_SCHEMA_VERSION) {
+    throw new Error(
+      `performance.json schema v${version} is newer than supported v${PERFORMANCE_SCHEMA_VERSION}. Please upgrade vibeflow.`
+    );
+  }
+
+  // Entries that are not run objects carry nothing to report and are dropped; runs without
+  // an ID are numbered after the highest written one so they cannot collide with it
+  const entries: any[] = Array.isArray(raw.runs)
+    ? raw.runs.filter((r: unknown) => r !== null && typeof r === 'object' && !Array.isArray(r))
+    : [];
+  let nextId = entries.reduce((max, r) => typeof r.run_id === 'number' ? Math.max(max, r.run_id) : max, 0);
+  const runs: RunRecord[] = entries.map(r => normalizeRun(r, typeof r.run_id === 'number' ? r.run_id : ++nextId));
+
+  return {
+    schema_version: PERFORMANCE_SCHEMA_VERSION,
+    runs,
+    // v3 → v4: the new columns were not recorded
+    file_processing: Array.isArray(raw.file_processing)
+      ? raw.file_processing.map((r: any) => (version < 4 ? { ...nullFileFields(), ...r } : r))
+      : [],
+    performance_metrics: Array.isArray(raw.performance_metrics) ? raw.performance_metrics : [],
+    // v1 → v2: no module lifecycle recorded yet
+    module_status: Array.isArray(raw.module_status) ? raw.module_status : [],
+    // v2 → v3: LLM calls were not recorded
+    llm_calls: Array.isArray(raw.llm_calls) ? raw.llm_calls : [],
+  };
+}
+
+function nullFileFields(): Record<typeof FILE_PROCESSING_V4_FIELDS[number], null> {
+  return Object.fromEntries(FILE_PROCESSING_V4_FIELDS.map(field => [field, null])) as Record<typeof FILE_PROCESSING_V4_FIELDS[number], null>;
+}
+
+function fileIndexKey(agent?: string, method?: ProcessingMethod): string {
+  return `${agent ?? '*'}\u0000${method ?? '*'}`;
+}
+
+/**
+ * Index entries of a record: exact, any agent, any method and any of both
+ */
+function fileIndexKeys(agent: string | undefined, method: ProcessingMethod): string[] {
+  return [
+    ...(agent !== undefined ? [fileIndexKey(agent, method), fileIndexKey(agent)] : []),
+    fileIndexKey(undefined, method),
+    fileIndexKey(),
+  ];
+}
+
+function migrateLegacyUsage(usage: UsageRecord[]): PerformanceData {
+  const data = createEmptyPerformanceData();
+
+  usage.forEach((record, index) => {
+    data.runs.push(normalizeRun({
+      command: record.operation,
+      status: 'success',
+      started_at: record.timestamp,
+      finished_at: record.timestamp,
+      input_tokens: record.tokens,
+      cost: record.cost,
+    }, index + 1));
+  });
+
+  return data;
+}
+
+function normalizeRun(raw: any, fallbackId: number): RunRecord {
+  return {
+    // Optional fields (skips, conflicts, verification, ...) are kept as written
+    ...(raw && typeof raw === 'object' ? raw : {}),
+    run_id: typeof raw?.run_id === 'number' ? raw.run_id : fallbackId,
+    command: String(raw?.command ?? 'unknown'),
+    status: (['running', 'success', 'failed', 'partial'].includes(raw?.status) ? raw.status : 'success') as RunStatus,
+    started_at: String(raw?.started_at ?? new Date(0).toISOString()),
+    finished_at: raw?.finished_at,
+    modules_planned: Number(raw?.modules_planned ?? 0),
+    modules_migrated: Number(raw?.modules_migrated ?? 0),
+    files_processed: Number(raw?.files_processed ?? 0),
+    loc: Number(raw?.loc ?? 0),
+    input_tokens: Number(raw?.input_tokens ?? 0),
+    output_tokens: Number(raw?.output_tokens ?? 0),
+    cost: Number(raw?.cost ?? 0),
+    model: raw?.model,
+    error: raw?.error,
+  };
+}
+
+/**
+ * Count lines of code for the given workspace-relative or absolute files
+ */
+export function countLinesOfCode(projectRoot: string, files: string[]): number {
+  let total = 0;
+
+  for (const file of files) {
+    const fullPath = path.isAbsolute(file) ? file : path.join(projectRoot, file);
+    try {
+      total += fs.readFileSync(fullPath, 'utf8').split('\n').length;
+    } catch {
+      // Missing files do not contribute
+    }
+  }
+
+  return total;
+}
+
+export interface RunFallbacks {
+  run_id: number;
+  command: string;
+  started_at: string;
+  modules: { module: string; module_name?: string; files: number; reasons: string[] }[];
+}
+
+/**
+ * Runs in which files were generated from templates because the LLM was unavailable
+ */
+export function findFallbackRuns(store: PerformanceStore): RunFallbacks[] {
+  const byRun = new Map<number, Map<string, RunFallbacks['modules'][number]>>();
+  for (const record of store.getFileProcessing().filter(r => r.method === 'template-fallback')) {
+    const modules = byRun.get(record.run_id) ?? new Map();
+    const entry = modules.get(record.module) ?? { module: record.module, ...(record.module_name ? { module_name: record.module_name } : {}), files: 0, reasons: [] };
+    entry.files++;
+    if (record.fallback_reason && !entry.reasons.includes(record.fallback_reason)) entry.reasons.push(record.fallback_reason);
+    modules.set(record.module, entry);
+    byRun.set(record.run_id, modules);
+  }
+
+  return store.getRuns()
+    .filter(run => byRun.has(run.run_id))
+    .map(run => ({ run_id: run.run_id, command: run.command, started_at: run.started_at, modules: [...byRun.get(run.run_id)!.values()] }));
+}
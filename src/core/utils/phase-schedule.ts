@@ -0,0 +1,377 @@
+import { ScheduleConfig } from '../types/config.js';
+
+/** Estimated effort days completed per calendar week when schedule.velocity is not set */
+export const DEFAULT_VELOCITY = 5;
+
+export const DEFAULT_SPRINT_WEEKS = 2;
+
+export const SCHEDULE_HEADING = '## スケジュール (Timeline)';
+
+export type ScheduleConstraint = 'deadline' | 'max-phase-effort' | 'team-availability';
+
+export interface ScheduleInput {
+  name: string;
+  effort_days: number;
+  /** Modules that have to be migrated first */
+  depends_on: string[];
+  team?: string;
+}
+
+export interface ScheduledModule {
+  module: string;
+  effort_days: number;
+  team?: string;
+  deadline?: string;
+  /** Accepted modules keep the dates of the plan they were accepted under */
+  status: 'scheduled' | 'accepted';
+  phase?: string;
+  start?: string;
+  end?: string;
+}
+
+export interface ScheduledPhase {
+  name: string;
+  modules: string[];
+  effort_days: number;
+  team?: string;
+  start: string;
+  /** Day after the last working day */
+  end: string;
+  /** Every module is accepted; re-planning keeps the phase as it is */
+  fixed: boolean;
+}
+
+export interface UnsatisfiedConstraint {
+  constraint: ScheduleConstraint;
+  module: string;
+  /** Deadline or end of the team's availability, or the phase effort limit in days */
+  limit: string;
+  actual: string;
+  /** Calendar days late, or effort days over the limit */
+  over_by_days: number;
+  message: string;
+}
+
+/**
+ * Dated phases of the migration plan, as recorded in plan.json
+ */
+export interface PlanSchedule {
+  start: string;
+  end: string;
+  velocity: number;
+  sprint_weeks: number;
+  max_phase_effort_days?: number;
+  phases: ScheduledPhase[];
+  modules: ScheduledModule[];
+  unsatisfiable: UnsatisfiedConstraint[];
+}
+
+const EFFORT_UNIT_DAYS: Record<string, (velocity: number, sprintWeeks: number) => number> = {
+  day: () => 1,
+  日: () => 1,
+  week: velocity => velocity,
+  週間: velocity => velocity,
+  週: velocity => velocity,
+  sprint: (velocity, sprintWeeks) => velocity * sprintWeeks,
+  スプリント: (velocity, sprintWeeks) => velocity * sprintWeeks,
+};
+
+/**
+ * Effort days of an estimate such as "1-2週間", "3-5日" or "2-sprints"; ranges count
+ * as their midpoint and unknown formats as 0
+ */
+export function estimateEffortDays(estimate: string, options: { velocity?: number; sprintWeeks?: number } = {}): number {
+  const match = estimate.trim().match(/^(\d+(?:\.\d+)?)(?:\s*[-~〜]\s*(\d+(?:\.\d+)?))?[-\s]*([a-z]+?|日|週間|週|スプリント)s?$/i);
+  const unit = match && EFFORT_UNIT_DAYS[match[3].toLowerCase()];
+  if (!match || !unit) return 0;
+
+  const low = Number(match[1]);
+  const high = match[2] !== undefined ? Number(match[2]) : low;
+  return ((low + high) / 2) * unit(options.velocity ?? DEFAULT_VELOCITY, options.sprintWeeks ?? DEFAULT_SPRINT_WEEKS);
+}
+
+/**
+ * Config for the plan: boundary.yaml fields win over vibeflow.config.yaml, map fields are merged
+ */
+export function mergeScheduleConfig(config?: ScheduleConfig, boundary?: ScheduleConfig): ScheduleConfig | undefined {
+  if (!config && !boundary) return undefined;
+  return {
+    ...config,
+    ...boundary,
+    deadlines: { ...config?.deadlines, ...boundary?.deadlines },
+    teamAvailability: { ...config?.teamAvailability, ...boundary?.teamAvailability },
+    teams: { ...config?.teams, ...boundary?.teams },
+  };
+}
+
+/**
+ * Group modules into dated phases. Modules follow their dependencies, and among
+ * those that are ready the one with the earliest deadline (including deadlines
+ * inherited from modules that depend on it) goes first. A phase holds modules of
+ * one team up to schedule.maxPhaseEffort and ends with a module that has a deadline.
+ * Phases run one after another at `velocity` effort days per week, inside the team's
+ * availability. Constraints that cannot be met are reported with how far they are missed.
+ *
+ * When re-planning, phases of the previous schedule whose modules are all accepted
+ * stay as they were and only the remaining modules are scheduled, after them.
+ */
+export function schedulePhases(
+  modules: ScheduleInput[],
+  config: ScheduleConfig,
+  options: { today: string; accepted?: Set<string>; previous?: PlanSchedule }
+): PlanSchedule {
+  const velocity = config.velocity ?? DEFAULT_VELOCITY;
+  const sprintWeeks = config.sprintWeeks ?? DEFAULT_SPRINT_WEEKS;
+  const maxEffort = config.maxPhaseEffort ? estimateEffortDays(config.maxPhaseEffort, { velocity, sprintWeeks }) : undefined;
+  const deadlines = config.deadlines ?? {};
+  const windows = new Map(Object.entries(config.teamAvailability ?? {}).map(([team, value]) => [team, teamWindow(value)]));
+  const accepted = options.accepted ?? new Set<string>();
+  const start = config.start ?? options.today;
+
+  const fixed = (options.previous?.phases ?? [])
+    .filter(phase => phase.modules.length > 0 && phase.modules.every(name => accepted.has(name)))
+    .map(phase => ({ ...phase, fixed: true }));
+  const done = new Set([...fixed.flatMap(phase => phase.modules), ...accepted]);
+  const previousModules = new Map((options.previous?.modules ?? []).map(module => [module.module, module]));
+  const scheduled: ScheduledModule[] = modules.filter(module => done.has(module.name)).map(module => ({
+    ...previousModules.get(module.name),
+    module: module.name,
+    effort_days: module.effort_days,
+    ...(module.team ? { team: module.team } : {}),
+    status: 'accepted',
+  }));
+
+  const remaining = modules.filter(module => !done.has(module.name));
+  const durations = new Map(remaining.map(module => [module.name, durationDays(module.effort_days, velocity)]));
+  const due = inheritedDeadlines(remaining, deadlines, durations);
+  const ordered = dependencyOrder(remaining, module => [due.get(module.name) ?? '~', windows.get(module.team ?? '')?.from ?? '']);
+
+  const groups: ScheduleInput[][] = [];
+  let group: ScheduleInput[] = [];
+  for (const module of ordered) {
+    const effort = group.reduce((sum, m) => sum + m.effort_days, 0) + module.effort_days;
+    if (group.length > 0 && (group[0].team !== module.team || (maxEffort !== undefined && effort > maxEffort))) {
+      groups.push(group);
+      group = [];
+    }
+    group.push(module);
+    if (deadlines[module.name]) {
+      groups.push(group);
+      group = [];
+    }
+  }
+  if (group.length > 0) groups.push(group);
+
+  const phases: ScheduledPhase[] = [...fixed];
+  const unsatisfiable: UnsatisfiedConstraint[] = [];
+  let cursor = [start, ...fixed.map(phase => phase.end)].sort().pop()!;
+
+  for (const members of groups) {
+    const team = members[0].team;
+    const window = team ? windows.get(team) : undefined;
+    const name = members.map(module => module.name).join(', ');
+    const phaseStart = window && window.from > cursor ? window.from : cursor;
+    let moduleStart = phaseStart;
+
+    for (const module of members) {
+      const end = addDays(moduleStart, durations.get(module.name)!);
+      const deadline = deadlines[module.name];
+      scheduled.push({
+        module: module.name,
+        effort_days: module.effort_days,
+        ...(team ? { team } : {}),
+        ...(deadline ? { deadline } : {}),
+        status: 'scheduled',
+        phase: name,
+        start: moduleStart,
+        end,
+      });
+
+      if (deadline && end > deadline) {
+        unsatisfiable.push({
+          constraint: 'deadline',
+          module: module.name,
+          limit: deadline,
+          actual: end,
+          over_by_days: daysBetween(deadline, end),
+          message: `${module.name} finishes ${end}, ${daysBetween(deadline, end)} days after its deadline ${deadline}`,
+        });
+      }
+      if (window && end > window.to) {
+        unsatisfiable.push({
+          constraint: 'team-availability',
+          module: module.name,
+          limit: window.to,
+          actual: end,
+          over_by_days: daysBetween(window.to, end),
+          message: `${team} is available until ${window.to}, but ${module.name} needs ${daysBetween(window.to, end)} more days`,
+        });
+      }
+      moduleStart = end;
+    }
+
+    const effort = members.reduce((sum, module) => sum + module.effort_days, 0);
+    if (maxEffort !== undefined && effort > maxEffort) {
+      unsatisfiable.push({
+        constraint: 'max-phase-effort',
+        module: name,
+        limit: `${maxEffort}`,
+        actual: `${effort}`,
+        over_by_days: effort - maxEffort,
+        message: `${name} alone is estimated at ${effort} effort days, ${effort - maxEffort} over maxPhaseEffort ${config.maxPhaseEffort}`,
+      });
+    }
+    phases.push({ name, modules: members.map(module => module.name), effort_days: effort, ...(team ? { team } : {}), start: phaseStart, end: moduleStart, fixed: false });
+    cursor = moduleStart;
+  }
+
+  return {
+    start,
+    end: [start, ...phases.map(phase => phase.end)].sort().pop()!,
+    velocity,
+    sprint_weeks: sprintWeeks,
+    ...(maxEffort !== undefined ? { max_phase_effort_days: maxEffort } : {}),
+    phases,
+    modules: scheduled,
+    unsatisfiable,
+  };
+}
+
+/**
+ * Calendar length of a phase in plan.md terms, e.g. "3週間"
+ */
+export function formatPhaseDuration(phase: Pick<ScheduledPhase, 'start' | 'end'>): string {
+  const days = daysBetween(phase.start, phase.end);
+  return days % 7 === 0 ? `${days / 7}週間` : `${days}日`;
+}
+
+/**
+ * plan.md section: Mermaid gantt of the phases and the constraints that cannot be met
+ */
+export function renderScheduleSection(schedule: PlanSchedule | undefined): string {
+  if (!schedule) return '';
+
+  const deadlines = schedule.modules.filter(module => module.deadline);
+  const gantt = [
+    'gantt',
+    '  title 移行スケジュール',
+    '  dateFormat YYYY-MM-DD',
+    '  axisFormat %Y-%m-%d',
+    ...schedule.phases.flatMap((phase, index) => [
+      `  section フェーズ${index + 1}${phase.team ? ` (${phase.team})` : ''}`,
+      ...phase.modules.map(name => {
+        const module = schedule.modules.find(m => m.module === name);
+        const start = module?.start ?? phase.start;
+        const end = module?.end ?? phase.end;
+        return `  ${name} :${phase.fixed ? 'done, ' : ''}${ganttId(name)}, ${start}, ${end}`;
+      }),
+    ]),
+    ...(deadlines.length > 0 ? [
+      '  section 期限',
+      ...deadlines.map(module => `  ${module.module} 期限 :milestone, ${ganttId(module.module)}_due, ${module.deadline}, 0d`),
+    ] : []),
+  ];
+
+  const unsatisfiable = schedule.unsatisfiable.map(item =>
+    `- **${item.constraint}** [${item.module}]: ${item.message}（超過: ${item.over_by_days}日）`
+  );
+
+  return `
+${SCHEDULE_HEADING}
+
+開始 ${schedule.start}、完了見込み ${schedule.end}（ベロシティ: ${schedule.velocity}人日/週${schedule.max_phase_effort_days !== undefined ? `、フェーズ上限: ${schedule.max_phase_effort_days}人日` : ''}）
+
+\`\`\`mermaid
+${gantt.join('\n')}
+\`\`\`
+${unsatisfiable.length > 0 ? `
+### 満たせないスケジュール制約
+
+${unsatisfiable.join('\n')}
+` : ''}`;
+}
+
+/**
+ * A quarter (2025-Q3) or a date range as the first and last available day
+ */
+function teamWindow(value: string | { from: string; to: string }): { from: string; to: string } {
+  if (typeof value !== 'string') return value;
+  const [year, quarter] = value.split('-Q').map(Number);
+  const firstMonth = (quarter - 1) * 3;
+  return {
+    from: formatDate(new Date(Date.UTC(year, firstMonth, 1))),
+    to: formatDate(new Date(Date.UTC(year, firstMonth + 3, 0))),
+  };
+}
+
+/**
+ * Latest date each module can finish so that it and every module depending on it meet their deadlines
+ */
+function inheritedDeadlines(modules: ScheduleInput[], deadlines: Record<string, string>, durations: Map<string, number>): Map<string, string> {
+  const due = new Map(modules.filter(module => deadlines[module.name]).map(module => [module.name, deadlines[module.name]]));
+  const names = new Set(modules.map(module => module.name));
+
+  for (let pass = 0; pass < modules.length; pass++) {
+    let changed = false;
+    for (const module of modules) {
+      const own = due.get(module.name);
+      if (!own) continue;
+      const latest = addDays(own, -durations.get(module.name)!);
+      for (const dependency of module.depends_on.filter(name => names.has(name))) {
+        const current = due.get(dependency);
+        if (current && current <= latest) continue;
+        due.set(dependency, latest);
+        changed = true;
+      }
+    }
+    if (!changed) break;
+  }
+  return due;
+}
+
+/**
+ * Dependencies first; among ready modules the smallest key wins, then the input order.
+ * A dependency cycle is broken at the module with the smallest key.
+ */
+function dependencyOrder(modules: ScheduleInput[], key: (module: ScheduleInput) => string[]): ScheduleInput[] {
+  const names = new Set(modules.map(module => module.name));
+  const placed = new Set<string>();
+  const ordered: ScheduleInput[] = [];
+  const compare = (a: ScheduleInput, b: ScheduleInput) => {
+    const [ka, kb] = [key(a), key(b)];
+    const index = ka.findIndex((value, i) => value !== kb[i]);
+    if (index >= 0) return ka[index] < kb[index] ? -1 : 1;
+    return modules.indexOf(a) - modules.indexOf(b);
+  };
+
+  while (ordered.length < modules.length) {
+    const pending = modules.filter(module => !placed.has(module.name));
+    const ready = pending.filter(module => module.depends_on.every(name => !names.has(name) || placed.has(name) || name === module.name));
+    const next = (ready.length > 0 ? ready : pending).sort(compare)[0];
+    placed.add(next.name);
+    ordered.push(next);
+  }
+  return ordered;
+}
+
+function durationDays(effortDays: number, velocity: number): number {
+  return Math.max(1, Math.ceil((effortDays / velocity) * 7));
+}
+
+function addDays(date: string, days: number): string {
+  const value = new Date(`${date}T00:00:00Z`);
+  value.setUTCDate(value.getUTCDate() + days);
+  return formatDate(value);
+}
+
+function daysBetween(from: string, to: string): number {
+  return Math.round((Date.parse(`${to}T00:00:00Z`) - Date.parse(`${from}T00:00:00Z`)) / 86400000);
+}
+
+function formatDate(date: Date): string {
+  return date.toISOString().slice(0, 10);
+}
+
+function ganttId(name: string): string {
+  return name.replace(/[^A-Za-z0-9_]/g, '_');
+}
@@ -0,0 +1,582 @@
+import { hashContent } from './module-manifest.js';
+import { planTargetFiles } from './architecture-style.js';
+import type { ArchitecturalPlan, ModuleDesign, RefactoringAction } from '../agents/architect-agent.js';
+
+/**
+ * Part of plan.md written from plan.json. Everything between the markers is
+ * replaced on regeneration; text outside them is kept verbatim.
+ */
+export interface GeneratedBlock {
+  /** overview, module:<id>, phase:<n> or section:<name> */
+  key: string;
+  content: string;
+}
+
+/**
+ * What plan.md and plan.json agreed on at the last generation or sync, as
+ * hashes: a field that differs from it on both sides is a conflict
+ */
+export interface PlanSyncState {
+  synced_at: string;
+  /** Structured field (see planFields) → hash of its value */
+  fields: Record<string, string>;
+  /** Generated block → hash of its content as written to plan.md */
+  blocks: Record<string, string>;
+}
+
+export interface FieldChange {
+  field: string;
+  from: string;
+  to: string;
+}
+
+export interface SyncConflict {
+  field: string;
+  markdown: string;
+  json: string;
+}
+
+/**
+ * Edit inside a generated block that does not map to a structured field
+ */
+export interface UncapturedEdit {
+  block: string;
+  reason: string;
+  lines: string[];
+}
+
+export interface ModuleRename {
+  id?: string;
+  from: string;
+  to: string;
+}
+
+export interface PlanSyncResult {
+  plan: ArchitecturalPlan;
+  applied: FieldChange[];
+  conflicts: SyncConflict[];
+  uncaptured: UncapturedEdit[];
+  renames: ModuleRename[];
+  /** Regenerated plan.md; null while conflicts or uncaptured edits (without force) are left */
+  markdown: string | null;
+}
+
+export const PLAN_FILES_HEADING = '**ファイル**:';
+export const PLAN_ACTIONS_HEADING = '**リファクタリングアクション**:';
+const DESCRIPTION_PREFIX = '**説明**: ';
+const PHASE_MODULES_PREFIX = '- 対象モジュール: ';
+
+const BEGIN_MARKER = /^<!-- vf:generated (\S+) -->$/;
+const END_MARKER = '<!-- /vf:generated -->';
+const PRIORITIES: RefactoringAction['priority'][] = ['high', 'medium', 'low'];
+
+interface ParsedPlanMarkdown {
+  blocks: GeneratedBlock[];
+  /** Lines before the first block (key null) and after each block */
+  prose: Map<string | null, string[]>;
+}
+
+/**
+ * Key of a plan module in block markers and field names; the boundary ID survives renames
+ */
+export function moduleKey(module: Pick<ModuleDesign, 'id' | 'name'>): string {
+  return module.id ?? module.name;
+}
+
+export function parsePlanMarkdown(markdown: string): ParsedPlanMarkdown {
+  const lines = markdown.replace(/\r\n/g, '\n').split('\n');
+  if (lines[lines.length - 1] === '') lines.pop();
+
+  const blocks: GeneratedBlock[] = [];
+  const prose = new Map<string | null, string[]>([[null, []]]);
+  let anchor: string | null = null;
+  let open: { key: string; lines: string[] } | null = null;
+
+  for (const line of lines) {
+    const begin = line.match(BEGIN_MARKER);
+    if (open) {
+      if (begin) throw new Error(`plan.md: generated block ${open.key} is not closed before ${begin[1]}`);
+      if (line === END_MARKER) {
+        blocks.push({ key: open.key, content: trimBlankLines(open.lines).join('\n') });
+        anchor = open.key;
+        prose.set(anchor, []);
+        open = null;
+      } else {
+        open.lines.push(line);
+      }
+    } else if (begin) {
+      if (blocks.some(block => block.key === begin[1])) throw new Error(`plan.md: generated block ${begin[1]} appears twice`);
+      open = { key: begin[1], lines: [] };
+    } else {
+      prose.get(anchor)!.push(line);
+    }
+  }
+  if (open) throw new Error(`plan.md: generated block ${open.key} is not closed`);
+
+  return { blocks, prose };
+}
+
+/**
+ * plan.md from generated blocks, keeping the human-authored text of the
+ * previous plan.md after the block it followed (or the closest earlier block
+ * that still exists)
+ */
+export function renderPlanDocument(blocks: GeneratedBlock[], previous?: string): string {
+  const prose = previous !== undefined ? anchoredProse(previous, new Set(blocks.map(block => block.key))) : new Map<string | null, string[]>();
+  const lines = [...(prose.get(null) ?? [])];
+  blocks.forEach((block, index) => {
+    lines.push(`<!-- vf:generated ${block.key} -->`, ...trimBlankLines(block.content.split('\n')), END_MARKER);
+    const after = prose.get(block.key);
+    if (after) lines.push(...after);
+    else if (index < blocks.length - 1) lines.push('');
+  });
+  return lines.join('\n') + '\n';
+}
+
+/**
+ * Replace the content of one generated block; null when plan.md does not have it
+ */
+export function replaceGeneratedBlock(markdown: string, key: string, content: string): string | null {
+  const begin = `<!-- vf:generated ${key} -->\n`;
+  const start = markdown.indexOf(begin);
+  if (start < 0) return null;
+  const end = markdown.indexOf(END_MARKER, start + begin.length);
+  if (end < 0) return null;
+  return markdown.slice(0, start + begin.length) + trimBlankLines(content.split('\n')).join('\n') + '\n' + markdown.slice(end);
+}
+
+/**
+ * Sync state after plan.md was written from `blocks` for `plan`
+ */
+export function planSyncState(plan: ArchitecturalPlan, blocks: GeneratedBlock[], now: Date = new Date()): PlanSyncState {
+  return {
+    synced_at: now.toISOString(),
+    fields: Object.fromEntries([...planFields(plan)].map(([field, value]) => [field, fieldHash(value)])),
+    blocks: Object.fromEntries(blocks.map(block => [block.key, blockHash(block.content)])),
+  };
+}
+
+/**
+ * Generated blocks of plan.md edited since they were written
+ */
+export function editedBlocks(markdown: string, state: PlanSyncState | undefined): string[] {
+  if (!state) return [];
+  return parsePlanMarkdown(markdown).blocks
+    .filter(block => state.blocks[block.key] !== undefined && state.blocks[block.key] !== blockHash(block.content))
+    .map(block => block.key);
+}
+
+/**
+ * Structured values plan.md can change: module names, descriptions, file
+ * lists and action wording, phase names and modules
+ */
+export function planFields(plan: ArchitecturalPlan): Map<string, string> {
+  const fields = new Map<string, string>();
+  for (const module of plan.modules) {
+    const key = `module:${moduleKey(module)}`;
+    fields.set(`${key}.name`, module.name);
+    fields.set(`${key}.description`, module.description);
+    fields.set(`${key}.files`, module.current_state.files.join('\n'));
+    module.refactoring_actions.forEach((action, index) => fields.set(`${key}.action.${index + 1}`, formatAction(action)));
+  }
+  plan.migration_strategy.phases.forEach((phase, index) => {
+    fields.set(`phase:${index + 1}.name`, phase.name);
+    fields.set(`phase:${index + 1}.modules`, phase.modules.join(', '));
+  });
+  return fields;
+}
+
+export function formatAction(action: Pick<RefactoringAction, 'description' | 'priority'>): string {
+  return `${action.description} (${action.priority})`;
+}
+
+/**
+ * Apply the structured edits of plan.md to plan.json. A field changed on
+ * both sides since the last sync is a conflict and left as is; plan.md is
+ * only regenerated when nothing needs a human (or with force). Besides the
+ * fields, action lines can be deleted and phase blocks moved around.
+ */
+export function syncPlan(
+  plan: ArchitecturalPlan,
+  markdown: string,
+  render: (plan: ArchitecturalPlan) => GeneratedBlock[],
+  options: { force?: boolean; now?: Date } = {}
+): PlanSyncResult {
+  const parsed = parsePlanMarkdown(markdown);
+  if (parsed.blocks.length === 0) {
+    throw new Error('plan.md has no generated blocks (written before vf plan sync); run vf plan to regenerate it');
+  }
+
+  const baseline = plan.sync?.fields ?? {};
+  const markdownFields = readMarkdownFields(parsed.blocks);
+  const next: ArchitecturalPlan = JSON.parse(JSON.stringify(plan));
+  const applied: FieldChange[] = [];
+  const conflicts: SyncConflict[] = [];
+  const uncaptured: UncapturedEdit[] = [...markdownFields.problems];
+  const renames: ModuleRename[] = [];
+  const mixed = new Set<string>();
+
+  // Dropped actions first: the fields of the actions left are numbered anew
+  for (const module of next.modules) {
+    const key = `module:${moduleKey(module)}`;
+    const block = parsed.blocks.find(b => b.key === key);
+    if (!block || !block.content.split('\n').includes(PLAN_ACTIONS_HEADING) || uncaptured.some(edit => edit.block === key)) continue;
+    const actions = module.refactoring_actions.map(formatAction);
+    const listed = [...markdownFields.fields].filter(([field]) => field.startsWith(`${key}.action.`)).map(([, value]) => value);
+    const kept = keptActions(actions, listed);
+    if (!kept) {
+      // Rewording the actions left would shift them onto the wrong fields
+      if (listed.length < actions.length) {
+        mixed.add(key);
+        addUncaptured(uncaptured, key, 'actions can be removed or reworded, not both in one sync', listed.filter(line => !actions.includes(line)));
+      }
+      continue;
+    }
+    if (kept.length === actions.length) continue;
+
+    const dropped = actions.map((action, index) => ({ field: `${key}.action.${index + 1}`, action, index }))
+      .filter(({ index }) => !kept.includes(index));
+    const changed = dropped.filter(({ field, action }) => baseline[field] !== undefined && baseline[field] !== fieldHash(action));
+    if (changed.length > 0) {
+      changed.forEach(({ field, action }) => conflicts.push({ field, markdown: '', json: action }));
+      continue;
+    }
+    module.refactoring_actions = kept.map(index => module.refactoring_actions[index]);
+    dropped.forEach(({ field, action }) => applied.push({ field, from: action, to: '' }));
+  }
+  const jsonFields = planFields(next);
+
+  for (const [field, value] of markdownFields.fields) {
+    const block = blockOf(field);
+    if (field.includes('.action.') && mixed.has(block)) continue;
+    const json = jsonFields.get(field);
+    if (json === undefined) {
+      if (field.includes('.action.') && jsonFields.has(`${block}.name`)) {
+        addUncaptured(uncaptured, block, 'actions can be reworded, not added', [value]);
+      } else {
+        addUncaptured(uncaptured, block, `${block.startsWith('module:') ? 'module' : 'phase'} is not in plan.json`, []);
+      }
+      continue;
+    }
+
+    const base = baseline[field] ?? fieldHash(json);
+    if (value === json || fieldHash(value) === base) continue;
+    if (fieldHash(json) !== base) {
+      conflicts.push({ field, markdown: value, json });
+      continue;
+    }
+
+    const error = applyField(next, field, value, renames);
+    if (error) addUncaptured(uncaptured, block, error, [value]);
+    else applied.push({ field, from: json, to: value });
+  }
+
+  const reported = new Set(uncaptured.map(edit => edit.block));
+  for (const [field, value] of jsonFields) {
+    const block = blockOf(field);
+    if (markdownFields.fields.has(field) || reported.has(block)) continue;
+    if (!parsed.blocks.some(b => b.key === block)) {
+      addUncaptured(uncaptured, block, 'block was removed from plan.md', []);
+    } else if (field.includes('.action.')) {
+      addUncaptured(uncaptured, block, 'actions can be removed or reworded, not both in one sync', [value]);
+    } else {
+      addUncaptured(uncaptured, block, `${fieldName(field)} was removed from the block`, []);
+    }
+  }
+
+  // Phase blocks moved in plan.md reorder the phases of plan.json
+  const order = parsed.blocks.filter(block => /^phase:\d+$/.test(block.key)).map(block => Number(block.key.slice('phase:'.length)) - 1);
+  const count = next.migration_strategy.phases.length;
+  if (order.length === count && order.every(position => position < count) && order.some((position, index) => position !== index)) {
+    const phases = next.migration_strategy.phases;
+    applied.push({ field: 'phases', from: phases.map(p => p.name).join(', '), to: order.map(position => phases[position].name).join(', ') });
+    next.migration_strategy.phases = order.map(position => phases[position]);
+  }
+
+  if (applied.length > 0) {
+    for (const phase of next.migration_strategy.phases) {
+      phase.actions = next.modules.filter(m => phase.modules.includes(m.name)).flatMap(m => m.refactoring_actions);
+    }
+    // Target files follow renamed modules and edited file lists
+    for (const module of next.modules) {
+      if (module.target_files) module.target_files = planTargetFiles(module.name, module.current_state.files, next.architecture_style ?? 'clean');
+    }
+  }
+
+  // Free text inside edited blocks: lines that are neither structured fields
+  // nor generated from plan.json before or after the sync
+  const rendered = render(next);
+  const generatedLines = new Map<string, Set<string>>();
+  for (const block of [...render(plan), ...rendered]) {
+    const lines = generatedLines.get(block.key) ?? new Set<string>();
+    block.content.split('\n').forEach(line => lines.add(line.trim()));
+    generatedLines.set(block.key, lines);
+  }
+  const conflicted = new Set(conflicts.map(conflict => blockOf(conflict.field)));
+  for (const block of parsed.blocks) {
+    const generated = generatedLines.get(block.key);
+    if (!generated || conflicted.has(block.key) || uncaptured.some(edit => edit.block === block.key)) continue;
+    if (plan.sync?.blocks[block.key] === blockHash(block.content)) continue;
+    const consumed = markdownFields.consumed.get(block.key) ?? new Set<string>();
+    const lines = block.content.split('\n').map(line => line.trim())
+      .filter(line => line !== '' && !generated.has(line) && !consumed.has(line));
+    if (lines.length > 0) addUncaptured(uncaptured, block.key, 'free text inside a generated block', lines);
+  }
+
+  const regenerate = conflicts.length === 0 && (uncaptured.length === 0 || options.force === true);
+  const now = options.now ?? new Date();
+  if (regenerate) {
+    next.sync = planSyncState(next, rendered, now);
+  } else {
+    // Only fields both files now agree on move the baseline
+    const agreed = planFields(next);
+    next.sync = {
+      synced_at: now.toISOString(),
+      fields: {
+        ...baseline,
+        ...Object.fromEntries([...markdownFields.fields]
+          .filter(([field, value]) => agreed.get(field) === value)
+          .map(([field, value]) => [field, fieldHash(value)])),
+      },
+      blocks: plan.sync?.blocks ?? {},
+    };
+  }
+
+  return {
+    plan: next,
+    applied,
+    conflicts,
+    uncaptured,
+    renames,
+    markdown: regenerate ? renderPlanDocument(rendered, markdown) : null,
+  };
+}
+
+/**
+ * Structured fields of the generated blocks, and the lines they were read from
+ */
+function readMarkdownFields(blocks: GeneratedBlock[]): {
+  fields: Map<string, string>;
+  consumed: Map<string, Set<string>>;
+  problems: UncapturedEdit[];
+} {
+  const fields = new Map<string, string>();
+  const consumed = new Map<string, Set<string>>();
+  const problems: UncapturedEdit[] = [];
+
+  for (const block of blocks) {
+    const lines = block.content.split('\n');
+    const used = new Set<string>();
+    consumed.set(block.key, used);
+    const read = (items: string[]) => {
+      items.forEach(line => used.add(line.trim()));
+      return items;
+    };
+    if (block.key.startsWith('module:')) {
+      const heading = lines.find(line => line.startsWith('### '));
+      if (!heading) {
+        problems.push({ block: block.key, reason: 'module heading (### <name>) is missing', lines: [] });
+        continue;
+      }
+      fields.set(`${block.key}.name`, read([heading])[0].slice(4).trim());
+
+      const description = lines.findIndex(line => line.startsWith(DESCRIPTION_PREFIX));
+      if (description >= 0) {
+        const text = read([lines[description], ...paragraph(lines, description + 1)]);
+        fields.set(`${block.key}.description`, [text[0].slice(DESCRIPTION_PREFIX.length), ...text.slice(1)].join('\n'));
+      }
+
+      const files = lines.indexOf(PLAN_FILES_HEADING);
+      if (files >= 0) {
+        const items = read(paragraph(lines, files + 1));
+        const malformed = items.filter(line => !/^- `[^`]+`$/.test(line));
+        if (malformed.length > 0) problems.push({ block: block.key, reason: 'file entries must be - `path`', lines: malformed });
+        else fields.set(`${block.key}.files`, items.map(line => line.slice(3, -1)).join('\n'));
+      }
+
+      const actions = lines.indexOf(PLAN_ACTIONS_HEADING);
+      if (actions >= 0) {
+        read(paragraph(lines, actions + 1)).forEach((line, index) => fields.set(`${block.key}.action.${index + 1}`, line.replace(/^- /, '')));
+      }
+    } else if (block.key.startsWith('phase:')) {
+      const heading = lines.find(line => /^### フェーズ\d+: /.test(line));
+      if (heading) fields.set(`${block.key}.name`, read([heading])[0].replace(/^### フェーズ\d+: /, '').trim());
+      const modules = lines.find(line => line.startsWith(PHASE_MODULES_PREFIX.trimEnd()));
+      if (modules !== undefined) {
+        fields.set(`${block.key}.modules`, splitList(read([modules])[0].slice(PHASE_MODULES_PREFIX.trimEnd().length)).join(', '));
+      }
+    }
+  }
+
+  return { fields, consumed, problems };
+}
+
+/**
+ * Set one field of plan.json; returns why the value cannot be applied
+ */
+function applyField(plan: ArchitecturalPlan, field: string, value: string, renames: ModuleRename[]): string | null {
+  const [block, name, index] = splitField(field);
+
+  if (block.startsWith('module:')) {
+    const module = plan.modules.find(m => `module:${moduleKey(m)}` === block)!;
+    switch (name) {
+      case 'name': {
+        if (value === '' || /[\s,]/.test(value)) return 'module names cannot be empty or contain spaces or commas';
+        if (plan.modules.some(m => m !== module && m.name === value)) return `another module is already named ${value}`;
+        const rename = { ...(module.id ? { id: module.id } : {}), from: module.name, to: value };
+        renames.push(rename);
+        module.name = value;
+        // Before later fields: a phase edit may already use the new name
+        renameReferences(plan, rename);
+        return null;
+      }
+      case 'description':
+        module.description = value;
+        return null;
+      case 'files': {
+        const files = value === '' ? [] : value.split('\n');
+        if (sameList(module.target_state.files, module.current_state.files)) module.target_state.files = files;
+        module.current_state.files = files;
+        return null;
+      }
+      case 'action': {
+        const match = value.match(/^(.*\S)\s+\((\w+)\)$/);
+        if (!match || !PRIORITIES.includes(match[2] as RefactoringAction['priority'])) {
+          return `actions must end with the priority: (${PRIORITIES.join(' | ')})`;
+        }
+        const action = module.refactoring_actions[Number(index) - 1];
+        action.description = match[1];
+        action.priority = match[2] as RefactoringAction['priority'];
+        return null;
+      }
+    }
+  }
+
+  const phase = plan.migration_strategy.phases[Number(block.slice('phase:'.length)) - 1];
+  if (name === 'name') {
+    if (value === '') return 'phase names cannot be empty';
+    phase.name = value;
+    return null;
+  }
+
+  const modules = splitList(value);
+  const known = new Set(plan.modules.map(m => m.name));
+  const unknown = modules.filter(m => !known.has(m) && !phase.modules.includes(m));
+  if (unknown.length > 0) return `unknown modules: ${unknown.join(', ')}`;
+  const elsewhere = modules.filter(m => plan.migration_strategy.phases.some(p => p !== phase && p.modules.includes(m)));
+  if (elsewhere.length > 0) {
+    // Moving a module between phases: it leaves the phase it was in
+    plan.migration_strategy.phases.forEach(p => {
+      if (p !== phase) p.modules = p.modules.filter(m => !elsewhere.includes(m));
+    });
+  }
+  phase.modules = modules;
+  return null;
+}
+
+/**
+ * Indexes of the actions plan.md still lists, when it only dropped some of
+ * them (the others unchanged and in order); null for any other edit
+ */
+function keptActions(actions: string[], listed: string[]): number[] | null {
+  const kept: number[] = [];
+  let next = 0;
+  for (const line of listed) {
+    while (next < actions.length && actions[next] !== line) next++;
+    if (next === actions.length) return null;
+    kept.push(next++);
+  }
+  return kept;
+}
+
+/**
+ * Point the other parts of plan.json at a renamed module
+ */
+function renameReferences(plan: ArchitecturalPlan, rename: ModuleRename): void {
+  const replace = (name: string) => (name === rename.from ? rename.to : name);
+  for (const phase of plan.migration_strategy.phases) phase.modules = phase.modules.map(replace);
+  for (const phase of plan.schedule?.phases ?? []) phase.modules = phase.modules.map(replace);
+  for (const module of plan.modules) {
+    module.dependencies.forEach(dep => { dep.module = replace(dep.module); });
+  }
+  for (const finding of plan.shared_state ?? []) {
+    finding.modules = finding.modules.map(replace);
+    if (finding.owner) finding.owner = replace(finding.owner);
+    finding.accesses.forEach(access => { access.module = replace(access.module); });
+  }
+}
+
+function anchoredProse(previous: string, keys: Set<string>): Map<string | null, string[]> {
+  const parsed = parsePlanMarkdown(previous);
+  const prose = new Map<string | null, string[]>();
+  // plan.md from before generated blocks: it is all generated text
+  if (parsed.blocks.length === 0) return prose;
+
+  const keep = (anchor: string | null, lines: string[]) => {
+    if (lines.every(line => line.trim() === '')) return;
+    prose.set(anchor, [...(prose.get(anchor) ?? []), ...lines]);
+  };
+  keep(null, parsed.prose.get(null) ?? []);
+  let anchor: string | null = null;
+  for (const block of parsed.blocks) {
+    if (keys.has(block.key)) anchor = block.key;
+    keep(anchor, parsed.prose.get(block.key) ?? []);
+  }
+  return prose;
+}
+
+/**
+ * Lines after `start` up to the next blank line
+ */
+function paragraph(lines: string[], start: number): string[] {
+  const end = lines.findIndex((line, index) => index >= start && line.trim() === '');
+  return lines.slice(start, end < 0 ? lines.length : end);
+}
+
+function addUncaptured(edits: UncapturedEdit[], block: string, reason: string, lines: string[]): void {
+  const existing = edits.find(edit => edit.block === block && edit.reason === reason);
+  if (existing) existing.lines.push(...lines);
+  else edits.push({ block, reason, lines });
+}
+
+function splitField(field: string): [string, string, string | undefined] {
+  const block = blockOf(field);
+  const [name, index] = field.slice(block.length + 1).split('.');
+  return [block, name, index];
+}
+
+function fieldName(field: string): string {
+  return splitField(field)[1];
+}
+
+function blockOf(field: string): string {
+  const match = field.match(/^(module:.+?|phase:\d+)\.(?:name|description|files|modules|action\.\d+)$/);
+  return match ? match[1] : field;
+}
+
+function splitList(value: string): string[] {
+  return value.split(',').map(item => item.trim()).filter(item => item !== '');
+}
+
+function sameList(a: string[], b: string[]): boolean {
+  return a.length === b.length && a.every((item, index) => item === b[index]);
+}
+
+function trimBlankLines(lines: string[]): string[] {
+  let start = 0;
+  let end = lines.length;
+  while (start < end && lines[start].trim() === '') start++;
+  while (end > start && lines[end - 1].trim() === '') end--;
+  return lines.slice(start, end);
+}
+
+function fieldHash(value: string): string {
+  return hashContent(value).slice(0, 16);
+}
+
+/**
+ * Hash of a generated block's content as recorded in PlanSyncState.blocks
+ */
+export function blockHash(content: string): string {
+  return hashContent(trimBlankLines(content.split('\n')).join('\n')).slice(0, 16);
+}
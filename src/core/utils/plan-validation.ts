@@ -0,0 +1,178 @@
+import { DomainBoundary } from '../types/config.js';
+import { dependencyLayers, findMisorderedModules } from './migration-order.js';
+import { editedBlocks } from './plan-sync.js';
+import { toPosixPath } from './workspace-paths.js';
+import type { ArchitecturalPlan } from '../agents/architect-agent.js';
+
+/**
+ * Inconsistency of a human-edited plan. Errors would make vf refactor work
+ * from a plan that no longer matches the code; warnings are worth a look.
+ */
+export interface PlanIssue {
+  severity: 'error' | 'warning';
+  /** module:<name>, phase:<n>, schedule or plan.md */
+  subject: string;
+  message: string;
+}
+
+/**
+ * Fields of plan.json that validation and vf refactor dereference, with their
+ * expected shape: a hand edit that drops or retypes one is reported here
+ * instead of failing later with a TypeError
+ */
+export function planStructureIssues(plan: ArchitecturalPlan): PlanIssue[] {
+  const issues: PlanIssue[] = [];
+  const error = (subject: string, message: string) => issues.push({ severity: 'error', subject, message });
+  const isObject = (value: unknown): value is Record<string, any> => !!value && typeof value === 'object' && !Array.isArray(value);
+
+  plan.modules.forEach((module: unknown, index) => {
+    if (!isObject(module) || typeof module.name !== 'string' || module.name === '') {
+      error(`module:#${index + 1}`, 'must be an object with a name');
+      return;
+    }
+    const subject = `module:${module.name}`;
+    if (!isObject(module.current_state)) error(subject, 'current_state is missing or not an object');
+    else if (!Array.isArray(module.current_state.files)) error(subject, 'current_state.files must be an array');
+    for (const key of ['dependencies', 'refactoring_actions'] as const) {
+      if (!Array.isArray(module[key])) error(subject, `${key} is missing or not an array`);
+    }
+    if (Array.isArray(module.dependencies) && module.dependencies.some((dependency: unknown) => !isObject(dependency) || typeof dependency.module !== 'string')) {
+      error(subject, 'every dependency needs a module name');
+    }
+    if (Array.isArray(module.refactoring_actions) && module.refactoring_actions.some((action: unknown) => !isObject(action) || !Array.isArray(action.files_affected))) {
+      error(subject, 'every refactoring action needs a files_affected array');
+    }
+  });
+
+  const strategy: unknown = plan.migration_strategy;
+  if (!isObject(strategy)) {
+    error('migration_strategy', 'is missing or not an object');
+  } else if (!Array.isArray(strategy.phases)) {
+    error('migration_strategy', 'phases is missing or not an array');
+  } else {
+    strategy.phases.forEach((phase: unknown, index: number) => {
+      if (!isObject(phase) || !Array.isArray(phase.modules)) error(`phase:${index + 1}`, 'must be an object with a modules array');
+    });
+  }
+
+  return issues;
+}
+
+/**
+ * Check an edited plan.json against domain-map.json (boundary.yaml curation
+ * applied) and itself: modules still match their boundaries, phases name
+ * existing modules once and after their dependencies, and plan.md has no
+ * edits left to sync. A plan missing the fields these checks walk reports
+ * only its structure errors.
+ */
+export function validatePlan(
+  plan: ArchitecturalPlan,
+  boundaries: DomainBoundary[],
+  options: { markdown?: string } = {}
+): PlanIssue[] {
+  const issues: PlanIssue[] = [];
+  const error = (subject: string, message: string) => issues.push({ severity: 'error', subject, message });
+  const warning = (subject: string, message: string) => issues.push({ severity: 'warning', subject, message });
+
+  // The checks below walk these fields
+  const malformed = planStructureIssues(plan);
+  if (malformed.length > 0) return malformed;
+
+  const names = new Set<string>();
+  const owners = new Map<string, string>();
+  const matched = new Set<DomainBoundary>();
+  for (const module of plan.modules) {
+    const subject = `module:${module.name}`;
+    if (names.has(module.name)) error(subject, 'another module has the same name');
+    names.add(module.name);
+
+    const boundary = module.id
+      ? boundaries.find(b => b.id === module.id)
+      : boundaries.find(b => b.name === module.name);
+    if (!boundary) {
+      error(subject, module.id
+        ? `boundary ${module.id} is no longer in domain-map.json (rerun vf plan)`
+        : 'no boundary of this name in domain-map.json (rename it with vf plan sync, which renames the boundary too)');
+    } else {
+      matched.add(boundary);
+      if (boundary.name !== module.name) {
+        error(subject, `the boundary is named ${boundary.name} in domain-map.json (rename it with vf plan sync, which renames the boundary too)`);
+      }
+      const mapped = new Set(boundary.files.map(toPosixPath));
+      const planned = module.current_state.files.map(toPosixPath);
+      const added = planned.filter(file => !mapped.has(file));
+      const missing = [...mapped].filter(file => !planned.includes(file));
+      if (added.length > 0) error(subject, `files not in the boundary of domain-map.json: ${added.join(', ')}`);
+      if (missing.length > 0) error(subject, `files of the boundary missing from plan.json: ${missing.join(', ')}`);
+    }
+
+    for (const file of module.current_state.files.map(toPosixPath)) {
+      const owner = owners.get(file);
+      if (owner && owner !== module.name) error(subject, `${file} is also a file of ${owner}`);
+      else owners.set(file, module.name);
+    }
+    for (const dependency of module.dependencies) {
+      if (!plan.modules.some(m => m.name === dependency.module) && !boundaries.some(b => b.name === dependency.module)) {
+        error(subject, `depends on ${dependency.module}, which is neither a module of the plan nor a boundary`);
+      }
+    }
+    for (const action of module.refactoring_actions) {
+      const unknown = action.files_affected.filter(file => !module.current_state.files.includes(file));
+      if (unknown.length > 0) warning(subject, `action "${action.description}" affects files outside the module: ${unknown.join(', ')}`);
+    }
+  }
+  for (const boundary of boundaries) {
+    if (!matched.has(boundary)) warning(`module:${boundary.name}`, 'boundary of domain-map.json is not a module of the plan');
+  }
+
+  const phases = plan.migration_strategy.phases;
+  const phaseOf = new Map<string, number>();
+  phases.forEach((phase, index) => {
+    const subject = `phase:${index + 1}`;
+    if (phase.modules.length === 0) warning(subject, `${phase.name} has no modules`);
+    for (const name of phase.modules) {
+      const module = plan.modules.find(m => m.name === name);
+      if (!module) {
+        error(subject, `${name} is not a module of the plan`);
+      } else if (module.status === 'established') {
+        error(subject, `${name} is established and stays out of the migration`);
+      } else if (phaseOf.has(name)) {
+        error(subject, `${name} is also in phase ${phaseOf.get(name)! + 1} (${phases[phaseOf.get(name)!].name})`);
+      } else {
+        phaseOf.set(name, index);
+      }
+    }
+  });
+  for (const module of plan.modules) {
+    if (module.status !== 'established' && module.refactoring_actions.length > 0 && !phaseOf.has(module.name)) {
+      warning(`module:${module.name}`, 'has refactoring actions but is in no phase');
+    }
+  }
+
+  const ordered = plan.modules
+    .filter(module => module.status !== 'established')
+    .map(module => ({ name: module.name, depends_on: module.dependencies.map(dependency => dependency.module) }));
+  const { cycles } = dependencyLayers(ordered);
+  for (const misordered of findMisorderedModules(phases, ordered, cycles)) {
+    const subject = `phase:${phaseOf.get(misordered.module)! + 1}`;
+    if (misordered.dependency_phase) {
+      error(subject, `${misordered.module} comes before its dependency ${misordered.dependency} (${misordered.dependency_phase})`);
+    } else {
+      warning(subject, `${misordered.module} depends on ${misordered.dependency}, which is in no phase`);
+    }
+  }
+
+  // Dated phases were scheduled in their original order
+  const scheduled = plan.schedule?.phases.map(phase => phase.name);
+  if (scheduled && scheduled.join('\n') !== phases.map(phase => phase.name).join('\n')) {
+    warning('schedule', 'the phases no longer match the schedule; rerun vf plan to date them again');
+  }
+
+  if (options.markdown !== undefined) {
+    const edited = editedBlocks(options.markdown, plan.sync);
+    if (edited.length > 0) warning('plan.md', `edits not applied to plan.json yet (run vf plan sync): ${edited.join(', ')}`);
+  }
+
+  return issues;
+}
+
@@ -0,0 +1,231 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { REFACTOR_TRANSFORMATION_PROMPT } from '../claude-code/prompts/refactor-agent.js';
+import { LlmCallRecord } from './performance-store.js';
+import { PromptInputRecord } from './llm-cache.js';
+import { estimateTokens } from './context-selector.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Task of RefactorAgent's file transformation prompts */
+export const REFACTOR_TASK = 'refactor';
+
+/** Run artifact name of the built-in refactor prompt */
+export const REFACTOR_TEMPLATE_NAME = 'prompts/refactor-transformation.txt';
+
+export interface PromptTemplate {
+  /** Run artifact name of a built-in template, or the configured file */
+  name: string;
+  text: string;
+  hash: string;
+}
+
+export interface PromptTemplateSummary {
+  task: string;
+  template: string;
+  template_hash: string;
+  calls: number;
+  files: number;
+  runs: number;
+  avg_input_tokens: number;
+  avg_output_tokens: number;
+  /** Retries with the next model / first attempts */
+  retry_rate: number;
+  malformed_rate: number;
+  verification_failure_rate: number;
+  /** Calls answered from the LLM cache */
+  cached: number;
+  cost: number;
+  /** Cost by module name */
+  cost_by_module: Record<string, number>;
+  avg_cost_per_module: number;
+  first_seen: string;
+  last_seen: string;
+}
+
+export interface PromptTemplateComparison {
+  task: string;
+  base: PromptTemplateSummary;
+  candidate: PromptTemplateSummary;
+  /** candidate - base */
+  delta: {
+    avg_input_tokens: number;
+    avg_output_tokens: number;
+    retry_rate: number;
+    malformed_rate: number;
+    verification_failure_rate: number;
+    avg_cost_per_module: number;
+  };
+  /** Modules both versions processed: cost under each */
+  modules: { module: string; base_cost: number; candidate_cost: number }[];
+}
+
+export interface PromptBenchFile {
+  module: string;
+  file: string;
+  /** Tokens of the prompt when it was recorded */
+  recorded_tokens: number;
+  /** Recorded inputs rendered with the current template */
+  baseline_tokens: number;
+  candidate_tokens: number;
+  delta: number;
+}
+
+export interface PromptBenchResult {
+  task: string;
+  baseline: { template: string; template_hash: string };
+  candidate: { template: string; template_hash: string };
+  files: PromptBenchFile[];
+  baseline_tokens: number;
+  candidate_tokens: number;
+  delta: number;
+  /** delta / baseline_tokens (0 without inputs) */
+  delta_rate: number;
+  /** Placeholders of the candidate no recorded input has a value for; they stay in the prompt as written */
+  unknown_placeholders: string[];
+  /** Inputs the candidate drops */
+  unused_variables: string[];
+}
+
+export function templateHash(text: string): string {
+  return createHash('sha256').update(text).digest('hex');
+}
+
+/**
+ * Fill {{name}} placeholders; placeholders without a value are left as written
+ */
+export function renderPromptTemplate(template: string, variables: Record<string, string>): string {
+  return template.replace(/\{\{(\w+)\}\}/g, (match, name: string) => variables[name] ?? match);
+}
+
+export function templatePlaceholders(template: string): string[] {
+  return [...new Set([...template.matchAll(/\{\{(\w+)\}\}/g)].map(match => match[1]))];
+}
+
+/**
+ * Refactor prompt template: the file of prompt.refactorTemplate, or the built-in one
+ */
+export function loadRefactorTemplate(projectRoot: string, configured?: string): PromptTemplate {
+  if (!configured) {
+    return { name: REFACTOR_TEMPLATE_NAME, text: REFACTOR_TRANSFORMATION_PROMPT, hash: templateHash(REFACTOR_TRANSFORMATION_PROMPT) };
+  }
+
+  const file = path.resolve(projectRoot, configured);
+  if (!fs.existsSync(file)) throw new Error(`prompt.refactorTemplate not found: ${configured}`);
+  const text = fs.readFileSync(file, 'utf8');
+  return { name: toPosixPath(path.relative(projectRoot, file)), text, hash: templateHash(text) };
+}
+
+/**
+ * LLM calls grouped by task and template version, oldest version first
+ */
+export function summarizePromptTemplates(calls: LlmCallRecord[], options: { task?: string } = {}): PromptTemplateSummary[] {
+  const groups = new Map<string, LlmCallRecord[]>();
+  for (const call of calls.filter(c => options.task === undefined || c.task === options.task)) {
+    const key = `${call.task}\0${call.template_hash}`;
+    groups.set(key, [...(groups.get(key) ?? []), call]);
+  }
+
+  return [...groups.values()].map(group => {
+    const rate = (count: number, total = group.length) => total > 0 ? count / total : 0;
+    const sum = (value: (call: LlmCallRecord) => number) => group.reduce((total, call) => total + value(call), 0);
+    const costByModule: Record<string, number> = {};
+    for (const call of group) {
+      const module = call.module_name ?? call.module;
+      costByModule[module] = (costByModule[module] ?? 0) + call.cost;
+    }
+    const recorded = group.map(call => call.recorded_at).sort();
+
+    return {
+      task: group[0].task,
+      template: group[group.length - 1].template,
+      template_hash: group[0].template_hash,
+      calls: group.length,
+      files: new Set(group.map(call => call.file)).size,
+      runs: new Set(group.map(call => call.run_id)).size,
+      avg_input_tokens: sum(call => call.input_tokens) / group.length,
+      avg_output_tokens: sum(call => call.output_tokens) / group.length,
+      retry_rate: rate(group.filter(call => call.attempt > 1).length, group.filter(call => call.attempt === 1).length),
+      malformed_rate: rate(group.filter(call => call.outcome === 'malformed').length),
+      verification_failure_rate: rate(group.filter(call => call.outcome === 'verification-failed').length),
+      cached: group.filter(call => call.cached).length,
+      cost: sum(call => call.cost),
+      cost_by_module: costByModule,
+      avg_cost_per_module: sum(call => call.cost) / Object.keys(costByModule).length,
+      first_seen: recorded[0],
+      last_seen: recorded[recorded.length - 1],
+    };
+  }).sort((a, b) => a.first_seen.localeCompare(b.first_seen) || a.task.localeCompare(b.task));
+}
+
+/**
+ * Two versions of a task's template side by side; hashes may be given as prefixes
+ *
+ * @throws Error when a hash matches no version or several
+ */
+export function comparePromptTemplates(summaries: PromptTemplateSummary[], base: string, candidate: string, task?: string): PromptTemplateComparison {
+  const find = (hash: string): PromptTemplateSummary => {
+    const matches = summaries.filter(s => s.template_hash.startsWith(hash) && (task === undefined || s.task === task));
+    if (matches.length === 0) throw new Error(`No LLM calls recorded for template ${hash}${task ? ` (task ${task})` : ''}`);
+    if (matches.length > 1) {
+      throw new Error(`Template ${hash} is ambiguous: ${matches.map(s => `${s.task}/${s.template_hash.slice(0, 12)}`).join(', ')} (use a longer hash or --task)`);
+    }
+    return matches[0];
+  };
+  const [from, to] = [find(base), find(candidate)];
+  if (from.task !== to.task) throw new Error(`Templates ${base} and ${candidate} belong to different tasks (${from.task}, ${to.task})`);
+
+  return {
+    task: from.task,
+    base: from,
+    candidate: to,
+    delta: {
+      avg_input_tokens: to.avg_input_tokens - from.avg_input_tokens,
+      avg_output_tokens: to.avg_output_tokens - from.avg_output_tokens,
+      retry_rate: to.retry_rate - from.retry_rate,
+      malformed_rate: to.malformed_rate - from.malformed_rate,
+      verification_failure_rate: to.verification_failure_rate - from.verification_failure_rate,
+      avg_cost_per_module: to.avg_cost_per_module - from.avg_cost_per_module,
+    },
+    modules: Object.keys(from.cost_by_module)
+      .filter(module => module in to.cost_by_module)
+      .sort()
+      .map(module => ({ module, base_cost: from.cost_by_module[module], candidate_cost: to.cost_by_module[module] })),
+  };
+}
+
+/**
+ * Input tokens of recorded prompts rendered with a candidate template instead of
+ * the current one. Nothing is sent to the model.
+ */
+export function benchPromptTemplate(inputs: PromptInputRecord[], candidate: PromptTemplate, baseline: PromptTemplate): PromptBenchResult {
+  const files: PromptBenchFile[] = inputs.map(input => {
+    const baselineTokens = estimateTokens(renderPromptTemplate(baseline.text, input.variables));
+    const candidateTokens = estimateTokens(renderPromptTemplate(candidate.text, input.variables));
+    return {
+      module: input.module,
+      file: input.file,
+      recorded_tokens: input.input_tokens,
+      baseline_tokens: baselineTokens,
+      candidate_tokens: candidateTokens,
+      delta: candidateTokens - baselineTokens,
+    };
+  });
+  const baselineTokens = files.reduce((sum, file) => sum + file.baseline_tokens, 0);
+  const candidateTokens = files.reduce((sum, file) => sum + file.candidate_tokens, 0);
+  const variables = new Set(inputs.flatMap(input => Object.keys(input.variables)));
+  const placeholders = templatePlaceholders(candidate.text);
+
+  return {
+    task: inputs[0]?.task ?? REFACTOR_TASK,
+    baseline: { template: baseline.name, template_hash: baseline.hash },
+    candidate: { template: candidate.name, template_hash: candidate.hash },
+    files,
+    baseline_tokens: baselineTokens,
+    candidate_tokens: candidateTokens,
+    delta: candidateTokens - baselineTokens,
+    delta_rate: baselineTokens > 0 ? (candidateTokens - baselineTokens) / baselineTokens : 0,
+    unknown_placeholders: inputs.length > 0 ? placeholders.filter(name => !variables.has(name)) : [],
+    unused_variables: [...variables].filter(name => !placeholders.includes(name)).sort(),
+  };
+}
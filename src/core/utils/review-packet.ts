@@ -0,0 +1,637 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import * as zlib from 'zlib';
+import { VibeFlowPaths } from './file-paths.js';
+import { FindingsReporter, Finding } from './findings.js';
+import { SharedStateFinding } from './shared-state.js';
+import { NetworkCallSite } from './service-deployment.js';
+import { formatLocation } from './source-positions.js';
+import { DebtInventoryScanner } from './debt-inventory.js';
+import { ModuleManifestStore, hashContent } from './module-manifest.js';
+import { RunArtifactStore } from './run-artifacts.js';
+import { ModuleStatusRecord, PerformanceStore, countLinesOfCode } from './performance-store.js';
+import { ModuleStatusTracker } from './module-status.js';
+import { parseGoDeclarations } from './context-selector.js';
+import { parseDomainMap, parsePlan } from './input-parsers.js';
+import { toPosixPath } from './workspace-paths.js';
+import { BoundaryDebt, DomainBoundary } from '../types/config.js';
+import type { ModuleDesign } from '../agents/architect-agent.js';
+
+export interface PacketArtifact {
+  name: string;
+  /** sha256 of the artifact the packet was built from; null when it does not exist */
+  hash: string | null;
+  modified_at?: string;
+  /** Why the artifact no longer matches the workspace */
+  stale?: string;
+}
+
+export interface PacketFile {
+  file: string;
+  loc: number;
+  /** Changed (or deleted) after domain-map.json was written */
+  changed_since_discovery: boolean;
+}
+
+export interface PacketDependency {
+  module: string;
+  direction: 'outgoing' | 'incoming';
+  type: string;
+  description: string;
+}
+
+export interface CrossBoundaryTransaction {
+  function: string;
+  file: string;
+  /** Tables touched inside the transaction that another module owns */
+  tables: { table: string; owner: string }[];
+}
+
+export interface PacketCostEstimate {
+  files: number;
+  tokens: number;
+  cost_usd: number;
+  time: string;
+}
+
+export interface ReviewPacket {
+  module: string;
+  /** Stable boundary ID from domain-map.json; quote it in review feedback */
+  id?: string;
+  description: string;
+  generated_at: string;
+  plan_section: string | null;
+  design?: ModuleDesign;
+  files: PacketFile[];
+  dependencies: PacketDependency[];
+  diagram: string;
+  business_rules: Finding[];
+  routes: string[];
+  tables: string[];
+  risks: {
+    cycles: string[];
+    shared_state: SharedStateFinding[];
+    transactions: CrossBoundaryTransaction[];
+    /** Calls that become network calls: into the module when it is a service, else from it into services */
+    network_calls: NetworkCallSite[];
+    degraded_files: string[];
+    debt?: BoundaryDebt;
+  };
+  cost: PacketCostEstimate;
+  artifacts: PacketArtifact[];
+  /** Generated outputs of the module edited since they were written */
+  edited_outputs: string[];
+  /** Migration lifecycle stage (see `vf status --modules`) */
+  status?: Pick<ModuleStatusRecord, 'stage' | 'run_id' | 'updated_at' | 'accepted_by'>;
+}
+
+export type CostEstimator = (boundary: DomainBoundary) => Promise<PacketCostEstimate>;
+
+const PACKET_FILES = ['README.md', 'plan.md', 'files.md', 'dependencies.md', 'business-rules.md', 'ownership.md', 'risks.md', 'cost.md'];
+
+/**
+ * ReviewPacketBuilder - アーキテクチャレビュー用のモジュール別資料
+ *
+ * Assembles a self-contained directory per module from artifacts already on
+ * disk (domain map, plan, findings, debt inventory, output manifests); no LLM
+ * is called. Each artifact is listed with its hash and marked stale when the
+ * workspace moved on after it was written.
+ */
+export class ReviewPacketBuilder {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+  private estimateCost: CostEstimator;
+
+  constructor(projectRoot: string, estimateCost?: CostEstimator) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+    this.estimateCost = estimateCost ?? (boundary => this.defaultEstimate(boundary));
+  }
+
+  listModules(): string[] {
+    return this.loadBoundaries().map(b => b.name);
+  }
+
+  async build(moduleName: string): Promise<ReviewPacket> {
+    const boundaries = this.loadBoundaries();
+    const boundary = boundaries.find(b => b.name === moduleName);
+    if (!boundary) {
+      throw new Error(`Module "${moduleName}" not found in domain map. Available: ${boundaries.map(b => b.name).join(', ')}`);
+    }
+
+    const plan = this.loadPlan();
+    const modules = (plan?.modules ?? []) as ModuleDesign[];
+    const design = modules.find(m => m.name === moduleName);
+    const files = boundary.files.map(file => this.relative(file));
+    const artifacts = this.describeArtifacts(files);
+    const discoveredAt = this.mtime(this.paths.domainMapPath);
+    const owners = tableOwners(boundaries, modules);
+
+    // plan.json dependencies when planned, otherwise the discovered internal imports
+    const graph: { name: string; dependencies: { module: string; type: string; description: string }[] }[] = modules.length > 0
+      ? modules
+      : boundaries.map(b => ({ name: b.name, dependencies: (b.dependencies?.internal ?? []).map(dep => ({ module: dep, type: 'interface', description: '' })) }));
+    const dependencies: PacketDependency[] = [
+      ...(graph.find(m => m.name === moduleName)?.dependencies ?? [])
+        .map(dep => ({ module: dep.module, direction: 'outgoing' as const, type: dep.type, description: dep.description })),
+      ...graph
+        .filter(other => other.name !== moduleName)
+        .flatMap(other => other.dependencies
+          .filter(dep => dep.module === moduleName)
+          .map(dep => ({ module: other.name, direction: 'incoming' as const, type: dep.type, description: dep.description }))),
+    ];
+
+    return {
+      module: moduleName,
+      ...(boundary.id ? { id: boundary.id } : {}),
+      description: boundary.description,
+      generated_at: new Date().toISOString(),
+      plan_section: this.planSection(moduleName),
+      ...(design ? { design } : {}),
+      files: files.map(file => ({
+        file,
+        loc: countLinesOfCode(this.projectRoot, [file]),
+        changed_since_discovery: discoveredAt === null || (this.mtime(path.join(this.projectRoot, file)) ?? Infinity) > discoveredAt,
+      })),
+      dependencies,
+      diagram: neighborhoodDiagram(moduleName, dependencies),
+      business_rules: this.businessRules(files),
+      routes: [...(boundary.apiEndpoints ?? [])].sort(),
+      tables: [...new Set([...(design?.owned_tables ?? []), ...(boundary.tables ?? [])])].sort(),
+      risks: {
+        cycles: boundary.circular_dependencies ?? [],
+        shared_state: ((plan?.shared_state ?? []) as SharedStateFinding[]).filter(f => f.modules.includes(moduleName)),
+        transactions: findCrossBoundaryTransactions(this.projectRoot, moduleName, files, owners),
+        network_calls: design?.service
+          ? design.service.network_calls
+          : modules.flatMap(m => m.service?.network_calls ?? []).filter(call => call.caller === moduleName),
+        degraded_files: (boundary.degraded_files ?? []).map(d => this.relative(d.file)),
+        ...(boundary.debt ? { debt: boundary.debt } : {}),
+      },
+      cost: await this.estimateCost({ ...boundary, files: files.map(file => path.join(this.projectRoot, file)) }),
+      artifacts,
+      edited_outputs: this.editedOutputs(moduleName),
+      ...this.moduleStatus(boundary),
+    };
+  }
+
+  private moduleStatus(boundary: DomainBoundary): Pick<ReviewPacket, 'status'> {
+    try {
+      const record = new ModuleStatusTracker(this.projectRoot, new PerformanceStore(this.projectRoot, { readOnly: true })).find(boundary);
+      if (!record) return {};
+      const { stage, run_id, updated_at, accepted_by } = record;
+      return { status: { stage, ...(run_id !== undefined ? { run_id } : {}), updated_at, ...(accepted_by ? { accepted_by } : {}) } };
+    } catch {
+      return {};
+    }
+  }
+
+  /**
+   * Write the packet directory (markdown index, sections and packet.json)
+   *
+   * @returns written file paths
+   */
+  write(packet: ReviewPacket, outputDir: string): string[] {
+    fs.mkdirSync(outputDir, { recursive: true });
+    const sections = renderPacket(packet);
+    const written = PACKET_FILES.map(name => {
+      const target = path.join(outputDir, name);
+      fs.writeFileSync(target, sections[name]);
+      return target;
+    });
+    const jsonPath = path.join(outputDir, 'packet.json');
+    fs.writeFileSync(jsonPath, JSON.stringify(packet, null, 2));
+    return [...written, jsonPath];
+  }
+
+  private loadBoundaries(): DomainBoundary[] {
+    let content: string;
+    try {
+      content = fs.readFileSync(this.paths.domainMapPath, 'utf8');
+    } catch {
+      throw new Error(`Domain map not found. Please run "vf plan" first to generate ${this.paths.getRelativePath(this.paths.domainMapPath)}`);
+    }
+    return parseDomainMap(content, this.paths.getRelativePath(this.paths.domainMapPath)).map.boundaries;
+  }
+
+  private loadPlan(): ReturnType<typeof parsePlan> | null {
+    if (!fs.existsSync(this.paths.planJsonPath)) return null;
+    return parsePlan(fs.readFileSync(this.paths.planJsonPath, 'utf8'), this.paths.getRelativePath(this.paths.planJsonPath));
+  }
+
+  /**
+   * The module's `### name` section of plan.md
+   */
+  private planSection(moduleName: string): string | null {
+    if (!fs.existsSync(this.paths.planPath)) return null;
+    const lines = fs.readFileSync(this.paths.planPath, 'utf8').split('\n');
+    const start = lines.findIndex(line => line.trim() === `### ${moduleName}`);
+    if (start < 0) return null;
+    const end = lines.findIndex((line, i) => i > start && /^#{2,3} /.test(line));
+    return lines.slice(start, end < 0 ? undefined : end).join('\n').trim();
+  }
+
+  private businessRules(files: string[]): Finding[] {
+    const moduleFiles = new Set(files);
+    return (new FindingsReporter(this.projectRoot).load()?.findings ?? [])
+      .filter(f => f.kind === 'business-rule' && moduleFiles.has(f.location.file));
+  }
+
+  private describeArtifacts(files: string[]): PacketArtifact[] {
+    const sourceChanged = (since: number | null) => since !== null && files.some(file => {
+      const modified = this.mtime(path.join(this.projectRoot, file));
+      return modified === null || modified > since;
+    });
+
+    const latestRun = new RunArtifactStore(this.projectRoot).listRuns().at(-1);
+    let drift = new Map<string, string>();
+    if (latestRun !== undefined) {
+      try {
+        drift = new Map(new RunArtifactStore(this.projectRoot).compare(latestRun)
+          .filter(a => a.status !== 'same')
+          .map(a => [a.name, a.status] as [string, string]));
+      } catch {
+        // No snapshot for the latest run
+      }
+    }
+
+    const domainMapAt = this.mtime(this.paths.domainMapPath);
+    const entries: [string, string][] = [
+      ['domain-map.json', this.paths.domainMapPath],
+      ['plan.json', this.paths.planJsonPath],
+      ['plan.md', this.paths.planPath],
+      ['findings.json', new FindingsReporter(this.projectRoot).reportPath],
+      ['debt-inventory.json', new DebtInventoryScanner(this.projectRoot, []).reportPath],
+    ];
+    return entries.map(([name, filePath]) => {
+      if (!fs.existsSync(filePath)) return { name, hash: null };
+      const modified = this.mtime(filePath);
+      const reasons = [
+        sourceChanged(modified) ? 'module sources changed after it was written' : null,
+        name !== 'domain-map.json' && domainMapAt !== null && modified !== null && modified < domainMapAt ? 'older than domain-map.json' : null,
+        drift.has(name) ? `${drift.get(name)} since the inputs of run ${latestRun}` : null,
+      ].filter((reason): reason is string => reason !== null);
+      return {
+        name,
+        hash: hashContent(fs.readFileSync(filePath, 'utf8')),
+        ...(modified !== null ? { modified_at: new Date(modified).toISOString() } : {}),
+        ...(reasons.length > 0 ? { stale: reasons.join('; ') } : {}),
+      };
+    });
+  }
+
+  private editedOutputs(moduleName: string): string[] {
+    const manifest = new ModuleManifestStore(this.projectRoot).load(moduleName);
+    return (manifest?.files ?? [])
+      .filter(entry => {
+        try {
+          return hashContent(fs.readFileSync(path.join(this.projectRoot, entry.path), 'utf8')) !== entry.hash;
+        } catch {
+          return true;
+        }
+      })
+      .map(entry => entry.path);
+  }
+
+  private async defaultEstimate(boundary: DomainBoundary): Promise<PacketCostEstimate> {
+    const { HybridRefactorAgent } = await import('../agents/hybrid-refactor-agent.js');
+    const estimate = await new HybridRefactorAgent(this.projectRoot).estimateCost([boundary]);
+    return { files: estimate.fileCount, tokens: estimate.estimatedTokens, cost_usd: estimate.estimatedCost, time: estimate.estimatedTime };
+  }
+
+  private mtime(filePath: string): number | null {
+    try {
+      return fs.statSync(filePath).mtimeMs;
+    } catch {
+      return null;
+    }
+  }
+
+  private relative(file: string): string {
+    return toPosixPath(path.isAbsolute(file) ? path.relative(this.projectRoot, file) : path.normalize(file));
+  }
+}
+
+/**
+ * Mermaid graph of the module and its direct neighbors
+ */
+export function neighborhoodDiagram(moduleName: string, dependencies: PacketDependency[]): string {
+  const id = (name: string) => `m_${name.replace(/\W/g, '_')}`;
+  const lines = ['graph LR', `  ${id(moduleName)}["${moduleName}"]:::focus`];
+  const neighbors = [...new Set(dependencies.map(d => d.module))].sort();
+  neighbors.forEach(name => lines.push(`  ${id(name)}["${name}"]`));
+  const edges = new Set(dependencies.map(dep => dep.direction === 'outgoing'
+    ? `  ${id(moduleName)} -->|${dep.type}| ${id(dep.module)}`
+    : `  ${id(dep.module)} -->|${dep.type}| ${id(moduleName)}`));
+  lines.push(...[...edges].sort(), '  classDef focus fill:#fde68a,stroke:#b45309,stroke-width:2px');
+  return lines.join('\n');
+}
+
+/**
+ * Minimal zip archive (deflate, UTF-8 names) of a directory tree
+ */
+export function zipDirectory(sourceDir: string, zipPath: string, root = path.basename(sourceDir)): void {
+  const entries = listFiles(sourceDir).map(file => ({
+    name: `${root}/${toPosixPath(path.relative(sourceDir, file))}`,
+    data: fs.readFileSync(file),
+  }));
+  fs.writeFileSync(zipPath, createZip(entries));
+}
+
+export function createZip(entries: { name: string; data: Buffer }[], date = new Date()): Buffer {
+  const time = (date.getHours() << 11) | (date.getMinutes() << 5) | Math.floor(date.getSeconds() / 2);
+  const day = ((Math.max(date.getFullYear(), 1980) - 1980) << 9) | ((date.getMonth() + 1) << 5) | date.getDate();
+  const locals: Buffer[] = [];
+  const centrals: Buffer[] = [];
+  let offset = 0;
+
+  for (const entry of entries) {
+    const name = Buffer.from(entry.name, 'utf8');
+    const compressed = zlib.deflateRawSync(entry.data);
+    const crc = crc32(entry.data);
+
+    const local = Buffer.alloc(30);
+    local.writeUInt32LE(0x04034b50, 0);
+    local.writeUInt16LE(20, 4);
+    local.writeUInt16LE(0x0800, 6);
+    local.writeUInt16LE(8, 8);
+    local.writeUInt16LE(time, 10);
+    local.writeUInt16LE(day, 12);
+    local.writeUInt32LE(crc, 14);
+    local.writeUInt32LE(compressed.length, 18);
+    local.writeUInt32LE(entry.data.length, 22);
+    local.writeUInt16LE(name.length, 26);
+    locals.push(local, name, compressed);
+
+    const central = Buffer.alloc(46);
+    central.writeUInt32LE(0x02014b50, 0);
+    central.writeUInt16LE(20, 4);
+    central.writeUInt16LE(20, 6);
+    central.writeUInt16LE(0x0800, 8);
+    central.writeUInt16LE(8, 10);
+    central.writeUInt16LE(time, 12);
+    central.writeUInt16LE(day, 14);
+    central.writeUInt32LE(crc, 16);
+    central.writeUInt32LE(compressed.length, 20);
+    central.writeUInt32LE(entry.data.length, 24);
+    central.writeUInt16LE(name.length, 28);
+    central.writeUInt32LE(offset, 42);
+    centrals.push(central, name);
+
+    offset += local.length + name.length + compressed.length;
+  }
+
+  const centralSize = centrals.reduce((sum, b) => sum + b.length, 0);
+  const end = Buffer.alloc(22);
+  end.writeUInt32LE(0x06054b50, 0);
+  end.writeUInt16LE(entries.length, 8);
+  end.writeUInt16LE(entries.length, 10);
+  end.writeUInt32LE(centralSize, 12);
+  end.writeUInt32LE(offset, 16);
+  return Buffer.concat([...locals, ...centrals, end]);
+}
+
+function renderPacket(packet: ReviewPacket): Record<string, string> {
+  const idLine = packet.id ? `Boundary ID: \`${packet.id}\` (quote it in review feedback)` : 'Boundary ID: (none; re-run `vf discover` to assign one)';
+  const staleArtifacts = packet.artifacts.filter(a => a.stale);
+  const missing = packet.artifacts.filter(a => a.hash === null).map(a => a.name);
+  const risks = packet.risks;
+  const riskCount = risks.cycles.length + risks.shared_state.length + risks.transactions.length +
+    risks.network_calls.length + risks.degraded_files.length;
+
+  const readme = [
+    `# Review packet: ${packet.module}`,
+    '',
+    idLine,
+    '',
+    packet.description,
+    '',
+    `Status: **${packet.status?.stage ?? 'discovered'}**${packet.status?.run_id !== undefined ? ` (run ${packet.status.run_id})` : ''}${packet.status?.accepted_by ? `, accepted by ${packet.status.accepted_by}` : ''}`,
+    '',
+    `Generated ${packet.generated_at} from existing vibeflow artifacts (no LLM calls).`,
+    '',
+    '## Contents',
+    '',
+    `- [Plan section](plan.md)`,
+    `- [Files](files.md): ${packet.files.length} files, ${packet.files.reduce((sum, f) => sum + f.loc, 0)} lines`,
+    `- [Dependencies](dependencies.md): ${packet.dependencies.filter(d => d.direction === 'outgoing').length} outgoing, ${packet.dependencies.filter(d => d.direction === 'incoming').length} incoming`,
+    `- [Business rules](business-rules.md): ${packet.business_rules.length}`,
+    `- [Routes and tables](ownership.md): ${packet.routes.length} routes, ${packet.tables.length} tables`,
+    `- [Risks](risks.md): ${riskCount}${risks.debt ? `, ${risks.debt.total} debt markers` : ''}`,
+    `- [Estimated cost](cost.md): $${packet.cost.cost_usd.toFixed(2)}`,
+    '',
+    '## Neighborhood',
+    '',
+    '```mermaid',
+    packet.diagram,
+    '```',
+    '',
+    '## Data freshness',
+    '',
+    staleArtifacts.length === 0 && missing.length === 0 && packet.edited_outputs.length === 0
+      ? 'All artifacts match the current workspace.'
+      : [
+        ...staleArtifacts.map(a => `- ⚠️ **${a.name}** is stale: ${a.stale}`),
+        ...missing.map(name => `- ${name} was not found; its sections are empty`),
+        ...packet.edited_outputs.map(file => `- ⚠️ generated output ${file} was edited after it was written`),
+      ].join('\n'),
+    '',
+    '| Artifact | sha256 | Modified |',
+    '|---|---|---|',
+    ...packet.artifacts.map(a => `| ${a.name} | ${a.hash ? `\`${a.hash.slice(0, 12)}\`` : '-'} | ${a.modified_at ?? '-'} |`),
+    '',
+  ].join('\n');
+
+  const plan = [
+    `# Plan: ${packet.module}`,
+    '',
+    packet.plan_section ?? '_No section for this module in plan.md. Run `vf plan`._',
+    '',
+    ...(packet.design && packet.design.refactoring_actions.length > 0 ? [
+      '## Refactoring actions',
+      '',
+      '| Type | Description | Priority | Effort |',
+      '|---|---|---|---|',
+      ...packet.design.refactoring_actions.map(a => `| ${a.type} | ${a.description} | ${a.priority} | ${a.effort_estimate} |`),
+      '',
+    ] : []),
+    ...(packet.design?.service ? [
+      '## Separately deployable service',
+      '',
+      `- API definition (${packet.design.service.api.protocol}): ${packet.design.service.api.path}, ${packet.design.service.api.operations.length} operations`,
+      `- Entry point: ${packet.design.service.entrypoint}`,
+      `- Direct access to tables across the service boundary: ${packet.design.service.foreign_table_access.length}`,
+      `- Call sites that become network calls: ${packet.design.service.network_calls.length} (see risks.md)`,
+      '',
+    ] : []),
+  ].join('\n');
+
+  const files = [
+    `# Files: ${packet.module}`,
+    '',
+    '| File | LOC | Changed since discovery |',
+    '|---|---:|---|',
+    ...packet.files.map(f => `| ${f.file} | ${f.loc} | ${f.changed_since_discovery ? '⚠️ yes' : 'no'} |`),
+    '',
+  ].join('\n');
+
+  const dependencies = [
+    `# Dependencies: ${packet.module}`,
+    '',
+    '```mermaid',
+    packet.diagram,
+    '```',
+    '',
+    ...(packet.dependencies.length > 0 ? [
+      '| Direction | Module | Type | Description |',
+      '|---|---|---|---|',
+      ...packet.dependencies.map(d => `| ${d.direction} | ${d.module} | ${d.type} | ${d.description} |`),
+    ] : ['No dependencies on or from other modules.']),
+    '',
+  ].join('\n');
+
+  const rules = [
+    `# Business rules: ${packet.module}`,
+    '',
+    ...(packet.business_rules.length > 0 ? [
+      '| Type | Rule | Location |',
+      '|---|---|---|',
+      ...packet.business_rules.map(r => `| ${r.rule} | ${r.message} | ${r.location.file}:${r.location.line} |`),
+    ] : ['_No business rules in findings.json for this module. Run `vf report findings --business-rules`._']),
+    '',
+  ].join('\n');
+
+  const ownership = [
+    `# Routes and tables: ${packet.module}`,
+    '',
+    '## Routes',
+    '',
+    ...(packet.routes.length > 0 ? packet.routes.map(r => `- \`${r}\``) : ['None detected.']),
+    '',
+    '## Owned tables',
+    '',
+    ...(packet.tables.length > 0 ? packet.tables.map(t => `- ${t}`) : ['None.']),
+    '',
+  ].join('\n');
+
+  const riskLines = [
+    `# Risks: ${packet.module}`,
+    '',
+    '## Dependency cycles',
+    '',
+    ...(risks.cycles.length > 0 ? risks.cycles.map(c => `- ${c}`) : ['None.']),
+    '',
+    '## Shared mutable state',
+    '',
+    ...(risks.shared_state.length > 0
+      ? risks.shared_state.map(f => `- \`${f.id}\` (${f.kind}, ${f.access}) used by ${f.modules.join(', ')}: ${f.resolution ? `resolved with ${f.resolution.strategy}` : '**unresolved, blocks refactor**'}`)
+      : ['None.']),
+    '',
+    '## Transactions crossing the boundary',
+    '',
+    ...(risks.transactions.length > 0
+      ? risks.transactions.map(t => `- ${t.file} \`${t.function}\`: ${t.tables.map(x => `${x.table} (owned by ${x.owner})`).join(', ')}`)
+      : ['None detected.']),
+    '',
+    '## Calls that become network calls',
+    '',
+    ...(risks.network_calls.length > 0
+      ? risks.network_calls.map(c => `- ${formatLocation(c.location)} ${c.caller}${c.function ? ` \`${c.function}\`` : ''} → \`${c.operation}\`` +
+        (c.per_request ? ` (${Object.entries(c.per_request).map(([request, n]) => `${request}: ~${n}/request`).join(', ')})` : ''))
+      : ['None.']),
+    '',
+    '## Files analyzed syntax-only',
+    '',
+    ...(risks.degraded_files.length > 0 ? risks.degraded_files.map(f => `- ${f}`) : ['None.']),
+    '',
+    ...(risks.debt ? [
+      '## Known debt',
+      '',
+      `${risks.debt.total} markers (${Object.entries(risks.debt.by_category).map(([c, n]) => `${c} ${n}`).join(', ') || 'none'}); ` +
+        `${risks.debt.test} in tests and ${risks.debt.generated} in generated code counted separately.`,
+      '',
+    ] : []),
+  ].join('\n');
+
+  const cost = [
+    `# Estimated cost: ${packet.module}`,
+    '',
+    `- Files: ${packet.cost.files}`,
+    `- Tokens: ${packet.cost.tokens.toLocaleString('en-US')}`,
+    `- Cost: $${packet.cost.cost_usd.toFixed(2)}`,
+    `- Time: ${packet.cost.time}`,
+    '',
+    'Same estimate as `vf estimate`, limited to this module.',
+    '',
+  ].join('\n');
+
+  return {
+    'README.md': readme,
+    'plan.md': plan,
+    'files.md': files,
+    'dependencies.md': dependencies,
+    'business-rules.md': rules,
+    'ownership.md': ownership,
+    'risks.md': riskLines,
+    'cost.md': cost,
+  };
+}
+
+/**
+ * Functions that open a transaction and touch tables owned by another module
+ */
+export function findCrossBoundaryTransactions(projectRoot: string, moduleName: string, files: string[], owners: Map<string, string>): CrossBoundaryTransaction[] {
+  const transactions: CrossBoundaryTransaction[] = [];
+  for (const file of files.filter(f => f.endsWith('.go'))) {
+    let source: string;
+    try {
+      source = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+    } catch {
+      continue;
+    }
+    for (const decl of parseGoDeclarations(source, file)) {
+      if (decl.kind === 'type' || !/\.Begin(?:Tx)?\(/.test(decl.body)) continue;
+      const tables = [...decl.body.matchAll(/\b(?:FROM|INTO|UPDATE|JOIN)\s+[`"]?(\w+)/gi)]
+        .map(match => match[1].toLowerCase())
+        .filter((table, i, all) => all.indexOf(table) === i)
+        .filter(table => owners.has(table) && owners.get(table) !== moduleName)
+        .map(table => ({ table, owner: owners.get(table)! }));
+      if (tables.length > 0) transactions.push({ function: decl.name, file, tables });
+    }
+  }
+  return transactions;
+}
+
+/**
+ * Owning module of each table (lower case): plan.json owned_tables first, then the discovered tables
+ */
+export function tableOwners(boundaries: DomainBoundary[], modules: ModuleDesign[]): Map<string, string> {
+  const owners = new Map<string, string>();
+  for (const module of modules) {
+    (module.owned_tables ?? []).forEach(table => owners.set(table.toLowerCase(), module.name));
+  }
+  for (const boundary of boundaries) {
+    (boundary.tables ?? []).forEach(table => {
+      if (!owners.has(table.toLowerCase())) owners.set(table.toLowerCase(), boundary.name);
+    });
+  }
+  return owners;
+}
+
+function listFiles(dir: string): string[] {
+  return fs.readdirSync(dir, { withFileTypes: true })
+    .sort((a, b) => (a.name < b.name ? -1 : a.name > b.name ? 1 : 0))
+    .flatMap(entry => entry.isDirectory() ? listFiles(path.join(dir, entry.name)) : [path.join(dir, entry.name)]);
+}
+
+const CRC_TABLE = Array.from({ length: 256 }, (_, n) => {
+  let c = n;
+  for (let k = 0; k < 8; k++) c = c & 1 ? 0xedb88320 ^ (c >>> 1) : c >>> 1;
+  return c >>> 0;
+});
+
+function crc32(data: Buffer): number {
+  let crc = 0xffffffff;
+  for (const byte of data) crc = CRC_TABLE[(crc ^ byte) & 0xff] ^ (crc >>> 8);
+  return (crc ^ 0xffffffff) >>> 0;
+}
@@ -0,0 +1,167 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { parseGoDeclarations } from './context-selector.js';
+import { cyclomaticComplexity } from './method-evaluation.js';
+import { FileChurn } from './co-change.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Heat (0-100) from which a module or file is hot: phased late or refactored by hand */
+export const HOT_HEAT = 60;
+/** Heat from which a module or file is warm: applied with review */
+export const WARM_HEAT = 25;
+/** Files listed in the plan section */
+export const HEATMAP_FILE_LIMIT = 15;
+
+export type HeatLevel = 'hot' | 'warm' | 'cool';
+
+/**
+ * How a module's refactoring should be handled given its heat
+ */
+export type HeatRecommendation = 'phase-late' | 'manual' | 'review' | 'none';
+
+export interface FileHeat {
+  file: string;
+  module: string;
+  /** Commits that changed the file */
+  churn: number;
+  /** Sum of the McCabe complexity of its functions and methods */
+  complexity: number;
+  /** churn × complexity */
+  score: number;
+  /** score scaled so the hottest file scores 100 */
+  heat: number;
+  level: HeatLevel;
+}
+
+export interface ModuleHeat {
+  module: string;
+  churn: number;
+  complexity: number;
+  /** Sum of the scores of its files */
+  score: number;
+  /** score scaled so the hottest module scores 100 */
+  heat: number;
+  level: HeatLevel;
+  /** 1-based migration phase of the module, when it is in one */
+  phase?: number;
+  recommendation: HeatRecommendation;
+}
+
+export interface RiskHeatmap {
+  /** Commits the churn was counted from */
+  history_commits: number;
+  /** Hottest first */
+  modules: ModuleHeat[];
+  /** Hottest files with a score, at most HEATMAP_FILE_LIMIT */
+  files: FileHeat[];
+}
+
+/**
+ * Rank the modules and their files by churn × complexity: code that changes
+ * often and branches a lot is where refactoring breaks things. Hot modules
+ * scheduled in the first half of the phases are recommended to move later,
+ * hot modules already late to be refactored by hand.
+ */
+export function buildRiskHeatmap(
+  projectRoot: string,
+  modules: { name: string; files: string[] }[],
+  churn: FileChurn,
+  phases: { name: string; modules: string[] }[]
+): RiskHeatmap {
+  const files = modules.flatMap(module => module.files
+    .map(file => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file))
+    .filter(file => file.endsWith('.go') && !file.endsWith('_test.go'))
+    .map(file => {
+      const complexity = fileComplexity(projectRoot, file);
+      const changes = churn.changes[file] ?? 0;
+      return { file, module: module.name, churn: changes, complexity, score: changes * complexity };
+    }));
+
+  const hottestFile = Math.max(0, ...files.map(file => file.score));
+  const rankedFiles: FileHeat[] = files
+    .filter(file => file.score > 0)
+    .sort((a, b) => b.score - a.score || a.file.localeCompare(b.file))
+    .slice(0, HEATMAP_FILE_LIMIT)
+    .map(file => ({ ...file, ...heatOf(file.score, hottestFile) }));
+
+  const measured = modules.map(module => {
+    const own = files.filter(file => file.module === module.name);
+    return {
+      module: module.name,
+      churn: own.reduce((sum, file) => sum + file.churn, 0),
+      complexity: own.reduce((sum, file) => sum + file.complexity, 0),
+      score: own.reduce((sum, file) => sum + file.score, 0),
+    };
+  });
+  const hottestModule = Math.max(0, ...measured.map(module => module.score));
+  const lateFrom = Math.ceil(phases.length / 2) + 1;
+  const rankedModules: ModuleHeat[] = measured
+    .map(module => {
+      const { heat, level } = heatOf(module.score, hottestModule);
+      const index = phases.findIndex(phase => phase.modules.includes(module.module));
+      const phase = index >= 0 ? index + 1 : undefined;
+      const recommendation: HeatRecommendation = level === 'hot'
+        ? (phase !== undefined && phases.length > 1 && phase < lateFrom ? 'phase-late' : 'manual')
+        : level === 'warm' ? 'review' : 'none';
+      return { ...module, heat, level, ...(phase !== undefined ? { phase } : {}), recommendation };
+    })
+    .sort((a, b) => b.score - a.score || a.module.localeCompare(b.module));
+
+  return { history_commits: churn.commits, modules: rankedModules, files: rankedFiles };
+}
+
+/**
+ * plan.md section: modules and files ranked by heat with what to do about the hot ones
+ */
+export function renderHeatmapSection(heatmap: RiskHeatmap | undefined): string {
+  if (!heatmap || heatmap.modules.every(module => module.score === 0)) return '';
+
+  const recommendations: Record<HeatRecommendation, string> = {
+    'phase-late': '後半のフェーズに移す',
+    manual: '手動でリファクタリング',
+    review: 'レビュー付きで適用',
+    none: '-',
+  };
+  const moduleRows = heatmap.modules.map((m, index) =>
+    `| ${index + 1} | ${m.module} | ${heatCell(m.heat, m.level)} | ${m.churn} | ${m.complexity} | ${m.phase ?? '-'} | ${recommendations[m.recommendation]} |`);
+  const fileRows = heatmap.files.map((f, index) =>
+    `| ${index + 1} | \`${f.file}\` | ${f.module} | ${heatCell(f.heat, f.level)} | ${f.churn} | ${f.complexity} |`);
+
+  return `
+## リスクヒートマップ (Risk Heat Map)
+
+変更回数（churn）× 循環的複雑度で、リファクタリングで壊しやすい箇所を順位付けしています（Git履歴 ${heatmap.history_commits}コミット、最も高いものを熱度 100 として換算）。🔴 のモジュールは後半のフェーズに回すか、手動でリファクタリングしてください。
+
+| 順位 | モジュール | 熱度 | 変更回数 | 循環的複雑度 | フェーズ | 推奨 |
+|------|------------|------|----------|--------------|----------|------|
+${moduleRows.join('\n')}
+${fileRows.length > 0 ? `
+### ファイル（上位${fileRows.length}件）
+
+| 順位 | ファイル | モジュール | 熱度 | 変更回数 | 循環的複雑度 |
+|------|----------|------------|------|----------|--------------|
+${fileRows.join('\n')}
+` : ''}`;
+}
+
+function heatOf(score: number, hottest: number): { heat: number; level: HeatLevel } {
+  const heat = hottest > 0 ? Math.round((score / hottest) * 100) : 0;
+  return { heat, level: heat >= HOT_HEAT ? 'hot' : heat >= WARM_HEAT ? 'warm' : 'cool' };
+}
+
+function heatCell(heat: number, level: HeatLevel): string {
+  const icon = level === 'hot' ? '🔴' : level === 'warm' ? '🟠' : '🟢';
+  const filled = Math.round(heat / 10);
+  return `${icon} ${'█'.repeat(filled)}${'░'.repeat(10 - filled)} ${heat}`;
+}
+
+function fileComplexity(projectRoot: string, file: string): number {
+  try {
+    const content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+    return parseGoDeclarations(content, file)
+      .filter(decl => decl.kind !== 'type')
+      .reduce((total, decl) => total + cyclomaticComplexity(decl.body), 0);
+  } catch {
+    return 0;
+  }
+}
@@ -0,0 +1,204 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { VibeFlowPaths } from './file-paths.js';
+import { ConfigLoader } from './config-loader.js';
+import { BOUNDARY_EXTRACTION_PROMPT, BOUNDARY_VALIDATION_PROMPT } from '../claude-code/prompts/boundary-agent.js';
+import { ARCHITECTURE_DESIGN_PROMPT } from '../claude-code/prompts/architect-agent.js';
+import { REFACTOR_TRANSFORMATION_PROMPT } from '../claude-code/prompts/refactor-agent.js';
+
+export interface RunArtifact {
+  /** Name inside the snapshot, e.g. domain-map.json or prompts/architecture-design.txt */
+  name: string;
+  /** sha256 of the content; also the blob name in the object store */
+  hash: string;
+  bytes: number;
+}
+
+export interface RunArtifactSnapshot {
+  run_id: number;
+  captured_at: string;
+  artifacts: RunArtifact[];
+}
+
+export type ArtifactDrift = 'same' | 'changed' | 'missing';
+
+export interface ArtifactComparison extends RunArtifact {
+  /** Hash of the current workspace version (null when it no longer exists) */
+  current_hash: string | null;
+  status: ArtifactDrift;
+}
+
+export const PROMPT_TEMPLATES: Record<string, string> = {
+  'prompts/boundary-extraction.txt': BOUNDARY_EXTRACTION_PROMPT,
+  'prompts/boundary-validation.txt': BOUNDARY_VALIDATION_PROMPT,
+  'prompts/architecture-design.txt': ARCHITECTURE_DESIGN_PROMPT,
+  'prompts/refactor-transformation.txt': REFACTOR_TRANSFORMATION_PROMPT,
+};
+
+/**
+ * RunArtifactStore - 実行ごとの入力成果物スナップショット
+ *
+ * Records the exact domain map, plan, boundary.yaml, resolved config and
+ * prompt templates each run used. Contents are stored once under
+ * .vibeflow/run-artifacts/objects/<sha256>; runs/<run-id>.json lists the hashes,
+ * so runs that share inputs share storage.
+ */
+export class RunArtifactStore {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+  private rootDir: string;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+    this.rootDir = path.join(this.paths.outputRootPath, 'run-artifacts');
+  }
+
+  /**
+   * Current workspace versions of the run inputs (missing artifacts are omitted)
+   */
+  collectInputs(): Map<string, string> {
+    const inputs = new Map<string, string>();
+    const files: Record<string, string> = {
+      'domain-map.json': this.paths.domainMapPath,
+      'plan.json': this.paths.planJsonPath,
+      'boundary.yaml': path.join(this.projectRoot, 'boundary.yaml'),
+    };
+
+    for (const [name, filePath] of Object.entries(files)) {
+      if (fs.existsSync(filePath)) inputs.set(name, fs.readFileSync(filePath, 'utf8'));
+    }
+
+    try {
+      const config = ConfigLoader.loadVibeFlowConfig(path.join(this.projectRoot, 'vibeflow.config.yaml'));
+      inputs.set('config.resolved.json', JSON.stringify(config, null, 2));
+    } catch {
+      // Invalid config: the run fails on it anyway
+    }
+
+    for (const [name, template] of Object.entries(PROMPT_TEMPLATES)) {
+      inputs.set(name, template);
+    }
+
+    return inputs;
+  }
+
+  /**
+   * Snapshot the current inputs for a run
+   */
+  capture(runId: number): RunArtifactSnapshot {
+    const artifacts: RunArtifact[] = [];
+
+    for (const [name, content] of this.collectInputs()) {
+      const hash = hashContent(content);
+      const objectPath = this.objectPath(hash);
+      if (!fs.existsSync(objectPath)) {
+        fs.mkdirSync(path.dirname(objectPath), { recursive: true });
+        fs.writeFileSync(objectPath, content);
+      }
+      artifacts.push({ name, hash, bytes: Buffer.byteLength(content, 'utf8') });
+    }
+
+    const snapshot: RunArtifactSnapshot = {
+      run_id: runId,
+      captured_at: new Date().toISOString(),
+      artifacts,
+    };
+    fs.mkdirSync(path.dirname(this.snapshotPath(runId)), { recursive: true });
+    fs.writeFileSync(this.snapshotPath(runId), JSON.stringify(snapshot, null, 2));
+    return snapshot;
+  }
+
+  load(runId: number): RunArtifactSnapshot | null {
+    try {
+      return JSON.parse(fs.readFileSync(this.snapshotPath(runId), 'utf8'));
+    } catch {
+      return null;
+    }
+  }
+
+  listRuns(): number[] {
+    const runsDir = path.join(this.rootDir, 'runs');
+    if (!fs.existsSync(runsDir)) return [];
+    return fs.readdirSync(runsDir)
+      .map(name => name.match(/^(\d+)\.json$/)?.[1])
+      .filter((id): id is string => id !== undefined)
+      .map(Number)
+      .sort((a, b) => a - b);
+  }
+
+  /**
+   * Compare a run's inputs with what the workspace has now
+   */
+  compare(runId: number): ArtifactComparison[] {
+    const snapshot = this.load(runId);
+    if (!snapshot) throw new Error(`No artifact snapshot recorded for run ${runId}`);
+
+    const current = this.collectInputs();
+    return snapshot.artifacts.map(artifact => {
+      const content = current.get(artifact.name);
+      const currentHash = content === undefined ? null : hashContent(content);
+      const status: ArtifactDrift = currentHash === null ? 'missing' : currentHash === artifact.hash ? 'same' : 'changed';
+      return { ...artifact, current_hash: currentHash, status };
+    });
+  }
+
+  /**
+   * Materialize a run's inputs into a directory
+   *
+   * @returns written file paths
+   */
+  extract(runId: number, destination: string): string[] {
+    const snapshot = this.load(runId);
+    if (!snapshot) throw new Error(`No artifact snapshot recorded for run ${runId}`);
+
+    return snapshot.artifacts.map(artifact => {
+      const objectPath = this.objectPath(artifact.hash);
+      if (!fs.existsSync(objectPath)) {
+        throw new Error(`Artifact ${artifact.name} of run ${runId} is missing from the store (${artifact.hash})`);
+      }
+      const target = path.join(destination, ...artifact.name.split('/'));
+      fs.mkdirSync(path.dirname(target), { recursive: true });
+      fs.copyFileSync(objectPath, target);
+      return target;
+    });
+  }
+
+  /**
+   * Drop snapshots of runs no longer kept by the metrics store, then unreferenced objects
+   */
+  prune(retainedRunIds: number[]): { runs: number; objects: number } {
+    const retained = new Set(retainedRunIds);
+    const removedRuns = this.listRuns().filter(runId => !retained.has(runId));
+    removedRuns.forEach(runId => fs.rmSync(this.snapshotPath(runId), { force: true }));
+
+    const referenced = new Set(this.listRuns().flatMap(runId => this.load(runId)?.artifacts.map(a => a.hash) ?? []));
+    const objectsDir = path.join(this.rootDir, 'objects');
+    let removedObjects = 0;
+    if (fs.existsSync(objectsDir)) {
+      for (const prefix of fs.readdirSync(objectsDir)) {
+        for (const name of fs.readdirSync(path.join(objectsDir, prefix))) {
+          if (!referenced.has(name)) {
+            fs.rmSync(path.join(objectsDir, prefix, name), { force: true });
+            removedObjects++;
+          }
+        }
+      }
+    }
+
+    return { runs: removedRuns.length, objects: removedObjects };
+  }
+
+  private snapshotPath(runId: number): string {
+    return path.join(this.rootDir, 'runs', `${runId}.json`);
+  }
+
+  private objectPath(hash: string): string {
+    return path.join(this.rootDir, 'objects', hash.slice(0, 2), hash);
+  }
+}
+
+function hashContent(content: string): string {
+  return createHash('sha256').update(content).digest('hex');
+}
@@ -0,0 +1,216 @@
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { execFileSync } from 'child_process';
+import { createHash } from 'crypto';
+import { fileURLToPath } from 'url';
+import fastGlob from 'fast-glob';
+import { ConfigLoader } from './config-loader.js';
+import { ANALYZER_VERSION } from './analysis-cache.js';
+import { loadGoPackages } from './go-packages.js';
+import { PROMPT_TEMPLATES } from './run-artifacts.js';
+import { getErrorMessage } from './error-utils.js';
+
+export interface RunEnvironment {
+  captured_at: string;
+  vibeflow: { version: string; commit?: string };
+  go: {
+    /** `go version` output, e.g. go1.22.3 linux/amd64 (absent without a toolchain) */
+    version?: string;
+    goflags?: string;
+    build_tags: string[];
+    /** GOOS, GOARCH, CGO_ENABLED, GOPROXY, ... when set */
+    env: Record<string, string>;
+  };
+  platform: { os: string; release: string; arch: string; node: string };
+  llm: {
+    provider: string;
+    model?: string;
+    escalation?: string[];
+    /** Base URL without credentials or query */
+    endpoint?: string;
+  };
+  /** sha256 of the resolved (redacted) configuration */
+  config_hash?: string;
+  /** sha256 of each prompt template, keyed like the run artifacts */
+  prompt_hashes: Record<string, string>;
+  workspace: { files: number; loc: number; packages: number };
+  analyzer_version: number;
+  /** Parts that could not be determined */
+  errors?: string[];
+}
+
+export const REDACTED = '[REDACTED]';
+
+/** Keys whose values are never recorded; token counts and budgets are not secrets */
+const SENSITIVE_KEY = /api[_-]?key|secret|passw(?:or)?d|credential|authorization|cookie|private[_-]?key|access[_-]?key|session[_-]?id|token(?!s|[_-]?(?:budget|count|usage|limit))/i;
+
+const SECRET_PATTERNS: RegExp[] = [
+  /\bsk-ant-[\w-]{8,}/g,
+  /\bsk-[A-Za-z0-9]{20,}/g,
+  /\bgh[pousr]_[A-Za-z0-9]{20,}/g,
+  /\bAKIA[0-9A-Z]{16}\b/g,
+  /\bxox[abprs]-[\w-]{10,}/g,
+];
+
+const GO_ENV = ['GOOS', 'GOARCH', 'CGO_ENABLED', 'GOFLAGS', 'GOPROXY', 'GOPRIVATE', 'GOWORK', 'GOEXPERIMENT'];
+
+/**
+ * Snapshot of everything that shapes a run's output, recorded with the run so a
+ * report can be reproduced. Never throws: unknown parts are listed in `errors`.
+ */
+export function captureRunEnvironment(projectRoot: string): RunEnvironment {
+  const errors: string[] = [];
+  const attempt = <T>(part: string, fn: () => T): T | undefined => {
+    try {
+      return fn();
+    } catch (error) {
+      errors.push(`${part}: ${getErrorMessage(error)}`);
+      return undefined;
+    }
+  };
+
+  const goflags = process.env.GOFLAGS?.trim() || undefined;
+  const config = attempt('config', () => ConfigLoader.loadVibeFlowConfig(path.join(projectRoot, 'vibeflow.config.yaml')));
+  const escalation = config?.llm?.escalation;
+  const model = escalation?.[0] ?? process.env.ANTHROPIC_MODEL ?? process.env.CLAUDE_MODEL;
+  const go = attempt('go', goVersion);
+
+  return {
+    captured_at: new Date().toISOString(),
+    vibeflow: attempt('vibeflow', vibeflowVersion) ?? { version: 'unknown' },
+    go: {
+      ...(go ? { version: go } : {}),
+      ...(goflags ? { goflags: redactText(goflags) } : {}),
+      build_tags: buildTags(goflags),
+      env: Object.fromEntries(GO_ENV.filter(name => process.env[name]).map(name => [name, redactText(process.env[name]!)])),
+    },
+    platform: { os: process.platform, release: os.release(), arch: process.arch, node: process.version },
+    llm: {
+      provider: llmProvider(),
+      ...(model ? { model } : {}),
+      ...(escalation ? { escalation } : {}),
+      ...(process.env.ANTHROPIC_BASE_URL ? { endpoint: sanitizeEndpoint(process.env.ANTHROPIC_BASE_URL) } : {}),
+    },
+    ...(config ? { config_hash: sha256(JSON.stringify(redactSecrets(config))) } : {}),
+    prompt_hashes: Object.fromEntries(Object.entries(PROMPT_TEMPLATES).map(([name, template]) => [name, sha256(template)])),
+    workspace: attempt('workspace', () => workspaceStats(projectRoot)) ?? { files: 0, loc: 0, packages: 0 },
+    analyzer_version: ANALYZER_VERSION,
+    ...(errors.length > 0 ? { errors } : {}),
+  };
+}
+
+/**
+ * Replace the values of sensitive keys and anything shaped like a credential
+ */
+export function redactSecrets<T>(value: T): T {
+  if (typeof value === 'string') return redactText(value) as T;
+  if (Array.isArray(value)) return value.map(item => redactSecrets(item)) as T;
+  if (!value || typeof value !== 'object') return value;
+
+  return Object.fromEntries(Object.entries(value).map(([key, item]) => [
+    key,
+    SENSITIVE_KEY.test(key) && item !== null && item !== undefined && item !== '' && typeof item !== 'object' ? REDACTED : redactSecrets(item),
+  ])) as T;
+}
+
+/**
+ * Redact credentials in free text: `key: value` / `key=value` pairs with sensitive
+ * keys (YAML, .env, flags), bearer tokens, URL user info and known token formats
+ */
+export function redactText(text: string): string {
+  let result = text.replace(/\b(Bearer|Basic)\s+[\w.~+/=-]{8,}/gi, `$1 ${REDACTED}`);
+  result = result.replace(/(["']?)([\w.-]+)\1(\s*[:=]\s*)(["']?)([^\s"',}]+)\4/g, (match, quote, key, separator, valueQuote) =>
+    SENSITIVE_KEY.test(key) ? `${quote}${key}${quote}${separator}${valueQuote}${REDACTED}${valueQuote}` : match);
+  result = result.replace(/(\w+:\/\/)[^\s/@:]+:[^\s/@]+@/g, `$1${REDACTED}@`);
+  for (const pattern of SECRET_PATTERNS) {
+    result = result.replace(pattern, REDACTED);
+  }
+  return result;
+}
+
+export function formatRunEnvironment(environment: RunEnvironment): string[] {
+  const tags = environment.go.build_tags.length > 0 ? `, tags: ${environment.go.build_tags.join(',')}` : '';
+  return [
+    `VibeFlow: ${environment.vibeflow.version}${environment.vibeflow.commit ? ` (${environment.vibeflow.commit.slice(0, 12)})` : ''}, analyzer v${environment.analyzer_version}`,
+    `Go: ${environment.go.version ?? 'not found'}${environment.go.goflags ? `, GOFLAGS=${environment.go.goflags}` : ''}${tags}`,
+    ...Object.entries(environment.go.env).filter(([name]) => name !== 'GOFLAGS').map(([name, value]) => `   ${name}=${value}`),
+    `Platform: ${environment.platform.os} ${environment.platform.release} (${environment.platform.arch}), Node ${environment.platform.node}`,
+    `LLM: ${environment.llm.provider}${environment.llm.model ? ` / ${environment.llm.model}` : ''}${environment.llm.endpoint ? ` @ ${environment.llm.endpoint}` : ''}`
+      + (environment.llm.escalation && environment.llm.escalation.length > 1 ? ` (escalation: ${environment.llm.escalation.join(' → ')})` : ''),
+    `Config: ${environment.config_hash ? environment.config_hash.slice(0, 12) : 'not loaded'}`,
+    `Prompts: ${Object.entries(environment.prompt_hashes).map(([name, hash]) => `${path.posix.basename(name, '.txt')}=${hash.slice(0, 12)}`).join(', ')}`,
+    `Workspace: ${environment.workspace.files} Go files, ${environment.workspace.loc} LOC, ${environment.workspace.packages} packages`,
+    ...(environment.errors ?? []).map(error => `Unknown: ${error}`),
+  ];
+}
+
+/**
+ * Version from the package manifest; the commit when running from a git checkout
+ */
+function vibeflowVersion(): RunEnvironment['vibeflow'] {
+  let dir = path.dirname(fileURLToPath(import.meta.url));
+  while (!fs.existsSync(path.join(dir, 'package.json')) && path.dirname(dir) !== dir) {
+    dir = path.dirname(dir);
+  }
+  const manifest = JSON.parse(fs.readFileSync(path.join(dir, 'package.json'), 'utf8'));
+
+  let commit: string | undefined = typeof manifest.gitHead === 'string' ? manifest.gitHead : undefined;
+  if (!commit && fs.existsSync(path.join(dir, '.git'))) {
+    try {
+      commit = execFileSync('git', ['rev-parse', 'HEAD'], { cwd: dir, encoding: 'utf8', stdio: ['ignore', 'pipe', 'ignore'], timeout: 5000 }).trim();
+    } catch {
+      // Not a usable checkout
+    }
+  }
+  return { version: String(manifest.version ?? 'unknown'), ...(commit ? { commit } : {}) };
+}
+
+function goVersion(): string {
+  return execFileSync('go', ['version'], { encoding: 'utf8', stdio: ['ignore', 'pipe', 'ignore'], timeout: 10000 })
+    .trim()
+    .replace(/^go version /, '');
+}
+
+function buildTags(goflags: string | undefined): string[] {
+  const match = goflags?.match(/(?:^|\s)-?-tags(?:=|\s+)(\S+)/);
+  return match ? match[1].split(',').filter(Boolean) : [];
+}
+
+/**
+ * Provider the Claude Code SDK talks to, from its environment switches
+ */
+export function llmProvider(): string {
+  if (process.env.CLAUDE_CODE_USE_BEDROCK) return 'bedrock';
+  if (process.env.CLAUDE_CODE_USE_VERTEX) return 'vertex';
+  return 'anthropic (claude-code sdk)';
+}
+
+function sanitizeEndpoint(endpoint: string): string {
+  try {
+    const url = new URL(endpoint);
+    return `${url.protocol}//${url.host}${url.pathname === '/' ? '' : url.pathname}`;
+  } catch {
+    return redactText(endpoint);
+  }
+}
+
+function workspaceStats(projectRoot: string): RunEnvironment['workspace'] {
+  const files = fastGlob.sync('**/*.go', {
+    cwd: projectRoot,
+    ignore: ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**', '**/testdata/**'],
+  });
+  let loc = 0;
+  for (const file of files) {
+    try {
+      loc += fs.readFileSync(path.join(projectRoot, file), 'utf8').split('\n').length;
+    } catch {
+      // Removed while counting
+    }
+  }
+  return { files: files.length, loc, packages: loadGoPackages(projectRoot).length };
+}
+
+function sha256(content: string): string {
+  return createHash('sha256').update(content).digest('hex');
+}
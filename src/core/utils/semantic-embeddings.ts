@@ -0,0 +1,209 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { createHash } from 'crypto';
+import { EmbeddingsConfig } from '../types/config.js';
+import { maskLiterals } from './api-surface.js';
+import { assertOnline } from './offline-guard.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Share of the clustering edge weight two files with identical embeddings add */
+export const DEFAULT_EMBEDDING_WEIGHT = 0.3;
+/** Embeddings of any two source files are somewhat similar; below this they are unrelated */
+export const DEFAULT_MIN_SIMILARITY = 0.5;
+
+const DEFAULT_MODELS: Record<EmbeddingsConfig['provider'], string> = {
+  ollama: 'nomic-embed-text',
+  openai: 'text-embedding-3-small',
+};
+const DEFAULT_ENDPOINTS: Record<EmbeddingsConfig['provider'], string> = {
+  ollama: 'http://localhost:11434',
+  openai: 'https://api.openai.com',
+};
+const DEFAULT_API_KEY_ENV = 'OPENAI_API_KEY';
+
+/** Texts sent per request */
+const BATCH_SIZE = 64;
+/** Characters embedded per file (well within the context of common embedding models) */
+const MAX_TEXT_LENGTH = 8000;
+
+/**
+ * Embeds texts; the vectors come back in the order of the texts
+ */
+export type Embedder = (texts: string[]) => Promise<number[][]>;
+
+interface EmbeddingCache {
+  provider: string;
+  model: string;
+  /** sha256 of the embedded text → vector */
+  vectors: Record<string, number[]>;
+}
+
+/**
+ * What a file is about, as text to embed: the words of its path, package name,
+ * declared identifiers (OrderService reads "order service") and comments.
+ * String literals and the code itself are left out.
+ */
+export function embeddingText(file: string, content: string): string {
+  const words = (identifier: string) => identifier
+    .replace(/([a-z0-9])([A-Z])/g, '$1 $2')
+    .replace(/([A-Z]+)([A-Z][a-z])/g, '$1 $2')
+    .replace(/[_\-./]+/g, ' ')
+    .toLowerCase()
+    .trim();
+
+  const comments = [...maskLiterals(content, { keepComments: true }).matchAll(/\/\/(.*)$|\/\*([\s\S]*?)\*\//gm)]
+    .map(match => (match[1] ?? match[2]).trim())
+    .filter(comment => comment && !/^(?:go:|vf:|nolint|\+build)/.test(comment));
+  const masked = maskLiterals(content);
+  const pkg = masked.match(/^package\s+(\w+)/m)?.[1];
+  const identifiers = [
+    ...masked.matchAll(/^func\s+(?:\([^)]*\)\s*)?(\w+)/gm),
+    ...masked.matchAll(/^(?:type|const|var)\s+(\w+)/gm),
+    ...masked.matchAll(/^\t(\w+)\s+(?:struct|interface)\s*\{/gm),
+  ].map(match => words(match[1]));
+
+  return [
+    words(toPosixPath(file).replace(/\.go$/, '')),
+    ...(pkg ? [`package ${words(pkg)}`] : []),
+    [...new Set(identifiers)].join(', '),
+    ...comments,
+  ].filter(line => line.length > 0).join('\n').slice(0, MAX_TEXT_LENGTH);
+}
+
+export function cosineSimilarity(a: number[], b: number[]): number {
+  let dot = 0;
+  let normA = 0;
+  let normB = 0;
+  for (let i = 0; i < Math.min(a.length, b.length); i++) {
+    dot += a[i] * b[i];
+    normA += a[i] * a[i];
+    normB += b[i] * b[i];
+  }
+  return normA === 0 || normB === 0 ? 0 : dot / Math.sqrt(normA * normB);
+}
+
+/**
+ * Semantic similarity of files from their embeddings, rescaled so that
+ * minSimilarity counts as 0 and identical files as 1
+ */
+export class EmbeddingIndex {
+  private vectors = new Map<string, number[]>();
+  private strengths = new Map<string, number>();
+
+  constructor(vectors: Map<string, number[]>, private minSimilarity = DEFAULT_MIN_SIMILARITY) {
+    for (const [file, vector] of vectors) this.vectors.set(toPosixPath(file), vector);
+  }
+
+  get size(): number {
+    return this.vectors.size;
+  }
+
+  strength(file1: string, file2: string): number {
+    const a = toPosixPath(file1);
+    const b = toPosixPath(file2);
+    if (a === b) return 0;
+    const key = a < b ? `${a}\n${b}` : `${b}\n${a}`;
+    let strength = this.strengths.get(key);
+    if (strength === undefined) {
+      const vectorA = this.vectors.get(a);
+      const vectorB = this.vectors.get(b);
+      const similarity = vectorA && vectorB ? cosineSimilarity(vectorA, vectorB) : 0;
+      strength = similarity <= this.minSimilarity ? 0 : (similarity - this.minSimilarity) / (1 - this.minSimilarity);
+      this.strengths.set(key, strength);
+    }
+    return strength;
+  }
+}
+
+/**
+ * Embedder for the configured provider: a local Ollama server (/api/embed) or an
+ * OpenAI-compatible embeddings API (/v1/embeddings, key from apiKeyEnv)
+ */
+export function createEmbedder(config: EmbeddingsConfig): Embedder {
+  const model = config.model ?? DEFAULT_MODELS[config.provider];
+  const endpoint = (config.endpoint ?? DEFAULT_ENDPOINTS[config.provider]).replace(/\/$/, '');
+
+  if (config.provider === 'ollama') {
+    return async texts => {
+      const result = await post(`${endpoint}/api/embed`, { model, input: texts }, {});
+      return result.embeddings;
+    };
+  }
+  return async texts => {
+    const apiKeyEnv = config.apiKeyEnv ?? DEFAULT_API_KEY_ENV;
+    const apiKey = process.env[apiKeyEnv];
+    if (!apiKey) throw new Error(`${apiKeyEnv} is not set`);
+    const result = await post(`${endpoint}/v1/embeddings`, { model, input: texts }, { authorization: `Bearer ${apiKey}` });
+    return [...result.data].sort((a: any, b: any) => a.index - b.index).map((item: any) => item.embedding);
+  };
+}
+
+/**
+ * Embedding of each file, reusing the vectors of unchanged texts from the cache
+ * (dropped when the provider or model changes). Files that cannot be read are left out.
+ */
+export async function embedFiles(
+  projectRoot: string,
+  files: string[],
+  config: EmbeddingsConfig,
+  cachePath: string,
+  embed: Embedder = createEmbedder(config)
+): Promise<Map<string, number[]>> {
+  const model = config.model ?? DEFAULT_MODELS[config.provider];
+  const cache = loadCache(cachePath, config.provider, model);
+
+  const texts = new Map<string, string>();
+  for (const file of [...new Set(files.map(toPosixPath))].sort()) {
+    try {
+      texts.set(file, embeddingText(file, fs.readFileSync(path.join(projectRoot, file), 'utf8')));
+    } catch {
+      continue;
+    }
+  }
+
+  const hashes = new Map([...texts].map(([file, text]) => [file, hashText(text)]));
+  const missing = [...new Set([...texts].filter(([file]) => !cache.vectors[hashes.get(file)!]).map(([, text]) => text))];
+  for (let i = 0; i < missing.length; i += BATCH_SIZE) {
+    const batch = missing.slice(i, i + BATCH_SIZE);
+    const vectors = await embed(batch);
+    if (vectors.length !== batch.length) {
+      throw new Error(`expected ${batch.length} embeddings, got ${vectors.length}`);
+    }
+    batch.forEach((text, index) => (cache.vectors[hashText(text)] = vectors[index]));
+  }
+
+  // Only the vectors of the current files are kept
+  const used = new Set(hashes.values());
+  cache.vectors = Object.fromEntries(Object.entries(cache.vectors).filter(([hash]) => used.has(hash)));
+  fs.mkdirSync(path.dirname(cachePath), { recursive: true });
+  fs.writeFileSync(cachePath, JSON.stringify(cache));
+
+  return new Map([...hashes].map(([file, hash]) => [file, cache.vectors[hash]]));
+}
+
+async function post(url: string, body: unknown, headers: Record<string, string>): Promise<any> {
+  assertOnline(`embeddings API ${url}`);
+  const response = await fetch(url, {
+    method: 'POST',
+    headers: { 'content-type': 'application/json', ...headers },
+    body: JSON.stringify(body),
+  });
+  if (!response.ok) {
+    throw new Error(`embeddings request to ${url} failed: HTTP ${response.status} ${(await response.text()).slice(0, 200)}`);
+  }
+  return response.json();
+}
+
+function loadCache(cachePath: string, provider: string, model: string): EmbeddingCache {
+  try {
+    const cache = JSON.parse(fs.readFileSync(cachePath, 'utf8')) as EmbeddingCache;
+    if (cache.provider === provider && cache.model === model && cache.vectors) return cache;
+  } catch {
+    // No cache yet, or unreadable: embed everything
+  }
+  return { provider, model, vectors: {} };
+}
+
+function hashText(text: string): string {
+  return createHash('sha256').update(text).digest('hex');
+}
@@ -0,0 +1,543 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { SourceFile, SourceLocation, formatLocation } from './source-positions.js';
+import { detectGoProject, goImportAlias, goPackageImportPath } from './go-project-utils.js';
+import { goPackageName } from './go-load-check.js';
+import { maskLiterals } from './api-surface.js';
+import { toPosixPath } from './workspace-paths.js';
+import { round } from './number-utils.js';
+
+/**
+ * Target deployability of a module: part of the modular monolith, or a
+ * separately deployed service with stricter rules
+ */
+export type ModuleDeployment = 'monolith' | 'service';
+
+export const MODULE_DEPLOYMENTS: ModuleDeployment[] = ['monolith', 'service'];
+
+export interface ServiceModule {
+  name: string;
+  /** Files relative to the project root */
+  files: string[];
+  deployment?: ModuleDeployment;
+  owned_tables?: string[];
+}
+
+/**
+ * A query on a table owned by another module, where one side is a service.
+ * Across a service boundary the database is not shared, so it has to go
+ * through the owning module's interface or events.
+ */
+export interface ForeignTableAccess {
+  /** Module whose code runs the query */
+  module: string;
+  table: string;
+  /** Module owning the table */
+  owner: string;
+  /** Enclosing function ('' at package level) */
+  function: string;
+  location: SourceLocation;
+}
+
+/**
+ * A call from another module into the service that becomes a network call
+ */
+export interface NetworkCallSite {
+  caller: string;
+  /** Enclosing function of the call ('' at package level) */
+  function: string;
+  /** Called operation, `<package>.<Function>` */
+  operation: string;
+  location: SourceLocation;
+  /** Estimated calls per request path, when a runtime profile exists */
+  per_request?: Record<string, number>;
+}
+
+export interface ServiceOperation {
+  /** Exported function of the service, `<package>.<Function>` */
+  name: string;
+  /** rpc name in the API definition */
+  rpc: string;
+  /** Go signature, e.g. `(ctx context.Context, userID string) error` */
+  signature: string;
+  /** Modules calling it */
+  callers: string[];
+}
+
+export interface ServiceRequirements {
+  module: string;
+  /** API definition covering every operation called from other modules */
+  api: { protocol: 'grpc'; path: string; operations: ServiceOperation[] };
+  /** Dedicated entry point with its own composition root */
+  entrypoint: string;
+  /** Scaffolds written under .vibeflow/services/<module>/, relative to the project root */
+  scaffolds?: string[];
+  foreign_table_access: ForeignTableAccess[];
+  network_calls: NetworkCallSite[];
+  /** Sum of the estimated network calls per request path */
+  network_calls_per_request?: Record<string, number>;
+}
+
+/**
+ * .vibeflow/runtime-profile.json: how often each function runs per request
+ * path, keyed `<package>.<function>` (methods by their name), e.g.
+ * `{ "requests": { "POST /orders": { "order.PlaceOrder": 1 } } }`
+ */
+export interface RuntimeProfile {
+  requests: Record<string, Record<string, number>>;
+}
+
+/**
+ * An operation with the Go package declaring it
+ */
+export interface ModuleOperation extends ServiceOperation {
+  package: { dir: string; import_path: string; name: string };
+}
+
+interface ServicePackage {
+  dir: string;
+  importPath: string;
+  name: string;
+  functions: Map<string, string>;
+}
+
+export const SERVICE_HEADING = '## サービス化要件 (Separately Deployable Services)';
+
+/**
+ * Parse `--deployment notifications=service,reporting=service`
+ */
+export function parseDeployments(value: string): Record<string, ModuleDeployment> {
+  const deployments: Record<string, ModuleDeployment> = {};
+  for (const entry of value.split(',').map(e => e.trim()).filter(Boolean)) {
+    const [name, target] = entry.split('=').map(part => part?.trim());
+    if (!name || !MODULE_DEPLOYMENTS.includes(target as ModuleDeployment)) {
+      throw new Error(`Invalid deployment '${entry}' (expected <module>=${MODULE_DEPLOYMENTS.join('|')})`);
+    }
+    deployments[name] = target as ModuleDeployment;
+  }
+  return deployments;
+}
+
+/**
+ * Deployment of every module: the override, else what the previous plan.json
+ * recorded for the same module (by ID, then name), else the default (the
+ * microservice extraction proposal), else monolith
+ *
+ * @returns the modules and override names that match no module
+ */
+export function resolveDeployments<T extends { id?: string; name: string }>(
+  modules: T[],
+  previous: { id?: string; name: string; deployment?: ModuleDeployment }[] = [],
+  overrides: Record<string, ModuleDeployment> = {},
+  defaults: Record<string, ModuleDeployment> = {}
+): { modules: (T & { deployment: ModuleDeployment })[]; unknown: string[] } {
+  const recorded = (module: T) =>
+    previous.find(p => module.id && p.id === module.id)?.deployment ?? previous.find(p => p.name === module.name)?.deployment;
+
+  return {
+    modules: modules.map(module => ({
+      ...module,
+      deployment: overrides[module.name] ?? (module.id ? overrides[module.id] : undefined) ?? recorded(module) ?? defaults[module.name] ?? 'monolith',
+    })),
+    unknown: Object.keys(overrides).filter(name => !modules.some(m => m.name === name || m.id === name)),
+  };
+}
+
+export function loadRuntimeProfile(projectRoot: string): RuntimeProfile | null {
+  try {
+    const profile = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).runtimeProfilePath, 'utf8'));
+    return profile && typeof profile.requests === 'object' && profile.requests !== null ? profile : null;
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Requirements of every service module: the operations other modules call
+ * (each becomes an rpc of the API definition and each call site a network
+ * call), its entry point, and queries on tables owned across the service boundary.
+ */
+export function analyzeServiceRequirements(
+  projectRoot: string,
+  modules: ServiceModule[],
+  profile: RuntimeProfile | null = loadRuntimeProfile(projectRoot)
+): ServiceRequirements[] {
+  const services = modules.filter(m => m.deployment === 'service');
+  if (services.length === 0) return [];
+
+  const sources = readModuleSources(projectRoot, modules);
+  const owners = tableOwners(modules);
+
+  return services.map(service => {
+    const api = findModuleOperations(projectRoot, modules, service.name, profile, sources);
+
+    const foreignTableAccess = modules.flatMap(module => (sources.get(module.name) ?? []).flatMap(source =>
+      tableReferences(source)
+        .map(ref => ({ module: module.name, owner: owners.get(ref.table), ...ref }))
+        .filter((ref): ref is ForeignTableAccess => ref.owner !== undefined && ref.owner !== module.name &&
+          (module.name === service.name || ref.owner === service.name))));
+
+    const slug = serviceSlug(service.name);
+    const perRequest = sumPerRequest(api.calls);
+    return {
+      module: service.name,
+      api: {
+        protocol: 'grpc' as const,
+        path: `api/${slug}/v1/${slug}.proto`,
+        operations: api.operations.map(({ name, rpc, signature, callers }) => ({ name, rpc, signature, callers })),
+      },
+      entrypoint: `cmd/${slug}/main.go`,
+      foreign_table_access: foreignTableAccess,
+      network_calls: api.calls,
+      ...(perRequest ? { network_calls_per_request: perRequest } : {}),
+    };
+  });
+}
+
+/**
+ * Exported functions of `moduleName` called from the other modules, each with
+ * the package declaring it, and the call sites. Two packages declaring the same
+ * function name get their operation named after the package as well.
+ */
+export function findModuleOperations(
+  projectRoot: string,
+  modules: ServiceModule[],
+  moduleName: string,
+  profile: RuntimeProfile | null = null,
+  sources: Map<string, SourceFile[]> = readModuleSources(projectRoot, modules)
+): { operations: ModuleOperation[]; calls: NetworkCallSite[] } {
+  const packages = servicePackages(projectRoot, sources.get(moduleName) ?? [], detectGoProject(projectRoot));
+  const dirs = new Set(packages.map(pkg => pkg.dir));
+
+  const calls = modules
+    .filter(module => module.name !== moduleName)
+    .flatMap(module => (sources.get(module.name) ?? [])
+      .filter(source => !dirs.has(path.posix.dirname(source.file)))
+      .flatMap(source => findServiceCalls(source, module.name, packages, profile)));
+
+  const operations = packages.flatMap(pkg => [...pkg.functions].map(([name, signature]) => ({ pkg, name, signature })))
+    .map(({ pkg, name, signature }) => ({
+      name: `${pkg.name}.${name}`,
+      rpc: name,
+      signature,
+      callers: [...new Set(calls.filter(c => c.operation === `${pkg.name}.${name}`).map(c => c.caller))].sort(),
+      package: { dir: pkg.dir, import_path: pkg.importPath, name: pkg.name },
+    }))
+    .filter(operation => operation.callers.length > 0);
+  // Same function name in two packages of the module
+  const duplicated = new Set(operations.map(o => o.rpc).filter((rpc, i, all) => all.indexOf(rpc) !== i));
+  operations.filter(o => duplicated.has(o.rpc)).forEach(o => { o.rpc = pascalCase(o.name); });
+
+  return { operations, calls };
+}
+
+/**
+ * Write the API definition and entry point scaffolds to .vibeflow/services/<module>/
+ * under their target paths
+ *
+ * @returns written paths relative to the project root
+ */
+export function writeServiceScaffolds(projectRoot: string, requirements: ServiceRequirements): string[] {
+  const root = path.join(new VibeFlowPaths(projectRoot).servicesDir, serviceSlug(requirements.module));
+  const goProject = detectGoProject(projectRoot);
+  const files: [string, string][] = [
+    [requirements.api.path, renderProtoDefinition(requirements, goProject.moduleName)],
+    [requirements.entrypoint, renderServiceEntrypoint(requirements, goProject.moduleName)],
+  ];
+
+  return files.map(([target, content]) => {
+    const filePath = path.join(root, target);
+    fs.mkdirSync(path.dirname(filePath), { recursive: true });
+    fs.writeFileSync(filePath, content);
+    return toPosixPath(path.relative(projectRoot, filePath));
+  });
+}
+
+/**
+ * gRPC API definition with one rpc per operation called from other modules.
+ * Parameters and results of basic types become message fields; others are
+ * left as TODO comments to map by hand.
+ */
+export function renderProtoDefinition(requirements: ServiceRequirements, goModule?: string): string {
+  const slug = serviceSlug(requirements.module);
+  const service = `${pascalCase(requirements.module)}Service`;
+  const rpcs = requirements.api.operations.map(op => {
+    const callers = requirements.network_calls.filter(c => c.operation === op.name);
+    return [
+      `  // ${op.name}${op.signature} — called from ${callers.map(c => formatLocation(c.location)).join(', ')}`,
+      `  rpc ${op.rpc}(${op.rpc}Request) returns (${op.rpc}Response);`,
+    ].join('\n');
+  });
+  const messages = requirements.api.operations.flatMap(op => {
+    const { params, results } = splitSignature(op.signature);
+    return [renderMessage(`${op.rpc}Request`, params), renderMessage(`${op.rpc}Response`, results)];
+  });
+
+  return `// Generated by vf plan for the ${requirements.module} service; review before moving it to ${requirements.api.path}.
+syntax = "proto3";
+
+package ${slug}.v1;
+${goModule ? `\noption go_package = "${goModule}/api/${slug}/v1;${slug}v1";\n` : ''}
+service ${service} {
+${rpcs.join('\n\n')}
+}
+${messages.map(message => `\n${message}`).join('')}`;
+}
+
+/**
+ * cmd/<service>/main.go scaffold: the service's own composition root
+ */
+export function renderServiceEntrypoint(requirements: ServiceRequirements, goModule?: string): string {
+  const slug = serviceSlug(requirements.module);
+  const env = `${slug.toUpperCase()}_ADDR`;
+  return `// Command ${slug} runs the ${requirements.module} module as a separately deployed service.
+// Generated by vf plan; review before moving it to ${requirements.entrypoint}.
+package main
+
+import (
+	"log"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+)
+
+func main() {
+	addr := os.Getenv("${env}")
+	if addr == "" {
+		addr = ":50051"
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("listen %s: %v", addr, err)
+	}
+
+	server := grpc.NewServer()
+
+	// Composition root: construct the ${requirements.module} repositories and usecases
+	// (${goModule ?? '<module>'}/internal/${slug}/...) with their own database connection,
+	// and register the generated ${pascalCase(requirements.module)}Service server.
+	// Do not import the internals of other modules; reach them through their APIs or events.
+
+	log.Printf("${slug} listening on %s", addr)
+	if err := server.Serve(listener); err != nil {
+		log.Fatal(err)
+	}
+}
+`;
+}
+
+/**
+ * plan.md section with the requirements of each service module
+ */
+export function renderServiceSection(requirements: ServiceRequirements[]): string {
+  if (requirements.length === 0) return '';
+
+  const entries = requirements.map(req => {
+    const perRequest = Object.entries(req.network_calls_per_request ?? {})
+      .sort(([a], [b]) => a.localeCompare(b))
+      .map(([request, count]) => `\`${request}\` 約${round(count, 2)}回`);
+    return [
+      `### ${req.module}`,
+      '',
+      `- API定義 (gRPC): \`${req.api.path}\` — ${req.api.operations.length}個の rpc`,
+      ...req.api.operations.map(op => `  - \`${op.rpc}\` ← \`${op.name}${op.signature}\` (呼び出し元: ${op.callers.join(', ')})`),
+      `- エントリポイント: \`${req.entrypoint}\` (専用のコンポジションルート)`,
+      ...(req.scaffolds && req.scaffolds.length > 0 ? [`- 生成したひな形: ${req.scaffolds.map(s => `\`${s}\``).join(', ')}`] : []),
+      req.foreign_table_access.length > 0
+        ? `- ⛔ 他モジュール所有テーブルへの直接アクセス: ${req.foreign_table_access.length}件 (所有モジュールのインターフェースまたはイベント経由に置き換え)`
+        : '- 他モジュール所有テーブルへの直接アクセス: なし',
+      ...req.foreign_table_access.map(a => `  - ${a.module} → \`${a.table}\` (所有: ${a.owner}) — ${formatLocation(a.location)}${a.function ? ` \`${a.function}\`` : ''}`),
+      `- ネットワーク呼び出しになる呼び出し箇所: ${req.network_calls.length}件${perRequest.length > 0 ? ` (リクエストあたり: ${perRequest.join(', ')})` : ''}`,
+      ...req.network_calls.map(c => `  - ${formatLocation(c.location)} ${c.caller}${c.function ? `.${c.function}` : ''} → \`${c.operation}\``),
+    ].join('\n');
+  });
+
+  return `
+${SERVICE_HEADING}
+
+\`deployment: service\` のモジュールは独立してデプロイされるため、モジュラーモノリスより厳しいルールが適用されます (\`vf check\` で検査)。
+他モジュール所有のテーブルには直接アクセスせず、モノリスからの呼び出しはすべて API 定義を経由します。
+
+${entries.join('\n\n')}
+`;
+}
+
+/**
+ * Non-test Go sources of each module, read once for several findModuleOperations calls
+ */
+export function readModuleSources(projectRoot: string, modules: ServiceModule[]): Map<string, SourceFile[]> {
+  return new Map<string, SourceFile[]>(modules.map(module => [module.name, module.files
+    .map(file => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file))
+    .filter(file => file.endsWith('.go') && !file.endsWith('_test.go'))
+    .sort()
+    .map(file => SourceFile.read(projectRoot, file))
+    .filter((source): source is SourceFile => source !== null)]));
+}
+
+function servicePackages(projectRoot: string, sources: SourceFile[], goProject: ReturnType<typeof detectGoProject>): ServicePackage[] {
+  const packages = new Map<string, ServicePackage>();
+  for (const source of sources) {
+    const dir = path.posix.dirname(source.file);
+    const importPath = goPackageImportPath(projectRoot, dir, goProject);
+    const name = goPackageName(source.content);
+    if (!importPath || !name || name === 'main') continue;
+
+    const pkg = packages.get(dir) ?? { dir, importPath, name, functions: new Map<string, string>() };
+    for (const match of maskLiterals(source.content).matchAll(/^func\s+([A-Z]\w*)\s*(\([^)]*\)[^{\n]*)/gm)) {
+      pkg.functions.set(match[1], match[2].trim());
+    }
+    packages.set(dir, pkg);
+  }
+  return [...packages.values()];
+}
+
+function findServiceCalls(
+  source: SourceFile,
+  caller: string,
+  packages: ServicePackage[],
+  profile: RuntimeProfile | null
+): NetworkCallSite[] {
+  const code = maskLiterals(source.content);
+  const callerPackage = goPackageName(source.content) ?? path.posix.basename(path.posix.dirname(source.file));
+  const calls: NetworkCallSite[] = [];
+
+  for (const pkg of packages) {
+    const alias = goImportAlias(source.content, pkg.importPath, pkg.name);
+    if (!alias || alias === '_' || alias === '.' || pkg.functions.size === 0) continue;
+
+    const pattern = new RegExp(`(?<![\\w.])${alias}\\.(${[...pkg.functions.keys()].join('|')})\\s*\\(`, 'g');
+    for (const match of code.matchAll(pattern)) {
+      const start = match.index! + alias.length + 1;
+      const fn = enclosingFunction(code, match.index!);
+      const perRequest = fn ? requestCounts(profile, `${callerPackage}.${fn}`) : undefined;
+      calls.push({
+        caller,
+        function: fn,
+        operation: `${pkg.name}.${match[1]}`,
+        location: source.locate(start, start + match[1].length),
+        ...(perRequest ? { per_request: perRequest } : {}),
+      });
+    }
+  }
+  return calls;
+}
+
+/**
+ * Tables named in SQL string literals (FROM/INTO/UPDATE/JOIN)
+ */
+function tableReferences(source: SourceFile): { table: string; function: string; location: SourceLocation }[] {
+  const code = maskLiterals(source.content, { keepComments: true });
+  const references: { table: string; function: string; location: SourceLocation }[] = [];
+
+  for (const literal of source.content.matchAll(/"(?:[^"\\\n]|\\.)*"|`[^`]*`/g)) {
+    for (const match of literal[0].matchAll(/\b(?:FROM|INTO|UPDATE|JOIN)\s+[`"]?(\w+)/gi)) {
+      const start = literal.index! + match.index! + match[0].length - match[1].length;
+      references.push({
+        table: match[1].toLowerCase(),
+        function: enclosingFunction(code, literal.index!),
+        location: source.locate(start, start + match[1].length),
+      });
+    }
+  }
+  return references;
+}
+
+function enclosingFunction(code: string, index: number): string {
+  let name = '';
+  for (const match of code.slice(0, index).matchAll(/^func\s*(?:\([^)]*\)\s*)?(\w+)|^\}/gm)) {
+    name = match[1] ?? '';
+  }
+  return name;
+}
+
+/**
+ * Runs of `key` per request path; each run is assumed to make the call once
+ */
+function requestCounts(profile: RuntimeProfile | null, key: string): Record<string, number> | undefined {
+  const counts: Record<string, number> = {};
+  for (const [request, functions] of Object.entries(profile?.requests ?? {})) {
+    if (typeof functions?.[key] === 'number') counts[request] = functions[key];
+  }
+  return Object.keys(counts).length > 0 ? counts : undefined;
+}
+
+function sumPerRequest(calls: NetworkCallSite[]): Record<string, number> | undefined {
+  const totals: Record<string, number> = {};
+  for (const call of calls) {
+    for (const [request, count] of Object.entries(call.per_request ?? {})) totals[request] = (totals[request] ?? 0) + count;
+  }
+  return Object.keys(totals).length > 0 ? totals : undefined;
+}
+
+function tableOwners(modules: ServiceModule[]): Map<string, string> {
+  const owners = new Map<string, string>();
+  for (const module of modules) {
+    (module.owned_tables ?? []).forEach(table => {
+      if (!owners.has(table.toLowerCase())) owners.set(table.toLowerCase(), module.name);
+    });
+  }
+  return owners;
+}
+
+const PROTO_TYPES: Record<string, string> = {
+  string: 'string', bool: 'bool', int: 'int64', int64: 'int64', int32: 'int32', uint: 'uint64', uint64: 'uint64',
+  uint32: 'uint32', float64: 'double', float32: 'float', '[]byte': 'bytes',
+};
+
+function renderMessage(name: string, fields: { name: string; type: string }[]): string {
+  let number = 0;
+  const lines = fields
+    .filter(field => field.type !== 'context.Context' && field.type !== 'error')
+    .map(field => {
+      const repeated = field.type.startsWith('[]') && field.type !== '[]byte';
+      const proto = PROTO_TYPES[repeated ? field.type.slice(2) : field.type];
+      if (!proto) return `  // TODO: map Go type ${field.type}${field.name ? ` (${field.name})` : ''}`;
+      number++;
+      return `  ${repeated ? 'repeated ' : ''}${proto} ${snakeCase(field.name || `result_${number}`)} = ${number};`;
+    });
+  return `message ${name} {\n${lines.join('\n')}${lines.length > 0 ? '\n' : ''}}\n`;
+}
+
+/**
+ * Named parameters and results of a Go signature; grouped names (`a, b int`) are expanded
+ */
+export function splitSignature(signature: string): { params: { name: string; type: string }[]; results: { name: string; type: string }[] } {
+  const close = signature.indexOf(')');
+  const params = parseFields(signature.slice(1, close));
+  const rest = signature.slice(close + 1).trim();
+  const results = rest.startsWith('(') ? parseFields(rest.slice(1, rest.lastIndexOf(')'))) : rest ? [{ name: '', type: rest }] : [];
+  return { params, results };
+}
+
+function parseFields(list: string): { name: string; type: string }[] {
+  const parts = list.split(',').map(p => p.trim()).filter(Boolean);
+  const fields: { name: string; type: string }[] = [];
+  let pending: string[] = [];
+  for (const part of parts) {
+    const match = part.match(/^(\w+)\s+(.+)$/);
+    if (!match) {
+      // Either a name waiting for its type or an unnamed type
+      pending.push(part);
+      continue;
+    }
+    fields.push(...pending.map(name => ({ name, type: match[2] })), { name: match[1], type: match[2] });
+    pending = [];
+  }
+  fields.push(...pending.map(type => ({ name: '', type })));
+  return fields;
+}
+
+function serviceSlug(name: string): string {
+  return name.toLowerCase().replace(/[^a-z0-9]/g, '') || 'service';
+}
+
+function pascalCase(name: string): string {
+  return name.split(/[^A-Za-z0-9]+/).filter(Boolean).map(part => part[0].toUpperCase() + part.slice(1)).join('');
+}
+
+function snakeCase(name: string): string {
+  return name.replace(/([a-z0-9])([A-Z])/g, '$1_$2').toLowerCase();
+}
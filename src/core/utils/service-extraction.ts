@@ -0,0 +1,245 @@
+import * as fs from 'fs';
+import { AsyncEdge, BoundaryCycle, GlobalCoupling, OrchestratorCandidate } from '../types/config.js';
+import { VibeFlowPaths } from './file-paths.js';
+import { ModuleDeployment, ServiceOperation, ServiceRequirements } from './service-deployment.js';
+
+/**
+ * What vf plan targets: a modular monolith (services only where marked with
+ * --deployment), or microservices, where the plan proposes which modules to extract
+ */
+export type PlanTarget = 'modular-monolith' | 'microservices';
+
+export const PLAN_TARGETS: PlanTarget[] = ['modular-monolith', 'microservices'];
+
+/** Lowest score of a module proposed as a service */
+export const DEFAULT_EXTRACTION_THRESHOLD = 60;
+
+/**
+ * How the rest of the system would talk to an extracted service: synchronous
+ * calls through its API, the message topics it already uses, both, or neither
+ */
+export type CommunicationStyle = 'sync' | 'async' | 'mixed' | 'none';
+
+export interface ExtractionModule {
+  name: string;
+  files: string[];
+  lines_of_code: number;
+  /** Modules it calls (code dependencies) */
+  depends_on: string[];
+  owned_tables?: string[];
+  status?: 'established';
+}
+
+/**
+ * Domain map findings that make splitting a module across a network harder
+ */
+export interface ExtractionFindings {
+  cycles?: BoundaryCycle[];
+  orchestrators?: OrchestratorCandidate[];
+  async_edges?: AsyncEdge[];
+  global_coupling?: GlobalCoupling[];
+}
+
+export interface ServiceCandidate {
+  module: string;
+  /** 0 (keep in the monolith) .. 100 (clean cut) */
+  score: number;
+  recommended: boolean;
+  /** What speaks for extracting it */
+  reasons: string[];
+  /** What speaks against it without ruling it out */
+  concerns: string[];
+  /** What has to be resolved first; any blocker keeps the module in the monolith */
+  blockers: string[];
+  /** Modules calling it, which would make network calls */
+  callers: string[];
+  /** Modules it calls, over the network once extracted */
+  calls: string[];
+  communication: {
+    style: CommunicationStyle;
+    /** Message topics it publishes or subscribes to */
+    topics: string[];
+  };
+  data: {
+    /** Tables that move to the service's own database */
+    owned_tables: string[];
+    /** Sagas writing its data together with another module's: one transaction becomes several */
+    sagas: string[];
+  };
+}
+
+export interface ServiceExtractionPlan {
+  threshold: number;
+  candidates: ServiceCandidate[];
+}
+
+/**
+ * Target of a --target value
+ */
+export function parsePlanTarget(value: string): PlanTarget {
+  const target = value.trim().toLowerCase();
+  if (!(PLAN_TARGETS as string[]).includes(target)) {
+    throw new Error(`Unknown plan target "${value}" (expected ${PLAN_TARGETS.join(' or ')})`);
+  }
+  return target as PlanTarget;
+}
+
+/**
+ * Target of the previous plan.json; modular-monolith when there is none
+ */
+export function loadPlanTarget(projectRoot: string): PlanTarget {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    return (PLAN_TARGETS as unknown[]).includes(plan.target) ? plan.target : 'modular-monolith';
+  } catch {
+    return 'modular-monolith';
+  }
+}
+
+/**
+ * Score every module as a microservice: few modules on either side of it, data
+ * of its own and existing messaging make a clean cut; call cycles, sagas over its
+ * tables and mutable globals shared with other modules block the extraction.
+ * Established modules are left out; they are kept as they are.
+ */
+export function proposeServiceExtraction(
+  modules: ExtractionModule[],
+  findings: ExtractionFindings = {},
+  options: { threshold?: number } = {}
+): ServiceExtractionPlan {
+  const threshold = options.threshold ?? DEFAULT_EXTRACTION_THRESHOLD;
+  const names = new Set(modules.map(module => module.name));
+
+  const candidates = modules.filter(module => module.status !== 'established').map(module => {
+    const calls = [...new Set(module.depends_on.filter(dep => dep !== module.name && names.has(dep)))].sort();
+    const callers = modules
+      .filter(other => other.name !== module.name && other.depends_on.includes(module.name))
+      .map(other => other.name)
+      .sort();
+    const topics = [...new Set((findings.async_edges ?? [])
+      .filter(edge => edge.from === module.name || edge.to === module.name)
+      .map(edge => edge.topic))].sort();
+    const sagas = (findings.orchestrators ?? [])
+      .filter(o => o.kind === 'saga' && o.touches.some(t => t.module === module.name && t.writes))
+      .map(o => o.function);
+    const cycles = (findings.cycles ?? []).filter(cycle => cycle.modules.includes(module.name));
+    const globals = (findings.global_coupling ?? []).filter(coupling =>
+      coupling.kind === 'global-state' && coupling.from_module && coupling.to_module && coupling.from_module !== coupling.to_module &&
+      (coupling.from_module === module.name || coupling.to_module === module.name));
+    const ownedTables = [...(module.owned_tables ?? [])].sort();
+
+    const reasons: string[] = [];
+    const concerns: string[] = [];
+    const blockers: string[] = [];
+    let score = 100;
+
+    score -= Math.min(30, calls.length * 10);
+    score -= Math.min(30, callers.length * 6);
+    if (calls.length === 0) reasons.push('他モジュールを呼び出さない');
+    if (callers.length <= 1) reasons.push(callers.length === 0 ? 'どのモジュールからも呼び出されない' : `呼び出し元は ${callers[0]} のみ`);
+    if (ownedTables.length > 0) reasons.push('自前のテーブルを所有（専用DBに分離可能）');
+    if (topics.length > 0) {
+      score += 10;
+      reasons.push(`メッセージング (${topics.join(', ')}) で連携済み`);
+    }
+    if (callers.length > 2) concerns.push(`${callers.length}モジュールから呼び出される：呼び出しがすべてネットワーク越しになる`);
+    if (module.lines_of_code < 200) {
+      score -= 10;
+      concerns.push(`小規模 (${module.lines_of_code}行)：独立サービスにする運用コストに見合わない可能性`);
+    }
+
+    cycles.forEach(cycle => blockers.push(`呼び出しの循環 (${[...cycle.modules, cycle.modules[0]].join(' → ')})：先に ${cycle.break_at.from} → ${cycle.break_at.to} を切る`));
+    if (sagas.length > 0) {
+      score -= Math.min(30, sagas.length * 15);
+      blockers.push(`他モジュールと同一トランザクションで書き込むサーガ ${sagas.length}件 (${sagas.join(', ')})：補償処理かアウトボックスが必要`);
+    }
+    globals.forEach(coupling => blockers.push(`ミュータブルなグローバル状態を共有 (${coupling.symbols.join(', ')}、${coupling.from_module} → ${coupling.to_module})`));
+
+    score = Math.max(0, Math.min(100, score));
+    const style: CommunicationStyle = callers.length > 0 && topics.length > 0 ? 'mixed'
+      : callers.length > 0 ? 'sync'
+      : topics.length > 0 ? 'async'
+      : 'none';
+
+    return {
+      module: module.name,
+      score,
+      recommended: blockers.length === 0 && score >= threshold,
+      reasons,
+      concerns,
+      blockers,
+      callers,
+      calls,
+      communication: { style, topics },
+      data: { owned_tables: ownedTables, sagas },
+    };
+  });
+
+  return { threshold, candidates: candidates.sort((a, b) => b.score - a.score || a.module.localeCompare(b.module)) };
+}
+
+/**
+ * Deployments the proposal gives the modules; --deployment and the decisions
+ * recorded in plan.json take precedence
+ */
+export function proposedDeployments(plan: ServiceExtractionPlan): Record<string, ModuleDeployment> {
+  return Object.fromEntries(plan.candidates.map(c => [c.module, c.recommended ? 'service' : 'monolith']));
+}
+
+/**
+ * Operations returning nothing but an error: commands the callers do not wait on,
+ * which can become events instead of rpcs
+ */
+export function isCommandOperation(operation: ServiceOperation): boolean {
+  const signature = operation.signature.trim();
+  let depth = 0;
+  for (let i = 0; i < signature.length; i++) {
+    if (signature[i] === '(') depth++;
+    else if (signature[i] === ')' && --depth === 0) {
+      const results = signature.slice(i + 1).trim().replace(/^\((.*)\)$/, '$1').trim();
+      return results === '' || /^(?:\w+\s+)?error$/.test(results);
+    }
+  }
+  return false;
+}
+
+/**
+ * plan.md section with the extraction proposal; the requirements of the modules
+ * marked as services follow in the services section
+ */
+export function renderServiceExtractionSection(plan: ServiceExtractionPlan | undefined, requirements: ServiceRequirements[] = []): string {
+  if (!plan || plan.candidates.length === 0) return '';
+
+  const styles: Record<CommunicationStyle, string> = {
+    sync: '同期 (gRPC)',
+    async: '非同期 (イベント)',
+    mixed: '同期 (gRPC) + 非同期 (イベント)',
+    none: '外部からの呼び出しなし',
+  };
+  const entries = plan.candidates.map(candidate => {
+    const service = requirements.find(req => req.module === candidate.module);
+    const commands = service?.api.operations.filter(isCommandOperation).map(op => `\`${op.rpc}\``) ?? [];
+    return [
+      `### ${candidate.module} — ${candidate.recommended ? '独立サービスに推奨' : 'モノリスに残す'} (スコア ${candidate.score})`,
+      '',
+      ...candidate.reasons.map(reason => `- ✅ ${reason}`),
+      ...candidate.concerns.map(concern => `- ⚠️ ${concern}`),
+      ...candidate.blockers.map(blocker => `- ⛔ ${blocker}`),
+      `- 通信方式: ${styles[candidate.communication.style]}${candidate.communication.topics.length > 0 ? `（トピック: ${candidate.communication.topics.join(', ')}）` : ''}`,
+      ...(candidate.callers.length > 0 ? [`- 呼び出し元（ネットワーク呼び出しになる）: ${candidate.callers.join(', ')}`] : []),
+      ...(candidate.calls.length > 0 ? [`- 呼び出し先: ${candidate.calls.join(', ')}`] : []),
+      ...(service ? [`- API: \`${service.api.path}\` — ${service.api.operations.length}個の rpc${commands.length > 0 ? `（うち ${commands.join(', ')} は結果を返さないためイベント化も可能）` : ''}`] : []),
+      `- データ所有: ${candidate.data.owned_tables.length > 0 ? candidate.data.owned_tables.map(t => `\`${t}\``).join(', ') : '所有テーブルなし'}`,
+    ].join('\n');
+  });
+
+  const recommended = plan.candidates.filter(c => c.recommended).map(c => c.module);
+  return `
+## マイクロサービス抽出計画
+
+\`vf plan --target microservices\` の提案です。スコア ${plan.threshold} 以上で阻害要因のないモジュールを独立サービスに推奨しています。
+推奨: ${recommended.length > 0 ? recommended.join(', ') : 'なし'}（\`--deployment <module>=monolith|service\` で上書きでき、決定は再生成後も保持されます）
+
+${entries.join('\n\n')}
+`;
+}
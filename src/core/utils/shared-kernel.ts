@@ -0,0 +1,162 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { DomainBoundary, SharedKernelPackage } from '../types/config.js';
+import { goImports } from './go-load-check.js';
+import { GoPackage, TEST_HELPER_DIR } from './go-packages.js';
+import { toPosixPath } from './workspace-paths.js';
+
+export type SharedKernelKind = SharedKernelPackage['kind'];
+
+/**
+ * Directory names of utility packages, by kind; `<x>util(s)` (stringutil, httputil) is a utility too
+ */
+const KERNEL_NAMES: [SharedKernelKind, RegExp][] = [
+  ['logging', /^(?:log|logs|logger|logging|logutil)$/],
+  ['config', /^(?:config|configs|conf|cfg)$/],
+  ['errors', /^(?:errors|errs|xerrors|apperrors?|errorx)$/],
+  ['common', /^(?:common|shared|sharedkernel|kernel)$/],
+  ['utility', /^(?:utils?|utility|utilities|helpers?|\w+utils?)$/],
+];
+
+/** A package most boundaries import (at least this many) is a fan-in candidate */
+const MIN_FAN_IN = 3;
+
+const KIND_LABELS: Record<SharedKernelKind, string> = {
+  utility: 'ユーティリティ',
+  logging: 'ロギング',
+  config: '設定',
+  errors: 'エラー',
+  common: '共通',
+};
+
+export const SHARED_KERNEL_HEADING = '## 共有カーネル (Shared Kernel)';
+
+export interface SharedKernelOptions {
+  /** Files or directories boundary.yaml or //vf:boundary assign to a module; their packages stay in it */
+  pinned?: string[];
+}
+
+/**
+ * Kind of a utility package from its directory: the deepest path element
+ * naming one (pkg/utils/strings is a utility, internal/common/money common)
+ */
+export function kernelKindOf(dir: string): SharedKernelKind | undefined {
+  for (const element of dir.split('/').reverse()) {
+    const kind = KERNEL_NAMES.find(([, pattern]) => pattern.test(element.toLowerCase()))?.[0];
+    if (kind) return kind;
+  }
+  return undefined;
+}
+
+/**
+ * Packages that belong to no domain boundary: utility, logging, config and
+ * error helpers named as such, and leaf packages (importing no other package
+ * of the project but the kernel) that most boundaries import. `package main`,
+ * shared test helpers (see test-support.ts), established modules and pinned
+ * packages are never part of the kernel.
+ */
+export function findSharedKernel(
+  projectRoot: string,
+  boundaries: DomainBoundary[],
+  packages: GoPackage[],
+  options: SharedKernelOptions = {}
+): SharedKernelPackage[] {
+  const relative = (file: string) => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+  const ownerOf = new Map<string, DomainBoundary>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) ownerOf.set(relative(file), boundary);
+  }
+  const pinned = (options.pinned ?? []).map(entry => toPosixPath(entry).replace(/^\.\//, '').replace(/\/+$/, ''));
+  const isPinned = (file: string) => pinned.some(entry => file === entry || file.startsWith(`${entry}/`));
+
+  const byImportPath = new Map(packages.map(pkg => [pkg.import_path, pkg]));
+  const imports = new Map<string, string[]>();
+  for (const file of packages.flatMap(pkg => pkg.files)) {
+    try {
+      imports.set(file, goImports(fs.readFileSync(path.join(projectRoot, file), 'utf8')).map(i => i.path));
+    } catch {
+      imports.set(file, []);
+    }
+  }
+
+  // Boundaries whose files import each package
+  const users = new Map<string, Set<string>>();
+  for (const [file, imported] of imports) {
+    const owner = ownerOf.get(file);
+    if (!owner) continue;
+    for (const importPath of imported) {
+      if (!users.has(importPath)) users.set(importPath, new Set());
+      users.get(importPath)!.add(owner.name);
+    }
+  }
+  const domainBoundaries = boundaries.filter(b => b.status !== 'established' && b.files.length > 0).length;
+  const fanIn = Math.max(MIN_FAN_IN, Math.ceil(domainBoundaries / 2));
+
+  const kernel: SharedKernelPackage[] = [];
+  for (const pkg of [...packages].sort((a, b) => (a.dir < b.dir ? -1 : a.dir > b.dir ? 1 : 0))) {
+    if (pkg.name === 'main' || TEST_HELPER_DIR.test(pkg.dir)) continue;
+    if (pkg.files.some(file => ownerOf.get(file)?.status === 'established' || isPinned(file))) continue;
+
+    const kind = kernelKindOf(pkg.dir);
+    const usedBy = [...(users.get(pkg.import_path) ?? [])].sort();
+    if (kind) {
+      kernel.push({ dir: pkg.dir, import_path: pkg.import_path, package: pkg.name, files: pkg.files, kind, reason: 'name', used_by: usedBy });
+      continue;
+    }
+
+    const leaf = pkg.files.every(file => (imports.get(file) ?? []).every(importPath => {
+      const imported = byImportPath.get(importPath);
+      return !imported || imported.dir === pkg.dir || kernelKindOf(imported.dir) !== undefined;
+    }));
+    if (leaf && usedBy.length >= fanIn) {
+      kernel.push({ dir: pkg.dir, import_path: pkg.import_path, package: pkg.name, files: pkg.files, kind: 'utility', reason: 'fan-in', used_by: usedBy });
+    }
+  }
+  return kernel;
+}
+
+/**
+ * Take the shared kernel's files out of the boundaries; boundaries left without
+ * files are dropped, along with the dependencies on them
+ */
+export function separateSharedKernel(
+  projectRoot: string,
+  boundaries: DomainBoundary[],
+  kernel: SharedKernelPackage[]
+): { boundaries: DomainBoundary[]; dropped: string[] } {
+  if (kernel.length === 0) return { boundaries, dropped: [] };
+  const relative = (file: string) => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+  const files = new Set(kernel.flatMap(pkg => pkg.files));
+
+  const remaining = boundaries.map(boundary => ({ ...boundary, files: boundary.files.filter(file => !files.has(relative(file))) }));
+  const dropped = remaining.filter((boundary, i) => boundary.files.length === 0 && boundaries[i].files.length > 0).map(b => b.name);
+  return {
+    boundaries: remaining
+      .filter(boundary => !dropped.includes(boundary.name))
+      .map(boundary => (boundary.dependencies?.internal?.some(dep => dropped.includes(dep))
+        ? { ...boundary, dependencies: { ...boundary.dependencies, internal: boundary.dependencies.internal.filter(dep => !dropped.includes(dep)) } }
+        : boundary)),
+    dropped,
+  };
+}
+
+/**
+ * plan.md section listing the shared kernel packages refactoring leaves in place
+ */
+export function renderSharedKernelSection(kernel: SharedKernelPackage[] = []): string {
+  if (kernel.length === 0) return '';
+
+  const entries = kernel.map(pkg => [
+    `- \`${pkg.dir}\` (${KIND_LABELS[pkg.kind]}、${pkg.files.length}ファイル) — ${pkg.reason === 'name' ? 'ディレクトリ名から判定' : `${pkg.used_by.length}個の境界が利用する末端パッケージ`}`,
+    ...(pkg.used_by.length > 0 ? [`  - 利用: ${pkg.used_by.join(', ')}`] : []),
+  ].join('\n'));
+
+  return `
+${SHARED_KERNEL_HEADING}
+
+以下のパッケージはどのモジュールにも属さない共有カーネルです。リファクタリングでは移動せず、各モジュールからの import はそのまま残します。
+ドメインのロジックが入り込まないよう、追加は最小限にしてください（vf drift は共有カーネルへのファイル追加を警告します）。
+
+${entries.join('\n')}
+`;
+}
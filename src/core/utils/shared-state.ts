@@ -0,0 +1,394 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { SourceFile, SourceLocation, formatLocation } from './source-positions.js';
+import { detectGoProject, goImportAlias, goPackageImportPath } from './go-project-utils.js';
+import { goPackageName } from './go-load-check.js';
+import { toPosixPath } from './workspace-paths.js';
+import { PlanSyncState, blockHash, replaceGeneratedBlock } from './plan-sync.js';
+
+export type SharedStateKind = 'map' | 'slice' | 'pointer' | 'sync';
+
+export type SharedStateAccess = 'read-only-after-init' | 'mutated-at-runtime';
+
+export type SharedStateStrategy = 'owning-module-interface' | 'injected-dependency' | 'cache-service';
+
+export const SHARED_STATE_STRATEGIES: SharedStateStrategy[] = ['owning-module-interface', 'injected-dependency', 'cache-service'];
+
+export interface SharedStateAccessSite {
+  module: string;
+  /** Enclosing function ('' at package level) */
+  function: string;
+  write: boolean;
+  location: SourceLocation;
+}
+
+export interface SharedStateResolution {
+  strategy: SharedStateStrategy;
+  note?: string;
+  accepted_at: string;
+}
+
+/**
+ * A package-level mutable variable used from more than one target module.
+ * After the split each module would get its own copy of it.
+ */
+export interface SharedStateFinding {
+  /** `<package dir>.<variable>`, stable across plan regenerations */
+  id: string;
+  variable: string;
+  /** Package directory relative to the project root ('.' for the root) */
+  package: string;
+  kind: SharedStateKind;
+  /** Declared type or initializer, e.g. map[string]*User */
+  type: string;
+  declared_at: SourceLocation;
+  /** Module that declares the variable, if it belongs to one */
+  owner?: string;
+  modules: string[];
+  access: SharedStateAccess;
+  accesses: SharedStateAccessSite[];
+  suggested_resolutions: { strategy: SharedStateStrategy; description: string }[];
+  /** Recorded with `vf shared-state --accept`; unresolved findings block refactor */
+  resolution?: SharedStateResolution;
+}
+
+interface PackageVariable {
+  name: string;
+  kind: SharedStateKind;
+  type: string;
+  file: string;
+  dir: string;
+  /** Declared package name; importers use it unless they alias the import */
+  packageName?: string;
+  location: SourceLocation;
+}
+
+/** Methods that change the state guarded by (or held in) a sync value */
+const MUTATING_METHODS = new Set([
+  'Lock', 'Unlock', 'Store', 'Swap', 'Delete', 'LoadOrStore', 'LoadAndDelete', 'CompareAndSwap',
+  'Add', 'Set', 'Put', 'Reset', 'Clear', 'Push', 'Remove',
+]);
+
+/**
+ * Find package-level maps, slices, pointers and sync values that are read or
+ * written from more than one target module. Writes outside init() count as
+ * runtime mutation; everything else is read-only after init.
+ *
+ * @param modules target modules with their files relative to the project root
+ */
+export function findSharedMutableState(projectRoot: string, modules: { name: string; files: string[] }[]): SharedStateFinding[] {
+  const moduleOf = new Map<string, string>();
+  for (const module of modules) {
+    for (const file of module.files) {
+      const key = toPosixPath(file);
+      if (key.endsWith('.go') && !moduleOf.has(key)) moduleOf.set(key, module.name);
+    }
+  }
+
+  const sources = [...moduleOf.keys()]
+    .filter(file => !file.endsWith('_test.go'))
+    .sort()
+    .map(file => SourceFile.read(projectRoot, file))
+    .filter((source): source is SourceFile => source !== null);
+
+  const goProject = detectGoProject(projectRoot);
+  const findings: SharedStateFinding[] = [];
+
+  for (const variable of sources.flatMap(packageVariables)) {
+    const importPath = goPackageImportPath(projectRoot, variable.dir, goProject);
+    const accesses = sources.flatMap(source => {
+      const module = moduleOf.get(source.file) as string;
+      const sameDir = packageDir(source.file) === variable.dir;
+      if (sameDir) return findAccesses(source, module, variable.name, variable.kind);
+
+      const alias = importPath && /^[A-Z]/.test(variable.name) ? goImportAlias(source.content, importPath, variable.packageName) : null;
+      return alias && alias !== '_' && alias !== '.' ? findAccesses(source, module, `${alias}.${variable.name}`, variable.kind) : [];
+    });
+
+    const involved = [...new Set(accesses.map(a => a.module))].sort();
+    if (involved.length < 2) continue;
+
+    const mutated = accesses.some(a => a.write && a.function !== '' && a.function !== 'init');
+    const owner = moduleOf.get(variable.file);
+    findings.push({
+      id: `${variable.dir}.${variable.name}`,
+      variable: variable.name,
+      package: variable.dir,
+      kind: variable.kind,
+      type: variable.type,
+      declared_at: variable.location,
+      ...(owner ? { owner } : {}),
+      modules: involved,
+      access: mutated ? 'mutated-at-runtime' : 'read-only-after-init',
+      accesses,
+      suggested_resolutions: suggestResolutions(variable, owner, mutated),
+    });
+  }
+
+  return findings;
+}
+
+/**
+ * Carry accepted resolutions of a previous plan over to regenerated findings
+ */
+export function mergeResolutions(findings: SharedStateFinding[], previous: SharedStateFinding[] | undefined): SharedStateFinding[] {
+  const accepted = new Map((previous ?? []).filter(f => f.resolution).map(f => [f.id, f.resolution as SharedStateResolution]));
+  return findings.map(finding => accepted.has(finding.id) ? { ...finding, resolution: accepted.get(finding.id) } : finding);
+}
+
+/**
+ * Reason a module must not be applied, or null when all of its shared state is resolved
+ */
+export function sharedStateRefusal(moduleName: string, findings: SharedStateFinding[]): string | null {
+  const blocking = findings.filter(f => !f.resolution && f.modules.includes(moduleName));
+  if (blocking.length === 0) return null;
+
+  const names = blocking.map(f => `${f.id} (${f.access})`).join(', ');
+  return `Module '${moduleName}' shares mutable package state with other modules: ${names}. ` +
+    `Choose a resolution with 'vf shared-state --accept <id> --resolution <strategy>' before applying.`;
+}
+
+/**
+ * Load the shared-state findings recorded in .vibeflow/plan.json (empty without a plan)
+ */
+export function loadSharedState(projectRoot: string): SharedStateFinding[] {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    return Array.isArray(plan.shared_state) ? plan.shared_state : [];
+  } catch {
+    return [];
+  }
+}
+
+/**
+ * Record an accepted resolution in plan.json (and refresh the plan.md section)
+ */
+export function acceptSharedStateResolution(
+  projectRoot: string,
+  id: string,
+  strategy: SharedStateStrategy,
+  note?: string
+): SharedStateFinding {
+  if (!SHARED_STATE_STRATEGIES.includes(strategy)) {
+    throw new Error(`Unknown resolution '${strategy}' (expected one of: ${SHARED_STATE_STRATEGIES.join(', ')})`);
+  }
+
+  const paths = new VibeFlowPaths(projectRoot);
+  let plan: { shared_state?: SharedStateFinding[]; sync?: PlanSyncState };
+  try {
+    plan = JSON.parse(fs.readFileSync(paths.planJsonPath, 'utf8'));
+  } catch {
+    throw new Error(`No plan found at ${paths.getRelativePath(paths.planJsonPath)}; run 'vf plan' first`);
+  }
+
+  const finding = plan.shared_state?.find(f => f.id === id);
+  if (!finding) throw new Error(`Unknown shared-state finding: ${id}`);
+
+  finding.resolution = { strategy, ...(note ? { note } : {}), accepted_at: new Date().toISOString() };
+
+  let markdown: string | null = null;
+  try {
+    markdown = replaceSharedStateSection(fs.readFileSync(paths.planPath, 'utf8'), plan.shared_state ?? []);
+  } catch {
+    // plan.md is informational; plan.json is the record
+  }
+  if (markdown !== null && plan.sync?.blocks[SHARED_STATE_BLOCK] !== undefined) {
+    // Regenerated from plan.json, so not an edit for vf plan sync
+    plan.sync.blocks[SHARED_STATE_BLOCK] = blockHash(renderSharedStateSection(plan.shared_state ?? []));
+  }
+  paths.writeArtifact(paths.planJsonPath, plan);
+  if (markdown !== null) fs.writeFileSync(paths.planPath, markdown);
+
+  return finding;
+}
+
+export const SHARED_STATE_HEADING = '## 共有ミュータブル状態 (Shared Mutable State)';
+
+/** Generated block of the section in plan.md (see plan-sync.ts) */
+const SHARED_STATE_BLOCK = 'section:shared-state';
+
+/**
+ * plan.md section listing the findings and how to record a resolution
+ */
+export function renderSharedStateSection(findings: SharedStateFinding[]): string {
+  if (findings.length === 0) return '';
+
+  const entries = findings.map(f => {
+    const status = f.resolution
+      ? `✅ 解決策承認済み: ${f.resolution.strategy}${f.resolution.note ? ` (${f.resolution.note})` : ''}`
+      : '⛔ 未解決 (refactor をブロック)';
+    const writers = [...new Set(f.accesses.filter(a => a.write).map(a => a.module))];
+    return [
+      `- **${f.id}** \`${f.type}\` (${f.kind}, ${f.access}) — ${formatLocation(f.declared_at)}`,
+      `  - モジュール: ${f.modules.join(', ')}${writers.length ? ` / 書き込み: ${writers.join(', ')}` : ''}`,
+      `  - 状態: ${status}`,
+      ...f.suggested_resolutions.map(r => `  - 候補 \`${r.strategy}\`: ${r.description}`),
+    ].join('\n');
+  });
+
+  return `
+${SHARED_STATE_HEADING}
+
+以下のパッケージ変数は複数のモジュールから使われています。分割後はモジュールごとに別々のコピーになるため、
+解決策を選んで \`vf shared-state --accept <id> --resolution <strategy>\` で記録してください。
+
+${entries.join('\n')}
+`;
+}
+
+function replaceSharedStateSection(markdown: string, findings: SharedStateFinding[]): string {
+  const block = replaceGeneratedBlock(markdown, SHARED_STATE_BLOCK, renderSharedStateSection(findings));
+  if (block !== null) return block;
+
+  const start = markdown.indexOf(`\n${SHARED_STATE_HEADING}`);
+  const section = renderSharedStateSection(findings);
+  if (start < 0) return markdown + section;
+
+  const next = markdown.indexOf('\n## ', start + SHARED_STATE_HEADING.length + 1);
+  return markdown.slice(0, start) + section + (next < 0 ? '' : markdown.slice(next));
+}
+
+function suggestResolutions(
+  variable: PackageVariable,
+  owner: string | undefined,
+  mutated: boolean
+): { strategy: SharedStateStrategy; description: string }[] {
+  const home = owner ? `the ${owner} module` : 'a single owning module';
+  const suggestions: { strategy: SharedStateStrategy; description: string }[] = [
+    {
+      strategy: 'owning-module-interface',
+      description: `Keep ${variable.name} in ${home} and expose the operations other modules need through its interface`,
+    },
+    {
+      strategy: 'injected-dependency',
+      description: `Create the ${variable.kind === 'sync' ? 'guarded state' : variable.name} once in main and inject it into the modules that use it`,
+    },
+  ];
+  if (variable.kind === 'map' || mutated) {
+    suggestions.push({
+      strategy: 'cache-service',
+      description: `Replace ${variable.name} with a shared cache service (e.g. Redis) so every module sees the same data`,
+    });
+  }
+  return suggestions;
+}
+
+/**
+ * Top-level `var` declarations of mutable kinds, single or grouped
+ */
+function packageVariables(source: SourceFile): PackageVariable[] {
+  const variables: PackageVariable[] = [];
+  const lines = source.content.split('\n');
+  let offset = 0;
+  let inGroup = false;
+
+  for (const line of lines) {
+    const lineStart = offset;
+    offset += line.length + 1;
+
+    if (/^var\s*\(\s*$/.test(line)) {
+      inGroup = true;
+      continue;
+    }
+    if (inGroup && /^\)/.test(line)) {
+      inGroup = false;
+      continue;
+    }
+
+    const spec = inGroup ? line.match(/^\s+(\w+(?:\s*,\s*\w+)*)\s+(.*)$/) : line.match(/^var\s+(\w+(?:\s*,\s*\w+)*)\s+(.*)$/);
+    if (!spec) continue;
+
+    const [type, init] = splitSpec(spec[2].replace(/\/\/.*$/, '').trim());
+    const kind = mutableKind(type, init);
+    if (!kind) continue;
+
+    const specStart = lineStart + line.indexOf(spec[1]);
+    let cursor = 0;
+    for (const name of spec[1].split(',').map(n => n.trim())) {
+      const index = spec[1].indexOf(name, cursor);
+      cursor = index + name.length;
+      if (name === '_') continue;
+      const dir = packageDir(source.file);
+      variables.push({
+        name,
+        kind,
+        type: type || init,
+        file: source.file,
+        dir,
+        packageName: goPackageName(source.content) ?? undefined,
+        location: source.locate(specStart + index, specStart + index + name.length),
+      });
+    }
+  }
+
+  return variables;
+}
+
+function splitSpec(rest: string): [string, string] {
+  if (rest.startsWith('=')) return ['', rest.slice(1).trim()];
+  const eq = rest.search(/(?<![=!<>:])=(?!=)/);
+  return eq < 0 ? [rest, ''] : [rest.slice(0, eq).trim(), rest.slice(eq + 1).trim()];
+}
+
+function mutableKind(type: string, init: string): SharedStateKind | null {
+  if (/^\*?(?:sync|atomic)\.\w+/.test(type) || /^(?:&?(?:sync|atomic)\.\w+\{|new\((?:sync|atomic)\.)/.test(init)) return 'sync';
+  if (/^map\[/.test(type) || /^(?:make\(\s*)?map\[/.test(init)) return 'map';
+  if (/^\[\]/.test(type) || /^(?:make\(\s*)?\[\]/.test(init)) return 'slice';
+  if (/^\*/.test(type) || /^(?:&|new\()/.test(init)) return 'pointer';
+  return null;
+}
+
+/**
+ * Uses of a variable (or `alias.Name`) inside a file, with their enclosing function
+ */
+function findAccesses(source: SourceFile, module: string, reference: string, kind: SharedStateKind): SharedStateAccessSite[] {
+  const accesses: SharedStateAccessSite[] = [];
+  const pattern = new RegExp(`(?<![\\w.])${reference.replace('.', '\\.')}\\b`, 'g');
+  const lines = source.content.split('\n');
+  let offset = 0;
+  let current = '';
+
+  for (const line of lines) {
+    const lineStart = offset;
+    offset += line.length + 1;
+
+    const func = line.match(/^func\s*(?:\([^)]*\)\s*)?(\w+)/);
+    if (func) current = func[1];
+    const topLevel = !func && current === '';
+
+    const code = line.replace(/"(?:[^"\\]|\\.)*"|`[^`]*`/g, m => ' '.repeat(m.length)).replace(/\/\/.*$/, '');
+    pattern.lastIndex = 0;
+    let match: RegExpExecArray | null;
+    while ((match = pattern.exec(code)) !== null) {
+      const rest = code.slice(match.index + match[0].length);
+      // The declaration itself and shadowing locals are not accesses
+      if (topLevel || /^\s*(?:,\s*\w+\s*)*:=/.test(rest)) continue;
+
+      const start = lineStart + match.index;
+      accesses.push({
+        module,
+        function: current,
+        write: isWrite(code.slice(0, match.index), rest, kind),
+        location: source.locate(start, start + match[0].length),
+      });
+    }
+
+    if (/^\}/.test(line)) current = '';
+  }
+
+  return accesses;
+}
+
+function isWrite(before: string, rest: string, kind: SharedStateKind): boolean {
+  if (/(?:delete|clear)\(\s*$/.test(before)) return true;
+  if (/^(?:\[[^\]]*\])*(?:\.\w+)*\s*(?:[-+*/%|&^]|<<|>>)?=(?!=)/.test(rest)) return true;
+  if (/^(?:\[[^\]]*\])*(?:\.\w+)*\s*(?:\+\+|--)/.test(rest)) return true;
+
+  const call = rest.match(/^\.(\w+)\(/);
+  return Boolean(call && (kind === 'sync' || kind === 'pointer' || kind === 'map') && MUTATING_METHODS.has(call[1]));
+}
+
+function packageDir(file: string): string {
+  return path.posix.dirname(toPosixPath(file));
+}
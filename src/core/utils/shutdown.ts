@@ -0,0 +1,63 @@
+import { getErrorMessage } from './error-utils.js';
+
+/** Exit code of a run stopped by Ctrl+C / Ctrl+Break */
+export const INTERRUPTED_EXIT_CODE = 130;
+
+type ShutdownHandler = (reason: string) => void;
+
+const handlers = new Set<ShutdownHandler>();
+let installed = false;
+
+/**
+ * Signals that mean "stop" on the given platform. Windows has no POSIX signals:
+ * Node only emulates SIGINT (Ctrl+C), SIGBREAK (Ctrl+Break) and SIGHUP (console closed).
+ */
+export function shutdownSignals(platform: NodeJS.Platform = process.platform): NodeJS.Signals[] {
+  return platform === 'win32' ? ['SIGINT', 'SIGBREAK', 'SIGHUP'] : ['SIGINT', 'SIGTERM', 'SIGHUP'];
+}
+
+/**
+ * Register cleanup to run when the process is interrupted or exits
+ *
+ * @returns function unregistering the handler once the guarded work is done
+ */
+export function onShutdown(handler: ShutdownHandler): () => void {
+  installShutdownHandlers();
+  handlers.add(handler);
+  return () => {
+    handlers.delete(handler);
+  };
+}
+
+/**
+ * Run registered cleanup, most recent first. Handlers run once and must be synchronous.
+ */
+export function runShutdownHandlers(reason: string): void {
+  for (const handler of [...handlers].reverse()) {
+    handlers.delete(handler);
+    try {
+      handler(reason);
+    } catch (error) {
+      console.warn(`⚠️  Cleanup after ${reason} failed: ${getErrorMessage(error)}`);
+    }
+  }
+}
+
+/**
+ * Clean up and exit as if interrupted. Use this instead of process.kill(process.pid, 'SIGINT'),
+ * which terminates the process without running any handler on Windows.
+ */
+export function requestShutdown(reason: string = 'SIGINT'): never {
+  runShutdownHandlers(reason);
+  process.exit(INTERRUPTED_EXIT_CODE);
+}
+
+function installShutdownHandlers(): void {
+  if (installed) return;
+  installed = true;
+
+  for (const signal of shutdownSignals()) {
+    process.once(signal, () => requestShutdown(signal));
+  }
+  process.on('exit', () => runShutdownHandlers('exit'));
+}
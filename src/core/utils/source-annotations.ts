@@ -0,0 +1,329 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { maskLiterals } from './api-surface.js';
+import { toPosixPath } from './workspace-paths.js';
+import { VerificationError } from './error-utils.js';
+
+/**
+ * Inline hints honored by the analysis (`//vf:<directive>` above a declaration):
+ * boundary pins the symbol (and its file when unambiguous) to a boundary,
+ * keep-together makes a type with its methods and helpers an unsplittable unit,
+ * ignore excludes the declaration from business-rule extraction and dead-code reporting,
+ * freeze forbids rewriting the declaration.
+ */
+export type AnnotationDirective = 'boundary' | 'keep-together' | 'ignore' | 'freeze';
+
+export const ANNOTATION_DIRECTIVES: AnnotationDirective[] = ['boundary', 'keep-together', 'ignore', 'freeze'];
+
+export interface AnnotationMember {
+  symbol: string;
+  file: string;
+}
+
+export interface SourceAnnotation {
+  directive: AnnotationDirective;
+  /** Boundary name of //vf:boundary */
+  argument?: string;
+  /** Annotated declaration: `Name`, or `Type.Method` for methods */
+  symbol: string;
+  declaration: 'func' | 'method' | 'type' | 'var' | 'const';
+  /** Relative to the project root */
+  file: string;
+  /** Line of the directive comment */
+  line: number;
+  /** Methods and helpers of a //vf:keep-together type */
+  members?: AnnotationMember[];
+  source: 'annotation';
+}
+
+export interface AnnotationProblem {
+  file: string;
+  line: number;
+  message: string;
+}
+
+const DIRECTIVE_LINE = /^\s*\/\/vf:([\w-]*)(?:[ \t]+(.*?))?\s*$/;
+
+const DECLARATIONS: [RegExp, SourceAnnotation['declaration']][] = [
+  [/^func\s*\(\s*(?:\w+\s+)?\*?\s*(\w+)(?:\[[^\]]*\])?\s*\)\s*(\w+)/, 'method'],
+  [/^func\s+(\w+)/, 'func'],
+  [/^type\s+(\w+)/, 'type'],
+  [/^var\s+(\w+)/, 'var'],
+  [/^const\s+(\w+)/, 'const'],
+];
+
+/**
+ * Directives of one file and the problems `vf validate` reports: unknown directives,
+ * directives not attached to a declaration and missing or misplaced arguments
+ */
+export function parseAnnotations(content: string, file: string): { annotations: SourceAnnotation[]; problems: AnnotationProblem[] } {
+  const lines = content.split('\n');
+  const annotations: SourceAnnotation[] = [];
+  const problems: AnnotationProblem[] = [];
+
+  lines.forEach((line, index) => {
+    const match = line.match(DIRECTIVE_LINE);
+    if (!match) return;
+    const problem = (message: string) => problems.push({ file, line: index + 1, message });
+
+    const directive = match[1] as AnnotationDirective;
+    if (!ANNOTATION_DIRECTIVES.includes(directive)) {
+      problem(`unknown directive //vf:${match[1]} (expected ${ANNOTATION_DIRECTIVES.map(d => `//vf:${d}`).join(', ')})`);
+      return;
+    }
+
+    // The directive belongs to the declaration below its comment block
+    let next = index + 1;
+    while (next < lines.length && lines[next].trim().startsWith('//')) next++;
+    const declaration = declarationAt(lines[next] ?? '');
+    if (!declaration) {
+      problem(`//vf:${directive} is not attached to a declaration`);
+      return;
+    }
+    if (directive === 'boundary' && !match[2]) {
+      problem('//vf:boundary needs a boundary name');
+      return;
+    }
+    if (directive === 'keep-together' && declaration.declaration !== 'type') {
+      problem(`//vf:keep-together applies to types, not to ${declaration.declaration} ${declaration.symbol}`);
+      return;
+    }
+
+    annotations.push({
+      directive,
+      ...(directive === 'boundary' ? { argument: match[2].split(/\s+/)[0] } : {}),
+      ...declaration,
+      file,
+      line: index + 1,
+      source: 'annotation',
+    });
+  });
+
+  return { annotations, problems };
+}
+
+/**
+ * Scan every Go file of the project (`vf validate`)
+ */
+export function collectProjectAnnotations(projectRoot: string): { annotations: SourceAnnotation[]; problems: AnnotationProblem[] } {
+  const files = fastGlob.sync('**/*.go', {
+    cwd: projectRoot,
+    ignore: ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**', '**/testdata/**'],
+  }).sort();
+
+  const annotations: SourceAnnotation[] = [];
+  const problems: AnnotationProblem[] = [];
+  for (const file of files) {
+    let content: string;
+    try {
+      content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+    } catch {
+      continue;
+    }
+    if (!content.includes('//vf:')) continue;
+    const parsed = parseAnnotations(content, file);
+    annotations.push(...parsed.annotations);
+    problems.push(...parsed.problems);
+  }
+  return { annotations, problems };
+}
+
+/**
+ * Methods of each //vf:keep-together type in its package, plus the unexported
+ * functions of the package those methods call or pass as values
+ */
+export function resolveKeepTogether(
+  annotations: SourceAnnotation[],
+  functions: { name: string; file: string; receiver?: string; calls: string[]; function_values?: string[] }[]
+): SourceAnnotation[] {
+  return annotations.map(annotation => {
+    if (annotation.directive !== 'keep-together') return annotation;
+
+    const dir = path.posix.dirname(annotation.file);
+    const inPackage = functions.filter(fn => path.posix.dirname(toPosixPath(fn.file)) === dir);
+    const methods = inPackage.filter(fn => fn.receiver?.split(/\s+/).pop()?.replace('*', '') === annotation.symbol);
+    const called = new Set(methods.flatMap(fn => [...fn.calls, ...(fn.function_values ?? [])]));
+    const helpers = inPackage.filter(fn => !fn.receiver && /^[a-z_]/.test(fn.name) && called.has(fn.name));
+
+    return {
+      ...annotation,
+      members: [
+        ...methods.map(fn => ({ symbol: `${annotation.symbol}.${fn.name}`, file: toPosixPath(fn.file) })),
+        ...helpers.map(fn => ({ symbol: fn.name, file: toPosixPath(fn.file) })),
+      ],
+    };
+  });
+}
+
+/**
+ * Override clustering for annotated symbols. A file moves to the boundary its
+ * //vf:boundary annotations name when they all agree; the files of a
+ * //vf:keep-together unit follow the file declaring the type. Each boundary
+ * records the annotations of its files and the symbols pinned to it.
+ *
+ * @returns the boundaries and the annotations naming no boundary (reported by `vf validate`)
+ */
+export function applyAnnotations<T extends { id?: string; name: string; files: string[] }>(
+  projectRoot: string,
+  boundaries: T[],
+  annotations: SourceAnnotation[]
+): { boundaries: (T & { annotations?: SourceAnnotation[] })[]; moved: string[]; unknown: SourceAnnotation[] } {
+  if (annotations.length === 0) return { boundaries, moved: [], unknown: [] };
+
+  const relative = (file: string) => toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+  const result = boundaries.map(boundary => ({ ...boundary, files: [...boundary.files] }));
+  const target = (name: string) => result.find(b => b.name === name || b.id === name);
+  const ownerOf = (file: string) => result.find(b => b.files.some(f => relative(f) === file));
+  const moved: string[] = [];
+
+  const moveFile = (file: string, to: T) => {
+    const from = ownerOf(file);
+    if (from === to) return;
+    const stored = from?.files.find(f => relative(f) === file) ?? file;
+    result.forEach(b => { b.files = b.files.filter(f => relative(f) !== file); });
+    to.files.push(stored);
+    moved.push(file);
+  };
+
+  const pins = annotations.filter(a => a.directive === 'boundary');
+  const unknown = pins.filter(a => !target(a.argument!));
+  const pinnedFiles = new Set<string>();
+  for (const file of [...new Set(pins.map(a => a.file))]) {
+    const names = [...new Set(pins.filter(a => a.file === file && target(a.argument!)).map(a => target(a.argument!)!))];
+    if (names.length !== 1) continue;
+    moveFile(file, names[0]);
+    pinnedFiles.add(file);
+  }
+
+  for (const unit of annotations.filter(a => a.directive === 'keep-together')) {
+    const owner = ownerOf(unit.file);
+    if (!owner) continue;
+    for (const member of unit.members ?? []) {
+      if (!pinnedFiles.has(member.file)) moveFile(member.file, owner);
+    }
+  }
+
+  return {
+    // Boundaries emptied by the moves are dropped
+    boundaries: result.filter((b, i) => b.files.length > 0 || boundaries[i].files.length === 0).map(boundary => {
+      const files = new Set(boundary.files.map(relative));
+      const attached = annotations.filter(a => a.directive === 'boundary'
+        ? target(a.argument!) === boundary
+        : files.has(a.file));
+      return attached.length > 0 ? { ...boundary, annotations: attached } : boundary;
+    }),
+    moved: [...new Set(moved)],
+    unknown,
+  };
+}
+
+/**
+ * Whether `symbol` (a function name, or `Type.Method`) of `file` is marked //vf:ignore
+ */
+export function isIgnored(annotations: SourceAnnotation[], file: string, symbol: string | undefined): boolean {
+  if (!symbol) return false;
+  return annotations.some(a => a.directive === 'ignore' && a.file === toPosixPath(file) &&
+    (a.symbol === symbol || a.symbol.split('.').pop() === symbol));
+}
+
+/**
+ * Prompt section asking the model to keep the directives and frozen declarations of a file
+ */
+export function renderAnnotationSection(annotations: SourceAnnotation[]): string {
+  if (annotations.length === 0) return '';
+
+  const frozen = annotations.filter(a => a.directive === 'freeze');
+  return `## Source annotations
+Keep every //vf: comment directly above the declaration it annotates, wherever that declaration moves:
+${annotations.map(a => `- //vf:${a.directive}${a.argument ? ` ${a.argument}` : ''} on ${a.symbol}`).join('\n')}
+${frozen.length > 0 ? `\nDeclarations marked //vf:freeze must be copied verbatim; do not rename, reformat or change them: ${frozen.map(a => a.symbol).join(', ')}\n` : ''}`;
+}
+
+/**
+ * Re-attach the directives of the original file to the declarations in the
+ * outputs that lost them, and reject outputs that rewrite a frozen declaration
+ *
+ * @throws VerificationError naming the output and the frozen symbol
+ */
+export function preserveAnnotations<T extends { path: string; content: string }>(
+  annotations: SourceAnnotation[],
+  originalCode: string,
+  outputs: T[]
+): T[] {
+  if (annotations.length === 0) return outputs;
+
+  return outputs.map(output => {
+    if (!output.path.endsWith('.go')) return output;
+
+    let content = output.content;
+    for (const annotation of annotations) {
+      const found = findDeclaration(content, annotation.symbol);
+      if (!found) continue;
+
+      if (annotation.directive === 'freeze') {
+        const original = findDeclaration(originalCode, annotation.symbol);
+        if (original && normalize(original.text) !== normalize(found.text)) {
+          throw new VerificationError(`frozen declaration ${annotation.symbol} was rewritten (//vf:freeze)`, output.path);
+        }
+      }
+
+      const comment = `//vf:${annotation.directive}${annotation.argument ? ` ${annotation.argument}` : ''}`;
+      if (!commentBlockAbove(content, found.start).some(line => line.trim().startsWith(comment))) {
+        content = `${content.slice(0, found.start)}${comment}\n${content.slice(found.start)}`;
+      }
+    }
+    return content === output.content ? output : { ...output, content };
+  });
+}
+
+function declarationAt(line: string): Pick<SourceAnnotation, 'symbol' | 'declaration'> | null {
+  for (const [pattern, declaration] of DECLARATIONS) {
+    const match = line.match(pattern);
+    if (!match) continue;
+    return { symbol: declaration === 'method' ? `${match[1]}.${match[2]}` : match[1], declaration };
+  }
+  return null;
+}
+
+/**
+ * Top-level declaration of `symbol` with its body (through the matching brace, else the line)
+ */
+function findDeclaration(content: string, symbol: string): { start: number; text: string } | null {
+  const code = maskLiterals(content);
+  let offset = 0;
+  for (const line of code.split('\n')) {
+    const declared = declarationAt(line);
+    if (declared?.symbol === symbol) {
+      const open = line.indexOf('{');
+      let end = offset + line.length;
+      if (open >= 0) {
+        let depth = 0;
+        for (let i = offset + open; i < code.length; i++) {
+          if (code[i] === '{') depth++;
+          if (code[i] === '}' && --depth === 0) {
+            end = i + 1;
+            break;
+          }
+        }
+      }
+      return { start: offset, text: content.slice(offset, end) };
+    }
+    offset += line.length + 1;
+  }
+  return null;
+}
+
+function commentBlockAbove(content: string, start: number): string[] {
+  const lines = content.slice(0, start).split('\n').slice(0, -1).reverse();
+  const block: string[] = [];
+  for (const line of lines) {
+    if (!line.trim().startsWith('//')) break;
+    block.push(line);
+  }
+  return block;
+}
+
+function normalize(text: string): string {
+  return text.replace(/\s+/g, ' ').trim();
+}
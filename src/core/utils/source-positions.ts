@@ -0,0 +1,197 @@
+import * as fs from 'fs';
+import { resolveWorkspacePath, toPosixPath } from './workspace-paths.js';
+import { escapeRegExp } from './text-utils.js';
+
+/**
+ * Exact source range of a finding, with go/token semantics:
+ * 1-based lines, 1-based UTF-8 byte columns and 0-based byte offsets.
+ * `end_*` point just past the last byte of the range.
+ */
+export interface SourceLocation {
+  /** Workspace-relative, forward slashes */
+  file: string;
+  line: number;
+  column: number;
+  offset: number;
+  end_line: number;
+  end_column: number;
+  end_offset: number;
+}
+
+/**
+ * SourceFile - 文字位置とバイト位置の変換 (token.File 相当)
+ *
+ * Columns and offsets are counted in UTF-8 bytes so they match gopls, go vet
+ * and the compiler even when a line contains Japanese comments or strings.
+ */
+export class SourceFile {
+  readonly file: string;
+  readonly content: string;
+  /** Character index at which each line starts */
+  private lineStarts: number[];
+
+  constructor(file: string, content: string) {
+    this.file = toPosixPath(file);
+    this.content = content;
+    this.lineStarts = [0];
+    for (let i = 0; i < content.length; i++) {
+      if (content[i] === '\n') this.lineStarts.push(i + 1);
+    }
+  }
+
+  /**
+   * Load a workspace file; null when it does not exist
+   */
+  static read(projectRoot: string, file: string): SourceFile | null {
+    try {
+      return new SourceFile(file, fs.readFileSync(resolveWorkspacePath(projectRoot, file), 'utf8'));
+    } catch {
+      return null;
+    }
+  }
+
+  get lineCount(): number {
+    return this.lineStarts.length;
+  }
+
+  /**
+   * Line, byte column and byte offset of a character index
+   */
+  positionAt(index: number): { line: number; column: number; offset: number } {
+    const clamped = Math.max(0, Math.min(index, this.content.length));
+    let low = 0;
+    let high = this.lineStarts.length - 1;
+    while (low < high) {
+      const mid = (low + high + 1) >> 1;
+      if (this.lineStarts[mid] <= clamped) low = mid;
+      else high = mid - 1;
+    }
+
+    const lineStart = this.lineStarts[low];
+    return {
+      line: low + 1,
+      column: byteLength(this.content.slice(lineStart, clamped)) + 1,
+      offset: byteLength(this.content.slice(0, clamped)),
+    };
+  }
+
+  /**
+   * Location of the character range [start, end)
+   */
+  locate(start: number, end: number): SourceLocation {
+    const from = this.positionAt(start);
+    const to = this.positionAt(Math.max(start, end));
+    return {
+      file: this.file,
+      line: from.line,
+      column: from.column,
+      offset: from.offset,
+      end_line: to.line,
+      end_column: to.column,
+      end_offset: to.offset,
+    };
+  }
+
+  /**
+   * Source text covered by a location (byte offsets)
+   */
+  textAt(location: SourceLocation): string {
+    return Buffer.from(this.content, 'utf8').subarray(location.offset, location.end_offset).toString('utf8');
+  }
+
+  /**
+   * Location of a whole line without its indentation
+   */
+  locateLine(line: number): SourceLocation | null {
+    if (line < 1 || line > this.lineStarts.length) return null;
+    const start = this.lineStarts[line - 1];
+    const end = line < this.lineStarts.length ? this.lineStarts[line] - 1 : this.content.length;
+    const text = this.content.slice(start, end).replace(/\r$/, '');
+    const indent = text.length - text.trimStart().length;
+    return this.locate(start + indent, start + text.length);
+  }
+
+  /**
+   * First occurrence of a code snippet outside comments, starting at the given line.
+   * Whitespace differences between the snippet and the source are ignored.
+   */
+  findSnippet(snippet: string, fromLine = 1): SourceLocation | null {
+    const trimmed = snippet.trim();
+    if (!trimmed) return null;
+
+    const pattern = new RegExp(trimmed.split(/\s+/).map(escapeRegExp).join('\\s+'), 'g');
+    return this.firstMatch(pattern, fromLine);
+  }
+
+  /**
+   * The quoted path of an import spec, in single or grouped import declarations
+   */
+  findImport(importPath: string): SourceLocation | null {
+    const pattern = new RegExp(`^\\s*(?:import\\s+)?(?:[\\w.]+\\s+)?("${escapeRegExp(importPath)}")`, 'gm');
+    let match: RegExpExecArray | null;
+    while ((match = pattern.exec(this.content)) !== null) {
+      const start = match.index + match[0].length - match[1].length;
+      if (this.isInImport(start)) return this.locate(start, start + match[1].length);
+    }
+    return null;
+  }
+
+  /**
+   * Imports whose path ends with the given package name (e.g. "order" matches ".../internal/order")
+   */
+  findImportOf(packageName: string): SourceLocation | null {
+    const pattern = new RegExp(`"((?:[^"\\s]*/)?${escapeRegExp(packageName)}s?)"`, 'g');
+    let match: RegExpExecArray | null;
+    while ((match = pattern.exec(this.content)) !== null) {
+      if (this.isInImport(match.index)) return this.locate(match.index, match.index + match[0].length);
+    }
+    return null;
+  }
+
+  /**
+   * Name of a top-level func, method or type declaration
+   */
+  findDeclaration(name: string): SourceLocation | null {
+    const pattern = new RegExp(`^(?:func\\s*(?:\\([^)]*\\)\\s*)?|type\\s+)(${escapeRegExp(name)})\\b`, 'gm');
+    const match = pattern.exec(this.content);
+    if (!match) return null;
+    const start = match.index + match[0].length - match[1].length;
+    return this.locate(start, start + name.length);
+  }
+
+  private firstMatch(pattern: RegExp, fromLine: number): SourceLocation | null {
+    pattern.lastIndex = this.lineStarts[Math.max(0, Math.min(fromLine, this.lineStarts.length) - 1)];
+    let match: RegExpExecArray | null;
+    while ((match = pattern.exec(this.content)) !== null) {
+      if (!this.isInComment(match.index)) return this.locate(match.index, match.index + match[0].length);
+      if (match[0].length === 0) pattern.lastIndex++;
+    }
+    return null;
+  }
+
+  private isInComment(index: number): boolean {
+    const line = this.positionAt(index).line;
+    const before = this.content.slice(this.lineStarts[line - 1], index);
+    return before.replace(/"(?:[^"\\]|\\.)*"|`[^`]*`/g, '').includes('//');
+  }
+
+  private isInImport(index: number): boolean {
+    const before = this.content.slice(0, index);
+    const lineStart = before.lastIndexOf('\n') + 1;
+    if (/^\s*import\s/.test(this.content.slice(lineStart, index + 1))) return true;
+
+    const block = before.lastIndexOf('import (');
+    return block !== -1 && !before.slice(block).includes(')');
+  }
+}
+
+/**
+ * `file:line:col` as printed by the Go toolchain
+ */
+export function formatLocation(location: Pick<SourceLocation, 'file' | 'line'> & Partial<SourceLocation>): string {
+  return location.column ? `${location.file}:${location.line}:${location.column}` : `${location.file}:${location.line}`;
+}
+
+function byteLength(text: string): number {
+  return Buffer.byteLength(text, 'utf8');
+}
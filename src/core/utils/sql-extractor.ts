@@ -0,0 +1,160 @@
+import { parseGoDeclarations } from './context-selector.js';
+
+export type SqlcCommand = ':one' | ':many' | ':exec';
+
+export interface ExtractedQuery {
+  /** sqlc query name (PascalCase) */
+  name: string;
+  function: string;
+  file: string;
+  sql: string;
+  command: SqlcCommand;
+}
+
+export interface NonExtractableQuery {
+  function: string;
+  file: string;
+  reason: string;
+  snippet: string;
+}
+
+export interface SqlExtractionResult {
+  queries: ExtractedQuery[];
+  nonExtractable: NonExtractableQuery[];
+}
+
+const SQL_START = /^\s*(SELECT\b[\s\S]*\bFROM\b|INSERT\s+INTO\b|UPDATE\s+\w+\s+SET\b|DELETE\s+FROM\b|WITH\s+\w+\s+AS\b)/i;
+
+/**
+ * Extract raw SQL string literals from data-access functions in a Go source file.
+ *
+ * Only static literals can be moved into sqlc query files. Queries assembled
+ * with string concatenation or fmt.Sprintf are reported as non-extractable and
+ * must stay in the Go code.
+ */
+export function extractSqlQueries(source: string, file: string): SqlExtractionResult {
+  const queries: ExtractedQuery[] = [];
+  const nonExtractable: NonExtractableQuery[] = [];
+
+  for (const decl of parseGoDeclarations(source, file)) {
+    if (decl.kind === 'type') continue;
+
+    const literals = findStringLiterals(decl.body).filter(l => SQL_START.test(l.value));
+    literals.forEach((literal, index) => {
+      const reason = detectDynamicQuery(decl.body, literal);
+      if (reason) {
+        nonExtractable.push({
+          function: decl.name,
+          file,
+          reason,
+          snippet: firstLine(literal.value),
+        });
+        return;
+      }
+
+      const baseName = toPascalCase(decl.name);
+      queries.push({
+        name: literals.length > 1 ? `${baseName}${index + 1}` : baseName,
+        function: decl.name,
+        file,
+        sql: normalizeSql(literal.value),
+        command: inferCommand(literal.value, decl.body),
+      });
+    });
+  }
+
+  return { queries, nonExtractable };
+}
+
+/**
+ * Render queries as a sqlc query file with named query annotations
+ */
+export function renderSqlcQueryFile(queries: ExtractedQuery[]): string {
+  return queries
+    .map(q => `-- name: ${q.name} ${q.command}\n-- source: ${q.file} (${q.function})\n${q.sql};\n`)
+    .join('\n');
+}
+
+/**
+ * Detect the SQL engine from placeholder style
+ */
+export function detectSqlEngine(queries: ExtractedQuery[]): 'postgresql' | 'mysql' {
+  return queries.some(q => /\$\d+/.test(q.sql)) ? 'postgresql' : 'mysql';
+}
+
+interface StringLiteral {
+  value: string;
+  raw: string;
+  start: number;
+  end: number;
+}
+
+function findStringLiterals(body: string): StringLiteral[] {
+  const literals: StringLiteral[] = [];
+  const pattern = /`([^`]*)`|"((?:[^"\\\n]|\\.)*)"/g;
+  let match: RegExpExecArray | null;
+
+  while ((match = pattern.exec(body)) !== null) {
+    literals.push({
+      value: match[1] ?? match[2].replace(/\\n/g, '\n').replace(/\\"/g, '"'),
+      raw: match[0],
+      start: match.index,
+      end: match.index + match[0].length,
+    });
+  }
+
+  return literals;
+}
+
+function detectDynamicQuery(body: string, literal: StringLiteral): string | null {
+  const before = body.slice(Math.max(0, literal.start - 40), literal.start);
+  const after = body.slice(literal.end, literal.end + 40);
+
+  if (/fmt\.Sprintf\s*\(\s*$/.test(before)) {
+    return 'built with fmt.Sprintf';
+  }
+  if (/\+\s*$/.test(before) || /^\s*\+/.test(after)) {
+    return 'built with string concatenation';
+  }
+  if (/%[sdvq]/.test(literal.value)) {
+    return 'contains format verbs';
+  }
+
+  // query := `...`; query += "..."
+  const assignment = before.match(/(\w+)\s*:?=\s*$/);
+  if (assignment && new RegExp(`\\b${assignment[1]}\\s*\\+=`).test(body.slice(literal.end))) {
+    return 'built with string concatenation';
+  }
+
+  return null;
+}
+
+function inferCommand(sql: string, body: string): SqlcCommand {
+  if (!/^\s*(SELECT|WITH)\b/i.test(sql) && !/\bRETURNING\b/i.test(sql)) {
+    return ':exec';
+  }
+  return /\.QueryRow(Context)?\s*\(/.test(body) ? ':one' : ':many';
+}
+
+function normalizeSql(sql: string): string {
+  const lines = sql.split('\n');
+  while (lines.length > 0 && !lines[0].trim()) lines.shift();
+  while (lines.length > 0 && !lines[lines.length - 1].trim()) lines.pop();
+
+  const indent = Math.min(
+    ...lines.filter(l => l.trim()).map(l => l.match(/^\s*/)![0].length)
+  );
+
+  return lines
+    .map(l => l.slice(indent).replace(/\s+$/, ''))
+    .join('\n')
+    .replace(/;\s*$/, '');
+}
+
+function firstLine(text: string): string {
+  return text.trim().split('\n')[0].slice(0, 80);
+}
+
+function toPascalCase(name: string): string {
+  return name.charAt(0).toUpperCase() + name.slice(1);
+}
@@ -0,0 +1,198 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { execSync } from 'child_process';
+import { ExtractedQuery, detectSqlEngine, renderSqlcQueryFile } from './sql-extractor.js';
+
+export interface GeneratedArtifact {
+  path: string;
+  content: string;
+  description: string;
+}
+
+export interface SqlcModuleArtifacts {
+  module: string;
+  /** Module infrastructure directory (relative to project root) */
+  moduleDir: string;
+  files: GeneratedArtifact[];
+}
+
+const SCHEMA_CANDIDATES = [
+  'db/migrations',
+  'migrations',
+  'db/schema.sql',
+  'schema.sql',
+  'sql/schema.sql',
+];
+
+/**
+ * Locate schema or migration sources for sqlc relative to the project root
+ */
+export function detectSchemaPaths(projectRoot: string, configured?: string[]): string[] {
+  if (configured && configured.length > 0) {
+    return configured;
+  }
+  return SCHEMA_CANDIDATES.filter(candidate => fs.existsSync(path.join(projectRoot, candidate)));
+}
+
+/**
+ * SqlcGenerator - sqlcスタイルのリポジトリ生成
+ *
+ * For each module, writes queries/<module>.sql with named queries, a sqlc.yaml
+ * pointing at the project's schema, and a repository implementation that calls
+ * the sqlc-generated Queries type.
+ */
+export class SqlcGenerator {
+  private projectRoot: string;
+  private schemaPaths: string[];
+
+  constructor(projectRoot: string, schemaPaths: string[]) {
+    this.projectRoot = projectRoot;
+    this.schemaPaths = schemaPaths;
+  }
+
+  generateModuleArtifacts(moduleName: string, queries: ExtractedQuery[]): SqlcModuleArtifacts {
+    const moduleDir = path.join('internal', moduleName, 'infrastructure');
+    const files: GeneratedArtifact[] = [
+      {
+        path: path.join(moduleDir, 'queries', `${moduleName}.sql`),
+        content: renderSqlcQueryFile(queries),
+        description: `sqlc named queries for ${moduleName}`,
+      },
+      {
+        path: path.join(moduleDir, 'sqlc.yaml'),
+        content: this.renderSqlcConfig(moduleDir, queries),
+        description: `sqlc configuration for ${moduleName}`,
+      },
+      {
+        path: path.join(moduleDir, 'generate.go'),
+        content: `package infrastructure\n\n//go:generate sqlc generate\n`,
+        description: 'go generate hook for sqlc',
+      },
+      {
+        path: path.join(moduleDir, `${moduleName}_repository_sqlc.go`),
+        content: this.renderRepository(moduleName, queries),
+        description: `${moduleName} repository backed by sqlc-generated queries`,
+      },
+    ];
+
+    return { module: moduleName, moduleDir, files };
+  }
+
+  /**
+   * Run sqlc in the module directory. Returns false (leaving a stub) when sqlc is not installed.
+   */
+  runSqlcGenerate(artifacts: SqlcModuleArtifacts): boolean {
+    const cwd = path.join(this.projectRoot, artifacts.moduleDir);
+
+    if (!isSqlcInstalled()) {
+      const stubPath = path.join(cwd, 'db', 'db.go');
+      if (!fs.existsSync(stubPath)) {
+        fs.mkdirSync(path.dirname(stubPath), { recursive: true });
+        fs.writeFileSync(stubPath, renderDbStub());
+      }
+      console.log(`    ⚠️  sqlc not installed - run "go generate ./${artifacts.moduleDir}/..." to generate queries`);
+      return false;
+    }
+
+    execSync('sqlc generate', { cwd, stdio: 'pipe' });
+    console.log(`    ✅ sqlc generate completed for ${artifacts.module}`);
+    return true;
+  }
+
+  private renderSqlcConfig(moduleDir: string, queries: ExtractedQuery[]): string {
+    const absoluteModuleDir = path.join(this.projectRoot, moduleDir);
+    const schema = this.schemaPaths.length > 0
+      ? this.schemaPaths.map(p => path.relative(absoluteModuleDir, path.join(this.projectRoot, p)))
+      : ['schema.sql'];
+
+    return [
+      'version: "2"',
+      'sql:',
+      `  - engine: "${detectSqlEngine(queries)}"`,
+      '    queries: "queries"',
+      '    schema:',
+      ...schema.map(s => `      - "${s}"`),
+      '    gen:',
+      '      go:',
+      '        package: "db"',
+      '        out: "db"',
+      '',
+    ].join('\n');
+  }
+
+  private renderRepository(moduleName: string, queries: ExtractedQuery[]): string {
+    const typeName = `${moduleName.charAt(0).toUpperCase()}${moduleName.slice(1)}SqlcRepository`;
+
+    // Embedding *db.Queries promotes the generated, type-safe query methods
+    return [
+      'package infrastructure',
+      '',
+      'import (',
+      '\t"database/sql"',
+      '',
+      `\t"${this.modulePath()}/internal/${moduleName}/infrastructure/db"`,
+      ')',
+      '',
+      `// ${typeName} implements the ${moduleName} repository using sqlc-generated code.`,
+      '//',
+      '// Queries:',
+      ...queries.map(q => `//   - ${q.name} (${q.command}) extracted from ${q.function}`),
+      `type ${typeName} struct {`,
+      '\t*db.Queries',
+      '}',
+      '',
+      `// New${typeName} creates a repository backed by the given database handle.`,
+      `func New${typeName}(conn *sql.DB) *${typeName} {`,
+      `\treturn &${typeName}{Queries: db.New(conn)}`,
+      '}',
+      '',
+    ].join('\n');
+  }
+
+  private modulePath(): string {
+    try {
+      const goMod = fs.readFileSync(path.join(this.projectRoot, 'go.mod'), 'utf8');
+      const match = goMod.match(/^module\s+(\S+)/m);
+      if (match) return match[1];
+    } catch {
+      // Fall through to project directory name
+    }
+    return path.basename(this.projectRoot);
+  }
+}
+
+export function isSqlcInstalled(): boolean {
+  try {
+    execSync('sqlc version', { stdio: 'ignore' });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+function renderDbStub(): string {
+  return `// Code generated as a placeholder until "sqlc generate" runs. DO NOT EDIT.
+
+package db
+
+import (
+\t"context"
+\t"database/sql"
+)
+
+type DBTX interface {
+\tExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+\tPrepareContext(context.Context, string) (*sql.Stmt, error)
+\tQueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+\tQueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+func New(db DBTX) *Queries {
+\treturn &Queries{db: db}
+}
+
+type Queries struct {
+\tdb DBTX
+}
+`;
+}
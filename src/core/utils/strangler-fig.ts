@@ -0,0 +1,226 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { formatLocation } from './source-positions.js';
+import { detectGoProject, goPackageImportPath } from './go-project-utils.js';
+import { toPosixPath } from './workspace-paths.js';
+import { NetworkCallSite, ServiceModule, findModuleOperations, splitSignature } from './service-deployment.js';
+
+/**
+ * How the plan migrates the modules: restructure each module into its target
+ * layout, or strangler fig, where the legacy code stays in place and the other
+ * modules are routed through a new facade per module, phase by phase
+ */
+export type MigrationMode = 'restructure' | 'strangler-fig';
+
+export const MIGRATION_MODES: MigrationMode[] = ['restructure', 'strangler-fig'];
+
+export interface FacadeOperation {
+  /** Exported function of the facade */
+  name: string;
+  /** Legacy function it delegates to, `<package>.<Function>` */
+  legacy: string;
+  /** Import path of the legacy package */
+  legacy_import: string;
+  /** Go signature of the legacy function */
+  signature: string;
+  /** Modules calling it */
+  callers: string[];
+}
+
+/**
+ * The facade of one module: the functions the other modules call, delegating
+ * to the legacy packages, and the call sites switched over to it
+ */
+export interface StranglerFacade {
+  module: string;
+  /** Package directory of the facade, relative to the project root */
+  path: string;
+  /** Import path the redirected call sites use; missing without a go.mod */
+  import_path?: string;
+  operations: FacadeOperation[];
+  /** Call sites in other modules that import the facade instead of the legacy package */
+  redirects: NetworkCallSite[];
+  /** Generated facade under .vibeflow/strangler/, relative to the project root */
+  scaffold?: string;
+}
+
+export interface StranglerStep {
+  phase: string;
+  /** Modules whose facade is put in place in the phase */
+  modules: string[];
+  /** Call sites redirected to the facades in the phase */
+  redirects: number;
+}
+
+export interface StranglerPlan {
+  facades: StranglerFacade[];
+  steps: StranglerStep[];
+}
+
+export const STRANGLER_HEADING = '## ストラングラーフィグ移行 (Strangler Fig)';
+
+/**
+ * Mode of a --migration value
+ */
+export function parseMigrationMode(value: string): MigrationMode {
+  const mode = value.trim().toLowerCase();
+  if (!(MIGRATION_MODES as string[]).includes(mode)) {
+    throw new Error(`Unknown migration mode "${value}" (expected ${MIGRATION_MODES.join(' or ')})`);
+  }
+  return mode as MigrationMode;
+}
+
+/**
+ * Mode of the previous plan.json; restructure when there is none
+ */
+export function loadMigrationMode(projectRoot: string): MigrationMode {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    return (MIGRATION_MODES as unknown[]).includes(plan.migration_mode) ? plan.migration_mode : 'restructure';
+  } catch {
+    return 'restructure';
+  }
+}
+
+/**
+ * Facade of every module: internal/facade/<module>/ exposing the exported
+ * functions other modules call, and those call sites to redirect. The facade
+ * package is named after the module, so a call site only changes its import.
+ */
+export function planFacades(projectRoot: string, modules: ServiceModule[]): StranglerFacade[] {
+  const goProject = detectGoProject(projectRoot);
+  return modules.map(module => {
+    const { operations, calls } = findModuleOperations(projectRoot, modules, module.name);
+    const dir = `internal/facade/${facadePackage(module.name)}`;
+    const importPath = goPackageImportPath(projectRoot, dir, goProject);
+    return {
+      module: module.name,
+      path: dir,
+      ...(importPath ? { import_path: importPath } : {}),
+      operations: operations.map(op => ({
+        name: op.rpc,
+        legacy: op.name,
+        legacy_import: op.package.import_path,
+        signature: op.signature,
+        callers: op.callers,
+      })),
+      redirects: calls,
+    };
+  });
+}
+
+/**
+ * Steps of the strangler fig: each phase puts the facades of its modules in
+ * place and redirects the call sites into them
+ */
+export function planStranglerSteps(facades: StranglerFacade[], phases: { name: string; modules: string[] }[]): StranglerStep[] {
+  return phases.map(phase => {
+    const placed = facades.filter(facade => phase.modules.includes(facade.module));
+    return {
+      phase: phase.name,
+      modules: placed.map(facade => facade.module),
+      redirects: placed.reduce((sum, facade) => sum + facade.redirects.length, 0),
+    };
+  });
+}
+
+/**
+ * Write the facade of a module to .vibeflow/strangler/<module>/ under its target path
+ *
+ * @returns written path relative to the project root
+ */
+export function writeFacadeScaffold(projectRoot: string, facade: StranglerFacade): string {
+  const target = `${facade.path}/${facadePackage(facade.module)}.go`;
+  const filePath = path.join(new VibeFlowPaths(projectRoot).stranglerDir, facadePackage(facade.module), target);
+  fs.mkdirSync(path.dirname(filePath), { recursive: true });
+  fs.writeFileSync(filePath, renderFacade(facade));
+  return toPosixPath(path.relative(projectRoot, filePath));
+}
+
+/**
+ * Go source of a facade: one delegating function per operation, and type
+ * aliases for the legacy types named in their signatures so callers keep them
+ */
+export function renderFacade(facade: StranglerFacade): string {
+  const pkg = facadePackage(facade.module);
+  const aliases = new Map<string, string>();
+  const imports = new Map<string, string>();
+
+  const functions = facade.operations.map(op => {
+    if (!imports.has(op.legacy_import)) {
+      // Two legacy packages of the same name get numbered aliases
+      const base = `legacy${op.legacy.split('.')[0].replace(/[^A-Za-z0-9]/g, '')}`;
+      const taken = [...imports.values()].filter(alias => alias === base || (alias.startsWith(base) && /^\d+$/.test(alias.slice(base.length)))).length;
+      imports.set(op.legacy_import, taken > 0 ? `${base}${taken + 1}` : base);
+    }
+    const alias = imports.get(op.legacy_import)!;
+    const { params, results } = splitSignature(op.signature);
+    const named = params.map((param, i) => ({ ...param, name: param.name && param.name !== '_' ? param.name : `p${i}` }));
+    for (const type of [...named, ...results].map(field => field.type)) {
+      for (const match of type.matchAll(/(?<![\w.])([A-Z]\w*)/g)) {
+        if (!aliases.has(match[1])) aliases.set(match[1], `${alias}.${match[1]}`);
+      }
+    }
+
+    const resultList = op.signature.slice(op.signature.indexOf(')') + 1).trim();
+    const args = named.map(param => (param.type.startsWith('...') ? `${param.name}...` : param.name)).join(', ');
+    const call = `${alias}.${op.legacy.split('.')[1]}(${args})`;
+    return `// ${op.name} delegates to ${op.legacy} (called from ${op.callers.join(', ')}).
+func ${op.name}(${named.map(param => `${param.name} ${param.type}`).join(', ')})${resultList ? ` ${resultList}` : ''} {
+	${results.length > 0 ? `return ${call}` : call}
+}`;
+  });
+
+  return `// Package ${pkg} is the strangler-fig facade of the ${facade.module} module: other modules
+// call it instead of the legacy packages, which are then replaced behind it.
+// Generated by vf plan; review before moving it to ${facade.path}/ (run goimports for
+// the packages the signatures use).
+package ${pkg}
+${imports.size > 0 ? `
+import (
+${[...imports].map(([importPath, alias]) => `\t${alias} "${importPath}"`).join('\n')}
+)
+` : ''}${aliases.size > 0 ? `
+${[...aliases].map(([name, target]) => `type ${name} = ${target}`).join('\n')}
+` : ''}${functions.map(fn => `\n${fn}\n`).join('')}`;
+}
+
+/**
+ * plan.md section: per phase, the facades put in place and the call sites to redirect
+ */
+export function renderStranglerSection(plan: StranglerPlan | undefined): string {
+  if (!plan) return '';
+
+  const steps = plan.steps.map((step, index) => {
+    const facades = plan.facades.filter(facade => step.modules.includes(facade.module));
+    return [
+      `### フェーズ${index + 1}: ${step.phase}`,
+      '',
+      ...facades.flatMap(facade => facade.operations.length === 0
+        ? [`- ${facade.module}: 他モジュールからの呼び出しなし（ファサード不要）`]
+        : [
+          `- ${facade.module}: ファサード \`${facade.path}\` (${facade.operations.length}個の関数${facade.scaffold ? `、ひな形: \`${facade.scaffold}\`` : ''})`,
+          ...facade.operations.map(op => `  - \`${op.name}\` → \`${op.legacy}\` (呼び出し元: ${op.callers.join(', ')})`),
+          `  - 切り替える呼び出し箇所: ${facade.redirects.length}件（import を \`${facade.import_path ?? facade.path}\` に変更）`,
+          ...facade.redirects.map(call => `    - ${formatLocation(call.location)} ${call.caller}${call.function ? `.${call.function}` : ''} → \`${call.operation}\``),
+        ]),
+    ].join('\n');
+  });
+
+  return `
+${STRANGLER_HEADING}
+
+レガシーコードはそのまま残し、フェーズごとにモジュールのファサードを追加して、他モジュールからの呼び出しをファサード経由に切り替えます。
+すべての呼び出しが切り替わったモジュールは、ファサードの裏でレガシーコードを置き換えられます（呼び出し側の変更は不要）。
+
+${steps.join('\n\n')}
+`;
+}
+
+/**
+ * Go package name of a module's facade
+ */
+function facadePackage(module: string): string {
+  return module.toLowerCase().replace(/[^a-z0-9]/g, '') || 'facade';
+}
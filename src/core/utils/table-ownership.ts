@@ -0,0 +1,447 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { parseGoDeclarations } from './context-selector.js';
+import { defaultTableName, extractOrmFields } from './data-mapping-report.js';
+import { detectSchemaPaths } from './sqlc-generator.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Share of the clustering edge weight two files owning the same tables add */
+export const TABLE_OWNERSHIP_WEIGHT = 0.6;
+
+export type OrmKind = 'gorm' | 'sqlx' | 'ent';
+
+export interface SchemaTable {
+  /** Lower-case table name without schema qualifier */
+  name: string;
+  /** Schema or migration file that creates it, or the Go file of the ORM model declaring it */
+  source: string;
+  /** Tables its foreign keys point to */
+  references: string[];
+}
+
+export interface TableAccess {
+  table: string;
+  file: string;
+  /** Struct mapped to the table, or the function or method holding the query */
+  symbol: string;
+  via: 'struct' | 'query';
+  /** Statement of a query; absent for struct mappings, 'select' for a struct only scanned from a query (sqlx) */
+  operation?: 'select' | 'insert' | 'update' | 'delete';
+  /** ORM the mapping or query goes through; absent for plain SQL */
+  orm?: OrmKind;
+}
+
+export interface TableOwnership {
+  /** Schema and migration sources read */
+  sources: string[];
+  tables: SchemaTable[];
+  access: TableAccess[];
+}
+
+/**
+ * Who holds a table: the structs mapped to it and the modules accessing it
+ */
+export interface TableOwnershipEntry {
+  table: string;
+  /** Structs mapped to the table, with the package declaring them */
+  structs: { struct: string; package: string; file: string; orm?: OrmKind }[];
+  /** Modules whose files access the table; `owns` when they map a struct to it or write it */
+  modules: { module: string; owns: boolean; files: string[] }[];
+}
+
+/**
+ * A table accessed by more than one module
+ */
+export interface SharedTableViolation {
+  table: string;
+  modules: string[];
+  /** Modules among them that map or write the table */
+  owners: string[];
+}
+
+export interface DataOwnershipMap {
+  tables: TableOwnershipEntry[];
+  violations: SharedTableViolation[];
+}
+
+// Table name, optionally schema-qualified and quoted; captures the unqualified name
+const TABLE = String.raw`(?:[\`"]?\w+[\`"]?\.)?[\`"]?(\w+)`;
+
+const QUERY_PATTERNS: { pattern: RegExp; operation: NonNullable<TableAccess['operation']> }[] = [
+  { pattern: new RegExp(`\\bINSERT\\s+INTO\\s+${TABLE}`, 'gi'), operation: 'insert' },
+  { pattern: new RegExp(`\\bUPDATE\\s+${TABLE}[\`"]?\\s+SET\\b`, 'gi'), operation: 'update' },
+  { pattern: new RegExp(`\\bDELETE\\s+FROM\\s+${TABLE}`, 'gi'), operation: 'delete' },
+  { pattern: new RegExp(`\\b(?:FROM|JOIN)\\s+${TABLE}`, 'gi'), operation: 'select' },
+];
+
+const OPERATION_RANK = { select: 0, insert: 1, update: 1, delete: 1 } as const;
+
+const GORM_CALLS: Record<string, NonNullable<TableAccess['operation']>> = {
+  Create: 'insert', Save: 'update', Delete: 'delete', Model: 'select', First: 'select', Find: 'select', Take: 'select', Last: 'select',
+};
+
+const ENT_CALLS: Record<string, NonNullable<TableAccess['operation']>> = {
+  Query: 'select', Get: 'select', GetX: 'select',
+  Create: 'insert', CreateBulk: 'insert',
+  Update: 'update', UpdateOne: 'update', UpdateOneID: 'update',
+  Delete: 'delete', DeleteOne: 'delete', DeleteOneID: 'delete',
+};
+
+/**
+ * Tables created by schema.sql and migrations (default locations or `repository.schema`).
+ * Migrations are applied in file order; `.down.sql` files are skipped and dropped tables removed.
+ */
+export function loadSchemaTables(projectRoot: string, configured?: string[]): { sources: string[]; tables: SchemaTable[] } {
+  const files = detectSchemaPaths(projectRoot, configured).flatMap(schemaPath => {
+    const fullPath = path.join(projectRoot, schemaPath);
+    if (!fs.existsSync(fullPath)) return [];
+    return fs.statSync(fullPath).isDirectory()
+      ? fastGlob.sync('**/*.sql', { cwd: fullPath }).sort().map(file => path.posix.join(toPosixPath(schemaPath), file))
+      : [toPosixPath(schemaPath)];
+  }).filter(file => !file.endsWith('.down.sql'));
+
+  const tables = new Map<string, SchemaTable>();
+  for (const file of files) {
+    const sql = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+    for (const table of parseSchemaTables(sql, file)) {
+      if (!tables.has(table.name)) tables.set(table.name, table);
+    }
+    for (const [from, to] of parseAlteredReferences(sql)) {
+      const table = tables.get(from);
+      if (table && !table.references.includes(to)) table.references.push(to);
+    }
+    for (const match of sql.matchAll(new RegExp(`\\bDROP\\s+TABLE\\s+(?:IF\\s+EXISTS\\s+)?${TABLE}`, 'gi'))) {
+      tables.delete(match[1].toLowerCase());
+    }
+  }
+
+  return { sources: files, tables: [...tables.values()].sort((a, b) => a.name.localeCompare(b.name)) };
+}
+
+/**
+ * CREATE TABLE statements with the tables their foreign keys reference
+ */
+export function parseSchemaTables(sql: string, source: string): SchemaTable[] {
+  const tables: SchemaTable[] = [];
+  const createPattern = new RegExp(`\\bCREATE\\s+TABLE\\s+(?:IF\\s+NOT\\s+EXISTS\\s+)?${TABLE}[\`"]?\\s*\\(`, 'gi');
+  let match: RegExpExecArray | null;
+
+  while ((match = createPattern.exec(sql)) !== null) {
+    const name = match[1].toLowerCase();
+    const body = parenthesized(sql, match.index + match[0].length - 1);
+    const references = [...body.matchAll(new RegExp(`\\bREFERENCES\\s+${TABLE}`, 'gi'))].map(m => m[1].toLowerCase());
+    tables.push({ name, source, references: [...new Set(references)].filter(ref => ref !== name) });
+  }
+
+  return tables;
+}
+
+/**
+ * Tables declared by ORM models, for projects without schema.sql or migrations:
+ * GORM models (gorm.Model, gorm tags or a TableName method) and ent schemas
+ */
+export function findOrmTables(projectRoot: string, files: string[]): SchemaTable[] {
+  const tables = new Map<string, SchemaTable>();
+  for (const [file, source] of readGoSources(projectRoot, files)) {
+    for (const model of ormModels(source, file)) {
+      if (!tables.has(model.table)) tables.set(model.table, { name: model.table, source: file, references: [] });
+    }
+  }
+  return [...tables.values()].sort((a, b) => a.name.localeCompare(b.name));
+}
+
+/**
+ * Tables each Go file maps a struct to or queries, limited to tables of the schema.
+ * Besides SQL literals this follows GORM calls on model literals and variables
+ * (db.Model(&Product{}).Update(...)), sqlx scans into structs (db.Select(&rows, query))
+ * and ent client calls (client.User.Create()).
+ */
+export function findTableAccess(projectRoot: string, files: string[], tables: string[]): TableAccess[] {
+  const known = new Set(tables.map(table => table.toLowerCase()));
+  if (known.size === 0) return [];
+
+  const access = new Map<string, TableAccess>();
+  const record = (entry: TableAccess) => {
+    const key = `${entry.table}\n${entry.file}\n${entry.symbol}\n${entry.via}`;
+    const existing = access.get(key);
+    if (!existing || accessRank(entry) > accessRank(existing)) {
+      access.set(key, entry);
+    }
+  };
+
+  const sources = readGoSources(projectRoot, files);
+  // Models by struct name, and the files declaring each struct (for sqlx scans)
+  const models = new Map<string, { table: string; orm: OrmKind }>();
+  const structFiles = new Map<string, string[]>();
+  for (const [file, source] of sources) {
+    for (const model of ormModels(source, file)) models.set(model.struct, model);
+    for (const declaration of parseGoDeclarations(source, file)) {
+      if (declaration.kind !== 'type' || !/\bstruct\s*\{/.test(declaration.body)) continue;
+      structFiles.set(declaration.name, [...(structFiles.get(declaration.name) ?? []), file]);
+    }
+  }
+
+  for (const [file, source] of sources) {
+    const orms = structOrms(source, file);
+    for (const field of extractOrmFields(source, file, known)) {
+      const table = field.table.toLowerCase();
+      const orm = orms.get(field.struct);
+      if (known.has(table)) record({ table, file, symbol: field.struct, via: 'struct', ...(orm ? { orm } : {}) });
+    }
+    for (const model of ormModels(source, file)) {
+      if (known.has(model.table)) record({ table: model.table, file, symbol: model.struct, via: 'struct', orm: model.orm });
+    }
+
+    for (const declaration of parseGoDeclarations(source, file)) {
+      if (declaration.kind === 'type') continue;
+      const body = declaration.body;
+
+      for (const match of body.matchAll(/\.(Model|Create|Save|Delete|First|Find|Take|Last)\(\s*&?(?:(?:\w+\.)?(\w+)\s*\{|(\w+)\s*[,)])/g)) {
+        const model = models.get(match[2] ?? variableType(body, match[3]) ?? '');
+        if (!model || model.orm !== 'gorm' || !known.has(model.table)) continue;
+        const chain = statementAt(body, match.index!);
+        const operation = match[1] === 'Model' && /\bUpdates?(?:Columns?)?\(/.test(chain) ? 'update'
+          : match[1] === 'Model' && /\bDelete\(/.test(chain) ? 'delete'
+          : GORM_CALLS[match[1]];
+        record({ table: model.table, file, symbol: declaration.name, via: 'query', operation, orm: 'gorm' });
+      }
+
+      for (const match of body.matchAll(/\.(?:Get|Select)(?:Context)?\(\s*(?:\w+\s*,\s*)?&(\w+)\s*,\s*(`[^`]*`|"(?:[^"\\\n]|\\.)*"|\w+)/g)) {
+        const struct = variableType(body, match[1]);
+        const declared = struct ? structFiles.get(struct) : undefined;
+        if (!struct || declared?.length !== 1) continue;
+        const query = /^\w+$/.test(match[2]) ? constantQuery(source, match[2]) : match[2].slice(1, -1);
+        for (const table of queriedTables(query ?? '')) {
+          if (known.has(table)) record({ table, file: declared[0], symbol: struct, via: 'struct', operation: 'select', orm: 'sqlx' });
+        }
+      }
+
+      for (const match of body.matchAll(/\.(\w+)\.(\w+)\(/g)) {
+        const model = models.get(match[1]);
+        const operation = ENT_CALLS[match[2]];
+        if (!model || model.orm !== 'ent' || !operation || !known.has(model.table)) continue;
+        record({ table: model.table, file, symbol: declaration.name, via: 'query', operation, orm: 'ent' });
+      }
+
+      for (const literal of stringLiterals(declaration.body)) {
+        for (const { pattern, operation } of QUERY_PATTERNS) {
+          for (const match of literal.matchAll(pattern)) {
+            const table = match[1].toLowerCase();
+            if (known.has(table)) record({ table, file, symbol: declaration.name, via: 'query', operation });
+          }
+        }
+      }
+      for (const match of declaration.body.matchAll(/\.Table\s*\(\s*["`](?:\w+\.)?(\w+)["`]\s*\)/g)) {
+        const table = match[1].toLowerCase();
+        if (known.has(table)) record({ table, file, symbol: declaration.name, via: 'query', operation: 'select' });
+      }
+    }
+  }
+
+  return [...access.values()].sort((a, b) =>
+    a.table.localeCompare(b.table) || a.file.localeCompare(b.file) || a.symbol.localeCompare(b.symbol) || a.via.localeCompare(b.via));
+}
+
+/**
+ * Whether the access makes the file an owner of the table: it maps a struct to it or writes it.
+ * A struct that is only scanned from a query (sqlx) reads the table.
+ */
+export function ownsTable(access: TableAccess): boolean {
+  return (access.via === 'struct' && access.operation === undefined) || (access.operation !== undefined && access.operation !== 'select');
+}
+
+/**
+ * Table → struct → package ownership map of the discovered modules, with the
+ * tables more than one module accesses
+ */
+export function buildDataOwnership(
+  ownership: TableOwnership,
+  modules: { name: string; files: string[] }[],
+  packageOf: (file: string) => string = file => path.posix.dirname(file)
+): DataOwnershipMap {
+  const moduleOf = new Map<string, string>();
+  for (const module of modules) {
+    for (const file of module.files) {
+      if (!moduleOf.has(toPosixPath(file))) moduleOf.set(toPosixPath(file), module.name);
+    }
+  }
+
+  const tables: TableOwnershipEntry[] = [];
+  for (const table of [...new Set(ownership.access.map(a => a.table))].sort()) {
+    const access = ownership.access.filter(a => a.table === table);
+    const structs = access
+      .filter(a => a.via === 'struct')
+      .map(a => ({ struct: a.symbol, package: packageOf(a.file), file: a.file, ...(a.orm ? { orm: a.orm } : {}) }));
+
+    const byModule = new Map<string, { owns: boolean; files: Set<string> }>();
+    for (const entry of access) {
+      const module = moduleOf.get(entry.file);
+      if (!module) continue;
+      const holder = byModule.get(module) ?? { owns: false, files: new Set<string>() };
+      holder.owns ||= ownsTable(entry);
+      holder.files.add(entry.file);
+      byModule.set(module, holder);
+    }
+    const entryModules = [...byModule]
+      .map(([module, holder]) => ({ module, owns: holder.owns, files: [...holder.files].sort() }))
+      .sort((a, b) => Number(b.owns) - Number(a.owns) || a.module.localeCompare(b.module));
+    tables.push({ table, structs, modules: entryModules });
+  }
+
+  return {
+    tables,
+    violations: tables
+      .filter(entry => entry.modules.length > 1)
+      .map(entry => ({
+        table: entry.table,
+        modules: entry.modules.map(m => m.module),
+        owners: entry.modules.filter(m => m.owns).map(m => m.module),
+      })),
+  };
+}
+
+/**
+ * Tables owned by each file, for clustering edge weights
+ */
+export class TableOwnershipIndex {
+  private owned = new Map<string, Set<string>>();
+
+  constructor(access: TableAccess[]) {
+    for (const entry of access.filter(ownsTable)) {
+      if (!this.owned.has(entry.file)) this.owned.set(entry.file, new Set());
+      this.owned.get(entry.file)!.add(entry.table);
+    }
+  }
+
+  tablesOwnedBy(files: string[]): string[] {
+    return [...new Set(files.flatMap(file => [...(this.owned.get(toPosixPath(file)) ?? [])]))].sort();
+  }
+
+  /** Shared share of the tables the two files own (0 when either owns none) */
+  strength(file1: string, file2: string): number {
+    const a = this.owned.get(toPosixPath(file1));
+    const b = this.owned.get(toPosixPath(file2));
+    if (!a || !b || file1 === file2) return 0;
+    const shared = [...a].filter(table => b.has(table)).length;
+    return shared / (a.size + b.size - shared);
+  }
+}
+
+/**
+ * Struct mapping ahead of query reads, writes ahead of reads
+ */
+function accessRank(access: TableAccess): number {
+  return access.via === 'struct' && access.operation === undefined ? 2 : OPERATION_RANK[access.operation ?? 'select'];
+}
+
+function readGoSources(projectRoot: string, files: string[]): Map<string, string> {
+  const sources = new Map<string, string>();
+  for (const file of [...new Set(files.map(toPosixPath))].sort()) {
+    if (!file.endsWith('.go') || file.endsWith('_test.go')) continue;
+    try {
+      sources.set(file, fs.readFileSync(path.resolve(projectRoot, file), 'utf8'));
+    } catch {
+      continue;
+    }
+  }
+  return sources;
+}
+
+/**
+ * GORM models (gorm.Model, gorm tags, or TableName without sqlx db tags) and ent
+ * schemas (ent.Schema, table from entsql.Annotation) with their tables
+ */
+function ormModels(source: string, file: string): { struct: string; table: string; orm: OrmKind }[] {
+  const declarations = parseGoDeclarations(source, file);
+  const methodOf = (receiver: string, name: string) => declarations.find(d =>
+    d.kind === 'method' && d.name === name && d.signature.match(/^func\s*\((?:\s*\w+\s+)?\s*\*?\s*(\w+)/)?.[1] === receiver);
+
+  const models: { struct: string; table: string; orm: OrmKind }[] = [];
+  for (const declaration of declarations) {
+    if (declaration.kind !== 'type' || !/\bstruct\s*\{/.test(declaration.body)) continue;
+    const tableName = methodOf(declaration.name, 'TableName')?.body.match(/return\s+"(?:\w+\.)?(\w+)"/)?.[1];
+
+    if (/^\s*ent\.Schema\s*$/m.test(declaration.body)) {
+      const annotated = methodOf(declaration.name, 'Annotations')?.body.match(/entsql\.Annotation\s*\{[^}]*\bTable:\s*"(\w+)"/)?.[1];
+      models.push({ struct: declaration.name, table: (annotated ?? defaultTableName(declaration.name)).toLowerCase(), orm: 'ent' });
+    } else if (/^\s*gorm\.Model\s*$/m.test(declaration.body) || /\bgorm:"/.test(declaration.body) || (tableName && !/\bdb:"/.test(declaration.body))) {
+      models.push({ struct: declaration.name, table: (tableName ?? defaultTableName(declaration.name)).toLowerCase(), orm: 'gorm' });
+    }
+  }
+  return models;
+}
+
+/**
+ * ORM of each tagged struct: gorm tags or gorm.Model, otherwise db tags (sqlx)
+ */
+function structOrms(source: string, file: string): Map<string, OrmKind> {
+  const orms = new Map<string, OrmKind>();
+  for (const model of ormModels(source, file)) orms.set(model.struct, model.orm);
+  for (const declaration of parseGoDeclarations(source, file)) {
+    if (declaration.kind === 'type' && !orms.has(declaration.name) && /\bdb:"/.test(declaration.body)) {
+      orms.set(declaration.name, 'sqlx');
+    }
+  }
+  return orms;
+}
+
+/**
+ * Struct type of a local variable (var x T, var xs []T, x := T{}, x := &T{}, make([]T, ...))
+ */
+function variableType(body: string, name: string | undefined): string | undefined {
+  if (!name) return undefined;
+  const patterns = [
+    new RegExp(`\\bvar\\s+${name}\\s+(?:\\[\\])?\\*?(?:\\w+\\.)?(\\w+)`),
+    new RegExp(`\\b${name}\\s*:=\\s*(?:&|\\[\\]\\*?)?(?:\\w+\\.)?(\\w+)\\s*\\{`),
+    new RegExp(`\\b${name}\\s*:=\\s*make\\(\\s*\\[\\]\\*?(?:\\w+\\.)?(\\w+)`),
+  ];
+  for (const pattern of patterns) {
+    const type = body.match(pattern)?.[1];
+    if (type) return type;
+  }
+  return undefined;
+}
+
+/**
+ * SQL of a constant or variable of the file (const q = `SELECT ...`)
+ */
+function constantQuery(source: string, name: string): string | undefined {
+  return source.match(new RegExp(`\\b${name}\\s*=\\s*(\`[^\`]*\`|"(?:[^"\\\\\\n]|\\\\.)*")`))?.[1].slice(1, -1);
+}
+
+function queriedTables(query: string): string[] {
+  return [...query.matchAll(new RegExp(`\\b(?:FROM|JOIN)\\s+${TABLE}`, 'gi'))].map(m => m[1].toLowerCase());
+}
+
+/**
+ * The statement a call starts in, with its chained continuation lines
+ */
+function statementAt(body: string, index: number): string {
+  const lines = body.slice(index).split('\n');
+  const statement = [lines[0]];
+  for (const line of lines.slice(1)) {
+    if (!/^\s*\./.test(line) && !/[.(,]\s*$/.test(statement[statement.length - 1])) break;
+    statement.push(line);
+  }
+  return statement.join('\n');
+}
+
+function parseAlteredReferences(sql: string): [string, string][] {
+  return [...sql.matchAll(new RegExp(`\\bALTER\\s+TABLE\\s+(?:ONLY\\s+)?${TABLE}[\`"]?[^;]*?\\bREFERENCES\\s+${TABLE}`, 'gi'))]
+    .map(m => [m[1].toLowerCase(), m[2].toLowerCase()] as [string, string])
+    .filter(([from, to]) => from !== to);
+}
+
+function stringLiterals(code: string): string[] {
+  return [...code.matchAll(/`[^`]*`|"(?:[^"\\\n]|\\.)*"/g)].map(m => m[0].slice(1, -1));
+}
+
+function parenthesized(text: string, open: number): string {
+  let depth = 0;
+  for (let i = open; i < text.length; i++) {
+    if (text[i] === '(') depth++;
+    else if (text[i] === ')' && --depth === 0) return text.slice(open + 1, i);
+  }
+  return text.slice(open + 1);
+}
@@ -0,0 +1,234 @@
+import { execFileSync } from 'child_process';
+import * as fs from 'fs';
+import * as path from 'path';
+import { BoundaryOwnership, DomainBoundary, OwnershipConfig } from '../types/config.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Where GitHub looks for CODEOWNERS, in order; the first one found is used */
+export const CODEOWNERS_LOCATIONS = ['.github/CODEOWNERS', 'CODEOWNERS', 'docs/CODEOWNERS'];
+
+/** Files blamed per boundary when no CODEOWNERS rule covers it */
+const DEFAULT_MAX_BLAME_FILES = 20;
+
+// git blame attributes uncommitted lines to this address
+const NOT_COMMITTED = 'not.committed.yet';
+
+export interface CodeownersRule {
+  pattern: string;
+  /** Empty when the pattern explicitly leaves the files without owner */
+  owners: string[];
+  line: number;
+}
+
+export interface Codeowners {
+  /** Path of the CODEOWNERS file relative to the repository root */
+  path: string;
+  rules: CodeownersRule[];
+  /** Project root relative to the repository root ('' at the root); CODEOWNERS patterns are relative to the repository */
+  prefix: string;
+}
+
+export interface BlameAuthor {
+  email: string;
+  name: string;
+  lines: number;
+}
+
+/**
+ * Rules of a CODEOWNERS file: one pattern per line followed by its owners
+ * (@user, @org/team or an email); comments and blank lines are skipped
+ */
+export function parseCodeowners(content: string): CodeownersRule[] {
+  const rules: CodeownersRule[] = [];
+  content.split('\n').forEach((raw, index) => {
+    const line = raw.replace(/(^|\s)#.*$/, '').trim();
+    if (!line) return;
+    const [pattern, ...owners] = line.split(/\s+/);
+    rules.push({ pattern: pattern.replace(/\\#/g, '#'), owners, line: index + 1 });
+  });
+  return rules;
+}
+
+/**
+ * Owners of a file (relative to the repository root): the last matching rule wins,
+ * as on GitHub. undefined when no rule matches.
+ */
+export function matchCodeowners(rules: CodeownersRule[], file: string): string[] | undefined {
+  const posix = toPosixPath(file);
+  for (let i = rules.length - 1; i >= 0; i--) {
+    if (codeownersPattern(rules[i].pattern).test(posix)) return rules[i].owners;
+  }
+  return undefined;
+}
+
+/**
+ * CODEOWNERS of the repository containing the project (or of the project
+ * itself when it is no git checkout); null when there is none
+ */
+export function loadCodeowners(projectRoot: string): Codeowners | null {
+  const topLevel = gitTopLevel(projectRoot);
+  const repositoryRoot = topLevel ?? projectRoot;
+  // git prints the top level with symlinks resolved
+  const prefix = topLevel ? toPosixPath(path.relative(topLevel, fs.realpathSync(projectRoot))) : '';
+  for (const location of CODEOWNERS_LOCATIONS) {
+    const file = path.join(repositoryRoot, location);
+    if (fs.existsSync(file)) {
+      return { path: location, rules: parseCodeowners(fs.readFileSync(file, 'utf8')), prefix };
+    }
+  }
+  return null;
+}
+
+/**
+ * Lines per author in `git blame --line-porcelain` output (uncommitted lines left out)
+ */
+export function parseBlame(output: string): BlameAuthor[] {
+  const authors = new Map<string, BlameAuthor>();
+  let name = '';
+  for (const line of output.split('\n')) {
+    if (line.startsWith('author ')) {
+      name = line.slice('author '.length);
+    } else if (line.startsWith('author-mail ')) {
+      const email = line.slice('author-mail '.length).replace(/^<|>$/g, '');
+      if (email === NOT_COMMITTED) continue;
+      const author = authors.get(email) ?? { email, name, lines: 0 };
+      author.lines++;
+      authors.set(email, author);
+    }
+  }
+  return [...authors.values()];
+}
+
+/**
+ * Authors of the lines of a file relative to the project root; null when the
+ * file is not tracked or git is not available
+ */
+export function blameFile(projectRoot: string, file: string): BlameAuthor[] | null {
+  try {
+    const output = execFileSync('git', ['blame', '--line-porcelain', '-w', '--', file], {
+      cwd: projectRoot,
+      encoding: 'utf8',
+      stdio: ['ignore', 'pipe', 'ignore'],
+      maxBuffer: 64 * 1024 * 1024,
+      timeout: 60000,
+    });
+    return parseBlame(output);
+  } catch {
+    return null;
+  }
+}
+
+/**
+ * Annotate each boundary with its owning team. CODEOWNERS decides when it covers
+ * any of the boundary's files (share: owned files / files); otherwise git blame of
+ * up to maxBlameFiles files does (share: lines / blamed lines), with authors mapped
+ * to teams through `ownership.teams` of boundary.yaml. Boundaries neither covers
+ * are left without ownership.
+ */
+export function attributeTeamOwnership(
+  projectRoot: string,
+  boundaries: DomainBoundary[],
+  config: OwnershipConfig = {}
+): DomainBoundary[] {
+  const codeowners = loadCodeowners(projectRoot);
+  const teamOf = authorTeams(config.teams ?? {});
+  const maxBlameFiles = config.maxBlameFiles ?? DEFAULT_MAX_BLAME_FILES;
+
+  return boundaries.map(boundary => {
+    const ownership = (codeowners && ownershipFromCodeowners(boundary.files, codeowners))
+      ?? ownershipFromBlame(projectRoot, boundary.files, maxBlameFiles, teamOf);
+    return ownership ? { ...boundary, ownership } : boundary;
+  });
+}
+
+function ownershipFromCodeowners(files: string[], codeowners: Codeowners): BoundaryOwnership | null {
+  const counts = new Map<string, number>();
+  for (const file of files) {
+    const owners = matchCodeowners(codeowners.rules, codeowners.prefix ? `${codeowners.prefix}/${file}` : file) ?? [];
+    for (const owner of new Set(owners)) counts.set(owner, (counts.get(owner) ?? 0) + 1);
+  }
+  return rank(counts, files.length, 'codeowners');
+}
+
+function ownershipFromBlame(
+  projectRoot: string,
+  files: string[],
+  maxBlameFiles: number,
+  teamOf: (author: BlameAuthor) => string
+): BoundaryOwnership | null {
+  const counts = new Map<string, number>();
+  let total = 0;
+  for (const file of [...files].sort().slice(0, maxBlameFiles)) {
+    for (const author of blameFile(projectRoot, file) ?? []) {
+      const owner = teamOf(author);
+      counts.set(owner, (counts.get(owner) ?? 0) + author.lines);
+      total += author.lines;
+    }
+  }
+  return rank(counts, total, 'blame');
+}
+
+function rank(counts: Map<string, number>, total: number, source: BoundaryOwnership['source']): BoundaryOwnership | null {
+  if (counts.size === 0 || total === 0) return null;
+  const owners = [...counts.entries()]
+    .sort(([a, x], [b, y]) => y - x || a.localeCompare(b))
+    .map(([owner, count]) => ({ owner, share: Math.round((count / total) * 100) / 100 }));
+  return { team: owners[0].owner, source, share: owners[0].share, owners };
+}
+
+/**
+ * Team of a blamed author by email or name (case-insensitive); authors of no team stand for themselves
+ */
+function authorTeams(teams: Record<string, string[]>): (author: BlameAuthor) => string {
+  const members = new Map<string, string>();
+  for (const [team, authors] of Object.entries(teams)) {
+    for (const author of authors) members.set(author.toLowerCase(), team);
+  }
+  return author => members.get(author.email.toLowerCase()) ?? members.get(author.name.toLowerCase()) ?? author.email;
+}
+
+/**
+ * CODEOWNERS pattern as a regular expression over repository-relative paths.
+ * Like .gitignore: a leading or inner slash anchors the pattern at the root, a
+ * directory matches everything below it; as on GitHub, `docs/*` does not match
+ * files in subdirectories of docs.
+ */
+function codeownersPattern(pattern: string): RegExp {
+  const directory = pattern.endsWith('/');
+  const glob = pattern.replace(/^\//, '').replace(/\/$/, '');
+  const anchored = pattern.startsWith('/') || glob.includes('/');
+
+  let body = '';
+  for (let i = 0; i < glob.length; i++) {
+    if (glob.startsWith('**/', i)) {
+      body += '(?:.*/)?';
+      i += 2;
+    } else if (glob.startsWith('**', i)) {
+      body += '.*';
+      i += 1;
+    } else if (glob[i] === '*') {
+      body += '[^/]*';
+    } else if (glob[i] === '?') {
+      body += '[^/]';
+    } else {
+      body += glob[i].replace(/[.+^${}()|[\]\\]/g, '\\$&');
+    }
+  }
+
+  const last = glob.split('/').pop()!;
+  const suffix = directory ? '/' : /[*?]/.test(last) && last !== '**' ? '$' : '(?:/|$)';
+  return new RegExp(`^${anchored ? '' : '(?:.*/)?'}${body}${suffix}`);
+}
+
+function gitTopLevel(projectRoot: string): string | null {
+  try {
+    return execFileSync('git', ['rev-parse', '--show-toplevel'], {
+      cwd: projectRoot,
+      encoding: 'utf8',
+      stdio: ['ignore', 'pipe', 'ignore'],
+      timeout: 10000,
+    }).trim() || null;
+  } catch {
+    return null;
+  }
+}
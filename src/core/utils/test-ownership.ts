@@ -0,0 +1,92 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { TestOwnership } from '../types/config.js';
+import { maskLiterals } from './api-surface.js';
+import { goPackageName } from './go-load-check.js';
+import { TEST_HELPER_DIR, goImportNames, loadGoPackages } from './go-packages.js';
+import { dominantOwners } from './test-support.js';
+import { toPosixPath } from './workspace-paths.js';
+
+const GO_IGNORE = ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**', '**/testdata/**'];
+
+/**
+ * The boundary each existing _test.go file exercises: the one whose declarations
+ * it references most, through imports (`order.NewService`) or, for internal tests,
+ * as identifiers of its own package. Shared test-helper packages do not count.
+ * Tests referencing no boundary code fall back to the owner of their source file
+ * (`foo_test.go` → `foo.go`), then to the boundary with the most files in their
+ * directory; tests with neither stay unowned.
+ *
+ * @param boundaries boundaries with their (non-test) files relative to the project root
+ */
+export function assignTestOwnership(projectRoot: string, boundaries: { name: string; files: string[] }[]): TestOwnership[] {
+  const packages = loadGoPackages(projectRoot);
+  const ownerOf = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) {
+      const key = toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+      if (!ownerOf.has(key)) ownerOf.set(key, boundary.name);
+    }
+  }
+  const dirOwner = dominantOwners(ownerOf);
+
+  // Owner of each top-level declaration, keyed `<import path>.<name>`
+  const declarations = new Map<string, string>();
+  for (const pkg of packages.filter(pkg => !TEST_HELPER_DIR.test(pkg.dir))) {
+    for (const file of pkg.files) {
+      const owner = ownerOf.get(file);
+      if (!owner) continue;
+      try {
+        const code = maskLiterals(fs.readFileSync(path.join(projectRoot, file), 'utf8'));
+        for (const match of code.matchAll(/^(?:type|func|var|const)\s+(\w+)/gm)) {
+          declarations.set(`${pkg.import_path}.${match[1]}`, owner);
+        }
+      } catch {
+        continue;
+      }
+    }
+  }
+
+  const ownership: TestOwnership[] = [];
+  for (const file of fastGlob.sync('**/*_test.go', { cwd: projectRoot, ignore: GO_IGNORE }).sort()) {
+    let content: string;
+    try {
+      content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+    } catch {
+      continue;
+    }
+
+    const dir = path.posix.dirname(file);
+    const own = packages.find(pkg => pkg.dir === dir);
+    // External test packages (foo_test) reach their package through an import like any other
+    const internal = own && goPackageName(content) === own.name ? own : undefined;
+    const imports = goImportNames(content, packages);
+    const references: Record<string, number> = {};
+    for (const match of maskLiterals(content).matchAll(/(?<![\w.])([A-Za-z_]\w*)(?:\.([A-Z]\w*))?/g)) {
+      const importPath = imports.get(match[1]);
+      const key = importPath && match[2] ? `${importPath}.${match[2]}` : internal ? `${internal.import_path}.${match[1]}` : undefined;
+      const owner = key && declarations.get(key);
+      if (owner) references[owner] = (references[owner] ?? 0) + 1;
+    }
+
+    const source = ownerOf.get(file.replace(/_test\.go$/, '.go'));
+    const ranked = Object.entries(references)
+      .sort((a, b) => b[1] - a[1] || Number(b[0] === source) - Number(a[0] === source) || a[0].localeCompare(b[0]));
+    if (ranked.length > 0) {
+      ownership.push({ file, module: ranked[0][0], basis: 'symbols', references });
+    } else if (source) {
+      ownership.push({ file, module: source, basis: 'source', references });
+    } else if (dirOwner.has(dir)) {
+      ownership.push({ file, module: dirOwner.get(dir)!, basis: 'directory', references });
+    }
+  }
+  return ownership;
+}
+
+/**
+ * Test files owned by a module, as recorded in domain-map.json
+ */
+export function testsOwnedBy(ownership: TestOwnership[], module: string): string[] {
+  return ownership.filter(entry => entry.module === module).map(entry => entry.file);
+}
@@ -0,0 +1,439 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import fastGlob from 'fast-glob';
+import { VibeFlowPaths } from './file-paths.js';
+import { maskLiterals } from './api-surface.js';
+import { dropUnusedImport, ensureImport } from './caller-migration.js';
+import { GoProjectInfo, detectGoProject, goImportAlias, goPackageImportPath } from './go-project-utils.js';
+import { goImports } from './go-load-check.js';
+import { GoPackage, TEST_HELPER_DIR, goImportNames, goImportSpec, loadGoPackages } from './go-packages.js';
+import { toPosixPath } from './workspace-paths.js';
+import { escapeRegExp } from './text-utils.js';
+import { TestHelperUsage } from '../types/config.js';
+
+export type TestHelperPlacement = TestHelperUsage['placement'];
+
+/** Package that keeps the helpers no single module owns (fake clocks, test DB bootstrap) */
+export const SHARED_TEST_SUPPORT_DIR = 'internal/testsupport';
+
+export interface TestSupportHelper {
+  id: string;
+  symbol: string;
+  /** Import path and declared name of the package the helper lives in today */
+  from: string;
+  from_package: string;
+}
+
+export interface TestSupportPackage {
+  /** Owning module; absent for the shared package */
+  module?: string;
+  dir: string;
+  import_path: string;
+  package: string;
+  helpers: TestSupportHelper[];
+}
+
+export interface TestSupportReview {
+  id: string;
+  used_by: string[];
+  references: string[];
+  reason: string;
+}
+
+/**
+ * Planned test-support layout: the package each shared test helper moves to,
+ * and the helpers that stay where they are until someone decides
+ */
+export interface TestSupportPlan {
+  packages: TestSupportPackage[];
+  manual_review: TestSupportReview[];
+}
+
+export interface HelperImportRewrite {
+  symbol: string;
+  from: string;
+  to: string;
+}
+
+interface HelperDeclaration {
+  symbol: string;
+  kind: TestHelperUsage['kind'];
+  file: string;
+  line: number;
+  /** Declaration (for types, with their methods) with literals and comments masked */
+  code: string;
+}
+
+const GO_IGNORE = ['**/vendor/**', '**/node_modules/**', '.vibeflow/**', '.git/**', '**/testdata/**'];
+
+export const TEST_SUPPORT_HEADING = '## テスト支援パッケージ (Test Support)';
+
+/**
+ * Attribute the exported declarations of shared test-helper packages to the
+ * boundaries whose tests use them. A helper package sits in a testutil/-style
+ * directory or is imported by other packages' tests only; a test file belongs
+ * to the boundary owning its source file, else the files next to it.
+ *
+ * @param boundaries boundaries with their (non-test) files relative to the project root
+ */
+export function analyzeTestHelpers(projectRoot: string, boundaries: { name: string; files: string[] }[]): TestHelperUsage[] {
+  const goProject = detectGoProject(projectRoot);
+  const packages = loadGoPackages(projectRoot, goProject);
+  const contents = new Map<string, string>();
+  for (const file of fastGlob.sync('**/*.go', { cwd: projectRoot, ignore: GO_IGNORE }).sort()) {
+    try {
+      contents.set(file, fs.readFileSync(path.join(projectRoot, file), 'utf8'));
+    } catch {
+      // Unreadable files cannot use helpers
+    }
+  }
+
+  const helperPackages = findHelperPackages(packages, contents);
+  if (helperPackages.length === 0) return [];
+
+  const ownerOf = new Map<string, string>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files) {
+      const key = toPosixPath(path.isAbsolute(file) ? path.relative(projectRoot, file) : file);
+      if (!ownerOf.has(key)) ownerOf.set(key, boundary.name);
+    }
+  }
+  const helperDirs = new Set(helperPackages.map(pkg => pkg.dir));
+  const entities = entityOwners(packages.filter(pkg => !helperDirs.has(pkg.dir)), contents, ownerOf);
+  const dirOwner = dominantOwners(ownerOf);
+
+  const usages: TestHelperUsage[] = [];
+  for (const pkg of helperPackages) {
+    const declarations = pkg.files.flatMap(file => helperDeclarations(file, contents.get(file) ?? ''));
+    const references = referencedOwners(declarations, packages, contents, entities);
+
+    const uses = new Map<string, { boundaries: Set<string>; files: string[] }>();
+    for (const [file, content] of contents) {
+      if (!file.endsWith('_test.go') || helperDirs.has(path.posix.dirname(file))) continue;
+      const name = [...goImportNames(content, packages)].find(([, importPath]) => importPath === pkg.import_path)?.[0];
+      if (!name) continue;
+
+      const owner = ownerOf.get(file.replace(/_test\.go$/, '.go')) ?? dirOwner.get(path.posix.dirname(file));
+      const code = maskLiterals(content);
+      for (const declaration of declarations) {
+        if (!new RegExp(`(?<![\\w.])${escapeRegExp(name)}\\.${declaration.symbol}\\b`).test(code)) continue;
+        const use = uses.get(declaration.symbol) ?? { boundaries: new Set<string>(), files: [] };
+        if (owner) use.boundaries.add(owner);
+        use.files.push(file);
+        uses.set(declaration.symbol, use);
+      }
+    }
+
+    for (const declaration of declarations) {
+      const use = uses.get(declaration.symbol);
+      if (!use) continue;
+      const usedBy = [...use.boundaries].sort();
+      const owners = [...(references.get(declaration.symbol) ?? [])].sort();
+      usages.push({
+        id: `${pkg.dir}.${declaration.symbol}`,
+        symbol: declaration.symbol,
+        kind: declaration.kind,
+        package: pkg.dir,
+        import_path: pkg.import_path,
+        package_name: pkg.name,
+        file: declaration.file,
+        line: declaration.line,
+        used_by: usedBy,
+        test_files: use.files,
+        references: owners,
+        ...classifyHelper(usedBy, owners),
+      });
+    }
+  }
+
+  return usages;
+}
+
+/**
+ * Where a helper belongs: with the module whose entities it builds, in the shared
+ * package when it refers to no module entities, or up for manual review when a
+ * single module's tests use it to build another module's entities (or it mixes several)
+ */
+export function classifyHelper(usedBy: string[], references: string[]): { placement: TestHelperPlacement; owner?: string; reason: string } {
+  if (references.length === 0) {
+    return { placement: 'shared', reason: 'cross-cutting: refers to no module entities' };
+  }
+
+  const foreign = usedBy.length === 1 ? references.filter(owner => owner !== usedBy[0]) : [];
+  if (foreign.length > 0) {
+    return { placement: 'manual-review', reason: `only ${usedBy[0]} tests use it, but it refers to entities of ${foreign.join(', ')}` };
+  }
+  if (references.length === 1) {
+    return { placement: 'module', owner: references[0], reason: `builds ${references[0]} entities` };
+  }
+  return { placement: 'manual-review', reason: `refers to entities of ${references.join(', ')}` };
+}
+
+/**
+ * Test-support layout of the plan. Helper attributions are mapped onto the planned
+ * modules (boundaries merged by constraints count as one) and classified again.
+ */
+export function planTestSupport(
+  projectRoot: string,
+  helpers: TestHelperUsage[],
+  modules: { name: string; merged_from?: string[] }[]
+): TestSupportPlan {
+  const moduleOf = (boundary: string) =>
+    modules.find(module => module.name === boundary || (module.merged_from ?? []).includes(boundary))?.name ?? boundary;
+  const goProject = detectGoProject(projectRoot);
+  const packages = new Map<string, TestSupportPackage>();
+  const manualReview: TestSupportReview[] = [];
+
+  for (const helper of helpers) {
+    const usedBy = [...new Set(helper.used_by.map(moduleOf))].sort();
+    const references = [...new Set(helper.references.map(moduleOf))].sort();
+    const { placement, owner, reason } = classifyHelper(usedBy, references);
+    const planned = !owner || modules.some(module => module.name === owner);
+    if (placement === 'manual-review' || !planned) {
+      manualReview.push({ id: helper.id, used_by: usedBy, references, reason: planned ? reason : `${owner} is not a module of the plan` });
+      continue;
+    }
+
+    const target = testSupportPackage(projectRoot, owner, goProject);
+    const entry = packages.get(target.dir) ?? { ...target, helpers: [] };
+    entry.helpers.push({ id: helper.id, symbol: helper.symbol, from: helper.import_path, from_package: helper.package_name });
+    packages.set(target.dir, entry);
+  }
+
+  // Module packages in plan order, the shared package last
+  const order = (pkg: TestSupportPackage) => pkg.module ? modules.findIndex(module => module.name === pkg.module) : modules.length;
+  return {
+    packages: [...packages.values()].sort((a, b) => order(a) - order(b)),
+    manual_review: manualReview,
+  };
+}
+
+/**
+ * Load the test-support layout recorded in .vibeflow/plan.json (undefined without a plan)
+ */
+export function loadTestSupport(projectRoot: string): TestSupportPlan | undefined {
+  try {
+    const plan = JSON.parse(fs.readFileSync(new VibeFlowPaths(projectRoot).planJsonPath, 'utf8'));
+    return plan.test_support && Array.isArray(plan.test_support.packages) ? plan.test_support : undefined;
+  } catch {
+    return undefined;
+  }
+}
+
+/**
+ * Point a test file's helper calls at the packages the helpers move to. The old
+ * import is dropped once nothing refers to it; helpers under manual review are left alone.
+ */
+export function rewriteHelperImports(content: string, plan: TestSupportPlan): { content: string; rewrites: HelperImportRewrite[] } {
+  let result = content;
+  const rewrites: HelperImportRewrite[] = [];
+  const replaced = new Map<string, string>();
+
+  for (const target of plan.packages) {
+    for (const helper of target.helpers) {
+      const alias = goImportAlias(result, helper.from, helper.from_package);
+      if (!alias || alias === '_' || alias === '.') continue;
+      const pattern = new RegExp(`(?<![\\w.])${escapeRegExp(alias)}\\.${helper.symbol}\\b`, 'g');
+      const matches = [...maskLiterals(result).matchAll(pattern)];
+      if (matches.length === 0) continue;
+
+      const spec = goImportSpec(result, target.import_path, target.package);
+      for (const match of matches.reverse()) {
+        result = result.slice(0, match.index) + `${spec.name}.${helper.symbol}` + result.slice(match.index! + match[0].length);
+      }
+      result = ensureImport(result, target.import_path, spec.alias);
+      replaced.set(helper.from, helper.from_package);
+      rewrites.push({ symbol: helper.symbol, from: helper.from, to: target.import_path });
+    }
+  }
+
+  for (const [importPath, packageName] of replaced) result = dropUnusedImport(result, importPath, packageName);
+  return { content: result, rewrites };
+}
+
+/**
+ * plan.md section with the proposed test-support package of each module
+ */
+export function renderTestSupportSection(plan: TestSupportPlan | undefined): string {
+  if (!plan || (plan.packages.length === 0 && plan.manual_review.length === 0)) return '';
+
+  const symbols = (pkg: TestSupportPackage) => pkg.helpers.map(helper => helper.symbol).join(', ');
+  const layout = plan.packages.map(pkg =>
+    `- **${pkg.module ?? '共有'}** → \`${pkg.dir}\` (package ${pkg.package}): ${symbols(pkg)}`
+  );
+  const reviews = plan.manual_review.map(review =>
+    `- \`${review.id}\` — 使用: ${review.used_by.join(', ') || '-'} / 参照: ${review.references.join(', ')}\n  - ${review.reason}`
+  );
+
+  return `
+${TEST_SUPPORT_HEADING}
+
+共有テストヘルパーの移動先です。エンティティのビルダーは所有モジュールへ、横断的なヘルパーは \`${SHARED_TEST_SUPPORT_DIR}\` へ移し、
+移動したテストの import は \`vf refactor\` のテスト移行で書き換えます。
+
+${layout.join('\n')}
+${reviews.length > 0 ? `
+### 要確認 (Manual Review)
+
+以下のヘルパーは自動では移動しません。
+
+${reviews.join('\n')}
+` : ''}`;
+}
+
+/**
+ * Packages of shared test helpers: testutil/-style directories imported by tests,
+ * and packages only other packages' tests import. `package main` never qualifies.
+ */
+function findHelperPackages(packages: GoPackage[], contents: Map<string, string>): GoPackage[] {
+  const dirOf = new Map(packages.map(pkg => [pkg.import_path, pkg.dir]));
+  const importers = new Map<string, { test: boolean; code: boolean }>();
+  for (const [file, content] of contents) {
+    for (const imported of goImports(content)) {
+      if (dirOf.get(imported.path) === path.posix.dirname(file)) continue;
+      const entry = importers.get(imported.path) ?? { test: false, code: false };
+      if (file.endsWith('_test.go')) entry.test = true;
+      else entry.code = true;
+      importers.set(imported.path, entry);
+    }
+  }
+
+  return packages.filter(pkg => {
+    const importedBy = importers.get(pkg.import_path);
+    if (pkg.name === 'main' || !importedBy?.test) return false;
+    return TEST_HELPER_DIR.test(pkg.dir) || !importedBy.code;
+  });
+}
+
+/**
+ * Exported top-level declarations of a helper file; methods are folded into their type
+ */
+function helperDeclarations(file: string, content: string): HelperDeclaration[] {
+  const code = maskLiterals(content);
+  const starts = [...code.matchAll(/^(?:func|type|var|const|import)\b/gm)].map(match => match.index!);
+  const declarations = new Map<string, HelperDeclaration>();
+  const methods: { receiver: string; code: string }[] = [];
+
+  starts.forEach((start, index) => {
+    const text = code.slice(start, starts[index + 1] ?? code.length);
+    const method = text.match(/^func\s*\(\s*(?:\w+\s+)?\*?([A-Z]\w*)/);
+    if (method) {
+      methods.push({ receiver: method[1], code: text });
+      return;
+    }
+    const declaration = text.match(/^(func|type|var|const)\s+([A-Z]\w*)/);
+    if (!declaration) return;
+    declarations.set(declaration[2], {
+      symbol: declaration[2],
+      kind: declaration[1] === 'func' ? 'func' : declaration[1] === 'type' ? 'type' : 'value',
+      file,
+      line: code.slice(0, start).split('\n').length,
+      code: text,
+    });
+  });
+
+  for (const method of methods) {
+    const type = declarations.get(method.receiver);
+    if (type) type.code += method.code;
+  }
+  return [...declarations.values()];
+}
+
+/**
+ * Boundary owning each exported declaration of the boundaries' packages, keyed `<import path>.<name>`
+ */
+function entityOwners(packages: GoPackage[], contents: Map<string, string>, ownerOf: Map<string, string>): Map<string, string> {
+  const owners = new Map<string, string>();
+  for (const pkg of packages) {
+    for (const file of pkg.files) {
+      const owner = ownerOf.get(file);
+      if (!owner) continue;
+      const code = maskLiterals(contents.get(file) ?? '');
+      for (const match of code.matchAll(/^(?:type|func|var|const)\s+([A-Z]\w*)/gm)) {
+        owners.set(`${pkg.import_path}.${match[1]}`, owner);
+      }
+    }
+  }
+  return owners;
+}
+
+/**
+ * Boundaries whose entities each helper refers to, including through other
+ * helpers of the same package it calls
+ */
+function referencedOwners(
+  declarations: HelperDeclaration[],
+  packages: GoPackage[],
+  contents: Map<string, string>,
+  entities: Map<string, string>
+): Map<string, Set<string>> {
+  const references = new Map<string, Set<string>>();
+  const calls = new Map<string, string[]>();
+
+  for (const declaration of declarations) {
+    const names = goImportNames(contents.get(declaration.file) ?? '', packages);
+    const owners = new Set<string>();
+    for (const match of declaration.code.matchAll(/(?<![\w.])([A-Za-z_]\w*)\.([A-Z]\w*)\b/g)) {
+      const importPath = names.get(match[1]);
+      const owner = importPath && entities.get(`${importPath}.${match[2]}`);
+      if (owner) owners.add(owner);
+    }
+    references.set(declaration.symbol, owners);
+    calls.set(declaration.symbol, declarations
+      .filter(other => other.symbol !== declaration.symbol && new RegExp(`(?<![\\w.])${other.symbol}\\b`).test(declaration.code))
+      .map(other => other.symbol));
+  }
+
+  for (let changed = true; changed;) {
+    changed = false;
+    for (const [symbol, callees] of calls) {
+      const owners = references.get(symbol)!;
+      for (const owner of callees.flatMap(callee => [...references.get(callee)!])) {
+        if (owners.has(owner)) continue;
+        owners.add(owner);
+        changed = true;
+      }
+    }
+  }
+  return references;
+}
+
+/**
+ * Boundary with the most files in each directory; test files without a source
+ * file of their own belong to it
+ */
+export function dominantOwners(ownerOf: Map<string, string>): Map<string, string> {
+  const counts = new Map<string, Map<string, number>>();
+  for (const [file, owner] of ownerOf) {
+    const dir = path.posix.dirname(file);
+    const byOwner = counts.get(dir) ?? new Map<string, number>();
+    byOwner.set(owner, (byOwner.get(owner) ?? 0) + 1);
+    counts.set(dir, byOwner);
+  }
+  return new Map([...counts].map(([dir, byOwner]) => [
+    dir,
+    [...byOwner].sort((a, b) => b[1] - a[1] || a[0].localeCompare(b[0]))[0][0],
+  ]));
+}
+
+/**
+ * `internal/<module>/<module>test` for a module, the shared package otherwise
+ */
+function testSupportPackage(projectRoot: string, module: string | undefined, goProject: GoProjectInfo): Omit<TestSupportPackage, 'helpers'> {
+  if (!module) {
+    return {
+      dir: SHARED_TEST_SUPPORT_DIR,
+      import_path: goPackageImportPath(projectRoot, SHARED_TEST_SUPPORT_DIR, goProject) ?? SHARED_TEST_SUPPORT_DIR,
+      package: path.posix.basename(SHARED_TEST_SUPPORT_DIR),
+    };
+  }
+
+  const name = `${module.toLowerCase().replace(/[^a-z0-9]/g, '')}test`;
+  const dir = `internal/${module}/${name}`;
+  return {
+    module,
+    dir,
+    import_path: goPackageImportPath(projectRoot, dir, goProject) ?? dir,
+    package: name,
+  };
+}
@@ -0,0 +1,53 @@
+/**
+ * Text helpers shared by the Go source scanners
+ */
+
+/**
+ * Escape a string for literal use in a RegExp
+ */
+export function escapeRegExp(text: string): string {
+  return text.replace(/[.*+?^${}()|[\]\\]/g, '\\$&');
+}
+
+export interface TopLevelPart {
+  /** The part as written, surrounding whitespace included */
+  text: string;
+  /** Offset of the part in the list */
+  start: number;
+}
+
+/**
+ * Split a parameter, argument or element list at the commas outside brackets and
+ * string literals. `scanned` is the same text with literals or comments masked,
+ * when the caller has it; the parts are still taken from `text`.
+ */
+export function topLevelParts(text: string, scanned = text): TopLevelPart[] {
+  const parts: TopLevelPart[] = [];
+  let depth = 0;
+  let start = 0;
+  let quote: string | null = null;
+  for (let i = 0; i < scanned.length; i++) {
+    const ch = scanned[i];
+    if (quote) {
+      if (ch === '\\' && quote !== '`') i++;
+      else if (ch === quote) quote = null;
+      continue;
+    }
+    if (ch === '"' || ch === '`' || ch === "'") quote = ch;
+    else if ('([{'.includes(ch)) depth++;
+    else if (')]}'.includes(ch)) depth--;
+    else if (ch === ',' && depth === 0) {
+      parts.push({ text: text.slice(start, i), start });
+      start = i + 1;
+    }
+  }
+  parts.push({ text: text.slice(start), start });
+  return parts;
+}
+
+/**
+ * The parts of a list split at top-level commas (see topLevelParts), as written
+ */
+export function splitTopLevel(text: string, scanned = text): string[] {
+  return topLevelParts(text, scanned).map(part => part.text);
+}
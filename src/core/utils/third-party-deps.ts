@@ -0,0 +1,135 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { DomainBoundary, ThirdPartyDependency } from '../types/config.js';
+import { goImports } from './go-load-check.js';
+import { detectGoProject } from './go-project-utils.js';
+import { loadGoWorkspace } from './go-workspace.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** A dependency imported directly by this many boundaries is flagged as shared */
+export const DEFAULT_SHARED_MODULES = 3;
+
+/**
+ * Module paths of the require directives of a go.mod (single-line and block form)
+ */
+export function parseGoModRequires(content: string): string[] {
+  const requires: string[] = [];
+  let inBlock = false;
+  for (const raw of content.split('\n')) {
+    const line = raw.replace(/\/\/.*$/, '').trim();
+    if (/^require\s*\($/.test(line)) {
+      inBlock = true;
+    } else if (inBlock && line.startsWith(')')) {
+      inBlock = false;
+    } else {
+      const match = inBlock ? line.match(/^"?([^"\s]+)"?\s+\S+/) : line.match(/^require\s+"?([^"\s]+)"?\s+\S+/);
+      if (match) requires.push(match[1]);
+    }
+  }
+  return requires;
+}
+
+/**
+ * What a third-party module belongs to: host, owner and repository without a
+ * major version suffix, so github.com/aws/aws-sdk-go-v2/service/s3 and
+ * .../config are one SDK and github.com/stripe/stripe-go/v76 is stripe-go
+ */
+export function dependencyFamily(modulePath: string): string {
+  const parts = modulePath.split('/').slice(0, 3);
+  if (parts.length > 2 && /^v\d+$/.test(parts[parts.length - 1])) parts.pop();
+  return parts.join('/');
+}
+
+/**
+ * Third-party packages (neither standard library nor the project's own modules)
+ * imported by the files of each boundary, grouped by dependency family. A family
+ * imported directly by sharedModules or more boundaries is `shared`: SDK sprawl
+ * the refactoring should contain behind an adapter.
+ */
+export function findThirdPartyDependencies(
+  projectRoot: string,
+  boundaries: { name: string; files: string[] }[],
+  options: { sharedModules?: number } = {}
+): ThirdPartyDependency[] {
+  const { own, required } = goModules(projectRoot);
+  const sharedModules = options.sharedModules ?? DEFAULT_SHARED_MODULES;
+  const isOwn = (importPath: string) => own.some(module => importPath === module || importPath.startsWith(`${module}/`));
+  const moduleOf = (importPath: string) =>
+    required.find(module => importPath === module || importPath.startsWith(`${module}/`)) ?? dependencyFamily(importPath);
+
+  const families = new Map<string, { modules: Set<string>; packages: Set<string>; usedBy: Map<string, Set<string>> }>();
+  for (const boundary of boundaries) {
+    for (const file of boundary.files.map(toPosixPath).filter(f => f.endsWith('.go') && !f.endsWith('_test.go'))) {
+      let content: string;
+      try {
+        content = fs.readFileSync(path.join(projectRoot, file), 'utf8');
+      } catch {
+        continue;
+      }
+      for (const { path: importPath } of goImports(content)) {
+        // The standard library has no dot in its first path element
+        if (!importPath.split('/')[0].includes('.') || isOwn(importPath)) continue;
+        const module = moduleOf(importPath);
+        const name = dependencyFamily(module);
+        const family = families.get(name) ?? { modules: new Set(), packages: new Set(), usedBy: new Map() };
+        family.modules.add(module);
+        family.packages.add(importPath);
+        family.usedBy.set(boundary.name, (family.usedBy.get(boundary.name) ?? new Set()).add(file));
+        families.set(name, family);
+      }
+    }
+  }
+
+  return [...families.entries()]
+    .map(([name, family]) => ({
+      name,
+      modules: [...family.modules].sort(),
+      packages: [...family.packages].sort(),
+      used_by: [...family.usedBy.entries()]
+        .map(([module, files]) => ({ module, files: [...files].sort() }))
+        .sort((a, b) => a.module.localeCompare(b.module)),
+      shared: family.usedBy.size >= sharedModules,
+    }))
+    .sort((a, b) => b.used_by.length - a.used_by.length || a.name.localeCompare(b.name));
+}
+
+/**
+ * Record the dependency families each boundary imports as its external dependencies
+ */
+export function attachThirdPartyDependencies(boundaries: DomainBoundary[], dependencies: ThirdPartyDependency[]): DomainBoundary[] {
+  return boundaries.map(boundary => {
+    const external = dependencies
+      .filter(dependency => dependency.used_by.some(usage => usage.module === boundary.name))
+      .map(dependency => dependency.name);
+    if (external.length === 0) return boundary;
+    return { ...boundary, dependencies: { ...boundary.dependencies, external } };
+  });
+}
+
+/**
+ * Module paths of the project (every go.work member, or its go.mod) and the
+ * modules they require, longest first so nested modules match before their parents
+ */
+function goModules(projectRoot: string): { own: string[]; required: string[] } {
+  const workspace = loadGoWorkspace(projectRoot);
+  const goProject = detectGoProject(projectRoot);
+  const goMods = workspace
+    ? workspace.modules.map(module => path.join(projectRoot, module.go_mod))
+    : goProject.goModulePath ? [goProject.goModulePath] : [];
+  const own = workspace
+    ? workspace.modules.flatMap(module => (module.module_path ? [module.module_path] : []))
+    : goProject.moduleName ? [goProject.moduleName] : [];
+
+  const required = new Set<string>();
+  for (const goMod of goMods) {
+    try {
+      parseGoModRequires(fs.readFileSync(goMod, 'utf8')).forEach(module => required.add(module));
+    } catch {
+      continue;
+    }
+  }
+  return {
+    own,
+    required: [...required].filter(module => !own.includes(module)).sort((a, b) => b.length - a.length),
+  };
+}
@@ -0,0 +1,264 @@
+import { execFileSync } from 'child_process';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { GoFunction, GoInterface, GoStruct } from './ast-analyzer.js';
+import { GoPackage } from './go-packages.js';
+import { detectGoProject } from './go-project-utils.js';
+import { loadGoWorkspace } from './go-workspace.js';
+import { toPosixPath } from './workspace-paths.js';
+
+/** Share of the clustering edge weight a type adds to the type it embeds or the interface it satisfies */
+export const TYPE_RELATION_WEIGHT = 0.6;
+
+/**
+ * Type-checks the given packages (go/types with the source importer, standard
+ * library only so it builds without a module cache) and prints the relations
+ */
+const TYPE_RELATIONS_PROGRAM = `// Embedded struct/interface types and interface satisfaction between the named
+// types of the packages given as import paths, one relation per line:
+// kind, file and name of the type, file and name of the embedded or implemented type
+package main
+
+import (
+	"fmt"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"os"
+)
+
+func main() {
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil).(types.ImporterFrom)
+	dir, _ := os.Getwd()
+
+	var named []*types.TypeName
+	for _, path := range os.Args[1:] {
+		pkg, err := imp.ImportFrom(path, dir, 0)
+		if err != nil || pkg == nil {
+			continue
+		}
+		for _, name := range pkg.Scope().Names() {
+			obj, ok := pkg.Scope().Lookup(name).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+			if t, ok := obj.Type().(*types.Named); ok && t.TypeParams().Len() > 0 {
+				continue
+			}
+			named = append(named, obj)
+		}
+	}
+
+	file := func(obj types.Object) string { return fset.Position(obj.Pos()).Filename }
+	emit := func(kind string, from, to *types.TypeName) {
+		fmt.Printf("%s\\t%s\\t%s\\t%s\\t%s\\n", kind, file(from), from.Name(), file(to), to.Name())
+	}
+
+	for _, obj := range named {
+		switch underlying := obj.Type().Underlying().(type) {
+		case *types.Struct:
+			for i := 0; i < underlying.NumFields(); i++ {
+				if field := underlying.Field(i); field.Embedded() {
+					if target := namedOf(field.Type()); target != nil {
+						emit("embeds", obj, target)
+					}
+				}
+			}
+		case *types.Interface:
+			for i := 0; i < underlying.NumEmbeddeds(); i++ {
+				if target := namedOf(underlying.EmbeddedType(i)); target != nil {
+					emit("embeds", obj, target)
+				}
+			}
+		}
+	}
+
+	for _, obj := range named {
+		if types.IsInterface(obj.Type()) {
+			continue
+		}
+		for _, other := range named {
+			iface, ok := other.Type().Underlying().(*types.Interface)
+			if !ok || trivial(iface) {
+				continue
+			}
+			if types.Implements(obj.Type(), iface) || types.Implements(types.NewPointer(obj.Type()), iface) {
+				emit("implements", obj, other)
+			}
+		}
+	}
+}
+
+// Named type of an embedded field (T, *T, pkg.T)
+func namedOf(t types.Type) *types.TypeName {
+	t = types.Unalias(t)
+	if pointer, ok := t.(*types.Pointer); ok {
+		t = types.Unalias(pointer.Elem())
+	}
+	if named, ok := t.(*types.Named); ok {
+		return named.Obj()
+	}
+	return nil
+}
+
+// Interfaces every other type satisfies (empty, String() or Error() only) say nothing about coupling
+func trivial(iface *types.Interface) bool {
+	for i := 0; i < iface.NumMethods(); i++ {
+		if name := iface.Method(i).Name(); name != "String" && name != "Error" {
+			return false
+		}
+	}
+	return true
+}
+`;
+
+/** Interfaces whose only methods are these are satisfied by almost anything */
+const TRIVIAL_METHODS = new Set(['String', 'Error']);
+
+export interface TypeRelation {
+  /** embeds: a struct embeds a type or an interface embeds an interface; implements: a type satisfies an interface */
+  kind: 'embeds' | 'implements';
+  from_file: string;
+  from_type: string;
+  /** The embedded or implemented type */
+  to_file: string;
+  to_type: string;
+}
+
+export interface TypeRelations {
+  /** syntactic: embedded names and method names matched from the parsed sources (Go toolchain unavailable or sampling) */
+  source: 'go/types' | 'syntactic';
+  relations: TypeRelation[];
+}
+
+/**
+ * Embedding and interface satisfaction between the project's named types, from
+ * go/types. null when the Go toolchain is not available.
+ */
+export function buildTypeRelations(projectRoot: string, packages: GoPackage[]): TypeRelations | null {
+  const goProject = detectGoProject(projectRoot);
+  if (!goProject.hasGoProject || packages.length === 0) return null;
+
+  const workspace = loadGoWorkspace(projectRoot);
+  const dir = fs.mkdtempSync(path.join(os.tmpdir(), 'vibeflow-type-relations-'));
+  try {
+    fs.writeFileSync(path.join(dir, 'go.mod'), 'module vibeflow-type-relations\n\ngo 1.22\n');
+    fs.writeFileSync(path.join(dir, 'main.go'), TYPE_RELATIONS_PROGRAM);
+    const binary = path.join(dir, 'type-relations');
+    execFileSync('go', ['build', '-o', binary, '.'], {
+      cwd: dir,
+      env: { ...process.env, GOFLAGS: '', GOWORK: 'off' },
+      stdio: 'ignore',
+      timeout: 120000,
+    });
+    const output = execFileSync(binary, packages.map(pkg => pkg.import_path), {
+      cwd: workspace ? projectRoot : goProject.workingDirectory!,
+      encoding: 'utf8',
+      stdio: ['ignore', 'pipe', 'ignore'],
+      maxBuffer: 256 * 1024 * 1024,
+      timeout: 600000,
+    });
+    return { source: 'go/types', relations: parseTypeRelations(output, projectRoot) };
+  } catch {
+    return null;
+  } finally {
+    fs.rmSync(dir, { recursive: true, force: true });
+  }
+}
+
+/**
+ * Relations of the program output, limited to types declared in non-test files of the project
+ */
+export function parseTypeRelations(output: string, projectRoot: string): TypeRelation[] {
+  const relations = new Map<string, TypeRelation>();
+  for (const line of output.split('\n')) {
+    const [kind, fromPath, fromType, toPath, toType] = line.split('\t');
+    if ((kind !== 'embeds' && kind !== 'implements') || !toType) continue;
+    const fromFile = projectFile(projectRoot, fromPath);
+    const toFile = projectFile(projectRoot, toPath);
+    if (!fromFile || !toFile) continue;
+    addRelation(relations, { kind, from_file: fromFile, from_type: fromType, to_file: toFile, to_type: toType });
+  }
+  return sortRelations(relations);
+}
+
+/**
+ * Relations from the parsed declarations: embedded names resolve to a type of the
+ * same directory, else to the only type of that name; a struct satisfies an
+ * interface when its methods include every method name of the interface.
+ * Used when go/types is not available.
+ */
+export function syntacticTypeRelations(structs: GoStruct[], interfaces: GoInterface[], functions: GoFunction[]): TypeRelations {
+  const types = [...structs, ...interfaces];
+  const resolve = (name: string, file: string) => {
+    const candidates = types.filter(type => type.name === name);
+    return candidates.find(c => path.dirname(c.file) === path.dirname(file)) ?? (candidates.length === 1 ? candidates[0] : undefined);
+  };
+
+  const relations = new Map<string, TypeRelation>();
+  const add = (kind: TypeRelation['kind'], from: GoStruct | GoInterface, to: GoStruct | GoInterface | undefined) => {
+    if (!to || to === from) return;
+    addRelation(relations, { kind, from_file: toPosixPath(from.file), from_type: from.name, to_file: toPosixPath(to.file), to_type: to.name });
+  };
+  for (const struct of structs) struct.embeds.forEach(name => add('embeds', struct, resolve(name, struct.file)));
+  for (const iface of interfaces) iface.extends.forEach(name => add('embeds', iface, resolve(name, iface.file)));
+
+  // Methods of each type, keyed by directory and receiver type
+  const methods = new Map<string, Set<string>>();
+  for (const fn of functions) {
+    const type = fn.receiver?.split(/\s+/).pop()?.replace(/^\*/, '').replace(/\[.*$/, '');
+    if (!type) continue;
+    const key = `${path.dirname(fn.file)}\n${type}`;
+    methods.set(key, new Set([...(methods.get(key) ?? []), fn.name]));
+  }
+  for (const iface of interfaces) {
+    const required = iface.methods.map(method => method.name);
+    if (required.every(name => TRIVIAL_METHODS.has(name))) continue;
+    for (const struct of structs) {
+      const own = methods.get(`${path.dirname(struct.file)}\n${struct.name}`);
+      if (own && required.every(name => own.has(name))) add('implements', struct, iface);
+    }
+  }
+
+  return { source: 'syntactic', relations: sortRelations(relations) };
+}
+
+/**
+ * Type relations between clustering nodes (structs and interfaces by name)
+ */
+export class TypeRelationIndex {
+  private related = new Set<string>();
+
+  constructor(relations: TypeRelations) {
+    for (const relation of relations.relations) {
+      const from = `${relation.from_file}\n${relation.from_type}`;
+      const to = `${relation.to_file}\n${relation.to_type}`;
+      this.related.add(`${from}\t${to}`);
+      this.related.add(`${to}\t${from}`);
+    }
+  }
+
+  /** Whether either node embeds or implements the other */
+  relates(node1: { file: string; name: string }, node2: { file: string; name: string }): boolean {
+    return this.related.has(`${toPosixPath(node1.file)}\n${node1.name}\t${toPosixPath(node2.file)}\n${node2.name}`);
+  }
+}
+
+function projectFile(projectRoot: string, file: string | undefined): string | null {
+  if (!file || !file.endsWith('.go') || file.endsWith('_test.go')) return null;
+  const relative = toPosixPath(path.relative(projectRoot, path.resolve(projectRoot, file)));
+  if (relative.startsWith('../') || path.isAbsolute(relative) || relative.split('/').includes('vendor')) return null;
+  return relative;
+}
+
+function addRelation(relations: Map<string, TypeRelation>, relation: TypeRelation): void {
+  relations.set([relation.kind, relation.from_file, relation.from_type, relation.to_file, relation.to_type].join('\n'), relation);
+}
+
+function sortRelations(relations: Map<string, TypeRelation>): TypeRelation[] {
+  return [...relations.values()].sort((a, b) =>
+    a.from_file.localeCompare(b.from_file) || a.from_type.localeCompare(b.from_type) ||
+    a.to_file.localeCompare(b.to_file) || a.to_type.localeCompare(b.to_type) || a.kind.localeCompare(b.kind));
+}
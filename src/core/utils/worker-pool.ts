@@ -0,0 +1,109 @@
+import { Worker, parentPort } from 'worker_threads';
+import { getErrorMessage } from './error-utils.js';
+
+type WorkerReply<O> = { result: O } | { error: string };
+
+interface QueuedTask<I, O> {
+  input: I;
+  resolve: (output: O) => void;
+  reject: (error: Error) => void;
+}
+
+/**
+ * A fixed number of worker threads running one script (see serveWorker); tasks
+ * wait in order until a worker is free. A worker that crashes fails its task
+ * and is replaced; when no worker is left, queued tasks fail.
+ */
+export class WorkerPool<I, O> {
+  private idle: Worker[] = [];
+  private busy = new Map<Worker, QueuedTask<I, O>>();
+  private queue: QueuedTask<I, O>[] = [];
+  private crashed = new WeakSet<Worker>();
+  private closed = false;
+
+  constructor(private readonly script: string | URL, size: number, private readonly workerData?: unknown) {
+    for (let i = 0; i < Math.max(1, size); i++) this.idle.push(this.spawn());
+  }
+
+  run(input: I): Promise<O> {
+    if (this.closed) return Promise.reject(new Error('worker pool is closed'));
+    return new Promise((resolve, reject) => {
+      this.queue.push({ input, resolve, reject });
+      this.dispatch();
+    });
+  }
+
+  /**
+   * Fail the running and queued tasks, then stop the workers
+   */
+  async close(): Promise<void> {
+    this.closed = true;
+    const closed = new Error('worker pool is closed');
+    const tasks = [...this.busy.values(), ...this.queue.splice(0)];
+    const workers = [...this.idle, ...this.busy.keys()];
+    this.idle = [];
+    this.busy.clear();
+    tasks.forEach(task => task.reject(closed));
+    await Promise.all(workers.map(worker => worker.terminate()));
+  }
+
+  private spawn(): Worker {
+    const worker = new Worker(this.script, { workerData: this.workerData });
+    worker.on('message', (reply: WorkerReply<O>) => {
+      const task = this.busy.get(worker);
+      if (!task) return;
+      this.busy.delete(worker);
+      if ('error' in reply) task.reject(new Error(reply.error));
+      else task.resolve(reply.result);
+      this.idle.push(worker);
+      this.dispatch();
+    });
+    worker.on('error', error => this.replace(worker, error));
+    worker.on('exit', code => {
+      if (!this.closed && code !== 0) this.replace(worker, new Error(`worker exited with code ${code}`));
+    });
+    return worker;
+  }
+
+  private replace(worker: Worker, error: Error): void {
+    // 'error' is followed by 'exit'
+    if (this.crashed.has(worker)) return;
+    this.crashed.add(worker);
+    const task = this.busy.get(worker);
+    this.busy.delete(worker);
+    this.idle = this.idle.filter(w => w !== worker);
+    task?.reject(error);
+    if (this.closed) return;
+    // A worker that fails before taking a task (e.g. the script does not load) is not respawned
+    if (task) this.idle.push(this.spawn());
+    if (this.idle.length + this.busy.size === 0) {
+      this.queue.splice(0).forEach(queued => queued.reject(error));
+      return;
+    }
+    this.dispatch();
+  }
+
+  private dispatch(): void {
+    while (this.idle.length > 0 && this.queue.length > 0) {
+      const worker = this.idle.shift()!;
+      const task = this.queue.shift()!;
+      this.busy.set(worker, task);
+      worker.postMessage(task.input);
+    }
+  }
+}
+
+/**
+ * Worker side of WorkerPool: answer every task posted by the pool with the handler's result
+ */
+export function serveWorker<I, O>(handler: (input: I) => O): void {
+  if (!parentPort) throw new Error('serveWorker must run in a worker thread');
+  const port = parentPort;
+  port.on('message', (input: I) => {
+    try {
+      port.postMessage({ result: handler(input) } satisfies WorkerReply<O>);
+    } catch (error) {
+      port.postMessage({ error: getErrorMessage(error) } satisfies WorkerReply<O>);
+    }
+  });
+}
@@ -0,0 +1,203 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import { VibeFlowPaths } from './file-paths.js';
+import { WorkspaceLock } from './workspace-lock.js';
+import { toPosixPath } from './workspace-paths.js';
+
+export type CleanScope = 'cache' | 'backups' | 'previews' | 'reports' | 'metrics' | 'plans';
+
+/** Scopes included by --all; metrics and plans always need their own flag */
+export const DEFAULT_CLEAN_SCOPES: CleanScope[] = ['cache', 'backups', 'previews', 'reports'];
+
+/** Backups kept by `vf clean --backups` unless backups.keepLast is configured */
+export const DEFAULT_BACKUP_KEEP_LAST = 3;
+
+/**
+ * .vibeflow entries per scope (relative to .vibeflow)
+ */
+const SCOPE_ENTRIES: Record<Exclude<CleanScope, 'backups'>, string[]> = {
+  cache: ['analysis-cache', 'metadata-cache', 'llm-cache', 'embeddings-cache.json'],
+  previews: ['patches'],
+  reports: [
+    'reports',
+    'results',
+    'quality-report.json',
+    'refinement-report.json',
+    'auto-boundary-discovery-report.json',
+    'discovery-gates.json',
+    'analysis.db',
+    'analysis.sql',
+  ],
+  metrics: ['performance.json', 'performance.db', 'metrics.json', 'usage-history.json', 'run-artifacts', 'failed-responses', 'caller-migration.json'],
+  plans: ['domain-map.json', 'plan.md', 'plan.json', 'drift-baseline.json'],
+};
+
+export interface CleanTarget {
+  scope: CleanScope;
+  /** Relative to the project root, forward slashes */
+  path: string;
+  bytes: number;
+}
+
+export interface CleanPlan {
+  targets: CleanTarget[];
+  totalBytes: number;
+  /** Backups retained by keep-last */
+  keptBackups: string[];
+}
+
+export interface CleanOptions {
+  scopes: CleanScope[];
+  keepLastBackups?: number;
+}
+
+/**
+ * WorkspaceCleaner - .vibeflow成果物のスコープ指定削除
+ *
+ * Only paths inside .vibeflow are ever removed. Plans (domain map, plan files)
+ * and metrics are removed only when their scope is requested explicitly;
+ * boundary.yaml and other project files are never touched.
+ */
+export class WorkspaceCleaner {
+  private projectRoot: string;
+  private paths: VibeFlowPaths;
+
+  constructor(projectRoot: string) {
+    this.projectRoot = projectRoot;
+    this.paths = new VibeFlowPaths(projectRoot);
+  }
+
+  plan(options: CleanOptions): CleanPlan {
+    const outputRoot = this.paths.outputRootPath;
+    const targets: CleanTarget[] = [];
+    let keptBackups: string[] = [];
+
+    for (const scope of unique(options.scopes)) {
+      if (scope === 'backups') {
+        const backups = this.listBackups();
+        const keep = options.keepLastBackups ?? DEFAULT_BACKUP_KEEP_LAST;
+        keptBackups = backups.slice(0, keep).map(dir => this.relative(dir));
+        targets.push(...backups.slice(keep).map(dir => this.target(scope, dir)));
+        continue;
+      }
+
+      for (const entry of SCOPE_ENTRIES[scope]) {
+        const fullPath = path.join(outputRoot, entry);
+        if (fs.existsSync(fullPath) && !isEmptyDirectory(fullPath)) {
+          targets.push(this.target(scope, fullPath));
+        }
+      }
+    }
+
+    return {
+      targets,
+      totalBytes: targets.reduce((sum, t) => sum + t.bytes, 0),
+      keptBackups,
+    };
+  }
+
+  /**
+   * Delete the planned paths and append an audit entry to the vibeflow log
+   *
+   * @throws when a workspace lock is held by another live process
+   */
+  execute(plan: CleanPlan): number {
+    const holder = this.findActiveLock();
+    if (holder) {
+      throw new Error(`Workspace is locked by ${holder} - finish or stop it before cleaning`);
+    }
+
+    let reclaimed = 0;
+    for (const target of plan.targets) {
+      const fullPath = path.join(this.projectRoot, ...target.path.split('/'));
+      if (!this.isInsideOutputRoot(fullPath)) continue;
+      fs.rmSync(fullPath, { recursive: true, force: true });
+      reclaimed += target.bytes;
+    }
+
+    this.appendAuditLog(plan, reclaimed);
+    return reclaimed;
+  }
+
+  /**
+   * Description of the live process holding a .vibeflow lock, if any
+   */
+  findActiveLock(): string | null {
+    const outputRoot = this.paths.outputRootPath;
+    if (!fs.existsSync(outputRoot)) return null;
+    const lockFiles = fs.readdirSync(outputRoot).filter(name => name.endsWith('.lock'));
+
+    for (const lockName of lockFiles) {
+      const lock = new WorkspaceLock(this.projectRoot, lockName);
+      if (lock.isHeldByOtherProcess()) {
+        const holder = lock.readHolder();
+        return `${holder?.command ?? 'unknown command'} (pid ${holder?.pid}, ${lockName})`;
+      }
+    }
+    return null;
+  }
+
+  /**
+   * Backup directories, newest first
+   */
+  private listBackups(): string[] {
+    const backupsDir = path.join(this.paths.outputRootPath, 'backups');
+    if (!fs.existsSync(backupsDir)) return [];
+
+    return fs.readdirSync(backupsDir, { withFileTypes: true })
+      .filter(entry => entry.isDirectory())
+      .map(entry => entry.name)
+      .sort()
+      .reverse()
+      .map(name => path.join(backupsDir, name));
+  }
+
+  private target(scope: CleanScope, fullPath: string): CleanTarget {
+    return { scope, path: this.relative(fullPath), bytes: directorySize(fullPath) };
+  }
+
+  private relative(fullPath: string): string {
+    return toPosixPath(path.relative(this.projectRoot, fullPath));
+  }
+
+  private isInsideOutputRoot(fullPath: string): boolean {
+    const relative = path.relative(this.paths.outputRootPath, fullPath);
+    return relative !== '' && !relative.startsWith('..') && !path.isAbsolute(relative);
+  }
+
+  private appendAuditLog(plan: CleanPlan, reclaimed: number): void {
+    const entry = {
+      timestamp: new Date().toISOString(),
+      event: 'clean',
+      scopes: unique(plan.targets.map(t => t.scope)),
+      removed: plan.targets.map(t => ({ path: t.path, bytes: t.bytes })),
+      kept_backups: plan.keptBackups,
+      bytes_reclaimed: reclaimed,
+    };
+
+    fs.mkdirSync(path.dirname(this.paths.logPath), { recursive: true });
+    fs.appendFileSync(this.paths.logPath, JSON.stringify(entry) + '\n');
+  }
+}
+
+export function formatBytes(bytes: number): string {
+  if (bytes < 1024) return `${bytes} B`;
+  if (bytes < 1024 * 1024) return `${(bytes / 1024).toFixed(1)} KB`;
+  return `${(bytes / 1024 / 1024).toFixed(1)} MB`;
+}
+
+function directorySize(fullPath: string): number {
+  const stat = fs.lstatSync(fullPath);
+  if (!stat.isDirectory()) return stat.size;
+
+  return fs.readdirSync(fullPath)
+    .reduce((sum, name) => sum + directorySize(path.join(fullPath, name)), 0);
+}
+
+function isEmptyDirectory(fullPath: string): boolean {
+  return fs.statSync(fullPath).isDirectory() && fs.readdirSync(fullPath).length === 0;
+}
+
+function unique<T>(values: T[]): T[] {
+  return [...new Set(values)];
+}
@@ -0,0 +1,180 @@
+import { randomUUID } from 'crypto';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { withSharingRetry, writeFileWithRetry } from './file-io.js';
+import { onShutdown } from './shutdown.js';
+
+export interface LockInfo {
+  pid: number;
+  command: string;
+  hostname: string;
+  acquired_at: string;
+  /** Identifies the WorkspaceLock instance holding it within its process */
+  token?: string;
+}
+
+/** Attempts to take over a stale lock before giving up */
+const ACQUIRE_ATTEMPTS = 3;
+/** How long an unreadable lock is taken for one still being written by its creator */
+const LOCK_WRITE_GRACE_MS = 5000;
+
+/**
+ * WorkspaceLock - .vibeflow配下のロックファイルによる排他制御
+ *
+ * The lock file records the owning pid so that stale locks left behind by a
+ * crashed process can be detected and taken over instead of blocking forever.
+ * It is created exclusively, so of two processes racing for it only one wins.
+ */
+export class WorkspaceLock {
+  private lockPath: string;
+  private token = randomUUID();
+  private held = false;
+  private stopShutdownWatch: () => void = () => {};
+
+  constructor(projectRoot: string, lockName: string = 'vibeflow.lock') {
+    this.lockPath = path.join(projectRoot, '.vibeflow', lockName);
+  }
+
+  /**
+   * Acquire the lock. Returns false when another live process holds it.
+   */
+  acquire(command: string): boolean {
+    const info: LockInfo = {
+      pid: process.pid,
+      command,
+      hostname: os.hostname(),
+      acquired_at: new Date().toISOString(),
+      token: this.token,
+    };
+    fs.mkdirSync(path.dirname(this.lockPath), { recursive: true });
+
+    for (let attempt = 1; ; attempt++) {
+      if (this.createLockFile(info)) break;
+
+      const holder = this.readHolder();
+      if (holder?.token === this.token) {
+        // Taken again by the instance holding it
+        writeFileWithRetry(this.lockPath, JSON.stringify(info, null, 2));
+        break;
+      }
+      if (attempt >= ACQUIRE_ATTEMPTS || !this.isStale(holder)) return false;
+      this.removeStale(holder);
+    }
+
+    this.held = true;
+    // Released on Ctrl+C / Ctrl+Break too, so an interrupted run leaves no lock behind
+    this.stopShutdownWatch();
+    this.stopShutdownWatch = onShutdown(() => this.release());
+    return true;
+  }
+
+  /**
+   * Release the lock if this process owns it
+   */
+  release(): void {
+    if (!this.held) return;
+
+    const holder = this.readHolder();
+    if (holder && holder.pid === process.pid && (holder.token ?? this.token) === this.token) {
+      try {
+        withSharingRetry(() => fs.unlinkSync(this.lockPath));
+      } catch {
+        // Already removed
+      }
+    }
+    this.held = false;
+    this.stopShutdownWatch();
+  }
+
+  /**
+   * Read current lock holder (null if unlocked or unreadable)
+   */
+  readHolder(): LockInfo | null {
+    try {
+      const content = fs.readFileSync(this.lockPath, 'utf8');
+      const parsed = JSON.parse(content);
+      return typeof parsed?.pid === 'number' ? parsed as LockInfo : null;
+    } catch {
+      return null;
+    }
+  }
+
+  /**
+   * Whether a live process other than this one currently holds the lock
+   */
+  isHeldByOtherProcess(): boolean {
+    const holder = this.readHolder();
+    return !!holder && holder.pid !== process.pid && !this.isStale(holder);
+  }
+
+  get path(): string {
+    return this.lockPath;
+  }
+
+  /**
+   * Create the lock file only if it does not exist yet; false when it does
+   */
+  private createLockFile(info: LockInfo): boolean {
+    let fd: number;
+    try {
+      fd = withSharingRetry(() => fs.openSync(this.lockPath, 'wx'));
+    } catch (error) {
+      if ((error as NodeJS.ErrnoException).code === 'EEXIST') return false;
+      throw error;
+    }
+    try {
+      fs.writeSync(fd, JSON.stringify(info, null, 2));
+    } finally {
+      fs.closeSync(fd);
+    }
+    return true;
+  }
+
+  /**
+   * Whether the holder can be taken over: its process is gone. Holders on
+   * another host cannot be verified and are never stale; an unreadable lock is
+   * stale only once its creator has had time to write it.
+   */
+  private isStale(holder: LockInfo | null): boolean {
+    if (!holder) {
+      try {
+        return Date.now() - fs.statSync(this.lockPath).mtimeMs > LOCK_WRITE_GRACE_MS;
+      } catch {
+        return true; // Removed in the meantime
+      }
+    }
+    if (holder.hostname !== os.hostname()) return false;
+    if (holder.pid === process.pid) {
+      // Another instance of this process, unless left by an earlier process with the same pid
+      return Date.parse(holder.acquired_at) < Date.now() - process.uptime() * 1000;
+    }
+    return !isProcessAlive(holder.pid);
+  }
+
+  /**
+   * Remove a stale lock, unless another process replaced it meanwhile
+   */
+  private removeStale(stale: LockInfo | null): void {
+    const current = this.readHolder();
+    if (stale && (current?.pid !== stale.pid || current?.acquired_at !== stale.acquired_at)) return;
+    try {
+      withSharingRetry(() => fs.unlinkSync(this.lockPath));
+    } catch {
+      // Already removed
+    }
+  }
+}
+
+/**
+ * Check whether a pid refers to a running process
+ */
+export function isProcessAlive(pid: number): boolean {
+  try {
+    process.kill(pid, 0);
+    return true;
+  } catch (error) {
+    // EPERM means the process exists but belongs to another user
+    return (error as NodeJS.ErrnoException).code === 'EPERM';
+  }
+}
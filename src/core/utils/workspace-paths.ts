@@ -0,0 +1,325 @@
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { getErrorMessage } from './error-utils.js';
+
+/**
+ * Prefix for references into the Go module cache (GOMODCACHE), which lives
+ * outside the workspace but is reproducible on every machine
+ */
+export const MODULE_CACHE_PREFIX = 'gomod:';
+
+/** Bumped when the on-disk path format of artifacts changes */
+export const ARTIFACT_PATH_FORMAT = 2;
+
+/**
+ * Keys whose values (string, string[] or "a → b" chains) are file paths in persisted artifacts
+ */
+const PATH_KEYS = new Set([
+  'files',
+  'file',
+  'path',
+  'backup',
+  'backupPath',
+  'originalPath',
+  'internal',
+  'circular_dependencies',
+  'processedFiles',
+  'failedFiles',
+  'old_file',
+  'new_file',
+  'source',
+]);
+
+/**
+ * Artifact sections whose path keys hold URL paths instead (HTTP routes, frontend requests);
+ * the exclusion applies to everything nested under the section
+ */
+const URL_KEYS_BY_SECTION = new Map<string, ReadonlySet<string>>([
+  ['routes', new Set(['path'])],
+  ['http_routes', new Set(['path'])],
+  ['external_consumers', new Set(['path'])],
+]);
+
+const NO_KEYS: ReadonlySet<string> = new Set();
+
+/**
+ * A path cannot be stored in a shared artifact
+ */
+export class WorkspacePathError extends Error {
+  constructor(public readonly filePath: string, public readonly workspaceRoot: string) {
+    super(
+      `Path is outside the workspace: ${filePath} (workspace: ${workspaceRoot}). ` +
+      `Only workspace files and Go module cache references can be stored in .vibeflow artifacts.`
+    );
+    this.name = 'WorkspacePathError';
+  }
+}
+
+/**
+ * Forward slashes regardless of platform
+ */
+export function toPosixPath(filePath: string): string {
+  return filePath.replace(/\\/g, '/');
+}
+
+/**
+ * Absolute on any platform (POSIX root, Windows drive or UNC path)
+ */
+export function isAbsoluteAnyPlatform(filePath: string): boolean {
+  return path.posix.isAbsolute(filePath) || path.win32.isAbsolute(filePath);
+}
+
+/**
+ * GOMODCACHE, or the default GOPATH/pkg/mod
+ */
+export function getModuleCacheRoot(env: NodeJS.ProcessEnv = process.env): string {
+  if (env.GOMODCACHE) return env.GOMODCACHE;
+  const gopath = env.GOPATH?.split(path.delimiter).find(Boolean) ?? path.join(os.homedir(), 'go');
+  return path.join(gopath, 'pkg', 'mod');
+}
+
+/**
+ * Convert a path to the portable form stored in artifacts:
+ * workspace-relative with forward slashes, or `gomod:<path>` for the module cache.
+ * Symlinked workspaces are matched through their real path.
+ *
+ * @throws WorkspacePathError for any other location outside the workspace
+ */
+export function toWorkspacePath(workspaceRoot: string, filePath: string): string {
+  if (filePath.startsWith(MODULE_CACHE_PREFIX)) return filePath;
+  if (!isAbsoluteAnyPlatform(filePath)) {
+    const normalized = path.posix.normalize(toPosixPath(filePath));
+    if (normalized !== '..' && !normalized.startsWith('../')) return normalized;
+  }
+
+  const absolute = pathModule(workspaceRoot).resolve(workspaceRoot, filePath);
+  const relative = relativeInside(workspaceRoot, absolute);
+  if (relative !== null) return relative;
+
+  const cacheRelative = relativeInside(getModuleCacheRoot(), absolute);
+  if (cacheRelative !== null) return `${MODULE_CACHE_PREFIX}${cacheRelative}`;
+
+  throw new WorkspacePathError(filePath, workspaceRoot);
+}
+
+/**
+ * Resolve a stored artifact path against the current workspace root.
+ * Legacy absolute paths are returned unchanged.
+ */
+export function resolveWorkspacePath(workspaceRoot: string, storedPath: string): string {
+  if (storedPath.startsWith(MODULE_CACHE_PREFIX)) {
+    const cacheRoot = getModuleCacheRoot();
+    return pathModule(cacheRoot).join(cacheRoot, ...storedPath.slice(MODULE_CACHE_PREFIX.length).split('/'));
+  }
+  if (isAbsoluteAnyPlatform(storedPath)) return storedPath;
+  return pathModule(workspaceRoot).resolve(workspaceRoot, ...toPosixPath(storedPath).split('/'));
+}
+
+/**
+ * Rewrite every path-valued field of an artifact to its portable form before writing it.
+ * Strings under the workspace root are also relativized inside free-text fields.
+ */
+export function portableArtifact<T>(workspaceRoot: string, value: T): T {
+  return mapArtifactPaths(value, (filePath, isPathKey) => {
+    if (isPathKey) return toWorkspacePath(workspaceRoot, filePath);
+    return stripWorkspaceRoot(workspaceRoot, filePath);
+  }) as T;
+}
+
+/**
+ * Rewrite an artifact written before paths were portable.
+ * Absolute paths from another checkout are re-anchored on the longest path suffix
+ * that exists in this workspace; unresolvable ones are kept and reported.
+ */
+export function relocateLegacyArtifact<T>(workspaceRoot: string, value: T): { value: T; unresolved: string[] } {
+  const unresolved: string[] = [];
+
+  const relocated = mapArtifactPaths(value, (filePath, isPathKey) => {
+    if (!isPathKey) return stripWorkspaceRoot(workspaceRoot, filePath);
+
+    try {
+      return toWorkspacePath(workspaceRoot, filePath);
+    } catch (error) {
+      if (!(error instanceof WorkspacePathError)) throw error;
+      const anchored = anchorInWorkspace(workspaceRoot, filePath);
+      if (anchored !== null) return anchored;
+      unresolved.push(filePath);
+      return filePath;
+    }
+  }) as T;
+
+  return { value: relocated, unresolved };
+}
+
+export interface ArtifactMigrationResult {
+  migrated: string[];
+  unresolved: string[];
+}
+
+/**
+ * One-time migration of existing .vibeflow artifacts to workspace-relative paths.
+ * Runs on first load and records the path format in .vibeflow/workspace.json.
+ */
+export function migrateWorkspaceArtifacts(workspaceRoot: string): ArtifactMigrationResult {
+  const outputRoot = path.join(workspaceRoot, '.vibeflow');
+  const markerPath = path.join(outputRoot, 'workspace.json');
+  const result: ArtifactMigrationResult = { migrated: [], unresolved: [] };
+
+  if (!fs.existsSync(outputRoot) || readPathFormat(markerPath) >= ARTIFACT_PATH_FORMAT) {
+    return result;
+  }
+
+  const manifestsDir = path.join(outputRoot, 'manifests');
+  const artifacts = [
+    'domain-map.json',
+    'plan.json',
+    'auto-boundary-discovery-report.json',
+    'checkpoint.json',
+    path.join('reports', 'data-mapping.json'),
+    ...(fs.existsSync(manifestsDir)
+      ? fs.readdirSync(manifestsDir).filter(f => f.endsWith('.json')).map(f => path.join('manifests', f))
+      : []),
+  ];
+
+  for (const artifact of artifacts) {
+    const fullPath = path.join(outputRoot, artifact);
+    if (!fs.existsSync(fullPath)) continue;
+
+    try {
+      const original = fs.readFileSync(fullPath, 'utf8');
+      const data = JSON.parse(original);
+      const { value, unresolved } = relocateLegacyArtifact(workspaceRoot, data);
+      if (artifact === 'checkpoint.json' && value.projectPath !== undefined) {
+        value.projectPath = '.';
+      }
+
+      const rewritten = JSON.stringify(value, null, 2);
+      if (rewritten !== JSON.stringify(data, null, 2)) {
+        fs.writeFileSync(fullPath, rewritten);
+        result.migrated.push(toPosixPath(path.join('.vibeflow', artifact)));
+      }
+      result.unresolved.push(...unresolved);
+    } catch (error) {
+      console.warn(`⚠️  Could not migrate paths in ${artifact}: ${getErrorMessage(error)}`);
+    }
+  }
+
+  fs.writeFileSync(markerPath, JSON.stringify({ path_format: ARTIFACT_PATH_FORMAT }, null, 2));
+  if (result.migrated.length > 0) {
+    console.log(`🔁 Migrated ${result.migrated.length} artifacts to workspace-relative paths`);
+  }
+  if (result.unresolved.length > 0) {
+    console.warn(`⚠️  ${result.unresolved.length} paths outside this workspace were kept as-is: ${result.unresolved.slice(0, 3).join(', ')}`);
+  }
+
+  return result;
+}
+
+function readPathFormat(markerPath: string): number {
+  try {
+    return JSON.parse(fs.readFileSync(markerPath, 'utf8')).path_format ?? 0;
+  } catch {
+    return 0;
+  }
+}
+
+/**
+ * Relative path of `target` inside `root`, trying real paths for symlinked workspaces
+ */
+function relativeInside(root: string, target: string): string | null {
+  const candidates = new Set<string>();
+  if (pathModule(root) !== pathModule(target)) return null;
+  const realRoot = realpathOrSelf(root);
+  const realTarget = realpathOrSelf(target);
+  for (const [r, t] of [[root, target], [realRoot, realTarget], [realRoot, target], [root, realTarget]]) {
+    const relative = pathModule(r).relative(r, t);
+    candidates.add(relative);
+  }
+
+  for (const relative of candidates) {
+    if (relative === '') return '.';
+    const posix = toPosixPath(relative);
+    if (!posix.startsWith('../') && posix !== '..' && !isAbsoluteAnyPlatform(posix)) return posix;
+  }
+  return null;
+}
+
+/**
+ * Real path of the deepest existing ancestor, so not-yet-created files still resolve through symlinks
+ */
+function realpathOrSelf(filePath: string): string {
+  const pathApi = pathModule(filePath);
+  let existing = filePath;
+  const rest: string[] = [];
+  while (!fs.existsSync(existing)) {
+    const parent = pathApi.dirname(existing);
+    if (parent === existing) return filePath;
+    rest.unshift(pathApi.basename(existing));
+    existing = parent;
+  }
+  try {
+    return pathApi.join(fs.realpathSync(existing), ...rest);
+  } catch {
+    return filePath;
+  }
+}
+
+function pathModule(filePath: string): path.PlatformPath {
+  return path.win32.isAbsolute(filePath) && !path.posix.isAbsolute(filePath) ? path.win32 : path;
+}
+
+function anchorInWorkspace(workspaceRoot: string, filePath: string): string | null {
+  const segments = toPosixPath(filePath).split('/').filter(s => s && !/^[A-Za-z]:$/.test(s));
+  for (let i = 0; i < segments.length; i++) {
+    const suffix = segments.slice(i).join('/');
+    if (fs.existsSync(path.join(workspaceRoot, ...segments.slice(i)))) return suffix;
+  }
+  return null;
+}
+
+function stripWorkspaceRoot(workspaceRoot: string, text: string): string {
+  let result = text;
+  for (const root of new Set([workspaceRoot, realpathOrSelf(workspaceRoot)])) {
+    for (const variant of new Set([root, toPosixPath(root)])) {
+      result = result.split(variant + (variant.includes('\\') ? '\\' : '/')).join('');
+    }
+  }
+  return result;
+}
+
+function mapArtifactPaths(
+  value: unknown,
+  convert: (filePath: string, isPathKey: boolean) => string,
+  key = '',
+  urlKeys: ReadonlySet<string> = NO_KEYS
+): unknown {
+  const isPathKey = PATH_KEYS.has(key) && !urlKeys.has(key);
+
+  if (typeof value === 'string') {
+    if (!isPathKey || value === '') return convert(value, false);
+    // Dependency chains: "a.go → b.go → a.go"
+    if (value.includes('→')) {
+      return value.split(/\s*→\s*/).map(part => convert(part, true)).join(' → ');
+    }
+    return looksLikePath(value) ? convert(value, true) : convert(value, false);
+  }
+  if (Array.isArray(value)) {
+    return value.map(item => mapArtifactPaths(item, convert, key, urlKeys));
+  }
+  if (value && typeof value === 'object') {
+    return Object.fromEntries(Object.entries(value).map(([k, v]) => [
+      k,
+      mapArtifactPaths(v, convert, k, URL_KEYS_BY_SECTION.get(k) ?? urlKeys),
+    ]));
+  }
+  return value;
+}
+
+/**
+ * Path keys also hold module names ("internal" dependencies, "source" module);
+ * only values with a separator or file extension are treated as paths
+ */
+function looksLikePath(value: string): boolean {
+  return /[/\\]/.test(value) || /\.\w+$/.test(value);
+}
@@ -10,6 +10,7 @@ import {
   ComplexityAnalysis
 } from '../types/business-logic.js';
 import { getErrorMessage } from '../utils/error-utils.js';
+import { formatLocation } from '../utils/source-positions.js';
 
 /**
  * 業務ロジック保存検証器
@@ -510,14 +511,14 @@ export class BusinessLogicPreservationValidator {
       
       if (migrationTarget) {
         ruleMapping[rule.description] = {
-          original_location: `${rule.location.file}:${rule.location.line}`,
+          original_location: formatLocation(rule.location),
           migrated_to: migrationTarget,
           preservation_status: 'fully_preserved'
         };
         preservedCount++;
       } else {
         ruleMapping[rule.description] = {
-          original_location: `${rule.location.file}:${rule.location.line}`,
+          original_location: formatLocation(rule.location),
           migrated_to: 'NOT_FOUND',
           preservation_status: 'missing'
         };
@@ -88,7 +88,9 @@ export async function executeAutoRefactor(
     console.log(`   Mode: ${applyChanges ? '🔥 APPLY CHANGES' : '🔍 DRY RUN'}`);
     
     const refactorAgent = new RefactorAgent(absolutePath);
-    const boundaries = boundaryResult?.domainMap?.boundaries || boundaryResult?.autoDiscoveredBoundaries || [];
+    // Established modules (own go.mod or boundary.yaml established:) are out of scope
+    const boundaries = (boundaryResult?.domainMap?.boundaries || boundaryResult?.autoDiscoveredBoundaries || [])
+      .filter((boundary: { status?: string }) => boundary.status !== 'established');
     const refactorResult = await refactorAgent.executeRefactoring(
       boundaries, 
       applyChanges
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"example.com/store/internal/catalog"
+	"example.com/store/internal/order"
+)
+
+func main() {
+	c := catalog.NewCatalog()
+	if err := c.Add(catalog.Product{ID: "book", Price: 10}); err != nil {
+		panic(err)
+	}
+	var o *order.Order
+	o, _ = order.Place(c, "book")
+	fmt.Println(len(o.Items))
+}
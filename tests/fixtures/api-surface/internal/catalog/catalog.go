@@ -0,0 +1,80 @@
+package catalog
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Product is a catalog entry.
+type Product struct {
+	ID    string `json:"id"`
+	Price int    `json:"price"`
+}
+
+// Catalog holds the products on sale.
+type Catalog struct {
+	items map[string]Product
+}
+
+// DefaultLimit is the page size used by List.
+const DefaultLimit = 20
+
+// MaxItems bounds the catalog size.
+var MaxItems = 100
+
+// ErrFull is returned when the catalog holds MaxItems products.
+var ErrFull = errors.New("catalog is full")
+
+func NewCatalog() *Catalog {
+	return &Catalog{items: map[string]Product{}}
+}
+
+func (c *Catalog) Add(p Product) error {
+	if len(c.items) >= MaxItems {
+		return ErrFull
+	}
+	if err := Validate(p); err != nil {
+		return err
+	}
+	c.items[Normalize(p.ID)] = p
+	return nil
+}
+
+func (c *Catalog) Find(id string) (Product, bool) {
+	p, ok := c.items[Normalize(id)]
+	return p, ok
+}
+
+func (c *Catalog) List() []Product {
+	products := make([]Product, 0, DefaultLimit)
+	for _, p := range c.items {
+		products = append(products, p)
+	}
+	return products
+}
+
+// Normalize trims and lower-cases product IDs.
+func Normalize(id string) string {
+	return strings.ToLower(strings.TrimSpace(id))
+}
+
+// Validate rejects products without an ID.
+func Validate(p Product) error {
+	if Normalize(p.ID) == "" {
+		return errors.New("Validate: missing id")
+	}
+	return nil
+}
+
+// Handler is registered by name for the admin console.
+func Handler() string {
+	return "catalog"
+}
+
+// String describes the package for debugging.
+func String() string {
+	return "catalog"
+}
+
+var handlers = map[string]reflect.Value{"catalog": reflect.ValueOf(Handler)}
@@ -0,0 +1,17 @@
+package order
+
+import "example.com/store/internal/catalog"
+
+type Order struct {
+	Items []catalog.Product
+}
+
+func Place(c *catalog.Catalog, ids ...string) (*Order, error) {
+	order := &Order{}
+	for _, id := range ids {
+		if p, ok := c.Find(catalog.Normalize(id)); ok {
+			order.Items = append(order.Items, p)
+		}
+	}
+	return order, nil
+}
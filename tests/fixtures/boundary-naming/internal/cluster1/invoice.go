@@ -0,0 +1,26 @@
+// Package cluster1 issues invoices for completed orders.
+package cluster1
+
+import "time"
+
+// Invoice is the bill sent to a customer.
+type Invoice struct {
+	ID       string
+	OrderID  string
+	Amount   int64
+	IssuedAt time.Time
+}
+
+type InvoiceLine struct {
+	Description string
+	Amount      int64
+}
+
+// IssueInvoice creates the invoice of an order.
+func IssueInvoice(orderID string, lines []InvoiceLine) (*Invoice, error) {
+	var amount int64
+	for _, line := range lines {
+		amount += line.Amount
+	}
+	return &Invoice{OrderID: orderID, Amount: amount, IssuedAt: time.Now()}, nil
+}
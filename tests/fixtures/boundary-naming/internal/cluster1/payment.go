@@ -0,0 +1,7 @@
+package cluster1
+
+type PaymentMethod string
+
+func CapturePayment(invoice *Invoice, method PaymentMethod) error {
+	return nil
+}
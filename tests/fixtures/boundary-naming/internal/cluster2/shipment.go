@@ -0,0 +1,15 @@
+// Package cluster2 ships orders to customers.
+package cluster2
+
+type Shipment struct {
+	OrderID string
+	Carrier string
+}
+
+func ScheduleShipment(orderID, carrier string) *Shipment {
+	return &Shipment{OrderID: orderID, Carrier: carrier}
+}
+
+func TrackShipment(s *Shipment) string {
+	return s.Carrier
+}
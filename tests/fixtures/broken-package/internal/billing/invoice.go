@@ -0,0 +1,12 @@
+package billing
+
+import "example.com/shop/internal/user"
+
+type Invoice struct {
+	ID     string
+	Amount int
+}
+
+// CreateInvoice bills a user (work in progress: the body is not closed)
+func CreateInvoice(u *user.User, amount int) *Invoice {
+	return &Invoice{ID: u.ID, Amount: amount}
@@ -0,0 +1,12 @@
+package report
+
+import (
+	"fmt"
+
+	"example.com/shop/internal/gen/reportpb"
+)
+
+// Render formats a report generated by protoc (reportpb is not generated in this fixture)
+func Render(r *reportpb.Report) string {
+	return fmt.Sprintf("%s: %d", r.Title, r.Total)
+}
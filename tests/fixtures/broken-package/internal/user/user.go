@@ -0,0 +1,22 @@
+package user
+
+import "errors"
+
+// User is a registered customer
+type User struct {
+	ID    string
+	Email string
+}
+
+// UserRepository persists users
+type UserRepository interface {
+	Save(u *User) error
+}
+
+// CreateUser validates and creates a user
+func CreateUser(email string) (*User, error) {
+	if email == "" {
+		return nil, errors.New("email required")
+	}
+	return &User{ID: "u1", Email: email}, nil
+}
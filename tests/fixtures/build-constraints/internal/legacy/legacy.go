@@ -0,0 +1,6 @@
+//go:build legacy
+
+package legacy
+
+// Migrate is only built with -tags=legacy.
+func Migrate() {}
@@ -0,0 +1,6 @@
+package order
+
+// Order is placed by a customer.
+type Order struct {
+	ID string
+}
@@ -0,0 +1,5 @@
+//go:build plan9
+
+package order
+
+const pathSeparator = '\\'
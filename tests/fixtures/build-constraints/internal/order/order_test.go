@@ -0,0 +1,5 @@
+package order
+
+import "testing"
+
+func TestOrder(t *testing.T) {}
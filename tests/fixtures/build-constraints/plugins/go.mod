@@ -0,0 +1,3 @@
+module example.com/shop/plugins
+
+go 1.22
@@ -0,0 +1,4 @@
+package tools
+
+// Version of the code generators.
+const Version = "1"
@@ -0,0 +1,23 @@
+package config
+
+import "time"
+
+type Config struct {
+	Port     int
+	Database struct {
+		Host string
+		User string
+	}
+	Cache CacheConfig
+}
+
+type CacheConfig struct {
+	TTL time.Duration
+}
+
+var Cfg *Config
+
+func Load() {
+	Cfg = &Config{}
+	Cfg.Port = 8080
+}
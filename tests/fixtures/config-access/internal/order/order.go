@@ -0,0 +1,26 @@
+package order
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"example.com/shop/config"
+	"github.com/spf13/viper"
+)
+
+const regionKey = "AWS_REGION"
+
+var workers = flag.Int("workers", 4, "number of workers")
+
+// os.Getenv("IN_COMMENT") is not a read
+func Place(tenant string) string {
+	host := os.Getenv("DB_HOST")
+	region := os.Getenv(regionKey)
+	dyn := os.Getenv("TENANT_" + tenant)
+	ttl := config.Cfg.Cache.TTL
+	fmt.Println(config.Cfg.Database.Host, viper.GetInt("server.port"), ttl, dyn, region)
+	return host + "os.Getenv(\"X\")"
+}
+
+func Dump() { fmt.Println(config.Cfg) }
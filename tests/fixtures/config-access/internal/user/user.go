@@ -0,0 +1,13 @@
+package user
+
+import (
+	"os"
+	cfg "example.com/shop/config"
+)
+
+func Host() string {
+	if v, ok := os.LookupEnv("DB_HOST"); ok {
+		return v
+	}
+	return cfg.Cfg.Database.Host
+}
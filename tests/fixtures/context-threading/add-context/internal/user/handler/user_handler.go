@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"example.com/shop/internal/user/domain"
+)
+
+// UserHandler serves user HTTP requests
+type UserHandler struct {
+	useCase domain.UserUseCase
+}
+
+// NewUserHandler creates a user handler
+func NewUserHandler(useCase domain.UserUseCase) *UserHandler {
+	return &UserHandler{useCase: useCase}
+}
+
+// RegisterUser handles POST /users
+func (h *UserHandler) RegisterUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.useCase.RegisterUser(r.Context(), r.FormValue("name"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+// FindUser handles GET /users/{id}
+func (h *UserHandler) FindUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.useCase.FindUser(r.Context(), r.URL.Query().Get("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
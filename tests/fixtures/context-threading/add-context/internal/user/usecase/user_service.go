@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+
+	"example.com/shop/internal/user/domain"
+)
+
+// UserService implements domain.UserUseCase
+type UserService struct {
+	repo domain.UserRepository
+}
+
+// NewUserService creates a user service
+func NewUserService(repo domain.UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+// RegisterUser registers a new user (migrated from the legacy RegisterUser)
+func (s *UserService) RegisterUser(ctx context.Context, name string) (*domain.User, error) {
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	user := &domain.User{ID: name, Name: name}
+	if err := s.repo.Save(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// FindUser loads a user by id (migrated from the legacy FindUser)
+func (s *UserService) FindUser(ctx context.Context, id string) (*domain.User, error) {
+	return s.repo.FindByID(ctx, id)
+}
@@ -0,0 +1,25 @@
+package legacy
+
+import (
+	"context"
+
+	"example.com/shop/internal/user/domain"
+)
+
+// Users serves legacy callers until they are migrated to the user module
+var Users domain.UserUseCase
+
+// RegisterUser keeps the legacy entry point
+func RegisterUser(name string) error {
+	_, err := Users.RegisterUser(context.TODO(), name)
+	return err
+}
+
+// FindUser keeps the legacy entry point
+func FindUser(ctx context.Context, id string) (string, error) {
+	user, err := Users.FindUser(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return user.Name, nil
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "context"
+
+// User is a registered user
+type User struct {
+	ID   string
+	Name string
+}
+
+// UserRepository stores users
+type UserRepository interface {
+	Save(ctx context.Context, user *User) error
+	FindByID(ctx context.Context, id string) (*User, error)
+}
+
+// UserUseCase is the application service of the user module
+type UserUseCase interface {
+	RegisterUser(ctx context.Context, name string) (*User, error)
+	FindUser(ctx context.Context, id string) (*User, error)
+}
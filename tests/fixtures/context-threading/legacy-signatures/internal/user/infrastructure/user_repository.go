@@ -0,0 +1,42 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"example.com/shop/internal/user/domain"
+)
+
+// MemoryUserRepository keeps users in memory
+type MemoryUserRepository struct {
+	mu    sync.Mutex
+	users map[string]*domain.User
+}
+
+// NewMemoryUserRepository creates an empty repository
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[string]*domain.User)}
+}
+
+// Save stores a user
+func (r *MemoryUserRepository) Save(user *domain.User) error {
+	if err := context.TODO().Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[user.ID] = user
+	return nil
+}
+
+// FindByID loads a user
+func (r *MemoryUserRepository) FindByID(id string) (*domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return user, nil
+}
@@ -0,0 +1,25 @@
+package legacy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var db *sql.DB
+
+// RegisterUser stores a new user
+func RegisterUser(name string) error {
+	if name == "" {
+		return errors.New("name is required")
+	}
+	_, err := db.Exec("INSERT INTO users (id, name) VALUES ($1, $1)", name)
+	return err
+}
+
+// FindUser loads a user by id
+func FindUser(ctx context.Context, id string) (string, error) {
+	var name string
+	err := db.QueryRowContext(ctx, "SELECT name FROM users WHERE id = $1", id).Scan(&name)
+	return name, err
+}
@@ -0,0 +1,18 @@
+package billing
+
+import "time"
+
+type Invoice struct {
+	ID     string
+	Status string
+	Total  int64
+	PaidAt *time.Time
+}
+
+type Line struct {
+	Amount int64
+}
+
+func (i *Invoice) GetStatus() string {
+	return i.Status
+}
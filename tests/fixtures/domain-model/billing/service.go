@@ -0,0 +1,35 @@
+package billing
+
+import (
+	"errors"
+	"time"
+)
+
+type InvoiceService struct {
+	repo InvoiceRepository
+}
+
+type InvoiceRepository interface {
+	Save(invoice *Invoice) error
+}
+
+func (s *InvoiceService) AddLines(invoice *Invoice, lines []Line) error {
+	for _, line := range lines {
+		invoice.Total += line.Amount
+	}
+	return s.repo.Save(invoice)
+}
+
+func (s *InvoiceService) MarkPaid(invoice *Invoice) error {
+	if invoice.Status == "paid" {
+		return errors.New("already paid")
+	}
+	now := time.Now()
+	invoice.Status = "paid"
+	invoice.PaidAt = &now
+	return s.repo.Save(invoice)
+}
+
+func Void(invoice *Invoice) {
+	invoice.Status = "void"
+}
@@ -0,0 +1,34 @@
+package order
+
+import "errors"
+
+type Order struct {
+	id     string
+	status string
+	items  []Item
+}
+
+type Item struct {
+	SKU      string
+	Quantity int
+}
+
+func (o *Order) AddItem(item Item) error {
+	if o.status != "draft" {
+		return errors.New("order is not a draft")
+	}
+	o.items = append(o.items, item)
+	return nil
+}
+
+func (o *Order) Place() error {
+	if len(o.items) == 0 {
+		return errors.New("order has no items")
+	}
+	o.status = "placed"
+	return nil
+}
+
+func (o *Order) ID() string {
+	return o.id
+}
@@ -0,0 +1,16 @@
+package order
+
+type OrderService struct {
+	repo OrderRepository
+}
+
+type OrderRepository interface {
+	Save(order *Order) error
+}
+
+func (s *OrderService) PlaceOrder(order *Order) error {
+	if err := order.Place(); err != nil {
+		return err
+	}
+	return s.repo.Save(order)
+}
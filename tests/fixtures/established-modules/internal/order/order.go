@@ -0,0 +1,18 @@
+package order
+
+import (
+	"example.com/auth"
+	"example.com/auth/session"
+	"example.com/shop/modules/billing"
+)
+
+// Place charges the user of a session token
+func Place(token string, amount float64) (float64, error) {
+	u, err := auth.Verify(token)
+	if err != nil {
+		return 0, err
+	}
+	billing.Rates["EUR"] = 1.1
+	_ = session.New()
+	return billing.Charge(u, amount), nil
+}
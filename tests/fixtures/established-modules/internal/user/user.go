@@ -0,0 +1,9 @@
+package user
+
+import "example.com/auth"
+
+// Current returns the account behind a token
+func Current(token string) *auth.User {
+	u, _ := auth.Verify(token)
+	return u
+}
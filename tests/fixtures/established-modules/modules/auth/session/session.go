@@ -0,0 +1,6 @@
+package session
+
+// New returns a fresh session token
+func New() string {
+	return "session"
+}
@@ -0,0 +1,11 @@
+package billing
+
+import "example.com/auth"
+
+// Rates per currency, filled at startup
+var Rates = map[string]float64{}
+
+// Charge bills a verified user
+func Charge(u *auth.User, amount float64) float64 {
+	return amount * Rates[u.ID]
+}
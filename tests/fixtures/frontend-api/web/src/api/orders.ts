@@ -0,0 +1,23 @@
+import axios from 'axios';
+
+const api = axios.create({ baseURL: import.meta.env.VITE_API_URL });
+
+export async function listOrders() {
+  const { data } = await api.get('/api/v1/orders?status=open');
+  return data;
+}
+
+export async function getOrder(id: string) {
+  return (await api.get(`/api/v1/orders/${id}`)).data;
+}
+
+export async function cancelOrder(id: string) {
+  return axios({
+    method: 'post',
+    url: `${import.meta.env.VITE_API_URL}/api/v1/orders/${id}/cancel`,
+  });
+}
+
+export async function exportReport(name: string) {
+  return api.get(name);
+}
@@ -0,0 +1 @@
+it('pays', () => fetch('/api/v1/test-only'));
@@ -0,0 +1,10 @@
+export function Checkout({ orderId }: { orderId: string }) {
+  const pay = async () => {
+    await fetch(`/api/v1/payments`, {
+      method: 'POST',
+      body: JSON.stringify({ orderId }),
+    });
+  };
+  const health = () => fetch('https://shop.example.com/healthz');
+  return <button onClick={pay}>Pay</button>;
+}
@@ -0,0 +1,25 @@
+package main
+
+import "example.com/shop/internal/order"
+
+type Router struct {
+	routes map[string]func(string) error
+}
+
+func (r *Router) Handle(name string, fn func(string) error) {
+	r.routes[name] = fn
+}
+
+func main() {
+	svc := &order.Service{}
+	r := &Router{routes: map[string]func(string) error{}}
+	r.Handle("process", svc.Process)
+
+	process := (*order.Service).Process
+	_ = process(svc, "42")
+
+	check := order.Validate
+	if check("42") {
+		svc.Process("42")
+	}
+}
@@ -0,0 +1,16 @@
+package order
+
+// Handlers maps event names to the validators run before processing.
+var Handlers = map[string]func(string) bool{
+	"validate": Validate,
+}
+
+// OnCancel is swapped out in tests.
+var OnCancel = Audit
+
+func (s *Service) Route(event string) func(string) error {
+	if event == "cancel" {
+		return s.Cancel
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package order
+
+import "sync"
+
+type Service struct {
+	mu sync.Mutex
+}
+
+func (s *Service) Process(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return nil
+}
+
+func (s *Service) Cancel(id string) error {
+	return s.Process(id)
+}
+
+func Validate(id string) bool {
+	return id != ""
+}
+
+func Audit(id string) {}
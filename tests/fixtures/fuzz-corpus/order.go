@@ -0,0 +1,13 @@
+//go:build !legacy
+
+// Package legacy holds the pre-migration order handling.
+package legacy
+
+import "errors"
+
+func PlaceOrder(total int) error {
+	if total > 1000 {
+		return errors.New("order limit exceeded")
+	}
+	return nil
+}
@@ -0,0 +1,13 @@
+package order
+
+//go:generate mockgen -source=repository.go -destination=mocks/repository.go -package=mocks
+
+type Repository interface {
+	Find(id string) (*Order, error)
+	Save(order *Order) error
+}
+
+type Order struct {
+	ID     string
+	Status Status
+}
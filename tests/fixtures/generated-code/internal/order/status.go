@@ -0,0 +1,10 @@
+package order
+
+//go:generate stringer -type=Status
+
+type Status int
+
+const (
+	Pending Status = iota
+	Paid
+)
@@ -0,0 +1,3 @@
+package payment
+
+var defaults = map[string]string{}
@@ -0,0 +1,7 @@
+package payment
+
+type Gateway interface {
+	Charge(amount int64) error
+}
+
+//go:generate go run go.uber.org/mock/mockgen@v0.4.0 -source=gateway.go -package=payment
@@ -0,0 +1,3 @@
+package payment
+
+type MockGateway struct{}
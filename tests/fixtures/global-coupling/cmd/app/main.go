@@ -0,0 +1,14 @@
+package main
+
+import (
+	"log"
+
+	_ "example.com/app/internal/codec/json"
+	"example.com/app/internal/server"
+)
+
+func main() {
+	if err := server.Run(8080); err != nil {
+		log.Fatal(err)
+	}
+}
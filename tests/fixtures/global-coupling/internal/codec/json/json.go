@@ -0,0 +1,17 @@
+package jsoncodec
+
+import (
+	"encoding/json"
+
+	"example.com/app/internal/codec"
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func init() {
+	codec.Register("json", jsonCodec{})
+}
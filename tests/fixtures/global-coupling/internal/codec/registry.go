@@ -0,0 +1,16 @@
+package codec
+
+type Codec interface {
+	Encode(v any) ([]byte, error)
+}
+
+var codecs = map[string]Codec{}
+
+func Register(name string, c Codec) {
+	codecs[name] = c
+}
+
+func Lookup(name string) (Codec, bool) {
+	c, ok := codecs[name]
+	return c, ok
+}
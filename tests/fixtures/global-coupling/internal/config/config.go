@@ -0,0 +1,25 @@
+package config
+
+import "errors"
+
+type Settings struct {
+	Port    int
+	Workers int
+}
+
+var (
+	// Current is replaced by Load
+	Current = &Settings{
+		Port:    8080,
+		Workers: 4,
+	}
+	ErrMissing = errors.New("config: missing")
+)
+
+func Load(port int) error {
+	if port == 0 {
+		return ErrMissing
+	}
+	Current = &Settings{Port: port, Workers: Current.Workers}
+	return nil
+}
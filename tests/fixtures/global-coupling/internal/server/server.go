@@ -0,0 +1,15 @@
+package server
+
+import (
+	"fmt"
+
+	"example.com/app/internal/config"
+)
+
+func Run(port int) error {
+	if err := config.Load(port); err != nil {
+		return err
+	}
+	fmt.Println("listening on", config.Current.Port)
+	return nil
+}
@@ -0,0 +1,11 @@
+package worker
+
+import "example.com/app/internal/config"
+
+func PoolSize() int {
+	n := config.Current.Workers
+	if n < 1 {
+		return 1
+	}
+	return n
+}
@@ -0,0 +1,10 @@
+package handler
+
+import (
+	"example.com/money"
+	"example.com/shop/billing/invoice"
+)
+
+func PlaceOrder(customer string, total money.Amount) (*invoice.Invoice, error) {
+	return invoice.Issue(customer, total)
+}
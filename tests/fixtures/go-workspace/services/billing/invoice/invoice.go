@@ -0,0 +1,12 @@
+package invoice
+
+import "example.com/money"
+
+type Invoice struct {
+	Customer string
+	Total    money.Amount
+}
+
+func Issue(customer string, total money.Amount) (*Invoice, error) {
+	return &Invoice{Customer: customer, Total: total}, nil
+}
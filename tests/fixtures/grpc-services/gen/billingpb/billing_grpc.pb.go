@@ -0,0 +1,7 @@
+package billingpb
+
+type InvoiceServiceServer interface {
+	IssueInvoice() error
+}
+
+type UnimplementedInvoiceServiceServer struct{}
@@ -0,0 +1,5 @@
+package orderv1
+
+type Order struct {
+	Id string
+}
@@ -0,0 +1,8 @@
+package orderv1
+
+type OrderServiceServer interface {
+	CreateOrder() (*Order, error)
+	GetOrder() (*Order, error)
+}
+
+type UnimplementedOrderServiceServer struct{}
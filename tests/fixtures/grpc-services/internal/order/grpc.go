@@ -0,0 +1,12 @@
+package order
+
+import orderv1 "example.com/shop/gen/order/v1"
+
+type grpcServer struct {
+	orderv1.UnimplementedOrderServiceServer
+	svc *Service
+}
+
+func (s *grpcServer) CreateOrder() (*orderv1.Order, error) {
+	return &orderv1.Order{}, nil
+}
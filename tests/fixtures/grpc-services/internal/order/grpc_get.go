@@ -0,0 +1,7 @@
+package order
+
+import orderv1 "example.com/shop/gen/order/v1"
+
+func (s *grpcServer) GetOrder() (*orderv1.Order, error) {
+	return &orderv1.Order{}, nil
+}
@@ -0,0 +1,7 @@
+package server
+
+import healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+}
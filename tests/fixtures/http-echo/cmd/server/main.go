@@ -0,0 +1,20 @@
+package main
+
+import (
+	"log"
+
+	"github.com/labstack/echo/v4"
+
+	"example.com/shop/internal/user"
+	"example.com/shop/pkg/httpx"
+)
+
+func main() {
+	e := echo.New()
+	e.Validator = httpx.NewValidator()
+	e.HTTPErrorHandler = httpx.ErrorHandler
+
+	user.Register(e.Group("/api"))
+
+	log.Fatal(e.Start(":8080"))
+}
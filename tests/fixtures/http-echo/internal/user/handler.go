@@ -0,0 +1,34 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"example.com/shop/pkg/httpx"
+)
+
+type createUser struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// Register mounts the user routes
+func Register(g *echo.Group) {
+	g.GET("/users/:id", get)
+	g.POST("/users", create)
+}
+
+func get(c echo.Context) error {
+	return httpx.RespondJSON(c, http.StatusOK, map[string]string{"id": c.Param("id")})
+}
+
+func create(c echo.Context) error {
+	var req createUser
+	if err := c.Bind(&req); err != nil {
+		return httpx.RespondError(c, http.StatusBadRequest, err)
+	}
+	if err := c.Validate(&req); err != nil {
+		return httpx.RespondError(c, http.StatusBadRequest, err)
+	}
+	return httpx.RespondJSON(c, http.StatusCreated, req)
+}
@@ -0,0 +1,35 @@
+package httpx
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Envelope wraps every response body
+type Envelope struct {
+	Data  interface{} `json:"data,omitempty"`
+	Error string      `json:"error,omitempty"`
+}
+
+// RespondJSON writes data inside the envelope
+func RespondJSON(c echo.Context, status int, data interface{}) error {
+	return c.JSON(status, Envelope{Data: data})
+}
+
+// RespondError writes err inside the envelope
+func RespondError(c echo.Context, status int, err error) error {
+	return c.JSON(status, Envelope{Error: err.Error()})
+}
+
+// ErrorHandler renders errors returned by handlers
+func ErrorHandler(err error, c echo.Context) {
+	_ = RespondError(c, http.StatusInternalServerError, err)
+}
+
+type validator struct{}
+
+func (validator) Validate(i interface{}) error { return nil }
+
+// NewValidator validates bound request bodies
+func NewValidator() echo.Validator { return validator{} }
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"example.com/api/internal/order"
+	"example.com/api/internal/user"
+	"github.com/gin-gonic/gin"
+)
+
+func main() {
+	r := gin.Default()
+	oh := order.NewHandler(nil)
+	uh := &user.Handler{}
+
+	api := r.Group("/api/v1")
+	orders := api.Group("/orders")
+	{
+		orders.GET("", oh.List)
+		orders.POST("", oh.Create)
+		orders.GET("/:id", oh.Get)
+		orders.POST("/:id/refund", order.Refund)
+	}
+	api.GET("/users/:id", uh.Get)
+	r.GET("/healthz", func(c *gin.Context) { c.String(200, "ok") })
+
+	r.Run()
+}
@@ -0,0 +1,19 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func Routes(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Get("/stats", Stats)
+		r.Route("/users", func(r chi.Router) {
+			r.Delete("/{id}", BanUser)
+		})
+	})
+}
+
+func Stats(w http.ResponseWriter, r *http.Request)   {}
+func BanUser(w http.ResponseWriter, r *http.Request) {}
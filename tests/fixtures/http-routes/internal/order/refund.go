@@ -0,0 +1,5 @@
+package order
+
+import "github.com/gin-gonic/gin"
+
+func Refund(c *gin.Context) {}
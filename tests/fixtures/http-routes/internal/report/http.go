@@ -0,0 +1,11 @@
+package report
+
+import "net/http"
+
+func Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /reports/{month}", monthly)
+	mux.Handle("/reports/export", http.HandlerFunc(export))
+}
+
+func monthly(w http.ResponseWriter, r *http.Request) {}
+func export(w http.ResponseWriter, r *http.Request)  {}
@@ -0,0 +1,7 @@
+package user
+
+import "github.com/gin-gonic/gin"
+
+type Handler struct{}
+
+func (h *Handler) Get(c *gin.Context) {}
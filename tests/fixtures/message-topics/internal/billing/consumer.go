@@ -0,0 +1,23 @@
+package billing
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func HandleOrderCreated(ctx context.Context, brokers []string) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: brokers,
+		GroupID: "billing",
+		Topic:   "order-created",
+	})
+	defer reader.Close()
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		_ = msg
+	}
+}
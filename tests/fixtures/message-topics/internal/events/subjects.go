@@ -0,0 +1,5 @@
+package events
+
+const (
+	OrderShipped = "orders.shipped"
+)
@@ -0,0 +1,20 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+func Poll(ctx context.Context, client *sqs.Client) error {
+	out, err := client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String("https://sqs.ap-northeast-1.amazonaws.com/123456789012/email-requests"),
+		MaxNumberOfMessages: 10,
+	})
+	if err != nil {
+		return err
+	}
+	_ = out
+	return nil
+}
@@ -0,0 +1,18 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+const emailQueueURL = "https://sqs.ap-northeast-1.amazonaws.com/123456789012/email-requests"
+
+func EnqueueEmail(ctx context.Context, client *sqs.Client, body string) error {
+	_, err := client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(emailQueueURL),
+		MessageBody: aws.String(body),
+	})
+	return err
+}
@@ -0,0 +1,30 @@
+package order
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+
+	"example.com/shop/internal/events"
+)
+
+type Publisher struct {
+	writer *kafka.Writer
+	nc     *nats.Conn
+}
+
+func NewPublisher(brokers []string, nc *nats.Conn) *Publisher {
+	return &Publisher{
+		writer: &kafka.Writer{Addr: kafka.TCP(brokers...), Topic: "order-created"},
+		nc:     nc,
+	}
+}
+
+func (p *Publisher) PublishOrderCreated(ctx context.Context, payload []byte) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{Value: payload})
+}
+
+func (p *Publisher) PublishOrderShipped(payload []byte) error {
+	return p.nc.Publish(events.OrderShipped, payload)
+}
@@ -0,0 +1,14 @@
+package shipping
+
+import (
+	"github.com/nats-io/nats.go"
+
+	"example.com/shop/internal/events"
+)
+
+func HandleOrderShipped(nc *nats.Conn) (*nats.Subscription, error) {
+	return nc.QueueSubscribe(events.OrderShipped, "shipping", func(msg *nats.Msg) {
+		_ = msg.Data
+	})
+}
+
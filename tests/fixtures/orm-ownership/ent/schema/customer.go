@@ -0,0 +1,20 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/dialect/entsql"
+	"entgo.io/ent/schema"
+	"entgo.io/ent/schema/field"
+)
+
+type Customer struct {
+	ent.Schema
+}
+
+func (Customer) Fields() []ent.Field {
+	return []ent.Field{field.String("name")}
+}
+
+func (Customer) Annotations() []schema.Annotation {
+	return []schema.Annotation{entsql.Annotation{Table: "clients"}}
+}
@@ -0,0 +1,14 @@
+package schema
+
+import (
+	"entgo.io/ent"
+	"entgo.io/ent/schema/field"
+)
+
+type Invoice struct {
+	ent.Schema
+}
+
+func (Invoice) Fields() []ent.Field {
+	return []ent.Field{field.Int64("amount_cents")}
+}
@@ -0,0 +1,16 @@
+module example.com/store
+
+go 1.23
+
+require (
+	entgo.io/ent v0.14.5
+	github.com/jmoiron/sqlx v1.4.0
+	gorm.io/gorm v1.31.2
+)
+
+require (
+	github.com/google/uuid v1.3.0 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	golang.org/x/text v0.21.0 // indirect
+)
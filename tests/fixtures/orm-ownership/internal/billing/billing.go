@@ -0,0 +1,15 @@
+package billing
+
+import (
+	"context"
+
+	"example.com/store/ent"
+)
+
+func Issue(ctx context.Context, client *ent.Client, customerID int, cents int64) error {
+	if _, err := client.Customer.Query().Where().Only(ctx); err != nil {
+		return err
+	}
+	_, err := client.Invoice.Create().SetAmountCents(cents).Save(ctx)
+	return err
+}
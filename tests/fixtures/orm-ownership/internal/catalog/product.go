@@ -0,0 +1,10 @@
+package catalog
+
+import "gorm.io/gorm"
+
+type Product struct {
+	gorm.Model
+	SKU        string `gorm:"uniqueIndex"`
+	Name       string
+	PriceCents int64
+}
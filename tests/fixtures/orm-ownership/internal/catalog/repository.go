@@ -0,0 +1,17 @@
+package catalog
+
+import "gorm.io/gorm"
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func (r *Repository) Add(p *Product) error {
+	return r.db.Create(&Product{SKU: p.SKU, Name: p.Name}).Error
+}
+
+func (r *Repository) All() ([]Product, error) {
+	var products []Product
+	err := r.db.Find(&products).Error
+	return products, err
+}
@@ -0,0 +1,11 @@
+package crm
+
+import (
+	"context"
+
+	"example.com/store/ent"
+)
+
+func Rename(ctx context.Context, client *ent.Client, id int, name string) error {
+	return client.Customer.UpdateOneID(id).SetName(name).Exec(ctx)
+}
@@ -0,0 +1,13 @@
+package pricing
+
+import (
+	"example.com/store/internal/catalog"
+	"gorm.io/gorm"
+)
+
+// Reprice writes product prices directly instead of going through the catalog
+func Reprice(db *gorm.DB, sku string, cents int64) error {
+	return db.Model(&catalog.Product{}).
+		Where("sku = ?", sku).
+		Update("price_cents", cents).Error
+}
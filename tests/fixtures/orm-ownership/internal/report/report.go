@@ -0,0 +1,16 @@
+package report
+
+import "github.com/jmoiron/sqlx"
+
+type ProductRow struct {
+	SKU        string `db:"sku"`
+	PriceCents int64  `db:"price_cents"`
+}
+
+const productsQuery = `SELECT sku, price_cents FROM products ORDER BY sku`
+
+func PriceList(db *sqlx.DB) ([]ProductRow, error) {
+	var rows []ProductRow
+	err := db.Select(&rows, productsQuery)
+	return rows, err
+}
@@ -0,0 +1,17 @@
+package billing
+
+import (
+	"fmt"
+
+	legacy "example.com/shop/internal/order"
+	"example.com/shop/internal/orderv2"
+)
+
+type Invoice struct {
+	Current *order.Order
+	Legacy  *legacy.Order
+}
+
+func Describe(inv *Invoice) string {
+	return fmt.Sprintf("%s/%s", inv.Current.ID, inv.Legacy.ID)
+}
@@ -0,0 +1,11 @@
+package order
+
+// Order is the current order model
+type Order struct {
+	ID    string
+	Total int
+}
+
+func NewOrder(id string) *Order {
+	return &Order{ID: id}
+}
@@ -0,0 +1,11 @@
+// Package order in internal/orderv2: the directory name does not match the package name
+package order
+
+type Order struct {
+	ID    string
+	Lines []string
+}
+
+func PlaceOrder(id string) *Order {
+	return &Order{ID: id}
+}
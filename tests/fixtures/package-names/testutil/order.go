@@ -0,0 +1,11 @@
+// Test doubles shared by the order tests; declares the same package name as internal/order
+package order
+
+type FakeRepository struct {
+	Saved []string
+}
+
+func (r *FakeRepository) Save(id string) error {
+	r.Saved = append(r.Saved, id)
+	return nil
+}
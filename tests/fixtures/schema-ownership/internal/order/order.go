@@ -0,0 +1,19 @@
+package order
+
+type Order struct {
+	ID         int64
+	UserID     int64
+	TotalCents int64
+	CouponCode string
+}
+
+func (Order) TableName() string { return "orders" }
+
+type Item struct {
+	ID       int64  `db:"id"`
+	OrderID  int64  `db:"order_id"`
+	SKU      string `db:"sku"`
+	Quantity int    `db:"quantity"`
+}
+
+func (Item) TableName() string { return "order_items" }
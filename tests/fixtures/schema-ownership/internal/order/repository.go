@@ -0,0 +1,28 @@
+package order
+
+import "database/sql"
+
+type Repository struct {
+	db *sql.DB
+}
+
+func (r *Repository) Save(o *Order, items []Item) error {
+	if _, err := r.db.Exec("INSERT INTO orders (user_id, total_cents) VALUES ($1, $2)", o.UserID, o.TotalCents); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if _, err := r.db.Exec(`INSERT INTO order_items (order_id, sku, quantity) VALUES ($1, $2, $3)`, o.ID, item.SKU, item.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Repository) ApplyCoupon(id int64, code string) error {
+	_, err := r.db.Exec(`UPDATE orders SET coupon_code = $1 WHERE id = $2`, code, id)
+	return err
+}
+
+func (r *Repository) ForUser(userID int64) (*sql.Rows, error) {
+	return r.db.Query(`SELECT o.id FROM orders o JOIN users u ON u.id = o.user_id WHERE u.id = $1`, userID)
+}
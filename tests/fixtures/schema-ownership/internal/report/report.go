@@ -0,0 +1,10 @@
+package report
+
+import "database/sql"
+
+// Revenue sums order totals; reports read from orders and users but own neither
+func Revenue(db *sql.DB) (int64, error) {
+	var total int64
+	err := db.QueryRow(`SELECT SUM(o.total_cents) FROM orders o JOIN users u ON u.id = o.user_id`).Scan(&total)
+	return total, err
+}
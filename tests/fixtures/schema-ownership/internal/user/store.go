@@ -0,0 +1,19 @@
+package user
+
+import "database/sql"
+
+type Store struct {
+	db *sql.DB
+}
+
+// Create stores a user; the name is taken from the signup form
+func (s *Store) Create(u *User) error {
+	_, err := s.db.Exec(`INSERT INTO users (email, name) VALUES ($1, $2)`, u.Email, u.Name)
+	return err
+}
+
+func (s *Store) Find(id int64) (*User, error) {
+	u := &User{}
+	err := s.db.QueryRow("SELECT id, email, name FROM users WHERE id = $1", id).Scan(&u.ID, &u.Email, &u.Name)
+	return u, err
+}
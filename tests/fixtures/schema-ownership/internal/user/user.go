@@ -0,0 +1,7 @@
+package user
+
+type User struct {
+	ID    int64  `db:"id"`
+	Email string `db:"email"`
+	Name  string `db:"name"`
+}
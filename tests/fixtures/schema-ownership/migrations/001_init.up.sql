@@ -0,0 +1,23 @@
+CREATE TABLE users (
+    id BIGSERIAL PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    name TEXT NOT NULL
+);
+
+CREATE TABLE orders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users (id),
+    total_cents BIGINT NOT NULL
+);
+
+CREATE TABLE order_items (
+    id BIGSERIAL PRIMARY KEY,
+    order_id BIGINT NOT NULL REFERENCES orders (id),
+    sku TEXT NOT NULL,
+    quantity INT NOT NULL
+);
+
+CREATE TABLE legacy_sessions (
+    token TEXT PRIMARY KEY,
+    user_id BIGINT REFERENCES users (id)
+);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS public.coupons (
+    code TEXT PRIMARY KEY,
+    percent_off INT NOT NULL
+);
+
+ALTER TABLE orders ADD COLUMN coupon_code TEXT;
+ALTER TABLE orders ADD CONSTRAINT orders_coupon_fk FOREIGN KEY (coupon_code) REFERENCES coupons (code);
+
+DROP TABLE IF EXISTS legacy_sessions;
@@ -0,0 +1,10 @@
+package main
+
+import (
+	"example.com/shop/internal/billing"
+	"example.com/shop/internal/order"
+)
+
+func main() {
+	billing.Bill(order.Order{})
+}
@@ -0,0 +1,10 @@
+package billing
+
+import (
+	"example.com/shop/internal/money"
+	"example.com/shop/internal/order"
+)
+
+func Bill(o order.Order) money.Money {
+	return o.Total
+}
@@ -0,0 +1,15 @@
+package catalog
+
+import (
+	"example.com/shop/internal/money"
+	"example.com/shop/pkg/stringutil"
+)
+
+type Item struct {
+	Slug  string
+	Price money.Money
+}
+
+func New(name string, price money.Money) Item {
+	return Item{Slug: stringutil.Slug(name), Price: price}
+}
@@ -0,0 +1,7 @@
+package logging
+
+import "log"
+
+func Info(msg string) {
+	log.Println(msg)
+}
@@ -0,0 +1,10 @@
+package money
+
+import "example.com/shop/internal/logging"
+
+type Money struct{ Cents int64 }
+
+func (m Money) Add(o Money) Money {
+	logging.Info("add")
+	return Money{Cents: m.Cents + o.Cents}
+}
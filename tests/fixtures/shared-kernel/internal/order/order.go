@@ -0,0 +1,12 @@
+package order
+
+import (
+	"example.com/shop/internal/logging"
+	"example.com/shop/internal/money"
+)
+
+type Order struct{ Total money.Money }
+
+func (o Order) Place() {
+	logging.Info("placed")
+}
@@ -0,0 +1,7 @@
+package testutil
+
+import "example.com/shop/internal/order"
+
+func NewOrder() order.Order {
+	return order.Order{}
+}
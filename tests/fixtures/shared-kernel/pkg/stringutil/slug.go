@@ -0,0 +1,7 @@
+package stringutil
+
+import "strings"
+
+func Slug(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, " ", "-"))
+}
@@ -0,0 +1,9 @@
+package billing
+
+import "example.com/shop/internal/cache"
+
+func Charge(id string) string {
+	cache.Put(id, "charged")
+	cache.UserCache[id] = "billed"
+	return cache.Registry["default"]
+}
@@ -0,0 +1,21 @@
+package cache
+
+import "sync"
+
+var (
+	mu        sync.Mutex
+	UserCache = map[string]string{}
+)
+
+// Registry is filled once at startup and only read afterwards
+var Registry = make(map[string]string)
+
+func init() {
+	Registry["default"] = "v1"
+}
+
+func Put(key, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+	UserCache[key] = value
+}
@@ -0,0 +1,10 @@
+package user
+
+import "example.com/shop/internal/cache"
+
+func Find(id string) string {
+	if v, ok := cache.UserCache[id]; ok {
+		return v
+	}
+	return cache.Registry["default"]
+}
@@ -0,0 +1,11 @@
+package order
+
+type Order struct {
+	ID     string
+	UserID string
+	Total  int
+}
+
+func (o *Order) Placed() bool {
+	return o.UserID != ""
+}
@@ -0,0 +1,22 @@
+package order_test
+
+import (
+	"testing"
+
+	"example.com/shop/internal/testutil"
+)
+
+func TestPlaced(t *testing.T) {
+	db := testutil.SetupTestDB(t)
+	_ = db
+	o, u := testutil.BuildOrderFor("u1")
+	if !o.Placed() || o.UserID != u.ID {
+		t.Fatal("expected the order to be placed by u1")
+	}
+	if testutil.BuildOrder("o2").Placed() {
+		t.Fatal("testutil.BuildOrder must not set a user")
+	}
+	if testutil.BuildUser("u2").ID != "u2" {
+		t.Fatal("unexpected user")
+	}
+}
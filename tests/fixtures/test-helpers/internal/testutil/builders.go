@@ -0,0 +1,22 @@
+package testutil
+
+import (
+	"example.com/shop/internal/order"
+	"example.com/shop/internal/user"
+)
+
+func BuildUser(id string) *user.User {
+	return &user.User{ID: id, Email: id + "@example.com"}
+}
+
+func BuildOrder(id string) *order.Order {
+	return &order.Order{ID: id, Total: 100}
+}
+
+// BuildOrderFor builds an order placed by a new user
+func BuildOrderFor(userID string) (*order.Order, *user.User) {
+	u := BuildUser(userID)
+	o := BuildOrder("o-" + userID)
+	o.UserID = u.ID
+	return o, u
+}
@@ -0,0 +1,15 @@
+package testutil
+
+import "time"
+
+type FakeClock struct {
+	now time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	return c.now
+}
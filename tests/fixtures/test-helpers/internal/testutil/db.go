@@ -0,0 +1,16 @@
+package testutil
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func SetupTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", "postgres://localhost/shop_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
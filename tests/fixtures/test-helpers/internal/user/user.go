@@ -0,0 +1,10 @@
+package user
+
+type User struct {
+	ID    string
+	Email string
+}
+
+func (u *User) Valid() bool {
+	return u.Email != ""
+}
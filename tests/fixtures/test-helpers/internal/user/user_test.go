@@ -0,0 +1,16 @@
+package user_test
+
+import (
+	"testing"
+	"time"
+
+	"example.com/shop/internal/testutil"
+)
+
+func TestValid(t *testing.T) {
+	clock := testutil.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	u := testutil.BuildUser("u1")
+	if !u.Valid() || clock.Now().IsZero() {
+		t.Fatal("expected a valid user")
+	}
+}
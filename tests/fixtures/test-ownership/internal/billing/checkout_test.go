@@ -0,0 +1,18 @@
+package billing_test
+
+import (
+	"testing"
+
+	"example.com/shop/internal/billing"
+	"example.com/shop/internal/order"
+)
+
+// Exercises order placement; billing only consumes the result
+func TestCheckout(t *testing.T) {
+	o := order.NewOrder("o-2")
+	if err := o.Place(); err != nil {
+		t.Fatal(err)
+	}
+	var _ *order.Order = o
+	_ = billing.NewInvoice(o)
+}
@@ -0,0 +1,11 @@
+package billing
+
+import "example.com/shop/internal/order"
+
+type Invoice struct {
+	OrderID string
+}
+
+func NewInvoice(o *order.Order) *Invoice {
+	return &Invoice{OrderID: o.ID}
+}
@@ -0,0 +1,15 @@
+package billing_test
+
+import (
+	"testing"
+
+	"example.com/shop/internal/billing"
+	"example.com/shop/internal/testutil"
+)
+
+func TestNewInvoice(t *testing.T) {
+	invoice := billing.NewInvoice(testutil.NewOrder())
+	if invoice.OrderID == "" {
+		t.Fatal("missing order id")
+	}
+}
@@ -0,0 +1,5 @@
+package billing
+
+import "testing"
+
+func TestSmoke(t *testing.T) {}
@@ -0,0 +1,20 @@
+package order
+
+import "errors"
+
+type Order struct {
+	ID     string
+	Placed bool
+}
+
+func NewOrder(id string) *Order {
+	return &Order{ID: id}
+}
+
+func (o *Order) Place() error {
+	if o.Placed {
+		return errors.New("already placed")
+	}
+	o.Placed = true
+	return nil
+}
@@ -0,0 +1,10 @@
+package order
+
+import "testing"
+
+func TestPlace(t *testing.T) {
+	o := NewOrder("o-1")
+	if err := o.Place(); err != nil {
+		t.Fatal(err)
+	}
+}
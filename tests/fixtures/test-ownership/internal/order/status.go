@@ -0,0 +1,3 @@
+package order
+
+type Status string
@@ -0,0 +1,5 @@
+package order_test
+
+import "testing"
+
+func TestStatusValues(t *testing.T) {}
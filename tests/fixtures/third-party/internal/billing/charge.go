@@ -0,0 +1,16 @@
+package billing
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/paymentintent"
+)
+
+func Charge(ctx context.Context, amount int64) (*stripe.PaymentIntent, error) {
+	if _, err := config.LoadDefaultConfig(ctx); err != nil {
+		return nil, err
+	}
+	return paymentintent.New(&stripe.PaymentIntentParams{Amount: stripe.Int64(amount)})
+}
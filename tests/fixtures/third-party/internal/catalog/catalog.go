@@ -0,0 +1,15 @@
+package catalog
+
+import (
+	"context"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+type Catalog struct {
+	cache *redis.Client
+}
+
+func (c *Catalog) Find(ctx context.Context, sku string) (string, error) {
+	return c.cache.Get(ctx, "sku:"+sku).Result()
+}
@@ -0,0 +1,23 @@
+package order
+
+import (
+	"context"
+
+	"example.com/shop/internal/catalog"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+type Service struct {
+	queue   *sqs.Client
+	cache   *redis.Client
+	catalog *catalog.Catalog
+}
+
+func (s *Service) Place(ctx context.Context, sku string) (string, error) {
+	if _, err := s.catalog.Find(ctx, sku); err != nil {
+		return "", err
+	}
+	return uuid.NewString(), nil
+}
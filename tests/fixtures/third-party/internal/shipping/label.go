@@ -0,0 +1,14 @@
+package shipping
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func StoreLabel(ctx context.Context, client *s3.Client, id string, label []byte) error {
+	_, err := client.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("labels"), Key: aws.String(id), Body: bytes.NewReader(label)})
+	return err
+}
@@ -0,0 +1,9 @@
+package audit
+
+type Entry struct {
+	Action string
+}
+
+func (e Entry) String() string {
+	return e.Action
+}
@@ -0,0 +1,16 @@
+package email
+
+import (
+	"context"
+
+	"example.com/notify/internal/notify"
+)
+
+type Sender struct {
+	notify.Base
+	From string
+}
+
+func (s *Sender) Send(ctx context.Context, msg string) error {
+	return nil
+}
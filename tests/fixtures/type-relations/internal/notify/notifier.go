@@ -0,0 +1,21 @@
+package notify
+
+import "context"
+
+// Notifier delivers a message over one channel
+type Notifier interface {
+	Send(ctx context.Context, msg string) error
+}
+
+// Labeled is satisfied by anything printable and says nothing about coupling
+type Labeled interface {
+	String() string
+}
+
+type Base struct {
+	Channel string
+}
+
+func (b Base) Describe() string {
+	return "notifier on " + b.Channel
+}
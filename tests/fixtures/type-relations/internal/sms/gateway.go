@@ -0,0 +1,15 @@
+package sms
+
+import "context"
+
+type Gateway struct {
+	Number string
+}
+
+func (g Gateway) Send(ctx context.Context, msg string) error {
+	return nil
+}
+
+func (g Gateway) String() string {
+	return g.Number
+}
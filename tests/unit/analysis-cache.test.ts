@@ -0,0 +1,116 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { AnalysisCache, ANALYZER_VERSION } from '../../src/core/utils/analysis-cache.js';
+import { CodeAnalyzer } from '../../src/core/utils/code-analyzer.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+describe('AnalysisCache', () => {
+  let tempDir: string;
+  const entriesDir = () => path.join(tempDir, '.vibeflow', 'analysis-cache', 'entries');
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('analysis-cache');
+    await createMockFile(path.join(tempDir, 'user', 'service.go'),
+      'package user\n\nimport "fmt"\n\ntype User struct {}\n\nfunc CreateUser() { fmt.Println() }\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should serve the second analysis from the cache', async () => {
+    const first = await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go']);
+    const second = await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go']);
+
+    expect(second).toEqual(first);
+    expect(second[0]).toMatchObject({ functions: ['CreateUser'], structs: ['User'], imports: ['fmt'] });
+    expect(new AnalysisCache(tempDir).stats()).toMatchObject({ entries: 1, total_hits: 1, total_misses: 1 });
+  });
+
+  it('should expose summaries, symbols and edges and miss on changed content', async () => {
+    await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go']);
+    const cache = new AnalysisCache(tempDir);
+    const content = fs.readFileSync(path.join(tempDir, 'user', 'service.go'), 'utf8');
+    const hash = AnalysisCache.hashContent(content);
+
+    expect(cache.getFileSummary('user/service.go', hash)).toMatchObject({ language: 'go', lines: 8 });
+    expect(cache.getSymbolTable('user/service.go', hash)).toEqual([
+      { name: 'CreateUser', kind: 'function' },
+      { name: 'User', kind: 'struct' },
+    ]);
+    expect(cache.getDependencyEdges('user/service.go', hash)).toEqual([{ from: 'user/service.go', to: 'fmt', import: 'fmt' }]);
+    expect(cache.get('user/service.go', AnalysisCache.hashContent(`${content}\n`))).toBeNull();
+  });
+
+  it('should rebuild corrupted entries transparently', async () => {
+    const expected = await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go']);
+    const [entryFile] = fs.readdirSync(entriesDir());
+    fs.writeFileSync(path.join(entriesDir(), entryFile), '{"path":"user/service.go","data":');
+
+    expect(await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go'])).toEqual(expected);
+    expect(new AnalysisCache(tempDir).stats()).toMatchObject({ entries: 1, total_corrupted: 1 });
+
+    // Tampered data fails the checksum
+    const entry = JSON.parse(fs.readFileSync(path.join(entriesDir(), entryFile), 'utf8'));
+    entry.data.summary.functions = ['Other'];
+    fs.writeFileSync(path.join(entriesDir(), entryFile), JSON.stringify(entry));
+
+    expect(await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go'])).toEqual(expected);
+    expect(new AnalysisCache(tempDir).stats().total_corrupted).toBe(2);
+  });
+
+  it('should never serve entries written by another analyzer version', () => {
+    const previous = new AnalysisCache(tempDir, { analyzerVersion: ANALYZER_VERSION - 1 });
+    previous.put('user/service.go', 'h', {
+      summary: { language: 'go', lines: 1, imports: [], exports: [] },
+      symbols: [],
+      edges: [],
+    });
+    expect(previous.flush()).toBe(true);
+
+    const current = new AnalysisCache(tempDir);
+    expect(current.get('user/service.go', 'h')).toBeNull();
+    expect(current.flush()).toBe(true);
+    expect(current.stats()).toMatchObject({ analyzer_version: ANALYZER_VERSION, entries: 0 });
+  });
+
+  it('should leave writing to the lock holder and still read', async () => {
+    await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go']);
+    fs.writeFileSync(path.join(tempDir, '.vibeflow', 'analysis-cache.lock'), JSON.stringify({
+      pid: process.ppid,
+      command: 'analysis-cache',
+      hostname: os.hostname(),
+      acquired_at: new Date().toISOString(),
+    }));
+    await createMockFile(path.join(tempDir, 'user', 'repo.go'), 'package user\n');
+
+    const cache = new AnalysisCache(tempDir);
+    const files = await new CodeAnalyzer(tempDir, cache).analyzeFiles(['**/*.go']);
+
+    expect(files).toHaveLength(2);
+    expect(cache.stats().entries).toBe(1);
+    expect(() => cache.clear()).toThrow(/another process/);
+  });
+
+  it('should record hits and misses on the active run and clear entries', async () => {
+    const store = new PerformanceStore(tempDir);
+    const runId = store.startRun('plan');
+    await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go']);
+    await new CodeAnalyzer(tempDir).analyzeFiles(['**/*.go']);
+
+    const metrics = new PerformanceStore(tempDir).getMetrics(runId)
+      .filter(m => m.metric.startsWith('analysis_cache_'))
+      .map(m => [m.metric, m.value]);
+    expect(metrics).toEqual([
+      ['analysis_cache_hits', 0], ['analysis_cache_misses', 1],
+      ['analysis_cache_hits', 1], ['analysis_cache_misses', 0],
+    ]);
+
+    const cache = new AnalysisCache(tempDir);
+    cache.clear();
+    expect(cache.stats()).toMatchObject({ entries: 0, total_hits: 0 });
+  });
+});
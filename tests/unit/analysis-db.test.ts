@@ -0,0 +1,124 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync, execSync } from 'child_process';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { AnalysisGraphInput, buildAnalysisGraph, renderAnalysisSql, writeAnalysisDatabase } from '../../src/core/utils/analysis-db.js';
+
+const projectRoot = '/work/shop';
+
+function hasSqlite(): boolean {
+  try {
+    execSync('sqlite3 -version', { stdio: 'ignore' });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+const input: AnalysisGraphInput = {
+  packages: [
+    { import_path: 'example.com/shop/internal/order', dir: 'internal/order', name: 'order', files: ['internal/order/service.go', 'internal/order/repository.go'] },
+    { import_path: 'example.com/shop/internal/billing', dir: 'internal/billing', name: 'billing', files: ['internal/billing/invoice.go'] },
+  ],
+  structs: [
+    { type: 'struct', name: 'Repository', file: `${projectRoot}/internal/order/repository.go`, line: 5, dependencies: [], properties: [], methods: [], implementsInterfaces: [], embeds: [] },
+  ],
+  interfaces: [
+    { type: 'interface', name: 'Store', file: `${projectRoot}/internal/order/service.go`, line: 7, dependencies: [], methods: [], extends: [] },
+  ],
+  functions: [
+    {
+      type: 'function', name: 'Place', file: `${projectRoot}/internal/order/service.go`, line: 12, receiver: 's *Service',
+      dependencies: ['example.com/shop/internal/billing', 'fmt'], parameters: [], returnType: 'error', calls: [], function_values: [], tables_accessed: [],
+    },
+    {
+      type: 'function', name: 'Save', file: `${projectRoot}/internal/order/repository.go`, line: 9, receiver: 'r *Repository',
+      dependencies: [], parameters: [], returnType: 'error', calls: [], function_values: [], tables_accessed: ['orders'],
+    },
+    {
+      type: 'function', name: 'Issue', file: `${projectRoot}/internal/billing/invoice.go`, line: 3,
+      dependencies: [], parameters: [], returnType: 'error', calls: [], function_values: [], tables_accessed: [],
+    },
+  ],
+  database_access: [{ table: 'orders', operation: 'insert', file: `${projectRoot}/internal/order/repository.go`, function: 'Save' }],
+  call_graph: {
+    algorithm: 'cha',
+    edges: [
+      { caller_file: 'internal/order/service.go', caller: 'Place', caller_type: 'Service', callee_file: 'internal/billing/invoice.go', callee: 'Issue', count: 2 },
+      { caller_file: 'internal/order/service.go', caller: 'Place', caller_type: 'Service', callee_file: 'internal/order/repository.go', callee: 'Save', callee_type: 'Repository', count: 1 },
+    ],
+  },
+  type_relations: {
+    source: 'go/types',
+    relations: [{ kind: 'implements', from_file: 'internal/order/repository.go', from_type: 'Repository', to_file: 'internal/order/service.go', to_type: 'Store' }],
+  },
+  table_ownership: {
+    sources: ['schema.sql'],
+    tables: [],
+    access: [
+      { table: 'orders', file: 'internal/order/repository.go', symbol: 'Repository', via: 'struct' },
+      { table: 'orders', file: 'internal/order/repository.go', symbol: 'Save', via: 'query', operation: 'insert' },
+    ],
+  },
+  boundaries: [
+    { name: 'order', files: ['internal/order/service.go', 'internal/order/repository.go'] },
+    { name: 'billing', files: ['internal/billing/invoice.go'] },
+  ],
+};
+
+describe('Analysis database', () => {
+  let tempDir: string;
+
+  beforeEach(() => {
+    tempDir = fs.mkdtempSync(path.join(os.tmpdir(), 'vibeflow-analysis-db-'));
+  });
+
+  afterEach(() => {
+    fs.rmSync(tempDir, { recursive: true, force: true });
+  });
+
+  it('should turn files, symbols, calls, type relations and table access into nodes and edges', () => {
+    const graph = buildAnalysisGraph(projectRoot, input);
+    const name = (id: number) => graph.nodes[id - 1].name;
+    const edges = (kind: string) => graph.edges.filter(edge => edge.kind === kind).map(edge => [name(edge.source), name(edge.target), edge.weight, edge.detail]);
+
+    expect(graph.nodes.find(node => node.name === 'Service.Place')).toMatchObject({
+      kind: 'method', file: 'internal/order/service.go', line: 12, package: 'example.com/shop/internal/order', module: 'order',
+    });
+    expect(edges('calls')).toEqual([
+      ['Service.Place', 'Issue', 2, undefined],
+      ['Service.Place', 'Repository.Save', 1, undefined],
+    ]);
+    expect(edges('imports')).toEqual([['internal/order/service.go', 'example.com/shop/internal/billing', 1, undefined]]);
+    expect(edges('implements')).toEqual([['Repository', 'Store', 1, undefined]]);
+    expect(edges('maps')).toEqual([['Repository', 'orders', 1, undefined]]);
+    expect(edges('accesses')).toEqual([['Repository.Save', 'orders', 1, 'insert']]);
+    expect(graph.nodes.filter(node => node.kind === 'table').map(({ id: _id, ...node }) => node)).toEqual([{ kind: 'table', name: 'orders' }]);
+  });
+
+  it('should render the graph as SQL with quoted values and NULLs', () => {
+    const sql = renderAnalysisSql(
+      { nodes: [{ id: 1, kind: 'module', name: "customer's" }], edges: [] },
+      { analyzed_at: '2025-01-01T00:00:00.000Z' }
+    );
+
+    expect(sql).toContain("INSERT INTO nodes VALUES (1, 'module', 'customer''s', NULL, NULL, NULL, NULL);");
+    expect(sql).toContain("INSERT INTO meta VALUES ('analyzed_at', '2025-01-01T00:00:00.000Z');");
+    expect(sql.trim().endsWith('COMMIT;')).toBe(true);
+  });
+
+  it.skipIf(!hasSqlite())('should write a database that answers ad-hoc queries', () => {
+    const dbPath = path.join(tempDir, 'analysis.db');
+    const graph = buildAnalysisGraph(projectRoot, input);
+
+    expect(writeAnalysisDatabase(dbPath, graph, { call_graph: 'cha' })).toBe(dbPath);
+    const query = (sql: string) => execFileSync('sqlite3', [dbPath, sql], { encoding: 'utf8' }).trim();
+    expect(query("SELECT source_module || '>' || target_module FROM named_edges WHERE kind = 'calls' AND source_module <> target_module")).toBe('order>billing');
+    expect(query("SELECT value FROM meta WHERE key = 'call_graph'")).toBe('cha');
+
+    // Re-running replaces the database instead of appending to it
+    writeAnalysisDatabase(dbPath, graph);
+    expect(query('SELECT COUNT(*) FROM nodes')).toBe(String(graph.nodes.length));
+  });
+});
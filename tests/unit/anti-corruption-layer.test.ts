@@ -0,0 +1,84 @@
+import { describe, it, expect } from 'vitest';
+import { recommendAntiCorruptionLayers, renderAclSection } from '../../src/core/utils/anti-corruption-layer.js';
+import { ModulePorts } from '../../src/core/utils/module-ports.js';
+
+const port = (name: string, kind: ModulePorts['exposed'][number]['kind'], pkg: string, consumers: string[]) =>
+  ({ name, kind, package: pkg, file: `${pkg}/${name.toLowerCase()}.go`, consumers, references: 1 });
+
+const modules = [
+  {
+    name: 'user',
+    ports: {
+      exposed: [
+        port('Repository', 'interface', 'internal/user', ['order']),
+        port('User', 'dto', 'internal/user', ['order', 'report']),
+        port('Status', 'type', 'internal/user', ['order']),
+        port('Find', 'func', 'internal/user', ['order']),
+      ],
+      internal: [],
+      kept: [],
+    },
+  },
+  { name: 'order', ports: { exposed: [port('Order', 'dto', 'internal/order', ['user', 'report'])], internal: [], kept: [] } },
+  { name: 'report', ports: { exposed: [], internal: [], kept: [] } },
+  { name: 'billing', status: 'established' as const },
+];
+
+const coupling = [
+  { from: 'order', to: 'user', references: 9, files: ['internal/order/place.go', 'internal/order/cart.go'] },
+  { from: 'user', to: 'order', references: 3, files: ['internal/user/history.go'] },
+  { from: 'report', to: 'user', references: 4, files: ['internal/report/report.go'] },
+  { from: 'billing', to: 'report', references: 20, files: ['billing/invoice.go'] },
+];
+
+describe('Anti-corruption layers', () => {
+  it('should recommend a layer on each side of strongly coupled pairs with the types it duplicates', () => {
+    const recommendations = recommendAntiCorruptionLayers(modules, coupling);
+
+    expect(recommendations.map(r => [r.modules, r.references])).toEqual([[['order', 'user'], 12]]);
+    expect(recommendations[0].sides).toEqual([
+      {
+        module: 'order',
+        upstream: 'user',
+        references: 9,
+        files: ['internal/order/cart.go', 'internal/order/place.go'],
+        file: 'internal/order/acl/user.go',
+        facade: 'UserGateway',
+        operations: ['user.Find'],
+        replaces: ['user.Repository'],
+        types: [
+          { upstream: 'user.User', local: 'User', kind: 'dto', translator: 'toUser' },
+          { upstream: 'user.Status', local: 'Status', kind: 'type', translator: 'toStatus' },
+        ],
+      },
+      {
+        module: 'user',
+        upstream: 'order',
+        references: 3,
+        files: ['internal/user/history.go'],
+        file: 'internal/user/acl/order.go',
+        facade: 'OrderGateway',
+        operations: [],
+        replaces: [],
+        types: [{ upstream: 'order.Order', local: 'Order', kind: 'dto', translator: 'toOrder' }],
+      },
+    ]);
+    expect(recommendAntiCorruptionLayers(modules, coupling, 4).map(r => r.modules)).toEqual([
+      ['order', 'user'],
+      ['report', 'user'],
+    ]);
+  });
+
+  it('should render the layers of each pair with a Go sketch', () => {
+    const section = renderAclSection(recommendAntiCorruptionLayers(modules, coupling));
+
+    expect(section).toContain('## 腐敗防止層 (Anti-Corruption Layers)');
+    expect(section).toContain('### order ⇄ user（参照 12箇所）');
+    expect(section).toContain('- order 側: `internal/order/acl/user.go` に `UserGateway` ファサードと変換関数を作成（user への参照 9箇所、2ファイル）');
+    expect(section).toContain('  - order 側に複製する型: `user.User` → `User`（変換: `toUser`）, `user.Status` → `Status`（変換: `toStatus`）');
+    expect(section).toContain('type UserGateway interface {\n\tFind(/* order の型 */)\n}');
+    expect(section).toContain('func toUser(v user.User) User {');
+    expect(section).toContain('func toStatus(v user.Status) Status {\n\treturn Status(v)\n}');
+    expect(renderAclSection([])).toBe('');
+  });
+});
@@ -0,0 +1,109 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { execSync } from 'child_process';
+import { ApiSurfaceReporter, renameIdentifiers, unexportedName } from '../../src/core/utils/api-surface.js';
+import { ModuleManifestStore } from '../../src/core/utils/module-manifest.js';
+import { FileSafetyManager } from '../../src/core/utils/file-safety.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+const fixtureRoot = './tests/fixtures/api-surface';
+const catalogFile = 'internal/catalog/catalog.go';
+
+function hasGo(): boolean {
+  try {
+    execSync('go version', { stdio: 'ignore' });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+describe('unexport renames', () => {
+  it('should follow Go naming for initialisms', () => {
+    expect(unexportedName('User')).toBe('user');
+    expect(unexportedName('HTTPClient')).toBe('httpClient');
+    expect(unexportedName('ID')).toBe('id');
+  });
+
+  it('should leave selectors, field names, literal keys and strings alone', () => {
+    const source = 'type T struct {\n\tLimit int\n}\n\n' +
+      'func f(o Other) T {\n\tswitch o.Limit {\n\tcase Limit:\n\t}\n\treturn T{Limit: Limit + len("Limit")}\n}\n';
+
+    expect(renameIdentifiers(source, new Map([['Limit', 'limit']]))).toBe(
+      'type T struct {\n\tLimit int\n}\n\n' +
+      'func f(o Other) T {\n\tswitch o.Limit {\n\tcase limit:\n\t}\n\treturn T{Limit: limit + len("Limit")}\n}\n');
+  });
+});
+
+describe('API surface report', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('api-surface');
+    fs.cpSync(fixtureRoot, tempDir, { recursive: true });
+    const store = new ModuleManifestStore(tempDir);
+    for (const [module, file] of [['catalog', catalogFile], ['order', 'internal/order/order.go']]) {
+      store.save({
+        module,
+        attempt: 1,
+        updated_at: '2024-01-01T00:00:00.000Z',
+        files: [{ path: file, source: file, hash: '', symbols: [], generated_at: '2024-01-01T00:00:00.000Z' }],
+      });
+    }
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should classify exported symbols by their references outside the module', () => {
+    const report = new ApiSurfaceReporter(tempDir).build();
+    const catalog = report.modules.find(m => m.module === 'catalog')!;
+
+    expect(catalog.unexport.map(s => [s.name, s.rename_to]))
+      .toEqual([['DefaultLimit', 'defaultLimit'], ['MaxItems', 'maxItems'], ['ErrFull', 'errFull'], ['Validate', 'validate']]);
+    expect(catalog.single_consumer.map(s => [s.name, s.external_consumers]))
+      .toEqual([['Catalog', ['order']], ['NewCatalog', ['cmd/store']], ['Normalize', ['order']]]);
+    expect(catalog.kept.map(s => [s.name, s.kept])).toEqual([['Handler', 'reflection'], ['String', 'name-collision']]);
+    expect(catalog.public_api).toEqual([
+      'internal/catalog.Product',
+      'internal/catalog.Catalog',
+      'internal/catalog.NewCatalog',
+      'internal/catalog.Normalize',
+      'internal/catalog.Handler',
+      'internal/catalog.String',
+    ]);
+    expect(report.totals).toEqual({ exported: 12, unexport: 4, single_consumer: 5, public_after: 8 });
+  });
+
+  it('should write the JSON and HTML reports', () => {
+    const reporter = new ApiSurfaceReporter(tempDir);
+    const { jsonPath, htmlPath } = reporter.write(reporter.build());
+
+    expect(JSON.parse(fs.readFileSync(jsonPath, 'utf8')).totals.unexport).toBe(4);
+    expect(fs.readFileSync(htmlPath, 'utf8')).toContain('<code>internal/catalog.Validate</code>');
+  });
+
+  it('should roll back a module whose rewrite does not compile', async () => {
+    const reporter = new ApiSurfaceReporter(tempDir);
+    const original = fs.readFileSync(path.join(tempDir, catalogFile), 'utf8');
+
+    const result = await reporter.applyUnexports(reporter.build(), new FileSafetyManager(tempDir), () => 'undefined: Validate');
+
+    expect(result.failed).toEqual([{ module: 'catalog', error: 'undefined: Validate' }]);
+    expect(fs.readFileSync(path.join(tempDir, catalogFile), 'utf8')).toBe(original);
+  });
+
+  it.skipIf(!hasGo())('should apply the unexports and keep the project compiling', async () => {
+    const reporter = new ApiSurfaceReporter(tempDir);
+
+    const result = await reporter.applyUnexports(reporter.build(), new FileSafetyManager(tempDir));
+
+    expect(result.failed).toEqual([]);
+    const source = fs.readFileSync(path.join(tempDir, catalogFile), 'utf8');
+    expect(source).toContain('func validate(p Product) error {');
+    expect(source).toContain('errors.New("Validate: missing id")');
+    expect(reporter.build().totals.unexport).toBe(0);
+  });
+});
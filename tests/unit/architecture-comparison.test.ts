@@ -0,0 +1,112 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import type { ArchitecturalPlan, ModuleDesign } from '../../src/core/agents/architect-agent.js';
+import { compareArchitectures, renderArchitectureComparison } from '../../src/core/utils/architecture-comparison.js';
+import { ArchitectureStyle, planTargetFiles } from '../../src/core/utils/architecture-style.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const module = (name: string, style: ArchitectureStyle, overrides: Partial<ModuleDesign> = {}): ModuleDesign => {
+  const state = { files: [`internal/${name}/${name}.go`], lines_of_code: 0, test_coverage: 0, cyclomatic_complexity: 0, coupling_score: 0, cohesion_score: 0 };
+  return {
+    name,
+    description: `${name} context`,
+    current_state: state,
+    target_state: state,
+    refactoring_actions: [],
+    dependencies: [],
+    interfaces: [],
+    target_files: planTargetFiles(name, state.files, style),
+    ...overrides,
+  };
+};
+
+const plan = (style: ArchitectureStyle) => ({
+  modules: [
+    module('order', style, {
+      interfaces: [{ name: 'OrderService', purpose: '', methods: [], go_definition: '' }],
+      risk: { score: 40, tier: 'apply-with-review', components: [] },
+    }),
+    module('billing', style, { risk: { score: 80, tier: 'manual-only', components: [] } }),
+    module('legacy', style, { status: 'established' }),
+  ],
+  estimates: {
+    modules: [
+      { module: 'order', loc_moved: 0, cyclomatic_complexity: 0, call_sites: 0, effort_days: 3 },
+      { module: 'billing', loc_moved: 0, cyclomatic_complexity: 0, call_sites: 0, effort_days: 2.5 },
+    ],
+  },
+  risk_heatmap: {
+    history_commits: 10,
+    modules: [{ module: 'billing', churn: 5, complexity: 4, score: 20, heat: 100, level: 'hot', recommendation: 'manual' }],
+    files: [],
+  },
+} as unknown as ArchitecturalPlan);
+
+describe('Architecture comparison', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('architecture-comparison');
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), 'package order\n'.padEnd(400, '/'));
+    await createMockFile(path.join(tempDir, 'internal/billing/billing.go'), 'package billing\n'.padEnd(200, '/'));
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should measure the files, interfaces, tokens, cost and risk of each candidate', () => {
+    const comparison = compareArchitectures(tempDir, [
+      { style: 'clean', plan: plan('clean') },
+      { style: 'hexagonal', plan: plan('hexagonal') },
+    ]);
+
+    expect(comparison.model).toBe('sonnet');
+    expect(comparison.candidates.map(({ cost_usd, ...candidate }) => candidate)).toEqual([
+      {
+        style: 'clean',
+        modules: 2,
+        files_moved: 2,
+        files_created: 8,
+        packages_created: 10,
+        interfaces_created: 3,
+        tokens: { input: 3150, output: 2550 },
+        effort_days: 5.5,
+        risk_score: 60,
+        manual_only: 1,
+        hot_modules: 1,
+      },
+      {
+        style: 'hexagonal',
+        modules: 2,
+        files_moved: 2,
+        files_created: 10,
+        packages_created: 12,
+        interfaces_created: 5,
+        tokens: { input: 3150, output: 3150 },
+        effort_days: 5.5,
+        risk_score: 60,
+        manual_only: 1,
+        hot_modules: 1,
+      },
+    ]);
+    expect(comparison.candidates[0].cost_usd).toBeCloseTo(0.0477);
+    expect(comparison.candidates[1].cost_usd).toBeCloseTo(0.0567);
+  });
+
+  it('should render the candidates side by side with their differences', () => {
+    const comparison = compareArchitectures(tempDir, [
+      { style: 'clean', plan: plan('clean') },
+      { style: 'hexagonal', plan: plan('hexagonal') },
+    ]);
+    const report = renderArchitectureComparison(comparison, { clean: '.vibeflow/plan.clean.md', hexagonal: '.vibeflow/plan.hexagonal.md' });
+
+    expect(report).toContain('# アーキテクチャ比較 (Architecture Comparison)');
+    expect(report).toContain('| 指標 | clean | hexagonal |\n|------|------|------|\n');
+    expect(report).toContain('| 作成するインターフェース | 3 | 5 |');
+    expect(report).toContain('| 推定トークン (入力 / 出力) | 3,150 / 2,550 | 3,150 / 3,150 |');
+    expect(report).toContain('| 推定コスト (sonnet) | $0.05 | $0.06 |');
+    expect(report).toContain('- hexagonal は clean に比べて: 作成するファイル +2、パッケージ +2、インターフェース +2、出力トークン +600、推定コスト +$0.01');
+    expect(report).toContain('- hexagonal: `.vibeflow/plan.hexagonal.md`');
+  });
+});
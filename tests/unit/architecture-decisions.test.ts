@@ -0,0 +1,95 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import type { ModuleDesign } from '../../src/core/agents/architect-agent.js';
+import { collectDecisions, renderDecisionsSection, writeDecisionRecords } from '../../src/core/utils/architecture-decisions.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const module = (name: string, overrides: Partial<ModuleDesign> = {}): ModuleDesign => {
+  const state = { files: [`internal/${name}/${name}.go`], lines_of_code: 100, test_coverage: 0, cyclomatic_complexity: 5, coupling_score: 0.6, cohesion_score: 0.4 };
+  return {
+    name,
+    description: `${name} context`,
+    current_state: state,
+    target_state: state,
+    refactoring_actions: [],
+    dependencies: [],
+    interfaces: [],
+    ...overrides,
+  };
+};
+
+const modules = [
+  module('billing', {
+    dependencies: [{ module: 'order', type: 'interface', description: '' }],
+    refactoring_actions: [
+      {
+        type: 'extract_interface',
+        description: '依存サイクルを billing → order の呼び出し2箇所で切断: billing 側にインターフェースを定義し、order の実装をコンポジションルートで注入',
+        files_affected: ['internal/billing/invoice.go'],
+        priority: 'high',
+        effort_estimate: '3-5日',
+        decision: 'cut-cycle:billing->order',
+      },
+      { type: 'split_function', description: 'テストカバレッジ向上のための関数分割とテスト追加', files_affected: [], priority: 'medium', effort_estimate: '1-2週間' },
+    ],
+  }),
+  module('order', { merged_from: ['cart'] }),
+  module('legacy', {
+    status: 'established',
+    refactoring_actions: [{ type: 'introduce_event', description: 'x', files_affected: [], priority: 'high', effort_estimate: '', decision: 'introduce-event:legacy' }],
+  }),
+];
+
+describe('Architecture decision records', () => {
+  let tempDir: string;
+  let adrDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('architecture-decisions-test');
+    adrDir = path.join(tempDir, 'docs/adr');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should collect the decisions of actions and merges, leaving established modules out', () => {
+    const decisions = collectDecisions(modules, ['Merged order, cart (mustMerge)']);
+
+    expect(decisions.map(d => [d.key, d.kind])).toEqual([
+      ['cut-cycle:billing->order', 'extract_interface'],
+      ['merge:order', 'merge_modules'],
+    ]);
+    expect(decisions[0].title).toBe('billing: 依存サイクルを billing → order の呼び出し2箇所で切断');
+    expect(decisions[0].context).toContain('依存先: order');
+    expect(decisions[0].context).toContain('対象: internal/billing/invoice.go');
+    expect(decisions[1].context).toContain('境界制約による調整: Merged order, cart (mustMerge)');
+  });
+
+  it('should number new ADRs after the existing ones and never rewrite decided ones', async () => {
+    await createMockFile(path.join(adrDir, '0001-use-postgres.md'), '# 1. Use Postgres\n');
+    const first = writeDecisionRecords(tempDir, adrDir, collectDecisions(modules), new Date('2026-10-01T00:00:00Z'));
+
+    expect(first.added).toEqual(['cut-cycle:billing->order', 'merge:order']);
+    expect(first.records.map(r => [r.path, r.status])).toEqual([
+      ['docs/adr/0002-cut-cycle-billing-order.md', 'Proposed'],
+      ['docs/adr/0003-merge-order.md', 'Proposed'],
+    ]);
+    const cycle = fs.readFileSync(path.join(adrDir, '0002-cut-cycle-billing-order.md'), 'utf8');
+    expect(cycle).toContain('# 2. billing: 依存サイクルを billing → order の呼び出し2箇所で切断');
+    expect(cycle).toContain('Date: 2026-10-01');
+    expect(cycle).toContain('## Consequences');
+    expect(cycle).toContain('<!-- vf:adr cut-cycle:billing->order -->');
+
+    const merge = path.join(adrDir, '0003-merge-order.md');
+    fs.writeFileSync(merge, fs.readFileSync(merge, 'utf8').replace('## Status\n\nProposed', '## Status\n\nAccepted'));
+    const second = writeDecisionRecords(tempDir, adrDir, [], new Date('2026-10-17T00:00:00Z'));
+
+    expect(second.added).toEqual([]);
+    expect(second.deprecated).toEqual(['cut-cycle:billing->order']);
+    expect(second.records.map(r => r.status)).toEqual(['Deprecated', 'Accepted']);
+    expect(fs.readFileSync(path.join(adrDir, '0002-cut-cycle-billing-order.md'), 'utf8')).toContain('Deprecated\n\n2026-10-17: vf plan no longer proposes this decision.');
+    expect(renderDecisionsSection(second.records)).toContain('- `docs/adr/0003-merge-order.md` order: cart を統合 — Accepted');
+  });
+});
@@ -0,0 +1,139 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { attachFileCoupling, attachFilePackages } from '../../src/core/agents/enhanced-boundary-agent.js';
+import { DomainMap } from '../../src/core/types/config.js';
+import {
+  QUERY_SCHEMA_VERSION,
+  UnknownNameError,
+  formatQueryResult,
+  queryDeps,
+  queryFiles,
+  queryRoutes,
+  queryTables,
+} from '../../src/core/utils/architecture-query.js';
+import { loadGoPackages } from '../../src/core/utils/go-packages.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const domainMap = (): DomainMap => ({
+  project: 'shop',
+  language: 'go',
+  analyzed_at: '2026-01-01T00:00:00.000Z',
+  total_files: 4,
+  metrics: { overall_cohesion: 0.8, overall_coupling: 0.2, modularity_score: 0.7 },
+  boundaries: [
+    {
+      name: 'billing',
+      description: 'Invoices',
+      files: ['internal/billing/invoice.go'],
+      tables: ['invoices', 'orders'],
+      dependencies: { internal: ['order'] },
+      file_coupling: [{ file: 'internal/billing/invoice.go', boundary: 'order', references: 2 }],
+    },
+    {
+      name: 'order',
+      description: 'Order placement',
+      files: ['internal/order/order.go'],
+      tables: ['orders'],
+      apiEndpoints: ['POST /orders', 'GET /orders/{id}'],
+    },
+    {
+      name: 'user',
+      description: 'Accounts',
+      files: ['internal/user/profile.go', 'internal/user/user.go'],
+      dependencies: { internal: ['billing', 'order'] },
+      file_packages: [
+        { file: 'internal/user/profile.go', import_path: 'example.com/shop/internal/user', package: 'user' },
+        { file: 'internal/user/user.go', import_path: 'example.com/shop/internal/user', package: 'user' },
+      ],
+      file_coupling: [
+        { file: 'internal/user/profile.go', boundary: 'order', references: 1 },
+        { file: 'internal/user/user.go', boundary: 'billing', references: 3 },
+        { file: 'internal/user/user.go', boundary: 'order', references: 6 },
+      ],
+    },
+  ],
+});
+
+describe('architecture queries', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('architecture-query');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should list dependencies in both directions and coupled files', () => {
+    const incoming = queryDeps(domainMap(), { of: 'order', direction: 'in' });
+    expect(incoming).toEqual({
+      query: 'deps',
+      schema_version: QUERY_SCHEMA_VERSION,
+      params: { of: 'order', direction: 'in' },
+      columns: ['from', 'to', 'files', 'references'],
+      rows: [
+        { from: 'billing', to: 'order', files: 1, references: 2 },
+        { from: 'user', to: 'order', files: 2, references: 7 },
+      ],
+    });
+    expect(queryDeps(domainMap(), { of: 'user', direction: 'out' }).rows.map(r => r.to)).toEqual(['billing', 'order']);
+
+    expect(queryFiles(domainMap(), { boundary: 'user', to: 'order', minCoupling: 5 }).rows).toEqual([
+      { file: 'internal/user/user.go', package: 'example.com/shop/internal/user', coupling: 6, coupled_to: ['order'] },
+    ]);
+    expect(queryFiles(domainMap(), { boundary: 'user' }).rows.map(r => [r.file, r.coupling])).toEqual([
+      ['internal/user/user.go', 9],
+      ['internal/user/profile.go', 1],
+    ]);
+  });
+
+  it('should answer table and route queries and suggest close names', () => {
+    expect(queryTables(domainMap(), 'orders').rows).toEqual([{ table: 'orders', boundaries: ['billing', 'order'] }]);
+    expect(queryRoutes(domainMap(), 'order').rows).toEqual([
+      { module: 'order', method: 'POST', path: '/orders' },
+      { module: 'order', method: 'GET', path: '/orders/{id}' },
+    ]);
+
+    expect(() => queryTables(domainMap(), 'order')).toThrow("Unknown table 'order'. Did you mean: orders?");
+    expect(() => queryDeps(domainMap(), { of: 'biling', direction: 'in' })).toThrow("Unknown boundary 'biling'. Did you mean: billing?");
+    expect(() => queryRoutes(domainMap(), 'payments')).toThrow(UnknownNameError);
+    expect(() => queryRoutes(domainMap(), 'payments')).toThrow(/^Unknown boundary 'payments'$/);
+  });
+
+  it('should format rows as table, csv and versioned json', () => {
+    const result = queryTables(domainMap());
+
+    expect(formatQueryResult(result, 'table')).toBe([
+      'table     boundaries',
+      '--------  -------------',
+      'invoices  billing',
+      'orders    billing;order',
+      '',
+    ].join('\n'));
+    expect(formatQueryResult(result, 'csv')).toBe('table,boundaries\ninvoices,billing\norders,billing;order\n');
+    expect(JSON.parse(formatQueryResult(result, 'json'))).toMatchObject({ query: 'tables', schema_version: 1, rows: result.rows });
+  });
+
+  it('should record qualified references to other boundaries at discovery', async () => {
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), 'package order\n\ntype Order struct{}\n\nfunc Find(id string) Order { return Order{} }\n');
+    await createMockFile(path.join(tempDir, 'internal/user/user.go'), [
+      'package user',
+      '',
+      'import ord "example.com/shop/internal/order"',
+      '',
+      'func Latest(id string) ord.Order { return ord.Find(id) }',
+      '',
+    ].join('\n'));
+    const boundaries = [
+      { name: 'order', description: '', files: ['internal/order/order.go'] },
+      { name: 'user', description: '', files: ['internal/user/user.go'] },
+    ];
+    const packages = loadGoPackages(tempDir);
+
+    const [order, user] = attachFileCoupling(tempDir, attachFilePackages(tempDir, boundaries, packages), packages);
+    expect(order.file_coupling).toBeUndefined();
+    expect(user.file_coupling).toEqual([{ file: 'internal/user/user.go', boundary: 'order', references: 2 }]);
+  });
+});
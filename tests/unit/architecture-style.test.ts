@@ -0,0 +1,82 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { ClaudeCodeClient } from '../../src/core/utils/claude-code-client.js';
+import {
+  loadArchitectureStyle,
+  moduleDirectories,
+  parseArchitectureStyle,
+  renderArchitectureSection,
+} from '../../src/core/utils/architecture-style.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const prompt = 'File: legacy/order.go\n\n```go\npackage legacy\n\nfunc GetOrder(id string) {}\n\nfunc SaveOrder(o *Order) {}\n```\n\ninternal/order/';
+
+describe('Architecture style', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('architecture-style-test');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should read --style values and the style of plan.json', async () => {
+    expect(parseArchitectureStyle('Hexagonal')).toBe('hexagonal');
+    expect(() => parseArchitectureStyle('onion')).toThrow('expected clean or hexagonal');
+
+    expect(loadArchitectureStyle(tempDir)).toBe('clean');
+    await createMockFile(path.join(tempDir, '.vibeflow/plan.json'), JSON.stringify({ modules: [], architecture_style: 'hexagonal' }));
+    expect(loadArchitectureStyle(tempDir)).toBe('hexagonal');
+
+    expect(moduleDirectories('order', 'hexagonal')).toEqual([
+      'internal/order/',
+      'internal/order/domain/',
+      'internal/order/port/',
+      'internal/order/service/',
+      'internal/order/adapter/handler/',
+      'internal/order/adapter/repository/',
+      'internal/order/test/',
+    ]);
+    expect(renderArchitectureSection('clean', 'order')).toBe('');
+    expect(renderArchitectureSection('hexagonal', 'order')).toContain('`internal/order/port/`');
+  });
+
+  it('should generate port interfaces and adapters from the templates', async () => {
+    const client = new ClaudeCodeClient({ cwd: tempDir, maxTurns: 1, systemPrompt: '', generationMode: 'template', architectureStyle: 'hexagonal' });
+    const { text, generation } = await client.queryWithGeneration(prompt);
+    const result = client.extractJsonFromResult(text);
+    const file = (target: string) => [...result.refactored_files, ...result.interfaces].find(f => f.path === target)!.content;
+
+    expect(generation.template?.name).toBe('builtin/hexagonal');
+    expect(result.refactored_files.map(f => f.path)).toEqual([
+      'internal/order/domain/order.go',
+      'internal/order/service/order_service.go',
+      'internal/order/adapter/repository/order_repository.go',
+      'internal/order/adapter/handler/order_handler.go',
+    ]);
+    expect(result.interfaces.map(i => i.path)).toEqual(['internal/order/port/repository.go', 'internal/order/port/usecase.go']);
+
+    expect(file('internal/order/port/repository.go')).toContain('package port');
+    expect(file('internal/order/port/repository.go')).toContain('Save(ctx context.Context, entity *domain.Order) (*domain.Order, error)');
+    expect(file('internal/order/service/order_service.go')).toContain('package service');
+    expect(file('internal/order/service/order_service.go')).toContain('repo port.OrderRepository');
+    expect(file('internal/order/adapter/repository/order_repository.go')).toContain('package repository');
+    expect(file('internal/order/adapter/handler/order_handler.go')).toContain('useCase port.OrderUseCase');
+    expect(file('internal/order/adapter/handler/order_handler.go')).toContain('"order/internal/order/port"');
+  });
+
+  it('should keep the clean-architecture layers by default', async () => {
+    const client = new ClaudeCodeClient({ cwd: tempDir, maxTurns: 1, systemPrompt: '', generationMode: 'template' });
+    const result = client.extractJsonFromResult((await client.queryWithGeneration(prompt)).text);
+
+    expect(result.refactored_files.map(f => f.path)).toEqual([
+      'internal/order/domain/order.go',
+      'internal/order/usecase/order_service.go',
+      'internal/order/infrastructure/order_repository.go',
+      'internal/order/handler/order_handler.go',
+    ]);
+    expect(result.interfaces[0].content).toContain('package domain\n\nimport "context"');
+  });
+});
@@ -0,0 +1,52 @@
+import { describe, it, expect } from 'vitest';
+import { annotateManualOverlap, explainConfidence, withManualOverlap } from '../../src/core/utils/boundary-confidence.js';
+
+describe('boundary confidence', () => {
+  it('should weigh the components into the score and name the strong and weak ones', () => {
+    const breakdown = explainConfidence({ structural_cohesion: 0.9, naming_similarity: 0.2, data_ownership: 0.5 });
+
+    expect(breakdown).toEqual({
+      score: 0.59,
+      structural_cohesion: 0.9,
+      naming_similarity: 0.2,
+      data_ownership: 0.5,
+      rationale: '信頼度59%（中）: 構造的凝集度が高い (90%)、命名の類似度が低い (20%)。LLM による境界の妥当性判定は含まない。',
+    });
+  });
+
+  it('should lower the score for syntax-only files and say so', () => {
+    const breakdown = explainConfidence({ structural_cohesion: 1, naming_similarity: 1, data_ownership: 1, degraded_share: 0.5 });
+
+    expect(breakdown.score).toBe(0.85);
+    expect(breakdown.degraded_share).toBe(0.5);
+    expect(breakdown.rationale).toBe(
+      '信頼度85%（高）: 構造的凝集度が高い (100%)、命名の類似度が高い (100%)、データ所有が高い (100%)。構文のみで解析したファイルが50%あり減点。LLM による境界の妥当性判定は含まない。'
+    );
+  });
+
+  it('should record the overlap with the manual analysis without changing the score', () => {
+    const breakdown = withManualOverlap(explainConfidence({ structural_cohesion: 0.6, naming_similarity: 0.6, data_ownership: 0.6 }), 0.1);
+
+    expect(breakdown.score).toBe(0.6);
+    expect(breakdown.manual_overlap).toBe(0.1);
+    expect(breakdown.rationale).toBe('信頼度60%（中）: 手動分析の境界とファイルがほぼ重ならない (10%)。LLM による境界の妥当性判定は含まない。');
+    expect(explainConfidence({ structural_cohesion: 0.6, naming_similarity: 0.6, data_ownership: 0.6 }).rationale)
+      .toBe('信頼度60%（中）: 突出した根拠・懸念なし。LLM による境界の妥当性判定は含まない。');
+  });
+
+  it('should measure every discovered boundary against its closest manual boundary', () => {
+    const confidence_breakdown = explainConfidence({ structural_cohesion: 0.6, naming_similarity: 0.6, data_ownership: 0.6 });
+    const [order, audit] = annotateManualOverlap(
+      [
+        { name: 'order', files: ['order/order.go', 'order/cart.go', 'order/item.go', 'order/tax.go'], confidence_breakdown },
+        { name: 'audit', files: ['audit/log.go'], confidence_breakdown },
+      ],
+      [{ files: ['order/order.go', 'order/cart.go', 'order/item.go'] }, { files: ['user/user.go'] }]
+    );
+
+    expect(order.confidence_breakdown.manual_overlap).toBe(0.75);
+    expect(order.confidence_breakdown.score).toBe(0.6);
+    expect(order.confidence_breakdown.rationale).toBe('信頼度60%（中）: 手動分析の境界とファイルが重なる (75%)。LLM による境界の妥当性判定は含まない。');
+    expect(audit.confidence_breakdown.manual_overlap).toBe(0);
+  });
+});
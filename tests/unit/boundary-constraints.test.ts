@@ -0,0 +1,98 @@
+import { describe, it, expect } from 'vitest';
+import {
+  resolveConstraints,
+  evaluateConstraints,
+  domainBoundaryAdapter,
+  fileBelongsTo,
+  attributeFile,
+} from '../../src/core/utils/boundary-constraints.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+
+function boundary(name: string, files: string[], extra: Partial<DomainBoundary> = {}): DomainBoundary {
+  return {
+    name,
+    description: `${name} module`,
+    files,
+    dependencies: { internal: [], external: [] },
+    circular_dependencies: [],
+    ...extra,
+  };
+}
+
+describe('boundary constraints', () => {
+  it('should merge modules listed in mustMerge', () => {
+    const result = resolveConstraints(
+      [boundary('shipping', ['shipping.go']), boundary('user', ['user.go']), boundary('logistics', ['logistics.go'])],
+      { mustMerge: [['shipping', 'logistics']] },
+      domainBoundaryAdapter
+    );
+
+    expect(result.items.map(b => b.name)).toEqual(['shipping', 'user']);
+    expect(result.items[0].files).toEqual(['shipping.go', 'logistics.go']);
+    expect(result.items[0].merged_from).toEqual(['logistics']);
+    expect(result.violations).toHaveLength(0);
+  });
+
+  it('should split a module that mixes mustSeparate domains', () => {
+    const result = resolveConstraints(
+      [boundary('user', ['user/user.go', 'user/auth_handler.go'])],
+      { mustSeparate: [['auth', 'user']] },
+      domainBoundaryAdapter
+    );
+
+    expect(result.items.map(b => b.name)).toEqual(['user', 'auth']);
+    expect(result.items[1].files).toEqual(['user/auth_handler.go']);
+    expect(result.violations).toHaveLength(0);
+  });
+
+  it('should report mustSeparate as a violation when a cycle makes it inseparable', () => {
+    const result = resolveConstraints(
+      [boundary('user', ['user.go', 'auth.go'], { circular_dependencies: ['user.go → auth.go → user.go'] })],
+      { mustSeparate: [['auth', 'user']] },
+      domainBoundaryAdapter
+    );
+
+    expect(result.items).toHaveLength(1);
+    expect(result.violations).toHaveLength(1);
+    expect(result.violations[0].constraint).toBe('mustSeparate');
+    expect(result.violations[0].reason).toContain('circular dependency');
+  });
+
+  it('should merge the smallest modules until maxModules is met without breaking mustSeparate', () => {
+    const result = resolveConstraints(
+      [
+        boundary('auth', ['auth.go']),
+        boundary('user', ['user.go', 'profile.go']),
+        boundary('order', ['order.go', 'cart.go', 'checkout.go']),
+      ],
+      { maxModules: 2, mustSeparate: [['auth', 'user']] },
+      domainBoundaryAdapter
+    );
+
+    expect(result.items).toHaveLength(2);
+    expect(result.items.find(b => b.name === 'order')!.merged_from).toEqual(['auth']);
+  });
+
+  it('should flag forbidden dependencies and table combinations', () => {
+    const modules = [
+      boundary('order', ['order.go'], { dependencies: { internal: ['payment.go'] }, tables: ['orders', 'payments'] }),
+      boundary('payment', ['payment.go']),
+    ];
+    const violations = evaluateConstraints(
+      modules.map(m => domainBoundaryAdapter.toConstrained(m, modules)),
+      {
+        forbiddenDependencies: [['order', 'payment']],
+        forbiddenTableCombinations: [['orders', 'payments']],
+      }
+    );
+
+    expect(violations.map(v => v.constraint)).toEqual(['forbiddenDependencies', 'forbiddenTableCombinations']);
+  });
+
+  it('should attribute files to module names by path token', () => {
+    expect(fileBelongsTo('internal/users/handler.go', 'user')).toBe(true);
+    expect(fileBelongsTo('user_service.go', 'user')).toBe(true);
+    expect(fileBelongsTo('superuser.go', 'user')).toBe(false);
+    expect(attributeFile('user/auth_handler.go', ['auth', 'user'])).toBe('auth');
+  });
+});
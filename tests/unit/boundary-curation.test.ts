@@ -0,0 +1,176 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  BoundaryEditSession,
+  applyCuratedModules,
+  curateModules,
+  writeCuration,
+} from '../../src/core/utils/boundary-curation.js';
+import { parseBoundaryConfig } from '../../src/core/utils/input-parsers.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+function boundary(id: string, name: string, files: string[], extra: Partial<DomainBoundary> = {}): DomainBoundary {
+  return { id, name, description: '', files, ...extra };
+}
+
+const discovered: DomainBoundary[] = [
+  boundary('user-1', 'user', ['internal/user/user.go', 'internal/user/store.go', 'internal/user/invoice.go'], {
+    file_coupling: [{ file: 'internal/user/invoice.go', boundary: 'billing', references: 2 }],
+  }),
+  boundary('billing-1', 'billing', ['internal/billing/invoice.go', 'internal/billing/payment.go'], {
+    dependencies: { internal: ['user'] },
+  }),
+  boundary('report-1', 'report', ['internal/report/report.go', 'internal/report/export/csv.go']),
+  boundary('auth-1', 'auth', ['auth/auth.go'], { status: 'established' }),
+];
+
+const names = (boundaries: DomainBoundary[]) =>
+  Object.fromEntries(boundaries.map(b => [b.name, [...b.files].sort()]));
+
+function edit(...commands: string[]): BoundaryEditSession {
+  const session = new BoundaryEditSession('/work/shop', discovered);
+  commands.forEach(command => session.execute(command));
+  return session;
+}
+
+describe('boundary curation', () => {
+  it('should move files and directories between boundaries with their per-file entries', () => {
+    const session = edit('move billing internal/user/invoice.go', 'move exports internal/report/export/');
+
+    expect(names(session.boundaries)).toEqual({
+      user: ['internal/user/store.go', 'internal/user/user.go'],
+      billing: ['internal/billing/invoice.go', 'internal/billing/payment.go', 'internal/user/invoice.go'],
+      report: ['internal/report/report.go'],
+      auth: ['auth/auth.go'],
+      exports: ['internal/report/export/csv.go'],
+    });
+    expect(session.boundaries.find(b => b.name === 'user')!.file_coupling).toEqual([]);
+    // Coupling to the boundary the file moved into is internal now
+    expect(session.boundaries.find(b => b.name === 'billing')!.file_coupling).toEqual([]);
+  });
+
+  it('should rename, merge and split modules and undo the last change', () => {
+    const session = edit('rename billing payments', 'merge payments report', 'split user accounts internal/user/store.go');
+
+    expect(names(session.boundaries)).toEqual({
+      user: ['internal/user/invoice.go', 'internal/user/user.go'],
+      payments: ['internal/billing/invoice.go', 'internal/billing/payment.go', 'internal/report/export/csv.go', 'internal/report/report.go'],
+      auth: ['auth/auth.go'],
+      accounts: ['internal/user/store.go'],
+    });
+    const payments = session.boundaries.find(b => b.name === 'payments')!;
+    expect(payments.id).toBe('billing-1');
+    expect(payments.merged_from).toEqual(['report']);
+    expect(session.boundaries.find(b => b.name === 'user')!.file_coupling).toEqual([
+      { file: 'internal/user/invoice.go', boundary: 'payments', references: 2 },
+    ]);
+
+    session.execute('undo');
+    expect(session.boundaries.map(b => b.name)).not.toContain('accounts');
+    expect(session.changed).toBe(true);
+  });
+
+  it('should reject unknown modules and files, established modules and unsaved quits', () => {
+    const session = edit();
+
+    expect(() => session.execute('show missing')).toThrow('no module missing');
+    expect(() => session.execute('move user internal/missing.go')).toThrow('no file matches internal/missing.go');
+    expect(() => session.execute('rename user billing')).toThrow('module billing already exists');
+    expect(() => session.execute('merge user auth')).toThrow('auth is an established module');
+    expect(() => session.execute('split report all internal/report')).toThrow('use rename');
+    expect(() => session.execute('frobnicate')).toThrow("unknown command 'frobnicate'");
+    expect(session.changed).toBe(false);
+    expect(session.execute('quit').exit).toBe('quit');
+
+    session.execute('rename report reporting');
+    expect(session.execute('quit').exit).toBeUndefined();
+    expect(session.execute('quit').exit).toBe('quit');
+  });
+
+  it('should record renames by ID and pin the files that changed boundary', () => {
+    const edited = edit('rename billing payments', 'move payments internal/user/invoice.go', 'split report exports internal/report/export').boundaries;
+    const { modules, renames } = curateModules({
+      billing: { owns_tables: ['invoices'] },
+      report: { files: ['internal/user/invoice.go'] },
+    }, discovered, edited);
+
+    expect(modules).toEqual({
+      payments: { id: 'billing-1', owns_tables: ['invoices'], files: ['internal/user/invoice.go'] },
+      exports: { files: ['internal/report/export/csv.go'] },
+    });
+    expect(renames).toEqual(new Map([['billing', 'payments']]));
+  });
+
+  it('should reproduce the edits on a new discovery', () => {
+    const edited = edit('rename billing payments', 'merge payments report', 'split user accounts internal/user/store.go').boundaries;
+    const { modules } = curateModules({}, discovered, edited);
+
+    const result = applyCuratedModules('/work/shop', discovered, {
+      ...modules,
+      legacy: { files: ['internal/legacy/old.go'] },
+    });
+
+    expect(names(result.boundaries)).toEqual(names(edited));
+    expect(result.renamed).toEqual(['billing → payments']);
+    expect(result.moved.sort()).toEqual(['internal/report/export/csv.go', 'internal/report/report.go', 'internal/user/store.go']);
+    expect(result.missing).toEqual(['internal/legacy/old.go']);
+  });
+
+  it('should match absolute paths of a discovery in progress', () => {
+    const absolute = discovered.map(b => ({ ...b, files: b.files.map(file => `/work/shop/${file}`) }));
+    const result = applyCuratedModules('/work/shop', absolute, { billing: { files: ['internal/user/invoice.go'] } });
+
+    expect(result.boundaries.find(b => b.name === 'billing')!.files).toContain('/work/shop/internal/user/invoice.go');
+  });
+
+  describe('boundary.yaml', () => {
+    let tempDir: string;
+
+    beforeEach(async () => {
+      tempDir = await createTempDir('boundary-curation');
+    });
+
+    afterEach(async () => {
+      await cleanupTempDir(tempDir);
+    });
+
+    it('should keep the other settings and rename the modules constraints name', async () => {
+      await createMockFile(path.join(tempDir, 'boundary.yaml'), [
+        'modules:',
+        '  billing:',
+        '    owns_tables: [invoices]',
+        'constraints:',
+        '  mustSeparate:',
+        '    - [billing, user]',
+        '  forbiddenDependencies:',
+        '    - [report, billing]',
+        'clustering:',
+        '  algorithm: leiden',
+        '',
+      ].join('\n'));
+      const edited = edit('rename billing payments', 'move payments internal/user/invoice.go').boundaries;
+
+      const file = writeCuration(tempDir, discovered, edited);
+      const config = parseBoundaryConfig(fs.readFileSync(file, 'utf8'), file);
+
+      expect(config.modules).toEqual({
+        payments: { id: 'billing-1', owns_tables: ['invoices'], files: ['internal/user/invoice.go'] },
+      });
+      expect(config.constraints).toEqual({
+        mustSeparate: [['payments', 'user']],
+        forbiddenDependencies: [['report', 'payments']],
+      });
+      expect(config.clustering).toEqual({ algorithm: 'leiden' });
+    });
+
+    it('should create boundary.yaml when there is none', () => {
+      const file = writeCuration(tempDir, discovered, edit('split report exports internal/report/export').boundaries);
+
+      expect(parseBoundaryConfig(fs.readFileSync(file, 'utf8'), file).modules).toEqual({
+        exports: { files: ['internal/report/export/csv.go'] },
+      });
+    });
+  });
+});
@@ -0,0 +1,67 @@
+import { describe, it, expect } from 'vitest';
+import { findBoundaryCycles, renderBoundaryCyclesSection } from '../../src/core/utils/boundary-cycles.js';
+import { CallEdge } from '../../src/core/utils/call-graph.js';
+
+const boundaries = [
+  { name: 'billing', files: ['internal/billing/invoice.go'] },
+  { name: 'order', files: ['internal/order/service.go', 'internal/order/repository.go'] },
+  { name: 'shipping', files: ['internal/shipping/shipment.go'] },
+  { name: 'user', files: ['internal/user/user.go'] },
+];
+
+const edge = (caller_file: string, caller: string, callee_file: string, callee: string, count = 1, types: { caller_type?: string; callee_type?: string } = {}): CallEdge =>
+  ({ caller_file, caller, callee_file, callee, count, ...types });
+
+const edges: CallEdge[] = [
+  // order ⇄ billing: 3 calls one way, 1 query back
+  edge('internal/order/service.go', 'Checkout', 'internal/billing/invoice.go', 'CreateInvoice', 2, { caller_type: 'Service' }),
+  edge('internal/order/service.go', 'Cancel', 'internal/billing/invoice.go', 'VoidInvoice', 1, { caller_type: 'Service' }),
+  edge('internal/billing/invoice.go', 'Issue', 'internal/order/repository.go', 'FindOrder', 1, { callee_type: 'Repository' }),
+  // order → shipping → billing → order
+  edge('internal/order/service.go', 'Checkout', 'internal/shipping/shipment.go', 'Schedule', 4, { caller_type: 'Service' }),
+  edge('internal/shipping/shipment.go', 'Deliver', 'internal/billing/invoice.go', 'MarkDelivered', 3),
+  // No cycle through user
+  edge('internal/order/service.go', 'Checkout', 'internal/user/user.go', 'GetUser'),
+  // Calls within a boundary are not edges
+  edge('internal/order/service.go', 'Checkout', 'internal/order/repository.go', 'SaveOrder', 5),
+];
+
+describe('Boundary cycles', () => {
+  it('should list each cycle with its call sites and cut the edge with the fewest calls', () => {
+    const cycles = findBoundaryCycles(edges, boundaries);
+
+    expect(cycles.map(c => [c.modules, c.break_at])).toEqual([
+      [['billing', 'order'], { from: 'billing', to: 'order', calls: 1, remedy: 'interface' }],
+      [['billing', 'order', 'shipping'], { from: 'billing', to: 'order', calls: 1, remedy: 'interface' }],
+    ]);
+    expect(cycles[0].edges[1]).toEqual({
+      from: 'order',
+      to: 'billing',
+      calls: 3,
+      sites: [
+        { caller_file: 'internal/order/service.go', caller: 'Service.Checkout', callee_file: 'internal/billing/invoice.go', callee: 'CreateInvoice', count: 2 },
+        { caller_file: 'internal/order/service.go', caller: 'Service.Cancel', callee_file: 'internal/billing/invoice.go', callee: 'VoidInvoice', count: 1 },
+      ],
+    });
+  });
+
+  it('should replace a command edge with an event', () => {
+    const cycles = findBoundaryCycles([
+      edge('internal/order/service.go', 'Checkout', 'internal/shipping/shipment.go', 'Schedule', 2),
+      edge('internal/shipping/shipment.go', 'Deliver', 'internal/order/service.go', 'MarkShipped'),
+    ], boundaries);
+
+    expect(cycles).toHaveLength(1);
+    expect(cycles[0].break_at).toEqual({ from: 'shipping', to: 'order', calls: 1, remedy: 'event' });
+  });
+
+  it('should render the cut edge of each cycle for the plan', () => {
+    const section = renderBoundaryCyclesSection(findBoundaryCycles(edges, boundaries));
+
+    expect(section).toContain('## 依存サイクル');
+    expect(section).toContain('- billing → order → billing: **billing → order** を切る（1箇所、billing 側にインターフェースを定義して依存を反転）');
+    expect(section).toContain('  - internal/billing/invoice.go Issue → internal/order/repository.go Repository.FindOrder');
+    expect(renderBoundaryCyclesSection([])).toBe('');
+    expect(findBoundaryCycles([], boundaries)).toEqual([]);
+  });
+});
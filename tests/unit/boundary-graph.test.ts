@@ -0,0 +1,98 @@
+import { describe, it, expect } from 'vitest';
+import { buildBoundaryGraph, renderBoundaryGraph } from '../../src/core/utils/boundary-graph.js';
+import { DomainBoundary, DomainMap } from '../../src/core/types/config.js';
+
+function boundary(name: string, files: string[], extra: Partial<DomainBoundary> = {}): DomainBoundary {
+  return { name, description: '', files, dependencies: { internal: [], external: [] }, circular_dependencies: [], ...extra };
+}
+
+const map: DomainMap = {
+  project: 'shop',
+  language: 'go',
+  analyzed_at: '2026-01-01T00:00:00.000Z',
+  total_files: 6,
+  boundaries: [
+    boundary('order', ['order/order.go', 'order/pricing.go', 'order/repository.go'], {
+      cohesion_score: 0.6667,
+      dependencies: { internal: ['user', 'order'], external: [] },
+      file_coupling: [
+        { file: 'order/order.go', boundary: 'user', references: 3 },
+        { file: 'order/pricing.go', boundary: 'user', references: 1 },
+        { file: 'order/pricing.go', boundary: 'billing "v2"', references: 6 },
+        { file: 'order/pricing.go', boundary: 'removed', references: 9 },
+      ],
+    }),
+    boundary('user', ['user/user.go', 'user/store.go'], { cohesion_score: 1, coupling_score: 0 }),
+    boundary('billing "v2"', ['billing/invoice.go'], {
+      status: 'established',
+      dependencies: { internal: ['user'], external: [] },
+    }),
+  ],
+  metrics: { overall_cohesion: 0.8, overall_coupling: 0.2, modularity_score: 0.6 },
+};
+
+describe('boundary graph', () => {
+  it('should weight dependencies with the file references behind them', () => {
+    expect(buildBoundaryGraph(map)).toEqual({
+      nodes: [
+        { name: 'billing "v2"', files: 1, established: true },
+        { name: 'order', files: 3, cohesion: 0.67, established: false },
+        { name: 'user', files: 2, cohesion: 1, coupling: 0, established: false },
+      ],
+      edges: [
+        { from: 'billing "v2"', to: 'user', references: 0, files: 0 },
+        { from: 'order', to: 'billing "v2"', references: 6, files: 1 },
+        { from: 'order', to: 'user', references: 4, files: 2 },
+      ],
+    });
+  });
+
+  it('should render Graphviz DOT', () => {
+    expect(renderBoundaryGraph(buildBoundaryGraph(map), 'dot')).toBe([
+      'digraph boundaries {',
+      '  rankdir=LR;',
+      '  node [shape=box, style=rounded];',
+      '  "billing \\"v2\\"" [label="billing \\"v2\\"\\n1 files\\nestablished", style="rounded,dashed"];',
+      '  "order" [label="order\\n3 files\\ncohesion 0.67"];',
+      '  "user" [label="user\\n2 files\\ncohesion 1"];',
+      '  "billing \\"v2\\"" -> "user" [label="0", weight=1, penwidth=1.0];',
+      '  "order" -> "billing \\"v2\\"" [label="6", weight=6, penwidth=5.0];',
+      '  "order" -> "user" [label="4", weight=4, penwidth=3.7];',
+      '}',
+      '',
+    ].join('\n'));
+  });
+
+  it('should render Mermaid with ids safe for any module name', () => {
+    expect(renderBoundaryGraph(buildBoundaryGraph(map), 'mermaid')).toBe([
+      'graph LR',
+      '  m0_billing__v2_["billing #quot;v2#quot;<br/>1 files<br/>established"]:::established',
+      '  m1_order["order<br/>3 files<br/>cohesion 0.67"]',
+      '  m2_user["user<br/>2 files<br/>cohesion 1"]',
+      '  m0_billing__v2_ -->|0| m2_user',
+      '  m1_order -->|6| m0_billing__v2_',
+      '  m1_order -->|4| m2_user',
+      '  classDef established stroke-dasharray: 5 5',
+      '',
+    ].join('\n'));
+  });
+
+  it('should render GraphML with typed attributes', () => {
+    const graphml = renderBoundaryGraph(buildBoundaryGraph(map), 'graphml');
+
+    expect(graphml).toContain('<graph id="boundaries" edgedefault="directed">');
+    expect(graphml).toContain([
+      '    <node id="order">',
+      '      <data key="files">3</data>',
+      '      <data key="cohesion">0.67</data>',
+      '      <data key="established">false</data>',
+      '    </node>',
+    ].join('\n'));
+    expect(graphml).toContain([
+      '    <edge source="order" target="billing &quot;v2&quot;">',
+      '      <data key="references">6</data>',
+      '      <data key="source_files">1</data>',
+      '    </edge>',
+    ].join('\n'));
+  });
+});
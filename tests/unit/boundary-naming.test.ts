@@ -0,0 +1,96 @@
+import { describe, it, expect } from 'vitest';
+import { glossaryMatch, isGenericBoundaryName, loadGlossary, nameBoundaries, namingEvidence, parseProposedName } from '../../src/core/utils/boundary-naming.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+
+const fixtureRoot = './tests/fixtures/boundary-naming';
+
+function boundary(name: string, files: string[], extra: Partial<DomainBoundary> = {}): DomainBoundary {
+  return { name, description: '', files, ...extra };
+}
+
+const billingFiles = ['internal/cluster1/invoice.go', 'internal/cluster1/payment.go'];
+const shippingFiles = ['internal/cluster2/shipment.go'];
+
+describe('Boundary naming', () => {
+  it.each([
+    ['module_3', true],
+    ['cluster-2', true],
+    ['service', true],
+    ['handlers', true],
+    ['order', false],
+    ['order_service', false],
+  ])('should tell whether %s is generic', (name, generic) => {
+    expect(isGenericBoundaryName(name)).toBe(generic);
+  });
+
+  it('should load terms, aliases and plain string entries of a glossary', () => {
+    expect(loadGlossary(fixtureRoot, 'glossary.yaml')).toEqual([
+      { term: 'billing', aliases: ['invoice', 'payment'], description: 'Charging customers for their orders' },
+      { term: 'fulfillment', aliases: ['shipment'] },
+      { term: 'catalog', aliases: [] },
+    ]);
+    expect(() => loadGlossary(fixtureRoot, 'missing.yaml')).toThrow(/Cannot read glossary missing\.yaml/);
+  });
+
+  it('should match the glossary term the identifiers and comments use', () => {
+    const glossary = loadGlossary(fixtureRoot, 'glossary.yaml');
+    const evidence = namingEvidence(fixtureRoot, billingFiles);
+
+    expect(evidence.words[0]).toEqual(['invoice', 3]);
+    expect(glossaryMatch(evidence, glossary)).toEqual({ term: 'billing', matches: 8 });
+    expect(glossaryMatch(namingEvidence(fixtureRoot, shippingFiles), [{ term: 'catalog', aliases: [] }])).toBeUndefined();
+  });
+
+  it('should name generic boundaries from the glossary and remap dependencies', async () => {
+    const named = await nameBoundaries([
+      boundary('module_1', billingFiles, { dependencies: { internal: ['module_2'] } }),
+      boundary('module_2', shippingFiles),
+      boundary('orders', ['internal/cluster2/shipment.go'], { dependencies: { internal: ['module_1'] } }),
+    ], { projectRoot: fixtureRoot, glossary: loadGlossary(fixtureRoot, 'glossary.yaml') });
+
+    expect(named.map(b => [b.name, b.naming?.source, b.naming?.previous])).toEqual([
+      ['billing', 'glossary', 'module_1'],
+      ['fulfillment', 'glossary', 'module_2'],
+      ['orders', undefined, undefined],
+    ]);
+    expect(named[0].dependencies?.internal).toEqual(['fulfillment']);
+    expect(named[2].dependencies?.internal).toEqual(['billing']);
+  });
+
+  it('should fall back to the most frequent identifier word without a glossary', async () => {
+    const named = await nameBoundaries([
+      boundary('module_1', billingFiles),
+      boundary('cluster_2', shippingFiles),
+      boundary('module_3', ['internal/cluster2/shipment.go'], { status: 'established' }),
+    ], { projectRoot: fixtureRoot });
+
+    expect(named.map(b => b.name)).toEqual(['invoice', 'shipment', 'module_3']);
+    expect(named[0].naming).toEqual({ source: 'identifiers', previous: 'module_1', rationale: '識別子の 3件に含まれる「invoice」' });
+  });
+
+  it('should only accept LLM names resolving to a glossary term', async () => {
+    const glossary = loadGlossary(fixtureRoot, 'glossary.yaml');
+    const prompts: string[] = [];
+    const replies = [
+      '{"name": "Invoice", "rationale": "Types model invoices."}',
+      '{"name": "logistics", "rationale": "Ships orders."}',
+    ];
+
+    const named = await nameBoundaries([boundary('module_1', billingFiles), boundary('module_2', shippingFiles)], {
+      projectRoot: fixtureRoot,
+      glossary,
+      namer: async prompt => {
+        prompts.push(prompt);
+        return replies.shift() ?? null;
+      },
+    });
+
+    expect(prompts[0]).toContain('- billing (also: invoice, payment): Charging customers for their orders');
+    expect(named.map(b => [b.name, b.naming?.source, b.naming?.rationale])).toEqual([
+      ['billing', 'llm', 'Types model invoices.'],
+      ['fulfillment', 'glossary', '用語集の「fulfillment」に一致する識別子・コメント 3件'],
+    ]);
+    expect(parseProposedName('{"name": "service"}', [])).toBeNull();
+    expect(parseProposedName('Sure: {"name": "order-history"}', [])).toEqual({ name: 'order_history', rationale: '' });
+  });
+});
@@ -0,0 +1,92 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { EnhancedBoundaryAgent } from '../../src/core/agents/enhanced-boundary-agent.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const BILLING = `package billing
+
+type Invoice struct {
+	ID     string
+	Amount int
+}
+
+func Issue(id string, amount int) *Invoice {
+	return &Invoice{ID: id, Amount: amount}
+}
+`;
+
+const REFUND = `package billing
+
+func Refund(invoice *Invoice) int {
+	return -invoice.Amount
+}
+`;
+
+const ORDER = `package order
+
+type Order struct {
+	ID    string
+	Items []string
+}
+
+func Place(id string, items []string) *Order {
+	return &Order{ID: id, Items: items}
+}
+`;
+
+describe('boundary.yaml seeds', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('boundary-seeds');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/billing/invoice.go'), BILLING);
+    await createMockFile(path.join(tempDir, 'internal/billing/refund.go'), REFUND);
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), ORDER);
+    await createMockFile(path.join(tempDir, 'boundary.yaml'), [
+      'modules:',
+      '  payments:',
+      '    seeds:',
+      '      - example.com/shop/internal/billing',
+      '',
+    ].join('\n'));
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should resolve an import path seed to its files and pin them to the seeded module', async () => {
+    const { domainMap } = await new EnhancedBoundaryAgent(tempDir).analyzeBoundaries();
+
+    const payments = domainMap.boundaries.find(b => b.name === 'payments');
+    expect(payments?.files).toContain('internal/billing/invoice.go');
+    expect(payments?.files).toContain('internal/billing/refund.go');
+    const others = domainMap.boundaries.filter(b => b.name !== 'payments').flatMap(b => b.files);
+    expect(others).not.toContain('internal/billing/invoice.go');
+    expect(others).not.toContain('internal/billing/refund.go');
+  });
+
+  it('should keep seeded modules whose confidence is below 0.5', async () => {
+    // Two unrelated functions: no naming similarity pulls the module's confidence below 0.5
+    await createMockFile(path.join(tempDir, 'internal/legacy/stock.go'), 'package legacy\n\nfunc Reserve() {}\n');
+    await createMockFile(path.join(tempDir, 'internal/legacy/mail.go'), 'package legacy\n\nfunc Notify() {}\n');
+    await createMockFile(path.join(tempDir, 'boundary.yaml'), [
+      'modules:',
+      '  legacy:',
+      '    seeds:',
+      '      - internal/legacy',
+      '',
+    ].join('\n'));
+
+    const { autoDiscoveredBoundaries, domainMap } = await new EnhancedBoundaryAgent(tempDir).analyzeBoundaries();
+
+    const legacy = autoDiscoveredBoundaries.find(b => b.name === 'legacy');
+    expect(legacy?.confidence).toBeLessThan(0.5);
+    expect(domainMap.boundaries.find(b => b.name === 'legacy')?.files)
+      .toEqual(['internal/legacy/mail.go', 'internal/legacy/stock.go']);
+    for (const other of autoDiscoveredBoundaries.filter(b => b.name !== 'legacy')) {
+      expect(other.confidence).toBeGreaterThan(0.5);
+    }
+  });
+});
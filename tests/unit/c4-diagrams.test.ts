@@ -0,0 +1,81 @@
+import { describe, it, expect } from 'vitest';
+import type { ArchitecturalPlan, ModuleDesign } from '../../src/core/agents/architect-agent.js';
+import { buildC4Model, renderC4Diagrams } from '../../src/core/utils/c4-diagrams.js';
+
+const module = (name: string, overrides: Partial<ModuleDesign> = {}): ModuleDesign => {
+  const state = { files: [], lines_of_code: 0, test_coverage: 0, cyclomatic_complexity: 0, coupling_score: 0, cohesion_score: 0 };
+  return {
+    name,
+    description: `${name} context`,
+    current_state: state,
+    target_state: state,
+    refactoring_actions: [],
+    dependencies: [],
+    interfaces: [],
+    ...overrides,
+  };
+};
+
+const plan = {
+  modules: [
+    module('order', {
+      owned_tables: ['orders'],
+      dependencies: [
+        { module: 'billing', type: 'interface', description: '' },
+        { module: 'notify', type: 'interface', description: '' },
+      ],
+    }),
+    module('billing', { owned_tables: ['invoices'] }),
+    module('notify', { owned_tables: ['notifications'], deployment: 'service' }),
+    module('legacy', { status: 'established' }),
+  ],
+  async_edges: [{ topic: 'order.placed', broker: 'kafka', from: 'order', to: 'notify', files: [] }],
+} as unknown as ArchitecturalPlan;
+
+describe('C4 diagrams', () => {
+  it('should model the monolith, services, databases and brokers of the plan', () => {
+    const model = buildC4Model(plan, 'shop');
+
+    expect(model.containers.map(c => [c.id, c.kind])).toEqual([
+      ['monolith', 'container'],
+      ['svc_notify', 'container'],
+      ['monolith_db', 'database'],
+      ['svc_notify_db', 'database'],
+      ['broker_kafka', 'queue'],
+    ]);
+    expect(model.components.filter(c => c.parent === 'monolith').map(c => c.name)).toEqual(['billing', 'legacy', 'order']);
+    expect(model.components.filter(c => c.parent === 'svc_notify').map(c => c.name)).toEqual(['handler', 'usecase', 'infrastructure']);
+    expect(model.relations).toContainEqual({ from: 'mod_order', to: 'mod_billing', label: 'Uses', technology: 'in-process' });
+    expect(model.relations).toContainEqual({ from: 'mod_order', to: 'svc_notify_handler', label: 'Calls', technology: 'gRPC' });
+    expect(model.relations).toContainEqual({ from: 'svc_notify_persistence', to: 'svc_notify_db', label: 'Reads from and writes to', technology: 'SQL' });
+    expect(model.relations).toContainEqual({ from: 'svc_notify_handler', to: 'broker_kafka', label: 'Subscribes to order.placed', technology: 'kafka' });
+
+    const hexagonal = buildC4Model({ ...plan, architecture_style: 'hexagonal' }, 'shop');
+    expect(hexagonal.components.filter(c => c.parent === 'svc_notify').map(c => c.name)).toEqual(['adapter/handler', 'service', 'adapter/repository']);
+  });
+
+  it('should render C4-PlantUML container and component diagrams', () => {
+    const diagrams = renderC4Diagrams(buildC4Model(plan, 'shop'), 'plantuml');
+
+    expect(diagrams.map(d => d.name)).toEqual(['containers', 'components-monolith', 'components-notify']);
+    const [containers, monolith] = diagrams.map(d => d.content);
+    expect(containers).toContain('!include <C4/C4_Container>');
+    expect(containers).toContain('ContainerDb(svc_notify_db, "notify database", "SQL", "Tables: notifications")');
+    expect(containers).toContain('Rel(monolith, svc_notify, "Calls", "gRPC")');
+    expect(containers).not.toContain('Rel(monolith, monolith,');
+    expect(monolith).toContain('Component_Ext(mod_legacy, "legacy", "Go module", "legacy context")');
+    expect(monolith).toContain('Rel(mod_order, svc_notify, "Calls", "gRPC")');
+    expect(monolith.trimEnd().endsWith('@enduml')).toBe(true);
+  });
+
+  it('should render a Structurizr workspace with one view per level', () => {
+    const [workspace] = renderC4Diagrams(buildC4Model(plan, 'shop'), 'structurizr');
+
+    expect(workspace.name).toBe('workspace');
+    expect(workspace.content).toContain('system = softwareSystem "shop" {');
+    expect(workspace.content).toContain('mod_legacy = component "legacy" "legacy context" "Go module" "Established"');
+    expect(workspace.content).toContain('broker_kafka = container "Message broker (kafka)" "Topics: order.placed" "kafka" "Queue"');
+    expect(workspace.content).toContain('mod_order -> svc_notify_handler "Calls" "gRPC"');
+    expect(workspace.content).toContain('component svc_notify "Components-svc_notify" {');
+  });
+});
@@ -0,0 +1,133 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  CallGraphIndex,
+  buildCallGraph,
+  callCoupling,
+  parseCallEdges,
+  ssaFunctionName,
+  syntacticCallGraph,
+} from '../../src/core/utils/call-graph.js';
+import { GoFunction } from '../../src/core/utils/ast-analyzer.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+function fn(name: string, file: string, calls: string[], receiver?: string): GoFunction {
+  return {
+    type: 'function',
+    name,
+    file,
+    line: 1,
+    dependencies: [],
+    receiver,
+    parameters: [],
+    returnType: 'void',
+    calls,
+    function_values: [],
+    tables_accessed: [],
+  };
+}
+
+const root = '/work/shop';
+const OUTPUT = [
+  `${root}/internal/service/order.go\t(*example.com/shop/internal/service.OrderService).Place\t${root}/internal/service/pricing.go\texample.com/shop/internal/service.Price`,
+  `${root}/internal/service/order.go\t(*example.com/shop/internal/service.OrderService).Place$1\t${root}/internal/service/pricing.go\texample.com/shop/internal/service.Price`,
+  `${root}/internal/service/order.go\t(*example.com/shop/internal/service.OrderService).Place\t${root}/internal/service/user.go\t(*example.com/shop/internal/service.UserService).Find`,
+  `${root}/internal/service/order.go\t(*example.com/shop/internal/service.OrderService).Place\t/usr/local/go/src/fmt/print.go\tfmt.Sprintf`,
+  `${root}/internal/service/order_test.go\texample.com/shop/internal/service.TestPlace\t${root}/internal/service/order.go\t(*example.com/shop/internal/service.OrderService).Place`,
+  `${root}/vendor/github.com/lib/pq/conn.go\tgithub.com/lib/pq.Open\t${root}/internal/service/user.go\t(*example.com/shop/internal/service.UserService).Find`,
+  '',
+].join('\n');
+
+describe('call graph', () => {
+  it('should keep the calls between functions declared in the project', () => {
+    expect(parseCallEdges(OUTPUT, root)).toEqual([
+      {
+        caller_file: 'internal/service/order.go', caller: 'Place', caller_type: 'OrderService',
+        callee_file: 'internal/service/pricing.go', callee: 'Price', count: 2,
+      },
+      {
+        caller_file: 'internal/service/order.go', caller: 'Place', caller_type: 'OrderService',
+        callee_file: 'internal/service/user.go', callee: 'Find', callee_type: 'UserService', count: 1,
+      },
+    ]);
+  });
+
+  it('should name ssa functions without package, closures and type arguments', () => {
+    expect(ssaFunctionName('(*example.com/shop/order.Repository).Save$1')).toEqual({ name: 'Save', type: 'Repository' });
+    expect(ssaFunctionName('(example.com/shop/order.Set[int]).Add')).toEqual({ name: 'Add', type: 'Set' });
+    expect(ssaFunctionName('example.com/shop/order.New$2')).toEqual({ name: 'New' });
+    expect(ssaFunctionName('example.com/shop/order.Map[string]')).toEqual({ name: 'Map' });
+  });
+
+  it('should match calls by name when callgraph is not available', () => {
+    const graph = syntacticCallGraph([
+      fn('Place', 'internal/service/order.go', ['s.validate', 'Price', 'fmt.Sprintf'], 's *OrderService'),
+      fn('validate', 'internal/service/order.go', []),
+      fn('Price', 'internal/service/pricing.go', []),
+      fn('Price', 'internal/legacy/pricing.go', []),
+    ]);
+
+    expect(graph.algorithm).toBe('syntactic');
+    expect(graph.edges.map(e => [e.caller, e.callee_file, e.callee])).toEqual([
+      ['Place', 'internal/service/order.go', 'validate'],
+      ['Place', 'internal/service/pricing.go', 'Price'],
+    ]);
+  });
+
+  it('should connect functions and the types of methods in either direction', () => {
+    const index = new CallGraphIndex({ algorithm: 'cha', edges: parseCallEdges(OUTPUT, root) });
+
+    expect(index.calls({ file: 'internal/service/pricing.go', name: 'Price' }, { file: 'internal/service/order.go', name: 'Place' })).toBe(true);
+    expect(index.calls({ file: 'internal/service/order.go', name: 'OrderService' }, { file: 'internal/service/user.go', name: 'UserService' })).toBe(true);
+    expect(index.calls({ file: 'internal/service/user.go', name: 'Find' }, { file: 'internal/service/pricing.go', name: 'Price' })).toBe(false);
+  });
+
+  it('should score cohesion and coupling of boundaries by their calls', () => {
+    const graph = { algorithm: 'cha' as const, edges: parseCallEdges(OUTPUT, root) };
+    const scores = callCoupling(graph, [
+      { name: 'order', files: ['internal/service/order.go', 'internal/service/pricing.go'] },
+      { name: 'user', files: ['internal/service/user.go'] },
+      { name: 'legacy', files: ['internal/legacy/legacy.go'] },
+    ]);
+
+    expect(scores.get('order')).toEqual({ internal: 2, external: 1, cohesion: 0.67, coupling: 0.33 });
+    expect(scores.get('user')).toEqual({ internal: 0, external: 1, cohesion: 0, coupling: 1 });
+    expect(scores.has('legacy')).toBe(false);
+  });
+
+  describe('callgraph tool', () => {
+    let tempDir: string;
+
+    beforeEach(async () => {
+      tempDir = await createTempDir('call-graph');
+      await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    });
+
+    afterEach(async () => {
+      await cleanupTempDir(tempDir);
+    });
+
+    it('should run the configured command with the algorithm', async () => {
+      const command = path.join(tempDir, 'fake-callgraph');
+      await createMockFile(command, [
+        '#!/bin/sh',
+        `echo "$1" > "${tempDir}/algo"`,
+        `printf '%s/a.go\\texample.com/shop.A\\t%s/b.go\\texample.com/shop.B\\n' "${tempDir}" "${tempDir}"`,
+      ].join('\n'));
+      fs.chmodSync(command, 0o755);
+
+      const graph = buildCallGraph(tempDir, { algorithm: 'rta', command });
+
+      expect(graph).toEqual({
+        algorithm: 'rta',
+        edges: [{ caller_file: 'a.go', caller: 'A', callee_file: 'b.go', callee: 'B', count: 1 }],
+      });
+      expect(fs.readFileSync(path.join(tempDir, 'algo'), 'utf8').trim()).toBe('-algo=rta');
+    });
+
+    it('should return null when the command is not available', () => {
+      expect(buildCallGraph(tempDir, { command: path.join(tempDir, 'missing-callgraph') })).toBeNull();
+    });
+  });
+});
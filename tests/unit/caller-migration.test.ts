@@ -0,0 +1,142 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { CallerMigrator } from '../../src/core/utils/caller-migration.js';
+import { MethodNameStore } from '../../src/core/utils/method-naming.js';
+import { ModuleManifestStore } from '../../src/core/utils/module-manifest.js';
+import { FileSafetyManager } from '../../src/core/utils/file-safety.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const handler = `package legacyapi
+
+import (
+	"context"
+	"net/http"
+
+	"example.com/shop/legacy"
+)
+
+func Place(w http.ResponseWriter, r *http.Request) {
+	id, _ := legacy.PlaceOrder("u1", 2)
+	w.Write([]byte(id))
+}
+
+func Cancel() error {
+	return legacy.CancelOrder("x")
+}
+
+func Find(ctx context.Context) {
+	legacy.FindOrder(ctx, "legacy.PlaceOrder(")
+}
+
+func Batch() {
+	legacy.PlaceOrder("u2",
+		1)
+}
+`;
+
+describe('CallerMigrator', () => {
+  let tempDir: string;
+  const read = (file: string) => fs.readFileSync(path.join(tempDir, file), 'utf8');
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('caller-migration');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'legacy/order.go'), 'package legacy\n\nimport "context"\n\n' +
+      'func PlaceOrder(userID string, qty int) (string, error) { return userID, nil }\n\n' +
+      'func CancelOrder(id string) error { return nil }\n\n' +
+      'func FindOrder(ctx context.Context, id string) (string, error) { return id, nil }\n');
+    await createMockFile(path.join(tempDir, 'internal/order/domain/usecase.go'), 'package domain\n\nimport "context"\n\n' +
+      'type OrderUseCase interface {\n' +
+      '\tPlaceOrder(ctx context.Context, userID string, qty int) (string, error)\n' +
+      '\tCancelOrder(ctx context.Context, id string, reason string) error\n' +
+      '\tFindOrder(ctx context.Context, id string) (string, error)\n' +
+      '}\n');
+    await createMockFile(path.join(tempDir, 'internal/order/usecase/service.go'), 'package usecase\n\n' +
+      'import "example.com/shop/internal/order/domain"\n\n' +
+      'type OrderService struct{}\n\n' +
+      'func NewOrderService() domain.OrderUseCase { return &OrderService{} }\n');
+    await createMockFile(path.join(tempDir, 'internal/legacyapi/handler.go'), handler);
+    await createMockFile(path.join(tempDir, 'cmd/server/main.go'), 'package main\n\n' +
+      'import "example.com/shop/internal/order/usecase"\n\n' +
+      'func main() {\n\tsvc := usecase.NewOrderService()\n\t_ = svc\n}\n');
+
+    new MethodNameStore(tempDir).save('order', 'legacy/order.go', ['CancelOrder', 'FindOrder', 'PlaceOrder'].map(name => ({
+      module: 'order', file: 'legacy/order.go', legacy: name, method: name, source: 'heuristic' as const,
+    })));
+    new ModuleManifestStore(tempDir).save({
+      module: 'order',
+      attempt: 1,
+      updated_at: '2024-01-01T00:00:00.000Z',
+      files: ['internal/order/domain/usecase.go', 'internal/order/usecase/service.go'].map(file => ({
+        path: file, source: 'legacy/order.go', hash: '', symbols: [], generated_at: '2024-01-01T00:00:00.000Z',
+      })),
+    });
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should propose usecase calls from the symbol table and leave changed signatures to a human', () => {
+    const sites = new CallerMigrator(tempDir).findCallSites('order', 'internal/legacyapi');
+
+    expect(sites.map(s => [s.line, s.kind, s.replacement])).toEqual([
+      [11, 'deterministic', 'orderUseCase.PlaceOrder(r.Context(), "u1", 2)'],
+      [16, 'ambiguous', undefined],
+      [20, 'deterministic', 'orderUseCase.FindOrder(ctx, "legacy.PlaceOrder(")'],
+      [24, 'deterministic', 'orderUseCase.PlaceOrder(context.TODO(), "u2",\n\t\t1)'],
+    ]);
+    expect(sites[1].reason).toContain('arguments changed from CancelOrder(id string)');
+    expect(sites[3].context_todo).toBe(true);
+    expect(new CallerMigrator(tempDir).planWiring('order', 'internal/legacyapi')).toMatchObject({
+      composition_root: 'cmd/server/main.go',
+      statement: 'legacyapi.SetOrderUseCase(svc)',
+      status: 'inserted',
+    });
+  });
+
+  it('should apply accepted rewrites, wire the usecase and track adoption', async () => {
+    const migrator = new CallerMigrator(tempDir);
+    const sites = migrator.findCallSites('order', 'internal/legacyapi');
+
+    const result = await migrator.apply('order', 'internal/legacyapi', sites.slice(0, 3), new FileSafetyManager(tempDir), () => null);
+
+    expect(result.applied.map(s => s.line)).toEqual([11, 20]);
+    expect(result.files).toEqual(['cmd/server/main.go', 'internal/legacyapi/handler.go', 'internal/legacyapi/order_usecase.go']);
+    expect(read('internal/legacyapi/handler.go')).toContain('\t// TODO(vibeflow): use orderUseCase.CancelOrder(');
+    expect(read('internal/legacyapi/order_usecase.go')).toContain('var orderUseCase orderdomain.OrderUseCase');
+    expect(read('cmd/server/main.go')).toContain('\tsvc := usecase.NewOrderService()\n\tlegacyapi.SetOrderUseCase(svc)\n');
+    expect(read('cmd/server/main.go')).toContain('"example.com/shop/internal/legacyapi"');
+
+    // Re-running neither duplicates the TODO nor the wiring
+    const again = await migrator.apply('order', 'internal/legacyapi', [], new FileSafetyManager(tempDir), () => null);
+    expect(again.files).toEqual([]);
+    expect(again.wiring?.status).toBe('existing');
+
+    expect(migrator.recordAdoption('order')).toEqual([
+      { package: 'internal/legacyapi', migrated: 2, remaining: 2, ambiguous: 1 },
+    ]);
+    expect(migrator.loadSummary().modules.order.usecase).toBe('internal/order/domain.OrderUseCase');
+  });
+
+  it('should roll back every file when the rewrite does not compile', async () => {
+    const migrator = new CallerMigrator(tempDir);
+    const sites = migrator.findCallSites('order', 'internal/legacyapi');
+
+    const result = await migrator.apply('order', 'internal/legacyapi', sites, new FileSafetyManager(tempDir), () => 'undefined: orderUseCase');
+
+    expect(result.error).toBe('undefined: orderUseCase');
+    expect(read('internal/legacyapi/handler.go')).toBe(handler);
+    expect(fs.existsSync(path.join(tempDir, 'internal/legacyapi/order_usecase.go'))).toBe(false);
+    expect(read('cmd/server/main.go')).not.toContain('SetOrderUseCase');
+  });
+
+  it('should refuse the module and legacy packages as consumers', () => {
+    const migrator = new CallerMigrator(tempDir);
+
+    expect(() => migrator.findCallSites('order', 'internal/order/usecase')).toThrow(/belongs to module order/);
+    expect(() => migrator.findCallSites('order', 'legacy')).toThrow(/legacy package/);
+    expect(() => migrator.findCallSites('user', 'internal/legacyapi')).toThrow(/No symbol mapping/);
+  });
+});
@@ -0,0 +1,87 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import * as fs from 'fs';
+import * as path from 'path';
+import { CoChangeIndex, analyzeCoChanges, countFileChanges, mineCoChanges, parseChangedFiles } from '../../src/core/utils/co-change.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const commits = [
+  ['internal/order/order.go', 'internal/billing/invoice.go'],
+  ['internal/order/order.go', 'internal/billing/invoice.go', 'internal/user/user.go'],
+  ['internal/order/order.go', 'internal/billing/invoice.go', 'internal/order/order_test.go'],
+  ['internal/order/order.go', 'internal/user/user.go'],
+  ['internal/order/order.go', 'internal/billing/invoice.go', 'internal/user/user.go', 'internal/user/store.go'],
+  ['README.md'],
+];
+
+describe('co-change analysis', () => {
+  it('should count file pairs changed together and leave out bulk commits', () => {
+    const analysis = analyzeCoChanges(commits, { maxFilesPerCommit: 3 });
+
+    expect(analysis).toEqual({
+      commits: 4,
+      skipped_commits: 1,
+      pairs: [{ files: ['internal/billing/invoice.go', 'internal/order/order.go'], count: 3, strength: 0.75 }],
+    });
+    expect(analyzeCoChanges(commits, { maxFilesPerCommit: 3, minCoChanges: 2 }).pairs.map(p => [p.files[1], p.strength]))
+      .toEqual([['internal/order/order.go', 0.75], ['internal/user/user.go', 0.5]]);
+  });
+
+  it('should look pairs up in either order', () => {
+    const index = new CoChangeIndex(analyzeCoChanges(commits, { maxFilesPerCommit: 3, minCoChanges: 2 }));
+
+    expect(index.strength('internal/user/user.go', 'internal/order/order.go')).toBe(0.5);
+    expect(index.strength('internal/order/order.go', 'internal/billing/invoice.go')).toBe(0.75);
+    expect(index.strength('internal/billing/invoice.go', 'internal/user/user.go')).toBe(0);
+    expect(index.strength('internal/order/order.go', 'internal/order/order.go')).toBe(0);
+    expect(index.pairsWithin(['internal/user/user.go', 'internal/order/order.go', 'internal/billing/invoice.go'])).toBe(2);
+  });
+
+  it('should count the commits changing each source file', () => {
+    expect(countFileChanges(commits, { maxFilesPerCommit: 3 })).toEqual({
+      commits: 4,
+      changes: { 'internal/order/order.go': 4, 'internal/billing/invoice.go': 3, 'internal/user/user.go': 2 },
+    });
+  });
+
+  it('should parse the files of each commit from git log output', () => {
+    expect(parseChangedFiles('\x1e\n\na.go\nb.go\n\x1e\n\nc.go\n\x1e\n')).toEqual([['a.go', 'b.go'], ['c.go']]);
+  });
+
+  describe('from git history', () => {
+    let tempDir: string;
+
+    const git = (...args: string[]) => execFileSync('git', args, { cwd: tempDir, stdio: 'ignore' });
+
+    beforeEach(async () => {
+      tempDir = await createTempDir('co-change');
+      git('init', '-q');
+      git('config', 'user.email', 'test@example.com');
+      git('config', 'user.name', 'Test User');
+      for (let i = 1; i <= 3; i++) {
+        await createMockFile(path.join(tempDir, 'service/internal/order/order.go'), `package order // ${i}\n`);
+        await createMockFile(path.join(tempDir, 'service/internal/order/repository.go'), `package order // ${i}\n`);
+        await createMockFile(path.join(tempDir, 'tools/gen.go'), `package tools // ${i}\n`);
+        git('add', '-A');
+        git('commit', '-q', '-m', `change ${i}`);
+      }
+    });
+
+    afterEach(async () => {
+      await cleanupTempDir(tempDir);
+    });
+
+    it('should mine pairs relative to the project root inside the repository', () => {
+      expect(mineCoChanges(path.join(tempDir, 'service'))).toEqual({
+        commits: 3,
+        skipped_commits: 0,
+        pairs: [{ files: ['internal/order/order.go', 'internal/order/repository.go'], count: 3, strength: 1 }],
+      });
+    });
+
+    it('should return null outside a git checkout', () => {
+      fs.rmSync(path.join(tempDir, '.git'), { recursive: true, force: true });
+      expect(mineCoChanges(tempDir)).toBeNull();
+    });
+  });
+});
@@ -0,0 +1,73 @@
+import { describe, it, expect } from 'vitest';
+import { WeightedEdge, detectCommunities, modularity } from '../../src/core/utils/community-detection.js';
+
+function clique(offset: number, size: number): WeightedEdge[] {
+  const edges: WeightedEdge[] = [];
+  for (let i = 0; i < size; i++) {
+    for (let j = i + 1; j < size; j++) edges.push({ source: offset + i, target: offset + j, weight: 1 });
+  }
+  return edges;
+}
+
+// Six triangles, each linked to the next one
+const ring: WeightedEdge[] = [];
+for (let k = 0; k < 6; k++) {
+  ring.push(...clique(k * 3, 3), { source: k * 3 + 2, target: ((k + 1) % 6) * 3, weight: 1 });
+}
+
+describe('community detection', () => {
+  it.each(['louvain', 'leiden'] as const)('should separate weakly linked groups with %s', algorithm => {
+    const edges = [...clique(0, 4), ...clique(4, 4), { source: 3, target: 4, weight: 0.1 }];
+    const communities = detectCommunities(8, edges, { algorithm });
+
+    expect(communities).toEqual([0, 0, 0, 0, 1, 1, 1, 1]);
+    expect(modularity(8, edges, communities)).toBeCloseTo(0.492, 3);
+  });
+
+  it.each(['louvain', 'leiden'] as const)('should give fewer, larger communities at a lower resolution with %s', algorithm => {
+    expect(detectCommunities(18, ring, { algorithm })).toEqual([0, 0, 0, 1, 1, 1, 2, 2, 2, 3, 3, 3, 4, 4, 4, 5, 5, 5]);
+    expect(detectCommunities(18, ring, { algorithm, resolution: 0.3 })).toEqual([0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 2, 2]);
+  });
+
+  it('should keep nodes without edges in communities of their own', () => {
+    expect(detectCommunities(3, [], { algorithm: 'leiden' })).toEqual([0, 1, 2]);
+    expect(detectCommunities(4, [{ source: 0, target: 1, weight: 1 }], { algorithm: 'louvain' })).toEqual([0, 0, 1, 2]);
+  });
+
+  it.each(['louvain', 'leiden'] as const)('should keep nodes seeded to one module together with %s', algorithm => {
+    const edges = [...clique(0, 4), ...clique(4, 4), { source: 3, target: 4, weight: 0.1 }];
+    const seeds = ['billing', undefined, undefined, undefined, undefined, undefined, undefined, 'billing'];
+
+    const communities = detectCommunities(8, edges, { algorithm, seeds });
+    expect(communities[0]).toBe(communities[7]);
+    expect(communities[1]).toBe(communities[0]);
+    expect(communities[5]).not.toBe(communities[0]);
+  });
+
+  it.each(['louvain', 'leiden'] as const)('should never group nodes seeded to different modules with %s', algorithm => {
+    const edges = clique(0, 6);
+    const seeds = ['user', undefined, undefined, undefined, undefined, 'billing'];
+
+    const communities = detectCommunities(6, edges, { algorithm, seeds });
+    expect(communities[0]).not.toBe(communities[5]);
+    expect(detectCommunities(3, [], { algorithm, seeds: ['user', undefined, 'user'] })).toEqual([0, 1, 0]);
+  });
+
+  it('should find planted groups in a larger graph and return the same result every run', () => {
+    let seed = 1;
+    const random = () => (seed = (seed * 16807) % 2147483647) / 2147483647;
+    const edges: WeightedEdge[] = [];
+    for (let i = 0; i < 200; i++) {
+      for (let j = i + 1; j < 200; j++) {
+        const sameGroup = Math.floor(i / 20) === Math.floor(j / 20);
+        if (random() < (sameGroup ? 0.5 : 0.01)) edges.push({ source: i, target: j, weight: 0.5 + random() / 2 });
+      }
+    }
+
+    const communities = detectCommunities(200, edges, { algorithm: 'leiden' });
+    expect(new Set(communities).size).toBe(10);
+    expect(communities.every((community, node) => community === communities[node - (node % 20)])).toBe(true);
+    expect(modularity(200, edges, communities)).toBeCloseTo(0.738, 3);
+    expect(detectCommunities(200, edges, { algorithm: 'leiden' })).toEqual(communities);
+  });
+});
@@ -0,0 +1,141 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  findConfigAccess,
+  goFieldName,
+  planModuleConfigs,
+  remainingConfigReads,
+  renderModuleConfigFile,
+  rewriteEnvReads,
+} from '../../src/core/utils/config-access.js';
+import { ArchitectAgent, ArchitecturalPlan } from '../../src/core/agents/architect-agent.js';
+import { DomainMapWriter } from '../../src/core/utils/domain-map-writer.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+const fixtureRoot = './tests/fixtures/config-access';
+const modules = [
+  { name: 'order', files: ['internal/order/order.go'] },
+  { name: 'user', files: ['internal/user/user.go'] },
+];
+
+describe('configuration access', () => {
+  it('should inventory env, viper, flag and global config reads per module', () => {
+    const inventory = findConfigAccess(fixtureRoot, modules);
+
+    expect(inventory.keys.map(k => [k.id, k.type, k.modules])).toEqual([
+      ['env:AWS_REGION', 'string', ['order']],
+      ['env:DB_HOST', 'string', ['order', 'user']],
+      ['flag:workers', 'int', ['order']],
+      ['global:Cfg.Cache.TTL', 'time.Duration', ['order']],
+      ['global:Cfg.Database.Host', 'string', ['order', 'user']],
+      ['viper:server.port', 'int', ['order']],
+    ]);
+
+    const dbHost = inventory.keys.find(k => k.id === 'env:DB_HOST')!;
+    expect(dbHost.accesses.map(a => [a.module, a.function, a.expression])).toEqual([
+      ['order', 'Place', 'os.Getenv("DB_HOST")'],
+      ['user', 'Host', 'os.LookupEnv("DB_HOST")'],
+    ]);
+    expect(dbHost.accesses[0].location).toMatchObject({ file: 'internal/order/order.go', line: 18, column: 10 });
+    expect(inventory.keys.find(k => k.id === 'env:AWS_REGION')!.accesses[0].expression).toBe('os.Getenv(regionKey)');
+    expect(inventory.keys.find(k => k.id === 'global:Cfg.Database.Host')!.accesses.map(a => a.expression))
+      .toEqual(['config.Cfg.Database.Host', 'cfg.Cfg.Database.Host']);
+
+    expect(inventory.unresolvable.map(u => [u.source, u.expression, u.location.line])).toEqual([
+      ['env', 'os.Getenv("TENANT_" + tenant)', 20],
+      ['global', 'config.Cfg', 26],
+    ]);
+  });
+
+  it('should plan a typed config per module with the composition root statement', () => {
+    const configs = planModuleConfigs(fixtureRoot, findConfigAccess(fixtureRoot, modules), ['order', 'user', 'billing']);
+
+    expect([...configs.keys()]).toEqual(['order', 'user']);
+    const user = configs.get('user')!;
+    expect(user).toMatchObject({ package: 'internal/user/config', import_path: 'example.com/shop/internal/user/config' });
+    expect(user.fields.map(f => [f.name, f.type])).toEqual([['DBHost', 'string'], ['DatabaseHost', 'string']]);
+    expect(user.wiring).toBe([
+      'userconfig.Set(userconfig.Config{',
+      '\tDBHost:       os.Getenv("DB_HOST"),',
+      '\tDatabaseHost: config.Cfg.Database.Host,',
+      '})',
+    ].join('\n'));
+
+    const order = configs.get('order')!;
+    expect(order.fields.find(f => f.key === 'workers')).toMatchObject({ name: 'Workers', source: 'flag', type: 'int' });
+    const file = renderModuleConfigFile('order', order);
+    expect(file).toContain('import "time"');
+    expect(file).toContain('\tCacheTTL     time.Duration // Cfg.Cache.TTL (global)');
+    expect(file).toContain('func Set(c Config) {');
+
+    expect([goFieldName('DB_HOST'), goFieldName('server.port'), goFieldName('apiURL'), goFieldName('1st')])
+      .toEqual(['DBHost', 'ServerPort', 'APIURL', 'K1st']);
+  });
+
+  it('should rewrite literal Getenv reads in generated code and report the rest', () => {
+    const config = planModuleConfigs(fixtureRoot, findConfigAccess(fixtureRoot, modules), ['order']).get('order')!;
+    const generated = [
+      'package usecase',
+      '',
+      'import (',
+      '\t"os"',
+      '',
+      '\t"github.com/spf13/viper"',
+      ')',
+      '',
+      '// os.Getenv("DB_HOST") is read once',
+      'func Host() (string, int, string) {',
+      '\treturn os.Getenv("DB_HOST"), viper.GetInt("server.port"), os.Getenv("UNPLANNED")',
+      '}',
+      '',
+    ].join('\n');
+
+    const { content, rewritten } = rewriteEnvReads(generated, config, 'order');
+
+    expect(rewritten).toBe(1);
+    expect(content).toContain('\torderconfig "example.com/shop/internal/order/config"\n');
+    expect(content).toContain('return orderconfig.Get().DBHost, viper.GetInt("server.port"), os.Getenv("UNPLANNED")');
+    expect(content).toContain('// os.Getenv("DB_HOST") is read once');
+    expect(remainingConfigReads('internal/order/usecase/host.go', content, config, 'order').map(r => r.expression))
+      .toEqual(['viper.GetInt("server.port")']);
+  });
+
+  describe('in the architectural plan', () => {
+    let tempDir: string;
+
+    beforeEach(async () => {
+      tempDir = await createTempDir('config-access');
+      fs.cpSync(fixtureRoot, tempDir, { recursive: true });
+      new DomainMapWriter(tempDir).write({
+        project: 'shop',
+        language: 'go',
+        analyzed_at: '2026-01-01T00:00:00.000Z',
+        total_files: 2,
+        boundaries: [
+          { name: 'order', description: 'Order placement', files: ['internal/order/order.go'] },
+          { name: 'user', description: 'Accounts', files: ['internal/user/user.go'] },
+        ],
+        metrics: { overall_cohesion: 0, overall_coupling: 0, modularity_score: 0 },
+      });
+    });
+
+    afterEach(async () => {
+      await cleanupTempDir(tempDir);
+    });
+
+    it('should record the key inventory and module configs', async () => {
+      const agent = new ArchitectAgent(tempDir, path.join(tempDir, 'vibeflow.config.yaml'), path.join(tempDir, 'boundary.yaml'));
+      await agent.generateArchitecturalPlan(path.join(tempDir, '.vibeflow/domain-map.json'));
+
+      const plan: ArchitecturalPlan = JSON.parse(fs.readFileSync(path.join(tempDir, '.vibeflow/plan.json'), 'utf8'));
+      expect(plan.config_access?.keys).toHaveLength(6);
+      expect(plan.modules.find(m => m.name === 'user')?.config?.fields.map(f => f.key)).toEqual(['DB_HOST', 'Cfg.Database.Host']);
+
+      const markdown = fs.readFileSync(path.join(tempDir, '.vibeflow/plan.md'), 'utf8');
+      expect(markdown).toContain('## 設定キー (Configuration Access)');
+      expect(markdown).toContain('複数モジュールが読むキー: `DB_HOST` (order, user)');
+      expect(markdown).toContain('- `os.Getenv("TENANT_" + tenant)` (env, order) — internal/order/order.go:20:9');
+    });
+  });
+});
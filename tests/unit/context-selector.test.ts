@@ -0,0 +1,128 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  ContextSelector,
+  applyBudget,
+  estimateTokens,
+  parseGoDeclarations,
+  ContextItem,
+} from '../../src/core/utils/context-selector.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { createTempDir, cleanupTempDir, createMockGoProject, createMockFile } from '../setup.js';
+
+const ORDER_GO = `
+package main
+
+import "errors"
+
+type Order struct {
+    ID      string
+    Buyer   *User
+    Product *Product
+}
+
+func PlaceOrder(name, email string, product *Product) (*Order, error) {
+    buyer := NewUser(name, email)
+    if err := buyer.Validate(); err != nil {
+        return nil, err
+    }
+    if product == nil {
+        return nil, errors.New("product is required")
+    }
+    return &Order{ID: "order-1", Buyer: buyer, Product: product}, nil
+}
+`;
+
+const CACHED_RESPONSE = JSON.stringify({
+  refactored_files: [
+    { path: 'internal/order/domain/order.go', content: 'package domain', description: 'order entity' },
+  ],
+  interfaces: [],
+  tests: [],
+});
+
+describe('ContextSelector', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('context-selector');
+    await createMockGoProject(tempDir);
+    await createMockFile(path.join(tempDir, 'order.go'), ORDER_GO);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should parse top-level Go declarations', () => {
+    const source = fs.readFileSync(path.join(tempDir, 'user.go'), 'utf8');
+    const declarations = parseGoDeclarations(source, 'user.go');
+
+    expect(declarations.map(d => d.name)).toEqual(['User', 'NewUser', 'Validate', 'generateID']);
+    expect(declarations.find(d => d.name === 'Validate')!.kind).toBe('method');
+    expect(declarations.find(d => d.name === 'NewUser')!.signature).toBe('func NewUser(name, email string) *User');
+  });
+
+  it('should select only referenced declarations', () => {
+    const selector = new ContextSelector(tempDir, { inlineBodyMaxLines: 10 });
+    const context = selector.selectContext('order.go');
+    const symbols = context.items.map(i => `${i.symbol}:${i.kind}`);
+
+    expect(symbols).toContain('User:type');
+    expect(symbols).toContain('Product:type');
+    expect(symbols).toContain('NewUser:body');
+    expect(symbols.some(s => s.startsWith('generateProductID'))).toBe(false);
+    expect(symbols.some(s => s.startsWith('main'))).toBe(false);
+  });
+
+  it('should use signatures for functions longer than the inline limit', () => {
+    const selector = new ContextSelector(tempDir, { inlineBodyMaxLines: 2 });
+    const context = selector.selectContext('order.go');
+    const newUser = context.items.find(i => i.symbol === 'NewUser')!;
+
+    expect(newUser.kind).toBe('signature');
+    expect(newUser.content).not.toContain('{');
+  });
+
+  it('should drop the lowest-relevance items first to respect the budget', () => {
+    const item = (symbol: string, relevance: number, tokens: number): ContextItem => ({
+      kind: 'signature', symbol, file: 'a.go', content: symbol, tokens, relevance,
+    });
+
+    const result = applyBudget([item('low', 1, 50), item('high', 5, 50), item('mid', 3, 50)], 100);
+
+    expect(result.items.map(i => i.symbol)).toEqual(['high', 'mid']);
+    expect(result.dropped.map(i => i.symbol)).toEqual(['low']);
+    expect(result.tokens).toBeLessThanOrEqual(100);
+  });
+
+  it('should extract the module section from plan.md', () => {
+    const plan = '# Plan\n\n## user module\n- extract entity\n\n## order module\n- create service\n';
+    expect(ContextSelector.extractPlanExcerpt(plan, 'order')).toBe('## order module\n- create service');
+  });
+
+  it('should produce the same output with materially fewer input tokens than full-file context', async () => {
+    const agent = new RefactorAgent(tempDir);
+    const prompts: string[] = [];
+    const client = (agent as any).claudeClient;
+    vi.spyOn(client, 'queryWithGeneration').mockImplementation(async (prompt: string) => {
+      prompts.push(prompt);
+      return { text: CACHED_RESPONSE, generation: { method: 'llm' } };
+    });
+
+    const boundary = { name: 'order', description: 'Order handling', files: ['order.go', 'user.go', 'product.go'] };
+    const result = await agent.generateRefactoredCode(path.join(tempDir, 'order.go'), boundary);
+
+    expect(result.refactored_files.map(f => f.path)).toEqual(['internal/order/domain/order.go']);
+
+    const fullFileContext = ['main.go', 'user.go', 'product.go']
+      .map(f => fs.readFileSync(path.join(tempDir, f), 'utf8'))
+      .join('\n');
+    const selected = new ContextSelector(tempDir).selectContext('order.go', boundary.files);
+
+    expect(prompts[0]).toContain('## Related Context');
+    expect(prompts[0]).not.toContain('generateProductID');
+    expect(selected.tokens).toBeLessThan(estimateTokens(fullFileContext) * 0.7);
+  });
+});
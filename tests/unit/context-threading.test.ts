@@ -0,0 +1,78 @@
+import { describe, it, expect } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { execSync } from 'child_process';
+import {
+  contextMethodNames,
+  legacyContextFunctions,
+  renderContextSection,
+  threadContext,
+} from '../../src/core/utils/context-threading.js';
+
+const fixtureRoot = './tests/fixtures/context-threading';
+
+function readTree(root: string): { path: string; content: string }[] {
+  const walk = (dir: string): string[] => fs.readdirSync(dir, { withFileTypes: true })
+    .flatMap(entry => entry.isDirectory() ? walk(path.join(dir, entry.name)) : [path.join(dir, entry.name)]);
+  return walk(root)
+    .filter(file => file.endsWith('.go'))
+    .sort()
+    .map(file => ({ path: path.relative(root, file), content: fs.readFileSync(file, 'utf8') }));
+}
+
+function hasGo(): boolean {
+  try {
+    execSync('go version', { stdio: 'ignore' });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+const legacySource = fs.readFileSync(path.join(fixtureRoot, 'legacy', 'user.go'), 'utf8');
+const legacyWithContext = contextMethodNames(legacyContextFunctions(legacySource, 'legacy/user.go'));
+const input = readTree(path.join(fixtureRoot, 'input'));
+
+describe('context threading', () => {
+  it('should find legacy functions that already take a context', () => {
+    expect([...legacyWithContext]).toEqual(['FindUser']);
+    expect([...contextMethodNames(['FindUser'], [
+      { module: 'user', file: 'user.go', legacy: 'FindUser', method: 'GetUser', source: 'heuristic' },
+    ])]).toEqual(['FindUser', 'GetUser']);
+  });
+
+  it('should thread ctx through the call chain and record context.TODO() at the legacy boundary', () => {
+    const { files, todos } = threadContext(input, { addContext: true, legacyWithContext });
+
+    expect(files).toEqual(readTree(path.join(fixtureRoot, 'add-context')));
+    expect(todos).toEqual([{ file: 'legacy/user.go', line: 14, function: 'RegisterUser' }]);
+  });
+
+  it('should keep legacy signatures without inventing ctx parameters', () => {
+    const { files, todos } = threadContext(input, { addContext: false, legacyWithContext });
+
+    expect(files).toEqual(readTree(path.join(fixtureRoot, 'legacy-signatures')));
+    expect(todos).toEqual([{ file: 'internal/user/infrastructure/user_repository.go', line: 24, function: 'MemoryUserRepository.Save' }]);
+  });
+
+  it('should not produce double ctx signatures or arguments', () => {
+    const usecase = (content: string) => threadContext([{ path: 'svc.go', content }], { addContext: true }).files[0].content;
+    const source = 'package usecase\n\nimport "context"\n\ntype Svc interface {\n\tFind(ctx context.Context, id string) error\n}\n\n' +
+      'func (s *S) Find(ctx context.Context, other context.Context, id string) error {\n\treturn s.repo.Find(ctx, other, id)\n}\n';
+
+    expect(usecase(source)).toContain('func (s *S) Find(ctx context.Context, id string) error {\n\treturn s.repo.Find(ctx, id)\n}');
+    expect(usecase(usecase(source))).toBe(usecase(source));
+  });
+
+  it('should tell the model which mode is active', () => {
+    expect(renderContextSection(true, ['FindUser'])).toContain('`context.TODO()`');
+    expect(renderContextSection(false, ['FindUser'])).toContain('must keep\nthe legacy function signatures');
+    expect(renderContextSection(false, ['FindUser'])).toContain('`FindUser`');
+  });
+
+  it.skipIf(!hasGo())('should compile the expected outputs of both modes', () => {
+    for (const mode of ['add-context', 'legacy-signatures']) {
+      expect(() => execSync('go vet ./...', { cwd: path.join(fixtureRoot, mode), stdio: 'pipe' })).not.toThrow();
+    }
+  });
+});
@@ -0,0 +1,114 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  DataMappingReporter,
+  extractOrmFields,
+  parseSchemaColumns,
+} from '../../src/core/utils/data-mapping-report.js';
+import { ModuleManifestStore } from '../../src/core/utils/module-manifest.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const ORIGINAL_ORDER = `package main
+
+type Order struct {
+	ID         string \`db:"id"\`
+	CustomerID string \`db:"customer_id"\`
+	Total      int    \`db:"total"\`
+}
+
+func SaveAudit(db DB, o *Order) {
+	db.Exec("INSERT INTO orders (id, customer_id, total, audit_note) VALUES (?, ?, ?, ?)")
+}
+`;
+
+const NEW_ORDER = `package domain
+
+type Order struct {
+	ID      string \`db:"id"\`
+	BuyerID string \`db:"customer_id"\`
+}
+`;
+
+const NEW_BILLING = `package domain
+
+type Invoice struct {
+	OrderID string \`db:"id"\`
+	Amount  int    \`db:"total"\`
+}
+
+func (Invoice) TableName() string { return "orders" }
+`;
+
+describe('DataMappingReporter', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('data-mapping');
+    await createMockFile(path.join(tempDir, 'order.go'), ORIGINAL_ORDER);
+    await createMockFile(
+      path.join(tempDir, 'migrations', '001_orders.sql'),
+      'CREATE TABLE orders (\n  id TEXT PRIMARY KEY,\n  customer_id TEXT NOT NULL,\n  total INTEGER,\n  audit_note TEXT,\n  PRIMARY KEY (id)\n);\n'
+    );
+    await createMockFile(path.join(tempDir, 'internal/order/domain/order.go'), NEW_ORDER);
+    await createMockFile(path.join(tempDir, 'internal/billing/domain/invoice.go'), NEW_BILLING);
+    await createMockFile(
+      path.join(tempDir, '.vibeflow', 'domain-map.json'),
+      JSON.stringify({ boundaries: [{ name: 'order', files: ['order.go'] }] })
+    );
+
+    const manifests = new ModuleManifestStore(tempDir);
+    for (const [module, file] of [['order', 'internal/order/domain/order.go'], ['billing', 'internal/billing/domain/invoice.go']]) {
+      manifests.save({
+        module,
+        attempt: 1,
+        updated_at: new Date().toISOString(),
+        files: [{ path: file, source: 'order.go', hash: 'x', symbols: [], generated_at: new Date().toISOString() }],
+      });
+    }
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should map every column and mark renamed, moved and unmapped ones', () => {
+    const report = new DataMappingReporter(tempDir).build();
+    const byColumn = Object.fromEntries(report.mappings.map(m => [m.column, m]));
+
+    expect(Object.keys(byColumn).sort()).toEqual(['audit_note', 'customer_id', 'id', 'total']);
+    expect(byColumn.customer_id.changes).toEqual(['renamed']);
+    expect(byColumn.customer_id.new_field).toBe('BuyerID');
+    expect(byColumn.id.changes).toEqual(['unchanged']);
+    expect(byColumn.total.changes).toEqual(['renamed', 'moved']);
+    expect(byColumn.total.new_module).toBe('billing');
+    expect(byColumn.audit_note.changes).toEqual(['unmapped']);
+  });
+
+  it('should flag tables whose columns are owned by more than one module', () => {
+    const reporter = new DataMappingReporter(tempDir);
+    const report = reporter.build();
+    const { csvPath, jsonPath } = reporter.write(report);
+
+    expect(report.split_ownership_risks).toEqual(['orders']);
+    expect(report.tables[0].modules).toEqual(['billing', 'order']);
+    expect(fs.existsSync(jsonPath)).toBe(true);
+    expect(fs.readFileSync(csvPath, 'utf8')).toContain('orders,audit_note,,,,,,,unmapped,yes');
+  });
+});
+
+describe('data mapping parsers', () => {
+  it('should read columns from gorm tags and default naming', () => {
+    const fields = extractOrmFields(
+      'type UserProfile struct {\n\tID uint\n\tDisplayName string `gorm:"column:name"`\n\tCache string `gorm:"-"`\n}\n',
+      'profile.go'
+    );
+
+    expect(fields.map(f => `${f.table}.${f.column}`)).toEqual(['user_profiles.id', 'user_profiles.name']);
+  });
+
+  it('should skip constraints when parsing CREATE TABLE', () => {
+    const columns = parseSchemaColumns('CREATE TABLE IF NOT EXISTS users (id INT, email VARCHAR(255), UNIQUE (email));');
+    expect([...columns.get('users')!]).toEqual(['id', 'email']);
+  });
+});
@@ -0,0 +1,49 @@
+import { describe, it, expect } from 'vitest';
+import { planDatabaseDecomposition, renderDatabaseSection } from '../../src/core/utils/database-decomposition.js';
+
+const fixtureRoot = './tests/fixtures/schema-ownership';
+const modules = [
+  { name: 'user', files: ['internal/user/user.go', 'internal/user/store.go'] },
+  { name: 'order', files: ['internal/order/order.go', 'internal/order/repository.go'], owned_tables: ['Coupons'] },
+  { name: 'report', files: ['internal/report/report.go'] },
+];
+const phases = [
+  { name: '基盤', modules: ['user'] },
+  { name: '注文', modules: ['order', 'report'] },
+];
+
+describe('Database decomposition', () => {
+  it('should assign tables to modules and list the queries and foreign keys crossing them', () => {
+    const database = planDatabaseDecomposition(fixtureRoot, modules, phases)!;
+
+    expect(database.sources).toEqual(['migrations/001_init.up.sql', 'migrations/002_coupons.up.sql']);
+    expect(database.tables).toEqual([
+      { table: 'coupons', owner: 'order', basis: 'configured', accessed_by: [] },
+      { table: 'order_items', owner: 'order', basis: 'writes', accessed_by: [] },
+      { table: 'orders', owner: 'order', basis: 'writes', accessed_by: ['report'] },
+      { table: 'users', owner: 'user', basis: 'writes', accessed_by: ['order', 'report'] },
+    ]);
+    expect(database.cross_module_queries.map(q => [q.module, q.table, q.owner, q.symbol, q.replacement])).toEqual([
+      ['report', 'orders', 'order', 'Revenue', 'query'],
+      ['order', 'users', 'user', 'ForUser', 'query'],
+      ['report', 'users', 'user', 'Revenue', 'query'],
+    ]);
+    expect(database.foreign_keys).toEqual([{ table: 'orders', owner: 'order', references: 'users', referenced_owner: 'user' }]);
+    expect(database.steps).toEqual([
+      { phase: '基盤', module: 'user', tables: ['users'], queries_to_replace: 0, queries_to_serve: 2, foreign_keys: [] },
+      { phase: '注文', module: 'order', tables: ['coupons', 'order_items', 'orders'], queries_to_replace: 1, queries_to_serve: 1, foreign_keys: ['orders → users'] },
+      { phase: '注文', module: 'report', tables: [], queries_to_replace: 2, queries_to_serve: 0, foreign_keys: [] },
+    ]);
+  });
+
+  it('should render ownership and an outline of the schema migration per phase', () => {
+    const section = renderDatabaseSection(planDatabaseDecomposition(fixtureRoot, modules, phases));
+
+    expect(section).toContain('## データベース分割 (Database Decomposition)');
+    expect(section).toContain('| `users` | user | 書き込み | order, report |');
+    expect(section).toContain('- report → `orders` (所有: order) — internal/report/report.go `Revenue` (select)、order の参照APIに置き換え');
+    expect(section).toContain('- 注文 / order\n  1. 他モジュールから order のテーブルへのクエリ 1件を order のAPI経由に置き換え');
+    expect(section).toContain('  3. モジュールをまたぐ外部キーを削除し、ID参照に変更: orders → users');
+    expect(renderDatabaseSection(undefined)).toBe('');
+  });
+});
@@ -0,0 +1,106 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  DebtInventoryScanner,
+  attachDebt,
+  classifyDebt,
+  findDebtMarkers,
+  topDebtFiles,
+} from '../../src/core/utils/debt-inventory.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const orderSource = `package order
+
+// TODO(alice): implement partial refunds
+func Refund(id int) error {
+\tmsg := "// TODO inside a string is not a marker"
+\t_ = msg
+\t/* FIXME: rounding is wrong for JPY
+\t * HACK: slow linear scan over all orders */
+\tpanic("not implemented")
+}
+
+// XXX remove once the legacy API is gone
+func Legacy() {} // todo: lowercase is ignored
+`;
+
+describe('debt markers', () => {
+  it('should find comment markers and not-implemented stubs with their lines', () => {
+    const items = findDebtMarkers(orderSource, 'internal/order/refund.go');
+
+    expect(items.map(i => [i.line, i.marker, i.category, i.text])).toEqual([
+      [3, 'TODO', 'missing-implementation', 'implement partial refunds'],
+      [7, 'FIXME', 'known-bug', 'rounding is wrong for JPY'],
+      [8, 'HACK', 'performance', 'slow linear scan over all orders'],
+      [9, 'panic', 'missing-implementation', 'panic("not implemented")'],
+      [12, 'XXX', 'deprecation', 'remove once the legacy API is gone'],
+    ]);
+  });
+
+  it('should classify by keywords before falling back to the marker', () => {
+    expect(classifyDebt('TODO', 'cache the lookup')).toBe('performance');
+    expect(classifyDebt('FIXME', 'clean this up')).toBe('known-bug');
+    expect(classifyDebt('TODO', 'clean this up')).toBe('other');
+  });
+
+  it('should honor configured markers', () => {
+    expect(findDebtMarkers('package a\n// NOTE(perf): slow\n// TODO: x\n', 'a.go', ['NOTE']).map(i => i.marker)).toEqual(['NOTE']);
+  });
+});
+
+describe('DebtInventoryScanner', () => {
+  let tempDir: string;
+  const boundaries = [
+    { name: 'order', description: '', files: ['internal/order/refund.go', 'internal/order/zz_generated.go'] },
+    { name: 'user', description: '', files: ['internal/user/user.go'] },
+  ];
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('debt-inventory');
+    await createMockFile(path.join(tempDir, 'internal/order/refund.go'), orderSource);
+    await createMockFile(path.join(tempDir, 'internal/order/refund_test.go'), 'package order\n\n// TODO: cover refunds\n');
+    await createMockFile(path.join(tempDir, 'internal/order/zz_generated.go'),
+      '// Code generated by stringer. DO NOT EDIT.\n\npackage order\n\n// TODO: regenerate\n');
+    await createMockFile(path.join(tempDir, 'internal/user/user.go'), 'package user\n\nfunc Find() {}\n');
+    await createMockFile(path.join(tempDir, 'cmd/tool/main.go'), 'package main\n\n// FIXME: crashes on empty input\n');
+    await createMockFile(path.join(tempDir, 'vendor/github.com/x/y/y.go'), 'package y\n\n// TODO: upstream\n// TODO: upstream\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should attribute markers to boundaries and count test, generated and vendored files apart', () => {
+    const inventory = new DebtInventoryScanner(tempDir).scan(boundaries);
+
+    expect(inventory.modules).toEqual([
+      { module: 'order', total: 5, by_category: { 'missing-implementation': 2, 'known-bug': 1, performance: 1, deprecation: 1 }, test: 1, generated: 1 },
+      { module: 'user', total: 0, by_category: {}, test: 0, generated: 0 },
+    ]);
+    expect(inventory.totals).toMatchObject({ source: 6, unassigned: 1, test: 1, generated: 1, vendor: 2 });
+
+    const [order, user] = attachDebt(boundaries, inventory);
+    expect(order.debt?.total).toBe(5);
+    expect(user.debt).toEqual({ total: 0, by_category: {}, test: 0, generated: 0 });
+
+    expect(topDebtFiles(inventory, 1)).toMatchObject([{ file: 'internal/order/refund.go', module: 'order' }]);
+  });
+
+  it('should write the report and record the counts for the run', () => {
+    const scanner = new DebtInventoryScanner(tempDir);
+    const inventory = scanner.scan(boundaries);
+    const store = new PerformanceStore(tempDir);
+    const runId = store.startRun('discover');
+
+    expect(fs.existsSync(scanner.write(inventory))).toBe(true);
+    scanner.recordMetrics(inventory, runId);
+
+    const metrics = new PerformanceStore(tempDir).getMetrics(runId);
+    expect(metrics.filter(m => m.metric === 'debt_markers').map(m => [m.labels?.module, m.value]))
+      .toEqual([['order', 5], ['user', 0]]);
+    expect(metrics.find(m => m.metric === 'debt_markers_excluded' && m.labels?.scope === 'vendor')?.value).toBe(2);
+    expect(metrics.find(m => m.metric === 'debt_markers_total')?.value).toBe(6);
+  });
+});
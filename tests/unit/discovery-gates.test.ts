@@ -0,0 +1,75 @@
+import { describe, it, expect } from 'vitest';
+import { checkDiscoveryGates, formatGateViolations, formatUnmeasuredGates, hasDiscoveryGates } from '../../src/core/utils/discovery-gates.js';
+import { DomainMap } from '../../src/core/types/config.js';
+
+function domainMap(boundaries: DomainMap['boundaries']): DomainMap {
+  return {
+    project: 'shop',
+    language: 'go',
+    analyzed_at: '2026-01-01T00:00:00.000Z',
+    total_files: 6,
+    boundaries,
+    metrics: { overall_cohesion: 0.5, overall_coupling: 0.4, modularity_score: 0.3 },
+  };
+}
+
+const map = domainMap([
+  { id: 'order-1', name: 'order', description: '', files: ['order/a.go', 'order/b.go', 'order/c.go'], metrics: { cohesion: 0.8, coupling: 0.2, complexity: 'low' } },
+  { id: 'billing-1', name: 'billing', description: '', files: ['billing/a.go'], metrics: { cohesion: 0.2, coupling: 0.7, complexity: 'low' } },
+  { name: 'legacy', description: '', files: ['legacy/a.go'], cohesion_score: 0.3 },
+  { name: 'payments', description: '', files: ['payments/a.go'], status: 'established', metrics: { cohesion: 0, coupling: 1, complexity: 'low' } },
+]);
+
+describe('Discovery gates', () => {
+  it('should report each module breaking a threshold', () => {
+    const report = checkDiscoveryGates(map, { minCohesion: 0.4, maxCoupling: 0.5, maxFilesPerModule: 2 });
+
+    expect(report.passed).toBe(false);
+    expect(report.modules).toBe(3);
+    expect(report.violations).toEqual([
+      { gate: 'max-coupling', module: 'billing', module_id: 'billing-1', actual: 0.7, threshold: 0.5 },
+      { gate: 'min-cohesion', module: 'billing', module_id: 'billing-1', actual: 0.2, threshold: 0.4 },
+      { gate: 'min-cohesion', module: 'legacy', actual: 0.3, threshold: 0.4 },
+      { gate: 'max-files-per-module', module: 'order', module_id: 'order-1', actual: 3, threshold: 2 },
+    ]);
+    expect(formatGateViolations(report)[0]).toBe('[max-coupling] billing: 結合度 0.70 > 0.5');
+  });
+
+  it('should pass when every module is within the thresholds and skip missing scores', () => {
+    const report = checkDiscoveryGates(map, { maxCoupling: 0.8, maxFilesPerModule: 3 });
+
+    expect(report.passed).toBe(true);
+    expect(report.violations).toEqual([]);
+    expect(report.unmeasured).toEqual([{ gate: 'max-coupling', module: 'legacy' }]);
+  });
+
+  it('should not gate on scores standing in from the discovery confidence', () => {
+    const estimated = domainMap([
+      { name: 'catalog', description: '', files: ['catalog/a.go'], cohesion_score: 0.1, coupling_score: 0.9, scores_estimated: true },
+      { name: 'search', description: '', files: ['search/a.go'], cohesion_score: 0.1, coupling_score: 0.9 },
+    ]);
+    const report = checkDiscoveryGates(estimated, { minCohesion: 0.4, maxCoupling: 0.5 });
+
+    expect(report.violations.map(v => [v.gate, v.module])).toEqual([['max-coupling', 'search'], ['min-cohesion', 'search']]);
+    expect(report.unmeasured).toEqual([{ gate: 'max-coupling', module: 'catalog' }, { gate: 'min-cohesion', module: 'catalog' }]);
+    expect(formatUnmeasuredGates(report)).toEqual([
+      '[min-cohesion] 計測値がないため未評価: catalog',
+      '[max-coupling] 計測値がないため未評価: catalog',
+    ]);
+  });
+
+  it('should order violations by code unit regardless of locale', () => {
+    const report = checkDiscoveryGates(domainMap([
+      { name: 'audit', description: '', files: ['audit/a.go', 'audit/b.go'] },
+      { name: 'Zeta', description: '', files: ['zeta/a.go', 'zeta/b.go'] },
+    ]), { maxFilesPerModule: 1 });
+
+    expect(report.violations.map(v => v.module)).toEqual(['Zeta', 'audit']);
+  });
+
+  it('should only gate when a threshold is set', () => {
+    expect(hasDiscoveryGates(undefined)).toBe(false);
+    expect(hasDiscoveryGates({})).toBe(false);
+    expect(hasDiscoveryGates({ maxFilesPerModule: 50 })).toBe(true);
+  });
+});
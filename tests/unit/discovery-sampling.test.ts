@@ -0,0 +1,126 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { errorBand, describeSample, parseSampleRate, selectSample } from '../../src/core/utils/discovery-sampling.js';
+import { loadGoPackages } from '../../src/core/utils/go-packages.js';
+import { ASTAnalyzer } from '../../src/core/utils/ast-analyzer.js';
+import { AnalysisCache } from '../../src/core/utils/analysis-cache.js';
+import { DomainMapWriter } from '../../src/core/utils/domain-map-writer.js';
+import { ArchitectAgent } from '../../src/core/agents/architect-agent.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const FILES = [
+  'internal/billing/invoice.go',
+  'internal/billing/tax.go',
+  'internal/billing/total.go',
+  'internal/billing/discount.go',
+  'internal/order/order.go',
+  'internal/order/place.go',
+  'internal/order/cancel.go',
+  'internal/order/refund.go',
+  'internal/order/status.go',
+  'internal/order/export.go',
+];
+
+describe('discovery sampling', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('discovery-sampling');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    for (const file of FILES) {
+      const pkg = path.basename(path.dirname(file));
+      const name = path.basename(file, '.go');
+      await createMockFile(path.join(tempDir, file), `package ${pkg}\n\nfunc ${name}Helper() {}\n`);
+    }
+    // Largest file of internal/order
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'),
+      `package order\n\ntype Order struct {\n\tID string\n}\n\n${'// history\n'.repeat(50)}`);
+    // Most referenced file of internal/order; also the largest of internal/billing imports it
+    await createMockFile(path.join(tempDir, 'internal/order/place.go'), 'package order\n\nfunc Place() {}\n');
+    await createMockFile(path.join(tempDir, 'internal/billing/invoice.go'),
+      `package billing\n\nimport "example.com/shop/internal/order"\n\nfunc Invoice() {\n\torder.Place()\n\torder.Place()\n}\n${'// notes\n'.repeat(20)}`);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should parse sample rates', () => {
+    expect(parseSampleRate('20%')).toBe(0.2);
+    expect(parseSampleRate('20')).toBe(0.2);
+    expect(parseSampleRate('0.2')).toBe(0.2);
+    expect(parseSampleRate('100%')).toBe(1);
+    expect(() => parseSampleRate('0%')).toThrow('Invalid sample rate');
+    expect(() => parseSampleRate('150%')).toThrow('Invalid sample rate');
+  });
+
+  it('should always keep the largest and most-imported file of each package and grow monotonically', () => {
+    const packages = loadGoPackages(tempDir);
+    const small = selectSample(tempDir, FILES, { rate: 0.4 }, packages);
+
+    expect(small.files).toHaveLength(4);
+    expect(small.files).toEqual(expect.arrayContaining([
+      'internal/billing/invoice.go',
+      'internal/order/order.go',
+      'internal/order/place.go',
+    ]));
+    expect(small).toMatchObject({ total: 10, skipped: 6 });
+    expect(small.coverage[0].coverage).toBeLessThanOrEqual(small.coverage[1].coverage);
+
+    const larger = selectSample(tempDir, FILES, { rate: 0.7 }, packages);
+    expect(larger.files).toEqual(expect.arrayContaining(small.files));
+    // Most-imported first, then by size
+    expect(selectSample(tempDir, FILES, { rate: 0.7, max_files: 2 }, packages).files).toEqual([
+      'internal/order/order.go',
+      'internal/order/place.go',
+    ]);
+  });
+
+  it('should describe the sample with an error band that vanishes at full coverage', () => {
+    const selection = selectSample(tempDir, FILES, { max_files: 5 }, loadGoPackages(tempDir));
+    const sampling = describeSample(selection, { max_files: 5 }, { overall_cohesion: 0.6, overall_coupling: 0.2 })!;
+
+    expect(sampling).toMatchObject({ max_files: 5, analyzed_files: 5, total_files: 10, skipped_files: 5 });
+    expect(sampling.error_band.cohesion).toBeGreaterThan(sampling.error_band.coupling);
+    expect(sampling.low_coverage_packages.every(p => p.coverage < 1)).toBe(true);
+    expect(errorBand(0.6, 10, 10)).toBe(0);
+    expect(describeSample(selectSample(tempDir, FILES, { rate: 1 }), { rate: 1 }, { overall_cohesion: 0, overall_coupling: 0 }))
+      .toBeUndefined();
+  });
+
+  it('should reuse analyzed files from the cache when the sample rate is raised', async () => {
+    await new ASTAnalyzer(tempDir, { sampling: { rate: 0.4 } }).analyzeGoProject();
+    const full = await new ASTAnalyzer(tempDir, { sampling: { rate: 1 } }).analyzeGoProject();
+
+    expect(full.sample).toMatchObject({ total: 10, skipped: 0 });
+    expect(full.functions.map(f => f.name)).toContain('Place');
+    const stats = new AnalysisCache(tempDir, { namespace: 'go-structure' }).stats();
+    expect(stats).toMatchObject({ entries: 10, total_hits: 4, total_misses: 10 });
+  });
+
+  it('should label plans from a sampled map exploratory and refuse to refactor from them', async () => {
+    const selection = selectSample(tempDir, FILES, { rate: 0.4 }, loadGoPackages(tempDir));
+    const metrics = { overall_cohesion: 0.5, overall_coupling: 0.3, modularity_score: 0.2 };
+    new DomainMapWriter(tempDir).write({
+      project: 'shop',
+      language: 'go',
+      analyzed_at: '2024-01-01T00:00:00.000Z',
+      total_files: selection.files.length,
+      boundaries: [{ name: 'order', description: 'orders', files: selection.files.filter(f => f.includes('order/')) }],
+      metrics,
+      sampling: describeSample(selection, { rate: 0.4 }, metrics),
+    });
+
+    const agent = new ArchitectAgent(tempDir, path.join(tempDir, 'vibeflow.config.yaml'), path.join(tempDir, 'boundary.yaml'));
+    const { plan, outputPath } = await agent.generateArchitecturalPlan(path.join(tempDir, '.vibeflow/domain-map.json'));
+
+    expect(plan.exploratory).toBe(true);
+    expect(plan.sampling).toMatchObject({ rate: 0.4, analyzed_files: 4, total_files: 10 });
+    expect(fs.readFileSync(outputPath, 'utf8')).toContain('**探索用 (exploratory)**');
+
+    await expect(new RefactorAgent(tempDir).executeRefactoring([], false)).rejects.toThrow('The plan is exploratory');
+    await expect(new RefactorAgent(tempDir).generateRefactorPlan(outputPath)).rejects.toThrow('--sample 40%');
+  });
+});
@@ -0,0 +1,71 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { ConfigLoader } from '../../src/core/utils/config-loader.js';
+import { discoveryScopes, findScopeEdges, resolveScopeRoot } from '../../src/core/utils/discovery-scopes.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+describe('discovery scopes', () => {
+  let tempDir: string;
+  const configure = (scopes: string[]) => ConfigLoader.saveConfig({
+    ...ConfigLoader.loadVibeFlowConfig(path.join(tempDir, 'missing.yaml')),
+    scopes,
+  }, path.join(tempDir, 'vibeflow.config.yaml'));
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('discovery-scopes');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/mono\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'pkg/log/log.go'), 'package log\n\nfunc Info(msg string) {}\n');
+    await createMockFile(path.join(tempDir, 'services/shop/catalog/catalog.go'), 'package catalog\n\ntype Product struct{}\n');
+    await createMockFile(path.join(tempDir, 'services/shop/order/order.go'), [
+      'package order',
+      '',
+      'import (',
+      '\t"fmt"',
+      '\t"example.com/billing/invoice"',
+      '\t"example.com/mono/pkg/log"',
+      '\t"example.com/mono/services/shop/catalog"',
+      ')',
+      '',
+      'func Place(p catalog.Product) { log.Info(fmt.Sprint(invoice.New())) }',
+      '',
+    ].join('\n'));
+    await createMockFile(path.join(tempDir, 'services/shop/order/cancel.go'), 'package order\n\nimport "example.com/billing/invoice"\n\nfunc Cancel() { invoice.Void() }\n');
+    // billing is a Go module of its own
+    await createMockFile(path.join(tempDir, 'services/billing/go.mod'), 'module example.com/billing\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'services/billing/invoice/invoice.go'), 'package invoice\n\nimport "example.com/mono/pkg/log"\n\nfunc New() int { log.Info("new"); return 1 }\n\nfunc Void() {}\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should report references that leave a scope, grouped by imported package', () => {
+    expect(findScopeEdges(tempDir, ['services/billing', 'services/shop'])).toEqual([
+      { from_scope: 'services/billing', to_scope: null, import_path: 'example.com/mono/pkg/log', files: ['services/billing/invoice/invoice.go'] },
+      {
+        from_scope: 'services/shop',
+        to_scope: 'services/billing',
+        import_path: 'example.com/billing/invoice',
+        files: ['services/shop/order/cancel.go', 'services/shop/order/order.go'],
+      },
+      { from_scope: 'services/shop', to_scope: null, import_path: 'example.com/mono/pkg/log', files: ['services/shop/order/order.go'] },
+    ]);
+  });
+
+  it('should take the scopes of the config and select the only one by default', () => {
+    expect(discoveryScopes(tempDir)).toEqual([]);
+    expect(resolveScopeRoot(tempDir)).toBe(tempDir);
+
+    configure(['services/shop']);
+    expect(discoveryScopes(tempDir)).toEqual(['services/shop']);
+    expect(discoveryScopes(tempDir, ['./services/billing/', 'services/billing'])).toEqual(['services/billing']);
+    expect(resolveScopeRoot(tempDir)).toBe(path.join(tempDir, 'services/shop'));
+
+    configure(['services/shop', 'services/billing']);
+    expect(() => resolveScopeRoot(tempDir)).toThrow('Several scopes are configured (services/shop, services/billing); choose one with --scope');
+    expect(resolveScopeRoot(tempDir, 'services/billing')).toBe(path.join(tempDir, 'services/billing'));
+    expect(() => resolveScopeRoot(tempDir, '..')).toThrow('must be a directory inside');
+    expect(() => resolveScopeRoot(tempDir, 'services/admin')).toThrow('Scope directory not found: services/admin');
+    expect(() => discoveryScopes(tempDir, ['services', 'services/shop'])).toThrow('Scope services/shop is nested in scope services');
+  });
+});
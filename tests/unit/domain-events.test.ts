@@ -0,0 +1,116 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { eventName, planDomainEvents, renderDomainEventsSection, renderEventsFile, renderEventsPromptSection } from '../../src/core/utils/domain-events.js';
+import { BoundaryCycle } from '../../src/core/types/config.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const STOCK = `package inventory
+
+import (
+	"context"
+	"time"
+)
+
+func (s *Service) Reserve(ctx context.Context, orderID string, skus []string, until time.Time) error {
+	return nil
+}
+
+func Notify(items []Item, urgent bool) {}
+`;
+
+const site = (caller: string, callee: string, callee_file = 'internal/inventory/stock.go') =>
+  ({ caller_file: 'internal/order/service.go', caller, callee_file, callee, count: 1 });
+
+const cycles: BoundaryCycle[] = [
+  {
+    modules: ['inventory', 'order'],
+    edges: [
+      { from: 'inventory', to: 'order', calls: 6, sites: [] },
+      { from: 'order', to: 'inventory', calls: 3, sites: [site('Service.PlaceOrder', 'Service.Reserve'), site('Service.PlaceOrder', 'Notify'), site('Cancel', 'Notify')] },
+    ],
+    break_at: { from: 'order', to: 'inventory', calls: 3, remedy: 'event' },
+  },
+  {
+    modules: ['billing', 'order'],
+    edges: [],
+    break_at: { from: 'billing', to: 'order', calls: 1, remedy: 'interface' },
+  },
+];
+
+describe('Domain events', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('domain-events');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/inventory/stock.go'), STOCK);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should name events after what the publisher did', () => {
+    expect(eventName('PlaceOrder', 'order')).toBe('OrderPlaced');
+    expect(eventName('Cancel', 'order')).toBe('OrderCancelled');
+    expect(eventName('shipParcel', 'delivery')).toBe('ParcelShipped');
+    expect(eventName('PayInvoice', 'billing')).toBe('InvoicePaid');
+  });
+
+  it('should turn the calls of a cut cycle edge into events with the handler parameters as payload', () => {
+    const plan = planDomainEvents(tempDir, cycles)!;
+
+    expect(plan.bus).toEqual({ package: 'internal/eventbus', import_path: 'example.com/shop/internal/eventbus' });
+    expect(plan.events).toEqual([
+      {
+        name: 'OrderPlaced',
+        producer: 'order',
+        publisher: { function: 'Service.PlaceOrder', file: 'internal/order/service.go' },
+        payload: [
+          { name: 'OrderID', type: 'string' },
+          { name: 'Skus', type: '[]string' },
+          { name: 'Until', type: 'time.Time' },
+          { name: 'Items', type: 'any', original: '[]Item' },
+          { name: 'Urgent', type: 'bool' },
+        ],
+        consumers: [
+          { module: 'inventory', handler: 'Service.Reserve', file: 'internal/inventory/stock.go' },
+          { module: 'inventory', handler: 'Notify', file: 'internal/inventory/stock.go' },
+        ],
+        package: 'internal/order/events',
+        import_path: 'example.com/shop/internal/order/events',
+      },
+      {
+        name: 'OrderCancelled',
+        producer: 'order',
+        publisher: { function: 'Cancel', file: 'internal/order/service.go' },
+        payload: [{ name: 'Items', type: 'any', original: '[]Item' }, { name: 'Urgent', type: 'bool' }],
+        consumers: [{ module: 'inventory', handler: 'Notify', file: 'internal/inventory/stock.go' }],
+        package: 'internal/order/events',
+        import_path: 'example.com/shop/internal/order/events',
+      },
+    ]);
+    expect(planDomainEvents(tempDir, [cycles[1]])).toBeUndefined();
+  });
+
+  it('should render the events package, the prompt instructions and the plan section', () => {
+    const plan = planDomainEvents(tempDir, cycles)!;
+
+    const file = renderEventsFile('order', plan.events);
+    expect(file).toContain('package events\n\nimport "time"\n');
+    expect(file).toContain('const OrderPlacedName = "order.OrderPlaced"');
+    expect(file).toContain('\tItems   any // TODO(vibeflow): was []Item; use a type of order or an ID\n');
+    expect(file).toContain('func (OrderCancelled) EventName() string { return OrderCancelledName }');
+
+    expect(renderEventsPromptSection('order', plan, 'internal/order/service.go')).toContain(
+      '- In Service.PlaceOrder, publish events.OrderPlaced{OrderID, Skus, Until, Items, Urgent} (`"example.com/shop/internal/order/events"`) on an injected *eventbus.Bus instead of calling inventory Service.Reserve, inventory Notify');
+    expect(renderEventsPromptSection('inventory', plan, 'internal/inventory/stock.go')).toContain(
+      '- Keep Notify and add a handler of events.OrderCancelled (`"example.com/shop/internal/order/events"`) calling it; the composition root subscribes it to events.OrderCancelledName');
+    expect(renderEventsPromptSection('order', plan, 'internal/order/repository.go')).toBe('');
+
+    const section = renderDomainEventsSection(plan);
+    expect(section).toContain('## ドメインイベント (Domain Events)');
+    expect(section).toContain('- **OrderPlaced**（`internal/order/events`）: order の Service.PlaceOrder が発行 → inventory の Service.Reserve、inventory の Notify が購読');
+    expect(section).toContain('  - ペイロード: `Items any`（元の型: []Item）, `Urgent bool`');
+  });
+});
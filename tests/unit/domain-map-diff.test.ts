@@ -0,0 +1,82 @@
+import { describe, it, expect } from 'vitest';
+import * as path from 'path';
+import { diffDomainMaps, formatDomainMapDiff } from '../../src/core/utils/domain-map-diff.js';
+import { DomainBoundary, DomainMap } from '../../src/core/types/config.js';
+
+const projectRoot = path.resolve('/tmp/shop');
+
+function domainMap(boundaries: DomainBoundary[], cohesion: number): DomainMap {
+  return {
+    project: 'shop',
+    language: 'go',
+    analyzed_at: '2026-01-01T00:00:00.000Z',
+    total_files: boundaries.reduce((sum, b) => sum + b.files.length, 0),
+    boundaries,
+    metrics: { overall_cohesion: cohesion, overall_coupling: 0.3, modularity_score: 0.5 },
+  };
+}
+
+function boundary(id: string, name: string, files: string[], cohesion: number, coupling: number): DomainBoundary {
+  return { id, name, description: '', files, cohesion_score: cohesion, coupling_score: coupling };
+}
+
+describe('domain map diff', () => {
+  const previous = domainMap([
+    boundary('order-1', 'order', ['internal/order/order.go', 'internal/order/cart.go'], 0.6, 0.4),
+    boundary('billing-1', 'billing', ['internal/billing/bill.go', 'internal/billing/legacy.go'], 0.5, 0.5),
+    boundary('report-1', 'report', ['internal/report/report.go'], 0.4, 0.6),
+  ], 0.5);
+  const current = domainMap([
+    // Renamed: matched by ID
+    boundary('order-1', 'ordering', ['internal/order/order.go', 'internal/order/checkout.go'], 0.7, 0.3),
+    boundary('billing-1', 'billing', [path.join(projectRoot, 'internal/billing/bill.go')], 0.5, 0.5),
+    boundary('cart-1', 'cart', ['internal/order/cart.go', 'internal/report/report.go'], 0.8, 0.2),
+  ], 0.6);
+
+  it('should report added, removed and moved files per boundary with score deltas', () => {
+    const diff = diffDomainMaps(projectRoot, previous, current, 'old/domain-map.json');
+
+    expect(diff.boundaries.map(b => [b.status, b.boundary])).toEqual([
+      ['added', 'cart'],
+      ['removed', 'report'],
+      ['changed', 'billing'],
+      ['changed', 'ordering'],
+    ]);
+    expect(diff.boundaries[3]).toMatchObject({
+      renamed_from: 'order',
+      added_files: ['internal/order/checkout.go'],
+      removed_files: [],
+      moved_in: [],
+      moved_out: [{ file: 'internal/order/cart.go', to: 'cart' }],
+      cohesion: { previous: 0.6, current: 0.7, delta: 0.1 },
+      coupling: { previous: 0.4, current: 0.3, delta: -0.1 },
+    });
+    expect(diff.boundaries[0].moved_in).toEqual([
+      { file: 'internal/order/cart.go', from: 'order' },
+      { file: 'internal/report/report.go', from: 'report' },
+    ]);
+    expect(diff.boundaries[1].moved_out).toEqual([{ file: 'internal/report/report.go', to: 'cart' }]);
+    expect(diff.boundaries[2].removed_files).toEqual(['internal/billing/legacy.go']);
+    expect(diff.summary).toEqual({ added: 1, removed: 1, changed: 2, unchanged: 0, added_files: 1, removed_files: 1, moved_files: 2 });
+    expect(diff.metrics.cohesion).toEqual({ previous: 0.5, current: 0.6, delta: 0.1 });
+  });
+
+  it('should find nothing to report for the same map', () => {
+    const diff = diffDomainMaps(projectRoot, previous, previous, 'domain-map.json');
+
+    expect(diff.boundaries.every(b => b.status === 'unchanged')).toBe(true);
+    expect(formatDomainMapDiff(diff)).toEqual([
+      '境界: 追加0 / 削除0 / 変更0 / 変更なし3',
+      'ファイル: 追加0 / 削除0 / 境界間の移動0',
+      '全体: 凝集度 0.50 → 0.50 (+0.00), 結合度 0.30 → 0.30 (+0.00), モジュール性 0.50 → 0.50 (+0.00)',
+    ]);
+  });
+
+  it('should summarize the changed boundaries on the console', () => {
+    const lines = formatDomainMapDiff(diffDomainMaps(projectRoot, previous, current, 'old/domain-map.json'));
+
+    expect(lines).toContain('+ cart: 移入2 (order, reportから)');
+    expect(lines).toContain('- report: 移出1 (cartへ)');
+    expect(lines).toContain('~ ordering: 旧名 order, +1ファイル, 移出1 (cartへ), 凝集度 0.60 → 0.70 (+0.10), 結合度 0.40 → 0.30 (-0.10)');
+  });
+});
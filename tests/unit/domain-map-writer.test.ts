@@ -0,0 +1,130 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  DOMAIN_MAP_SCHEMA_VERSION,
+  DomainMapWriter,
+  assignBoundaryIds,
+  boundaryId,
+  canonicalJson,
+  canonicalizeDomainMap,
+  migrateDomainMap,
+} from '../../src/core/utils/domain-map-writer.js';
+import { EnhancedBoundaryAgent } from '../../src/core/agents/enhanced-boundary-agent.js';
+import { DomainMap } from '../../src/core/types/config.js';
+import { createTempDir, cleanupTempDir, createMockGoProject } from '../setup.js';
+
+function domainMap(boundaries: DomainMap['boundaries']): DomainMap {
+  return {
+    project: 'shop',
+    language: 'go',
+    analyzed_at: '2024-01-01T00:00:00.000Z',
+    total_files: 4,
+    boundaries,
+    metrics: { overall_cohesion: 0.1 + 0.2, overall_coupling: 0.5, modularity_score: 0 },
+  };
+}
+
+describe('domain map canonical form', () => {
+  it('should derive IDs from the name and the seed file set', () => {
+    expect(boundaryId('User Account', ['b.go', 'a.go'])).toMatch(/^user-account-[0-9a-f]{8}$/);
+    expect(boundaryId('user', ['a.go', 'b.go'])).toBe(boundaryId('user', ['b.go', 'a.go']));
+    expect(boundaryId('user', ['a.go'])).not.toBe(boundaryId('user', ['b.go']));
+  });
+
+  it('should keep IDs across renames and small file moves', () => {
+    const [user, order] = assignBoundaryIds([
+      { name: 'user', description: '', files: ['user.go', 'profile.go'] },
+      { name: 'order', description: '', files: ['order.go'] },
+    ]);
+
+    const next = assignBoundaryIds([
+      { name: 'order', description: '', files: ['order.go', 'cart.go'] },
+      { name: 'account', description: '', files: ['user.go', 'profile.go', 'session.go'] },
+      { name: 'billing', description: '', files: ['invoice.go'] },
+    ], [user, order]);
+
+    expect(next.map(b => [b.name, b.id])).toEqual([
+      ['order', order.id],
+      ['account', user.id],
+      ['billing', boundaryId('billing', ['invoice.go'])],
+    ]);
+  });
+
+  it('should serialize the same analysis to the same bytes regardless of input order', () => {
+    const user = { id: 'user-1', name: 'user', description: '', files: ['b.go', 'a.go'],
+      dependencies: { internal: ['z.go', 'order'], external: [] }, cohesion_score: 2 / 3 };
+    const order = { id: 'order-1', name: 'order', description: '', files: ['o.go'], coupling_score: 0.1 + 0.2 };
+
+    const a = canonicalJson(canonicalizeDomainMap(domainMap([user, order])));
+    const b = canonicalJson(canonicalizeDomainMap(domainMap([order, { ...user, files: ['a.go', 'b.go'] }])));
+
+    expect(a).toBe(b);
+    const parsed = JSON.parse(a);
+    expect(parsed.boundaries.map((x: { id: string }) => x.id)).toEqual(['order-1', 'user-1']);
+    expect(parsed.boundaries[1].files).toEqual(['a.go', 'b.go']);
+    expect(parsed.boundaries[1].dependencies.internal).toEqual(['order', 'z.go']);
+    expect(parsed.boundaries[1].cohesion_score).toBe(0.6667);
+    expect(parsed.metrics.overall_cohesion).toBe(0.3);
+    expect(Object.keys(parsed)).toEqual([...Object.keys(parsed)].sort());
+  });
+});
+
+describe('domain map schema version', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('domain-map-version');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should upgrade unversioned maps and reject maps from a newer release', () => {
+    const legacy = { ...domainMap([{ name: 'order', description: '', files: ['order.go'] }]) };
+
+    expect(migrateDomainMap(legacy)).toEqual({ ...legacy, schema_version: DOMAIN_MAP_SCHEMA_VERSION });
+    expect(() => migrateDomainMap({ ...legacy, schema_version: DOMAIN_MAP_SCHEMA_VERSION + 1 })).toThrow(/newer than supported/);
+    expect(() => migrateDomainMap({ ...legacy, schema_version: '1' })).toThrow(/non-negative integer/);
+  });
+
+  it('should rewrite a map from an older release with the current version', () => {
+    const mapPath = path.join(tempDir, '.vibeflow', 'domain-map.json');
+    const map = domainMap([{ name: 'order', description: '', files: ['order.go'] }]);
+    const writer = new DomainMapWriter(tempDir);
+    fs.writeFileSync(mapPath, canonicalJson(canonicalizeDomainMap({ ...map, boundaries: assignBoundaryIds(map.boundaries) })));
+
+    expect(writer.load()?.schema_version).toBe(DOMAIN_MAP_SCHEMA_VERSION);
+    writer.write(map);
+    expect(JSON.parse(fs.readFileSync(mapPath, 'utf8')).schema_version).toBe(DOMAIN_MAP_SCHEMA_VERSION);
+
+    fs.writeFileSync(mapPath, JSON.stringify({ ...map, schema_version: DOMAIN_MAP_SCHEMA_VERSION + 1 }));
+    expect(writer.load()).toBeNull();
+  });
+});
+
+describe('discovery output', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('domain-map-determinism');
+    await createMockGoProject(tempDir);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should write byte-identical domain-map.json on repeated discovery', async () => {
+    const mapPath = path.join(tempDir, '.vibeflow', 'domain-map.json');
+
+    const first = await new EnhancedBoundaryAgent(tempDir).analyzeBoundaries();
+    const firstBytes = fs.readFileSync(mapPath);
+    const second = await new EnhancedBoundaryAgent(tempDir).analyzeBoundaries();
+
+    expect(fs.readFileSync(mapPath).equals(firstBytes)).toBe(true);
+    expect(second.domainMap.boundaries.map(b => b.id)).toEqual(first.domainMap.boundaries.map(b => b.id));
+    expect(first.domainMap.boundaries.every(b => b.id)).toBe(true);
+  });
+});
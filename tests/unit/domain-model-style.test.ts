@@ -0,0 +1,58 @@
+import { describe, it, expect } from 'vitest';
+import { attachDomainModels, classifyDomainModel, renderDomainModelSection } from '../../src/core/utils/domain-model-style.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+
+const fixtureRoot = './tests/fixtures/domain-model';
+const billing = ['billing/invoice.go', 'billing/service.go'];
+const order = ['order/order.go', 'order/service.go'];
+
+describe('Domain model style', () => {
+  it('should classify service functions setting entity fields as anemic', () => {
+    expect(classifyDomainModel(fixtureRoot, billing)).toEqual({
+      style: 'anemic',
+      entities: ['Invoice', 'Line'],
+      entity_methods: [],
+      script_functions: ['InvoiceService.AddLines', 'InvoiceService.MarkPaid', 'Void'],
+      rationale: '0 behaviour methods on entities, 3 service functions setting entity fields',
+    });
+  });
+
+  it('should classify entities guarding their own state as rich', () => {
+    const model = classifyDomainModel(fixtureRoot, order);
+
+    expect(model?.style).toBe('rich');
+    expect(model?.entity_methods).toEqual(['Order.AddItem', 'Order.Place']);
+    expect(model?.script_functions).toEqual([]);
+  });
+
+  it('should classify boundaries with logic in both places as mixed', () => {
+    expect(classifyDomainModel(fixtureRoot, [...billing, ...order])?.style).toBe('mixed');
+  });
+
+  it('should leave boundaries without entities or logic unclassified', () => {
+    expect(classifyDomainModel(fixtureRoot, ['order/service.go'])).toBeUndefined();
+    expect(classifyDomainModel(fixtureRoot, ['missing.go'])).toBeUndefined();
+  });
+
+  it('should attach the style to boundaries except established ones', () => {
+    const boundaries: DomainBoundary[] = [
+      { name: 'billing', description: '', files: billing },
+      { name: 'order', description: '', files: order, status: 'established' },
+    ];
+
+    const attached = attachDomainModels(fixtureRoot, boundaries);
+
+    expect(attached[0].domain_model?.style).toBe('anemic');
+    expect(attached[1].domain_model).toBeUndefined();
+  });
+
+  it('should render a transformation strategy for each style', () => {
+    const anemic = renderDomainModelSection(classifyDomainModel(fixtureRoot, billing));
+    const rich = renderDomainModelSection(classifyDomainModel(fixtureRoot, order));
+
+    expect(anemic).toContain('## Domain Model: anemic (transaction scripts)');
+    expect(anemic).toContain('service functions (InvoiceService.AddLines, InvoiceService.MarkPaid, Void)');
+    expect(rich).toContain('do not pull their logic out into services');
+    expect(renderDomainModelSection(undefined)).toBe('');
+  });
+});
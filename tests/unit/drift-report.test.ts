@@ -0,0 +1,109 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import {
+  analyzeDrift,
+  captureDriftBaseline,
+  loadDriftBaseline,
+  ruleCatalogFindings,
+  SHARED_KERNEL,
+  UNASSIGNED,
+} from '../../src/core/utils/drift-report.js';
+import { toSarif } from '../../src/core/utils/findings.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const ORDER = `package domain
+
+type Order struct{ Total int }
+
+func (o Order) Validate() bool {
+	if o.Total < 0 {
+		return false
+	}
+	return o.Qty == 0
+}
+`;
+
+describe('drift report', () => {
+  let tempDir: string;
+
+  const modules = [
+    { name: 'order', files: ['internal/order/domain/order.go', 'internal/order/infrastructure/repo.go'], dependencies: [] },
+    { name: 'billing', files: ['internal/billing/bill.go'], dependencies: ['order'] },
+  ];
+  const write = (file: string, content: string) => createMockFile(path.join(tempDir, file), content);
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('drift-report');
+    await write('go.mod', 'module example.com/shop\n\ngo 1.21\n');
+    await write('internal/order/domain/order.go', ORDER);
+    await write('internal/order/infrastructure/repo.go',
+      'package infrastructure\n\nimport "example.com/shop/internal/order/domain"\n\nfunc Save(o domain.Order) {}\n');
+    await write('internal/billing/bill.go',
+      'package billing\n\nimport "example.com/shop/internal/order/domain"\n\nfunc Bill(o domain.Order) bool {\n\treturn o.Total > 1000\n}\n');
+    await write('internal/shared/money.go', 'package shared\n\ntype Money int\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should report drift against the verified baseline with per-module percentages', async () => {
+    const baseline = captureDriftBaseline(tempDir, modules, 7);
+    expect(loadDriftBaseline(tempDir)).toEqual(baseline);
+    expect(baseline.dependencies).toEqual([['billing', 'order']]);
+    expect(baseline.shared_kernel.files).toEqual(['internal/shared/money.go']);
+
+    const rules = ruleCatalogFindings([
+      { type: 'calculation', description: 'large bills', code: 'o.Total > 1000', location: { file: 'internal/billing/bill.go', line: 6 }, dependencies: [], complexity: 'low' },
+      { type: 'validation', description: 'no negative totals', code: 'o.Total < 0', location: { file: 'internal/order/domain/order.go', line: 6 }, dependencies: [], complexity: 'low' },
+      { type: 'validation', description: 'empty orders', code: 'o.Qty == 0', location: { file: 'internal/order/domain/order.go', line: 9 }, dependencies: [], complexity: 'low' },
+    ]);
+
+    // Six weeks later
+    await write('internal/order/domain/order.go',
+      'package domain\n\nimport "example.com/shop/internal/order/infrastructure"\n\ntype Order struct{ Total int }\n\nvar _ = infrastructure.Save\n');
+    await write('internal/order/domain/discount.go', 'package domain\n\nfunc Valid(o Order) bool {\n\treturn !(o.Total < 0)\n}\n');
+    await write('internal/order/infrastructure/repo.go',
+      'package infrastructure\n\nimport (\n\t"example.com/shop/internal/billing"\n\t"example.com/shop/internal/order/domain"\n)\n\nfunc Save(o domain.Order) { billing.Bill(o) }\n');
+    await write('internal/shared/clock.go', 'package shared\n\nfunc Now() int { return 0 }\n');
+    await write('internal/invoice/invoice_pdf.go',
+      'package invoice\n\nimport "example.com/shop/internal/billing"\n\nvar _ = billing.Bill\n');
+
+    const report = analyzeDrift(tempDir, modules, { rules, accepted: ['order'] });
+
+    expect(report.baseline).toMatchObject({ source: 'verified', run_id: 7 });
+    expect(report.findings.map(f => [f.rule, f.severity, f.module, f.location.file, f.location.line])).toEqual([
+      ['unassigned-file', 'warning', UNASSIGNED, 'internal/invoice/invoice_pdf.go', 1],
+      ['rule-moved', 'note', 'order', 'internal/order/domain/discount.go', 4],
+      ['layer-violation', 'error', 'order', 'internal/order/domain/order.go', 3],
+      ['rule-missing', 'error', 'order', 'internal/order/domain/order.go', 9],
+      ['undeclared-dependency', 'warning', 'order', 'internal/order/infrastructure/repo.go', 4],
+      ['shared-kernel-growth', 'warning', SHARED_KERNEL, 'internal/shared/clock.go', 1],
+    ]);
+    expect(report.findings[0].suggested_boundary).toEqual({ module: 'billing', score: 1, reasons: ['imports billing', 'same directory tree'] });
+    expect(report.modules).toEqual([
+      { module: 'order', accepted: true, files: 3, drifted_files: 3, percent: 100 },
+      { module: 'billing', accepted: false, files: 1, drifted_files: 0, percent: 0 },
+      { module: SHARED_KERNEL, accepted: false, files: 2, drifted_files: 1, percent: 50 },
+      { module: UNASSIGNED, accepted: false, files: 1, drifted_files: 1, percent: 100 },
+    ]);
+    expect(report.score).toBe(71.4);
+    expect(report.shared_kernel).toMatchObject({ files: 2, baseline_files: 1 });
+
+    const sarif = toSarif(report.findings) as any;
+    expect(sarif.runs[0].tool.driver.rules.map((r: { id: string }) => r.id)).toContain('drift/layer-violation');
+  });
+
+  it('should fall back to the plan and flag forbidden dependencies without a baseline', async () => {
+    await write('internal/order/infrastructure/repo.go',
+      'package infrastructure\n\nimport "example.com/shop/internal/billing"\n\nvar _ = billing.Bill\n');
+
+    const report = analyzeDrift(tempDir, modules, { constraints: { forbiddenDependencies: [['order', 'billing']] } });
+
+    expect(report.baseline).toEqual({ source: 'plan' });
+    expect(report.findings.map(f => [f.rule, f.location.file])).toEqual([
+      ['forbidden-dependency', 'internal/order/infrastructure/repo.go'],
+      ['shared-kernel-growth', 'internal/shared/money.go'],
+    ]);
+  });
+});
@@ -0,0 +1,149 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  establishedModuleRefusal,
+  findEstablishedModules,
+  importEstablishedBoundaries,
+} from '../../src/core/utils/established-modules.js';
+import { ArchitectAgent, ArchitecturalPlan } from '../../src/core/agents/architect-agent.js';
+import { DomainMapWriter } from '../../src/core/utils/domain-map-writer.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const fixtureRoot = './tests/fixtures/established-modules';
+
+function boundary(name: string, files: string[], extra: Partial<DomainBoundary> = {}): DomainBoundary {
+  return {
+    name,
+    description: `${name} module`,
+    files,
+    dependencies: { internal: [], external: [] },
+    circular_dependencies: [],
+    ...extra,
+  };
+}
+
+const clustered = () => [
+  boundary('accounts', ['internal/user/user.go', 'modules/auth/auth.go', 'modules/auth/session/session.go']),
+  boundary('order', ['internal/order/order.go']),
+  boundary('billing', ['modules/billing/billing.go'], { id: 'billing-1234', description: 'Billing' }),
+];
+
+describe('established modules', () => {
+  it('should find nested go.mod modules and configured directories', () => {
+    expect(findEstablishedModules(fixtureRoot, ['modules/billing/'])).toEqual([
+      { name: 'auth', root: 'modules/auth', module_path: 'example.com/auth', source: 'go.mod' },
+      { name: 'billing', root: 'modules/billing', source: 'config' },
+    ]);
+    expect(findEstablishedModules(fixtureRoot)).toHaveLength(1);
+  });
+
+  it('should carve established modules out of the clusters as fixed boundaries', () => {
+    const established = findEstablishedModules(fixtureRoot, ['modules/billing']);
+    const result = importEstablishedBoundaries(fixtureRoot, clustered(), established);
+
+    expect(result.imported).toEqual(['auth', 'billing']);
+    expect(result.boundaries.map(b => [b.name, b.files, b.dependencies.internal, b.status])).toEqual([
+      ['accounts', ['internal/user/user.go'], ['auth'], undefined],
+      ['order', ['internal/order/order.go'], ['auth', 'billing'], undefined],
+      ['auth', ['modules/auth/auth.go', 'modules/auth/session/session.go'], [], 'established'],
+      ['billing', ['modules/billing/billing.go'], ['auth'], 'established'],
+    ]);
+
+    const [, , auth, billing] = result.boundaries;
+    expect(auth).toMatchObject({ description: '既存モジュール modules/auth', module_root: 'modules/auth', module_path: 'example.com/auth' });
+    expect(billing).toMatchObject({ id: 'billing-1234', description: 'Billing', module_root: 'modules/billing' });
+
+    expect(establishedModuleRefusal(auth)).toBe(
+      'Module auth is an established module (modules/auth) and is not refactored; rediscover with --reconsider-established auth to include it'
+    );
+    expect(establishedModuleRefusal(result.boundaries[0])).toBeNull();
+  });
+
+  it('should leave reconsidered modules to clustering and report unknown names', () => {
+    const established = findEstablishedModules(fixtureRoot, ['modules/billing']);
+    const result = importEstablishedBoundaries(fixtureRoot, clustered(), established, ['billing', 'payments']);
+
+    expect(result).toMatchObject({ imported: ['auth'], reconsidered: ['billing'], unknown: ['payments'] });
+    expect(result.boundaries.find(b => b.name === 'billing')).toMatchObject({ id: 'billing-1234', status: undefined });
+  });
+
+  describe('in the architectural plan', () => {
+    let tempDir: string;
+
+    const writeDomainMap = () => {
+      const established = findEstablishedModules(tempDir, ['modules/billing']);
+      new DomainMapWriter(tempDir).write({
+        project: 'shop',
+        language: 'go',
+        analyzed_at: '2026-01-01T00:00:00.000Z',
+        total_files: 5,
+        boundaries: importEstablishedBoundaries(tempDir, clustered(), established).boundaries,
+        metrics: { overall_cohesion: 0, overall_coupling: 0, modularity_score: 0 },
+      });
+    };
+
+    const generatePlan = async (): Promise<ArchitecturalPlan> => {
+      const agent = new ArchitectAgent(tempDir, path.join(tempDir, 'vibeflow.config.yaml'), path.join(tempDir, 'boundary.yaml'));
+      await agent.generateArchitecturalPlan(path.join(tempDir, '.vibeflow/domain-map.json'));
+      return JSON.parse(fs.readFileSync(path.join(tempDir, '.vibeflow/plan.json'), 'utf8'));
+    };
+
+    beforeEach(async () => {
+      tempDir = await createTempDir('established-modules');
+      fs.cpSync(fixtureRoot, tempDir, { recursive: true });
+    });
+
+    afterEach(async () => {
+      await cleanupTempDir(tempDir);
+    });
+
+    it('should keep established modules out of phases and list who uses their API', async () => {
+      writeDomainMap();
+      const plan = await generatePlan();
+
+      const auth = plan.modules.find(m => m.name === 'auth')!;
+      expect(auth.status).toBe('established');
+      expect(auth.refactoring_actions).toEqual([]);
+      expect(auth.established?.consumers).toEqual([
+        { module: 'accounts', symbols: ['modules/auth.User', 'modules/auth.Verify'] },
+        { module: 'billing', symbols: ['modules/auth.User'] },
+        { module: 'order', symbols: ['modules/auth.Verify', 'modules/auth/session.New'] },
+      ]);
+
+      const phased = plan.migration_strategy.phases.flatMap(phase => phase.modules);
+      expect(phased).not.toContain('auth');
+      expect(phased).not.toContain('billing');
+      expect(phased).toContain('order');
+
+      expect(plan.established_api_changes).toEqual([
+        {
+          module: 'billing',
+          proposal: 'shared-state',
+          description: 'modules/billing.Rates を order から直接使わない形に置き換える',
+          symbols: ['modules/billing.Rates'],
+        },
+      ]);
+
+      const markdown = fs.readFileSync(path.join(tempDir, '.vibeflow/plan.md'), 'utf8');
+      expect(markdown).toContain('## 確立済みモジュール (Established Modules)');
+      expect(markdown).toContain('- **auth** (`modules/auth`, `example.com/auth`)\n  - accounts: `modules/auth.User`, `modules/auth.Verify`');
+      expect(markdown).toContain('**状態**: established（`modules/auth`、再クラスタリング・リファクタリングの対象外）');
+      expect(markdown).toContain('- ⚠️ **billing** (共有ミュータブル状態): modules/billing.Rates を order から直接使わない形に置き換える');
+    });
+
+    it('should flag constraints that merge an established module with the symbols they affect', async () => {
+      await createMockFile(path.join(tempDir, 'boundary.yaml'), 'constraints:\n  mustMerge:\n    - [accounts, auth]\n');
+      writeDomainMap();
+      const plan = await generatePlan();
+
+      expect(plan.established_api_changes?.find(c => c.proposal === 'constraint')).toEqual({
+        module: 'auth',
+        proposal: 'constraint',
+        description: 'Merged accounts, auth (mustMerge)',
+        symbols: ['modules/auth.User', 'modules/auth.Verify', 'modules/auth/session.New'],
+      });
+    });
+  });
+});
@@ -0,0 +1,132 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { PerformanceStore, migratePerformanceData } from '../../src/core/utils/performance-store.js';
+import {
+  describeTransformations,
+  formatFileProcessing,
+  formatMethodTable,
+  summarizeProcessing,
+} from '../../src/core/utils/file-processing.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+const LEGACY = [
+  'package handlers',
+  '',
+  'import (',
+  '\t"database/sql"',
+  '\t"net/http"',
+  ')',
+  '',
+  'func GetUser(db *sql.DB, w http.ResponseWriter) {}',
+  '',
+].join('\n');
+
+describe('file_processing records', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('file-processing');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should add the new columns as nulls to rows of older stores', () => {
+    const data = migratePerformanceData({
+      schema_version: 3,
+      runs: [],
+      file_processing: [
+        { run_id: 1, file: 'internal/user/user.go', module: 'user', method: 'static', status: 'success', recorded_at: '2026-01-01T00:00:00.000Z' },
+      ],
+      performance_metrics: [],
+    });
+
+    expect(data.file_processing[0]).toEqual({
+      run_id: 1,
+      file: 'internal/user/user.go',
+      module: 'user',
+      method: 'static',
+      status: 'success',
+      recorded_at: '2026-01-01T00:00:00.000Z',
+      agent: null,
+      bytes_in: null,
+      bytes_out: null,
+      transformations: null,
+      verification: null,
+      template: null,
+      template_hash: null,
+    });
+  });
+
+  it('should filter records by run, agent and method', () => {
+    const store = new PerformanceStore(tempDir);
+    const first = store.startRun('refactor');
+    const second = store.startRun('refactor');
+    const record = (run_id: number, file: string, agent: string, method: 'llm' | 'static' | 'template') =>
+      store.recordFileProcessing({ run_id, file, module: 'user', agent, method, status: 'success' });
+    record(first, 'a.go', 'RefactorAgent', 'llm');
+    record(first, 'b.go', 'RefactorAgent', 'template');
+    record(first, 'c.go', 'BusinessLogicMigrationAgent', 'static');
+    record(second, 'd.go', 'BusinessLogicMigrationAgent', 'static');
+
+    const files = (filter: Parameters<PerformanceStore['queryFileProcessing']>[0]) =>
+      store.queryFileProcessing(filter).map(r => r.file);
+    expect(files({ agent: 'RefactorAgent' })).toEqual(['a.go', 'b.go']);
+    expect(files({ method: 'static' })).toEqual(['c.go', 'd.go']);
+    expect(files({ agent: 'BusinessLogicMigrationAgent', method: 'static', runId: second })).toEqual(['d.go']);
+    expect(files({ agent: 'RefactorAgent', method: 'static' })).toEqual([]);
+    expect(files({})).toHaveLength(4);
+
+    record(second, 'e.go', 'RefactorAgent', 'static');
+    expect(files({ agent: 'RefactorAgent', method: 'static' })).toEqual(['e.go']);
+  });
+
+  it('should describe what processing did to a file from its outputs', () => {
+    const source = { path: 'handlers/user.go', content: LEGACY };
+
+    expect(describeTransformations(source, [
+      { path: 'internal/user/domain/user.go', content: 'package domain\n\ntype User struct{}\n' },
+      { path: 'internal/user/infrastructure/user_repository.go', content: 'package infrastructure\n\nimport "database/sql"\n' },
+      { path: 'internal/user/handler/user_handler.go', content: 'package handler\n\nimport "net/http"\n' },
+      { path: 'internal/user/domain/user_test.go', content: 'package domain\n\nimport "testing"\n' },
+    ], 'template')).toEqual(['moved', 'package-renamed', 'split', 'template-entity', 'template-repository', 'template-handler']);
+
+    expect(describeTransformations(source, [
+      { path: 'handlers/user.go', content: LEGACY.replace('\t"database/sql"\n', '\t"github.com/jmoiron/sqlx"\n') },
+    ], 'llm')).toEqual(['imports-rewritten']);
+  });
+
+  it('should export every column and summarize each method', () => {
+    const records = [
+      {
+        run_id: 1, file: 'a.go', module: 'user', agent: 'RefactorAgent', method: 'template' as const, status: 'success' as const,
+        duration_ms: 40, bytes_in: 120, bytes_out: 300, transformations: ['moved' as const, 'split' as const],
+        verification: 'not-run' as const, template: 'builtin/clean-architecture', template_hash: 'abc', recorded_at: '2026-01-01T00:00:00.000Z',
+      },
+      {
+        run_id: 1, file: 'b.go', module: 'user', agent: 'RefactorAgent', method: 'llm' as const, status: 'failed' as const,
+        duration_ms: 2500, error: 'build failed, "go vet"', recorded_at: '2026-01-01T00:00:01.000Z',
+      },
+      { run_id: 1, file: 'c.go', module: 'user', method: 'llm' as const, status: 'success' as const, duration_ms: 1500, recorded_at: '2026-01-01T00:00:02.000Z' },
+    ];
+
+    const csv = formatFileProcessing(records, 'csv').trimEnd().split('\n');
+    expect(csv[0]).toBe('run_id,file,module,method,status,duration_ms,agent,bytes_in,bytes_out,transformations,verification,template,template_hash,fallback_reason,error,recorded_at');
+    expect(csv[1]).toBe('1,a.go,user,template,success,40,RefactorAgent,120,300,moved;split,not-run,builtin/clean-architecture,abc,,,2026-01-01T00:00:00.000Z');
+    expect(csv[2]).toContain(',"build failed, ""go vet""",');
+
+    const jsonl = formatFileProcessing(records, 'jsonl').trimEnd().split('\n');
+    expect(jsonl.map(line => JSON.parse(line))).toEqual(records);
+
+    expect(summarizeProcessing(records)).toEqual([
+      { method: 'llm', files: 2, failed: 1, avg_duration_ms: 2000 },
+      { method: 'template', files: 1, failed: 0, avg_duration_ms: 40 },
+    ]);
+    expect(formatMethodTable(summarizeProcessing(records), '')).toEqual([
+      'Method               Files  Failed     Avg',
+      'llm                      2       1    2.0s',
+      'template                 1       0    40ms',
+    ]);
+    expect(formatMethodTable([])).toEqual([]);
+  });
+});
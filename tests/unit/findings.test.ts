@@ -0,0 +1,158 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { SourceFile, formatLocation } from '../../src/core/utils/source-positions.js';
+import {
+  FindingsReporter,
+  businessRuleFindings,
+  constraintViolationFindings,
+  cycleEdgeFindings,
+  formatFinding,
+  toSarif,
+} from '../../src/core/utils/findings.js';
+import { CodeAnalyzer } from '../../src/core/utils/code-analyzer.js';
+import { ModuleManifestStore } from '../../src/core/utils/module-manifest.js';
+import { BusinessLogicMigrationAgent } from '../../src/core/agents/business-logic-migration-agent.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const fixturePath = './tests/fixtures/business-logic-samples.go';
+
+describe('SourceFile', () => {
+  it('should count columns and offsets in UTF-8 bytes', () => {
+    const source = new SourceFile('main.go', 'package main\n\nvar x = "日本"; y()\n');
+    const location = source.findSnippet('y()')!;
+
+    expect(location).toMatchObject({ file: 'main.go', line: 3, column: 19, end_line: 3, end_column: 22 });
+    expect(location.offset).toBe(Buffer.byteLength('package main\n\nvar x = "日本"; '));
+    expect(source.textAt(location)).toBe('y()');
+  });
+
+  it('should locate grouped imports and declarations in the business-logic fixture', () => {
+    const source = new SourceFile('business-logic-samples.go', fs.readFileSync(fixturePath, 'utf8'));
+
+    expect(formatLocation(source.findImport('regexp')!)).toBe('business-logic-samples.go:9:2');
+    expect(formatLocation(source.findDeclaration('CreateUser')!)).toBe('business-logic-samples.go:41:6');
+    expect(formatLocation(source.findDeclaration('isValidEmail')!)).toBe('business-logic-samples.go:70:6');
+  });
+
+  it('should skip matches inside comments', () => {
+    const source = new SourceFile('a.go', 'package a\n\n// call userExists(email) first\nfunc f() { userExists(email) }\n');
+    expect(formatLocation(source.findSnippet('userExists(email)')!)).toBe('a.go:4:12');
+  });
+});
+
+describe('findings', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('findings');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should locate business rules at their code in the fixture', async () => {
+    await createMockFile(path.join(tempDir, 'samples.go'), fs.readFileSync(fixturePath, 'utf8'));
+    const agent = new BusinessLogicMigrationAgent(tempDir, {
+      extractionLevel: 'basic',
+      patterns: { validations: true, calculations: true, workflows: true, dataAccess: true, errorHandling: true },
+      complexityThreshold: 'low',
+      language: 'go',
+      claudeCode: { enabled: false },
+    });
+
+    const { rules } = await agent.extractBusinessLogic('samples.go');
+    const email = rules.find(r => r.description === 'Email format validation')!;
+    const uniqueness = rules.find(r => r.description === 'User uniqueness check')!;
+
+    expect(email.location).toMatchObject({ file: 'samples.go', line: 43, column: 6, end_column: 25, function: 'CreateUser' });
+    expect(uniqueness.location).toMatchObject({ line: 53, column: 5 });
+
+    const findings = businessRuleFindings(tempDir, [email]);
+    expect(formatFinding(findings[0])).toBe('samples.go:43:6: note: Email format validation [business-rule/validation]');
+    expect(findings[0].snippet).toBe('isValidEmail(email)');
+  });
+
+  it('should locate forbidden dependencies at the import', async () => {
+    await createMockFile(path.join(tempDir, 'billing', 'invoice.go'),
+      'package billing\n\nimport (\n\t"fmt"\n\tuser "example.com/app/internal/user"\n)\n');
+
+    const findings = constraintViolationFindings(tempDir, [{
+      constraint: 'forbiddenDependencies',
+      modules: ['billing', 'user'],
+      message: 'billing must not depend on user',
+    }], [{ name: 'billing', files: ['billing/invoice.go'] }]);
+
+    expect(findings).toHaveLength(1);
+    expect(formatLocation(findings[0].location)).toBe('billing/invoice.go:5:7');
+    expect(findings[0].snippet).toBe('"example.com/app/internal/user"');
+  });
+
+  it('should report every edge of a dependency cycle', async () => {
+    await createMockFile(path.join(tempDir, 'a.ts'), "import { b } from './b.ts';\n");
+    await createMockFile(path.join(tempDir, 'b.ts'), "// b\nimport { a } from './a.ts';\n");
+    const analyzer = new CodeAnalyzer(tempDir);
+    const files = await analyzer.analyzeFiles(['*.ts']);
+    const cycles = analyzer.detectCircularDependencies(analyzer.buildDependencyGraph(files));
+
+    const findings = cycleEdgeFindings(analyzer, cycles, files);
+
+    expect(findings.map(f => formatLocation(f.location)).sort()).toEqual(['a.ts:1:19', 'b.ts:2:19']);
+    expect(findings[0].related).toHaveLength(1);
+  });
+
+  it('should emit the same positions in SARIF', () => {
+    const source = new SourceFile('a.go', 'package a\n\nfunc f() { g() }\n');
+    const location = source.findSnippet('g()')!;
+    const sarif = toSarif([{ kind: 'dead-code', rule: 'unused', severity: 'warning', message: 'g is unused', location }]) as any;
+
+    expect(sarif.runs[0].results[0]).toMatchObject({
+      ruleId: 'dead-code/unused',
+      level: 'warning',
+      locations: [{
+        physicalLocation: {
+          artifactLocation: { uri: 'a.go' },
+          region: { startLine: 3, startColumn: 12, endLine: 3, endColumn: 15, byteOffset: 22, byteLength: 3 },
+        },
+      }],
+    });
+  });
+
+  it('should recompute positions after apply using the module manifest', async () => {
+    await createMockFile(path.join(tempDir, 'user.go'), 'package main\n\nfunc CreateUser() {\n\tif userExists(email) {\n\t}\n}\n');
+    const source = SourceFile.read(tempDir, 'user.go')!;
+    const reporter = new FindingsReporter(tempDir);
+    reporter.write([{
+      kind: 'business-rule',
+      rule: 'constraint',
+      severity: 'note',
+      message: 'User uniqueness check',
+      location: source.findSnippet('userExists(email)')!,
+      snippet: 'userExists(email)',
+      symbol: 'CreateUser',
+    }]);
+
+    // Apply moved the function into the usecase layer
+    fs.unlinkSync(path.join(tempDir, 'user.go'));
+    await createMockFile(path.join(tempDir, 'internal', 'user', 'usecase', 'user_usecase.go'),
+      'package usecase\n\n// CreateUser registers a user\nfunc (s *UserService) CreateUser(email string) error {\n\tif s.repo.userExists(email) {\n\t\treturn ErrExists\n\t}\n\treturn nil\n}\n');
+    new ModuleManifestStore(tempDir).save({
+      module: 'user',
+      attempt: 1,
+      updated_at: new Date().toISOString(),
+      files: [{
+        path: 'internal/user/usecase/user_usecase.go',
+        source: 'user.go',
+        hash: 'h',
+        symbols: ['CreateUser'],
+        generated_at: new Date().toISOString(),
+      }],
+    });
+
+    expect(reporter.relocate()).toEqual({ relocated: 1, stale: 0 });
+    const finding = reporter.load()!.findings[0];
+    expect(formatLocation(finding.location)).toBe('internal/user/usecase/user_usecase.go:5:12');
+    expect(finding.relocated_from).toBe('user.go:4:5');
+  });
+});
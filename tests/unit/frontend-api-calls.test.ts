@@ -0,0 +1,102 @@
+import { describe, it, expect } from 'vitest';
+import {
+  attachExternalConsumers,
+  findFrontendApiCalls,
+  matchApiCalls,
+  parseApiCalls,
+  renderExternalConsumersSection,
+} from '../../src/core/utils/frontend-api-calls.js';
+import { HttpRoute } from '../../src/core/utils/http-routes.js';
+
+const fixtureRoot = './tests/fixtures/frontend-api';
+
+function route(method: string, path: string, handler: string, module: string): HttpRoute {
+  return { method, path, handler, file: `internal/${module}/handler.go`, registered_in: 'cmd/server/main.go', framework: 'echo', module };
+}
+
+const routes = [
+  route('GET', '/v1/orders', 'OrderHandler.List', 'order'),
+  route('GET', '/v1/orders/{id}', 'OrderHandler.Get', 'order'),
+  route('POST', '/v1/orders/{id}/cancel', 'OrderHandler.Cancel', 'order'),
+  route('POST', '/v1/payments', 'PaymentHandler.Create', 'payment'),
+];
+
+describe('Frontend API calls', () => {
+  it('should find fetch and axios requests with literal URLs outside tests', () => {
+    const calls = findFrontendApiCalls(fixtureRoot, ['web/src']);
+
+    expect(calls.map(call => [call.file, call.line, call.client, call.method, call.path])).toEqual([
+      ['web/src/api/orders.ts', 6, 'axios', 'GET', '/api/v1/orders'],
+      ['web/src/api/orders.ts', 11, 'axios', 'GET', '/api/v1/orders/{param}'],
+      ['web/src/api/orders.ts', 15, 'axios', 'POST', '/api/v1/orders/{param}/cancel'],
+      ['web/src/components/Checkout.tsx', 3, 'fetch', 'POST', '/api/v1/payments'],
+      ['web/src/components/Checkout.tsx', 8, 'fetch', 'GET', '/healthz'],
+    ]);
+    expect(findFrontendApiCalls(fixtureRoot, ['missing'])).toEqual([]);
+  });
+
+  it('should read axios.request configs and skip relative URLs', () => {
+    const calls = parseApiCalls(`
+axios.request({ url: '/v1/invoices', method: 'PUT' });
+axios.delete("/v1/invoices/" + id);
+fetch('relative/path');
+`, 'web/invoices.ts');
+
+    expect(calls.map(call => `${call.method} ${call.path}`)).toEqual(['PUT /v1/invoices', 'DELETE /v1/invoices']);
+  });
+
+  it('should match requests to routes after stripping the proxy prefix', () => {
+    const { matched, unmatched } = matchApiCalls(findFrontendApiCalls(fixtureRoot, ['web/src']), routes, { stripPrefix: '/api' });
+
+    expect(matched.map(({ call, route }) => `${call.method} ${call.path} → ${route.handler}`)).toEqual([
+      'GET /api/v1/orders → OrderHandler.List',
+      'GET /api/v1/orders/{param} → OrderHandler.Get',
+      'POST /api/v1/orders/{param}/cancel → OrderHandler.Cancel',
+      'POST /api/v1/payments → PaymentHandler.Create',
+    ]);
+    expect(unmatched.map(call => call.path)).toEqual(['/healthz']);
+  });
+
+  it('should prefer literal segments, methods over ANY and honor trailing wildcards', () => {
+    const call = (method: string, path: string) => ({ file: 'web/a.ts', line: 1, client: 'fetch' as const, method, path });
+    const candidates = [
+      route('GET', '/orders/{id}', 'OrderHandler.Get', 'order'),
+      route('GET', '/orders/export', 'ReportHandler.Export', 'report'),
+      route('ANY', '/orders/export', 'ReportHandler.Any', 'report'),
+      route('GET', '/static/*', 'Static', 'web'),
+    ];
+
+    const { matched, unmatched } = matchApiCalls(
+      [call('GET', '/orders/export'), call('GET', '/orders/{param}'), call('GET', '/static/js/app.js'), call('DELETE', '/orders/1')],
+      candidates
+    );
+
+    expect(matched.map(({ route }) => route.handler)).toEqual(['ReportHandler.Export', 'OrderHandler.Get', 'Static']);
+    expect(unmatched.map(c => `${c.method} ${c.path}`)).toEqual(['DELETE /orders/1']);
+  });
+
+  it('should record the consumers on the boundaries and render them for the plan', () => {
+    const { matched } = matchApiCalls(findFrontendApiCalls(fixtureRoot, ['web/src']), routes, { stripPrefix: '/api/' });
+    const boundaries = attachExternalConsumers([
+      { name: 'order', files: ['internal/order/handler.go'] },
+      { name: 'payment', files: ['internal/payment/handler.go'] },
+      { name: 'user', files: ['internal/user/handler.go'], external_consumers: [] },
+    ], matched);
+
+    expect(boundaries.map(b => b.external_consumers?.length)).toEqual([3, 1, undefined]);
+    expect(boundaries[1].external_consumers).toEqual([{
+      file: 'web/src/components/Checkout.tsx',
+      line: 3,
+      client: 'fetch',
+      method: 'POST',
+      path: '/api/v1/payments',
+      route: 'POST /v1/payments',
+      handler: 'PaymentHandler.Create',
+    }]);
+
+    const section = renderExternalConsumersSection([{ module: 'payment', consumers: boundaries[1].external_consumers! }]);
+    expect(section).toContain('## 外部コンシューマー (フロントエンド)');
+    expect(section).toContain('  - `POST /v1/payments` (PaymentHandler.Create): web/src/components/Checkout.tsx:3');
+    expect(renderExternalConsumersSection([])).toBe('');
+  });
+});
@@ -0,0 +1,101 @@
+import { describe, it, expect } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  checkValueCompatibility,
+  declaredFunctions,
+  formatIncompatibleValues,
+  functionValueCandidates,
+  scanFunctionValueUses,
+} from '../../src/core/utils/function-values.js';
+
+const fixtureRoot = './tests/fixtures/function-values';
+const serviceFile = 'internal/order/service.go';
+const service = fs.readFileSync(path.join(fixtureRoot, serviceFile), 'utf8');
+
+const REFACTORED = `package usecase
+
+import "context"
+
+type Service struct{}
+
+func (s *Service) Process(ctx context.Context, id string) error {
+	return nil
+}
+
+func (s *Service) Cancel(id string) error {
+	return s.Process(context.Background(), id)
+}
+
+func Validate(id string) bool {
+	return id != ""
+}
+`;
+
+const INTERFACE = `package domain
+
+import "context"
+
+type OrderService interface {
+	Process(ctx context.Context, id string) error
+	Cancel(id string) error
+}
+`;
+
+describe('function values', () => {
+  it('should parse declarations and find method values, registries and function variables', () => {
+    expect(declaredFunctions(service).map(f => [f.symbol, f.signature])).toEqual([
+      ['Service.Process', '(id string) error'],
+      ['Service.Cancel', '(id string) error'],
+      ['Validate', '(id string) bool'],
+      ['Audit', '(id string)'],
+    ]);
+
+    const uses = scanFunctionValueUses(fixtureRoot, serviceFile, service);
+    expect(uses.map(u => [u.symbol, u.kind, u.context, u.function, `${u.location.file}:${u.location.line}`])).toEqual([
+      ['Service.Process', 'method-value', 'argument', 'main', 'cmd/server/main.go:16'],
+      ['Service.Process', 'method-expression', 'variable', 'main', 'cmd/server/main.go:18'],
+      ['Validate', 'func-value', 'variable', 'main', 'cmd/server/main.go:21'],
+      ['Validate', 'func-value', 'registry', '', 'internal/order/registry.go:5'],
+      ['Audit', 'func-value', 'variable', '', 'internal/order/registry.go:9'],
+      ['Service.Cancel', 'method-value', 'return', 'Route', 'internal/order/registry.go:13'],
+    ]);
+    expect(functionValueCandidates(fs.readFileSync(path.join(fixtureRoot, 'cmd/server/main.go'), 'utf8')))
+      .toEqual(expect.arrayContaining(['svc.Process', 'order.Service.Process', 'order.Validate']));
+  });
+
+  it('should keep the old signature behind an adapter when only a context was added', () => {
+    const uses = scanFunctionValueUses(fixtureRoot, serviceFile, service);
+    const outputs = [
+      { path: 'internal/order/usecase/order_service.go', content: REFACTORED },
+      { path: 'internal/order/domain/service.go', content: INTERFACE },
+    ];
+
+    const { outputs: adapted, checks } = checkValueCompatibility(declaredFunctions(service), uses, outputs);
+
+    expect(checks.map(c => [c.symbol, c.status, c.renamed_to, c.uses.length])).toEqual([
+      ['Service.Process', 'adapter', 'ProcessContext', 2],
+      ['Service.Cancel', 'compatible', undefined, 1],
+      ['Validate', 'compatible', undefined, 2],
+    ]);
+    expect(adapted[0].content).toContain('func (s *Service) ProcessContext(ctx context.Context, id string) error {');
+    expect(adapted[0].content).toContain('return s.ProcessContext(context.Background(), id)');
+    expect(adapted[0].content).toContain('func (s *Service) Process(id string) error {\n\treturn s.ProcessContext(context.TODO(), id)\n}');
+    expect(adapted[1].content).toContain('\tProcessContext(ctx context.Context, id string) error');
+  });
+
+  it('should list the value-usage sites when the signature changed otherwise', () => {
+    const uses = scanFunctionValueUses(fixtureRoot, serviceFile, service);
+    const outputs = [{ path: 'internal/order/usecase/validate.go', content: 'package usecase\n\nfunc Validate(id string, strict bool) bool {\n\treturn id != ""\n}\n' }];
+
+    const { outputs: unchanged, checks } = checkValueCompatibility(declaredFunctions(service), uses, outputs);
+
+    expect(unchanged).toEqual(outputs);
+    expect(checks.map(c => [c.symbol, c.status])).toEqual([['Validate', 'incompatible']]);
+    expect(formatIncompatibleValues(checks)).toContain([
+      '  Validate: (id string) bool → (id string, strict bool) bool',
+      '    cmd/server/main.go:21:17 (func-value, variable in main)',
+      '    internal/order/registry.go:5:14 (func-value, registry)',
+    ].join('\n'));
+  });
+});
@@ -0,0 +1,53 @@
+import { describe, it, expect } from 'vitest';
+import { assignGeneratedModules, findGeneratedCode, generatedReason, renderGeneratedCodeSection } from '../../src/core/utils/generated-code.js';
+
+const fixtureRoot = './tests/fixtures/generated-code';
+const goFiles = [
+  'api/orderpb/order.pb.go',
+  'internal/order/mocks/repository.go',
+  'internal/order/repository.go',
+  'internal/order/status.go',
+  'internal/order/status_string.go',
+  'internal/payment/config_gen.go',
+  'internal/payment/gateway.go',
+  'internal/payment/mock_gateway.go',
+];
+
+describe('Generated code', () => {
+  it.each([
+    ['api/orderpb/order.pb.go', 'package orderpb\n', 'protobuf'],
+    ['internal/payment/config_gen.go', 'package payment\n', 'gen-suffix'],
+    ['internal/payment/mock_gateway.go', 'package payment\n', 'mock'],
+    ['internal/order/status_string.go', '// Code generated by "stringer -type=Status"; DO NOT EDIT.\n\npackage order\n', 'header'],
+    ['internal/order/status.go', 'package order\n\n// Code generated by hand. DO NOT EDIT.\n', undefined],
+  ])('should classify %s', (file, content, reason) => {
+    expect(generatedReason(file, content)).toBe(reason);
+  });
+
+  it('should link generated files to the go:generate directive producing them', () => {
+    const generated = findGeneratedCode(fixtureRoot, goFiles);
+
+    expect(generated.map(entry => [entry.file, entry.reason, entry.generator, entry.directive && `${entry.directive.file}:${entry.directive.line}`])).toEqual([
+      ['api/orderpb/order.pb.go', 'protobuf', 'protoc-gen-go', undefined],
+      ['internal/order/mocks/repository.go', 'header', 'mockgen', 'internal/order/repository.go:3'],
+      ['internal/order/status_string.go', 'header', 'stringer', 'internal/order/status.go:3'],
+      ['internal/payment/config_gen.go', 'gen-suffix', undefined, 'internal/payment/gateway.go:7'],
+      ['internal/payment/mock_gateway.go', 'mock', undefined, 'internal/payment/gateway.go:7'],
+    ]);
+  });
+
+  it('should record the module owning each go:generate source and render it for the plan', () => {
+    const generated = assignGeneratedModules(findGeneratedCode(fixtureRoot, goFiles), [
+      { name: 'order', files: ['internal/order/repository.go', 'internal/order/status.go'] },
+      { name: 'payment', files: ['internal/payment/gateway.go'] },
+    ]);
+
+    expect(generated.map(entry => entry.module)).toEqual([undefined, 'order', 'order', 'payment', 'payment']);
+
+    const section = renderGeneratedCodeSection(generated);
+    expect(section).toContain('## 生成コード (go:generate)');
+    expect(section).toContain('- internal/order/repository.go:3 (order): `mockgen -source=repository.go -destination=mocks/repository.go -package=mocks` → internal/order/mocks/repository.go');
+    expect(section).toContain('ディレクティブが見つからない生成コードが1ファイルあります');
+    expect(renderGeneratedCodeSection([])).toBe('');
+  });
+});
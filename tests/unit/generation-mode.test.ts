@@ -0,0 +1,137 @@
+import { describe, it, expect, beforeEach, afterEach, vi } from 'vitest';
+import * as http from 'http';
+import * as net from 'net';
+import * as path from 'path';
+import { ClaudeCodeClient } from '../../src/core/utils/claude-code-client.js';
+import { LlmUnavailableError } from '../../src/core/utils/llm-call-guard.js';
+import { OfflineNetworkError, assertOnline, enforceOffline, isOffline } from '../../src/core/utils/offline-guard.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { GenerationMode, RefactoredFile } from '../../src/core/types/refactor.js';
+import { PerformanceStore, findFallbackRuns } from '../../src/core/utils/performance-store.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+vi.mock('../../src/core/utils/claude-code-integration.js', () => ({
+  ClaudeCodeIntegration: class {
+    async transformCode(): Promise<never> {
+      throw new Error('connect ECONNRESET api.anthropic.com');
+    }
+  },
+}));
+
+const prompt = 'File: internal/order/order.go\nBoundary: order\n\n```go\npackage order\n\nfunc Place() {}\n```\n';
+
+describe('ClaudeCodeClient generation mode', () => {
+  const client = (generationMode: GenerationMode) => new ClaudeCodeClient({ cwd: '.', maxTurns: 1, systemPrompt: '', generationMode });
+
+  it('should label an unavailable LLM as template-fallback in auto mode', async () => {
+    const { generation } = await client('auto').queryWithGeneration(prompt);
+
+    expect(generation).toEqual({ method: 'template-fallback', fallback_reason: 'connect ECONNRESET api.anthropic.com' });
+  });
+
+  it('should throw instead of falling back in llm mode and label template mode as intentional', async () => {
+    await expect(client('llm').queryWithGeneration(prompt)).rejects.toThrow(LlmUnavailableError);
+    expect((await client('template').queryWithGeneration(prompt)).generation).toEqual({ method: 'template' });
+  });
+});
+
+describe('offline guard', () => {
+  let restore: () => void = () => {};
+
+  afterEach(() => {
+    restore();
+  });
+
+  it('should make network access fail fast and keep local sockets working', async () => {
+    restore = enforceOffline();
+
+    expect(isOffline()).toBe(true);
+    expect(() => http.get('http://api.anthropic.com/')).toThrow(OfflineNetworkError);
+    expect(() => net.connect(443, 'api.anthropic.com')).toThrow('Network access attempted in offline mode: api.anthropic.com:443');
+    await expect(fetch('https://api.anthropic.com/v1/messages')).rejects.toThrow(OfflineNetworkError);
+    expect(() => assertOnline('Claude Code SDK')).toThrow(OfflineNetworkError);
+    expect(process.env.GOPROXY).toBe('off');
+
+    const ipc = net.connect(path.join(process.cwd(), 'missing.sock'));
+    await new Promise(resolve => ipc.on('error', resolve));
+
+    restore();
+    expect(isOffline()).toBe(false);
+    expect(() => assertOnline('Claude Code SDK')).not.toThrow();
+  });
+});
+
+class FakeGenerationAgent extends RefactorAgent {
+  constructor(projectRoot: string, mode: GenerationMode, private respond: (file: string) => RefactoredFile) {
+    super(projectRoot, mode);
+  }
+
+  protected async requestTransformation(prompt: string): Promise<RefactoredFile> {
+    return this.respond(prompt.match(/File: ([^\n]+)/)![1]);
+  }
+}
+
+describe('RefactorAgent fallback handling', () => {
+  let tempDir: string;
+  const output = (file: string) => ({
+    refactored_files: [{ path: `internal/order/domain/${path.basename(file)}`, content: 'package domain\n', description: '' }],
+    interfaces: [],
+    tests: [],
+  });
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('generation-mode');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'legacy/order.go'), 'package legacy\n\nfunc PlaceOrder() {}\n');
+    await createMockFile(path.join(tempDir, 'legacy/refund.go'), 'package legacy\n\nfunc Refund() {}\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  const boundary = () => ({
+    id: 'bnd-order',
+    name: 'order',
+    description: '',
+    files: [path.join(tempDir, 'legacy/order.go'), path.join(tempDir, 'legacy/refund.go')],
+  });
+
+  it('should record template-fallback files and report the downgraded module', async () => {
+    const store = new PerformanceStore(tempDir);
+    const runId = store.startRun('refactor-module');
+    const agent = new FakeGenerationAgent(tempDir, 'auto', file => file.endsWith('refund.go')
+      ? { ...output(file), generation: { method: 'template-fallback', fallback_reason: 'rate limited' } }
+      : { ...output(file), generation: { method: 'llm' } });
+
+    const result = await agent.executeRefactoring([boundary()], false);
+    store.finishRun(runId, { status: 'success' });
+
+    expect(result.fallback_modules).toEqual([{ module: 'order', files: 1, reason: 'rate limited' }]);
+    const records = new PerformanceStore(tempDir).getFileProcessing(runId);
+    expect(records.map(r => [r.file, r.method, r.module, r.module_name])).toEqual([
+      ['legacy/order.go', 'llm', 'bnd-order', 'order'],
+      ['legacy/refund.go', 'template-fallback', 'bnd-order', 'order'],
+    ]);
+    expect(findFallbackRuns(new PerformanceStore(tempDir))).toMatchObject([
+      { run_id: runId, modules: [{ module: 'bnd-order', module_name: 'order', files: 1, reasons: ['rate limited'] }] },
+    ]);
+  });
+
+  it('should fail the whole module when the LLM is required but unavailable', async () => {
+    const agent = new FakeGenerationAgent(tempDir, 'llm', file => {
+      if (file.endsWith('refund.go')) throw new LlmUnavailableError('connect ECONNRESET');
+      return output(file);
+    });
+
+    const result = await agent.executeRefactoring([boundary()], true);
+
+    expect(result.applied_patches).toEqual([]);
+    expect(result.created_files).toEqual([]);
+    expect(result.failed_patches.map(f => [path.basename(f.file), f.category])).toEqual([
+      ['order.go', 'llm'],
+      ['refund.go', 'llm'],
+    ]);
+    expect(result.fallback_modules).toBeUndefined();
+  });
+});
@@ -0,0 +1,37 @@
+import { describe, it, expect } from 'vitest';
+import { assignGlobalCouplingModules, findGlobalCoupling } from '../../src/core/utils/global-coupling.js';
+
+const fixtureRoot = './tests/fixtures/global-coupling';
+
+describe('Global coupling', () => {
+  it('should find packages sharing mutable globals or coupled through init()', () => {
+    const couplings = findGlobalCoupling(fixtureRoot);
+
+    expect(couplings).toEqual([
+      { from: 'cmd/app', to: 'internal/codec/json', kind: 'init-side-effect', symbols: ['init()'], files: ['cmd/app/main.go'], exclusive: true },
+      { from: 'internal/codec/json', to: 'internal/codec', kind: 'init-side-effect', symbols: ['Register'], files: ['internal/codec/json/json.go'], exclusive: true },
+      { from: 'internal/server', to: 'internal/config', kind: 'global-state', symbols: ['Current'], files: ['internal/server/server.go'], exclusive: false },
+      { from: 'internal/worker', to: 'internal/config', kind: 'global-state', symbols: ['Current'], files: ['internal/worker/worker.go'], exclusive: true },
+    ]);
+  });
+
+  it('should not count variables that are never assigned after their declaration', () => {
+    const couplings = findGlobalCoupling(fixtureRoot);
+
+    expect(couplings.some(coupling => coupling.symbols.includes('ErrMissing'))).toBe(false);
+  });
+
+  it('should attach the boundaries of both packages', () => {
+    const couplings = assignGlobalCouplingModules(findGlobalCoupling(fixtureRoot), [
+      { name: 'platform', files: ['internal/config/config.go', 'internal/codec/registry.go', 'internal/codec/json/json.go'] },
+      { name: 'api', files: ['internal/server/server.go', 'internal/worker/worker.go'] },
+    ]);
+
+    expect(couplings.map(coupling => [coupling.from_module, coupling.to_module])).toEqual([
+      [undefined, 'platform'],
+      ['platform', 'platform'],
+      ['api', 'platform'],
+      ['api', 'platform'],
+    ]);
+  });
+});
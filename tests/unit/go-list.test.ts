@@ -0,0 +1,54 @@
+import { describe, it, expect } from 'vitest';
+import { execSync } from 'child_process';
+import * as path from 'path';
+import { listGoPackages, parseGoListOutput } from '../../src/core/utils/go-list.js';
+import { loadGoPackages } from '../../src/core/utils/go-packages.js';
+
+const fixtureRoot = './tests/fixtures/build-constraints';
+
+function hasGo(): boolean {
+  try {
+    execSync('go version', { stdio: 'ignore' });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+describe('go list packages', () => {
+  it('should read the packages of the JSON stream relative to the project', () => {
+    const root = path.resolve('/repo');
+    const output = [
+      JSON.stringify({ Dir: path.join(root, 'internal/order'), ImportPath: 'example.com/shop/internal/order', Name: 'order', GoFiles: ['order.go'], IgnoredGoFiles: ['order_plan9.go', 'gen_test.go'] }, null, '\t'),
+      // Broken files stay in their package for the syntax-only analysis
+      JSON.stringify({ Dir: root, ImportPath: 'example.com/shop', Name: 'main', GoFiles: ['main.go'], InvalidGoFiles: ['broken.go'] }, null, '\t'),
+      JSON.stringify({ Dir: path.join(root, 'internal/order/fixtures'), ImportPath: 'example.com/shop/internal/order/fixtures', Name: 'fixtures', TestGoFiles: ['x_test.go'] }, null, '\t'),
+      JSON.stringify({ ImportPath: 'example.com/missing', Error: { Err: 'cannot find module' } }, null, '\t'),
+    ].join('\n') + '\n';
+
+    expect(parseGoListOutput(root, output)).toEqual({
+      packages: [
+        { import_path: 'example.com/shop', dir: '.', name: 'main', files: ['broken.go', 'main.go'] },
+        { import_path: 'example.com/shop/internal/order', dir: 'internal/order', name: 'order', files: ['internal/order/order.go'] },
+      ],
+      ignored_files: ['internal/order/order_plan9.go'],
+    });
+  });
+
+  it.skipIf(!hasGo())('should list only the files the toolchain builds', () => {
+    const listed = listGoPackages(fixtureRoot)!;
+
+    // vendor/, testdata/ and internal/legacy (built with -tags=legacy only) are left out; plugins is a module of its own
+    expect(listed.packages.map(pkg => [pkg.import_path, pkg.files])).toEqual([
+      ['example.com/shop/internal/order', ['internal/order/order.go']],
+      ['example.com/shop/plugins/audit', ['plugins/audit/audit.go']],
+      ['example.com/shop/tools', ['tools/tools.go']],
+    ]);
+    expect(listed.ignored_files).toEqual(['internal/order/order_plan9.go', 'tools/gen.go']);
+    expect(loadGoPackages(fixtureRoot)).toEqual(listed.packages);
+  });
+
+  it('should not list packages of a directory that is not a Go project', () => {
+    expect(listGoPackages('./tests/fixtures/third-party/internal')).toBeNull();
+  });
+});
@@ -0,0 +1,92 @@
+import { describe, it, expect } from 'vitest';
+import * as fs from 'fs';
+import { checkGoSyntax, findPackageLoadErrors } from '../../src/core/utils/go-load-check.js';
+import { ASTAnalyzer } from '../../src/core/utils/ast-analyzer.js';
+import { AutoBoundaryDiscovery } from '../../src/core/utils/auto-boundary-discovery.js';
+import { markDegradedFiles } from '../../src/core/agents/enhanced-boundary-agent.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+const fixtureRoot = './tests/fixtures/broken-package';
+
+describe('checkGoSyntax', () => {
+  it('should accept valid code with brackets inside strings and comments', () => {
+    expect(checkGoSyntax('// header {\npackage a\n\nvar s = "}" + `)`\n/* ( */\nfunc f() { _ = \'{\' }\n')).toBeNull();
+  });
+
+  it('should report the first error with its position', () => {
+    expect(checkGoSyntax('func f() {}\n')).toEqual({ line: 1, column: 1, message: "expected 'package', found 'func'" });
+    expect(checkGoSyntax('package a\n\nfunc f() {\n')).toEqual({ line: 4, column: 1, message: "expected '}', found 'EOF'" });
+    expect(checkGoSyntax('package a\n\nfunc f() { g(] }\n')).toEqual({ line: 3, column: 14, message: "expected ')', found ']'" });
+    expect(checkGoSyntax('package a\n\nvar s = "open\n')).toEqual({ line: 3, column: 9, message: 'newline in string' });
+  });
+});
+
+describe('partial analysis of packages that fail to load', () => {
+  it('should collect errors per broken package', () => {
+    const files = ['internal/user/user.go', 'internal/billing/invoice.go', 'internal/report/report.go'];
+
+    expect(findPackageLoadErrors(fixtureRoot, files)).toEqual([
+      {
+        package: 'internal/billing',
+        files: ['internal/billing/invoice.go'],
+        errors: ["internal/billing/invoice.go:13:1: expected '}', found 'EOF'"],
+      },
+      {
+        package: 'internal/report',
+        files: ['internal/report/report.go'],
+        errors: ['internal/report/report.go:6:2: could not import example.com/shop/internal/gen/reportpb (no Go files in internal/gen/reportpb)'],
+      },
+    ]);
+  });
+
+  it('should fall back to syntax-only analysis instead of aborting', async () => {
+    const analysis = await new ASTAnalyzer(fixtureRoot).analyzeGoProject();
+
+    expect([...analysis.degraded_files].sort()).toEqual(['internal/billing/invoice.go', 'internal/report/report.go']);
+    expect(analysis.structs.map(s => s.name).sort()).toEqual(['Invoice', 'User']);
+    expect(analysis.interfaces.map(i => i.name)).toEqual(['UserRepository']);
+    expect(analysis.functions.find(f => f.name === 'CreateInvoice')).toMatchObject({
+      file: 'internal/billing/invoice.go',
+      line: 11,
+      dependencies: ['example.com/shop/internal/user'],
+    });
+    expect(analysis.functions.find(f => f.name === 'Render')?.dependencies).toEqual(['fmt', 'example.com/shop/internal/gen/reportpb']);
+  });
+
+  it('should report broken packages from discovery', async () => {
+    const result = await new AutoBoundaryDiscovery(fixtureRoot).discoverBoundaries();
+
+    expect(result.load_errors?.map(e => e.package)).toEqual(['internal/billing', 'internal/report']);
+    for (const boundary of result.discovered_boundaries) {
+      for (const file of boundary.degraded_files ?? []) {
+        expect(['internal/billing/invoice.go', 'internal/report/report.go']).toContain(file);
+      }
+    }
+  });
+
+  it('should mark degraded files in the domain map and refuse their module', async () => {
+    const [billing, user] = markDegradedFiles([
+      { name: 'billing', description: 'billing', files: ['internal/billing/invoice.go'] },
+      { name: 'user', description: 'user', files: ['internal/user/user.go'] },
+    ], findPackageLoadErrors(fixtureRoot, ['internal/billing/invoice.go', 'internal/user/user.go']));
+
+    expect(billing.degraded_files).toEqual([{
+      file: 'internal/billing/invoice.go',
+      analysis: 'degraded',
+      error: "internal/billing/invoice.go:13:1: expected '}', found 'EOF'",
+    }]);
+    expect(user.degraded_files).toBeUndefined();
+
+    // The agent writes .vibeflow/, so it runs on a copy of the fixture
+    const tempDir = await createTempDir('go-load-check');
+    fs.cpSync(fixtureRoot, tempDir, { recursive: true });
+    try {
+      const result = await new RefactorAgent(tempDir).executeRefactoring([billing], false);
+      expect(result.failed_patches).toHaveLength(1);
+      expect(result.failed_patches[0].error).toMatch(/failed to load .*--allow-degraded/);
+    } finally {
+      await cleanupTempDir(tempDir);
+    }
+  });
+});
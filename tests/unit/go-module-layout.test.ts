@@ -0,0 +1,135 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import {
+  addRootDirectives,
+  parseGoModuleLayout,
+  planGoModuleLayout,
+  renderGoModFile,
+  renderGoModulesSection,
+  renderGoWorkFile,
+} from '../../src/core/utils/go-module-layout.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const GO_MOD = `module example.com/shop
+
+go 1.21
+
+require (
+	github.com/google/uuid v1.6.0
+	gorm.io/gorm v1.25.5 // indirect
+)
+`;
+
+const modules = [
+  { name: 'order', files: ['order/service.go'], dependencies: ['user', 'billing'] },
+  { name: 'user', files: ['user/user.go'], dependencies: [] },
+  { name: 'billing', files: ['billing/invoice.go'], dependencies: [], established: { root: 'billing', module_path: 'example.com/billing' } },
+];
+
+const phases = [
+  { name: 'Phase 1', modules: ['user', 'billing'] },
+  { name: 'Phase 2', modules: ['order'] },
+];
+
+describe('Go module layout', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('go-module-layout');
+    await createMockFile(path.join(tempDir, 'go.mod'), GO_MOD);
+    await createMockFile(path.join(tempDir, 'order/service.go'), `package order
+
+import (
+	"github.com/google/uuid"
+
+	"example.com/shop/pkg/money"
+	"example.com/shop/user"
+)
+`);
+    await createMockFile(path.join(tempDir, 'user/user.go'), 'package user\n\nimport "gorm.io/gorm"\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should plan a go.mod per module with replace directives for the transition', () => {
+    const plan = planGoModuleLayout(tempDir, 'multi-module', modules, phases)!;
+
+    expect(plan.root).toEqual({
+      dir: '.',
+      go_mod: 'go.mod',
+      module_path: 'example.com/shop',
+      go_version: '1.21',
+      requires: [
+        { module_path: 'example.com/shop/internal/order', version: 'v0.0.0' },
+        { module_path: 'example.com/shop/internal/user', version: 'v0.0.0' },
+      ],
+      replaces: [
+        { module_path: 'example.com/shop/internal/order', path: './internal/order' },
+        { module_path: 'example.com/shop/internal/user', path: './internal/user' },
+      ],
+    });
+    expect(plan.modules[0]).toEqual({
+      module: 'order',
+      dir: 'internal/order',
+      go_mod: 'internal/order/go.mod',
+      module_path: 'example.com/shop/internal/order',
+      requires: [
+        { module_path: 'example.com/shop/internal/user', version: 'v0.0.0' },
+        { module_path: 'example.com/billing', version: 'v0.0.0' },
+        { module_path: 'example.com/shop', version: 'v0.0.0' },
+        { module_path: 'github.com/google/uuid', version: 'v1.6.0' },
+      ],
+      replaces: [
+        { module_path: 'example.com/shop/internal/user', path: '../user' },
+        { module_path: 'example.com/billing', path: '../../billing' },
+        { module_path: 'example.com/shop', path: '../..' },
+      ],
+    });
+    expect(plan.modules[1].requires).toEqual([{ module_path: 'gorm.io/gorm', version: 'v1.25.5' }]);
+    expect(plan.go_work).toBeUndefined();
+    expect(plan.steps).toEqual([{ phase: 'Phase 1', modules: ['user'] }, { phase: 'Phase 2', modules: ['order'] }]);
+
+    expect(planGoModuleLayout(tempDir, 'single', modules, phases)!.modules).toEqual([]);
+    expect(() => parseGoModuleLayout('workspace')).toThrow('Unknown Go module layout');
+  });
+
+  it('should use a go.work instead of replace directives', () => {
+    const plan = planGoModuleLayout(tempDir, 'go-work', modules, phases)!;
+
+    expect(plan.root.replaces).toEqual([]);
+    expect(plan.modules.every(module => module.replaces.length === 0)).toBe(true);
+    expect(plan.go_work).toEqual({ file: 'go.work', use: ['.', './billing', './internal/order', './internal/user'] });
+    expect(renderGoWorkFile(plan, ['internal/user'], ['./tools/'])).toBe(`// Generated by vibeflow from the Go module layout in plan.json.
+go 1.21
+
+use (
+\t./tools
+\t.
+\t./billing
+\t./internal/user
+)
+`);
+  });
+
+  it('should render the go.mod files, the root directives and the plan section', () => {
+    const plan = planGoModuleLayout(tempDir, 'multi-module', modules, phases)!;
+
+    const goMod = renderGoModFile(plan.modules[1], plan.root.go_version);
+    expect(goMod).toContain('module example.com/shop/internal/user\n\ngo 1.21\n\nrequire (\n\tgorm.io/gorm v1.25.5\n)\n');
+
+    const root = addRootDirectives(GO_MOD, plan, 'user');
+    expect(root).toContain(')\n\nrequire (\n\texample.com/shop/internal/user v0.0.0\n)\n');
+    expect(root).toContain('replace (\n\texample.com/shop/internal/user => ./internal/user\n)\n');
+    expect(addRootDirectives(root, plan, 'user')).toBe(root);
+
+    const section = renderGoModulesSection(plan);
+    expect(section).toContain('## Goモジュール構成 (Go Module Layout)');
+    expect(section).toContain('- 構成: マルチモジュール（replace ディレクティブ）');
+    expect(section).toContain('| order | `internal/order/go.mod` | `example.com/shop/internal/order` | `example.com/shop/internal/user`, `example.com/billing`, `example.com/shop` |');
+    expect(section).toContain('- `internal/order/go.mod`: `example.com/shop => ../..`');
+    expect(section).toContain('- Phase 2: order を独立した go.mod に分離');
+    expect(renderGoModulesSection(planGoModuleLayout(tempDir, 'single', modules, phases))).toContain('- 構成: 単一モジュール');
+  });
+});
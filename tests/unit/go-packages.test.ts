@@ -0,0 +1,149 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  findPackageMismatches,
+  goImportSpec,
+  loadGoPackages,
+  rewriteGoImport,
+} from '../../src/core/utils/go-packages.js';
+import { goImportAlias, impliedPackageName } from '../../src/core/utils/go-project-utils.js';
+import { ASTAnalyzer } from '../../src/core/utils/ast-analyzer.js';
+import { CodeAnalyzer } from '../../src/core/utils/code-analyzer.js';
+import { attachFilePackages } from '../../src/core/agents/enhanced-boundary-agent.js';
+import { ArchitectAgent } from '../../src/core/agents/architect-agent.js';
+import { DomainMapWriter } from '../../src/core/utils/domain-map-writer.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+const fixtureRoot = './tests/fixtures/package-names';
+
+describe('package identity', () => {
+  it('should load packages by their declared names, not their directories', () => {
+    const packages = loadGoPackages(fixtureRoot);
+
+    expect(packages.map(p => [p.dir, p.import_path, p.name]).sort()).toEqual([
+      ['internal/billing', 'example.com/shop/internal/billing', 'billing'],
+      ['internal/order', 'example.com/shop/internal/order', 'order'],
+      ['internal/orderv2', 'example.com/shop/internal/orderv2', 'order'],
+      ['testutil', 'example.com/shop/testutil', 'order'],
+    ]);
+    expect(impliedPackageName('github.com/go-chi/chi/v5')).toBe('chi');
+    expect(impliedPackageName('gopkg.in/yaml.v3')).toBe('yaml');
+    expect(impliedPackageName('example.com/go-redis')).toBe('redis');
+  });
+
+  it('should attribute aliased and mismatched imports to the right package', async () => {
+    const analysis = await new ASTAnalyzer(fixtureRoot).analyzeGoProject();
+    const invoice = analysis.structs.find(s => s.name === 'Invoice')!;
+
+    // `order.Order` is internal/orderv2 (package order); `legacy.Order` is internal/order
+    expect(invoice.dependencies).toEqual(['example.com/shop/internal/orderv2', 'example.com/shop/internal/order']);
+
+    const files = await new CodeAnalyzer(fixtureRoot, null).analyzeFiles(['**/*.go']);
+    expect(files.find(f => f.relativePath === 'internal/billing/invoice.go')?.imports).toEqual([
+      'fmt',
+      'example.com/shop/internal/order',
+      'example.com/shop/internal/orderv2',
+    ]);
+  });
+
+  it('should flag mismatched and duplicate package names with a canonical name', () => {
+    expect(findPackageMismatches(loadGoPackages(fixtureRoot))).toEqual([
+      {
+        kind: 'duplicate-name',
+        dir: 'internal/orderv2',
+        import_path: 'example.com/shop/internal/orderv2',
+        package: 'order',
+        implied_name: 'orderv2',
+        conflicts_with: ['example.com/shop/internal/order', 'example.com/shop/testutil'],
+        proposed_name: 'orderv2',
+      },
+      {
+        kind: 'duplicate-name',
+        dir: 'testutil',
+        import_path: 'example.com/shop/testutil',
+        package: 'order',
+        implied_name: 'testutil',
+        conflicts_with: ['example.com/shop/internal/order', 'example.com/shop/internal/orderv2'],
+        proposed_name: 'ordertest',
+      },
+    ]);
+    expect(findPackageMismatches([{ import_path: 'example.com/shop/internal/orderv2', dir: 'internal/orderv2', name: 'order' }]))
+      .toMatchObject([{ kind: 'name-mismatch', proposed_name: 'orderv2' }]);
+  });
+});
+
+describe('import rewriting', () => {
+  const packages = loadGoPackages(fixtureRoot);
+
+  it('should alias imports whose package name differs from the path', () => {
+    const file = 'package api\n\nfunc Handle() {}\n';
+
+    expect(goImportSpec(file, 'example.com/shop/internal/orderv2', 'order')).toEqual({ name: 'order', alias: 'order' });
+    expect(goImportSpec(file, 'example.com/shop/internal/order', 'order')).toEqual({ name: 'order' });
+    expect(goImportAlias('import "example.com/shop/internal/orderv2"\n', 'example.com/shop/internal/orderv2', 'order')).toBe('order');
+
+    const moved = rewriteGoImport(
+      'package api\n\nimport (\n\t"fmt"\n\t"example.com/shop/internal/order"\n)\n',
+      'example.com/shop/internal/order', 'example.com/shop/internal/orderv2', packages
+    );
+    expect(moved).toContain('\torder "example.com/shop/internal/orderv2"\n');
+    expect(rewriteGoImport('import "example.com/shop/internal/orderv2"\n', 'example.com/shop/internal/orderv2', 'example.com/shop/internal/order', packages))
+      .toBe('import "example.com/shop/internal/order"\n');
+  });
+
+  it('should never import under a name the file already uses', () => {
+    const file = 'package api\n\nimport legacy "example.com/shop/legacy"\n\nfunc Handle(order string) {\n\tlegacy.Place(order)\n}\n';
+
+    const spec = goImportSpec(file, 'example.com/shop/internal/order', 'order');
+    expect(spec).toEqual({ name: 'internalorder', alias: 'internalorder' });
+    expect(goImportSpec(file, 'example.com/shop/internal/legacy', 'legacy').name).not.toBe('legacy');
+    // Selectors (x.order) are not identifiers of the file
+    expect(goImportSpec('package api\n\nvar x = y.order\n', 'example.com/shop/internal/order', 'order')).toEqual({ name: 'order' });
+  });
+});
+
+describe('package names in the domain map and plan', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('go-packages');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should record file packages and flag mismatches as cleanup in the plan', async () => {
+    const [boundary] = attachFilePackages(fixtureRoot, [{
+      name: 'order',
+      description: 'orders',
+      files: ['internal/orderv2/order.go', 'internal/order/order.go', 'testutil/order.go'],
+    }], loadGoPackages(fixtureRoot));
+
+    const map = new DomainMapWriter(tempDir).write({
+      project: 'shop',
+      language: 'go',
+      analyzed_at: '2024-01-01T00:00:00.000Z',
+      total_files: 3,
+      boundaries: [boundary],
+      metrics: { overall_cohesion: 0, overall_coupling: 0, modularity_score: 0 },
+    });
+    expect(map.boundaries[0].file_packages).toEqual([
+      { file: 'internal/order/order.go', import_path: 'example.com/shop/internal/order', package: 'order' },
+      { file: 'internal/orderv2/order.go', import_path: 'example.com/shop/internal/orderv2', package: 'order' },
+      { file: 'testutil/order.go', import_path: 'example.com/shop/testutil', package: 'order' },
+    ]);
+
+    const agent = new ArchitectAgent(tempDir, path.join(tempDir, 'vibeflow.config.yaml'), path.join(tempDir, 'boundary.yaml'));
+    const { plan, outputPath } = await agent.generateArchitecturalPlan(path.join(tempDir, '.vibeflow/domain-map.json'));
+
+    expect(plan.package_mismatches?.map(m => [m.dir, m.proposed_name])).toEqual([
+      ['internal/orderv2', 'orderv2'],
+      ['testutil', 'ordertest'],
+    ]);
+    const markdown = fs.readFileSync(outputPath, 'utf8');
+    expect(markdown).toContain('## パッケージ名の不一致 (Cleanup)');
+    expect(markdown).toContain('`example.com/shop/testutil` は `package order` を宣言');
+  });
+});
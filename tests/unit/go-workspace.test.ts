@@ -0,0 +1,101 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { attachGoModules, loadGoWorkspace, parseGoWork, workspaceImportPath } from '../../src/core/utils/go-workspace.js';
+import { loadGoPackages } from '../../src/core/utils/go-packages.js';
+import { findEstablishedModules } from '../../src/core/utils/established-modules.js';
+import { findPackageLoadErrors } from '../../src/core/utils/go-load-check.js';
+import { detectGoProject } from '../../src/core/utils/go-project-utils.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const fixtureRoot = './tests/fixtures/go-workspace';
+
+function boundary(name: string, files: string[]): DomainBoundary {
+  return { name, description: '', files };
+}
+
+describe('go.work workspaces', () => {
+  it('should parse single-line and block use directives', () => {
+    expect(parseGoWork([
+      'go 1.22',
+      'use ./app // main service',
+      'use (',
+      '\t./lib',
+      '\t"./quoted dir"',
+      ')',
+      'replace example.com/x => ./x',
+    ].join('\n'))).toEqual(['./app', './lib', './quoted dir']);
+  });
+
+  it('should load the member modules, most specific first, and report uses without go.mod', () => {
+    const workspace = loadGoWorkspace(fixtureRoot)!;
+
+    expect(workspace.modules).toEqual([
+      { dir: 'libs/money', go_mod: 'libs/money/go.mod', module_path: 'example.com/money' },
+      { dir: 'services/api', go_mod: 'services/api/go.mod', module_path: 'example.com/shop/api' },
+      { dir: 'services/billing', go_mod: 'services/billing/go.mod', module_path: 'example.com/shop/billing' },
+    ]);
+    expect(workspace.missing).toEqual(['tools']);
+    expect(workspaceImportPath(workspace, 'services/billing/invoice')).toBe('example.com/shop/billing/invoice');
+    expect(workspaceImportPath(workspace, 'scripts')).toBeNull();
+    expect(detectGoProject(fixtureRoot)).toMatchObject({ hasGoProject: true, goWorkPath: path.join(fixtureRoot, 'go.work') });
+  });
+
+  it('should resolve packages of every member and analyze them together', () => {
+    expect(loadGoPackages(fixtureRoot).map(pkg => [pkg.dir, pkg.import_path])).toEqual([
+      ['libs/money', 'example.com/money'],
+      ['services/api/handler', 'example.com/shop/api/handler'],
+      ['services/billing/invoice', 'example.com/shop/billing/invoice'],
+    ]);
+    // Members are part of the analysis, not fixed established modules
+    expect(findEstablishedModules(fixtureRoot)).toEqual([]);
+  });
+
+  it('should record the go.mod each boundary maps to', () => {
+    const workspace = loadGoWorkspace(fixtureRoot)!;
+    const boundaries = attachGoModules(path.resolve(fixtureRoot), [
+      boundary('billing', ['services/billing/invoice/invoice.go', path.resolve(fixtureRoot, 'libs/money/money.go'), 'services/billing/invoice/tax.go']),
+      boundary('scripts', ['scripts/seed.go']),
+    ], workspace);
+
+    expect(boundaries[0]).toMatchObject({
+      go_mod: 'services/billing/go.mod',
+      go_mods: ['services/billing/go.mod', 'libs/money/go.mod'],
+    });
+    expect(boundaries[1]).toEqual(boundary('scripts', ['scripts/seed.go']));
+  });
+
+  describe('load check', () => {
+    let tempDir: string;
+
+    beforeEach(async () => {
+      tempDir = await createTempDir('go-workspace');
+    });
+
+    afterEach(async () => {
+      await cleanupTempDir(tempDir);
+    });
+
+    it('should check imports into other members', async () => {
+      await createMockFile(path.join(tempDir, 'go.work'), 'go 1.22\n\nuse (\n\t./api\n\t./billing\n)\n');
+      await createMockFile(path.join(tempDir, 'api/go.mod'), 'module example.com/api\n');
+      await createMockFile(path.join(tempDir, 'billing/go.mod'), 'module example.com/billing\n');
+      await createMockFile(path.join(tempDir, 'billing/invoice/invoice.go'), 'package invoice\n');
+      await createMockFile(path.join(tempDir, 'api/order.go'), [
+        'package api',
+        '',
+        'import (',
+        '\t"example.com/billing/invoice"',
+        '\t"example.com/billing/refund"',
+        ')',
+        '',
+      ].join('\n'));
+
+      expect(findPackageLoadErrors(tempDir, ['api/order.go'])).toEqual([{
+        package: 'api',
+        files: ['api/order.go'],
+        errors: ['api/order.go:5:2: could not import example.com/billing/refund (no Go files in billing/refund)'],
+      }]);
+    });
+  });
+});
@@ -0,0 +1,70 @@
+import { describe, it, expect } from 'vitest';
+import { findGrpcServices, grpcSeeds, parseProtoServices, serviceModuleName } from '../../src/core/utils/grpc-services.js';
+import { loadGoPackages } from '../../src/core/utils/go-packages.js';
+
+const fixtureRoot = './tests/fixtures/grpc-services';
+
+describe('gRPC services', () => {
+  it('should parse services, RPCs and go_package, ignoring commented-out RPCs', () => {
+    expect(parseProtoServices(`
+      package shop.order.v1;
+      option go_package = "example.com/shop/gen/order/v1;orderv1";
+      service OrderService {
+        rpc CreateOrder(CreateOrderRequest) returns (Order);
+        rpc GetOrder(GetOrderRequest) returns (Order) { option (google.api.http) = { get: "/v1/orders/{id}" }; }
+        // rpc CancelOrder(CancelOrderRequest) returns (Order);
+      }
+      service AdminAPI {}
+    `)).toEqual([
+      { name: 'OrderService', package: 'shop.order.v1', go_package: 'example.com/shop/gen/order/v1', rpcs: ['CreateOrder', 'GetOrder'] },
+      { name: 'AdminAPI', package: 'shop.order.v1', go_package: 'example.com/shop/gen/order/v1', rpcs: [] },
+    ]);
+  });
+
+  it.each([
+    ['OrderService', 'order'],
+    ['UserAccountService', 'user_account'],
+    ['HTTPGatewayAPI', 'http_gateway'],
+    ['Inventory', 'inventory'],
+  ])('should name the module of %s %s', (service, module) => {
+    expect(serviceModuleName(service)).toBe(module);
+  });
+
+  it('should find the generated package and the servers implementing each service', () => {
+    const services = findGrpcServices(fixtureRoot, loadGoPackages(fixtureRoot));
+
+    expect(services).toEqual([
+      {
+        name: 'InvoiceService',
+        full_name: 'shop.billing.InvoiceService',
+        proto: 'proto/billing.proto',
+        rpcs: ['IssueInvoice'],
+        generated_dir: 'gen/billingpb',
+        implementations: [{ struct: 'invoiceServer', files: ['internal/server/billing.go'], shared: true }],
+        module: 'invoice',
+      },
+      {
+        name: 'OrderService',
+        full_name: 'shop.order.v1.OrderService',
+        proto: 'proto/order/v1/order.proto',
+        rpcs: ['CreateOrder', 'GetOrder'],
+        generated_dir: 'gen/order/v1',
+        implementations: [{ struct: 'grpcServer', files: ['internal/order/grpc.go', 'internal/order/grpc_get.go'], shared: false }],
+        module: 'order',
+      },
+    ]);
+  });
+
+  it('should seed each module with its generated and implementing packages, leaving configured seeds alone', () => {
+    const services = findGrpcServices(fixtureRoot, loadGoPackages(fixtureRoot));
+
+    expect(grpcSeeds(services)).toEqual({
+      invoice: ['gen/billingpb', 'internal/server/billing.go'],
+      order: ['gen/order/v1', 'internal/order'],
+    });
+    expect(grpcSeeds(services, ['internal/order', 'gen/billingpb/billing_grpc.pb.go'])).toEqual({
+      invoice: ['internal/server/billing.go'],
+      order: ['gen/order/v1'],
+    });
+  });
+});
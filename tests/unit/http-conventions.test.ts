@@ -0,0 +1,80 @@
+import { describe, it, expect } from 'vitest';
+import { ClaudeCodeClient } from '../../src/core/utils/claude-code-client.js';
+import {
+  detectHttpConventions,
+  extractHandlerRoutes,
+  handlerDialect,
+  renderRoutesFile,
+  routeMountCall,
+} from '../../src/core/utils/http-conventions.js';
+
+const fixtureRoot = './tests/fixtures/http-echo';
+
+describe('HTTP conventions', () => {
+  it('should detect echo, the envelope helpers, validation and the router mount', () => {
+    const conventions = detectHttpConventions(fixtureRoot);
+
+    expect(conventions).toMatchObject({
+      framework: 'echo',
+      source: 'detected',
+      framework_import: 'github.com/labstack/echo/v4',
+      module: 'example.com/shop',
+      validates: true,
+      mount: { dir: 'cmd/server', file: 'cmd/server/main.go', router: 'e' },
+      warnings: [],
+    });
+    expect(conventions.respond_json).toMatchObject({ ref: 'pkg/httpx.RespondJSON', import_path: 'example.com/shop/pkg/httpx', params: ['target', 'status', 'payload'], returns_error: true });
+    expect(conventions.respond_error).toMatchObject({ ref: 'pkg/httpx.RespondError', params: ['target', 'status', 'error'] });
+
+    const dialect = handlerDialect(conventions);
+    expect(dialect.signature('OrderHandler', 'Get')).toBe('func (h *OrderHandler) Get(c echo.Context) error');
+    expect(dialect.bind('&req')).toEqual([
+      'if err := c.Bind(&req); err != nil {',
+      '\treturn httpx.RespondError(c, http.StatusBadRequest, err)',
+      '}',
+      'if err := c.Validate(&req); err != nil {',
+      '\treturn httpx.RespondError(c, http.StatusBadRequest, err)',
+      '}',
+    ]);
+  });
+
+  it('should let http.* config override the framework and helpers', () => {
+    const conventions = detectHttpConventions(fixtureRoot, { framework: 'gin', respondJSON: 'pkg/render.JSON', mount: 'cmd/api' });
+
+    expect(conventions).toMatchObject({ framework: 'gin', source: 'config', framework_import: 'github.com/gin-gonic/gin', validates: false, mount: { dir: 'cmd/api' } });
+    expect(conventions.respond_json).toMatchObject({ import_path: 'example.com/shop/pkg/render', package: 'render', params: ['target', 'status', 'payload'] });
+    // The echo helpers do not fit gin handlers
+    expect(conventions.respond_error).toBeUndefined();
+    expect(conventions.warnings).toEqual([
+      'pkg/render.JSON: declaration not found, assuming (target, status, payload)',
+      'http.mount: cmd/api does not exist',
+    ]);
+    expect(handlerDialect(conventions).fail('http.StatusNotFound', 'err'))
+      .toEqual(['c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})', 'return']);
+  });
+
+  it('should generate handlers in the detected conventions and a routes.go mounted by one call', async () => {
+    const client = new ClaudeCodeClient({ cwd: fixtureRoot, maxTurns: 1, systemPrompt: '', generationMode: 'template' });
+    const prompt = 'File: legacy/order.go\nBoundary: order\n\n```go\npackage legacy\n\nfunc GetOrder(id string) {}\n\nfunc CancelOrder(id string) {}\n```\n\ninternal/order/';
+    const result = client.extractJsonFromResult((await client.queryWithGeneration(prompt)).text);
+    const handler = result.refactored_files.find(f => f.path === 'internal/order/handler/order_handler.go')!.content;
+
+    expect(handler).toContain('"example.com/shop/pkg/httpx"');
+    expect(handler).toContain([
+      '// GetOrder handles GET /orders/{id}/get-order',
+      'func (h *OrderHandler) GetOrder(c echo.Context) error {',
+      '\tid := c.Param("id")',
+    ].join('\n'));
+    expect(handler).toContain('\treturn httpx.RespondJSON(c, http.StatusOK, entity)');
+
+    const conventions = detectHttpConventions(fixtureRoot);
+    const routes = extractHandlerRoutes(handler);
+    expect(renderRoutesFile(conventions, 'handler', routes, 'order')).toContain([
+      'func RegisterRoutes(g *echo.Group, orderHandler *OrderHandler) {',
+      '\tg.PUT("/orders/:id/cancel-order", orderHandler.CancelOrder)',
+      '\tg.GET("/orders/:id/get-order", orderHandler.GetOrder)',
+      '}',
+    ].join('\n'));
+    expect(routeMountCall(conventions, 'orderhandler', routes)).toBe('orderhandler.RegisterRoutes(e.Group(""), orderHandler)');
+  });
+});
@@ -0,0 +1,91 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { EnhancedBoundaryAgent } from '../../src/core/agents/enhanced-boundary-agent.js';
+import { RoutePrefixIndex, assignRouteModules, findHttpRoutes, routePrefix } from '../../src/core/utils/http-routes.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+const fixtureRoot = './tests/fixtures/http-routes';
+const goFiles = [
+  'cmd/api/main.go',
+  'internal/admin/routes.go',
+  'internal/order/handler.go',
+  'internal/order/refund.go',
+  'internal/order/service.go',
+  'internal/report/http.go',
+  'internal/user/handler.go',
+];
+
+describe('HTTP routes', () => {
+  it('should find gin, chi and net/http routes with group prefixes and their handler files', () => {
+    const routes = findHttpRoutes(fixtureRoot, goFiles);
+
+    expect(routes.map(r => [r.method, r.path, r.handler, r.file, r.framework])).toEqual([
+      ['GET', '/admin/stats', 'Stats', 'internal/admin/routes.go', 'chi'],
+      ['DELETE', '/admin/users/{id}', 'BanUser', 'internal/admin/routes.go', 'chi'],
+      ['GET', '/api/v1/orders', 'Handler.List', 'internal/order/handler.go', 'gin'],
+      ['POST', '/api/v1/orders', 'Handler.Create', 'internal/order/handler.go', 'gin'],
+      ['GET', '/api/v1/orders/{id}', 'Handler.Get', 'internal/order/handler.go', 'gin'],
+      ['POST', '/api/v1/orders/{id}/refund', 'order.Refund', 'internal/order/refund.go', 'gin'],
+      ['GET', '/api/v1/users/{id}', 'Handler.Get', 'internal/user/handler.go', 'gin'],
+      ['GET', '/healthz', 'func literal', 'cmd/api/main.go', 'gin'],
+      ['GET', '/reports/{month}', 'monthly', 'internal/report/http.go', 'stdlib'],
+      ['ANY', '/reports/export', 'export', 'internal/report/http.go', 'stdlib'],
+    ]);
+    expect(routes[2].registered_in).toBe('cmd/api/main.go');
+  });
+
+  it.each([
+    ['/api/v1/orders/{id}/items', '/api/v1/orders'],
+    ['/orders', '/orders'],
+    ['/v2/{tenant}/users', '/v2/{tenant}/users'],
+    ['/', '/'],
+  ])('should group %s under %s', (routePath, prefix) => {
+    expect(routePrefix(routePath)).toBe(prefix);
+  });
+
+  it('should relate handler files serving the same URL prefix', () => {
+    const index = new RoutePrefixIndex(findHttpRoutes(fixtureRoot, goFiles));
+
+    expect(index.strength('internal/order/handler.go', 'internal/order/refund.go')).toBe(1);
+    expect(index.strength('internal/order/handler.go', 'internal/user/handler.go')).toBe(0);
+    expect(index.strength('internal/order/handler.go', 'internal/order/service.go')).toBe(0);
+    expect(index.prefixesOf(['internal/order/handler.go', 'internal/admin/routes.go'])).toEqual(['/admin', '/api/v1/orders']);
+  });
+
+  it('should record the boundary of each route handler', () => {
+    const routes = assignRouteModules(findHttpRoutes(fixtureRoot, goFiles), [
+      { name: 'order', files: ['internal/order/handler.go', 'internal/order/refund.go'] },
+      { name: 'user', files: ['internal/user/handler.go'] },
+    ]);
+
+    expect(routes.filter(r => r.module).map(r => [r.path, r.module])).toEqual([
+      ['/api/v1/orders', 'order'],
+      ['/api/v1/orders', 'order'],
+      ['/api/v1/orders/{id}', 'order'],
+      ['/api/v1/orders/{id}/refund', 'order'],
+      ['/api/v1/users/{id}', 'user'],
+    ]);
+  });
+
+  describe('discovery', () => {
+    let tempDir: string;
+
+    beforeEach(async () => {
+      tempDir = await createTempDir('http-routes');
+      fs.cpSync(fixtureRoot, tempDir, { recursive: true });
+    });
+
+    afterEach(async () => {
+      await cleanupTempDir(tempDir);
+    });
+
+    it('should write domain-map.json with route URL paths kept as-is', async () => {
+      await new EnhancedBoundaryAgent(tempDir).analyzeBoundaries();
+
+      const domainMap = JSON.parse(fs.readFileSync(path.join(tempDir, '.vibeflow/domain-map.json'), 'utf8'));
+      expect(domainMap.routes.map((r: { method: string; path: string }) => `${r.method} ${r.path}`)).toContain('GET /api/v1/orders/{id}');
+      expect(domainMap.routes.find((r: { path: string }) => r.path === '/healthz').registered_in).toBe('cmd/api/main.go');
+    });
+  });
+});
@@ -0,0 +1,144 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  diffFiles,
+  discoveryStatePath,
+  loadDiscoveryState,
+  mergeIncrementalBoundaries,
+  mergeScopedBoundaries,
+  saveDiscoveryState,
+} from '../../src/core/utils/incremental-discovery.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+function boundary(name: string, files: string[], extra: Partial<DomainBoundary> = {}): DomainBoundary {
+  return {
+    name,
+    description: `${name} module`,
+    files,
+    dependencies: { internal: [], external: [] },
+    circular_dependencies: [],
+    ...extra,
+  };
+}
+
+describe('incremental discovery', () => {
+  const previous = [
+    boundary('order', ['order/order.go', 'order/repository.go', 'order/pricing.go'], { id: 'order-1', file_packages: [] }),
+    boundary('user', ['user/user.go', 'user/store.go'], { id: 'user-1' }),
+    boundary('legacy', ['legacy/util.go'], { id: 'legacy-1' }),
+  ];
+
+  it('should keep unchanged files in their boundaries and place only changed and added files', () => {
+    // The new clustering would split order; only pricing.go changed and follows its cluster
+    const fresh = [
+      boundary('checkout', ['order/order.go', 'order/pricing.go', 'user/user.go', 'user/store.go']),
+      boundary('order', ['order/repository.go']),
+      boundary('shipping', ['shipping/shipment.go', 'shipping/carrier.go']),
+      boundary('audit', ['user/audit.go', 'order/order.go']),
+    ];
+    const merged = mergeIncrementalBoundaries(previous, fresh, {
+      added: ['shipping/carrier.go', 'shipping/shipment.go', 'user/audit.go'],
+      changed: ['order/pricing.go'],
+      removed: ['legacy/util.go'],
+      unchanged: ['order/order.go', 'order/repository.go', 'user/store.go', 'user/user.go'],
+    });
+
+    expect(merged.boundaries.map(b => [b.id, b.name, b.files])).toEqual([
+      ['order-1', 'order', ['order/order.go', 'order/repository.go', 'user/audit.go']],
+      ['user-1', 'user', ['user/user.go', 'user/store.go', 'order/pricing.go']],
+      [undefined, 'shipping', ['shipping/shipment.go', 'shipping/carrier.go']],
+    ]);
+    expect(merged.placed).toEqual([
+      { file: 'order/pricing.go', boundary: 'user' },
+      { file: 'shipping/shipment.go', boundary: 'shipping' },
+      { file: 'shipping/carrier.go', boundary: 'shipping' },
+      { file: 'user/audit.go', boundary: 'order' },
+    ]);
+    expect(merged.dropped).toEqual(['legacy']);
+    expect(merged.boundaries[0].file_packages).toBeUndefined();
+  });
+
+  it('should return the previous boundaries when nothing changed', () => {
+    const merged = mergeIncrementalBoundaries(previous, [boundary('everything', previous.flatMap(b => b.files))], {
+      added: [],
+      changed: [],
+      removed: [],
+      unchanged: previous.flatMap(b => b.files),
+    });
+
+    expect(merged.boundaries.map(b => [b.name, b.files])).toEqual(previous.map(b => [b.name, b.files]));
+    expect(merged.placed).toEqual([]);
+  });
+
+  it('should only replace the files inside the scope of a scoped discovery', () => {
+    const inScope = (file: string) => file.startsWith('order/') || file.startsWith('legacy/');
+    const fresh = [
+      // user/user.go was not analyzed and stays where it was
+      boundary('checkout', ['order/order.go', 'order/pricing.go', 'user/user.go']),
+      boundary('persistence', ['order/repository.go', 'order/audit.go']),
+    ];
+    const merged = mergeScopedBoundaries(previous, fresh, inScope);
+
+    expect(merged.boundaries.map(b => [b.id, b.name, b.files])).toEqual([
+      // Most of checkout was in order, which keeps its name and id
+      ['order-1', 'order', ['order/order.go', 'order/pricing.go']],
+      ['user-1', 'user', ['user/user.go', 'user/store.go']],
+      [undefined, 'persistence', ['order/repository.go', 'order/audit.go']],
+    ]);
+    expect(merged.placed.map(p => p.boundary)).toEqual(['order', 'order', 'persistence', 'persistence']);
+    expect(merged.dropped).toEqual(['legacy']);
+    expect(merged.boundaries[0].file_packages).toBeUndefined();
+  });
+
+  describe('state', () => {
+    let tempDir: string;
+    const mapPath = () => path.join(tempDir, '.vibeflow', 'domain-map.json');
+
+    beforeEach(async () => {
+      tempDir = await createTempDir('incremental-discovery');
+      await createMockFile(path.join(tempDir, 'order/order.go'), 'package order\n');
+      await createMockFile(path.join(tempDir, 'order/pricing.go'), 'package order\n');
+      await createMockFile(path.join(tempDir, 'legacy/util.go'), 'package legacy\n');
+      await createMockFile(mapPath(), '{"boundaries":[]}\n');
+    });
+
+    afterEach(async () => {
+      await cleanupTempDir(tempDir);
+    });
+
+    it('should report changed, removed and added files since the saved state', async () => {
+      saveDiscoveryState(tempDir, mapPath(), ['order/order.go', 'order/pricing.go', 'legacy/util.go']);
+      expect(fs.existsSync(discoveryStatePath(tempDir))).toBe(true);
+
+      await createMockFile(path.join(tempDir, 'order/pricing.go'), 'package order\n\nconst Tax = 0.1\n');
+      fs.rmSync(path.join(tempDir, 'legacy/util.go'));
+
+      const state = loadDiscoveryState(tempDir, mapPath())!;
+      expect(diffFiles(tempDir, state.files, ['order/order.go', 'order/pricing.go', 'user/user.go'])).toEqual({
+        added: ['user/user.go'],
+        changed: ['order/pricing.go'],
+        removed: ['legacy/util.go'],
+        unchanged: ['order/order.go'],
+      });
+    });
+
+    it('should keep the hashes of files a scoped discovery did not analyze', async () => {
+      saveDiscoveryState(tempDir, mapPath(), ['order/order.go', 'legacy/util.go']);
+      const before = loadDiscoveryState(tempDir, mapPath())!;
+      await createMockFile(path.join(tempDir, 'legacy/util.go'), 'package legacy\n\nfunc Helper() {}\n');
+
+      saveDiscoveryState(tempDir, mapPath(), ['order/order.go', 'legacy/util.go'], { 'legacy/util.go': before.files['legacy/util.go'] });
+      const state = loadDiscoveryState(tempDir, mapPath())!;
+      expect(diffFiles(tempDir, state.files, []).changed).toEqual(['legacy/util.go']);
+    });
+
+    it('should ignore the state once the domain map was written by something else', async () => {
+      saveDiscoveryState(tempDir, mapPath(), ['order/order.go']);
+      await createMockFile(mapPath(), '{"boundaries":[{"name":"edited"}]}\n');
+
+      expect(loadDiscoveryState(tempDir, mapPath())).toBeNull();
+    });
+  });
+});
@@ -0,0 +1,182 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  checkGoSource,
+  parseBoundaryConfig,
+  parseCoverageProfile,
+  parseDomainMap,
+  parseLlmResponse,
+  parsePlan,
+  readTextInput,
+} from '../../src/core/utils/input-parsers.js';
+import { InputParseError, failureCategory } from '../../src/core/utils/error-utils.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const corpusRoot = './tests/fixtures/fuzz-corpus';
+const ITERATIONS = 300;
+
+const parsers: Record<string, (content: string) => unknown> = {
+  'boundary.yaml': content => parseBoundaryConfig(content, 'boundary.yaml'),
+  'domain-map.json': content => parseDomainMap(content, 'domain-map.json'),
+  'plan.json': content => parsePlan(content, 'plan.json'),
+  'llm-response.txt': content => parseLlmResponse(content),
+  'coverage.out': content => parseCoverageProfile(content, 'coverage.out'),
+  'order.go': content => checkGoSource(content, 'order.go'),
+};
+
+/**
+ * mulberry32: a failing seed can be replayed exactly
+ */
+function prng(seed: number): () => number {
+  return () => {
+    seed = (seed + 0x6d2b79f5) | 0;
+    let t = Math.imul(seed ^ (seed >>> 15), 1 | seed);
+    t = (t + Math.imul(t ^ (t >>> 7), 61 | t)) ^ t;
+    return ((t ^ (t >>> 14)) >>> 0) / 4294967296;
+  };
+}
+
+function mutate(seed: Buffer, random: () => number): string {
+  const bytes = [...seed];
+  const at = () => Math.floor(random() * (bytes.length + 1));
+  const rounds = 1 + Math.floor(random() * 4);
+  for (let i = 0; i < rounds; i++) {
+    switch (Math.floor(random() * 5)) {
+      case 0: // flip a byte, often into invalid UTF-8
+        if (bytes.length > 0) bytes[Math.min(at(), bytes.length - 1)] = Math.floor(random() * 256);
+        break;
+      case 1: // truncate
+        bytes.length = at();
+        break;
+      case 2: { // delete a range
+        const start = at();
+        bytes.splice(start, Math.floor(random() * 32));
+        break;
+      }
+      case 3: { // duplicate a range
+        const start = at();
+        bytes.splice(at(), 0, ...bytes.slice(start, start + Math.floor(random() * 32)));
+        break;
+      }
+      default: // insert structural noise
+        bytes.splice(at(), 0, ...Buffer.from(['null', '{', ']', '"', ':', '\n', '- ', '0x'][Math.floor(random() * 8)]));
+    }
+  }
+  return Buffer.from(bytes).toString('utf8');
+}
+
+describe('input parsers under fuzzed input', () => {
+  for (const [name, parse] of Object.entries(parsers)) {
+    it(`should accept the ${name} seed and only throw InputParseError for mutations`, () => {
+      const seed = fs.readFileSync(path.join(corpusRoot, name));
+      expect(() => parse(seed.toString('utf8'))).not.toThrow();
+
+      const random = prng(0x5eed + name.length);
+      for (let i = 0; i < ITERATIONS; i++) {
+        const input = mutate(seed, random);
+        try {
+          parse(input);
+        } catch (error) {
+          if (!(error instanceof InputParseError)) {
+            throw new Error(`${name} iteration ${i} threw ${String(error)} for input ${JSON.stringify(input.slice(0, 200))}`);
+          }
+        }
+      }
+    });
+  }
+});
+
+describe('input parsers', () => {
+  it('should reject a Go file holding only a build tag', () => {
+    expect(() => checkGoSource('//go:build ignore\n', 'tools.go')).toThrow(InputParseError);
+    expect(() => checkGoSource('//go:build ignore\n', 'tools.go')).toThrow('tools.go: invalid go-source: no package clause');
+    expect(() => checkGoSource(fs.readFileSync(path.join(corpusRoot, 'order.go'), 'utf8'))).not.toThrow();
+  });
+
+  it('should report a null module entry in boundary.yaml', () => {
+    expect(() => parseBoundaryConfig('modules:\n  order:\n  customer:\n    owns_tables: [customers]\n', 'boundary.yaml'))
+      .toThrow(/boundary\.yaml: invalid boundary-config: modules\.order/);
+    expect(parseBoundaryConfig('')).toEqual({ modules: {} });
+    expect(() => parseBoundaryConfig('- order\n')).toThrow(/expected a mapping/);
+  });
+
+  it('should keep the valid boundaries of a domain map and report the others', () => {
+    expect(() => parseDomainMap('', 'domain-map.json')).toThrow(/empty file/);
+
+    const { map, invalid } = parseDomainMap(JSON.stringify({
+      project: 'shop',
+      boundaries: [null, { name: 'order', description: '', files: null }, { name: 'user', description: '', files: ['user.go'] }],
+    }));
+    expect(map.project).toBe('shop');
+    expect(map.boundaries.map(b => b.name)).toEqual(['user']);
+    expect(invalid.map(i => [i.index, i.name])).toEqual([[0, undefined], [1, 'order']]);
+    expect(map.schema_version).toBe(1);
+    expect(() => parseDomainMap(JSON.stringify({ schema_version: 99, boundaries: [] }), 'domain-map.json'))
+      .toThrow(/domain-map\.json: invalid domain-map: .*schema v99 is newer than supported/);
+  });
+
+  it('should reject invalid UTF-8 in LLM responses and artifacts', async () => {
+    const response = Buffer.concat([Buffer.from('{"refactored_files": [{"path": "a.go", "content": "'), Buffer.from([0xc3, 0x28]), Buffer.from('"}]}')]);
+    expect(() => parseLlmResponse(response.toString('utf8'))).toThrow('invalid llm-response: response contains invalid UTF-8');
+    expect(() => parseLlmResponse('{"refactored_files": [{"path": "a.go"}]}')).toThrow(/refactored_files\[0\]/);
+    expect(parseLlmResponse('{"refactored_files": []}')).toEqual({ refactored_files: [], interfaces: [], tests: [] });
+
+    const tempDir = await createTempDir('input-parsers');
+    try {
+      const file = path.join(tempDir, 'boundary.yaml');
+      fs.writeFileSync(file, Buffer.from([0x6d, 0x3a, 0xff, 0x0a]));
+      expect(() => readTextInput('boundary-config', file)).toThrow(InputParseError);
+    } finally {
+      await cleanupTempDir(tempDir);
+    }
+  });
+
+  it('should compute statement coverage and count repeated blocks once', () => {
+    const seed = fs.readFileSync(path.join(corpusRoot, 'coverage.out'), 'utf8');
+    expect(parseCoverageProfile(seed)).toMatchObject({ mode: 'set', statements: 5, covered: 2, percentage: 40 });
+    expect(parseCoverageProfile(seed + seed.split('\n').slice(2, 3).join('\n').replace(/ 0$/, ' 1')).covered).toBe(3);
+    expect(() => parseCoverageProfile('example.com/a.go:1.1,2.2 1 1\n')).toThrow(/missing "mode:" header/);
+  });
+
+  it('should categorize failures', () => {
+    expect(failureCategory(new InputParseError('plan', 'bad'))).toBe('invalid-input');
+    expect(failureCategory(Object.assign(new Error('no such file'), { code: 'ENOENT' }))).toBe('io');
+    expect(failureCategory(new TypeError('x is undefined'))).toBe('internal');
+  });
+});
+
+describe('RefactorAgent with malformed inputs', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('input-parsers-agent');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/legacy/order.go'), fs.readFileSync(path.join(corpusRoot, 'order.go'), 'utf8'));
+    await createMockFile(path.join(tempDir, 'internal/legacy/tools.go'), '//go:build tools\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should fail only the module whose input is malformed', async () => {
+    const boundaries = [
+      { name: 'tools', description: '', files: [path.join(tempDir, 'internal/legacy/tools.go')] },
+      { name: 'billing', description: '', files: null } as unknown as DomainBoundary,
+      { name: 'order', description: '', files: [path.join(tempDir, 'internal/legacy/order.go')] },
+    ];
+
+    const result = await new RefactorAgent(tempDir, 'template').executeRefactoring(boundaries, true);
+
+    expect(result.failed_patches.map(f => [f.file, f.category])).toEqual([
+      [path.join(tempDir, 'internal/legacy/tools.go'), 'invalid-input'],
+      ['<module billing>', 'invalid-input'],
+    ]);
+    expect(result.failed_patches[0].error).toContain('no package clause');
+    expect(result.applied_patches).toEqual([path.join(tempDir, 'internal/legacy/order.go')]);
+    expect(result.created_files.length).toBeGreaterThan(0);
+  });
+});
@@ -0,0 +1,147 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { RefactoredFile } from '../../src/core/types/refactor.js';
+import {
+  BatchItemResult,
+  BatchProvider,
+  BatchRequest,
+  BatchRunState,
+  LlmBatchSession,
+  advanceBatchRun,
+  parseBatchResult,
+  pollBatchJobs,
+} from '../../src/core/utils/llm-batch.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const response = (name: string) => JSON.stringify({
+  refactored_files: [{ path: `internal/${name}/domain/${name}.go`, content: `package domain\n\nfunc ${name}() {}\n`, description: '' }],
+  interfaces: [],
+  tests: [],
+});
+
+class FakeBatchProvider implements BatchProvider {
+  readonly name = 'fake';
+  readonly discount = 0.5;
+  submitted = new Map<string, BatchRequest[]>();
+  ended = new Set<string>();
+
+  constructor(private answer: (request: BatchRequest) => BatchItemResult) {}
+
+  async submit(requests: BatchRequest[]) {
+    const id = `batch_${this.submitted.size + 1}`;
+    this.submitted.set(id, requests);
+    return { id, expires_at: '2026-01-02T00:00:00.000Z' };
+  }
+
+  async status(id: string) {
+    return { ended: this.ended.has(id) };
+  }
+
+  async results(id: string) {
+    return (this.submitted.get(id) ?? []).map(request => this.answer(request));
+  }
+}
+
+class SynchronousCallAgent extends RefactorAgent {
+  protected async requestTransformation(): Promise<RefactoredFile> {
+    throw new Error('async batch runs must not call the model synchronously');
+  }
+}
+
+describe('async LLM batches', () => {
+  let tempDir: string;
+  const boundaries = () => ['order', 'billing'].map(name => ({
+    name,
+    description: `${name} context`,
+    files: [path.join(tempDir, `legacy/${name}.go`)],
+  }));
+  const state = (runId: number, provider: BatchProvider): BatchRunState => ({
+    run_id: runId,
+    provider: provider.name,
+    discount: provider.discount,
+    options: { apply: true, cleanModule: false, allowDegraded: false, escalation: true, commit: false, reopenAccepted: false },
+    modules: [],
+    jobs: [],
+  });
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('llm-batch');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'legacy/order.go'), 'package legacy\n\nfunc PlaceOrder() {}\n');
+    await createMockFile(path.join(tempDir, 'legacy/billing.go'), 'package legacy\n\nfunc Charge() {}\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should queue prompts, submit one job per module and apply the modules whose results are in', async () => {
+    const provider = new FakeBatchProvider(request => ({
+      custom_id: request.custom_id,
+      response: response(request.prompt.includes('PlaceOrder') ? 'order' : 'billing'),
+    }));
+    const store = new PerformanceStore(tempDir);
+    const runId = store.startRun('refactor-module');
+
+    const session = new LlmBatchSession(tempDir, runId, provider.discount);
+    const submitted = await new SynchronousCallAgent(tempDir).executeRefactoring(boundaries(), true, { batch: session });
+    expect(submitted.pending_modules).toEqual(['order', 'billing']);
+    expect(submitted.applied_patches).toEqual([]);
+    const run = await advanceBatchRun(provider, session, state(runId, provider), ['order', 'billing'], submitted.pending_modules!);
+    expect(run.jobs.map(job => [job.id, job.module, job.requests.map(r => r.file)])).toEqual([
+      ['batch_1', 'order', ['legacy/order.go']],
+      ['batch_2', 'billing', ['legacy/billing.go']],
+    ]);
+    expect(new PerformanceStore(tempDir, { readOnly: true }).getLlmCalls(runId)).toEqual([]);
+
+    // Only order's job has ended: billing keeps waiting
+    provider.ended.add('batch_1');
+    const collecting = new LlmBatchSession(tempDir, runId, provider.discount);
+    const saved = collecting.loadState()!;
+    expect(await pollBatchJobs(provider, collecting, saved, new Date('2026-01-01T00:00:00.000Z'))).toEqual({ ready: ['order'], waiting: ['billing'] });
+
+    const collected = await new SynchronousCallAgent(tempDir).executeRefactoring(boundaries().filter(b => b.name === 'order'), true, { batch: collecting });
+    expect(collected.pending_modules).toBeUndefined();
+    expect(fs.existsSync(path.join(tempDir, 'internal/order/domain/order.go'))).toBe(true);
+    expect(fs.existsSync(path.join(tempDir, 'internal/billing/domain/billing.go'))).toBe(false);
+    await advanceBatchRun(provider, collecting, saved, ['order'], []);
+    expect(collecting.loadState()?.modules).toEqual(['billing']);
+
+    const calls = new PerformanceStore(tempDir, { readOnly: true }).getLlmCalls(runId);
+    expect(calls).toHaveLength(1);
+    expect(calls[0]).toMatchObject({ module: 'order', batch_job: 'batch_1', batch_discount: 0.5, outcome: 'success' });
+  });
+
+  it('should fail the files of an expired job as LLM errors', async () => {
+    const provider = new FakeBatchProvider(request => ({ custom_id: request.custom_id, response: response('order') }));
+    const runId = new PerformanceStore(tempDir).startRun('refactor-module');
+    const session = new LlmBatchSession(tempDir, runId, provider.discount);
+    const order = boundaries().filter(b => b.name === 'order');
+    const run = state(runId, provider);
+    await advanceBatchRun(provider, session, run, ['order'], (await new SynchronousCallAgent(tempDir).executeRefactoring(order, false, { batch: session })).pending_modules!);
+
+    expect(await pollBatchJobs(provider, session, run, new Date('2026-01-03T00:00:00.000Z'))).toEqual({ ready: ['order'], waiting: [] });
+    const result = await new SynchronousCallAgent(tempDir).executeRefactoring(order, false, { batch: session });
+    expect(result.failed_patches).toEqual([{
+      file: path.join(tempDir, 'legacy/order.go'),
+      error: 'Batch request expired: job batch_1 expired at 2026-01-02T00:00:00.000Z',
+      category: 'llm',
+    }]);
+    await advanceBatchRun(provider, session, run, ['order'], []);
+    expect(session.loadState()).toBeNull();
+  });
+
+  it('should read succeeded and failed lines of a results file', () => {
+    expect(parseBatchResult({
+      custom_id: 'a',
+      result: { type: 'succeeded', message: { content: [{ type: 'text', text: '{"refactored_files"' }, { type: 'text', text: ': []}' }] } },
+    })).toEqual({ custom_id: 'a', response: '{"refactored_files": []}' });
+    expect(parseBatchResult({ custom_id: 'b', result: { type: 'errored', error: { type: 'error', error: { type: 'overloaded_error', message: 'Overloaded' } } } }))
+      .toEqual({ custom_id: 'b', failure: 'errored', error: 'Overloaded' });
+    expect(parseBatchResult({ custom_id: 'c', result: { type: 'canceled' } }))
+      .toEqual({ custom_id: 'c', failure: 'canceled', error: 'request canceled before it was processed' });
+  });
+});
@@ -0,0 +1,137 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import {
+  guardLlmCall,
+  LlmTimeoutError,
+  ModuleSkippedError,
+  ModuleSkipController,
+  LlmCallControl,
+} from '../../src/core/utils/llm-call-guard.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { RefactoredFile } from '../../src/core/types/refactor.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import { createTempDir, cleanupTempDir, createMockGoProject } from '../setup.js';
+
+const sleep = (ms: number) => new Promise(resolve => setTimeout(resolve, ms));
+
+/**
+ * Fake LLM client that streams `messages` chunks with a fixed delay between them
+ */
+function fakeStreamingCall(messages: number, delayMs: number) {
+  return async (control: LlmCallControl) => {
+    for (let i = 0; i < messages; i++) {
+      await sleep(delayMs);
+      if (control.abortController.signal.aborted) throw new Error('aborted');
+      control.onProgress();
+    }
+    return 'done';
+  };
+}
+
+describe('guardLlmCall', () => {
+  it('should keep slow but streaming calls alive past the idle timeout', async () => {
+    const result = await guardLlmCall(fakeStreamingCall(5, 20), { idleTimeoutMs: 50, requestTimeoutMs: 1000 });
+    expect(result).toBe('done');
+  });
+
+  it('should fail stalled calls with a retryable idle timeout', async () => {
+    const error = await guardLlmCall(fakeStreamingCall(1, 200), { idleTimeoutMs: 30, requestTimeoutMs: 1000 })
+      .catch(e => e);
+    expect(error).toBeInstanceOf(LlmTimeoutError);
+    expect(error.kind).toBe('idle');
+    expect(error.retryable).toBe(true);
+  });
+
+  it('should enforce the hard request timeout even while progressing', async () => {
+    const error = await guardLlmCall(fakeStreamingCall(20, 10), { idleTimeoutMs: 50, requestTimeoutMs: 60 })
+      .catch(e => e);
+    expect(error).toBeInstanceOf(LlmTimeoutError);
+    expect(error.kind).toBe('request');
+  });
+
+  it('should abort the call when the current module is skipped', async () => {
+    const skipController = new ModuleSkipController();
+    const signal = skipController.beginModule('order');
+
+    const pending = guardLlmCall(fakeStreamingCall(10, 20), { signal }).catch(e => e);
+    await sleep(10);
+    expect(skipController.skipCurrent()).toBe('order');
+
+    const error = await pending;
+    expect(error).toBeInstanceOf(ModuleSkippedError);
+    expect(error.module).toBe('order');
+  });
+});
+
+class FakeLlmRefactorAgent extends RefactorAgent {
+  calls = 0;
+
+  protected async requestTransformation(prompt: string): Promise<RefactoredFile> {
+    this.calls++;
+    const original = prompt.split('Original code:')[1] ?? '';
+    if (original.includes('type User struct') && original.includes('func (u *User) Validate')) {
+      throw new LlmTimeoutError('request', 10);
+    }
+    return {
+      refactored_files: [{
+        path: 'internal/user/domain/user.go',
+        content: `package domain\n\nimport "errors"\n\n// part ${this.calls}\n`,
+        description: 'user',
+      }],
+      interfaces: [],
+      tests: [],
+    };
+  }
+}
+
+describe('RefactorAgent timeout and skip handling', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('llm-call-guard');
+    await createMockGoProject(tempDir);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  const boundary = (dir: string) => ({
+    name: 'user',
+    description: 'User management',
+    files: [path.join(dir, 'user.go')],
+  });
+
+  it('should retry a timed-out file in smaller chunks and merge the outputs', async () => {
+    const agent = new FakeLlmRefactorAgent(tempDir);
+    const result = await agent.generateRefactoredCode(path.join(tempDir, 'user.go'), boundary(tempDir));
+
+    expect(agent.calls).toBe(3);
+    expect(result.refactored_files).toHaveLength(1);
+    expect(result.refactored_files[0].content).toContain('// part 2');
+    expect(result.refactored_files[0].content).toContain('// part 3');
+    expect(result.refactored_files[0].content.match(/"errors"/g)).toHaveLength(1);
+  });
+
+  it('should mark a module skipped from another terminal in the run record', async () => {
+    const store = new PerformanceStore(tempDir);
+    const runId = store.startRun('refactor-module');
+
+    const agent = new FakeLlmRefactorAgent(tempDir);
+    const stop = agent.skipController.watchRunRecord(store, runId, 5);
+    const originalRequest = agent['requestTransformation'].bind(agent);
+    (agent as any).requestTransformation = async (prompt: string, signal?: AbortSignal) => {
+      store.requestSkip(runId);
+      await new Promise((_, reject) => signal?.addEventListener('abort', () => reject(new ModuleSkippedError('user'))));
+      return originalRequest(prompt);
+    };
+
+    const result = await agent.executeRefactoring([boundary(tempDir)], false);
+    stop();
+
+    const run = new PerformanceStore(tempDir).getRun(runId)!;
+    expect(result.skipped_modules).toEqual(['user']);
+    expect(run.skipped_modules).toEqual(['user']);
+    expect(run.skip_requested).toEqual([]);
+  });
+});
@@ -0,0 +1,146 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  ConflictStore,
+  applyResolutions,
+  hasConflictMarkers,
+  mergeWithMarkers,
+  parseConflictBlocks,
+  planExcerpt,
+} from '../../src/core/utils/merge-conflicts.js';
+import { FileSafetyManager } from '../../src/core/utils/file-safety.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import { RefactorAgent, ModuleOutput } from '../../src/core/agents/refactor-agent.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const GENERATED = 'package domain\n\ntype Order struct {\n\tID string\n}\n\nfunc (o Order) Total() int {\n\treturn 0\n}\n';
+
+class TestableRefactorAgent extends RefactorAgent {
+  apply(outputs: ModuleOutput[], safetyManager?: FileSafetyManager) {
+    return this.applyModuleOutputs('order', outputs, safetyManager);
+  }
+}
+
+function output(content: string): ModuleOutput {
+  return {
+    source: 'order.go',
+    result: {
+      refactored_files: [{ path: 'internal/order/domain/order.go', content, description: 'order entity' }],
+      interfaces: [],
+      tests: [],
+    },
+  };
+}
+
+describe('mergeWithMarkers', () => {
+  it('should keep common lines and mark each differing region', () => {
+    const ours = 'a\nmine\nc\nd\n';
+    const theirs = 'a\nb\nc\nd\nnew\n';
+
+    const merged = mergeWithMarkers(ours, theirs);
+
+    expect(merged.hunks).toEqual([
+      { ours: ['mine'], theirs: ['b'] },
+      { ours: [], theirs: ['new'] },
+    ]);
+    expect(merged.content).toBe([
+      'a',
+      '<<<<<<< your manual edit', 'mine', '=======', 'b', '>>>>>>> vibeflow regeneration',
+      'c', 'd',
+      '<<<<<<< your manual edit', '=======', 'new', '>>>>>>> vibeflow regeneration',
+      '',
+    ].join('\n'));
+    expect(parseConflictBlocks(merged.content)).toHaveLength(2);
+  });
+
+  it('should resolve blocks by hunk choice and keep undecided blocks', () => {
+    const { content } = mergeWithMarkers('a\nmine\nc\nd\n', 'a\nb\nc\nd\nnew\n');
+
+    expect(applyResolutions(content, ['ours', 'theirs'])).toBe('a\nmine\nc\nd\nnew\n');
+    expect(applyResolutions(content, ['both', 'ours'])).toBe('a\nmine\nb\nc\nd\n');
+
+    const partial = applyResolutions(content, [undefined, 'theirs']);
+    expect(parseConflictBlocks(partial)).toEqual([{ start: 1, end: 5, ours: ['mine'], theirs: ['b'] }]);
+  });
+});
+
+describe('conflicts left by re-runs', () => {
+  let tempDir: string;
+  const filePath = 'internal/order/domain/order.go';
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('merge-conflicts');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should merge manual edits instead of overwriting them and record the conflict', async () => {
+    const store = new PerformanceStore(tempDir);
+    const runId = store.startRun('refactor --apply');
+    const agent = new TestableRefactorAgent(tempDir);
+
+    await agent.apply([output(GENERATED)]);
+    const edited = GENERATED.replace('return 0', 'return 42');
+    fs.writeFileSync(path.join(tempDir, filePath), edited);
+
+    // 再生成が変わらなければ手動編集をそのまま残す
+    await agent.apply([output(GENERATED)]);
+    expect(fs.readFileSync(path.join(tempDir, filePath), 'utf8')).toBe(edited);
+
+    await agent.apply([output(GENERATED.replace('ID string', 'ID string\n\tStatus string'))], new FileSafetyManager(tempDir));
+    const content = fs.readFileSync(path.join(tempDir, filePath), 'utf8');
+    expect(content).toContain('<<<<<<< your manual edit\n\treturn 42\n=======\n\treturn 0\n>>>>>>> vibeflow regeneration');
+
+    const [conflict] = new ConflictStore(tempDir).unresolved();
+    expect(conflict).toMatchObject({ path: filePath, module: 'order', source: 'order.go', run_id: runId });
+    expect(conflict.hunks).toHaveLength(2);
+    expect(new PerformanceStore(tempDir).getRun(runId)?.conflicts).toBe('unresolved');
+
+    // 未解決のマーカーがあるファイルは次の再実行でも触らない
+    await agent.apply([output(GENERATED)]);
+    expect(fs.readFileSync(path.join(tempDir, filePath), 'utf8')).toBe(content);
+  });
+
+  it('should apply resolutions with a backup and mark the run conflicts-resolved', async () => {
+    const runId = new PerformanceStore(tempDir).startRun('refactor --apply');
+    const merged = mergeWithMarkers('a\nmine\nc\n', 'a\nb\nc\n');
+    await createMockFile(path.join(tempDir, filePath), merged.content);
+    const conflicts = new ConflictStore(tempDir);
+    conflicts.record(runId, [{ path: filePath, module: 'order', source: 'order.go', hunks: merged.hunks }]);
+
+    const safetyManager = new FileSafetyManager(tempDir);
+    expect(await conflicts.apply(filePath, [undefined], safetyManager)).toBe(false);
+    expect(await conflicts.apply(filePath, ['theirs'], safetyManager)).toBe(true);
+
+    expect(fs.readFileSync(path.join(tempDir, filePath), 'utf8')).toBe('a\nb\nc\n');
+    expect(safetyManager.getBackupSummary().count).toBeGreaterThan(0);
+    expect(conflicts.unresolved()).toEqual([]);
+    expect(conflicts.load()?.files[0].hunks[0].resolution).toBe('theirs');
+    expect(new PerformanceStore(tempDir).getRun(runId)?.conflicts).toBe('conflicts-resolved');
+  });
+
+  it('should treat files whose markers were removed by hand as resolved', async () => {
+    const merged = mergeWithMarkers('x\n', 'y\n');
+    await createMockFile(path.join(tempDir, filePath), merged.content);
+    const conflicts = new ConflictStore(tempDir);
+    conflicts.record(undefined, [{ path: filePath, module: 'order', source: 'order.go', hunks: merged.hunks }]);
+    expect(conflicts.unresolved()).toHaveLength(1);
+
+    fs.writeFileSync(path.join(tempDir, filePath), 'x\ny\n');
+
+    expect(hasConflictMarkers('x\ny\n')).toBe(false);
+    expect(conflicts.unresolved()).toEqual([]);
+    expect(conflicts.load()?.files[0].hunks[0].resolution).toBe('manual');
+  });
+
+  it('should excerpt the plan section of a module', async () => {
+    await createMockFile(path.join(tempDir, '.vibeflow', 'plan.md'),
+      '# Plan\n\n## user\n\n- move user.go\n\n## order\n\n- move order.go\n- extract OrderRepository\n\n## payment\n');
+
+    expect(planExcerpt(tempDir, 'order')).toEqual(['## order', '', '- move order.go', '- extract OrderRepository']);
+    expect(planExcerpt(tempDir, 'shipping')).toEqual([]);
+  });
+});
@@ -0,0 +1,54 @@
+import { describe, it, expect } from 'vitest';
+import { MessageTopicIndex, buildAsyncEdges, findMessageEndpoints, renderAsyncEdgesSection } from '../../src/core/utils/message-topics.js';
+
+const fixtureRoot = './tests/fixtures/message-topics';
+const goFiles = [
+  'internal/billing/consumer.go',
+  'internal/events/subjects.go',
+  'internal/mailer/worker.go',
+  'internal/notification/queue.go',
+  'internal/order/publisher.go',
+  'internal/shipping/listener.go',
+];
+
+describe('Message topics', () => {
+  it('should find Kafka, NATS and SQS call sites with literal and constant topics', () => {
+    const endpoints = findMessageEndpoints(fixtureRoot, goFiles);
+
+    expect(endpoints.map(e => [e.broker, e.topic, e.role, e.file, e.symbol])).toEqual([
+      ['sqs', 'email-requests', 'publish', 'internal/notification/queue.go', 'EnqueueEmail'],
+      ['sqs', 'email-requests', 'subscribe', 'internal/mailer/worker.go', 'Poll'],
+      ['kafka', 'order-created', 'publish', 'internal/order/publisher.go', 'NewPublisher'],
+      ['kafka', 'order-created', 'subscribe', 'internal/billing/consumer.go', 'HandleOrderCreated'],
+      ['nats', 'orders.shipped', 'publish', 'internal/order/publisher.go', 'PublishOrderShipped'],
+      ['nats', 'orders.shipped', 'subscribe', 'internal/shipping/listener.go', 'HandleOrderShipped'],
+    ]);
+  });
+
+  it('should treat a publisher and its subscriber as integrated through the topic', () => {
+    const index = new MessageTopicIndex(findMessageEndpoints(fixtureRoot, goFiles));
+
+    expect(index.integrates('internal/order/publisher.go', 'internal/billing/consumer.go')).toBe(true);
+    expect(index.integrates('internal/shipping/listener.go', 'internal/order/publisher.go')).toBe(true);
+    expect(index.integrates('internal/billing/consumer.go', 'internal/shipping/listener.go')).toBe(false);
+  });
+
+  it('should record async edges between boundaries and render them for the plan', () => {
+    const edges = buildAsyncEdges(findMessageEndpoints(fixtureRoot, goFiles), [
+      { name: 'order', files: ['internal/order/publisher.go', 'internal/events/subjects.go'] },
+      { name: 'billing', files: ['internal/billing/consumer.go'] },
+      { name: 'shipping', files: ['internal/shipping/listener.go'] },
+      { name: 'notification', files: ['internal/notification/queue.go', 'internal/mailer/worker.go'] },
+    ]);
+
+    expect(edges).toEqual([
+      { topic: 'order-created', broker: 'kafka', from: 'order', to: 'billing', files: ['internal/billing/consumer.go', 'internal/order/publisher.go'] },
+      { topic: 'orders.shipped', broker: 'nats', from: 'order', to: 'shipping', files: ['internal/order/publisher.go', 'internal/shipping/listener.go'] },
+    ]);
+
+    const section = renderAsyncEdgesSection(edges);
+    expect(section).toContain('## 非同期連携 (Async Edges)');
+    expect(section).toContain('- `order-created (kafka)`: order → billing');
+    expect(renderAsyncEdgesSection([])).toBe('');
+  });
+});
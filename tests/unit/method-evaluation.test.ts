@@ -0,0 +1,151 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  CommandRunner,
+  EvaluatedMethod,
+  MethodEvaluator,
+  cyclomaticComplexity,
+  formatScorecard,
+  ruleSurvives,
+  summarizeMethods,
+} from '../../src/core/utils/method-evaluation.js';
+import { ModuleManifestStore } from '../../src/core/utils/module-manifest.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import { BusinessRule } from '../../src/core/types/business-logic.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const limitRule: BusinessRule = {
+  type: 'validation',
+  description: 'orders above 1000 are rejected',
+  code: 'if total > 1000 {\n\treturn errors.New("order limit exceeded")\n}',
+  location: { file: 'internal/legacy/order.go', line: 6 },
+  dependencies: [],
+  complexity: 'low',
+};
+
+const outputs: Record<EvaluatedMethod, string> = {
+  template: 'package usecase\n\n// TODO: port the legacy validation\nfunc (s *Service) Place(total int) error {\n\tpanic("not implemented")\n}\n',
+  llm: 'package usecase\n\nimport "errors"\n\nfunc (s *Service) Place(total int) error {\n\tif total > 1000 || total < 0 {\n' +
+    '\t\treturn errors.New("order limit exceeded")\n\t}\n\treturn nil\n}\n',
+};
+
+/**
+ * Writes the canned output of each method and records it in the workspace manifest
+ */
+async function generate(workspace: string, boundary: DomainBoundary, method: EvaluatedMethod): Promise<{ failed: number }> {
+  const file = `internal/${boundary.name}/usecase/service.go`;
+  await createMockFile(path.join(workspace, file), outputs[method]);
+  const files = [{ path: file, source: boundary.files[0], hash: '', symbols: [], generated_at: '2024-01-01T00:00:00.000Z' }];
+  if (method === 'llm') {
+    const test = `internal/${boundary.name}/usecase/service_test.go`;
+    await createMockFile(path.join(workspace, test), 'package usecase\n');
+    files.push({ ...files[0], path: test });
+  }
+  new ModuleManifestStore(workspace).save({ module: boundary.name, attempt: 1, updated_at: '2024-01-01T00:00:00.000Z', files });
+  return { failed: 0 };
+}
+
+const run: CommandRunner = (command, cwd) => {
+  if (command.startsWith('go test')) {
+    return { ok: false, output: ['pass', 'pass', 'fail'].map((action, i) => JSON.stringify({ Action: action, Test: `Test${i}` })).join('\n') };
+  }
+  if (command.startsWith('go vet') && fs.readFileSync(path.join(cwd, 'internal/order/usecase/service.go'), 'utf8').includes('panic')) {
+    return { ok: false, output: '# example.com/shop/internal/order/usecase\ninternal/order/usecase/service.go:4:32: unused parameter\nmain.go:3:1: elsewhere\n' };
+  }
+  return { ok: true, output: '' };
+};
+
+describe('quality rubric', () => {
+  it('should approximate cyclomatic complexity from branches', () => {
+    expect(cyclomaticComplexity('func f() {\n\treturn 1\n}')).toBe(1);
+    expect(cyclomaticComplexity('func f(a, b bool) {\n\tif a && b { // if\n\t\tfmt.Println("for")\n\t}\n\tfor {}\n}')).toBe(4);
+  });
+
+  it('should treat a rule as preserved when its literals survive', () => {
+    expect(ruleSurvives(limitRule, outputs.llm)).toBe(true);
+    expect(ruleSurvives(limitRule, outputs.template)).toBe(false);
+    expect(ruleSurvives({ ...limitRule, code: 'if total > 10000 {' }, outputs.llm)).toBe(false);
+    expect(ruleSurvives({ ...limitRule, code: 'return nil' }, outputs.llm)).toBe(true);
+  });
+});
+
+describe('MethodEvaluator', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('method-evaluation');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/legacy/order.go'), 'package legacy\n');
+    await createMockFile(path.join(tempDir, '.git/HEAD'), 'ref: refs/heads/main\n');
+    await createMockFile(path.join(tempDir, '.vibeflow/domain-map.json'), JSON.stringify({
+      project: 'shop',
+      boundaries: [{ id: 'order-1234abcd', name: 'order', description: '', files: ['internal/legacy/order.go'] }],
+    }));
+    await createMockFile(path.join(tempDir, 'rules.json'), JSON.stringify([limitRule]));
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should score both methods in isolated workspaces', async () => {
+    const seen: string[] = [];
+    const evaluator = new MethodEvaluator(tempDir);
+
+    const scorecard = await evaluator.evaluate('order', {
+      rulesPath: path.join(tempDir, 'rules.json'),
+      run,
+      generate: async (workspace, boundary, method) => {
+        seen.push(...fs.readdirSync(workspace), ...fs.readdirSync(path.join(workspace, '.vibeflow')));
+        expect(boundary.files).toEqual([path.join(workspace, 'internal/legacy/order.go')]);
+        return generate(workspace, boundary, method);
+      },
+    });
+
+    expect(seen).not.toContain('.git');
+    expect(fs.existsSync(path.join(tempDir, 'internal/order'))).toBe(false);
+
+    const [template, llm] = scorecard.scores;
+    expect(template).toMatchObject({
+      method: 'template', compiles: true, vet_warnings: 1, rules_preserved: 0, rules_total: 1,
+      todo_count: 1, not_implemented_count: 1, test_pass_rate: null, cyclomatic_max: 1,
+    });
+    expect(llm).toMatchObject({
+      method: 'llm', generated_files: 2, vet_warnings: 0, rules_preserved: 1,
+      tests_passed: 2, tests_failed: 1, todo_count: 0, not_implemented_count: 0, cyclomatic_max: 3,
+    });
+    expect(formatScorecard(scorecard)).toMatch(/Rules\s+0\/1\s+1\/1/);
+  });
+
+  it('should record the scores by method for vf metrics methods', async () => {
+    const evaluator = new MethodEvaluator(tempDir);
+    const scorecard = await evaluator.evaluate('order', { rulesPath: path.join(tempDir, 'rules.json'), run, generate });
+
+    const runId = evaluator.record(scorecard);
+
+    const store = new PerformanceStore(tempDir);
+    expect(store.getRun(runId)).toMatchObject({ command: 'evaluate-methods', status: 'success' });
+    expect(store.getFileProcessing(runId).map(r => [r.method, r.module])).toEqual([['template', 'order-1234abcd'], ['llm', 'order-1234abcd']]);
+
+    const summaries = Object.fromEntries(summarizeMethods(store).map(s => [s.method, s]));
+    expect(summaries.static).toMatchObject({ files: 0, evaluations: 0, quality: {} });
+    expect(summaries.template.quality).toMatchObject({ rules_preserved_rate: 0, not_implemented_count: 1 });
+    expect(summaries.llm.quality).toMatchObject({ rules_preserved_rate: 1, test_pass_rate: 2 / 3 });
+    expect(summaries.template.quality.test_pass_rate).toBeUndefined();
+  });
+
+  it('should keep scoring the other methods when one fails', async () => {
+    const scorecard = await new MethodEvaluator(tempDir).evaluate('order', {
+      rulesPath: path.join(tempDir, 'rules.json'),
+      run,
+      generate: async (workspace, boundary, method) => {
+        if (method === 'llm') throw new Error('Claude Code SDK not available');
+        return generate(workspace, boundary, method);
+      },
+    });
+
+    expect(scorecard.scores.map(s => [s.method, s.error])).toEqual([['template', undefined], ['llm', 'Claude Code SDK not available']]);
+  });
+});
@@ -0,0 +1,147 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import {
+  extractLegacyFunctions,
+  deriveMethodName,
+  buildMethodNameMapping,
+  reconcileMethodNames,
+  renameMethods,
+  renderMethodNamingSection,
+  parseMethodNamingSection,
+  methodKind,
+  MethodNameStore,
+} from '../../src/core/utils/method-naming.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+const LEGACY_SOURCE = `package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+func ProcessOrder(o *Order) error {
+	if o.Total <= 0 {
+		return errors.New("order total must be positive")
+	}
+	return nil
+}
+
+func AuthenticateUser(name, password string) (*User, error) {
+	return nil, errors.New("invalid credentials")
+}
+
+// Handle authenticates the user and issues a session
+func Handle(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, "failed to refresh session", http.StatusUnauthorized)
+}
+
+func (o *Order) Cancel() error {
+	return nil
+}
+
+func NewOrder() *Order {
+	return &Order{}
+}
+
+func calculateTax(total int) int {
+	return total / 10
+}
+`;
+
+describe('method naming', () => {
+  it('should keep domain verbs from legacy function names', () => {
+    const functions = extractLegacyFunctions(LEGACY_SOURCE, 'order.go');
+    const names = Object.fromEntries(functions.map(fn => [fn.name, deriveMethodName(fn)]));
+
+    expect(Object.keys(names).sort()).toEqual(['AuthenticateUser', 'Cancel', 'Handle', 'ProcessOrder']);
+    expect(names.ProcessOrder).toBe('ProcessOrder');
+    expect(names.AuthenticateUser).toBe('AuthenticateUser');
+    expect(names.Cancel).toBe('CancelOrder');
+    expect(names.Handle).toBe('RefreshSession');
+  });
+
+  it('should fall back to the doc comment verb for generic names', () => {
+    const name = deriveMethodName({ name: 'Do', comment: 'Do registers the customer', errorMessages: [] });
+    expect(name).toBe('RegisterCustomer');
+  });
+
+  it('should disambiguate collisions deterministically', () => {
+    const functions = [
+      { name: 'ProcessOrderHandler', comment: '', errorMessages: [] },
+      { name: 'processOrder', comment: '', errorMessages: [] },
+    ];
+    const first = buildMethodNameMapping('order', 'order.go', functions);
+    const second = buildMethodNameMapping('order', 'order.go', [...functions].reverse());
+
+    expect(first).toEqual(second);
+    expect(first.map(m => m.method).sort()).toEqual(['ProcessOrder', 'ProcessOrder2']);
+    expect(first.find(m => m.method === 'ProcessOrder2')!.disambiguated).toBe(true);
+  });
+
+  it('should reuse recorded names and avoid names used by other files of the module', () => {
+    const existing = [
+      { module: 'order', file: 'order.go', legacy: 'ProcessOrder', method: 'FulfilOrder', source: 'llm' as const },
+      { module: 'order', file: 'cart.go', legacy: 'Checkout', method: 'Checkout', source: 'heuristic' as const },
+    ];
+    const mapping = buildMethodNameMapping('order', 'order.go', [
+      { name: 'ProcessOrder', comment: '', errorMessages: [] },
+      { name: 'Checkout', comment: '', errorMessages: [] },
+    ], existing);
+
+    expect(mapping.map(m => `${m.legacy}→${m.method}`)).toEqual(['Checkout→Checkout2', 'ProcessOrder→FulfilOrder']);
+  });
+
+  it('should rename leftover legacy identifiers and accept non-colliding model suggestions', () => {
+    const mappings = buildMethodNameMapping('auth', 'auth.go', [
+      { name: 'LoginHandler', comment: '', errorMessages: [] },
+      { name: 'Handle', comment: '', errorMessages: ['failed to refresh session'] },
+    ]);
+    const outputs = [
+      { content: 'type AuthUseCase interface {\n    LoginHandler(ctx context.Context) error\n    RenewSession(ctx context.Context) error\n}' },
+      { content: 'func (s *AuthService) LoginHandler(ctx context.Context) error { return nil }\nfunc (s *AuthService) RenewSession(ctx context.Context) error { return nil }' },
+    ];
+
+    const { mappings: reconciled, renames } = reconcileMethodNames(mappings, outputs, [{ legacy: 'Handle', method: 'RenewSession' }]);
+
+    expect(renames).toEqual(new Map([['LoginHandler', 'Login']]));
+    expect(reconciled.find(m => m.legacy === 'Handle')).toMatchObject({ method: 'RenewSession', source: 'llm' });
+    expect(renameMethods(outputs[1].content, renames)).toContain('func (s *AuthService) Login(ctx');
+  });
+
+  it('should round-trip the prompt naming section', () => {
+    const mappings = buildMethodNameMapping('order', 'order.go', extractLegacyFunctions(LEGACY_SOURCE));
+    const section = renderMethodNamingSection(mappings);
+
+    expect(parseMethodNamingSection(`prompt\n${section}\n## Original`)).toEqual(
+      mappings.map(m => ({ legacy: m.legacy, method: m.method }))
+    );
+  });
+
+  it('should classify methods by their leading verb', () => {
+    expect(methodKind('RegisterCustomer')).toBe('create');
+    expect(methodKind('AuthenticateUser')).toBe('read');
+    expect(methodKind('CancelOrder')).toBe('update');
+    expect(methodKind('RemoveItem')).toBe('delete');
+  });
+});
+
+describe('MethodNameStore', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('method-names');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should replace mappings per file and keep other files of the module', () => {
+    const store = new MethodNameStore(tempDir);
+    store.save('order', 'order.go', [{ module: 'order', file: 'order.go', legacy: 'A', method: 'A', source: 'heuristic' }]);
+    store.save('order', 'cart.go', [{ module: 'order', file: 'cart.go', legacy: 'B', method: 'B', source: 'heuristic' }]);
+    store.save('order', 'order.go', [{ module: 'order', file: 'order.go', legacy: 'C', method: 'C', source: 'heuristic' }]);
+
+    expect(new MethodNameStore(tempDir).load('order').map(m => m.legacy).sort()).toEqual(['B', 'C']);
+  });
+});
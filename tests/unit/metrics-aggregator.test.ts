@@ -0,0 +1,146 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import {
+  MetricsAggregator,
+  formatCsv,
+  formatPrometheus,
+  resolveWorkspaces,
+} from '../../src/core/utils/metrics-aggregator.js';
+import { PerformanceStore, migratePerformanceData } from '../../src/core/utils/performance-store.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+function writeStore(workspace: string, content: unknown, fileName = 'performance.json') {
+  fs.mkdirSync(path.join(workspace, '.vibeflow'), { recursive: true });
+  fs.writeFileSync(path.join(workspace, '.vibeflow', fileName), JSON.stringify(content));
+}
+
+describe('MetricsAggregator', () => {
+  let rootDir: string;
+
+  beforeEach(async () => {
+    rootDir = await createTempDir('metrics-aggregate');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(rootDir);
+  });
+
+  it('should combine runs from multiple workspaces with workspace labels', () => {
+    const billing = path.join(rootDir, 'billing');
+    const orders = path.join(rootDir, 'orders');
+
+    const billingStore = new PerformanceStore(billing);
+    const runId = billingStore.startRun('refactor', { modules_planned: 4, loc: 2000 });
+    billingStore.finishRun(runId, {
+      status: 'success',
+      modules_migrated: 3,
+      input_tokens: 3000,
+      output_tokens: 1000,
+      cost: 1.5,
+    });
+
+    const ordersStore = new PerformanceStore(orders);
+    const failedRun = ordersStore.startRun('refactor', { modules_planned: 2, loc: 1000 });
+    ordersStore.finishRun(failedRun, { status: 'failed', cost: 0.5 });
+
+    const result = new MetricsAggregator().aggregate([billing, orders]);
+
+    expect(result.skipped).toHaveLength(0);
+    expect(result.workspaces.map(w => w.workspace)).toEqual(['billing', 'orders']);
+
+    const billingSummary = result.workspaces[0];
+    expect(billingSummary.successRate).toBe(1);
+    expect(billingSummary.modulesPlanned).toBe(4);
+    expect(billingSummary.modulesMigrated).toBe(3);
+    expect(billingSummary.tokensPerKloc).toBe(2000);
+
+    expect(result.totals.runs).toBe(2);
+    expect(result.totals.successRate).toBe(0.5);
+    expect(result.totals.cost).toBeCloseTo(2.0);
+    expect(result.monthlySpend).toHaveLength(2);
+  });
+
+  it('should report missing and locked workspaces as skipped', () => {
+    const missing = path.join(rootDir, 'missing');
+    const locked = path.join(rootDir, 'locked');
+    fs.mkdirSync(missing, { recursive: true });
+    writeStore(locked, { schema_version: 1, runs: [], file_processing: [], performance_metrics: [] });
+    writeStore(locked, {
+      pid: process.ppid,
+      command: 'refactor',
+      hostname: os.hostname(),
+      acquired_at: new Date().toISOString(),
+    }, 'performance.lock');
+
+    const result = new MetricsAggregator().aggregate([missing, locked]);
+
+    expect(result.workspaces).toHaveLength(0);
+    expect(result.skipped.map(s => s.workspace)).toEqual(['missing', 'locked']);
+    expect(result.skipped[0].reason).toContain('not found');
+    expect(result.skipped[1].reason).toContain('locked');
+  });
+
+  it('should read legacy usage history through the migration-aware reader', () => {
+    const legacy = path.join(rootDir, 'legacy');
+    writeStore(legacy, [
+      { timestamp: '2025-01-10T00:00:00.000Z', tokens: 1200, cost: 0.3, operation: 'refactor' },
+      { timestamp: '2025-02-03T00:00:00.000Z', tokens: 800, cost: 0.2, operation: 'refactor' },
+    ], 'usage-history.json');
+
+    const result = new MetricsAggregator().aggregate([legacy]);
+
+    expect(result.skipped).toHaveLength(0);
+    expect(result.workspaces[0].migratedFromVersion).toBe(0);
+    expect(result.monthlySpend.map(m => m.month)).toEqual(['2025-01', '2025-02']);
+  });
+
+  it('should reject stores written by a newer schema version', () => {
+    expect(() => migratePerformanceData({ schema_version: 99, runs: [] })).toThrow(/newer/);
+  });
+
+  it('should drop run entries that are not objects and number runs without an ID after the highest one', () => {
+    const workspace = path.join(rootDir, 'damaged');
+    writeStore(workspace, {
+      schema_version: 1,
+      runs: [null, 'interrupted', 42, [], { command: 'discover', status: 'success' }, { run_id: 7, command: 'refactor', status: 'failed' }],
+    });
+
+    const store = new PerformanceStore(workspace);
+    expect(store.getRuns().map(run => [run.run_id, run.command, run.status])).toEqual([
+      [8, 'discover', 'success'],
+      [7, 'refactor', 'failed'],
+    ]);
+    expect(store.startRun('plan')).toBe(9);
+  });
+
+  it('should resolve comma separated lists and globs', async () => {
+    fs.mkdirSync(path.join(rootDir, 'svc-a'));
+    fs.mkdirSync(path.join(rootDir, 'svc-b'));
+    fs.mkdirSync(path.join(rootDir, 'other'));
+
+    const globbed = await resolveWorkspaces('svc-*', rootDir);
+    expect(globbed.map(p => path.basename(p))).toEqual(['svc-a', 'svc-b']);
+
+    const listed = await resolveWorkspaces('svc-a, other', rootDir);
+    expect(listed.map(p => path.basename(p))).toEqual(['svc-a', 'other']);
+  });
+
+  it('should export CSV and Prometheus textfile formats with workspace labels', () => {
+    const workspace = path.join(rootDir, 'payments');
+    const store = new PerformanceStore(workspace);
+    const runId = store.startRun('refactor', { modules_planned: 1 });
+    store.finishRun(runId, { status: 'success', modules_migrated: 1, cost: 0.25 });
+
+    const result = new MetricsAggregator().aggregate([workspace]);
+
+    const csv = formatCsv(result);
+    expect(csv.split('\n')[0]).toContain('workspace,month,cost');
+    expect(csv).toContain('payments,');
+
+    const prom = formatPrometheus(result);
+    expect(prom).toContain('# TYPE vibeflow_spend_usd gauge');
+    expect(prom).toContain('vibeflow_run_success_ratio{workspace="payments"} 1');
+  });
+});
@@ -0,0 +1,113 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  MigrationCommitWriter,
+  ModuleChangeSummary,
+  renderCommitMessage,
+} from '../../src/core/utils/migration-commit.js';
+import { ModuleManifestStore } from '../../src/core/utils/module-manifest.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const order: ModuleChangeSummary = {
+  module: 'order',
+  files_created: 6,
+  files_moved: 2,
+  files_removed: 0,
+  interfaces: ['OrderRepository'],
+  events: ['OrderCreated'],
+  summary: 'Extracted the order module.',
+};
+
+describe('renderCommitMessage', () => {
+  it('should follow Conventional Commits with the run id', () => {
+    const message = renderCommitMessage({ run_id: 23, modules: [order], verification: { build: true, tests: false } });
+
+    expect(message.split('\n')[0]).toBe('refactor(order): extract order module (vibeflow run 23)');
+    expect(message).toContain('Extracted the order module.');
+    expect(message).toContain('- order: extracted interfaces OrderRepository');
+    expect(message).toContain('- order: introduced events OrderCreated');
+    expect(message).toContain('Verification: build passed, tests failed');
+    expect(message).toContain('Vibeflow-Run: 23');
+  });
+
+  it('should list every module summary in multi-module commits', () => {
+    const payment = { ...order, module: 'payment', interfaces: [], events: [], summary: 'Extracted payment.' };
+    const message = renderCommitMessage({ modules: [order, payment] });
+
+    expect(message.split('\n')[0]).toBe('refactor(order,payment): extract 2 modules');
+    expect(message).toContain('Modules:\n- order: Extracted the order module.\n- payment: Extracted payment.');
+    expect(message).toContain('Verification: not run');
+  });
+});
+
+describe('MigrationCommitWriter', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('migration-commit');
+    await createMockFile(path.join(tempDir, 'internal', 'order', 'domain', 'order.go'),
+      'package domain\n\ntype Order struct{}\n\ntype OrderCreated struct{}\n');
+    await createMockFile(path.join(tempDir, 'internal', 'order', 'usecase', 'ports.go'),
+      'package usecase\n\ntype OrderRepository interface {\n\tSave() error\n}\n');
+    const now = new Date().toISOString();
+    new ModuleManifestStore(tempDir).save({
+      module: 'order',
+      attempt: 1,
+      updated_at: now,
+      files: [
+        { path: 'internal/order/domain/order.go', source: 'order.go', hash: 'a', symbols: ['Order'], generated_at: now },
+        { path: 'internal/order/usecase/ports.go', source: 'order.go', hash: 'b', symbols: ['OrderRepository'], generated_at: now },
+      ],
+    });
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should describe a module from its output manifest', async () => {
+    const [change] = await new MigrationCommitWriter(tempDir).describeModules(['order'], ['internal/order/handler/old.go']);
+
+    expect(change).toMatchObject({
+      files_created: 2,
+      files_moved: 1,
+      files_removed: 1,
+      interfaces: ['OrderRepository'],
+      events: ['OrderCreated'],
+    });
+    expect(change.summary).toBe('Extracted the order module: 2 files created from 1 legacy files, 1 outdated outputs removed. ' +
+      'Extracted interfaces OrderRepository. Introduced events OrderCreated.');
+  });
+
+  it('should use the LLM summary and fall back to the template when it fails', async () => {
+    const writer = new MigrationCommitWriter(tempDir);
+
+    const [summarized] = await writer.describeModules(['order'], [], async () => 'Order handling now lives in its own module.');
+    const [fallback] = await writer.describeModules(['order'], [], async () => { throw new Error('SDK not available'); });
+
+    expect(summarized.summary).toBe('Order handling now lives in its own module.');
+    expect(fallback.summary).toMatch(/^Extracted the order module: 2 files created/);
+  });
+
+  it('should render the commit template override', async () => {
+    const writer = new MigrationCommitWriter(tempDir);
+    await createMockFile(writer.templatePath, '{{type}}({{scope}}): {{subject}}\n\nRun: {{run_id}}\n{{verification}}\n');
+
+    expect(writer.composeMessage({ run_id: 7, modules: [order], verification: { build: true, tests: true } }))
+      .toBe('refactor(order): extract order module\n\nRun: 7\nbuild passed, tests passed\n');
+  });
+
+  it('should append one migration log entry per module', () => {
+    const writer = new MigrationCommitWriter(tempDir);
+    writer.appendMigrationLog({ run_id: 3, modules: [order] }, 'abc123');
+    writer.appendMigrationLog({ run_id: 4, modules: [{ ...order, module: 'payment' }] });
+
+    const log = fs.readFileSync(writer.migrationLogPath, 'utf8');
+    expect(log.startsWith('# Migration Log')).toBe(true);
+    expect(log).toMatch(/## \d{4}-\d{2}-\d{2} — order \(vibeflow run 3\)\n\nExtracted the order module\.\n/);
+    expect(log).toContain('- Files: 6 created, 2 moved, 0 removed');
+    expect(log).toContain('- Commit: abc123');
+    expect(log.indexOf('— order')).toBeLessThan(log.indexOf('— payment'));
+  });
+});
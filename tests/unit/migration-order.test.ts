@@ -0,0 +1,56 @@
+import { describe, it, expect } from 'vitest';
+import { dependencyLayers, findMisorderedModules, renderMigrationOrderSection } from '../../src/core/utils/migration-order.js';
+
+const modules = [
+  { name: 'order', depends_on: ['billing', 'user'] },
+  { name: 'billing', depends_on: ['user', 'auth'] },
+  { name: 'user', depends_on: [] },
+  { name: 'notify', depends_on: ['order'] },
+];
+
+describe('Migration order', () => {
+  it('should layer modules leaves first, ignoring dependencies outside the list', () => {
+    expect(dependencyLayers(modules)).toEqual({
+      layers: [['user'], ['billing'], ['order'], ['notify']],
+      cycles: [],
+    });
+  });
+
+  it('should put the modules of a cycle in one layer and flag the cycle', () => {
+    const cyclic = [
+      { name: 'order', depends_on: ['billing'] },
+      { name: 'billing', depends_on: ['ledger'] },
+      { name: 'ledger', depends_on: ['order', 'user'] },
+      { name: 'user', depends_on: [] },
+      { name: 'report', depends_on: ['ledger'] },
+    ];
+
+    expect(dependencyLayers(cyclic)).toEqual({
+      layers: [['user'], ['order', 'billing', 'ledger'], ['report']],
+      cycles: [['order', 'billing', 'ledger']],
+    });
+  });
+
+  it('should report modules configured before their dependencies', () => {
+    const phases = [
+      { name: '注文', modules: ['order', 'user'] },
+      { name: '請求', modules: ['billing'] },
+    ];
+
+    const misordered = findMisorderedModules(phases, modules);
+    expect(misordered).toEqual([{ module: 'order', dependency: 'billing', phase: '注文', dependency_phase: '請求' }]);
+    expect(findMisorderedModules([{ name: '通知', modules: ['notify'] }], modules)).toEqual([
+      { module: 'notify', dependency: 'order', phase: '通知' },
+    ]);
+    expect(findMisorderedModules([{ name: '一括', modules: ['order', 'billing'] }], [
+      { name: 'order', depends_on: ['billing'] },
+      { name: 'billing', depends_on: ['order'] },
+    ], [['order', 'billing']])).toEqual([]);
+
+    const section = renderMigrationOrderSection({ source: 'dependencies', cycles: [['order', 'billing']], misordered });
+    expect(section).toContain('## 移行順序');
+    expect(section).toContain('- ⚠️ 依存サイクル: order, billing');
+    expect(section).toContain('migration.phases では order（注文）が依存先 billing（請求）より前になるため');
+    expect(renderMigrationOrderSection({ source: 'configured', cycles: [], misordered: [] })).toBe('');
+  });
+});
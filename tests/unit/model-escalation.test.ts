@@ -0,0 +1,124 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { RefactoredFile } from '../../src/core/types/refactor.js';
+import { ConfigLoader } from '../../src/core/utils/config-loader.js';
+import { InputParseError } from '../../src/core/utils/error-utils.js';
+import { PerformanceStore, RunRecord } from '../../src/core/utils/performance-store.js';
+import { nextModel, summarizeEscalations } from '../../src/core/utils/model-escalation.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const CHAIN = ['claude-haiku', 'claude-sonnet', 'claude-opus'];
+
+const output = (content: string): RefactoredFile => ({
+  refactored_files: [{ path: 'internal/order/domain/order.go', content, description: '' }],
+  interfaces: [],
+  tests: [],
+});
+
+class EscalatingAgent extends RefactorAgent {
+  calls: { model?: string; prompt: string }[] = [];
+
+  constructor(projectRoot: string, private respond: (model?: string) => RefactoredFile) {
+    super(projectRoot);
+  }
+
+  protected async requestTransformation(prompt: string, _signal?: AbortSignal, model?: string): Promise<RefactoredFile> {
+    this.calls.push({ model, prompt });
+    return this.respond(model);
+  }
+}
+
+describe('model escalation', () => {
+  let tempDir: string;
+  const boundary = () => ({ name: 'order', description: '', files: [path.join(tempDir, 'legacy/order.go')] });
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('model-escalation');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'legacy/order.go'), 'package legacy\n\nfunc PlaceOrder() {}\n');
+    ConfigLoader.saveConfig({
+      ...ConfigLoader.loadVibeFlowConfig(path.join(tempDir, 'missing.yaml')),
+      llm: { escalation: CHAIN },
+    }, path.join(tempDir, 'vibeflow.config.yaml'));
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should retry a malformed response once with the next model and record it in the run', async () => {
+    const store = new PerformanceStore(tempDir);
+    const runId = store.startRun('refactor-module');
+    const agent = new EscalatingAgent(tempDir, model => {
+      if (model === 'claude-haiku') throw new InputParseError('llm-response', 'no JSON found in response');
+      return output('package domain\n\nfunc Place() {}\n');
+    });
+
+    const result = await agent.executeRefactoring([boundary()], false);
+
+    expect(agent.calls.map(c => c.model)).toEqual(['claude-haiku', 'claude-sonnet']);
+    expect(agent.calls[1].prompt).toContain('## Previous Attempt\nThe previous attempt (claude-haiku) failed with llm-malformed-response:');
+    expect(result.failed_patches).toEqual([]);
+
+    const run = new PerformanceStore(tempDir).getRun(runId)!;
+    expect(run.escalations).toHaveLength(1);
+    expect(run.escalations![0]).toMatchObject({
+      module: 'order',
+      category: 'llm-malformed-response',
+      original_model: 'claude-haiku',
+      escalated_model: 'claude-sonnet',
+      outcome: 'success',
+    });
+    expect(run.escalations![0].extra_cost).toBeGreaterThan(0);
+    expect(run.cost).toBe(run.escalations![0].extra_cost);
+  });
+
+  it('should fail the file with verification-failure when the stronger model does not fix it', async () => {
+    const agent = new EscalatingAgent(tempDir, () => output('package domain\n\nfunc Place() {\n'));
+
+    const result = await agent.executeRefactoring([boundary()], false);
+
+    expect(agent.calls).toHaveLength(2);
+    expect(result.failed_patches.map(f => f.category)).toEqual(['verification-failure']);
+    expect(result.escalations?.map(e => e.outcome)).toEqual(['failed']);
+  });
+
+  it('should not escalate beyond the budget or with escalation disabled', async () => {
+    const malformed = () => { throw new InputParseError('llm-response', 'empty response'); };
+    await createMockFile(path.join(tempDir, '.vibeflow/cost-limits.json'), JSON.stringify({ perRun: 0.000001, daily: 10, monthly: 100 }));
+
+    const overBudget = new EscalatingAgent(tempDir, malformed);
+    const refused = await overBudget.executeRefactoring([boundary()], false);
+    expect(overBudget.calls).toHaveLength(1);
+    expect(refused.escalations).toMatchObject([{ outcome: 'over-budget', extra_cost: 0 }]);
+    expect(refused.failed_patches.map(f => f.category)).toEqual(['llm-malformed-response']);
+
+    const disabled = new EscalatingAgent(tempDir, malformed);
+    const result = await disabled.executeRefactoring([boundary()], false, { escalation: false });
+    expect(disabled.calls).toHaveLength(1);
+    expect(result.escalations).toBeUndefined();
+  });
+});
+
+describe('summarizeEscalations', () => {
+  it('should report how often runs escalated and how often it helped', () => {
+    const escalation = (outcome: 'success' | 'failed' | 'over-budget', extra_cost: number) => ({
+      module: 'order', file: 'legacy/order.go', category: 'verification-failure',
+      original_model: 'claude-haiku', escalated_model: 'claude-sonnet', outcome, extra_cost, recorded_at: '',
+    });
+    const run = (run_id: number, escalations?: ReturnType<typeof escalation>[]) =>
+      ({ run_id, command: 'refactor-module', escalations } as RunRecord);
+
+    const summary = summarizeEscalations([
+      run(1, [escalation('success', 0.5), escalation('failed', 0.25)]),
+      run(2, [escalation('success', 0.25), escalation('over-budget', 0)]),
+      run(3),
+    ]);
+
+    expect(summary).toMatchObject({ runs: 3, runs_with_escalations: 2, escalations: 4, succeeded: 2, failed: 1, over_budget: 1, extra_cost: 1 });
+    expect(summary.success_rate).toBeCloseTo(2 / 3);
+    expect(summary.by_model).toEqual({ 'claude-haiku → claude-sonnet': 4 });
+    expect(nextModel(['claude-haiku', 'claude-sonnet'], 'claude-sonnet')).toBeUndefined();
+  });
+});
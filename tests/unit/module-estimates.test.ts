@@ -0,0 +1,79 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { estimateModules, estimatePhases, renderEstimatesSection } from '../../src/core/utils/module-estimates.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const USER = `package user
+
+func Find(id string) string {
+	if id == "" {
+		return "anonymous"
+	}
+	return id
+}
+`;
+
+const ORDER = `package order
+
+import "example.com/shop/internal/user"
+
+func Place(id string, items []string) string {
+	for _, item := range items {
+		if item == "" || len(item) > 10 {
+			return ""
+		}
+	}
+	return user.Find(id) + user.Find("")
+}
+`;
+
+describe('Module estimates', () => {
+  let tempDir: string;
+  const modules = [
+    { name: 'user', files: ['internal/user/user.go', 'internal/user/user_test.go'], effort_days: 4 },
+    { name: 'order', files: ['internal/order/order.go'], effort_days: 7.5 },
+  ];
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('module-estimates');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/user/user.go'), USER);
+    await createMockFile(path.join(tempDir, 'internal/user/user_test.go'), 'package user\n');
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), ORDER);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should measure size, complexity, call sites and churn risk of each module', () => {
+    const estimates = estimateModules(tempDir, modules, {
+      commits: 12,
+      changes: { 'internal/user/user.go': 2, 'internal/order/order.go': 9 },
+    });
+
+    expect(estimates).toEqual({
+      modules: [
+        { module: 'user', loc_moved: 9, cyclomatic_complexity: 2, call_sites: 2, churn: 2, churn_risk: 11, effort_days: 4 },
+        { module: 'order', loc_moved: 12, cyclomatic_complexity: 4, call_sites: 0, churn: 9, churn_risk: 100, effort_days: 7.5 },
+      ],
+      history_commits: 12,
+    });
+    expect(estimatePhases(estimates, [{ name: '基盤', modules: ['user', 'order'] }])).toEqual([
+      { phase: '基盤', modules: ['user', 'order'], loc_moved: 21, call_sites: 2, effort_days: 11.5, churn_risk: 100 },
+    ]);
+
+    const section = renderEstimatesSection(estimates, [{ name: '基盤', modules: ['user', 'order'] }]);
+    expect(section).toContain('## 見積もり (Effort & Risk)');
+    expect(section).toContain('| order | 12 | 4 | 0 | 9 | 100 | 7.5 |');
+    expect(section).toContain('| フェーズ1: 基盤 | 21 | 2 | 11.5 | 100 |');
+  });
+
+  it('should leave churn out without git history and LOC out when the code stays in place', () => {
+    const estimates = estimateModules(tempDir, modules, null, { moved: false });
+
+    expect(estimates.history_commits).toBeUndefined();
+    expect(estimates.modules[0]).toEqual({ module: 'user', loc_moved: 0, cyclomatic_complexity: 2, call_sites: 2, effort_days: 4 });
+    expect(renderEstimatesSection(estimates, [])).toContain('| user | 0 | 2 | 2 | - | - | 4 |');
+  });
+});
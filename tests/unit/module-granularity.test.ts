@@ -0,0 +1,91 @@
+import { describe, it, expect } from 'vitest';
+import { adjustGranularity, GranularityAdapter } from '../../src/core/utils/module-granularity.js';
+import { domainBoundaryAdapter } from '../../src/core/utils/boundary-constraints.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+
+function boundary(name: string, files: string[], dependsOn: string[] = []): DomainBoundary {
+  return {
+    name,
+    description: `${name} module`,
+    files,
+    dependencies: { internal: dependsOn, external: [] },
+    circular_dependencies: [],
+  };
+}
+
+const adapter: GranularityAdapter<DomainBoundary> = {
+  ...domainBoundaryAdapter,
+  affinity: (a, b) => [a, b].filter((from, i) => (from.dependencies?.internal ?? []).includes([b, a][i].name)).length,
+  pinned: b => b.name.startsWith('seeded_'),
+};
+
+const mixed = boundary('module_1', [
+  'internal/billing/invoice.go',
+  'internal/billing/payment.go',
+  'internal/order/order.go',
+  'internal/order/repository.go',
+  'internal/order/service.go',
+  'internal/shipping/shipment.go',
+  'internal/shipping/carrier.go',
+]);
+
+describe('Module granularity', () => {
+  it('should leave modules alone without targets', () => {
+    const result = adjustGranularity([mixed], {}, adapter);
+
+    expect(result.items).toEqual([mixed]);
+    expect(result.adjustments).toEqual([]);
+  });
+
+  it('should split modules above maxFiles along their package directories', () => {
+    const result = adjustGranularity([mixed], { maxFiles: 4 }, adapter);
+
+    expect(result.items.map(b => [b.name, b.files.length])).toEqual([['module_1', 2], ['order', 3], ['billing', 2]]);
+    expect(result.items[0].files).toEqual(['internal/shipping/shipment.go', 'internal/shipping/carrier.go']);
+    expect(result.adjustments).toEqual([
+      'Split billing (2 files) out of module_1 (maxFiles 4)',
+      'Split order (3 files) out of module_1 (maxFiles 4)',
+    ]);
+  });
+
+  it('should report modules of a single package it cannot split', () => {
+    const result = adjustGranularity([boundary('order', ['order/a.go', 'order/b.go', 'order/c.go'])], { maxFiles: 2 }, adapter);
+
+    expect(result.items).toHaveLength(1);
+    expect(result.unmet).toEqual(['order has 3 files in one package (maxFiles 2)']);
+  });
+
+  it('should split the largest modules while below targetModules', () => {
+    const result = adjustGranularity([mixed, boundary('user', ['internal/user/user.go'])], { targetModules: 3 }, adapter);
+
+    expect(result.items.map(b => b.name)).toEqual(['module_1', 'billing', 'user']);
+  });
+
+  it('should merge the smallest modules into the most coupled one while above targetModules', () => {
+    const result = adjustGranularity([
+      boundary('order', ['order/a.go', 'order/b.go', 'order/c.go']),
+      boundary('pricing', ['pricing/price.go'], ['catalog']),
+      boundary('catalog', ['catalog/product.go', 'catalog/category.go']),
+    ], { targetModules: 2 }, adapter);
+
+    expect(result.items.map(b => b.name)).toEqual(['order', 'catalog']);
+    expect(result.items[1].merged_from).toEqual(['pricing']);
+    expect(result.adjustments).toEqual(['Merged pricing into catalog (targetModules 2)']);
+  });
+
+  it('should merge modules below minFiles but never two seeded ones or a mustSeparate pair', () => {
+    const result = adjustGranularity([
+      boundary('seeded_auth', ['auth/token.go']),
+      boundary('seeded_user', ['user/user.go'], ['seeded_auth']),
+      boundary('audit', ['audit/log.go'], ['seeded_user']),
+    ], { minFiles: 2 }, adapter, { mustSeparate: [['audit', 'seeded_auth']] });
+
+    expect(result.items.map(b => [b.name, b.files])).toEqual([
+      ['seeded_auth', ['auth/token.go']],
+      ['seeded_user', ['user/user.go', 'audit/log.go']],
+    ]);
+    expect(result.unmet).toEqual([
+      '1 modules stay below minFiles 2: every merge would violate a mustSeparate constraint or join two seeded modules',
+    ]);
+  });
+});
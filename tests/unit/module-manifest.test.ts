@@ -0,0 +1,125 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  ModuleManifestStore,
+  PendingOutput,
+  defineSymbols,
+  stripDeclarations,
+} from '../../src/core/utils/module-manifest.js';
+import { createTempDir, cleanupTempDir } from '../setup.js';
+
+const REPOSITORY_GO = `package infrastructure
+
+type OrderRepository struct {
+	db *sql.DB
+}
+
+func NewOrderRepository(db *sql.DB) *OrderRepository {
+	return &OrderRepository{db: db}
+}
+
+func (r *OrderRepository) Save(o *Order) error {
+	return nil
+}
+`;
+
+function writeOutputs(root: string, outputs: PendingOutput[]) {
+  for (const output of outputs) {
+    const fullPath = path.join(root, output.path);
+    fs.mkdirSync(path.dirname(fullPath), { recursive: true });
+    fs.writeFileSync(fullPath, output.content);
+  }
+}
+
+describe('ModuleManifestStore', () => {
+  let tempDir: string;
+  let store: ModuleManifestStore;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('module-manifest');
+    store = new ModuleManifestStore(tempDir);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  function firstRun(outputs: PendingOutput[]) {
+    const plan = store.planWrite('order', outputs, ['order.go']);
+    writeOutputs(tempDir, plan.write);
+    store.save(store.buildManifest('order', plan, new Map()));
+  }
+
+  it('should define method symbols with their receiver', () => {
+    expect(defineSymbols('repo.go', REPOSITORY_GO)).toEqual(['OrderRepository', 'NewOrderRepository', 'OrderRepository.Save']);
+  });
+
+  it('should treat identical regenerated outputs as unchanged', () => {
+    const outputs = [{ path: 'internal/order/infrastructure/order_repository.go', content: REPOSITORY_GO, source: 'order.go' }];
+    firstRun(outputs);
+
+    const plan = store.planWrite('order', outputs, ['order.go']);
+    expect(plan.write).toHaveLength(0);
+    expect(plan.unchanged).toHaveLength(1);
+    expect(store.load('order')!.attempt).toBe(1);
+  });
+
+  it('should detect renamed outputs by defined-symbol overlap', () => {
+    firstRun([{ path: 'internal/order/infrastructure/order_repository.go', content: REPOSITORY_GO, source: 'order.go' }]);
+
+    const plan = store.planWrite('order', [
+      { path: 'internal/order/infrastructure/orderrepository.go', content: REPOSITORY_GO, source: 'order.go' },
+    ], ['order.go']);
+
+    expect(plan.renamed).toEqual([{
+      from: path.normalize('internal/order/infrastructure/order_repository.go'),
+      to: path.normalize('internal/order/infrastructure/orderrepository.go'),
+    }]);
+    expect(plan.stale).toHaveLength(0);
+  });
+
+  it('should mark outputs of reprocessed sources that are no longer produced as stale', () => {
+    firstRun([
+      { path: 'internal/order/infrastructure/order_repository.go', content: REPOSITORY_GO, source: 'order.go' },
+      { path: 'internal/order/handler/order_handler.go', content: 'package handler\n\nfunc Handle() {\n}\n', source: 'order.go' },
+    ]);
+
+    const plan = store.planWrite('order', [
+      { path: 'internal/order/infrastructure/order_repository.go', content: REPOSITORY_GO, source: 'order.go' },
+    ], ['order.go']);
+
+    expect(plan.stale).toEqual([path.normalize('internal/order/handler/order_handler.go')]);
+  });
+
+  it('should keep outputs of sources that failed this run but strip redefined symbols', () => {
+    firstRun([
+      { path: 'internal/order/infrastructure/legacy.go', content: `package infrastructure\n\nfunc Helper() {\n}\n\nfunc Keep() {\n}\n`, source: 'legacy.go' },
+    ]);
+
+    const plan = store.planWrite('order', [
+      { path: 'internal/order/infrastructure/helpers.go', content: `package infrastructure\n\nfunc Helper() {\n}\n`, source: 'order.go' },
+    ], ['order.go']);
+
+    expect(plan.stale).toHaveLength(0);
+    expect(plan.strip).toEqual([{ path: path.normalize('internal/order/infrastructure/legacy.go'), symbols: ['Helper'] }]);
+  });
+
+  it('should resolve symbols declared twice in new outputs by keeping the newest', () => {
+    const plan = store.planWrite('order', [
+      { path: 'internal/order/domain/a.go', content: `package domain\n\ntype Order struct {\n}\n\nfunc A() {\n}\n`, source: 'order.go' },
+      { path: 'internal/order/domain/b.go', content: `package domain\n\ntype Order struct {\n\tID string\n}\n`, source: 'order.go' },
+    ], ['order.go']);
+
+    expect(plan.conflicts).toHaveLength(1);
+    expect(plan.conflicts[0].symbol).toBe('Order');
+    const first = plan.write.find(o => o.path.endsWith('a.go'))!;
+    expect(defineSymbols(first.path, first.content)).toEqual(['A']);
+  });
+
+  it('should strip declarations from Go source', () => {
+    const stripped = stripDeclarations('repo.go', REPOSITORY_GO, ['NewOrderRepository']);
+    expect(stripped).not.toContain('func NewOrderRepository');
+    expect(stripped).toContain('func (r *OrderRepository) Save');
+  });
+});
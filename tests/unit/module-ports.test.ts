@@ -0,0 +1,129 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { planModulePorts, renderPortsPromptSection, renderPortsSection } from '../../src/core/utils/module-ports.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const USER = `package user
+
+type Repository interface {
+	Find(id string) (*User, error)
+}
+
+type User struct {
+	ID   string
+	Name string
+}
+
+type Status string
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, limit: DefaultLimit}
+}
+
+type Service struct {
+	repo  Repository
+	limit int
+}
+
+const DefaultLimit = 10
+
+func Normalize(name string) string {
+	return name
+}
+`;
+
+const STORE = `package store
+
+import "example.com/shop/internal/user"
+
+func Clean(name string) string {
+	return user.Normalize(name)
+}
+`;
+
+const ORDER = `package order
+
+import "example.com/shop/internal/user"
+
+type Placer struct {
+	users user.Repository
+}
+
+func (p *Placer) Owner(id string) (*user.User, error) {
+	return p.users.Find(id)
+}
+
+func New(users user.Repository) *Placer {
+	return &Placer{users: users}
+}
+`;
+
+const MAIN = `package main
+
+import (
+	"example.com/shop/internal/order"
+	"example.com/shop/internal/user"
+)
+
+func main() {
+	order.New(user.NewService(nil))
+}
+`;
+
+describe('Module ports', () => {
+  let tempDir: string;
+  const modules = [
+    { name: 'user', files: ['internal/user/user.go', 'internal/user/store/store.go'] },
+    { name: 'order', files: ['internal/order/order.go'] },
+  ];
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('module-ports');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/user/user.go'), USER);
+    await createMockFile(path.join(tempDir, 'internal/user/store/store.go'), STORE);
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), ORDER);
+    await createMockFile(path.join(tempDir, 'cmd/shop/main.go'), MAIN);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should expose the interfaces, DTOs and functions other modules use and mark the rest internal', () => {
+    const ports = planModulePorts(tempDir, modules);
+    const user = ports.get('user')!;
+
+    expect(user.exposed.map(port => [port.name, port.kind, port.consumers, port.references])).toEqual([
+      ['Repository', 'interface', ['order'], 2],
+      ['User', 'dto', ['order'], 1],
+      ['NewService', 'func', ['cmd/shop'], 1],
+    ]);
+    expect(user.internal).toEqual([
+      { name: 'Status', package: 'internal/user', file: 'internal/user/user.go' },
+      { name: 'Service', package: 'internal/user', file: 'internal/user/user.go' },
+      { name: 'DefaultLimit', package: 'internal/user', file: 'internal/user/user.go' },
+      { name: 'Normalize', package: 'internal/user', file: 'internal/user/user.go', shared: true },
+      { name: 'Clean', package: 'internal/user/store', file: 'internal/user/store/store.go' },
+    ]);
+    expect(ports.get('order')!.exposed.map(port => [port.name, port.kind])).toEqual([['New', 'func']]);
+    expect(ports.get('order')!.internal.map(symbol => symbol.name)).toEqual(['Placer']);
+  });
+
+  it('should tell the refactor prompt which exports of the file stay public', () => {
+    const ports = planModulePorts(tempDir, modules);
+
+    const prompt = renderPortsPromptSection('user', ports.get('user'), 'internal/user/user.go');
+    expect(prompt).toContain('## Public API');
+    expect(prompt).toContain('- Repository (interface, used by order)');
+    expect(prompt).toContain('Unexport these symbols, which are used only inside their package: Status, Service, DefaultLimit\n');
+    expect(prompt).toContain('only under its internal/ directory: Normalize\n');
+    expect(renderPortsPromptSection('user', ports.get('user'), 'internal/order/order.go')).toBe('');
+
+    const section = renderPortsSection([{ name: 'user', ports: ports.get('user') }, { name: 'billing' }]);
+    expect(section).toContain('## 公開ポート (Public Ports)');
+    expect(section).toContain('| `internal/user.Repository` | インターフェース | order | 2 |');
+    expect(section).toContain('- 内部化: 5個（うちモジュール内の他パッケージが使う 1個は internal/ 配下で公開）');
+    expect(renderPortsSection([{ name: 'billing' }])).toBe('');
+  });
+});
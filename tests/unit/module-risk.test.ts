@@ -0,0 +1,161 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import {
+  RiskInputs,
+  autonomyTierRefusal,
+  gatherRiskInputs,
+  resolveRiskThresholds,
+  scoreModuleRisk,
+  tierAllows,
+} from '../../src/core/utils/module-risk.js';
+import { LlmCallRecord } from '../../src/core/utils/performance-store.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const ORDER = [
+  'package order',
+  '',
+  'import "reflect"',
+  '',
+  'type Order struct {',
+  '\tID string `json:"id" db:"id"`',
+  '}',
+  '',
+  'func Kind(v any) string { return reflect.TypeOf(v).String() }',
+  '',
+  'func Pay(db *sql.DB) error {',
+  '\ttx, _ := db.Begin()',
+  '\ttx.Exec("UPDATE invoices SET paid = 1")',
+  '\treturn tx.Commit()',
+  '}',
+  '',
+].join('\n');
+
+const inputs = (): RiskInputs => ({
+  source_files: 4,
+  tested_files: 3,
+  shared_state: 1,
+  transactions: 0,
+  contract_surface: 5,
+  cycles: 0,
+  lines_of_code: 1000,
+  debt_markers: 5,
+  verification: { calls: 10, failed: 2, scope: 'module' },
+});
+
+const call = (module: string, outcome: LlmCallRecord['outcome']): LlmCallRecord => ({
+  run_id: 1,
+  task: 'refactor',
+  template: 'prompts/refactor-transformation.txt',
+  template_hash: 'abc',
+  module,
+  file: `internal/${module}/${module}.go`,
+  attempt: 1,
+  input_tokens: 100,
+  output_tokens: 100,
+  cost: 0.01,
+  outcome,
+  recorded_at: '2026-01-01T00:00:00.000Z',
+});
+
+describe('module risk classification', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('module-risk');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should add up visible components into a score and a configurable tier', () => {
+    const risk = scoreModuleRisk(inputs());
+
+    expect(risk.components.map(c => [c.factor, c.points])).toEqual([
+      ['test_coverage', 6.3],
+      ['shared_mutable_state', 5],
+      ['cross_module_transactions', 0],
+      ['contract_surface', 2.5],
+      ['cyclic_dependencies', 0],
+      ['size', 2],
+      ['verification_failure_rate', 3],
+      ['debt_density', 5],
+    ]);
+    expect(risk.components[6].detail).toBe('2/10 LLM calls failed verification (this module)');
+    expect(risk).toMatchObject({ score: 23.8, tier: 'auto-apply' });
+
+    expect(scoreModuleRisk(inputs(), resolveRiskThresholds({ thresholds: { autoApply: 20 } })).tier).toBe('apply-with-review');
+    expect(scoreModuleRisk({ ...inputs(), tested_files: 0, cycles: 3 }).tier).toBe('apply-with-review');
+    expect(scoreModuleRisk({ ...inputs(), tested_files: 0, cycles: 3, transactions: 2, shared_state: 2 }).tier).toBe('manual-only');
+    expect(() => resolveRiskThresholds({ thresholds: { autoApply: 70 } })).toThrow('autonomy.thresholds.autoApply (70) must not exceed applyWithReview (60)');
+
+    expect(tierAllows('auto-apply', 'apply-with-review')).toBe(true);
+    expect(tierAllows('manual-only', 'apply-with-review')).toBe(false);
+  });
+
+  it('should gather the signals of a module from its sources and earlier runs', async () => {
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), ORDER);
+    await createMockFile(path.join(tempDir, 'internal/order/order_test.go'), 'package order\n');
+    await createMockFile(path.join(tempDir, 'internal/order/legacy/util.go'), 'package legacy\n\nfunc Util() {}\n');
+
+    const module = {
+      name: 'order',
+      files: [path.join(tempDir, 'internal/order/order.go'), 'internal/order/order_test.go', 'internal/order/legacy/util.go'],
+      debt: { total: 3, by_category: {}, test: 1, generated: 0 },
+    };
+    const gathered = gatherRiskInputs(tempDir, module, {
+      sharedState: [],
+      tableOwners: new Map([['invoices', 'billing'], ['orders', 'order']]),
+      circularDependencies: ['order -> billing -> order'],
+      llmCalls: [call('billing', 'verification-failed'), call('billing', 'success')],
+    });
+
+    expect(gathered).toEqual({
+      source_files: 2,
+      tested_files: 1,
+      shared_state: 0,
+      transactions: 1,
+      contract_surface: 2,
+      cycles: 1,
+      lines_of_code: ORDER.split('\n').length + 4,
+      debt_markers: 3,
+      verification: { calls: 2, failed: 1, scope: 'project' },
+    });
+  });
+
+  it('should refuse to apply modules above the allowed tier unless forced', async () => {
+    const risk = (score: number, tier: string) => ({ score, tier, components: [] });
+    await createMockFile(path.join(tempDir, '.vibeflow/plan.json'), JSON.stringify({
+      modules: [
+        { name: 'order', risk: risk(72.5, 'manual-only') },
+        { name: 'user', risk: risk(10, 'auto-apply') },
+      ],
+    }));
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), ORDER);
+    await createMockFile(path.join(tempDir, 'internal/billing/invoice.go'), 'package billing\n');
+
+    const result = await new RefactorAgent(tempDir).executeRefactoring([
+      { name: 'order', description: 'order', files: ['internal/order/order.go'] },
+      { name: 'billing', description: 'billing', files: ['internal/billing/invoice.go'] },
+    ], true, { maxAutoApplyTier: 'apply-with-review' });
+
+    expect(result.failed_patches).toEqual([
+      {
+        file: 'internal/order/order.go',
+        error: 'Module order is manual-only (risk 72.5), above the allowed tier apply-with-review. Review it and pass --force to apply',
+        category: 'refused',
+      },
+      {
+        file: 'internal/billing/invoice.go',
+        error: 'Module billing has no risk classification in plan.json (re-run vf plan); the allowed tier is apply-with-review. Review it and pass --force to apply',
+        category: 'refused',
+      },
+    ]);
+    expect(fs.existsSync(path.join(tempDir, 'internal/order/domain'))).toBe(false);
+
+    expect(autonomyTierRefusal('order', risk(72.5, 'manual-only') as any, 'apply-with-review', true)).toBeNull();
+    expect(autonomyTierRefusal('user', risk(10, 'auto-apply') as any, 'auto-apply', false)).toBeNull();
+  });
+});
@@ -0,0 +1,96 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { ModuleNotVerifiedError, ModuleStatusTracker, formatModuleStatus, nextStage } from '../../src/core/utils/module-status.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { RefactoredFile } from '../../src/core/types/refactor.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+describe('nextStage', () => {
+  it('should only move modules forward except when they are refactored again', () => {
+    expect(nextStage(undefined, 'discovered')).toBe('discovered');
+    expect(nextStage('refactored', 'planned')).toBeNull();
+    expect(nextStage('accepted', 'verified')).toBeNull();
+    expect(nextStage('verified', 'refactored')).toBe('refactored');
+    expect(nextStage('accepted', 'refactored')).toBe('refactored');
+  });
+});
+
+class FakeAgent extends RefactorAgent {
+  protected async requestTransformation(): Promise<RefactoredFile> {
+    return {
+      refactored_files: [{ path: 'internal/order/domain/order.go', content: 'package domain\n', description: '' }],
+      interfaces: [],
+      tests: [],
+    };
+  }
+}
+
+describe('ModuleStatusTracker', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('module-status');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'legacy/order.go'), 'package legacy\n\nfunc PlaceOrder() {}\n');
+    await createMockFile(path.join(tempDir, '.vibeflow/domain-map.json'), JSON.stringify({
+      project: 'shop',
+      boundaries: [
+        { id: 'bnd-order', name: 'order', description: '', files: ['legacy/order.go'] },
+        { name: 'user', description: '', files: [] },
+      ],
+    }));
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should follow a module from discovery to acceptance', () => {
+    const tracker = new ModuleStatusTracker(tempDir);
+
+    expect(tracker.advance([{ name: 'order' }, { name: 'user' }], 'discovered', 1)).toEqual(['order', 'user']);
+    tracker.advance([{ name: 'order' }, { name: 'user' }], 'planned');
+    tracker.advance([{ name: 'order' }], 'refactored', 2);
+    expect(() => tracker.accept('order', 'tanaka')).toThrow(ModuleNotVerifiedError);
+
+    expect(tracker.verifyRun(2)).toEqual(['order']);
+    tracker.accept('order', 'tanaka');
+    expect(tracker.advance([{ name: 'order' }], 'discovered', 3)).toEqual([]);
+
+    const [order, user] = new ModuleStatusTracker(tempDir).list();
+    expect(order).toMatchObject({ module: 'order', id: 'bnd-order', stage: 'accepted', run_id: 2, accepted_by: 'tanaka', remaining: [] });
+    expect(order.next).toBeUndefined();
+    expect(user).toMatchObject({ module: 'user', stage: 'planned', run_id: 1, next: 'vf refactor --module user --apply' });
+    expect(user.remaining).toEqual(['refactored', 'tests-relocated', 'verified', 'accepted']);
+
+    const record = new PerformanceStore(tempDir).getModuleStatuses().find(r => r.module === 'bnd-order')!;
+    expect(record.history.map(h => h.stage)).toEqual(['discovered', 'planned', 'refactored', 'verified', 'accepted']);
+    expect(formatModuleStatus([order, user], 'csv').split('\n').slice(0, 2)).toEqual([
+      'module,id,stage,run_id,updated_at,accepted_by,team,remaining,next',
+      `order,bnd-order,accepted,2,${order.updated_at},tanaka,,,`,
+    ]);
+  });
+
+  it('should refuse to overwrite an accepted module unless the caller confirms', async () => {
+    const tracker = new ModuleStatusTracker(tempDir);
+    tracker.advance([{ name: 'order' }], 'verified', 1);
+    tracker.accept('order', 'tanaka');
+    const boundary = { id: 'bnd-order', name: 'order', description: '', files: [path.join(tempDir, 'legacy/order.go')] };
+
+    const refused = await new FakeAgent(tempDir).executeRefactoring([boundary], true);
+    expect(refused.failed_patches.map(f => f.category)).toEqual(['refused']);
+    expect(refused.created_files).toEqual([]);
+
+    const asked: string[] = [];
+    const reopened = await new FakeAgent(tempDir).executeRefactoring([boundary], true, {
+      confirmReopen: async (moduleName, acceptedBy) => {
+        asked.push(`${moduleName}:${acceptedBy}`);
+        return true;
+      },
+    });
+    expect(asked).toEqual(['order:tanaka']);
+    expect(reopened.created_files).toContain('internal/order/domain/order.go');
+    expect(new ModuleStatusTracker(tempDir).find(boundary)?.stage).toBe('refactored');
+  });
+});
@@ -0,0 +1,91 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { MutationRunner, checkModuleMutations, generateMutants, selectMutants } from '../../src/core/utils/mutation-check.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const ORDER = [
+  'package domain',
+  '',
+  'import (',
+  '\t"errors"',
+  ')',
+  '',
+  '// Limit: qty < 10',
+  'func Validate(qty int) error {',
+  '\tif qty > 10 {',
+  '\t\treturn errors.New("too many: <")',
+  '\t}',
+  '\treturn nil',
+  '}',
+  '',
+].join('\n');
+
+describe('mutation smoke check', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('mutation-check');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/order/domain/order.go'), ORDER);
+    await createMockFile(path.join(tempDir, 'internal/order/domain/limit.go'), 'package domain\n\nconst MaxItems = 5\n');
+    await createMockFile(path.join(tempDir, 'internal/order/domain/order_test.go'), 'package domain\n\nimport "testing"\n\nfunc TestValidate(t *testing.T) {}\n');
+    await createMockFile(path.join(tempDir, 'internal/order/test/order_service_test.go'), 'package order_test\n\nimport "testing"\n\nfunc TestService(t *testing.T) {}\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should mutate code but not comments, strings or imports, and spread a bounded set over the files', () => {
+    const mutants = generateMutants('internal/order/domain/order.go', ORDER);
+    expect(mutants.map(m => [m.line, m.operator, m.mutated.trim()])).toEqual([
+      [9, 'negate-comparison', 'if qty <= 10 {'],
+      [9, 'off-by-one', 'if qty > 11 {'],
+      [10, 'error-to-nil', 'return nil'],
+    ]);
+    expect(generateMutants('a.go', 'package a\n\nfunc f(ch <-chan int, n int) (int, error) {\n\tx := n >> 1\n\treturn x, err\n}\n').map(m => m.mutated.trim()))
+      .toEqual(['x := n >> 2', 'return x, nil']);
+
+    const limit = generateMutants('internal/order/domain/limit.go', 'package domain\n\nconst MaxItems = 5\n');
+    expect(selectMutants([...mutants, ...limit], 3).map(m => [m.file, m.operator])).toEqual([
+      ['internal/order/domain/limit.go', 'off-by-one'],
+      ['internal/order/domain/order.go', 'negate-comparison'],
+      ['internal/order/domain/order.go', 'off-by-one'],
+    ]);
+  });
+
+  it('should report kill rates per file and flag generated tests that kill nothing', () => {
+    const commands: string[] = [];
+    const run: MutationRunner = (command, cwd) => {
+      commands.push(command);
+      const order = fs.readFileSync(path.join(cwd, 'internal/order/domain/order.go'), 'utf8');
+      const fail = (test: string) => ({ ok: false, output: `${JSON.stringify({ Action: 'fail', Package: 'example.com/shop/internal/order/domain', Test: test })}\n` });
+      if (order.includes('qty <= 10')) return fail('TestValidate');
+      if (!order.includes('errors.New')) return fail('TestValidate/too_many');
+      if (order.includes('qty > 11')) return { ok: false, output: 'FAIL\texample.com/shop/internal/order/domain [build failed]\n' };
+      return { ok: true, output: '' };
+    };
+
+    const result = checkModuleMutations(tempDir, 'order', { generatedTests: ['internal/order/test/order_service_test.go'], run });
+
+    expect(commands).toHaveLength(5);
+    expect(commands[0]).toBe('go test -json -count=1 ./internal/order/...');
+    expect(result).toMatchObject({ mutants: 3, killed: 2, invalid: 1, timed_out: 0 });
+    expect(result.kill_rate).toBeCloseTo(2 / 3);
+    expect(result.files).toEqual([
+      { file: 'internal/order/domain/limit.go', mutants: 1, killed: 0, kill_rate: 0 },
+      { file: 'internal/order/domain/order.go', mutants: 2, killed: 2, kill_rate: 1 },
+    ]);
+    expect(result.tests.map(t => [t.file, t.generated, t.killed, t.vacuous])).toEqual([
+      ['internal/order/domain/order_test.go', false, 2, false],
+      ['internal/order/test/order_service_test.go', true, 0, true],
+    ]);
+    // The workspace copy is mutated, never the project
+    expect(fs.readFileSync(path.join(tempDir, 'internal/order/domain/order.go'), 'utf8')).toBe(ORDER);
+
+    const failing = checkModuleMutations(tempDir, 'order', { run: () => ({ ok: false, output: '' }) });
+    expect(failing.skipped).toBe('the module\'s tests fail without mutations');
+    expect(checkModuleMutations(tempDir, 'billing', { run }).skipped).toBe('no test files in internal/billing');
+  });
+});
@@ -0,0 +1,86 @@
+import { describe, it, expect } from 'vitest';
+import { findOrchestrators, renderOrchestratorsSection } from '../../src/core/utils/orchestrators.js';
+import { CallEdge } from '../../src/core/utils/call-graph.js';
+import { TableAccess } from '../../src/core/utils/table-ownership.js';
+
+const boundaries = [
+  { name: 'order', files: ['internal/order/process.go', 'internal/order/repository.go'] },
+  { name: 'user', files: ['internal/user/user.go'] },
+  { name: 'inventory', files: ['internal/inventory/stock.go'] },
+  { name: 'payment', files: ['internal/payment/charge.go'] },
+];
+
+const call = (caller: string, callee_file: string, callee: string, count = 1): CallEdge => ({
+  caller_file: 'internal/order/process.go',
+  caller,
+  caller_type: 'OrderService',
+  callee_file,
+  callee,
+  count,
+});
+
+const access: TableAccess[] = [
+  { table: 'orders', file: 'internal/order/repository.go', symbol: 'Order', via: 'struct' },
+  { table: 'users', file: 'internal/user/user.go', symbol: 'User', via: 'struct' },
+  { table: 'stock', file: 'internal/inventory/stock.go', symbol: 'Reserve', via: 'query', operation: 'update' },
+  { table: 'payments', file: 'internal/payment/charge.go', symbol: 'Charge', via: 'query', operation: 'insert' },
+  { table: 'orders', file: 'internal/order/process.go', symbol: 'ProcessOrder', via: 'query', operation: 'insert' },
+  { table: 'users', file: 'internal/order/process.go', symbol: 'Summary', via: 'query', operation: 'select' },
+  { table: 'stock', file: 'internal/order/process.go', symbol: 'Summary', via: 'query', operation: 'select' },
+];
+
+describe('Orchestrator candidates', () => {
+  it('should flag a transaction script writing the data of several modules as a saga', () => {
+    const edges = [
+      call('ProcessOrder', 'internal/user/user.go', 'Get'),
+      call('ProcessOrder', 'internal/inventory/stock.go', 'Reserve', 2),
+      call('ProcessOrder', 'internal/payment/charge.go', 'Charge'),
+      // Calls within its own module do not count
+      call('ProcessOrder', 'internal/order/repository.go', 'Save'),
+      call('Cancel', 'internal/inventory/stock.go', 'Release'),
+    ];
+
+    const candidates = findOrchestrators(edges, access, boundaries);
+
+    expect(candidates).toHaveLength(2);
+    expect(candidates[0]).toEqual({
+      function: 'OrderService.ProcessOrder',
+      file: 'internal/order/process.go',
+      module: 'order',
+      kind: 'saga',
+      touches: [
+        { module: 'inventory', calls: 2, tables: [], writes: true },
+        { module: 'payment', calls: 1, tables: [], writes: true },
+        { module: 'user', calls: 1, tables: [], writes: false },
+      ],
+    });
+    // Only reads other modules' tables: coordinates them without a transaction
+    expect(candidates[1]).toMatchObject({
+      function: 'Summary',
+      kind: 'orchestrator',
+      touches: [
+        { module: 'inventory', calls: 0, tables: ['stock'], writes: false },
+        { module: 'user', calls: 0, tables: ['users'], writes: false },
+      ],
+    });
+  });
+
+  it('should honour the configured number of touched modules', () => {
+    const edges = [call('Cancel', 'internal/inventory/stock.go', 'Release')];
+
+    expect(findOrchestrators(edges, [], boundaries)).toEqual([]);
+    expect(findOrchestrators(edges, [], boundaries, { minModules: 1 }).map(c => c.function)).toEqual(['OrderService.Cancel']);
+  });
+
+  it('should render the candidates as a plan section', () => {
+    expect(renderOrchestratorsSection([])).toBe('');
+
+    const section = renderOrchestratorsSection(findOrchestrators([
+      call('ProcessOrder', 'internal/inventory/stock.go', 'Reserve'),
+      call('ProcessOrder', 'internal/payment/charge.go', 'Charge'),
+    ], access, boundaries));
+    expect(section).toContain('## サーガ・オーケストレーター候補');
+    expect(section).toContain('**OrderService.ProcessOrder** (internal/order/process.go、現在は order): 複数モジュールのデータを更新するため');
+    expect(section).toContain('  - payment: 呼び出し1箇所（書き込みあり）');
+  });
+});
@@ -0,0 +1,54 @@
+import { describe, it, expect } from 'vitest';
+import {
+  describePathFilters,
+  matchesPathFilters,
+  parsePackagePattern,
+  parsePathPattern,
+  resolvePathFilters,
+} from '../../src/core/utils/path-filters.js';
+
+const fixtureRoot = './tests/fixtures/third-party';
+
+const packages = [
+  { import_path: 'example.com/shop/internal/billing', dir: 'internal/billing', name: 'billing', files: [] },
+  { import_path: 'example.com/shop/internal/billing/invoice', dir: 'internal/billing/invoice', name: 'invoice', files: [] },
+  { import_path: 'example.com/shop/internal/billingx', dir: 'internal/billingx', name: 'billingx', files: [] },
+];
+
+describe('Path filters', () => {
+  it('should read directory patterns the way go list does', () => {
+    expect(parsePathPattern(fixtureRoot, './internal/billing/...')).toEqual({ dir: 'internal/billing', recursive: true });
+    expect(parsePathPattern(fixtureRoot, 'internal/order')).toEqual({ dir: 'internal/order', recursive: false });
+    expect(parsePathPattern(fixtureRoot, './...')).toEqual({ dir: '.', recursive: true });
+
+    expect(() => parsePathPattern(fixtureRoot, './internal/payment/...')).toThrow('no such directory');
+    expect(() => parsePathPattern(fixtureRoot, '../...')).toThrow('outside the project');
+  });
+
+  it('should match a package directory only, or its whole subtree', () => {
+    const filters = [{ dir: 'internal/billing', recursive: true }, { dir: 'cmd', recursive: false }];
+
+    expect(matchesPathFilters('internal/billing/charge.go', filters)).toBe(true);
+    expect(matchesPathFilters('internal/billing/invoice/pdf.go', filters)).toBe(true);
+    expect(matchesPathFilters('internal/billingx/charge.go', filters)).toBe(false);
+    expect(matchesPathFilters('cmd/main.go', filters)).toBe(true);
+    expect(matchesPathFilters('cmd/tool/main.go', filters)).toBe(false);
+    expect(matchesPathFilters('main.go', [{ dir: '.', recursive: false }])).toBe(true);
+    expect(describePathFilters(filters)).toBe('./internal/billing/..., ./cmd');
+  });
+
+  it('should resolve import path patterns to package directories', () => {
+    expect(parsePackagePattern('example.com/shop/internal/billing/...', packages)).toEqual([
+      { dir: 'internal/billing', recursive: false },
+      { dir: 'internal/billing/invoice', recursive: false },
+    ]);
+    expect(parsePackagePattern('example.com/shop/internal/billing', packages)).toEqual([{ dir: 'internal/billing', recursive: false }]);
+    expect(() => parsePackagePattern('example.com/shop/internal/payment/...', packages)).toThrow('matches no package');
+
+    expect(resolvePathFilters(fixtureRoot, { paths: ['./internal/order'], packages: ['example.com/shop/internal/billing'] })).toEqual([
+      { dir: 'internal/order', recursive: false },
+      { dir: 'internal/billing', recursive: false },
+    ]);
+    expect(resolvePathFilters(fixtureRoot, {})).toEqual([]);
+  });
+});
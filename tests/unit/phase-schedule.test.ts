@@ -0,0 +1,81 @@
+import { describe, it, expect } from 'vitest';
+import {
+  estimateEffortDays,
+  mergeScheduleConfig,
+  renderScheduleSection,
+  schedulePhases,
+} from '../../src/core/utils/phase-schedule.js';
+
+const modules = [
+  { name: 'catalog', effort_days: 5, depends_on: [], team: 'search' },
+  { name: 'payments', effort_days: 15, depends_on: ['user'], team: 'core' },
+  { name: 'user', effort_days: 10, depends_on: [], team: 'core' },
+];
+
+const config = {
+  start: '2025-07-01',
+  maxPhaseEffort: '2-sprints',
+  deadlines: { payments: '2025-08-01' },
+  teamAvailability: { core: '2025-Q3', search: { from: '2025-07-14', to: '2025-07-31' } },
+};
+
+describe('phase schedule', () => {
+  it('should read effort estimates and merge the schedule of boundary.yaml over the config', () => {
+    expect(estimateEffortDays('1-2週間')).toBe(7.5);
+    expect(estimateEffortDays('3-5日')).toBe(4);
+    expect(estimateEffortDays('2-sprints')).toBe(20);
+    expect(estimateEffortDays('3-weeks', { velocity: 3 })).toBe(9);
+    expect(estimateEffortDays('soon')).toBe(0);
+
+    expect(mergeScheduleConfig({ velocity: 4, deadlines: { user: '2025-09-01' } }, { deadlines: { payments: '2025-11-01' } }))
+      .toEqual({ velocity: 4, deadlines: { user: '2025-09-01', payments: '2025-11-01' }, teamAvailability: {}, teams: {} });
+    expect(mergeScheduleConfig(undefined, undefined)).toBeUndefined();
+  });
+
+  it('should order phases by dependencies and deadlines and report the constraints it misses', () => {
+    const schedule = schedulePhases(modules, config, { today: '2025-06-20' });
+
+    // user goes first: payments depends on it and has the earliest deadline
+    expect(schedule.phases.map(p => [p.name, p.team, p.start, p.end, p.effort_days])).toEqual([
+      ['user', 'core', '2025-07-01', '2025-07-15', 10],
+      ['payments', 'core', '2025-07-15', '2025-08-05', 15],
+      ['catalog', 'search', '2025-08-05', '2025-08-12', 5],
+    ]);
+    expect(schedule).toMatchObject({ start: '2025-07-01', end: '2025-08-12', velocity: 5, max_phase_effort_days: 20 });
+    expect(schedule.unsatisfiable.map(u => [u.constraint, u.module, u.limit, u.actual, u.over_by_days])).toEqual([
+      ['deadline', 'payments', '2025-08-01', '2025-08-05', 4],
+      ['team-availability', 'catalog', '2025-07-31', '2025-08-12', 12],
+    ]);
+
+    const oversized = schedulePhases([{ name: 'billing', effort_days: 25, depends_on: [] }], config, { today: '2025-06-20' });
+    expect(oversized.unsatisfiable).toMatchObject([{ constraint: 'max-phase-effort', module: 'billing', over_by_days: 5 }]);
+  });
+
+  it('should keep accepted phases fixed when re-planning and draw the timeline', () => {
+    const previous = schedulePhases(modules, config, { today: '2025-06-20' });
+    const replanned = schedulePhases(
+      modules.map(m => (m.name === 'payments' ? { ...m, effort_days: 10 } : m)),
+      config,
+      { today: '2025-07-16', accepted: new Set(['user']), previous }
+    );
+
+    expect(replanned.phases.map(p => [p.name, p.start, p.end, p.fixed])).toEqual([
+      ['user', '2025-07-01', '2025-07-15', true],
+      ['payments', '2025-07-15', '2025-07-29', false],
+      ['catalog', '2025-07-29', '2025-08-05', false],
+    ]);
+    expect(replanned.modules.find(m => m.module === 'user')).toMatchObject({ status: 'accepted', start: '2025-07-01', end: '2025-07-15' });
+    expect(replanned.unsatisfiable.map(u => [u.module, u.over_by_days])).toEqual([['catalog', 5]]);
+
+    const section = renderScheduleSection(replanned);
+    expect(section).toContain('## スケジュール (Timeline)');
+    expect(section).toContain([
+      '  section フェーズ1 (core)',
+      '  user :done, user, 2025-07-01, 2025-07-15',
+      '  section フェーズ2 (core)',
+      '  payments :payments, 2025-07-15, 2025-07-29',
+    ].join('\n'));
+    expect(section).toContain('  payments 期限 :milestone, payments_due, 2025-08-01, 0d');
+    expect(section).toContain('- **team-availability** [catalog]: search is available until 2025-07-31, but catalog needs 5 more days（超過: 5日）');
+  });
+});
@@ -0,0 +1,247 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { ArchitectAgent, ArchitecturalPlan, PLAN_SCHEMA_VERSION } from '../../src/core/agents/architect-agent.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { ConfigLoader } from '../../src/core/utils/config-loader.js';
+import { DomainMapWriter } from '../../src/core/utils/domain-map-writer.js';
+import { formatAction, moduleKey, parsePlanMarkdown, renderPlanDocument, replaceGeneratedBlock } from '../../src/core/utils/plan-sync.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+describe('plan.md ⇄ plan.json sync', () => {
+  let tempDir: string;
+  let agent: ArchitectAgent;
+
+  const planMd = () => path.join(tempDir, '.vibeflow/plan.md');
+  const planJson = () => path.join(tempDir, '.vibeflow/plan.json');
+  const readPlan = (): ArchitecturalPlan => JSON.parse(fs.readFileSync(planJson(), 'utf8'));
+  const moduleOf = (plan: ArchitecturalPlan, name: string) => plan.modules.find(m => m.name === name)!;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('plan-sync');
+    await createMockFile(path.join(tempDir, 'internal/user/user.go'), 'package user\n\ntype User struct{}\n');
+    await createMockFile(path.join(tempDir, 'internal/user/profile.go'), 'package user\n\ntype Profile struct{}\n');
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), 'package order\n\ntype Order struct{}\n');
+
+    ConfigLoader.saveConfig({
+      ...ConfigLoader.loadVibeFlowConfig(path.join(tempDir, 'missing.yaml')),
+      migration: {
+        phases: {
+          foundation: { name: '基盤', duration: '1週間', modules: ['user'] },
+          ordering: { name: '注文', duration: '2週間', modules: ['order'] },
+        },
+      },
+    }, path.join(tempDir, 'vibeflow.config.yaml'));
+
+    new DomainMapWriter(tempDir).write({
+      project: 'shop',
+      language: 'go',
+      analyzed_at: '2026-01-01T00:00:00.000Z',
+      total_files: 3,
+      boundaries: [
+        { name: 'user', description: 'Accounts', files: ['internal/user/user.go', 'internal/user/profile.go'] },
+        { name: 'order', description: 'Order placement', files: ['internal/order/order.go'] },
+      ],
+      metrics: { overall_cohesion: 0, overall_coupling: 0, modularity_score: 0 },
+    });
+
+    agent = new ArchitectAgent(tempDir, path.join(tempDir, 'vibeflow.config.yaml'), path.join(tempDir, 'boundary.yaml'));
+    await agent.generateArchitecturalPlan(path.join(tempDir, '.vibeflow/domain-map.json'));
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should keep human-authored text outside generated blocks verbatim', () => {
+    const rendered = renderPlanDocument(
+      [{ key: 'overview', content: '# Plan' }, { key: 'module:m1', content: '### user' }],
+      '<!-- vf:generated overview -->\n# Old\n<!-- /vf:generated -->\n\nKeep me.\n\n<!-- vf:generated module:gone -->\n### gone\n<!-- /vf:generated -->\n\nAbout gone.\n'
+    );
+
+    expect(rendered).toBe([
+      '<!-- vf:generated overview -->',
+      '# Plan',
+      '<!-- /vf:generated -->',
+      '',
+      'Keep me.',
+      '',
+      '',
+      'About gone.',
+      '<!-- vf:generated module:m1 -->',
+      '### user',
+      '<!-- /vf:generated -->',
+      '',
+    ].join('\n'));
+    expect(() => parsePlanMarkdown('<!-- vf:generated overview -->\n# Plan\n')).toThrow('plan.md: generated block overview is not closed');
+  });
+
+  it('should apply structured edits to plan.json and the domain map and regenerate plan.md stably', async () => {
+    const before = readPlan();
+    const user = moduleOf(before, 'user');
+    const order = moduleOf(before, 'order');
+    expect(before.migration_strategy.phases.map(p => p.modules)).toEqual([['user'], ['order']]);
+
+    const prose = '> Decided with the payments team: profiles belong to ordering.\n';
+    const edited = fs.readFileSync(planMd(), 'utf8')
+      .replace(`<!-- vf:generated module:${moduleKey(order)} -->`, `${prose}\n<!-- vf:generated module:${moduleKey(order)} -->`)
+      .replace('### user\n', '### account\n')
+      .replace('**説明**: Order placement', '**説明**: Orders and carts')
+      .replace('- `internal/user/profile.go`\n', '')
+      .replace('- `internal/order/order.go`\n', '- `internal/order/order.go`\n- `internal/user/profile.go`\n')
+      .replace(`- ${formatAction(order.refactoring_actions[0])}`, '- Split the order handlers (low)')
+      .replace('- 対象モジュール: order\n', '- 対象モジュール: order, account\n');
+    fs.writeFileSync(planMd(), edited);
+
+    const result = agent.syncPlanMarkdown();
+
+    expect(result.conflicts).toEqual([]);
+    expect(result.uncaptured).toEqual([]);
+    expect(result.applied.map(change => change.field).sort()).toEqual([
+      `module:${moduleKey(order)}.action.1`,
+      `module:${moduleKey(order)}.description`,
+      `module:${moduleKey(order)}.files`,
+      `module:${moduleKey(user)}.files`,
+      `module:${moduleKey(user)}.name`,
+      'phase:2.modules',
+    ]);
+    expect(result.renames).toEqual([{ id: user.id, from: 'user', to: 'account' }]);
+
+    const after = readPlan();
+    expect(moduleOf(after, 'account').current_state.files).toEqual(['internal/user/user.go']);
+    expect(moduleOf(after, 'order')).toMatchObject({
+      description: 'Orders and carts',
+      current_state: { files: ['internal/order/order.go', 'internal/user/profile.go'] },
+    });
+    expect(moduleOf(after, 'order').refactoring_actions[0]).toMatchObject({ description: 'Split the order handlers', priority: 'low' });
+    expect(after.migration_strategy.phases.map(p => p.modules)).toEqual([[], ['order', 'account']]);
+
+    expect(result.domainMapUpdates.sort()).toEqual(['account', 'order']);
+    const map = JSON.parse(fs.readFileSync(path.join(tempDir, '.vibeflow/domain-map.json'), 'utf8'));
+    expect(map.boundaries.find((b: { id: string }) => b.id === user.id)).toMatchObject({ name: 'account', files: ['internal/user/user.go'] });
+
+    const synced = fs.readFileSync(planMd(), 'utf8');
+    expect(synced).toContain(prose);
+    expect(synced).toContain('### account\n');
+    expect(synced).toContain('- 対象モジュール: order, account\n');
+
+    const again = agent.syncPlanMarkdown();
+    expect(again.applied).toEqual([]);
+    expect(again.uncaptured).toEqual([]);
+    expect(fs.readFileSync(planMd(), 'utf8')).toBe(synced);
+
+    await agent.generateArchitecturalPlan(path.join(tempDir, '.vibeflow/domain-map.json'));
+    expect(fs.readFileSync(planMd(), 'utf8')).toContain(prose);
+  });
+
+  it('should report fields changed in both files and leave plan.md as is', () => {
+    const plan = readPlan();
+    moduleOf(plan, 'order').description = 'Checkout';
+    fs.writeFileSync(planJson(), JSON.stringify(plan, null, 2));
+    const edited = fs.readFileSync(planMd(), 'utf8').replace('**説明**: Order placement', '**説明**: Orders and carts');
+    fs.writeFileSync(planMd(), edited);
+
+    const result = agent.syncPlanMarkdown();
+
+    expect(result.conflicts).toEqual([
+      { field: `module:${moduleKey(moduleOf(plan, 'order'))}.description`, markdown: 'Orders and carts', json: 'Checkout' },
+    ]);
+    expect(result.markdown).toBeNull();
+    expect(fs.readFileSync(planMd(), 'utf8')).toBe(edited);
+    expect(moduleOf(readPlan(), 'order').description).toBe('Checkout');
+  });
+
+  it('should flag free text inside generated blocks until it is moved out or forced', () => {
+    const key = moduleKey(moduleOf(readPlan(), 'user'));
+    const edited = fs.readFileSync(planMd(), 'utf8').replace('### user\n', '### user\n\nProfiles may move to ordering.\n');
+    fs.writeFileSync(planMd(), edited);
+
+    const result = agent.syncPlanMarkdown();
+    expect(result.uncaptured).toEqual([
+      { block: `module:${key}`, reason: 'free text inside a generated block', lines: ['Profiles may move to ordering.'] },
+    ]);
+    expect(result.markdown).toBeNull();
+    expect(fs.readFileSync(planMd(), 'utf8')).toBe(edited);
+
+    agent.syncPlanMarkdown({ force: true });
+    expect(fs.readFileSync(planMd(), 'utf8')).not.toContain('Profiles may move to ordering.');
+  });
+
+  it('should drop actions deleted from plan.md and reorder the phases moved there', () => {
+    const before = readPlan();
+    const key = `module:${moduleKey(moduleOf(before, 'user'))}`;
+    const actions = moduleOf(before, 'user').refactoring_actions;
+    const last = formatAction(actions[actions.length - 1]);
+
+    let markdown = fs.readFileSync(planMd(), 'utf8');
+    const blocks = parsePlanMarkdown(markdown).blocks;
+    const content = (block: string) => blocks.find(b => b.key === block)!.content;
+    const phase = (n: number) => `<!-- vf:generated phase:${n} -->\n${content(`phase:${n}`)}\n<!-- /vf:generated -->`;
+    // The actions are the last lines of the module's block
+    markdown = replaceGeneratedBlock(markdown, key, content(key).split('\n').slice(0, -1).join('\n'))!;
+    markdown = markdown.replace(phase(1), '@@').replace(phase(2), phase(1)).replace('@@', phase(2));
+    fs.writeFileSync(planMd(), markdown);
+
+    const result = agent.syncPlanMarkdown();
+
+    expect(result.conflicts).toEqual([]);
+    expect(result.uncaptured).toEqual([]);
+    expect(result.applied).toEqual([
+      { field: `${key}.action.${actions.length}`, from: last, to: '' },
+      { field: 'phases', from: '基盤, 注文', to: '注文, 基盤' },
+    ]);
+    const synced = readPlan();
+    expect(moduleOf(synced, 'user').refactoring_actions).toEqual(actions.slice(0, -1));
+    expect(synced.migration_strategy.phases.map(p => [p.name, p.modules])).toEqual([['注文', ['order']], ['基盤', ['user']]]);
+    expect(fs.readFileSync(planMd(), 'utf8')).toContain('### フェーズ1: 注文');
+  });
+
+  it('should write a versioned plan.json vf refactor generates its patches from', async () => {
+    const plan = readPlan();
+    expect(plan.schema_version).toBe(PLAN_SCHEMA_VERSION);
+    expect(moduleOf(plan, 'order').target_files).toEqual([{
+      source: 'internal/order/order.go',
+      targets: [
+        { path: 'internal/order/domain/order_entity.go', description: 'Extract order domain entity from internal/order/order.go' },
+        { path: 'internal/order/usecase/order_service.go', description: 'Create order service with business logic' },
+        { path: 'internal/order/infrastructure/order_repository.go', description: 'Create order repository implementation' },
+        { path: 'internal/order/handler/order_handler.go', description: 'Create order HTTP handler' },
+      ],
+    }]);
+
+    // Modules follow the phases of plan.json; plan.md is not read
+    plan.migration_strategy.phases.reverse();
+    fs.writeFileSync(planJson(), JSON.stringify(plan, null, 2));
+    fs.rmSync(planMd());
+    const { plan: refactorPlan } = await new RefactorAgent(tempDir).generateRefactorPlan();
+
+    expect(refactorPlan.summary.target_modules).toEqual(['order', 'user']);
+    expect(refactorPlan.patches.map(p => [p.id, p.target_file])).toEqual([
+      ['001_order', 'internal/order/order.go'],
+      ['002_profile', 'internal/user/profile.go'],
+      ['002_user', 'internal/user/user.go'],
+    ]);
+    expect(refactorPlan.patches[0].changes[0]).toEqual({
+      type: 'create',
+      target_path: 'internal/order/domain/order_entity.go',
+      description: 'Extract order domain entity from internal/order/order.go',
+    });
+  });
+
+  it('should refuse a malformed plan.json with a clear error instead of a TypeError', async () => {
+    const plan = readPlan() as any;
+    delete plan.migration_strategy.phases;
+    delete moduleOf(plan, 'user').current_state;
+    moduleOf(plan, 'order').dependencies = 'user';
+    fs.writeFileSync(planJson(), JSON.stringify(plan, null, 2));
+
+    const refactoring = new RefactorAgent(tempDir).generateRefactorPlan();
+    await expect(refactoring).rejects.toThrow('invalid plan: ');
+    await expect(refactoring).rejects.toThrow('module:user: current_state is missing or not an object');
+    await expect(refactoring).rejects.toThrow('module:order: dependencies is missing or not an array');
+    await expect(refactoring).rejects.toThrow('migration_strategy: phases is missing or not an array');
+
+    fs.writeFileSync(planJson(), '{"modules": {}}');
+    await expect(new RefactorAgent(tempDir).generateRefactorPlan()).rejects.toThrow('invalid plan: modules must be an array');
+  });
+});
@@ -0,0 +1,121 @@
+import { describe, it, expect } from 'vitest';
+import { ArchitecturalPlan, ModuleDesign } from '../../src/core/agents/architect-agent.js';
+import { validatePlan } from '../../src/core/utils/plan-validation.js';
+
+const state = (files: string[]) => ({
+  files,
+  lines_of_code: 0,
+  test_coverage: 0,
+  cyclomatic_complexity: 0,
+  coupling_score: 0,
+  cohesion_score: 0,
+});
+
+const module = (id: string, name: string, files: string[], dependsOn: string[] = []): ModuleDesign => ({
+  id,
+  name,
+  description: '',
+  current_state: state(files),
+  target_state: state(files),
+  refactoring_actions: [{
+    type: 'move_file',
+    description: `Move ${name}`,
+    files_affected: files,
+    priority: 'high',
+    effort_estimate: '1日',
+  }],
+  dependencies: dependsOn.map(dep => ({ module: dep, type: 'interface' as const, description: '' })),
+  interfaces: [],
+});
+
+const phase = (name: string, modules: string[]) => ({ name, duration: '1週間', modules, actions: [], success_criteria: [], risks: [] });
+
+const plan = (modules: ModuleDesign[], phases: ReturnType<typeof phase>[]) => ({
+  overview: '',
+  modules,
+  migration_strategy: { phases, rollback_plan: '', validation_steps: [] },
+  implementation_guide: {
+    directory_structure: {},
+    naming_conventions: [],
+    code_patterns: [],
+    testing_strategy: { unit_tests: '', integration_tests: '', e2e_tests: '' },
+  },
+  quality_gates: [],
+  constraint_adjustments: [],
+  constraint_violations: [],
+}) as unknown as ArchitecturalPlan;
+
+const boundaries = [
+  { id: 'b-user', name: 'user', description: '', files: ['internal/user/user.go'] },
+  { id: 'b-order', name: 'order', description: '', files: ['internal/order/order.go', 'internal/order/cart.go'] },
+];
+
+describe('Plan validation', () => {
+  it('should accept a plan consistent with the domain map', () => {
+    const consistent = plan(
+      [module('b-user', 'user', ['internal/user/user.go']), module('b-order', 'order', ['internal/order/order.go', 'internal/order/cart.go'], ['user'])],
+      [phase('基盤', ['user']), phase('注文', ['order'])]
+    );
+
+    expect(validatePlan(consistent, boundaries)).toEqual([]);
+  });
+
+  it('should report renames, files and phases that no longer match', () => {
+    const edited = plan(
+      [
+        module('b-user', 'account', ['internal/user/user.go', 'internal/order/cart.go']),
+        module('b-order', 'order', ['internal/order/order.go'], ['account']),
+        module('b-gone', 'legacy', []),
+      ],
+      [phase('注文', ['order', 'legacy']), phase('基盤', ['account', 'order', 'billing']), phase('空', [])]
+    );
+
+    expect(validatePlan(edited, boundaries)).toEqual([
+      { severity: 'error', subject: 'module:account', message: 'the boundary is named user in domain-map.json (rename it with vf plan sync, which renames the boundary too)' },
+      { severity: 'error', subject: 'module:account', message: 'files not in the boundary of domain-map.json: internal/order/cart.go' },
+      { severity: 'error', subject: 'module:order', message: 'files of the boundary missing from plan.json: internal/order/cart.go' },
+      { severity: 'error', subject: 'module:legacy', message: 'boundary b-gone is no longer in domain-map.json (rerun vf plan)' },
+      { severity: 'error', subject: 'phase:2', message: 'order is also in phase 1 (注文)' },
+      { severity: 'error', subject: 'phase:2', message: 'billing is not a module of the plan' },
+      { severity: 'warning', subject: 'phase:3', message: '空 has no modules' },
+      { severity: 'error', subject: 'phase:1', message: 'order comes before its dependency account (基盤)' },
+    ]);
+  });
+
+  it('should warn about plan.md edits that were not synced', () => {
+    const consistent = plan([module('b-user', 'user', ['internal/user/user.go'])], [phase('基盤', ['user'])]);
+    consistent.sync = { synced_at: '2026-01-01T00:00:00.000Z', fields: {}, blocks: { 'phase:1': '0000000000000000' } };
+    const markdown = '<!-- vf:generated phase:1 -->\n### フェーズ1: 基盤\n<!-- /vf:generated -->\n';
+
+    expect(validatePlan(consistent, [boundaries[0]], { markdown })).toEqual([
+      { severity: 'warning', subject: 'plan.md', message: 'edits not applied to plan.json yet (run vf plan sync): phase:1' },
+    ]);
+  });
+
+  it('should report a module without current_state instead of failing', () => {
+    const broken = plan([module('b-user', 'user', ['internal/user/user.go'])], [phase('基盤', ['user'])]);
+    delete (broken.modules[0] as Partial<ModuleDesign>).current_state;
+
+    expect(validatePlan(broken, [boundaries[0]])).toEqual([
+      { severity: 'error', subject: 'module:user', message: 'current_state is missing or not an object' },
+    ]);
+  });
+
+  it('should report a plan without migration_strategy', () => {
+    const broken = plan([module('b-user', 'user', ['internal/user/user.go'])], []);
+    delete (broken as Partial<ArchitecturalPlan>).migration_strategy;
+
+    expect(validatePlan(broken, [boundaries[0]])).toEqual([
+      { severity: 'error', subject: 'migration_strategy', message: 'is missing or not an object' },
+    ]);
+  });
+
+  it('should report dependencies that are not an array', () => {
+    const broken = plan([module('b-user', 'user', ['internal/user/user.go'])], [phase('基盤', ['user'])]);
+    (broken.modules[0] as any).dependencies = 'order';
+
+    expect(validatePlan(broken, [boundaries[0]])).toEqual([
+      { severity: 'error', subject: 'module:user', message: 'dependencies is missing or not an array' },
+    ]);
+  });
+});
@@ -0,0 +1,132 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { REFACTOR_TRANSFORMATION_PROMPT } from '../../src/core/claude-code/prompts/refactor-agent.js';
+import { RefactoredFile } from '../../src/core/types/refactor.js';
+import { ConfigLoader } from '../../src/core/utils/config-loader.js';
+import { InputParseError } from '../../src/core/utils/error-utils.js';
+import { LlmCache } from '../../src/core/utils/llm-cache.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import {
+  benchPromptTemplate,
+  comparePromptTemplates,
+  loadRefactorTemplate,
+  renderPromptTemplate,
+  summarizePromptTemplates,
+  templateHash,
+} from '../../src/core/utils/prompt-metrics.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const output = (): RefactoredFile => ({
+  refactored_files: [{ path: 'internal/order/domain/order.go', content: 'package domain\n\nfunc Place() {}\n', description: '' }],
+  interfaces: [],
+  tests: [],
+});
+
+class RecordingAgent extends RefactorAgent {
+  prompts: string[] = [];
+
+  constructor(projectRoot: string, private respond: (model?: string) => RefactoredFile) {
+    super(projectRoot);
+  }
+
+  protected async requestTransformation(prompt: string, _signal?: AbortSignal, model?: string): Promise<RefactoredFile> {
+    this.prompts.push(prompt);
+    return this.respond(model);
+  }
+}
+
+describe('prompt template metrics', () => {
+  let tempDir: string;
+  const boundary = () => ({ name: 'order', description: 'Order placement', files: [path.join(tempDir, 'legacy/order.go')] });
+  const configure = (extra: object) => ConfigLoader.saveConfig({
+    ...ConfigLoader.loadVibeFlowConfig(path.join(tempDir, 'missing.yaml')),
+    ...extra,
+  }, path.join(tempDir, 'vibeflow.config.yaml'));
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('prompt-metrics');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'legacy/order.go'), 'package legacy\n\nfunc PlaceOrder() {}\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should render the built-in template and leave unknown placeholders as written', () => {
+    const prompt = renderPromptTemplate(REFACTOR_TRANSFORMATION_PROMPT, { boundary: 'order', file: 'legacy/order.go', code: 'func {{x}}() {}' });
+
+    expect(prompt).toContain('- File: legacy/order.go\n- Target bounded context: order\n');
+    expect(prompt).toContain('"path": "internal/order/domain/order.go"');
+    // Values are not rendered again
+    expect(prompt).toContain('func {{x}}() {}');
+    expect(prompt).toContain('- Business capability: {{description}}');
+  });
+
+  it('should record each attempt with its template hash and compare template versions', async () => {
+    configure({ llm: { escalation: ['claude-haiku', 'claude-sonnet'] } });
+    const store = new PerformanceStore(tempDir);
+    const first = store.startRun('refactor-module');
+    await new RecordingAgent(tempDir, model => {
+      if (model === 'claude-haiku') throw new InputParseError('llm-response', 'no JSON found in response');
+      return output();
+    }).executeRefactoring([boundary()], false);
+    store.finishRun(first, { status: 'success' });
+
+    await createMockFile(path.join(tempDir, 'prompts/short.md'), 'Refactor {{file}} into {{boundary}}:\n{{code}}\n');
+    configure({ llm: { escalation: ['claude-haiku', 'claude-sonnet'] }, prompt: { refactorTemplate: 'prompts/short.md' } });
+    const second = store.startRun('refactor-module');
+    const agent = new RecordingAgent(tempDir, () => output());
+    await agent.executeRefactoring([boundary()], false);
+    store.finishRun(second, { status: 'success' });
+
+    expect(agent.prompts[0]).toBe(`Refactor ${path.join(tempDir, 'legacy/order.go')} into order:\npackage legacy\n\nfunc PlaceOrder() {}\n\n`);
+    const calls = new PerformanceStore(tempDir, { readOnly: true }).getLlmCalls();
+    expect(calls.map(c => [c.run_id, c.template, c.model, c.attempt, c.outcome])).toEqual([
+      [first, 'prompts/refactor-transformation.txt', 'claude-haiku', 1, 'malformed'],
+      [first, 'prompts/refactor-transformation.txt', 'claude-sonnet', 2, 'success'],
+      [second, 'prompts/short.md', 'claude-haiku', 1, 'success'],
+    ]);
+    expect(calls[0]).toMatchObject({ task: 'refactor', module: 'order', file: 'legacy/order.go', template_hash: templateHash(REFACTOR_TRANSFORMATION_PROMPT) });
+
+    const summaries = summarizePromptTemplates(calls);
+    expect(summaries.map(s => [s.template, s.calls, s.retry_rate, s.malformed_rate, s.verification_failure_rate])).toEqual([
+      ['prompts/refactor-transformation.txt', 2, 1, 0.5, 0],
+      ['prompts/short.md', 1, 0, 0, 0],
+    ]);
+    expect(summaries[0].cost_by_module.order).toBeGreaterThan(summaries[1].cost_by_module.order);
+
+    const comparison = comparePromptTemplates(summaries, summaries[0].template_hash.slice(0, 8), summaries[1].template_hash.slice(0, 8));
+    expect(comparison.delta.avg_input_tokens).toBeLessThan(0);
+    expect(comparison.delta.retry_rate).toBe(-1);
+    expect(comparison.modules.map(m => m.module)).toEqual(['order']);
+    expect(() => comparePromptTemplates(summaries, 'ffffffff', summaries[1].template_hash)).toThrow('No LLM calls recorded for template ffffffff');
+  });
+
+  it('should keep prompt inputs with llm.cache and replay them against a candidate template', async () => {
+    configure({ llm: { cache: true } });
+    const store = new PerformanceStore(tempDir);
+    const runId = store.startRun('refactor-module');
+    await new RecordingAgent(tempDir, () => output()).executeRefactoring([boundary()], false);
+    store.finishRun(runId, { status: 'success' });
+
+    const cache = new LlmCache(tempDir);
+    const inputs = cache.listInputs('order');
+    expect(inputs).toHaveLength(1);
+    expect(inputs[0]).toMatchObject({ task: 'refactor', module: 'order', file: 'legacy/order.go' });
+    expect(inputs[0].variables).toMatchObject({ boundary: 'order', description: 'Order placement', code: 'package legacy\n\nfunc PlaceOrder() {}\n' });
+
+    const candidate = 'Refactor {{file}} for {{boundary}} ({{owner}}):\n{{code}}\n';
+    const result = benchPromptTemplate(inputs, { name: 'new.md', text: candidate, hash: templateHash(candidate) }, loadRefactorTemplate(tempDir));
+    expect(result.files[0].baseline_tokens).toBe(inputs[0].input_tokens);
+    expect(result.delta).toBe(result.candidate_tokens - result.baseline_tokens);
+    expect(result.delta).toBeLessThan(0);
+    expect(result.unknown_placeholders).toEqual(['owner']);
+    expect(result.unused_variables).toContain('method_naming');
+
+    cache.put('prompt', 'claude-sonnet', '{"refactored_files": []}');
+    expect(cache.get('prompt', 'claude-sonnet')).toBe('{"refactored_files": []}');
+    expect(cache.get('prompt', 'claude-haiku')).toBeUndefined();
+  });
+});
@@ -0,0 +1,145 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import * as zlib from 'zlib';
+import { ReviewPacketBuilder, createZip, neighborhoodDiagram } from '../../src/core/utils/review-packet.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const orderSource = `package order
+
+import "database/sql"
+
+func Checkout(db *sql.DB, id int) error {
+\ttx, err := db.Begin()
+\tif err != nil {
+\t\treturn err
+\t}
+\ttx.Exec("UPDATE orders SET status = 'paid' WHERE id = ?", id)
+\ttx.Exec("INSERT INTO inventory_moves (order_id) VALUES (?)", id)
+\treturn tx.Commit()
+}
+
+func Find(db *sql.DB, id int) {
+\tdb.Query("SELECT * FROM customers WHERE id = ?", id)
+}
+`;
+
+const stubCost = async (boundary: { files: string[] }) => ({ files: boundary.files.length, tokens: 1200, cost_usd: 0.42, time: '1 minutes' });
+
+describe('ReviewPacketBuilder', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('review-packet');
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), orderSource);
+    await createMockFile(path.join(tempDir, 'internal/stock/stock.go'), 'package stock\n');
+
+    const old = new Date(Date.now() - 60_000);
+    fs.utimesSync(path.join(tempDir, 'internal/order/order.go'), old, old);
+    fs.utimesSync(path.join(tempDir, 'internal/stock/stock.go'), old, old);
+
+    await createMockFile(path.join(tempDir, '.vibeflow/domain-map.json'), JSON.stringify({
+      project: 'shop',
+      boundaries: [
+        { id: 'bnd-order', name: 'order', description: 'Order handling', files: ['internal/order/order.go'], apiEndpoints: ['POST /orders'], tables: ['orders'], circular_dependencies: ['order -> stock -> order'] },
+        { id: 'bnd-stock', name: 'stock', description: 'Inventory', files: ['internal/stock/stock.go'], tables: ['inventory_moves'] },
+      ],
+    }));
+    await createMockFile(path.join(tempDir, '.vibeflow/plan.json'), JSON.stringify({
+      modules: [
+        { name: 'order', description: '', current_state: {}, target_state: {}, refactoring_actions: [], interfaces: [], owned_tables: ['orders'], dependencies: [{ module: 'stock', type: 'interface', description: 'reserve items' }] },
+        { name: 'stock', description: '', current_state: {}, target_state: {}, refactoring_actions: [], interfaces: [], owned_tables: ['inventory_moves'], dependencies: [{ module: 'order', type: 'event', description: 'order paid' }] },
+      ],
+      shared_state: [{ id: 'order.cache', variable: 'cache', package: 'order', kind: 'map', modules: ['order', 'stock'], access: 'mutated-at-runtime', accesses: [], suggested_resolutions: [] }],
+    }));
+    await createMockFile(path.join(tempDir, '.vibeflow/plan.md'),
+      '# Plan\n\n## モジュール設計\n\n### order\n\nOrder module.\n\n### stock\n\nStock module.\n');
+    await createMockFile(path.join(tempDir, '.vibeflow/reports/findings.json'), JSON.stringify({
+      generated_at: '2026-01-01T00:00:00.000Z',
+      findings: [
+        { kind: 'business-rule', rule: 'validation', severity: 'note', message: 'Order must be paid', location: { file: 'internal/order/order.go', line: 10, column: 2 } },
+        { kind: 'business-rule', rule: 'validation', severity: 'note', message: 'Stock rule', location: { file: 'internal/stock/stock.go', line: 1, column: 1 } },
+      ],
+    }));
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should assemble the module packet from the existing artifacts', async () => {
+    const packet = await new ReviewPacketBuilder(tempDir, stubCost).build('order');
+
+    expect(packet.id).toBe('bnd-order');
+    expect(packet.plan_section).toBe('### order\n\nOrder module.');
+    expect(packet.files).toEqual([{ file: 'internal/order/order.go', loc: orderSource.split('\n').length, changed_since_discovery: false }]);
+    expect(packet.dependencies.map(d => [d.direction, d.module, d.type])).toEqual([['outgoing', 'stock', 'interface'], ['incoming', 'stock', 'event']]);
+    expect(packet.business_rules.map(r => r.message)).toEqual(['Order must be paid']);
+    expect(packet.routes).toEqual(['POST /orders']);
+    expect(packet.tables).toEqual(['orders']);
+    expect(packet.risks.cycles).toEqual(['order -> stock -> order']);
+    expect(packet.risks.shared_state.map(f => f.id)).toEqual(['order.cache']);
+    expect(packet.risks.transactions).toEqual([
+      { function: 'Checkout', file: 'internal/order/order.go', tables: [{ table: 'inventory_moves', owner: 'stock' }] },
+    ]);
+    expect(packet.cost).toMatchObject({ files: 1, cost_usd: 0.42 });
+    expect(packet.artifacts.find(a => a.name === 'domain-map.json')?.hash).toMatch(/^[0-9a-f]{64}$/);
+    expect(packet.artifacts.find(a => a.name === 'debt-inventory.json')?.hash).toBeNull();
+    expect(packet.artifacts.every(a => !a.stale)).toBe(true);
+  });
+
+  it('should flag artifacts older than the module sources', async () => {
+    fs.appendFileSync(path.join(tempDir, 'internal/order/order.go'), '\nfunc Cancel() {}\n');
+
+    const packet = await new ReviewPacketBuilder(tempDir, stubCost).build('order');
+
+    expect(packet.files[0].changed_since_discovery).toBe(true);
+    expect(packet.artifacts.find(a => a.name === 'domain-map.json')?.stale).toContain('module sources changed');
+    await expect(new ReviewPacketBuilder(tempDir, stubCost).build('billing')).rejects.toThrow(/Module "billing" not found/);
+  });
+
+  it('should write the markdown sections and packet.json', async () => {
+    const builder = new ReviewPacketBuilder(tempDir, stubCost);
+    const outputDir = path.join(tempDir, 'order-review');
+    const written = builder.write(await builder.build('order'), outputDir);
+
+    expect(written.map(f => path.basename(f))).toEqual([
+      'README.md', 'plan.md', 'files.md', 'dependencies.md', 'business-rules.md', 'ownership.md', 'risks.md', 'cost.md', 'packet.json',
+    ]);
+    const readme = fs.readFileSync(path.join(outputDir, 'README.md'), 'utf8');
+    expect(readme).toContain('Boundary ID: `bnd-order`');
+    expect(readme).toContain('```mermaid\ngraph LR');
+    expect(fs.readFileSync(path.join(outputDir, 'risks.md'), 'utf8')).toContain('inventory_moves (owned by stock)');
+    expect(JSON.parse(fs.readFileSync(path.join(outputDir, 'packet.json'), 'utf8')).module).toBe('order');
+  });
+});
+
+describe('review packet helpers', () => {
+  it('should draw the module with its direct neighbors', () => {
+    expect(neighborhoodDiagram('order', [
+      { module: 'stock', direction: 'outgoing', type: 'interface', description: '' },
+      { module: 'user-api', direction: 'incoming', type: 'event', description: '' },
+    ])).toBe([
+      'graph LR',
+      '  m_order["order"]:::focus',
+      '  m_stock["stock"]',
+      '  m_user_api["user-api"]',
+      '  m_order -->|interface| m_stock',
+      '  m_user_api -->|event| m_order',
+      '  classDef focus fill:#fde68a,stroke:#b45309,stroke-width:2px',
+    ].join('\n'));
+  });
+
+  it('should write a readable zip archive', () => {
+    const data = Buffer.from('# Review packet\n'.repeat(20));
+    const zip = createZip([{ name: 'order-review/README.md', data }]);
+
+    expect(zip.readUInt32LE(0)).toBe(0x04034b50);
+    const nameLength = zip.readUInt16LE(26);
+    const compressedSize = zip.readUInt32LE(18);
+    expect(zip.subarray(30, 30 + nameLength).toString()).toBe('order-review/README.md');
+    expect(zlib.inflateRawSync(zip.subarray(30 + nameLength, 30 + nameLength + compressedSize))).toEqual(data);
+    expect(zip.readUInt32LE(zip.length - 22)).toBe(0x06054b50);
+    expect(zip.readUInt16LE(zip.length - 12)).toBe(1);
+  });
+});
@@ -0,0 +1,97 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { buildRiskHeatmap, renderHeatmapSection } from '../../src/core/utils/risk-heatmap.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const USER = `package user
+
+func Find(id string) string {
+	if id == "" {
+		return "anonymous"
+	}
+	return id
+}
+`;
+
+const ORDER = `package order
+
+func Place(id string, items []string) string {
+	for _, item := range items {
+		if item == "" || len(item) > 10 {
+			return ""
+		}
+	}
+	return id
+}
+`;
+
+const CART = `package order
+
+func Total(prices []int) int {
+	sum := 0
+	for _, price := range prices {
+		sum += price
+	}
+	return sum
+}
+`;
+
+describe('Risk heat map', () => {
+  let tempDir: string;
+  const modules = [
+    { name: 'user', files: ['internal/user/user.go', 'internal/user/user_test.go'] },
+    { name: 'order', files: ['internal/order/order.go', 'internal/order/cart.go'] },
+    { name: 'audit', files: ['internal/audit/audit.go'] },
+  ];
+  const churn = {
+    commits: 20,
+    changes: { 'internal/user/user.go': 4, 'internal/user/user_test.go': 30, 'internal/order/order.go': 9, 'internal/order/cart.go': 1 },
+  };
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('risk-heatmap');
+    await createMockFile(path.join(tempDir, 'internal/user/user.go'), USER);
+    await createMockFile(path.join(tempDir, 'internal/user/user_test.go'), 'package user\n');
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), ORDER);
+    await createMockFile(path.join(tempDir, 'internal/order/cart.go'), CART);
+    await createMockFile(path.join(tempDir, 'internal/audit/audit.go'), 'package audit\n\nfunc Log() {}\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should rank modules and files by churn × complexity and recommend late phases for hot modules', () => {
+    const heatmap = buildRiskHeatmap(tempDir, modules, churn, [
+      { name: '基盤', modules: ['order'] },
+      { name: '周辺', modules: ['user', 'audit'] },
+    ]);
+
+    expect(heatmap.history_commits).toBe(20);
+    expect(heatmap.modules).toEqual([
+      { module: 'order', churn: 10, complexity: 6, score: 38, heat: 100, level: 'hot', phase: 1, recommendation: 'phase-late' },
+      { module: 'user', churn: 4, complexity: 2, score: 8, heat: 21, level: 'cool', phase: 2, recommendation: 'none' },
+      { module: 'audit', churn: 0, complexity: 1, score: 0, heat: 0, level: 'cool', phase: 2, recommendation: 'none' },
+    ]);
+    expect(heatmap.files).toEqual([
+      { file: 'internal/order/order.go', module: 'order', churn: 9, complexity: 4, score: 36, heat: 100, level: 'hot' },
+      { file: 'internal/user/user.go', module: 'user', churn: 4, complexity: 2, score: 8, heat: 22, level: 'cool' },
+      { file: 'internal/order/cart.go', module: 'order', churn: 1, complexity: 2, score: 2, heat: 6, level: 'cool' },
+    ]);
+
+    const late = buildRiskHeatmap(tempDir, modules, churn, [{ name: '周辺', modules: ['user'] }, { name: '基盤', modules: ['order'] }]);
+    expect(late.modules[0].recommendation).toBe('manual');
+  });
+
+  it('should render the ranking with heat bars', () => {
+    const section = renderHeatmapSection(buildRiskHeatmap(tempDir, modules, churn, [{ name: '基盤', modules: ['order', 'user'] }]));
+
+    expect(section).toContain('## リスクヒートマップ (Risk Heat Map)');
+    expect(section).toContain('（Git履歴 20コミット、最も高いものを熱度 100 として換算）');
+    expect(section).toContain('| 1 | order | 🔴 ██████████ 100 | 10 | 6 | 1 | 手動でリファクタリング |');
+    expect(section).toContain('| 2 | user | 🟢 ██░░░░░░░░ 21 | 4 | 2 | 1 | - |');
+    expect(section).toContain('### ファイル（上位3件）');
+    expect(section).toContain('| 1 | `internal/order/order.go` | order | 🔴 ██████████ 100 | 9 | 4 |');
+    expect(renderHeatmapSection(buildRiskHeatmap(tempDir, modules, { commits: 0, changes: {} }, []))).toBe('');
+  });
+});
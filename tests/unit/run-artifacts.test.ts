@@ -0,0 +1,71 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { RunArtifactStore } from '../../src/core/utils/run-artifacts.js';
+import { PerformanceStore } from '../../src/core/utils/performance-store.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+describe('RunArtifactStore', () => {
+  let tempDir: string;
+  const objectCount = () => {
+    const objectsDir = path.join(tempDir, '.vibeflow', 'run-artifacts', 'objects');
+    return fs.readdirSync(objectsDir).reduce((sum, prefix) => sum + fs.readdirSync(path.join(objectsDir, prefix)).length, 0);
+  };
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('run-artifacts');
+    await createMockFile(path.join(tempDir, '.vibeflow', 'domain-map.json'), '{"boundaries":[{"name":"user"}]}');
+    await createMockFile(path.join(tempDir, '.vibeflow', 'plan.json'), '{"modules":[]}');
+    await createMockFile(path.join(tempDir, 'boundary.yaml'), 'modules: {}\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should store identical inputs of different runs once', () => {
+    const store = new RunArtifactStore(tempDir);
+    const first = store.capture(1);
+    const objects = objectCount();
+    const second = store.capture(2);
+
+    expect(first.artifacts.map(a => a.name)).toEqual(expect.arrayContaining([
+      'domain-map.json', 'plan.json', 'boundary.yaml', 'config.resolved.json', 'prompts/architecture-design.txt',
+    ]));
+    expect(second.artifacts.map(a => a.hash)).toEqual(first.artifacts.map(a => a.hash));
+    expect(objectCount()).toBe(objects);
+  });
+
+  it('should report which inputs differ from the workspace and extract the originals', () => {
+    const store = new RunArtifactStore(tempDir);
+    store.capture(17);
+    fs.writeFileSync(path.join(tempDir, '.vibeflow', 'domain-map.json'), '{"boundaries":[]}');
+    fs.unlinkSync(path.join(tempDir, 'boundary.yaml'));
+
+    const comparison = Object.fromEntries(store.compare(17).map(a => [a.name, a.status]));
+    expect(comparison['domain-map.json']).toBe('changed');
+    expect(comparison['boundary.yaml']).toBe('missing');
+    expect(comparison['plan.json']).toBe('same');
+
+    const destination = path.join(tempDir, 'run17');
+    store.extract(17, destination);
+    expect(fs.readFileSync(path.join(destination, 'domain-map.json'), 'utf8')).toBe('{"boundaries":[{"name":"user"}]}');
+    expect(fs.existsSync(path.join(destination, 'prompts', 'boundary-extraction.txt'))).toBe(true);
+  });
+
+  it('should follow the metrics retention when pruning', () => {
+    const performance = new PerformanceStore(tempDir);
+    const store = new RunArtifactStore(tempDir);
+    for (const version of [1, 2, 3]) {
+      const runId = performance.startRun('refactor');
+      fs.writeFileSync(path.join(tempDir, '.vibeflow', 'plan.json'), `{"version":${version}}`);
+      store.capture(runId);
+      performance.finishRun(runId, { status: 'success' });
+    }
+
+    expect(performance.pruneRuns(2)).toEqual([1]);
+    expect(store.prune(performance.getRuns().map(r => r.run_id))).toEqual({ runs: 1, objects: 1 });
+    expect(store.listRuns()).toEqual([2, 3]);
+    expect(() => store.extract(1, path.join(tempDir, 'run1'))).toThrow(/No artifact snapshot/);
+  });
+});
@@ -0,0 +1,100 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { EmbeddingIndex, createEmbedder, embedFiles, embeddingText } from '../../src/core/utils/semantic-embeddings.js';
+
+const INVOICE = `// Package billing issues invoices for placed orders.
+package billing
+
+import "fmt"
+
+// InvoiceService bills customers.
+type InvoiceService struct{}
+
+//go:generate mockgen -source=invoice.go
+func (s *InvoiceService) IssueInvoice(id string) error {
+	return fmt.Errorf("not found: // %s", id)
+}
+`;
+
+describe('Semantic embeddings', () => {
+  let tempDir: string;
+  const originalFetch = globalThis.fetch;
+
+  beforeEach(() => {
+    tempDir = fs.mkdtempSync(path.join(os.tmpdir(), 'vibeflow-embeddings-'));
+  });
+
+  afterEach(() => {
+    globalThis.fetch = originalFetch;
+    fs.rmSync(tempDir, { recursive: true, force: true });
+  });
+
+  it('should embed the path, identifiers and comments of a file but not its code', () => {
+    expect(embeddingText('internal/util/invoice.go', INVOICE)).toBe([
+      'internal util invoice',
+      'package billing',
+      'issue invoice, invoice service',
+      'Package billing issues invoices for placed orders.',
+      'InvoiceService bills customers.',
+    ].join('\n'));
+  });
+
+  it('should count only the similarity above the threshold', () => {
+    const index = new EmbeddingIndex(new Map([
+      ['billing/invoice.go', [1, 0]],
+      ['util/charge.go', [0.8, 0.6]],
+      ['shipping/label.go', [0, 1]],
+    ]), 0.5);
+
+    // cos = 0.8, rescaled to (0.8 - 0.5) / 0.5
+    expect(index.strength('util/charge.go', 'billing/invoice.go')).toBeCloseTo(0.6);
+    expect(index.strength('billing/invoice.go', 'shipping/label.go')).toBe(0);
+    expect(index.strength('billing/invoice.go', 'missing.go')).toBe(0);
+  });
+
+  it('should only embed files whose text changed since the cached run', async () => {
+    fs.writeFileSync(path.join(tempDir, 'a.go'), 'package a\n\nfunc Bill() {}\n');
+    fs.writeFileSync(path.join(tempDir, 'b.go'), 'package b\n\nfunc Ship() {}\n');
+    const cachePath = path.join(tempDir, '.vibeflow', 'embeddings-cache.json');
+    const embedded: string[][] = [];
+    const embed = async (texts: string[]) => {
+      embedded.push(texts);
+      return texts.map(text => [text.length, 1]);
+    };
+
+    const vectors = await embedFiles(tempDir, ['a.go', 'b.go', 'missing.go'], { provider: 'ollama' }, cachePath, embed);
+    expect([...vectors.keys()]).toEqual(['a.go', 'b.go']);
+
+    fs.writeFileSync(path.join(tempDir, 'b.go'), 'package b\n\nfunc ShipParcel() {}\n');
+    await embedFiles(tempDir, ['a.go', 'b.go'], { provider: 'ollama' }, cachePath, embed);
+    expect(embedded.map(batch => batch.length)).toEqual([2, 1]);
+    expect(embedded[1][0]).toContain('ship parcel');
+
+    // Another model does not reuse the vectors
+    await embedFiles(tempDir, ['a.go', 'b.go'], { provider: 'ollama', model: 'mxbai-embed-large' }, cachePath, embed);
+    expect(embedded[2]).toHaveLength(2);
+  });
+
+  it('should call the OpenAI-compatible API with the key from the environment and keep the input order', async () => {
+    const requests: { url: string; init: any }[] = [];
+    globalThis.fetch = (async (url: string, init: any) => {
+      requests.push({ url, init });
+      return new Response(JSON.stringify({ data: [{ index: 1, embedding: [0, 1] }, { index: 0, embedding: [1, 0] }] }));
+    }) as typeof fetch;
+    process.env.VF_TEST_EMBEDDINGS_KEY = 'secret';
+
+    try {
+      const embed = createEmbedder({ provider: 'openai', endpoint: 'https://llm.example.com/', apiKeyEnv: 'VF_TEST_EMBEDDINGS_KEY' });
+      expect(await embed(['billing', 'shipping'])).toEqual([[1, 0], [0, 1]]);
+    } finally {
+      delete process.env.VF_TEST_EMBEDDINGS_KEY;
+    }
+    expect(requests[0].url).toBe('https://llm.example.com/v1/embeddings');
+    expect(requests[0].init.headers.authorization).toBe('Bearer secret');
+    expect(JSON.parse(requests[0].init.body)).toEqual({ model: 'text-embedding-3-small', input: ['billing', 'shipping'] });
+
+    await expect(createEmbedder({ provider: 'openai', apiKeyEnv: 'VF_TEST_EMBEDDINGS_KEY' })(['x'])).rejects.toThrow('VF_TEST_EMBEDDINGS_KEY is not set');
+  });
+});
@@ -0,0 +1,158 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  analyzeServiceRequirements,
+  parseDeployments,
+  renderProtoDefinition,
+  resolveDeployments,
+} from '../../src/core/utils/service-deployment.js';
+import { serviceRuleFindings } from '../../src/core/utils/findings.js';
+import { DomainMapWriter } from '../../src/core/utils/domain-map-writer.js';
+import { ArchitectAgent } from '../../src/core/agents/architect-agent.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const NOTIFY = `package notify
+
+import (
+	"context"
+	"database/sql"
+)
+
+func Send(ctx context.Context, userID string, count int) error {
+	_, err := db.ExecContext(ctx, "UPDATE orders SET notified = true WHERE user_id = ?", userID)
+	return err
+}
+
+func Broadcast(message string) {}
+
+var db *sql.DB
+`;
+
+const ORDER = `package order
+
+import (
+	"context"
+
+	"example.com/shop/internal/notify"
+)
+
+func PlaceOrder(ctx context.Context) error {
+	if err := notify.Send(ctx, "u1", 1); err != nil {
+		return err
+	}
+	return notify.Send(ctx, "u1", 2)
+}
+
+func pending() string {
+	return "SELECT id FROM notifications WHERE sent = false"
+}
+`;
+
+describe('service deployment', () => {
+  let tempDir: string;
+
+  const modules = () => [
+    { name: 'order', files: ['internal/order/order.go'], deployment: 'monolith' as const, owned_tables: ['orders'] },
+    { name: 'notify', files: ['internal/notify/notify.go'], deployment: 'service' as const, owned_tables: ['notifications'] },
+  ];
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('service-deployment');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/notify/notify.go'), NOTIFY);
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), ORDER);
+    await createMockFile(path.join(tempDir, '.vibeflow/runtime-profile.json'),
+      JSON.stringify({ requests: { 'POST /orders': { 'order.PlaceOrder': 3 } } }));
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should parse and resolve deployments', () => {
+    expect(parseDeployments('notify=service, order=monolith')).toEqual({ notify: 'service', order: 'monolith' });
+    expect(() => parseDeployments('notify=lambda')).toThrow('Invalid deployment');
+
+    const { modules: resolved, unknown } = resolveDeployments(
+      [{ id: 'b1', name: 'notifications' }, { name: 'order' }, { name: 'billing' }],
+      [{ id: 'b1', name: 'notify', deployment: 'service' }],
+      { billing: 'service', missing: 'service' }
+    );
+    expect(resolved.map(m => m.deployment)).toEqual(['service', 'monolith', 'service']);
+    expect(unknown).toEqual(['missing']);
+  });
+
+  it('should list the calls that become network calls and the tables accessed across the service boundary', () => {
+    const [notify] = analyzeServiceRequirements(tempDir, modules());
+
+    expect(notify.api).toMatchObject({
+      path: 'api/notify/v1/notify.proto',
+      operations: [{ name: 'notify.Send', rpc: 'Send', signature: '(ctx context.Context, userID string, count int) error', callers: ['order'] }],
+    });
+    expect(notify.entrypoint).toBe('cmd/notify/main.go');
+    expect(notify.network_calls.map(c => [c.caller, c.function, c.operation, c.location.line, c.per_request])).toEqual([
+      ['order', 'PlaceOrder', 'notify.Send', 10, { 'POST /orders': 3 }],
+      ['order', 'PlaceOrder', 'notify.Send', 13, { 'POST /orders': 3 }],
+    ]);
+    expect(notify.network_calls_per_request).toEqual({ 'POST /orders': 6 });
+    expect(notify.foreign_table_access.map(a => [a.module, a.table, a.owner, a.function])).toEqual([
+      ['order', 'notifications', 'notify', 'pending'],
+      ['notify', 'orders', 'order', 'Send'],
+    ]);
+
+    const proto = renderProtoDefinition(notify, 'example.com/shop');
+    expect(proto).toContain('rpc Send(SendRequest) returns (SendResponse);');
+    expect(proto).toContain('  string user_id = 1;\n  int64 count = 2;');
+    expect(proto).toContain('option go_package = "example.com/shop/api/notify/v1;notifyv1";');
+  });
+
+  it('should add service requirements to the plan and keep the deployment on regeneration', async () => {
+    new DomainMapWriter(tempDir).write({
+      project: 'shop',
+      language: 'go',
+      analyzed_at: '2024-01-01T00:00:00.000Z',
+      total_files: 2,
+      boundaries: modules().map(m => ({ name: m.name, description: m.name, files: m.files, tables: m.owned_tables })),
+      metrics: { overall_cohesion: 0, overall_coupling: 0, modularity_score: 0 },
+    });
+    const agent = new ArchitectAgent(tempDir, path.join(tempDir, 'vibeflow.config.yaml'), path.join(tempDir, 'boundary.yaml'));
+    const domainMapPath = path.join(tempDir, '.vibeflow/domain-map.json');
+
+    const { plan, outputPath } = await agent.generateArchitecturalPlan(domainMapPath, { deployments: { notify: 'service' } });
+    const notify = plan.modules.find(m => m.name === 'notify')!;
+
+    expect(Object.fromEntries(plan.modules.map(m => [m.name, m.deployment]))).toEqual({ order: 'monolith', notify: 'service' });
+    expect(notify.service?.scaffolds).toEqual([
+      '.vibeflow/services/notify/api/notify/v1/notify.proto',
+      '.vibeflow/services/notify/cmd/notify/main.go',
+    ]);
+    expect(fs.readFileSync(path.join(tempDir, '.vibeflow/services/notify/cmd/notify/main.go'), 'utf8')).toContain('package main');
+    expect(notify.refactoring_actions.map(a => a.type).slice(0, 3)).toEqual(['introduce_event', 'extract_interface', 'create_entrypoint']);
+    expect(fs.readFileSync(outputPath, 'utf8')).toContain('## サービス化要件 (Separately Deployable Services)');
+
+    const regenerated = await agent.generateArchitecturalPlan(domainMapPath);
+    expect(regenerated.plan.modules.find(m => m.name === 'notify')?.deployment).toBe('service');
+  });
+
+  it('should enforce the service rules against the planned API definition', async () => {
+    const planned = analyzeServiceRequirements(tempDir, modules());
+    await createMockFile(path.join(tempDir, 'internal/order/broadcast.go'),
+      'package order\n\nimport "example.com/shop/internal/notify"\n\nfunc Announce() {\n\tnotify.Broadcast("sale")\n}\n');
+    const current = analyzeServiceRequirements(tempDir, [
+      { ...modules()[0], files: ['internal/order/order.go', 'internal/order/broadcast.go'] },
+      modules()[1],
+    ]);
+
+    const findings = serviceRuleFindings(tempDir, current, planned);
+    expect(findings.map(f => [f.rule, f.severity, f.location.file])).toEqual([
+      ['foreign-table-access', 'error', 'internal/order/order.go'],
+      ['foreign-table-access', 'error', 'internal/notify/notify.go'],
+      ['undeclared-operation', 'error', 'internal/order/broadcast.go'],
+      ['missing-entrypoint', 'warning', 'cmd/notify/main.go'],
+    ]);
+    // Monolith modules only get the service rules for their use of services
+    expect(serviceRuleFindings(tempDir, analyzeServiceRequirements(tempDir, modules().map(m => ({ ...m, deployment: 'monolith' as const }))), []))
+      .toEqual([]);
+  });
+});
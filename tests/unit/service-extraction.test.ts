@@ -0,0 +1,86 @@
+import { describe, it, expect } from 'vitest';
+import { resolveDeployments } from '../../src/core/utils/service-deployment.js';
+import {
+  isCommandOperation,
+  parsePlanTarget,
+  proposeServiceExtraction,
+  proposedDeployments,
+  renderServiceExtractionSection,
+} from '../../src/core/utils/service-extraction.js';
+
+const modules = [
+  { name: 'order', files: ['internal/order/order.go'], lines_of_code: 900, depends_on: ['billing', 'notify'], owned_tables: ['orders'] },
+  { name: 'billing', files: ['internal/billing/billing.go'], lines_of_code: 600, depends_on: ['order'], owned_tables: ['invoices'] },
+  { name: 'notify', files: ['internal/notify/notify.go'], lines_of_code: 400, depends_on: [], owned_tables: ['notifications'] },
+  { name: 'legacy', files: ['legacy/go.mod'], lines_of_code: 5000, depends_on: [], status: 'established' as const },
+];
+
+const findings = {
+  cycles: [{
+    modules: ['order', 'billing'],
+    edges: [],
+    break_at: { from: 'billing', to: 'order', calls: 1, remedy: 'event' as const },
+  }],
+  async_edges: [{ topic: 'order.placed', broker: 'kafka' as const, from: 'order', to: 'notify', files: ['internal/notify/consumer.go'] }],
+  orchestrators: [{
+    function: 'Checkout',
+    file: 'internal/order/checkout.go',
+    module: 'order',
+    kind: 'saga' as const,
+    touches: [{ module: 'billing', calls: 1, tables: ['invoices'], writes: true }],
+  }],
+};
+
+describe('Service extraction', () => {
+  it('should recommend modules with a clean cut and block cycles and sagas', () => {
+    const plan = proposeServiceExtraction(modules, findings);
+
+    expect(plan.candidates.map(c => [c.module, c.score, c.recommended])).toEqual([
+      ['notify', 100, true],
+      ['order', 84, false],
+      ['billing', 69, false],
+    ]);
+    const [notify, order, billing] = plan.candidates;
+    expect(notify).toMatchObject({
+      callers: ['order'],
+      calls: [],
+      communication: { style: 'mixed', topics: ['order.placed'] },
+      data: { owned_tables: ['notifications'], sagas: [] },
+      blockers: [],
+    });
+    expect(order.blockers).toEqual(['呼び出しの循環 (order → billing → order)：先に billing → order を切る']);
+    expect(billing.data.sagas).toEqual(['Checkout']);
+    expect(proposedDeployments(plan)).toEqual({ notify: 'service', order: 'monolith', billing: 'monolith' });
+  });
+
+  it('should let recorded decisions and --deployment win over the proposal', () => {
+    const { modules: resolved } = resolveDeployments(
+      [{ name: 'order' }, { name: 'notify' }, { name: 'billing' }],
+      [{ name: 'notify', deployment: 'monolith' }],
+      { billing: 'service' },
+      { order: 'monolith', notify: 'service', billing: 'monolith' }
+    );
+    expect(resolved.map(m => m.deployment)).toEqual(['monolith', 'monolith', 'service']);
+  });
+
+  it('should mark operations without results as event candidates', () => {
+    const operation = (signature: string) => ({ name: 'notify.Send', rpc: 'Send', signature, callers: ['order'] });
+
+    expect(isCommandOperation(operation('(ctx context.Context, userID string) error'))).toBe(true);
+    expect(isCommandOperation(operation('(message string)'))).toBe(true);
+    expect(isCommandOperation(operation('(fn func(int) error) (err error)'))).toBe(true);
+    expect(isCommandOperation(operation('(id string) (*Notification, error)'))).toBe(false);
+
+    const section = renderServiceExtractionSection(proposeServiceExtraction(modules, findings), [{
+      module: 'notify',
+      api: { protocol: 'grpc', path: 'api/notify/v1/notify.proto', operations: [operation('(ctx context.Context, userID string) error')] },
+      entrypoint: 'cmd/notify/main.go',
+      foreign_table_access: [],
+      network_calls: [],
+    }]);
+    expect(section).toContain('### notify — 独立サービスに推奨 (スコア 100)');
+    expect(section).toContain('うち `Send` は結果を返さないためイベント化も可能');
+    expect(parsePlanTarget('Microservices')).toBe('microservices');
+    expect(() => parsePlanTarget('serverless')).toThrow('expected modular-monolith or microservices');
+  });
+});
@@ -0,0 +1,82 @@
+import { describe, it, expect } from 'vitest';
+import {
+  SHARED_KERNEL_HEADING,
+  findSharedKernel,
+  kernelKindOf,
+  renderSharedKernelSection,
+  separateSharedKernel,
+} from '../../src/core/utils/shared-kernel.js';
+import { loadGoPackages } from '../../src/core/utils/go-packages.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+
+const fixtureRoot = './tests/fixtures/shared-kernel';
+
+function boundary(name: string, files: string[], internal: string[] = []): DomainBoundary {
+  return { name, description: '', files, dependencies: { internal, external: [] } };
+}
+
+const boundaries = [
+  boundary('order', ['internal/order/order.go', 'internal/logging/log.go']),
+  boundary('billing', ['internal/billing/bill.go', 'internal/money/money.go'], ['order']),
+  boundary('catalog', ['internal/catalog/item.go'], ['billing', 'text']),
+  boundary('text', ['pkg/stringutil/slug.go']),
+  boundary('app', ['cmd/shop/main.go', 'internal/testutil/orders.go'], ['billing', 'order']),
+];
+
+describe('shared kernel', () => {
+  it.each([
+    ['pkg/utils', 'utility'],
+    ['pkg/stringutil', 'utility'],
+    ['internal/logging', 'logging'],
+    ['internal/config', 'config'],
+    ['internal/apperrors', 'errors'],
+    ['internal/common/money', 'common'],
+    ['pkg/utils/strings', 'utility'],
+    ['internal/order', undefined],
+  ])('should classify %s as %s', (dir, kind) => {
+    expect(kernelKindOf(dir)).toBe(kind);
+  });
+
+  it('should find utility packages by name and leaf packages most boundaries import', () => {
+    const kernel = findSharedKernel(fixtureRoot, boundaries, loadGoPackages(fixtureRoot));
+
+    expect(kernel.map(pkg => [pkg.dir, pkg.kind, pkg.reason, pkg.used_by])).toEqual([
+      ['internal/logging', 'logging', 'name', ['billing', 'order']],
+      ['internal/money', 'utility', 'fan-in', ['billing', 'catalog', 'order']],
+      ['pkg/stringutil', 'utility', 'name', ['catalog']],
+    ]);
+  });
+
+  it('should keep pinned and established packages in their modules', () => {
+    const kernel = findSharedKernel(fixtureRoot, [
+      ...boundaries.slice(0, 3),
+      { ...boundaries[3], status: 'established' },
+      boundaries[4],
+    ], loadGoPackages(fixtureRoot), { pinned: ['internal/money/'] });
+
+    expect(kernel.map(pkg => pkg.dir)).toEqual(['internal/logging']);
+  });
+
+  it('should take kernel files out of the boundaries and drop the emptied ones', () => {
+    const kernel = findSharedKernel(fixtureRoot, boundaries, loadGoPackages(fixtureRoot));
+    const separated = separateSharedKernel(fixtureRoot, boundaries, kernel);
+
+    expect(separated.dropped).toEqual(['text']);
+    expect(separated.boundaries.map(b => [b.name, b.files, b.dependencies?.internal])).toEqual([
+      ['order', ['internal/order/order.go'], []],
+      ['billing', ['internal/billing/bill.go'], ['order']],
+      ['catalog', ['internal/catalog/item.go'], ['billing']],
+      ['app', ['cmd/shop/main.go', 'internal/testutil/orders.go'], ['billing', 'order']],
+    ]);
+  });
+
+  it('should render the kernel as a plan section', () => {
+    const kernel = findSharedKernel(fixtureRoot, boundaries, loadGoPackages(fixtureRoot));
+    const section = renderSharedKernelSection(kernel);
+
+    expect(section).toContain(SHARED_KERNEL_HEADING);
+    expect(section).toContain('- `internal/money` (ユーティリティ、1ファイル) — 3個の境界が利用する末端パッケージ');
+    expect(section).toContain('  - 利用: billing, catalog, order');
+    expect(renderSharedKernelSection([])).toBe('');
+  });
+});
@@ -0,0 +1,112 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  acceptSharedStateResolution,
+  findSharedMutableState,
+  loadSharedState,
+  mergeResolutions,
+  sharedStateRefusal,
+} from '../../src/core/utils/shared-state.js';
+import { sharedStateFindings, toSarif } from '../../src/core/utils/findings.js';
+import { RefactorAgent } from '../../src/core/agents/refactor-agent.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const fixtureRoot = './tests/fixtures/shared-state';
+const modules = [
+  { name: 'cache', files: ['internal/cache/cache.go'] },
+  { name: 'user', files: ['internal/user/service.go'] },
+  { name: 'billing', files: ['internal/billing/invoice.go'] },
+];
+
+describe('shared mutable state', () => {
+  it('should report package maps used from several modules and classify their access', () => {
+    const findings = findSharedMutableState(fixtureRoot, modules);
+
+    expect(findings.map(f => [f.id, f.kind, f.access, f.modules])).toEqual([
+      ['internal/cache.UserCache', 'map', 'mutated-at-runtime', ['billing', 'cache', 'user']],
+      ['internal/cache.Registry', 'map', 'read-only-after-init', ['billing', 'cache', 'user']],
+    ]);
+
+    const [userCache] = findings;
+    expect(userCache.owner).toBe('cache');
+    expect(userCache.declared_at).toMatchObject({ file: 'internal/cache/cache.go', line: 7, column: 2 });
+    expect(userCache.accesses.map(a => [a.module, a.function, a.write])).toEqual([
+      ['billing', 'Charge', true],
+      ['cache', 'Put', true],
+      ['user', 'Find', false],
+    ]);
+    expect(userCache.suggested_resolutions.map(r => r.strategy))
+      .toEqual(['owning-module-interface', 'injected-dependency', 'cache-service']);
+  });
+
+  it('should not report state that stays within one module', () => {
+    const findings = findSharedMutableState(fixtureRoot, [
+      { name: 'cache', files: ['internal/cache/cache.go', 'internal/user/service.go', 'internal/billing/invoice.go'] },
+    ]);
+
+    expect(findings).toEqual([]);
+  });
+
+  it('should block modules until a resolution is accepted', () => {
+    const findings = findSharedMutableState(fixtureRoot, modules);
+
+    expect(sharedStateRefusal('user', findings)).toContain('internal/cache.UserCache (mutated-at-runtime)');
+
+    const resolved = findings.map(f => ({
+      ...f,
+      resolution: { strategy: 'owning-module-interface' as const, accepted_at: '2024-01-01T00:00:00.000Z' },
+    }));
+    expect(sharedStateRefusal('user', resolved)).toBeNull();
+    expect(mergeResolutions(findings, resolved).every(f => f.resolution)).toBe(true);
+  });
+
+  it('should export findings as errors until resolved', () => {
+    const findings = sharedStateFindings(fixtureRoot, findSharedMutableState(fixtureRoot, modules));
+
+    expect(findings[0]).toMatchObject({ kind: 'shared-state', rule: 'mutated-at-runtime', severity: 'error', snippet: 'UserCache' });
+    expect(findings[0].related).toHaveLength(3);
+
+    const sarif = toSarif(findings) as { runs: { results: { ruleId: string; relatedLocations?: unknown[] }[] }[] };
+    expect(sarif.runs[0].results.map(r => r.ruleId))
+      .toEqual(['shared-state/mutated-at-runtime', 'shared-state/read-only-after-init']);
+  });
+});
+
+describe('accepted resolutions in the plan', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('shared-state');
+    fs.cpSync(fixtureRoot, tempDir, { recursive: true });
+    await createMockFile(path.join(tempDir, '.vibeflow', 'plan.json'),
+      JSON.stringify({ modules: [], shared_state: findSharedMutableState(tempDir, modules) }));
+    await createMockFile(path.join(tempDir, '.vibeflow', 'plan.md'), '# Plan\n\n## 品質ゲート\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should refuse to apply a module with unresolved shared state', async () => {
+    const result = await new RefactorAgent(tempDir).executeRefactoring([
+      { name: 'user', description: 'user', files: ['internal/user/service.go'] },
+    ], true);
+
+    expect(result.failed_patches).toHaveLength(1);
+    expect(result.failed_patches[0].error).toContain('shares mutable package state');
+  });
+
+  it('should record accepted resolutions in plan.json and plan.md', () => {
+    acceptSharedStateResolution(tempDir, 'internal/cache.UserCache', 'cache-service', 'moving to Redis');
+    acceptSharedStateResolution(tempDir, 'internal/cache.Registry', 'injected-dependency');
+
+    const findings = loadSharedState(tempDir);
+    expect(findings[0].resolution).toMatchObject({ strategy: 'cache-service', note: 'moving to Redis' });
+    expect(sharedStateRefusal('user', findings)).toBeNull();
+    expect(fs.readFileSync(path.join(tempDir, '.vibeflow', 'plan.md'), 'utf8'))
+      .toContain('✅ 解決策承認済み: cache-service (moving to Redis)');
+
+    expect(() => acceptSharedStateResolution(tempDir, 'internal/cache.Missing', 'cache-service')).toThrow(/Unknown shared-state finding/);
+  });
+});
@@ -0,0 +1,114 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import {
+  applyAnnotations,
+  isIgnored,
+  parseAnnotations,
+  preserveAnnotations,
+} from '../../src/core/utils/source-annotations.js';
+import { ASTAnalyzer } from '../../src/core/utils/ast-analyzer.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const INVOICE = `package order
+
+// Invoice belongs to billing even though it lives here.
+//vf:boundary billing
+func Invoice() {}
+
+//vf:freeze
+func LegacyChecksum(v int) int {
+	return v * 31
+}
+
+//vf:ignore
+func debugDump(o Order) string {
+	return o.ID
+}
+`;
+
+describe('source annotations', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('source-annotations');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'),
+      'package order\n\n//vf:keep-together\ntype Order struct {\n\tID string\n}\n');
+    await createMockFile(path.join(tempDir, 'internal/order/order_total.go'),
+      'package order\n\nfunc (o *Order) Total() int {\n\treturn round(len(o.ID))\n}\n\nfunc round(v int) int {\n\treturn v\n}\n');
+    await createMockFile(path.join(tempDir, 'internal/order/invoice.go'), INVOICE);
+    await createMockFile(path.join(tempDir, 'internal/billing/bill.go'), 'package billing\n\nfunc Bill() {}\n');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should parse directives and report invalid ones', () => {
+    const { annotations, problems } = parseAnnotations(INVOICE, 'internal/order/invoice.go');
+
+    expect(annotations.map(a => [a.directive, a.symbol, a.declaration, a.line, a.argument])).toEqual([
+      ['boundary', 'Invoice', 'func', 4, 'billing'],
+      ['freeze', 'LegacyChecksum', 'func', 7, undefined],
+      ['ignore', 'debugDump', 'func', 12, undefined],
+    ]);
+    expect(annotations.every(a => a.source === 'annotation')).toBe(true);
+    expect(isIgnored(annotations, 'internal/order/invoice.go', 'debugDump')).toBe(true);
+    expect(isIgnored(annotations, 'internal/order/invoice.go', 'Invoice')).toBe(false);
+    expect(problems).toEqual([]);
+
+    const invalid = parseAnnotations(
+      'package order\n\n//vf:pin billing\nfunc A() {}\n\n//vf:boundary\nfunc B() {}\n\n//vf:keep-together\nfunc C() {}\n\n//vf:freeze\n\nvar x = 1\n',
+      'a.go'
+    );
+    expect(invalid.annotations).toEqual([]);
+    expect(invalid.problems.map(p => [p.line, p.message.split(' (')[0]])).toEqual([
+      [3, 'unknown directive //vf:pin'],
+      [6, '//vf:boundary needs a boundary name'],
+      [9, '//vf:keep-together applies to types, not to func C'],
+      [12, '//vf:freeze is not attached to a declaration'],
+    ]);
+  });
+
+  it('should collect annotations during AST parsing and let them override clustering', async () => {
+    const analysis = await new ASTAnalyzer(tempDir).analyzeGoProject();
+    const unit = analysis.annotations.find(a => a.directive === 'keep-together')!;
+    expect(unit.members).toEqual([
+      { symbol: 'Order.Total', file: 'internal/order/order_total.go' },
+      { symbol: 'round', file: 'internal/order/order_total.go' },
+    ]);
+
+    const result = applyAnnotations(tempDir, [
+      { name: 'order', files: ['internal/order/order.go', 'internal/order/invoice.go'] },
+      { name: 'misc', files: ['internal/order/order_total.go'] },
+      { name: 'billing', files: ['internal/billing/bill.go'] },
+    ], [...analysis.annotations, { ...unit, directive: 'boundary', argument: 'shipping', symbol: 'Order', members: undefined }]);
+
+    expect(result.moved).toEqual(['internal/order/invoice.go', 'internal/order/order_total.go']);
+    expect(result.boundaries.map(b => [b.name, b.files])).toEqual([
+      ['order', ['internal/order/order.go', 'internal/order/order_total.go']],
+      ['billing', ['internal/billing/bill.go', 'internal/order/invoice.go']],
+    ]);
+    expect(result.boundaries[1].annotations?.map(a => `${a.directive}:${a.symbol}`)).toEqual([
+      'boundary:Invoice',
+      'freeze:LegacyChecksum',
+      'ignore:debugDump',
+    ]);
+    expect(result.unknown.map(a => a.argument)).toEqual(['shipping']);
+  });
+
+  it('should keep directives on moved declarations and refuse to rewrite frozen ones', () => {
+    const { annotations } = parseAnnotations(INVOICE, 'internal/order/invoice.go');
+    const moved = 'package billing\n\n// Invoice creates an invoice.\nfunc Invoice() {}\n\nfunc LegacyChecksum(v int) int {\n    return v * 31\n}\n';
+
+    const [output] = preserveAnnotations(annotations, INVOICE, [{ path: 'internal/billing/invoice.go', content: moved }]);
+    expect(output.content).toContain('// Invoice creates an invoice.\n//vf:boundary billing\nfunc Invoice() {}');
+    expect(output.content).toContain('//vf:freeze\nfunc LegacyChecksum(v int) int {');
+    // Already attached directives are not duplicated
+    expect(preserveAnnotations(annotations, INVOICE, [output])[0].content).toBe(output.content);
+
+    const rewritten = moved.replace('v * 31', 'v * 37');
+    expect(() => preserveAnnotations(annotations, INVOICE, [{ path: 'internal/billing/invoice.go', content: rewritten }]))
+      .toThrow('frozen declaration LegacyChecksum was rewritten');
+  });
+});
@@ -0,0 +1,68 @@
+import { describe, it, expect } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import { extractSqlQueries, renderSqlcQueryFile } from '../../src/core/utils/sql-extractor.js';
+import { SqlcGenerator } from '../../src/core/utils/sqlc-generator.js';
+
+const fixturePath = './tests/fixtures/business-logic-samples.go';
+
+describe('extractSqlQueries', () => {
+  it('should extract the static getUserOrderHistory query as a named :many query', () => {
+    const source = fs.readFileSync(fixturePath, 'utf8');
+    const { queries, nonExtractable } = extractSqlQueries(source, 'business-logic-samples.go');
+
+    expect(nonExtractable).toHaveLength(0);
+    expect(queries).toHaveLength(1);
+    expect(queries[0].name).toBe('GetUserOrderHistory');
+    expect(queries[0].command).toBe(':many');
+    expect(queries[0].sql.startsWith('SELECT o.id')).toBe(true);
+    expect(queries[0].sql).toContain('LIMIT ?');
+
+    const queryFile = renderSqlcQueryFile(queries);
+    expect(queryFile).toContain('-- name: GetUserOrderHistory :many');
+  });
+
+  it('should flag queries built with fmt.Sprintf or concatenation as non-extractable', () => {
+    const source = `
+package repo
+
+func findByStatus(db *sql.DB, status string) error {
+	query := fmt.Sprintf("SELECT id FROM orders WHERE status = '%s'", status)
+	_, err := db.Query(query)
+	return err
+}
+
+func findSorted(db *sql.DB, column string) error {
+	_, err := db.Query("SELECT id FROM orders ORDER BY " + column)
+	return err
+}
+
+func getOrder(db *sql.DB, id string) error {
+	return db.QueryRow("SELECT id FROM orders WHERE id = ?", id).Scan(&id)
+}
+`;
+    const { queries, nonExtractable } = extractSqlQueries(source, 'repo.go');
+
+    expect(nonExtractable.map(q => q.function)).toEqual(['findByStatus', 'findSorted']);
+    expect(nonExtractable[0].reason).toContain('fmt.Sprintf');
+    expect(nonExtractable[1].reason).toContain('concatenation');
+    expect(queries.map(q => `${q.name}${q.command}`)).toEqual(['GetOrder:one']);
+  });
+});
+
+describe('SqlcGenerator', () => {
+  it('should generate query file, sqlc.yaml, generate hook and repository for a module', () => {
+    const source = fs.readFileSync(fixturePath, 'utf8');
+    const { queries } = extractSqlQueries(source, 'business-logic-samples.go');
+    const generator = new SqlcGenerator('/tmp/nonexistent-project', ['db/migrations']);
+
+    const artifacts = generator.generateModuleArtifacts('order', queries);
+    const byName = new Map(artifacts.files.map(f => [path.basename(f.path), f.content]));
+
+    expect(byName.get('order.sql')).toContain('-- name: GetUserOrderHistory :many');
+    expect(byName.get('sqlc.yaml')).toContain('engine: "mysql"');
+    expect(byName.get('sqlc.yaml')).toContain('../../../db/migrations');
+    expect(byName.get('generate.go')).toContain('//go:generate sqlc generate');
+    expect(byName.get('order_repository_sqlc.go')).toContain('*db.Queries');
+  });
+});
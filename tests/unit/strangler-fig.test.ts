@@ -0,0 +1,111 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  parseMigrationMode,
+  planFacades,
+  planStranglerSteps,
+  renderFacade,
+  renderStranglerSection,
+  writeFacadeScaffold,
+} from '../../src/core/utils/strangler-fig.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+const USER = `package user
+
+import "context"
+
+type User struct{ ID string }
+
+func Find(ctx context.Context, id string) (*User, error) {
+	return &User{ID: id}, nil
+}
+
+func Touch(string, ...int) {}
+
+func internalOnly() {}
+`;
+
+const ORDER = `package order
+
+import (
+	"context"
+
+	"example.com/shop/internal/user"
+)
+
+func Place(ctx context.Context, id string) error {
+	u, err := user.Find(ctx, id)
+	if err != nil {
+		return err
+	}
+	user.Touch(u.ID, 1, 2)
+	return nil
+}
+`;
+
+describe('Strangler fig migration', () => {
+  let tempDir: string;
+  const modules = [
+    { name: 'user', files: ['internal/user/user.go'] },
+    { name: 'order', files: ['internal/order/order.go'] },
+  ];
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('strangler-fig');
+    await createMockFile(path.join(tempDir, 'go.mod'), 'module example.com/shop\n\ngo 1.21\n');
+    await createMockFile(path.join(tempDir, 'internal/user/user.go'), USER);
+    await createMockFile(path.join(tempDir, 'internal/order/order.go'), ORDER);
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should plan a facade per module with the call sites to redirect in its phase', () => {
+    expect(parseMigrationMode('Strangler-Fig')).toBe('strangler-fig');
+    expect(() => parseMigrationMode('big-bang')).toThrow('Unknown migration mode');
+
+    const facades = planFacades(tempDir, modules);
+    const [user, order] = facades;
+
+    expect(user).toMatchObject({ module: 'user', path: 'internal/facade/user', import_path: 'example.com/shop/internal/facade/user' });
+    expect(user.operations.map(op => [op.name, op.legacy, op.legacy_import, op.callers])).toEqual([
+      ['Find', 'user.Find', 'example.com/shop/internal/user', ['order']],
+      ['Touch', 'user.Touch', 'example.com/shop/internal/user', ['order']],
+    ]);
+    expect(user.redirects.map(call => [call.location.file, call.location.line, call.function, call.operation])).toEqual([
+      ['internal/order/order.go', 10, 'Place', 'user.Find'],
+      ['internal/order/order.go', 14, 'Place', 'user.Touch'],
+    ]);
+    expect(order.operations).toEqual([]);
+
+    const steps = planStranglerSteps(facades, [{ name: 'user', modules: ['user'] }, { name: 'order', modules: ['order'] }]);
+    expect(steps).toEqual([
+      { phase: 'user', modules: ['user'], redirects: 2 },
+      { phase: 'order', modules: ['order'], redirects: 0 },
+    ]);
+
+    const section = renderStranglerSection({ facades, steps });
+    expect(section).toContain('## ストラングラーフィグ移行 (Strangler Fig)');
+    expect(section).toContain('- user: ファサード `internal/facade/user` (2個の関数)');
+    expect(section).toContain('  - 切り替える呼び出し箇所: 2件（import を `example.com/shop/internal/facade/user` に変更）');
+    expect(section).toContain('    - internal/order/order.go:10:17 order.Place → `user.Find`');
+    expect(section).toContain('- order: 他モジュールからの呼び出しなし（ファサード不要）');
+  });
+
+  it('should generate facades delegating to the legacy package', () => {
+    const [user] = planFacades(tempDir, modules);
+    const source = renderFacade(user);
+
+    expect(source).toContain('package user\n');
+    expect(source).toContain('\tlegacyuser "example.com/shop/internal/user"');
+    expect(source).toContain('type User = legacyuser.User');
+    expect(source).toContain('func Find(ctx context.Context, id string) (*User, error) {\n\treturn legacyuser.Find(ctx, id)\n}');
+    expect(source).toContain('func Touch(p0 string, p1 ...int) {\n\tlegacyuser.Touch(p0, p1...)\n}');
+
+    const scaffold = writeFacadeScaffold(tempDir, user);
+    expect(scaffold).toBe('.vibeflow/strangler/user/internal/facade/user/user.go');
+    expect(fs.readFileSync(path.join(tempDir, scaffold), 'utf8')).toBe(source);
+  });
+});
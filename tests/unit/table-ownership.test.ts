@@ -0,0 +1,146 @@
+import { describe, it, expect } from 'vitest';
+import {
+  TableOwnershipIndex,
+  buildDataOwnership,
+  findOrmTables,
+  findTableAccess,
+  loadSchemaTables,
+  parseSchemaTables,
+} from '../../src/core/utils/table-ownership.js';
+
+const fixtureRoot = './tests/fixtures/schema-ownership';
+const goFiles = [
+  'internal/order/order.go',
+  'internal/order/repository.go',
+  'internal/report/report.go',
+  'internal/user/store.go',
+  'internal/user/user.go',
+];
+
+describe('table ownership', () => {
+  it('should apply migrations in order, skipping down migrations and dropped tables', () => {
+    const schema = loadSchemaTables(fixtureRoot);
+
+    expect(schema.sources).toEqual(['migrations/001_init.up.sql', 'migrations/002_coupons.up.sql']);
+    expect(schema.tables).toEqual([
+      { name: 'coupons', source: 'migrations/002_coupons.up.sql', references: [] },
+      { name: 'order_items', source: 'migrations/001_init.up.sql', references: ['orders'] },
+      { name: 'orders', source: 'migrations/001_init.up.sql', references: ['users', 'coupons'] },
+      { name: 'users', source: 'migrations/001_init.up.sql', references: [] },
+    ]);
+  });
+
+  it('should read configured schema files instead of the default locations', () => {
+    expect(loadSchemaTables(fixtureRoot, ['migrations/002_coupons.up.sql']).tables.map(t => t.name)).toEqual(['coupons']);
+  });
+
+  it('should parse qualified and quoted table names', () => {
+    const sql = 'CREATE TABLE "billing"."Invoices" (id INT, order_id INT REFERENCES shop.orders(id), parent INT REFERENCES invoices(id));';
+    expect(parseSchemaTables(sql, 'schema.sql')).toEqual([
+      { name: 'invoices', source: 'schema.sql', references: ['orders'] },
+    ]);
+  });
+
+  it('should map tables to the structs and queries that touch them', () => {
+    const access = findTableAccess(fixtureRoot, goFiles, ['coupons', 'order_items', 'orders', 'users']);
+
+    expect(access.map(a => [a.table, a.file, a.symbol, a.operation ?? a.via])).toEqual([
+      ['order_items', 'internal/order/order.go', 'Item', 'struct'],
+      ['order_items', 'internal/order/repository.go', 'Save', 'insert'],
+      ['orders', 'internal/order/order.go', 'Order', 'struct'],
+      ['orders', 'internal/order/repository.go', 'ApplyCoupon', 'update'],
+      ['orders', 'internal/order/repository.go', 'ForUser', 'select'],
+      ['orders', 'internal/order/repository.go', 'Save', 'insert'],
+      ['orders', 'internal/report/report.go', 'Revenue', 'select'],
+      ['users', 'internal/order/repository.go', 'ForUser', 'select'],
+      ['users', 'internal/report/report.go', 'Revenue', 'select'],
+      ['users', 'internal/user/store.go', 'Create', 'insert'],
+      ['users', 'internal/user/store.go', 'Find', 'select'],
+      ['users', 'internal/user/user.go', 'User', 'struct'],
+    ]);
+  });
+
+  it('should only count mapped structs and writes as ownership', () => {
+    const index = new TableOwnershipIndex(findTableAccess(fixtureRoot, goFiles, ['order_items', 'orders', 'users']));
+
+    expect(index.tablesOwnedBy(['internal/order/order.go', 'internal/report/report.go'])).toEqual(['order_items', 'orders']);
+    expect(index.strength('internal/order/order.go', 'internal/order/repository.go')).toBe(1);
+    expect(index.strength('internal/user/user.go', 'internal/user/store.go')).toBe(1);
+    expect(index.strength('internal/order/repository.go', 'internal/user/store.go')).toBe(0);
+    expect(index.strength('internal/report/report.go', 'internal/order/order.go')).toBe(0);
+  });
+
+  describe('through ORMs', () => {
+    const ormRoot = './tests/fixtures/orm-ownership';
+    const ormFiles = [
+      'ent/schema/customer.go',
+      'ent/schema/invoice.go',
+      'internal/billing/billing.go',
+      'internal/catalog/product.go',
+      'internal/catalog/repository.go',
+      'internal/crm/crm.go',
+      'internal/pricing/pricing.go',
+      'internal/report/report.go',
+    ];
+
+    it('should take the tables from gorm models and ent schemas without a schema file', () => {
+      expect(findOrmTables(ormRoot, ormFiles)).toEqual([
+        { name: 'clients', source: 'ent/schema/customer.go', references: [] },
+        { name: 'invoices', source: 'ent/schema/invoice.go', references: [] },
+        { name: 'products', source: 'internal/catalog/product.go', references: [] },
+      ]);
+    });
+
+    it('should follow gorm calls, sqlx scans and ent client calls to their tables', () => {
+      const access = findTableAccess(ormRoot, ormFiles, ['clients', 'invoices', 'products']);
+
+      expect(access.map(a => [a.table, a.file, a.symbol, a.operation ?? a.via, a.orm])).toEqual([
+        ['clients', 'ent/schema/customer.go', 'Customer', 'struct', 'ent'],
+        ['clients', 'internal/billing/billing.go', 'Issue', 'select', 'ent'],
+        ['clients', 'internal/crm/crm.go', 'Rename', 'update', 'ent'],
+        ['invoices', 'ent/schema/invoice.go', 'Invoice', 'struct', 'ent'],
+        ['invoices', 'internal/billing/billing.go', 'Issue', 'insert', 'ent'],
+        ['products', 'internal/catalog/product.go', 'Product', 'struct', 'gorm'],
+        ['products', 'internal/catalog/repository.go', 'Add', 'insert', 'gorm'],
+        ['products', 'internal/catalog/repository.go', 'All', 'select', 'gorm'],
+        ['products', 'internal/pricing/pricing.go', 'Reprice', 'update', 'gorm'],
+        ['products', 'internal/report/report.go', 'ProductRow', 'select', 'sqlx'],
+      ]);
+    });
+
+    it('should map tables to structs, packages and modules and flag tables several modules access', () => {
+      const access = findTableAccess(ormRoot, ormFiles, ['clients', 'invoices', 'products']);
+      const ownership = buildDataOwnership({ sources: [], tables: [], access }, [
+        { name: 'catalog', files: ['internal/catalog/product.go', 'internal/catalog/repository.go'] },
+        { name: 'pricing', files: ['internal/pricing/pricing.go'] },
+        { name: 'report', files: ['internal/report/report.go'] },
+        { name: 'billing', files: ['internal/billing/billing.go', 'ent/schema/invoice.go'] },
+        { name: 'crm', files: ['internal/crm/crm.go', 'ent/schema/customer.go'] },
+      ]);
+
+      expect(ownership.tables.find(t => t.table === 'products')).toEqual({
+        table: 'products',
+        structs: [
+          { struct: 'Product', package: 'internal/catalog', file: 'internal/catalog/product.go', orm: 'gorm' },
+          { struct: 'ProductRow', package: 'internal/report', file: 'internal/report/report.go', orm: 'sqlx' },
+        ],
+        modules: [
+          { module: 'catalog', owns: true, files: ['internal/catalog/product.go', 'internal/catalog/repository.go'] },
+          { module: 'pricing', owns: true, files: ['internal/pricing/pricing.go'] },
+          { module: 'report', owns: false, files: ['internal/report/report.go'] },
+        ],
+      });
+      expect(ownership.violations).toEqual([
+        { table: 'clients', modules: ['crm', 'billing'], owners: ['crm'] },
+        { table: 'products', modules: ['catalog', 'pricing', 'report'], owners: ['catalog', 'pricing'] },
+      ]);
+    });
+
+    it('should not count a struct only scanned from a query as owning the table', () => {
+      const index = new TableOwnershipIndex(findTableAccess(ormRoot, ormFiles, ['products']));
+
+      expect(index.tablesOwnedBy(['internal/report/report.go'])).toEqual([]);
+      expect(index.tablesOwnedBy(['internal/pricing/pricing.go'])).toEqual(['products']);
+    });
+  });
+});
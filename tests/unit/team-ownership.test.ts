@@ -0,0 +1,122 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { execFileSync } from 'child_process';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { attributeTeamOwnership, matchCodeowners, parseBlame, parseCodeowners } from '../../src/core/utils/team-ownership.js';
+import { DomainBoundary } from '../../src/core/types/config.js';
+
+const CODEOWNERS = `# Default owners
+*                       @acme/platform
+/internal/order/        @acme/orders
+internal/billing/*.go   @acme/billing finance@acme.com
+docs/*                  @acme/docs
+**/generated/           @acme/tooling
+/internal/order/legacy.go
+`;
+
+function boundary(name: string, files: string[]): DomainBoundary {
+  return { name, description: '', files };
+}
+
+describe('Team ownership', () => {
+  it('should let the last matching CODEOWNERS rule win', () => {
+    const rules = parseCodeowners(CODEOWNERS);
+
+    expect(rules).toHaveLength(6);
+    expect(matchCodeowners(rules, 'internal/order/service.go')).toEqual(['@acme/orders']);
+    expect(matchCodeowners(rules, 'internal/billing/invoice.go')).toEqual(['@acme/billing', 'finance@acme.com']);
+    // *.go in a directory does not reach into its subdirectories
+    expect(matchCodeowners(rules, 'internal/billing/tax/rate.go')).toEqual(['@acme/platform']);
+    expect(matchCodeowners(rules, 'docs/guide/setup.md')).toEqual(['@acme/platform']);
+    expect(matchCodeowners(rules, 'pkg/api/generated/types.go')).toEqual(['@acme/tooling']);
+    // A pattern without owners leaves the file unowned
+    expect(matchCodeowners(rules, 'internal/order/legacy.go')).toEqual([]);
+    expect(matchCodeowners(parseCodeowners('/cmd/ @acme/cli'), 'internal/cmd/main.go')).toBeUndefined();
+  });
+
+  it('should count the lines of each author in blame output', () => {
+    const output = [
+      'abc 1 1 1', 'author Alice', 'author-mail <alice@acme.com>', '\tpackage order',
+      'abc 2 2', 'author Alice', 'author-mail <alice@acme.com>', '\tfunc Place() {}',
+      '000 3 3 1', 'author Not Committed Yet', 'author-mail <not.committed.yet>', '\t// wip',
+      'def 4 4 1', 'author Bob', 'author-mail <bob@acme.com>', '\t}',
+    ].join('\n');
+
+    expect(parseBlame(output)).toEqual([
+      { email: 'alice@acme.com', name: 'Alice', lines: 2 },
+      { email: 'bob@acme.com', name: 'Bob', lines: 1 },
+    ]);
+  });
+
+  describe('in a git checkout', () => {
+    let tempDir: string;
+    let projectRoot: string;
+    const git = (...args: string[]) => execFileSync('git', args, { cwd: tempDir, stdio: 'ignore' });
+    const commit = (author: string, files: Record<string, string>) => {
+      for (const [file, content] of Object.entries(files)) {
+        fs.mkdirSync(path.dirname(path.join(tempDir, file)), { recursive: true });
+        fs.writeFileSync(path.join(tempDir, file), content);
+      }
+      git('add', '-A');
+      git('-c', `user.name=${author}`, '-c', `user.email=${author.toLowerCase()}@acme.com`, 'commit', '-q', '-m', `change by ${author}`);
+    };
+
+    beforeEach(() => {
+      tempDir = fs.mkdtempSync(path.join(os.tmpdir(), 'vibeflow-team-ownership-'));
+      projectRoot = path.join(tempDir, 'services', 'shop');
+      git('init', '-q');
+      commit('Alice', {
+        'services/shop/order/service.go': 'package order\n\nfunc Place() {}\n',
+        'services/shop/order/repository.go': 'package order\n\nfunc Save() {}\n',
+        'services/shop/billing/invoice.go': 'package billing\n\nfunc Issue() {}\n',
+      });
+      commit('Bob', { 'services/shop/billing/invoice.go': 'package billing\n\nfunc Issue() {}\n\nfunc Void() {}\n\nfunc Refund() {}\n' });
+    });
+
+    afterEach(() => {
+      fs.rmSync(tempDir, { recursive: true, force: true });
+    });
+
+    it('should attribute a boundary to the CODEOWNERS team owning most of its files', () => {
+      commit('Alice', {
+        '.github/CODEOWNERS': '/services/shop/order/ @acme/orders\n/services/shop/order/repository.go @acme/orders @acme/dba\n',
+      });
+
+      const [order, billing] = attributeTeamOwnership(projectRoot, [
+        boundary('order', ['order/service.go', 'order/repository.go']),
+        boundary('billing', ['billing/invoice.go']),
+      ]);
+
+      expect(order.ownership).toEqual({
+        team: '@acme/orders',
+        source: 'codeowners',
+        share: 1,
+        owners: [{ owner: '@acme/orders', share: 1 }, { owner: '@acme/dba', share: 0.5 }],
+      });
+      // Not covered by CODEOWNERS: blamed lines decide
+      expect(billing.ownership).toMatchObject({ source: 'blame', team: 'bob@acme.com' });
+    });
+
+    it('should map blamed authors to teams', () => {
+      const [billing] = attributeTeamOwnership(projectRoot, [boundary('billing', ['billing/invoice.go'])], {
+        teams: { payments: ['BOB'], platform: ['alice@acme.com'] },
+      });
+
+      expect(billing.ownership).toEqual({
+        team: 'payments',
+        source: 'blame',
+        share: 0.57,
+        owners: [{ owner: 'payments', share: 0.57 }, { owner: 'platform', share: 0.43 }],
+      });
+    });
+
+    it('should leave boundaries without CODEOWNERS rules or history unowned', () => {
+      fs.rmSync(path.join(tempDir, '.git'), { recursive: true, force: true });
+
+      const [order] = attributeTeamOwnership(projectRoot, [boundary('order', ['order/service.go'])]);
+
+      expect(order.ownership).toBeUndefined();
+    });
+  });
+});
@@ -0,0 +1,39 @@
+import { describe, it, expect } from 'vitest';
+import { assignTestOwnership, testsOwnedBy } from '../../src/core/utils/test-ownership.js';
+
+const fixtureRoot = './tests/fixtures/test-ownership';
+const boundaries = [
+  { name: 'order', files: ['internal/order/order.go', 'internal/order/status.go'] },
+  { name: 'billing', files: ['internal/billing/invoice.go'] },
+];
+
+describe('Test ownership', () => {
+  it('should assign each test to the boundary whose code it references most', () => {
+    const ownership = assignTestOwnership(fixtureRoot, boundaries);
+
+    expect(ownership.map(entry => [entry.file, entry.module, entry.basis])).toEqual([
+      ['internal/billing/checkout_test.go', 'order', 'symbols'],
+      ['internal/billing/invoice_test.go', 'billing', 'symbols'],
+      ['internal/billing/smoke_test.go', 'billing', 'directory'],
+      ['internal/order/order_test.go', 'order', 'symbols'],
+      ['internal/order/status_test.go', 'order', 'source'],
+    ]);
+  });
+
+  it('should count imported and same-package references but not shared test helpers', () => {
+    const ownership = assignTestOwnership(fixtureRoot, boundaries);
+    const references = (file: string) => ownership.find(entry => entry.file === file)?.references;
+
+    expect(references('internal/billing/checkout_test.go')).toEqual({ order: 2, billing: 1 });
+    expect(references('internal/billing/invoice_test.go')).toEqual({ billing: 1 });
+    expect(references('internal/order/order_test.go')).toEqual({ order: 1 });
+  });
+
+  it('should leave tests outside every boundary unowned', () => {
+    const ownership = assignTestOwnership(fixtureRoot, boundaries);
+
+    expect(ownership.some(entry => entry.file.startsWith('cmd/'))).toBe(false);
+    expect(testsOwnedBy(ownership, 'billing')).toEqual(['internal/billing/invoice_test.go', 'internal/billing/smoke_test.go']);
+    expect(assignTestOwnership(fixtureRoot, [])).toEqual([]);
+  });
+});
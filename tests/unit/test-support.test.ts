@@ -0,0 +1,87 @@
+import { describe, it, expect } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  analyzeTestHelpers,
+  planTestSupport,
+  renderTestSupportSection,
+  rewriteHelperImports,
+} from '../../src/core/utils/test-support.js';
+
+const fixtureRoot = './tests/fixtures/test-helpers';
+const boundaries = [
+  { name: 'user', files: ['internal/user/user.go'] },
+  { name: 'order', files: ['internal/order/order.go'] },
+];
+
+describe('shared test helpers', () => {
+  it('should attribute helpers to the boundaries whose tests use them and classify where they belong', () => {
+    const helpers = analyzeTestHelpers(fixtureRoot, boundaries);
+
+    expect(helpers.map(h => [h.id, h.used_by, h.references, h.placement, h.owner])).toEqual([
+      ['internal/testutil.BuildUser', ['order', 'user'], ['user'], 'module', 'user'],
+      ['internal/testutil.BuildOrder', ['order'], ['order'], 'module', 'order'],
+      // Builds a user through BuildUser, but only order tests use it
+      ['internal/testutil.BuildOrderFor', ['order'], ['order', 'user'], 'manual-review', undefined],
+      ['internal/testutil.NewFakeClock', ['user'], [], 'shared', undefined],
+      ['internal/testutil.SetupTestDB', ['order'], [], 'shared', undefined],
+    ]);
+    expect(helpers[0]).toMatchObject({
+      kind: 'func',
+      import_path: 'example.com/shop/internal/testutil',
+      package_name: 'testutil',
+      file: 'internal/testutil/builders.go',
+      line: 8,
+      test_files: ['internal/order/order_test.go', 'internal/user/user_test.go'],
+    });
+  });
+
+  it('should plan a test-support package per module and keep cross-cutting helpers shared', () => {
+    const plan = planTestSupport(fixtureRoot, analyzeTestHelpers(fixtureRoot, boundaries), [{ name: 'user' }, { name: 'order' }]);
+
+    expect(plan.packages.map(p => [p.module, p.dir, p.import_path, p.helpers.map(h => h.symbol)])).toEqual([
+      ['user', 'internal/user/usertest', 'example.com/shop/internal/user/usertest', ['BuildUser']],
+      ['order', 'internal/order/ordertest', 'example.com/shop/internal/order/ordertest', ['BuildOrder']],
+      [undefined, 'internal/testsupport', 'example.com/shop/internal/testsupport', ['NewFakeClock', 'SetupTestDB']],
+    ]);
+    expect(plan.manual_review).toEqual([{
+      id: 'internal/testutil.BuildOrderFor',
+      used_by: ['order'],
+      references: ['order', 'user'],
+      reason: 'only order tests use it, but it refers to entities of user',
+    }]);
+
+    const section = renderTestSupportSection(plan);
+    expect(section).toContain('- **user** → `internal/user/usertest` (package usertest): BuildUser');
+    expect(section).toContain('- **共有** → `internal/testsupport` (package testsupport): NewFakeClock, SetupTestDB');
+    expect(section).toContain('`internal/testutil.BuildOrderFor`');
+
+    // Once user and order are merged, the order builder is no longer foreign
+    const merged = planTestSupport(fixtureRoot, analyzeTestHelpers(fixtureRoot, boundaries), [{ name: 'shop', merged_from: ['user', 'order'] }]);
+    expect(merged.manual_review).toEqual([]);
+    expect(merged.packages[0]).toMatchObject({ module: 'shop', dir: 'internal/shop/shoptest' });
+  });
+
+  it('should rewrite helper imports of a test and keep helpers under review on the old package', () => {
+    const plan = planTestSupport(fixtureRoot, analyzeTestHelpers(fixtureRoot, boundaries), [{ name: 'user' }, { name: 'order' }]);
+    const original = fs.readFileSync(path.join(fixtureRoot, 'internal/order/order_test.go'), 'utf8');
+
+    const { content, rewrites } = rewriteHelperImports(original, plan);
+
+    expect(rewrites.map(r => [r.symbol, r.to])).toEqual([
+      ['BuildUser', 'example.com/shop/internal/user/usertest'],
+      ['BuildOrder', 'example.com/shop/internal/order/ordertest'],
+      ['SetupTestDB', 'example.com/shop/internal/testsupport'],
+    ]);
+    expect(content).toContain('db := testsupport.SetupTestDB(t)');
+    expect(content).toContain('if ordertest.BuildOrder("o2").Placed() {');
+    expect(content).toContain('o, u := testutil.BuildOrderFor("u1")');
+    // String literals are left alone
+    expect(content).toContain('t.Fatal("testutil.BuildOrder must not set a user")');
+    expect(content).toContain('"example.com/shop/internal/testutil"');
+
+    const user = rewriteHelperImports(fs.readFileSync(path.join(fixtureRoot, 'internal/user/user_test.go'), 'utf8'), plan);
+    expect(user.content).toContain('"example.com/shop/internal/user/usertest"');
+    expect(user.content).not.toContain('"example.com/shop/internal/testutil"');
+  });
+});
@@ -0,0 +1,71 @@
+import { describe, it, expect } from 'vitest';
+import {
+  attachThirdPartyDependencies,
+  dependencyFamily,
+  findThirdPartyDependencies,
+  parseGoModRequires,
+} from '../../src/core/utils/third-party-deps.js';
+
+const fixtureRoot = './tests/fixtures/third-party';
+
+const boundaries = [
+  { name: 'order', description: '', files: ['internal/order/service.go'] },
+  { name: 'billing', description: '', files: ['internal/billing/charge.go'] },
+  { name: 'shipping', description: '', files: ['internal/shipping/label.go'] },
+  { name: 'catalog', description: '', files: ['internal/catalog/catalog.go', 'internal/catalog/catalog_test.go'] },
+];
+
+describe('Third-party dependencies', () => {
+  it('should group the modules of an SDK into one dependency and flag the ones many boundaries import', () => {
+    const dependencies = findThirdPartyDependencies(fixtureRoot, boundaries);
+
+    expect(dependencies.map(d => [d.name, d.used_by.map(usage => usage.module), d.shared])).toEqual([
+      ['github.com/aws/aws-sdk-go-v2', ['billing', 'order', 'shipping'], true],
+      ['github.com/redis/go-redis', ['catalog', 'order'], false],
+      ['github.com/google/uuid', ['order'], false],
+      ['github.com/stripe/stripe-go', ['billing'], false],
+    ]);
+    expect(dependencies[0].modules).toEqual([
+      // Not required directly: attributed to the repository it comes from
+      'github.com/aws/aws-sdk-go-v2',
+      'github.com/aws/aws-sdk-go-v2/config',
+      'github.com/aws/aws-sdk-go-v2/service/s3',
+      'github.com/aws/aws-sdk-go-v2/service/sqs',
+    ]);
+    expect(dependencies[3]).toMatchObject({
+      modules: ['github.com/stripe/stripe-go/v76'],
+      packages: ['github.com/stripe/stripe-go/v76', 'github.com/stripe/stripe-go/v76/paymentintent'],
+      used_by: [{ module: 'billing', files: ['internal/billing/charge.go'] }],
+    });
+    // Test-only dependencies (testify) and the project's own packages are left out
+    expect(dependencies.some(d => d.name.includes('testify') || d.name.startsWith('example.com'))).toBe(false);
+  });
+
+  it('should lower the shared threshold when configured', () => {
+    const dependencies = findThirdPartyDependencies(fixtureRoot, boundaries, { sharedModules: 2 });
+
+    expect(dependencies.filter(d => d.shared).map(d => d.name)).toEqual(['github.com/aws/aws-sdk-go-v2', 'github.com/redis/go-redis']);
+  });
+
+  it('should record the dependencies of each boundary as external', () => {
+    const dependencies = findThirdPartyDependencies(fixtureRoot, boundaries);
+    const [order, , , catalog] = attachThirdPartyDependencies(
+      boundaries.map(boundary => ({ ...boundary, dependencies: { internal: ['catalog'], external: [] } })),
+      dependencies
+    );
+
+    expect(order.dependencies).toEqual({
+      internal: ['catalog'],
+      external: ['github.com/aws/aws-sdk-go-v2', 'github.com/redis/go-redis', 'github.com/google/uuid'],
+    });
+    expect(catalog.dependencies?.external).toEqual(['github.com/redis/go-redis']);
+  });
+
+  it('should parse require directives and name dependency families', () => {
+    expect(parseGoModRequires('require (\n\tgithub.com/a/b v1.0.0 // indirect\n\t"gopkg.in/yaml.v3" v3.0.1\n)\nrequire go.uber.org/zap v1.27.0\n'))
+      .toEqual(['github.com/a/b', 'gopkg.in/yaml.v3', 'go.uber.org/zap']);
+    expect(dependencyFamily('github.com/jackc/pgx/v5')).toBe('github.com/jackc/pgx');
+    expect(dependencyFamily('github.com/aws/aws-sdk-go-v2/service/s3')).toBe('github.com/aws/aws-sdk-go-v2');
+    expect(dependencyFamily('gopkg.in/yaml.v3')).toBe('gopkg.in/yaml.v3');
+  });
+});
@@ -0,0 +1,103 @@
+import { describe, it, expect } from 'vitest';
+import { execSync } from 'child_process';
+import {
+  TypeRelationIndex,
+  buildTypeRelations,
+  parseTypeRelations,
+  syntacticTypeRelations,
+} from '../../src/core/utils/type-relations.js';
+import { GoFunction, GoInterface, GoStruct } from '../../src/core/utils/ast-analyzer.js';
+import { loadGoPackages } from '../../src/core/utils/go-packages.js';
+
+const fixtureRoot = './tests/fixtures/type-relations';
+
+function hasGo(): boolean {
+  try {
+    execSync('go version', { stdio: 'ignore' });
+    return true;
+  } catch {
+    return false;
+  }
+}
+
+function struct(name: string, file: string, embeds: string[] = []): GoStruct {
+  return { type: 'struct', name, file, line: 1, dependencies: [], properties: [], methods: [], implementsInterfaces: [], embeds };
+}
+
+function iface(name: string, file: string, methods: string[], extendsNames: string[] = []): GoInterface {
+  return {
+    type: 'interface', name, file, line: 1, dependencies: [], extends: extendsNames,
+    methods: methods.map(method => ({ name: method, parameters: [], returnType: 'error', calls: [] })),
+  };
+}
+
+function method(name: string, file: string, receiver: string): GoFunction {
+  return {
+    type: 'function', name, file, line: 1, dependencies: [], receiver,
+    parameters: [], returnType: 'void', calls: [], function_values: [], tables_accessed: [],
+  };
+}
+
+const expected = [
+  { kind: 'embeds', from_file: 'internal/email/sender.go', from_type: 'Sender', to_file: 'internal/notify/notifier.go', to_type: 'Base' },
+  { kind: 'implements', from_file: 'internal/email/sender.go', from_type: 'Sender', to_file: 'internal/notify/notifier.go', to_type: 'Notifier' },
+  { kind: 'implements', from_file: 'internal/sms/gateway.go', from_type: 'Gateway', to_file: 'internal/notify/notifier.go', to_type: 'Notifier' },
+];
+
+describe('Type relations', () => {
+  it('should keep relations between types declared in non-test project files', () => {
+    const root = '/work/shop';
+    const output = [
+      `embeds\t${root}/internal/email/sender.go\tSender\t${root}/internal/notify/notifier.go\tBase`,
+      `embeds\t${root}/internal/store/cache.go\tCache\t/usr/local/go/src/sync/mutex.go\tMutex`,
+      `implements\t${root}/internal/email/sender_test.go\tfakeSender\t${root}/internal/notify/notifier.go\tNotifier`,
+      `implements\t${root}/vendor/github.com/acme/mail/client.go\tClient\t${root}/internal/notify/notifier.go\tNotifier`,
+      `implements\t${root}/internal/sms/gateway.go\tGateway\t${root}/internal/notify/notifier.go\tNotifier`,
+      'garbage',
+      '',
+    ].join('\n');
+
+    expect(parseTypeRelations(output, root)).toEqual([expected[0], expected[2]]);
+  });
+
+  it('should match embedded names and method sets when go/types is not available', () => {
+    const relations = syntacticTypeRelations(
+      [
+        struct('Base', 'internal/notify/notifier.go'),
+        struct('Sender', 'internal/email/sender.go', ['Base']),
+        struct('Gateway', 'internal/sms/gateway.go'),
+        struct('Entry', 'internal/audit/entry.go'),
+      ],
+      [
+        iface('Notifier', 'internal/notify/notifier.go', ['Send']),
+        iface('Labeled', 'internal/notify/notifier.go', ['String']),
+      ],
+      [
+        method('Send', 'internal/email/sender.go', 's *Sender'),
+        method('Send', 'internal/sms/gateway.go', 'g Gateway'),
+        method('String', 'internal/sms/gateway.go', 'g Gateway'),
+        method('String', 'internal/audit/entry.go', 'e Entry'),
+      ]
+    );
+
+    expect(relations).toEqual({ source: 'syntactic', relations: expected });
+  });
+
+  it('should connect a type and the type it embeds or implements in either direction', () => {
+    const index = new TypeRelationIndex({ source: 'syntactic', relations: expected });
+
+    expect(index.relates({ file: 'internal/notify/notifier.go', name: 'Notifier' }, { file: 'internal/sms/gateway.go', name: 'Gateway' })).toBe(true);
+    expect(index.relates({ file: 'internal/email/sender.go', name: 'Sender' }, { file: 'internal/notify/notifier.go', name: 'Base' })).toBe(true);
+    expect(index.relates({ file: 'internal/email/sender.go', name: 'Sender' }, { file: 'internal/sms/gateway.go', name: 'Gateway' })).toBe(false);
+  });
+
+  it.skipIf(!hasGo())('should type-check the packages with go/types, ignoring trivial interfaces', () => {
+    const relations = buildTypeRelations(fixtureRoot, loadGoPackages(fixtureRoot));
+
+    expect(relations).toEqual({ source: 'go/types', relations: expected });
+  });
+
+  it('should return null without a Go project', () => {
+    expect(buildTypeRelations('./tests/fixtures/frontend-api', [])).toBeNull();
+  });
+});
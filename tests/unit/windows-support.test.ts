@@ -0,0 +1,138 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  applyLineEndings,
+  detectLineEnding,
+  fileSafeTimestamp,
+  isSharingViolation,
+  withSharingRetry,
+  writeTextFile,
+} from '../../src/core/utils/file-io.js';
+import { hashContent } from '../../src/core/utils/module-manifest.js';
+import { resolveWorkspacePath, toWorkspacePath } from '../../src/core/utils/workspace-paths.js';
+import { WorkspaceLock } from '../../src/core/utils/workspace-lock.js';
+import { onShutdown, runShutdownHandlers, shutdownSignals } from '../../src/core/utils/shutdown.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+// These run on every platform: Windows behavior is selected through path.win32 and
+// explicit platform arguments instead of depending on the host OS.
+
+const sharingViolation = (code: string) => Object.assign(new Error(`${code}: resource busy or locked`), { code });
+
+describe('line endings', () => {
+  it('should detect the dominant ending', () => {
+    expect(detectLineEnding('a\r\nb\r\nc\n')).toBe('\r\n');
+    expect(detectLineEnding('a\nb\r\n')).toBe('\n');
+    expect(detectLineEnding('no newline')).toBeNull();
+  });
+
+  it('should preserve the endings of the replaced file by default', () => {
+    const generated = 'package user\n\nfunc Find() {}\n';
+
+    expect(applyLineEndings(generated, 'preserve', 'package user\r\n')).toBe('package user\r\n\r\nfunc Find() {}\r\n');
+    expect(applyLineEndings('package user\r\n', 'preserve', 'package legacy\n')).toBe('package user\n');
+    expect(applyLineEndings(generated, 'preserve', null)).toBe(generated);
+    expect(applyLineEndings('a\r\nb\n', 'crlf')).toBe('a\r\nb\r\n');
+    expect(applyLineEndings('a\r\nb\n', 'lf')).toBe('a\nb\n');
+  });
+
+  it('should treat outputs differing only in endings as unchanged', () => {
+    expect(hashContent('package user\r\n\r\nfunc Find() {}\r\n')).toBe(hashContent('package user\n\nfunc Find() {}\n'));
+    expect(hashContent('package user\n')).not.toBe(hashContent('package order\n'));
+  });
+});
+
+describe('writeTextFile', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('windows-support');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should keep CRLF files CRLF and write new files as generated', async () => {
+    const existing = path.join(tempDir, 'internal/user/user.go');
+    await createMockFile(existing, 'package user\r\n');
+
+    await writeTextFile(existing, 'package user\n\nfunc Find() {}\n');
+    await writeTextFile(path.join(tempDir, 'internal/user/usecase/find.go'), 'package usecase\n');
+
+    expect(fs.readFileSync(existing, 'utf8')).toBe('package user\r\n\r\nfunc Find() {}\r\n');
+    expect(fs.readFileSync(path.join(tempDir, 'internal/user/usecase/find.go'), 'utf8')).toBe('package usecase\n');
+  });
+
+  it('should release the workspace lock on interrupt', () => {
+    const lock = new WorkspaceLock(tempDir, 'interrupt.lock');
+    expect(lock.acquire('test')).toBe(true);
+
+    runShutdownHandlers('SIGBREAK');
+
+    expect(fs.existsSync(lock.path)).toBe(false);
+  });
+});
+
+describe('sharing violations', () => {
+  it('should only retry errors meaning another process holds the file', () => {
+    expect(isSharingViolation(sharingViolation('EBUSY'), 'linux')).toBe(true);
+    expect(isSharingViolation(sharingViolation('EPERM'), 'win32')).toBe(true);
+    expect(isSharingViolation(sharingViolation('EPERM'), 'linux')).toBe(false);
+    expect(isSharingViolation(sharingViolation('ENOENT'), 'win32')).toBe(false);
+  });
+
+  it('should retry until the handle is released and give up after the last attempt', () => {
+    let calls = 0;
+    const result = withSharingRetry(() => {
+      if (++calls < 3) throw sharingViolation('EACCES');
+      return 'renamed';
+    }, { platform: 'win32', delayMs: 1 });
+
+    expect(result).toBe('renamed');
+    expect(calls).toBe(3);
+
+    calls = 0;
+    expect(() => withSharingRetry(() => {
+      calls++;
+      throw sharingViolation('EBUSY');
+    }, { attempts: 2, delayMs: 1 })).toThrow('EBUSY');
+    expect(calls).toBe(2);
+  });
+});
+
+describe('Windows paths and names', () => {
+  it('should store backslash paths with forward slashes and resolve them back with backslashes', () => {
+    const root = 'C:\\work\\monolith';
+
+    expect(toWorkspacePath(root, 'internal\\user\\user.go')).toBe('internal/user/user.go');
+    expect(toWorkspacePath(root, 'C:\\work\\monolith\\.vibeflow\\performance.json')).toBe('.vibeflow/performance.json');
+    expect(resolveWorkspacePath(root, 'internal/user/user.go')).toBe(path.win32.join(root, 'internal', 'user', 'user.go'));
+  });
+
+  it('should name backup directories without characters NTFS rejects', () => {
+    const name = fileSafeTimestamp(new Date('2026-03-01T12:34:56.789Z'));
+
+    expect(name).toBe('2026-03-01T12-34-56-789Z');
+    expect(name).not.toMatch(/[<>:"/\\|?*]/);
+  });
+
+  it('should listen for Ctrl+Break instead of SIGTERM on Windows', () => {
+    expect(shutdownSignals('win32')).toEqual(['SIGINT', 'SIGBREAK', 'SIGHUP']);
+    expect(shutdownSignals('linux')).toContain('SIGTERM');
+  });
+
+  it('should run shutdown handlers once, most recent first', () => {
+    const calls: string[] = [];
+    onShutdown(reason => calls.push(`first:${reason}`));
+    const unregister = onShutdown(() => calls.push('removed'));
+    onShutdown(reason => calls.push(`last:${reason}`));
+    unregister();
+
+    runShutdownHandlers('SIGINT');
+    runShutdownHandlers('exit');
+
+    expect(calls).toEqual(['last:SIGINT', 'first:SIGINT']);
+  });
+});
@@ -0,0 +1,97 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as path from 'path';
+import { WorkerPool } from '../../src/core/utils/worker-pool.js';
+import { ASTAnalyzer } from '../../src/core/utils/ast-analyzer.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+// Answers like serveWorker: { result } or { error }
+const WORKER = [
+  "import { parentPort, threadId, workerData } from 'worker_threads';",
+  'parentPort.on("message", input => {',
+  '  if (input === "crash") process.exit(1);',
+  '  if (input === "hang") return;',
+  '  if (input === "fail") parentPort.postMessage({ error: "cannot fail" });',
+  '  else parentPort.postMessage({ result: { value: input * workerData.factor, thread: threadId } });',
+  '});',
+  '',
+].join('\n');
+
+describe('worker pool', () => {
+  let tempDir: string;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('worker-pool');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should run every task on a bounded number of threads', async () => {
+    const script = path.join(tempDir, 'worker.mjs');
+    await createMockFile(script, WORKER);
+    const pool = new WorkerPool<number, { value: number; thread: number }>(script, 2, { factor: 3 });
+
+    try {
+      const results = await Promise.all([1, 2, 3, 4, 5, 6].map(n => pool.run(n)));
+      expect(results.map(r => r.value)).toEqual([3, 6, 9, 12, 15, 18]);
+      expect(new Set(results.map(r => r.thread)).size).toBeLessThanOrEqual(2);
+    } finally {
+      await pool.close();
+    }
+  });
+
+  it('should fail only the task that errors or crashes its worker', async () => {
+    const script = path.join(tempDir, 'worker.mjs');
+    await createMockFile(script, WORKER);
+    const pool = new WorkerPool<unknown, { value: number }>(script, 1, { factor: 2 });
+
+    try {
+      const results = await Promise.allSettled([pool.run(1), pool.run('fail'), pool.run('crash'), pool.run(4)]);
+      expect(results.map(r => (r.status === 'fulfilled' ? r.value.value : r.reason.message))).toEqual([
+        2,
+        'cannot fail',
+        'worker exited with code 1',
+        8,
+      ]);
+    } finally {
+      await pool.close();
+    }
+  });
+
+  it('should fail queued tasks when no worker starts', async () => {
+    const script = path.join(tempDir, 'broken.mjs');
+    await createMockFile(script, 'throw new Error("no worker");\n');
+    const pool = new WorkerPool<number, number>(script, 2);
+
+    try {
+      await expect(pool.run(1)).rejects.toThrow('no worker');
+    } finally {
+      await pool.close();
+    }
+  });
+
+  it('should fail pending tasks and refuse new ones once closed', async () => {
+    const script = path.join(tempDir, 'worker.mjs');
+    await createMockFile(script, WORKER);
+    const pool = new WorkerPool<unknown, { value: number }>(script, 1, { factor: 2 });
+
+    const pending = Promise.allSettled([pool.run('hang'), pool.run(2)]);
+    await pool.close();
+
+    expect((await pending).map(r => (r.status === 'rejected' ? r.reason.message : r.status))).toEqual([
+      'worker pool is closed',
+      'worker pool is closed',
+    ]);
+    await expect(pool.run(3)).rejects.toThrow('worker pool is closed');
+  });
+
+  it('should give the same analysis with any concurrency', async () => {
+    const fixtureRoot = './tests/fixtures/package-names';
+    const sequential = await new ASTAnalyzer(fixtureRoot).analyzeGoProject();
+    // Run from the sources there is no built worker script: packages are analyzed in process
+    const parallel = await new ASTAnalyzer(fixtureRoot, { concurrency: 4 }).analyzeGoProject();
+
+    expect(parallel).toEqual(sequential);
+  });
+});
@@ -0,0 +1,102 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { WorkspaceCleaner } from '../../src/core/utils/workspace-cleaner.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+describe('WorkspaceCleaner', () => {
+  let tempDir: string;
+  const vibeflow = (...parts: string[]) => path.join(tempDir, '.vibeflow', ...parts);
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('workspace-cleaner');
+    await createMockFile(path.join(tempDir, 'boundary.yaml'), 'boundaries: []\n');
+    await createMockFile(vibeflow('metadata-cache', 'user.json'), '{"a":1}');
+    await createMockFile(vibeflow('patches', 'user.patch'), 'diff --git a/user.go b/user.go\n');
+    await createMockFile(vibeflow('reports', 'data-mapping.json'), '{}');
+    await createMockFile(vibeflow('performance.json'), '{"runs":[]}');
+    await createMockFile(vibeflow('domain-map.json'), '{"boundaries":[]}');
+    for (const run of ['2024-01-01T00-00-00', '2024-01-02T00-00-00', '2024-01-03T00-00-00', '2024-01-04T00-00-00']) {
+      await createMockFile(vibeflow('backups', run, 'user.go'), 'package user\n');
+    }
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should keep the most recent backups', () => {
+    const plan = new WorkspaceCleaner(tempDir).plan({ scopes: ['backups'], keepLastBackups: 2 });
+
+    expect(plan.targets.map(t => t.path)).toEqual([
+      '.vibeflow/backups/2024-01-02T00-00-00',
+      '.vibeflow/backups/2024-01-01T00-00-00',
+    ]);
+    expect(plan.keptBackups).toEqual([
+      '.vibeflow/backups/2024-01-04T00-00-00',
+      '.vibeflow/backups/2024-01-03T00-00-00',
+    ]);
+  });
+
+  it('should leave plans and metrics alone unless requested', () => {
+    const cleaner = new WorkspaceCleaner(tempDir);
+    const plan = cleaner.plan({ scopes: ['cache', 'backups', 'previews', 'reports'] });
+    cleaner.execute(plan);
+
+    expect(fs.existsSync(vibeflow('metadata-cache'))).toBe(false);
+    expect(fs.existsSync(vibeflow('patches'))).toBe(false);
+    expect(fs.existsSync(vibeflow('domain-map.json'))).toBe(true);
+    expect(fs.existsSync(vibeflow('performance.json'))).toBe(true);
+    expect(fs.existsSync(path.join(tempDir, 'boundary.yaml'))).toBe(true);
+
+    cleaner.execute(cleaner.plan({ scopes: ['plans', 'metrics'] }));
+    expect(fs.existsSync(vibeflow('domain-map.json'))).toBe(false);
+    expect(fs.existsSync(vibeflow('performance.json'))).toBe(false);
+  });
+
+  it('should report sizes without deleting on a dry run plan', () => {
+    const plan = new WorkspaceCleaner(tempDir).plan({ scopes: ['cache', 'previews'] });
+
+    expect(plan.targets).toEqual([
+      { scope: 'cache', path: '.vibeflow/metadata-cache', bytes: 7 },
+      { scope: 'previews', path: '.vibeflow/patches', bytes: 31 },
+    ]);
+    expect(plan.totalBytes).toBe(38);
+    expect(fs.existsSync(vibeflow('metadata-cache', 'user.json'))).toBe(true);
+  });
+
+  it('should refuse to clean while another process holds a workspace lock', async () => {
+    await createMockFile(vibeflow('vibeflow.lock'), JSON.stringify({
+      pid: process.ppid,
+      command: 'refactor',
+      hostname: os.hostname(),
+      acquired_at: new Date().toISOString(),
+    }));
+    const cleaner = new WorkspaceCleaner(tempDir);
+
+    expect(cleaner.findActiveLock()).toContain('refactor');
+    expect(() => cleaner.execute(cleaner.plan({ scopes: ['cache'] }))).toThrow(/locked/);
+    expect(fs.existsSync(vibeflow('metadata-cache'))).toBe(true);
+  });
+
+  it('should find no lock in a project without .vibeflow', async () => {
+    fs.rmSync(vibeflow(), { recursive: true, force: true });
+
+    expect(new WorkspaceCleaner(tempDir).findActiveLock()).toBeNull();
+  });
+
+  it('should append an audit entry to the vibeflow log', () => {
+    const cleaner = new WorkspaceCleaner(tempDir);
+    const reclaimed = cleaner.execute(cleaner.plan({ scopes: ['cache'] }));
+
+    const lines = fs.readFileSync(vibeflow('logs', 'vibeflow.log'), 'utf8').trim().split('\n');
+    const entry = JSON.parse(lines[lines.length - 1]);
+    expect(entry).toMatchObject({
+      event: 'clean',
+      scopes: ['cache'],
+      removed: [{ path: '.vibeflow/metadata-cache', bytes: 7 }],
+      bytes_reclaimed: reclaimed,
+    });
+  });
+});
@@ -0,0 +1,60 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import { spawnSync } from 'child_process';
+import * as fs from 'fs';
+import * as os from 'os';
+import * as path from 'path';
+import { LockInfo, WorkspaceLock } from '../../src/core/utils/workspace-lock.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+describe('WorkspaceLock', () => {
+  let tempDir: string;
+  const lockFile = () => path.join(tempDir, '.vibeflow/race.lock');
+  const holder = (overrides: Partial<LockInfo>): string => JSON.stringify({
+    pid: process.pid,
+    command: 'vf refactor',
+    hostname: os.hostname(),
+    acquired_at: new Date().toISOString(),
+    ...overrides,
+  });
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('workspace-lock');
+  });
+
+  afterEach(async () => {
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should let only one of two racing acquires take the lock', () => {
+    const first = new WorkspaceLock(tempDir, 'race.lock');
+    const second = new WorkspaceLock(tempDir, 'race.lock');
+
+    expect([first.acquire('vf refactor'), second.acquire('vf clean')]).toEqual([true, false]);
+    expect(first.readHolder()?.command).toBe('vf refactor');
+
+    first.release();
+    expect(second.acquire('vf clean')).toBe(true);
+    second.release();
+    expect(fs.existsSync(lockFile())).toBe(false);
+  });
+
+  it('should let only one of two racing acquires take over a stale lock', async () => {
+    const deadPid = spawnSync(process.execPath, ['-e', '']).pid!;
+    await createMockFile(lockFile(), holder({ pid: deadPid }));
+    const first = new WorkspaceLock(tempDir, 'race.lock');
+    const second = new WorkspaceLock(tempDir, 'race.lock');
+
+    expect([first.acquire('vf refactor'), second.acquire('vf clean')]).toEqual([true, false]);
+    expect(first.readHolder()).toMatchObject({ pid: process.pid, command: 'vf refactor' });
+    first.release();
+  });
+
+  it('should treat a lock held on another host as held by acquire and isHeldByOtherProcess alike', async () => {
+    await createMockFile(lockFile(), holder({ pid: 1, hostname: `${os.hostname()}-remote` }));
+    const lock = new WorkspaceLock(tempDir, 'race.lock');
+
+    expect(lock.isHeldByOtherProcess()).toBe(true);
+    expect(lock.acquire('vf refactor')).toBe(false);
+    expect(lock.readHolder()?.hostname).toBe(`${os.hostname()}-remote`);
+  });
+});
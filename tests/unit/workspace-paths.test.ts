@@ -0,0 +1,104 @@
+import { describe, it, expect, beforeEach, afterEach } from 'vitest';
+import * as fs from 'fs';
+import * as path from 'path';
+import {
+  toWorkspacePath,
+  resolveWorkspacePath,
+  portableArtifact,
+  migrateWorkspaceArtifacts,
+  WorkspacePathError,
+  MODULE_CACHE_PREFIX,
+} from '../../src/core/utils/workspace-paths.js';
+import { createTempDir, cleanupTempDir, createMockFile } from '../setup.js';
+
+describe('workspace paths', () => {
+  let tempDir: string;
+  let workspace: string;
+  const originalModCache = process.env.GOMODCACHE;
+
+  beforeEach(async () => {
+    tempDir = await createTempDir('workspace-paths');
+    workspace = path.join(tempDir, 'monolith');
+    await createMockFile(path.join(workspace, 'internal', 'user', 'user.go'), 'package user\n');
+    process.env.GOMODCACHE = path.join(tempDir, 'gomod');
+  });
+
+  afterEach(async () => {
+    if (originalModCache === undefined) delete process.env.GOMODCACHE;
+    else process.env.GOMODCACHE = originalModCache;
+    await cleanupTempDir(tempDir);
+  });
+
+  it('should round-trip POSIX paths through the workspace-relative form', () => {
+    const absolute = path.join(workspace, 'internal', 'user', 'user.go');
+    const stored = toWorkspacePath(workspace, absolute);
+
+    expect(stored).toBe('internal/user/user.go');
+    expect(resolveWorkspacePath(workspace, stored)).toBe(absolute);
+  });
+
+  it('should round-trip Windows paths with forward slashes', () => {
+    const root = 'C:\\Users\\tanaka\\src\\monolith';
+    const stored = toWorkspacePath(root, 'C:\\Users\\tanaka\\src\\monolith\\internal\\user\\user.go');
+
+    expect(stored).toBe('internal/user/user.go');
+    expect(toWorkspacePath(root, 'internal\\user\\user.go')).toBe('internal/user/user.go');
+    expect(resolveWorkspacePath(root, stored)).toBe('C:\\Users\\tanaka\\src\\monolith\\internal\\user\\user.go');
+  });
+
+  it('should match files through a symlinked workspace root', () => {
+    const link = path.join(tempDir, 'link');
+    fs.symlinkSync(workspace, link, 'dir');
+
+    expect(toWorkspacePath(link, path.join(workspace, 'internal', 'user', 'user.go'))).toBe('internal/user/user.go');
+    expect(toWorkspacePath(workspace, path.join(link, 'internal', 'user', 'user.go'))).toBe('internal/user/user.go');
+  });
+
+  it('should prefix module cache references and reject other outside paths', () => {
+    const cached = path.join(tempDir, 'gomod', 'github.com', 'pkg', 'errors@v0.9.1', 'errors.go');
+    const stored = toWorkspacePath(workspace, cached);
+
+    expect(stored).toBe(`${MODULE_CACHE_PREFIX}github.com/pkg/errors@v0.9.1/errors.go`);
+    expect(resolveWorkspacePath(workspace, stored)).toBe(cached);
+    expect(() => toWorkspacePath(workspace, '/etc/passwd')).toThrow(WorkspacePathError);
+    expect(() => toWorkspacePath(workspace, '../other/main.go')).toThrow(/outside the workspace/);
+  });
+
+  it('should make path fields portable and strip the root from free text', () => {
+    const artifact = portableArtifact(workspace, {
+      boundaries: [{
+        name: 'user',
+        description: `Files under ${workspace}/internal`,
+        files: [path.join(workspace, 'internal', 'user', 'user.go')],
+        dependencies: { internal: ['order'] },
+        circular_dependencies: [`${workspace}/a.go → ${workspace}/b.go`],
+      }],
+    });
+
+    expect(artifact.boundaries[0].files).toEqual(['internal/user/user.go']);
+    expect(artifact.boundaries[0].description).toBe('Files under internal');
+    expect(artifact.boundaries[0].dependencies.internal).toEqual(['order']);
+    expect(artifact.boundaries[0].circular_dependencies).toEqual(['a.go → b.go']);
+  });
+
+  it('should migrate artifacts written in another checkout once', async () => {
+    await createMockFile(path.join(workspace, '.vibeflow', 'domain-map.json'), JSON.stringify({
+      boundaries: [{ name: 'user', files: ['/Users/tanaka/src/monolith/internal/user/user.go', '/Users/tanaka/gone.go'] }],
+    }));
+    await createMockFile(path.join(workspace, '.vibeflow', 'checkpoint.json'), JSON.stringify({
+      projectPath: '/Users/tanaka/src/monolith',
+      stepProgress: { processedFiles: ['internal\\user\\user.go'], failedFiles: [] },
+    }));
+
+    const result = migrateWorkspaceArtifacts(workspace);
+    const domainMap = JSON.parse(fs.readFileSync(path.join(workspace, '.vibeflow', 'domain-map.json'), 'utf8'));
+    const checkpoint = JSON.parse(fs.readFileSync(path.join(workspace, '.vibeflow', 'checkpoint.json'), 'utf8'));
+
+    expect(result.migrated).toEqual(['.vibeflow/domain-map.json', '.vibeflow/checkpoint.json']);
+    expect(result.unresolved).toEqual(['/Users/tanaka/gone.go']);
+    expect(domainMap.boundaries[0].files[0]).toBe('internal/user/user.go');
+    expect(checkpoint.projectPath).toBe('.');
+    expect(checkpoint.stepProgress.processedFiles).toEqual(['internal/user/user.go']);
+    expect(migrateWorkspaceArtifacts(workspace).migrated).toEqual([]);
+  });
+});